@@ -3,11 +3,22 @@ package main
 import (
 	"github.com/PremiereGlobal/stim/stim"
 	"github.com/PremiereGlobal/stim/stimpacks/aws"
+	"github.com/PremiereGlobal/stim/stimpacks/azure"
 	"github.com/PremiereGlobal/stim/stimpacks/completion"
+	"github.com/PremiereGlobal/stim/stimpacks/config"
+	"github.com/PremiereGlobal/stim/stimpacks/datadog"
 	"github.com/PremiereGlobal/stim/stimpacks/deploy"
+	"github.com/PremiereGlobal/stim/stimpacks/doctor"
+	"github.com/PremiereGlobal/stim/stimpacks/github"
 	"github.com/PremiereGlobal/stim/stimpacks/kubernetes"
 	"github.com/PremiereGlobal/stim/stimpacks/pagerduty"
+	"github.com/PremiereGlobal/stim/stimpacks/registry"
+	"github.com/PremiereGlobal/stim/stimpacks/scaffold"
+	"github.com/PremiereGlobal/stim/stimpacks/schema"
 	"github.com/PremiereGlobal/stim/stimpacks/slack"
+	"github.com/PremiereGlobal/stim/stimpacks/ssh"
+	"github.com/PremiereGlobal/stim/stimpacks/terraform"
+	"github.com/PremiereGlobal/stim/stimpacks/update"
 	"github.com/PremiereGlobal/stim/stimpacks/vault"
 	"github.com/PremiereGlobal/stim/stimpacks/version"
 )
@@ -15,11 +26,22 @@ import (
 func main() {
 	stim := stim.New()
 	stim.AddStimpack(aws.New())
+	stim.AddStimpack(azure.New())
 	stim.AddStimpack(completion.New())
+	stim.AddStimpack(config.New())
+	stim.AddStimpack(datadog.New())
 	stim.AddStimpack(deploy.New())
+	stim.AddStimpack(doctor.New())
+	stim.AddStimpack(github.New())
 	stim.AddStimpack(kubernetes.New())
 	stim.AddStimpack(pagerduty.New())
+	stim.AddStimpack(registry.New())
+	stim.AddStimpack(scaffold.New())
+	stim.AddStimpack(schema.New())
 	stim.AddStimpack(slack.New())
+	stim.AddStimpack(ssh.New())
+	stim.AddStimpack(terraform.New())
+	stim.AddStimpack(update.New())
 	stim.AddStimpack(vault.New())
 	stim.AddStimpack(version.New())
 	stim.Execute()
@@ -0,0 +1,66 @@
+package aws
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// AssumeRoleOptions are the options of an STS AssumeRole call.  ExternalID,
+// MFASerial (with MFAToken) and Duration are optional.
+type AssumeRoleOptions struct {
+	RoleARN     string
+	SessionName string
+	ExternalID  string
+	MFASerial   string
+	MFAToken    string
+	Duration    time.Duration
+}
+
+// CreateSessionWithToken creates a session from temporary credentials.  The
+// region is optional and overrides the default.
+func (a *Aws) CreateSessionWithToken(accessKey string, secretKey string, sessionToken string, region string) error {
+	config := &aws.Config{Credentials: credentials.NewStaticCredentials(accessKey, secretKey, sessionToken)}
+	if region != "" {
+		config.Region = aws.String(region)
+	}
+
+	session, err := session.NewSession(config)
+	if err != nil {
+		return err
+	}
+	a.setSession(session)
+
+	return nil
+}
+
+// AssumeRole assumes a role with the credentials of the current session and
+// returns the credentials of the role
+func (a *Aws) AssumeRole(options *AssumeRoleOptions) (*sts.Credentials, error) {
+
+	input := &sts.AssumeRoleInput{
+		RoleArn:         aws.String(options.RoleARN),
+		RoleSessionName: aws.String(options.SessionName),
+	}
+	if options.ExternalID != "" {
+		input.ExternalId = aws.String(options.ExternalID)
+	}
+	if options.MFASerial != "" {
+		input.SerialNumber = aws.String(options.MFASerial)
+		input.TokenCode = aws.String(options.MFAToken)
+	}
+	if options.Duration > 0 {
+		input.DurationSeconds = aws.Int64(int64(options.Duration.Seconds()))
+	}
+
+	output, err := sts.New(a.session).AssumeRole(input)
+	if err != nil {
+		return nil, fmt.Errorf("Error assuming role %s: %v", options.RoleARN, err)
+	}
+
+	return output.Credentials, nil
+}
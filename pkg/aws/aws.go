@@ -3,6 +3,8 @@ package aws
 import (
 	// 	"github.com/aws/aws-sdk-go/aws"
 	// 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/PremiereGlobal/stim/pkg/bom"
+	"github.com/PremiereGlobal/stim/pkg/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 )
 
@@ -17,6 +19,14 @@ type Config struct {
 	AccessKey string
 	SecretKey string
 	Log       Logger
+	// Recorder records the API calls of the sessions in the bill of materials
+	Recorder *bom.Recorder
+	// MaxRetries is the number of times the AWS SDK retries failed calls, the
+	// SDK default if nil
+	MaxRetries *int
+	// Credentials caches the SSO tokens instead of the AWS CLI cache files,
+	// if set
+	Credentials credentials.Store
 }
 
 type Logger interface {
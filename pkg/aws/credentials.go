@@ -2,6 +2,7 @@ package aws
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/PremiereGlobal/stim/pkg/utils"
@@ -11,7 +12,7 @@ import (
 
 // GetFederationToken takes in a name and returns a set of STS Credentials
 // based on the current session
-func (a *Aws) GetFederationToken(name string, duration time.Duration) *sts.Credentials {
+func (a *Aws) GetFederationToken(name string, duration time.Duration) (*sts.Credentials, error) {
 
 	// Start a new STS session
 	s := sts.New(a.session)
@@ -26,15 +27,16 @@ func (a *Aws) GetFederationToken(name string, duration time.Duration) *sts.Crede
 	durationSeconds := int64(duration.Seconds())
 	output, err := s.GetFederationToken(&sts.GetFederationTokenInput{Name: &name, Policy: &stsUserPolicy, DurationSeconds: &durationSeconds})
 	if err != nil {
-		a.log.Fatal("Error getting Federation Token: ", err)
+		return nil, fmt.Errorf("Error getting Federation Token: %v", err)
 	}
-	return output.Credentials
+	return output.Credentials, nil
 }
 
-// WaitForActiveCreds waits for the current session to become valid
+// VerifyActiveCreds waits for the current session to become valid and returns
+// an error if the credentials are invalid or don't become active in time.
 // This is useful when IAM credentials were just provisioned and we need to wait
 // until they're active to take the next step.
-func (a *Aws) WaitForActiveCreds() {
+func (a *Aws) VerifyActiveCreds() error {
 
 	retryInterval := time.Second * 2
 	retryLimit := 20
@@ -46,6 +48,7 @@ func (a *Aws) WaitForActiveCreds() {
 
 	// Here we retry a call to GetCallerIdentity which will return an
 	// InvalidClientTokenId error code until the credentials become active
+	var validationErr error
 	err := utils.Retry(retryLimit, retryInterval, func() error {
 
 		_, err := s.GetCallerIdentity(&sts.GetCallerIdentityInput{})
@@ -54,9 +57,9 @@ func (a *Aws) WaitForActiveCreds() {
 				a.log.Info("AWS credentials not yet active, waiting...")
 				successes = 0
 				return err
-			} else {
-				a.log.Fatal("Error validating AWS credentials: ", err)
 			}
+			validationErr = err
+			return nil
 		}
 
 		successes += 1
@@ -68,10 +71,15 @@ func (a *Aws) WaitForActiveCreds() {
 		a.log.Info("AWS credentials are active")
 		return nil
 	})
+	if validationErr != nil {
+		return fmt.Errorf("Error validating AWS credentials: %v", validationErr)
+	}
 
 	// If we've reached this point, the credentials did not become active within
 	// the retry limit
 	if err != nil {
-		a.log.Fatal("Error validating AWS credentials (not active within "+string(retryLimit)+" seconds) ", err)
+		return fmt.Errorf("Error validating AWS credentials (not active within %s): %v", time.Duration(retryLimit)*retryInterval, err)
 	}
+
+	return nil
 }
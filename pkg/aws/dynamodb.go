@@ -0,0 +1,84 @@
+package aws
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Table describes a DynamoDB table.  The item count and size are estimates
+// that DynamoDB updates about every six hours.
+type Table struct {
+	Name           string    `json:"name"`
+	Status         string    `json:"status"`
+	ItemCount      int64     `json:"itemCount"`
+	SizeBytes      int64     `json:"sizeBytes"`
+	Created        time.Time `json:"created"`
+	BillingMode    string    `json:"billingMode"`
+	PartitionKey   string    `json:"partitionKey"`
+	SortKey        string    `json:"sortKey,omitempty"`
+	Indexes        []string  `json:"indexes,omitempty"`
+	StreamViewType string    `json:"streamViewType,omitempty"`
+}
+
+// ListTables returns the names of the DynamoDB tables of the region
+func (a *Aws) ListTables() ([]string, error) {
+
+	s := dynamodb.New(a.session)
+
+	var names []string
+	err := s.ListTablesPages(&dynamodb.ListTablesInput{}, func(page *dynamodb.ListTablesOutput, lastPage bool) bool {
+		names = append(names, aws.StringValueSlice(page.TableNames)...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+// DescribeTable returns the status, estimated size and keys of a DynamoDB
+// table
+func (a *Aws) DescribeTable(name string) (*Table, error) {
+
+	s := dynamodb.New(a.session)
+
+	result, err := s.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String(name)})
+	if err != nil {
+		return nil, err
+	}
+
+	d := result.Table
+	table := &Table{
+		Name:        aws.StringValue(d.TableName),
+		Status:      aws.StringValue(d.TableStatus),
+		ItemCount:   aws.Int64Value(d.ItemCount),
+		SizeBytes:   aws.Int64Value(d.TableSizeBytes),
+		Created:     aws.TimeValue(d.CreationDateTime),
+		BillingMode: dynamodb.BillingModeProvisioned,
+	}
+	if d.BillingModeSummary != nil {
+		table.BillingMode = aws.StringValue(d.BillingModeSummary.BillingMode)
+	}
+	for _, key := range d.KeySchema {
+		switch aws.StringValue(key.KeyType) {
+		case dynamodb.KeyTypeHash:
+			table.PartitionKey = aws.StringValue(key.AttributeName)
+		case dynamodb.KeyTypeRange:
+			table.SortKey = aws.StringValue(key.AttributeName)
+		}
+	}
+	for _, index := range d.GlobalSecondaryIndexes {
+		table.Indexes = append(table.Indexes, aws.StringValue(index.IndexName))
+	}
+	for _, index := range d.LocalSecondaryIndexes {
+		table.Indexes = append(table.Indexes, aws.StringValue(index.IndexName))
+	}
+	if d.StreamSpecification != nil && aws.BoolValue(d.StreamSpecification.StreamEnabled) {
+		table.StreamViewType = aws.StringValue(d.StreamSpecification.StreamViewType)
+	}
+
+	return table, nil
+}
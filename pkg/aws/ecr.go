@@ -0,0 +1,135 @@
+package aws
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/private/protocol/jsonrpc"
+)
+
+// ecrHostRegexp matches the host of an ECR registry and captures its
+// account ID and region
+var ecrHostRegexp = regexp.MustCompile(`^(\d{12})\.dkr\.ecr(?:-fips)?\.([a-z0-9-]+)\.amazonaws\.com(?:\.cn)?$`)
+
+// ECRAuthorization is the Docker login of an ECR registry
+type ECRAuthorization struct {
+	Username string
+	Password string
+
+	// Endpoint is the URL of the registry (ex.
+	// `https://123456789012.dkr.ecr.us-west-2.amazonaws.com`)
+	Endpoint string
+
+	ExpiresAt time.Time
+}
+
+// ecrGetAuthorizationTokenInput is the input of the ECR GetAuthorizationToken
+// call.  The ECR client isn't vendored, so only this call is defined.
+type ecrGetAuthorizationTokenInput struct {
+	_ struct{} `type:"structure"`
+
+	RegistryIds []*string `locationName:"registryIds" min:"1" type:"list"`
+}
+
+// ecrGetAuthorizationTokenOutput is the output of GetAuthorizationToken
+type ecrGetAuthorizationTokenOutput struct {
+	_ struct{} `type:"structure"`
+
+	AuthorizationData []*ecrAuthorizationData `locationName:"authorizationData" type:"list"`
+}
+
+// ecrAuthorizationData is a registry login returned by GetAuthorizationToken
+type ecrAuthorizationData struct {
+	_ struct{} `type:"structure"`
+
+	AuthorizationToken *string    `locationName:"authorizationToken" type:"string"`
+	ExpiresAt          *time.Time `locationName:"expiresAt" type:"timestamp"`
+	ProxyEndpoint      *string    `locationName:"proxyEndpoint" type:"string"`
+}
+
+// ParseECRHost returns the account ID and region of an ECR registry host (ex.
+// `123456789012.dkr.ecr.us-west-2.amazonaws.com`).  ok is false if the host
+// isn't an ECR registry.
+func ParseECRHost(host string) (accountID string, region string, ok bool) {
+	match := ecrHostRegexp.FindStringSubmatch(host)
+	if match == nil {
+		return "", "", false
+	}
+	return match[1], match[2], true
+}
+
+// GetECRAuthorization returns the Docker login of the ECR registry of an
+// account in the region of the session.  The registry of the account of the
+// credentials is used if the account ID is empty.
+func (a *Aws) GetECRAuthorization(accountID string) (*ECRAuthorization, error) {
+
+	c := a.session.ClientConfig("api.ecr")
+	if c.SigningNameDerived || c.SigningName == "" {
+		c.SigningName = "ecr"
+	}
+	svc := client.New(*c.Config, metadata.ClientInfo{
+		ServiceName:   "ecr",
+		ServiceID:     "ECR",
+		SigningName:   c.SigningName,
+		SigningRegion: c.SigningRegion,
+		Endpoint:      c.Endpoint,
+		APIVersion:    "2015-09-21",
+		JSONVersion:   "1.1",
+		TargetPrefix:  "AmazonEC2ContainerRegistry_V20150921",
+	}, c.Handlers)
+	svc.Handlers.Sign.PushBackNamed(v4.SignRequestHandler)
+	svc.Handlers.Build.PushBackNamed(jsonrpc.BuildHandler)
+	svc.Handlers.Unmarshal.PushBackNamed(jsonrpc.UnmarshalHandler)
+	svc.Handlers.UnmarshalMeta.PushBackNamed(jsonrpc.UnmarshalMetaHandler)
+	svc.Handlers.UnmarshalError.PushBackNamed(jsonrpc.UnmarshalErrorHandler)
+
+	input := &ecrGetAuthorizationTokenInput{}
+	if accountID != "" {
+		input.RegistryIds = aws.StringSlice([]string{accountID})
+	}
+	output := &ecrGetAuthorizationTokenOutput{}
+	op := &request.Operation{Name: "GetAuthorizationToken", HTTPMethod: "POST", HTTPPath: "/"}
+	err := svc.NewRequest(op, input, output).Send()
+	if err != nil {
+		return nil, err
+	}
+	if len(output.AuthorizationData) == 0 {
+		return nil, errors.New("ECR returned no authorization token")
+	}
+
+	data := output.AuthorizationData[0]
+	username, password, err := decodeECRToken(aws.StringValue(data.AuthorizationToken))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ECRAuthorization{
+		Username:  username,
+		Password:  password,
+		Endpoint:  aws.StringValue(data.ProxyEndpoint),
+		ExpiresAt: aws.TimeValue(data.ExpiresAt),
+	}, nil
+}
+
+// decodeECRToken returns the username and password of an ECR authorization
+// token, which is the base64 of `<username>:<password>`
+func decodeECRToken(token string) (string, string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return "", "", fmt.Errorf("Invalid ECR authorization token: %v", err)
+	}
+	parts := strings.SplitN(string(decoded), ":", 2)
+	if len(parts) != 2 {
+		return "", "", errors.New("Invalid ECR authorization token: not in the <username>:<password> format")
+	}
+	return parts[0], parts[1], nil
+}
@@ -0,0 +1,126 @@
+package aws
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+)
+
+// AccessKey describes an IAM access key and when it was last used
+type AccessKey struct {
+	ID              string
+	UserName        string
+	Status          string
+	Created         time.Time
+	LastUsed        time.Time
+	LastUsedService string
+	LastUsedRegion  string
+}
+
+// Age returns how long ago the access key was created
+func (k *AccessKey) Age() time.Duration {
+	return time.Since(k.Created)
+}
+
+// ListAccessKeys returns the access keys of the given IAM user, including
+// last used details.  If userName is empty, the keys of the current user are
+// returned.
+func (a *Aws) ListAccessKeys(userName string) ([]*AccessKey, error) {
+
+	s := iam.New(a.session)
+
+	input := &iam.ListAccessKeysInput{}
+	if userName != "" {
+		input.UserName = aws.String(userName)
+	}
+
+	var keys []*AccessKey
+	for {
+		result, err := s.ListAccessKeys(input)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range result.AccessKeyMetadata {
+			key := &AccessKey{
+				ID:       aws.StringValue(m.AccessKeyId),
+				UserName: aws.StringValue(m.UserName),
+				Status:   aws.StringValue(m.Status),
+				Created:  aws.TimeValue(m.CreateDate),
+			}
+
+			lastUsed, err := s.GetAccessKeyLastUsed(&iam.GetAccessKeyLastUsedInput{AccessKeyId: m.AccessKeyId})
+			if err != nil {
+				return nil, err
+			}
+			if lastUsed.AccessKeyLastUsed != nil {
+				key.LastUsed = aws.TimeValue(lastUsed.AccessKeyLastUsed.LastUsedDate)
+				key.LastUsedService = aws.StringValue(lastUsed.AccessKeyLastUsed.ServiceName)
+				key.LastUsedRegion = aws.StringValue(lastUsed.AccessKeyLastUsed.Region)
+			}
+
+			keys = append(keys, key)
+		}
+
+		if !aws.BoolValue(result.IsTruncated) {
+			return keys, nil
+		}
+		input.Marker = result.Marker
+	}
+}
+
+// CreateAccessKey creates a new access key for the given IAM user (or the
+// current user if userName is empty) and returns it as a profile
+func (a *Aws) CreateAccessKey(userName string) (*Profile, error) {
+
+	s := iam.New(a.session)
+
+	input := &iam.CreateAccessKeyInput{}
+	if userName != "" {
+		input.UserName = aws.String(userName)
+	}
+
+	result, err := s.CreateAccessKey(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Profile{
+		AccessKeyID:     aws.StringValue(result.AccessKey.AccessKeyId),
+		SecretAccessKey: aws.StringValue(result.AccessKey.SecretAccessKey),
+	}, nil
+}
+
+// DeactivateAccessKey sets the given access key to inactive
+func (a *Aws) DeactivateAccessKey(userName string, accessKeyID string) error {
+
+	s := iam.New(a.session)
+
+	input := &iam.UpdateAccessKeyInput{
+		AccessKeyId: aws.String(accessKeyID),
+		Status:      aws.String(iam.StatusTypeInactive),
+	}
+	if userName != "" {
+		input.UserName = aws.String(userName)
+	}
+
+	_, err := s.UpdateAccessKey(input)
+	return err
+}
+
+// DeleteAccessKey deletes the given access key
+func (a *Aws) DeleteAccessKey(userName string, accessKeyID string) error {
+
+	s := iam.New(a.session)
+
+	input := &iam.DeleteAccessKeyInput{
+		AccessKeyId: aws.String(accessKeyID),
+	}
+	if userName != "" {
+		input.UserName = aws.String(userName)
+	}
+
+	_, err := s.DeleteAccessKey(input)
+	return err
+}
@@ -108,6 +108,31 @@ func writeSection(profileConfig *ini.File, name string, profile *Profile, additi
 	return nil
 }
 
+// GetProfiles returns the keys and values of every profile in the credentials
+// file
+func (a *Aws) GetProfiles() (map[string]map[string]string, error) {
+
+	credentialPath, err := a.GetCredentialPath()
+	if err != nil {
+		return nil, err
+	}
+
+	profileConfig, err := ini.Load(credentialPath)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := make(map[string]map[string]string)
+	for _, section := range profileConfig.Sections() {
+		if section.Name() == ini.DefaultSection {
+			continue
+		}
+		profiles[section.Name()] = section.KeysHash()
+	}
+
+	return profiles, nil
+}
+
 // GetCredentialPath gets the filepath to the credential path in the user's
 // home directory
 func (a *Aws) GetCredentialPath() (string, error) {
@@ -124,3 +149,64 @@ func (a *Aws) GetCredentialPath() (string, error) {
 
 	return credentialPath, nil
 }
+
+// FindAccessKeyProfiles returns the names of the profiles in the credentials
+// file that use the given access key ID
+func (a *Aws) FindAccessKeyProfiles(accessKeyID string) ([]string, error) {
+
+	credentialPath, err := a.GetCredentialPath()
+	if err != nil {
+		return nil, err
+	}
+
+	profileConfig, err := ini.Load(credentialPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles []string
+	for _, section := range profileConfig.Sections() {
+		if section.HasKey("aws_access_key_id") && section.Key("aws_access_key_id").String() == accessKeyID {
+			profiles = append(profiles, section.Name())
+		}
+	}
+
+	return profiles, nil
+}
+
+// ReplaceAccessKey updates the keys of every profile using the given access
+// key ID, leaving any other profile settings unchanged.  Returns the names of
+// the updated profiles.
+func (a *Aws) ReplaceAccessKey(accessKeyID string, profile *Profile) ([]string, error) {
+
+	credentialPath, err := a.GetCredentialPath()
+	if err != nil {
+		return nil, err
+	}
+
+	profileConfig, err := ini.Load(credentialPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated []string
+	for _, section := range profileConfig.Sections() {
+		if !section.HasKey("aws_access_key_id") || section.Key("aws_access_key_id").String() != accessKeyID {
+			continue
+		}
+
+		section.Key("aws_access_key_id").SetValue(profile.AccessKeyID)
+		section.Key("aws_secret_access_key").SetValue(profile.SecretAccessKey)
+		updated = append(updated, section.Name())
+	}
+
+	if len(updated) > 0 {
+		a.log.Debug("Saving profiles {} in {}", updated, credentialPath)
+		err = profileConfig.SaveTo(credentialPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return updated, nil
+}
@@ -0,0 +1,109 @@
+package aws
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// PutObject uploads an object to S3
+func (a *Aws) PutObject(bucket string, key string, body []byte, contentType string) error {
+
+	s := s3.New(a.session)
+
+	_, err := s.PutObject(&s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String(contentType),
+	})
+
+	return err
+}
+
+// Bucket describes an S3 bucket
+type Bucket struct {
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+}
+
+// Object describes an S3 object
+type Object struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"lastModified"`
+	StorageClass string    `json:"storageClass"`
+}
+
+// ListBuckets returns the S3 buckets of the account
+func (a *Aws) ListBuckets() ([]*Bucket, error) {
+
+	s := s3.New(a.session)
+
+	result, err := s.ListBuckets(&s3.ListBucketsInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	var buckets []*Bucket
+	for _, b := range result.Buckets {
+		buckets = append(buckets, &Bucket{Name: aws.StringValue(b.Name), Created: aws.TimeValue(b.CreationDate)})
+	}
+
+	return buckets, nil
+}
+
+// GetBucketRegion returns the region of an S3 bucket
+func (a *Aws) GetBucketRegion(bucket string) (string, error) {
+
+	s := s3.New(a.session)
+
+	result, err := s.GetBucketLocation(&s3.GetBucketLocationInput{Bucket: aws.String(bucket)})
+	if err != nil {
+		return "", err
+	}
+
+	// Buckets in us-east-1 have no location constraint
+	region := aws.StringValue(result.LocationConstraint)
+	if region == "" {
+		region = "us-east-1"
+	}
+	return region, nil
+}
+
+// ListObjects returns up to max objects of the bucket whose keys start with
+// the prefix, and whether there are more objects
+func (a *Aws) ListObjects(bucket string, prefix string, max int) ([]*Object, bool, error) {
+
+	s := s3.New(a.session)
+
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(bucket)}
+	if prefix != "" {
+		input.Prefix = aws.String(prefix)
+	}
+
+	var objects []*Object
+	truncated := false
+	err := s.ListObjectsV2Pages(input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, o := range page.Contents {
+			if len(objects) == max {
+				truncated = true
+				return false
+			}
+			objects = append(objects, &Object{
+				Key:          aws.StringValue(o.Key),
+				Size:         aws.Int64Value(o.Size),
+				LastModified: aws.TimeValue(o.LastModified),
+				StorageClass: aws.StringValue(o.StorageClass),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return objects, truncated, nil
+}
@@ -0,0 +1,31 @@
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+)
+
+// GetSecretValue returns the value of a Secrets Manager secret.  If
+// versionStage is empty, the current version (AWSCURRENT) is returned.
+func (a *Aws) GetSecretValue(secretID string, versionStage string) (string, error) {
+
+	s := secretsmanager.New(a.session)
+
+	input := &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(secretID),
+	}
+	if versionStage != "" {
+		input.VersionStage = aws.String(versionStage)
+	}
+
+	result, err := s.GetSecretValue(input)
+	if err != nil {
+		return "", err
+	}
+
+	if result.SecretString != nil {
+		return *result.SecretString, nil
+	}
+
+	return string(result.SecretBinary), nil
+}
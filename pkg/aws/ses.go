@@ -0,0 +1,17 @@
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ses"
+)
+
+// SendRawEmail sends a MIME message with SES in the region of the session
+func (a *Aws) SendRawEmail(from string, to []string, message []byte) error {
+
+	_, err := ses.New(a.session).SendRawEmail(&ses.SendRawEmailInput{
+		Source:       aws.String(from),
+		Destinations: aws.StringSlice(to),
+		RawMessage:   &ses.RawMessage{Data: message},
+	})
+	return err
+}
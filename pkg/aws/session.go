@@ -1,8 +1,10 @@
 package aws
 
 import (
+	"github.com/PremiereGlobal/stim/pkg/bom"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 )
 
@@ -12,7 +14,56 @@ func (a *Aws) CreateSession(accessKey string, secretKey string) error {
 	if err != nil {
 		return err
 	}
-	a.session = session
+	a.setSession(session)
 
 	return nil
 }
+
+// CreateDefaultSession creates a session using the default credential chain
+// (environment, shared credentials/config files, instance role, etc.)
+// Profile and region are optional and override the defaults.
+func (a *Aws) CreateDefaultSession(profile string, region string) error {
+	config := aws.Config{}
+	if region != "" {
+		config.Region = aws.String(region)
+	}
+
+	session, err := session.NewSessionWithOptions(session.Options{
+		Config:            config,
+		Profile:           profile,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return err
+	}
+	a.setSession(session)
+
+	return nil
+}
+
+// setSession sets the session of the client and records its API calls if
+// there is a recorder
+func (a *Aws) setSession(s *session.Session) {
+	if a.config.Recorder != nil {
+		recorder := a.config.Recorder
+		s.Handlers.Complete.PushBack(func(r *request.Request) {
+			recorder.Add(bom.KindAws, r.ClientInfo.ServiceName+":"+r.Operation.Name, map[string]string{
+				"region": aws.StringValue(r.Config.Region),
+			})
+		})
+	}
+	if a.config.MaxRetries != nil {
+		s.Config.MaxRetries = aws.Int(*a.config.MaxRetries)
+	}
+	a.session = s
+}
+
+// GetAccessKeyID returns the access key ID used by the current session
+func (a *Aws) GetAccessKeyID() (string, error) {
+	creds, err := a.session.Config.Credentials.Get()
+	if err != nil {
+		return "", err
+	}
+
+	return creds.AccessKeyID, nil
+}
@@ -0,0 +1,45 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+// ssmMaxParameters is the maximum number of parameters per GetParameters call
+const ssmMaxParameters = 10
+
+// GetParameters returns the (decrypted) values of the given SSM parameters,
+// keyed by parameter name.  An error is returned if any are not found.
+func (a *Aws) GetParameters(names []string) (map[string]string, error) {
+
+	s := ssm.New(a.session)
+
+	values := make(map[string]string)
+	for i := 0; i < len(names); i += ssmMaxParameters {
+		end := i + ssmMaxParameters
+		if end > len(names) {
+			end = len(names)
+		}
+
+		result, err := s.GetParameters(&ssm.GetParametersInput{
+			Names:          aws.StringSlice(names[i:end]),
+			WithDecryption: aws.Bool(true),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(result.InvalidParameters) > 0 {
+			return nil, fmt.Errorf("SSM parameter(s) not found: %s", strings.Join(aws.StringValueSlice(result.InvalidParameters), ", "))
+		}
+
+		for _, p := range result.Parameters {
+			values[aws.StringValue(p.Name)] = aws.StringValue(p.Value)
+		}
+	}
+
+	return values, nil
+}
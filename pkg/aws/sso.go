@@ -0,0 +1,407 @@
+package aws
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/utils"
+	"github.com/mitchellh/go-homedir"
+)
+
+// The IAM Identity Center (SSO) APIs are unsigned REST/JSON APIs, so they are
+// called directly rather than through the SDK
+const (
+	ssoOIDCEndpoint   = "https://oidc.%s.amazonaws.com"
+	ssoPortalEndpoint = "https://portal.sso.%s.amazonaws.com"
+	ssoClientName     = "stim"
+	ssoGrantType      = "urn:ietf:params:oauth:grant-type:device_code"
+
+	// ssoDeviceExpiry is used if the device authorization doesn't say when
+	// it expires
+	ssoDeviceExpiry = 10 * time.Minute
+
+	// ssoRequestTimeout is the timeout of each SSO API request
+	ssoRequestTimeout = 30 * time.Second
+)
+
+// ssoClient is the HTTP client used for the SSO APIs
+var ssoClient = &http.Client{Timeout: ssoRequestTimeout}
+
+// SSOToken is a cached IAM Identity Center access token.  The format matches
+// the AWS CLI so that tokens are shared between the two.
+type SSOToken struct {
+	StartURL    string    `json:"startUrl"`
+	Region      string    `json:"region"`
+	AccessToken string    `json:"accessToken"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+// SSOAccount is an AWS account available to the SSO user
+type SSOAccount struct {
+	AccountID   string `json:"accountId"`
+	AccountName string `json:"accountName"`
+}
+
+// SSOCredentials are temporary role credentials obtained through SSO
+type SSOCredentials struct {
+	AccessKeyID     string `json:"accessKeyId"`
+	SecretAccessKey string `json:"secretAccessKey"`
+	SessionToken    string `json:"sessionToken"`
+	Expiration      int64  `json:"expiration"`
+}
+
+// SSODeviceAuthorization contains the details the user needs to approve a login
+type SSODeviceAuthorization struct {
+	UserCode                string
+	VerificationURIComplete string
+}
+
+// IsValid returns true if the token exists and hasn't expired
+func (t *SSOToken) IsValid() bool {
+	return t != nil && t.AccessToken != "" && time.Now().Add(time.Minute).Before(t.ExpiresAt)
+}
+
+// SSOLogin returns an IAM Identity Center access token for the given start URL.
+// A cached token is used if still valid, otherwise the device authorization
+// grant is performed.  The approve function is called with the details the
+// user needs to approve the login in their browser.
+func (a *Aws) SSOLogin(startURL string, region string, approve func(*SSODeviceAuthorization)) (*SSOToken, error) {
+
+	token, cache, err := a.readSSOCache(startURL)
+	if err != nil {
+		return nil, err
+	}
+	if token.IsValid() {
+		a.log.Debug("Using cached SSO token from {}", cache)
+		return token, nil
+	}
+
+	oidcURL := fmt.Sprintf(ssoOIDCEndpoint, region)
+
+	// Register a public client
+	var client struct {
+		ClientID     string `json:"clientId"`
+		ClientSecret string `json:"clientSecret"`
+	}
+	err = ssoRequest("POST", oidcURL+"/client/register", "", map[string]interface{}{
+		"clientName": ssoClientName,
+		"clientType": "public",
+	}, &client)
+	if err != nil {
+		return nil, err
+	}
+
+	// Start the device authorization
+	var device struct {
+		DeviceCode              string `json:"deviceCode"`
+		UserCode                string `json:"userCode"`
+		VerificationURIComplete string `json:"verificationUriComplete"`
+		ExpiresIn               int    `json:"expiresIn"`
+		Interval                int    `json:"interval"`
+	}
+	err = ssoRequest("POST", oidcURL+"/device_authorization", "", map[string]interface{}{
+		"clientId":     client.ClientID,
+		"clientSecret": client.ClientSecret,
+		"startUrl":     startURL,
+	}, &device)
+	if err != nil {
+		return nil, err
+	}
+
+	approve(&SSODeviceAuthorization{UserCode: device.UserCode, VerificationURIComplete: device.VerificationURIComplete})
+
+	// Poll until the user approves the login
+	interval := time.Duration(device.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	expiresIn := time.Duration(device.ExpiresIn) * time.Second
+	if expiresIn <= 0 {
+		expiresIn = ssoDeviceExpiry
+	}
+	deadline := time.Now().Add(expiresIn)
+	for time.Now().Before(deadline) {
+		time.Sleep(interval)
+
+		var result struct {
+			AccessToken string `json:"accessToken"`
+			ExpiresIn   int    `json:"expiresIn"`
+		}
+		err = ssoRequest("POST", oidcURL+"/token", "", map[string]interface{}{
+			"clientId":     client.ClientID,
+			"clientSecret": client.ClientSecret,
+			"deviceCode":   device.DeviceCode,
+			"grantType":    ssoGrantType,
+		}, &result)
+		if serr, ok := err.(*ssoError); ok && serr.Code == "AuthorizationPendingException" {
+			continue
+		} else if ok && serr.Code == "SlowDownException" {
+			interval += 5 * time.Second
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		token = &SSOToken{
+			StartURL:    startURL,
+			Region:      region,
+			AccessToken: result.AccessToken,
+			ExpiresAt:   time.Now().Add(time.Duration(result.ExpiresIn) * time.Second).UTC(),
+		}
+
+		err = a.writeSSOCache(token)
+		if err != nil {
+			return nil, err
+		}
+
+		return token, nil
+	}
+
+	return nil, errors.New("SSO login was not approved before the device code expired")
+}
+
+// GetCachedSSOToken returns the cached SSO token for the start URL, or nil if
+// there is no valid cached token
+func (a *Aws) GetCachedSSOToken(startURL string) (*SSOToken, error) {
+
+	token, _, err := a.readSSOCache(startURL)
+	if err != nil {
+		return nil, err
+	}
+	if !token.IsValid() {
+		return nil, nil
+	}
+	return token, nil
+}
+
+// readSSOCache reads the cached SSO token of the start URL from the
+// credential store, or from the AWS CLI cache file if there is no store.  The
+// token is nil if there is none or it can't be read.  The location of the
+// cache is returned for logging.
+func (a *Aws) readSSOCache(startURL string) (*SSOToken, string, error) {
+
+	if a.config.Credentials != nil {
+		value, err := a.config.Credentials.Get(ssoCredentialKey(startURL))
+		if err != nil {
+			return nil, "", err
+		}
+		return parseSSOToken([]byte(value)), "credential store " + a.config.Credentials.Name(), nil
+	}
+
+	cachePath, err := getSSOCachePath(startURL)
+	if err != nil {
+		return nil, "", err
+	}
+	b, err := ioutil.ReadFile(cachePath)
+	if err != nil {
+		return nil, cachePath, nil
+	}
+	return parseSSOToken(b), cachePath, nil
+}
+
+// writeSSOCache caches the SSO token in the credential store, or in the AWS
+// CLI cache file (so the AWS CLI can use it) if there is no store
+func (a *Aws) writeSSOCache(token *SSOToken) error {
+
+	b, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	if a.config.Credentials != nil {
+		return a.config.Credentials.Set(ssoCredentialKey(token.StartURL), string(b))
+	}
+
+	cachePath, err := getSSOCachePath(token.StartURL)
+	if err != nil {
+		return err
+	}
+	err = utils.CreateDirIfNotExist(filepath.Dir(cachePath), utils.UserOnlyMode)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(cachePath, b, 0600)
+}
+
+// parseSSOToken parses a cached SSO token, returning nil if it is invalid
+func parseSSOToken(b []byte) *SSOToken {
+	token := &SSOToken{}
+	if json.Unmarshal(b, token) != nil {
+		return nil
+	}
+	return token
+}
+
+// ListSSOAccounts returns the accounts available to the SSO token
+func (a *Aws) ListSSOAccounts(token *SSOToken) ([]SSOAccount, error) {
+
+	var accounts []SSOAccount
+	nextToken := ""
+	for {
+		var result struct {
+			AccountList []SSOAccount `json:"accountList"`
+			NextToken   string       `json:"nextToken"`
+		}
+		query := url.Values{}
+		if nextToken != "" {
+			query.Set("next_token", nextToken)
+		}
+		err := ssoRequest("GET", fmt.Sprintf(ssoPortalEndpoint, token.Region)+"/assignment/accounts?"+query.Encode(), token.AccessToken, nil, &result)
+		if err != nil {
+			return nil, err
+		}
+
+		accounts = append(accounts, result.AccountList...)
+		if result.NextToken == "" {
+			return accounts, nil
+		}
+		nextToken = result.NextToken
+	}
+}
+
+// ListSSOAccountRoles returns the roles available to the SSO token in the given account
+func (a *Aws) ListSSOAccountRoles(token *SSOToken, accountID string) ([]string, error) {
+
+	var roles []string
+	nextToken := ""
+	for {
+		var result struct {
+			RoleList []struct {
+				RoleName string `json:"roleName"`
+			} `json:"roleList"`
+			NextToken string `json:"nextToken"`
+		}
+		query := url.Values{"account_id": []string{accountID}}
+		if nextToken != "" {
+			query.Set("next_token", nextToken)
+		}
+		err := ssoRequest("GET", fmt.Sprintf(ssoPortalEndpoint, token.Region)+"/assignment/roles?"+query.Encode(), token.AccessToken, nil, &result)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range result.RoleList {
+			roles = append(roles, r.RoleName)
+		}
+		if result.NextToken == "" {
+			return roles, nil
+		}
+		nextToken = result.NextToken
+	}
+}
+
+// GetSSORoleCredentials returns temporary credentials for the given account and role
+func (a *Aws) GetSSORoleCredentials(token *SSOToken, accountID string, roleName string) (*SSOCredentials, error) {
+
+	var result struct {
+		RoleCredentials SSOCredentials `json:"roleCredentials"`
+	}
+	query := url.Values{"account_id": []string{accountID}, "role_name": []string{roleName}}
+	err := ssoRequest("GET", fmt.Sprintf(ssoPortalEndpoint, token.Region)+"/federation/credentials?"+query.Encode(), token.AccessToken, nil, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result.RoleCredentials, nil
+}
+
+// ssoError is an error returned by the SSO APIs
+type ssoError struct {
+	Code    string
+	Message string
+}
+
+func (e *ssoError) Error() string {
+	return fmt.Sprintf("AWS SSO: %s %s", e.Code, e.Message)
+}
+
+// ssoRequest makes a JSON request to the SSO APIs and decodes the response into output
+func ssoRequest(method string, requestURL string, bearerToken string, input interface{}, output interface{}) error {
+
+	var body *bytes.Reader
+	if input != nil {
+		b, err := json.Marshal(input)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	} else {
+		body = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequest(method, requestURL, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if bearerToken != "" {
+		req.Header.Set("x-amz-sso_bearer_token", bearerToken)
+	}
+
+	resp, err := ssoClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		serr := &ssoError{Code: resp.Header.Get("x-amzn-ErrorType")}
+		var errBody struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+			Message     string `json:"message"`
+		}
+		if json.Unmarshal(b, &errBody) == nil {
+			if errBody.Error != "" {
+				serr.Code = errBody.Error
+			}
+			serr.Message = errBody.Message + errBody.Description
+		}
+		if serr.Code == "" {
+			serr.Code = resp.Status
+		}
+
+		// The OIDC API returns OAuth style error codes
+		switch serr.Code {
+		case "authorization_pending":
+			serr.Code = "AuthorizationPendingException"
+		case "slow_down":
+			serr.Code = "SlowDownException"
+		}
+		return serr
+	}
+
+	return json.Unmarshal(b, output)
+}
+
+// ssoCredentialKey returns the credential store key of the SSO token of a
+// start URL
+func ssoCredentialKey(startURL string) string {
+	hash := sha1.Sum([]byte(startURL))
+	return "aws-sso/" + hex.EncodeToString(hash[:])
+}
+
+// getSSOCachePath returns the AWS CLI compatible cache path for the SSO token
+func getSSOCachePath(startURL string) (string, error) {
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha1.Sum([]byte(startURL))
+	return filepath.Join(home, ".aws", "sso", "cache", hex.EncodeToString(hash[:])+".json"), nil
+}
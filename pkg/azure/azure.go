@@ -0,0 +1,477 @@
+// Package azure logs in to Azure Active Directory and reads subscriptions and
+// AKS clusters from the Azure Resource Manager API
+package azure
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultAuthorityURL is the Azure Active Directory address of the
+	// public cloud
+	DefaultAuthorityURL = "https://login.microsoftonline.com"
+
+	// DefaultManagementURL is the Azure Resource Manager address of the
+	// public cloud
+	DefaultManagementURL = "https://management.azure.com"
+
+	// CLIClientID is the public client ID of the Azure CLI, used for the
+	// device code login when no other client ID is set
+	CLIClientID = "04b07795-8ddb-461a-bbee-02f9e1bf7b46"
+)
+
+// Config configures an Azure client
+type Config struct {
+
+	// TenantID is the Azure AD tenant (directory) to log in to.  Defaults to
+	// `organizations` (the home tenant of the user) for device code logins
+	TenantID string
+
+	// AuthorityURL overrides DefaultAuthorityURL (ex. for sovereign clouds)
+	AuthorityURL string
+
+	// ManagementURL overrides DefaultManagementURL (ex. for sovereign clouds)
+	ManagementURL string
+
+	// Timeout of each request.  Defaults to 30 seconds
+	Timeout time.Duration
+}
+
+// Azure is an Azure Resource Manager API client
+type Azure struct {
+	tenantID      string
+	authorityURL  string
+	managementURL string
+	client        *http.Client
+	token         *Token
+	sleep         func(time.Duration)
+}
+
+// Token is an Azure AD access token of the Resource Manager API
+type Token struct {
+	TenantID     string    `yaml:"tenant-id"`
+	ClientID     string    `yaml:"client-id"`
+	AccessToken  string    `yaml:"access-token"`
+	RefreshToken string    `yaml:"refresh-token"`
+	ExpiresOn    time.Time `yaml:"expires-on"`
+}
+
+// DeviceCode is the code the user enters to approve a device code login
+type DeviceCode struct {
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	Message         string `json:"message"`
+}
+
+// Subscription is an Azure subscription
+type Subscription struct {
+	ID       string `json:"subscriptionId"`
+	Name     string `json:"displayName"`
+	State    string `json:"state"`
+	TenantID string `json:"tenantId"`
+}
+
+// ManagedCluster is an AKS cluster
+type ManagedCluster struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Location   string `json:"location"`
+	Properties struct {
+		KubernetesVersion string `json:"kubernetesVersion"`
+		Fqdn              string `json:"fqdn"`
+		ProvisioningState string `json:"provisioningState"`
+		PowerState        struct {
+			Code string `json:"code"`
+		} `json:"powerState"`
+	} `json:"properties"`
+}
+
+// tokenResponse is the response of the Azure AD token endpoint
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// New returns an Azure client.  One of the Login functions or SetToken must
+// be called before using the API.
+func New(config *Config) *Azure {
+	a := &Azure{
+		tenantID:      config.TenantID,
+		authorityURL:  strings.TrimSuffix(config.AuthorityURL, "/"),
+		managementURL: strings.TrimSuffix(config.ManagementURL, "/"),
+		client:        &http.Client{Timeout: config.Timeout},
+		sleep:         time.Sleep,
+	}
+	if a.authorityURL == "" {
+		a.authorityURL = DefaultAuthorityURL
+	}
+	if a.managementURL == "" {
+		a.managementURL = DefaultManagementURL
+	}
+	if a.client.Timeout == 0 {
+		a.client.Timeout = 30 * time.Second
+	}
+	return a
+}
+
+// Token returns the access token of the client, nil before logging in
+func (a *Azure) Token() *Token {
+	return a.token
+}
+
+// SetToken sets the access token of the client (ex. from a cache).  An
+// expired token is refreshed with its refresh token on the next request.
+func (a *Azure) SetToken(token *Token) {
+	a.token = token
+}
+
+// LoginClientSecret logs in as a service principal
+func (a *Azure) LoginClientSecret(clientID string, clientSecret string) error {
+
+	if a.tenantID == "" {
+		return errors.New("Azure: The tenant ID must be set to log in as a service principal")
+	}
+
+	response, err := a.requestToken(url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {clientID},
+		"client_secret": {clientSecret},
+		"scope":         {a.scope()},
+	})
+	if err != nil {
+		return err
+	}
+
+	a.token = a.newToken(clientID, response)
+	return nil
+}
+
+// LoginDeviceCode logs in as a user with the device code flow.  prompt is
+// called with the code the user must enter at the verification URI, then
+// the login waits until the user approves or declines it.
+func (a *Azure) LoginDeviceCode(clientID string, prompt func(*DeviceCode)) error {
+
+	if clientID == "" {
+		clientID = CLIClientID
+	}
+
+	var code struct {
+		DeviceCode
+		Code      string `json:"device_code"`
+		ExpiresIn int64  `json:"expires_in"`
+		Interval  int64  `json:"interval"`
+	}
+	err := a.postForm(a.tokenURL("devicecode"), url.Values{
+		"client_id": {clientID},
+		"scope":     {a.scope() + " offline_access"},
+	}, &code)
+	if err != nil {
+		return err
+	}
+
+	prompt(&code.DeviceCode)
+
+	interval := time.Duration(code.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	deadline := time.Now().Add(time.Duration(code.ExpiresIn) * time.Second)
+	for time.Now().Before(deadline) {
+		a.sleep(interval)
+
+		response, err := a.requestToken(url.Values{
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+			"client_id":   {clientID},
+			"device_code": {code.Code},
+		})
+		if err == nil {
+			a.token = a.newToken(clientID, response)
+			return nil
+		}
+
+		if tokenErr, ok := err.(*tokenError); ok {
+			switch tokenErr.code {
+			case "authorization_pending":
+				continue
+			case "slow_down":
+				interval += 5 * time.Second
+				continue
+			}
+		}
+		return err
+	}
+
+	return errors.New("Azure: The device code expired before the login was approved")
+}
+
+// Subscriptions returns the subscriptions the token has access to
+func (a *Azure) Subscriptions() ([]*Subscription, error) {
+
+	var subscriptions []*Subscription
+	err := a.list("/subscriptions?api-version=2020-01-01", func(value json.RawMessage) error {
+		var page []*Subscription
+		err := json.Unmarshal(value, &page)
+		subscriptions = append(subscriptions, page...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// ManagedClusters returns the AKS clusters of a subscription
+func (a *Azure) ManagedClusters(subscriptionID string) ([]*ManagedCluster, error) {
+
+	var clusters []*ManagedCluster
+	err := a.list(fmt.Sprintf("/subscriptions/%s/providers/Microsoft.ContainerService/managedClusters?api-version=2023-08-01", url.PathEscape(subscriptionID)), func(value json.RawMessage) error {
+		var page []*ManagedCluster
+		err := json.Unmarshal(value, &page)
+		clusters = append(clusters, page...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return clusters, nil
+}
+
+// ManagedClusterUserKubeconfig returns the user kubeconfig of an AKS cluster
+func (a *Azure) ManagedClusterUserKubeconfig(subscriptionID string, resourceGroup string, name string) ([]byte, error) {
+
+	var response struct {
+		Kubeconfigs []struct {
+			Name  string `json:"name"`
+			Value []byte `json:"value"`
+		} `json:"kubeconfigs"`
+	}
+	path := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.ContainerService/managedClusters/%s/listClusterUserCredential?api-version=2023-08-01",
+		url.PathEscape(subscriptionID), url.PathEscape(resourceGroup), url.PathEscape(name))
+	err := a.do(http.MethodPost, a.managementURL+path, nil, &response)
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Kubeconfigs) == 0 {
+		return nil, fmt.Errorf("Azure: No kubeconfig returned for cluster %s", name)
+	}
+
+	return response.Kubeconfigs[0].Value, nil
+}
+
+// ResourceGroup returns the resource group of the cluster, from its ID
+func (cluster *ManagedCluster) ResourceGroup() string {
+	parts := strings.Split(cluster.ID, "/")
+	for i := 0; i < len(parts)-1; i++ {
+		if strings.EqualFold(parts[i], "resourceGroups") {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
+
+// list gets all the pages of a Resource Manager list, passing the `value` of
+// each page to add
+func (a *Azure) list(path string, add func(json.RawMessage) error) error {
+
+	next := a.managementURL + path
+	for next != "" {
+		var page struct {
+			Value    json.RawMessage `json:"value"`
+			NextLink string          `json:"nextLink"`
+		}
+		err := a.do(http.MethodGet, next, nil, &page)
+		if err != nil {
+			return err
+		}
+		err = add(page.Value)
+		if err != nil {
+			return fmt.Errorf("Azure: Unable to parse the response: %v", err)
+		}
+		next = page.NextLink
+	}
+
+	return nil
+}
+
+// do sends a Resource Manager request with the access token (refreshing it
+// if expired) and decodes the response into result (if not nil)
+func (a *Azure) do(method string, requestURL string, body interface{}, result interface{}) error {
+
+	err := a.refreshToken()
+	if err != nil {
+		return err
+	}
+
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, requestURL, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+a.token.AccessToken)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode/100 != 2 {
+		var apiErr struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(respBody, &apiErr) == nil && apiErr.Error.Message != "" {
+			return fmt.Errorf("Azure: %s: %s: %s", resp.Status, apiErr.Error.Code, apiErr.Error.Message)
+		}
+		return fmt.Errorf("Azure: %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+
+	if result != nil && len(respBody) > 0 {
+		err = json.Unmarshal(respBody, result)
+		if err != nil {
+			return fmt.Errorf("Azure: Unable to parse the response: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// refreshToken gets a new access token with the refresh token if the access
+// token expires within a minute
+func (a *Azure) refreshToken() error {
+
+	if a.token == nil {
+		return errors.New("Azure: Not logged in")
+	}
+	if time.Now().Add(time.Minute).Before(a.token.ExpiresOn) {
+		return nil
+	}
+	if a.token.RefreshToken == "" {
+		return errors.New("Azure: The access token expired, log in again")
+	}
+
+	response, err := a.requestToken(url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {a.token.ClientID},
+		"refresh_token": {a.token.RefreshToken},
+		"scope":         {a.scope() + " offline_access"},
+	})
+	if err != nil {
+		return fmt.Errorf("Azure: Unable to refresh the access token, log in again: %v", err)
+	}
+
+	a.token = a.newToken(a.token.ClientID, response)
+	return nil
+}
+
+// tokenError is an error of the Azure AD token endpoint
+type tokenError struct {
+	code        string
+	description string
+}
+
+func (e *tokenError) Error() string {
+	return fmt.Sprintf("Azure: %s: %s", e.code, e.description)
+}
+
+// requestToken requests an access token from the Azure AD token endpoint
+func (a *Azure) requestToken(form url.Values) (*tokenResponse, error) {
+	response := &tokenResponse{}
+	err := a.postForm(a.tokenURL("token"), form, response)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// postForm posts a form to Azure AD and decodes the JSON response into
+// result.  OAuth errors are returned as *tokenError.
+func (a *Azure) postForm(endpoint string, form url.Values, result interface{}) error {
+
+	resp, err := a.client.PostForm(endpoint, form)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode/100 != 2 {
+		var oauthErr tokenResponse
+		if json.Unmarshal(respBody, &oauthErr) == nil && oauthErr.Error != "" {
+			return &tokenError{code: oauthErr.Error, description: strings.SplitN(oauthErr.ErrorDescription, "\r\n", 2)[0]}
+		}
+		return fmt.Errorf("Azure: %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+
+	err = json.Unmarshal(respBody, result)
+	if err != nil {
+		return fmt.Errorf("Azure: Unable to parse the response: %v", err)
+	}
+	return nil
+}
+
+// newToken returns the token of a token endpoint response
+func (a *Azure) newToken(clientID string, response *tokenResponse) *Token {
+	return &Token{
+		TenantID:     a.tenant(),
+		ClientID:     clientID,
+		AccessToken:  response.AccessToken,
+		RefreshToken: response.RefreshToken,
+		ExpiresOn:    time.Now().Add(time.Duration(response.ExpiresIn) * time.Second),
+	}
+}
+
+// tokenURL returns the address of an Azure AD OAuth endpoint of the tenant
+func (a *Azure) tokenURL(endpoint string) string {
+	return fmt.Sprintf("%s/%s/oauth2/v2.0/%s", a.authorityURL, url.PathEscape(a.tenant()), endpoint)
+}
+
+// tenant returns the tenant to log in to
+func (a *Azure) tenant() string {
+	if a.tenantID == "" {
+		return "organizations"
+	}
+	return a.tenantID
+}
+
+// scope returns the OAuth scope of the Resource Manager API
+func (a *Azure) scope() string {
+	return a.managementURL + "/.default"
+}
@@ -0,0 +1,137 @@
+package azure
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestLoginClientSecret(t *testing.T) {
+	var form map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.URL.Path, "/tenant/oauth2/v2.0/token")
+		assert.NilError(t, r.ParseForm())
+		form = r.PostForm
+		w.Write([]byte(`{"access_token":"at","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	err := New(&Config{AuthorityURL: server.URL}).LoginClientSecret("id", "secret")
+	assert.Error(t, err, "Azure: The tenant ID must be set to log in as a service principal")
+
+	a := New(&Config{TenantID: "tenant", AuthorityURL: server.URL})
+	assert.NilError(t, a.LoginClientSecret("id", "secret"))
+	assert.Equal(t, a.Token().AccessToken, "at")
+	assert.Equal(t, a.Token().TenantID, "tenant")
+	assert.Assert(t, a.Token().ExpiresOn.After(time.Now().Add(59*time.Minute)))
+	assert.Equal(t, form["grant_type"][0], "client_credentials")
+	assert.Equal(t, form["scope"][0], "https://management.azure.com/.default")
+}
+
+func TestLoginDeviceCode(t *testing.T) {
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/organizations/oauth2/v2.0/devicecode":
+			w.Write([]byte(`{"device_code":"dc","user_code":"ABC123","verification_uri":"https://microsoft.com/devicelogin","expires_in":900,"interval":5}`))
+		case "/organizations/oauth2/v2.0/token":
+			assert.NilError(t, r.ParseForm())
+			assert.Equal(t, r.PostForm.Get("device_code"), "dc")
+			polls++
+			if polls == 1 {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"authorization_pending","error_description":"AADSTS70016: Pending"}`))
+				return
+			}
+			w.Write([]byte(`{"access_token":"at","refresh_token":"rt","expires_in":3600}`))
+		default:
+			t.Fatalf("Unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	a := New(&Config{AuthorityURL: server.URL})
+	a.sleep = func(time.Duration) {}
+	var code *DeviceCode
+	assert.NilError(t, a.LoginDeviceCode("", func(c *DeviceCode) { code = c }))
+
+	assert.Equal(t, code.UserCode, "ABC123")
+	assert.Equal(t, polls, 2)
+	assert.Equal(t, a.Token().ClientID, CLIClientID)
+	assert.Equal(t, a.Token().RefreshToken, "rt")
+}
+
+func TestManagedClusters(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.Header.Get("Authorization"), "Bearer at")
+		if r.URL.Query().Get("page") == "2" {
+			w.Write([]byte(`{"value":[{"id":"/subscriptions/sub/resourceGroups/rg-2/providers/Microsoft.ContainerService/managedClusters/aks-2","name":"aks-2"}]}`))
+			return
+		}
+		assert.Equal(t, r.URL.Path, "/subscriptions/sub/providers/Microsoft.ContainerService/managedClusters")
+		w.Write([]byte(`{"value":[{"id":"/subscriptions/sub/resourceGroups/rg-1/providers/Microsoft.ContainerService/managedClusters/aks-1","name":"aks-1","properties":{"kubernetesVersion":"1.27.3"}}],"nextLink":"` + server.URL + `/next?page=2"}`))
+	}))
+	defer server.Close()
+
+	a := New(&Config{ManagementURL: server.URL})
+	_, err := a.ManagedClusters("sub")
+	assert.Error(t, err, "Azure: Not logged in")
+
+	a.SetToken(&Token{AccessToken: "at", ExpiresOn: time.Now().Add(time.Hour)})
+	clusters, err := a.ManagedClusters("sub")
+	assert.NilError(t, err)
+	assert.Equal(t, len(clusters), 2)
+	assert.Equal(t, clusters[0].Properties.KubernetesVersion, "1.27.3")
+	assert.Equal(t, clusters[0].ResourceGroup(), "rg-1")
+	assert.Equal(t, clusters[1].ResourceGroup(), "rg-2")
+}
+
+func TestError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error":{"code":"AuthorizationFailed","message":"No access"}}`))
+	}))
+	defer server.Close()
+
+	a := New(&Config{ManagementURL: server.URL})
+	a.SetToken(&Token{AccessToken: "at", ExpiresOn: time.Now().Add(time.Hour)})
+	_, err := a.Subscriptions()
+	assert.Error(t, err, "Azure: 403 Forbidden: AuthorizationFailed: No access")
+
+	a.SetToken(&Token{AccessToken: "at", ExpiresOn: time.Now()})
+	_, err = a.Subscriptions()
+	assert.Error(t, err, "Azure: The access token expired, log in again")
+}
+
+func TestKubeconfigCredentials(t *testing.T) {
+	ca := base64.StdEncoding.EncodeToString([]byte("-----BEGIN CERTIFICATE-----"))
+	credentials, err := KubeconfigCredentials([]byte(`
+clusters:
+- name: aks-1
+  cluster:
+    server: https://aks-1.hcp.westus2.azmk8s.io:443
+    certificate-authority-data: ` + ca + `
+users:
+- name: clusterUser_rg-1_aks-1
+  user:
+    token: secret-token
+`))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, *credentials, ClusterCredentials{Server: "https://aks-1.hcp.westus2.azmk8s.io:443", CA: "-----BEGIN CERTIFICATE-----", Token: "secret-token"})
+
+	_, err = KubeconfigCredentials([]byte(`
+clusters:
+- cluster:
+    server: https://aks-1
+users:
+- user:
+    exec:
+      command: kubelogin
+`))
+	assert.Error(t, err, "Azure: The cluster uses Azure AD authentication, only clusters with local accounts can be used")
+}
@@ -0,0 +1,61 @@
+package azure
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ClusterCredentials are the server, CA and token of an AKS kubeconfig
+type ClusterCredentials struct {
+	Server string
+	CA     string
+	Token  string
+}
+
+// KubeconfigCredentials returns the credentials of the first cluster and user
+// of an AKS kubeconfig.  Only clusters with local accounts have a token;
+// clusters with Azure AD authentication use the kubelogin exec plugin
+// instead, which stim contexts don't support.
+func KubeconfigCredentials(kubeconfig []byte) (*ClusterCredentials, error) {
+
+	var config struct {
+		Clusters []struct {
+			Cluster struct {
+				Server                   string `yaml:"server"`
+				CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			} `yaml:"cluster"`
+		} `yaml:"clusters"`
+		Users []struct {
+			User struct {
+				Token string      `yaml:"token"`
+				Exec  interface{} `yaml:"exec"`
+			} `yaml:"user"`
+		} `yaml:"users"`
+	}
+	err := yaml.Unmarshal(kubeconfig, &config)
+	if err != nil {
+		return nil, fmt.Errorf("Azure: Unable to parse the kubeconfig: %v", err)
+	}
+	if len(config.Clusters) == 0 || len(config.Users) == 0 {
+		return nil, errors.New("Azure: The kubeconfig has no cluster or user")
+	}
+
+	user := config.Users[0].User
+	if user.Token == "" {
+		if user.Exec != nil {
+			return nil, errors.New("Azure: The cluster uses Azure AD authentication, only clusters with local accounts can be used")
+		}
+		return nil, errors.New("Azure: The kubeconfig user has no token")
+	}
+
+	cluster := config.Clusters[0].Cluster
+	ca, err := base64.StdEncoding.DecodeString(cluster.CertificateAuthorityData)
+	if err != nil {
+		return nil, fmt.Errorf("Azure: Invalid certificate-authority-data in the kubeconfig: %v", err)
+	}
+
+	return &ClusterCredentials{Server: cluster.Server, CA: string(ca), Token: user.Token}, nil
+}
@@ -0,0 +1,212 @@
+package bom
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kinds of the recorded resources
+const (
+	KindVault      = "vault"
+	KindImage      = "image"
+	KindKubernetes = "kubernetes"
+	KindAws        = "aws"
+)
+
+// Statuses of a bill of materials
+const (
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+)
+
+// BOM is the bill of materials of a command: every external resource it
+// touched while it ran
+type BOM struct {
+	Command   string            `json:"command"`
+	User      string            `json:"user,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Started   time.Time         `json:"started"`
+	Finished  time.Time         `json:"finished"`
+	Status    string            `json:"status"`
+	Error     string            `json:"error,omitempty"`
+	Resources []*Resource       `json:"resources"`
+}
+
+// Resource is an external resource and how many times it was used.  Uses of
+// the same resource with the same details are recorded once.
+type Resource struct {
+	Kind    string            `json:"kind"`
+	Name    string            `json:"name"`
+	Details map[string]string `json:"details,omitempty"`
+	Count   int               `json:"count"`
+}
+
+// Recorder collects the resources used by the instrumented clients.  Nothing
+// is recorded until Start is called and a Recorder is safe to use from several
+// goroutines.
+type Recorder struct {
+	mu        sync.Mutex
+	started   bool
+	bom       *BOM
+	resources map[string]*Resource
+}
+
+// NewRecorder returns a stopped recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Start clears the recorder and starts recording the resources of a command
+func (r *Recorder) Start(command string, user string, at time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.started = true
+	r.bom = &BOM{Command: command, User: user, Labels: make(map[string]string), Started: at}
+	r.resources = make(map[string]*Resource)
+}
+
+// IsStarted returns true if the recorder is recording
+func (r *Recorder) IsStarted() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.started
+}
+
+// SetLabel sets a label of the bill of materials (ex. the environment)
+func (r *Recorder) SetLabel(name string, value string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.started {
+		r.bom.Labels[name] = value
+	}
+}
+
+// Add records the use of a resource
+func (r *Recorder) Add(kind string, name string, details map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.started {
+		return
+	}
+
+	key := resourceKey(kind, name, details)
+	if resource, ok := r.resources[key]; ok {
+		resource.Count++
+		return
+	}
+
+	copied := make(map[string]string, len(details))
+	for k, v := range details {
+		copied[k] = v
+	}
+	r.resources[key] = &Resource{Kind: kind, Name: name, Details: copied, Count: 1}
+}
+
+// Finish stops the recorder and returns the bill of materials.  The resources
+// are sorted by kind and name and err sets the failure status.
+func (r *Recorder) Finish(at time.Time, err error) *BOM {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.started {
+		return nil
+	}
+	r.started = false
+
+	b := r.bom
+	b.Finished = at
+	b.Status = StatusSuccess
+	if err != nil {
+		b.Status = StatusFailure
+		b.Error = err.Error()
+	}
+
+	b.Resources = make([]*Resource, 0, len(r.resources))
+	keys := make([]string, 0, len(r.resources))
+	for key := range r.resources {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		b.Resources = append(b.Resources, r.resources[key])
+	}
+
+	return b
+}
+
+// WriteFile writes the bill of materials to a JSON file
+func (b *BOM) WriteFile(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Transport wraps an HTTP transport of a Vault client to record the Vault
+// path and operation of each request.  Only paths are recorded, never the
+// request or response bodies.
+func (r *Recorder) Transport(address string, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &vaultTransport{recorder: r, address: address, base: base}
+}
+
+type vaultTransport struct {
+	recorder *Recorder
+	address  string
+	base     http.RoundTripper
+}
+
+// RoundTrip records the request and sends it with the wrapped transport
+func (t *vaultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.recorder.Add(KindVault, strings.TrimPrefix(req.URL.Path, "/v1/"), map[string]string{
+		"address":   t.address,
+		"operation": vaultOperation(req),
+	})
+	return t.base.RoundTrip(req)
+}
+
+// vaultOperation returns the Vault operation (ex. `read`) of a request
+func vaultOperation(req *http.Request) string {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		if req.URL.Query().Get("list") == "true" {
+			return "list"
+		}
+		return "read"
+	case "LIST":
+		return "list"
+	case http.MethodDelete:
+		return "delete"
+	default:
+		return "write"
+	}
+}
+
+// resourceKey returns the key that identifies uses of the same resource with
+// the same details.  It also orders the resources by kind and name.
+func resourceKey(kind string, name string, details map[string]string) string {
+	keys := make([]string, 0, len(details))
+	for k := range details {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := []string{kind, name}
+	for _, k := range keys {
+		parts = append(parts, k+"="+details[k])
+	}
+	return strings.Join(parts, "\x00")
+}
@@ -0,0 +1,93 @@
+package bom
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	r := NewRecorder()
+
+	// Nothing is recorded before Start
+	r.Add(KindVault, "secret/ignored", nil)
+	assert.Assert(t, r.Finish(start, nil) == nil)
+
+	r.Start("deploy", "jdoe", start)
+	r.SetLabel("environment", "prod")
+	r.Add(KindKubernetes, "prod-east", map[string]string{"server": "https://prod-east"})
+	r.Add(KindAws, "ssm:GetParameter", map[string]string{"region": "us-east-1"})
+	r.Add(KindAws, "ssm:GetParameter", map[string]string{"region": "us-east-1"})
+	r.Add(KindAws, "ssm:GetParameter", map[string]string{"region": "us-west-2"})
+
+	b := r.Finish(start.Add(time.Minute), errors.New("boom"))
+	assert.Assert(t, !r.IsStarted())
+	assert.Equal(t, b.Command, "deploy")
+	assert.Equal(t, b.User, "jdoe")
+	assert.Equal(t, b.Labels["environment"], "prod")
+	assert.Equal(t, b.Status, StatusFailure)
+	assert.Equal(t, b.Error, "boom")
+	assert.Equal(t, b.Finished, start.Add(time.Minute))
+	assert.Equal(t, len(b.Resources), 3)
+	assert.Equal(t, b.Resources[0].Name, "ssm:GetParameter")
+	assert.Equal(t, b.Resources[0].Details["region"], "us-east-1")
+	assert.Equal(t, b.Resources[0].Count, 2)
+	assert.Equal(t, b.Resources[1].Details["region"], "us-west-2")
+	assert.Equal(t, b.Resources[2].Kind, KindKubernetes)
+
+	dir, err := ioutil.TempDir("", "bom")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "bom.json")
+	assert.NilError(t, b.WriteFile(path))
+	data, err := ioutil.ReadFile(path)
+	assert.NilError(t, err)
+	var written BOM
+	assert.NilError(t, json.Unmarshal(data, &written))
+	assert.DeepEqual(t, written.Resources, b.Resources)
+}
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	defer server.Close()
+
+	r := NewRecorder()
+	r.Start("deploy", "", time.Now())
+	client := &http.Client{Transport: r.Transport("https://vault", nil)}
+
+	for _, req := range []struct {
+		method string
+		path   string
+	}{
+		{"GET", "/v1/secret/app"},
+		{"GET", "/v1/secret/app"},
+		{"GET", "/v1/secret/?list=true"},
+		{"PUT", "/v1/secret/app"},
+	} {
+		request, err := http.NewRequest(req.method, server.URL+req.path, nil)
+		assert.NilError(t, err)
+		resp, err := client.Do(request)
+		assert.NilError(t, err)
+		resp.Body.Close()
+	}
+
+	b := r.Finish(time.Now(), nil)
+	assert.Equal(t, b.Status, StatusSuccess)
+	assert.Equal(t, len(b.Resources), 3)
+	assert.Equal(t, b.Resources[0].Name, "secret/")
+	assert.Equal(t, b.Resources[0].Details["operation"], "list")
+	assert.Equal(t, b.Resources[1].Name, "secret/app")
+	assert.Equal(t, b.Resources[1].Details["operation"], "read")
+	assert.Equal(t, b.Resources[1].Details["address"], "https://vault")
+	assert.Equal(t, b.Resources[1].Count, 2)
+	assert.Equal(t, b.Resources[2].Details["operation"], "write")
+}
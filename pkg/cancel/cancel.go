@@ -0,0 +1,79 @@
+// Package cancel stops the calls of a command to external services (ex.
+// Vault, AWS and Kubernetes) that are in flight when it's cancelled, by Ctrl-C
+// or a timeout.
+package cancel
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Transport wraps an HTTP transport (the default transport if nil) so that
+// the requests in flight when the context is done are cancelled.  Requests
+// started after it's done are sent as usual, so that cleanup (ex. deleting a
+// deploy job or revoking leases) still runs.
+func Transport(ctx context.Context, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if ctx == nil {
+		return base
+	}
+	return &transport{ctx: ctx, base: base}
+}
+
+type transport struct {
+	ctx  context.Context
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	if t.ctx.Err() != nil {
+		return t.base.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithCancel(req.Context())
+	done := make(chan struct{})
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			close(done)
+			cancel()
+		})
+	}
+	go func() {
+		select {
+		case <-t.ctx.Done():
+			cancel()
+		case <-done:
+		}
+	}()
+
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		stop()
+		return resp, err
+	}
+
+	// The body is read after RoundTrip returns (ex. a stream of logs), so
+	// the request is only released once it's closed
+	resp.Body = &body{ReadCloser: resp.Body, stop: stop}
+	return resp, nil
+}
+
+// body releases the request of a response when closed
+type body struct {
+	io.ReadCloser
+	stop func()
+}
+
+// Close implements io.Closer
+func (b *body) Close() error {
+	err := b.ReadCloser.Close()
+	b.stop()
+	return err
+}
@@ -0,0 +1,58 @@
+package cancel
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestTransport(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	client := &http.Client{Transport: Transport(ctx, nil)}
+
+	// Requests work as usual until the context is done
+	resp, err := client.Get(server.URL + "/fast")
+	assert.NilError(t, err)
+	b, err := ioutil.ReadAll(resp.Body)
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), "ok")
+	resp.Body.Close()
+
+	// A request in flight is cancelled
+	errs := make(chan error)
+	go func() {
+		_, err := client.Get(server.URL + "/slow")
+		errs <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancelFunc()
+	select {
+	case err := <-errs:
+		assert.ErrorContains(t, err, "context canceled")
+	case <-time.After(5 * time.Second):
+		t.Fatal("The request wasn't cancelled")
+	}
+
+	// Requests after the cancellation (ex. cleanup) are sent
+	resp, err = client.Get(server.URL + "/fast")
+	assert.NilError(t, err)
+	resp.Body.Close()
+}
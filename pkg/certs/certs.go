@@ -0,0 +1,82 @@
+// Package certs provides the root CAs for TLS connections.  Builds with the
+// `embedroots` tag embed a CA bundle (see roots_gen.go), which is used when
+// the system has no trust store (ex. scratch-based containers).
+package certs
+
+//go:generate go run roots_gen.go
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"sync"
+)
+
+var (
+	once     sync.Once
+	rootCAs  *x509.CertPool
+	embedded bool
+)
+
+// RootCAs returns the root CAs for TLS connections: nil to use the system
+// roots, or the embedded roots when the system has none.  Builds without
+// embedded roots don't load the system roots, which takes several
+// milliseconds, since they always use them.
+func RootCAs() *x509.CertPool {
+	once.Do(func() {
+		if embeddedRoots == "" {
+			return
+		}
+		system, err := x509.SystemCertPool()
+		if err != nil {
+			system = nil
+		}
+		rootCAs, embedded = selectRoots(system, embeddedRoots)
+	})
+	return rootCAs
+}
+
+// UsingEmbedded returns whether the embedded roots are used instead of the
+// system roots
+func UsingEmbedded() bool {
+	RootCAs()
+	return embedded
+}
+
+// Configure sets the root CAs of the default HTTP transport, which is used by
+// clients without their own transport
+func Configure() {
+	if transport, ok := http.DefaultTransport.(*http.Transport); ok {
+		ConfigureTransport(transport)
+	}
+}
+
+// ConfigureTransport sets the root CAs of an HTTP transport that doesn't
+// have its own
+func ConfigureTransport(transport *http.Transport) {
+	pool := RootCAs()
+	if pool == nil {
+		return
+	}
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	if transport.TLSClientConfig.RootCAs == nil {
+		transport.TLSClientConfig.RootCAs = pool
+	}
+}
+
+// selectRoots returns the embedded roots if the system roots are unavailable
+func selectRoots(system *x509.CertPool, pem string) (*x509.CertPool, bool) {
+	if system != nil && len(system.Subjects()) > 0 {
+		return nil, false
+	}
+	if pem == "" {
+		return nil, false
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(pem)) {
+		return nil, false
+	}
+	return pool, true
+}
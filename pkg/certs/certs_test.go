@@ -0,0 +1,74 @@
+package certs
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+// testRoot returns a self-signed CA certificate in PEM
+func testRoot(t *testing.T) string {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NilError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Stim Test Root"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NilError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestSelectRoots(t *testing.T) {
+	root := testRoot(t)
+
+	system := x509.NewCertPool()
+	system.AppendCertsFromPEM([]byte(root))
+
+	// The system roots are used when available
+	pool, embedded := selectRoots(system, root)
+	assert.Assert(t, pool == nil)
+	assert.Assert(t, !embedded)
+
+	// The embedded roots are used without system roots
+	pool, embedded = selectRoots(x509.NewCertPool(), root)
+	assert.Assert(t, embedded)
+	assert.Equal(t, len(pool.Subjects()), 1)
+
+	pool, embedded = selectRoots(nil, root)
+	assert.Assert(t, embedded)
+
+	// Builds without embedded roots keep the (empty) system roots
+	pool, embedded = selectRoots(nil, "")
+	assert.Assert(t, pool == nil)
+	assert.Assert(t, !embedded)
+}
+
+func TestConfigureTransport(t *testing.T) {
+	once.Do(func() {})
+	pool := x509.NewCertPool()
+	rootCAs = pool
+	defer func() { rootCAs = nil }()
+
+	transport := &http.Transport{}
+	ConfigureTransport(transport)
+	assert.Equal(t, transport.TLSClientConfig.RootCAs, pool)
+
+	// Transports with their own roots are not changed
+	own := x509.NewCertPool()
+	transport.TLSClientConfig.RootCAs = own
+	ConfigureTransport(transport)
+	assert.Equal(t, transport.TLSClientConfig.RootCAs, own)
+}
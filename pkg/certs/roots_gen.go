@@ -0,0 +1,64 @@
+//go:build ignore
+// +build ignore
+
+// roots_gen generates roots_embedded.go, the CA bundle of `embedroots`
+// builds, from a PEM file (by default the CA bundle of the build machine):
+//
+//	go run roots_gen.go [-in /etc/ssl/certs/ca-certificates.crt]
+package main
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+)
+
+func main() {
+	in := flag.String("in", "/etc/ssl/certs/ca-certificates.crt", "PEM file of the CA certificates to embed")
+	out := flag.String("out", "roots_embedded.go", "Generated Go file")
+	flag.Parse()
+
+	b, err := ioutil.ReadFile(*in)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Only keep the valid certificates, without comments
+	var bundle bytes.Buffer
+	count := 0
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			continue
+		}
+		pem.Encode(&bundle, &pem.Block{Type: block.Type, Bytes: block.Bytes})
+		count++
+	}
+	if count == 0 {
+		log.Fatalf("No certificates found in %s", *in)
+	}
+
+	var src strings.Builder
+	src.WriteString("// Code generated by roots_gen.go. DO NOT EDIT.\n\n")
+	src.WriteString("//go:build embedroots\n// +build embedroots\n\npackage certs\n\n")
+	fmt.Fprintf(&src, "// embeddedRoots are the %d CA certificates of %s\n", count, *in)
+	fmt.Fprintf(&src, "const embeddedRoots = %s\n", "`"+bundle.String()+"`")
+
+	err = ioutil.WriteFile(*out, []byte(src.String()), 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Wrote %d certificates to %s", count, *out)
+}
@@ -0,0 +1,7 @@
+//go:build !embedroots
+// +build !embedroots
+
+package certs
+
+// embeddedRoots is empty without the `embedroots` build tag
+const embeddedRoots = ""
@@ -0,0 +1,28 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+
+	after := f.After(time.Minute)
+	assert.Equal(t, f.Waiters(), 1)
+
+	f.Advance(30 * time.Second)
+	select {
+	case <-after:
+		t.Fatal("fired before its time")
+	default:
+	}
+
+	f.Sleep(30 * time.Second)
+	assert.Equal(t, <-after, start.Add(time.Minute))
+	assert.Equal(t, f.Now(), start.Add(time.Minute))
+	assert.Equal(t, f.Waiters(), 0)
+}
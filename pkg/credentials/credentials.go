@@ -0,0 +1,132 @@
+// Package credentials stores cached credentials (ex. the Vault token) in the
+// OS keychain (macOS Keychain, Windows Credential Manager or libsecret) or an
+// encrypted file instead of plaintext files.
+package credentials
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// The credential stores
+const (
+	// StorePlaintext keeps the plaintext cache files
+	StorePlaintext = "plaintext"
+	// StoreKeychain is the OS keychain
+	StoreKeychain = "keychain"
+	// StoreFile is an encrypted file
+	StoreFile = "file"
+	// StoreAuto is the OS keychain if available, otherwise an encrypted file
+	StoreAuto = "auto"
+)
+
+// Stores are the credential stores that can be configured
+var Stores = []string{StorePlaintext, StoreKeychain, StoreFile, StoreAuto}
+
+// service is the service (or target prefix) of the keychain items
+const service = "stim"
+
+// Store stores credentials between runs
+type Store interface {
+
+	// Name describes the store (ex. `keychain` or the path of the file)
+	Name() string
+
+	// Get returns the credential, or an empty string if there isn't one
+	Get(key string) (string, error)
+
+	// Set stores the credential, replacing the previous one
+	Set(key string, value string) error
+
+	// Delete removes the credential if it exists
+	Delete(key string) error
+}
+
+// Config describes the credential store to use
+type Config struct {
+
+	// Store is one of Stores, plaintext if empty
+	Store string
+
+	// FilePath is the path of the encrypted file
+	FilePath string
+
+	// KeyPath is the path of the generated key of the encrypted file, used if
+	// Passphrase is empty
+	KeyPath string
+
+	// Passphrase is the passphrase the key of the encrypted file is derived
+	// from
+	Passphrase string
+}
+
+// New returns the credential store of the config, nil for plaintext
+func New(config *Config) (Store, error) {
+	switch config.Store {
+	case StorePlaintext, "":
+		return nil, nil
+	case StoreKeychain:
+		if !KeychainAvailable() {
+			return nil, errors.New("The OS keychain is not available (libsecret's `secret-tool` and a D-Bus session are needed on Linux), use the `file` or `auto` credential store")
+		}
+		return &keychain{}, nil
+	case StoreFile:
+		return newFile(config)
+	case StoreAuto:
+		if KeychainAvailable() {
+			return &keychain{}, nil
+		}
+		return newFile(config)
+	}
+	return nil, errors.New("Unknown credential store '" + config.Store + "', must be one of " + strings.Join(Stores, ", "))
+}
+
+// MigrateFile moves a credential from a plaintext cache file into the store,
+// if the store doesn't have it yet.  The file is removed once the credential
+// is stored.
+func MigrateFile(store Store, key string, path string) error {
+
+	value, err := store.Get(key)
+	if err != nil || value != "" {
+		return err
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = store.Set(key, string(b))
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// keychain stores the credentials in the OS keychain
+type keychain struct{}
+
+// Name implements Store
+func (k *keychain) Name() string {
+	return StoreKeychain
+}
+
+// Get implements Store
+func (k *keychain) Get(key string) (string, error) {
+	return keychainGet(key)
+}
+
+// Set implements Store
+func (k *keychain) Set(key string, value string) error {
+	return keychainSet(key, value)
+}
+
+// Delete implements Store
+func (k *keychain) Delete(key string) error {
+	return keychainDelete(key)
+}
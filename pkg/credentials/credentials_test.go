@@ -0,0 +1,100 @@
+package credentials
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stim-credentials")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	store, err := New(&Config{Store: StoreFile, FilePath: filepath.Join(dir, "credentials.enc"), KeyPath: filepath.Join(dir, "credentials.key")})
+	assert.NilError(t, err)
+
+	value, err := store.Get("vault-token")
+	assert.NilError(t, err)
+	assert.Equal(t, value, "")
+
+	assert.NilError(t, store.Set("vault-token", "s.secret"))
+	assert.NilError(t, store.Set("azure-token", "{}"))
+	value, err = store.Get("vault-token")
+	assert.NilError(t, err)
+	assert.Equal(t, value, "s.secret")
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "credentials.enc"))
+	assert.NilError(t, err)
+	assert.Assert(t, !strings.Contains(string(b), "s.secret"))
+
+	assert.NilError(t, store.Delete("vault-token"))
+	assert.NilError(t, store.Delete("vault-token"))
+	value, err = store.Get("vault-token")
+	assert.NilError(t, err)
+	assert.Equal(t, value, "")
+
+	// A different key can't decrypt the file
+	other, err := New(&Config{Store: StoreFile, FilePath: filepath.Join(dir, "credentials.enc"), Passphrase: "hunter2"})
+	assert.NilError(t, err)
+	_, err = other.Get("azure-token")
+	assert.ErrorContains(t, err, "was not encrypted with a passphrase")
+}
+
+func TestFilePassphrase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stim-credentials")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "credentials.enc")
+
+	store, err := New(&Config{Store: StoreFile, FilePath: path, Passphrase: "correct horse"})
+	assert.NilError(t, err)
+	assert.NilError(t, store.Set("vault-token", "s.secret"))
+
+	value, err := store.Get("vault-token")
+	assert.NilError(t, err)
+	assert.Equal(t, value, "s.secret")
+
+	wrong, err := New(&Config{Store: StoreFile, FilePath: path, Passphrase: "battery staple"})
+	assert.NilError(t, err)
+	_, err = wrong.Get("vault-token")
+	assert.Error(t, err, "Unable to decrypt "+path+", the key or passphrase changed")
+}
+
+func TestMigrateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stim-credentials")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	store, err := New(&Config{Store: StoreFile, FilePath: filepath.Join(dir, "credentials.enc"), KeyPath: filepath.Join(dir, "credentials.key")})
+	assert.NilError(t, err)
+
+	legacy := filepath.Join(dir, "token")
+	assert.NilError(t, ioutil.WriteFile(legacy, []byte("s.old"), 0600))
+	assert.NilError(t, MigrateFile(store, "vault-token", legacy))
+
+	value, err := store.Get("vault-token")
+	assert.NilError(t, err)
+	assert.Equal(t, value, "s.old")
+	_, err = os.Stat(legacy)
+	assert.Assert(t, os.IsNotExist(err))
+
+	// Nothing to migrate
+	assert.NilError(t, MigrateFile(store, "vault-token", legacy))
+}
+
+func TestNew(t *testing.T) {
+	store, err := New(&Config{})
+	assert.NilError(t, err)
+	assert.Assert(t, store == nil)
+
+	_, err = New(&Config{Store: "vault"})
+	assert.Error(t, err, "Unknown credential store 'vault', must be one of plaintext, keychain, file, auto")
+
+	_, err = New(&Config{Store: StoreFile})
+	assert.Error(t, err, "The encrypted credential file needs a path and a key path or passphrase")
+}
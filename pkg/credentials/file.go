@@ -0,0 +1,221 @@
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/PremiereGlobal/stim/pkg/utils"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// fileVersion is the version of the encrypted file format
+const fileVersion = 1
+
+// passphraseIterations are the PBKDF2 iterations of a passphrase key
+const passphraseIterations = 100000
+
+// encryptedFile is the format of the encrypted file.  Data is the JSON map of
+// the credentials, encrypted with AES-256-GCM.  Salt is only set for keys
+// derived from a passphrase.
+type encryptedFile struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt,omitempty"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// file stores the credentials in an encrypted file
+type file struct {
+	config *Config
+	mu     sync.Mutex
+}
+
+// newFile returns the encrypted file store of the config
+func newFile(config *Config) (*file, error) {
+	if config.FilePath == "" || (config.KeyPath == "" && config.Passphrase == "") {
+		return nil, errors.New("The encrypted credential file needs a path and a key path or passphrase")
+	}
+	return &file{config: config}, nil
+}
+
+// Name implements Store
+func (f *file) Name() string {
+	return f.config.FilePath
+}
+
+// Get implements Store
+func (f *file) Get(key string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	credentials, err := f.read()
+	if err != nil {
+		return "", err
+	}
+	return credentials[key], nil
+}
+
+// Set implements Store
+func (f *file) Set(key string, value string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	credentials, err := f.read()
+	if err != nil {
+		return err
+	}
+	credentials[key] = value
+	return f.write(credentials)
+}
+
+// Delete implements Store
+func (f *file) Delete(key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	credentials, err := f.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := credentials[key]; !ok {
+		return nil
+	}
+	delete(credentials, key)
+	return f.write(credentials)
+}
+
+// read decrypts the credentials, empty if the file doesn't exist
+func (f *file) read() (map[string]string, error) {
+
+	credentials := make(map[string]string)
+	b, err := ioutil.ReadFile(f.config.FilePath)
+	if os.IsNotExist(err) {
+		return credentials, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted := &encryptedFile{}
+	err = json.Unmarshal(b, encrypted)
+	if err != nil || encrypted.Version != fileVersion {
+		return nil, fmt.Errorf("%s is not a stim credential file", f.config.FilePath)
+	}
+
+	key, err := f.key(encrypted.Salt, false)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := gcm.Open(nil, encrypted.Nonce, encrypted.Data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to decrypt %s, the key or passphrase changed", f.config.FilePath)
+	}
+
+	err = json.Unmarshal(data, &credentials)
+	return credentials, err
+}
+
+// write encrypts the credentials to the file
+func (f *file) write(credentials map[string]string) error {
+
+	encrypted := &encryptedFile{Version: fileVersion}
+	if f.config.Passphrase != "" {
+		encrypted.Salt = make([]byte, 16)
+		if _, err := io.ReadFull(rand.Reader, encrypted.Salt); err != nil {
+			return err
+		}
+	}
+
+	key, err := f.key(encrypted.Salt, true)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	encrypted.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, encrypted.Nonce); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(credentials)
+	if err != nil {
+		return err
+	}
+	encrypted.Data = gcm.Seal(nil, encrypted.Nonce, data, nil)
+
+	b, err := json.Marshal(encrypted)
+	if err != nil {
+		return err
+	}
+	return writeFile(f.config.FilePath, b)
+}
+
+// key returns the key derived from the passphrase with the salt, or the
+// generated key (created if create is set and it doesn't exist)
+func (f *file) key(salt []byte, create bool) ([]byte, error) {
+
+	if f.config.Passphrase != "" {
+		if len(salt) == 0 {
+			return nil, fmt.Errorf("%s was not encrypted with a passphrase", f.config.FilePath)
+		}
+		return pbkdf2.Key([]byte(f.config.Passphrase), salt, passphraseIterations, 32, sha256.New), nil
+	}
+
+	key, err := ioutil.ReadFile(f.config.KeyPath)
+	if os.IsNotExist(err) && create {
+		key = make([]byte, 32)
+		if _, err := io.ReadFull(rand.Reader, key); err != nil {
+			return nil, err
+		}
+		return key, writeFile(f.config.KeyPath, key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the key of the credential file: %v", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("%s is not a valid credential key", f.config.KeyPath)
+	}
+	return key, nil
+}
+
+// newGCM returns the AES-256-GCM cipher of the key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// writeFile writes a user-only file, replacing it at once so that a failed
+// write doesn't lose the credentials
+func writeFile(path string, b []byte) error {
+
+	err := utils.CreateDirIfNotExist(filepath.Dir(path), utils.UserOnlyMode)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	err = ioutil.WriteFile(tmp, b, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,65 @@
+//go:build darwin
+// +build darwin
+
+package credentials
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// securityItemNotFound is the exit code of `security` for a missing item
+const securityItemNotFound = 44
+
+// KeychainAvailable returns true if the macOS Keychain can be used
+func KeychainAvailable() bool {
+	_, err := exec.LookPath("security")
+	return err == nil
+}
+
+// keychainGet reads a generic password of the login keychain
+func keychainGet(key string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", service, "-a", key, "-w").Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == securityItemNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("Unable to read %s from the keychain: %v", key, err)
+	}
+
+	value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(out)))
+	if err != nil {
+		return "", fmt.Errorf("Invalid keychain item %s: %v", key, err)
+	}
+	return string(value), nil
+}
+
+// keychainSet writes a generic password to the login keychain.  The command
+// is passed on stdin (`security -i`) so the value isn't visible in the
+// process list, and base64 encoded so it needs no quoting.
+func keychainSet(key string, value string) error {
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %q -a %q -w %q\n", service, key, base64.StdEncoding.EncodeToString([]byte(value))))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf("Unable to write %s to the keychain: %v: %s", key, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// keychainDelete deletes a generic password from the login keychain
+func keychainDelete(key string) error {
+	err := exec.Command("security", "delete-generic-password", "-s", service, "-a", key).Run()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == securityItemNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to delete %s from the keychain: %v", key, err)
+	}
+	return nil
+}
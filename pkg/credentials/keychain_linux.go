@@ -0,0 +1,68 @@
+//go:build linux
+// +build linux
+
+package credentials
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// KeychainAvailable returns true if libsecret's `secret-tool` and a D-Bus
+// session (for the Secret Service, ex. GNOME Keyring) are available
+func KeychainAvailable() bool {
+	if os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+		return false
+	}
+	_, err := exec.LookPath("secret-tool")
+	return err == nil
+}
+
+// keychainGet looks up a secret in the Secret Service.  `secret-tool lookup`
+// exits with 1 and no output for a missing secret.
+func keychainGet(key string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("secret-tool", "lookup", "service", service, "key", key)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 && stderr.Len() == 0 {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("Unable to read %s from the Secret Service: %v: %s", key, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// keychainSet stores a secret in the Secret Service.  The value is passed on
+// stdin so it isn't visible in the process list.
+func keychainSet(key string, value string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("secret-tool", "store", "--label", service+" "+key, "service", service, "key", key)
+	cmd.Stdin = strings.NewReader(value)
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf("Unable to write %s to the Secret Service: %v: %s", key, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// keychainDelete removes a secret from the Secret Service
+func keychainDelete(key string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("secret-tool", "clear", "service", service, "key", key)
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 && stderr.Len() == 0 {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to delete %s from the Secret Service: %v: %s", key, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
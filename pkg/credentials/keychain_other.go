@@ -0,0 +1,26 @@
+//go:build !darwin && !linux && !windows
+// +build !darwin,!linux,!windows
+
+package credentials
+
+import "errors"
+
+// errNoKeychain is returned on systems without a supported keychain
+var errNoKeychain = errors.New("No supported OS keychain on this system")
+
+// KeychainAvailable returns false as there is no supported keychain
+func KeychainAvailable() bool {
+	return false
+}
+
+func keychainGet(key string) (string, error) {
+	return "", errNoKeychain
+}
+
+func keychainSet(key string, value string) error {
+	return errNoKeychain
+}
+
+func keychainDelete(key string) error {
+	return errNoKeychain
+}
@@ -0,0 +1,119 @@
+//go:build windows
+// +build windows
+
+package credentials
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	advapi32        = syscall.NewLazyDLL("advapi32.dll")
+	procCredReadW   = advapi32.NewProc("CredReadW")
+	procCredWriteW  = advapi32.NewProc("CredWriteW")
+	procCredDeleteW = advapi32.NewProc("CredDeleteW")
+	procCredFree    = advapi32.NewProc("CredFree")
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+	errorNotFound           = syscall.Errno(1168)
+)
+
+// credential is the CREDENTIALW struct of the Credential Manager API
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// KeychainAvailable returns true as the Windows Credential Manager is always
+// available
+func KeychainAvailable() bool {
+	return true
+}
+
+// target returns the Credential Manager target name of a key
+func target(key string) (*uint16, error) {
+	return syscall.UTF16PtrFromString(service + ":" + key)
+}
+
+// keychainGet reads a generic credential of the Credential Manager
+func keychainGet(key string) (string, error) {
+	name, err := target(key)
+	if err != nil {
+		return "", err
+	}
+
+	var cred *credential
+	ret, _, err := procCredReadW.Call(uintptr(unsafe.Pointer(name)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if ret == 0 {
+		if err == errorNotFound {
+			return "", nil
+		}
+		return "", fmt.Errorf("Unable to read %s from the Credential Manager: %v", key, err)
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+
+	if cred.CredentialBlobSize == 0 {
+		return "", nil
+	}
+	blob := (*[1 << 20]byte)(unsafe.Pointer(cred.CredentialBlob))[:cred.CredentialBlobSize:cred.CredentialBlobSize]
+	return string(blob), nil
+}
+
+// keychainSet writes a generic credential to the Credential Manager
+func keychainSet(key string, value string) error {
+	name, err := target(key)
+	if err != nil {
+		return err
+	}
+	userName, err := syscall.UTF16PtrFromString(service)
+	if err != nil {
+		return err
+	}
+
+	blob := []byte(value)
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         name,
+		CredentialBlobSize: uint32(len(blob)),
+		Persist:            credPersistLocalMachine,
+		UserName:           userName,
+	}
+	if len(blob) > 0 {
+		cred.CredentialBlob = &blob[0]
+	}
+
+	ret, _, err := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0)
+	if ret == 0 {
+		return fmt.Errorf("Unable to write %s to the Credential Manager: %v", key, err)
+	}
+	return nil
+}
+
+// keychainDelete deletes a generic credential from the Credential Manager
+func keychainDelete(key string) error {
+	name, err := target(key)
+	if err != nil {
+		return err
+	}
+
+	ret, _, err := procCredDeleteW.Call(uintptr(unsafe.Pointer(name)), credTypeGeneric, 0)
+	if ret == 0 && err != errorNotFound {
+		return fmt.Errorf("Unable to delete %s from the Credential Manager: %v", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+// Package datadog posts events and schedules monitor downtimes with the
+// Datadog API, so that deploys show up on dashboards and don't page anyone
+package datadog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultSite is the Datadog site of the API when none is set
+const DefaultSite = "datadoghq.com"
+
+// The alert types of an event
+const (
+	AlertInfo    = "info"
+	AlertSuccess = "success"
+	AlertWarning = "warning"
+	AlertError   = "error"
+)
+
+// AlertTypes are the valid event alert types
+var AlertTypes = []string{AlertInfo, AlertSuccess, AlertWarning, AlertError}
+
+// Config configures a Datadog client
+type Config struct {
+
+	// APIKey is required for every request
+	APIKey string
+
+	// AppKey is the application key, only required to manage downtimes
+	AppKey string
+
+	// Site is the Datadog site (ex. `datadoghq.eu`).  Defaults to DefaultSite
+	Site string
+
+	// APIURL overrides the API address of the site (ex. for a proxy)
+	APIURL string
+
+	// Timeout of each request.  Defaults to 30 seconds
+	Timeout time.Duration
+}
+
+// Datadog is a Datadog API client
+type Datadog struct {
+	apiKey string
+	appKey string
+	apiURL string
+	client *http.Client
+}
+
+// Event is an event of the event stream
+type Event struct {
+	ID             int64    `json:"id,omitempty"`
+	Title          string   `json:"title"`
+	Text           string   `json:"text"`
+	Tags           []string `json:"tags,omitempty"`
+	AlertType      string   `json:"alert_type,omitempty"`
+	AggregationKey string   `json:"aggregation_key,omitempty"`
+	SourceTypeName string   `json:"source_type_name,omitempty"`
+}
+
+// Downtime mutes the monitors matching its monitor tags (all monitors if
+// none) for the groups in its scope (ex. `env:prod`, `*` for all) between
+// Start and End (Unix times)
+type Downtime struct {
+	ID          int64    `json:"id,omitempty"`
+	Scope       []string `json:"scope"`
+	MonitorTags []string `json:"monitor_tags,omitempty"`
+	Start       int64    `json:"start,omitempty"`
+	End         int64    `json:"end,omitempty"`
+	Message     string   `json:"message,omitempty"`
+	Active      bool     `json:"active,omitempty"`
+	Disabled    bool     `json:"disabled,omitempty"`
+}
+
+// New returns a Datadog client
+func New(config *Config) *Datadog {
+	d := &Datadog{
+		apiKey: config.APIKey,
+		appKey: config.AppKey,
+		apiURL: strings.TrimSuffix(config.APIURL, "/"),
+		client: &http.Client{Timeout: config.Timeout},
+	}
+	if d.apiURL == "" {
+		site := config.Site
+		if site == "" {
+			site = DefaultSite
+		}
+		d.apiURL = "https://api." + site
+	}
+	if d.client.Timeout == 0 {
+		d.client.Timeout = 30 * time.Second
+	}
+	return d
+}
+
+// PostEvent posts an event to the event stream and returns its ID
+func (d *Datadog) PostEvent(event *Event) (int64, error) {
+
+	if event.Title == "" {
+		return 0, errors.New("Datadog: The event title must be set")
+	}
+	if event.AlertType != "" && !ValidAlertType(event.AlertType) {
+		return 0, fmt.Errorf("Datadog: Invalid alert type '%s', must be one of %s", event.AlertType, strings.Join(AlertTypes, ", "))
+	}
+
+	var response struct {
+		Event Event `json:"event"`
+	}
+	err := d.do(http.MethodPost, "/api/v1/events", false, event, &response)
+	if err != nil {
+		return 0, err
+	}
+
+	return response.Event.ID, nil
+}
+
+// CreateDowntime schedules a downtime and returns it with its ID
+func (d *Datadog) CreateDowntime(downtime *Downtime) (*Downtime, error) {
+
+	if len(downtime.Scope) == 0 {
+		downtime.Scope = []string{"*"}
+	}
+
+	created := &Downtime{}
+	err := d.do(http.MethodPost, "/api/v1/downtime", true, downtime, created)
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// CancelDowntime cancels a downtime, unmuting its monitors
+func (d *Datadog) CancelDowntime(id int64) error {
+	return d.do(http.MethodDelete, fmt.Sprintf("/api/v1/downtime/%d", id), true, nil, nil)
+}
+
+// Downtimes returns the active and scheduled downtimes
+func (d *Datadog) Downtimes() ([]*Downtime, error) {
+
+	var downtimes []*Downtime
+	err := d.do(http.MethodGet, "/api/v1/downtime?current_only=true", true, nil, &downtimes)
+	if err != nil {
+		return nil, err
+	}
+
+	return downtimes, nil
+}
+
+// do sends the request body (if not nil) as JSON and decodes the response
+// into result (if not nil).  Non-2xx responses are returned as errors with
+// Datadog's messages.
+func (d *Datadog) do(method string, path string, needsAppKey bool, body interface{}, result interface{}) error {
+
+	if d.apiKey == "" {
+		return errors.New("Datadog: No API key")
+	}
+	if needsAppKey && d.appKey == "" {
+		return errors.New("Datadog: An application key is required to manage downtimes")
+	}
+
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, d.apiURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("DD-API-KEY", d.apiKey)
+	if d.appKey != "" {
+		req.Header.Set("DD-APPLICATION-KEY", d.appKey)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode/100 != 2 {
+		var apiErr struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(respBody, &apiErr) == nil && len(apiErr.Errors) > 0 {
+			return fmt.Errorf("Datadog: %s: %s", resp.Status, strings.Join(apiErr.Errors, ", "))
+		}
+		return fmt.Errorf("Datadog: %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+
+	if result != nil && len(respBody) > 0 {
+		err = json.Unmarshal(respBody, result)
+		if err != nil {
+			return fmt.Errorf("Datadog: Unable to parse the response: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// ValidAlertType returns true if the alert type is a valid event alert type
+func ValidAlertType(alertType string) bool {
+	for _, t := range AlertTypes {
+		if t == alertType {
+			return true
+		}
+	}
+	return false
+}
+
+// HasTags returns true if the downtime's monitor tags include all the tags
+func (downtime *Downtime) HasTags(tags []string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, t := range downtime.MonitorTags {
+			if t == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
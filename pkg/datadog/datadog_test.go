@@ -0,0 +1,99 @@
+package datadog
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+// request is a request received by the test server
+type request struct {
+	Method string
+	Path   string
+	APIKey string
+	AppKey string
+	Body   map[string]interface{}
+}
+
+func newTestServer(t *testing.T, status int, response string, requests *[]request) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NilError(t, err)
+		req := request{Method: r.Method, Path: r.URL.RequestURI(), APIKey: r.Header.Get("DD-API-KEY"), AppKey: r.Header.Get("DD-APPLICATION-KEY")}
+		if len(body) > 0 {
+			assert.NilError(t, json.Unmarshal(body, &req.Body))
+		}
+		*requests = append(*requests, req)
+
+		w.WriteHeader(status)
+		w.Write([]byte(response))
+	}))
+}
+
+func TestNew(t *testing.T) {
+	assert.Equal(t, New(&Config{}).apiURL, "https://api.datadoghq.com")
+	assert.Equal(t, New(&Config{Site: "datadoghq.eu"}).apiURL, "https://api.datadoghq.eu")
+	assert.Equal(t, New(&Config{Site: "datadoghq.eu", APIURL: "http://proxy/"}).apiURL, "http://proxy")
+}
+
+func TestPostEvent(t *testing.T) {
+	var requests []request
+	server := newTestServer(t, http.StatusAccepted, `{"status":"ok","event":{"id":123,"title":"Deploy"}}`, &requests)
+	defer server.Close()
+
+	d := New(&Config{APIKey: "api", APIURL: server.URL})
+	id, err := d.PostEvent(&Event{Title: "Deploy", Text: "web to prod", Tags: []string{"env:prod"}, AlertType: AlertSuccess})
+	assert.NilError(t, err)
+	assert.Equal(t, id, int64(123))
+
+	assert.Equal(t, len(requests), 1)
+	assert.Equal(t, requests[0].Path, "/api/v1/events")
+	assert.Equal(t, requests[0].APIKey, "api")
+	assert.Equal(t, requests[0].AppKey, "")
+	assert.Equal(t, requests[0].Body["alert_type"], "success")
+	assert.DeepEqual(t, requests[0].Body["tags"], []interface{}{"env:prod"})
+
+	_, err = d.PostEvent(&Event{Title: "Deploy", AlertType: "critical"})
+	assert.Error(t, err, "Datadog: Invalid alert type 'critical', must be one of info, success, warning, error")
+}
+
+func TestDowntimes(t *testing.T) {
+	var requests []request
+	server := newTestServer(t, http.StatusOK, `{"id":7,"scope":["*"],"monitor_tags":["service:web"],"end":1700000000}`, &requests)
+	defer server.Close()
+
+	_, err := New(&Config{APIKey: "api", APIURL: server.URL}).CreateDowntime(&Downtime{})
+	assert.Error(t, err, "Datadog: An application key is required to manage downtimes")
+
+	d := New(&Config{APIKey: "api", AppKey: "app", APIURL: server.URL})
+	downtime, err := d.CreateDowntime(&Downtime{MonitorTags: []string{"service:web"}, End: 1700000000})
+	assert.NilError(t, err)
+	assert.Equal(t, downtime.ID, int64(7))
+	assert.NilError(t, d.CancelDowntime(7))
+
+	assert.Equal(t, len(requests), 2)
+	assert.Equal(t, requests[0].Method+" "+requests[0].Path, "POST /api/v1/downtime")
+	assert.Equal(t, requests[0].AppKey, "app")
+	assert.DeepEqual(t, requests[0].Body["scope"], []interface{}{"*"})
+	assert.Equal(t, requests[1].Method+" "+requests[1].Path, "DELETE /api/v1/downtime/7")
+}
+
+func TestError(t *testing.T) {
+	var requests []request
+	server := newTestServer(t, http.StatusForbidden, `{"errors":["Forbidden"]}`, &requests)
+	defer server.Close()
+
+	_, err := New(&Config{APIKey: "api", APIURL: server.URL}).PostEvent(&Event{Title: "Deploy"})
+	assert.Error(t, err, "Datadog: 403 Forbidden: Forbidden")
+}
+
+func TestHasTags(t *testing.T) {
+	downtime := &Downtime{MonitorTags: []string{"service:web", "team:a"}}
+	assert.Assert(t, downtime.HasTags([]string{"service:web"}))
+	assert.Assert(t, downtime.HasTags(nil))
+	assert.Assert(t, !downtime.HasTags([]string{"service:web", "team:b"}))
+}
@@ -0,0 +1,151 @@
+// Package diff creates unified diffs of text
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines shown around changes
+const contextLines = 3
+
+// operation is a line of the edit script between two texts
+type operation struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// Unified returns the unified diff of two texts, or an empty string if they
+// are the same.  The names are shown in the `---` and `+++` headers.
+func Unified(fromName string, toName string, from string, to string) string {
+
+	if from == to {
+		return ""
+	}
+
+	ops := editScript(splitLines(from), splitLines(to))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", fromName, toName)
+
+	// Group the changes with their context into hunks
+	for start := 0; start < len(ops); {
+		if ops[start].kind == ' ' {
+			start++
+			continue
+		}
+
+		first := start - contextLines
+		if first < 0 {
+			first = 0
+		}
+		last := start
+		for i := start; i < len(ops) && i <= last+2*contextLines; i++ {
+			if ops[i].kind != ' ' {
+				last = i
+			}
+		}
+		end := last + contextLines + 1
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		writeHunk(&b, ops, first, end)
+		start = end
+	}
+
+	return b.String()
+}
+
+// writeHunk writes the operations of a hunk with its line numbers header
+func writeHunk(b *strings.Builder, ops []operation, first int, end int) {
+
+	fromLine, toLine := 1, 1
+	for _, op := range ops[:first] {
+		if op.kind != '+' {
+			fromLine++
+		}
+		if op.kind != '-' {
+			toLine++
+		}
+	}
+
+	fromCount, toCount := 0, 0
+	for _, op := range ops[first:end] {
+		if op.kind != '+' {
+			fromCount++
+		}
+		if op.kind != '-' {
+			toCount++
+		}
+	}
+	if fromCount == 0 {
+		fromLine--
+	}
+	if toCount == 0 {
+		toLine--
+	}
+
+	fmt.Fprintf(b, "@@ -%d,%d +%d,%d @@\n", fromLine, fromCount, toLine, toCount)
+	for _, op := range ops[first:end] {
+		b.WriteByte(op.kind)
+		b.WriteString(op.line)
+		b.WriteByte('\n')
+	}
+}
+
+// editScript returns the operations that turn the from lines into the to
+// lines, using their longest common subsequence
+func editScript(from []string, to []string) []operation {
+
+	// lcs[i][j] is the length of the longest common subsequence of from[i:]
+	// and to[j:]
+	lcs := make([][]int, len(from)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(to)+1)
+	}
+	for i := len(from) - 1; i >= 0; i-- {
+		for j := len(to) - 1; j >= 0; j-- {
+			if from[i] == to[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []operation
+	i, j := 0, 0
+	for i < len(from) && j < len(to) {
+		switch {
+		case from[i] == to[j]:
+			ops = append(ops, operation{' ', from[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, operation{'-', from[i]})
+			i++
+		default:
+			ops = append(ops, operation{'+', to[j]})
+			j++
+		}
+	}
+	for ; i < len(from); i++ {
+		ops = append(ops, operation{'-', from[i]})
+	}
+	for ; j < len(to); j++ {
+		ops = append(ops, operation{'+', to[j]})
+	}
+
+	return ops
+}
+
+// splitLines splits a text into lines without their line endings
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
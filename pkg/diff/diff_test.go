@@ -0,0 +1,40 @@
+package diff
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestUnified(t *testing.T) {
+	assert.Equal(t, Unified("a", "b", "same\n", "same\n"), "")
+
+	from := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n"
+	to := "a\nb\nc\nd\nE\nf\ng\nh\ni\nj\nk\nl\nm\n"
+	assert.Equal(t, Unified("live", "merged", from, to), `--- live
++++ merged
+@@ -2,7 +2,7 @@
+ b
+ c
+ d
+-e
++E
+ f
+ g
+ h
+@@ -10,3 +10,4 @@
+ j
+ k
+ l
++m
+`)
+}
+
+func TestUnifiedNewText(t *testing.T) {
+	assert.Equal(t, Unified("live", "merged", "", "a\nb\n"), `--- live
++++ merged
+@@ -0,0 +1,2 @@
++a
++b
+`)
+}
@@ -6,12 +6,16 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 
 	docker "github.com/docker/docker/client"
 )
 
-// NewClient returns a new Docker client
+// NewClient returns a new Docker client.  Unless DOCKER_HOST is set, the
+// client connects to the unix socket, or the Docker Desktop named pipe on
+// Windows.
 func NewClient() (*docker.Client, error) {
 	dockerClient, err := docker.NewClientWithOpts(docker.FromEnv, docker.WithAPIVersionNegotiation())
 	if err != nil {
@@ -21,6 +25,46 @@ func NewClient() (*docker.Client, error) {
 	return dockerClient, nil
 }
 
+// NewClientWithHost returns a new Docker client for the daemon (or
+// Docker-compatible API, ex. Podman) at the host, such as
+// `unix:///run/podman/podman.sock`.  An empty host is the same as NewClient.
+func NewClientWithHost(host string) (*docker.Client, error) {
+	if host == "" {
+		return NewClient()
+	}
+
+	dockerClient, err := docker.NewClientWithOpts(docker.WithHost(host), docker.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, fmt.Errorf("Error creating docker client for %s. %v", host, err)
+	}
+
+	return dockerClient, nil
+}
+
+// PodmanHost returns the address of the Docker-compatible API socket of
+// Podman: CONTAINER_HOST if set, the rootless socket of the user if it
+// exists, or the system socket otherwise
+func PodmanHost() string {
+	if host := os.Getenv("CONTAINER_HOST"); host != "" {
+		return host
+	}
+	return podmanHost(os.Getenv("XDG_RUNTIME_DIR"), func(path string) bool {
+		_, err := os.Stat(path)
+		return err == nil
+	})
+}
+
+// podmanHost returns the Podman socket given the runtime dir of the user
+func podmanHost(runtimeDir string, exists func(string) bool) string {
+	if runtimeDir != "" {
+		socket := filepath.Join(runtimeDir, "podman", "podman.sock")
+		if exists(socket) {
+			return "unix://" + filepath.ToSlash(socket)
+		}
+	}
+	return "unix:///run/podman/podman.sock"
+}
+
 // IsDockerAvailable performs an 'info' call to the docker server to see if it is available
 // Returns true if it is, otherwise returns false and the error message
 func IsDockerAvailable() (bool, error) {
@@ -66,3 +110,23 @@ func IsInDocker() bool {
 
 	return false
 }
+
+// MountSource returns the host path of a bind mount in the form the Docker
+// daemon expects.  Docker Desktop on Windows takes paths like `/c/Users/me`
+// rather than `C:\Users\me`.
+func MountSource(path string) string {
+	return mountSource(path, runtime.GOOS)
+}
+
+// mountSource returns the bind mount source of a path on the given OS
+func mountSource(path string, goos string) string {
+	if goos != "windows" {
+		return path
+	}
+
+	path = strings.Replace(path, "\\", "/", -1)
+	if len(path) >= 2 && path[1] == ':' {
+		path = "/" + strings.ToLower(path[:1]) + path[2:]
+	}
+	return path
+}
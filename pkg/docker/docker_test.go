@@ -0,0 +1,46 @@
+package docker
+
+import (
+	"testing"
+)
+
+func TestMountSource(t *testing.T) {
+	tests := []struct {
+		path string
+		goos string
+		want string
+	}{
+		{"/home/me/app", "linux", "/home/me/app"},
+		{"/Users/me/app", "darwin", "/Users/me/app"},
+		{`C:\Users\me\app`, "windows", "/c/Users/me/app"},
+		{`d:\deploy`, "windows", "/d/deploy"},
+		{`\\server\share\app`, "windows", "//server/share/app"},
+	}
+
+	for _, test := range tests {
+		got := mountSource(test.path, test.goos)
+		if got != test.want {
+			t.Errorf("mountSource(%q, %q) = %q, want %q", test.path, test.goos, got, test.want)
+		}
+	}
+}
+
+func TestPodmanHost(t *testing.T) {
+	exists := func(path string) bool { return path == "/run/user/1000/podman/podman.sock" }
+
+	tests := []struct {
+		runtimeDir string
+		want       string
+	}{
+		{"/run/user/1000", "unix:///run/user/1000/podman/podman.sock"},
+		{"/run/user/1001", "unix:///run/podman/podman.sock"},
+		{"", "unix:///run/podman/podman.sock"},
+	}
+
+	for _, test := range tests {
+		got := podmanHost(test.runtimeDir, exists)
+		if got != test.want {
+			t.Errorf("podmanHost(%q) = %q, want %q", test.runtimeDir, got, test.want)
+		}
+	}
+}
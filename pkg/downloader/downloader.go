@@ -3,7 +3,12 @@ package downloader
 import (
 	"archive/tar"
 	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -15,33 +20,61 @@ import (
 	"github.com/krolaw/zipstream"
 )
 
+// ErrNotCached is returned by offline downloads of binaries that are not in
+// the cache
+var ErrNotCached = errors.New("not in the tool cache")
+
+// checksumSuffix is the suffix of the file stored next to a cached binary
+// with the verified checksum of its download and the SHA256 of the binary
+const checksumSuffix = ".sha256"
+
 // Downloader represents the Downloader type (duh)
 type Downloader interface {
 	Download() (DownloadResult, error)
 	SetVersion(version string)
+	SetOptions(options Options)
 	GetVersion() string
+	GetPlatform() string
 	GetDownloadURL() string
 	GetBinPath() string
 	GetBinName() string
 	GetBinBaseName() string
 }
 
+// Options change how a binary is downloaded
+type Options struct {
+
+	// Checksum is the SHA256 (hex) of the downloaded file (the archive for
+	// archived binaries).  Downloads that don't match are discarded.  The
+	// checksum is not verified if empty.
+	Checksum string
+
+	// Offline fails with ErrNotCached instead of downloading the binary
+	Offline bool
+
+	// Progress is called as the file downloads with the number of bytes read
+	// and the total (-1 if unknown)
+	Progress func(read int64, total int64)
+}
+
 type baseDownloader struct {
 	version, name, path string
 	url                 utils.StringReplacer
+	options             Options
 }
 
 type DownloadResult struct {
 	RenderedURL      string
 	FileExists       bool
 	DownloadDuration time.Duration
+	Checksum         string
 }
 
 // NewBaseDownloader returns a New baseDownloader
 // url = URL template for the download
 // version = version to download
 // name = name of the binary file
-// path = where the binary file should end up
+// path = root of the tool cache, binaries are stored in <path>/<name>/<version>/<os>-<arch>/<name>
 func NewBaseDownloader(url, version, name, path string) Downloader {
 	d := &baseDownloader{
 		url:  utils.StringReplacer(url),
@@ -59,11 +92,21 @@ func (bd *baseDownloader) SetVersion(version string) {
 	bd.version = GetBaseVersion(version)
 }
 
+// SetOptions sets the options of the download
+func (bd *baseDownloader) SetOptions(options Options) {
+	bd.options = options
+}
+
 // SetVersion gets the version to be downloaded
 func (bd *baseDownloader) GetVersion() string {
 	return bd.version
 }
 
+// GetPlatform returns the platform of the binary (ex. linux-amd64)
+func (bd *baseDownloader) GetPlatform() string {
+	return runtime.GOOS + "-" + runtime.GOARCH
+}
+
 // GetDownloadURL returns the constructed download url
 func (bd *baseDownloader) GetDownloadURL() string {
 	return bd.url.ReplaceAll("{VERSION}", bd.version).
@@ -83,84 +126,255 @@ func (bd *baseDownloader) GetBinName() string {
 	return bd.name + "-v" + bd.version
 }
 
-// GetBinPath returns the full path to the binary
+// GetBinPath returns the full path to the binary in the tool cache
 func (bd *baseDownloader) GetBinPath() string {
-	return filepath.Join(bd.path, bd.GetBinName())
+	return filepath.Join(bd.path, bd.name, bd.version, bd.GetPlatform(), bd.name)
 }
 
-// Download downloads the file and move it to the appropriate path
+// Download downloads the file, verifies its checksum and moves the binary to
+// the tool cache.  Binaries already in the cache are not downloaded again,
+// unless a checksum is set and the cached binary can't be verified against it
+// (see verifyCached), in which case it is downloaded again.
 func (bd *baseDownloader) Download() (DownloadResult, error) {
 	binPath := bd.GetBinPath()
 	urlDL := bd.GetDownloadURL()
 	result := DownloadResult{}
 	result.RenderedURL = urlDL
 
-	if data, err := os.Stat(binPath); !os.IsNotExist(err) && data.Size() > 40000 {
+	if data, err := os.Stat(binPath); err == nil {
+		result.Checksum, err = bd.verifyCached(binPath)
+		if err == nil {
 
-		// Ensure the file is executable (by the user)
-		if data.Mode().Perm()&0001 == 0 {
-			os.Chmod(binPath, 0755)
+			// Ensure the file is executable (by the user)
+			if data.Mode().Perm()&0100 == 0 {
+				os.Chmod(binPath, 0755)
+			}
+			result.FileExists = true
+			return result, nil
 		}
-		result.FileExists = true
-		return result, nil
+		if bd.options.Offline {
+			return result, fmt.Errorf("Cached %s %s (%s) can't be verified: %v", bd.name, bd.version, bd.GetPlatform(), err)
+		}
+	}
+
+	if bd.options.Offline {
+		return result, fmt.Errorf("%s %s (%s) is %w (%s)", bd.name, bd.version, bd.GetPlatform(), ErrNotCached, binPath)
+	}
+
+	dir := filepath.Dir(binPath)
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return result, err
 	}
 
+	// Binaries are written to temporary files in the cache directory and
+	// renamed once complete, so that concurrent downloads of the same binary
+	// never see a partial file
+	archive, err := ioutil.TempFile(dir, ".download-")
+	if err != nil {
+		return result, err
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
 	start := time.Now()
-	resp, err := http.Get(urlDL)
+	result.Checksum, err = bd.fetch(urlDL, archive)
+	if err != nil {
+		return result, err
+	}
+	result.DownloadDuration = time.Since(start)
+
+	if bd.options.Checksum != "" && !strings.EqualFold(bd.options.Checksum, result.Checksum) {
+		return result, fmt.Errorf("Checksum mismatch for %s: expected %s, got %s", urlDL, bd.options.Checksum, result.Checksum)
+	}
+
+	_, err = archive.Seek(0, io.SeekStart)
+	if err != nil {
+		return result, err
+	}
+
+	bin, err := ioutil.TempFile(dir, ".bin-")
+	if err != nil {
+		return result, err
+	}
+	defer os.Remove(bin.Name())
+	defer bin.Close()
+
+	binHash := sha256.New()
+	err = bd.extract(urlDL, archive, io.MultiWriter(bin, binHash))
 	if err != nil {
 		return result, err
 	}
+	err = bin.Close()
+	if err != nil {
+		return result, err
+	}
+	err = os.Chmod(bin.Name(), 0755)
+	if err != nil {
+		return result, err
+	}
+
+	// The checksums are stored before the binary is renamed, so a cached
+	// binary always has the checksums of its own download
+	err = writeChecksums(binPath, result.Checksum, hex.EncodeToString(binHash.Sum(nil)))
+	if err != nil {
+		return result, err
+	}
+
+	return result, os.Rename(bin.Name(), binPath)
+}
+
+// verifyCached returns the checksum of the download of a cached binary.  If a
+// checksum is set, it must match the stored checksum of the download and the
+// binary must still match its stored SHA256, so that a binary cached without
+// a checksum, with another checksum or modified in the cache is not used.
+func (bd *baseDownloader) verifyCached(binPath string) (string, error) {
+
+	download, binary, err := readChecksums(binPath)
+	if bd.options.Checksum == "" {
+		return download, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("No verified checksum: %v", err)
+	}
+	if !strings.EqualFold(bd.options.Checksum, download) {
+		return "", fmt.Errorf("Checksum mismatch: expected %s, cached %s", bd.options.Checksum, download)
+	}
+
+	f, err := os.Open(binPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	_, err = io.Copy(hash, f)
+	if err != nil {
+		return "", err
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != binary {
+		return "", fmt.Errorf("%s was modified since it was downloaded", binPath)
+	}
+
+	return download, nil
+}
+
+// writeChecksums stores the checksum of the download and the SHA256 of the
+// binary next to the binary
+func writeChecksums(binPath string, download string, binary string) error {
+	f, err := ioutil.TempFile(filepath.Dir(binPath), ".checksum-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "%s %s\n", download, binary)
+	if err != nil {
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), binPath+checksumSuffix)
+}
+
+// readChecksums returns the checksums stored by writeChecksums
+func readChecksums(binPath string) (string, string, error) {
+	b, err := ioutil.ReadFile(binPath + checksumSuffix)
+	if err != nil {
+		return "", "", err
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) != 2 {
+		return "", "", fmt.Errorf("Invalid checksum file %s", binPath+checksumSuffix)
+	}
+	return fields[0], fields[1], nil
+}
+
+// fetch downloads the URL to the file and returns its SHA256
+func (bd *baseDownloader) fetch(url string, out io.Writer) (string, error) {
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", err
+	}
 	defer resp.Body.Close()
 
-	result.DownloadDuration = time.Since(start)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Unable to download %s: %s", url, resp.Status)
+	}
 
-	if strings.HasSuffix(urlDL, ".tar.gz") {
-		archive, err := gzip.NewReader(resp.Body)
+	var body io.Reader = resp.Body
+	if bd.options.Progress != nil {
+		body = &progressReader{reader: body, total: resp.ContentLength, progress: bd.options.Progress}
+	}
+
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(out, hash), body)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// extract copies the binary out of the downloaded file.  Files that are not
+// archives are the binary itself.
+func (bd *baseDownloader) extract(url string, in io.Reader, out io.Writer) error {
+
+	switch {
+	case strings.HasSuffix(url, ".tar.gz"):
+		archive, err := gzip.NewReader(in)
 		if err != nil {
-			return result, err
+			return err
 		}
 		tr := tar.NewReader(archive)
 		for {
 			hdr, err := tr.Next()
+			if err == io.EOF {
+				return fmt.Errorf("%s not found in %s", bd.name, url)
+			}
 			if err != nil {
-				return result, err
+				return err
 			}
-
 			if hdr.FileInfo().Mode().IsRegular() && strings.HasSuffix(hdr.Name, bd.GetBinBaseName()) {
-				out, err := os.Create(binPath)
-				if err != nil {
-					return result, err
-				}
-				defer out.Close()
 				_, err = io.Copy(out, tr)
-				if err != nil {
-					return result, err
-				}
-				os.Chmod(binPath, 0755)
-				break
+				return err
 			}
 		}
-	} else if strings.HasSuffix(urlDL, ".zip") {
-		archive := zipstream.NewReader(resp.Body)
+	case strings.HasSuffix(url, ".zip"):
+		archive := zipstream.NewReader(in)
 		for {
 			hdr, err := archive.Next()
+			if err == io.EOF {
+				return fmt.Errorf("%s not found in %s", bd.name, url)
+			}
 			if err != nil {
-				return result, err
+				return err
 			}
 			if hdr.FileInfo().Mode().IsRegular() && strings.HasSuffix(hdr.Name, bd.GetBinBaseName()) {
-				out, err := os.Create(binPath)
-				if err != nil {
-					return result, err
-				}
-				defer out.Close()
 				_, err = io.Copy(out, archive)
-				if err != nil {
-					return result, err
-				}
-				os.Chmod(binPath, 0755)
-				break
+				return err
 			}
 		}
 	}
-	return result, nil
+
+	_, err := io.Copy(out, in)
+	return err
+}
+
+// progressReader reports the progress of a download
+type progressReader struct {
+	reader   io.Reader
+	read     int64
+	total    int64
+	progress func(read int64, total int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.reader.Read(b)
+	p.read += int64(n)
+	p.progress(p.read, p.total)
+	return n, err
 }
@@ -1,5 +1,9 @@
 package downloader
 
+import (
+	"runtime"
+)
+
 // NewHelmDownloader provides a downloader for the 'helm' command line utility
 func NewHelmDownloader(version string, downloadPath string) Downloader {
 	return NewBaseDownloader("https://get.helm.sh/helm-v{VERSION}-{OS}-{ARCH}.tar.gz", GetBaseVersion(version), "helm", downloadPath)
@@ -14,3 +18,19 @@ func NewKubeDownloader(version string, downloadPath string) Downloader {
 func NewVaultDownloader(version string, downloadPath string) Downloader {
 	return NewBaseDownloader("https://releases.hashicorp.com/vault/{VERSION}/vault_{VERSION}_{OS}_{ARCH}.zip", GetBaseVersion(version), "vault", downloadPath)
 }
+
+// NewTerraformDownloader provides a downloader for the 'terraform' command line utility
+func NewTerraformDownloader(version string, downloadPath string) Downloader {
+	return NewBaseDownloader("https://releases.hashicorp.com/terraform/{VERSION}/terraform_{VERSION}_{OS}_{ARCH}.zip", GetBaseVersion(version), "terraform", downloadPath)
+}
+
+// NewOpaDownloader provides a downloader for the 'opa' command line utility.
+// OPA releases plain binaries, which are only static for Linux and arm64
+// macOS.
+func NewOpaDownloader(version string, downloadPath string) Downloader {
+	url := "https://openpolicyagent.org/downloads/v{VERSION}/opa_{OS}_{ARCH}"
+	if runtime.GOOS == "linux" || runtime.GOARCH == "arm64" {
+		url += "_static"
+	}
+	return NewBaseDownloader(url, GetBaseVersion(version), "opa", downloadPath)
+}
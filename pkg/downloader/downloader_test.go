@@ -0,0 +1,177 @@
+package downloader
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+// testArchive returns a tar.gz archive containing the file
+func testArchive(t *testing.T, name string, content string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	assert.NilError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+	_, err := tw.Write([]byte(content))
+	assert.NilError(t, err)
+	assert.NilError(t, tw.Close())
+	assert.NilError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestDownload(t *testing.T) {
+	archive := testArchive(t, "linux-amd64/tool", "#!/bin/sh\necho tool\n")
+	sum := sha256.Sum256(archive)
+	checksum := hex.EncodeToString(sum[:])
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write(archive)
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "stim-downloader")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	dl := NewBaseDownloader(server.URL+"/{NAME}-v{VERSION}.tar.gz", "v1.2.3", "tool", dir)
+	assert.Equal(t, dl.GetBinPath(), filepath.Join(dir, "tool", "1.2.3", dl.GetPlatform(), "tool"))
+
+	// Offline downloads fail until the tool is cached
+	dl.SetOptions(Options{Offline: true})
+	_, err = dl.Download()
+	assert.Assert(t, errors.Is(err, ErrNotCached))
+
+	// Mismatched checksums are discarded
+	dl.SetOptions(Options{Checksum: "0000"})
+	_, err = dl.Download()
+	assert.ErrorContains(t, err, "Checksum mismatch")
+	_, err = os.Stat(dl.GetBinPath())
+	assert.Assert(t, os.IsNotExist(err))
+
+	var progress []int64
+	dl.SetOptions(Options{Checksum: checksum, Progress: func(read int64, total int64) {
+		progress = append(progress, read)
+		assert.Equal(t, total, int64(len(archive)))
+	}})
+	result, err := dl.Download()
+	assert.NilError(t, err)
+	assert.Equal(t, result.Checksum, checksum)
+	assert.Assert(t, !result.FileExists)
+	assert.Equal(t, progress[len(progress)-1], int64(len(archive)))
+
+	b, err := ioutil.ReadFile(dl.GetBinPath())
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), "#!/bin/sh\necho tool\n")
+
+	// Cached tools are not downloaded again, even offline
+	dl.SetOptions(Options{Offline: true})
+	result, err = dl.Download()
+	assert.NilError(t, err)
+	assert.Assert(t, result.FileExists)
+	assert.Equal(t, requests, 2)
+
+	// Cached tools are verified against the checksum
+	dl.SetOptions(Options{Checksum: checksum, Offline: true})
+	result, err = dl.Download()
+	assert.NilError(t, err)
+	assert.Assert(t, result.FileExists)
+	assert.Equal(t, result.Checksum, checksum)
+
+	dl.SetOptions(Options{Checksum: "0000", Offline: true})
+	_, err = dl.Download()
+	assert.ErrorContains(t, err, "Checksum mismatch: expected 0000")
+
+	// A cached binary that was modified is downloaded again
+	assert.NilError(t, ioutil.WriteFile(dl.GetBinPath(), []byte("#!/bin/sh\necho modified\n"), 0755))
+	dl.SetOptions(Options{Checksum: checksum, Offline: true})
+	_, err = dl.Download()
+	assert.ErrorContains(t, err, "was modified since it was downloaded")
+
+	dl.SetOptions(Options{Checksum: checksum})
+	result, err = dl.Download()
+	assert.NilError(t, err)
+	assert.Assert(t, !result.FileExists)
+	assert.Equal(t, requests, 3)
+	b, err = ioutil.ReadFile(dl.GetBinPath())
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), "#!/bin/sh\necho tool\n")
+
+	// No temporary files are left in the cache
+	files, err := ioutil.ReadDir(filepath.Dir(dl.GetBinPath()))
+	assert.NilError(t, err)
+	assert.Equal(t, len(files), 2)
+}
+
+// Binaries cached without checksums are downloaded again once a checksum is
+// set
+func TestDownloadCachedWithoutChecksum(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stim-downloader")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	dl := NewBaseDownloader("http://127.0.0.1:0/tool", "1.0.0", "tool", dir)
+	assert.NilError(t, os.MkdirAll(filepath.Dir(dl.GetBinPath()), 0755))
+	assert.NilError(t, ioutil.WriteFile(dl.GetBinPath(), []byte("tool"), 0755))
+
+	result, err := dl.Download()
+	assert.NilError(t, err)
+	assert.Assert(t, result.FileExists)
+
+	dl.SetOptions(Options{Checksum: "abc123", Offline: true})
+	_, err = dl.Download()
+	assert.ErrorContains(t, err, "No verified checksum")
+}
+
+func TestDownloadNotFound(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "stim-downloader")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = NewBaseDownloader(server.URL+"/tool.tar.gz", "1.0.0", "tool", dir).Download()
+	assert.ErrorContains(t, err, "404 Not Found")
+}
+
+func TestOpaDownloader(t *testing.T) {
+	dl := NewOpaDownloader("v0.61.0", "/cache")
+	assert.Equal(t, dl.GetVersion(), "0.61.0")
+	url := dl.GetDownloadURL()
+	assert.Assert(t, strings.HasPrefix(url, "https://openpolicyagent.org/downloads/v0.61.0/opa_"+runtime.GOOS+"_"+runtime.GOARCH), url)
+	assert.Equal(t, strings.HasSuffix(url, "_static"), runtime.GOOS == "linux" || runtime.GOARCH == "arm64")
+}
+
+func TestManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stim-manifest")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "manifest.yaml")
+	assert.NilError(t, ioutil.WriteFile(path, []byte("kubectl:\n  1.18.6:\n    linux-amd64: abc123\n  \"1.19\":\n    linux-amd64: def456\n"), 0644))
+
+	manifest, err := LoadManifest(path)
+	assert.NilError(t, err)
+	assert.Equal(t, manifest.Checksum("kubectl", "v1.18.6", "linux-amd64"), "abc123")
+	assert.Equal(t, manifest.Checksum("kubectl", "1.19", "linux-amd64"), "def456")
+	assert.Equal(t, manifest.Checksum("kubectl", "1.18.6", "darwin-amd64"), "")
+	assert.Equal(t, manifest.Checksum("helm", "3.2.4", "linux-amd64"), "")
+
+	var empty Manifest
+	assert.Equal(t, empty.Checksum("kubectl", "1.18.6", "linux-amd64"), "")
+}
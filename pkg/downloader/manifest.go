@@ -0,0 +1,40 @@
+package downloader
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Manifest pins the SHA256 checksums of tool downloads by tool, version and
+// platform:
+//
+//	kubectl:
+//	  1.18.6:
+//	    linux-amd64: 1d6c1a0b...
+//	    darwin-amd64: 5b6b8b9a...
+type Manifest map[string]map[string]map[string]string
+
+// LoadManifest reads a checksum manifest file
+func LoadManifest(path string) (Manifest, error) {
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := Manifest{}
+	err = yaml.Unmarshal(b, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid tool checksum manifest %s: %v", path, err)
+	}
+
+	return manifest, nil
+}
+
+// Checksum returns the pinned checksum of the tool version for the platform,
+// or an empty string if it is not pinned
+func (m Manifest) Checksum(name string, version string, platform string) string {
+	return m[name][GetBaseVersion(version)][platform]
+}
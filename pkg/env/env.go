@@ -22,6 +22,7 @@ type Config struct {
 	Path    *Path
 	EnvVars []string
 	WorkDir string
+	Shell   []string
 }
 
 // Path represents the environment's PATH configuration
@@ -96,7 +97,7 @@ func (e *Env) Link(source string, linkFileName string) error {
 
 // GetEnvVars provides all the environment variables (including the generated PATH)
 func (e *Env) GetEnvVars() []string {
-	path := fmt.Sprintf("PATH=%s:%s", e.GetPath(), os.Getenv("PATH"))
+	path := fmt.Sprintf("PATH=%s%c%s", e.GetPath(), os.PathListSeparator, os.Getenv("PATH"))
 	envs := append([]string{path}, e.config.EnvVars...)
 	return envs
 }
@@ -104,10 +105,11 @@ func (e *Env) GetEnvVars() []string {
 // Run runs a shell command in the environment
 func (e *Env) Run(cmdString string) (string, error) {
 
-	fullCmd := fmt.Sprintf("cd %s && %s", e.config.WorkDir, cmdString)
 	s, err := shell.Run(shell.ShellCommand{
-		Command: []string{fullCmd},
+		Shell:   e.config.Shell,
+		Command: []string{cmdString},
 		Envs:    e.GetEnvVars(),
+		WorkDir: e.config.WorkDir,
 	})
 
 	return s, err
@@ -118,9 +120,15 @@ func (e *Env) SetWorkDir(workDir string) {
 	e.config.WorkDir = workDir
 }
 
+// SetShell sets the shell that commands are run with (ex. `shell.PowerShell()`).
+// Defaults to `shell.DefaultShell()`.
+func (e *Env) SetShell(shell []string) {
+	e.config.Shell = shell
+}
+
 // Close cleans up resources created by the env
 func (e *Env) Close() {
 	if e.config.Path.RemoveOnClose {
-		os.Remove(e.config.Path.Directory)
+		os.RemoveAll(e.config.Path.Directory)
 	}
 }
@@ -0,0 +1,241 @@
+// Package github creates GitHub Deployments, Deployment Statuses and Releases
+// so that GitHub's environment and release views reflect what was deployed
+package github
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultAPIURL is the GitHub API address
+const DefaultAPIURL = "https://api.github.com"
+
+// The states of a deployment status
+const (
+	StateQueued     = "queued"
+	StatePending    = "pending"
+	StateInProgress = "in_progress"
+	StateSuccess    = "success"
+	StateFailure    = "failure"
+	StateError      = "error"
+	StateInactive   = "inactive"
+)
+
+// States are the valid deployment status states
+var States = []string{StateQueued, StatePending, StateInProgress, StateSuccess, StateFailure, StateError, StateInactive}
+
+// Config configures a Github client
+type Config struct {
+
+	// Token is the API token.  It needs the `repo_deployment` scope for
+	// deployments and `repo` (or `contents: write`) for releases.
+	Token string
+
+	// APIURL is the GitHub API address (ex. `https://github.example.com/api/v3`
+	// for GitHub Enterprise).  Defaults to DefaultAPIURL
+	APIURL string
+
+	// Timeout of each request.  Defaults to 30 seconds
+	Timeout time.Duration
+}
+
+// Github is a GitHub API client
+type Github struct {
+	token  string
+	apiURL string
+	client *http.Client
+}
+
+// DeploymentRequest describes a deployment to create
+type DeploymentRequest struct {
+
+	// Ref is the commit SHA, branch or tag that was deployed
+	Ref string `json:"ref"`
+
+	// Environment is the name of the GitHub environment (ex. `production`)
+	Environment string `json:"environment"`
+
+	Description string            `json:"description,omitempty"`
+	Payload     map[string]string `json:"payload,omitempty"`
+
+	// Production marks the environment as a production environment
+	Production bool `json:"production_environment"`
+
+	// Deployments are recorded after the fact, so GitHub must neither merge
+	// the default branch into the ref nor require commit statuses
+	AutoMerge        bool     `json:"auto_merge"`
+	RequiredContexts []string `json:"required_contexts"`
+}
+
+// Deployment is a created deployment
+type Deployment struct {
+	ID          int64  `json:"id"`
+	Ref         string `json:"ref"`
+	Sha         string `json:"sha"`
+	Environment string `json:"environment"`
+	URL         string `json:"url"`
+}
+
+// StatusRequest describes a deployment status to create
+type StatusRequest struct {
+	State          string `json:"state"`
+	Description    string `json:"description,omitempty"`
+	Environment    string `json:"environment,omitempty"`
+	EnvironmentURL string `json:"environment_url,omitempty"`
+	LogURL         string `json:"log_url,omitempty"`
+}
+
+// ReleaseRequest describes a release to create
+type ReleaseRequest struct {
+	TagName string `json:"tag_name"`
+
+	// Target is the commit SHA or branch the tag is created from if it
+	// doesn't exist yet.  Defaults to the default branch.
+	Target     string `json:"target_commitish,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Body       string `json:"body,omitempty"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
+}
+
+// Release is a created release
+type Release struct {
+	ID      int64  `json:"id"`
+	TagName string `json:"tag_name"`
+	HTMLURL string `json:"html_url"`
+}
+
+// New returns a Github client
+func New(config *Config) *Github {
+	g := &Github{
+		token:  config.Token,
+		apiURL: strings.TrimSuffix(config.APIURL, "/"),
+		client: &http.Client{Timeout: config.Timeout},
+	}
+	if g.apiURL == "" {
+		g.apiURL = DefaultAPIURL
+	}
+	if g.client.Timeout == 0 {
+		g.client.Timeout = 30 * time.Second
+	}
+	return g
+}
+
+// CreateDeployment creates a deployment in the repo (`owner/name`)
+func (g *Github) CreateDeployment(repo string, request *DeploymentRequest) (*Deployment, error) {
+
+	if request.Ref == "" || request.Environment == "" {
+		return nil, errors.New("Github: The deployment ref and environment must be set")
+	}
+	if request.RequiredContexts == nil {
+		request.RequiredContexts = []string{}
+	}
+
+	deployment := &Deployment{}
+	err := g.post(fmt.Sprintf("/repos/%s/deployments", repo), request, deployment)
+	if err != nil {
+		return nil, err
+	}
+
+	// GitHub answers 202 without a deployment when it merged the default
+	// branch into the ref instead
+	if deployment.ID == 0 {
+		return nil, errors.New("Github: The deployment was not created")
+	}
+
+	return deployment, nil
+}
+
+// CreateDeploymentStatus sets the state of a deployment of the repo
+func (g *Github) CreateDeploymentStatus(repo string, deploymentID int64, request *StatusRequest) error {
+
+	if !ValidState(request.State) {
+		return fmt.Errorf("Github: Invalid deployment state '%s', must be one of %s", request.State, strings.Join(States, ", "))
+	}
+
+	return g.post(fmt.Sprintf("/repos/%s/deployments/%d/statuses", repo, deploymentID), request, nil)
+}
+
+// CreateRelease creates a release in the repo.  The tag is created from the
+// target if it doesn't exist.
+func (g *Github) CreateRelease(repo string, request *ReleaseRequest) (*Release, error) {
+
+	if request.TagName == "" {
+		return nil, errors.New("Github: The release tag must be set")
+	}
+
+	release := &Release{}
+	err := g.post(fmt.Sprintf("/repos/%s/releases", repo), request, release)
+	if err != nil {
+		return nil, err
+	}
+
+	return release, nil
+}
+
+// post sends the request body as JSON and decodes the response into result
+// (if not nil).  Non-2xx responses are returned as errors with GitHub's
+// message.
+func (g *Github) post(path string, body interface{}, result interface{}) error {
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, g.apiURL+path, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if g.token != "" {
+		req.Header.Set("Authorization", "token "+g.token)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode/100 != 2 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(respBody, &apiErr) == nil && apiErr.Message != "" {
+			return fmt.Errorf("Github: %s: %s", resp.Status, apiErr.Message)
+		}
+		return fmt.Errorf("Github: %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+
+	if result != nil && len(respBody) > 0 {
+		err = json.Unmarshal(respBody, result)
+		if err != nil {
+			return fmt.Errorf("Github: Unable to parse the response: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// ValidState returns true if the state is a valid deployment status state
+func ValidState(state string) bool {
+	for _, s := range States {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
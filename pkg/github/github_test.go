@@ -0,0 +1,103 @@
+package github
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+// request is a request received by the test server
+type request struct {
+	Path          string
+	Authorization string
+	Body          map[string]interface{}
+}
+
+func newTestServer(t *testing.T, status int, response string, requests *[]request) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NilError(t, err)
+		req := request{Path: r.URL.Path, Authorization: r.Header.Get("Authorization")}
+		assert.NilError(t, json.Unmarshal(body, &req.Body))
+		*requests = append(*requests, req)
+
+		w.WriteHeader(status)
+		w.Write([]byte(response))
+	}))
+}
+
+func TestCreateDeployment(t *testing.T) {
+	var requests []request
+	server := newTestServer(t, http.StatusCreated, `{"id":42,"ref":"abc123","sha":"abc123","environment":"prod/us-east-1"}`, &requests)
+	defer server.Close()
+
+	g := New(&Config{Token: "secret", APIURL: server.URL + "/"})
+	deployment, err := g.CreateDeployment("acme/app", &DeploymentRequest{
+		Ref:         "abc123",
+		Environment: "prod/us-east-1",
+		Production:  true,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, deployment.ID, int64(42))
+
+	assert.Equal(t, len(requests), 1)
+	assert.Equal(t, requests[0].Path, "/repos/acme/app/deployments")
+	assert.Equal(t, requests[0].Authorization, "token secret")
+	assert.Equal(t, requests[0].Body["ref"], "abc123")
+	assert.Equal(t, requests[0].Body["production_environment"], true)
+	assert.Equal(t, requests[0].Body["auto_merge"], false)
+	assert.DeepEqual(t, requests[0].Body["required_contexts"], []interface{}{})
+}
+
+func TestCreateDeploymentMerged(t *testing.T) {
+	var requests []request
+	server := newTestServer(t, http.StatusAccepted, `{"message":"Auto-merged master into topic on deployment."}`, &requests)
+	defer server.Close()
+
+	g := New(&Config{APIURL: server.URL})
+	_, err := g.CreateDeployment("acme/app", &DeploymentRequest{Ref: "topic", Environment: "dev"})
+	assert.Error(t, err, "Github: The deployment was not created")
+}
+
+func TestCreateDeploymentStatus(t *testing.T) {
+	var requests []request
+	server := newTestServer(t, http.StatusCreated, `{"id":7,"state":"success"}`, &requests)
+	defer server.Close()
+
+	g := New(&Config{APIURL: server.URL})
+	err := g.CreateDeploymentStatus("acme/app", 42, &StatusRequest{State: StateSuccess, EnvironmentURL: "https://app.example.com"})
+	assert.NilError(t, err)
+	assert.Equal(t, requests[0].Path, "/repos/acme/app/deployments/42/statuses")
+	assert.Equal(t, requests[0].Body["environment_url"], "https://app.example.com")
+
+	err = g.CreateDeploymentStatus("acme/app", 42, &StatusRequest{State: "done"})
+	assert.ErrorContains(t, err, "Invalid deployment state 'done'")
+	assert.Equal(t, len(requests), 1)
+}
+
+func TestCreateRelease(t *testing.T) {
+	var requests []request
+	server := newTestServer(t, http.StatusCreated, `{"id":3,"tag_name":"v1.0.0","html_url":"https://github.com/acme/app/releases/tag/v1.0.0"}`, &requests)
+	defer server.Close()
+
+	g := New(&Config{APIURL: server.URL})
+	release, err := g.CreateRelease("acme/app", &ReleaseRequest{TagName: "v1.0.0", Target: "abc123", Body: "Deployed"})
+	assert.NilError(t, err)
+	assert.Equal(t, release.HTMLURL, "https://github.com/acme/app/releases/tag/v1.0.0")
+	assert.Equal(t, requests[0].Path, "/repos/acme/app/releases")
+	assert.Equal(t, requests[0].Body["target_commitish"], "abc123")
+}
+
+func TestAPIError(t *testing.T) {
+	var requests []request
+	server := newTestServer(t, http.StatusUnprocessableEntity, `{"message":"Validation Failed"}`, &requests)
+	defer server.Close()
+
+	g := New(&Config{APIURL: server.URL})
+	_, err := g.CreateRelease("acme/app", &ReleaseRequest{TagName: "v1.0.0"})
+	assert.Error(t, err, "Github: 422 Unprocessable Entity: Validation Failed")
+}
@@ -0,0 +1,82 @@
+package i18n
+
+// IDs of the messages in the catalogs
+const (
+	MessageYes                   = "prompt.yes"
+	MessageNo                    = "prompt.no"
+	MessageProceed               = "prompt.proceed"
+	MessageLastSelected          = "prompt.last-selected"
+	MessageSelectDone            = "prompt.select-done"
+	MessageCommaSeparated        = "prompt.comma-separated"
+	MessageNotInList             = "prompt.not-in-list"
+	MessageWhichService          = "deploy.which-service"
+	MessageWhichEnvironment      = "deploy.which-environment"
+	MessageWhichInstance         = "deploy.which-instance"
+	MessageNoEnvironment         = "deploy.no-environment"
+	MessageNoInstance            = "deploy.no-instance"
+	MessageNoService             = "deploy.no-service"
+	MessageUnknownEnvironment    = "deploy.unknown-environment"
+	MessageUnknownInstance       = "deploy.unknown-instance"
+	MessageDeployFrozen          = "deploy.frozen"
+	MessageApprovalRejected      = "deploy.approval-rejected"
+	MessageApprovalExpired       = "deploy.approval-expired"
+	MessageDeployCancelled       = "deploy.cancelled"
+	MessageContinueRollout       = "deploy.continue-rollout"
+	MessageTypeToConfirm         = "deploy.type-to-confirm"
+	MessageConfirmationMismatch  = "deploy.confirmation-mismatch"
+	MessageTypedConfirmAutomated = "deploy.typed-confirmation-automated"
+	MessageDeactivateAccessKey   = "aws.keys.deactivate"
+	MessageDeleteAccessKey       = "aws.keys.delete"
+	MessageUpdateConfirm         = "update.confirm"
+	MessageUpdateCancelled       = "update.cancelled"
+	MessageUpdateAutomated       = "update.automated"
+	MessageRollbackConfirm       = "vault.kv.rollback"
+	MessageRollbackCancelled     = "vault.kv.rollback-cancelled"
+	MessageRollbackAutomated     = "vault.kv.rollback-automated"
+	MessageSetCurrentContext     = "kube.set-current-context"
+	MessageApplyCancelled        = "terraform.cancelled"
+	MessageTypeToConfirmApply    = "terraform.type-to-confirm"
+	MessageApplyMismatch         = "terraform.confirmation-mismatch"
+	MessageApplyTypedAutomated   = "terraform.typed-confirmation-automated"
+)
+
+// english is the catalog of the default locale.  Every message ID must be in
+// it.
+var english = map[string]string{
+	MessageYes:                   "y",
+	MessageNo:                    "n",
+	MessageProceed:               "Proceed?",
+	MessageLastSelected:          "%s (last)",
+	MessageSelectDone:            "Done (%d selected)",
+	MessageCommaSeparated:        "%s (comma separated, from: %s)",
+	MessageNotInList:             "'%s' is not one of [%s]",
+	MessageWhichService:          "Which service?",
+	MessageWhichEnvironment:      "Which environment?",
+	MessageWhichInstance:         "Which instance?",
+	MessageNoEnvironment:         "No environment selected! exiting",
+	MessageNoInstance:            "No instance selected! exiting",
+	MessageNoService:             "No service selected! exiting",
+	MessageUnknownEnvironment:    "Provided environment value '%s' is not in config file",
+	MessageUnknownInstance:       "Provided instance value '%s' is not in config file under environment '%s'",
+	MessageDeployFrozen:          "Deploys to environment '%s' are frozen until %s: %s.  Use --override-freeze <reason> to deploy anyway",
+	MessageApprovalRejected:      "Deploy rejected in Slack by %s",
+	MessageApprovalExpired:       "Deploy was not approved within %s",
+	MessageDeployCancelled:       "Deploy cancelled",
+	MessageContinueRollout:       "Continue the rollout with %s?",
+	MessageTypeToConfirm:         "Type '%s' to confirm the deploy",
+	MessageConfirmationMismatch:  "Confirmation did not match, deploy cancelled",
+	MessageTypedConfirmAutomated: "Environment '%s' requires typed confirmation, use --yes to deploy non-interactively",
+	MessageDeactivateAccessKey:   "Deactivate old access key %s?",
+	MessageDeleteAccessKey:       "Delete old access key %s?",
+	MessageUpdateConfirm:         "Update stim at %s from %s to %s?",
+	MessageUpdateCancelled:       "Update cancelled",
+	MessageUpdateAutomated:       "Use --yes to update non-interactively",
+	MessageRollbackConfirm:       "Roll back?",
+	MessageRollbackCancelled:     "Rollback cancelled",
+	MessageRollbackAutomated:     "Use --yes to roll back non-interactively",
+	MessageSetCurrentContext:     "Set as current context?",
+	MessageApplyCancelled:        "Apply cancelled",
+	MessageTypeToConfirmApply:    "Type '%s' to confirm the apply",
+	MessageApplyMismatch:         "Confirmation did not match, apply cancelled",
+	MessageApplyTypedAutomated:   "Environment '%s' requires typed confirmation, use --yes to apply non-interactively",
+}
@@ -0,0 +1,41 @@
+package i18n
+
+// spanish is the Spanish catalog.  Answers to yes/no prompts are `s` or `n`.
+var spanish = map[string]string{
+	MessageYes:                   "s",
+	MessageNo:                    "n",
+	MessageProceed:               "¿Continuar?",
+	MessageLastSelected:          "%s (último)",
+	MessageSelectDone:            "Listo (%d seleccionados)",
+	MessageCommaSeparated:        "%s (separados por comas, de: %s)",
+	MessageNotInList:             "'%s' no es uno de [%s]",
+	MessageWhichService:          "¿Qué servicio?",
+	MessageWhichEnvironment:      "¿Qué entorno?",
+	MessageWhichInstance:         "¿Qué instancia?",
+	MessageNoEnvironment:         "¡No se seleccionó ningún entorno! Saliendo",
+	MessageNoInstance:            "¡No se seleccionó ninguna instancia! Saliendo",
+	MessageNoService:             "¡No se seleccionó ningún servicio! Saliendo",
+	MessageUnknownEnvironment:    "El entorno '%s' no está en el archivo de configuración",
+	MessageUnknownInstance:       "La instancia '%s' no está en el archivo de configuración del entorno '%s'",
+	MessageDeployFrozen:          "Los despliegues al entorno '%s' están congelados hasta %s: %s.  Use --override-freeze <motivo> para desplegar de todos modos",
+	MessageApprovalRejected:      "Despliegue rechazado en Slack por %s",
+	MessageApprovalExpired:       "El despliegue no fue aprobado en %s",
+	MessageDeployCancelled:       "Despliegue cancelado",
+	MessageContinueRollout:       "¿Continuar el despliegue con %s?",
+	MessageTypeToConfirm:         "Escriba '%s' para confirmar el despliegue",
+	MessageConfirmationMismatch:  "La confirmación no coincide, despliegue cancelado",
+	MessageTypedConfirmAutomated: "El entorno '%s' requiere confirmación escrita, use --yes para desplegar de forma no interactiva",
+	MessageDeactivateAccessKey:   "¿Desactivar la clave de acceso anterior %s?",
+	MessageDeleteAccessKey:       "¿Eliminar la clave de acceso anterior %s?",
+	MessageUpdateConfirm:         "¿Actualizar stim en %s de %s a %s?",
+	MessageUpdateCancelled:       "Actualización cancelada",
+	MessageUpdateAutomated:       "Use --yes para actualizar de forma no interactiva",
+	MessageRollbackConfirm:       "¿Revertir?",
+	MessageRollbackCancelled:     "Reversión cancelada",
+	MessageRollbackAutomated:     "Use --yes para revertir de forma no interactiva",
+	MessageSetCurrentContext:     "¿Establecer como contexto actual?",
+	MessageApplyCancelled:        "Aplicación cancelada",
+	MessageTypeToConfirmApply:    "Escriba '%s' para confirmar la aplicación",
+	MessageApplyMismatch:         "La confirmación no coincide, aplicación cancelada",
+	MessageApplyTypedAutomated:   "El entorno '%s' requiere confirmación escrita, use --yes para aplicar de forma no interactiva",
+}
@@ -0,0 +1,99 @@
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultLocale is used when no supported locale is set.  Its catalog is also
+// the fallback for messages missing from the other catalogs.
+const DefaultLocale = "en"
+
+// catalogs are the messages of each supported locale.  Messages are fmt
+// format strings keyed by message ID.
+var catalogs = map[string]map[string]string{
+	"en": english,
+	"es": spanish,
+}
+
+// Localizer formats messages in a locale
+type Localizer struct {
+	locale  string
+	catalog map[string]string
+}
+
+// New returns a localizer for the locale.  The locale can be a language (ex.
+// `es`) or a POSIX locale (ex. `es_MX.UTF-8`).  Unsupported locales use the
+// default locale.
+func New(locale string) *Localizer {
+	l := &Localizer{locale: Normalize(locale)}
+	l.catalog = catalogs[l.locale]
+	return l
+}
+
+// Locale returns the supported locale of the localizer
+func (l *Localizer) Locale() string {
+	return l.locale
+}
+
+// Message formats the message with the given ID.  Messages missing from the
+// catalog of the locale use the default locale and unknown IDs are returned as
+// is.
+func (l *Localizer) Message(id string, args ...interface{}) string {
+	format, ok := l.catalog[id]
+	if !ok {
+		format, ok = catalogs[DefaultLocale][id]
+	}
+	if !ok {
+		format = id
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// IsYes returns true if the answer to a yes/no prompt means yes.  The first
+// letter of the answer is compared to the yes letter of the locale.
+func (l *Localizer) IsYes(answer string) bool {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer != "" && strings.HasPrefix(answer, l.Message(MessageYes))
+}
+
+// Normalize returns the supported locale matching the given locale (ex.
+// `es_MX.UTF-8` is `es`) or the default locale
+func Normalize(locale string) string {
+	language := strings.ToLower(locale)
+	if i := strings.IndexAny(language, "_-.@"); i >= 0 {
+		language = language[:i]
+	}
+	if _, ok := catalogs[language]; ok {
+		return language
+	}
+	return DefaultLocale
+}
+
+// Detect returns the locale set in the config or, if not set, the first set
+// of the LC_ALL, LC_MESSAGES and LANG environment variables
+func Detect(configured string, getenv func(string) string) string {
+	if configured != "" {
+		return Normalize(configured)
+	}
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := getenv(name); value != "" {
+			return Normalize(value)
+		}
+	}
+	return DefaultLocale
+}
+
+// Locales returns the supported locales
+func Locales() []string {
+	locales := make([]string, 0, len(catalogs))
+	for locale := range catalogs {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
@@ -0,0 +1,64 @@
+package i18n
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestCatalogs(t *testing.T) {
+	for locale, catalog := range catalogs {
+		for id, message := range catalog {
+			english, ok := catalogs[DefaultLocale][id]
+			assert.Assert(t, ok, "message %s of locale %s is not in the default catalog", id, locale)
+			assert.Equal(t, strings.Count(message, "%"), strings.Count(english, "%"), "message %s of locale %s", id, locale)
+		}
+	}
+}
+
+func TestMessage(t *testing.T) {
+	en := New("")
+	assert.Equal(t, en.Locale(), "en")
+	assert.Equal(t, en.Message(MessageTypeToConfirm, "prod"), "Type 'prod' to confirm the deploy")
+	assert.Equal(t, en.Message("unknown.id"), "unknown.id")
+
+	es := New("es_MX.UTF-8")
+	assert.Equal(t, es.Locale(), "es")
+	assert.Equal(t, es.Message(MessageTypeToConfirm, "prod"), "Escriba 'prod' para confirmar el despliegue")
+	assert.Equal(t, es.Message(MessageApprovalRejected, "ana"), "Despliegue rechazado en Slack por ana")
+
+	// Missing messages fall back to English
+	delete(spanish, MessageProceed)
+	defer func() { spanish[MessageProceed] = "¿Continuar?" }()
+	assert.Equal(t, es.Message(MessageProceed), "Proceed?")
+}
+
+func TestIsYes(t *testing.T) {
+	en := New("en")
+	assert.Assert(t, en.IsYes("y"))
+	assert.Assert(t, en.IsYes(" Yes "))
+	assert.Assert(t, !en.IsYes("n"))
+	assert.Assert(t, !en.IsYes("  "))
+
+	es := New("es")
+	assert.Assert(t, es.IsYes("s"))
+	assert.Assert(t, es.IsYes("Sí"))
+	assert.Assert(t, !es.IsYes("y"))
+	assert.Assert(t, !es.IsYes("no"))
+}
+
+func TestDetect(t *testing.T) {
+	env := map[string]string{"LANG": "es_AR.UTF-8"}
+	getenv := func(name string) string { return env[name] }
+
+	assert.Equal(t, Detect("", getenv), "es")
+	assert.Equal(t, Detect("en", getenv), "en")
+
+	env["LC_ALL"] = "C"
+	assert.Equal(t, Detect("", getenv), "en")
+
+	assert.Equal(t, Detect("", func(string) string { return "" }), "en")
+	assert.Equal(t, Normalize("fr_FR"), "en")
+	assert.DeepEqual(t, Locales(), []string{"en", "es"})
+}
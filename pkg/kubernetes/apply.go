@@ -0,0 +1,268 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// applyPatchType is the server-side apply patch type.  It is not defined in
+// the vendored apimachinery version.
+const applyPatchType = types.PatchType("application/apply-patch+yaml")
+
+// ObjectRef identifies a Kubernetes object
+type ObjectRef struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+// String returns the object as `kind/namespace/name`
+func (r ObjectRef) String() string {
+	if r.Namespace == "" {
+		return r.Kind + "/" + r.Name
+	}
+	return r.Kind + "/" + r.Namespace + "/" + r.Name
+}
+
+// ApplyObject creates or updates an object with server-side apply, forcing
+// ownership of conflicting fields to the field manager.  Namespaced objects
+// without a namespace are applied to the default namespace.
+func (k *Kubernetes) ApplyObject(object map[string]interface{}, defaultNamespace string, fieldManager string) (ObjectRef, error) {
+
+	ref, path, body, err := k.prepareObject(object, defaultNamespace)
+	if err != nil {
+		return ref, err
+	}
+
+	discoveryClient, err := k.DiscoveryClient()
+	if err != nil {
+		return ref, err
+	}
+
+	err = discoveryClient.RESTClient().Patch(applyPatchType).
+		AbsPath(path).
+		Param("fieldManager", fieldManager).
+		Param("force", "true").
+		Body(body).
+		Do().
+		Error()
+	if err != nil {
+		return ref, fmt.Errorf("Error applying %s: %v", ref, err)
+	}
+
+	return ref, nil
+}
+
+// DiffObject returns the live object and the object as it would be after a
+// server-side apply, using a dry run.  The live object is nil if the object
+// doesn't exist yet.
+func (k *Kubernetes) DiffObject(object map[string]interface{}, defaultNamespace string, fieldManager string) (ObjectRef, map[string]interface{}, map[string]interface{}, error) {
+
+	ref, path, body, err := k.prepareObject(object, defaultNamespace)
+	if err != nil {
+		return ref, nil, nil, err
+	}
+
+	discoveryClient, err := k.DiscoveryClient()
+	if err != nil {
+		return ref, nil, nil, err
+	}
+
+	var live map[string]interface{}
+	result, err := discoveryClient.RESTClient().Get().AbsPath(path).Do().Raw()
+	if err != nil && !apierrors.IsNotFound(err) {
+		return ref, nil, nil, fmt.Errorf("Error getting %s: %v", ref, err)
+	}
+	if err == nil {
+		err = json.Unmarshal(result, &live)
+		if err != nil {
+			return ref, nil, nil, err
+		}
+	}
+
+	result, err = discoveryClient.RESTClient().Patch(applyPatchType).
+		AbsPath(path).
+		Param("fieldManager", fieldManager).
+		Param("force", "true").
+		Param("dryRun", "All").
+		Body(body).
+		Do().
+		Raw()
+	if err != nil {
+		return ref, nil, nil, fmt.Errorf("Error applying %s (dry run): %v", ref, err)
+	}
+	var merged map[string]interface{}
+	err = json.Unmarshal(result, &merged)
+	if err != nil {
+		return ref, nil, nil, err
+	}
+
+	return ref, live, merged, nil
+}
+
+// prepareObject returns the reference, API path and JSON body of an object
+// to apply, setting the default namespace of namespaced objects
+func (k *Kubernetes) prepareObject(object map[string]interface{}, defaultNamespace string) (ObjectRef, string, []byte, error) {
+
+	ref := ObjectRef{}
+	ref.APIVersion, _ = object["apiVersion"].(string)
+	ref.Kind, _ = object["kind"].(string)
+	metadata, _ := object["metadata"].(map[string]interface{})
+	if metadata == nil || ref.APIVersion == "" || ref.Kind == "" {
+		return ref, "", nil, fmt.Errorf("Object is missing apiVersion, kind or metadata")
+	}
+	ref.Name, _ = metadata["name"].(string)
+	ref.Namespace, _ = metadata["namespace"].(string)
+	if ref.Name == "" {
+		return ref, "", nil, fmt.Errorf("%s is missing metadata.name", ref.Kind)
+	}
+
+	resource, namespaced, err := k.resourceFor(ref.APIVersion, ref.Kind)
+	if err != nil {
+		return ref, "", nil, err
+	}
+	if !namespaced {
+		ref.Namespace = ""
+	} else if ref.Namespace == "" {
+		ref.Namespace = defaultNamespace
+		metadata["namespace"] = defaultNamespace
+	}
+
+	body, err := json.Marshal(object)
+	if err != nil {
+		return ref, "", nil, err
+	}
+
+	return ref, objectPath(ref, resource), body, nil
+}
+
+// lastAppliedAnnotation is the annotation of `kubectl apply` with the last
+// applied configuration, which includes the data of Secrets
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// ObjectForDiff returns a copy of an object without the fields set by the
+// server that change on every update (ex. resourceVersion, managedFields and
+// status), so that diffs only show changes to the object's configuration.
+// The last-applied-configuration annotation of `kubectl apply` is removed
+// too: it repeats the object, including the values of Secrets.
+func ObjectForDiff(object map[string]interface{}) map[string]interface{} {
+
+	if object == nil {
+		return nil
+	}
+
+	result := make(map[string]interface{}, len(object))
+	for key, value := range object {
+		if key != "status" {
+			result[key] = value
+		}
+	}
+
+	if metadata, ok := object["metadata"].(map[string]interface{}); ok {
+		m := make(map[string]interface{}, len(metadata))
+		for key, value := range metadata {
+			switch key {
+			case "managedFields", "resourceVersion", "generation", "uid", "creationTimestamp", "selfLink":
+			case "annotations":
+				if annotations := objectAnnotationsForDiff(value); annotations != nil {
+					m[key] = annotations
+				}
+			default:
+				m[key] = value
+			}
+		}
+		result["metadata"] = m
+	}
+
+	return result
+}
+
+// objectAnnotationsForDiff returns a copy of the annotations without the
+// last-applied-configuration annotation, or nil if there are no others
+func objectAnnotationsForDiff(value interface{}) interface{} {
+	annotations, ok := value.(map[string]interface{})
+	if !ok {
+		return value
+	}
+	result := make(map[string]interface{}, len(annotations))
+	for key, value := range annotations {
+		if key != lastAppliedAnnotation {
+			result[key] = value
+		}
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// DeleteObject deletes an object.  Objects that no longer exist are ignored.
+func (k *Kubernetes) DeleteObject(ref ObjectRef) error {
+
+	resource, _, err := k.resourceFor(ref.APIVersion, ref.Kind)
+	if err != nil {
+		return err
+	}
+
+	discoveryClient, err := k.DiscoveryClient()
+	if err != nil {
+		return err
+	}
+
+	err = discoveryClient.RESTClient().Delete().AbsPath(objectPath(ref, resource)).Do().Error()
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("Error deleting %s: %v", ref, err)
+	}
+
+	return nil
+}
+
+// resourceFor returns the resource name of a kind and whether it is
+// namespaced, using the API discovery of its group version
+func (k *Kubernetes) resourceFor(apiVersion string, kind string) (string, bool, error) {
+
+	if k.resources == nil {
+		k.resources = make(map[string]*metav1.APIResourceList)
+	}
+
+	list, ok := k.resources[apiVersion]
+	if !ok {
+		discoveryClient, err := k.DiscoveryClient()
+		if err != nil {
+			return "", false, err
+		}
+		list, err = discoveryClient.ServerResourcesForGroupVersion(apiVersion)
+		if err != nil {
+			return "", false, fmt.Errorf("Unable to discover resources for %s: %v", apiVersion, err)
+		}
+		k.resources[apiVersion] = list
+	}
+
+	for _, r := range list.APIResources {
+		if r.Kind == kind && !strings.Contains(r.Name, "/") {
+			return r.Name, r.Namespaced, nil
+		}
+	}
+
+	return "", false, fmt.Errorf("Kind %s not found in %s", kind, apiVersion)
+}
+
+// objectPath returns the API path of an object
+func objectPath(ref ObjectRef, resource string) string {
+
+	path := "/apis/" + ref.APIVersion
+	if !strings.Contains(ref.APIVersion, "/") {
+		path = "/api/" + ref.APIVersion
+	}
+	if ref.Namespace != "" {
+		path += "/namespaces/" + ref.Namespace
+	}
+
+	return path + "/" + resource + "/" + ref.Name
+}
@@ -0,0 +1,59 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestObjectForDiff(t *testing.T) {
+	object := map[string]interface{}{
+		"kind": "Deployment",
+		"metadata": map[string]interface{}{
+			"name":            "app",
+			"labels":          map[string]interface{}{"app": "app"},
+			"managedFields":   []interface{}{},
+			"resourceVersion": "42",
+			"generation":      3,
+			"uid":             "abc",
+			"annotations": map[string]interface{}{
+				"kubectl.kubernetes.io/last-applied-configuration": `{"kind":"Deployment"}`,
+				"owner": "team-a",
+			},
+		},
+		"spec":   map[string]interface{}{"replicas": 2},
+		"status": map[string]interface{}{"readyReplicas": 2},
+	}
+
+	assert.DeepEqual(t, ObjectForDiff(object), map[string]interface{}{
+		"kind": "Deployment",
+		"metadata": map[string]interface{}{
+			"name":        "app",
+			"labels":      map[string]interface{}{"app": "app"},
+			"annotations": map[string]interface{}{"owner": "team-a"},
+		},
+		"spec": map[string]interface{}{"replicas": 2},
+	})
+
+	// Annotations are dropped if only the last applied configuration is set
+	secret := map[string]interface{}{
+		"kind": "Secret",
+		"metadata": map[string]interface{}{
+			"name":        "db",
+			"annotations": map[string]interface{}{"kubectl.kubernetes.io/last-applied-configuration": `{"data":{"password":"c2VjcmV0"}}`},
+		},
+	}
+	assert.DeepEqual(t, ObjectForDiff(secret), map[string]interface{}{
+		"kind":     "Secret",
+		"metadata": map[string]interface{}{"name": "db"},
+	})
+
+	// The object is not changed
+	assert.Equal(t, object["metadata"].(map[string]interface{})["uid"], "abc")
+	assert.Assert(t, ObjectForDiff(nil) == nil)
+}
+
+func TestObjectPath(t *testing.T) {
+	assert.Equal(t, objectPath(ObjectRef{APIVersion: "v1", Kind: "ConfigMap", Namespace: "apps", Name: "settings"}, "configmaps"), "/api/v1/namespaces/apps/configmaps/settings")
+	assert.Equal(t, objectPath(ObjectRef{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole", Name: "view"}, "clusterroles"), "/apis/rbac.authorization.k8s.io/v1/clusterroles/view")
+}
@@ -0,0 +1,165 @@
+package kubernetes
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Certificate describes a certificate found in a cluster or kubeconfig
+type Certificate struct {
+
+	// Source is where the certificate was found (ex. secret, kubeconfig)
+	Source string
+
+	// Namespace of the secret containing the certificate (if applicable)
+	Namespace string
+
+	// Name of the secret or kubeconfig user containing the certificate
+	Name string
+
+	// Subject is the common name of the certificate
+	Subject string
+
+	// Hosts are the DNS names of the certificate and any ingress hosts using it
+	Hosts []string
+
+	// NotAfter is when the certificate expires
+	NotAfter time.Time
+}
+
+// ExpiresWithin returns true if the certificate expires within the given duration
+func (c *Certificate) ExpiresWithin(d time.Duration) bool {
+	return time.Until(c.NotAfter) < d
+}
+
+// GetTLSCertificates returns the certificates stored in all TLS secrets in
+// the cluster.  Secrets referenced by ingresses also include the ingress hosts.
+// Failures to read a single source (ex. listing ingresses is denied) are
+// returned as warnings along with the certificates that could be read.
+func (k *Kubernetes) GetTLSCertificates() ([]*Certificate, []error, error) {
+
+	clientSet, err := k.GetClientset()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var warnings []error
+
+	secrets, err := clientSet.CoreV1().Secrets("").List(metav1.ListOptions{FieldSelector: "type=" + string(v1.SecretTypeTLS)})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Map ingress hosts to the secrets they use
+	ingressHosts := make(map[string][]string)
+	ingresses, err := clientSet.ExtensionsV1beta1().Ingresses("").List(metav1.ListOptions{})
+	if err != nil {
+		warnings = append(warnings, fmt.Errorf("Unable to list ingresses, hosts will only include certificate names: %v", err))
+	} else {
+		for _, ingress := range ingresses.Items {
+			for _, tls := range ingress.Spec.TLS {
+				key := ingress.Namespace + "/" + tls.SecretName
+				ingressHosts[key] = append(ingressHosts[key], tls.Hosts...)
+			}
+		}
+	}
+
+	var result []*Certificate
+	for _, secret := range secrets.Items {
+		cert, err := parseCertificate(secret.Data[v1.TLSCertKey])
+		if err != nil {
+			warnings = append(warnings, fmt.Errorf("Unable to parse certificate in secret %s/%s: %v", secret.Namespace, secret.Name, err))
+			continue
+		}
+		if cert == nil {
+			continue
+		}
+
+		hosts := append([]string{}, cert.DNSNames...)
+		hosts = append(hosts, ingressHosts[secret.Namespace+"/"+secret.Name]...)
+		result = append(result, &Certificate{
+			Source:    "secret",
+			Namespace: secret.Namespace,
+			Name:      secret.Name,
+			Subject:   cert.Subject.CommonName,
+			Hosts:     uniqueStrings(hosts),
+			NotAfter:  cert.NotAfter,
+		})
+	}
+
+	return result, warnings, nil
+}
+
+// GetClientCertificates returns the client certificates of all users in the
+// kubeconfig.  Users whose certificate can't be read are returned as warnings
+// along with the certificates that could be read.
+func (c *Config) GetClientCertificates() ([]*Certificate, []error, error) {
+
+	config, err := c.configAccess.GetStartingConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var result []*Certificate
+	var warnings []error
+	for name, authInfo := range config.AuthInfos {
+		data := authInfo.ClientCertificateData
+		if len(data) == 0 && authInfo.ClientCertificate != "" {
+			data, err = ioutil.ReadFile(authInfo.ClientCertificate)
+			if err != nil {
+				warnings = append(warnings, fmt.Errorf("Unable to read client certificate of kubeconfig user %s: %v", name, err))
+				continue
+			}
+		}
+
+		cert, err := parseCertificate(data)
+		if err != nil {
+			warnings = append(warnings, fmt.Errorf("Unable to parse client certificate of kubeconfig user %s: %v", name, err))
+			continue
+		}
+		if cert == nil {
+			continue
+		}
+
+		result = append(result, &Certificate{
+			Source:   "kubeconfig",
+			Name:     name,
+			Subject:  cert.Subject.CommonName,
+			NotAfter: cert.NotAfter,
+		})
+	}
+
+	return result, warnings, nil
+}
+
+// parseCertificate parses the first certificate in PEM encoded data
+// Returns nil if there is no certificate
+func parseCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, nil
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// uniqueStrings returns the sorted unique values of the given list
+func uniqueStrings(list []string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, s := range list {
+		if !seen[s] {
+			seen[s] = true
+			result = append(result, s)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
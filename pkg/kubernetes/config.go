@@ -1,11 +1,25 @@
 package kubernetes
 
 import (
+	"context"
+	"net/http"
+
+	"github.com/PremiereGlobal/stim/pkg/cancel"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
+// cancelContext cancels the requests in flight of every client when it's
+// done, set with SetCancelContext
+var cancelContext context.Context
+
+// SetCancelContext sets the context that cancels the requests in flight of
+// every client when it's done (ex. Ctrl-C)
+func SetCancelContext(ctx context.Context) {
+	cancelContext = ctx
+}
+
 // Config effectivly represents a kubeconfig file configuration allowing for
 // creating or modifying the values in that file
 type Config struct {
@@ -33,6 +47,14 @@ type ConfigOptions struct {
 	// AuthToken is the authentication token
 	AuthToken string
 
+	// AuthExecCommand is the command of an exec credential plugin that
+	// provides the token each time the context is used.  If set, it is used
+	// instead of AuthToken
+	AuthExecCommand string
+
+	// AuthExecArgs are the arguments of the exec credential plugin
+	AuthExecArgs []string
+
 	// ContextName is the name of the context
 	ContextName string
 
@@ -79,7 +101,15 @@ func (c *Config) Modify(options *ConfigOptions) error {
 	newConfig.Clusters[options.ClusterName] = cluster
 
 	authInfo := clientcmdapi.NewAuthInfo()
-	authInfo.Token = options.AuthToken
+	if options.AuthExecCommand != "" {
+		authInfo.Exec = &clientcmdapi.ExecConfig{
+			APIVersion: ExecCredentialAPIVersion,
+			Command:    options.AuthExecCommand,
+			Args:       options.AuthExecArgs,
+		}
+	} else {
+		authInfo.Token = options.AuthToken
+	}
 	newConfig.AuthInfos[options.AuthName] = authInfo
 
 	context := clientcmdapi.NewContext()
@@ -113,6 +143,71 @@ func (c *Config) GetRestClientConfig() (*rest.Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	if ctx := cancelContext; ctx != nil {
+		clientConfig.WrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+			return cancel.Transport(ctx, rt)
+		}
+	}
 
 	return clientConfig, nil
 }
+
+// CurrentContext returns the name of the current context
+func (c *Config) CurrentContext() (string, error) {
+
+	clientcmdapiConfig, err := c.configAccess.GetStartingConfig()
+	if err != nil {
+		return "", err
+	}
+
+	return clientcmdapiConfig.CurrentContext, nil
+}
+
+// Namespace returns the namespace of the current context, or `default` if it
+// is not set
+func (c *Config) Namespace() (string, error) {
+
+	clientcmdapiConfig, err := c.configAccess.GetStartingConfig()
+	if err != nil {
+		return "", err
+	}
+
+	namespace, _, err := clientcmd.NewDefaultClientConfig(*clientcmdapiConfig, &clientcmd.ConfigOverrides{}).Namespace()
+	return namespace, err
+}
+
+// RemoveContexts removes the given contexts from the kubeconfig, along with
+// any clusters and users that are no longer used by another context
+func (c *Config) RemoveContexts(names []string) error {
+
+	newConfig, err := c.configAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		context, ok := newConfig.Contexts[name]
+		if !ok {
+			continue
+		}
+		delete(newConfig.Contexts, name)
+
+		if newConfig.CurrentContext == name {
+			newConfig.CurrentContext = ""
+		}
+
+		clusterUsed, authInfoUsed := false, false
+		for _, other := range newConfig.Contexts {
+			clusterUsed = clusterUsed || other.Cluster == context.Cluster
+			authInfoUsed = authInfoUsed || other.AuthInfo == context.AuthInfo
+		}
+		if !clusterUsed {
+			delete(newConfig.Clusters, context.Cluster)
+		}
+		if !authInfoUsed {
+			delete(newConfig.AuthInfos, context.AuthInfo)
+		}
+	}
+
+	return clientcmd.ModifyConfig(c.configAccess, *newConfig, false)
+}
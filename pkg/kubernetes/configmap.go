@@ -0,0 +1,65 @@
+package kubernetes
+
+import (
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ApplyConfigMap creates the ConfigMap with the given data and labels, or
+// replaces the data and labels if it already exists
+func (k *Kubernetes) ApplyConfigMap(namespace string, name string, data map[string]string, labels map[string]string) error {
+
+	clientSet, err := k.GetClientset()
+	if err != nil {
+		return err
+	}
+
+	configMaps := clientSet.CoreV1().ConfigMaps(namespace)
+
+	existing, err := configMaps.Get(name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = configMaps.Create(&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    labels,
+			},
+			Data: data,
+		})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if existing.Labels == nil {
+		existing.Labels = make(map[string]string)
+	}
+	for k, v := range labels {
+		existing.Labels[k] = v
+	}
+	existing.Data = data
+
+	_, err = configMaps.Update(existing)
+	return err
+}
+
+// GetConfigMapData returns the data of a ConfigMap, or nil if it does not exist
+func (k *Kubernetes) GetConfigMapData(namespace string, name string) (map[string]string, error) {
+
+	clientSet, err := k.GetClientset()
+	if err != nil {
+		return nil, err
+	}
+
+	configMap, err := clientSet.CoreV1().ConfigMaps(namespace).Get(name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return configMap.Data, nil
+}
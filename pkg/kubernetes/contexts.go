@@ -0,0 +1,73 @@
+package kubernetes
+
+import (
+	"fmt"
+	"sort"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Context describes a context of the kubeconfig
+type Context struct {
+	Name      string `json:"name"`
+	Cluster   string `json:"cluster"`
+	User      string `json:"user"`
+	Namespace string `json:"namespace,omitempty"`
+	Current   bool   `json:"current"`
+}
+
+// Contexts returns the contexts of the kubeconfig, sorted by name
+func (c *Config) Contexts() ([]Context, error) {
+
+	config, err := c.configAccess.GetStartingConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var contexts []Context
+	for name, context := range config.Contexts {
+		contexts = append(contexts, Context{
+			Name:      name,
+			Cluster:   context.Cluster,
+			User:      context.AuthInfo,
+			Namespace: context.Namespace,
+			Current:   name == config.CurrentContext,
+		})
+	}
+	sort.Slice(contexts, func(i, j int) bool { return contexts[i].Name < contexts[j].Name })
+
+	return contexts, nil
+}
+
+// UseContext sets the current context of the kubeconfig
+func (c *Config) UseContext(name string) error {
+
+	config, err := c.configAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := config.Contexts[name]; !ok {
+		return fmt.Errorf("Context '%s' not found in the kubeconfig", name)
+	}
+	config.CurrentContext = name
+
+	return clientcmd.ModifyConfig(c.configAccess, *config, false)
+}
+
+// SetNamespace sets the default namespace of the current context
+func (c *Config) SetNamespace(namespace string) error {
+
+	config, err := c.configAccess.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+
+	context, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return fmt.Errorf("No current context in the kubeconfig")
+	}
+	context.Namespace = namespace
+
+	return clientcmd.ModifyConfig(c.configAccess, *config, false)
+}
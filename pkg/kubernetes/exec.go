@@ -0,0 +1,35 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientauthv1beta1 "k8s.io/client-go/pkg/apis/clientauthentication/v1beta1"
+)
+
+// ExecCredentialAPIVersion is the API version of the exec credentials written
+// to kubeconfigs and returned by exec credential plugins
+const ExecCredentialAPIVersion = "client.authentication.k8s.io/v1beta1"
+
+// ExecCredential returns the output of an exec credential plugin for the
+// token.  A zero expiration means the token is cached by the client until it
+// is rejected.
+func ExecCredential(token string, expiration time.Time) ([]byte, error) {
+
+	credential := &clientauthv1beta1.ExecCredential{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: ExecCredentialAPIVersion,
+			Kind:       "ExecCredential",
+		},
+		Status: &clientauthv1beta1.ExecCredentialStatus{
+			Token: token,
+		},
+	}
+	if !expiration.IsZero() {
+		expires := metav1.NewTime(expiration)
+		credential.Status.ExpirationTimestamp = &expires
+	}
+
+	return json.Marshal(credential)
+}
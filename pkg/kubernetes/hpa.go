@@ -0,0 +1,227 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// HPAOverrideAnnotation is the HorizontalPodAutoscaler annotation holding the
+// replica limits to revert a temporary override to
+const HPAOverrideAnnotation = "stim.kube/hpa-override"
+
+// HPA is the status of a HorizontalPodAutoscaler
+type HPA struct {
+	Namespace       string       `json:"namespace"`
+	Name            string       `json:"name"`
+	Target          string       `json:"target"`
+	MinReplicas     int32        `json:"minReplicas"`
+	MaxReplicas     int32        `json:"maxReplicas"`
+	CurrentReplicas int32        `json:"currentReplicas"`
+	DesiredReplicas int32        `json:"desiredReplicas"`
+	CurrentCPU      *int32       `json:"currentCPUUtilizationPercentage,omitempty"`
+	TargetCPU       *int32       `json:"targetCPUUtilizationPercentage,omitempty"`
+	Override        *HPAOverride `json:"override,omitempty"`
+}
+
+// HPAOverride describes a temporary override of the replica limits of a
+// HorizontalPodAutoscaler.  MinReplicas and MaxReplicas are the limits from
+// before the override, which it is reverted to.
+type HPAOverride struct {
+	MinReplicas int32     `json:"minReplicas"`
+	MaxReplicas int32     `json:"maxReplicas"`
+	User        string    `json:"user"`
+	Reason      string    `json:"reason,omitempty"`
+	Until       time.Time `json:"until"`
+}
+
+// ListHPAs returns the HorizontalPodAutoscalers in the namespace (all
+// namespaces if empty) that match the label selector, sorted by namespace and
+// name
+func (k *Kubernetes) ListHPAs(namespace string, selector string) ([]*HPA, error) {
+
+	clientSet, err := k.GetClientset()
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := clientSet.AutoscalingV1().HorizontalPodAutoscalers(namespace).List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+
+	hpas := make([]*HPA, len(list.Items))
+	for i := range list.Items {
+		hpas[i] = newHPA(&list.Items[i])
+	}
+	sort.Slice(hpas, func(i, j int) bool {
+		if hpas[i].Namespace != hpas[j].Namespace {
+			return hpas[i].Namespace < hpas[j].Namespace
+		}
+		return hpas[i].Name < hpas[j].Name
+	})
+
+	return hpas, nil
+}
+
+// OverrideHPA sets the replica limits of a HorizontalPodAutoscaler and records
+// the previous limits in its HPAOverrideAnnotation so they can be reverted.
+// Overriding an overridden HPA keeps the limits from before the first
+// override.  A min or max of 0 keeps the current value.
+func (k *Kubernetes) OverrideHPA(namespace string, name string, min int32, max int32, override HPAOverride) (*HPA, error) {
+
+	clientSet, err := k.GetClientset()
+	if err != nil {
+		return nil, err
+	}
+
+	hpa, err := clientSet.AutoscalingV1().HorizontalPodAutoscalers(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	err = applyHPAOverride(hpa, min, max, override)
+	if err != nil {
+		return nil, err
+	}
+
+	hpa, err = clientSet.AutoscalingV1().HorizontalPodAutoscalers(namespace).Update(hpa)
+	if err != nil {
+		return nil, err
+	}
+
+	return newHPA(hpa), nil
+}
+
+// RevertHPA restores the replica limits of an overridden HorizontalPodAutoscaler
+// and returns the override that was reverted, nil if it was not overridden
+func (k *Kubernetes) RevertHPA(namespace string, name string) (*HPAOverride, error) {
+
+	clientSet, err := k.GetClientset()
+	if err != nil {
+		return nil, err
+	}
+
+	hpa, err := clientSet.AutoscalingV1().HorizontalPodAutoscalers(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	override, err := revertHPAOverride(hpa)
+	if err != nil || override == nil {
+		return nil, err
+	}
+
+	_, err = clientSet.AutoscalingV1().HorizontalPodAutoscalers(namespace).Update(hpa)
+	if err != nil {
+		return nil, err
+	}
+
+	return override, nil
+}
+
+// newHPA returns the status of a HorizontalPodAutoscaler.  An unreadable
+// override annotation is ignored.
+func newHPA(hpa *autoscalingv1.HorizontalPodAutoscaler) *HPA {
+
+	status := &HPA{
+		Namespace:       hpa.Namespace,
+		Name:            hpa.Name,
+		Target:          hpa.Spec.ScaleTargetRef.Kind + "/" + hpa.Spec.ScaleTargetRef.Name,
+		MinReplicas:     minReplicas(hpa),
+		MaxReplicas:     hpa.Spec.MaxReplicas,
+		CurrentReplicas: hpa.Status.CurrentReplicas,
+		DesiredReplicas: hpa.Status.DesiredReplicas,
+		CurrentCPU:      hpa.Status.CurrentCPUUtilizationPercentage,
+		TargetCPU:       hpa.Spec.TargetCPUUtilizationPercentage,
+	}
+	status.Override, _ = hpaOverride(hpa)
+
+	return status
+}
+
+// applyHPAOverride sets the replica limits of the HPA and its override
+// annotation
+func applyHPAOverride(hpa *autoscalingv1.HorizontalPodAutoscaler, min int32, max int32, override HPAOverride) error {
+
+	if min == 0 {
+		min = minReplicas(hpa)
+	}
+	if max == 0 {
+		max = hpa.Spec.MaxReplicas
+	}
+	if min < 1 || max < min {
+		return fmt.Errorf("Invalid replicas min %d max %d, min must be at least 1 and max at least min", min, max)
+	}
+
+	existing, err := hpaOverride(hpa)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		override.MinReplicas = existing.MinReplicas
+		override.MaxReplicas = existing.MaxReplicas
+	} else {
+		override.MinReplicas = minReplicas(hpa)
+		override.MaxReplicas = hpa.Spec.MaxReplicas
+	}
+
+	b, err := json.Marshal(override)
+	if err != nil {
+		return err
+	}
+	if hpa.Annotations == nil {
+		hpa.Annotations = map[string]string{}
+	}
+	hpa.Annotations[HPAOverrideAnnotation] = string(b)
+	hpa.Spec.MinReplicas = &min
+	hpa.Spec.MaxReplicas = max
+
+	return nil
+}
+
+// revertHPAOverride restores the replica limits of the override annotation
+// and removes it, returning the reverted override (nil if there is none)
+func revertHPAOverride(hpa *autoscalingv1.HorizontalPodAutoscaler) (*HPAOverride, error) {
+
+	override, err := hpaOverride(hpa)
+	if err != nil || override == nil {
+		return nil, err
+	}
+
+	min := override.MinReplicas
+	hpa.Spec.MinReplicas = &min
+	hpa.Spec.MaxReplicas = override.MaxReplicas
+	delete(hpa.Annotations, HPAOverrideAnnotation)
+
+	return override, nil
+}
+
+// hpaOverride returns the override of the HPA, nil if it is not overridden
+func hpaOverride(hpa *autoscalingv1.HorizontalPodAutoscaler) (*HPAOverride, error) {
+
+	value, ok := hpa.Annotations[HPAOverrideAnnotation]
+	if !ok {
+		return nil, nil
+	}
+
+	override := &HPAOverride{}
+	err := json.Unmarshal([]byte(value), override)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid %s annotation on HorizontalPodAutoscaler %s/%s: %v", HPAOverrideAnnotation, hpa.Namespace, hpa.Name, err)
+	}
+
+	return override, nil
+}
+
+// minReplicas returns the min replicas of the HPA, which default to 1
+func minReplicas(hpa *autoscalingv1.HorizontalPodAutoscaler) int32 {
+	if hpa.Spec.MinReplicas == nil {
+		return 1
+	}
+	return *hpa.Spec.MinReplicas
+}
@@ -0,0 +1,42 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+)
+
+func TestHPAOverride(t *testing.T) {
+	hpa := &autoscalingv1.HorizontalPodAutoscaler{}
+	hpa.Spec.MaxReplicas = 10
+
+	until := time.Date(2024, 5, 1, 17, 0, 0, 0, time.UTC)
+	assert.NilError(t, applyHPAOverride(hpa, 20, 40, HPAOverride{User: "alice", Until: until}))
+	assert.Equal(t, *hpa.Spec.MinReplicas, int32(20))
+	assert.Equal(t, hpa.Spec.MaxReplicas, int32(40))
+
+	// A second override keeps the limits from before the first one
+	assert.NilError(t, applyHPAOverride(hpa, 0, 60, HPAOverride{User: "bob", Until: until}))
+	assert.Equal(t, *hpa.Spec.MinReplicas, int32(20))
+	assert.Equal(t, hpa.Spec.MaxReplicas, int32(60))
+
+	override, err := hpaOverride(hpa)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, override, &HPAOverride{MinReplicas: 1, MaxReplicas: 10, User: "bob", Until: until})
+
+	override, err = revertHPAOverride(hpa)
+	assert.NilError(t, err)
+	assert.Equal(t, override.User, "bob")
+	assert.Equal(t, *hpa.Spec.MinReplicas, int32(1))
+	assert.Equal(t, hpa.Spec.MaxReplicas, int32(10))
+	_, ok := hpa.Annotations[HPAOverrideAnnotation]
+	assert.Assert(t, !ok)
+
+	override, err = revertHPAOverride(hpa)
+	assert.NilError(t, err)
+	assert.Assert(t, override == nil)
+
+	assert.ErrorContains(t, applyHPAOverride(hpa, 5, 2, HPAOverride{}), "Invalid replicas")
+}
@@ -0,0 +1,283 @@
+package kubernetes
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// jobContainer is the name of the container of the pods of jobs run with
+// RunJob
+const jobContainer = "job"
+
+// jobPollInterval is how often the pod of a job is checked while waiting
+// for it to start or finish
+const jobPollInterval = 2 * time.Second
+
+// jobTTL is how long a finished job is kept if it isn't deleted by RunJob
+// (ex. if stim is killed)
+const jobTTL int32 = 3600
+
+// podStartErrors are the waiting reasons of a container that won't start
+// without a change to the pod
+var podStartErrors = map[string]bool{
+	"ErrImagePull":               true,
+	"ImagePullBackOff":           true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+}
+
+// JobSpec describes a job that runs a single container to completion
+type JobSpec struct {
+	Namespace  string
+	Name       string
+	Labels     map[string]string
+	Image      string
+	Command    []string
+	WorkingDir string
+
+	// Env is set from a Secret created along with the job, so that secrets
+	// aren't stored in the job
+	Env map[string]string
+
+	// Files are written to the Secret as well and mounted at FilesPath
+	Files     map[string][]byte
+	FilesPath string
+
+	// EmptyDirs are the paths of writable empty volumes
+	EmptyDirs []string
+}
+
+// RunJob runs the job, streaming the logs of its container to out, and
+// returns the exit code of the container.  The job and its Secret are
+// deleted once it finishes, or when the context is done (which stops the
+// container).  An error is returned if the pod doesn't start within the start
+// timeout.
+func (k *Kubernetes) RunJob(ctx context.Context, spec *JobSpec, out io.Writer, startTimeout time.Duration) (int, error) {
+
+	clientSet, err := k.GetClientset()
+	if err != nil {
+		return 0, err
+	}
+
+	job, secret := jobObjects(spec)
+
+	secret, err = clientSet.CoreV1().Secrets(spec.Namespace).Create(secret)
+	if err != nil {
+		return 0, fmt.Errorf("Unable to create Secret %s/%s: %v", spec.Namespace, spec.Name, err)
+	}
+	defer clientSet.CoreV1().Secrets(spec.Namespace).Delete(secret.Name, &metav1.DeleteOptions{})
+
+	job, err = clientSet.BatchV1().Jobs(spec.Namespace).Create(job)
+	if err != nil {
+		return 0, fmt.Errorf("Unable to create Job %s/%s: %v", spec.Namespace, spec.Name, err)
+	}
+	propagation := metav1.DeletePropagationBackground
+	defer clientSet.BatchV1().Jobs(spec.Namespace).Delete(job.Name, &metav1.DeleteOptions{PropagationPolicy: &propagation})
+
+	// The Secret is owned by the job so that it's garbage collected with
+	// the job if it isn't deleted above
+	controller := true
+	secret.OwnerReferences = []metav1.OwnerReference{{
+		APIVersion: "batch/v1",
+		Kind:       "Job",
+		Name:       job.Name,
+		UID:        job.UID,
+		Controller: &controller,
+	}}
+	clientSet.CoreV1().Secrets(spec.Namespace).Update(secret)
+
+	pod, err := k.waitForJobPod(ctx, spec.Namespace, job.Name, startTimeout)
+	if err != nil {
+		return 0, err
+	}
+
+	logs, err := clientSet.CoreV1().Pods(spec.Namespace).GetLogs(pod, &v1.PodLogOptions{Container: jobContainer, Follow: true}).Stream()
+	if err != nil {
+		return 0, fmt.Errorf("Unable to get the logs of pod %s/%s: %v", spec.Namespace, pod, err)
+	}
+	scanner := bufio.NewScanner(logs)
+	for scanner.Scan() {
+		fmt.Fprintln(out, scanner.Text())
+	}
+	logs.Close()
+
+	// The logs end when the container exits, but its status may not be
+	// updated yet
+	for {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		p, err := clientSet.CoreV1().Pods(spec.Namespace).Get(pod, metav1.GetOptions{})
+		if err != nil {
+			return 0, err
+		}
+		if code, done := containerExitCode(p, jobContainer); done {
+			return int(code), nil
+		}
+		if p.Status.Phase == v1.PodFailed {
+			return 0, fmt.Errorf("The pod %s/%s failed: %s %s", spec.Namespace, pod, p.Status.Reason, p.Status.Message)
+		}
+		time.Sleep(jobPollInterval)
+	}
+}
+
+// waitForJobPod waits for the pod of a job to start and returns its name
+func (k *Kubernetes) waitForJobPod(ctx context.Context, namespace string, job string, timeout time.Duration) (string, error) {
+
+	clientSet, err := k.GetClientset()
+	if err != nil {
+		return "", err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		pods, err := clientSet.CoreV1().Pods(namespace).List(metav1.ListOptions{LabelSelector: "job-name=" + job})
+		if err != nil {
+			return "", err
+		}
+		for i := range pods.Items {
+			pod := &pods.Items[i]
+			if err := podStartError(pod, jobContainer); err != nil {
+				return "", err
+			}
+			if pod.Status.Phase != v1.PodPending {
+				return pod.Name, nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("The pod of Job %s/%s didn't start within %s", namespace, job, timeout)
+		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		time.Sleep(jobPollInterval)
+	}
+}
+
+// jobObjects returns the job and the Secret with its env vars and files
+func jobObjects(spec *JobSpec) (*batchv1.Job, *v1.Secret) {
+
+	meta := metav1.ObjectMeta{
+		Name:      spec.Name,
+		Namespace: spec.Namespace,
+		Labels:    spec.Labels,
+	}
+
+	secret := &v1.Secret{
+		ObjectMeta: meta,
+		Type:       v1.SecretTypeOpaque,
+		Data:       map[string][]byte{},
+	}
+
+	container := v1.Container{
+		Name:       jobContainer,
+		Image:      spec.Image,
+		Command:    spec.Command,
+		WorkingDir: spec.WorkingDir,
+	}
+
+	// Env vars are sorted so that the job is the same for the same spec
+	var names []string
+	for name := range spec.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		key := fmt.Sprintf("env-%d", i)
+		secret.Data[key] = []byte(spec.Env[name])
+		container.Env = append(container.Env, v1.EnvVar{
+			Name: name,
+			ValueFrom: &v1.EnvVarSource{
+				SecretKeyRef: &v1.SecretKeySelector{
+					LocalObjectReference: v1.LocalObjectReference{Name: spec.Name},
+					Key:                  key,
+				},
+			},
+		})
+	}
+
+	var volumes []v1.Volume
+	if len(spec.Files) > 0 {
+		var items []v1.KeyToPath
+		var files []string
+		for name := range spec.Files {
+			files = append(files, name)
+		}
+		sort.Strings(files)
+		for i, name := range files {
+			key := fmt.Sprintf("file-%d", i)
+			secret.Data[key] = spec.Files[name]
+			items = append(items, v1.KeyToPath{Key: key, Path: name})
+		}
+		volumes = append(volumes, v1.Volume{
+			Name: "files",
+			VolumeSource: v1.VolumeSource{
+				Secret: &v1.SecretVolumeSource{SecretName: spec.Name, Items: items},
+			},
+		})
+		container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{Name: "files", MountPath: spec.FilesPath, ReadOnly: true})
+	}
+	for i, path := range spec.EmptyDirs {
+		name := fmt.Sprintf("empty-%d", i)
+		volumes = append(volumes, v1.Volume{
+			Name:         name,
+			VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}},
+		})
+		container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{Name: name, MountPath: path})
+	}
+
+	backoffLimit := int32(0)
+	ttl := jobTTL
+	job := &batchv1.Job{
+		ObjectMeta: meta,
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            &backoffLimit,
+			TTLSecondsAfterFinished: &ttl,
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: spec.Labels},
+				Spec: v1.PodSpec{
+					RestartPolicy: v1.RestartPolicyNever,
+					Containers:    []v1.Container{container},
+					Volumes:       volumes,
+				},
+			},
+		},
+	}
+
+	return job, secret
+}
+
+// podStartError returns an error if the container of the pod is waiting for
+// a reason that it won't recover from (ex. its image can't be pulled)
+func podStartError(pod *v1.Pod, container string) error {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name != container || status.State.Waiting == nil {
+			continue
+		}
+		if podStartErrors[status.State.Waiting.Reason] {
+			return fmt.Errorf("The pod %s/%s can't start: %s %s", pod.Namespace, pod.Name, status.State.Waiting.Reason, status.State.Waiting.Message)
+		}
+	}
+	return nil
+}
+
+// containerExitCode returns the exit code of the container of the pod, and
+// false if it hasn't exited yet
+func containerExitCode(pod *v1.Pod, container string) (int32, bool) {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == container && status.State.Terminated != nil {
+			return status.State.Terminated.ExitCode, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,62 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestJobObjects(t *testing.T) {
+	job, secret := jobObjects(&JobSpec{
+		Namespace: "deploys",
+		Name:      "stim-deploy-api-x7k2p",
+		Image:     "premiereglobal/stim:deploy",
+		Command:   []string{"/bin/sh", "-c", "./deploy.sh"},
+		Env:       map[string]string{"VAULT_TOKEN": "s.secret", "DEPLOY_ENVIRONMENT": "prod"},
+		Files:     map[string][]byte{"deploy.tar.gz": []byte("bundle")},
+		FilesPath: "/stim/bundle",
+		EmptyDirs: []string{"/scripts"},
+	})
+
+	assert.Equal(t, *job.Spec.BackoffLimit, int32(0))
+	pod := job.Spec.Template.Spec
+	assert.Equal(t, pod.RestartPolicy, v1.RestartPolicyNever)
+
+	// Env vars are read from the Secret, never stored in the job
+	container := pod.Containers[0]
+	assert.Equal(t, len(container.Env), 2)
+	assert.Equal(t, container.Env[0].Name, "DEPLOY_ENVIRONMENT")
+	assert.Equal(t, container.Env[0].Value, "")
+	assert.Equal(t, container.Env[1].Name, "VAULT_TOKEN")
+	ref := container.Env[1].ValueFrom.SecretKeyRef
+	assert.Equal(t, ref.Name, "stim-deploy-api-x7k2p")
+	assert.Equal(t, string(secret.Data[ref.Key]), "s.secret")
+
+	assert.Equal(t, len(pod.Volumes), 2)
+	items := pod.Volumes[0].Secret.Items
+	assert.Equal(t, items[0].Path, "deploy.tar.gz")
+	assert.Equal(t, string(secret.Data[items[0].Key]), "bundle")
+	assert.Equal(t, container.VolumeMounts[0].MountPath, "/stim/bundle")
+	assert.Assert(t, pod.Volumes[1].EmptyDir != nil)
+	assert.Equal(t, container.VolumeMounts[1].MountPath, "/scripts")
+}
+
+func TestPodStatus(t *testing.T) {
+	pod := &v1.Pod{}
+	pod.Status.ContainerStatuses = []v1.ContainerStatus{{
+		Name:  jobContainer,
+		State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ContainerCreating"}},
+	}}
+	assert.NilError(t, podStartError(pod, jobContainer))
+	_, done := containerExitCode(pod, jobContainer)
+	assert.Assert(t, !done)
+
+	pod.Status.ContainerStatuses[0].State.Waiting.Reason = "ImagePullBackOff"
+	assert.ErrorContains(t, podStartError(pod, jobContainer), "ImagePullBackOff")
+
+	pod.Status.ContainerStatuses[0].State = v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 3}}
+	code, done := containerExitCode(pod, jobContainer)
+	assert.Assert(t, done)
+	assert.Equal(t, code, int32(3))
+}
@@ -1,12 +1,18 @@
 package kubernetes
 
 import (
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
 
 // Kubernetes represents a interface with a Kubernetes cluster
 type Kubernetes struct {
 	config *Config
+
+	// resources caches the discovered resources of each group version
+	resources map[string]*metav1.APIResourceList
 }
 
 // New returns a new Kubernetes object with the given config
@@ -34,3 +40,25 @@ func (k *Kubernetes) GetClientset() (*kubernetes.Clientset, error) {
 	}
 	return clientSet, nil
 }
+
+// ListNamespaces returns the names of the namespaces of the cluster, sorted
+func (k *Kubernetes) ListNamespaces() ([]string, error) {
+
+	clientSet, err := k.GetClientset()
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces, err := clientSet.CoreV1().Namespaces().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, namespace := range namespaces.Items {
+		names = append(names, namespace.Name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
@@ -0,0 +1,149 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// RestartedAtAnnotation is the pod template annotation set to restart a
+// rollout, the same as `kubectl rollout restart`
+const RestartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+// RolloutStatus returns whether the rollout of a Deployment or StatefulSet is
+// complete, and a message describing its progress.  An error is returned if
+// the rollout can't complete (ex. its progress deadline was exceeded).
+func (k *Kubernetes) RolloutStatus(kind string, namespace string, name string) (bool, string, error) {
+
+	clientSet, err := k.GetClientset()
+	if err != nil {
+		return false, "", err
+	}
+
+	switch kind {
+	case "Deployment":
+		deployment, err := clientSet.AppsV1().Deployments(namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return false, "", err
+		}
+		return deploymentRolloutStatus(deployment)
+	case "StatefulSet":
+		statefulSet, err := clientSet.AppsV1().StatefulSets(namespace).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return false, "", err
+		}
+		done, message := statefulSetRolloutStatus(statefulSet)
+		return done, message, nil
+	}
+
+	return false, "", fmt.Errorf("Rollout status is not supported for kind %s", kind)
+}
+
+// RolloutRestart restarts the pods of a Deployment or StatefulSet by setting
+// the restartedAt annotation of its pod template to the given time
+func (k *Kubernetes) RolloutRestart(kind string, namespace string, name string, at time.Time) error {
+
+	clientSet, err := k.GetClientset()
+	if err != nil {
+		return err
+	}
+
+	patch, err := restartPatch(at)
+	if err != nil {
+		return err
+	}
+
+	switch kind {
+	case "Deployment":
+		_, err = clientSet.AppsV1().Deployments(namespace).Patch(name, types.StrategicMergePatchType, patch)
+		return err
+	case "StatefulSet":
+		_, err = clientSet.AppsV1().StatefulSets(namespace).Patch(name, types.StrategicMergePatchType, patch)
+		return err
+	}
+
+	return fmt.Errorf("Rollout restart is not supported for kind %s", kind)
+}
+
+// restartPatch returns the patch that sets the restartedAt annotation
+func restartPatch(at time.Time) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{
+						RestartedAtAnnotation: at.Format(time.RFC3339),
+					},
+				},
+			},
+		},
+	})
+}
+
+// deploymentRolloutStatus follows the checks of `kubectl rollout status`
+func deploymentRolloutStatus(deployment *appsv1.Deployment) (bool, string, error) {
+
+	if deployment.Generation > deployment.Status.ObservedGeneration {
+		return false, "waiting for the deployment spec update to be observed", nil
+	}
+
+	for _, c := range deployment.Status.Conditions {
+		if c.Type == appsv1.DeploymentProgressing && c.Reason == "ProgressDeadlineExceeded" {
+			return false, "", fmt.Errorf("Deployment %s exceeded its progress deadline", deployment.Name)
+		}
+	}
+
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	status := deployment.Status
+	switch {
+	case status.UpdatedReplicas < replicas:
+		return false, fmt.Sprintf("%d of %d updated replicas", status.UpdatedReplicas, replicas), nil
+	case status.Replicas > status.UpdatedReplicas:
+		return false, fmt.Sprintf("%d old replicas pending termination", status.Replicas-status.UpdatedReplicas), nil
+	case status.AvailableReplicas < status.UpdatedReplicas:
+		return false, fmt.Sprintf("%d of %d updated replicas available", status.AvailableReplicas, status.UpdatedReplicas), nil
+	}
+
+	return true, fmt.Sprintf("%d replicas available", status.AvailableReplicas), nil
+}
+
+// statefulSetRolloutStatus follows the checks of `kubectl rollout status`
+func statefulSetRolloutStatus(statefulSet *appsv1.StatefulSet) (bool, string) {
+
+	if statefulSet.Status.ObservedGeneration == 0 || statefulSet.Generation > statefulSet.Status.ObservedGeneration {
+		return false, "waiting for the statefulset spec update to be observed"
+	}
+
+	replicas := int32(1)
+	if statefulSet.Spec.Replicas != nil {
+		replicas = *statefulSet.Spec.Replicas
+	}
+	status := statefulSet.Status
+	if status.ReadyReplicas < replicas {
+		return false, fmt.Sprintf("%d of %d replicas ready", status.ReadyReplicas, replicas)
+	}
+
+	strategy := statefulSet.Spec.UpdateStrategy
+	if strategy.Type == appsv1.RollingUpdateStatefulSetStrategyType && strategy.RollingUpdate != nil {
+		partition := strategy.RollingUpdate.Partition
+		if partition != nil && *partition > 0 {
+			if status.UpdatedReplicas < replicas-*partition {
+				return false, fmt.Sprintf("%d of %d partitioned replicas updated", status.UpdatedReplicas, replicas-*partition)
+			}
+			return true, fmt.Sprintf("%d partitioned replicas updated", status.UpdatedReplicas)
+		}
+	}
+
+	if status.UpdateRevision != status.CurrentRevision {
+		return false, fmt.Sprintf("%d of %d replicas updated", status.UpdatedReplicas, replicas)
+	}
+
+	return true, fmt.Sprintf("%d replicas ready", status.ReadyReplicas)
+}
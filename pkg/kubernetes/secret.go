@@ -0,0 +1,63 @@
+package kubernetes
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GetSecretData returns the decoded data of a Secret along with its type
+func (k *Kubernetes) GetSecretData(namespace string, name string) (map[string][]byte, string, error) {
+
+	clientSet, err := k.GetClientset()
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret, err := clientSet.CoreV1().Secrets(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return secret.Data, string(secret.Type), nil
+}
+
+// ApplyTLSSecret creates a TLS Secret with the given data (`tls.crt`,
+// `tls.key` and optionally `ca.crt`), or replaces the data if it already
+// exists.  The type of a Secret can't be changed, so an existing Secret of
+// another type is an error.
+func (k *Kubernetes) ApplyTLSSecret(namespace string, name string, data map[string][]byte) error {
+
+	clientSet, err := k.GetClientset()
+	if err != nil {
+		return err
+	}
+
+	secrets := clientSet.CoreV1().Secrets(namespace)
+
+	existing, err := secrets.Get(name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = secrets.Create(&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+			Type: v1.SecretTypeTLS,
+			Data: data,
+		})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if existing.Type != v1.SecretTypeTLS {
+		return fmt.Errorf("Secret %s/%s is of type %s, not %s", namespace, name, existing.Type, v1.SecretTypeTLS)
+	}
+	existing.Data = data
+
+	_, err = secrets.Update(existing)
+	return err
+}
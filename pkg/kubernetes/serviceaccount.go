@@ -0,0 +1,130 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/utils"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Attempts and interval of the wait for the token controller to fill in a new
+// service account token
+const (
+	tokenWaitAttempts = 30
+	tokenWaitInterval = time.Second
+)
+
+// ServiceAccountToken is a token of a service account stored in a Secret
+type ServiceAccountToken struct {
+	SecretName string
+	Token      string
+	CA         string
+}
+
+// CreateServiceAccountToken creates a service account token Secret for the
+// service account and waits for the token controller to fill in the token
+func (k *Kubernetes) CreateServiceAccountToken(namespace string, serviceAccount string) (*ServiceAccountToken, error) {
+
+	clientSet, err := k.GetClientset()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = clientSet.CoreV1().ServiceAccounts(namespace).Get(serviceAccount, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := clientSet.CoreV1().Secrets(namespace).Create(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: serviceAccount + "-token-",
+			Namespace:    namespace,
+			Annotations:  map[string]string{corev1.ServiceAccountNameKey: serviceAccount},
+		},
+		Type: corev1.SecretTypeServiceAccountToken,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	token := &ServiceAccountToken{SecretName: secret.Name}
+	err = utils.Retry(tokenWaitAttempts, tokenWaitInterval, func() error {
+		secret, err := clientSet.CoreV1().Secrets(namespace).Get(token.SecretName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if len(secret.Data[corev1.ServiceAccountTokenKey]) == 0 {
+			return errors.New("token not yet created")
+		}
+		token.Token = string(secret.Data[corev1.ServiceAccountTokenKey])
+		token.CA = string(secret.Data[corev1.ServiceAccountRootCAKey])
+		return nil
+	})
+	if err != nil {
+		// Don't leave an empty token secret behind
+		clientSet.CoreV1().Secrets(namespace).Delete(token.SecretName, &metav1.DeleteOptions{})
+		return nil, fmt.Errorf("Token of secret %s/%s was not created within %s: %v", namespace, token.SecretName, tokenWaitAttempts*tokenWaitInterval, err)
+	}
+
+	return token, nil
+}
+
+// FindServiceAccountTokenSecrets returns the names of the token Secrets of the
+// service account that hold the given token
+func (k *Kubernetes) FindServiceAccountTokenSecrets(namespace string, serviceAccount string, token string) ([]string, error) {
+
+	clientSet, err := k.GetClientset()
+	if err != nil {
+		return nil, err
+	}
+
+	secrets, err := clientSet.CoreV1().Secrets(namespace).List(metav1.ListOptions{
+		FieldSelector: "type=" + string(corev1.SecretTypeServiceAccountToken),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, secret := range secrets.Items {
+		if secret.Annotations[corev1.ServiceAccountNameKey] == serviceAccount && string(secret.Data[corev1.ServiceAccountTokenKey]) == token {
+			names = append(names, secret.Name)
+		}
+	}
+
+	return names, nil
+}
+
+// DeleteSecret deletes a Secret.  Deleting a service account token Secret
+// revokes its token.
+func (k *Kubernetes) DeleteSecret(namespace string, name string) error {
+
+	clientSet, err := k.GetClientset()
+	if err != nil {
+		return err
+	}
+
+	return clientSet.CoreV1().Secrets(namespace).Delete(name, &metav1.DeleteOptions{})
+}
+
+// CheckAuthenticated checks that the credentials of the config are accepted
+// by the cluster.  Unlike the version endpoint, access reviews can't be
+// created anonymously.
+func (k *Kubernetes) CheckAuthenticated() error {
+
+	clientSet, err := k.GetClientset()
+	if err != nil {
+		return err
+	}
+
+	_, err = clientSet.AuthorizationV1().SelfSubjectAccessReviews().Create(&authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			NonResourceAttributes: &authorizationv1.NonResourceAttributes{Path: "/version", Verb: "get"},
+		},
+	})
+	return err
+}
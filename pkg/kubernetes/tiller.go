@@ -0,0 +1,43 @@
+package kubernetes
+
+import (
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TillerVersion returns the version of the Helm v2 Tiller deployed in
+// kube-system, or an empty string if Tiller is not deployed
+func (k *Kubernetes) TillerVersion() (string, error) {
+
+	clientSet, err := k.GetClientset()
+	if err != nil {
+		return "", err
+	}
+
+	deployments, err := clientSet.AppsV1().Deployments("kube-system").List(metav1.ListOptions{LabelSelector: "app=helm,name=tiller"})
+	if err != nil {
+		return "", err
+	}
+
+	for _, deployment := range deployments.Items {
+		for _, container := range deployment.Spec.Template.Spec.Containers {
+			if version := imageTag(container.Image); version != "" {
+				return version, nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// imageTag returns the tag of an image reference, or an empty string if the
+// image is not tagged
+func imageTag(image string) string {
+	image = strings.SplitN(image, "@", 2)[0]
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return ""
+	}
+	return image[i+1:]
+}
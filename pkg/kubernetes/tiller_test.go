@@ -0,0 +1,14 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestImageTag(t *testing.T) {
+	assert.Equal(t, imageTag("gcr.io/kubernetes-helm/tiller:v2.16.1"), "v2.16.1")
+	assert.Equal(t, imageTag("registry:5000/tiller:v2.14.3@sha256:abc"), "v2.14.3")
+	assert.Equal(t, imageTag("registry:5000/tiller"), "")
+	assert.Equal(t, imageTag("tiller"), "")
+}
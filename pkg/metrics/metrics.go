@@ -0,0 +1,89 @@
+// Package metrics times the phases of a stim run (ex. Vault login, secret
+// fetching, tool downloads) and exports the timings to a Prometheus
+// Pushgateway or a StatsD server.
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/clock"
+)
+
+// Phase is the time spent in a phase of a run.  Phases that run more than
+// once (ex. a deploy to several instances) add up.
+type Phase struct {
+	Name     string
+	Count    int
+	Duration time.Duration
+}
+
+// Run is the timings of a stim command
+type Run struct {
+	Command  string
+	Result   string
+	Finished time.Time
+	Duration time.Duration
+	Phases   []Phase
+}
+
+// Timer records the time spent in each phase.  It is safe for concurrent
+// use.
+type Timer struct {
+	mu     sync.Mutex
+	clock  clock.Clock
+	phases []*Phase
+}
+
+// NewTimer returns a timer using the clock
+func NewTimer(c clock.Clock) *Timer {
+	return &Timer{clock: c}
+}
+
+// SetClock sets the clock of the timer
+func (t *Timer) SetClock(c clock.Clock) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.clock = c
+}
+
+// Start starts timing a phase and returns the function that stops it
+//
+//	defer timer.Start("vault.auth")()
+func (t *Timer) Start(name string) func() {
+	t.mu.Lock()
+	c := t.clock
+	t.mu.Unlock()
+
+	start := c.Now()
+	return func() {
+		t.Add(name, c.Now().Sub(start))
+	}
+}
+
+// Add adds the duration to a phase
+func (t *Timer) Add(name string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, phase := range t.phases {
+		if phase.Name == name {
+			phase.Count++
+			phase.Duration += d
+			return
+		}
+	}
+	t.phases = append(t.phases, &Phase{Name: name, Count: 1, Duration: d})
+}
+
+// Phases returns the timed phases in the order they first finished
+func (t *Timer) Phases() []Phase {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	phases := make([]Phase, len(t.phases))
+	for i, phase := range t.phases {
+		phases[i] = *phase
+	}
+	return phases
+}
@@ -0,0 +1,29 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/clock"
+	"gotest.tools/assert"
+)
+
+func TestTimer(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	timer := NewTimer(fake)
+
+	stop := timer.Start("vault.auth")
+	fake.Advance(2 * time.Second)
+	stop()
+
+	for i := 0; i < 2; i++ {
+		stop = timer.Start("secrets")
+		fake.Advance(500 * time.Millisecond)
+		stop()
+	}
+
+	assert.DeepEqual(t, timer.Phases(), []Phase{
+		{Name: "vault.auth", Count: 1, Duration: 2 * time.Second},
+		{Name: "secrets", Count: 2, Duration: time.Second},
+	})
+}
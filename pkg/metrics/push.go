@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// PushTimeout keeps a slow metrics endpoint from delaying stim
+const PushTimeout = 3 * time.Second
+
+// PushGateway pushes the run to a Prometheus Pushgateway, grouped by job and
+// instance.  Pushing replaces the metrics of the previous run of the same
+// command from the instance.
+func PushGateway(gatewayURL string, job string, instance string, run *Run) error {
+
+	pushURL := fmt.Sprintf("%s/metrics/job/%s/instance/%s", strings.TrimSuffix(gatewayURL, "/"), url.PathEscape(job), url.PathEscape(instance))
+	client := &http.Client{Timeout: PushTimeout}
+	resp, err := client.Post(pushURL, "text/plain; version=0.0.4", bytes.NewBufferString(PrometheusText(run)))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s returned %s", pushURL, resp.Status)
+	}
+	return nil
+}
+
+// PrometheusText returns the run in the Prometheus text exposition format
+func PrometheusText(run *Run) string {
+
+	command := escapeLabel(run.Command)
+	var b strings.Builder
+	fmt.Fprintln(&b, "# HELP stim_run_duration_seconds Duration of the last run of the stim command")
+	fmt.Fprintln(&b, "# TYPE stim_run_duration_seconds gauge")
+	fmt.Fprintf(&b, "stim_run_duration_seconds{command=\"%s\",result=\"%s\"} %g\n", command, escapeLabel(run.Result), run.Duration.Seconds())
+	fmt.Fprintln(&b, "# HELP stim_run_timestamp_seconds Time the last run of the stim command finished")
+	fmt.Fprintln(&b, "# TYPE stim_run_timestamp_seconds gauge")
+	fmt.Fprintf(&b, "stim_run_timestamp_seconds{command=\"%s\"} %d\n", command, run.Finished.Unix())
+	fmt.Fprintln(&b, "# HELP stim_phase_duration_seconds Time spent in a phase of the last run of the stim command")
+	fmt.Fprintln(&b, "# TYPE stim_phase_duration_seconds gauge")
+	for _, phase := range run.Phases {
+		fmt.Fprintf(&b, "stim_phase_duration_seconds{command=\"%s\",phase=\"%s\"} %g\n", command, escapeLabel(phase.Name), phase.Duration.Seconds())
+	}
+	fmt.Fprintln(&b, "# HELP stim_phase_count Number of times a phase ran in the last run of the stim command")
+	fmt.Fprintln(&b, "# TYPE stim_phase_count gauge")
+	for _, phase := range run.Phases {
+		fmt.Fprintf(&b, "stim_phase_count{command=\"%s\",phase=\"%s\"} %d\n", command, escapeLabel(phase.Name), phase.Count)
+	}
+
+	return b.String()
+}
+
+// StatsD sends the run to a StatsD server (`host:port`) over UDP
+func StatsD(address string, prefix string, run *Run) error {
+
+	conn, err := net.DialTimeout("udp", address, PushTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// One metric per packet keeps each packet small enough for any server
+	for _, line := range StatsDLines(prefix, run) {
+		_, err = conn.Write([]byte(line))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StatsDLines returns the StatsD metrics of the run, named
+// `<prefix>.<command>.duration`, `<prefix>.<command>.<result>` and
+// `<prefix>.<command>.phase.<phase>` (ex. `stim.deploy.phase.vault.auth`)
+func StatsDLines(prefix string, run *Run) []string {
+
+	name := prefix + "." + statsDName(strings.TrimPrefix(run.Command, "stim "))
+	lines := []string{
+		fmt.Sprintf("%s.duration:%d|ms", name, run.Duration.Milliseconds()),
+		fmt.Sprintf("%s.%s:1|c", name, statsDName(run.Result)),
+	}
+	for _, phase := range run.Phases {
+		lines = append(lines, fmt.Sprintf("%s.phase.%s:%d|ms", name, statsDName(phase.Name), phase.Duration.Milliseconds()))
+	}
+	return lines
+}
+
+// escapeLabel escapes a Prometheus label value
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// statsDName replaces the characters that StatsD uses as separators
+func statsDName(name string) string {
+	return strings.NewReplacer(" ", ".", ":", "_", "|", "_", "@", "_").Replace(name)
+}
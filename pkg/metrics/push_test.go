@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+var testRun = &Run{
+	Command:  "stim deploy",
+	Result:   "success",
+	Finished: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC),
+	Duration: 12500 * time.Millisecond,
+	Phases: []Phase{
+		{Name: "vault.auth", Count: 1, Duration: 1500 * time.Millisecond},
+		{Name: "secrets", Count: 2, Duration: 250 * time.Millisecond},
+	},
+}
+
+func TestPrometheusText(t *testing.T) {
+	assert.Equal(t, PrometheusText(testRun), `# HELP stim_run_duration_seconds Duration of the last run of the stim command
+# TYPE stim_run_duration_seconds gauge
+stim_run_duration_seconds{command="stim deploy",result="success"} 12.5
+# HELP stim_run_timestamp_seconds Time the last run of the stim command finished
+# TYPE stim_run_timestamp_seconds gauge
+stim_run_timestamp_seconds{command="stim deploy"} 1717243200
+# HELP stim_phase_duration_seconds Time spent in a phase of the last run of the stim command
+# TYPE stim_phase_duration_seconds gauge
+stim_phase_duration_seconds{command="stim deploy",phase="vault.auth"} 1.5
+stim_phase_duration_seconds{command="stim deploy",phase="secrets"} 0.25
+# HELP stim_phase_count Number of times a phase ran in the last run of the stim command
+# TYPE stim_phase_count gauge
+stim_phase_count{command="stim deploy",phase="vault.auth"} 1
+stim_phase_count{command="stim deploy",phase="secrets"} 2
+`)
+}
+
+func TestPushGateway(t *testing.T) {
+	var path, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer server.Close()
+
+	assert.NilError(t, PushGateway(server.URL+"/", "stim", "laptop-1", testRun))
+	assert.Equal(t, path, "/metrics/job/stim/instance/laptop-1")
+	assert.Equal(t, body, PrometheusText(testRun))
+}
+
+func TestStatsDLines(t *testing.T) {
+	assert.DeepEqual(t, StatsDLines("stim", testRun), []string{
+		"stim.deploy.duration:12500|ms",
+		"stim.deploy.success:1|c",
+		"stim.deploy.phase.vault.auth:1500|ms",
+		"stim.deploy.phase.secrets:250|ms",
+	})
+}
@@ -0,0 +1,270 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/certs"
+)
+
+// Default templates of the email backend.  The templates get the emailData of
+// the notification.
+const (
+	defaultEmailSubject = `{{.Title}}`
+
+	defaultEmailText = `{{.Title}}
+{{if .Text}}
+{{.Text}}
+{{end}}
+{{range .FieldNames}}{{.}}: {{index $.Fields .}}
+{{end}}`
+
+	defaultEmailHTML = `<html><body>
+<h3 style="color: #{{.Color}}">{{.Title}}</h3>
+{{if .Text}}<p>{{.Text}}</p>{{end}}
+<table>
+{{range .FieldNames}}<tr><td><b>{{.}}</b></td><td>{{index $.Fields .}}</td></tr>
+{{end}}</table>
+</body></html>`
+)
+
+// smtpTimeout keeps an unreachable mail server from blocking the command
+const smtpTimeout = 10 * time.Second
+
+// EmailSender sends a MIME message to the recipients
+type EmailSender interface {
+	SendEmail(from string, to []string, message []byte) error
+}
+
+// EmailBackend emails notifications with a plain text and an HTML body
+type EmailBackend struct {
+	from    string
+	to      []string
+	subject *template.Template
+	text    *template.Template
+	html    *htmltemplate.Template
+	sender  EmailSender
+}
+
+// emailData is passed to the email templates
+type emailData struct {
+	Event      string
+	Title      string
+	Text       string
+	Status     string
+	Color      string
+	Fields     map[string]string
+	FieldNames []string
+}
+
+// NewEmailBackend returns an email backend that sends with the given sender.
+// The `from` and `to` (comma separated) options are required.  The
+// `subject`, `text-template` and `html-template` options replace the default
+// templates and are Go templates with the `Event`, `Title`, `Text`, `Status`
+// and `Fields` of the notification.
+func NewEmailBackend(options map[string]string, sender EmailSender) (*EmailBackend, error) {
+
+	if options["from"] == "" || options["to"] == "" {
+		return nil, errors.New("Email notifications require the `from` and `to` options")
+	}
+
+	e := &EmailBackend{from: options["from"], sender: sender}
+	for _, to := range strings.Split(options["to"], ",") {
+		if to = strings.TrimSpace(to); to != "" {
+			e.to = append(e.to, to)
+		}
+	}
+
+	var err error
+	e.subject, err = template.New("subject").Parse(optionOrDefault(options, "subject", defaultEmailSubject))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid email subject template: %v", err)
+	}
+	e.text, err = template.New("text").Parse(optionOrDefault(options, "text-template", defaultEmailText))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid email text template: %v", err)
+	}
+	e.html, err = htmltemplate.New("html").Parse(optionOrDefault(options, "html-template", defaultEmailHTML))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid email HTML template: %v", err)
+	}
+
+	return e, nil
+}
+
+// Notify emails the payload to the recipients
+func (e *EmailBackend) Notify(event string, payload *Payload) error {
+
+	message, err := e.message(event, payload, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return e.sender.SendEmail(e.from, e.to, message)
+}
+
+// message renders the templates into a multipart/alternative MIME message
+func (e *EmailBackend) message(event string, payload *Payload, date time.Time) ([]byte, error) {
+
+	data := &emailData{
+		Event:      event,
+		Title:      payload.Title,
+		Text:       payload.Text,
+		Status:     payload.Status,
+		Color:      teamsColors[payload.Status],
+		Fields:     payload.Fields,
+		FieldNames: sortedFields(payload.Fields),
+	}
+
+	var subject, text, html bytes.Buffer
+	err := e.subject.Execute(&subject, data)
+	if err == nil {
+		err = e.text.Execute(&text, data)
+	}
+	if err == nil {
+		err = e.html.Execute(&html, data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to render the email: %v", err)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     []byte
+	}{
+		{"text/plain; charset=utf-8", text.Bytes()},
+		{"text/html; charset=utf-8", html.Bytes()},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		qp.Write(part.content)
+		qp.Close()
+	}
+	mw.Close()
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", e.from)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(e.to, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.TrimSpace(subject.String())))
+	fmt.Fprintf(&message, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	fmt.Fprintf(&message, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+	message.Write(body.Bytes())
+
+	return message.Bytes(), nil
+}
+
+// SMTPSender sends email through an SMTP server
+type SMTPSender struct {
+	host     string
+	addr     string
+	username string
+	password string
+	startTLS bool
+}
+
+// NewSMTPSender returns an SMTP sender.  The `smtp-host` option is required.
+// `smtp-port` defaults to 587 and STARTTLS is required unless `smtp-starttls`
+// is `false`.  `smtp-username` and `smtp-password` log in to the server.
+func NewSMTPSender(options map[string]string) (*SMTPSender, error) {
+
+	if options["smtp-host"] == "" {
+		return nil, errors.New("SMTP email notifications require the `smtp-host` option")
+	}
+
+	return &SMTPSender{
+		host:     options["smtp-host"],
+		addr:     net.JoinHostPort(options["smtp-host"], optionOrDefault(options, "smtp-port", "587")),
+		username: options["smtp-username"],
+		password: options["smtp-password"],
+		startTLS: options["smtp-starttls"] != "false",
+	}, nil
+}
+
+// SendEmail sends the message, upgrading the connection with STARTTLS
+func (s *SMTPSender) SendEmail(from string, to []string, message []byte) error {
+
+	conn, err := net.DialTimeout("tcp", s.addr, smtpTimeout)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	c, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if s.startTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("SMTP server %s does not support STARTTLS", s.addr)
+		}
+		err = c.StartTLS(&tls.Config{ServerName: s.host, RootCAs: certs.RootCAs()})
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.username != "" {
+		err = c.Auth(smtp.PlainAuth("", s.username, s.password, s.host))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = c.Mail(from)
+	if err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		err = c.Rcpt(rcpt)
+		if err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(message)
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+// optionOrDefault returns the option or the default if it is not set
+func optionOrDefault(options map[string]string, key string, defaultValue string) string {
+	if value, ok := options[key]; ok && value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,121 @@
+package notify
+
+import (
+	"errors"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Payload statuses, used by backends for colors/emoji
+const (
+	StatusInfo    = "info"
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+)
+
+// Payload is the content of a notification
+type Payload struct {
+	// Title is a short, one line summary
+	Title string
+
+	// Text is an optional longer description
+	Text string
+
+	// Status is one of StatusInfo, StatusSuccess or StatusFailure
+	Status string
+
+	// Fields are additional key/value details
+	Fields map[string]string
+
+	// Thread groups related notifications (ex. the start and end of a deploy)
+	// so that backends that support threads can reply to the first one
+	Thread string
+}
+
+// Backend sends notifications to a single destination
+type Backend interface {
+	Notify(event string, payload *Payload) error
+}
+
+// BackendConfig configures a backend.  Options are specific to the backend
+// type (ex. `channel` for Slack or `url` for webhooks).
+type BackendConfig struct {
+	Name    string            `mapstructure:"name" yaml:"name"`
+	Type    string            `mapstructure:"type" yaml:"type"`
+	Events  []string          `mapstructure:"events" yaml:"events"`
+	Options map[string]string `mapstructure:"options" yaml:"options"`
+}
+
+// route is a backend and the events that are sent to it
+type route struct {
+	name    string
+	events  []string
+	backend Backend
+}
+
+// Router sends notifications to every backend whose events match
+type Router struct {
+	routes []*route
+}
+
+// NewRouter returns an empty Router
+func NewRouter() *Router {
+	return &Router{}
+}
+
+// Add adds a backend to the router.  Events are patterns (ex. `deploy.*`),
+// all events are sent to the backend if none are given.
+func (r *Router) Add(name string, events []string, backend Backend) {
+	r.routes = append(r.routes, &route{name: name, events: events, backend: backend})
+}
+
+// Len returns the number of backends
+func (r *Router) Len() int {
+	return len(r.routes)
+}
+
+// Notify sends the event to all matching backends.  Every backend is tried
+// even if some fail.
+func (r *Router) Notify(event string, payload *Payload) error {
+
+	var failures []string
+	for _, route := range r.routes {
+		if !matchEvent(route.events, event) {
+			continue
+		}
+		err := route.backend.Notify(event, payload)
+		if err != nil {
+			failures = append(failures, route.name+": "+err.Error())
+		}
+	}
+
+	if len(failures) > 0 {
+		return errors.New("Notification failed for " + strings.Join(failures, ", "))
+	}
+
+	return nil
+}
+
+// matchEvent returns true if the event matches one of the patterns
+func matchEvent(patterns []string, event string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, event); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedFields returns the field names in a consistent order
+func sortedFields(fields map[string]string) []string {
+	var names []string
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,215 @@
+package notify
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+type recordingBackend struct {
+	events []string
+	err    error
+}
+
+func (r *recordingBackend) Notify(event string, payload *Payload) error {
+	r.events = append(r.events, event)
+	return r.err
+}
+
+func TestRouterMatchesEvents(t *testing.T) {
+	all := &recordingBackend{}
+	deploys := &recordingBackend{}
+	failures := &recordingBackend{}
+
+	router := NewRouter()
+	router.Add("all", nil, all)
+	router.Add("deploys", []string{"deploy.*"}, deploys)
+	router.Add("failures", []string{"deploy.failure", "kube.certs.*"}, failures)
+
+	for _, event := range []string{"deploy.start", "deploy.failure", "kube.certs.critical"} {
+		assert.NilError(t, router.Notify(event, &Payload{Title: event}))
+	}
+
+	assert.DeepEqual(t, all.events, []string{"deploy.start", "deploy.failure", "kube.certs.critical"})
+	assert.DeepEqual(t, deploys.events, []string{"deploy.start", "deploy.failure"})
+	assert.DeepEqual(t, failures.events, []string{"deploy.failure", "kube.certs.critical"})
+}
+
+func TestRouterTriesAllBackends(t *testing.T) {
+	failing := &recordingBackend{err: errors.New("boom")}
+	working := &recordingBackend{}
+
+	router := NewRouter()
+	router.Add("failing", nil, failing)
+	router.Add("working", nil, working)
+
+	err := router.Notify("deploy.start", &Payload{Title: "test"})
+	assert.Error(t, err, "Notification failed for failing: boom")
+	assert.DeepEqual(t, working.events, []string{"deploy.start"})
+}
+
+func TestWebhookBackend(t *testing.T) {
+	var body webhookBody
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&body)
+	}))
+	defer server.Close()
+
+	backend, err := NewWebhookBackend(map[string]string{"url": server.URL, "header-authorization": "Bearer token"})
+	assert.NilError(t, err)
+
+	err = backend.Notify("deploy.success", &Payload{Title: "Deployed", Status: StatusSuccess, Fields: map[string]string{"instance": "us-west-2"}})
+	assert.NilError(t, err)
+	assert.Equal(t, auth, "Bearer token")
+	assert.Equal(t, body.Event, "deploy.success")
+	assert.Equal(t, body.Status, StatusSuccess)
+	assert.Equal(t, body.Fields["instance"], "us-west-2")
+
+	_, err = NewWebhookBackend(map[string]string{})
+	assert.Error(t, err, "Webhook notifications require the `url` option")
+}
+
+type recordingSender struct {
+	from    string
+	to      []string
+	message []byte
+}
+
+func (r *recordingSender) SendEmail(from string, to []string, message []byte) error {
+	r.from, r.to, r.message = from, to, message
+	return nil
+}
+
+func TestEmailBackend(t *testing.T) {
+	sender := &recordingSender{}
+	backend, err := NewEmailBackend(map[string]string{
+		"from":    "stim@my-domain.com",
+		"to":      "sre@my-domain.com, release@my-domain.com",
+		"subject": "[{{.Status}}] {{.Title}}",
+	}, sender)
+	assert.NilError(t, err)
+
+	err = backend.Notify("deploy.failure", &Payload{Title: "Deploy failed", Text: "exit status 1", Status: StatusFailure, Fields: map[string]string{"instance": "prod1"}})
+	assert.NilError(t, err)
+	assert.Equal(t, sender.from, "stim@my-domain.com")
+	assert.DeepEqual(t, sender.to, []string{"sre@my-domain.com", "release@my-domain.com"})
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(sender.message)))
+	assert.NilError(t, err)
+	assert.Equal(t, msg.Header.Get("Subject"), "[failure] Deploy failed")
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	assert.NilError(t, err)
+	assert.Equal(t, mediaType, "multipart/alternative")
+
+	var parts []string
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		b, err := ioutil.ReadAll(part)
+		assert.NilError(t, err)
+		parts = append(parts, string(b))
+	}
+	assert.Equal(t, len(parts), 2)
+	assert.Assert(t, strings.Contains(parts[0], "exit status 1"))
+	assert.Assert(t, strings.Contains(parts[0], "instance: prod1"))
+	assert.Assert(t, strings.Contains(parts[1], "<b>instance</b>"))
+
+	_, err = NewEmailBackend(map[string]string{"from": "stim@my-domain.com"}, sender)
+	assert.Error(t, err, "Email notifications require the `from` and `to` options")
+}
+
+func TestSMTPSender(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	defer listener.Close()
+
+	// A minimal SMTP server that records the commands and message
+	received := make(chan []string, 2)
+	serve := func(conn net.Conn) {
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		var lines []string
+		conn.Write([]byte("220 localhost\r\n"))
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				break
+			}
+			line = strings.TrimRight(line, "\r\n")
+			lines = append(lines, line)
+			switch {
+			case strings.HasPrefix(line, "EHLO"):
+				conn.Write([]byte("250 localhost\r\n"))
+			case line == "DATA":
+				conn.Write([]byte("354 go ahead\r\n"))
+				for {
+					data, _ := r.ReadString('\n')
+					if data == ".\r\n" || data == "" {
+						break
+					}
+					lines = append(lines, strings.TrimRight(data, "\r\n"))
+				}
+				conn.Write([]byte("250 ok\r\n"))
+			case line == "QUIT":
+				conn.Write([]byte("221 bye\r\n"))
+				received <- lines
+				return
+			default:
+				conn.Write([]byte("250 ok\r\n"))
+			}
+		}
+		received <- lines
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	sender, err := NewSMTPSender(map[string]string{"smtp-host": host, "smtp-port": port, "smtp-starttls": "false"})
+	assert.NilError(t, err)
+
+	err = sender.SendEmail("stim@my-domain.com", []string{"sre@my-domain.com"}, []byte("Subject: test\r\n\r\nhello\r\n"))
+	assert.NilError(t, err)
+
+	lines := <-received
+	assert.Assert(t, contains(lines, "MAIL FROM:<stim@my-domain.com>"), lines)
+	assert.Assert(t, contains(lines, "RCPT TO:<sre@my-domain.com>"), lines)
+	assert.Assert(t, contains(lines, "hello"), lines)
+
+	sender, err = NewSMTPSender(map[string]string{"smtp-host": host, "smtp-port": port})
+	assert.NilError(t, err)
+	err = sender.SendEmail("stim@my-domain.com", []string{"sre@my-domain.com"}, nil)
+	assert.ErrorContains(t, err, "does not support STARTTLS")
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
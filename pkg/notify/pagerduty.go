@@ -0,0 +1,44 @@
+package notify
+
+import (
+	"errors"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/pagerduty"
+)
+
+// PagerdutyBackend sends notifications to a Pagerduty service as change
+// events.  Change events are informational and never open incidents.
+type PagerdutyBackend struct {
+	client  *pagerduty.Pagerduty
+	service string
+}
+
+// NewPagerdutyBackend returns a Pagerduty backend.  The `service` option
+// (required) is the name of the Pagerduty service.
+func NewPagerdutyBackend(client *pagerduty.Pagerduty, options map[string]string) (*PagerdutyBackend, error) {
+	if options["service"] == "" {
+		return nil, errors.New("Pagerduty notifications require the `service` option")
+	}
+
+	return &PagerdutyBackend{client: client, service: options["service"]}, nil
+}
+
+// Notify sends the payload as a change event
+func (p *PagerdutyBackend) Notify(event string, payload *Payload) error {
+
+	details := map[string]interface{}{"event": event}
+	for name, value := range payload.Fields {
+		details[name] = value
+	}
+	if payload.Text != "" {
+		details["text"] = payload.Text
+	}
+
+	return p.client.SendChangeEvent(&pagerduty.ChangeEvent{
+		Service:   p.service,
+		Summary:   payload.Title,
+		Timestamp: time.Now(),
+		Details:   details,
+	})
+}
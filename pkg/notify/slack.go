@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"errors"
+
+	"github.com/PremiereGlobal/stim/pkg/slack"
+	slackapi "github.com/nlopes/slack"
+)
+
+// slackColors are the attachment colors of each status
+var slackColors = map[string]string{
+	StatusInfo:    "#439FE0",
+	StatusSuccess: "good",
+	StatusFailure: "danger",
+}
+
+// SlackBackend posts notifications to a Slack channel
+type SlackBackend struct {
+	client   *slack.Slack
+	channel  string
+	username string
+	iconURL  string
+	threads  map[string]string
+}
+
+// NewSlackBackend returns a Slack backend.  Options are `channel` (required),
+// `username` and `icon-url`.
+func NewSlackBackend(client *slack.Slack, options map[string]string) (*SlackBackend, error) {
+	if options["channel"] == "" {
+		return nil, errors.New("Slack notifications require the `channel` option")
+	}
+
+	return &SlackBackend{
+		client:   client,
+		channel:  options["channel"],
+		username: options["username"],
+		iconURL:  options["icon-url"],
+		threads:  make(map[string]string),
+	}, nil
+}
+
+// Notify posts the payload as a colored attachment.  Later payloads in the
+// same thread are posted as replies, failures are also broadcast to the channel.
+func (s *SlackBackend) Notify(event string, payload *Payload) error {
+
+	attachment := slackapi.Attachment{
+		Color:    slackColors[payload.Status],
+		Title:    payload.Title,
+		Text:     payload.Text,
+		Fallback: payload.Title,
+	}
+	for _, name := range sortedFields(payload.Fields) {
+		attachment.Fields = append(attachment.Fields, slackapi.AttachmentField{Title: name, Value: payload.Fields[name], Short: true})
+	}
+
+	threadTS := ""
+	if payload.Thread != "" {
+		threadTS = s.threads[payload.Thread]
+	}
+
+	ts, err := s.client.PostMessage(&slack.Message{
+		Channel:     s.channel,
+		Username:    s.username,
+		IconUrl:     s.iconURL,
+		Attachments: []slackapi.Attachment{attachment},
+		ThreadTS:    threadTS,
+		Broadcast:   payload.Status == StatusFailure,
+	})
+	if err != nil {
+		return err
+	}
+
+	if payload.Thread != "" && threadTS == "" {
+		s.threads[payload.Thread] = ts
+	}
+
+	return nil
+}
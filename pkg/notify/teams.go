@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"errors"
+)
+
+// teamsColors are the card theme colors of each status
+var teamsColors = map[string]string{
+	StatusInfo:    "439FE0",
+	StatusSuccess: "2EB886",
+	StatusFailure: "A30200",
+}
+
+// TeamsBackend posts notifications to a Microsoft Teams incoming webhook
+type TeamsBackend struct {
+	url string
+}
+
+// NewTeamsBackend returns a Teams backend.  The `url` option (required) is
+// the incoming webhook URL of the channel.
+func NewTeamsBackend(options map[string]string) (*TeamsBackend, error) {
+	if options["url"] == "" {
+		return nil, errors.New("Teams notifications require the `url` option")
+	}
+
+	return &TeamsBackend{url: options["url"]}, nil
+}
+
+// Notify posts the payload as a message card
+func (t *TeamsBackend) Notify(event string, payload *Payload) error {
+
+	var facts []map[string]string
+	for _, name := range sortedFields(payload.Fields) {
+		facts = append(facts, map[string]string{"name": name, "value": payload.Fields[name]})
+	}
+
+	card := map[string]interface{}{
+		"@type":      "MessageCard",
+		"@context":   "http://schema.org/extensions",
+		"themeColor": teamsColors[payload.Status],
+		"summary":    payload.Title,
+		"title":      payload.Title,
+		"text":       payload.Text,
+		"sections":   []map[string]interface{}{{"facts": facts}},
+	}
+
+	return postJSON(t.url, nil, card)
+}
@@ -0,0 +1,98 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// webhookHeaderPrefix is the option prefix for additional request headers
+const webhookHeaderPrefix = "header-"
+
+// httpClient is used for webhook and Teams requests.  The timeout keeps a hung
+// endpoint from blocking the command sending the notification.
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// WebhookBackend posts notifications as JSON to a URL
+type WebhookBackend struct {
+	url     string
+	headers map[string]string
+}
+
+// webhookBody is the JSON body posted by the webhook backend
+type webhookBody struct {
+	Event     string            `json:"event"`
+	Title     string            `json:"title"`
+	Text      string            `json:"text,omitempty"`
+	Status    string            `json:"status"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	Thread    string            `json:"thread,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// NewWebhookBackend returns a webhook backend.  The `url` option is required
+// and options starting with `header-` are sent as request headers (ex.
+// `header-authorization`).
+func NewWebhookBackend(options map[string]string) (*WebhookBackend, error) {
+	if options["url"] == "" {
+		return nil, errors.New("Webhook notifications require the `url` option")
+	}
+
+	headers := make(map[string]string)
+	for key, value := range options {
+		if strings.HasPrefix(key, webhookHeaderPrefix) {
+			headers[strings.TrimPrefix(key, webhookHeaderPrefix)] = value
+		}
+	}
+
+	return &WebhookBackend{url: options["url"], headers: headers}, nil
+}
+
+// Notify posts the event and payload
+func (w *WebhookBackend) Notify(event string, payload *Payload) error {
+	return postJSON(w.url, w.headers, &webhookBody{
+		Event:     event,
+		Title:     payload.Title,
+		Text:      payload.Text,
+		Status:    payload.Status,
+		Fields:    payload.Fields,
+		Thread:    payload.Thread,
+		Timestamp: time.Now().UTC(),
+	})
+}
+
+// postJSON posts the body as JSON and returns an error for non-2xx responses
+func postJSON(url string, headers map[string]string, body interface{}) error {
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("HTTP Status Code: %d, Message: %s", resp.StatusCode, string(respBody))
+	}
+
+	return nil
+}
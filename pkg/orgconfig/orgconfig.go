@@ -0,0 +1,325 @@
+// Package orgconfig fetches the org-managed stim config.  The config is a YAML
+// document served over HTTPS and signed with an Ed25519 key: the signature of
+// `<url>` is served base64 encoded at `<url>.sig`.  Verified documents are
+// cached and re-fetched once the cache is older than the refresh interval.
+// The cache is verified whenever it is read and is used when the endpoint
+// can't be reached.
+package orgconfig
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/clock"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// SignatureSuffix is appended to the config URL to get its signature
+const SignatureSuffix = ".sig"
+
+// DefaultRefreshInterval is how often the config is re-fetched when no
+// interval is set
+const DefaultRefreshInterval = time.Hour
+
+// DefaultTimeout keeps a slow endpoint from delaying stim
+const DefaultTimeout = 3 * time.Second
+
+// Config contains the org config source
+type Config struct {
+
+	// URL of the config.  Must be HTTPS
+	URL string
+
+	// PublicKey is the PEM encoded Ed25519 public key the config is signed with
+	PublicKey string
+
+	// CacheFile is where the verified config is cached
+	CacheFile string
+
+	// RefreshInterval is how long the cached config is used before it is
+	// fetched again.  Defaults to DefaultRefreshInterval
+	RefreshInterval time.Duration
+
+	// Timeout of the requests.  Defaults to DefaultTimeout
+	Timeout time.Duration
+
+	// Client to fetch the config with.  Defaults to a client with the Timeout
+	Client *http.Client
+
+	// Refresh fetches the config even if the cache is fresh
+	Refresh bool
+
+	// Offline uses the cached config even if it is stale and never fetches it
+	Offline bool
+
+	// Clock defaults to the system clock
+	Clock clock.Clock
+}
+
+// Overlay is the org config
+type Overlay struct {
+
+	// Settings are the default values of stim config options, nested by the
+	// dot separated key parts (ex. `vault-address` or `stimpacks.ssh.enabled`)
+	Settings map[string]interface{} `yaml:"settings"`
+
+	// Locked are the keys of Settings that can't be overridden by the user
+	// config, environment variables or flags
+	Locked []string `yaml:"locked"`
+
+	// FetchedAt is when the config was fetched
+	FetchedAt time.Time `yaml:"-"`
+
+	// RefreshError is set when the config couldn't be refreshed and the cached
+	// config was used instead
+	RefreshError error `yaml:"-"`
+}
+
+// cacheEntry is the cached config and its signature
+type cacheEntry struct {
+	FetchedAt time.Time `json:"fetchedAt"`
+	URL       string    `json:"url"`
+	Config    []byte    `json:"config"`
+	Signature []byte    `json:"signature"`
+}
+
+// Load returns the org config, fetching it if the cache is missing, stale or
+// for a different URL.  If the fetch fails the cached config is returned with
+// its RefreshError set, an error is only returned when there is no valid
+// cached config.
+func Load(config *Config) (*Overlay, error) {
+
+	if !strings.HasPrefix(config.URL, "https://") {
+		return nil, fmt.Errorf("The org config URL must be HTTPS: %s", config.URL)
+	}
+	if _, err := url.Parse(config.URL); err != nil {
+		return nil, fmt.Errorf("Invalid org config URL '%s': %v", config.URL, err)
+	}
+	publicKey, err := ParsePublicKey(config.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	c := config.Clock
+	if c == nil {
+		c = clock.New()
+	}
+	interval := config.RefreshInterval
+	if interval <= 0 {
+		interval = DefaultRefreshInterval
+	}
+
+	cached, cacheErr := readCache(config.CacheFile, config.URL, publicKey)
+	if cached != nil && (config.Offline || !config.Refresh && c.Now().Sub(cached.FetchedAt) < interval) {
+		return parseEntry(cached)
+	}
+	if config.Offline {
+		if cacheErr != nil {
+			return nil, cacheErr
+		}
+		return nil, errors.New("The org config is not cached")
+	}
+
+	entry, err := fetch(config, publicKey)
+	if err != nil {
+		if cached == nil {
+			if cacheErr != nil {
+				return nil, fmt.Errorf("%v (and the cache is unusable: %v)", err, cacheErr)
+			}
+			return nil, err
+		}
+		overlay, parseErr := parseEntry(cached)
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		overlay.RefreshError = err
+		return overlay, nil
+	}
+	entry.FetchedAt = c.Now()
+
+	overlay, err := parseEntry(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	// A cache that can't be written only costs a fetch on the next run
+	b, _ := json.Marshal(entry)
+	ioutil.WriteFile(config.CacheFile, b, 0600)
+
+	return overlay, nil
+}
+
+// ParsePublicKey parses a PEM encoded (`openssl pkey -pubout`) Ed25519 public
+// key.  The base64 body of the PEM block (without the header and footer) is
+// accepted too, for environment variables.
+func ParsePublicKey(key string) (ed25519.PublicKey, error) {
+
+	var der []byte
+	if block, _ := pem.Decode([]byte(key)); block != nil {
+		der = block.Bytes
+	} else {
+		var err error
+		der, err = base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+		if err != nil {
+			return nil, errors.New("The org config public key is not PEM encoded")
+		}
+	}
+	parsed, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid org config public key: %v", err)
+	}
+	publicKey, ok := parsed.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.New("The org config public key is not an Ed25519 key")
+	}
+
+	return publicKey, nil
+}
+
+// Verify checks the signature of the config
+func Verify(publicKey ed25519.PublicKey, config []byte, signature []byte) error {
+	if !ed25519.Verify(publicKey, config, signature) {
+		return errors.New("The org config signature is invalid")
+	}
+	return nil
+}
+
+// Parse parses a config document
+func Parse(config []byte) (*Overlay, error) {
+
+	overlay := &Overlay{}
+	err := yaml.Unmarshal(config, overlay)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse the org config: %v", err)
+	}
+
+	for _, key := range overlay.Locked {
+		if !hasKey(overlay.Settings, strings.Split(key, ".")) {
+			return nil, fmt.Errorf("The org config locks '%s', which it doesn't set", key)
+		}
+	}
+
+	return overlay, nil
+}
+
+// Flatten returns the leaf values of the settings keyed by their dot
+// separated names.  Lists are returned as a single value.
+func (o *Overlay) Flatten() map[string]interface{} {
+	values := make(map[string]interface{})
+	flatten(o.Settings, "", values)
+	return values
+}
+
+// fetch downloads the config and its signature and verifies them
+func fetch(config *Config, publicKey ed25519.PublicKey) (*cacheEntry, error) {
+
+	client := config.Client
+	if client == nil {
+		timeout := config.Timeout
+		if timeout <= 0 {
+			timeout = DefaultTimeout
+		}
+		client = &http.Client{Timeout: timeout}
+	}
+
+	body, err := get(client, config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to fetch the org config: %v", err)
+	}
+	sig, err := get(client, config.URL+SignatureSuffix)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to fetch the org config signature: %v", err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil {
+		return nil, fmt.Errorf("The org config signature is not base64 encoded: %v", err)
+	}
+
+	err = Verify(publicKey, body, signature)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cacheEntry{URL: config.URL, Config: body, Signature: signature}, nil
+}
+
+// readCache returns the cached config if it is for the URL and its signature
+// is valid
+func readCache(path string, configURL string, publicKey ed25519.PublicKey) (*cacheEntry, error) {
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil
+	}
+
+	entry := &cacheEntry{}
+	err = json.Unmarshal(b, entry)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the org config cache %s: %v", path, err)
+	}
+	if entry.URL != configURL {
+		return nil, nil
+	}
+
+	err = Verify(publicKey, entry.Config, entry.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return entry, nil
+}
+
+// parseEntry parses a verified config
+func parseEntry(entry *cacheEntry) (*Overlay, error) {
+	overlay, err := Parse(entry.Config)
+	if err != nil {
+		return nil, err
+	}
+	overlay.FetchedAt = entry.FetchedAt
+	return overlay, nil
+}
+
+// get returns the body of a GET request
+func get(client *http.Client, url string) ([]byte, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// hasKey returns true if the nested settings have the key
+func hasKey(settings map[string]interface{}, keys []string) bool {
+	value, ok := settings[keys[0]]
+	if !ok || len(keys) == 1 {
+		return ok
+	}
+	child, ok := value.(map[string]interface{})
+	return ok && hasKey(child, keys[1:])
+}
+
+// flatten adds the leaf values of the nested settings to values
+func flatten(settings map[string]interface{}, prefix string, values map[string]interface{}) {
+	for key, value := range settings {
+		if child, ok := value.(map[string]interface{}); ok && len(child) > 0 {
+			flatten(child, prefix+key+".", values)
+		} else {
+			values[prefix+key] = value
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package orgconfig
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/clock"
+	"gotest.tools/assert"
+)
+
+const testConfig = `settings:
+  vault-address: https://vault.example.com
+  stimpacks:
+    ssh:
+      enabled: false
+  deploy:
+    protected-envs: [prod]
+locked:
+  - stimpacks.ssh.enabled
+`
+
+// testServer serves the config signed with a new key and returns the PEM
+// encoded public key
+func testServer(t *testing.T, config *string, requests *int) (*httptest.Server, string) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NilError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	assert.NilError(t, err)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		switch r.URL.Path {
+		case "/stim.yaml":
+			w.Write([]byte(*config))
+		case "/stim.yaml" + SignatureSuffix:
+			w.Write([]byte(base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, []byte(*config)))))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	return server, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stim-orgconfig")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	config := testConfig
+	requests := 0
+	server, publicKey := testServer(t, &config, &requests)
+	defer server.Close()
+
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	c := &Config{
+		URL:       server.URL + "/stim.yaml",
+		PublicKey: publicKey,
+		CacheFile: filepath.Join(dir, "org-config.json"),
+		Client:    server.Client(),
+		Clock:     fake,
+	}
+
+	overlay, err := Load(c)
+	assert.NilError(t, err)
+	assert.Equal(t, requests, 2)
+	assert.Equal(t, overlay.FetchedAt, start)
+	assert.DeepEqual(t, overlay.Locked, []string{"stimpacks.ssh.enabled"})
+	values := overlay.Flatten()
+	assert.Equal(t, values["vault-address"], "https://vault.example.com")
+	assert.Equal(t, values["stimpacks.ssh.enabled"], false)
+	assert.DeepEqual(t, values["deploy.protected-envs"], []interface{}{"prod"})
+
+	// The cache is used until it is older than the refresh interval
+	fake.Advance(30 * time.Minute)
+	_, err = Load(c)
+	assert.NilError(t, err)
+	assert.Equal(t, requests, 2)
+
+	fake.Advance(time.Hour)
+	config = "settings:\n  vault-address: https://vault2.example.com\n"
+	overlay, err = Load(c)
+	assert.NilError(t, err)
+	assert.Equal(t, requests, 4)
+	assert.Equal(t, overlay.Flatten()["vault-address"], "https://vault2.example.com")
+
+	// Offline loads never fetch
+	fake.Advance(2 * time.Hour)
+	_, err = Load(&Config{URL: c.URL, PublicKey: c.PublicKey, CacheFile: c.CacheFile, Client: c.Client, Clock: fake, Offline: true})
+	assert.NilError(t, err)
+	assert.Equal(t, requests, 4)
+
+	// The cache is used when the endpoint is down
+	server.Close()
+	fake.Advance(2 * time.Hour)
+	overlay, err = Load(c)
+	assert.NilError(t, err)
+	assert.Assert(t, overlay.RefreshError != nil)
+	assert.Equal(t, overlay.Flatten()["vault-address"], "https://vault2.example.com")
+}
+
+func TestLoadInvalidSignature(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stim-orgconfig")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	config := testConfig
+	requests := 0
+	server, _ := testServer(t, &config, &requests)
+	defer server.Close()
+
+	// Signed with another key
+	_, otherKey := testServer(t, &config, &requests)
+
+	_, err = Load(&Config{
+		URL:       server.URL + "/stim.yaml",
+		PublicKey: otherKey,
+		CacheFile: filepath.Join(dir, "org-config.json"),
+		Client:    server.Client(),
+	})
+	assert.Error(t, err, "The org config signature is invalid")
+	_, err = os.Stat(filepath.Join(dir, "org-config.json"))
+	assert.Assert(t, os.IsNotExist(err))
+}
+
+func TestLoadHTTPS(t *testing.T) {
+	_, err := Load(&Config{URL: "http://config.example.com/stim.yaml"})
+	assert.Error(t, err, "The org config URL must be HTTPS: http://config.example.com/stim.yaml")
+}
+
+func TestParse(t *testing.T) {
+	_, err := Parse([]byte("settings:\n  vault-address: https://vault.example.com\nlocked: [vault-username]\n"))
+	assert.Error(t, err, "The org config locks 'vault-username', which it doesn't set")
+
+	_, err = Parse([]byte("settings:\n  stimpacks:\n    ssh:\n      enabled: false\nlocked: [stimpacks.ssh.enabled]\n"))
+	assert.NilError(t, err)
+}
+
+func TestParsePublicKey(t *testing.T) {
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.NilError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	assert.NilError(t, err)
+
+	parsed, err := ParsePublicKey(string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, parsed, publicKey)
+
+	parsed, err = ParsePublicKey(base64.StdEncoding.EncodeToString(der))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, parsed, publicKey)
+
+	_, err = ParsePublicKey("not a key")
+	assert.Error(t, err, "The org config public key is not PEM encoded")
+}
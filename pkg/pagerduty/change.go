@@ -0,0 +1,90 @@
+package pagerduty
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+)
+
+// changeEventEndpoint is the Events API v2 endpoint for change events.  The
+// vendored client doesn't support change events so they are sent directly.
+const changeEventEndpoint = "https://events.pagerduty.com/v2/change/enqueue"
+
+// changeEventClient is used to send change events.  The timeout keeps a hung
+// endpoint from blocking a deploy.
+var changeEventClient = &http.Client{Timeout: 10 * time.Second}
+
+// ChangeEvent contains the fields of a change event (ex. a deployment).
+// Change events are informational and never open incidents.
+type ChangeEvent struct {
+	Service   string
+	Summary   string
+	Source    string
+	Timestamp time.Time
+	Details   map[string]interface{}
+}
+
+// SendChangeEvent sends the provided ChangeEvent to the Pagerduty service.
+// It automatically detects and sets the hostname as the `source`, if not set.
+func (p *Pagerduty) SendChangeEvent(e *ChangeEvent) error {
+
+	if e.Service == "" {
+		return errors.New("Pagerduty: Change Event Service Name must be set")
+	}
+	if e.Summary == "" {
+		return errors.New("Pagerduty: Change Event Summary must be set")
+	}
+
+	integrationid, err := p.getServiceIntegrationID(e.Service)
+	if err != nil {
+		return err
+	}
+
+	source := e.Source
+	if source == "" {
+		source, err = os.Hostname()
+		if err != nil {
+			source = "unknown"
+		}
+	}
+
+	timestamp := e.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	event := map[string]interface{}{
+		"routing_key": integrationid,
+		"payload": map[string]interface{}{
+			"summary":        e.Summary,
+			"source":         source,
+			"timestamp":      timestamp.UTC().Format(time.RFC3339),
+			"custom_details": e.Details,
+		},
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	resp, err := changeEventClient.Post(changeEventEndpoint, "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("Pagerduty: HTTP Status Code: %d, Message: %s", resp.StatusCode, string(body))
+	}
+
+	p.log.Debug("Pagerduty change event sent to service " + e.Service)
+
+	return nil
+}
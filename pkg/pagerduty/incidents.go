@@ -0,0 +1,255 @@
+package pagerduty
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	pdApi "github.com/PagerDuty/go-pagerduty"
+)
+
+// apiEndpoint is the default Pagerduty REST API, see SetAPIEndpoint
+const apiEndpoint = "https://api.pagerduty.com"
+
+// manageIncidentsLimit is the most incidents that can be updated in one
+// request
+const manageIncidentsLimit = 250
+
+// The statuses of an incident
+const (
+	StatusTriggered    = "triggered"
+	StatusAcknowledged = "acknowledged"
+	StatusResolved     = "resolved"
+)
+
+// Incident is a Pagerduty incident
+type Incident struct {
+	ID        string    `json:"id"`
+	Number    uint      `json:"number"`
+	Title     string    `json:"title"`
+	Status    string    `json:"status"`
+	Urgency   string    `json:"urgency"`
+	Service   string    `json:"service"`
+	Assignees []string  `json:"assignees"`
+	CreatedAt time.Time `json:"createdAt"`
+	URL       string    `json:"url"`
+}
+
+// IncidentFilter selects the incidents to list.  Empty fields match all
+// incidents.
+type IncidentFilter struct {
+	ServiceIDs []string
+	UserIDs    []string
+	Statuses   []string
+	Urgencies  []string
+}
+
+// ListIncidents returns the incidents matching the filter.  Incidents that
+// are triggered or acknowledged are returned if no statuses are given.
+func (p *Pagerduty) ListIncidents(filter *IncidentFilter) ([]Incident, error) {
+
+	statuses := filter.Statuses
+	if len(statuses) == 0 {
+		statuses = []string{StatusTriggered, StatusAcknowledged}
+	}
+
+	limit := uint(100)
+	options := pdApi.ListIncidentsOptions{
+		APIListObject: pdApi.APIListObject{Offset: 0, Limit: limit},
+		ServiceIDs:    filter.ServiceIDs,
+		UserIDs:       filter.UserIDs,
+		Statuses:      statuses,
+		Urgencies:     filter.Urgencies,
+	}
+
+	var results []Incident
+	for {
+		incidents, err := p.client.ListIncidents(options)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, i := range incidents.Incidents {
+			results = append(results, newIncident(&i))
+		}
+
+		if !incidents.APIListObject.More {
+			return results, nil
+		}
+		options.APIListObject.Offset = options.APIListObject.Offset + limit
+	}
+}
+
+// GetIncident returns the incident with the given ID
+func (p *Pagerduty) GetIncident(id string) (*Incident, error) {
+
+	i, err := p.client.GetIncident(id)
+	if err != nil {
+		return nil, fmt.Errorf("Pagerduty incident \"%s\" not found: %v", id, err)
+	}
+
+	incident := newIncident(i)
+	return &incident, nil
+}
+
+// newIncident converts a Pagerduty API incident
+func newIncident(i *pdApi.Incident) Incident {
+
+	incident := Incident{
+		ID:      i.Id,
+		Number:  i.IncidentNumber,
+		Title:   i.Title,
+		Status:  i.Status,
+		Urgency: i.Urgency,
+		Service: i.Service.Summary,
+		URL:     i.HTMLURL,
+	}
+	for _, a := range i.Assignments {
+		incident.Assignees = append(incident.Assignees, a.Assignee.Summary)
+	}
+	incident.CreatedAt, _ = time.Parse(time.RFC3339, i.CreatedAt)
+
+	return incident
+}
+
+// GetServiceID returns the ID of the service with the given name
+func (p *Pagerduty) GetServiceID(name string) (string, error) {
+
+	services, err := p.client.ListServices(pdApi.ListServiceOptions{Query: name})
+	if err != nil {
+		return "", err
+	}
+	for _, s := range services.Services {
+		if strings.EqualFold(s.Name, name) || s.ID == name {
+			return s.ID, nil
+		}
+	}
+
+	return "", errors.New("Pagerduty service \"" + name + "\" not found")
+}
+
+// incidentReference is an incident in a manage incidents request
+type incidentReference struct {
+	ID          string              `json:"id"`
+	Type        string              `json:"type"`
+	Status      string              `json:"status,omitempty"`
+	Assignments []assigneeReference `json:"assignments,omitempty"`
+}
+
+// assigneeReference is a user assigned to an incident
+type assigneeReference struct {
+	Assignee struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+	} `json:"assignee"`
+}
+
+// SetIncidentStatus acknowledges or resolves the incidents.  from is the
+// email of the Pagerduty user making the change.
+func (p *Pagerduty) SetIncidentStatus(from string, incidentIDs []string, status string) error {
+
+	if status != StatusAcknowledged && status != StatusResolved {
+		return fmt.Errorf("Pagerduty: Invalid incident status '%s'", status)
+	}
+
+	return p.manageIncidents(from, incidentIDs, func(id string) incidentReference {
+		return incidentReference{ID: id, Type: "incident_reference", Status: status}
+	})
+}
+
+// ReassignIncidents assigns the incidents to the users
+func (p *Pagerduty) ReassignIncidents(from string, incidentIDs []string, userIDs []string) error {
+
+	var assignments []assigneeReference
+	for _, userID := range userIDs {
+		a := assigneeReference{}
+		a.Assignee.ID = userID
+		a.Assignee.Type = "user_reference"
+		assignments = append(assignments, a)
+	}
+
+	return p.manageIncidents(from, incidentIDs, func(id string) incidentReference {
+		return incidentReference{ID: id, Type: "incident_reference", Assignments: assignments}
+	})
+}
+
+// manageIncidents updates the incidents in batches
+func (p *Pagerduty) manageIncidents(from string, incidentIDs []string, update func(id string) incidentReference) error {
+
+	for start := 0; start < len(incidentIDs); start += manageIncidentsLimit {
+		end := start + manageIncidentsLimit
+		if end > len(incidentIDs) {
+			end = len(incidentIDs)
+		}
+
+		var incidents []incidentReference
+		for _, id := range incidentIDs[start:end] {
+			incidents = append(incidents, update(id))
+		}
+
+		err := p.request("PUT", "/incidents", from, map[string]interface{}{"incidents": incidents})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SnoozeIncident snoozes an acknowledged incident for the duration
+func (p *Pagerduty) SnoozeIncident(from string, incidentID string, duration time.Duration) error {
+	return p.request("POST", "/incidents/"+incidentID+"/snooze", from, map[string]interface{}{"duration": int(duration.Seconds())})
+}
+
+// MergeIncidents merges the source incidents into the target incident
+func (p *Pagerduty) MergeIncidents(from string, targetID string, sourceIDs []string) error {
+
+	var sources []incidentReference
+	for _, id := range sourceIDs {
+		sources = append(sources, incidentReference{ID: id, Type: "incident_reference"})
+	}
+
+	return p.request("PUT", "/incidents/"+targetID+"/merge", from, map[string]interface{}{"source_incidents": sources})
+}
+
+// request sends a request to the Pagerduty REST API.  The incident
+// management endpoints are called directly since the client library can't
+// snooze incidents and sends empty fields (ex. `resolve_reason`) in updates.
+func (p *Pagerduty) request(method string, path string, from string, payload interface{}) error {
+
+	if from == "" {
+		return errors.New("Pagerduty: The email of the user making the change is required")
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(method, p.endpoint+path, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.pagerduty+json;version=2")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Token token="+p.apiKey)
+	req.Header.Set("From", from)
+
+	resp, err := p.client.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("Pagerduty: %s %s returned %s: %s", method, path, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package pagerduty
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/stimlog"
+	"gotest.tools/assert"
+)
+
+// pagerdutyRequest is a request received by the fake Pagerduty API
+type pagerdutyRequest struct {
+	Method string
+	Path   string
+	From   string
+	Body   map[string]interface{}
+}
+
+// newTestPagerduty returns a client of a fake Pagerduty API that records the
+// requests and replies with handler, if set.  The server must be closed.
+func newTestPagerduty(t *testing.T, handler http.HandlerFunc) (*Pagerduty, *[]pagerdutyRequest, *httptest.Server) {
+
+	var mu sync.Mutex
+	var requests []pagerdutyRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.Header.Get("Authorization"), "Token token=api-key")
+
+		req := pagerdutyRequest{Method: r.Method, Path: r.URL.Path, From: r.Header.Get("From")}
+		json.NewDecoder(r.Body).Decode(&req.Body)
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+
+		if handler != nil {
+			handler(w, r)
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+
+	p := New("api-key", stimlog.GetLogger())
+	p.SetHTTPClient(&http.Client{})
+	p.SetAPIEndpoint(server.URL)
+
+	return p, &requests, server
+}
+
+// Incidents are updated in batches of at most manageIncidentsLimit
+func TestSetIncidentStatusBatches(t *testing.T) {
+	p, requests, server := newTestPagerduty(t, nil)
+	defer server.Close()
+
+	ids := make([]string, manageIncidentsLimit+50)
+	for i := range ids {
+		ids[i] = "P" + strconv.Itoa(i)
+	}
+
+	err := p.SetIncidentStatus("me@my-domain.com", ids, StatusResolved)
+	assert.NilError(t, err)
+
+	assert.Equal(t, len(*requests), 2)
+	for i, size := range []int{manageIncidentsLimit, 50} {
+		req := (*requests)[i]
+		assert.Equal(t, req.Method, "PUT")
+		assert.Equal(t, req.Path, "/incidents")
+		assert.Equal(t, req.From, "me@my-domain.com")
+		incidents := req.Body["incidents"].([]interface{})
+		assert.Equal(t, len(incidents), size)
+		assert.DeepEqual(t, incidents[0], map[string]interface{}{
+			"id":     ids[i*manageIncidentsLimit],
+			"type":   "incident_reference",
+			"status": StatusResolved,
+		})
+	}
+
+	err = p.SetIncidentStatus("me@my-domain.com", ids, StatusTriggered)
+	assert.Error(t, err, "Pagerduty: Invalid incident status 'triggered'")
+}
+
+func TestReassignIncidents(t *testing.T) {
+	p, requests, server := newTestPagerduty(t, nil)
+	defer server.Close()
+
+	err := p.ReassignIncidents("me@my-domain.com", []string{"P1", "P2"}, []string{"U1"})
+	assert.NilError(t, err)
+
+	assert.Equal(t, len(*requests), 1)
+	incidents := (*requests)[0].Body["incidents"].([]interface{})
+	assert.DeepEqual(t, incidents[1], map[string]interface{}{
+		"id":   "P2",
+		"type": "incident_reference",
+		"assignments": []interface{}{
+			map[string]interface{}{"assignee": map[string]interface{}{"id": "U1", "type": "user_reference"}},
+		},
+	})
+}
+
+func TestSnoozeAndMergeIncidents(t *testing.T) {
+	p, requests, server := newTestPagerduty(t, nil)
+	defer server.Close()
+
+	err := p.SnoozeIncident("me@my-domain.com", "P1", 2*time.Hour)
+	assert.NilError(t, err)
+	err = p.MergeIncidents("me@my-domain.com", "P1", []string{"P2", "P3"})
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, *requests, []pagerdutyRequest{
+		{Method: "POST", Path: "/incidents/P1/snooze", From: "me@my-domain.com", Body: map[string]interface{}{"duration": float64(7200)}},
+		{Method: "PUT", Path: "/incidents/P1/merge", From: "me@my-domain.com", Body: map[string]interface{}{"source_incidents": []interface{}{
+			map[string]interface{}{"id": "P2", "type": "incident_reference"},
+			map[string]interface{}{"id": "P3", "type": "incident_reference"},
+		}}},
+	})
+}
+
+func TestManageIncidentsErrors(t *testing.T) {
+	p, requests, server := newTestPagerduty(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error":{"message":"Access Denied"}}`))
+	})
+	defer server.Close()
+
+	err := p.SetIncidentStatus("", []string{"P1"}, StatusAcknowledged)
+	assert.Error(t, err, "Pagerduty: The email of the user making the change is required")
+	assert.Equal(t, len(*requests), 0)
+
+	err = p.SetIncidentStatus("me@my-domain.com", []string{"P1"}, StatusAcknowledged)
+	assert.Error(t, err, `Pagerduty: PUT /incidents returned 403 Forbidden: {"error":{"message":"Access Denied"}}`)
+}
+
+// The incidents listed with the client library are paged through
+func TestListIncidents(t *testing.T) {
+	p, requests, server := newTestPagerduty(t, func(w http.ResponseWriter, r *http.Request) {
+		offset := r.URL.Query().Get("offset")
+		more := offset == ""
+		fmt.Fprintf(w, `{"more":%t,"incidents":[{"id":"P%s","incident_number":1,"title":"Disk full","status":"triggered",
+			"service":{"summary":"db"},"assignments":[{"assignee":{"summary":"Ops"}}],"created_at":"2024-05-01T10:00:00Z"}]}`, more, offset)
+	})
+	defer server.Close()
+
+	incidents, err := p.ListIncidents(&IncidentFilter{})
+	assert.NilError(t, err)
+
+	assert.Equal(t, len(*requests), 2)
+	assert.Equal(t, (*requests)[0].Path, "/incidents")
+	assert.Equal(t, len(incidents), 2)
+	assert.Equal(t, incidents[0].ID, "P")
+	assert.Equal(t, incidents[1].ID, "P100")
+	assert.Equal(t, incidents[0].Service, "db")
+	assert.DeepEqual(t, incidents[0].Assignees, []string{"Ops"})
+	assert.Equal(t, incidents[0].CreatedAt, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+}
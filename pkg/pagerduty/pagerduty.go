@@ -2,6 +2,8 @@ package pagerduty
 
 import (
 	"errors"
+	"net/http"
+	"net/url"
 	"os"
 	"strings"
 
@@ -11,8 +13,10 @@ import (
 
 // Pagerduty is the main object
 type Pagerduty struct {
-	client *pdApi.Client
-	log    Logger
+	client   *pdApi.Client
+	apiKey   string
+	endpoint string
+	log      Logger
 }
 
 // Event contains the required and optional fields to sent an event
@@ -27,20 +31,87 @@ type Event struct {
 	Class     string
 	Details   string
 	DedupKey  string
+
+	// CustomDetails are structured details of a trigger event, sent instead
+	// of Details if set
+	CustomDetails map[string]interface{}
 }
 
+// The actions of the Events API v2
+const (
+	EventTrigger     = "trigger"
+	EventAcknowledge = "acknowledge"
+	EventResolve     = "resolve"
+)
+
+// EventActions are the actions of the Events API v2
+var EventActions = []string{EventTrigger, EventAcknowledge, EventResolve}
+
+// EventSeverities are the severities of trigger events
+var EventSeverities = []string{"critical", "error", "warning", "info"}
+
 type Logger interface {
 	Debug(...interface{})
 	Warn(...interface{})
 	Fatal(...interface{})
 }
 
+// SetHTTPClient sets the HTTP client of the API calls (ex. one that retries
+// failed calls)
+func (p *Pagerduty) SetHTTPClient(client *http.Client) {
+	p.client.HTTPClient = client
+	p.redirectClient()
+}
+
+// SetAPIEndpoint sets the URL of the REST API (ex. a test server) instead of
+// https://api.pagerduty.com
+func (p *Pagerduty) SetAPIEndpoint(endpoint string) {
+	p.endpoint = strings.TrimSuffix(endpoint, "/")
+	p.redirectClient()
+}
+
+// redirectClient sends the requests of the client library to the API
+// endpoint, since the vendored version of the library can't be given one
+func (p *Pagerduty) redirectClient() {
+
+	if p.endpoint == apiEndpoint {
+		return
+	}
+	target, err := url.Parse(p.endpoint)
+	if err != nil {
+		return
+	}
+
+	p.client.HTTPClient = &endpointClient{base: p.client.HTTPClient, target: target}
+}
+
+// endpointClient sends the requests for the default API endpoint to another
+// one
+type endpointClient struct {
+	base   pdApi.HTTPClient
+	target *url.URL
+}
+
+// Do implements the HTTPClient of the client library
+func (c *endpointClient) Do(req *http.Request) (*http.Response, error) {
+
+	if "https://"+req.URL.Host == apiEndpoint {
+		req = req.Clone(req.Context())
+		req.URL.Scheme = c.target.Scheme
+		req.URL.Host = c.target.Host
+		req.URL.Path = c.target.Path + req.URL.Path
+		req.Host = ""
+	}
+
+	return c.base.Do(req)
+}
+
 // New returns a new Pagerduty "instance"
 func New(apiKey string, log Logger) *Pagerduty {
 
 	// Initialize client
 	client := pdApi.NewClient(apiKey)
-	p := &Pagerduty{client: client, log: log}
+	p := &Pagerduty{client: client, apiKey: apiKey, endpoint: apiEndpoint, log: log}
 
 	return p
 }
@@ -84,79 +155,99 @@ func (p *Pagerduty) GetServices() ([]string, error) {
 	return results, nil
 }
 
-// SendEvent sends the provided Event to Pagerduty.  It automatically detects
-// and sets the hostname as the `source`, if not set.
-func (p *Pagerduty) SendEvent(e *Event) error {
+// SendEvent sends the provided Event to Pagerduty with the Events API v2 and
+// returns the dedup key of the alert, which is generated by Pagerduty for
+// triggers without one.  It automatically detects and sets the hostname as
+// the `source` of triggers, if not set.  Acknowledge and resolve events only
+// need the dedup key of the alert.
+func (p *Pagerduty) SendEvent(e *Event) (string, error) {
 
-	err := p.validateEventFields(e)
+	err := validateEvent(e)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	integrationid, err := p.getServiceIntegrationID(e.Service)
 	if err != nil {
-		return err
+		return "", err
+	}
+
+	event := pdApi.V2Event{
+		RoutingKey: integrationid,
+		Action:     e.Action,
+		DedupKey:   e.DedupKey,
+	}
+	if e.Action == EventTrigger {
+		event.Payload = eventPayload(e)
+	}
+
+	resp, err := pdApi.ManageEvent(event)
+	if err != nil {
+		return "", err
 	}
 
-	var source string
-	if e.Source == "" {
+	p.log.Debug("Pagerduty " + e.Action + " event sent to service " + e.Service)
+
+	return resp.DedupKey, nil
+}
+
+// eventPayload returns the payload of a trigger event
+func eventPayload(e *Event) *pdApi.V2Payload {
+
+	source := e.Source
+	if source == "" {
+		var err error
 		source, err = os.Hostname()
 		if err != nil {
 			source = "unknown"
 		}
-	} else {
-		source = e.Source
 	}
 
-	payload := pdApi.V2Payload{
+	payload := &pdApi.V2Payload{
 		Summary:   e.Summary,
 		Source:    source,
 		Severity:  e.Severity,
 		Component: e.Component,
 		Group:     e.Group,
 		Class:     e.Class,
-		Details:   e.Details,
 	}
-
-	event := pdApi.V2Event{
-		RoutingKey: integrationid,
-		Action:     e.Action,
-		Payload:    &payload,
-		DedupKey:   e.DedupKey,
-	}
-
-	_, err = pdApi.ManageEvent(event)
-	if err != nil {
-		return err
+	if len(e.CustomDetails) > 0 {
+		payload.Details = e.CustomDetails
+	} else if e.Details != "" {
+		payload.Details = e.Details
 	}
 
-	return nil
+	return payload
 }
 
-func (p *Pagerduty) validateEventFields(e *Event) error {
+// validateEvent checks the fields required by the action of the event
+func validateEvent(e *Event) error {
 	if e.Service == "" {
 		return errors.New("Pagerduty: Event Service Name must be set")
 	}
-	if e.Summary == "" {
-		return errors.New("Pagerduty: Event Summary must be set")
-	}
 	if e.Action == "" {
 		return errors.New("Pagerduty: Event Action must be set")
 	}
-	validActions := []string{"trigger", "acknowledge", "resolve"}
-	if !utils.Contains(validActions, e.Action) {
-		return errors.New("Pagerduty: Invalid value for Event Action. Valid values are: [" + strings.Join(validActions, ",") + "]")
-	}
-	if e.Severity == "" {
-		return errors.New("Pagerduty: Event Severity must be set")
+	if !utils.Contains(EventActions, e.Action) {
+		return errors.New("Pagerduty: Invalid value for Event Action. Valid values are: [" + strings.Join(EventActions, ",") + "]")
 	}
-	validSeverities := []string{"critical", "warning", "error", "info"}
-	if !utils.Contains(validSeverities, e.Severity) {
-		return errors.New("Pagerduty: Invalid value for Event Severity. Valid values are: [" + strings.Join(validSeverities, ",") + "]")
+
+	if e.Action != EventTrigger {
+		if e.DedupKey == "" {
+			return errors.New("Pagerduty: Event Dedup Key must be set to " + e.Action + " an alert")
+		}
+		return nil
 	}
+
 	if e.Summary == "" {
 		return errors.New("Pagerduty: Event Summary must be set")
 	}
+	if e.Severity == "" {
+		return errors.New("Pagerduty: Event Severity must be set")
+	}
+	if !utils.Contains(EventSeverities, e.Severity) {
+		return errors.New("Pagerduty: Invalid value for Event Severity. Valid values are: [" + strings.Join(EventSeverities, ",") + "]")
+	}
 
 	return nil
 }
@@ -169,7 +260,7 @@ func (p *Pagerduty) getServiceIntegrationID(servicename string) (string, error)
 	opts := pdApi.ListServiceOptions{Query: servicename, Includes: []string{"integrations"}}
 
 	if svcs, err := p.client.ListServices(opts); err != nil {
-		panic(err)
+		return "", err
 	} else {
 		for _, s := range svcs.Services {
 			if s.Name == servicename {
@@ -0,0 +1,48 @@
+package pagerduty
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestValidateEvent(t *testing.T) {
+	tests := []struct {
+		name  string
+		event Event
+		err   string
+	}{
+		{"trigger", Event{Action: EventTrigger, Service: "payments", Summary: "Backup failed", Severity: "error"}, ""},
+		{"no service", Event{Action: EventTrigger, Summary: "Backup failed", Severity: "error"}, "Pagerduty: Event Service Name must be set"},
+		{"bad action", Event{Action: "snooze", Service: "payments"}, "Pagerduty: Invalid value for Event Action. Valid values are: [trigger,acknowledge,resolve]"},
+		{"trigger without summary", Event{Action: EventTrigger, Service: "payments", Severity: "error"}, "Pagerduty: Event Summary must be set"},
+		{"bad severity", Event{Action: EventTrigger, Service: "payments", Summary: "Backup failed", Severity: "Error"}, "Pagerduty: Invalid value for Event Severity. Valid values are: [critical,error,warning,info]"},
+		{"resolve", Event{Action: EventResolve, Service: "payments", DedupKey: "nightly-backup"}, ""},
+		{"acknowledge without dedup key", Event{Action: EventAcknowledge, Service: "payments"}, "Pagerduty: Event Dedup Key must be set to acknowledge an alert"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateEvent(&test.event)
+			if test.err == "" {
+				assert.NilError(t, err)
+			} else {
+				assert.Error(t, err, test.err)
+			}
+		})
+	}
+}
+
+func TestEventPayload(t *testing.T) {
+	payload := eventPayload(&Event{Summary: "Backup failed", Severity: "error", Source: "backup-01", Details: "exit code 1"})
+	assert.Equal(t, payload.Source, "backup-01")
+	assert.Equal(t, payload.Details, "exit code 1")
+
+	details := map[string]interface{}{"exit-code": 1}
+	payload = eventPayload(&Event{Summary: "Backup failed", Severity: "error", Details: "exit code 1", CustomDetails: details})
+	assert.DeepEqual(t, payload.Details, details)
+	assert.Assert(t, payload.Source != "")
+
+	payload = eventPayload(&Event{Summary: "Backup failed", Severity: "error"})
+	assert.Equal(t, payload.Details, nil)
+}
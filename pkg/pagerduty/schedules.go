@@ -0,0 +1,166 @@
+package pagerduty
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	pdApi "github.com/PagerDuty/go-pagerduty"
+)
+
+// Schedule is an on-call schedule
+type Schedule struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	TimeZone    string `json:"timeZone"`
+	Description string `json:"description"`
+}
+
+// OnCall is a user that is on call for an escalation policy level
+type OnCall struct {
+	Schedule         string    `json:"schedule"`
+	EscalationPolicy string    `json:"escalationPolicy"`
+	EscalationLevel  uint      `json:"escalationLevel"`
+	User             string    `json:"user"`
+	UserID           string    `json:"userId"`
+	Start            time.Time `json:"start"`
+	End              time.Time `json:"end"`
+}
+
+// Override is a temporary change to who is on call for a schedule
+type Override struct {
+	ID    string    `json:"id"`
+	User  string    `json:"user"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// User is a Pagerduty user
+type User struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// ListSchedules returns the on-call schedules matching the query (all
+// schedules if the query is empty)
+func (p *Pagerduty) ListSchedules(query string) ([]Schedule, error) {
+
+	limit := uint(50)
+	options := pdApi.ListSchedulesOptions{APIListObject: pdApi.APIListObject{Offset: 0, Limit: limit}, Query: query}
+
+	var results []Schedule
+	for {
+		schedules, err := p.client.ListSchedules(options)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range schedules.Schedules {
+			results = append(results, Schedule{ID: s.ID, Name: s.Name, TimeZone: s.TimeZone, Description: s.Description})
+		}
+
+		if !schedules.APIListObject.More {
+			return results, nil
+		}
+		options.APIListObject.Offset = options.APIListObject.Offset + limit
+	}
+}
+
+// GetSchedule returns the schedule with the given name or ID
+func (p *Pagerduty) GetSchedule(nameOrID string) (*Schedule, error) {
+
+	schedules, err := p.ListSchedules(nameOrID)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range schedules {
+		if strings.EqualFold(s.Name, nameOrID) || s.ID == nameOrID {
+			return &s, nil
+		}
+	}
+
+	// The query only matches names so look up IDs directly
+	s, err := p.client.GetSchedule(nameOrID, pdApi.GetScheduleOptions{})
+	if err != nil {
+		return nil, errors.New("Pagerduty schedule \"" + nameOrID + "\" not found")
+	}
+
+	return &Schedule{ID: s.ID, Name: s.Name, TimeZone: s.TimeZone, Description: s.Description}, nil
+}
+
+// GetOnCalls returns who is currently on call.  If schedule IDs are given only
+// their on-calls are returned.
+func (p *Pagerduty) GetOnCalls(scheduleIDs []string) ([]OnCall, error) {
+
+	limit := uint(50)
+	options := pdApi.ListOnCallOptions{APIListObject: pdApi.APIListObject{Offset: 0, Limit: limit}, ScheduleIDs: scheduleIDs}
+
+	var results []OnCall
+	for {
+		oncalls, err := p.client.ListOnCalls(options)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, o := range oncalls.OnCalls {
+			oncall := OnCall{
+				Schedule:         o.Schedule.Summary,
+				EscalationPolicy: o.EscalationPolicy.Summary,
+				EscalationLevel:  o.EscalationLevel,
+				User:             o.User.Summary,
+				UserID:           o.User.ID,
+			}
+
+			// Start/end are empty for users that are always on call
+			oncall.Start, _ = time.Parse(time.RFC3339, o.Start)
+			oncall.End, _ = time.Parse(time.RFC3339, o.End)
+
+			results = append(results, oncall)
+		}
+
+		if !oncalls.APIListObject.More {
+			return results, nil
+		}
+		options.APIListObject.Offset = options.APIListObject.Offset + limit
+	}
+}
+
+// GetUser returns the user with the given email address
+func (p *Pagerduty) GetUser(email string) (*User, error) {
+
+	users, err := p.client.ListUsers(pdApi.ListUsersOptions{Query: email})
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range users.Users {
+		if strings.EqualFold(u.Email, email) {
+			return &User{ID: u.ID, Name: u.Name, Email: u.Email}, nil
+		}
+	}
+
+	return nil, errors.New("Pagerduty user \"" + email + "\" not found")
+}
+
+// CreateOverride puts the user on call for the schedule between start and end
+func (p *Pagerduty) CreateOverride(scheduleID string, userID string, start time.Time, end time.Time) (*Override, error) {
+
+	if !end.After(start) {
+		return nil, errors.New("Pagerduty: Override end must be after the start")
+	}
+
+	o, err := p.client.CreateOverride(scheduleID, pdApi.Override{
+		Start: start.Format(time.RFC3339),
+		End:   end.Format(time.RFC3339),
+		User:  pdApi.APIObject{ID: userID, Type: "user_reference"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	override := &Override{ID: o.ID, User: o.User.Summary}
+	override.Start, _ = time.Parse(time.RFC3339, o.Start)
+	override.End, _ = time.Parse(time.RFC3339, o.End)
+
+	return override, nil
+}
@@ -0,0 +1,90 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DockerHubHost is the registry of images without a registry host (ex.
+// `premiereglobal/kube-vault-deploy`)
+const DockerHubHost = "registry-1.docker.io"
+
+// Reference is a parsed image reference
+type Reference struct {
+
+	// Host is the registry host (ex. `docker.example.com:5000`)
+	Host string
+
+	// Repo is the repository in the registry (ex. `team/app`)
+	Repo string
+
+	// Tag and/or Digest identify the image.  Neither is set for a repository
+	// (ex. `team/app`).
+	Tag    string
+	Digest string
+}
+
+// ParseReference parses an image reference the way Docker does: the first
+// path component is the registry host if it contains a `.` or `:` or is
+// `localhost`, otherwise the image is on Docker Hub.  Single component Docker
+// Hub repos are under `library/`.
+func ParseReference(image string) (*Reference, error) {
+
+	ref := &Reference{}
+	name := image
+
+	if i := strings.Index(name, "@"); i >= 0 {
+		ref.Digest = name[i+1:]
+		name = name[:i]
+		if !strings.Contains(ref.Digest, ":") {
+			return nil, fmt.Errorf("Invalid image digest in '%s'", image)
+		}
+	}
+
+	// A tag is after the last `:` that isn't part of the host port
+	if i := strings.LastIndex(name, ":"); i >= 0 && !strings.Contains(name[i+1:], "/") {
+		ref.Tag = name[i+1:]
+		name = name[:i]
+	}
+
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		ref.Host = parts[0]
+		ref.Repo = parts[1]
+	} else {
+		ref.Host = DockerHubHost
+		ref.Repo = name
+		if !strings.Contains(name, "/") {
+			ref.Repo = "library/" + name
+		}
+	}
+
+	if ref.Repo == "" || ref.Repo != strings.ToLower(ref.Repo) || strings.HasSuffix(ref.Repo, "/") {
+		return nil, fmt.Errorf("Invalid image repository in '%s'", image)
+	}
+
+	return ref, nil
+}
+
+// Reference returns the digest, or the tag (`latest` if not set)
+func (r *Reference) Reference() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	if r.Tag == "" {
+		return "latest"
+	}
+	return r.Tag
+}
+
+// String returns the image reference
+func (r *Reference) String() string {
+	s := r.Host + "/" + r.Repo
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
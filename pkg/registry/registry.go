@@ -0,0 +1,591 @@
+// Package registry talks to container registries (ex. Docker Hub, Artifactory,
+// Harbor) with the Docker Registry HTTP API V2: listing tags, inspecting
+// images and copying images between repositories.
+package registry
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The manifest media types that are requested
+const (
+	MediaTypeManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	MediaTypeOCIManifest  = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeOCIIndex     = "application/vnd.oci.image.index.v1+json"
+)
+
+var manifestMediaTypes = []string{MediaTypeManifestList, MediaTypeOCIIndex, MediaTypeManifest, MediaTypeOCIManifest}
+
+// ErrNotFound is returned when a repository, tag or blob doesn't exist
+var ErrNotFound = errors.New("Not found")
+
+// Config configures a Registry
+type Config struct {
+
+	// Host is the registry host (ex. `docker.example.com`)
+	Host string
+
+	// Username and Password are used for basic auth and to get bearer tokens
+	// from the registry's token service.  Anonymous if not set.
+	Username string
+	Password string
+
+	// Insecure uses HTTP instead of HTTPS
+	Insecure bool
+
+	// Timeout of each request.  Defaults to 60 seconds
+	Timeout time.Duration
+}
+
+// Registry is a client of a container registry
+type Registry struct {
+	baseURL  string
+	username string
+	password string
+	client   *http.Client
+
+	// tokens are the bearer tokens by scope
+	mu     sync.Mutex
+	tokens map[string]string
+}
+
+// Descriptor describes the content (config, layer or manifest) referenced by
+// a manifest
+type Descriptor struct {
+	MediaType string    `json:"mediaType"`
+	Digest    string    `json:"digest"`
+	Size      int64     `json:"size"`
+	Platform  *Platform `json:"platform,omitempty"`
+}
+
+// Platform is the platform of an image in a manifest list
+type Platform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+// String returns the platform in the format `os/architecture[/variant]`
+func (p *Platform) String() string {
+	s := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		s += "/" + p.Variant
+	}
+	return s
+}
+
+// Manifest is an image manifest or a manifest list (multi-platform image)
+type Manifest struct {
+	MediaType string       `json:"mediaType"`
+	Config    *Descriptor  `json:"config,omitempty"`
+	Layers    []Descriptor `json:"layers,omitempty"`
+	Manifests []Descriptor `json:"manifests,omitempty"`
+
+	// Digest and Raw are the digest and content of the manifest as stored
+	Digest string `json:"-"`
+	Raw    []byte `json:"-"`
+}
+
+// IsList returns true if the manifest is a manifest list or image index
+func (m *Manifest) IsList() bool {
+	return m.MediaType == MediaTypeManifestList || m.MediaType == MediaTypeOCIIndex
+}
+
+// Size returns the total size of the config and layers of an image manifest
+func (m *Manifest) Size() int64 {
+	var size int64
+	if m.Config != nil {
+		size += m.Config.Size
+	}
+	for _, layer := range m.Layers {
+		size += layer.Size
+	}
+	return size
+}
+
+// ImageConfig is the part of the image config that is used
+type ImageConfig struct {
+	Created      time.Time `json:"created"`
+	Architecture string    `json:"architecture"`
+	OS           string    `json:"os"`
+}
+
+// registryErrors is the error response of the registry API
+type registryErrors struct {
+	Errors []struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// New returns a Registry client
+func New(config *Config) *Registry {
+	scheme := "https"
+	if config.Insecure {
+		scheme = "http"
+	}
+	r := &Registry{
+		baseURL:  scheme + "://" + config.Host,
+		username: config.Username,
+		password: config.Password,
+		client:   &http.Client{Timeout: config.Timeout},
+		tokens:   make(map[string]string),
+	}
+	if r.client.Timeout == 0 {
+		r.client.Timeout = 60 * time.Second
+	}
+	return r
+}
+
+// Tags returns the tags of the repository, sorted
+func (r *Registry) Tags(repo string) ([]string, error) {
+
+	var tags []string
+	next := fmt.Sprintf("/v2/%s/tags/list?n=1000", repo)
+	for next != "" {
+		resp, err := r.do(http.MethodGet, next, nil, nil, pullScope(repo))
+		if err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			Tags []string `json:"tags"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("Registry: Unable to parse the tags of %s: %v", repo, err)
+		}
+		tags = append(tags, page.Tags...)
+		next = nextLink(resp.Header.Get("Link"))
+	}
+
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// Manifest returns the manifest of a tag or digest of the repository
+func (r *Registry) Manifest(repo string, reference string) (*Manifest, error) {
+
+	headers := map[string]string{"Accept": strings.Join(manifestMediaTypes, ", ")}
+	resp, err := r.do(http.MethodGet, fmt.Sprintf("/v2/%s/manifests/%s", repo, reference), headers, nil, pullScope(repo))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{}
+	err = json.Unmarshal(raw, manifest)
+	if err != nil {
+		return nil, fmt.Errorf("Registry: Unable to parse the manifest of %s:%s: %v", repo, reference, err)
+	}
+	if manifest.MediaType == "" {
+		manifest.MediaType = resp.Header.Get("Content-Type")
+	}
+	manifest.Raw = raw
+	manifest.Digest = resp.Header.Get("Docker-Content-Digest")
+	if manifest.Digest == "" {
+		sum := sha256.Sum256(raw)
+		manifest.Digest = "sha256:" + hex.EncodeToString(sum[:])
+	}
+
+	return manifest, nil
+}
+
+// ManifestExists returns true if the tag or digest exists in the repository
+func (r *Registry) ManifestExists(repo string, reference string) (bool, error) {
+
+	headers := map[string]string{"Accept": strings.Join(manifestMediaTypes, ", ")}
+	resp, err := r.do(http.MethodHead, fmt.Sprintf("/v2/%s/manifests/%s", repo, reference), headers, nil, pullScope(repo))
+	if err == ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+
+	return true, nil
+}
+
+// ImageConfig returns the config of an image manifest
+func (r *Registry) ImageConfig(repo string, manifest *Manifest) (*ImageConfig, error) {
+
+	if manifest.Config == nil {
+		return nil, errors.New("Registry: The manifest has no image config")
+	}
+
+	resp, err := r.do(http.MethodGet, fmt.Sprintf("/v2/%s/blobs/%s", repo, manifest.Config.Digest), nil, nil, pullScope(repo))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	config := &ImageConfig{}
+	err = json.NewDecoder(resp.Body).Decode(config)
+	if err != nil {
+		return nil, fmt.Errorf("Registry: Unable to parse the image config: %v", err)
+	}
+
+	return config, nil
+}
+
+// Copy tags the image with the tag or digest reference of srcRepo as dstTag
+// in dstRepo and returns the digest of the image.  The blobs are mounted from
+// srcRepo (or copied if the registry can't mount them), so the image is
+// identical.  Multi-platform images are copied with all their platforms.
+func (r *Registry) Copy(srcRepo string, reference string, dstRepo string, dstTag string) (string, error) {
+
+	manifest, err := r.Manifest(srcRepo, reference)
+	if err != nil {
+		return "", err
+	}
+
+	if srcRepo != dstRepo {
+		err = r.copyContent(srcRepo, dstRepo, manifest)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err = r.putManifest(dstRepo, dstTag, manifest)
+	if err != nil {
+		return "", err
+	}
+
+	return manifest.Digest, nil
+}
+
+// copyContent copies the blobs and child manifests of the manifest
+func (r *Registry) copyContent(srcRepo string, dstRepo string, manifest *Manifest) error {
+
+	for _, child := range manifest.Manifests {
+		childManifest, err := r.Manifest(srcRepo, child.Digest)
+		if err != nil {
+			return err
+		}
+		err = r.copyContent(srcRepo, dstRepo, childManifest)
+		if err != nil {
+			return err
+		}
+		err = r.putManifest(dstRepo, child.Digest, childManifest)
+		if err != nil {
+			return err
+		}
+	}
+
+	blobs := manifest.Layers
+	if manifest.Config != nil {
+		blobs = append([]Descriptor{*manifest.Config}, blobs...)
+	}
+	for _, blob := range blobs {
+		err := r.mountBlob(srcRepo, dstRepo, blob)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mountBlob mounts the blob of srcRepo in dstRepo, or uploads it if the
+// registry doesn't support mounting
+func (r *Registry) mountBlob(srcRepo string, dstRepo string, blob Descriptor) error {
+
+	scope := []string{pushScope(dstRepo), pullScope(srcRepo)[0]}
+	path := fmt.Sprintf("/v2/%s/blobs/uploads/?mount=%s&from=%s", dstRepo, url.QueryEscape(blob.Digest), url.QueryEscape(srcRepo))
+	resp, err := r.do(http.MethodPost, path, nil, nil, scope)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusCreated {
+		return nil
+	}
+
+	// The registry started an upload instead
+	location, err := resp.Location()
+	if err != nil {
+		return fmt.Errorf("Registry: Unable to mount blob %s: %v", blob.Digest, err)
+	}
+
+	src, err := r.do(http.MethodGet, fmt.Sprintf("/v2/%s/blobs/%s", srcRepo, blob.Digest), nil, nil, scope)
+	if err != nil {
+		return err
+	}
+	defer src.Body.Close()
+
+	query := location.Query()
+	query.Set("digest", blob.Digest)
+	location.RawQuery = query.Encode()
+	headers := map[string]string{"Content-Type": "application/octet-stream"}
+	upload, err := r.doReader(http.MethodPut, location.String(), headers, src.Body, src.ContentLength, scope)
+	if err != nil {
+		return err
+	}
+	upload.Body.Close()
+
+	return nil
+}
+
+// putManifest stores the manifest under the tag or digest reference
+func (r *Registry) putManifest(repo string, reference string, manifest *Manifest) error {
+
+	headers := map[string]string{"Content-Type": manifest.MediaType}
+	resp, err := r.do(http.MethodPut, fmt.Sprintf("/v2/%s/manifests/%s", repo, reference), headers, manifest.Raw, []string{pushScope(repo)})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// do sends a request, authenticating with a bearer token for the scope if the
+// registry asks for one.  Non-2xx responses are returned as errors.
+func (r *Registry) do(method string, path string, headers map[string]string, body []byte, scope []string) (*http.Response, error) {
+
+	newRequest := func() (*http.Request, error) {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		return http.NewRequest(method, r.url(path), reader)
+	}
+
+	req, err := newRequest()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.send(req, headers, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		err = r.authenticate(resp.Header.Get("WWW-Authenticate"), scope)
+		if err != nil {
+			return nil, err
+		}
+		req, err = newRequest()
+		if err != nil {
+			return nil, err
+		}
+		resp, err = r.send(req, headers, scope)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, checkResponse(resp)
+}
+
+// doReader sends a request with a streamed body, which can't be sent again,
+// so the scope must already be authenticated
+func (r *Registry) doReader(method string, path string, headers map[string]string, body io.Reader, length int64, scope []string) (*http.Response, error) {
+
+	req, err := http.NewRequest(method, r.url(path), body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = length
+	resp, err := r.send(req, headers, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, checkResponse(resp)
+}
+
+// send sends the request with the token of the scope, or basic auth
+func (r *Registry) send(req *http.Request, headers map[string]string, scope []string) (*http.Response, error) {
+
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	r.mu.Lock()
+	token, ok := r.tokens[strings.Join(scope, " ")]
+	r.mu.Unlock()
+	if ok {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if r.username != "" {
+		req.SetBasicAuth(r.username, r.password)
+	}
+
+	return r.client.Do(req)
+}
+
+// authenticate gets a bearer token for the scope from the token service in
+// the challenge of the registry
+func (r *Registry) authenticate(challenge string, scope []string) error {
+
+	scheme, params := parseChallenge(challenge)
+	if scheme != "bearer" || params["realm"] == "" {
+		return errors.New("Registry: Authentication failed, check the registry credentials")
+	}
+
+	tokenURL, err := url.Parse(params["realm"])
+	if err != nil {
+		return fmt.Errorf("Registry: Invalid token service '%s': %v", params["realm"], err)
+	}
+	query := tokenURL.Query()
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	for _, s := range scope {
+		query.Add("scope", s)
+	}
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	if r.username != "" {
+		req.SetBasicAuth(r.username, r.password)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("Registry: Unable to get a token from %s: %s", tokenURL.Host, resp.Status)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return fmt.Errorf("Registry: Unable to parse the token: %v", err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+
+	r.mu.Lock()
+	r.tokens[strings.Join(scope, " ")] = token.Token
+	r.mu.Unlock()
+
+	return nil
+}
+
+// url returns the URL of an API path, or the URL itself if it is absolute
+// (ex. an upload location)
+func (r *Registry) url(path string) string {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+	return r.baseURL + path
+}
+
+// checkResponse returns an error (and closes the body) for non-2xx responses
+func checkResponse(resp *http.Response) error {
+
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	var errs registryErrors
+	if json.Unmarshal(body, &errs) == nil && len(errs.Errors) > 0 {
+		var messages []string
+		for _, e := range errs.Errors {
+			messages = append(messages, fmt.Sprintf("%s: %s", e.Code, e.Message))
+		}
+		return fmt.Errorf("Registry: %s: %s", resp.Status, strings.Join(messages, ", "))
+	}
+	return fmt.Errorf("Registry: %s", resp.Status)
+}
+
+// parseChallenge parses a `WWW-Authenticate` header (ex. `Bearer
+// realm="https://auth.docker.io/token",service="registry.docker.io"`)
+func parseChallenge(challenge string) (string, map[string]string) {
+
+	params := map[string]string{}
+	parts := strings.SplitN(strings.TrimSpace(challenge), " ", 2)
+	if len(parts) < 2 {
+		return strings.ToLower(parts[0]), params
+	}
+
+	rest := parts[1]
+	for rest != "" {
+		eq := strings.Index(rest, "=")
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = rest[eq+1:]
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				break
+			}
+			value = rest[1 : end+1]
+			rest = rest[end+2:]
+		} else {
+			end := strings.Index(rest, ",")
+			if end < 0 {
+				end = len(rest)
+			}
+			value = rest[:end]
+			rest = rest[end:]
+		}
+		params[key] = value
+		rest = strings.TrimLeft(rest, ", ")
+	}
+
+	return strings.ToLower(parts[0]), params
+}
+
+// nextLink returns the path of the next page from a `Link` header (ex.
+// `</v2/app/tags/list?n=1000&last=v1.2>; rel="next"`)
+func nextLink(link string) string {
+	if !strings.Contains(link, `rel="next"`) {
+		return ""
+	}
+	start := strings.Index(link, "<")
+	end := strings.Index(link, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return link[start+1 : end]
+}
+
+func pullScope(repo string) []string {
+	return []string{fmt.Sprintf("repository:%s:pull", repo)}
+}
+
+func pushScope(repo string) string {
+	return fmt.Sprintf("repository:%s:pull,push", repo)
+}
@@ -0,0 +1,201 @@
+package registry
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+const testManifest = `{"schemaVersion":2,"mediaType":"application/vnd.docker.distribution.manifest.v2+json",
+"config":{"mediaType":"application/vnd.docker.container.image.v1+json","digest":"sha256:c0","size":100},
+"layers":[{"mediaType":"application/vnd.docker.image.rootfs.diff.tar.gzip","digest":"sha256:l1","size":1000}]}`
+
+// fakeRegistry is a registry that requires a bearer token from its token
+// service and records the requests it gets
+type fakeRegistry struct {
+	server    *httptest.Server
+	requests  []string
+	manifests map[string]string
+	blobs     map[string]string
+	canMount  bool
+}
+
+func newFakeRegistry(t *testing.T) *fakeRegistry {
+	f := &fakeRegistry{
+		manifests: map[string]string{"team/app:v1": testManifest},
+		blobs:     map[string]string{"team/app@sha256:c0": `{"created":"2024-06-01T12:00:00Z","architecture":"amd64","os":"linux"}`, "team/app@sha256:l1": "layer"},
+		canMount:  true,
+	}
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			user, pass, _ := r.BasicAuth()
+			assert.Equal(t, user+":"+pass, "stim:secret")
+			fmt.Fprintf(w, `{"token":"t-%s"}`, strings.Join(r.URL.Query()["scope"], "+"))
+			return
+		}
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer t-") {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="fake"`, f.server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		f.requests = append(f.requests, r.Method+" "+r.URL.RequestURI())
+
+		path := strings.TrimPrefix(r.URL.Path, "/v2/")
+		switch {
+		case path == "team/app/tags/list" && r.URL.Query().Get("last") == "":
+			w.Header().Set("Link", `</v2/team/app/tags/list?n=1000&last=v1>; rel="next"`)
+			fmt.Fprint(w, `{"name":"team/app","tags":["v2","v1"]}`)
+		case path == "team/app/tags/list":
+			fmt.Fprint(w, `{"name":"team/app","tags":["latest"]}`)
+		case strings.Contains(path, "/manifests/"):
+			parts := strings.SplitN(path, "/manifests/", 2)
+			key := parts[0] + ":" + parts[1]
+			if r.Method == http.MethodPut {
+				body, _ := ioutil.ReadAll(r.Body)
+				f.manifests[key] = string(body)
+				w.WriteHeader(http.StatusCreated)
+				return
+			}
+			manifest, ok := f.manifests[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"errors":[{"code":"MANIFEST_UNKNOWN","message":"manifest unknown"}]}`)
+				return
+			}
+			w.Header().Set("Content-Type", MediaTypeManifest)
+			w.Header().Set("Docker-Content-Digest", "sha256:m1")
+			if r.Method == http.MethodGet {
+				fmt.Fprint(w, manifest)
+			}
+		case strings.HasSuffix(path, "/blobs/uploads/") && f.canMount:
+			w.WriteHeader(http.StatusCreated)
+		case strings.HasSuffix(path, "/blobs/uploads/"):
+			w.Header().Set("Location", "/upload/1")
+			w.WriteHeader(http.StatusAccepted)
+		case strings.Contains(path, "/blobs/"):
+			parts := strings.SplitN(path, "/blobs/", 2)
+			fmt.Fprint(w, f.blobs[parts[0]+"@"+parts[1]])
+		case r.URL.Path == "/upload/1":
+			body, _ := ioutil.ReadAll(r.Body)
+			f.blobs["upload@"+r.URL.Query().Get("digest")] = string(body)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return f
+}
+
+func (f *fakeRegistry) registry() *Registry {
+	return New(&Config{Host: strings.TrimPrefix(f.server.URL, "http://"), Username: "stim", Password: "secret", Insecure: true})
+}
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		image    string
+		expected Reference
+		err      string
+	}{
+		{"alpine", Reference{Host: DockerHubHost, Repo: "library/alpine"}, ""},
+		{"premiereglobal/kube-vault-deploy:0.3.3", Reference{Host: DockerHubHost, Repo: "premiereglobal/kube-vault-deploy", Tag: "0.3.3"}, ""},
+		{"docker.example.com/team/app:v1", Reference{Host: "docker.example.com", Repo: "team/app", Tag: "v1"}, ""},
+		{"localhost:5000/app", Reference{Host: "localhost:5000", Repo: "app"}, ""},
+		{"docker.example.com/app@sha256:abc", Reference{Host: "docker.example.com", Repo: "app", Digest: "sha256:abc"}, ""},
+		{"docker.example.com/App:v1", Reference{}, "Invalid image repository in 'docker.example.com/App:v1'"},
+	}
+
+	for _, test := range tests {
+		ref, err := ParseReference(test.image)
+		if test.err != "" {
+			assert.Error(t, err, test.err)
+		} else {
+			assert.NilError(t, err)
+			assert.DeepEqual(t, *ref, test.expected)
+		}
+	}
+}
+
+func TestReference(t *testing.T) {
+	ref, _ := ParseReference("docker.example.com/team/app")
+	assert.Equal(t, ref.Reference(), "latest")
+	ref, _ = ParseReference("docker.example.com/team/app:v1@sha256:abc")
+	assert.Equal(t, ref.Reference(), "sha256:abc")
+	assert.Equal(t, ref.String(), "docker.example.com/team/app:v1@sha256:abc")
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/alpine:pull,push"`)
+	assert.Equal(t, scheme, "bearer")
+	assert.DeepEqual(t, params, map[string]string{
+		"realm":   "https://auth.docker.io/token",
+		"service": "registry.docker.io",
+		"scope":   "repository:library/alpine:pull,push",
+	})
+}
+
+func TestTags(t *testing.T) {
+	f := newFakeRegistry(t)
+	defer f.server.Close()
+
+	tags, err := f.registry().Tags("team/app")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, tags, []string{"latest", "v1", "v2"})
+}
+
+func TestManifest(t *testing.T) {
+	f := newFakeRegistry(t)
+	defer f.server.Close()
+	r := f.registry()
+
+	manifest, err := r.Manifest("team/app", "v1")
+	assert.NilError(t, err)
+	assert.Equal(t, manifest.Digest, "sha256:m1")
+	assert.Equal(t, manifest.Size(), int64(1100))
+	assert.Assert(t, !manifest.IsList())
+
+	config, err := r.ImageConfig("team/app", manifest)
+	assert.NilError(t, err)
+	assert.Equal(t, config.OS+"/"+config.Architecture, "linux/amd64")
+
+	exists, err := r.ManifestExists("team/app", "v1")
+	assert.NilError(t, err)
+	assert.Assert(t, exists)
+	exists, err = r.ManifestExists("team/app", "v9")
+	assert.NilError(t, err)
+	assert.Assert(t, !exists)
+
+	_, err = r.Manifest("team/app", "v9")
+	assert.Equal(t, err, ErrNotFound)
+}
+
+func TestCopy(t *testing.T) {
+	f := newFakeRegistry(t)
+	defer f.server.Close()
+
+	digest, err := f.registry().Copy("team/app", "v1", "prod/app", "v1")
+	assert.NilError(t, err)
+	assert.Equal(t, digest, "sha256:m1")
+	assert.Equal(t, f.manifests["prod/app:v1"], testManifest)
+	assert.DeepEqual(t, f.requests, []string{
+		"GET /v2/team/app/manifests/v1",
+		"POST /v2/prod/app/blobs/uploads/?mount=sha256%3Ac0&from=team%2Fapp",
+		"POST /v2/prod/app/blobs/uploads/?mount=sha256%3Al1&from=team%2Fapp",
+		"PUT /v2/prod/app/manifests/v1",
+	})
+}
+
+func TestCopyWithoutMount(t *testing.T) {
+	f := newFakeRegistry(t)
+	f.canMount = false
+	defer f.server.Close()
+
+	_, err := f.registry().Copy("team/app", "v1", "prod/app", "v1")
+	assert.NilError(t, err)
+	assert.Equal(t, f.blobs["upload@sha256:l1"], "layer")
+	assert.Equal(t, f.manifests["prod/app:v1"], testManifest)
+}
@@ -0,0 +1,162 @@
+package registry
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version parsed from an image tag (ex. `v1.4.2`).
+// Missing minor and patch numbers are 0.
+type Version struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+
+	// Tag is the tag the version was parsed from
+	Tag string
+}
+
+// ParseVersion parses a tag of the form `[v]MAJOR[.MINOR[.PATCH]][-PRERELEASE][+BUILD]`
+func ParseVersion(tag string) (*Version, error) {
+
+	v := &Version{Tag: tag}
+	s := strings.TrimPrefix(tag, "v")
+	if i := strings.Index(s, "+"); i >= 0 {
+		s = s[:i]
+	}
+	if i := strings.Index(s, "-"); i >= 0 {
+		v.Prerelease = s[i+1:]
+		s = s[:i]
+	}
+
+	parts := strings.Split(s, ".")
+	if s == "" || len(parts) > 3 {
+		return nil, fmt.Errorf("'%s' is not a semantic version", tag)
+	}
+	numbers := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("'%s' is not a semantic version", tag)
+		}
+		*numbers[i] = n
+	}
+
+	return v, nil
+}
+
+// Compare returns -1, 0 or 1 if v is older, the same or newer than o.  A
+// pre-release is older than its release.
+func (v *Version) Compare(o *Version) int {
+	for _, pair := range [][2]int{{v.Major, o.Major}, {v.Minor, o.Minor}, {v.Patch, o.Patch}} {
+		if pair[0] < pair[1] {
+			return -1
+		}
+		if pair[0] > pair[1] {
+			return 1
+		}
+	}
+	switch {
+	case v.Prerelease == o.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case o.Prerelease == "":
+		return -1
+	case v.Prerelease < o.Prerelease:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// constraintOperators are the comparisons of a version constraint, longest
+// first so `>=` isn't parsed as `>`
+var constraintOperators = []string{">=", "<=", "!=", ">", "<", "="}
+
+// constraintTerm is a comparison with a version (ex. `>=1.4`)
+type constraintTerm struct {
+	operator string
+	version  *Version
+}
+
+// Constraint is a comma separated list of comparisons that a version must
+// all match (ex. `>=1.4,<2`).  An empty constraint matches every version.
+type Constraint []*constraintTerm
+
+// ParseConstraint parses a version constraint
+func ParseConstraint(s string) (Constraint, error) {
+
+	var constraint Constraint
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		operator := "="
+		for _, op := range constraintOperators {
+			if strings.HasPrefix(term, op) {
+				operator = op
+				term = strings.TrimSpace(term[len(op):])
+				break
+			}
+		}
+		version, err := ParseVersion(term)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid version constraint '%s': %v", s, err)
+		}
+		constraint = append(constraint, &constraintTerm{operator: operator, version: version})
+	}
+
+	return constraint, nil
+}
+
+// Matches returns true if the version matches every comparison
+func (c Constraint) Matches(v *Version) bool {
+	for _, term := range c {
+		cmp := v.Compare(term.version)
+		var ok bool
+		switch term.operator {
+		case ">=":
+			ok = cmp >= 0
+		case "<=":
+			ok = cmp <= 0
+		case "!=":
+			ok = cmp != 0
+		case ">":
+			ok = cmp > 0
+		case "<":
+			ok = cmp < 0
+		default:
+			ok = cmp == 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// LatestVersion returns the tag of the newest release version that matches
+// the constraint, empty if none does.  Tags that aren't semantic versions and
+// pre-releases are ignored.
+func LatestVersion(tags []string, constraint Constraint) string {
+
+	var latest *Version
+	for _, tag := range tags {
+		v, err := ParseVersion(tag)
+		if err != nil || v.Prerelease != "" || !constraint.Matches(v) {
+			continue
+		}
+		if latest == nil || v.Compare(latest) > 0 {
+			latest = v
+		}
+	}
+
+	if latest == nil {
+		return ""
+	}
+	return latest.Tag
+}
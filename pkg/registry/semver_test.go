@@ -0,0 +1,60 @@
+package registry
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestParseVersion(t *testing.T) {
+	v, err := ParseVersion("v1.4.2-rc1+build5")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, *v, Version{Major: 1, Minor: 4, Patch: 2, Prerelease: "rc1", Tag: "v1.4.2-rc1+build5"})
+
+	v, err = ParseVersion("2")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, *v, Version{Major: 2, Tag: "2"})
+
+	for _, tag := range []string{"latest", "", "1.2.3.4", "1.x", "sha-abc123"} {
+		_, err = ParseVersion(tag)
+		assert.ErrorContains(t, err, "is not a semantic version")
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	parse := func(tag string) *Version {
+		v, err := ParseVersion(tag)
+		assert.NilError(t, err)
+		return v
+	}
+
+	assert.Equal(t, parse("1.10.0").Compare(parse("1.9.9")), 1)
+	assert.Equal(t, parse("v1.4").Compare(parse("1.4.0")), 0)
+	assert.Equal(t, parse("1.4.0-rc1").Compare(parse("1.4.0")), -1)
+	assert.Equal(t, parse("1.4.0-rc2").Compare(parse("1.4.0-rc1")), 1)
+}
+
+func TestLatestVersion(t *testing.T) {
+	tags := []string{"latest", "1.3.9", "v1.4.0", "1.4.10", "1.5.0-rc1", "1.4.9", "2.0.0", "sha-abc123"}
+
+	tests := []struct {
+		constraint string
+		expected   string
+	}{
+		{"", "2.0.0"},
+		{">=1.4,<2", "1.4.10"},
+		{"<1.4", "1.3.9"},
+		{"1.4.0", "v1.4.0"},
+		{">=1.4, !=1.4.10, <2", "1.4.9"},
+		{">2", ""},
+	}
+
+	for _, test := range tests {
+		constraint, err := ParseConstraint(test.constraint)
+		assert.NilError(t, err)
+		assert.Equal(t, LatestVersion(tags, constraint), test.expected, test.constraint)
+	}
+
+	_, err := ParseConstraint(">=one")
+	assert.Error(t, err, "Invalid version constraint '>=one': 'one' is not a semantic version")
+}
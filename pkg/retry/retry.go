@@ -0,0 +1,268 @@
+// Package retry retries HTTP calls to external services (ex. Vault, Slack and
+// Pagerduty) that fail with transient errors, with exponential backoff and
+// jitter.
+package retry
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/clock"
+)
+
+// The classes of errors that can be retried
+const (
+	// ClassNetwork is connection errors and timeouts
+	ClassNetwork = "network"
+	// ClassServer is 502, 503 and 504 responses
+	ClassServer = "5xx"
+	// ClassThrottle is 429 (rate limited) responses
+	ClassThrottle = "429"
+)
+
+// Classes are the classes of errors that can be retried
+var Classes = []string{ClassNetwork, ClassServer, ClassThrottle}
+
+// Logger is the logger of the retries
+type Logger interface {
+	Debug(...interface{})
+}
+
+// Policy describes how failed calls are retried
+type Policy struct {
+
+	// MaxRetries is the number of retries after the first attempt, 0 to never
+	// retry
+	MaxRetries int
+
+	// Delay is the wait before the first retry.  It doubles after each retry,
+	// up to MaxDelay.
+	Delay time.Duration
+
+	// MaxDelay is the longest wait between attempts, including waits asked
+	// for by a Retry-After header
+	MaxDelay time.Duration
+
+	// Jitter is the fraction (0 to 1) of each wait that is randomized, so that
+	// clients that failed together don't retry together
+	Jitter float64
+
+	// RetryOn are the classes of errors that are retried, all of Classes if
+	// empty
+	RetryOn []string
+
+	Clock clock.Clock
+	Log   Logger
+
+	// random returns a number in [0, 1), replaced in tests
+	random func() float64
+}
+
+// DefaultPolicy returns the policy used when stim isn't configured
+func DefaultPolicy() *Policy {
+	return &Policy{
+		MaxRetries: 2,
+		Delay:      500 * time.Millisecond,
+		MaxDelay:   10 * time.Second,
+		Jitter:     0.2,
+	}
+}
+
+// Validate checks that the retry classes are known
+func (p *Policy) Validate() error {
+	for _, class := range p.RetryOn {
+		known := false
+		for _, c := range Classes {
+			known = known || c == class
+		}
+		if !known {
+			return errors.New("Unknown retry class '" + class + "', must be one of network, 5xx or 429")
+		}
+	}
+	return nil
+}
+
+// Backoff returns the wait before a retry (1 for the first retry)
+func (p *Policy) Backoff(retry int) time.Duration {
+
+	delay := p.Delay
+	for i := 1; i < retry && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	if p.Jitter > 0 {
+		random := p.random
+		if random == nil {
+			random = rand.Float64
+		}
+		delay -= time.Duration(float64(delay) * p.Jitter * random())
+	}
+
+	return delay
+}
+
+// retries returns true if the class of error is retried
+func (p *Policy) retries(class string) bool {
+	if len(p.RetryOn) == 0 {
+		return true
+	}
+	for _, c := range p.RetryOn {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// Transport wraps an HTTP transport (the default transport if nil) to retry
+// requests that fail with the retried classes of errors.  Requests that may
+// not be safe to repeat (ex. POSTs) are only retried if they were never sent
+// (connection refused) or were rate limited.
+func (p *Policy) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if p == nil || p.MaxRetries <= 0 {
+		return base
+	}
+	return &transport{policy: p, base: base}
+}
+
+type transport struct {
+	policy *Policy
+	base   http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	p := t.policy
+	c := p.Clock
+	if c == nil {
+		c = clock.New()
+	}
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+
+	for retry := 1; ; retry++ {
+		resp, err := t.base.RoundTrip(req)
+
+		class := ""
+		if err != nil {
+			class = p.errorClass(req, err)
+		} else {
+			class = p.responseClass(req, resp)
+		}
+		if class == "" || retry > p.MaxRetries || !replayable || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		wait := p.Backoff(retry)
+		if resp != nil {
+			if after := retryAfter(resp); after > 0 {
+				wait = after
+				if p.MaxDelay > 0 && wait > p.MaxDelay {
+					wait = p.MaxDelay
+				}
+			}
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if p.Log != nil {
+			reason := class
+			if err != nil {
+				reason = err.Error()
+			}
+			p.Log.Debug("Retry: {} {} failed ({}), retry {} of {} in {}", req.Method, req.URL.Host, reason, retry, p.MaxRetries, wait)
+		}
+
+		// A cancelled request (ex. Ctrl-C) stops waiting
+		if done := req.Context().Done(); done == nil {
+			c.Sleep(wait)
+		} else {
+			select {
+			case <-c.After(wait):
+			case <-done:
+				return nil, req.Context().Err()
+			}
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// errorClass returns the retried class of a failed request, empty if it
+// isn't retried
+func (p *Policy) errorClass(req *http.Request, err error) string {
+
+	if !p.retries(ClassNetwork) {
+		return ""
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		// The request was never sent
+		return ClassNetwork
+	}
+	if !idempotent(req) {
+		return ""
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ClassNetwork
+	}
+
+	return ""
+}
+
+// responseClass returns the retried class of a response, empty if it isn't
+// retried
+func (p *Policy) responseClass(req *http.Request, resp *http.Response) string {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		if p.retries(ClassThrottle) {
+			return ClassThrottle
+		}
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		if p.retries(ClassServer) && idempotent(req) {
+			return ClassServer
+		}
+	}
+	return ""
+}
+
+// idempotent returns true if repeating the request has the same effect as
+// sending it once
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// retryAfter returns the wait of a Retry-After header in seconds, 0 if there
+// is none
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
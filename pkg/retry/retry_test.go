@@ -0,0 +1,109 @@
+package retry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/clock"
+	"gotest.tools/assert"
+)
+
+func testPolicy(fake *clock.Fake) *Policy {
+	return &Policy{MaxRetries: 2, Delay: time.Second, MaxDelay: 10 * time.Second, Clock: fake}
+}
+
+func TestBackoff(t *testing.T) {
+	p := &Policy{Delay: time.Second, MaxDelay: 5 * time.Second}
+	assert.Equal(t, p.Backoff(1), time.Second)
+	assert.Equal(t, p.Backoff(2), 2*time.Second)
+	assert.Equal(t, p.Backoff(3), 4*time.Second)
+	assert.Equal(t, p.Backoff(4), 5*time.Second)
+
+	p.Jitter = 0.5
+	p.random = func() float64 { return 0.5 }
+	assert.Equal(t, p.Backoff(1), 750*time.Millisecond)
+}
+
+func TestTransport(t *testing.T) {
+	statuses := []int{}
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b := make([]byte, 16)
+		n, _ := r.Body.Read(b)
+		bodies = append(bodies, string(b[:n]))
+		status := statuses[0]
+		statuses = statuses[1:]
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "3")
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	client := &http.Client{Transport: testPolicy(fake).Transport(nil)}
+
+	// Server errors are retried with backoff until they succeed
+	statuses = []int{503, 502, 200}
+	resp, err := client.Get(server.URL)
+	assert.NilError(t, err)
+	assert.Equal(t, resp.StatusCode, 200)
+	assert.Equal(t, fake.Now(), start.Add(3*time.Second))
+
+	// And give up after MaxRetries
+	statuses = []int{503, 503, 503, 200}
+	resp, err = client.Get(server.URL)
+	assert.NilError(t, err)
+	assert.Equal(t, resp.StatusCode, 503)
+	assert.Equal(t, len(statuses), 1)
+
+	// POSTs are retried when rate limited, with the body and Retry-After
+	statuses = []int{429, 200}
+	bodies = nil
+	resp, err = client.Post(server.URL, "text/plain", strings.NewReader("event"))
+	assert.NilError(t, err)
+	assert.Equal(t, resp.StatusCode, 200)
+	assert.DeepEqual(t, bodies, []string{"event", "event"})
+	assert.Equal(t, fake.Now(), start.Add(3*time.Second+3*time.Second+3*time.Second))
+
+	// But not on server errors, as the request may have been processed
+	statuses = []int{503, 200}
+	resp, err = client.Post(server.URL, "text/plain", strings.NewReader("event"))
+	assert.NilError(t, err)
+	assert.Equal(t, resp.StatusCode, 503)
+}
+
+func TestTransportRetryOn(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	p := testPolicy(clock.NewFake(time.Now()))
+	p.RetryOn = []string{ClassNetwork, ClassThrottle}
+	resp, err := (&http.Client{Transport: p.Transport(nil)}).Get(server.URL)
+	assert.NilError(t, err)
+	assert.Equal(t, resp.StatusCode, 503)
+	assert.Equal(t, calls, 1)
+
+	p.RetryOn = []string{"4xx"}
+	assert.Error(t, p.Validate(), "Unknown retry class '4xx', must be one of network, 5xx or 429")
+}
+
+func TestTransportNetwork(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	_, err := (&http.Client{Transport: testPolicy(fake).Transport(nil)}).Post(url, "text/plain", strings.NewReader("event"))
+	assert.ErrorContains(t, err, "connection refused")
+	assert.Equal(t, fake.Now(), start.Add(3*time.Second))
+}
@@ -0,0 +1,384 @@
+package selfupdate
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultRepository is the GitHub repository of the stim releases
+	DefaultRepository = "PremiereGlobal/stim"
+
+	// DefaultAPIURL is the GitHub API address
+	DefaultAPIURL = "https://api.github.com"
+
+	// ChecksumsAsset is the release asset listing the SHA-256 checksums of the
+	// other assets, in the format of `sha256sum`
+	ChecksumsAsset = "checksums.txt"
+
+	// SignatureAsset is the release asset with the Ed25519 signature of the
+	// checksums, raw or base64 encoded
+	SignatureAsset = "checksums.txt.sig"
+
+	// binaryName is the name of the binary in the release archives
+	binaryName = "stim"
+)
+
+// Config configures an Updater
+type Config struct {
+
+	// Repository is the GitHub repository (owner/name) of the releases.
+	// Defaults to DefaultRepository
+	Repository string
+
+	// APIURL is the GitHub API address.  Defaults to DefaultAPIURL
+	APIURL string
+
+	// Timeout of each request.  Defaults to 30 seconds
+	Timeout time.Duration
+
+	// PublicKey is the base64 encoded Ed25519 public key that the release
+	// checksums are signed with.  Downloads fail without it.
+	PublicKey string
+}
+
+// Updater finds, downloads and installs stim releases
+type Updater struct {
+	repository string
+	apiURL     string
+	client     *http.Client
+	publicKey  string
+}
+
+// Release is a published stim release
+type Release struct {
+	Version string
+	URL     string
+
+	// Assets maps the asset names to their download URLs
+	Assets map[string]string
+}
+
+// githubRelease is the part of the GitHub release API response that is used
+type githubRelease struct {
+	TagName string `json:"tag_name"`
+	HTMLURL string `json:"html_url"`
+	Assets  []struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// New returns an Updater
+func New(config *Config) *Updater {
+	u := &Updater{
+		repository: config.Repository,
+		apiURL:     strings.TrimSuffix(config.APIURL, "/"),
+		client:     &http.Client{Timeout: config.Timeout},
+		publicKey:  config.PublicKey,
+	}
+	if u.repository == "" {
+		u.repository = DefaultRepository
+	}
+	if u.apiURL == "" {
+		u.apiURL = DefaultAPIURL
+	}
+	if u.client.Timeout == 0 {
+		u.client.Timeout = 30 * time.Second
+	}
+	return u
+}
+
+// LatestRelease returns the latest published release
+func (u *Updater) LatestRelease() (*Release, error) {
+
+	body, err := u.get(fmt.Sprintf("%s/repos/%s/releases/latest", u.apiURL, u.repository))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get the latest release of %s: %v", u.repository, err)
+	}
+
+	var gr githubRelease
+	err = json.Unmarshal(body, &gr)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse the latest release of %s: %v", u.repository, err)
+	}
+
+	release := &Release{
+		Version: gr.TagName,
+		URL:     gr.HTMLURL,
+		Assets:  make(map[string]string),
+	}
+	for _, asset := range gr.Assets {
+		release.Assets[asset.Name] = asset.BrowserDownloadURL
+	}
+	return release, nil
+}
+
+// Download downloads the binary of the release for the current platform and
+// verifies it against the release checksums, whose signature is verified with
+// the public key
+func (u *Updater) Download(release *Release) ([]byte, error) {
+
+	publicKey, err := parsePublicKey(u.publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	name := ArchiveName(release.Version)
+	url, ok := release.Assets[name]
+	if !ok {
+		return nil, fmt.Errorf("Release %s has no binary for %s/%s (%s)", release.Version, runtime.GOOS, runtime.GOARCH, name)
+	}
+	checksumsURL, ok := release.Assets[ChecksumsAsset]
+	if !ok {
+		return nil, fmt.Errorf("Release %s has no %s to verify the download with", release.Version, ChecksumsAsset)
+	}
+	signatureURL, ok := release.Assets[SignatureAsset]
+	if !ok {
+		return nil, fmt.Errorf("Release %s has no %s to verify the download with", release.Version, SignatureAsset)
+	}
+
+	checksums, err := u.get(checksumsURL)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to download %s: %v", ChecksumsAsset, err)
+	}
+	signature, err := u.get(signatureURL)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to download %s: %v", SignatureAsset, err)
+	}
+	err = VerifySignature(checksums, signature, publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("%s of release %s: %v", ChecksumsAsset, release.Version, err)
+	}
+
+	sum, ok := ParseChecksums(checksums)[name]
+	if !ok {
+		return nil, fmt.Errorf("%s of release %s has no checksum for %s", ChecksumsAsset, release.Version, name)
+	}
+
+	archive, err := u.get(url)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to download %s: %v", name, err)
+	}
+	err = VerifyChecksum(archive, sum)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	return ExtractBinary(archive)
+}
+
+// get returns the body of a GET request
+func (u *Updater) get(url string) ([]byte, error) {
+	resp, err := u.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// ArchiveName returns the name of the release archive for the current
+// platform (ex. stim-linux-amd64-v0.5.0.tar.gz)
+func ArchiveName(version string) string {
+	return fmt.Sprintf("%s-%s-%s-%s.tar.gz", binaryName, runtime.GOOS, runtime.GOARCH, version)
+}
+
+// parsePublicKey decodes a base64 encoded Ed25519 public key
+func parsePublicKey(key string) (ed25519.PublicKey, error) {
+	if key == "" {
+		return nil, errors.New("This build of stim has no release signing key to verify downloads with, install the release manually")
+	}
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(decoded) != ed25519.PublicKeySize {
+		return nil, errors.New("The release signing key of this build of stim is not a base64 encoded Ed25519 public key")
+	}
+	return ed25519.PublicKey(decoded), nil
+}
+
+// VerifySignature returns an error if the signature (raw or base64 encoded)
+// isn't a valid Ed25519 signature of the data by the public key
+func VerifySignature(data []byte, signature []byte, publicKey ed25519.PublicKey) error {
+	if len(signature) != ed25519.SignatureSize {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+		if err != nil || len(decoded) != ed25519.SignatureSize {
+			return errors.New("Invalid signature, must be an Ed25519 signature")
+		}
+		signature = decoded
+	}
+	if !ed25519.Verify(publicKey, data, signature) {
+		return errors.New("Signature verification failed, the release was not signed by the stim release key")
+	}
+	return nil
+}
+
+// ParseChecksums parses checksums in the format of `sha256sum` and returns
+// them by file name
+func ParseChecksums(data []byte) map[string]string {
+	checksums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		// sha256sum marks files read in binary mode with `*`
+		checksums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+	}
+	return checksums
+}
+
+// VerifyChecksum returns an error if the SHA-256 checksum of the data isn't
+// the given hex encoded checksum
+func VerifyChecksum(data []byte, checksum string) error {
+	sum := sha256.Sum256(data)
+	actual := hex.EncodeToString(sum[:])
+	if actual != strings.ToLower(checksum) {
+		return fmt.Errorf("Checksum mismatch, expected %s but got %s", checksum, actual)
+	}
+	return nil
+}
+
+// ExtractBinary returns the stim binary from a release archive
+func ExtractBinary(archive []byte) ([]byte, error) {
+
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("The release archive does not contain `%s`", binaryName)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag == tar.TypeReg && filepath.Base(hdr.Name) == binaryName {
+			return ioutil.ReadAll(tr)
+		}
+	}
+}
+
+// Install replaces the binary at path with the new binary.  The new binary is
+// written next to the old one and renamed over it so that the binary is never
+// left half written.
+func Install(binary []byte, path string) error {
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".new-")
+	if err != nil {
+		return fmt.Errorf("Unable to write to %s: %v", dir, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(binary)
+	if err == nil {
+		err = tmp.Chmod(info.Mode().Perm() | 0111)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	// A running binary can't be replaced on Windows, but it can be renamed
+	old := path + ".old"
+	os.Remove(old)
+	err = os.Rename(path, old)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		os.Rename(old, path)
+		return err
+	}
+	os.Remove(old)
+
+	return nil
+}
+
+// CompareVersions compares two release versions (ex. v0.5.0 and 0.10.1) and
+// returns -1, 0 or 1 if a is older, the same or newer than b.  Versions that
+// aren't release versions (ex. local builds) return an error.
+func CompareVersions(a string, b string) (int, error) {
+
+	va, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := 0; i < len(va) || i < len(vb); i++ {
+		var x, y int
+		if i < len(va) {
+			x = va[i]
+		}
+		if i < len(vb) {
+			y = vb[i]
+		}
+		if x < y {
+			return -1, nil
+		}
+		if x > y {
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+// parseVersion returns the numbers of a version.  Pre-release and build
+// suffixes (ex. -rc1) are ignored.
+func parseVersion(version string) ([]int, error) {
+
+	v := strings.TrimPrefix(version, "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	if v == "" {
+		return nil, fmt.Errorf("'%s' is not a release version", version)
+	}
+
+	var numbers []int
+	for _, part := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not a release version", version)
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, nil
+}
@@ -0,0 +1,183 @@
+package selfupdate
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func testArchive(t *testing.T, binary []byte) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	err := tw.WriteHeader(&tar.Header{Name: "stim", Mode: 0755, Size: int64(len(binary)), Typeflag: tar.TypeReg})
+	assert.NilError(t, err)
+	_, err = tw.Write(binary)
+	assert.NilError(t, err)
+	assert.NilError(t, tw.Close())
+	assert.NilError(t, gz.Close())
+	return buf.Bytes()
+}
+
+// newTestServer serves a release whose checksums are signed with the private
+// key
+func newTestServer(t *testing.T, archive []byte, checksum string, privateKey ed25519.PrivateKey) *httptest.Server {
+	name := ArchiveName("v1.2.0")
+	checksums := fmt.Sprintf("%s  %s\n%s  stim-other-amd64-v1.2.0.tar.gz\n", checksum, name, checksum)
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/PremiereGlobal/stim/releases/latest":
+			fmt.Fprintf(w, `{"tag_name":"v1.2.0","html_url":"%[1]s/release","assets":[
+				{"name":"%[2]s","browser_download_url":"%[1]s/%[2]s"},
+				{"name":"checksums.txt","browser_download_url":"%[1]s/checksums.txt"},
+				{"name":"checksums.txt.sig","browser_download_url":"%[1]s/checksums.txt.sig"}]}`, server.URL, name)
+		case "/checksums.txt":
+			fmt.Fprint(w, checksums)
+		case "/checksums.txt.sig":
+			w.Write(ed25519.Sign(privateKey, []byte(checksums)))
+		case "/" + name:
+			w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return server
+}
+
+// testKey returns a signing key and its base64 encoded public key
+func testKey(t *testing.T) (ed25519.PrivateKey, string) {
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	assert.NilError(t, err)
+	return privateKey, base64.StdEncoding.EncodeToString(publicKey)
+}
+
+func TestDownload(t *testing.T) {
+	privateKey, publicKey := testKey(t)
+	archive := testArchive(t, []byte("new stim"))
+	sum := sha256.Sum256(archive)
+	server := newTestServer(t, archive, hex.EncodeToString(sum[:]), privateKey)
+	defer server.Close()
+
+	u := New(&Config{APIURL: server.URL, PublicKey: publicKey})
+	release, err := u.LatestRelease()
+	assert.NilError(t, err)
+	assert.Equal(t, release.Version, "v1.2.0")
+
+	binary, err := u.Download(release)
+	assert.NilError(t, err)
+	assert.Equal(t, string(binary), "new stim")
+}
+
+func TestDownloadSignature(t *testing.T) {
+	privateKey, _ := testKey(t)
+	_, otherKey := testKey(t)
+	archive := testArchive(t, []byte("new stim"))
+	sum := sha256.Sum256(archive)
+	server := newTestServer(t, archive, hex.EncodeToString(sum[:]), privateKey)
+	defer server.Close()
+
+	// Checksums signed by another key
+	u := New(&Config{APIURL: server.URL, PublicKey: otherKey})
+	release, err := u.LatestRelease()
+	assert.NilError(t, err)
+	_, err = u.Download(release)
+	assert.ErrorContains(t, err, "Signature verification failed")
+
+	// Builds without a key can't verify downloads
+	_, err = New(&Config{APIURL: server.URL}).Download(release)
+	assert.ErrorContains(t, err, "no release signing key")
+
+	delete(release.Assets, SignatureAsset)
+	_, err = New(&Config{APIURL: server.URL, PublicKey: otherKey}).Download(release)
+	assert.ErrorContains(t, err, "no checksums.txt.sig")
+}
+
+func TestVerifySignature(t *testing.T) {
+	privateKey, publicKey := testKey(t)
+	key, err := parsePublicKey(publicKey)
+	assert.NilError(t, err)
+
+	signature := ed25519.Sign(privateKey, []byte("checksums"))
+	assert.NilError(t, VerifySignature([]byte("checksums"), signature, key))
+	assert.NilError(t, VerifySignature([]byte("checksums"), []byte(base64.StdEncoding.EncodeToString(signature)+"\n"), key))
+	assert.ErrorContains(t, VerifySignature([]byte("changed"), signature, key), "Signature verification failed")
+	assert.ErrorContains(t, VerifySignature([]byte("checksums"), []byte("short"), key), "Invalid signature")
+
+	_, err = parsePublicKey("bm90IGEga2V5")
+	assert.ErrorContains(t, err, "not a base64 encoded Ed25519 public key")
+}
+
+func TestDownloadChecksumMismatch(t *testing.T) {
+	privateKey, publicKey := testKey(t)
+	archive := testArchive(t, []byte("new stim"))
+	sum := sha256.Sum256([]byte("something else"))
+	server := newTestServer(t, archive, hex.EncodeToString(sum[:]), privateKey)
+	defer server.Close()
+
+	u := New(&Config{APIURL: server.URL, PublicKey: publicKey})
+	release, err := u.LatestRelease()
+	assert.NilError(t, err)
+
+	_, err = u.Download(release)
+	assert.ErrorContains(t, err, "Checksum mismatch")
+
+	delete(release.Assets, ChecksumsAsset)
+	_, err = u.Download(release)
+	assert.ErrorContains(t, err, "no checksums.txt")
+}
+
+func TestInstall(t *testing.T) {
+	dir, err := ioutil.TempDir("", "selfupdate")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "stim")
+	assert.NilError(t, ioutil.WriteFile(path, []byte("old stim"), 0755))
+
+	err = Install([]byte("new stim"), path)
+	assert.NilError(t, err)
+
+	data, err := ioutil.ReadFile(path)
+	assert.NilError(t, err)
+	assert.Equal(t, string(data), "new stim")
+
+	files, err := ioutil.ReadDir(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, len(files), 1)
+	assert.Equal(t, files[0].Mode().Perm(), os.FileMode(0755))
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"v0.5.0", "v0.5.0", 0},
+		{"v0.5.0", "0.5", 0},
+		{"v0.5.0", "v0.10.0", -1},
+		{"v1.0.0", "v0.10.3", 1},
+		{"v1.2.0-rc1", "v1.2.0", 0},
+	}
+	for _, test := range tests {
+		result, err := CompareVersions(test.a, test.b)
+		assert.NilError(t, err)
+		assert.Equal(t, result, test.expected, "%s vs %s", test.a, test.b)
+	}
+
+	_, err := CompareVersions("local", "v1.0.0")
+	assert.ErrorContains(t, err, "not a release version")
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os/exec"
+	"runtime"
 	"syscall"
 )
 
@@ -16,12 +17,31 @@ type ShellCommand struct {
 	WorkDir string
 }
 
+// DefaultShell returns the POSIX shell that commands are run with.  On
+// Windows this is the `sh` in the PATH (ex. from Git for Windows).
+func DefaultShell() []string {
+	if runtime.GOOS == "windows" {
+		return []string{"sh", "-c"}
+	}
+	return []string{"/bin/sh", "-c"}
+}
+
+// PowerShell returns the PowerShell that commands are run with, Windows
+// PowerShell on Windows and PowerShell Core (`pwsh`) elsewhere
+func PowerShell() []string {
+	exe := "pwsh"
+	if runtime.GOOS == "windows" {
+		exe = "powershell"
+	}
+	return []string{exe, "-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-Command"}
+}
+
 // Run runs a shell command and returns the output
 func Run(shellCommand ShellCommand) (string, error) {
 
 	// Set default shell
 	if len(shellCommand.Shell) == 0 {
-		shellCommand.Shell = []string{"/bin/sh", "-c"}
+		shellCommand.Shell = DefaultShell()
 	}
 
 	if len(shellCommand.Command) == 0 {
@@ -31,6 +51,7 @@ func Run(shellCommand ShellCommand) (string, error) {
 	fullCommand := append(shellCommand.Shell, shellCommand.Command...)
 	cmd := exec.Command(fullCommand[0], fullCommand[1:]...)
 	cmd.Env = shellCommand.Envs
+	cmd.Dir = shellCommand.WorkDir
 
 	// Capture stdout messages
 	stdout, err := cmd.StdoutPipe()
@@ -0,0 +1,167 @@
+package slack
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/nlopes/slack"
+)
+
+// idPattern matches Slack channel, group, DM and user IDs, which messages can
+// be posted to directly
+var idPattern = regexp.MustCompile(`^[CGDUW][A-Z0-9]{8,}$`)
+
+// The Markdown constructs converted to Slack mrkdwn
+var (
+	markdownHeading = regexp.MustCompile(`^#{1,6}\s+(.*?)\s*#*$`)
+	markdownBullet  = regexp.MustCompile(`^(\s*)[-*+]\s+`)
+	markdownBold    = regexp.MustCompile(`\*\*(.+?)\*\*|__(.+?)__`)
+	markdownStrike  = regexp.MustCompile(`~~(.+?)~~`)
+	markdownLink    = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+)
+
+// ParseTargets returns the channels and users of a broadcast list, one per
+// line, in order and without duplicates.  Blank lines are skipped.
+func ParseTargets(data []byte) []string {
+
+	var targets []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		target := strings.TrimSpace(scanner.Text())
+		if target == "" || seen[target] {
+			continue
+		}
+		seen[target] = true
+		targets = append(targets, target)
+	}
+
+	return targets
+}
+
+// MarkdownToMrkdwn converts the Markdown of a message (headings, bold,
+// strikethrough, links and bullets) to Slack mrkdwn.  Code blocks are kept as
+// they are.
+func MarkdownToMrkdwn(markdown string) string {
+
+	lines := strings.Split(strings.Replace(markdown, "\r\n", "\n", -1), "\n")
+	code := false
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			code = !code
+			continue
+		}
+		if code {
+			continue
+		}
+
+		// Headings become bold, as Slack has no headings
+		if m := markdownHeading.FindStringSubmatch(line); m != nil {
+			line = "**" + m[1] + "**"
+		}
+		line = markdownBullet.ReplaceAllString(line, "$1• ")
+		line = markdownBold.ReplaceAllString(line, "*$1$2*")
+		line = markdownStrike.ReplaceAllString(line, "~$1~")
+		line = markdownLink.ReplaceAllString(line, "<$2|$1>")
+		lines[i] = line
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// RetryAfter returns how long Slack asked to wait if the error is a rate
+// limit
+func RetryAfter(err error) (time.Duration, bool) {
+	var rateLimited *slack.RateLimitedError
+	if errors.As(err, &rateLimited) {
+		return rateLimited.RetryAfter, true
+	}
+	return 0, false
+}
+
+// ResolveTargets returns the IDs to post to for broadcast targets, which are
+// channel names (with or without `#`), user names or display names with `@`,
+// email addresses, or IDs.  Messages to users are sent as direct messages.
+// Targets that can't be found are returned with their error rather than
+// failing the whole list.
+func (s *Slack) ResolveTargets(ctx context.Context, targets []string) (map[string]string, map[string]error, error) {
+
+	ids := make(map[string]string)
+	failed := make(map[string]error)
+
+	var channels map[string]string
+	var users []slack.User
+	for _, target := range targets {
+		switch {
+		case idPattern.MatchString(target):
+			ids[target] = target
+
+		case strings.HasPrefix(target, "@"):
+			if users == nil {
+				var err error
+				users, err = s.client.GetUsersContext(ctx)
+				if err != nil {
+					return nil, nil, err
+				}
+			}
+			if id := findUserID(users, strings.TrimPrefix(target, "@")); id != "" {
+				ids[target] = id
+			} else {
+				failed[target] = errors.New("Slack user " + target + " not found")
+			}
+
+		case strings.Contains(target, "@"):
+			user, err := s.client.GetUserByEmailContext(ctx, target)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, nil, ctx.Err()
+				}
+				failed[target] = errors.New("Slack user " + target + " not found: " + err.Error())
+				continue
+			}
+			ids[target] = user.ID
+
+		default:
+			if channels == nil {
+				list, err := s.client.GetChannelsContext(ctx, true)
+				if err != nil {
+					return nil, nil, err
+				}
+				channels = make(map[string]string)
+				for _, channel := range list {
+					channels[channel.Name] = channel.ID
+				}
+			}
+			if id, ok := channels[strings.TrimPrefix(target, "#")]; ok {
+				ids[target] = id
+			} else {
+				failed[target] = errors.New("Channel " + strings.TrimPrefix(target, "#") + " not found")
+			}
+		}
+	}
+
+	return ids, failed, nil
+}
+
+// PostMessageTo posts a new message to a channel or user ID and returns its
+// timestamp.  Rate limits are returned as errors (see RetryAfter) once the
+// retries of the HTTP client are used up.
+func (s *Slack) PostMessageTo(ctx context.Context, id string, msg *Message) (string, error) {
+
+	if msg.Text == "" && len(msg.Blocks) == 0 && len(msg.Attachments) == 0 {
+		return "", errors.New("Slack message text, blocks or attachments required.")
+	}
+
+	_, timestamp, err := s.postMessage(ctx, id, msg)
+	if err != nil {
+		return "", err
+	}
+
+	s.log.Debug("Slack message successfully sent at " + timestamp + " to " + id)
+	return timestamp, nil
+}
@@ -0,0 +1,45 @@
+package slack
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
+	"gotest.tools/assert"
+)
+
+func TestParseTargets(t *testing.T) {
+	targets := ParseTargets([]byte("#general\n\n  ops  \r\n@alice\njdoe@example.com\n#general\nC012AB3CD\n"))
+	assert.DeepEqual(t, targets, []string{"#general", "ops", "@alice", "jdoe@example.com", "C012AB3CD"})
+}
+
+func TestMarkdownToMrkdwn(t *testing.T) {
+	markdown := `# Maintenance tonight
+
+The **database** will be __down__ from 22:00 UTC, ~~not~~ see [the plan](https://wiki.example.com/plan).
+
+- Deploys are frozen
+* Pages go to #ops
+
+` + "```\n# not a heading **kept**\n```\n"
+
+	assert.Equal(t, MarkdownToMrkdwn(markdown), `*Maintenance tonight*
+
+The *database* will be *down* from 22:00 UTC, ~not~ see <https://wiki.example.com/plan|the plan>.
+
+• Deploys are frozen
+• Pages go to #ops
+
+`+"```\n# not a heading **kept**\n```")
+}
+
+func TestRetryAfter(t *testing.T) {
+	retry, ok := RetryAfter(fmt.Errorf("posting: %w", &slack.RateLimitedError{RetryAfter: 30 * time.Second}))
+	assert.Assert(t, ok)
+	assert.Equal(t, retry, 30*time.Second)
+
+	_, ok = RetryAfter(errors.New("channel_not_found"))
+	assert.Assert(t, !ok)
+}
@@ -0,0 +1,150 @@
+package slack
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/skratchdot/open-golang/open"
+)
+
+// oauthAuthorizeURL is the Slack page that users approve the app on
+const oauthAuthorizeURL = "https://slack.com/oauth/authorize"
+
+// oauthCallbackTimeout is how long the user has to approve the app
+const oauthCallbackTimeout = 2 * time.Minute
+
+// OAuthConfig describes the Slack app to authorize
+type OAuthConfig struct {
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+
+	// CallbackPort is the local port that Slack redirects to, which must match
+	// a redirect URL of the app (`http://localhost:<port>/slack/callback`)
+	CallbackPort int
+
+	// Team is the ID of the workspace to authorize, the user picks one if empty
+	Team string
+
+	Log Logger
+}
+
+// OAuthToken is the token of an authorized workspace
+type OAuthToken struct {
+	Token    string
+	TeamID   string
+	TeamName string
+	UserID   string
+}
+
+// Authorize runs the Slack OAuth flow.  The approval page is opened in the
+// browser and a local listener receives the callback, whose code is then
+// exchanged for a token.
+func Authorize(config *OAuthConfig) (*OAuthToken, error) {
+
+	if config.ClientID == "" || config.ClientSecret == "" {
+		return nil, errors.New("The client ID and secret of the Slack app are required")
+	}
+
+	listenAddress := fmt.Sprintf("localhost:%d", config.CallbackPort)
+	redirectURI := fmt.Sprintf("http://%s/slack/callback", listenAddress)
+
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	state := hex.EncodeToString(b)
+
+	listener, err := net.Listen("tcp", listenAddress)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to start Slack callback listener on %s: %v", listenAddress, err)
+	}
+
+	type callbackResult struct {
+		token *OAuthToken
+		err   error
+	}
+	results := make(chan callbackResult, 1)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slack/callback", func(w http.ResponseWriter, r *http.Request) {
+		token, err := exchangeCode(config, r.URL.Query(), state, redirectURI)
+		if err != nil {
+			fmt.Fprintln(w, "Slack authorization failed, you may close this window and check the terminal for details.")
+		} else {
+			fmt.Fprintln(w, "Slack authorization successful, you may close this window.")
+		}
+
+		// Only the first callback is used, ignore any others (ex. browser retries)
+		select {
+		case results <- callbackResult{token: token, err: err}:
+		default:
+		}
+	})
+
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	defer server.Shutdown(context.Background())
+
+	authURL := authorizeURL(config, redirectURI, state)
+	fmt.Println("Approve the stim Slack app in your browser. Launching browser to:")
+	fmt.Printf("\n    %s\n\n", authURL)
+	err = open.Start(authURL)
+	if err != nil && config.Log != nil {
+		config.Log.Warn("Unable to launch browser, please open the URL above manually: {}", err)
+	}
+
+	select {
+	case result := <-results:
+		return result.token, result.err
+	case <-time.After(oauthCallbackTimeout):
+		return nil, errors.New("Timed out waiting for Slack authorization to complete")
+	}
+}
+
+// authorizeURL returns the URL of the approval page
+func authorizeURL(config *OAuthConfig, redirectURI string, state string) string {
+
+	query := url.Values{}
+	query.Set("client_id", config.ClientID)
+	query.Set("scope", strings.Join(config.Scopes, ","))
+	query.Set("redirect_uri", redirectURI)
+	query.Set("state", state)
+	if config.Team != "" {
+		query.Set("team", config.Team)
+	}
+
+	return oauthAuthorizeURL + "?" + query.Encode()
+}
+
+// exchangeCode checks the callback query and exchanges its code for a token
+func exchangeCode(config *OAuthConfig, query url.Values, state string, redirectURI string) (*OAuthToken, error) {
+
+	if message := query.Get("error"); message != "" {
+		return nil, fmt.Errorf("Slack authorization failed: %s", message)
+	}
+	if query.Get("state") != state {
+		return nil, errors.New("Slack authorization failed: the state of the callback does not match")
+	}
+
+	response, err := slack.GetOAuthResponse(http.DefaultClient, config.ClientID, config.ClientSecret, query.Get("code"), redirectURI)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to exchange the Slack authorization code for a token: %v", err)
+	}
+
+	return &OAuthToken{
+		Token:    response.AccessToken,
+		TeamID:   response.TeamID,
+		TeamName: response.TeamName,
+		UserID:   response.UserID,
+	}, nil
+}
@@ -0,0 +1,31 @@
+package slack
+
+import (
+	"net/url"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestAuthorizeURL(t *testing.T) {
+	config := &OAuthConfig{ClientID: "123.456", Scopes: []string{"chat:write:user", "users:read"}}
+
+	u, err := url.Parse(authorizeURL(config, "http://localhost:8251/slack/callback", "abc"))
+	assert.NilError(t, err)
+	assert.Equal(t, u.Scheme+"://"+u.Host+u.Path, oauthAuthorizeURL)
+	assert.Equal(t, u.Query().Get("client_id"), "123.456")
+	assert.Equal(t, u.Query().Get("scope"), "chat:write:user,users:read")
+	assert.Equal(t, u.Query().Get("redirect_uri"), "http://localhost:8251/slack/callback")
+	assert.Equal(t, u.Query().Get("state"), "abc")
+	assert.Equal(t, u.Query().Get("team"), "")
+}
+
+func TestExchangeCodeErrors(t *testing.T) {
+	config := &OAuthConfig{ClientID: "123.456", ClientSecret: "secret"}
+
+	_, err := exchangeCode(config, url.Values{"error": {"access_denied"}}, "abc", "")
+	assert.Error(t, err, "Slack authorization failed: access_denied")
+
+	_, err = exchangeCode(config, url.Values{"code": {"c"}, "state": {"other"}}, "abc", "")
+	assert.Error(t, err, "Slack authorization failed: the state of the callback does not match")
+}
@@ -0,0 +1,27 @@
+package slack
+
+import (
+	"github.com/nlopes/slack"
+)
+
+// GetReactions returns the IDs of the users that reacted to a message, by
+// reaction name (ex. `white_check_mark`)
+func (s *Slack) GetReactions(channel string, timestamp string) (map[string][]string, error) {
+
+	id, err := s.getChannelIdByName(channel)
+	if err != nil {
+		return nil, err
+	}
+
+	reactions, err := s.client.GetReactions(slack.ItemRef{Channel: id, Timestamp: timestamp}, slack.GetReactionsParameters{Full: true})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]string)
+	for _, reaction := range reactions {
+		result[reaction.Name] = reaction.Users
+	}
+
+	return result, nil
+}
@@ -1,10 +1,13 @@
 package slack
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 
 	"github.com/PremiereGlobal/stim/pkg/stimlog"
 	"github.com/nlopes/slack"
+	"sigs.k8s.io/yaml"
 )
 
 // Slack is the main object
@@ -26,6 +29,28 @@ type Message struct {
 	Username string
 	Text     string
 	IconUrl  string
+
+	// Blocks are Block Kit layout blocks
+	Blocks []slack.Block
+
+	// Attachments are legacy message attachments (ex. for colored messages)
+	Attachments []slack.Attachment
+
+	// ThreadTS is the timestamp of the parent message to reply to
+	ThreadTS string
+
+	// Broadcast also posts a thread reply to the channel
+	Broadcast bool
+
+	// UpdateTS is the timestamp of an existing message to replace
+	UpdateTS string
+}
+
+// Payload is a rich message payload in the format of the Slack API
+type Payload struct {
+	Text        string             `json:"text"`
+	Blocks      slack.Blocks       `json:"blocks"`
+	Attachments []slack.Attachment `json:"attachments"`
 }
 
 type Logger interface {
@@ -64,6 +89,11 @@ func (s *Slack) GetChannels() ([]string, error) {
 	return result, nil
 }
 
+// GetChannelID returns the ID of the channel with the given name
+func (s *Slack) GetChannelID(name string) (string, error) {
+	return s.getChannelIdByName(name)
+}
+
 func (s *Slack) getChannelIdByName(name string) (string, error) {
 	channels, err := s.client.GetChannels(false)
 	if err != nil {
@@ -79,18 +109,103 @@ func (s *Slack) getChannelIdByName(name string) (string, error) {
 	return "", errors.New("Channel " + name + " not found")
 }
 
+// GetMessageText returns the current text of the message with the timestamp
+// in the channel (by ID)
+func (s *Slack) GetMessageText(channelID string, timestamp string) (string, error) {
+
+	history, err := s.client.GetConversationHistory(&slack.GetConversationHistoryParameters{
+		ChannelID: channelID,
+		Latest:    timestamp,
+		Oldest:    timestamp,
+		Inclusive: true,
+		Limit:     1,
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(history.Messages) == 0 || history.Messages[0].Timestamp != timestamp {
+		return "", errors.New("Message " + timestamp + " not found")
+	}
+
+	return history.Messages[0].Text, nil
+}
+
+// ParsePayload parses a JSON or YAML rich message payload.  The payload can
+// contain text, blocks and attachments, ex. as generated by Block Kit Builder.
+func ParsePayload(data []byte) (*Payload, error) {
+
+	// YAML is a superset of JSON so this handles both
+	j, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := &Payload{}
+	err = json.Unmarshal(j, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
 // PostMessage posts a message to a Slack channel with the provided
-// Message parameters
-func (s *Slack) PostMessage(msg *Message) error {
+// Message parameters, or updates an existing message if UpdateTS is set.
+// Returns the timestamp of the message.
+func (s *Slack) PostMessage(msg *Message) (string, error) {
 
-	if msg.Text == "" {
-		return errors.New("Slack message text required.")
+	if msg.Text == "" && len(msg.Blocks) == 0 && len(msg.Attachments) == 0 {
+		return "", errors.New("Slack message text, blocks or attachments required.")
 	}
 
 	id, err := s.getChannelIdByName(msg.Channel)
 	if err != nil {
-		return err
+		return "", err
+	}
+
+	if msg.UpdateTS != "" {
+		channelId, timestamp, _, err := s.client.UpdateMessage(id, msg.UpdateTS, messageContent(msg)...)
+		if err != nil {
+			return "", err
+		}
+
+		s.log.Debug("Slack message " + timestamp + " successfully updated in channel " + msg.Channel + " (" + channelId + ")")
+		return timestamp, nil
+	}
+
+	channelId, timestamp, err := s.postMessage(context.Background(), id, msg)
+	if err != nil {
+		return "", err
+	}
+
+	s.log.Debug("Slack message successfully sent at " + timestamp + " to channel " + msg.Channel + " (" + channelId + ")")
+
+	return timestamp, nil
+}
+
+// messageContent returns the options of the text, blocks and attachments of
+// a message
+func messageContent(msg *Message) []slack.MsgOption {
+
+	options := []slack.MsgOption{}
+	if msg.Text != "" {
+		options = append(options, slack.MsgOptionText(msg.Text, false))
+	}
+	if len(msg.Blocks) > 0 {
+		options = append(options, slack.MsgOptionBlocks(msg.Blocks...))
 	}
+	if len(msg.Attachments) > 0 {
+		options = append(options, slack.MsgOptionAttachments(msg.Attachments...))
+	}
+
+	return options
+}
+
+// postMessage posts a new message to a channel ID and returns the channel ID
+// and timestamp of the message
+func (s *Slack) postMessage(ctx context.Context, id string, msg *Message) (string, string, error) {
+
+	options := messageContent(msg)
 
 	parameters := slack.NewPostMessageParameters()
 	if msg.Username != "" {
@@ -99,13 +214,14 @@ func (s *Slack) PostMessage(msg *Message) error {
 	if msg.IconUrl != "" {
 		parameters.IconURL = msg.IconUrl
 	}
+	options = append(options, slack.MsgOptionPostMessageParameters(parameters))
 
-	channelId, timestamp, err := s.client.PostMessage(id, slack.MsgOptionText(msg.Text, false), slack.MsgOptionPostMessageParameters(parameters))
-	if err != nil {
-		return err
+	if msg.ThreadTS != "" {
+		options = append(options, slack.MsgOptionTS(msg.ThreadTS))
+		if msg.Broadcast {
+			options = append(options, slack.MsgOptionBroadcast())
+		}
 	}
 
-	s.log.Debug("Slack message successfully sent at " + timestamp + " to channel " + msg.Channel + " (" + channelId + ")")
-
-	return nil
+	return s.client.PostMessageContext(ctx, id, options...)
 }
@@ -0,0 +1,179 @@
+package slack
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/nlopes/slack"
+)
+
+// topicSeparator separates the text of a topic from the captain mention
+const topicSeparator = " | "
+
+// captainPattern matches the captain segment at the end of a topic, ex.
+// `:ship: captain: <@U012AB3CD>`
+var captainPattern = regexp.MustCompile(`^(?:(:[\w+-]+:) )?captain: <@(\w+)>$`)
+
+// Topic is a channel topic made of free text and an optional captain mention
+type Topic struct {
+	Text    string
+	Emoji   string
+	Captain string
+}
+
+// ParseTopic splits a channel topic into its text and captain mention
+func ParseTopic(topic string) *Topic {
+
+	i := strings.LastIndex(topic, topicSeparator)
+	if i >= 0 {
+		if m := captainPattern.FindStringSubmatch(topic[i+len(topicSeparator):]); m != nil {
+			return &Topic{Text: topic[:i], Emoji: m[1], Captain: m[2]}
+		}
+	}
+	if m := captainPattern.FindStringSubmatch(topic); m != nil {
+		return &Topic{Emoji: m[1], Captain: m[2]}
+	}
+
+	return &Topic{Text: topic}
+}
+
+// String returns the topic in the format used by Slack
+func (t *Topic) String() string {
+
+	if t.Captain == "" {
+		return t.Text
+	}
+
+	captain := "captain: <@" + t.Captain + ">"
+	if t.Emoji != "" {
+		captain = t.Emoji + " " + captain
+	}
+	if t.Text == "" {
+		return captain
+	}
+
+	return t.Text + topicSeparator + captain
+}
+
+// NextCaptain returns the user after the current captain in the rotation.
+// The first user is returned if the current captain is not in the rotation.
+func NextCaptain(rotation []string, current string) (string, error) {
+
+	if len(rotation) == 0 {
+		return "", errors.New("Captain rotation is empty")
+	}
+
+	for i, user := range rotation {
+		if user == current {
+			return rotation[(i+1)%len(rotation)], nil
+		}
+	}
+
+	return rotation[0], nil
+}
+
+// GetChannelTopic returns the topic of a channel
+func (s *Slack) GetChannelTopic(name string) (string, error) {
+
+	channels, err := s.client.GetChannels(false)
+	if err != nil {
+		return "", err
+	}
+
+	for _, channel := range channels {
+		if channel.Name == name {
+			return channel.Topic.Value, nil
+		}
+	}
+
+	return "", errors.New("Channel " + name + " not found")
+}
+
+// SetChannelTopic sets the topic of a channel
+func (s *Slack) SetChannelTopic(name string, topic string) error {
+
+	id, err := s.getChannelIdByName(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.SetTopicOfConversation(id, topic)
+	if err != nil {
+		return err
+	}
+
+	s.log.Debug("Slack channel " + name + " topic set to: " + topic)
+
+	return nil
+}
+
+// GetUserID returns the ID of the user with the given email address, user
+// name or display name
+func (s *Slack) GetUserID(user string) (string, error) {
+
+	ids, err := s.GetUserIDs([]string{user})
+	if err != nil {
+		return "", err
+	}
+
+	return ids[0], nil
+}
+
+// GetUserIDs returns the IDs of the users with the given email addresses,
+// user names or display names.  The workspace user list is fetched at most
+// once.
+func (s *Slack) GetUserIDs(users []string) ([]string, error) {
+
+	var all []slack.User
+	ids := make([]string, len(users))
+	for i, user := range users {
+		user = strings.TrimPrefix(user, "@")
+
+		if strings.Contains(user, "@") {
+			u, err := s.client.GetUserByEmail(user)
+			if err != nil {
+				return nil, errors.New("Slack user " + user + " not found: " + err.Error())
+			}
+			ids[i] = u.ID
+			continue
+		}
+
+		if all == nil {
+			var err error
+			all, err = s.client.GetUsers()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		ids[i] = findUserID(all, user)
+		if ids[i] == "" {
+			return nil, errors.New("Slack user " + user + " not found")
+		}
+	}
+
+	return ids, nil
+}
+
+// GetUserName returns the user name of the user with the given ID
+func (s *Slack) GetUserName(id string) (string, error) {
+
+	user, err := s.client.GetUserInfo(id)
+	if err != nil {
+		return "", errors.New("Slack user " + id + " not found: " + err.Error())
+	}
+
+	return user.Name, nil
+}
+
+// findUserID returns the ID of the active user with the given user name,
+// display name or ID, or an empty string if there is none
+func findUserID(users []slack.User, user string) string {
+	for _, u := range users {
+		if !u.Deleted && (u.Name == user || u.Profile.DisplayName == user || u.ID == user) {
+			return u.ID
+		}
+	}
+	return ""
+}
@@ -0,0 +1,69 @@
+package slack
+
+import (
+	"testing"
+
+	"github.com/nlopes/slack"
+	"gotest.tools/assert"
+)
+
+func TestParseTopic(t *testing.T) {
+	tests := []struct {
+		topic    string
+		expected Topic
+	}{
+		{"", Topic{}},
+		{"train 42 — cut Friday", Topic{Text: "train 42 — cut Friday"}},
+		{"train 42 | :ship: captain: <@U012AB3CD>", Topic{Text: "train 42", Emoji: ":ship:", Captain: "U012AB3CD"}},
+		{"a | b | captain: <@U1>", Topic{Text: "a | b", Captain: "U1"}},
+		{":ship: captain: <@U1>", Topic{Emoji: ":ship:", Captain: "U1"}},
+		{"deploys | ask in #help", Topic{Text: "deploys | ask in #help"}},
+	}
+
+	for _, test := range tests {
+		actual := ParseTopic(test.topic)
+		assert.DeepEqual(t, *actual, test.expected)
+		assert.Equal(t, actual.String(), test.topic)
+	}
+}
+
+func TestTopicString(t *testing.T) {
+	topic := &Topic{Text: "train 42"}
+	assert.Equal(t, topic.String(), "train 42")
+
+	topic.Captain = "U2"
+	topic.Emoji = ":crown:"
+	assert.Equal(t, topic.String(), "train 42 | :crown: captain: <@U2>")
+}
+
+func TestNextCaptain(t *testing.T) {
+	rotation := []string{"U1", "U2", "U3"}
+
+	next, err := NextCaptain(rotation, "U1")
+	assert.NilError(t, err)
+	assert.Equal(t, next, "U2")
+
+	next, err = NextCaptain(rotation, "U3")
+	assert.NilError(t, err)
+	assert.Equal(t, next, "U1")
+
+	next, err = NextCaptain(rotation, "")
+	assert.NilError(t, err)
+	assert.Equal(t, next, "U1")
+
+	_, err = NextCaptain(nil, "U1")
+	assert.Error(t, err, "Captain rotation is empty")
+}
+
+func TestFindUserID(t *testing.T) {
+	users := []slack.User{
+		{ID: "U1", Name: "alice", Deleted: true},
+		{ID: "U2", Name: "bob", Profile: slack.UserProfile{DisplayName: "Bobby"}},
+		{ID: "U3", Name: "alice"},
+	}
+
+	assert.Equal(t, findUserID(users, "alice"), "U3")
+	assert.Equal(t, findUserID(users, "Bobby"), "U2")
+	assert.Equal(t, findUserID(users, "U2"), "U2")
+	assert.Equal(t, findUserID(users, "carol"), "")
+}
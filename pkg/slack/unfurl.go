@@ -0,0 +1,161 @@
+package slack
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/nlopes/slack"
+)
+
+// maxEventSize is the largest Slack event request that is read
+const maxEventSize = 1 << 20
+
+// Unfurler returns the preview of a link, or nil if the link is not one it
+// knows how to preview
+type Unfurler func(url string) (*slack.Attachment, error)
+
+// eventEnvelope is the outer payload of a Slack Events API request
+type eventEnvelope struct {
+	Type      string          `json:"type"`
+	Challenge string          `json:"challenge"`
+	Event     json.RawMessage `json:"event"`
+}
+
+// LinkSharedEvent is sent when a message contains links to the domains
+// registered for unfurling
+type LinkSharedEvent struct {
+	Type      string `json:"type"`
+	Channel   string `json:"channel"`
+	MessageTS string `json:"message_ts"`
+	Links     []struct {
+		Domain string `json:"domain"`
+		URL    string `json:"url"`
+	} `json:"links"`
+}
+
+// ReactionAddedEvent is sent when a reaction is added to a message in a
+// channel the app is in
+type ReactionAddedEvent struct {
+	Type     string `json:"type"`
+	User     string `json:"user"`
+	Reaction string `json:"reaction"`
+	Item     struct {
+		Type    string `json:"type"`
+		Channel string `json:"channel"`
+		TS      string `json:"ts"`
+	} `json:"item"`
+}
+
+// ReactionHandler handles a reaction added to a message
+type ReactionHandler func(event *ReactionAddedEvent)
+
+// UnfurlHandler is an HTTP handler for the Slack Events API that previews
+// the links of `link_shared` events and passes `reaction_added` events to a
+// reaction handler, if set.  Requests are verified with the signing secret of
+// the Slack app.
+type UnfurlHandler struct {
+	slack         *Slack
+	signingSecret string
+	unfurler      Unfurler
+	onReaction    ReactionHandler
+}
+
+// NewUnfurlHandler returns an unfurl handler that previews links with the
+// unfurler
+func (s *Slack) NewUnfurlHandler(signingSecret string, unfurler Unfurler) (*UnfurlHandler, error) {
+	if signingSecret == "" {
+		return nil, errors.New("A Slack signing secret is required to verify events")
+	}
+	return &UnfurlHandler{slack: s, signingSecret: signingSecret, unfurler: unfurler}, nil
+}
+
+// OnReactionAdded sets the handler of `reaction_added` events
+func (h *UnfurlHandler) OnReactionAdded(handler ReactionHandler) {
+	h.onReaction = handler
+}
+
+// ServeHTTP verifies and handles a Slack event.  Events are handled after
+// responding since Slack expects a response within 3 seconds.
+func (h *UnfurlHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxEventSize))
+	if err != nil {
+		http.Error(w, "unable to read request", http.StatusBadRequest)
+		return
+	}
+
+	verifier, err := slack.NewSecretsVerifier(r.Header, h.signingSecret)
+	if err == nil {
+		verifier.Write(body)
+		err = verifier.Ensure()
+	}
+	if err != nil {
+		h.slack.log.Debug("Rejected Slack event: {}", err)
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var envelope eventEnvelope
+	err = json.Unmarshal(body, &envelope)
+	if err != nil {
+		http.Error(w, "invalid event", http.StatusBadRequest)
+		return
+	}
+
+	switch envelope.Type {
+	case "url_verification":
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(envelope.Challenge))
+		return
+	case "event_callback":
+		var eventType struct {
+			Type string `json:"type"`
+		}
+		json.Unmarshal(envelope.Event, &eventType)
+		switch eventType.Type {
+		case "link_shared":
+			var event LinkSharedEvent
+			if json.Unmarshal(envelope.Event, &event) == nil {
+				go h.unfurl(&event)
+			}
+		case "reaction_added":
+			var event ReactionAddedEvent
+			if h.onReaction != nil && json.Unmarshal(envelope.Event, &event) == nil {
+				go h.onReaction(&event)
+			}
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// unfurl previews the links of the event that the unfurler knows
+func (h *UnfurlHandler) unfurl(event *LinkSharedEvent) {
+
+	unfurls := make(map[string]slack.Attachment)
+	for _, link := range event.Links {
+		attachment, err := h.unfurler(link.URL)
+		if err != nil {
+			h.slack.log.Warn("Unable to preview {}: {}", link.URL, err)
+			continue
+		}
+		if attachment != nil {
+			unfurls[link.URL] = *attachment
+		}
+	}
+	if len(unfurls) == 0 {
+		return
+	}
+
+	_, _, _, err := h.slack.client.UnfurlMessage(event.Channel, event.MessageTS, unfurls)
+	if err != nil {
+		h.slack.log.Warn("Unable to unfurl links in {}: {}", event.Channel, err)
+	}
+}
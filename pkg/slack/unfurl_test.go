@@ -0,0 +1,97 @@
+package slack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nlopes/slack"
+	"gotest.tools/assert"
+)
+
+// signedEvent returns a Slack event request signed with the secret
+func signedEvent(secret string, body string) *http.Request {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":" + body))
+
+	r := httptest.NewRequest(http.MethodPost, "/slack/events", strings.NewReader(body))
+	r.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	r.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return r
+}
+
+func TestUnfurlHandler(t *testing.T) {
+	s, err := New(&Config{})
+	assert.NilError(t, err)
+
+	_, err = s.NewUnfurlHandler("", nil)
+	assert.ErrorContains(t, err, "signing secret")
+
+	unfurled := make(chan string, 1)
+	handler, err := s.NewUnfurlHandler("shh", func(url string) (*slack.Attachment, error) {
+		unfurled <- url
+		return nil, nil
+	})
+	assert.NilError(t, err)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, signedEvent("shh", `{"type":"url_verification","challenge":"abc123"}`))
+	assert.Equal(t, w.Code, http.StatusOK)
+	assert.Equal(t, w.Body.String(), "abc123")
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, signedEvent("wrong", `{"type":"url_verification","challenge":"abc123"}`))
+	assert.Equal(t, w.Code, http.StatusUnauthorized)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slack/events", nil))
+	assert.Equal(t, w.Code, http.StatusMethodNotAllowed)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, signedEvent("shh", `{"type":"event_callback","event":{"type":"link_shared","channel":"C1","message_ts":"1.2","links":[{"domain":"vault","url":"https://vault/ui"}]}}`))
+	assert.Equal(t, w.Code, http.StatusOK)
+	select {
+	case url := <-unfurled:
+		assert.Equal(t, url, "https://vault/ui")
+	case <-time.After(time.Second):
+		t.Fatal("link was not unfurled")
+	}
+}
+
+func TestUnfurlHandlerReactions(t *testing.T) {
+	s, err := New(&Config{})
+	assert.NilError(t, err)
+
+	handler, err := s.NewUnfurlHandler("shh", nil)
+	assert.NilError(t, err)
+
+	// Without a reaction handler, reactions are ignored
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, signedEvent("shh", `{"type":"event_callback","event":{"type":"reaction_added","user":"U1","reaction":"x","item":{"type":"message","channel":"C1","ts":"1.2"}}}`))
+	assert.Equal(t, w.Code, http.StatusOK)
+
+	reactions := make(chan *ReactionAddedEvent, 1)
+	handler.OnReactionAdded(func(event *ReactionAddedEvent) {
+		reactions <- event
+	})
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, signedEvent("shh", `{"type":"event_callback","event":{"type":"reaction_added","user":"U1","reaction":"white_check_mark","item":{"type":"message","channel":"C1","ts":"1.2"}}}`))
+	assert.Equal(t, w.Code, http.StatusOK)
+	select {
+	case event := <-reactions:
+		assert.Equal(t, event.User, "U1")
+		assert.Equal(t, event.Reaction, "white_check_mark")
+		assert.Equal(t, event.Item.Channel, "C1")
+		assert.Equal(t, event.Item.TS, "1.2")
+	case <-time.After(time.Second):
+		t.Fatal("reaction was not handled")
+	}
+}
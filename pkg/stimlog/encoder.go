@@ -0,0 +1,133 @@
+package stimlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Format is the encoding of log messages
+type Format string
+
+const (
+	// ConsoleFormat is a line of text per message, with its timestamp and
+	// level
+	ConsoleFormat Format = "console"
+	// JSONFormat is a JSON object per line, with the `time`, `level`,
+	// `component` and `msg` of the message along with its fields
+	JSONFormat Format = "json"
+)
+
+// Field is structured data of a message.  Fields are passed along with the
+// placeholder values of a message (ex. `log.Info("Deployed {}", name,
+// stimlog.Field{Key: "instance", Value: instance})`) and are not part of its
+// text.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// levelNames are the names of the levels, as set in the config
+var levelNames = map[Level]string{
+	FatalLevel:   "error",
+	WarnLevel:    "warn",
+	InfoLevel:    "info",
+	VerboseLevel: "verbose",
+	DebugLevel:   "debug",
+	TraceLevel:   "trace",
+}
+
+// levelName returns the name of the level
+func levelName(l Level) string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("%d", l)
+}
+
+// ParseLevel returns the level of a name (ex. `debug`).  `fatal` is the same
+// as `error`, which only logs the errors that stop stim.
+func ParseLevel(name string) (Level, error) {
+	name = strings.ToLower(name)
+	if name == "fatal" {
+		return FatalLevel, nil
+	}
+	for level, levelName := range levelNames {
+		if levelName == name {
+			return level, nil
+		}
+	}
+	return defaultLevel, fmt.Errorf("Invalid log level '%s', must be one of trace, debug, verbose, info, warn or error", name)
+}
+
+// ParseFormat returns the format of a name (`console` or `json`)
+func ParseFormat(name string) (Format, error) {
+	switch Format(strings.ToLower(name)) {
+	case ConsoleFormat:
+		return ConsoleFormat, nil
+	case JSONFormat:
+		return JSONFormat, nil
+	}
+	return "", fmt.Errorf("Invalid log format '%s', must be console or json", name)
+}
+
+// encode encodes the message as a line of the format
+func (stimLogger *fullStimLogger) encode(format Format, lm *logMessage) string {
+	if format == JSONFormat {
+		return stimLogger.formatJSONMessage(lm)
+	}
+	return stimLogger.formatLogMessage(lm)
+}
+
+// formatJSONMessage encodes the message as a line of the JSON format.  The
+// keys are written in a fixed order so logs are easy to read as well.
+func (stimLogger *fullStimLogger) formatJSONMessage(lm *logMessage) string {
+
+	var b bytes.Buffer
+	b.WriteString("{")
+	writeJSONField(&b, "time", lm.ltime.Format(time.RFC3339Nano), true)
+	writeJSONField(&b, "level", levelName(lm.logLevel), false)
+
+	component := lm.prefix
+	if component == "" {
+		component = stimLogger.component
+	}
+	if component != "" {
+		writeJSONField(&b, "component", component, false)
+	}
+	writeJSONField(&b, "msg", lm.text(), false)
+	for _, field := range lm.fields {
+		switch field.Key {
+		case "time", "level", "component", "msg":
+			// Fields can't replace the keys of the message
+			writeJSONField(&b, "field."+field.Key, field.Value, false)
+		default:
+			writeJSONField(&b, field.Key, field.Value, false)
+		}
+	}
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+// writeJSONField writes a key and value of a JSON object.  Values that can't
+// be encoded (ex. channels) are written as text.
+func writeJSONField(b *bytes.Buffer, key string, value interface{}, first bool) {
+	if !first {
+		b.WriteString(",")
+	}
+	k, _ := json.Marshal(key)
+	b.Write(k)
+	b.WriteString(":")
+
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	}
+	v, err := json.Marshal(value)
+	if err != nil {
+		v, _ = json.Marshal(fmt.Sprintf("%v", value))
+	}
+	b.Write(v)
+}
@@ -0,0 +1,57 @@
+package stimlog
+
+import (
+	"fmt"
+	"os"
+)
+
+// open opens the file to append to, creating it if needed
+func (lf *logFile) open() error {
+	fp, err := os.OpenFile(lf.path, os.O_RDWR|os.O_CREATE, 0750)
+	if err != nil {
+		return err
+	}
+	fs, err := fp.Stat()
+	if err != nil {
+		fp.Close()
+		return err
+	}
+	fp.Seek(fs.Size(), 0)
+	lf.fp = fp
+	lf.size = fs.Size()
+	return nil
+}
+
+// rotate moves the file to `<path>.1` (and the older backups up by one,
+// dropping the ones past maxBackups) and starts a new file
+func (lf *logFile) rotate() error {
+
+	lf.fp.Close()
+
+	if lf.maxBackups < 1 {
+		err := os.Remove(lf.path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return lf.open()
+	}
+
+	os.Remove(backupPath(lf.path, lf.maxBackups))
+	for i := lf.maxBackups - 1; i >= 1; i-- {
+		err := os.Rename(backupPath(lf.path, i), backupPath(lf.path, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	err := os.Rename(lf.path, backupPath(lf.path, 1))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return lf.open()
+}
+
+// backupPath returns the path of a rotated log file
+func backupPath(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
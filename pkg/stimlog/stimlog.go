@@ -25,6 +25,7 @@ type StimLogger interface {
 	Info(...interface{})
 	Warn(...interface{})
 	Fatal(...interface{})
+	Exit(int, ...interface{})
 	GetLogLevel() Level
 }
 type StimLoggerConfig interface {
@@ -32,11 +33,15 @@ type StimLoggerConfig interface {
 	SetLevel(Level)
 	SetDateFormat(string)
 	AddLogFile(string, Level) error
+	AddLogFileOptions(string, LogFileOptions) error
 	RemoveLogFile(string)
 	ForceFlush(bool)
 	Flush()
 	EnableLevelLogging(bool)
 	EnableTimeLogging(bool)
+	SetFormat(Format)
+	SetComponent(string)
+	AddExitHook(func(string))
 }
 
 // Level is the Level of logging set in stim
@@ -44,6 +49,8 @@ type Level int32
 
 const (
 	defaultLevel Level = -1
+	//DefaultLevel makes a log file follow the level set with SetLevel
+	DefaultLevel Level = defaultLevel
 	//FatalLevel this is used to log an error that will cause fatal problems in the program
 	FatalLevel Level = 0
 	//WarnLevel is logging for interesting events that need to be known about but are not crazy
@@ -59,6 +66,29 @@ type logFile struct {
 	path     string
 	logLevel Level
 	fp       *os.File
+
+	// format is the encoding of the file, the format set with SetFormat if
+	// empty
+	format Format
+
+	// size is the size of the file, tracked for rotation when maxSize is set
+	size       int64
+	maxSize    int64
+	maxBackups int
+}
+
+// LogFileOptions are the options of a log file added with AddLogFileOptions
+type LogFileOptions struct {
+	Level Level
+
+	// Format is the encoding of the file.  The format set with SetFormat is
+	// used if empty.
+	Format Format
+
+	// MaxSize is the size in bytes the file is rotated at, 0 to never rotate
+	// it.  MaxBackups rotated files are kept (`<path>.1` being the newest).
+	MaxSize    int64
+	MaxBackups int
 }
 
 type logMessage struct {
@@ -67,6 +97,10 @@ type logMessage struct {
 	msg      string
 	args     []interface{}
 	wg       *sync.WaitGroup
+
+	// prefix is the prefix of a logger from GetLoggerWithPrefix
+	prefix string
+	fields []Field
 }
 
 type fullStimLogger struct {
@@ -79,6 +113,9 @@ type fullStimLogger struct {
 	forceFlush   bool
 	logLevel     bool
 	logTime      bool
+	exitHooks    []func(string)
+	format       Format
+	component    string
 	// wqc          *sync.Cond
 }
 
@@ -122,6 +159,7 @@ func GetLogger() StimLogger {
 				forceFlush:   true,
 				logLevel:     true,
 				logTime:      true,
+				format:       ConsoleFormat,
 			}
 			logger.AddLogFile("STDOUT", defaultLevel)
 			go logger.writeLogQueue()
@@ -187,27 +225,29 @@ func (stimLogger *fullStimLogger) RemoveLogFile(file string) {
 
 //AddLogFile adds logging of a file (can be STDOUT/STDERR too)
 func (stimLogger *fullStimLogger) AddLogFile(file string, logLevel Level) error {
-	var fp *os.File
-	var err error
+	return stimLogger.AddLogFileOptions(file, LogFileOptions{Level: logLevel})
+}
+
+// AddLogFileOptions adds logging of a file (can be STDOUT/STDERR too) with
+// its own format and rotation
+func (stimLogger *fullStimLogger) AddLogFileOptions(file string, options LogFileOptions) error {
+	lf := &logFile{path: file, logLevel: options.Level, format: options.Format}
 	if file == "STDOUT" {
-		fp = os.Stdout
+		lf.fp = os.Stdout
 	} else if file == "STDERR" {
-		fp = os.Stderr
+		lf.fp = os.Stderr
 	} else {
-		fp, err = os.OpenFile(file, os.O_RDWR|os.O_CREATE, 0750)
-		if err != nil {
-			return err
-		}
-		fs, err := fp.Stat()
+		err := lf.open()
 		if err != nil {
 			return err
 		}
-		fp.Seek(fs.Size(), 0)
+		lf.maxSize = options.MaxSize
+		lf.maxBackups = options.MaxBackups
 	}
-	if logLevel > stimLogger.highestLevel {
-		stimLogger.highestLevel = logLevel
+	if options.Level > stimLogger.highestLevel {
+		stimLogger.highestLevel = options.Level
 	}
-	stimLogger.logfiles.Set(file, &logFile{path: file, logLevel: logLevel, fp: fp})
+	stimLogger.logfiles.Set(file, lf)
 	return nil
 }
 
@@ -218,7 +258,7 @@ func (stimLogger *fullStimLogger) writeLogQueue() {
 	for {
 		select {
 		case lm := <-stimLogger.logQueue:
-			stimLogger.writeLogs(lm.logLevel, lm.wg != nil, stimLogger.formatLogMessage(lm))
+			stimLogger.writeLogs(lm)
 			if lm.wg != nil {
 				lm.wg.Done()
 				syncDelay = syncNone
@@ -237,6 +277,7 @@ func (stimLogger *fullStimLogger) writeLogQueue() {
 	}
 }
 
+// formatLogMessage encodes the message as a line of the console format
 func (stimLogger *fullStimLogger) formatLogMessage(lm *logMessage) string {
 	var sb strings.Builder
 	if stimLogger.logTime {
@@ -262,8 +303,26 @@ func (stimLogger *fullStimLogger) formatLogMessage(lm *logMessage) string {
 		sb.WriteString("\t")
 	}
 
-	subs := strings.Split(lm.msg, subSTR)
+	if lm.prefix != "" {
+		sb.WriteString(lm.prefix)
+		sb.WriteString(":")
+	}
+	sb.WriteString(lm.text())
+	for _, field := range lm.fields {
+		sb.WriteString(" ")
+		sb.WriteString(field.Key)
+		sb.WriteString("=")
+		sb.WriteString(fmt.Sprintf("%v", field.Value))
+	}
+	sb.WriteString("\n")
 
+	return sb.String()
+}
+
+// text returns the message with its {} placeholders replaced
+func (lm *logMessage) text() string {
+	var sb strings.Builder
+	subs := strings.Split(lm.msg, subSTR)
 	for i, v := range subs {
 		v = strings.Replace(strings.Replace(v, "{{", "{", -1), "}}", "}", -1)
 		sb.WriteString(v)
@@ -271,17 +330,33 @@ func (stimLogger *fullStimLogger) formatLogMessage(lm *logMessage) string {
 			sb.WriteString(fmt.Sprintf("%v", lm.args[i]))
 		}
 	}
-	sb.WriteString("\n")
-
 	return sb.String()
 }
 
-func (stimLogger *fullStimLogger) writeLogs(logLevel Level, sync bool, msg string) {
+func (stimLogger *fullStimLogger) writeLogs(lm *logMessage) {
+	// The message is encoded once per format
+	encoded := make(map[Format]string)
 	for kv := range stimLogger.logfiles.Iter() {
 		lgr := kv.Value.(*logFile)
-		if lgr.logLevel >= logLevel || (lgr.logLevel == defaultLevel && stimLogger.currentLevel >= logLevel) {
-			lgr.fp.WriteString(msg)
-			if stimLogger.forceFlush || sync {
+		if lgr.logLevel >= lm.logLevel || (lgr.logLevel == defaultLevel && stimLogger.currentLevel >= lm.logLevel) {
+			format := lgr.format
+			if format == "" {
+				format = stimLogger.format
+			}
+			msg, ok := encoded[format]
+			if !ok {
+				msg = stimLogger.encode(format, lm)
+				encoded[format] = msg
+			}
+			if lgr.maxSize > 0 && lgr.size > 0 && lgr.size+int64(len(msg)) > lgr.maxSize {
+				err := lgr.rotate()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Unable to rotate log file %s: %v\n", lgr.path, err)
+				}
+			}
+			n, _ := lgr.fp.WriteString(msg)
+			lgr.size += int64(n)
+			if stimLogger.forceFlush || lm.wg != nil {
 				lgr.fp.Sync()
 			}
 		}
@@ -297,13 +372,36 @@ func (stimLogger *fullStimLogger) wrapMessage(ll Level, wg *sync.WaitGroup, args
 	default:
 		msg = fmt.Sprintf("%v", args[0])
 	}
-	return &logMessage{
+	lm := &logMessage{
 		ltime:    st,
 		msg:      msg,
-		args:     args[1:],
 		logLevel: ll,
 		wg:       wg,
 	}
+
+	// Fields and the prefix of prefix loggers aren't placeholder values
+	for _, arg := range args[1:] {
+		switch a := arg.(type) {
+		case Field:
+			lm.fields = append(lm.fields, a)
+		case prefixArg:
+			lm.prefix = string(a)
+		default:
+			lm.args = append(lm.args, arg)
+		}
+	}
+	return lm
+}
+
+// messageText returns the message with its {} placeholders replaced
+func (stimLogger *fullStimLogger) messageText(message ...interface{}) string {
+	return stimLogger.wrapMessage(FatalLevel, nil, message...).text()
+}
+
+// AddExitHook adds a function that is called with the message of a Fatal log
+// before the process exits
+func (stimLogger *fullStimLogger) AddExitHook(hook func(string)) {
+	stimLogger.exitHooks = append(stimLogger.exitHooks, hook)
 }
 
 //Forces logger to write all current logs, will block till done
@@ -322,6 +420,19 @@ func (stimLogger *fullStimLogger) ForceFlush(ff bool) {
 	}
 }
 
+// SetFormat sets the encoding of the log files added without a format
+// (including STDOUT and STDERR)
+func (stimLogger *fullStimLogger) SetFormat(format Format) {
+	stimLogger.format = format
+}
+
+// SetComponent sets the component of the messages of loggers without a
+// prefix (ex. the stimpack of the command).  It is only part of structured
+// formats.
+func (stimLogger *fullStimLogger) SetComponent(component string) {
+	stimLogger.component = component
+}
+
 //SetDateFormat allows you to set how the date/time is formated
 func (stimLogger *fullStimLogger) SetDateFormat(nf string) {
 	stimLogger.dateFMT = nf
@@ -441,16 +552,24 @@ func (stimLogger *fullStimLogger) Info(message ...interface{}) {
 
 // Fatal logs a message at level Fatal on the standard logger then the process will exit with status set to 1.
 func (stimLogger *fullStimLogger) Fatal(message ...interface{}) {
+	stimLogger.Exit(1, message...)
+}
+
+// Exit logs a message at level Fatal on the standard logger then the process will exit with the given status.
+func (stimLogger *fullStimLogger) Exit(code int, message ...interface{}) {
 	if stimLogger.highestLevel >= FatalLevel {
 		if stimLogger.setLogger == nil {
 			wg := &sync.WaitGroup{}
 			wg.Add(1)
-			stimLogger.logQueue <- stimLogger.wrapMessage(InfoLevel, wg, message...)
+			stimLogger.logQueue <- stimLogger.wrapMessage(FatalLevel, wg, message...)
 			wg.Wait()
 		} else {
 			stimLogger.setLogger.Fatal(message...)
 		}
-		os.Exit(5)
+		for _, hook := range stimLogger.exitHooks {
+			hook(stimLogger.messageText(message...))
+		}
+		os.Exit(code)
 	}
 }
 
@@ -459,14 +578,12 @@ type stimPrefixLogger struct {
 	prefix     string
 }
 
+// prefixArg is the prefix of a prefix logger, passed along with the message
+// arguments
+type prefixArg string
+
 func (spl *stimPrefixLogger) prefixLog(i ...interface{}) []interface{} {
-	s := fmt.Sprintf("%v", i[0])
-	var sb strings.Builder
-	sb.WriteString(spl.prefix)
-	sb.WriteString(":")
-	sb.WriteString(s)
-	i[0] = sb.String()
-	return i
+	return append(i, prefixArg(spl.prefix))
 }
 func (spl *stimPrefixLogger) Trace(i ...interface{}) {
 	if spl.stimLogger.GetLogLevel() >= TraceLevel {
@@ -498,4 +615,9 @@ func (spl *stimPrefixLogger) Fatal(i ...interface{}) {
 		spl.stimLogger.Fatal(spl.prefixLog(i...)...)
 	}
 }
+func (spl *stimPrefixLogger) Exit(code int, i ...interface{}) {
+	if spl.stimLogger.GetLogLevel() >= FatalLevel {
+		spl.stimLogger.Exit(code, spl.prefixLog(i...)...)
+	}
+}
 func (spl *stimPrefixLogger) GetLogLevel() Level { return spl.stimLogger.GetLogLevel() }
@@ -1,9 +1,12 @@
 package stimlog
 
 import (
+	"encoding/json"
 	"errors"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -401,3 +404,108 @@ func BenchmarkSyncNoFormat(b *testing.B) {
 
 //TODO: add date/time test
 //TODO: add file log error test
+
+func TestJSONFormat(t *testing.T) {
+	resetLogger()
+	slc := GetLoggerConfig()
+	slc.RemoveLogFile("STDOUT")
+	slc.SetLevel(InfoLevel)
+	slc.SetComponent("deploy")
+	tmpfile, err := ioutil.TempFile("", "TESTLOG")
+	check(err)
+	defer os.Remove(tmpfile.Name())
+	check(slc.AddLogFileOptions(tmpfile.Name(), LogFileOptions{Level: InfoLevel, Format: JSONFormat}))
+
+	GetLogger().Info("Deploying {} to {{blue}}", "app", Field{Key: "instance", Value: "blue"}, Field{Key: "msg", Value: 1})
+	GetLoggerWithPrefix("vault").Warn("Lease {} not revoked", "a", Field{Key: "error", Value: errors.New("denied")})
+	slc.Flush()
+
+	data, err := ioutil.ReadFile(tmpfile.Name())
+	check(err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Equal(t, len(lines), 2)
+
+	var info map[string]interface{}
+	assert.NilError(t, json.Unmarshal([]byte(lines[0]), &info))
+	_, err = time.Parse(time.RFC3339Nano, info["time"].(string))
+	assert.NilError(t, err)
+	delete(info, "time")
+	assert.DeepEqual(t, info, map[string]interface{}{"level": "info", "component": "deploy", "msg": "Deploying app to {blue}", "instance": "blue", "field.msg": float64(1)})
+	assert.Assert(t, strings.HasPrefix(lines[0], `{"time":`))
+
+	// The prefix of a prefix logger is its component
+	var warn map[string]interface{}
+	assert.NilError(t, json.Unmarshal([]byte(lines[1]), &warn))
+	delete(warn, "time")
+	assert.DeepEqual(t, warn, map[string]interface{}{"level": "warn", "component": "vault", "msg": "Lease a not revoked", "error": "denied"})
+}
+
+func TestConsoleFields(t *testing.T) {
+	resetLogger()
+	slc := GetLoggerConfig()
+	slc.EnableTimeLogging(false)
+	slc.RemoveLogFile("STDOUT")
+	slc.SetLevel(InfoLevel)
+	slc.SetComponent("deploy")
+	tmpfile, err := ioutil.TempFile("", "TESTLOG")
+	check(err)
+	defer os.Remove(tmpfile.Name())
+	check(slc.AddLogFile(tmpfile.Name(), DefaultLevel))
+
+	GetLoggerWithPrefix("PREFIX").Info("Deployed {}", "app", Field{Key: "instance", Value: "blue"})
+	slc.Flush()
+
+	data, err := ioutil.ReadFile(tmpfile.Name())
+	check(err)
+	assert.Equal(t, string(data), "[ INFO  ]\tPREFIX:Deployed app instance=blue\n")
+}
+
+func TestLogFileRotation(t *testing.T) {
+	resetLogger()
+	slc := GetLoggerConfig()
+	slc.EnableTimeLogging(false)
+	slc.EnableLevelLogging(false)
+	slc.RemoveLogFile("STDOUT")
+	slc.SetLevel(InfoLevel)
+	dir, err := ioutil.TempDir("", "stimlog")
+	check(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "stim.log")
+	check(ioutil.WriteFile(path, []byte("old\n"), 0640))
+
+	// Each message is 10 bytes, so the file is rotated every 2 messages.  The
+	// existing content counts towards the first file.
+	check(slc.AddLogFileOptions(path, LogFileOptions{Level: InfoLevel, MaxSize: 20, MaxBackups: 2}))
+	sl := GetLogger()
+	for i := 0; i < 7; i++ {
+		sl.Info("message {}", i)
+	}
+	slc.Flush()
+
+	read := func(name string) string {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		check(err)
+		return string(data)
+	}
+	assert.Equal(t, read("stim.log"), "message 5\nmessage 6\n")
+	assert.Equal(t, read("stim.log.1"), "message 3\nmessage 4\n")
+	assert.Equal(t, read("stim.log.2"), "message 1\nmessage 2\n")
+	_, err = os.Stat(filepath.Join(dir, "stim.log.3"))
+	assert.Assert(t, os.IsNotExist(err))
+}
+
+func TestParseLevel(t *testing.T) {
+	for name, level := range map[string]Level{"trace": TraceLevel, "DEBUG": DebugLevel, "verbose": VerboseLevel, "info": InfoLevel, "warn": WarnLevel, "error": FatalLevel, "fatal": FatalLevel} {
+		parsed, err := ParseLevel(name)
+		assert.NilError(t, err)
+		assert.Equal(t, parsed, level)
+	}
+	_, err := ParseLevel("loud")
+	assert.ErrorContains(t, err, "Invalid log level")
+
+	format, err := ParseFormat("JSON")
+	assert.NilError(t, err)
+	assert.Equal(t, format, JSONFormat)
+	_, err = ParseFormat("xml")
+	assert.ErrorContains(t, err, "Invalid log format")
+}
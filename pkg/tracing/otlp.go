@@ -0,0 +1,164 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExportTimeout keeps a slow collector from delaying stim
+const ExportTimeout = 5 * time.Second
+
+// The OTLP span kind and status codes that are used
+const (
+	otlpSpanKindInternal = 1
+	otlpStatusOK         = 1
+	otlpStatusError      = 2
+)
+
+// Resource describes the process that produced the spans
+type Resource struct {
+	ServiceName    string
+	ServiceVersion string
+	Attributes     map[string]string
+}
+
+// otlpRequest is the OTLP/JSON ExportTraceServiceRequest
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// Export posts the spans to the `/v1/traces` path of an OTLP/HTTP collector
+// (ex. `http://localhost:4318`) with the given extra headers (ex. an API key)
+func Export(endpoint string, headers map[string]string, resource *Resource, spans []*Span) error {
+
+	body, err := OTLPJSON(resource, spans)
+	if err != nil {
+		return err
+	}
+
+	url := strings.TrimSuffix(endpoint, "/") + "/v1/traces"
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	client := &http.Client{Timeout: ExportTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+// OTLPJSON returns the spans as an OTLP/JSON export request
+func OTLPJSON(resource *Resource, spans []*Span) ([]byte, error) {
+
+	resourceAttributes := map[string]string{"service.name": resource.ServiceName}
+	if resource.ServiceVersion != "" {
+		resourceAttributes["service.version"] = resource.ServiceVersion
+	}
+	for key, value := range resource.Attributes {
+		resourceAttributes[key] = value
+	}
+
+	scopeSpans := otlpScopeSpans{Scope: otlpScope{Name: resource.ServiceName, Version: resource.ServiceVersion}}
+	for _, span := range spans {
+		s := otlpSpan{
+			TraceID:           span.TraceID.String(),
+			SpanID:            span.SpanID.String(),
+			Name:              span.Name,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+			Attributes:        otlpAttributes(span.Attributes),
+			Status:            otlpStatus{Code: otlpStatusOK},
+		}
+		if !span.ParentID.IsZero() {
+			s.ParentSpanID = span.ParentID.String()
+		}
+		if span.Err != nil {
+			s.Status = otlpStatus{Code: otlpStatusError, Message: span.Err.Error()}
+		}
+		scopeSpans.Spans = append(scopeSpans.Spans, s)
+	}
+
+	return json.Marshal(otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: otlpAttributes(resourceAttributes)},
+		ScopeSpans: []otlpScopeSpans{scopeSpans},
+	}}})
+}
+
+// otlpAttributes returns the attributes sorted by key
+func otlpAttributes(attributes map[string]string) []otlpAttribute {
+	var keys []string
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var result []otlpAttribute
+	for _, key := range keys {
+		result = append(result, otlpAttribute{Key: key, Value: otlpValue{StringValue: attributes[key]}})
+	}
+	return result
+}
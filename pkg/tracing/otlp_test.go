@@ -0,0 +1,58 @@
+package tracing
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/clock"
+	"gotest.tools/assert"
+)
+
+func TestOTLPJSON(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	span := &Span{
+		TraceID:    TraceID{0x4b, 0xf9},
+		SpanID:     SpanID{0x01},
+		ParentID:   SpanID{0x02},
+		Name:       "container.run",
+		Start:      start,
+		End:        start.Add(time.Second),
+		Attributes: map[string]string{"deploy.instance": "us-east-1", "deploy.environment": "prod"},
+		Err:        errors.New("exit code 1"),
+	}
+
+	b, err := OTLPJSON(&Resource{ServiceName: "stim", ServiceVersion: "v1.0.0"}, []*Span{span})
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), `{"resourceSpans":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"stim"}},{"key":"service.version","value":{"stringValue":"v1.0.0"}}]},`+
+		`"scopeSpans":[{"scope":{"name":"stim","version":"v1.0.0"},"spans":[{"traceId":"4bf90000000000000000000000000000","spanId":"0100000000000000","parentSpanId":"0200000000000000",`+
+		`"name":"container.run","kind":1,"startTimeUnixNano":"1717243200000000000","endTimeUnixNano":"1717243201000000000",`+
+		`"attributes":[{"key":"deploy.environment","value":{"stringValue":"prod"}},{"key":"deploy.instance","value":{"stringValue":"us-east-1"}}],`+
+		`"status":{"code":2,"message":"exit code 1"}}]}]}]}`)
+}
+
+func TestExport(t *testing.T) {
+	var path, apiKey, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		apiKey = r.Header.Get("X-Api-Key")
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer server.Close()
+
+	tracer := NewTracer(clock.New(), "")
+	span := tracer.Start("stim deploy", nil, nil)
+	span.Finish(nil)
+	resource := &Resource{ServiceName: "stim"}
+
+	err := Export(server.URL+"/", map[string]string{"X-Api-Key": "secret"}, resource, tracer.Spans())
+	assert.NilError(t, err)
+	assert.Equal(t, path, "/v1/traces")
+	assert.Equal(t, apiKey, "secret")
+	expected, _ := OTLPJSON(resource, tracer.Spans())
+	assert.Equal(t, body, string(expected))
+}
@@ -0,0 +1,151 @@
+// Package tracing records the spans of a stim run and exports them to an
+// OpenTelemetry collector with OTLP over HTTP (JSON encoding).  Trace context
+// is propagated with W3C `traceparent` values, so a run joins the trace of the
+// pipeline that started it and the deploy container can continue it.
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/clock"
+)
+
+// TraceparentEnv is the environment variable that carries the trace context
+// into and out of stim
+const TraceparentEnv = "TRACEPARENT"
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+// SpanID identifies a span
+type SpanID [8]byte
+
+// String returns the hex encoded ID
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// String returns the hex encoded ID
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsZero returns true if the ID is not set
+func (id SpanID) IsZero() bool {
+	return id == SpanID{}
+}
+
+// Span is a timed operation of a trace
+type Span struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	ParentID   SpanID
+	Name       string
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]string
+	Err        error
+
+	tracer *Tracer
+}
+
+// Tracer creates the spans of a trace
+type Tracer struct {
+	mu           sync.Mutex
+	clock        clock.Clock
+	traceID      TraceID
+	remoteParent SpanID
+	ended        []*Span
+}
+
+// NewTracer starts a trace.  If traceparent is a valid W3C `traceparent`
+// value, the trace continues it and its root spans are children of the remote
+// span.  Otherwise a new trace is started.
+func NewTracer(c clock.Clock, traceparent string) *Tracer {
+	t := &Tracer{clock: c}
+	traceID, spanID, err := ParseTraceparent(traceparent)
+	if err == nil {
+		t.traceID = traceID
+		t.remoteParent = spanID
+	} else {
+		rand.Read(t.traceID[:])
+	}
+	return t
+}
+
+// TraceID returns the ID of the trace
+func (t *Tracer) TraceID() TraceID {
+	return t.traceID
+}
+
+// Start starts a span.  A nil parent makes it a root span of the run.
+func (t *Tracer) Start(name string, parent *Span, attributes map[string]string) *Span {
+	span := &Span{
+		TraceID:    t.traceID,
+		ParentID:   t.remoteParent,
+		Name:       name,
+		Start:      t.clock.Now(),
+		Attributes: attributes,
+		tracer:     t,
+	}
+	if parent != nil {
+		span.ParentID = parent.SpanID
+	}
+	rand.Read(span.SpanID[:])
+	return span
+}
+
+// Spans returns the ended spans in the order they ended
+func (t *Tracer) Spans() []*Span {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*Span(nil), t.ended...)
+}
+
+// Finish ends the span with the error of the operation, if any
+func (s *Span) Finish(err error) {
+	s.End = s.tracer.clock.Now()
+	s.Err = err
+
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.tracer.ended = append(s.tracer.ended, s)
+}
+
+// Traceparent returns the W3C `traceparent` value of the span, for child
+// operations in other processes
+func (s *Span) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-01", s.TraceID, s.SpanID)
+}
+
+// ParseTraceparent parses a W3C `traceparent` value
+// (`00-<trace id>-<parent span id>-<flags>`)
+func ParseTraceparent(traceparent string) (TraceID, SpanID, error) {
+
+	var traceID TraceID
+	var spanID SpanID
+
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) != 4 || parts[0] != "00" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return traceID, spanID, errors.New("Invalid traceparent")
+	}
+	_, err := hex.Decode(traceID[:], []byte(parts[1]))
+	if err != nil {
+		return traceID, spanID, fmt.Errorf("Invalid traceparent trace ID: %v", err)
+	}
+	_, err = hex.Decode(spanID[:], []byte(parts[2]))
+	if err != nil {
+		return traceID, spanID, fmt.Errorf("Invalid traceparent span ID: %v", err)
+	}
+	if traceID == (TraceID{}) || spanID.IsZero() {
+		return traceID, spanID, errors.New("Invalid traceparent, the IDs must not be zero")
+	}
+
+	return traceID, spanID, nil
+}
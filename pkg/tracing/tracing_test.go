@@ -0,0 +1,56 @@
+package tracing
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/clock"
+	"gotest.tools/assert"
+)
+
+func TestTracer(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	tracer := NewTracer(fake, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.Equal(t, tracer.TraceID().String(), "4bf92f3577b34da6a3ce929d0e0e4736")
+
+	root := tracer.Start("stim deploy", nil, nil)
+	child := tracer.Start("vault.auth", root, map[string]string{"vault.address": "https://vault.example.com"})
+	fake.Advance(time.Second)
+	child.Finish(nil)
+	root.Finish(errors.New("failed"))
+
+	assert.Equal(t, root.ParentID.String(), "00f067aa0ba902b7")
+	assert.Equal(t, child.ParentID, root.SpanID)
+	assert.Equal(t, child.End.Sub(child.Start), time.Second)
+	assert.Error(t, root.Err, "failed")
+	spans := tracer.Spans()
+	assert.Equal(t, len(spans), 2)
+	assert.Equal(t, spans[0], child)
+	assert.Equal(t, spans[1], root)
+	assert.Equal(t, child.Traceparent(), "00-4bf92f3577b34da6a3ce929d0e0e4736-"+child.SpanID.String()+"-01")
+}
+
+func TestNewTracerWithoutParent(t *testing.T) {
+	tracer := NewTracer(clock.New(), "")
+	assert.Assert(t, tracer.TraceID() != TraceID{})
+	assert.Assert(t, tracer.Start("stim deploy", nil, nil).ParentID.IsZero())
+}
+
+func TestParseTraceparent(t *testing.T) {
+	traceID, spanID, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.NilError(t, err)
+	assert.Equal(t, traceID.String(), "4bf92f3577b34da6a3ce929d0e0e4736")
+	assert.Equal(t, spanID.String(), "00f067aa0ba902b7")
+
+	for _, invalid := range []string{
+		"",
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",
+		"00-zzf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+	} {
+		_, _, err = ParseTraceparent(invalid)
+		assert.Assert(t, err != nil, invalid)
+	}
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeFormat is the format of timestamps in human readable output
+const TimeFormat = "2006-01-02 15:04 MST"
+
+// durationUnits are the units used by HumanizeDuration, largest first
+var durationUnits = []struct {
+	suffix string
+	size   time.Duration
+}{
+	{"d", 24 * time.Hour},
+	{"h", time.Hour},
+	{"m", time.Minute},
+	{"s", time.Second},
+}
+
+// HumanizeDuration formats a duration using its two largest units, ex. `3d 4h`
+// or `12m 5s`.  Durations under a second are rounded to `0s`.
+func HumanizeDuration(d time.Duration) string {
+
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+
+	var parts []string
+	for _, unit := range durationUnits {
+		if d >= unit.size || len(parts) > 0 {
+			parts = append(parts, fmt.Sprintf("%d%s", d/unit.size, unit.suffix))
+			d = d % unit.size
+		}
+		if len(parts) == 2 {
+			break
+		}
+	}
+
+	// Drop a trailing zero unit, ex. `2h 0m` is `2h`
+	if len(parts) == 2 && strings.HasPrefix(parts[1], "0") {
+		parts = parts[:1]
+	}
+	if len(parts) == 0 {
+		return "0s"
+	}
+
+	return sign + strings.Join(parts, " ")
+}
+
+// HumanizeRelative formats a time relative to now, ex. `3d 4h ago` or
+// `in 12m 5s`
+func HumanizeRelative(t time.Time, now time.Time) string {
+	d := t.Sub(now)
+	if d < 0 {
+		return HumanizeDuration(-d) + " ago"
+	}
+	return "in " + HumanizeDuration(d)
+}
+
+// FormatTime formats a timestamp in the local time zone, or in UTC if utc is
+// true
+func FormatTime(t time.Time, utc bool) string {
+	if utc {
+		return t.UTC().Format(TimeFormat)
+	}
+	return t.Local().Format(TimeFormat)
+}
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestHumanizeDuration(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		expected string
+	}{
+		{0, "0s"},
+		{500 * time.Millisecond, "0s"},
+		{45 * time.Second, "45s"},
+		{12*time.Minute + 5*time.Second, "12m 5s"},
+		{2 * time.Hour, "2h"},
+		{2*time.Hour + 30*time.Second, "2h"},
+		{768 * time.Hour, "32d"},
+		{76*time.Hour + 10*time.Minute, "3d 4h"},
+		{-90 * time.Minute, "-1h 30m"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, HumanizeDuration(test.duration), test.expected, test.duration.String())
+	}
+}
+
+func TestHumanizeRelative(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, HumanizeRelative(now.Add(-50*time.Hour), now), "2d 2h ago")
+	assert.Equal(t, HumanizeRelative(now.Add(90*time.Second), now), "in 1m 30s")
+}
+
+func TestFormatTime(t *testing.T) {
+	ts := time.Date(2020, 6, 1, 12, 30, 0, 0, time.FixedZone("EST", -5*60*60))
+
+	assert.Equal(t, FormatTime(ts, true), "2020-06-01 17:30 UTC")
+}
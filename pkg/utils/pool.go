@@ -0,0 +1,29 @@
+package utils
+
+import "sync"
+
+// ForEachConcurrent calls fn for each index from 0 to count-1 with at most
+// limit calls running at once (1 if limit is below 1) and returns the error of
+// each call by index
+func ForEachConcurrent(count int, limit int, fn func(i int) error) []error {
+
+	if limit < 1 {
+		limit = 1
+	}
+
+	errs := make([]error, count)
+	slots := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			errs[i] = fn(i)
+		}(i)
+	}
+	wg.Wait()
+
+	return errs
+}
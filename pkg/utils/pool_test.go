@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestForEachConcurrent(t *testing.T) {
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	release := make(chan struct{})
+
+	done := make(chan []error)
+	go func() {
+		done <- ForEachConcurrent(6, 2, func(i int) error {
+			mu.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mu.Unlock()
+
+			<-release
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+			if i%2 == 1 {
+				return fmt.Errorf("item %d failed", i)
+			}
+			return nil
+		})
+	}()
+	for i := 0; i < 6; i++ {
+		release <- struct{}{}
+	}
+	errs := <-done
+
+	assert.Assert(t, maxRunning <= 2)
+	assert.Equal(t, len(errs), 6)
+	for i, err := range errs {
+		if i%2 == 1 {
+			assert.Error(t, err, fmt.Sprintf("item %d failed", i))
+		} else {
+			assert.NilError(t, err)
+		}
+	}
+}
+
+func TestForEachConcurrentLimit(t *testing.T) {
+	calls := 0
+	errs := ForEachConcurrent(3, 0, func(i int) error {
+		calls++
+		return nil
+	})
+	assert.Equal(t, calls, 3)
+	assert.Equal(t, len(errs), 3)
+}
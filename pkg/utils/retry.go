@@ -2,11 +2,18 @@ package utils
 
 import (
 	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/clock"
 )
 
 // Retry will make a given number of attempts to run the provided function
 // sleeping between each attempt
 func Retry(attempts int, sleep time.Duration, fn func() error) error {
+	return RetryClock(clock.New(), attempts, sleep, fn)
+}
+
+// RetryClock is the same as Retry but sleeps with the given clock
+func RetryClock(c clock.Clock, attempts int, sleep time.Duration, fn func() error) error {
 	if err := fn(); err != nil {
 		if s, ok := err.(stop); ok {
 			// Return the original error for later checking
@@ -14,8 +21,8 @@ func Retry(attempts int, sleep time.Duration, fn func() error) error {
 		}
 
 		if attempts--; attempts > 0 {
-			time.Sleep(sleep)
-			return Retry(attempts, sleep, fn)
+			c.Sleep(sleep)
+			return RetryClock(c, attempts, sleep, fn)
 		}
 		return err
 	}
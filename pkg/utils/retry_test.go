@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/clock"
+	"gotest.tools/assert"
+)
+
+func TestRetryClock(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+
+	calls := 0
+	err := RetryClock(fake, 3, 2*time.Second, func() error {
+		calls++
+		return errors.New("not ready")
+	})
+	assert.Error(t, err, "not ready")
+	assert.Equal(t, calls, 3)
+	assert.Equal(t, fake.Now(), start.Add(4*time.Second))
+
+	calls = 0
+	err = RetryClock(fake, 3, 2*time.Second, func() error {
+		calls++
+		if calls < 2 {
+			return errors.New("not ready")
+		}
+		return nil
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, calls, 2)
+}
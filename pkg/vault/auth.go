@@ -1,14 +1,12 @@
 package vault
 
 import (
-	"github.com/hashicorp/vault/command/token"
 	"golang.org/x/crypto/ssh/terminal"
 
 	"bufio"
 	"errors"
 	"fmt"
 	"os"
-	"path"
 	"strings"
 	"syscall"
 )
@@ -20,8 +18,7 @@ func (v *Vault) Login() error {
 	if v.client.Token() != "" {
 		v.log.Debug("Reading token from environment 'VAULT_TOKEN'")
 	} else { // If no environment token set
-		// Reading token from user's dot file
-		v.tokenHelper = token.InternalTokenHelper{}
+		// Reading token from the token cache
 		token, err := v.tokenHelper.Get()
 		if err != nil {
 			return v.parseError(err).(error)
@@ -85,6 +82,15 @@ func (v *Vault) isCurrentTokenValid() bool {
 		return false
 	}
 
+	// If the token is about to expire, try to renew it before giving up on it
+	if duration < v.config.MinTokenTTL {
+		v.log.Debug("Current token TTL {} is below the minimum of {}, attempting renewal", duration.String(), v.config.MinTokenTTL.String())
+		duration, err = v.RenewToken(v.config.InitialTokenDuration)
+		if err != nil || duration < v.config.MinTokenTTL {
+			return false
+		}
+	}
+
 	v.log.Debug("Current token is valid for {}", duration.String())
 
 	return true
@@ -93,24 +99,19 @@ func (v *Vault) isCurrentTokenValid() bool {
 // userLogin authenticates with Vault and obtains a user token
 func (v *Vault) userLogin() error {
 
-	if v.config.Noprompt == true {
+	if v.config.Noprompt == true && isInteractiveAuthMethod(v.config.AuthMethod) {
 		return errors.New("No interactive prompt is set, but user input is required to continue")
 	}
 
-	username, password, err := v.getCredentials()
-	if err != nil {
-		return err
-	}
-
 	// Login and obtain a token
-	authPath := path.Join("auth/", v.config.AuthPath, "/login/", username)
-	secret, err := v.client.Logical().Write(authPath, map[string]interface{}{
-		"password": password,
-	})
+	v.log.Debug("Logging in with auth method {} at path auth/{}", v.config.AuthMethod, v.config.AuthPath)
+	secret, err := v.loginAuthMethod()
 	if err != nil {
-		v.log.Debug("Do you have a bad username or password?")
 		return v.parseError(err)
 	}
+	if secret == nil || secret.Auth == nil {
+		return v.newError("No token returned from login")
+	}
 	v.client.SetToken(secret.Auth.ClientToken)
 
 	// Write token to user's dot file
@@ -125,7 +126,7 @@ func (v *Vault) userLogin() error {
 		return v.parseError(err)
 	}
 	// spew.Dump(secret)
-	entityID := secret.Data["entity_id"].(string)
+	entityID, _ := secret.Data["entity_id"].(string)
 	v.log.Debug("Vault entity ID: ", entityID)
 
 	v.newLogin = true // Set if we had to prompt user for a login
@@ -0,0 +1,257 @@
+package vault
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/skratchdot/open-golang/open"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// Supported authentication methods
+const (
+	AuthMethodLdap       = "ldap"
+	AuthMethodUserpass   = "userpass"
+	AuthMethodOIDC       = "oidc"
+	AuthMethodJWT        = "jwt"
+	AuthMethodAppRole    = "approle"
+	AuthMethodKubernetes = "kubernetes"
+	AuthMethodToken      = "token"
+)
+
+const (
+	defaultKubernetesJWTPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	defaultOIDCCallbackPort  = 8250
+	oidcCallbackTimeout      = 2 * time.Minute
+)
+
+// AuthMethods lists the supported authentication methods
+var AuthMethods = []string{AuthMethodLdap, AuthMethodUserpass, AuthMethodOIDC, AuthMethodJWT, AuthMethodAppRole, AuthMethodKubernetes, AuthMethodToken}
+
+// isInteractiveAuthMethod returns true if the given method requires user input
+func isInteractiveAuthMethod(method string) bool {
+	return method != AuthMethodAppRole && method != AuthMethodKubernetes && method != AuthMethodJWT
+}
+
+// loginAuthMethod authenticates using the configured auth method and
+// returns the resulting auth secret
+func (v *Vault) loginAuthMethod() (*api.Secret, error) {
+
+	switch v.config.AuthMethod {
+	case AuthMethodOIDC:
+		return v.loginOIDC()
+	case AuthMethodJWT:
+		return v.loginJWT()
+	case AuthMethodAppRole:
+		return v.loginAppRole()
+	case AuthMethodKubernetes:
+		return v.loginKubernetes()
+	case AuthMethodToken:
+		return v.loginToken()
+	default:
+		return v.loginPassword()
+	}
+}
+
+// loginPassword authenticates using a username and password (ldap, userpass, etc.)
+func (v *Vault) loginPassword() (*api.Secret, error) {
+
+	username, password, err := v.getCredentials()
+	if err != nil {
+		return nil, err
+	}
+
+	authPath := path.Join("auth/", v.config.AuthPath, "/login/", username)
+	secret, err := v.client.Logical().Write(authPath, map[string]interface{}{
+		"password": password,
+	})
+	if err != nil {
+		v.log.Debug("Do you have a bad username or password?")
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+// loginAppRole authenticates using an AppRole role ID and secret ID
+func (v *Vault) loginAppRole() (*api.Secret, error) {
+
+	if v.config.RoleID == "" {
+		return nil, errors.New("AppRole role ID is required for approle authentication")
+	}
+
+	return v.client.Logical().Write(path.Join("auth/", v.config.AuthPath, "/login"), map[string]interface{}{
+		"role_id":   v.config.RoleID,
+		"secret_id": v.config.SecretID,
+	})
+}
+
+// loginKubernetes authenticates using the pod's service account token
+func (v *Vault) loginKubernetes() (*api.Secret, error) {
+
+	if v.config.Role == "" {
+		return nil, errors.New("Role is required for kubernetes authentication")
+	}
+
+	jwtPath := v.config.JWTPath
+	if jwtPath == "" {
+		jwtPath = defaultKubernetesJWTPath
+	}
+
+	jwt, err := ioutil.ReadFile(jwtPath)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read service account token: %v", err)
+	}
+
+	return v.client.Logical().Write(path.Join("auth/", v.config.AuthPath, "/login"), map[string]interface{}{
+		"role": v.config.Role,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	})
+}
+
+// loginJWT authenticates with a JWT (ex. a CI job's OIDC token), either given
+// directly or read from a file
+func (v *Vault) loginJWT() (*api.Secret, error) {
+
+	jwt := v.config.JWT
+	if jwt == "" {
+		if v.config.JWTPath == "" {
+			return nil, errors.New("A JWT or JWT path is required for jwt authentication")
+		}
+		b, err := ioutil.ReadFile(v.config.JWTPath)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read JWT: %v", err)
+		}
+		jwt = string(b)
+	}
+
+	// The role can be omitted to use the auth method's default role
+	return v.client.Logical().Write(path.Join("auth/", v.config.AuthPath, "/login"), map[string]interface{}{
+		"role": v.config.Role,
+		"jwt":  strings.TrimSpace(jwt),
+	})
+}
+
+// loginToken prompts the user for an existing token
+func (v *Vault) loginToken() (*api.Secret, error) {
+
+	fmt.Print("Token: ")
+	byteToken, err := terminal.ReadPassword(int(syscall.Stdin))
+	if err != nil {
+		return nil, err
+	}
+	fmt.Println("")
+
+	v.client.SetToken(strings.TrimSpace(string(byteToken)))
+	secret, err := v.client.Auth().Token().LookupSelf()
+	if err != nil {
+		return nil, err
+	}
+
+	// A lookup doesn't return an auth block so build one from the token data
+	secret.Auth = &api.SecretAuth{ClientToken: v.client.Token()}
+
+	return secret, nil
+}
+
+// loginOIDC runs the OIDC browser flow.  A local listener receives the
+// callback from the identity provider, which is then exchanged for a token.
+func (v *Vault) loginOIDC() (*api.Secret, error) {
+
+	port := v.config.OIDCCallbackPort
+	if port == 0 {
+		port = defaultOIDCCallbackPort
+	}
+	listenAddress := fmt.Sprintf("localhost:%d", port)
+	redirectURI := fmt.Sprintf("http://%s/oidc/callback", listenAddress)
+
+	clientNonce, err := randomString()
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := v.client.Logical().Write(path.Join("auth/", v.config.AuthPath, "/oidc/auth_url"), map[string]interface{}{
+		"role":         v.config.Role,
+		"redirect_uri": redirectURI,
+		"client_nonce": clientNonce,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	authURL, _ := secret.Data["auth_url"].(string)
+	if authURL == "" {
+		return nil, fmt.Errorf("Unable to get OIDC auth URL. Ensure the role '%s' exists and has %s as an allowed redirect URI", v.config.Role, redirectURI)
+	}
+
+	listener, err := net.Listen("tcp", listenAddress)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to start OIDC callback listener on %s: %v", listenAddress, err)
+	}
+
+	type callbackResult struct {
+		secret *api.Secret
+		err    error
+	}
+	results := make(chan callbackResult, 1)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oidc/callback", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		secret, err := v.client.Logical().ReadWithData(path.Join("auth/", v.config.AuthPath, "/oidc/callback"), map[string][]string{
+			"state":        query["state"],
+			"code":         query["code"],
+			"id_token":     query["id_token"],
+			"client_nonce": []string{clientNonce},
+		})
+		if err != nil {
+			fmt.Fprintln(w, "Vault login failed, you may close this window and check the terminal for details.")
+		} else {
+			fmt.Fprintln(w, "Vault login successful, you may close this window.")
+		}
+
+		// Only the first callback is used, ignore any others (ex. browser retries)
+		select {
+		case results <- callbackResult{secret: secret, err: err}:
+		default:
+		}
+	})
+
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	defer server.Shutdown(context.Background())
+
+	fmt.Println("Complete the login via your OIDC provider. Launching browser to:")
+	fmt.Printf("\n    %s\n\n", authURL)
+	err = open.Start(authURL)
+	if err != nil {
+		v.log.Warn("Unable to launch browser, please open the URL above manually: {}", err)
+	}
+
+	select {
+	case result := <-results:
+		return result.secret, result.err
+	case <-time.After(oidcCallbackTimeout):
+		return nil, errors.New("Timed out waiting for OIDC login to complete")
+	}
+}
+
+// randomString returns a random hex string suitable for use as a nonce
+func randomString() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
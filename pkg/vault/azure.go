@@ -0,0 +1,38 @@
+package vault
+
+import (
+	"github.com/hashicorp/vault/api"
+
+	"errors"
+)
+
+// AzureCredentials returns the client ID and secret of a service principal
+// from the role of a Vault Azure secrets engine
+func (v *Vault) AzureCredentials(account string, role string) (*api.Secret, error) {
+	if account == "" {
+		return nil, errors.New("Account not set")
+	}
+	if role == "" {
+		return nil, errors.New("Role not set")
+	}
+
+	path := "/" + account + "/creds/" + role
+	v.log.Debug("Getting Azure credentials via path: ", path)
+
+	secret, err := v.GetSecret(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+// AzureConfig returns the config of a Vault Azure secrets engine, which has
+// the tenant and subscription IDs of its service principals
+func (v *Vault) AzureConfig(account string) (map[string]string, error) {
+	if account == "" {
+		return nil, errors.New("Account not set")
+	}
+
+	return v.GetSecretKeys("/" + account + "/config")
+}
@@ -0,0 +1,47 @@
+package vault
+
+import (
+	"strings"
+	"time"
+)
+
+// DatabaseCredentials are dynamic credentials of the database secrets engine
+type DatabaseCredentials struct {
+	Username string
+	Password string
+
+	// LeaseID is the lease the credentials are revoked with
+	LeaseID       string
+	LeaseDuration time.Duration
+	Renewable     bool
+}
+
+// GetDatabaseCredentials creates credentials with a role of the database
+// secrets engine at the mount
+func (v *Vault) GetDatabaseCredentials(mount string, role string) (*DatabaseCredentials, error) {
+
+	path := strings.Trim(mount, "/") + "/creds/" + role
+	v.log.Debug("Getting database credentials via path: ", path)
+	secret, err := v.client.Logical().Read(path)
+	if err != nil {
+		return nil, v.parseError(err).(error)
+	}
+	v.trackLease(path, secret)
+	if secret == nil || secret.Data == nil {
+		return nil, v.newError("No credentials returned from `" + path + "`").(error)
+	}
+
+	username, _ := secret.Data["username"].(string)
+	password, _ := secret.Data["password"].(string)
+	if username == "" {
+		return nil, v.newError("No credentials returned from `" + path + "`").(error)
+	}
+
+	return &DatabaseCredentials{
+		Username:      username,
+		Password:      password,
+		LeaseID:       secret.LeaseID,
+		LeaseDuration: time.Duration(secret.LeaseDuration) * time.Second,
+		Renewable:     secret.Renewable,
+	}, nil
+}
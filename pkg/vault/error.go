@@ -73,3 +73,21 @@ func (v *Vault) parseError(err error) CustomError {
 func (v *Vault) newError(msg string) CustomError {
 	return v.parseError(errors.New(msg))
 }
+
+// ErrNotFound is the original error of the errors returned for secrets and
+// paths that don't exist.  Use IsNotFound to check for it.
+var ErrNotFound = errors.New("not found")
+
+// notFoundError returns an error for a secret or path that doesn't exist
+func (v *Vault) notFoundError(msg string) CustomError {
+	return &CustomVaultError{MessageParts: []string{msg}, OriginalError: ErrNotFound}
+}
+
+// IsNotFound returns true if the error is for a secret or path that doesn't
+// exist, rather than for a failed request
+func IsNotFound(err error) bool {
+	if verr, ok := err.(*CustomVaultError); ok {
+		return verr.OriginalError == ErrNotFound
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package vault
+
+// TokenIdentity is who the current token belongs to, as Vault knows it
+type TokenIdentity struct {
+	// EntityID is the identity entity of the token, empty for tokens without
+	// an entity (ex. root tokens and orphan tokens created by hand)
+	EntityID string
+
+	// DisplayName is the display name of the token (ex. `ldap-alice`)
+	DisplayName string
+
+	// Username is the `username` metadata of the token, set by the ldap and
+	// userpass auth methods
+	Username string
+}
+
+// LookupSelfIdentity returns the identity of the current token from
+// `auth/token/lookup-self`.  Unlike the local user name, it can't be chosen
+// by the caller.
+func (v *Vault) LookupSelfIdentity() (*TokenIdentity, error) {
+
+	secret, err := v.client.Auth().Token().LookupSelf()
+	if err != nil {
+		return nil, v.parseError(err).(error)
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, v.newError("No token information returned from `auth/token/lookup-self`").(error)
+	}
+
+	identity := &TokenIdentity{}
+	identity.EntityID, _ = secret.Data["entity_id"].(string)
+	identity.DisplayName, _ = secret.Data["display_name"].(string)
+
+	metadata, err := secret.TokenMetadata()
+	if err == nil {
+		identity.Username = metadata["username"]
+	}
+
+	return identity, nil
+}
+
+// GetEntityNames returns the name of an identity entity followed by the
+// names of its aliases (ex. the LDAP user name)
+func (v *Vault) GetEntityNames(entityID string) ([]string, error) {
+
+	path := "identity/entity/id/" + entityID
+	secret, err := v.client.Logical().Read(path)
+	if err != nil {
+		return nil, v.parseError(err).(error)
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, v.notFoundError("Entity '" + entityID + "' not found").(error)
+	}
+
+	var names []string
+	if name, ok := secret.Data["name"].(string); ok && name != "" {
+		names = append(names, name)
+	}
+	aliases, _ := secret.Data["aliases"].([]interface{})
+	for _, alias := range aliases {
+		a, _ := alias.(map[string]interface{})
+		if name, ok := a["name"].(string); ok && name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names, nil
+}
@@ -0,0 +1,228 @@
+package vault
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// kvMount describes the secret engine mount that a path belongs to
+type kvMount struct {
+	path    string
+	version int
+	// kv is true if the mount is known to be a KV secret engine, whose
+	// secrets can be cached
+	kv bool
+}
+
+// getKVMount looks up the mount for the given secret path and determines
+// whether it is a KV v2 secret engine.  Lookups are cached per path and safe
+// for concurrent use, as secrets are read concurrently.
+// If the mount can't be determined (for example, the token doesn't have
+// access to the lookup endpoint) KV v1 is assumed.
+func (v *Vault) getKVMount(secretPath string) *kvMount {
+
+	secretPath = strings.TrimPrefix(secretPath, "/")
+
+	v.mountsMu.Lock()
+	defer v.mountsMu.Unlock()
+
+	if v.kvMounts == nil {
+		v.kvMounts = make(map[string]*kvMount)
+	}
+	if m, ok := v.kvMounts[secretPath]; ok {
+		return m
+	}
+
+	mount := &kvMount{version: 1}
+	secret, err := v.client.Logical().Read("sys/internal/ui/mounts/" + secretPath)
+	if err != nil || secret == nil {
+		v.log.Debug("Unable to determine mount for {}, assuming KV v1", secretPath)
+	} else {
+		if mountPath, ok := secret.Data["path"].(string); ok {
+			mount.path = mountPath
+		}
+		if mountType, ok := secret.Data["type"].(string); ok && (mountType == "kv" || mountType == "generic") {
+			mount.kv = true
+		}
+		if mountType, ok := secret.Data["type"].(string); ok && mountType == "kv" {
+			if options, ok := secret.Data["options"].(map[string]interface{}); ok {
+				if version, ok := options["version"].(string); ok {
+					mount.version, _ = strconv.Atoi(version)
+				}
+			}
+		}
+	}
+
+	v.kvMounts[secretPath] = mount
+	return mount
+}
+
+// kvPath rewrites a secret path for KV v2 mounts by inserting the given
+// API prefix (ex. "data" or "metadata") after the mount path.  Paths on other
+// mounts, or which already contain the prefix, are returned unchanged.
+func (v *Vault) kvPath(secretPath string, prefix string) (string, bool) {
+
+	mount := v.getKVMount(secretPath)
+	if mount.version != 2 || mount.path == "" {
+		return secretPath, false
+	}
+
+	secretPath = strings.TrimPrefix(secretPath, "/")
+	rest := strings.TrimPrefix(secretPath, mount.path)
+	if strings.HasPrefix(rest, prefix+"/") {
+		return secretPath, true
+	}
+
+	return path.Join(mount.path, prefix, rest), true
+}
+
+// readSecretData reads the secret at the given path and returns its data,
+// handling both KV v1 and v2 layouts.  For KV v2 a version of 0 returns the
+// latest version of the secret and negative versions go back from the latest
+// version (ex. -1 is the version before the latest).  Secrets of KV mounts are
+// cached by path and version (see readCache), so the data must not be
+// modified.
+func (v *Vault) readSecretData(secretPath string, version int) (map[string]interface{}, error) {
+
+	if !v.getKVMount(secretPath).kv {
+		return v.readSecretDataUncached(secretPath, version)
+	}
+
+	data, hit, err := v.reads.get(readCacheKey(secretPath, version), func() (map[string]interface{}, error) {
+		return v.readSecretDataUncached(secretPath, version)
+	})
+	if hit && err == nil {
+		v.log.Debug("Using the cached read of secret {}", secretPath)
+	}
+	return data, err
+}
+
+// readSecretDataUncached reads the secret at the given path from Vault
+func (v *Vault) readSecretDataUncached(secretPath string, version int) (map[string]interface{}, error) {
+
+	readPath, isV2 := v.kvPath(secretPath, "data")
+
+	if !isV2 && version != 0 {
+		return nil, v.newError("Version specified on non-versioned secret `" + secretPath + "`").(error)
+	}
+
+	if version < 0 {
+		current, err := v.currentSecretVersion(secretPath)
+		if err != nil {
+			return nil, err
+		}
+		if current+version < 1 {
+			return nil, v.newError(fmt.Sprintf("Version %d of secret `%s` does not exist, the current version is %d", version, secretPath, current)).(error)
+		}
+		version = current + version
+	}
+
+	var params map[string][]string
+	if version != 0 {
+		params = map[string][]string{"version": []string{strconv.Itoa(version)}}
+	}
+
+	secret, err := v.client.Logical().ReadWithData(readPath, params)
+	if err != nil {
+		return nil, v.parseError(err).(error)
+	}
+
+	// Dynamic secrets (ex. `aws/creds/<role>`) are read like KV v1 secrets
+	v.trackLease(readPath, secret)
+
+	// If we got back an empty response, fail
+	if secret == nil || secret.Data == nil {
+		return nil, v.notFoundError("Could not find secret `" + secretPath + "`").(error)
+	}
+
+	if !isV2 {
+		return secret.Data, nil
+	}
+
+	// KV v2 nests the secret under data and returns null data for deleted versions
+	data, ok := secret.Data["data"].(map[string]interface{})
+	if !ok {
+		return nil, v.newError("Could not find secret `" + secretPath + "` (it may have been deleted)").(error)
+	}
+
+	return data, nil
+}
+
+// currentSecretVersion returns the latest version number of a KV v2 secret
+func (v *Vault) currentSecretVersion(secretPath string) (int, error) {
+
+	metadataPath, _ := v.kvPath(secretPath, "metadata")
+	secret, err := v.client.Logical().Read(metadataPath)
+	if err != nil {
+		return 0, v.parseError(err).(error)
+	}
+	if secret == nil || secret.Data == nil {
+		return 0, v.notFoundError("Could not find secret `" + secretPath + "`").(error)
+	}
+
+	current, err := strconv.Atoi(fmt.Sprintf("%v", secret.Data["current_version"]))
+	if err != nil {
+		return 0, v.newError("Could not determine the current version of secret `" + secretPath + "`").(error)
+	}
+
+	return current, nil
+}
+
+// SecretVersion resolves a KV v2 secret version.  A version of 0 is the
+// latest version and negative versions go back from the latest version.
+func (v *Vault) SecretVersion(secretPath string, version int) (int, error) {
+
+	if _, isV2 := v.kvPath(secretPath, "data"); !isV2 {
+		return 0, v.newError("Secret `" + secretPath + "` is not on a KV v2 mount and has no versions").(error)
+	}
+
+	if version > 0 {
+		return version, nil
+	}
+
+	current, err := v.currentSecretVersion(secretPath)
+	if err != nil {
+		return 0, err
+	}
+	if current+version < 1 {
+		return 0, v.newError(fmt.Sprintf("Version %d of secret `%s` does not exist, the current version is %d", version, secretPath, current)).(error)
+	}
+
+	return current + version, nil
+}
+
+// RollbackSecret writes the data of an earlier version of a KV v2 secret as
+// its new latest version.  The write fails if the secret changes while rolling
+// back.  Returns the new version.
+func (v *Vault) RollbackSecret(secretPath string, version int) (int, error) {
+
+	current, err := v.SecretVersion(secretPath, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	data, err := v.readSecretData(secretPath, version)
+	if err != nil {
+		return 0, err
+	}
+
+	writePath, _ := v.kvPath(secretPath, "data")
+	secret, err := v.client.Logical().Write(writePath, map[string]interface{}{
+		"data":    data,
+		"options": map[string]interface{}{"cas": current},
+	})
+	v.reads.invalidate(secretPath)
+	if err != nil {
+		return 0, v.parseError(err).(error)
+	}
+
+	if secret != nil && secret.Data != nil {
+		if newVersion, err := strconv.Atoi(fmt.Sprintf("%v", secret.Data["version"])); err == nil {
+			return newVersion, nil
+		}
+	}
+
+	return current + 1, nil
+}
@@ -0,0 +1,120 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/PremiereGlobal/stim/pkg/clock"
+	"github.com/PremiereGlobal/stim/pkg/stimlog"
+	"github.com/PremiereGlobal/stim/pkg/utils"
+	"github.com/hashicorp/vault/api"
+	"gotest.tools/assert"
+)
+
+// newTestVault returns a client of a fake Vault with a KV v2 mount at
+// secret/, counting the mount lookups.  The server must be closed.
+func newTestVault(t *testing.T, lookups *int32) (*Vault, *httptest.Server) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body interface{}
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/v1/sys/internal/ui/mounts/"):
+			atomic.AddInt32(lookups, 1)
+			body = map[string]interface{}{"data": map[string]interface{}{
+				"path":    "secret/",
+				"type":    "kv",
+				"options": map[string]interface{}{"version": "2"},
+			}}
+		case r.URL.Path == "/v1/sys/capabilities-self":
+			var req struct{ Path string }
+			json.NewDecoder(r.Body).Decode(&req)
+			body = map[string]interface{}{"data": map[string]interface{}{req.Path: []string{"read"}}}
+		case strings.HasPrefix(r.URL.Path, "/v1/secret/data/"):
+			body = map[string]interface{}{"data": map[string]interface{}{
+				"data": map[string]interface{}{"key": "value"},
+			}}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(body)
+	}))
+
+	client, err := api.NewClient(&api.Config{Address: server.URL})
+	assert.NilError(t, err)
+	client.SetToken("token")
+
+	return &Vault{client: client, config: &Config{}, log: stimlog.GetLogger(), clock: clock.New()}, server
+}
+
+// Preflight checks secrets concurrently, and secrets on the same mount share
+// the cached mount lookup.  Run with -race.
+func TestConcurrentSecretChecks(t *testing.T) {
+	var lookups int32
+	v, server := newTestVault(t, &lookups)
+	defer server.Close()
+
+	paths := make([]string, 20)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("secret/app/secret-%d", i%4)
+	}
+
+	errs := utils.ForEachConcurrent(len(paths), 8, func(i int) error {
+		canRead, err := v.CanReadSecret(paths[i])
+		if err != nil || !canRead {
+			return fmt.Errorf("unable to read %s: %v", paths[i], err)
+		}
+		keys, err := v.GetSecretKeyNames(paths[i], 0)
+		if err != nil {
+			return err
+		}
+		if len(keys) != 1 || keys[0] != "key" {
+			return fmt.Errorf("unexpected keys of %s: %v", paths[i], keys)
+		}
+		return nil
+	})
+	for _, err := range errs {
+		assert.NilError(t, err)
+	}
+
+	// Each path is looked up once
+	assert.Equal(t, atomic.LoadInt32(&lookups), int32(4))
+}
+
+// Listing the key names of a secret doesn't keep its values in the read cache
+func TestSecretKeyNamesNotCached(t *testing.T) {
+	var lookups int32
+	v, server := newTestVault(t, &lookups)
+	defer server.Close()
+
+	keys, err := v.GetSecretKeyNames("secret/app/db", 0)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, keys, []string{"key"})
+	assert.Equal(t, len(v.reads.entries), 0)
+}
+
+// Only a missing path is reported as not found, a denied list is an error
+func TestListSecretsNotFound(t *testing.T) {
+	var lookups int32
+	v, server := newTestVault(t, &lookups)
+	defer server.Close()
+
+	_, err := v.ListSecrets("secret/missing")
+	assert.Assert(t, IsNotFound(err))
+
+	denied := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"errors":["permission denied"]}`))
+	}))
+	defer denied.Close()
+	v.client.SetAddress(denied.URL)
+
+	_, err = v.ListSecrets("secret/denied")
+	assert.ErrorContains(t, err, "permission denied")
+	assert.Assert(t, !IsNotFound(err))
+}
@@ -1,9 +1,155 @@
 package vault
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/hashicorp/vault/api"
 )
 
+// Lease is the lease of a dynamic secret (ex. AWS or database credentials)
+// read by the client
+type Lease struct {
+	ID      string    `json:"id"`
+	Path    string    `json:"path"`
+	Created time.Time `json:"created"`
+	Expires time.Time `json:"expires"`
+
+	// Kept leases are handed out (ex. printed credentials) and aren't
+	// revoked when the command ends
+	Kept bool `json:"kept,omitempty"`
+}
+
+// leaseTracker records the leases of the secrets read by a client until they
+// are revoked
+type leaseTracker struct {
+	mu     sync.Mutex
+	leases []*Lease
+}
+
+// trackLease records the lease of a secret read from the path, if it has one
+func (v *Vault) trackLease(path string, secret *api.Secret) {
+
+	if secret == nil || secret.LeaseID == "" {
+		return
+	}
+
+	now := v.clock.Now()
+	v.leases.mu.Lock()
+	defer v.leases.mu.Unlock()
+	v.leases.leases = append(v.leases.leases, &Lease{
+		ID:      secret.LeaseID,
+		Path:    strings.TrimPrefix(path, "/"),
+		Created: now,
+		Expires: now.Add(time.Duration(secret.LeaseDuration) * time.Second),
+	})
+}
+
+// Leases returns the leases of the secrets read by the client that haven't
+// been revoked
+func (v *Vault) Leases() []*Lease {
+	v.leases.mu.Lock()
+	defer v.leases.mu.Unlock()
+	leases := make([]*Lease, len(v.leases.leases))
+	copy(leases, v.leases.leases)
+	return leases
+}
+
+// KeepLeases marks the leases read so far as handed out, so that they aren't
+// revoked when the command ends
+func (v *Vault) KeepLeases() {
+	v.leases.mu.Lock()
+	defer v.leases.mu.Unlock()
+	for _, lease := range v.leases.leases {
+		lease.Kept = true
+	}
+}
+
+// updateLease updates the expiry of a tracked lease after a renewal, or stops
+// tracking it once it's revoked (ttl of 0)
+func (v *Vault) updateLease(leaseID string, ttl time.Duration) {
+	v.leases.mu.Lock()
+	defer v.leases.mu.Unlock()
+	for i, lease := range v.leases.leases {
+		if lease.ID != leaseID {
+			continue
+		}
+		if ttl == 0 {
+			v.leases.leases = append(v.leases.leases[:i], v.leases.leases[i+1:]...)
+		} else {
+			lease.Expires = v.clock.Now().Add(ttl)
+		}
+		return
+	}
+}
+
+// ReadLeaseFile returns the leases recorded in the file that haven't expired,
+// oldest first.  A missing file has no leases.
+func ReadLeaseFile(path string, now time.Time) ([]*Lease, error) {
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var leases []*Lease
+	err = json.Unmarshal(b, &leases)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid lease file %s: %v", path, err)
+	}
+
+	return unexpiredLeases(leases, now), nil
+}
+
+// WriteLeaseFile records the leases that haven't expired in the file
+func WriteLeaseFile(path string, leases []*Lease, now time.Time) error {
+
+	leases = unexpiredLeases(leases, now)
+	if leases == nil {
+		leases = []*Lease{}
+	}
+	b, err := json.MarshalIndent(leases, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0600)
+}
+
+// unexpiredLeases returns the leases that haven't expired, without
+// duplicates, sorted by creation
+func unexpiredLeases(leases []*Lease, now time.Time) []*Lease {
+
+	var unexpired []*Lease
+	seen := make(map[string]bool)
+	for _, lease := range leases {
+		if seen[lease.ID] || !lease.Expires.After(now) {
+			continue
+		}
+		seen[lease.ID] = true
+		unexpired = append(unexpired, lease)
+	}
+	sort.SliceStable(unexpired, func(i, j int) bool {
+		return unexpired[i].Created.Before(unexpired[j].Created)
+	})
+
+	return unexpired
+}
+
 // Renew lease takes a Vault lease ID and renews it for the provided duration
 // Returns the actual renew time (may be different than requested)
 func (v *Vault) RenewLease(leaseID string, duration time.Duration) (time.Duration, error) {
@@ -15,6 +161,44 @@ func (v *Vault) RenewLease(leaseID string, duration time.Duration) (time.Duratio
 	}
 
 	leaseDuration := time.Duration(secret.LeaseDuration) * time.Second
+	v.updateLease(leaseID, leaseDuration)
 
 	return leaseDuration, nil
 }
+
+// RevokeLease revokes a lease, ex. to remove dynamic credentials as soon as
+// they are no longer needed
+func (v *Vault) RevokeLease(leaseID string) error {
+
+	v.log.Debug("Revoking lease " + leaseID)
+	err := v.client.Sys().Revoke(leaseID)
+	if err != nil {
+		return v.parseError(err).(error)
+	}
+	v.updateLease(leaseID, 0)
+
+	return nil
+}
+
+// LookupLease returns the remaining TTL of a lease
+func (v *Vault) LookupLease(leaseID string) (time.Duration, error) {
+
+	secret, err := v.client.Logical().Write("sys/leases/lookup", map[string]interface{}{"lease_id": leaseID})
+	if err != nil {
+		return 0, v.parseError(err).(error)
+	}
+	if secret == nil || secret.Data == nil {
+		return 0, fmt.Errorf("Lease %s not found", leaseID)
+	}
+
+	ttl, ok := secret.Data["ttl"].(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("Unexpected TTL in lease %s", leaseID)
+	}
+	seconds, err := ttl.Int64()
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(seconds) * time.Second, nil
+}
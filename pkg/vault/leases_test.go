@@ -0,0 +1,75 @@
+package vault
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/clock"
+	"github.com/PremiereGlobal/stim/pkg/stimlog"
+	"github.com/hashicorp/vault/api"
+	"gotest.tools/assert"
+)
+
+func TestLeaseTracking(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	v := &Vault{config: &Config{}, log: stimlog.GetLogger(), clock: clock.NewFake(now)}
+
+	// Secrets without a lease (ex. KV) aren't tracked
+	v.trackLease("/secret/kube/blue", &api.Secret{})
+	v.trackLease("/aws/creds/deploy", &api.Secret{LeaseID: "aws/creds/deploy/a", LeaseDuration: 3600})
+	v.trackLease("database/creds/app", &api.Secret{LeaseID: "database/creds/app/b", LeaseDuration: 60})
+	assert.DeepEqual(t, v.Leases(), []*Lease{
+		{ID: "aws/creds/deploy/a", Path: "aws/creds/deploy", Created: now, Expires: now.Add(time.Hour)},
+		{ID: "database/creds/app/b", Path: "database/creds/app", Created: now, Expires: now.Add(time.Minute)},
+	})
+
+	v.updateLease("database/creds/app/b", 2*time.Hour)
+	assert.Equal(t, v.Leases()[1].Expires, now.Add(2*time.Hour))
+
+	v.KeepLeases()
+	v.trackLease("/aws/creds/deploy", &api.Secret{LeaseID: "aws/creds/deploy/c", LeaseDuration: 3600})
+	v.updateLease("aws/creds/deploy/a", 0)
+	leases := v.Leases()
+	assert.Equal(t, len(leases), 2)
+	assert.Equal(t, leases[0].ID, "database/creds/app/b")
+	assert.Assert(t, leases[0].Kept)
+	assert.Assert(t, !leases[1].Kept)
+}
+
+func TestLeaseFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stim-vault-leases")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "vault", "leases.json")
+
+	// A missing file has no leases
+	leases, err := ReadLeaseFile(path, time.Now())
+	assert.NilError(t, err)
+	assert.Assert(t, leases == nil)
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	older := &Lease{ID: "aws/creds/deploy/a", Path: "aws/creds/deploy", Created: now.Add(-time.Hour), Expires: now.Add(time.Hour)}
+	newer := &Lease{ID: "database/creds/app/b", Path: "database/creds/app", Created: now, Expires: now.Add(2 * time.Hour), Kept: true}
+	expired := &Lease{ID: "aws/creds/deploy/c", Path: "aws/creds/deploy", Created: now.Add(-2 * time.Hour), Expires: now}
+	assert.NilError(t, WriteLeaseFile(path, []*Lease{newer, expired, older, newer}, now))
+
+	info, err := os.Stat(path)
+	assert.NilError(t, err)
+	assert.Equal(t, info.Mode().Perm(), os.FileMode(0600))
+
+	// Sorted by creation, without duplicates or expired leases
+	leases, err = ReadLeaseFile(path, now)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, leases, []*Lease{older, newer})
+
+	leases, err = ReadLeaseFile(path, now.Add(90*time.Minute))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, leases, []*Lease{newer})
+
+	assert.NilError(t, ioutil.WriteFile(path, []byte("{"), 0600))
+	_, err = ReadLeaseFile(path, now)
+	assert.ErrorContains(t, err, "Invalid lease file")
+}
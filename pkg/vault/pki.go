@@ -0,0 +1,71 @@
+package vault
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// IssuedCertificate is a certificate and private key issued by the PKI
+// secrets engine of Vault
+type IssuedCertificate struct {
+	Certificate  string
+	PrivateKey   string
+	IssuingCA    string
+	CAChain      []string
+	SerialNumber string
+	Expiration   time.Time
+}
+
+// IssueCertificate issues a certificate for the common name with a role of
+// the PKI secrets engine at the mount.  The TTL defaults to the one of the
+// role if empty.
+func (v *Vault) IssueCertificate(mount string, role string, commonName string, altNames []string, ttl time.Duration) (*IssuedCertificate, error) {
+
+	data := map[string]interface{}{
+		"common_name": commonName,
+	}
+	if len(altNames) > 0 {
+		data["alt_names"] = strings.Join(altNames, ",")
+	}
+	if ttl > 0 {
+		data["ttl"] = ttl.String()
+	}
+
+	path := strings.Trim(mount, "/") + "/issue/" + role
+	v.log.Debug("Issuing certificate via path: ", path)
+	secret, err := v.client.Logical().Write(path, data)
+	if err != nil {
+		return nil, v.parseError(err).(error)
+	}
+	if secret == nil {
+		return nil, v.newError("No certificate returned from `" + path + "`").(error)
+	}
+
+	issued := &IssuedCertificate{}
+	issued.Certificate, _ = secret.Data["certificate"].(string)
+	issued.PrivateKey, _ = secret.Data["private_key"].(string)
+	issued.IssuingCA, _ = secret.Data["issuing_ca"].(string)
+	issued.SerialNumber, _ = secret.Data["serial_number"].(string)
+	if issued.Certificate == "" || issued.PrivateKey == "" {
+		return nil, v.newError("No certificate returned from `" + path + "`").(error)
+	}
+
+	chain, _ := secret.Data["ca_chain"].([]interface{})
+	for _, value := range chain {
+		if ca, ok := value.(string); ok {
+			issued.CAChain = append(issued.CAChain, ca)
+		}
+	}
+	if len(issued.CAChain) == 0 && issued.IssuingCA != "" {
+		issued.CAChain = []string{issued.IssuingCA}
+	}
+
+	if expiration, ok := secret.Data["expiration"].(json.Number); ok {
+		if seconds, err := expiration.Int64(); err == nil {
+			issued.Expiration = time.Unix(seconds, 0)
+		}
+	}
+
+	return issued, nil
+}
@@ -0,0 +1,84 @@
+package vault
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// readCache caches the data of the KV secrets read by a client, so that a
+// secret read for several instances of a deploy (ex. the kube-config of a
+// shared cluster) is only read once.  Concurrent reads of the same secret
+// wait for the first one.  Failed reads aren't cached.
+type readCache struct {
+	mu      sync.Mutex
+	entries map[string]*readEntry
+}
+
+// readEntry is a cached or in-flight read
+type readEntry struct {
+	done chan struct{}
+	data map[string]interface{}
+	err  error
+}
+
+// readCacheKey returns the cache key of a version of a secret
+func readCacheKey(secretPath string, version int) string {
+	return strings.TrimPrefix(secretPath, "/") + "@" + strconv.Itoa(version)
+}
+
+// get returns the cached data of the key, or reads it with read.  hit is
+// true if the data wasn't read by this call.
+func (c *readCache) get(key string, read func() (map[string]interface{}, error)) (data map[string]interface{}, hit bool, err error) {
+
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = make(map[string]*readEntry)
+	}
+	if entry, ok := c.entries[key]; ok {
+		c.mu.Unlock()
+		<-entry.done
+		return entry.data, true, entry.err
+	}
+	entry := &readEntry{done: make(chan struct{})}
+	c.entries[key] = entry
+	c.mu.Unlock()
+
+	entry.data, entry.err = read()
+	if entry.err != nil {
+		c.mu.Lock()
+		delete(c.entries, key)
+		c.mu.Unlock()
+	}
+	close(entry.done)
+
+	return entry.data, false, entry.err
+}
+
+// invalidate removes every cached version of a secret
+func (c *readCache) invalidate(secretPath string) {
+
+	prefix := strings.TrimPrefix(secretPath, "/") + "@"
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// clear removes every cached secret
+func (c *readCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = nil
+}
+
+// ClearReadCache forgets the secrets read so far, so that they are read
+// again from Vault.  Secrets are cached for the lifetime of the client, which
+// is a single stim run unless stim is used as a library.
+func (v *Vault) ClearReadCache() {
+	v.reads.clear()
+}
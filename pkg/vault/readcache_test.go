@@ -0,0 +1,71 @@
+package vault
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestReadCache(t *testing.T) {
+	var cache readCache
+	var reads int32
+	release := make(chan struct{})
+	read := func() (map[string]interface{}, error) {
+		atomic.AddInt32(&reads, 1)
+		<-release
+		return map[string]interface{}{"user-token": "token"}, nil
+	}
+
+	// Concurrent reads of the same secret wait for the first one
+	var wg sync.WaitGroup
+	hits := int32(0)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, hit, err := cache.get(readCacheKey("/secret/kube/blue", 0), read)
+			assert.NilError(t, err)
+			assert.Equal(t, data["user-token"], "token")
+			if hit {
+				atomic.AddInt32(&hits, 1)
+			}
+		}()
+	}
+	for atomic.LoadInt32(&reads) == 0 {
+		runtime.Gosched()
+	}
+	close(release)
+	wg.Wait()
+	assert.Equal(t, reads, int32(1))
+	assert.Equal(t, hits, int32(4))
+
+	// Versions are cached separately and writes invalidate every version
+	_, hit, _ := cache.get(readCacheKey("secret/kube/blue", 2), read)
+	assert.Assert(t, !hit)
+	_, hit, _ = cache.get(readCacheKey("secret/kube/blue", 0), read)
+	assert.Assert(t, hit)
+	cache.invalidate("/secret/kube/blue")
+	_, hit, _ = cache.get(readCacheKey("secret/kube/blue", 0), read)
+	assert.Assert(t, !hit)
+	assert.Equal(t, reads, int32(3))
+
+	// Failed reads aren't cached
+	failing := func() (map[string]interface{}, error) {
+		atomic.AddInt32(&reads, 1)
+		return nil, errors.New("permission denied")
+	}
+	_, _, err := cache.get(readCacheKey("secret/app", 0), failing)
+	assert.ErrorContains(t, err, "permission denied")
+	_, hit, err = cache.get(readCacheKey("secret/app", 0), failing)
+	assert.Assert(t, !hit)
+	assert.ErrorContains(t, err, "permission denied")
+	assert.Equal(t, reads, int32(5))
+
+	cache.clear()
+	_, hit, _ = cache.get(readCacheKey("secret/kube/blue", 0), read)
+	assert.Assert(t, !hit)
+}
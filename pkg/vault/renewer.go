@@ -0,0 +1,92 @@
+package vault
+
+import (
+	"time"
+)
+
+// minRenewInterval keeps the renewer from hammering Vault when a token is
+// close to expiring
+const minRenewInterval = 5 * time.Second
+
+// StartTokenRenewer periodically renews the current token in the background
+// until StopTokenRenewer is called.  This keeps the token alive during long
+// running operations such as deploys.  Non-renewable tokens are left alone.
+func (v *Vault) StartTokenRenewer() {
+
+	if v.renewStop != nil {
+		return
+	}
+
+	status, err := v.GetTokenStatus()
+	if err != nil {
+		v.log.Warn("Unable to look up Vault token, not starting renewer: {}", err)
+		return
+	}
+	if !status.Renewable {
+		v.log.Debug("Vault token is not renewable, not starting renewer")
+		return
+	}
+
+	v.renewStop = make(chan struct{})
+	go v.renewLoop("Vault token", status.TTL, v.renewStop, v.RenewToken)
+}
+
+// StopTokenRenewer stops the background token renewer, if running
+func (v *Vault) StopTokenRenewer() {
+	if v.renewStop != nil {
+		close(v.renewStop)
+		v.renewStop = nil
+	}
+}
+
+// StartLeaseRenewer periodically renews a lease by increment in the
+// background, starting from its TTL, until the returned function is called.
+// This keeps dynamic credentials alive while they are in use.
+func (v *Vault) StartLeaseRenewer(leaseID string, ttl time.Duration, increment time.Duration) func() {
+	stop := make(chan struct{})
+	go v.renewLoop("lease "+leaseID, ttl, stop, func(time.Duration) (time.Duration, error) {
+		return v.RenewLease(leaseID, increment)
+	})
+	return func() { close(stop) }
+}
+
+// renewLoop renews the token (or lease) named what with renew at half of its
+// remaining TTL.  Failed renewals are retried at half of the time left until
+// it expires, after which the renewer gives up.
+func (v *Vault) renewLoop(what string, ttl time.Duration, stop chan struct{}, renew func(increment time.Duration) (time.Duration, error)) {
+	expires := v.clock.Now().Add(ttl)
+	for {
+		interval := ttl / 2
+		if interval < minRenewInterval {
+			interval = minRenewInterval
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-v.clock.After(interval):
+		}
+
+		newTTL, err := renew(v.config.InitialTokenDuration)
+		if err != nil {
+			ttl = expires.Sub(v.clock.Now())
+			if ttl <= 0 {
+				v.log.Warn("Unable to renew {} before it expired, stopping renewer: {}", what, err)
+				return
+			}
+			v.log.Warn("Unable to renew {}, retrying (expires in {}): {}", what, ttl.Round(time.Second).String(), err)
+			continue
+		}
+
+		// A TTL this short means it's capped by its max TTL and renewing
+		// again won't extend it
+		if newTTL <= minRenewInterval {
+			v.log.Warn("The maximum TTL of {} has been reached, it will expire in {}", what, newTTL.String())
+			return
+		}
+
+		v.log.Debug("Renewed {}, now valid for {}", what, newTTL.String())
+		ttl = newTTL
+		expires = v.clock.Now().Add(ttl)
+	}
+}
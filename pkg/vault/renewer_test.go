@@ -0,0 +1,49 @@
+package vault
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/clock"
+	"github.com/PremiereGlobal/stim/pkg/stimlog"
+	"gotest.tools/assert"
+)
+
+func TestRenewLoop(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	v := &Vault{config: &Config{}, log: stimlog.GetLogger(), clock: fake}
+
+	// The token renews to 1h, then is capped by its max TTL
+	ttls := []time.Duration{time.Hour, time.Second}
+	renewed := make(chan time.Time)
+	renew := func(increment time.Duration) (time.Duration, error) {
+		renewed <- fake.Now()
+		ttl := ttls[0]
+		ttls = ttls[1:]
+		return ttl, nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		v.renewLoop("Vault token", 20*time.Minute, make(chan struct{}), renew)
+		close(done)
+	}()
+
+	// Renewed at half of the TTL
+	advance(fake, 10*time.Minute)
+	assert.Equal(t, <-renewed, time.Date(2024, 6, 1, 12, 10, 0, 0, time.UTC))
+
+	advance(fake, 30*time.Minute)
+	assert.Equal(t, <-renewed, time.Date(2024, 6, 1, 12, 40, 0, 0, time.UTC))
+
+	// The renewer stops once the max TTL is reached
+	<-done
+}
+
+// advance moves the fake clock once the renewer is waiting on it
+func advance(fake *clock.Fake, d time.Duration) {
+	for fake.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	fake.Advance(d)
+}
@@ -1,7 +1,9 @@
 package vault
 
 import (
+	"fmt"
 	"path/filepath"
+	"sort"
 
 	"github.com/hashicorp/vault/api"
 )
@@ -13,65 +15,129 @@ import (
 // the secret string present in that key.
 func (v *Vault) GetSecretKey(path string, key string) (string, error) {
 
-	secret, err := v.client.Logical().Read(path)
+	data, err := v.readSecretData(path, 0)
 	if err != nil {
-		return "", v.parseError(err).(error)
-	}
-
-	// If we got back an empty response, fail
-	if secret == nil {
-		return "", v.newError("Could not find secret `" + path + "`").(error)
+		return "", err
 	}
 
 	// If the provided key doesn't exist, fail
-	if secret.Data[key] == nil {
+	if data[key] == nil {
 		return "", v.newError("Vault: Could not find key `" + key + "` for secret `" + path + "`").(error)
 	}
 
-	return secret.Data[key].(string), nil
+	return fmt.Sprintf("%v", data[key]), nil
 }
 
 // GetSecretKeys takes a secret path and returns, if successful,
 // a map of all the keys at that path.
 func (v *Vault) GetSecretKeys(path string) (map[string]string, error) {
+	return v.GetSecretKeysVersion(path, 0)
+}
 
-	secret, err := v.client.Logical().Read(path)
-	if err != nil {
-		return nil, v.parseError(err).(error)
-	}
+// GetSecretKeysVersion is the same as GetSecretKeys but reads a specific
+// version of a KV v2 secret.  A version of 0 reads the latest version and
+// negative versions go back from the latest version.
+func (v *Vault) GetSecretKeysVersion(path string, version int) (map[string]string, error) {
 
-	// If we got back an empty response, fail
-	if secret == nil {
-		return nil, v.newError("Could not find secret `" + path + "`")
+	data, err := v.readSecretData(path, version)
+	if err != nil {
+		return nil, err
 	}
 
 	// Loop through and get all the keys
 	var secretList map[string]string
 	secretList = make(map[string]string)
-	for key, value := range secret.Data {
-		secretList[key] = value.(string)
+	for key, value := range data {
+		secretList[key] = fmt.Sprintf("%v", value)
 	}
 
 	return secretList, nil
 }
 
+// GetSecretKeyNames returns the names of the keys of a secret without
+// returning their values.  Versions are the same as GetSecretKeysVersion.
+// Vault has no API that lists the keys alone (KV v2 metadata only has the
+// versions), so the secret is read, but the values are dropped as soon as the
+// names are copied: they are never cached (see readCache) or logged.
+func (v *Vault) GetSecretKeyNames(path string, version int) ([]string, error) {
+
+	data, err := v.readSecretDataUncached(path, version)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+// CanReadSecret returns true if the current token has read capability on the
+// secret path
+func (v *Vault) CanReadSecret(path string) (bool, error) {
+
+	readPath, _ := v.kvPath(path, "data")
+	capabilities, err := v.client.Sys().CapabilitiesSelf(readPath)
+	if err != nil {
+		return false, v.parseError(err).(error)
+	}
+
+	for _, capability := range capabilities {
+		if capability == "read" || capability == "root" {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// SecretCapabilities returns true if the current token has the capability
+// (ex. `read`, `update` or `list`) on the secret path, along with all of the
+// token's capabilities on the path.  On KV v2 mounts `list` is checked on the
+// metadata path and the other capabilities on the data path.
+func (v *Vault) SecretCapabilities(path string, capability string) (bool, []string, error) {
+
+	prefix := "data"
+	if capability == "list" {
+		prefix = "metadata"
+	}
+	capabilityPath, _ := v.kvPath(path, prefix)
+	capabilities, err := v.client.Sys().CapabilitiesSelf(capabilityPath)
+	if err != nil {
+		return false, nil, v.parseError(err).(error)
+	}
+
+	for _, c := range capabilities {
+		if c == capability || c == "root" {
+			return true, capabilities, nil
+		}
+	}
+
+	return false, capabilities, nil
+}
+
 // ListSecrets takes a secret path and returns, if successful,
 // a list of all child paths under that path.
 func (v *Vault) ListSecrets(path string) ([]string, error) {
 
-	secret, err := v.client.Logical().List(path)
+	listPath, _ := v.kvPath(path, "metadata")
+	secret, err := v.client.Logical().List(listPath)
 	if err != nil {
 		return nil, v.parseError(err).(error)
 	}
 
-	// If we got back an empty response, fail
+	// If we got back an empty response (a 404), fail
 	if secret == nil {
-		return nil, v.newError("Could not find secret `" + path + "`").(error)
+		return nil, v.notFoundError("Could not find secret `" + path + "`").(error)
 	}
 
 	// Loop through and get all the keys
 	var secretList []string
-	for _, value := range secret.Data["keys"].([]interface{}) {
+	keys, _ := secret.Data["keys"].([]interface{})
+	for _, value := range keys {
 		secretList = append(secretList, filepath.Clean(value.(string)))
 	}
 
@@ -84,6 +150,44 @@ func (v *Vault) GetSecret(path string) (*api.Secret, error) {
 	if err != nil {
 		return nil, v.parseError(err).(error)
 	}
+	v.trackLease(path, secret)
 
 	return secret, nil
 }
+
+// WriteSecretKeys writes the keys of a secret, replacing the existing keys.
+// On KV v2 mounts a new version of the secret is created.
+func (v *Vault) WriteSecretKeys(path string, keys map[string]string) error {
+
+	data := make(map[string]interface{})
+	for key, value := range keys {
+		data[key] = value
+	}
+
+	writePath, isV2 := v.kvPath(path, "data")
+	if isV2 {
+		data = map[string]interface{}{"data": data}
+	}
+
+	_, err := v.client.Logical().Write(writePath, data)
+	v.reads.invalidate(path)
+	if err != nil {
+		return v.parseError(err).(error)
+	}
+
+	return nil
+}
+
+// DeleteSecret deletes a secret.  On KV v2 mounts all versions and the
+// metadata of the secret are deleted.
+func (v *Vault) DeleteSecret(path string) error {
+
+	deletePath, _ := v.kvPath(path, "metadata")
+	_, err := v.client.Logical().Delete(deletePath)
+	v.reads.invalidate(path)
+	if err != nil {
+		return v.parseError(err).(error)
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package vault
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SignedSSHKey is a public key signed by the SSH CA of Vault
+type SignedSSHKey struct {
+	Certificate  string
+	SerialNumber string
+}
+
+// SignSSHKey signs a public key with a role of the SSH secrets engine at the
+// mount.  The TTL and principals default to the ones of the role if empty.
+func (v *Vault) SignSSHKey(mount string, role string, publicKey string, ttl time.Duration, principals []string) (*SignedSSHKey, error) {
+
+	data := map[string]interface{}{
+		"public_key": publicKey,
+		"cert_type":  "user",
+	}
+	if ttl > 0 {
+		data["ttl"] = ttl.String()
+	}
+	if len(principals) > 0 {
+		data["valid_principals"] = strings.Join(principals, ",")
+	}
+
+	path := strings.Trim(mount, "/") + "/sign/" + role
+	v.log.Debug("Signing SSH public key via path: ", path)
+	secret, err := v.client.Logical().Write(path, data)
+	if err != nil {
+		return nil, v.parseError(err).(error)
+	}
+	if secret == nil {
+		return nil, v.newError("No certificate returned from `" + path + "`").(error)
+	}
+
+	certificate, _ := secret.Data["signed_key"].(string)
+	if certificate == "" {
+		return nil, v.newError("No certificate returned from `" + path + "`").(error)
+	}
+	serial, _ := secret.Data["serial_number"].(string)
+
+	return &SignedSSHKey{Certificate: certificate, SerialNumber: serial}, nil
+}
+
+// ListSSHRoles returns the roles of the SSH secrets engine at the mount
+func (v *Vault) ListSSHRoles(mount string) ([]string, error) {
+
+	path := strings.Trim(mount, "/") + "/roles"
+	secret, err := v.client.Logical().List(path)
+	if err != nil {
+		return nil, v.parseError(err).(error)
+	}
+	if secret == nil {
+		return nil, v.newError("No SSH roles found at `" + path + "`").(error)
+	}
+
+	keys, _ := secret.Data["keys"].([]interface{})
+	var roles []string
+	for _, value := range keys {
+		if role, ok := value.(string); ok {
+			roles = append(roles, filepath.Clean(role))
+		}
+	}
+
+	return roles, nil
+}
@@ -2,7 +2,11 @@ package vault
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
+
+	"github.com/hashicorp/vault/api"
 )
 
 // GetCurrentTokenTTL gets the TTL of the current token
@@ -29,3 +33,184 @@ func (v *Vault) GetCurrentTokenTTL() (time.Duration, error) {
 
 	return duration, nil
 }
+
+// TokenStatus describes the current Vault token
+type TokenStatus struct {
+	Accessor   string
+	TTL        time.Duration
+	ExpireTime time.Time
+	Renewable  bool
+	Policies   []string
+}
+
+// GetTokenStatus looks up the current token and returns its status
+func (v *Vault) GetTokenStatus() (*TokenStatus, error) {
+
+	secret, err := v.client.Auth().Token().LookupSelf()
+	if err != nil {
+		return nil, v.parseError(err).(error)
+	}
+
+	status := &TokenStatus{}
+	status.Accessor, _ = secret.TokenAccessor()
+	status.Policies, _ = secret.TokenPolicies()
+	status.Renewable, err = secret.TokenIsRenewable()
+	if err != nil {
+		return nil, err
+	}
+	status.TTL, err = secret.TokenTTL()
+	if err != nil {
+		return nil, err
+	}
+	if expireTime, ok := secret.Data["expire_time"].(string); ok {
+		status.ExpireTime, err = time.Parse(time.RFC3339Nano, expireTime)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to parse token expire time '%s': %v", expireTime, err)
+		}
+	}
+
+	return status, nil
+}
+
+// RenewToken renews the current token for the given increment and returns
+// the new TTL.  An increment of 0 uses the token's default increment.
+func (v *Vault) RenewToken(increment time.Duration) (time.Duration, error) {
+
+	secret, err := v.client.Auth().Token().RenewSelf(int(increment.Seconds()))
+	if err != nil {
+		return 0, v.parseError(err).(error)
+	}
+
+	return secret.TokenTTL()
+}
+
+// TokenCreateOptions describes a child token to create.  Role is the token
+// role to create the token with, if any.  When WrapTTL is set the token is
+// response-wrapped and only the wrapping token is returned.
+type TokenCreateOptions struct {
+	Policies       []string
+	TTL            time.Duration
+	ExplicitMaxTTL time.Duration
+	NotRenewable   bool
+	NumUses        int
+	DisplayName    string
+	Metadata       map[string]string
+	Role           string
+	WrapTTL        time.Duration
+}
+
+// CreatedToken describes a newly created token.  For wrapped tokens only
+// WrapToken and Accessor are set.
+type CreatedToken struct {
+	Token     string
+	Accessor  string
+	TTL       time.Duration
+	Policies  []string
+	WrapToken string
+}
+
+// CanCreateTokens returns true if the current token can create child tokens
+// with any policies (it has `sudo` on `auth/token/create` or is a root token)
+func (v *Vault) CanCreateTokens() (bool, error) {
+
+	capabilities, err := v.client.Sys().CapabilitiesSelf("auth/token/create")
+	if err != nil {
+		return false, v.parseError(err).(error)
+	}
+
+	for _, capability := range capabilities {
+		if capability == "sudo" || capability == "root" {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// CreateToken creates a child token of the current token, or a token of the
+// token role if one is given
+func (v *Vault) CreateToken(options *TokenCreateOptions) (*CreatedToken, error) {
+
+	request := &api.TokenCreateRequest{
+		Policies:    options.Policies,
+		DisplayName: options.DisplayName,
+		NumUses:     options.NumUses,
+		Metadata:    options.Metadata,
+	}
+	if options.TTL > 0 {
+		request.TTL = options.TTL.String()
+	}
+	if options.ExplicitMaxTTL > 0 {
+		request.ExplicitMaxTTL = options.ExplicitMaxTTL.String()
+	}
+	if options.NotRenewable {
+		renewable := false
+		request.Renewable = &renewable
+	}
+
+	client := v.client
+	if options.WrapTTL > 0 {
+		var err error
+		client, err = v.client.Clone()
+		if err != nil {
+			return nil, err
+		}
+		client.SetToken(v.client.Token())
+		client.SetWrappingLookupFunc(func(operation, path string) string {
+			return options.WrapTTL.String()
+		})
+	}
+
+	var secret *api.Secret
+	var err error
+	if options.Role != "" {
+		secret, err = client.Auth().Token().CreateWithRole(request, options.Role)
+	} else {
+		secret, err = client.Auth().Token().Create(request)
+	}
+	if err != nil {
+		return nil, v.parseError(err).(error)
+	}
+
+	if options.WrapTTL > 0 {
+		if secret.WrapInfo == nil {
+			return nil, errors.New("No wrapped token returned by Vault")
+		}
+		return &CreatedToken{
+			Accessor:  secret.WrapInfo.WrappedAccessor,
+			WrapToken: secret.WrapInfo.Token,
+		}, nil
+	}
+
+	return createdToken(secret)
+}
+
+// UnwrapToken returns the token wrapped by a wrapping token.  A wrapping
+// token can only be unwrapped once.
+func (v *Vault) UnwrapToken(wrapToken string) (*CreatedToken, error) {
+
+	secret, err := v.client.Logical().Unwrap(wrapToken)
+	if err != nil {
+		return nil, v.parseError(err).(error)
+	}
+	if secret == nil {
+		return nil, errors.New("The wrapped token was not found, it has expired or was already unwrapped")
+	}
+
+	return createdToken(secret)
+}
+
+// createdToken returns the token of a token creation response
+func createdToken(secret *api.Secret) (*CreatedToken, error) {
+
+	if secret.Auth == nil {
+		return nil, errors.New("No token returned by Vault")
+	}
+
+	return &CreatedToken{
+		Token:    secret.Auth.ClientToken,
+		Accessor: secret.Auth.Accessor,
+		TTL:      time.Duration(secret.Auth.LeaseDuration) * time.Second,
+		Policies: secret.Auth.Policies,
+	}, nil
+}
@@ -0,0 +1,113 @@
+package vault
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/PremiereGlobal/stim/pkg/credentials"
+	"github.com/PremiereGlobal/stim/pkg/utils"
+	"github.com/hashicorp/vault/command/token"
+)
+
+// TokenHelper stores and retrieves the Vault token between runs
+type TokenHelper interface {
+	Path() string
+	Get() (string, error)
+	Store(string) error
+	Erase() error
+}
+
+// fileTokenHelper caches the token in a user-only (0600) file at a custom path
+type fileTokenHelper struct {
+	path string
+}
+
+// tokenCredentialKey is the key of the token in the credential store
+const tokenCredentialKey = "vault-token"
+
+// newTokenHelper returns the token helper to use for the given cache path.
+// If no path is given, the standard Vault token file (~/.vault-token) is used
+// so that tokens are shared with the vault CLI.  With a credential store the
+// token is kept in the store instead, and moved there from the cache path.
+func newTokenHelper(cachePath string, store credentials.Store) (TokenHelper, error) {
+	if store != nil {
+		if cachePath != "" {
+			err := credentials.MigrateFile(store, tokenCredentialKey, cachePath)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return &storeTokenHelper{store: store}, nil
+	}
+
+	if cachePath == "" {
+		return &token.InternalTokenHelper{}, nil
+	}
+
+	return &fileTokenHelper{path: cachePath}, nil
+}
+
+// Path returns the path of the token cache file
+func (f *fileTokenHelper) Path() string {
+	return f.path
+}
+
+// Get returns the cached token, or an empty string if there isn't one
+func (f *fileTokenHelper) Get() (string, error) {
+	b, err := ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(b)), nil
+}
+
+// Store writes the token to the cache file, readable only by the user
+func (f *fileTokenHelper) Store(input string) error {
+	err := utils.CreateDirIfNotExist(filepath.Dir(f.path), utils.UserOnlyMode)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(f.path, []byte(input), 0600)
+}
+
+// Erase removes the cached token
+func (f *fileTokenHelper) Erase() error {
+	if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// storeTokenHelper keeps the token in a credential store (ex. the OS keychain)
+type storeTokenHelper struct {
+	store credentials.Store
+}
+
+// Path describes where the token is kept
+func (s *storeTokenHelper) Path() string {
+	return "credential store " + s.store.Name()
+}
+
+// Get returns the stored token, or an empty string if there isn't one
+func (s *storeTokenHelper) Get() (string, error) {
+	value, err := s.store.Get(tokenCredentialKey)
+	return strings.TrimSpace(value), err
+}
+
+// Store saves the token in the credential store
+func (s *storeTokenHelper) Store(input string) error {
+	return s.store.Set(tokenCredentialKey, input)
+}
+
+// Erase removes the stored token
+func (s *storeTokenHelper) Erase() error {
+	return s.store.Delete(tokenCredentialKey)
+}
@@ -1,30 +1,65 @@
 package vault
 
 import (
+	"context"
+	"net/http"
+	"sync"
 	"time"
 
+	"github.com/PremiereGlobal/stim/pkg/bom"
+	"github.com/PremiereGlobal/stim/pkg/cancel"
+	"github.com/PremiereGlobal/stim/pkg/certs"
+	"github.com/PremiereGlobal/stim/pkg/clock"
+	"github.com/PremiereGlobal/stim/pkg/credentials"
+	"github.com/PremiereGlobal/stim/pkg/retry"
 	"github.com/PremiereGlobal/stim/pkg/stimlog"
+	"github.com/PremiereGlobal/stim/pkg/utils"
 	"github.com/hashicorp/vault/api"
-	"github.com/hashicorp/vault/command/token"
 )
 
 type Vault struct {
 	client      *api.Client
 	config      *Config
-	tokenHelper token.InternalTokenHelper
+	tokenHelper TokenHelper
 	newLogin    bool
+	renewStop   chan struct{}
+	kvMounts    map[string]*kvMount
+	mountsMu    sync.Mutex
+	reads       readCache
+	leases      leaseTracker
 	log         Logger
+	clock       clock.Clock
 }
 
 type Config struct {
+	AuthMethod           string
 	AuthPath             string
+	Role                 string
+	RoleID               string
+	SecretID             string
+	JWT                  string
+	JWTPath              string
+	OIDCCallbackPort     int
 	Noprompt             bool
 	Address              string
 	Username             string
 	UsernameSkipPrompt   bool
 	Timeout              time.Duration
 	InitialTokenDuration time.Duration
+	TokenCachePath       string
+	MinTokenTTL          time.Duration
 	Log                  Logger
+	// Clock is used by the token renewer.  Defaults to the real clock.
+	Clock clock.Clock
+	// Recorder records the paths used by the client in the bill of materials
+	Recorder *bom.Recorder
+	// Retry retries requests that fail with transient errors instead of the
+	// retries of the Vault client
+	Retry *retry.Policy
+	// Credentials keeps the token instead of the token cache file, if set
+	Credentials credentials.Store
+	// Context cancels the requests in flight when it's done (ex. Ctrl-C)
+	Context context.Context
 }
 
 type Logger interface {
@@ -40,6 +75,10 @@ func New(config *Config) (*Vault, error) {
 	} else {
 		v.log = stimlog.GetLogger()
 	}
+	v.clock = config.Clock
+	if v.clock == nil {
+		v.clock = clock.New()
+	}
 
 	// Ensure that the Vault address is set
 	if config.Address == "" {
@@ -48,17 +87,49 @@ func New(config *Config) (*Vault, error) {
 
 	// Set a default auth method to ldap if not set
 	// TODO: change this to token auth as it is more generic
+	if config.AuthMethod == "" {
+		config.AuthMethod = AuthMethodLdap
+	}
+
+	// Unknown methods are treated as the mount path of a username/password
+	// backend for backwards compatibility with the old `auth.method` option
+	if !utils.Contains(AuthMethods, config.AuthMethod) {
+		if config.AuthPath == "" {
+			config.AuthPath = config.AuthMethod
+		}
+		config.AuthMethod = AuthMethodLdap
+	}
+
+	// The auth backend is mounted at the method name by default
 	if config.AuthPath == "" {
-		config.AuthPath = "ldap"
+		config.AuthPath = config.AuthMethod
+	}
+
+	var err error
+	v.tokenHelper, err = newTokenHelper(config.TokenCachePath, config.Credentials)
+	if err != nil {
+		return nil, v.newError("Unable to read the Vault token: " + err.Error())
 	}
 
 	// Configure new Vault Client
 	apiConfig := api.DefaultConfig()
 	apiConfig.Address = v.config.Address // Since we read the env we can override
 	apiConfig.Timeout = time.Duration(v.config.Timeout) * time.Second
+	if transport, ok := apiConfig.HttpClient.Transport.(*http.Transport); ok {
+		certs.ConfigureTransport(transport)
+	}
+	if v.config.Retry != nil {
+		apiConfig.HttpClient.Transport = v.config.Retry.Transport(apiConfig.HttpClient.Transport)
+		apiConfig.MaxRetries = 0
+	}
+	if v.config.Recorder != nil {
+		apiConfig.HttpClient.Transport = v.config.Recorder.Transport(v.config.Address, apiConfig.HttpClient.Transport)
+	}
+	if v.config.Context != nil {
+		apiConfig.HttpClient.Transport = cancel.Transport(v.config.Context, apiConfig.HttpClient.Transport)
+	}
 
 	// Create our new API client
-	var err error
 	v.client, err = api.NewClient(apiConfig)
 	if err != nil {
 		return nil, v.parseError(err)
@@ -4,20 +4,21 @@ set -eo pipefail
 
 VERSION=${1:-master}
 GOOS=${2:-linux}
+GOARCH=${3:-amd64}
 DOCKER_REPO="premiereglobal/stim"
 
 # Directory to house our binaries
 mkdir -p bin
 
 # Build the container
-docker build --build-arg VERSION=${VERSION} --build-arg GOOS=${GOOS} -t ${DOCKER_REPO}:${VERSION}-${GOOS} ./
+docker build --build-arg VERSION=${VERSION} --build-arg GOOS=${GOOS} --build-arg GOARCH=${GOARCH} --build-arg RELEASE_PUBLIC_KEY=${STIM_RELEASE_PUBLIC_KEY} -t ${DOCKER_REPO}:${VERSION}-${GOOS} ./
 
 # Extract the binary from the container
 docker run --rm --entrypoint "" --name stim-build -v $(pwd)/bin:/stim-bin ${DOCKER_REPO}:${VERSION}-${GOOS} sh -c "cp /usr/bin/stim /stim-bin"
 
 # Zip up the binary
 cd bin
-tar -cvzf stim-${GOOS}-${VERSION}.tar.gz stim
+tar -cvzf stim-${GOOS}-${GOARCH}-${VERSION}.tar.gz stim
 cd ..
 
 # Build the deploy container
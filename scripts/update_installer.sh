@@ -8,7 +8,16 @@ mkdir -p bin
 cp install.sh bin
 cd bin
 sed -i 's/^VERSION\=v.*$/VERSION\='${VERSION}'/g' install.sh
-SHA_DARWIN=$(sha256sum stim-darwin-${VERSION}.tar.gz | cut -d' ' -f 1)
+SHA_DARWIN=$(sha256sum stim-darwin-amd64-${VERSION}.tar.gz | cut -d' ' -f 1)
 sed -i 's/^SHA_DARWIN\=.*$/SHA_DARWIN\='${SHA_DARWIN}'/g' install.sh
-SHA_LINUX=$(sha256sum stim-linux-${VERSION}.tar.gz | cut -d' ' -f 1)
+SHA_LINUX=$(sha256sum stim-linux-amd64-${VERSION}.tar.gz | cut -d' ' -f 1)
 sed -i 's/^SHA_LINUX\=.*$/SHA_LINUX\='${SHA_LINUX}'/g' install.sh
+
+# Checksums of the release archives, used by `stim update` to verify downloads
+sha256sum stim-*-${VERSION}.tar.gz > checksums.txt
+
+# Sign the checksums with the release signing key (an Ed25519 private key in
+# PEM format), whose public key is built into stim to verify the checksums
+if [ -n "${STIM_RELEASE_SIGNING_KEY}" ]; then
+  openssl pkeyutl -sign -rawin -inkey <(echo "${STIM_RELEASE_SIGNING_KEY}") -in checksums.txt -out checksums.txt.sig
+fi
@@ -0,0 +1,317 @@
+package stim
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// The results of an audited command
+const (
+	auditSuccess = "success"
+	auditFailure = "failure"
+)
+
+// auditRedacted replaces secret values in the audited arguments
+const auditRedacted = "<redacted>"
+
+// auditSensitive are the parts of flag and key names whose values are
+// redacted (ex. `--secret-id`, `vault-token`, `DB_PASSWORD=...`)
+var auditSensitive = []string{"password", "secret", "token", "jwt", "credential", "api-key", "apikey"}
+
+// AuditEvent is a record of a stim command invocation
+type AuditEvent struct {
+	Time            time.Time `json:"time"`
+	User            string    `json:"user"`
+	Host            string    `json:"host"`
+	Profile         string    `json:"profile,omitempty"`
+	Version         string    `json:"version"`
+	Command         string    `json:"command"`
+	Args            []string  `json:"args"`
+	Result          string    `json:"result"`
+	Error           string    `json:"error,omitempty"`
+	DurationSeconds float64   `json:"durationSeconds"`
+}
+
+// auditInit starts auditing the command.  Commands that fail with a Fatal log
+// are audited before stim exits.
+func (stim *Stim) auditInit() {
+	if stim.ConfigGetBool("audit.disable") {
+		return
+	}
+	stim.logConfig.AddExitHook(func(message string) {
+		stim.audit(message)
+	})
+}
+
+// audit records the command invocation to the audit log and ships it to the
+// configured audit destinations.  An empty errMessage means the command
+// succeeded.  Audit errors are logged but never fail the command.
+func (stim *Stim) audit(errMessage string) {
+
+	if stim.audited || !stim.initialized || stim.ConfigGetBool("audit.disable") {
+		return
+	}
+	stim.audited = true
+
+	cmd, args, err := stim.rootCmd.Find(os.Args[1:])
+	if err != nil {
+		cmd = stim.rootCmd
+		args = os.Args[1:]
+	}
+
+	// Help, shell completions (which run on every tab press) and other
+	// informational commands don't change anything
+	if isInformational(cmd) {
+		return
+	}
+
+	event := &AuditEvent{
+		Time:            stim.startTime.UTC(),
+		Profile:         stim.ConfigGetProfile(),
+		Version:         stim.GetVersion(),
+		Command:         cmd.CommandPath(),
+		Args:            auditArgs(cmd, args),
+		Result:          auditSuccess,
+		DurationSeconds: stim.clock.Now().Sub(stim.startTime).Seconds(),
+	}
+	if errMessage != "" {
+		event.Result = auditFailure
+		event.Error = errMessage
+	}
+	stim.auditShip(event)
+}
+
+// AuditAction records an action that a command takes on its own (ex. an
+// automatic revert) as a separate audit event, so it is in the audit log when
+// it happens rather than when the command exits
+func (stim *Stim) AuditAction(action string, args []string, actionErr error) {
+
+	if stim.ConfigGetBool("audit.disable") {
+		return
+	}
+
+	event := &AuditEvent{
+		Time:    stim.clock.Now().UTC(),
+		Profile: stim.ConfigGetProfile(),
+		Version: stim.GetVersion(),
+		Command: action,
+		Args:    args,
+		Result:  auditSuccess,
+	}
+	if actionErr != nil {
+		event.Result = auditFailure
+		event.Error = actionErr.Error()
+	}
+	stim.auditShip(event)
+}
+
+// auditShip sets the user and host of the event, then writes it to the audit
+// log and ships it to the configured audit destinations
+func (stim *Stim) auditShip(event *AuditEvent) {
+
+	var err error
+	event.User, err = stim.User()
+	if err != nil {
+		event.User = "unknown"
+	}
+	event.Host, _ = os.Hostname()
+
+	err = stim.auditWrite(event)
+	if err != nil {
+		stim.log.Warn("Stim-Audit: Unable to write the audit log: {}", err)
+	}
+
+	if url := stim.ConfigGetString("audit.webhook"); url != "" {
+		err = auditWebhook(url, event)
+		if err != nil {
+			stim.log.Warn("Stim-Audit: Unable to send the audit event to the webhook: {}", err)
+		}
+	}
+
+	if s3Path := stim.ConfigGetString("audit.s3"); s3Path != "" {
+		err = stim.auditS3(s3Path, event)
+		if err != nil {
+			stim.log.Warn("Stim-Audit: Unable to upload the audit event to S3: {}", err)
+		}
+	}
+
+	if vaultPath := stim.ConfigGetString("audit.vault-path"); vaultPath != "" {
+		// The audit never logs in to Vault, so only commands that already used
+		// Vault are shipped
+		if stim.vault == nil {
+			stim.log.Debug("Stim-Audit: Not logged in to Vault, skipping the Vault audit path")
+		} else {
+			err = stim.vault.WriteSecretKeys(strings.TrimSuffix(vaultPath, "/")+"/"+auditEventID(event), auditEventKeys(event))
+			if err != nil {
+				stim.log.Warn("Stim-Audit: Unable to write the audit event to Vault: {}", err)
+			}
+		}
+	}
+}
+
+// auditWrite appends the event as a JSON line to the local audit log
+func (stim *Stim) auditWrite(event *AuditEvent) error {
+
+	path := stim.ConfigGetString("audit.path")
+	if path == "" {
+		dir, err := stim.ConfigGetStimConfigDir()
+		if err != nil {
+			return err
+		}
+		path = filepath.Join(dir, "audit.log")
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Encode writes one line per event.  HTML escaping is turned off so that
+	// the log is readable (ex. `<redacted>`).
+	encoder := json.NewEncoder(f)
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(event)
+}
+
+// auditWebhook posts the event as JSON to the URL
+func auditWebhook(url string, event *AuditEvent) error {
+
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// auditS3 uploads the event to `s3://<bucket>/<prefix>` using the default AWS
+// credentials
+func (stim *Stim) auditS3(s3Path string, event *AuditEvent) error {
+
+	bucket := strings.TrimPrefix(s3Path, "s3://")
+	prefix := ""
+	if i := strings.Index(bucket, "/"); i >= 0 {
+		bucket, prefix = bucket[:i], strings.Trim(bucket[i+1:], "/")
+	}
+
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	aws, err := stim.NewAws("", "")
+	if err != nil {
+		return err
+	}
+	err = aws.CreateDefaultSession(stim.ConfigGetString("audit.s3-profile"), stim.ConfigGetString("audit.s3-region"))
+	if err != nil {
+		return err
+	}
+
+	key := filepath.ToSlash(filepath.Join(prefix, event.Time.Format("2006/01/02"), auditEventID(event)+".json"))
+	return aws.PutObject(bucket, key, b, "application/json")
+}
+
+// auditEventID returns a unique, time ordered ID for the event
+func auditEventID(event *AuditEvent) string {
+	return event.Time.Format("20060102T150405.000000000Z") + "-" + event.User
+}
+
+// auditEventKeys returns the event as Vault secret keys
+func auditEventKeys(event *AuditEvent) map[string]string {
+	return map[string]string{
+		"time":             event.Time.Format(time.RFC3339Nano),
+		"user":             event.User,
+		"host":             event.Host,
+		"profile":          event.Profile,
+		"version":          event.Version,
+		"command":          event.Command,
+		"args":             strings.Join(event.Args, " "),
+		"result":           event.Result,
+		"error":            event.Error,
+		"duration-seconds": strconv.FormatFloat(event.DurationSeconds, 'f', 3, 64),
+	}
+}
+
+// auditArgs returns the arguments of the command with the values of
+// sensitive flags, `KEY=VALUE` pairs and key/value arguments (ex. `stim
+// config set vault-token <token>`) redacted
+func auditArgs(cmd *cobra.Command, args []string) []string {
+
+	var audited []string
+	redactNext := false
+	for _, arg := range args {
+		switch {
+		case redactNext:
+			audited = append(audited, auditRedacted)
+			redactNext = false
+		case strings.HasPrefix(arg, "-"):
+			name := strings.TrimLeft(arg, "-")
+			i := strings.Index(name, "=")
+			if i >= 0 {
+				name = name[:i]
+			}
+
+			var flag *pflag.Flag
+			if strings.HasPrefix(arg, "--") {
+				flag = cmd.Flags().Lookup(name)
+			} else if len(name) == 1 {
+				flag = cmd.Flags().ShorthandLookup(name)
+			}
+			if !IsSensitive(name) && (flag == nil || !IsSensitive(flag.Name)) {
+				audited = append(audited, arg)
+				continue
+			}
+
+			if i >= 0 {
+				audited = append(audited, arg[:strings.Index(arg, "=")+1]+auditRedacted)
+				continue
+			}
+			audited = append(audited, arg)
+			redactNext = flag == nil || flag.Value.Type() != "bool"
+		case strings.Contains(arg, "="):
+			i := strings.Index(arg, "=")
+			if IsSensitive(arg[:i]) {
+				arg = arg[:i+1] + auditRedacted
+			}
+			audited = append(audited, arg)
+		default:
+			audited = append(audited, arg)
+			redactNext = IsSensitive(arg)
+		}
+	}
+
+	return audited
+}
+
+// IsSensitive returns true if the flag, key or env var name may hold a secret
+func IsSensitive(name string) bool {
+	name = strings.Replace(strings.ToLower(name), "_", "-", -1)
+	for _, s := range auditSensitive {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package stim
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"gotest.tools/assert"
+)
+
+func TestAuditArgs(t *testing.T) {
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().StringP("secret-id", "s", "", "")
+	cmd.Flags().String("environment", "", "")
+	cmd.Flags().Bool("token-only", false, "")
+
+	tests := []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"--environment", "prod"}, []string{"--environment", "prod"}},
+		{[]string{"--secret-id", "abc", "-e"}, []string{"--secret-id", auditRedacted, "-e"}},
+		{[]string{"-s", "abc"}, []string{"-s", auditRedacted}},
+		{[]string{"--secret-id=abc"}, []string{"--secret-id=" + auditRedacted}},
+		{[]string{"--token-only", "prod"}, []string{"--token-only", "prod"}},
+		{[]string{"--param", "DB_PASSWORD=hunter2", "BRANCH=main"}, []string{"--param", "DB_PASSWORD=" + auditRedacted, "BRANCH=main"}},
+		{[]string{"vault-token", "s.abc"}, []string{"vault-token", auditRedacted}},
+	}
+
+	for _, test := range tests {
+		assert.DeepEqual(t, auditArgs(cmd, test.args), test.expected)
+	}
+}
+
+func TestIsInformational(t *testing.T) {
+	version := &cobra.Command{Use: "version", Annotations: map[string]string{AnnotationInformational: "true"}}
+	assert.Assert(t, isInformational(version))
+	assert.Assert(t, isInformational(&cobra.Command{Use: "help"}))
+	assert.Assert(t, isInformational(&cobra.Command{Use: completionValuesCommand}))
+
+	deploy := &cobra.Command{Use: "deploy"}
+	deploy.Flags().BoolP("help", "h", false, "")
+	assert.Assert(t, !isInformational(deploy))
+	deploy.Flags().Set("help", "true")
+	assert.Assert(t, isInformational(deploy))
+}
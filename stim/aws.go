@@ -1,15 +1,79 @@
 package stim
 
 import (
+	"fmt"
+
 	"github.com/PremiereGlobal/stim/pkg/aws"
 )
 
 func (stim *Stim) Aws(accessKey string, secretKey string) *aws.Aws {
-	stim.GetLogger().Debug("Stim-Aws: Creating")
-	a, err := aws.New(&aws.Config{AccessKey: accessKey, SecretKey: secretKey, Log: stim.GetLogger()})
+	a, err := stim.NewAws(accessKey, secretKey)
 	if err != nil {
-		stim.log.Fatal("Stim-Aws: Error Initializaing: ", err)
+		stim.Fatal(err)
 	}
 
 	return a
 }
+
+// NewAws is the same as Aws but returns an error instead of exiting if the
+// AWS session can't be created
+func (stim *Stim) NewAws(accessKey string, secretKey string) (*aws.Aws, error) {
+	stim.GetLogger().Debug("Stim-Aws: Creating")
+	store, err := stim.Credentials()
+	if err != nil {
+		return nil, err
+	}
+	maxRetries := stim.RetryPolicy().MaxRetries
+	a, err := aws.New(&aws.Config{AccessKey: accessKey, SecretKey: secretKey, Log: stim.GetLogger(), Recorder: stim.bom, MaxRetries: &maxRetries, Credentials: store})
+	if err != nil {
+		return nil, fmt.Errorf("Stim-Aws: Error Initializaing: %v", err)
+	}
+
+	return a, nil
+}
+
+// ECRAuthorization returns the Docker login of the ECR registry of an account
+// (the account of the credentials if empty) in a region.  The AWS credentials
+// are read from the Vault AWS mount and role if they are set, otherwise from
+// the default credential chain.
+func (stim *Stim) ECRAuthorization(accountID string, region string, vaultAccount string, vaultRole string) (*aws.ECRAuthorization, error) {
+
+	a, err := stim.NewAws("", "")
+	if err != nil {
+		return nil, err
+	}
+
+	if vaultAccount == "" && vaultRole == "" {
+		err = a.CreateDefaultSession("", region)
+		if err != nil {
+			return nil, err
+		}
+		return a.GetECRAuthorization(accountID)
+	}
+
+	vault, err := stim.NewVault()
+	if err != nil {
+		return nil, err
+	}
+	secret, err := vault.AWScredentials(vaultAccount, vaultRole)
+	if err != nil {
+		return nil, err
+	}
+	accessKey, _ := secret.Data["access_key"].(string)
+	secretKey, _ := secret.Data["secret_key"].(string)
+	sessionToken, _ := secret.Data["security_token"].(string)
+	err = a.CreateSessionWithToken(accessKey, secretKey, sessionToken, region)
+	if err != nil {
+		return nil, err
+	}
+
+	// New IAM users take a while to become active
+	if sessionToken == "" {
+		err = a.VerifyActiveCreds()
+		if err != nil {
+			return nil, AuthError(err)
+		}
+	}
+
+	return a.GetECRAuthorization(accountID)
+}
@@ -0,0 +1,220 @@
+package stim
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/azure"
+	"github.com/PremiereGlobal/stim/pkg/credentials"
+	"gopkg.in/yaml.v2"
+)
+
+// azureTokenCacheFile is the cache file (in the `azure` cache directory) of
+// the token of `stim azure login --device-code`
+const azureTokenCacheFile = "token.yaml"
+
+// azureTokenCredentialKey is the credential store key of the device code token
+const azureTokenCredentialKey = "azure-token"
+
+// azureLoginAttempts is how many times a service principal from Vault is
+// tried while it propagates in Azure AD, azureLoginRetryDelay apart
+const (
+	azureLoginAttempts   = 12
+	azureLoginRetryDelay = 5 * time.Second
+)
+
+// AzureServicePrincipal is a service principal from a Vault Azure secrets
+// engine role, with the tenant and subscription it is used with
+type AzureServicePrincipal struct {
+	ClientID       string
+	ClientSecret   string
+	TenantID       string
+	SubscriptionID string
+	LeaseID        string
+}
+
+// Envs returns the env vars that the terraform azurerm provider and backend
+// read the credentials of the service principal from
+func (sp *AzureServicePrincipal) Envs() []string {
+	envs := []string{
+		"ARM_CLIENT_ID=" + sp.ClientID,
+		"ARM_CLIENT_SECRET=" + sp.ClientSecret,
+		"ARM_TENANT_ID=" + sp.TenantID,
+	}
+	if sp.SubscriptionID != "" {
+		envs = append(envs, "ARM_SUBSCRIPTION_ID="+sp.SubscriptionID)
+	}
+	return envs
+}
+
+// Azure returns an Azure client that is already logged in
+func (stim *Stim) Azure(account string, role string) *azure.Azure {
+	a, err := stim.NewAzure(account, role)
+	if err != nil {
+		stim.Fatal(err)
+	}
+	return a
+}
+
+// NewAzure is the same as Azure but returns an error instead of exiting if
+// it can't log in.  The client logs in as the service principal of the Vault
+// Azure secrets engine `account` and `role` if set, otherwise it uses the
+// cached token of `stim azure login --device-code`.
+func (stim *Stim) NewAzure(account string, role string) (*azure.Azure, error) {
+	stim.log.Debug("Stim-Azure: Creating")
+
+	if account == "" {
+		token, err := stim.AzureCachedToken()
+		if err != nil {
+			return nil, AuthError(err)
+		}
+		if token == nil {
+			return nil, AuthError(errors.New("Stim-Azure: Not logged in to Azure, run `stim azure login --device-code` or set `azure.vault-account` and `azure.vault-role` in the stim config"))
+		}
+		a := azure.New(&azure.Config{TenantID: token.TenantID})
+		a.SetToken(token)
+		return a, nil
+	}
+
+	sp, err := stim.AzureServicePrincipal(account, role)
+	if err != nil {
+		return nil, AuthError(err)
+	}
+	a := azure.New(&azure.Config{TenantID: sp.TenantID})
+	err = stim.AzureLogin(a, sp)
+	if err != nil {
+		return nil, AuthError(err)
+	}
+	return a, nil
+}
+
+// AzureServicePrincipal reads a new service principal from the role of a
+// Vault Azure secrets engine.  The tenant and subscription are
+// `azure.tenant-id` and `azure.subscription-id`, or those of the secrets
+// engine config if not set (reading it needs access to `<account>/config`).
+func (stim *Stim) AzureServicePrincipal(account string, role string) (*AzureServicePrincipal, error) {
+
+	vault, err := stim.NewVault()
+	if err != nil {
+		return nil, err
+	}
+
+	sp := &AzureServicePrincipal{
+		TenantID:       stim.ConfigGetString("azure.tenant-id"),
+		SubscriptionID: stim.ConfigGetString("azure.subscription-id"),
+	}
+	if sp.TenantID == "" || sp.SubscriptionID == "" {
+		config, err := vault.AzureConfig(account)
+		if err != nil {
+			stim.log.Debug("Stim-Azure: Unable to read the config of Vault mount {}: {}", account, err)
+		} else {
+			if sp.TenantID == "" {
+				sp.TenantID = config["tenant_id"]
+			}
+			if sp.SubscriptionID == "" {
+				sp.SubscriptionID = config["subscription_id"]
+			}
+		}
+	}
+	stim.log.Debug("Stim-Azure: Getting Azure credentials from Vault {}/creds/{}", account, role)
+	secret, err := vault.AzureCredentials(account, role)
+	if err != nil {
+		return nil, fmt.Errorf("Stim-Azure: Unable to get Azure credentials from Vault: %v", err)
+	}
+	sp.ClientID, _ = secret.Data["client_id"].(string)
+	sp.ClientSecret, _ = secret.Data["client_secret"].(string)
+	sp.LeaseID = secret.LeaseID
+
+	return sp, nil
+}
+
+// AzureLogin logs the client in as the service principal.  New service
+// principals from Vault take a while to propagate in Azure AD, so failed
+// logins are retried for up to a minute.
+func (stim *Stim) AzureLogin(a *azure.Azure, sp *AzureServicePrincipal) error {
+
+	if sp.TenantID == "" {
+		return errors.New("Stim-Azure: No Azure tenant, set `azure.tenant-id` in the stim config")
+	}
+
+	var err error
+	for attempt := 1; attempt <= azureLoginAttempts; attempt++ {
+		err = a.LoginClientSecret(sp.ClientID, sp.ClientSecret)
+		if err == nil {
+			return nil
+		}
+		stim.log.Debug("Stim-Azure: Login attempt {} of service principal {} failed: {}", attempt, sp.ClientID, err)
+		if attempt < azureLoginAttempts {
+			time.Sleep(azureLoginRetryDelay)
+		}
+	}
+
+	return fmt.Errorf("Stim-Azure: Unable to log in as service principal %s: %v", sp.ClientID, err)
+}
+
+// AzureCachedToken returns the token saved by `stim azure login
+// --device-code`, nil if there is none.  With a credential store the token is
+// kept in the store, and a token cache file is moved into it.
+func (stim *Stim) AzureCachedToken() (*azure.Token, error) {
+
+	cachePath := filepath.Join(stim.ConfigGetCacheDir("azure"), azureTokenCacheFile)
+	store, err := stim.Credentials()
+	if err != nil {
+		return nil, err
+	}
+
+	var b []byte
+	if store != nil {
+		err = credentials.MigrateFile(store, azureTokenCredentialKey, cachePath)
+		if err != nil {
+			return nil, err
+		}
+		value, err := store.Get(azureTokenCredentialKey)
+		if err != nil {
+			return nil, err
+		}
+		if value == "" {
+			return nil, nil
+		}
+		b = []byte(value)
+	} else {
+		b, err = ioutil.ReadFile(cachePath)
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	token := &azure.Token{}
+	err = yaml.Unmarshal(b, token)
+	if err != nil {
+		return nil, fmt.Errorf("Stim-Azure: Invalid token cache: %v", err)
+	}
+	return token, nil
+}
+
+// SaveAzureToken saves the token of a device code login (or its refreshed
+// token) for the next commands
+func (stim *Stim) SaveAzureToken(token *azure.Token) error {
+
+	b, err := yaml.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	store, err := stim.Credentials()
+	if err != nil {
+		return err
+	}
+	if store != nil {
+		return store.Set(azureTokenCredentialKey, string(b))
+	}
+
+	return ioutil.WriteFile(filepath.Join(stim.ConfigGetCacheDir("azure"), azureTokenCacheFile), b, 0600)
+}
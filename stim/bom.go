@@ -0,0 +1,12 @@
+package stim
+
+import (
+	"github.com/PremiereGlobal/stim/pkg/bom"
+)
+
+// BOM returns the recorder of the bill of materials.  The Vault, AWS and
+// Kubernetes clients of stim record the resources they use once it is
+// started.
+func (stim *Stim) BOM() *bom.Recorder {
+	return stim.bom
+}
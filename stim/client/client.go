@@ -0,0 +1,190 @@
+// Package client is the library API of stim.  It lets other Go programs log
+// in to Vault, create AWS and Kubernetes credentials and run deploys with the
+// user's stim config without going through the stim commands.  Errors are
+// returned instead of exiting the program and carry the same exit codes as
+// the stim commands (see stim.ExitCode).
+package client
+
+import (
+	"errors"
+
+	"github.com/PremiereGlobal/stim/pkg/aws"
+	"github.com/PremiereGlobal/stim/pkg/clock"
+	"github.com/PremiereGlobal/stim/pkg/kubernetes"
+	"github.com/PremiereGlobal/stim/pkg/vault"
+	"github.com/PremiereGlobal/stim/stim"
+	"github.com/PremiereGlobal/stim/stimpacks/deploy"
+)
+
+// Options configures a Client
+type Options struct {
+
+	// ConfigFile is the path of the stim config file.  Defaults to
+	// config.yaml in the stim path
+	ConfigFile string
+
+	// Path is the stim home directory.  Defaults to ~/.stim
+	Path string
+
+	// Profile is the stim profile to use.  Defaults to the current profile of
+	// the config file
+	Profile string
+
+	// Settings are config values (ex. `vault-address`) that take precedence
+	// over the config file, the same as command line flags
+	Settings map[string]interface{}
+
+	// Interactive allows stim to prompt the user (ex. for Vault logins and
+	// confirmations).  Clients run as automated by default.
+	Interactive bool
+
+	// Clock is the clock used by stim.  Defaults to the system clock
+	Clock clock.Clock
+}
+
+// Client is a stim instance for use as a library
+type Client struct {
+	stim   *stim.Stim
+	deploy *deploy.Deploy
+}
+
+// DeployOptions selects what to deploy.  These are the same as the flags of
+// `stim deploy`.
+type DeployOptions struct {
+
+	// File is the deploy config file.  Defaults to stim.deploy.yaml
+	File string
+
+	// Services are the services of a monorepo workspace to deploy, or `all`,
+	// instead of File
+	Services []string
+
+	// WorkspaceRoot is the directory of the workspace.  Defaults to the
+	// current directory
+	WorkspaceRoot string
+
+	// Environment is the name of the environment to deploy to
+	Environment string
+
+	// Instance is the name of the instance to deploy to, or `all`
+	Instance string
+
+	// Method is `auto`, `docker`, `podman`, `containerd`, `kubernetes` or
+	// `shell`.  Defaults to `auto`
+	Method string
+
+	// Yes skips the confirmation prompts.  Approvals and freeze windows
+	// still apply
+	Yes bool
+
+	// OverrideFreeze is the reason to deploy during a freeze window
+	OverrideFreeze string
+}
+
+// New creates a Client and loads the stim config
+func New(options *Options) (*Client, error) {
+
+	if options == nil {
+		options = &Options{}
+	}
+
+	s := stim.New()
+	if options.Clock != nil {
+		s.SetClock(options.Clock)
+	}
+
+	overrides := map[string]string{
+		"config-file": options.ConfigFile,
+		"path":        options.Path,
+		"profile":     options.Profile,
+	}
+	for key, value := range overrides {
+		if value != "" {
+			s.ConfigOverride(key, value)
+		}
+	}
+	s.ConfigOverride("is-automated", !options.Interactive)
+	for key, value := range options.Settings {
+		s.ConfigOverride(key, value)
+	}
+
+	d := deploy.New()
+	s.AddStimpack(d)
+
+	err := s.Init()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{stim: s, deploy: d}, nil
+}
+
+// Stim returns the stim instance of the client for the helpers that the
+// client doesn't wrap.  Use its error-returning New* helpers (ex. NewVault,
+// NewSlack), as the helpers without the prefix (ex. Vault) exit the program
+// on errors for the commands.
+func (c *Client) Stim() *stim.Stim {
+	return c.stim
+}
+
+// Vault returns the Vault client, logging in if needed
+func (c *Client) Vault() (*vault.Vault, error) {
+	return c.stim.NewVault()
+}
+
+// Aws returns an AWS client for the given keys.  Empty keys use the default
+// AWS credentials.
+func (c *Client) Aws(accessKey string, secretKey string) (*aws.Aws, error) {
+	return c.stim.NewAws(accessKey, secretKey)
+}
+
+// KubeConfig writes a kubeconfig using the credentials stored in Vault
+func (c *Client) KubeConfig(options *stim.KubeConfigOptions) (*kubernetes.Config, error) {
+	return c.stim.KubeConfigFromVault(options)
+}
+
+// Deploy deploys to an instance (or all instances) of an environment.  The
+// environment and instance are required as clients don't prompt for them.
+func (c *Client) Deploy(options *DeployOptions) error {
+
+	if options == nil || options.Environment == "" || options.Instance == "" {
+		return stim.UsageError(errors.New("An environment and instance are required to deploy"))
+	}
+
+	method := options.Method
+	if method == "" {
+		method = "auto"
+	}
+
+	// The default file is set so that a workspace file doesn't prompt for
+	// the services
+	file := options.File
+	if file == "" && len(options.Services) == 0 {
+		file = "./stim.deploy.yaml"
+	}
+
+	// Every option is set so that nothing is left over from an earlier deploy
+	c.stim.ConfigOverride("deploy.file", file)
+	c.stim.ConfigOverride("deploy.service", options.Services)
+	c.stim.ConfigOverride("deploy.workspace-root", options.WorkspaceRoot)
+	c.stim.ConfigOverride("deploy.environment", options.Environment)
+	c.stim.ConfigOverride("deploy.instance", options.Instance)
+	c.stim.ConfigOverride("deploy.method", method)
+	c.stim.ConfigOverride("deploy.yes", options.Yes)
+	c.stim.ConfigOverride("deploy.override-freeze", options.OverrideFreeze)
+
+	// Log in first so that a failed login is returned instead of exiting
+	vault, err := c.Vault()
+	if err != nil {
+		return err
+	}
+	if c.stim.IsReadOnly() {
+		return stim.AuthError(errors.New("Deploys are not allowed in read-only mode"))
+	}
+
+	// Secrets are only cached for the length of a deploy, a client may be
+	// used for several deploys
+	vault.ClearReadCache()
+
+	return c.deploy.Run()
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/PremiereGlobal/stim/stim"
+	"gotest.tools/assert"
+)
+
+const testConfig = `
+vault-address: https://vault.company.com
+current-profile: company
+profiles:
+  company: {}
+  lab:
+    vault-address: https://vault.lab.local
+`
+
+func newTestClient(t *testing.T, options *Options) *Client {
+	dir, err := ioutil.TempDir("", "stim-client")
+	assert.NilError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	configFile := filepath.Join(dir, "config.yaml")
+	err = ioutil.WriteFile(configFile, []byte(testConfig), 0600)
+	assert.NilError(t, err)
+
+	options.ConfigFile = configFile
+	options.Path = dir
+	if options.Settings == nil {
+		options.Settings = map[string]interface{}{}
+	}
+	options.Settings["logging.file.disable"] = true
+
+	c, err := New(options)
+	assert.NilError(t, err)
+	return c
+}
+
+func TestNew(t *testing.T) {
+	c := newTestClient(t, &Options{})
+	assert.Equal(t, c.Stim().ConfigGetString("vault-address"), "https://vault.company.com")
+	assert.Assert(t, c.Stim().IsAutomated())
+
+	c = newTestClient(t, &Options{
+		Profile:  "lab",
+		Settings: map[string]interface{}{"vault.role": "deployer"},
+	})
+	assert.Equal(t, c.Stim().ConfigGetString("vault-address"), "https://vault.lab.local")
+	assert.Equal(t, c.Stim().ConfigGetString("vault.role"), "deployer")
+}
+
+func TestNewUnknownProfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stim-client")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = New(&Options{
+		ConfigFile: filepath.Join(dir, "config.yaml"),
+		Profile:    "missing",
+	})
+	assert.Equal(t, stim.ExitCode(err), stim.ExitCodeConfig)
+}
+
+func TestDeployRequiresInstance(t *testing.T) {
+	c := newTestClient(t, &Options{})
+	err := c.Deploy(&DeployOptions{Environment: "prod"})
+	assert.Equal(t, stim.ExitCode(err), stim.ExitCodeUsage)
+}
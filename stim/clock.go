@@ -0,0 +1,33 @@
+package stim
+
+import (
+	"math/rand"
+
+	"github.com/PremiereGlobal/stim/pkg/clock"
+)
+
+// Clock returns the clock of stim.  Stimpacks should use it instead of the
+// time package so tests can control the time with clock.NewFake.
+func (stim *Stim) Clock() clock.Clock {
+	return stim.clock
+}
+
+// SetClock replaces the clock of stim (ex. with a fake clock in tests).  It
+// must be set before Vault is used since the Vault token renewer uses it.
+func (stim *Stim) SetClock(c clock.Clock) {
+	stim.clock = c
+	stim.timer.SetClock(c)
+}
+
+// Rand returns the random source of stim.  Stimpacks should use it instead of
+// the math/rand functions so tests can use a seeded source.  It is not safe
+// for concurrent use and must not be used for secrets (use crypto/rand).
+func (stim *Stim) Rand() *rand.Rand {
+	return stim.rand
+}
+
+// SetRand replaces the random source of stim (ex. with a seeded source in
+// tests)
+func (stim *Stim) SetRand(r *rand.Rand) {
+	stim.rand = r
+}
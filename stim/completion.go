@@ -4,8 +4,28 @@ import (
 	"fmt"
 	"io"
 	"os"
+
+	"github.com/spf13/cobra"
 )
 
+// completionValuesCommand is the hidden command that the bash completion
+// script runs to get the dynamic values of a flag
+const completionValuesCommand = "__complete_values"
+
+// bashCompletionFunction completes a flag with the values printed by the
+// hidden completion command of the stim being completed
+const bashCompletionFunction = `
+__stim_complete_values()
+{
+    local values
+    values=$("${words[0]}" ` + completionValuesCommand + ` "$1" 2>/dev/null)
+    COMPREPLY=( $(compgen -W "${values}" -- "$cur") )
+}
+`
+
+// CompletionFunc returns the values that a flag can be completed with
+type CompletionFunc func() ([]string, error)
+
 func (stim *Stim) GetCompletion(shell string) error {
 	switch shell {
 	case `bash`:
@@ -19,3 +39,55 @@ func (stim *Stim) GetCompletion(shell string) error {
 
 	return nil
 }
+
+// BindFlagCompletion completes the values of a flag of the command in bash
+// with the values returned by fn.  name identifies the values (ex.
+// `deploy-environments`) and can be shared by the flags of several commands.
+// fn runs on every completion without prompting, so it should be quick and
+// its errors are not shown.
+func (stim *Stim) BindFlagCompletion(cmd *cobra.Command, flag string, name string, fn CompletionFunc) {
+
+	if stim.completions == nil {
+		stim.completions = make(map[string]CompletionFunc)
+	}
+	stim.completions[name] = fn
+
+	flags := cmd.Flags()
+	if cmd.PersistentFlags().Lookup(flag) != nil {
+		flags = cmd.PersistentFlags()
+	}
+	cobra.MarkFlagCustom(flags, flag, "__stim_complete_values "+name)
+}
+
+// completionCommand returns the hidden command that prints the completion
+// values of a flag, one per line
+func (stim *Stim) completionCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:    completionValuesCommand + " <name>",
+		Hidden: true,
+		Annotations: map[string]string{
+			AnnotationStderrLogs:    "true",
+			AnnotationInformational: "true",
+		},
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+
+			// Completions never prompt (ex. for a Vault login)
+			stim.ConfigOverride("is-automated", true)
+
+			fn, ok := stim.completions[args[0]]
+			if !ok {
+				return nil
+			}
+			values, err := fn()
+			if err != nil {
+				stim.log.Debug("Unable to complete {}: {}", args[0], err)
+				return nil
+			}
+			for _, value := range values {
+				fmt.Println(value)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,28 @@
+package stim
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"gotest.tools/assert"
+)
+
+func TestBindFlagCompletion(t *testing.T) {
+	stim := &Stim{}
+	cmd := &cobra.Command{Use: "deploy"}
+	cmd.PersistentFlags().StringP("environment", "e", "", "")
+	cmd.Flags().String("cluster", "", "")
+
+	values := func() ([]string, error) { return []string{"dev", "prod"}, nil }
+	stim.BindFlagCompletion(cmd, "environment", "deploy-environments", values)
+	stim.BindFlagCompletion(cmd, "cluster", "kube-clusters", values)
+
+	flag := cmd.PersistentFlags().Lookup("environment")
+	assert.DeepEqual(t, flag.Annotations[cobra.BashCompCustom], []string{"__stim_complete_values deploy-environments"})
+	flag = cmd.Flags().Lookup("cluster")
+	assert.DeepEqual(t, flag.Annotations[cobra.BashCompCustom], []string{"__stim_complete_values kube-clusters"})
+
+	result, err := stim.completions["deploy-environments"]()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, result, []string{"dev", "prod"})
+}
@@ -1,6 +1,7 @@
 package stim
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
@@ -8,7 +9,6 @@ import (
 	"strings"
 
 	"github.com/PremiereGlobal/stim/pkg/utils"
-	"github.com/imdario/mergo"
 	yaml "gopkg.in/yaml.v3"
 )
 
@@ -26,6 +26,16 @@ func (stim *Stim) ConfigGetString(configKey string) string {
 	return configValue
 }
 
+// ConfigGetInt takes a config key and returns the integer result
+func (stim *Stim) ConfigGetInt(configKey string) int {
+	return stim.config.GetInt(configKey)
+}
+
+// ConfigGetStringSlice takes a config key and returns the list of strings
+func (stim *Stim) ConfigGetStringSlice(configKey string) []string {
+	return stim.config.GetStringSlice(configKey)
+}
+
 // GetConfigBool takes a config key and returns the boolean result
 func (stim *Stim) ConfigGetBool(configKey string) bool {
 	configValue := stim.config.Get(configKey)
@@ -35,6 +45,12 @@ func (stim *Stim) ConfigGetBool(configKey string) bool {
 	return false
 }
 
+// ConfigUnmarshalKey decodes the value of a config key that is a map or list
+// of objects (ex. `aws.role-chains`) into out, using `mapstructure` tags
+func (stim *Stim) ConfigUnmarshalKey(configKey string, out interface{}) error {
+	return stim.config.UnmarshalKey(configKey, out)
+}
+
 func (stim *Stim) ConfigHasValue(configKey string) bool {
 	configValue := stim.config.Get(configKey)
 	if configValue != nil {
@@ -51,77 +67,107 @@ func (stim *Stim) ConfigSetBool(key string, value bool) error {
 	return stim.ConfigSetRaw(key, value)
 }
 
+// ConfigOverride sets the value of the key for this run only, taking
+// precedence over flags, environment variables and the config file.  The
+// config file is not changed.
+func (stim *Stim) ConfigOverride(key string, value interface{}) {
+	stim.config.Set(key, value)
+}
+
+// ConfigRemoveKey removes the (dot separated) key from the stim config file
 func (stim *Stim) ConfigRemoveKey(key string) error {
-	keys := []string{key}
-	if strings.Contains(key, ".") {
-		keys = strings.Split(key, ".")
-	}
 	config, err := stim.getConfigData()
 	if err != nil {
 		return err
 	}
-	cm, ok := config[keys[0]].(map[interface{}]interface{})
-	if !ok {
-		// Key doesn't exist, not thing to remove
+
+	if !removeConfigKey(config, strings.Split(key, ".")) {
+		// Key doesn't exist, nothing to remove
 		return nil
 	}
 
-	//This is super gross, not sure of a better way to deal with this
-Main:
-	for i, v := range keys[1:] {
-		for cmk, cmv := range cm {
-			if sv, ok := cmk.(string); ok {
-				if i == len(keys)-2 {
-					delete(cm, v)
-					break Main
-				} else {
-					if sv == v {
-						cm = cmv.(map[interface{}]interface{})
-						break
-					} else {
-						return nil //We return nil because the entrie does not exit currently anyway
-					}
-				}
-			}
-		}
-	}
 	return stim.writeConfigData(config)
 }
 
+// ConfigSetRaw sets the (dot separated) key in the stim config file,
+// replacing any existing value
 func (stim *Stim) ConfigSetRaw(key string, value interface{}) error {
-	config := make(map[string]interface{})
-	sc := make(map[string]interface{})
-	if strings.Contains(key, ".") {
-		keys := strings.Split(key, ".")
-		bm := make(map[string]interface{})
-		cm := bm
-		for i, v := range keys {
-			if i < len(keys)-1 {
-				lm := make(map[string]interface{})
-				cm[v] = lm
-				cm = lm
-			} else {
-				cm[v] = value
-			}
-		}
-		sc = bm
-	} else {
-		sc[key] = value
-	}
 	config, err := stim.getConfigData()
 	if err != nil {
 		return err
 	}
 
-	err = mergo.Merge(&config, sc)
+	err = setConfigKey(config, strings.Split(key, "."), value)
 	if err != nil {
-		stim.log.Debug("Problem merging config:{}", err)
+		stim.log.Debug("Problem setting config key {}: {}", key, err)
 		return err
 	}
 
 	return stim.writeConfigData(config)
 }
 
+// ConfigGetFileValues returns the values set in the stim config file keyed by
+// their dot separated names.  Lists are returned as a single value.
+func (stim *Stim) ConfigGetFileValues() (map[string]interface{}, error) {
+	config, err := stim.getConfigData()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]interface{})
+	flattenConfig(config, "", values)
+	return values, nil
+}
+
+// setConfigKey sets the value in the nested config maps, creating maps as
+// needed
+func setConfigKey(config map[string]interface{}, keys []string, value interface{}) error {
+	for i, key := range keys[:len(keys)-1] {
+		child, ok := config[key]
+		if !ok || child == nil {
+			child = make(map[string]interface{})
+			config[key] = child
+		}
+		childMap, ok := child.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("'%s' is not a map", strings.Join(keys[:i+1], "."))
+		}
+		config = childMap
+	}
+	config[keys[len(keys)-1]] = value
+	return nil
+}
+
+// removeConfigKey removes the key from the nested config maps, along with any
+// maps left empty.  Returns false if the key was not found.
+func removeConfigKey(config map[string]interface{}, keys []string) bool {
+	if len(keys) == 1 {
+		_, ok := config[keys[0]]
+		delete(config, keys[0])
+		return ok
+	}
+
+	child, ok := config[keys[0]].(map[string]interface{})
+	if !ok || !removeConfigKey(child, keys[1:]) {
+		return false
+	}
+	if len(child) == 0 {
+		delete(config, keys[0])
+	}
+	return true
+}
+
+// flattenConfig adds the leaf values of the nested config maps to values
+func flattenConfig(config map[string]interface{}, prefix string, values map[string]interface{}) {
+	for key, value := range config {
+		if child, ok := value.(map[string]interface{}); ok && len(child) > 0 {
+			flattenConfig(child, prefix+key+".", values)
+		} else {
+			values[prefix+key] = value
+		}
+	}
+}
+
 func (stim *Stim) writeConfigData(config map[string]interface{}) error {
 	var err error
 	stimConfigFile := stim.config.ConfigFileUsed()
@@ -192,18 +238,18 @@ func (stim *Stim) ConfigGetStimConfigFile() (string, error) {
 	return cfp, nil
 }
 
-func (stim *Stim) configLoadConfigFile() {
+func (stim *Stim) configLoadConfigFile() error {
 
 	stim.config.SetConfigType("yaml")
 	configFile, err := stim.ConfigGetStimConfigFile()
 	if err != nil {
-		stim.log.Fatal("Problem accessing config file: {}", err)
+		return fmt.Errorf("Problem accessing config file: %v", err)
 	}
 
 	stim.config.SetConfigFile(configFile)
 	err = stim.config.ReadInConfig()
 	if err != nil {
-		stim.log.Fatal("Problem loading config file: {}", err)
+		return fmt.Errorf("Problem loading config file: %v", err)
 	}
 
 	// If the config file has a config-file entry remove it to avoid any sort
@@ -212,10 +258,15 @@ func (stim *Stim) configLoadConfigFile() {
 	// removes it from the config file and not from the current stim.config
 	// stim.ConfigRemoveKey("config-file") // old way
 	// stim.ConfigRemoveKey("config.file") // new way
+
+	return nil
 }
 
 // ConfigGetStimCacheDir returns the stim cache directory
-// subdir paramter optionally provides a subdirectory within the cache
+// subdir paramter optionally provides a subdirectory within the cache.
+// If the directory can't be created a warning is logged and the path is
+// still returned, so that reading or writing the files in it fails with an
+// error.
 func (stim *Stim) ConfigGetCacheDir(subDir string) string {
 
 	cachePath := stim.ConfigGetString("cache-path")
@@ -223,7 +274,7 @@ func (stim *Stim) ConfigGetCacheDir(subDir string) string {
 
 	err := utils.CreateDirIfNotExist(cacheSubPath, utils.UserGroupMode)
 	if err != nil {
-		stim.log.Fatal("Error creating cache directory at {}", cacheSubPath)
+		stim.log.Warn("Error creating cache directory at {}: {}", cacheSubPath, err)
 	}
 
 	return cacheSubPath
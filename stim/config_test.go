@@ -0,0 +1,42 @@
+package stim
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestSetConfigKey(t *testing.T) {
+	config := map[string]interface{}{
+		"vault":   map[string]interface{}{"address": "https://old"},
+		"verbose": true,
+	}
+
+	assert.NilError(t, setConfigKey(config, []string{"vault", "address"}, "https://new"))
+	assert.NilError(t, setConfigKey(config, []string{"aws", "sso", "region"}, "us-west-2"))
+	assert.Error(t, setConfigKey(config, []string{"verbose", "level"}, "debug"), "'verbose' is not a map")
+
+	values := make(map[string]interface{})
+	flattenConfig(config, "", values)
+	assert.DeepEqual(t, values, map[string]interface{}{
+		"vault.address":  "https://new",
+		"aws.sso.region": "us-west-2",
+		"verbose":        true,
+	})
+}
+
+func TestRemoveConfigKey(t *testing.T) {
+	config := map[string]interface{}{
+		"vault": map[string]interface{}{"address": "https://vault"},
+		"aws":   map[string]interface{}{"ttl": "1h", "use-profiles": true},
+	}
+
+	assert.Assert(t, removeConfigKey(config, []string{"vault", "address"}))
+	assert.Assert(t, removeConfigKey(config, []string{"aws", "ttl"}))
+	assert.Assert(t, !removeConfigKey(config, []string{"aws", "web-ttl"}))
+	assert.Assert(t, !removeConfigKey(config, []string{"slack", "channel"}))
+
+	assert.DeepEqual(t, config, map[string]interface{}{
+		"aws": map[string]interface{}{"use-profiles": true},
+	})
+}
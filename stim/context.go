@@ -0,0 +1,175 @@
+package stim
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/kubernetes"
+)
+
+// cancellation is the cancellation of the command by Ctrl-C or --timeout
+type cancellation struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	reason string
+
+	// traps receive the interrupts instead of cancelling the command, the
+	// last one first
+	traps []chan os.Signal
+}
+
+// newCancellation returns the cancellation of a command
+func newCancellation() *cancellation {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &cancellation{ctx: ctx, cancel: cancel}
+}
+
+// Context returns the context of the command, which is done once the command
+// is cancelled by Ctrl-C (or SIGTERM) or times out (`--timeout`).  Calls to
+// Vault, AWS and Kubernetes that are in flight are cancelled with it.
+// Commands should stop and clean up (ex. remove containers and temp files)
+// once it's done.
+func (stim *Stim) Context() context.Context {
+	return stim.cancellation.ctx
+}
+
+// Cancel cancels the command, with the reason shown in its error
+func (stim *Stim) Cancel(reason string) {
+	c := stim.cancellation
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.reason == "" {
+		c.reason = reason
+		stim.log.Warn("{}, cancelling the command.  Press Ctrl-C again to exit without cleaning up", reason)
+	}
+	c.cancel()
+}
+
+// TrapInterrupts delivers Ctrl-C to the returned channel instead of
+// cancelling the command, for commands where Ctrl-C is part of their normal
+// flow (ex. stopping a command they run).  stop must be called once done.
+func (stim *Stim) TrapInterrupts() (<-chan os.Signal, func()) {
+	c := stim.cancellation
+	trap := make(chan os.Signal, 1)
+	c.mu.Lock()
+	c.traps = append(c.traps, trap)
+	c.mu.Unlock()
+
+	stop := func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for i, t := range c.traps {
+			if t == trap {
+				c.traps = append(c.traps[:i], c.traps[i+1:]...)
+				break
+			}
+		}
+	}
+	return trap, stop
+}
+
+// cancelledError returns the error of a command that failed after it was
+// cancelled, with ExitCodeCancelled.  Other errors are returned as they are.
+func (stim *Stim) cancelledError(err error) error {
+	c := stim.cancellation
+	if err == nil || c.ctx.Err() == nil {
+		return err
+	}
+	c.mu.Lock()
+	reason := c.reason
+	c.mu.Unlock()
+	return NewExitError(ExitCodeCancelled, fmt.Errorf("%s: %v", reason, err))
+}
+
+// handleInterrupts cancels the command on Ctrl-C or SIGTERM (unless the
+// command traps interrupts), and exits right away on a second one
+func (stim *Stim) handleInterrupts() {
+
+	// Kubernetes clients are created in many places, so they all use the
+	// context of the command
+	kubernetes.SetCancelContext(stim.Context())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		for sig := range signals {
+			c := stim.cancellation
+			c.mu.Lock()
+			var trap chan os.Signal
+			if sig == os.Interrupt && len(c.traps) > 0 {
+				trap = c.traps[len(c.traps)-1]
+			}
+			c.mu.Unlock()
+
+			if trap != nil {
+				select {
+				case trap <- sig:
+				default:
+				}
+				continue
+			}
+			if stim.Context().Err() != nil {
+				stim.log.Exit(ExitCodeCancelled, "Exiting without cleaning up")
+			}
+			if sig == os.Interrupt {
+				stim.Cancel("Interrupted")
+			} else {
+				stim.Cancel("Terminated")
+			}
+		}
+	}()
+}
+
+// startTimeout cancels the command once the `--timeout` (`timeout`) passes
+func (stim *Stim) startTimeout() error {
+
+	value := stim.ConfigGetString("timeout")
+	if value == "" {
+		return nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return UsageError(fmt.Errorf("Invalid --timeout '%s': %v", value, err))
+	}
+	if timeout <= 0 {
+		return nil
+	}
+
+	go func() {
+		select {
+		case <-stim.clock.After(timeout):
+			stim.Cancel(fmt.Sprintf("Timed out after %s", timeout))
+		case <-stim.Context().Done():
+		}
+	}()
+	return nil
+}
+
+// Sleep waits for the duration on the stim clock.  It returns the error of
+// the context if the command is cancelled before then.
+func (stim *Stim) Sleep(d time.Duration) error {
+	ctx := stim.Context()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	// The fake clock of tests returns right away
+	slept := make(chan struct{})
+	go func() {
+		stim.clock.Sleep(d)
+		close(slept)
+	}()
+	select {
+	case <-slept:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
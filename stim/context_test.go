@@ -0,0 +1,50 @@
+package stim
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/clock"
+	"gotest.tools/assert"
+)
+
+func TestTimeout(t *testing.T) {
+	stim := New()
+	fake := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	stim.SetClock(fake)
+
+	stim.config.Set("timeout", "soon")
+	assert.Equal(t, ExitCode(stim.startTimeout()), ExitCodeUsage)
+
+	stim.config.Set("timeout", "15m")
+	assert.NilError(t, stim.startTimeout())
+	for fake.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	assert.NilError(t, stim.Context().Err())
+	assert.NilError(t, stim.cancelledError(nil))
+
+	fake.Advance(15 * time.Minute)
+	<-stim.Context().Done()
+
+	err := stim.cancelledError(errors.New("context canceled"))
+	assert.Equal(t, ExitCode(err), ExitCodeCancelled)
+	assert.Equal(t, err.Error(), "Timed out after 15m0s: context canceled")
+}
+
+func TestSleep(t *testing.T) {
+	stim := New()
+	fake := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	stim.SetClock(fake)
+
+	assert.NilError(t, stim.Sleep(time.Minute))
+	assert.Equal(t, fake.Now(), time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC))
+
+	// Errors before the cancellation keep their exit code
+	assert.Equal(t, ExitCode(stim.cancelledError(ConfigError(errors.New("bad config")))), ExitCodeConfig)
+
+	stim.Cancel("Interrupted")
+	assert.Assert(t, stim.Sleep(time.Minute) != nil)
+	assert.Equal(t, fake.Now(), time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC))
+}
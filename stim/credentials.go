@@ -0,0 +1,39 @@
+package stim
+
+import (
+	"path/filepath"
+
+	"github.com/PremiereGlobal/stim/pkg/credentials"
+)
+
+// The files of the encrypted credential store, in the stim path
+const (
+	credentialsFile    = "credentials.enc"
+	credentialsKeyFile = "credentials.key"
+)
+
+// Credentials returns the store that cached credentials (the Vault token and
+// the AWS SSO and Azure tokens) are kept in, from `credentials.store`.  It is
+// nil for the default `plaintext` store, which keeps the plaintext cache
+// files.
+func (stim *Stim) Credentials() (credentials.Store, error) {
+
+	if stim.credentials == nil {
+		store, err := credentials.New(&credentials.Config{
+			Store:      stim.ConfigGetString("credentials.store"),
+			FilePath:   filepath.Join(stim.ConfigGetString("path"), credentialsFile),
+			KeyPath:    filepath.Join(stim.ConfigGetString("path"), credentialsKeyFile),
+			Passphrase: stim.ConfigGetString("credentials.passphrase"),
+		})
+		if err != nil {
+			return nil, ConfigError(err)
+		}
+		if store == nil {
+			return nil, nil
+		}
+		stim.log.Debug("Stim-Credentials: Using credential store {}", store.Name())
+		stim.credentials = store
+	}
+
+	return stim.credentials, nil
+}
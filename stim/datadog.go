@@ -0,0 +1,72 @@
+package stim
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/PremiereGlobal/stim/pkg/datadog"
+)
+
+// Datadog returns a Datadog client that is already authenticated
+func (stim *Stim) Datadog() *datadog.Datadog {
+	d, err := stim.NewDatadog()
+	if err != nil {
+		stim.Fatal(err)
+	}
+	return d
+}
+
+// NewDatadog is the same as Datadog but returns an error instead of exiting
+// if the API key can't be read
+func (stim *Stim) NewDatadog() (*datadog.Datadog, error) {
+	stim.log.Debug("Stim-Datadog: Creating")
+
+	apiKey, appKey, err := stim.DatadogKeys(stim.ConfigGetString("datadog.vault-path"))
+	if err != nil {
+		return nil, AuthError(err)
+	}
+
+	return datadog.New(&datadog.Config{APIKey: apiKey, AppKey: appKey, Site: stim.ConfigGetString("datadog.site")}), nil
+}
+
+// DatadogKeys returns the Datadog API and application keys, which are read
+// from the Vault secret at vaultPath (`datadog.vault-apikey-key` and
+// `datadog.vault-appkey-key`), or from DD_API_KEY and DD_APP_KEY if vaultPath
+// is empty.  The application key is optional.
+func (stim *Stim) DatadogKeys(vaultPath string) (string, string, error) {
+
+	if vaultPath == "" {
+		apiKey := os.Getenv("DD_API_KEY")
+		if apiKey == "" {
+			return "", "", errors.New("Stim-Datadog: No Datadog API key, set `datadog.vault-path` in the stim config or DD_API_KEY")
+		}
+		return apiKey, os.Getenv("DD_APP_KEY"), nil
+	}
+
+	apiKeyKey := stim.ConfigGetString("datadog.vault-apikey-key")
+	if apiKeyKey == "" {
+		apiKeyKey = "api-key"
+	}
+	appKeyKey := stim.ConfigGetString("datadog.vault-appkey-key")
+	if appKeyKey == "" {
+		appKeyKey = "app-key"
+	}
+
+	stim.log.Debug("Stim-Datadog: Fetching Datadog keys from Vault `{}`", vaultPath)
+	vault, err := stim.NewVault()
+	if err != nil {
+		return "", "", err
+	}
+	apiKey, err := vault.GetSecretKey(vaultPath, apiKeyKey)
+	if err != nil {
+		return "", "", fmt.Errorf("Stim-Datadog: error getting API key from Vault: %v", err)
+	}
+	appKey, err := vault.GetSecretKey(vaultPath, appKeyKey)
+	if err != nil {
+		stim.log.Debug("Stim-Datadog: No application key in Vault `{}`: {}", vaultPath, err)
+		appKey = ""
+	}
+
+	return apiKey, appKey, nil
+}
@@ -3,14 +3,19 @@ package stim
 import (
 	"fmt"
 	"path/filepath"
-	"runtime"
+	"strings"
 
 	"github.com/PremiereGlobal/stim/pkg/downloader"
 	"github.com/PremiereGlobal/stim/pkg/env"
 	"github.com/PremiereGlobal/stim/pkg/kubernetes"
+	"github.com/PremiereGlobal/stim/pkg/utils"
 	"github.com/PremiereGlobal/vault-to-envs/pkg/vaulttoenvs"
 )
 
+// defaultSecretConcurrency is the number of Vault secrets fetched at once
+// when `vault.secret-concurrency` isn't set
+const defaultSecretConcurrency = 8
+
 // EnvConfig represets a environment configuration
 type EnvConfig struct {
 
@@ -27,6 +32,9 @@ type EnvConfig struct {
 	// WorkDir sets the working directory where any shell commands will be executed
 	WorkDir string
 
+	// Shell that shell commands are executed with.  Defaults to a POSIX shell
+	Shell []string
+
 	// Tools should contains a list of supported binary tools to install and link
 	Tools map[string]EnvTool
 }
@@ -57,55 +65,59 @@ type EnvTool struct {
 	Unset   bool   `yaml:"unset"`
 }
 
+// toolDownloaders are the downloaders of the supported CLI tools
+var toolDownloaders = map[string]func(version string, downloadPath string) downloader.Downloader{
+	"helm":      downloader.NewHelmDownloader,
+	"kubectl":   downloader.NewKubeDownloader,
+	"terraform": downloader.NewTerraformDownloader,
+	"vault":     downloader.NewVaultDownloader,
+}
+
 // Env sets up an environment based on the given config
-// Shell commands can be executed against the environment
-func (stim *Stim) Env(config *EnvConfig) *env.Env {
+// Shell commands can be executed against the environment.  The environment
+// should be closed when done to remove its files.
+func (stim *Stim) Env(config *EnvConfig) (*env.Env, error) {
 
 	e, err := env.New(env.Config{})
 	if err != nil {
-		stim.log.Fatal("Stim: Error creating new environment.", err)
+		return nil, fmt.Errorf("Stim: Error creating new environment: %v", err)
+	}
+
+	err = stim.setupEnv(e, config)
+	if err != nil {
+		e.Close()
+		return nil, err
 	}
 
+	return e, nil
+}
+
+// setupEnv adds the variables, kubeconfig, secrets and tools to the
+// environment
+func (stim *Stim) setupEnv(e *env.Env, config *EnvConfig) error {
+
 	e.SetWorkDir(config.WorkDir)
+	e.SetShell(config.Shell)
 	e.AddEnvVars(config.EnvVars...)
 
 	// If requiring Kubernetes, set things up
 	var kc *kubernetes.Config
+	var err error
 	if config.Kubernetes != nil {
 
 		// This is the path where the kubeconfig will be written
 		kubeConfigFilePath := filepath.Join(e.GetPath(), "kubeconfig")
 
-		vault := stim.Vault()
-
-		// Get the Kubernetes creds from Vault
-		secretValues, err := vault.GetSecretKeys("secret/kubernetes/" + config.Kubernetes.Cluster + "/" + config.Kubernetes.ServiceAccount + "/kube-config")
-		if err != nil {
-			stim.log.Fatal("Stim: Error getting kubeconfig secrets for environment. {}", err)
-		}
-
-		// If namespace not set use the default from Vault
-		defaultNamespace := config.Kubernetes.DefaultNamespace
-		if defaultNamespace == "" {
-			defaultNamespace = secretValues["default-namespace"]
-		}
-
-		// Build the Kube config options
-		kubeConfigOptions := &kubernetes.ConfigOptions{
-			ClusterName:             config.Kubernetes.Cluster,
-			ClusterServer:           secretValues["cluster-server"],
-			ClusterCA:               secretValues["cluster-ca"],
-			AuthName:                config.Kubernetes.Cluster + "-" + config.Kubernetes.ServiceAccount,
-			AuthToken:               secretValues["user-token"],
-			ContextName:             config.Kubernetes.Cluster,
-			ContextSetCurrent:       true,
-			ContextDefaultNamespace: defaultNamespace,
-		}
-
-		kc = kubernetes.NewConfigFromPath(kubeConfigFilePath)
-		err = kc.Modify(kubeConfigOptions)
+		stopTimer := stim.Time(PhaseKubeConfig)
+		kc, err = stim.KubeConfigFromVault(&KubeConfigOptions{
+			Cluster:          config.Kubernetes.Cluster,
+			ServiceAccount:   config.Kubernetes.ServiceAccount,
+			DefaultNamespace: config.Kubernetes.DefaultNamespace,
+			Path:             kubeConfigFilePath,
+		})
+		stopTimer()
 		if err != nil {
-			stim.log.Fatal("Stim: Error writing kubeconfig for environment. {}", err)
+			return fmt.Errorf("Stim: Error writing kubeconfig for environment: %v", err)
 		}
 
 		// Tell the environment to use the kubeconfig in the environment PATH
@@ -115,87 +127,115 @@ func (stim *Stim) Env(config *EnvConfig) *env.Env {
 	// If requiring secrets, set those up
 	if config.Vault != nil && len(config.Vault.SecretItems) > 0 {
 
-		vault := stim.Vault()
-
-		vaultAddress, err := vault.GetAddress()
+		secretEnvs, err := stim.VaultSecretEnvs(config.Vault.SecretItems)
 		if err != nil {
-			stim.log.Fatal("Stim: Unable to get Vault address for environment. {}", err)
-		}
-
-		vaultToken, err := vault.GetToken()
-		if err != nil {
-			stim.log.Fatal("Stim: Unable to get Vault token for environment. {}", err)
-		}
-
-		v2e := vaulttoenvs.NewVaultToEnvs(&vaulttoenvs.Config{
-			VaultAddr: vaultAddress,
-		})
-		v2e.SetVaultToken(vaultToken)
-		v2e.AddSecretItems(config.Vault.SecretItems...)
-
-		secretEnvs, err := v2e.GetEnvs()
-		if err != nil {
-			stim.log.Fatal("Stim: Unable to get Vault secrets for environment. {}", err)
+			return err
 		}
 
 		e.AddEnvVars(secretEnvs...)
 	}
 
 	// if requiring any CLI tools, download and link them here
+	downloaders := make(map[string]downloader.Downloader)
 	for toolName, toolParams := range config.Tools {
 
+		newDownloader, ok := toolDownloaders[toolName]
+		if !ok {
+			return ConfigError(fmt.Errorf("Unknown deploy tool: %s", toolName))
+		}
+
 		version := toolParams.Version
 		if version == "" {
 			stim.log.Debug("Detecting tool version for: {}", toolName)
+			version, err = stim.DetectToolVersion(toolName, kc)
+			if err != nil {
+				return err
+			}
 		} else {
 			stim.log.Debug("Setting tool version {}/{} based on configuration", toolName, version)
 		}
 
-		var dl downloader.Downloader
-		cacheDir := stim.ConfigGetCacheDir(filepath.Join("bin", runtime.GOOS))
-		switch toolName {
-		case "vault":
-			if version == "" {
-				version, err = stim.Vault().Version()
-				if err != nil {
-					stim.log.Fatal("Unable to determine version for {}: {}", toolName, err)
-				}
-			}
-			dl = downloader.NewVaultDownloader(version, cacheDir)
-		case "kubectl":
-			if version == "" {
-				if kc == nil {
-					stim.log.Fatal("Kubernetes server not specified, cannot determine version")
-				}
-				k, err := kubernetes.New(kc)
-				if err != nil {
-					stim.log.Fatal("Unable to load Kube config, cannot determine version")
-				}
-				version, err = k.Version()
-				if err != nil {
-					stim.log.Fatal("Unable to determine version for {}: {}", toolName, err)
-				}
-			}
-			dl = downloader.NewKubeDownloader(version, cacheDir)
-		case "helm":
-			if version == "" {
-				stim.log.Fatal("Version detection not supported for helm, please specify a version in the config")
-			}
-			dl = downloader.NewHelmDownloader(version, cacheDir)
-		default:
-			stim.log.Fatal("Unknown deploy tool: {}", toolName)
-		}
+		dl := newDownloader(version, stim.ConfigGetCacheDir("tools"))
+		downloaders[toolName] = dl
+	}
 
-		result, err := dl.Download()
-		if err != nil {
-			stim.log.Fatal("Download failed: {} {}", result, err)
-		}
-		if !result.FileExists {
-			stim.log.Debug("Downloaded {} in {}", result.RenderedURL, result.DownloadDuration)
-		}
+	err = stim.DownloadTools(downloaders)
+	if err != nil {
+		return err
+	}
+	for toolName, dl := range downloaders {
 		stim.log.Debug("Linking binary from {} to PATH location {}/{}", dl.GetBinPath(), e.GetPath(), toolName)
 		e.Link(dl.GetBinPath(), toolName)
 	}
 
-	return e
+	return nil
+}
+
+// VaultSecretEnvs resolves the secret items into environment variables
+// (`NAME=value`) with the Vault token of the current login
+func (stim *Stim) VaultSecretEnvs(items []*vaulttoenvs.SecretItem) ([]string, error) {
+
+	vault, err := stim.NewVault()
+	if err != nil {
+		return nil, err
+	}
+
+	vaultAddress, err := vault.GetAddress()
+	if err != nil {
+		return nil, fmt.Errorf("Stim: Unable to get Vault address for environment: %v", err)
+	}
+
+	vaultToken, err := vault.GetToken()
+	if err != nil {
+		return nil, AuthError(fmt.Errorf("Stim: Unable to get Vault token for environment: %v", err))
+	}
+
+	return stim.vaultSecretEnvs(vaultAddress, vaultToken, items)
+}
+
+// vaultSecretEnvs resolves the secret items into environment variables,
+// fetching up to SecretConcurrency secrets at once.  Every failed secret is
+// reported in the returned error.
+func (stim *Stim) vaultSecretEnvs(vaultAddress string, vaultToken string, items []*vaulttoenvs.SecretItem) ([]string, error) {
+
+	defer stim.Time(PhaseSecretFetch)()
+
+	itemEnvs := make([][]string, len(items))
+	errs := utils.ForEachConcurrent(len(items), stim.SecretConcurrency(), func(i int) error {
+		v2e := vaulttoenvs.NewVaultToEnvs(&vaulttoenvs.Config{
+			VaultAddr: vaultAddress,
+		})
+		v2e.SetVaultToken(vaultToken)
+		v2e.AddSecretItems(items[i])
+
+		var err error
+		itemEnvs[i], err = v2e.GetEnvs()
+		return err
+	})
+
+	var messages []string
+	for i, err := range errs {
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %v", items[i].SecretPath, err))
+		}
+	}
+	if len(messages) > 0 {
+		return nil, fmt.Errorf("Stim: Unable to get %d Vault secret(s) for environment:\n  %s", len(messages), strings.Join(messages, "\n  "))
+	}
+
+	var envs []string
+	for _, e := range itemEnvs {
+		envs = append(envs, e...)
+	}
+
+	return envs, nil
+}
+
+// SecretConcurrency returns the number of Vault secrets to fetch at once,
+// from `vault.secret-concurrency`
+func (stim *Stim) SecretConcurrency() int {
+	if concurrency := stim.ConfigGetInt("vault.secret-concurrency"); concurrency > 0 {
+		return concurrency
+	}
+	return defaultSecretConcurrency
 }
@@ -1,5 +1,118 @@
 package stim
 
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+)
+
+// The exit codes of stim.  Scripts can use them to tell why a command failed.
+const (
+	// ExitCodeError is a general failure
+	ExitCodeError = 1
+	// ExitCodeUsage is an invalid command, flag or argument
+	ExitCodeUsage = 2
+	// ExitCodeConfig is a missing or invalid configuration
+	ExitCodeConfig = 3
+	// ExitCodeAuth is a failed login or missing permissions
+	ExitCodeAuth = 4
+	// ExitCodeDeploy is a failed deployment
+	ExitCodeDeploy = 5
+	// ExitCodeAborted is a command cancelled by the user
+	ExitCodeAborted = 6
+	// ExitCodeCancelled is a command interrupted (ex. Ctrl-C) or timed out
+	ExitCodeCancelled = 7
+)
+
+// ExitError is an error that exits stim with a specific exit code
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// NewExitError wraps the error with the exit code.  A nil error stays nil.
+func NewExitError(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ExitError{Code: code, Err: err}
+}
+
+// UsageError wraps the error of an invalid command, flag or argument
+func UsageError(err error) error {
+	return NewExitError(ExitCodeUsage, err)
+}
+
+// ConfigError wraps the error of a missing or invalid configuration
+func ConfigError(err error) error {
+	return NewExitError(ExitCodeConfig, err)
+}
+
+// AuthError wraps the error of a failed login or missing permissions
+func AuthError(err error) error {
+	return NewExitError(ExitCodeAuth, err)
+}
+
+// DeployError wraps the error of a failed deployment
+func DeployError(err error) error {
+	return NewExitError(ExitCodeDeploy, err)
+}
+
+// Aborted returns the error of a command cancelled by the user
+func Aborted(message string) error {
+	return &ExitError{Code: ExitCodeAborted, Err: errors.New(message)}
+}
+
+// ExitCode returns the exit code for the error.  Errors without an exit code
+// are general failures.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return ExitCodeError
+}
+
+// initUsageErrors makes stim log the errors of the commands itself and exit
+// with ExitCodeUsage for invalid flags and arguments
+func (stim *Stim) initUsageErrors() {
+
+	stim.rootCmd.SilenceErrors = true
+	stim.rootCmd.SilenceUsage = true
+	stim.rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return UsageError(err)
+	})
+	if stim.rootCmd.Args == nil {
+		stim.rootCmd.Args = cobra.NoArgs
+	}
+
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		if cmd.Args != nil {
+			args := cmd.Args
+			cmd.Args = func(cmd *cobra.Command, a []string) error {
+				return UsageError(args(cmd, a))
+			}
+		}
+		for _, sub := range cmd.Commands() {
+			walk(sub)
+		}
+	}
+	walk(stim.rootCmd)
+}
+
 func (stim *Stim) Debug(message string) {
 	if message != "" {
 		stim.log.Debug(message)
@@ -12,8 +125,10 @@ func (stim *Stim) DebugError(err error) {
 	}
 }
 
+// Fatal logs the error and exits with its exit code.  Commands should return
+// their errors instead so that deferred cleanup runs.
 func (stim *Stim) Fatal(err error) {
 	if err != nil {
-		stim.log.Fatal(err)
+		stim.log.Exit(ExitCode(err), err)
 	}
 }
@@ -0,0 +1,42 @@
+package stim
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, ExitCode(nil), 0)
+	assert.Equal(t, ExitCode(errors.New("failed")), ExitCodeError)
+	assert.Equal(t, ExitCode(ConfigError(errors.New("bad config"))), ExitCodeConfig)
+	assert.Equal(t, ExitCode(Aborted("Cancelled")), ExitCodeAborted)
+
+	// The exit code is kept when the error is wrapped
+	wrapped := fmt.Errorf("deploy: %w", DeployError(errors.New("failed")))
+	assert.Equal(t, ExitCode(wrapped), ExitCodeDeploy)
+	assert.Equal(t, wrapped.Error(), "deploy: failed")
+
+	assert.NilError(t, AuthError(nil))
+}
+
+func TestNewClientErrors(t *testing.T) {
+	s := New()
+	s.ConfigOverride("github.vault-token-path", "")
+	s.ConfigOverride("datadog.vault-path", "")
+	for _, name := range []string{"GITHUB_TOKEN", "DD_API_KEY"} {
+		if value, ok := os.LookupEnv(name); ok {
+			os.Unsetenv(name)
+			defer os.Setenv(name, value)
+		}
+	}
+
+	// Missing credentials are returned as auth errors instead of exiting
+	_, err := s.NewGithub()
+	assert.Equal(t, ExitCode(err), ExitCodeAuth)
+	_, err = s.NewDatadog()
+	assert.Equal(t, ExitCode(err), ExitCodeAuth)
+}
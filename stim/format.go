@@ -0,0 +1,24 @@
+package stim
+
+import (
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/utils"
+)
+
+// FormatTime formats a timestamp for human readable output in the local time
+// zone, or in UTC when `--utc` (or `utc` in the config) is set.  JSON output
+// should use the time.Time value instead.
+func (stim *Stim) FormatTime(t time.Time) string {
+	return utils.FormatTime(t, stim.ConfigGetBool("utc"))
+}
+
+// FormatDuration formats a duration (TTL, age, etc.) for human readable output
+func (stim *Stim) FormatDuration(d time.Duration) string {
+	return utils.HumanizeDuration(d)
+}
+
+// FormatRelative formats a timestamp relative to now, ex. `3d 4h ago`
+func (stim *Stim) FormatRelative(t time.Time) string {
+	return utils.HumanizeRelative(t, stim.clock.Now())
+}
@@ -0,0 +1,60 @@
+package stim
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/PremiereGlobal/stim/pkg/github"
+)
+
+// Github returns a GitHub client that is already authenticated
+func (stim *Stim) Github() *github.Github {
+	g, err := stim.NewGithub()
+	if err != nil {
+		stim.Fatal(err)
+	}
+	return g
+}
+
+// NewGithub is the same as Github but returns an error instead of exiting if
+// the token can't be read
+func (stim *Stim) NewGithub() (*github.Github, error) {
+	stim.log.Debug("Stim-Github: Creating")
+
+	token, err := stim.GithubToken()
+	if err != nil {
+		return nil, AuthError(err)
+	}
+
+	return github.New(&github.Config{Token: token, APIURL: stim.ConfigGetString("github.url")}), nil
+}
+
+// GithubToken returns the GitHub token, which is read from Vault at
+// `github.vault-token-path`, or from GITHUB_TOKEN if that is not set
+func (stim *Stim) GithubToken() (string, error) {
+
+	vaultPath := stim.ConfigGetString("github.vault-token-path")
+	if vaultPath == "" {
+		token := os.Getenv("GITHUB_TOKEN")
+		if token == "" {
+			return "", errors.New("Stim-Github: No GitHub token, set `github.vault-token-path` in the stim config or GITHUB_TOKEN")
+		}
+		return token, nil
+	}
+
+	vaultKey := stim.ConfigGetString("github.vault-token-key")
+	if vaultKey == "" {
+		vaultKey = "token"
+	}
+	stim.log.Debug("Stim-Github: Fetching GitHub token from Vault `{}`", vaultPath)
+	vault, err := stim.NewVault()
+	if err != nil {
+		return "", err
+	}
+	token, err := vault.GetSecretKey(vaultPath, vaultKey)
+	if err != nil {
+		return "", fmt.Errorf("Stim-Github: error getting token from Vault: %v", err)
+	}
+	return token, nil
+}
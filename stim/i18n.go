@@ -0,0 +1,22 @@
+package stim
+
+import (
+	"os"
+
+	"github.com/PremiereGlobal/stim/pkg/i18n"
+)
+
+// Message returns the message with the given ID (ex. i18n.MessageProceed) in
+// the locale of the user.  The locale is the `locale` config option or, if not
+// set, the locale of the LC_ALL, LC_MESSAGES or LANG environment variables.
+func (stim *Stim) Message(id string, args ...interface{}) string {
+	return stim.Localizer().Message(id, args...)
+}
+
+// Localizer returns the localizer of the locale of the user
+func (stim *Stim) Localizer() *i18n.Localizer {
+	if stim.localizer == nil {
+		stim.localizer = i18n.New(i18n.Detect(stim.ConfigGetString("locale"), os.Getenv))
+	}
+	return stim.localizer
+}
@@ -0,0 +1,41 @@
+package stim
+
+import (
+	"github.com/PremiereGlobal/stim/pkg/vault"
+)
+
+// The interfaces below (and stimlog.StimLogger) are the parts of stim that
+// stimpack logic commonly depends on.  Functions that take them instead of
+// *Stim can be unit tested with the fakes in the stimtest package.
+
+// ConfigReader reads stim config options
+type ConfigReader interface {
+	ConfigGetString(key string) string
+	ConfigGetInt(key string) int
+	ConfigGetBool(key string) bool
+	ConfigGetStringSlice(key string) []string
+}
+
+// Prompter asks the user for input
+type Prompter interface {
+	PromptBool(label string, override bool, defaultvalue bool) (bool, error)
+	PromptString(label string, defaultvalue string) (string, error)
+	PromptList(label string, list []string, override string) (string, error)
+	PromptListRemembered(key string, label string, list []string, override string) (string, error)
+	PromptMultiList(key string, label string, list []string, override []string) ([]string, error)
+}
+
+// VaultClient reads secrets from Vault
+type VaultClient interface {
+	GetToken() (string, error)
+	GetAddress() (string, error)
+	GetSecretKey(path string, key string) (string, error)
+	GetSecretKeys(path string) (map[string]string, error)
+	ListSecrets(path string) ([]string, error)
+}
+
+var (
+	_ ConfigReader = (*Stim)(nil)
+	_ Prompter     = (*Stim)(nil)
+	_ VaultClient  = (*vault.Vault)(nil)
+)
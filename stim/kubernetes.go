@@ -0,0 +1,184 @@
+package stim
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/PremiereGlobal/stim/pkg/bom"
+	"github.com/PremiereGlobal/stim/pkg/kubernetes"
+	"github.com/PremiereGlobal/stim/pkg/utils"
+)
+
+// defaultKubeConfigPathTemplate is the Vault path of the kube-config secret
+// for a given cluster and service account.  It can be overridden with the
+// vault.kubeConfigPathTemplate config key.
+const defaultKubeConfigPathTemplate = "secret/kubernetes/{CLUSTER}/{SERVICE_ACCOUNT}/kube-config"
+
+// KubeConfigOptions describes a kubeconfig to be generated from Vault
+type KubeConfigOptions struct {
+
+	// Cluster is the name of the Kubernetes cluster
+	Cluster string
+
+	// ServiceAccount to use when connecting to Kubernetes
+	ServiceAccount string
+
+	// DefaultNamespace to use for the context.  If not set, the default from Vault is used
+	DefaultNamespace string
+
+	// Path is where the kubeconfig will be written.  If not set, the default
+	// kubeconfig is used
+	Path string
+
+	// ContextName is the name of the context.  Defaults to the cluster name
+	ContextName string
+
+	// KeepCurrentContext leaves the current context unchanged instead of
+	// switching to the new context
+	KeepCurrentContext bool
+
+	// UseLock makes the context of a cluster in `kube.locked-clusters` get its
+	// token from `stim kube credential`, which only hands out the token while
+	// the cluster is unlocked with `stim kube unlock`
+	UseLock bool
+}
+
+// KubeConfigFromVault writes a kubeconfig for the given cluster and service
+// account, using the credentials stored in Vault, and returns the config
+func (stim *Stim) KubeConfigFromVault(options *KubeConfigOptions) (*kubernetes.Config, error) {
+
+	// Get the Kubernetes creds from Vault
+	vault, err := stim.NewVault()
+	if err != nil {
+		return nil, err
+	}
+	secretValues, err := vault.GetSecretKeys(stim.KubeConfigSecretPath(options.Cluster, options.ServiceAccount))
+	if err != nil {
+		return nil, err
+	}
+
+	// If namespace not set use the default from Vault
+	defaultNamespace := options.DefaultNamespace
+	if defaultNamespace == "" {
+		defaultNamespace = secretValues["default-namespace"]
+	}
+
+	contextName := options.ContextName
+	if contextName == "" {
+		contextName = options.Cluster
+	}
+
+	// Build the Kube config options
+	kubeConfigOptions := &kubernetes.ConfigOptions{
+		ClusterName:             options.Cluster,
+		ClusterServer:           secretValues["cluster-server"],
+		ClusterCA:               secretValues["cluster-ca"],
+		AuthName:                options.Cluster + "-" + options.ServiceAccount,
+		AuthToken:               secretValues["user-token"],
+		ContextName:             contextName,
+		ContextSetCurrent:       !options.KeepCurrentContext,
+		ContextDefaultNamespace: defaultNamespace,
+	}
+
+	if options.UseLock && stim.IsKubeClusterLockable(options.Cluster) {
+		kubeConfigOptions.AuthExecCommand, kubeConfigOptions.AuthExecArgs, err = stim.KubeCredentialCommand(options.Cluster, options.ServiceAccount)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	stim.bom.Add(bom.KindKubernetes, options.Cluster, map[string]string{
+		"server":          secretValues["cluster-server"],
+		"service-account": options.ServiceAccount,
+	})
+
+	kc := kubernetes.NewConfig()
+	if options.Path != "" {
+		kc = kubernetes.NewConfigFromPath(options.Path)
+	}
+	err = kc.Modify(kubeConfigOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return kc, nil
+}
+
+// IsKubeClusterLockable returns true if the cluster matches one of the
+// `kube.locked-clusters` patterns (ex. `prod-*`)
+func (stim *Stim) IsKubeClusterLockable(cluster string) bool {
+	for _, pattern := range stim.ConfigGetStringSlice("kube.locked-clusters") {
+		if matched, _ := path.Match(pattern, cluster); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// KubeCredentialCommand returns the exec credential plugin command and
+// arguments that get the token of the cluster and service account from
+// `stim kube credential`
+func (stim *Stim) KubeCredentialCommand(cluster string, serviceAccount string) (string, []string, error) {
+
+	command, err := os.Executable()
+	if err != nil {
+		return "", nil, err
+	}
+	command, err = filepath.EvalSymlinks(command)
+	if err != nil {
+		return "", nil, err
+	}
+
+	args := []string{"kube", "credential", "--cluster", cluster, "--service-account", serviceAccount}
+	if profile := stim.ConfigGetString("profile"); profile != "" {
+		args = append(args, "--profile", profile)
+	}
+	return command, args, nil
+}
+
+// KubeConfigSecretPath returns the Vault path of the kube-config secret for
+// the given cluster and service account
+func (stim *Stim) KubeConfigSecretPath(cluster string, serviceAccount string) string {
+	return stim.kubeConfigPathTemplate().
+		ReplaceAll("{CLUSTER}", cluster).
+		ReplaceAll("{SERVICE_ACCOUNT}", serviceAccount).
+		String()
+}
+
+// KubeClusterListPath returns the Vault path that lists the available clusters
+func (stim *Stim) KubeClusterListPath() string {
+	return templatePrefix(stim.kubeConfigPathTemplate().String(), "{CLUSTER}")
+}
+
+// KubeServiceAccountListPath returns the Vault path that lists the available
+// service accounts for the given cluster
+func (stim *Stim) KubeServiceAccountListPath(cluster string) string {
+	return templatePrefix(stim.kubeConfigPathTemplate().ReplaceAll("{CLUSTER}", cluster).String(), "{SERVICE_ACCOUNT}")
+}
+
+// kubeConfigPathTemplate returns the configured kube-config path template
+func (stim *Stim) kubeConfigPathTemplate() utils.StringReplacer {
+	template := stim.ConfigGetString("vault.kubeConfigPathTemplate")
+	if template == "" {
+		template = defaultKubeConfigPathTemplate
+	}
+	return utils.StringReplacer(strings.Trim(template, "/"))
+}
+
+// templatePrefix returns the path segments of the template before the given
+// placeholder.  The prefix is cut at the last `/` before the first placeholder
+// of any kind so that placeholders sharing a segment with other text (ex.
+// `secret/{CLUSTER}-kube`) list from the parent path.
+func templatePrefix(template string, placeholder string) string {
+	i := strings.Index(template, placeholder)
+	if i < 0 {
+		return template
+	}
+	if j := strings.Index(template, "{"); j >= 0 && j < i {
+		i = j
+	}
+	prefix := template[:i]
+	return strings.TrimRight(prefix[:strings.LastIndex(prefix, "/")+1], "/")
+}
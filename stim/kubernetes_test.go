@@ -0,0 +1,38 @@
+package stim
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestTemplatePrefix(t *testing.T) {
+	tests := []struct {
+		template    string
+		placeholder string
+		expected    string
+	}{
+		{"secret/kubernetes/{CLUSTER}/{SERVICE_ACCOUNT}/kube-config", "{CLUSTER}", "secret/kubernetes"},
+		{"secret/kubernetes/c1/{SERVICE_ACCOUNT}/kube-config", "{SERVICE_ACCOUNT}", "secret/kubernetes/c1"},
+		{"secret/{CLUSTER}-app/{SERVICE_ACCOUNT}", "{CLUSTER}", "secret"},
+		{"secret/kube-{CLUSTER}/{SERVICE_ACCOUNT}", "{CLUSTER}", "secret"},
+		{"secret/c1/sa-{SERVICE_ACCOUNT}", "{SERVICE_ACCOUNT}", "secret/c1"},
+		{"secret/{SERVICE_ACCOUNT}/{CLUSTER}/kube-config", "{CLUSTER}", "secret"},
+		{"{CLUSTER}/kube-config", "{CLUSTER}", ""},
+		{"secret/kubernetes/kube-config", "{CLUSTER}", "secret/kubernetes/kube-config"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, templatePrefix(test.template, test.placeholder), test.expected, test.template)
+	}
+}
+
+func TestIsKubeClusterLockable(t *testing.T) {
+	stim := New()
+	stim.config.Set("kube.locked-clusters", []string{"prod-*", "billing"})
+
+	assert.Assert(t, stim.IsKubeClusterLockable("prod-usw2"))
+	assert.Assert(t, stim.IsKubeClusterLockable("billing"))
+	assert.Assert(t, !stim.IsKubeClusterLockable("staging-usw2"))
+	assert.Assert(t, !stim.IsKubeClusterLockable("billing-dev"))
+}
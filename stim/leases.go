@@ -0,0 +1,67 @@
+package stim
+
+import (
+	"path/filepath"
+
+	"github.com/PremiereGlobal/stim/pkg/vault"
+)
+
+// leaseFile is the file (in the `vault` cache directory) that the leases
+// left behind by commands are recorded in, for `stim vault leases`
+const leaseFile = "leases.json"
+
+// revokeLeases revokes the leases of the dynamic secrets read by the command
+// (ex. AWS credentials of a deploy), unless they were handed out or
+// `vault.keep-leases` is set.  The leases that are left are recorded so they
+// can be listed and revoked later.
+func (stim *Stim) revokeLeases() {
+
+	if stim.vault == nil {
+		return
+	}
+	leases := stim.vault.Leases()
+	if len(leases) == 0 {
+		return
+	}
+
+	keep := stim.ConfigGetBool("vault.keep-leases")
+	var left []*vault.Lease
+	for _, lease := range leases {
+		if lease.Kept || keep {
+			left = append(left, lease)
+			continue
+		}
+		err := stim.vault.RevokeLease(lease.ID)
+		if err != nil {
+			stim.log.Warn("Unable to revoke the lease of {}, it expires at {}: {}", lease.Path, stim.FormatTime(lease.Expires), err)
+			left = append(left, lease)
+			continue
+		}
+		stim.log.Debug("Stim-Vault: Revoked the lease of {}", lease.Path)
+	}
+	if len(left) == 0 {
+		return
+	}
+
+	recorded, err := stim.RecordedLeases()
+	if err != nil {
+		stim.log.Warn("Unable to record the Vault leases left by the command: {}", err)
+		return
+	}
+	err = stim.WriteRecordedLeases(append(recorded, left...))
+	if err != nil {
+		stim.log.Warn("Unable to record the Vault leases left by the command: {}", err)
+	}
+}
+
+// RecordedLeases returns the unexpired leases left behind by commands,
+// oldest first
+func (stim *Stim) RecordedLeases() ([]*vault.Lease, error) {
+	return vault.ReadLeaseFile(filepath.Join(stim.ConfigGetCacheDir("vault"), leaseFile), stim.clock.Now())
+}
+
+// WriteRecordedLeases replaces the recorded leases.  Expired leases are
+// dropped.
+func (stim *Stim) WriteRecordedLeases(leases []*vault.Lease) error {
+	return vault.WriteLeaseFile(filepath.Join(stim.ConfigGetCacheDir("vault"), leaseFile), leases, stim.clock.Now())
+}
@@ -0,0 +1,107 @@
+package stim
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/PremiereGlobal/stim/pkg/stimlog"
+)
+
+// defaultLogFileMaxSize is the size in MB the log file is rotated at when
+// `logging.file.max-size` is not set
+const defaultLogFileMaxSize = 10
+
+// defaultLogFileMaxBackups is the number of rotated log files kept when
+// `logging.file.max-backups` is not set
+const defaultLogFileMaxBackups = 3
+
+// configureLogging sets the log level and format (`--log-level` and
+// `--log-format`, or `logging.level` and `logging.format`) and adds the log
+// file
+func (stim *Stim) configureLogging() error {
+
+	format := stimlog.ConsoleFormat
+	if value := stim.ConfigGetString("logging.format"); value != "" {
+		var err error
+		format, err = stimlog.ParseFormat(value)
+		if err != nil {
+			return err
+		}
+	}
+	stim.logConfig.SetFormat(format)
+
+	// --verbose is the same as the debug level
+	levelName := "info"
+	if stim.ConfigGetBool("verbose") {
+		levelName = "debug"
+	}
+	if value := stim.ConfigGetString("logging.level"); value != "" {
+		levelName = value
+	}
+	level, err := stimlog.ParseLevel(levelName)
+	if err != nil {
+		return err
+	}
+
+	if !stim.ConfigGetBool("logging.file.disable") {
+		err = stim.addLogFile()
+		if err != nil {
+			return err
+		}
+	}
+
+	stim.logConfig.SetLevel(level)
+	stim.log.Debug("Stim version: {}", version)
+	stim.log.Debug("Log level set to {}", levelName)
+
+	return nil
+}
+
+// addLogFile adds the log file (`logging.file.path`, stim.log in the stim
+// config directory by default), which is rotated once it reaches
+// `logging.file.max-size`
+func (stim *Stim) addLogFile() error {
+
+	options := stimlog.LogFileOptions{
+		Level:      stimlog.DebugLevel,
+		MaxSize:    defaultLogFileMaxSize,
+		MaxBackups: defaultLogFileMaxBackups,
+	}
+	if value := stim.ConfigGetString("logging.file.level"); value != "" {
+		var err error
+		options.Level, err = stimlog.ParseLevel(value)
+		if err != nil {
+			return fmt.Errorf("logging.file.level: %v", err)
+		}
+	}
+	if value := stim.ConfigGetString("logging.file.format"); value != "" {
+		var err error
+		options.Format, err = stimlog.ParseFormat(value)
+		if err != nil {
+			return fmt.Errorf("logging.file.format: %v", err)
+		}
+	}
+	if stim.config.IsSet("logging.file.max-size") {
+		options.MaxSize = int64(stim.ConfigGetInt("logging.file.max-size"))
+	}
+	if stim.config.IsSet("logging.file.max-backups") {
+		options.MaxBackups = stim.ConfigGetInt("logging.file.max-backups")
+	}
+	options.MaxSize *= 1024 * 1024
+
+	lfp := stim.ConfigGetString("logging.file.path")
+	if lfp == "" {
+		sh, err := stim.ConfigGetStimConfigDir()
+		if err != nil {
+			stim.log.Warn("Could not find stim config dir path, not creating log file")
+			return nil
+		}
+		lfp = filepath.Join(sh, "stim.log")
+	}
+
+	err := stim.logConfig.AddLogFileOptions(lfp, options)
+	if err != nil {
+		stim.log.Warn("Unable to open the log file {}: {}", lfp, err)
+	}
+	return nil
+}
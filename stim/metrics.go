@@ -0,0 +1,123 @@
+package stim
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/metrics"
+)
+
+// The phases of a stim run that are timed
+const (
+	PhaseConfig       = "config"
+	PhaseVaultAuth    = "vault.auth"
+	PhaseKubeConfig   = "kube.config"
+	PhaseSecretFetch  = "secrets.fetch"
+	PhaseSecretCheck  = "secrets.check"
+	PhaseToolDownload = "tools.download"
+	PhaseDeployConfig = "deploy.config"
+	PhaseTemplates    = "templates.render"
+	PhaseImagePull    = "image.pull"
+	PhaseContainerRun = "container.run"
+	PhaseScriptRun    = "script.run"
+	PhaseTerraform    = "terraform.run"
+)
+
+// defaultMetricsJob is the Pushgateway job when `metrics.job` is not set
+const defaultMetricsJob = "stim"
+
+// statsDPrefix is the prefix of the StatsD metric names
+const statsDPrefix = "stim"
+
+// Time starts timing a phase of the run and returns the function that stops
+// it.  The phases are printed with `--timings`, sent to the metrics endpoints
+// after the command and traced as spans when tracing is configured.
+//
+//	defer stim.Time(stim.PhaseSecretFetch)()
+func (stim *Stim) Time(phase string) func() {
+	stopTimer := stim.timer.Start(phase)
+	endSpan := stim.StartSpan(phase, nil)
+	return func() {
+		endSpan(nil)
+		stopTimer()
+	}
+}
+
+// metricsInit reports the timings of the run when it fails with a Fatal log
+func (stim *Stim) metricsInit() {
+	stim.logConfig.AddExitHook(func(message string) {
+		stim.reportMetrics(message)
+	})
+}
+
+// reportMetrics prints the `--timings` summary and pushes the timings to
+// `metrics.pushgateway` and `metrics.statsd`.  An empty errMessage means the
+// command succeeded.  Errors are only logged at debug level so that metrics
+// never get in the way of the command.
+func (stim *Stim) reportMetrics(errMessage string) {
+
+	if stim.metricsReported || !stim.initialized {
+		return
+	}
+	stim.metricsReported = true
+
+	cmd, _, err := stim.rootCmd.Find(os.Args[1:])
+	if err != nil {
+		cmd = stim.rootCmd
+	}
+	if isInformational(cmd) {
+		return
+	}
+
+	now := stim.clock.Now()
+	run := &metrics.Run{
+		Command:  cmd.CommandPath(),
+		Result:   auditSuccess,
+		Finished: now,
+		Duration: now.Sub(stim.startTime),
+		Phases:   stim.timer.Phases(),
+	}
+	if errMessage != "" {
+		run.Result = auditFailure
+	}
+
+	stim.exportTrace(errMessage)
+
+	if stim.ConfigGetBool("timings") {
+		printTimings(run)
+	}
+
+	if gateway := stim.ConfigGetString("metrics.pushgateway"); gateway != "" {
+		job := stim.ConfigGetString("metrics.job")
+		if job == "" {
+			job = defaultMetricsJob
+		}
+		host, _ := os.Hostname()
+		err = metrics.PushGateway(gateway, job, host, run)
+		if err != nil {
+			stim.log.Debug("Stim-Metrics: Unable to push the metrics to {}: {}", gateway, err)
+		}
+	}
+
+	if address := stim.ConfigGetString("metrics.statsd"); address != "" {
+		err = metrics.StatsD(address, statsDPrefix, run)
+		if err != nil {
+			stim.log.Debug("Stim-Metrics: Unable to send the metrics to {}: {}", address, err)
+		}
+	}
+}
+
+// printTimings prints the time spent in each phase to stderr, so that it
+// doesn't mix with output read by other programs
+func printTimings(run *metrics.Run) {
+
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PHASE\tCOUNT\tDURATION")
+	for _, phase := range run.Phases {
+		fmt.Fprintf(w, "%s\t%d\t%s\n", phase.Name, phase.Count, phase.Duration.Round(time.Millisecond))
+	}
+	fmt.Fprintf(w, "total\t\t%s\n", run.Duration.Round(time.Millisecond))
+	w.Flush()
+}
@@ -0,0 +1,22 @@
+package stim
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/clock"
+	"github.com/PremiereGlobal/stim/pkg/metrics"
+	"gotest.tools/assert"
+)
+
+func TestTime(t *testing.T) {
+	stim := New()
+	fake := clock.NewFake(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	stim.SetClock(fake)
+
+	stop := stim.Time(PhaseVaultAuth)
+	fake.Advance(3 * time.Second)
+	stop()
+
+	assert.DeepEqual(t, stim.timer.Phases(), []metrics.Phase{{Name: PhaseVaultAuth, Count: 1, Duration: 3 * time.Second}})
+}
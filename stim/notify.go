@@ -0,0 +1,159 @@
+package stim
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PremiereGlobal/stim/pkg/aws"
+	"github.com/PremiereGlobal/stim/pkg/notify"
+)
+
+// notifyVaultPrefix marks a backend option whose value is read from Vault,
+// ex. `vault:secret/teams/deploys#url`
+const notifyVaultPrefix = "vault:"
+
+// notifyBackends creates a notification backend of each type from its
+// options.  Adding a new type of backend only requires an entry here.
+var notifyBackends = map[string]func(stim *Stim, options map[string]string) (notify.Backend, error){
+	"slack": func(stim *Stim, options map[string]string) (notify.Backend, error) {
+		slack, err := stim.NewSlack()
+		if err != nil {
+			return nil, err
+		}
+		return notify.NewSlackBackend(slack, options)
+	},
+	"teams": func(stim *Stim, options map[string]string) (notify.Backend, error) {
+		return notify.NewTeamsBackend(options)
+	},
+	"webhook": func(stim *Stim, options map[string]string) (notify.Backend, error) {
+		return notify.NewWebhookBackend(options)
+	},
+	"email": func(stim *Stim, options map[string]string) (notify.Backend, error) {
+		var sender notify.EmailSender
+		var err error
+		switch options["transport"] {
+		case "", "smtp":
+			sender, err = notify.NewSMTPSender(options)
+		case "ses":
+			sender, err = stim.newSESSender(options)
+		default:
+			err = fmt.Errorf("Unknown email transport '%s'.  Must be one of ['smtp','ses']", options["transport"])
+		}
+		if err != nil {
+			return nil, err
+		}
+		return notify.NewEmailBackend(options, sender)
+	},
+	"pagerduty": func(stim *Stim, options map[string]string) (notify.Backend, error) {
+		pagerduty, err := stim.NewPagerduty()
+		if err != nil {
+			return nil, err
+		}
+		return notify.NewPagerdutyBackend(pagerduty, options)
+	},
+}
+
+// NewNotifier returns a notification router with the backends configured in
+// the stim config (`notify.backends`) plus the given backends.  Backends that
+// can't be created (ex. their credentials can't be read from Vault) are logged
+// and skipped so that notifications never stop the command using them.
+func (stim *Stim) NewNotifier(configs []*notify.BackendConfig) (*notify.Router, error) {
+
+	var configured []*notify.BackendConfig
+	err := stim.config.UnmarshalKey("notify.backends", &configured)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid `notify.backends` config: %v", err)
+	}
+
+	router := notify.NewRouter()
+	for _, config := range append(configured, configs...) {
+		create, ok := notifyBackends[config.Type]
+		if !ok {
+			return nil, fmt.Errorf("Unknown notification backend type '%s' for '%s'", config.Type, config.Name)
+		}
+
+		options, err := stim.resolveNotifyOptions(config.Options)
+		if err != nil {
+			stim.log.Warn("Stim-Notify: Skipping {} backend {}: {}", config.Type, config.Name, err)
+			continue
+		}
+
+		backend, err := create(stim, options)
+		if err != nil {
+			stim.log.Warn("Stim-Notify: Skipping {} backend {}: {}", config.Type, config.Name, err)
+			continue
+		}
+
+		stim.log.Debug("Stim-Notify: Adding {} backend {}", config.Type, config.Name)
+		router.Add(config.Name, config.Events, backend)
+	}
+
+	return router, nil
+}
+
+// Notify sends the event to the backends configured in the stim config
+func (stim *Stim) Notify(event string, payload *notify.Payload) error {
+
+	if stim.notifier == nil {
+		notifier, err := stim.NewNotifier(nil)
+		if err != nil {
+			return err
+		}
+		stim.notifier = notifier
+	}
+
+	return stim.notifier.Notify(event, payload)
+}
+
+// resolveNotifyOptions reads option values that reference Vault secrets
+func (stim *Stim) resolveNotifyOptions(options map[string]string) (map[string]string, error) {
+
+	result := make(map[string]string)
+	for key, value := range options {
+		if strings.HasPrefix(value, notifyVaultPrefix) {
+			parts := strings.SplitN(strings.TrimPrefix(value, notifyVaultPrefix), "#", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("Notification option '%s' must be in the format `vault:<path>#<key>`", key)
+			}
+			vault, err := stim.NewVault()
+			if err != nil {
+				return nil, err
+			}
+			secret, err := vault.GetSecretKey(parts[0], parts[1])
+			if err != nil {
+				return nil, err
+			}
+			value = secret
+		}
+		result[key] = value
+	}
+
+	return result, nil
+}
+
+// sesSender sends notification emails with SES
+type sesSender struct {
+	aws *aws.Aws
+}
+
+// newSESSender returns an SES email sender using the default AWS credentials
+// or the `aws-profile` option (ex. a profile saved by `stim aws login
+// --use-profiles`) in the `aws-region` region
+func (stim *Stim) newSESSender(options map[string]string) (*sesSender, error) {
+
+	a, err := stim.NewAws("", "")
+	if err != nil {
+		return nil, err
+	}
+	err = a.CreateDefaultSession(options["aws-profile"], options["aws-region"])
+	if err != nil {
+		return nil, err
+	}
+
+	return &sesSender{aws: a}, nil
+}
+
+// SendEmail sends the message with SES
+func (s *sesSender) SendEmail(from string, to []string, message []byte) error {
+	return s.aws.SendRawEmail(from, to, message)
+}
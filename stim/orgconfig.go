@@ -0,0 +1,130 @@
+package stim
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/orgconfig"
+	"github.com/PremiereGlobal/stim/pkg/utils"
+)
+
+// orgConfigCacheFile is the cache file of the org config
+const orgConfigCacheFile = "org-config.json"
+
+// orgConfigIgnoredKeys can't be set by the org config since they are resolved
+// before it is loaded or configure the org config itself
+var orgConfigIgnoredKeys = []string{"config-file", "path", "cache-path", "profile", "profiles", "current-profile", "org-config", "logging", "verbose"}
+
+// configApplyOrgConfig loads the org config from `org-config.url` and layers
+// it under the user config.  Its settings become defaults, which the config
+// file, environment variables and flags take precedence over, except for its
+// locked settings which take precedence over everything.  An org config that
+// can't be loaded is logged and ignored so that stim keeps working offline.
+func (stim *Stim) configApplyOrgConfig() {
+
+	if stim.ConfigGetString("org-config.url") == "" {
+		return
+	}
+
+	overlay, err := stim.loadOrgConfig(false)
+	if err != nil {
+		if stim.offline {
+			stim.log.Debug("Stim-OrgConfig: Not using the org config: {}", err)
+		} else {
+			stim.log.Warn("Stim-OrgConfig: Not using the org config: {}", err)
+		}
+		return
+	}
+	if overlay.RefreshError != nil {
+		stim.log.Debug("Stim-OrgConfig: Using the org config cached {}: {}", stim.FormatTime(overlay.FetchedAt), overlay.RefreshError)
+	}
+
+	stim.applyOrgConfig(overlay)
+}
+
+// applyOrgConfig sets the defaults and locked settings of the org config
+func (stim *Stim) applyOrgConfig(overlay *orgconfig.Overlay) {
+
+	stim.orgConfig = overlay
+	for key, value := range overlay.Flatten() {
+		if isOrgConfigIgnored(key) {
+			stim.log.Debug("Stim-OrgConfig: Ignoring '{}', which can't be set by the org config", key)
+			continue
+		}
+		stim.config.SetDefault(key, value)
+		if utils.Contains(overlay.Locked, key) {
+			stim.config.Set(key, value)
+		}
+	}
+}
+
+// OrgConfig returns the org config in use, nil if there is none
+func (stim *Stim) OrgConfig() *orgconfig.Overlay {
+	return stim.orgConfig
+}
+
+// RefreshOrgConfig fetches the org config even if the cached config is
+// fresh.  The new config is used by the next stim command.
+func (stim *Stim) RefreshOrgConfig() (*orgconfig.Overlay, error) {
+	if stim.ConfigGetString("org-config.url") == "" {
+		return nil, ConfigError(fmt.Errorf("No org config, `org-config.url` is not set"))
+	}
+	overlay, err := stim.loadOrgConfig(true)
+	if err != nil {
+		return nil, err
+	}
+	if overlay.RefreshError != nil {
+		return nil, overlay.RefreshError
+	}
+	return overlay, nil
+}
+
+// ConfigIsLocked returns true if the key (or a section containing it) is
+// locked by the org config
+func (stim *Stim) ConfigIsLocked(key string) bool {
+	if stim.orgConfig == nil {
+		return false
+	}
+	for _, locked := range stim.orgConfig.Locked {
+		if key == locked || strings.HasPrefix(locked, key+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// loadOrgConfig loads the org config, from the cache unless it is stale or
+// refresh is set
+func (stim *Stim) loadOrgConfig(refresh bool) (*orgconfig.Overlay, error) {
+
+	interval := orgconfig.DefaultRefreshInterval
+	if i := stim.ConfigGetString("org-config.refresh-interval"); i != "" {
+		d, err := time.ParseDuration(i)
+		if err != nil {
+			return nil, ConfigError(fmt.Errorf("Invalid org-config.refresh-interval '%s': %v", i, err))
+		}
+		interval = d
+	}
+
+	return orgconfig.Load(&orgconfig.Config{
+		URL:             stim.ConfigGetString("org-config.url"),
+		PublicKey:       stim.ConfigGetString("org-config.public-key"),
+		CacheFile:       filepath.Join(stim.ConfigGetCacheDir(""), orgConfigCacheFile),
+		RefreshInterval: interval,
+		Refresh:         refresh,
+		Offline:         stim.offline && !refresh,
+		Clock:           stim.clock,
+	})
+}
+
+// isOrgConfigIgnored returns true if the key can't be set by the org config
+func isOrgConfigIgnored(key string) bool {
+	for _, ignored := range orgConfigIgnoredKeys {
+		if key == ignored || strings.HasPrefix(key, ignored+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package stim
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PremiereGlobal/stim/pkg/orgconfig"
+	"gotest.tools/assert"
+)
+
+func TestApplyOrgConfig(t *testing.T) {
+	stim := New()
+	stim.config.SetConfigType("yaml")
+	err := stim.config.ReadConfig(strings.NewReader("vault-address: https://vault.user.example.com\nstimpacks:\n  ssh:\n    enabled: true\n"))
+	assert.NilError(t, err)
+
+	overlay, err := orgconfig.Parse([]byte(`settings:
+  vault-address: https://vault.example.com
+  vault-username-skip-prompt: true
+  path: /org
+  stimpacks:
+    ssh:
+      enabled: false
+locked:
+  - stimpacks.ssh.enabled
+`))
+	assert.NilError(t, err)
+	stim.applyOrgConfig(overlay)
+
+	// The user config takes precedence unless the key is locked
+	assert.Equal(t, stim.ConfigGetString("vault-address"), "https://vault.user.example.com")
+	assert.Equal(t, stim.ConfigGetBool("vault-username-skip-prompt"), true)
+	assert.Equal(t, stim.IsStimpackEnabled("ssh"), false)
+	assert.Equal(t, stim.ConfigGetString("path"), "")
+
+	assert.Assert(t, stim.ConfigIsLocked("stimpacks.ssh.enabled"))
+	assert.Assert(t, stim.ConfigIsLocked("stimpacks.ssh"))
+	assert.Assert(t, !stim.ConfigIsLocked("vault-address"))
+}
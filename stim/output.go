@@ -0,0 +1,37 @@
+package stim
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// Output formats of PrintOutput
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+)
+
+// PrintOutput prints the data as indented JSON or as a table written by the
+// table function, depending on the format (`table` if empty).  Commands should
+// bind the format to an `--output` flag.
+func (stim *Stim) PrintOutput(format string, data interface{}, table func(w *tabwriter.Writer)) error {
+
+	switch format {
+	case OutputJSON:
+		b, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	case OutputTable, "":
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		table(w)
+		w.Flush()
+	default:
+		return UsageError(fmt.Errorf("Invalid output format '%s', must be one of [%s, %s]", format, OutputTable, OutputJSON))
+	}
+
+	return nil
+}
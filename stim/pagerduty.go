@@ -1,20 +1,39 @@
 package stim
 
 import (
+	"fmt"
+	"net/http"
+
 	"github.com/PremiereGlobal/stim/pkg/pagerduty"
 )
 
 // Pagerduty returns a Pagerduty instance that is already authenticated
 func (stim *Stim) Pagerduty() *pagerduty.Pagerduty {
+	pagerduty, err := stim.NewPagerduty()
+	if err != nil {
+		stim.Fatal(err)
+	}
+	return pagerduty
+}
+
+// NewPagerduty is the same as Pagerduty but returns an error instead of
+// exiting if the API key can't be read
+func (stim *Stim) NewPagerduty() (*pagerduty.Pagerduty, error) {
 	stim.log.Debug("Stim-Pagerduty: Creating")
 	vaultPath := stim.ConfigGetString("pagerduty.vault-apikey-path")
 	vaultKey := stim.ConfigGetString("pagerduty.vault-apikey-key")
 	stim.log.Debug("Stim-Pagerduty: Fetching Pagerduty API key from Vault `{}``", vaultPath)
-	vault := stim.Vault()
+	vault, err := stim.NewVault()
+	if err != nil {
+		return nil, AuthError(err)
+	}
 	apikey, err := vault.GetSecretKey(vaultPath, vaultKey)
 	if err != nil {
-		stim.log.Fatal("Stim-Pagerduty: error getting API key from Vault: {}", err)
+		return nil, AuthError(fmt.Errorf("Stim-Pagerduty: error getting API key from Vault: %v", err))
 	}
 	pagerduty := pagerduty.New(apikey, stim.log)
-	return pagerduty
+	// The vendored client has its own transport, the default one retries
+	// failed calls
+	pagerduty.SetHTTPClient(&http.Client{})
+	return pagerduty, nil
 }
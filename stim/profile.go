@@ -0,0 +1,60 @@
+package stim
+
+import (
+	"fmt"
+	"sort"
+)
+
+// configApplyProfile merges the active profile (from `--profile` or
+// `current-profile` in the config file) over the top-level config so that
+// every stimpack resolves its settings through the profile.  Command line
+// flags and environment variables still take precedence.
+func (stim *Stim) configApplyProfile() error {
+
+	profile := stim.ConfigGetProfile()
+	if profile == "" {
+		return nil
+	}
+
+	profileConfig := stim.config.Sub("profiles." + profile)
+	if profileConfig == nil {
+		return fmt.Errorf("Profile '%s' not found in the stim config, must be one of %v", profile, stim.ConfigGetProfiles())
+	}
+
+	stim.log.Debug("Using stim profile: {}", profile)
+	return stim.config.MergeConfigMap(profileConfig.AllSettings())
+}
+
+// ConfigGetProfile returns the name of the active profile or an empty string
+// if no profile is in use
+func (stim *Stim) ConfigGetProfile() string {
+	profile := stim.ConfigGetString("profile")
+	if profile == "" {
+		profile = stim.ConfigGetString("current-profile")
+	}
+	return profile
+}
+
+// ConfigGetProfiles returns the sorted names of the profiles in the stim config
+func (stim *Stim) ConfigGetProfiles() []string {
+	var profiles []string
+	for profile := range stim.config.GetStringMap("profiles") {
+		profiles = append(profiles, profile)
+	}
+	sort.Strings(profiles)
+	return profiles
+}
+
+// ConfigUseProfile sets the default profile in the stim config file.  An
+// empty name clears the default profile.
+func (stim *Stim) ConfigUseProfile(profile string) error {
+	if profile != "" && !stim.config.IsSet("profiles."+profile) {
+		return fmt.Errorf("Profile '%s' not found in the stim config, must be one of %v", profile, stim.ConfigGetProfiles())
+	}
+
+	if profile == "" {
+		return stim.ConfigRemoveKey("current-profile")
+	}
+
+	return stim.ConfigSetString("current-profile", profile)
+}
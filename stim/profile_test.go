@@ -0,0 +1,56 @@
+package stim
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+const testProfileConfig = `
+vault:
+  address: https://vault.company.com
+  auth-method: oidc
+current-profile: company
+profiles:
+  company: {}
+  lab:
+    vault:
+      address: https://vault.lab.local
+`
+
+func newProfileTestStim(t *testing.T) *Stim {
+	stim := New()
+	stim.config.SetConfigType("yaml")
+	err := stim.config.ReadConfig(strings.NewReader(testProfileConfig))
+	assert.NilError(t, err)
+	return stim
+}
+
+func TestConfigApplyProfile(t *testing.T) {
+	stim := newProfileTestStim(t)
+	stim.config.Set("profile", "lab")
+
+	err := stim.configApplyProfile()
+	assert.NilError(t, err)
+	assert.Equal(t, stim.ConfigGetProfile(), "lab")
+	assert.Equal(t, stim.ConfigGetString("vault.address"), "https://vault.lab.local")
+	assert.Equal(t, stim.ConfigGetString("vault.auth-method"), "oidc")
+}
+
+func TestConfigApplyCurrentProfile(t *testing.T) {
+	stim := newProfileTestStim(t)
+
+	err := stim.configApplyProfile()
+	assert.NilError(t, err)
+	assert.Equal(t, stim.ConfigGetProfile(), "company")
+	assert.Equal(t, stim.ConfigGetString("vault.address"), "https://vault.company.com")
+}
+
+func TestConfigApplyUnknownProfile(t *testing.T) {
+	stim := newProfileTestStim(t)
+	stim.config.Set("profile", "missing")
+
+	err := stim.configApplyProfile()
+	assert.Error(t, err, "Profile 'missing' not found in the stim config, must be one of [company lab]")
+}
@@ -1,10 +1,23 @@
 package stim
 
 import (
+	"fmt"
+
 	"github.com/PremiereGlobal/stim/pkg/prometheus"
 )
 
 func (stim *Stim) Prometheus() *prometheus.Prometheus {
+	p, err := stim.NewPrometheus()
+	if err != nil {
+		stim.Fatal(err)
+	}
+
+	return p
+}
+
+// NewPrometheus is the same as Prometheus but returns an error instead of
+// exiting if the client can't be created
+func (stim *Stim) NewPrometheus() (*prometheus.Prometheus, error) {
 	stim.log.Debug("Stim-Prometheus: Creating")
 
 	address := stim.ConfigGetString("prometheus.address")
@@ -12,8 +25,8 @@ func (stim *Stim) Prometheus() *prometheus.Prometheus {
 
 	p, err := prometheus.New(&prometheus.Config{Address: address, Log: stim.log})
 	if err != nil {
-		stim.log.Fatal("Stim-Prometheus: Error Initializaing: {}", err)
+		return nil, ConfigError(fmt.Errorf("Stim-Prometheus: Error Initializaing: %v", err))
 	}
 
-	return p
+	return p, nil
 }
@@ -1,9 +1,12 @@
 package stim
 
 import (
+	"errors"
 	"os"
 	"strings"
 
+	"github.com/PremiereGlobal/stim/pkg/i18n"
+	"github.com/PremiereGlobal/stim/pkg/utils"
 	"github.com/chzyer/readline"
 	"github.com/manifoldco/promptui"
 )
@@ -39,8 +42,10 @@ func (stim *Stim) PromptBool(label string, override bool, defaultvalue bool) (bo
 		return true, nil
 	}
 
-	y := "y"
-	n := "n"
+	// Answers are in the locale of the user (ex. `s/n` in Spanish)
+	localizer := stim.Localizer()
+	y := localizer.Message(i18n.MessageYes)
+	n := localizer.Message(i18n.MessageNo)
 	if defaultvalue {
 		y = strings.ToUpper(y)
 	} else {
@@ -61,11 +66,7 @@ func (stim *Stim) PromptBool(label string, override bool, defaultvalue bool) (bo
 		return defaultvalue, nil
 	}
 
-	if strings.ToLower(strings.TrimSpace(result))[0:1] == "y" {
-		return true, nil
-	}
-
-	return false, nil
+	return localizer.IsYes(result), nil
 }
 
 // PromptString prompts the user to enter a string
@@ -103,9 +104,10 @@ func (stim *Stim) PromptList(label string, list []string, override string) (stri
 	}
 
 	prompt := promptui.Select{
-		Label: label,
-		Items: list,
-		Size:  10,
+		Label:    label,
+		Items:    list,
+		Size:     10,
+		Searcher: fuzzySearcher(list),
 	}
 
 	_, result, err := prompt.Run()
@@ -116,6 +118,260 @@ func (stim *Stim) PromptList(label string, list []string, override string) (stri
 	return result, nil
 }
 
+// PromptListRemembered is the same as PromptList but remembers the selection
+// under the key for the current repo (see RememberedSelection).  The last
+// selection is moved to the top of the list.
+func (stim *Stim) PromptListRemembered(key string, label string, list []string, override string) (string, error) {
+
+	if override != "" {
+		stim.Debug("PromptListRemembered: Using override value of `" + override + "`")
+		return override, nil
+	}
+
+	items, remembered := rememberedFirst(list, stim.RememberedSelection(key))
+	display := make([]string, len(items))
+	copy(display, items)
+	if remembered {
+		display[0] = stim.Message(i18n.MessageLastSelected, items[0])
+	}
+
+	prompt := promptui.Select{
+		Label:    label,
+		Items:    display,
+		Size:     10,
+		Searcher: fuzzySearcher(display),
+	}
+
+	i, _, err := prompt.Run()
+	if err != nil {
+		return "", err
+	}
+
+	stim.RememberSelection(key, []string{items[i]})
+	return items[i], nil
+}
+
+// rememberedFirst returns the list with the last selection moved to the top,
+// and whether the last selection is in the list
+func rememberedFirst(list []string, last []string) ([]string, bool) {
+
+	if len(last) == 0 || !utils.Contains(list, last[0]) {
+		return list, false
+	}
+
+	items := []string{last[0]}
+	for _, item := range list {
+		if item != last[0] {
+			items = append(items, item)
+		}
+	}
+	return items, true
+}
+
+// PromptMultiList prompts the user to select any number of items from the
+// list, returned in the order of the list.  Items are toggled one at a time
+// until `Done` is selected, or entered comma separated on terminals that
+// can't redraw the list.  If key isn't empty the selection is remembered for
+// the current repo and is selected by default the next time.  If override is
+// not empty it will be returned without prompting.
+func (stim *Stim) PromptMultiList(key string, label string, list []string, override []string) ([]string, error) {
+
+	if len(override) > 0 {
+		stim.Debug("PromptMultiList: Using override value of `" + strings.Join(override, ",") + "`")
+		return override, nil
+	}
+
+	selected := make(map[string]bool)
+	if key != "" {
+		for _, item := range stim.RememberedSelection(key) {
+			if utils.Contains(list, item) {
+				selected[item] = true
+			}
+		}
+	}
+
+	var err error
+	if IsFancyTerminal() {
+		err = stim.promptToggleList(label, list, selected)
+	} else {
+		err = stim.promptCommaList(label, list, selected)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := []string{}
+	for _, item := range list {
+		if selected[item] {
+			result = append(result, item)
+		}
+	}
+	if key != "" && len(result) > 0 {
+		stim.RememberSelection(key, result)
+	}
+
+	return result, nil
+}
+
+// promptToggleList toggles the selection of an item each time it's selected
+// until `Done` (the first item) is selected
+func (stim *Stim) promptToggleList(label string, list []string, selected map[string]bool) error {
+
+	for {
+		count := 0
+		items := make([]string, len(list)+1)
+		for i, item := range list {
+			box := "[ ] "
+			if selected[item] {
+				box = "[x] "
+				count++
+			}
+			items[i+1] = box + item
+		}
+		items[0] = stim.Message(i18n.MessageSelectDone, count)
+
+		prompt := promptui.Select{
+			Label: label,
+			Items: items,
+			Size:  10,
+			Searcher: func(input string, index int) bool {
+				return index == 0 || fuzzyMatch(input, list[index-1])
+			},
+		}
+
+		i, _, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			return nil
+		}
+		selected[list[i-1]] = !selected[list[i-1]]
+	}
+}
+
+// promptCommaList asks for the selected items separated by commas
+func (stim *Stim) promptCommaList(label string, list []string, selected map[string]bool) error {
+
+	var current []string
+	for _, item := range list {
+		if selected[item] {
+			current = append(current, item)
+		}
+	}
+
+	answer, err := stim.PromptString(stim.Message(i18n.MessageCommaSeparated, label, strings.Join(list, ", ")), strings.Join(current, ","))
+	if err != nil {
+		return err
+	}
+
+	for item := range selected {
+		delete(selected, item)
+	}
+	for _, item := range strings.Split(answer, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !utils.Contains(list, item) {
+			return UsageError(errors.New(stim.Message(i18n.MessageNotInList, item, strings.Join(list, ", "))))
+		}
+		selected[item] = true
+	}
+
+	return nil
+}
+
+// fuzzyMatch returns true if the characters of the input appear in the item
+// in order (ex. `usw2` matches `us-west-2`).  Case and spaces are ignored.
+func fuzzyMatch(input string, item string) bool {
+
+	input = strings.Replace(strings.ToLower(input), " ", "", -1)
+	item = strings.Replace(strings.ToLower(item), " ", "", -1)
+
+	remaining := []rune(input)
+	for _, c := range item {
+		if len(remaining) == 0 {
+			break
+		}
+		if c == remaining[0] {
+			remaining = remaining[1:]
+		}
+	}
+	return len(remaining) == 0
+}
+
+// fuzzySearcher filters the items of a list prompt with fuzzyMatch when
+// searching (`/`)
+func fuzzySearcher(list []string) func(input string, index int) bool {
+	return func(input string, index int) bool {
+		return fuzzyMatch(input, list[index])
+	}
+}
+
+// PromptItem is an item of PromptListDetails
+type PromptItem struct {
+
+	// Name is the value returned when the item is selected
+	Name string
+
+	// Details are the lines shown below the list when the item is highlighted
+	Details []string
+}
+
+// promptDetailsTemplates shows the details of the highlighted item below the
+// list
+var promptDetailsTemplates = &promptui.SelectTemplates{
+	Label:    "{{ . }}",
+	Active:   "\u25B8 {{ .Name | cyan }}",
+	Inactive: "  {{ .Name }}",
+	Selected: "\u2714 {{ .Name | faint }}",
+	Details:  "{{ range .Details }}\n  {{ . }}{{ end }}",
+}
+
+// PromptListDetails is the same as PromptList but shows the details of the
+// highlighted item (ex. the last deploy of an instance).  Terminals that
+// can't redraw the list (ex. TERM=dumb) get the plain PromptList.
+func (stim *Stim) PromptListDetails(label string, items []*PromptItem, override string) (string, error) {
+
+	if override != "" {
+		stim.Debug("PromptListDetails: Using override value of `" + override + "`")
+		return override, nil
+	}
+
+	names := make([]string, len(items))
+	for i, item := range items {
+		names[i] = item.Name
+	}
+	if !IsFancyTerminal() {
+		return stim.PromptList(label, names, "")
+	}
+
+	prompt := promptui.Select{
+		Label:     label,
+		Items:     items,
+		Size:      10,
+		Templates: promptDetailsTemplates,
+		Searcher:  fuzzySearcher(names),
+	}
+
+	i, _, err := prompt.Run()
+	if err != nil {
+		return "", err
+	}
+
+	return names[i], nil
+}
+
+// IsFancyTerminal returns true if stdin and stderr (where prompts are shown)
+// are terminals that can redraw the screen
+func IsFancyTerminal() bool {
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return readline.IsTerminal(int(os.Stdin.Fd())) && readline.IsTerminal(int(os.Stderr.Fd()))
+}
+
 // PromptListVault uses a path from vault and prompts to select the list
 // of secrets within that list.  Returns the value selected.
 // If override string is not empty it will be returned without
@@ -126,7 +382,10 @@ func (stim *Stim) PromptListVault(vaultPath string, label string, override strin
 		return override, nil
 	}
 
-	vault := stim.Vault()
+	vault, err := stim.NewVault()
+	if err != nil {
+		return "", err
+	}
 	list, err := vault.ListSecrets(vaultPath)
 	if err != nil {
 		return "", err
@@ -145,17 +404,11 @@ func (stim *Stim) PromptListVault(vaultPath string, label string, override strin
 // prompting
 func (stim *Stim) PromptSearchList(label string, list []string) (string, error) {
 
-	searcher := func(input string, index int) bool {
-		name := strings.Replace(strings.ToLower(list[index]), " ", "", -1)
-		input = strings.Replace(strings.ToLower(input), " ", "", -1)
-		return strings.Contains(name, input)
-	}
-
 	prompt := promptui.Select{
 		Label:             label,
 		Items:             list,
 		Size:              10,
-		Searcher:          searcher,
+		Searcher:          fuzzySearcher(list),
 		StartInSearchMode: true,
 	}
 
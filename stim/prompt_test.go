@@ -0,0 +1,43 @@
+package stim
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		input    string
+		item     string
+		expected bool
+	}{
+		{"", "us-west-2", true},
+		{"usw2", "us-west-2", true},
+		{"USW", "us-west-2", true},
+		{"us west", "us-west-2", true},
+		{"prod", "Production", true},
+		{"wsu", "us-west-2", false},
+		{"usw3", "us-west-2", false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, fuzzyMatch(test.input, test.item), test.expected, "%s ~ %s", test.input, test.item)
+	}
+}
+
+func TestRememberedFirst(t *testing.T) {
+	list := []string{"--ALL--", "us-east-1", "us-west-2"}
+
+	items, remembered := rememberedFirst(list, []string{"us-west-2"})
+	assert.Assert(t, remembered)
+	assert.DeepEqual(t, items, []string{"us-west-2", "--ALL--", "us-east-1"})
+
+	items, remembered = rememberedFirst(list, []string{"eu-west-1"})
+	assert.Assert(t, !remembered)
+	assert.DeepEqual(t, items, list)
+
+	items, remembered = rememberedFirst(list, nil)
+	assert.Assert(t, !remembered)
+	assert.DeepEqual(t, items, list)
+}
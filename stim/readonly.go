@@ -0,0 +1,139 @@
+package stim
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+
+	"github.com/PremiereGlobal/stim/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// AnnotationMutating marks a command that changes something outside of the
+// local machine (ex. deploys, posting messages).  Mutating commands are hidden
+// and refuse to run in read-only mode.
+//
+//	cmd.Annotations = map[string]string{stim.AnnotationMutating: "true"}
+const AnnotationMutating = "stim.mutating"
+
+// readOnlyCacheFile is the cache file of the last read-only detection from
+// the Vault token policies, used to hide commands without logging in
+const readOnlyCacheFile = "read-only"
+
+// IsMutating returns true if the command is marked as mutating
+func IsMutating(cmd *cobra.Command) bool {
+	return cmd.Annotations[AnnotationMutating] == "true"
+}
+
+// IsReadOnly returns true if stim is in read-only mode, either set with the
+// `read-only` config option or detected from the policies of the Vault token.
+// Detection uses the result cached at the last Vault login, so Vault isn't
+// accessed.
+func (stim *Stim) IsReadOnly() bool {
+	if stim.ConfigGetBool("read-only") {
+		return true
+	}
+	if len(stim.ConfigGetStringSlice("read-only-policies")) == 0 {
+		return false
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(stim.ConfigGetString("cache-path"), readOnlyCacheFile))
+	if err != nil {
+		return false
+	}
+	readOnly, _ := strconv.ParseBool(string(b))
+	return readOnly
+}
+
+// detectReadOnly checks the policies of the Vault token against the
+// `read-only-policies` config option and caches the result
+func (stim *Stim) detectReadOnly() {
+
+	readOnlyPolicies := stim.ConfigGetStringSlice("read-only-policies")
+	if len(readOnlyPolicies) == 0 {
+		return
+	}
+
+	status, err := stim.vault.GetTokenStatus()
+	if err != nil {
+		stim.log.Debug("Unable to read the Vault token policies: {}", err)
+		return
+	}
+
+	readOnly := isReadOnlyToken(status.Policies, readOnlyPolicies)
+	stim.log.Debug("Vault token policies {} read-only: {}", status.Policies, readOnly)
+
+	path := filepath.Join(stim.ConfigGetCacheDir(""), readOnlyCacheFile)
+	err = ioutil.WriteFile(path, []byte(strconv.FormatBool(readOnly)), 0600)
+	if err != nil {
+		stim.log.Debug("Unable to cache the read-only detection: {}", err)
+	}
+}
+
+// isReadOnlyToken returns true if the token only has read-only policies.  The
+// `default` policy is ignored since every token has it.
+func isReadOnlyToken(policies []string, readOnlyPolicies []string) bool {
+	found := false
+	for _, policy := range policies {
+		if policy == "default" {
+			continue
+		}
+		if !utils.Contains(readOnlyPolicies, policy) {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+// applyReadOnly hides the mutating commands in read-only mode and guards
+// them so they refuse to run.  Mutating commands with read-only subcommands
+// (ex. `deploy` and `deploy explain`) stay visible.  The guard logs in to Vault first when the
+// read-only mode is detected from the token policies, so a stale cache can't
+// let a mutating command through.
+func (stim *Stim) applyReadOnly() {
+
+	readOnly := stim.IsReadOnly()
+	detect := len(stim.ConfigGetStringSlice("read-only-policies")) > 0
+	if !readOnly && !detect {
+		return
+	}
+
+	guard := func(cmd *cobra.Command, args []string) error {
+		if detect && !stim.ConfigGetBool("read-only") {
+			_, err := stim.NewVault()
+			if err != nil {
+				return err
+			}
+		}
+		if stim.IsReadOnly() {
+			return AuthError(errors.New("`" + cmd.CommandPath() + "` is not allowed in read-only mode"))
+		}
+		return nil
+	}
+
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		subs, visible := 0, 0
+		for _, sub := range cmd.Commands() {
+			walk(sub)
+			if sub.Name() == "help" {
+				continue
+			}
+			subs++
+			if !sub.Hidden {
+				visible++
+			}
+		}
+		switch {
+		case IsMutating(cmd):
+			cmd.Hidden = readOnly && visible == 0
+			cmd.PreRunE = guard
+		case subs > 0 && visible == 0:
+			// Groups of only mutating commands (ex. `deploy preview`)
+			cmd.Hidden = true
+		}
+	}
+	walk(stim.rootCmd)
+}
@@ -0,0 +1,41 @@
+package stim
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"gotest.tools/assert"
+)
+
+func TestIsReadOnlyToken(t *testing.T) {
+	readOnlyPolicies := []string{"auditor", "read-secrets"}
+
+	assert.Assert(t, isReadOnlyToken([]string{"default", "auditor"}, readOnlyPolicies))
+	assert.Assert(t, !isReadOnlyToken([]string{"default", "auditor", "deployer"}, readOnlyPolicies))
+	assert.Assert(t, !isReadOnlyToken([]string{"default"}, readOnlyPolicies))
+}
+
+func TestApplyReadOnly(t *testing.T) {
+	stim := New()
+	stim.config.Set("read-only", true)
+
+	mutating := map[string]string{AnnotationMutating: "true"}
+	deploy := &cobra.Command{Use: "deploy", Annotations: mutating}
+	explain := &cobra.Command{Use: "explain"}
+	preview := &cobra.Command{Use: "preview"}
+	create := &cobra.Command{Use: "create", Annotations: mutating}
+	slack := &cobra.Command{Use: "slack", Annotations: mutating}
+	preview.AddCommand(create)
+	deploy.AddCommand(explain, preview)
+	stim.rootCmd.AddCommand(deploy, slack)
+
+	stim.applyReadOnly()
+
+	assert.Assert(t, !deploy.Hidden)
+	assert.Assert(t, deploy.PreRunE != nil)
+	assert.Assert(t, !explain.Hidden)
+	assert.Assert(t, explain.PreRunE == nil)
+	assert.Assert(t, preview.Hidden)
+	assert.Assert(t, create.Hidden)
+	assert.Assert(t, slack.Hidden)
+}
@@ -0,0 +1,87 @@
+package stim
+
+import (
+	"fmt"
+
+	"github.com/PremiereGlobal/stim/pkg/aws"
+	"github.com/PremiereGlobal/stim/pkg/registry"
+)
+
+// RegistryCredentials configures the Vault secret with the credentials of a
+// container registry (an item of `registry.credentials`)
+type RegistryCredentials struct {
+	Host        string `mapstructure:"host"`
+	VaultPath   string `mapstructure:"vault-path"`
+	UsernameKey string `mapstructure:"username-key"`
+	PasswordKey string `mapstructure:"password-key"`
+	Insecure    bool   `mapstructure:"insecure"`
+}
+
+// Registry returns a client of the container registry at host, logged in
+// with the credentials in `registry.credentials` (anonymous if the host has
+// none)
+func (stim *Stim) Registry(host string) (*registry.Registry, error) {
+	stim.log.Debug("Stim-Registry: Creating for {}", host)
+
+	var credentials []*RegistryCredentials
+	err := stim.ConfigUnmarshalKey("registry.credentials", &credentials)
+	if err != nil {
+		return nil, ConfigError(fmt.Errorf("Invalid `registry.credentials` config: %v", err))
+	}
+
+	config := &registry.Config{Host: host}
+	for _, c := range credentials {
+		if !registryHostMatches(c.Host, host) {
+			continue
+		}
+
+		config.Insecure = c.Insecure
+		if c.VaultPath == "" {
+			break
+		}
+		if c.UsernameKey == "" {
+			c.UsernameKey = "username"
+		}
+		if c.PasswordKey == "" {
+			c.PasswordKey = "password"
+		}
+
+		stim.log.Debug("Stim-Registry: Fetching the {} credentials from Vault `{}`", host, c.VaultPath)
+		vault, err := stim.NewVault()
+		if err != nil {
+			return nil, err
+		}
+		config.Username, err = vault.GetSecretKey(c.VaultPath, c.UsernameKey)
+		if err != nil {
+			return nil, fmt.Errorf("Stim-Registry: error getting the %s username from Vault: %v", host, err)
+		}
+		config.Password, err = vault.GetSecretKey(c.VaultPath, c.PasswordKey)
+		if err != nil {
+			return nil, fmt.Errorf("Stim-Registry: error getting the %s password from Vault: %v", host, err)
+		}
+		break
+	}
+
+	// ECR registries are logged in to with AWS credentials
+	if config.Username == "" {
+		if accountID, region, ok := aws.ParseECRHost(host); ok {
+			stim.log.Debug("Stim-Registry: Getting an ECR login for {}", host)
+			auth, err := stim.ECRAuthorization(accountID, region, stim.ConfigGetString("aws.ecr.account"), stim.ConfigGetString("aws.ecr.role"))
+			if err != nil {
+				return nil, fmt.Errorf("Stim-Registry: error getting the %s ECR login: %v", host, err)
+			}
+			config.Username, config.Password = auth.Username, auth.Password
+		}
+	}
+
+	return registry.New(config), nil
+}
+
+// registryHostMatches returns true if the configured host is the registry
+// host.  Docker Hub can be configured as `docker.io`.
+func registryHostMatches(configured string, host string) bool {
+	if host == registry.DockerHubHost && (configured == "docker.io" || configured == "index.docker.io") {
+		return true
+	}
+	return configured == host
+}
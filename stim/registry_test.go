@@ -0,0 +1,15 @@
+package stim
+
+import (
+	"testing"
+
+	"github.com/PremiereGlobal/stim/pkg/registry"
+	"gotest.tools/assert"
+)
+
+func TestRegistryHostMatches(t *testing.T) {
+	assert.Assert(t, registryHostMatches("artifactory.my-company.com", "artifactory.my-company.com"))
+	assert.Assert(t, !registryHostMatches("artifactory.my-company.com", "localhost:5000"))
+	assert.Assert(t, registryHostMatches("docker.io", registry.DockerHubHost))
+	assert.Assert(t, registryHostMatches(registry.DockerHubHost, registry.DockerHubHost))
+}
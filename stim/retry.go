@@ -0,0 +1,53 @@
+package stim
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/cancel"
+	"github.com/PremiereGlobal/stim/pkg/retry"
+)
+
+// RetryPolicy returns the policy for retrying calls to external services
+// from the `retry.*` config, or `--retries`.  Invalid values are logged and
+// the defaults used instead.
+func (stim *Stim) RetryPolicy() *retry.Policy {
+
+	policy := retry.DefaultPolicy()
+	policy.Clock = stim.clock
+	policy.Log = stim.log
+
+	if stim.ConfigGetString("retry.max-retries") != "" {
+		policy.MaxRetries = stim.ConfigGetInt("retry.max-retries")
+	}
+	for key, value := range map[string]*time.Duration{"retry.delay": &policy.Delay, "retry.max-delay": &policy.MaxDelay} {
+		if s := stim.ConfigGetString(key); s != "" {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				stim.log.Warn("Stim-Retry: Invalid {} '{}': {}", key, s, err)
+				continue
+			}
+			*value = d
+		}
+	}
+	if stim.ConfigGetString("retry.jitter-percent") != "" {
+		policy.Jitter = float64(stim.ConfigGetInt("retry.jitter-percent")) / 100
+	}
+	if on := stim.ConfigGetStringSlice("retry.on"); len(on) > 0 {
+		policy.RetryOn = on
+		if err := policy.Validate(); err != nil {
+			stim.log.Warn("Stim-Retry: Invalid retry.on: {}", err)
+			policy.RetryOn = nil
+		}
+	}
+
+	return policy
+}
+
+// configureRetries retries the calls of the clients that use the default
+// HTTP transport (ex. Slack, Pagerduty, Datadog and GitHub), and cancels the
+// calls in flight (AWS included) with the command.  The Vault client has its
+// own transport and AWS its own retries, they are configured when created.
+func (stim *Stim) configureRetries() {
+	http.DefaultTransport = cancel.Transport(stim.Context(), stim.RetryPolicy().Transport(http.DefaultTransport))
+}
@@ -0,0 +1,31 @@
+package stim
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/retry"
+	"gotest.tools/assert"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	stim := New()
+	policy := stim.RetryPolicy()
+	assert.Equal(t, policy.MaxRetries, retry.DefaultPolicy().MaxRetries)
+	assert.Assert(t, policy.RetryOn == nil)
+
+	stim.config.Set("retry.max-retries", 0)
+	stim.config.Set("retry.delay", "2s")
+	stim.config.Set("retry.max-delay", "soon")
+	stim.config.Set("retry.jitter-percent", 50)
+	stim.config.Set("retry.on", []string{"network", "429"})
+	policy = stim.RetryPolicy()
+	assert.Equal(t, policy.MaxRetries, 0)
+	assert.Equal(t, policy.Delay, 2*time.Second)
+	assert.Equal(t, policy.MaxDelay, retry.DefaultPolicy().MaxDelay)
+	assert.Equal(t, policy.Jitter, 0.5)
+	assert.DeepEqual(t, policy.RetryOn, []string{"network", "429"})
+
+	stim.config.Set("retry.on", []string{"4xx"})
+	assert.Assert(t, stim.RetryPolicy().RetryOn == nil)
+}
@@ -8,6 +8,31 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// AnnotationStderrLogs marks a command whose output is read by other programs
+// (ex. exec credential plugins).  Its logs are written to stderr instead of
+// stdout.
+//
+//	cmd.Annotations = map[string]string{stim.AnnotationStderrLogs: "true"}
+const AnnotationStderrLogs = "stim.stderr-logs"
+
+// AnnotationInformational marks a command that only prints information about
+// stim itself (ex. `version`, `completion`).  Informational commands, help
+// and shell completions are not audited and don't check for updates, so they
+// start quickly and never touch the network.
+//
+//	cmd.Annotations = map[string]string{stim.AnnotationInformational: "true"}
+const AnnotationInformational = "stim.informational"
+
+// isInformational returns true for help, shell completions and the commands
+// marked as informational
+func isInformational(cmd *cobra.Command) bool {
+	if cmd.Annotations[AnnotationInformational] == "true" || cmd.Name() == "help" || cmd.Name() == completionValuesCommand {
+		return true
+	}
+	help, _ := cmd.Flags().GetBool("help")
+	return help
+}
+
 func (stim *Stim) initRootCommand() {
 
 	var cmd = &cobra.Command{
@@ -26,17 +51,59 @@ func (stim *Stim) initRootCommand() {
 	stim.config.BindPFlag("cache-path", cmd.PersistentFlags().Lookup("cache-path"))
 	cmd.PersistentFlags().String("config", "", "Path to an explicit config file (defaults to ${STIM_PATH}/config.yaml)")
 	stim.config.BindPFlag("config-file", cmd.PersistentFlags().Lookup("config"))
+	cmd.PersistentFlags().String("profile", "", "Name of the profile in the stim config to use (defaults to current-profile in the config)")
+	stim.config.BindPFlag("profile", cmd.PersistentFlags().Lookup("profile"))
 	cmd.PersistentFlags().BoolP("verbose", "v", false, "verbose output")
 	stim.config.BindPFlag("verbose", cmd.PersistentFlags().Lookup("verbose"))
+	cmd.PersistentFlags().String("log-level", "", "Log level (trace, debug, verbose, info, warn or error).  Overrides --verbose")
+	stim.config.BindPFlag("logging.level", cmd.PersistentFlags().Lookup("log-level"))
+	cmd.PersistentFlags().String("log-format", "", "Log format (console or json)")
+	stim.config.BindPFlag("logging.format", cmd.PersistentFlags().Lookup("log-format"))
 	cmd.PersistentFlags().BoolP("noprompt", "x", false, "Do not prompt for input. Will default to true for Jenkin builds.")
 	stim.config.BindPFlag("noprompt", cmd.PersistentFlags().Lookup("noprompt"))
-	cmd.PersistentFlags().StringP("auth-method", "", "", "Default authentication method (ex: ldap, github, etc.)")
-	stim.config.BindPFlag("auth.method", cmd.PersistentFlags().Lookup("auth-method"))
+	cmd.PersistentFlags().StringP("auth-method", "", "", "Vault authentication method (ldap, userpass, oidc, jwt, approle, kubernetes or token)")
+	stim.config.BindPFlag("vault.auth-method", cmd.PersistentFlags().Lookup("auth-method"))
+	cmd.PersistentFlags().String("auth-path", "", "Mount path of the Vault auth method (defaults to the auth method name)")
+	stim.config.BindPFlag("vault.auth-path", cmd.PersistentFlags().Lookup("auth-path"))
+	cmd.PersistentFlags().String("vault-role", "", "Vault role to log in with (oidc, jwt and kubernetes auth methods)")
+	stim.config.BindPFlag("vault.role", cmd.PersistentFlags().Lookup("vault-role"))
+	cmd.PersistentFlags().String("role-id", "", "Vault AppRole role ID (approle auth method)")
+	stim.config.BindPFlag("vault.role-id", cmd.PersistentFlags().Lookup("role-id"))
+	stim.config.BindEnv("vault.secret-id", "STIM_VAULT_SECRET_ID")
+	cmd.PersistentFlags().String("jwt-path", "", "Path to the JWT (jwt auth method) or service account token (kubernetes auth method)")
+	stim.config.BindPFlag("vault.jwt-path", cmd.PersistentFlags().Lookup("jwt-path"))
+	stim.config.BindEnv("vault.jwt", "STIM_VAULT_JWT")
+	stim.config.BindEnv("credentials.passphrase", "STIM_CREDENTIALS_PASSPHRASE")
+	stim.config.BindEnv("org-config.url", "STIM_ORG_CONFIG_URL")
+	stim.config.BindEnv("org-config.public-key", "STIM_ORG_CONFIG_PUBLIC_KEY")
+	stim.config.BindEnv("tracing.endpoint", "STIM_TRACING_ENDPOINT", otlpEndpointEnv)
+	cmd.PersistentFlags().Int("oidc-port", 0, "Local port for the Vault OIDC callback listener (oidc auth method, default 8250)")
+	stim.config.BindPFlag("vault.oidc-callback-port", cmd.PersistentFlags().Lookup("oidc-port"))
 	cmd.PersistentFlags().BoolP("is-automated", "", false, "Error on anything that needs to prompt and was not passed in as an ENV var or command flag")
 	stim.config.BindPFlag("is-automated", cmd.PersistentFlags().Lookup("is-automated"))
+	cmd.PersistentFlags().Bool("utc", false, "Show timestamps in UTC instead of the local time zone")
+	stim.config.BindPFlag("utc", cmd.PersistentFlags().Lookup("utc"))
+	cmd.PersistentFlags().Bool("timings", false, "Print the time spent in each phase (ex. Vault login, secret fetching, tool downloads) after the command")
+	stim.config.BindPFlag("timings", cmd.PersistentFlags().Lookup("timings"))
+	cmd.PersistentFlags().Int("retries", 2, "Number of times to retry calls to external services (ex. Vault, Slack, Pagerduty, AWS) that fail with transient errors, 0 to never retry")
+	stim.config.BindPFlag("retry.max-retries", cmd.PersistentFlags().Lookup("retries"))
+	cmd.PersistentFlags().String("timeout", "", "Cancel the command (ex. a deploy) if it runs longer than this (ex. 15m).  The command cleans up and exits with code 7")
+	stim.config.BindPFlag("timeout", cmd.PersistentFlags().Lookup("timeout"))
 
 	// Set some defaults
 	stim.config.SetDefault("vault-timeout", 15)
+	stim.config.SetDefault("retry.max-retries", 2)
+
+	cmd.BashCompletionFunction = bashCompletionFunction
+	cmd.AddCommand(stim.completionCommand())
+
+	// The config is loaded before showing help so that read-only mode can hide
+	// the mutating commands
+	help := cmd.HelpFunc()
+	cmd.SetHelpFunc(func(c *cobra.Command, args []string) {
+		stim.commandInit()
+		help(c, args)
+	})
 
 	stim.rootCmd = cmd
 }
@@ -0,0 +1,94 @@
+package stim
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// selectionsFile is the cache file (in the `prompts` cache directory) of the
+// last selections of the remembered prompts, by repo
+const selectionsFile = "selections.yaml"
+
+// RememberedSelection returns the last selection saved under the key for the
+// current repo (the git repo of the working directory, or the directory
+// itself outside of a repo)
+func (stim *Stim) RememberedSelection(key string) []string {
+
+	path := filepath.Join(stim.ConfigGetCacheDir("prompts"), selectionsFile)
+	selections, err := readSelections(path)
+	if err != nil {
+		stim.log.Debug("Unable to read {}: {}", path, err)
+	}
+
+	return selections[repoRoot()][key]
+}
+
+// RememberSelection saves the selection under the key for the current repo.
+// Errors are only logged at debug since remembering is a convenience.
+func (stim *Stim) RememberSelection(key string, selection []string) {
+
+	path := filepath.Join(stim.ConfigGetCacheDir("prompts"), selectionsFile)
+	selections, err := readSelections(path)
+	if err != nil {
+		stim.log.Debug("Unable to read {}: {}", path, err)
+		selections = make(map[string]map[string][]string)
+	}
+
+	root := repoRoot()
+	if selections[root] == nil {
+		selections[root] = make(map[string][]string)
+	}
+	selections[root][key] = selection
+
+	b, err := yaml.Marshal(selections)
+	if err == nil {
+		err = ioutil.WriteFile(path, b, 0600)
+	}
+	if err != nil {
+		stim.log.Debug("Unable to write {}: {}", path, err)
+	}
+}
+
+// readSelections reads the selections file
+func readSelections(path string) (map[string]map[string][]string, error) {
+
+	selections := make(map[string]map[string][]string)
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return selections, nil
+	}
+	if err != nil {
+		return selections, err
+	}
+
+	err = yaml.Unmarshal(b, &selections)
+	return selections, err
+}
+
+// repoRoot returns the root of the git repo of the working directory, or the
+// working directory if it isn't in a repo
+func repoRoot() string {
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+
+	return findRepoRoot(wd)
+}
+
+// findRepoRoot returns the closest parent of the directory with a `.git`
+// directory (or file, for worktrees and submodules), or the directory itself
+func findRepoRoot(dir string) string {
+	for current := dir; ; current = filepath.Dir(current) {
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			return current
+		}
+		if filepath.Dir(current) == current {
+			return dir
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package stim
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestRememberSelection(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stim-selections")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	stim := New()
+	stim.config.Set("cache-path", dir)
+
+	assert.Equal(t, len(stim.RememberedSelection("deploy/api/environment")), 0)
+
+	stim.RememberSelection("deploy/api/environment", []string{"prod"})
+	stim.RememberSelection("deploy/api/instances/prod", []string{"us-east-1", "us-west-2"})
+	stim.RememberSelection("deploy/api/environment", []string{"stage"})
+
+	assert.DeepEqual(t, stim.RememberedSelection("deploy/api/environment"), []string{"stage"})
+	assert.DeepEqual(t, stim.RememberedSelection("deploy/api/instances/prod"), []string{"us-east-1", "us-west-2"})
+
+	info, err := os.Stat(filepath.Join(dir, "prompts", selectionsFile))
+	assert.NilError(t, err)
+	assert.Equal(t, info.Mode().Perm(), os.FileMode(0600))
+}
+
+func TestFindRepoRoot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stim-repo")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	repo := filepath.Join(dir, "repo")
+	services := filepath.Join(repo, "services", "api")
+	assert.NilError(t, os.MkdirAll(filepath.Join(repo, ".git"), 0700))
+	assert.NilError(t, os.MkdirAll(services, 0700))
+
+	assert.Equal(t, findRepoRoot(services), repo)
+	assert.Equal(t, findRepoRoot(repo), repo)
+	assert.Equal(t, findRepoRoot(dir), dir)
+}
@@ -1,22 +1,127 @@
 package stim
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
 	"github.com/PremiereGlobal/stim/pkg/slack"
+	"gopkg.in/yaml.v2"
 )
 
+// slackTokensCacheFile is the cache file (in the `slack` cache directory) of
+// the tokens of `stim slack auth`, by workspace
+const slackTokensCacheFile = "tokens.yaml"
+
+// slackTokenCredentialKey is the credential store key prefix of the token of
+// a workspace
+const slackTokenCredentialKey = "slack-token-"
+
 func (stim *Stim) Slack() *slack.Slack {
+	s, err := stim.NewSlack()
+	if err != nil {
+		stim.Fatal(err)
+	}
+
+	return s
+}
+
+// NewSlack is the same as Slack but returns an error instead of exiting if
+// the Slack token can't be read.  The token of the `slack.workspace`
+// workspace from `stim slack auth` is used if set, otherwise the stimbot
+// token from Vault.
+func (stim *Stim) NewSlack() (*slack.Slack, error) {
 	stim.log.Debug("Stim-Slack: Creating")
 
-	vault := stim.Vault()
-	token, err := vault.GetSecretKey("secret/slack/stimbot", "apikey")
-	if err != nil {
-		stim.log.Fatal(err)
+	var token string
+	if workspace := stim.ConfigGetString("slack.workspace"); workspace != "" {
+		var err error
+		token, err = stim.SlackToken(workspace)
+		if err != nil {
+			return nil, AuthError(err)
+		}
+		if token == "" {
+			return nil, AuthError(fmt.Errorf("Stim-Slack: No token for workspace '%s', run `stim slack auth --workspace %s`", workspace, workspace))
+		}
+	} else {
+		vault, err := stim.NewVault()
+		if err != nil {
+			return nil, AuthError(err)
+		}
+		token, err = vault.GetSecretKey("secret/slack/stimbot", "apikey")
+		if err != nil {
+			return nil, AuthError(err)
+		}
 	}
 
 	s, err := slack.New(&slack.Config{Token: token, Log: stim.log})
 	if err != nil {
-		stim.log.Fatal("Stim-Slack: Error Initializaing: ", err)
+		return nil, AuthError(fmt.Errorf("Stim-Slack: Error Initializaing: %v", err))
 	}
 
-	return s
+	return s, nil
+}
+
+// SlackToken returns the token of the workspace saved by `stim slack auth`,
+// or an empty string if there is none
+func (stim *Stim) SlackToken(workspace string) (string, error) {
+
+	store, err := stim.Credentials()
+	if err != nil {
+		return "", err
+	}
+	if store != nil {
+		return store.Get(slackTokenCredentialKey + workspace)
+	}
+
+	tokens, err := readSlackTokens(filepath.Join(stim.ConfigGetCacheDir("slack"), slackTokensCacheFile))
+	if err != nil {
+		return "", err
+	}
+	return tokens[workspace], nil
+}
+
+// SaveSlackToken saves the token of the workspace for the next commands
+func (stim *Stim) SaveSlackToken(workspace string, token string) error {
+
+	store, err := stim.Credentials()
+	if err != nil {
+		return err
+	}
+	if store != nil {
+		return store.Set(slackTokenCredentialKey+workspace, token)
+	}
+
+	path := filepath.Join(stim.ConfigGetCacheDir("slack"), slackTokensCacheFile)
+	tokens, err := readSlackTokens(path)
+	if err != nil {
+		return err
+	}
+	tokens[workspace] = token
+
+	b, err := yaml.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0600)
+}
+
+// readSlackTokens reads the tokens cache file
+func readSlackTokens(path string) (map[string]string, error) {
+
+	tokens := make(map[string]string)
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return tokens, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(b, &tokens)
+	if err != nil {
+		return nil, fmt.Errorf("Stim-Slack: Invalid token cache: %v", err)
+	}
+	return tokens, nil
 }
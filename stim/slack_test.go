@@ -0,0 +1,41 @@
+package stim
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestSaveSlackToken(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stim-slack")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	stim := New()
+	stim.config.Set("cache-path", dir)
+
+	token, err := stim.SlackToken("acme")
+	assert.NilError(t, err)
+	assert.Equal(t, token, "")
+
+	assert.NilError(t, stim.SaveSlackToken("acme", "xoxp-1"))
+	assert.NilError(t, stim.SaveSlackToken("acme-dev", "xoxp-2"))
+
+	token, err = stim.SlackToken("acme")
+	assert.NilError(t, err)
+	assert.Equal(t, token, "xoxp-1")
+	token, err = stim.SlackToken("acme-dev")
+	assert.NilError(t, err)
+	assert.Equal(t, token, "xoxp-2")
+
+	info, err := os.Stat(filepath.Join(dir, "slack", slackTokensCacheFile))
+	assert.NilError(t, err)
+	assert.Equal(t, info.Mode().Perm(), os.FileMode(0600))
+
+	stim.config.Set("slack.workspace", "other")
+	_, err = stim.NewSlack()
+	assert.ErrorContains(t, err, "run `stim slack auth --workspace other`")
+}
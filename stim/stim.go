@@ -1,12 +1,24 @@
 package stim
 
 import (
+	"math/rand"
 	"os"
 	"os/user"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/PremiereGlobal/stim/pkg/bom"
+	"github.com/PremiereGlobal/stim/pkg/certs"
+	"github.com/PremiereGlobal/stim/pkg/clock"
+	"github.com/PremiereGlobal/stim/pkg/credentials"
+	"github.com/PremiereGlobal/stim/pkg/i18n"
+	"github.com/PremiereGlobal/stim/pkg/metrics"
+	"github.com/PremiereGlobal/stim/pkg/notify"
+	"github.com/PremiereGlobal/stim/pkg/orgconfig"
 	"github.com/PremiereGlobal/stim/pkg/stimlog"
+	"github.com/PremiereGlobal/stim/pkg/tracing"
 	"github.com/PremiereGlobal/stim/pkg/vault"
 	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
@@ -29,17 +41,50 @@ type Stim struct {
 	rootCmd   *cobra.Command
 	log       stimlog.StimLogger
 	logConfig stimlog.StimLoggerConfig
-	stimpacks []*Stimpack
+	stimpacks []loadedStimpack
 	vault     *vault.Vault
+	notifier  *notify.Router
+	clock     clock.Clock
+
+	// cancellation cancels the command on Ctrl-C or --timeout
+	cancellation *cancellation
+	rand         *rand.Rand
+	bom          *bom.Recorder
+	localizer    *i18n.Localizer
+	orgConfig    *orgconfig.Overlay
+	timer        *metrics.Timer
+	tracer       *tracing.Tracer
+
+	// credentials is the credential store, nil until first used or for the
+	// plaintext store
+	credentials credentials.Store
+
+	// spans are the root span and the open spans of the trace, innermost last
+	spans   []*tracing.Span
+	traceMu sync.Mutex
+
+	// completions are the dynamic completion values of flags by name
+	completions map[string]CompletionFunc
+
+	initialized     bool
+	offline         bool
+	audited         bool
+	metricsReported bool
+	startTime       time.Time
 }
 
-//New gets the Stim struct, which is treated like a singleton so you will get the same one
-//as everywhere when this is called
+// New gets the Stim struct, which is treated like a singleton so you will get the same one
+// as everywhere when this is called
 func New() *Stim {
 	stim := &Stim{}
 	stim.log = stimlog.GetLogger()
 	stim.logConfig = stimlog.GetLoggerConfig()
 	stim.logConfig.ForceFlush(true)
+	stim.clock = clock.New()
+	stim.cancellation = newCancellation()
+	stim.timer = metrics.NewTimer(stim.clock)
+	stim.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	stim.bom = bom.NewRecorder()
 	stim.config = viper.New()
 	stim.config.SetEnvPrefix("stim")
 	stim.config.AutomaticEnv()
@@ -48,20 +93,100 @@ func New() *Stim {
 	return stim
 }
 
-//GetLogger for Stim
+// GetLogger for Stim
 func (stim *Stim) GetLogger() stimlog.StimLogger {
 	return stim.log
 }
 
 func (stim *Stim) Execute() {
 	defer stimlog.GetLoggerConfig().Flush()
+	stim.startTime = stim.clock.Now()
 	cobra.OnInitialize(stim.commandInit)
-	err := stim.rootCmd.Execute()
+	stim.initUsageErrors()
+	stim.handleInterrupts()
+	cmd, err := stim.rootCmd.ExecuteC()
+	err = stim.cancelledError(err)
+
+	// Dynamic secrets aren't left behind, even if the command fails
+	stim.revokeLeases()
+	if err == nil {
+		stim.audit("")
+		stim.reportMetrics("")
+		stim.checkForUpdate(cmd)
+		return
+	}
+	if ExitCode(err) == ExitCodeUsage {
+		cmd.Usage()
+	}
+	// Failures are audited before exiting
 	stim.Fatal(err)
 }
 
 func (stim *Stim) commandInit() {
 
+	// Help is shown before cobra runs the initializers, so the help function
+	// may have already initialized
+	if stim.initialized {
+		return
+	}
+
+	if cmd, _, err := stim.rootCmd.Find(os.Args[1:]); err == nil {
+		// Commands whose output is read by other programs log to stderr
+		if cmd.Annotations[AnnotationStderrLogs] == "true" {
+			stim.logConfig.RemoveLogFile("STDOUT")
+			stim.logConfig.AddLogFile("STDERR", stimlog.DefaultLevel)
+		}
+
+		// Informational commands never touch the network
+		stim.offline = isInformational(cmd)
+
+		// Messages are tagged with the stimpack of the command in structured
+		// log formats
+		if name := stim.commandStimpack(cmd); name != "" {
+			stim.logConfig.SetComponent(name)
+		}
+	}
+
+	err := stim.Init()
+	if err != nil {
+		stim.Fatal(err)
+	}
+
+	// Cancel the command once it times out
+	err = stim.startTimeout()
+	if err != nil {
+		stim.Fatal(err)
+	}
+
+	// Audit the command, including when it fails
+	stim.auditInit()
+
+	// Report the timings of the command, including when it fails
+	stim.metricsInit()
+
+	// Trace the command when tracing is configured
+	if cmd, _, err := stim.rootCmd.Find(os.Args[1:]); err == nil {
+		stim.tracingInit(cmd)
+	}
+
+	// Hide and guard the mutating commands in read-only mode
+	stim.applyReadOnly()
+
+	// Hide and guard the commands of disabled stimpacks
+	stim.applyDisabledStimpacks()
+}
+
+// Init loads the config file and active profile and sets up logging.  It is
+// run before every command and must be called by programs that use stim as a
+// library before using it.  Init only runs once.
+func (stim *Stim) Init() error {
+
+	if stim.initialized {
+		return nil
+	}
+	stim.initialized = true
+	defer stim.Time(PhaseConfig)()
+
 	// Here we need to process certain config variables as this is the first time we have
 	// access to them, including command line flags.
 	// Particularly needed around flags that could change global pathing
@@ -80,7 +205,16 @@ func (stim *Stim) commandInit() {
 	}
 
 	// Load a config file (if present)
-	stim.configLoadConfigFile()
+	err := stim.configLoadConfigFile()
+	if err != nil {
+		return ConfigError(err)
+	}
+
+	// Apply the active profile over the config file values
+	err = stim.configApplyProfile()
+	if err != nil {
+		return ConfigError(err)
+	}
 
 	// Now that we've loaded the config file, do one final check (in case path was set in the file)
 	// If not set, use the basePath
@@ -92,36 +226,32 @@ func (stim *Stim) commandInit() {
 		stim.config.Set("cache-path", filepath.Join(stim.config.GetString("path"), "cache"))
 	}
 
-	if !stim.ConfigGetBool("logging.file.disable") {
-		lfp := stim.ConfigGetString("logging.file.path")
-		if lfp == "" {
-			sh, err := stim.ConfigGetStimConfigDir()
-			if err != nil {
-				stim.log.Warn("Could not find stim config dir path, not creating log file")
-			} else {
-				lfp = filepath.Join(sh, "stim.log")
-			}
-		}
-		if lfp != "" {
-			stim.logConfig.AddLogFile(lfp, stimlog.DebugLevel)
-		}
-	}
-	// Set log level, this is done as early as possible so we can start using it
-	if stim.ConfigGetBool("verbose") == true {
-		stim.logConfig.SetLevel(stimlog.DebugLevel)
-		stim.log.Debug("Stim version: {}", version)
-		stim.log.Debug("Debug log level set")
-	} else {
-		// Set the default log level
-		stim.logConfig.SetLevel(stimlog.InfoLevel)
+	// Set up logging, this is done as early as possible so we can start using it
+	err = stim.configureLogging()
+	if err != nil {
+		return ConfigError(err)
 	}
 	if stim.IsAutomated() {
 		stim.log.Info("Running in automated way")
 	}
 
+	// Use the embedded CA roots of static builds if the system has none
+	certs.Configure()
+	if certs.UsingEmbedded() {
+		stim.log.Debug("No system CA roots found, using the embedded CA roots")
+	}
+
+	// Layer the org config under the user config
+	stim.configApplyOrgConfig()
+
+	// Retry transient failures of external calls
+	stim.configureRetries()
+
 	stim.log.Debug("STIM_CONFIG_FILE: {}", stim.config.Get("config-file"))
 	stim.log.Debug("STIM_PATH: {}", stim.config.Get("path"))
 	stim.log.Debug("STIM_CACHE_PATH: {}", stim.config.Get("cache-path"))
+
+	return nil
 }
 
 func (stim *Stim) BindCommand(command *cobra.Command, parentCommand *cobra.Command) {
@@ -141,8 +271,7 @@ func (stim *Stim) User() (string, error) {
 // This username will be the default option when authenticating against Vault
 func (stim *Stim) UpdateVaultUser(username string) error {
 	if username != stim.ConfigGetString("vault-username") {
-		stim.ConfigSetString("vault-username", username)
-		err := stim.ConfigSetRaw("vault-username", username)
+		err := stim.ConfigSetString("vault-username", username)
 		if err != nil {
 			return err
 		}
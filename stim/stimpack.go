@@ -1,21 +1,81 @@
 package stim
 
 import (
+	"fmt"
+
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
-// This is the interface for stimpacks
+// This is the interface for stimpacks.  Command should only build the cobra
+// commands, integrations (ex. Vault, Pagerduty) are set up through stim when a
+// command uses them so that startup doesn't pay for them.
 type Stimpack interface {
 	Command(*viper.Viper) *cobra.Command
 	Name() string
 	BindStim(*Stim)
 }
 
+// loadedStimpack is a stimpack and the command it added
+type loadedStimpack struct {
+	stimpack Stimpack
+	cmd      *cobra.Command
+}
+
 func (stim *Stim) AddStimpack(s Stimpack) {
 
 	stim.log.Debug("Loading stimpack `", s.Name(), "`")
 	s.BindStim(stim)
 	cmd := s.Command(stim.config)
 	stim.rootCmd.AddCommand(cmd)
+	stim.stimpacks = append(stim.stimpacks, loadedStimpack{stimpack: s, cmd: cmd})
+}
+
+// IsStimpackEnabled returns false if the stimpack is turned off with the
+// `stimpacks.<name>.enabled` config option.  Stimpacks are enabled unless set.
+func (stim *Stim) IsStimpackEnabled(name string) bool {
+	key := "stimpacks." + name + ".enabled"
+	return !stim.config.IsSet(key) || stim.ConfigGetBool(key)
+}
+
+// applyDisabledStimpacks hides the commands of disabled stimpacks and guards
+// them so they refuse to run
+func (stim *Stim) applyDisabledStimpacks() {
+
+	for _, s := range stim.stimpacks {
+		name := s.stimpack.Name()
+		if stim.IsStimpackEnabled(name) {
+			continue
+		}
+		stim.log.Debug("Stimpack `{}` is disabled", name)
+
+		guard := func(cmd *cobra.Command, args []string) error {
+			return ConfigError(fmt.Errorf("`%s` is disabled by the `stimpacks.%s.enabled` config option", cmd.CommandPath(), name))
+		}
+
+		var walk func(cmd *cobra.Command)
+		walk = func(cmd *cobra.Command) {
+			cmd.PreRunE = guard
+			for _, sub := range cmd.Commands() {
+				walk(sub)
+			}
+		}
+		walk(s.cmd)
+		s.cmd.Hidden = true
+	}
+}
+
+// commandStimpack returns the name of the stimpack that added the command,
+// or an empty string for the commands of stim itself
+func (stim *Stim) commandStimpack(cmd *cobra.Command) string {
+
+	for cmd.HasParent() && cmd.Parent() != stim.rootCmd {
+		cmd = cmd.Parent()
+	}
+	for _, s := range stim.stimpacks {
+		if s.cmd == cmd {
+			return s.stimpack.Name()
+		}
+	}
+	return ""
 }
@@ -0,0 +1,48 @@
+package stim
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gotest.tools/assert"
+)
+
+type testStimpack struct {
+	name string
+	cmd  *cobra.Command
+}
+
+func (s *testStimpack) Command(*viper.Viper) *cobra.Command { return s.cmd }
+func (s *testStimpack) Name() string                        { return s.name }
+func (s *testStimpack) BindStim(*Stim)                      {}
+
+func TestApplyDisabledStimpacks(t *testing.T) {
+	stim := New()
+	stim.config.Set("stimpacks.pagerduty.enabled", false)
+	stim.config.Set("stimpacks.slack.enabled", true)
+
+	pagerduty := &cobra.Command{Use: "pagerduty"}
+	oncall := &cobra.Command{Use: "oncall"}
+	pagerduty.AddCommand(oncall)
+	slack := &cobra.Command{Use: "slack"}
+	kube := &cobra.Command{Use: "kube"}
+	stim.AddStimpack(&testStimpack{name: "pagerduty", cmd: pagerduty})
+	stim.AddStimpack(&testStimpack{name: "slack", cmd: slack})
+	stim.AddStimpack(&testStimpack{name: "kubernetes", cmd: kube})
+
+	stim.applyDisabledStimpacks()
+
+	assert.Assert(t, !stim.IsStimpackEnabled("pagerduty"))
+	assert.Assert(t, stim.IsStimpackEnabled("slack"))
+	assert.Assert(t, stim.IsStimpackEnabled("kubernetes"))
+
+	assert.Assert(t, pagerduty.Hidden)
+	err := oncall.PreRunE(oncall, nil)
+	assert.Error(t, err, "`stim pagerduty oncall` is disabled by the `stimpacks.pagerduty.enabled` config option")
+	assert.Equal(t, ExitCode(err), ExitCodeConfig)
+
+	assert.Assert(t, !slack.Hidden)
+	assert.Assert(t, slack.PreRunE == nil)
+	assert.Assert(t, !kube.Hidden)
+}
@@ -0,0 +1,14 @@
+package stimtest_test
+
+import (
+	log "github.com/PremiereGlobal/stim/pkg/stimlog"
+	"github.com/PremiereGlobal/stim/stim"
+	"github.com/PremiereGlobal/stim/stim/stimtest"
+)
+
+var (
+	_ stim.ConfigReader = stimtest.Config{}
+	_ stim.VaultClient  = &stimtest.Vault{}
+	_ stim.Prompter     = &stimtest.Prompter{}
+	_ log.StimLogger    = &stimtest.Logger{}
+)
@@ -0,0 +1,374 @@
+// Package stimtest provides fakes of the stim config, Vault client, logger and
+// prompts so that stimpack logic written against the interfaces of the stim
+// package can be unit tested without a stim config file, Vault server or
+// terminal.
+package stimtest
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/PremiereGlobal/stim/pkg/stimlog"
+	"github.com/PremiereGlobal/stim/pkg/vault"
+)
+
+// Config is a fake stim config of option values by key.  Values can be set
+// with their type or as strings, like in the stim config file.
+type Config map[string]interface{}
+
+// ConfigGetString returns the option as a string
+func (c Config) ConfigGetString(key string) string {
+	if c[key] == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", c[key])
+}
+
+// ConfigGetInt returns the option as an int, 0 if it isn't a number
+func (c Config) ConfigGetInt(key string) int {
+	if i, ok := c[key].(int); ok {
+		return i
+	}
+	i, _ := strconv.Atoi(c.ConfigGetString(key))
+	return i
+}
+
+// ConfigGetBool returns the option as a bool
+func (c Config) ConfigGetBool(key string) bool {
+	if b, ok := c[key].(bool); ok {
+		return b
+	}
+	b, _ := strconv.ParseBool(c.ConfigGetString(key))
+	return b
+}
+
+// ConfigGetStringSlice returns the option as a list of strings.  A string is
+// split on spaces.
+func (c Config) ConfigGetStringSlice(key string) []string {
+	switch value := c[key].(type) {
+	case nil:
+		return []string{}
+	case []string:
+		return value
+	case []interface{}:
+		list := make([]string, len(value))
+		for i, v := range value {
+			list[i] = fmt.Sprintf("%v", v)
+		}
+		return list
+	default:
+		return strings.Fields(c.ConfigGetString(key))
+	}
+}
+
+// Vault is a fake Vault client with the secrets in memory
+type Vault struct {
+	Token   string
+	Address string
+
+	// Secrets are the keys and values of each secret by path
+	Secrets map[string]map[string]string
+}
+
+// GetToken returns the fake token
+func (v *Vault) GetToken() (string, error) {
+	if v.Token == "" {
+		return "", fmt.Errorf("Vault: No token")
+	}
+	return v.Token, nil
+}
+
+// GetAddress returns the fake address
+func (v *Vault) GetAddress() (string, error) {
+	return v.Address, nil
+}
+
+// GetSecretKey returns the value of a key of a secret
+func (v *Vault) GetSecretKey(path string, key string) (string, error) {
+	secret, err := v.GetSecretKeys(path)
+	if err != nil {
+		return "", err
+	}
+	value, ok := secret[key]
+	if !ok {
+		return "", fmt.Errorf("Vault: Could not find key `%s` for secret `%s`", key, path)
+	}
+	return value, nil
+}
+
+// GetSecretKeys returns a copy of the keys and values of a secret
+func (v *Vault) GetSecretKeys(path string) (map[string]string, error) {
+	secret, ok := v.Secrets[path]
+	if !ok {
+		return nil, notFound("Could not find secret `%s`", path)
+	}
+	keys := make(map[string]string, len(secret))
+	for key, value := range secret {
+		keys[key] = value
+	}
+	return keys, nil
+}
+
+// ListSecrets returns the names of the secrets and directories directly
+// under the path
+func (v *Vault) ListSecrets(path string) ([]string, error) {
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	seen := make(map[string]bool)
+	var names []string
+	for secretPath := range v.Secrets {
+		if !strings.HasPrefix(secretPath, prefix) {
+			continue
+		}
+		name := strings.SplitN(strings.TrimPrefix(secretPath, prefix), "/", 2)[0]
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, notFound("Could not find secret `%s`", path)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// WriteSecretKeys replaces the keys and values of a secret with a copy of
+// keys
+func (v *Vault) WriteSecretKeys(path string, keys map[string]string) error {
+	if v.Secrets == nil {
+		v.Secrets = make(map[string]map[string]string)
+	}
+	secret := make(map[string]string, len(keys))
+	for key, value := range keys {
+		secret[key] = value
+	}
+	v.Secrets[path] = secret
+	return nil
+}
+
+// notFound returns an error of the Vault package for a missing secret, so
+// that vault.IsNotFound can be used on the errors of the fake
+func notFound(format string, args ...interface{}) error {
+	return &vault.CustomVaultError{MessageParts: []string{fmt.Sprintf(format, args...)}, OriginalError: vault.ErrNotFound}
+}
+
+// Entry is a message logged to the fake logger
+type Entry struct {
+	Level   log.Level
+	Message string
+}
+
+// Logger is a fake logger that keeps the messages logged to it.  `{}`
+// placeholders are replaced the same way as the stim logger.  Fatal and Exit
+// panic after logging, since the stim logger exits.
+type Logger struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// Entries returns the messages logged at the level or a more severe level
+func (l *Logger) Entries(level log.Level) []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var entries []Entry
+	for _, e := range l.entries {
+		if e.Level <= level {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Messages returns the text of the messages logged at the level or a more
+// severe level
+func (l *Logger) Messages(level log.Level) []string {
+	var messages []string
+	for _, e := range l.Entries(level) {
+		messages = append(messages, e.Message)
+	}
+	return messages
+}
+
+func (l *Logger) log(level log.Level, message ...interface{}) string {
+	text := messageText(message...)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, Entry{Level: level, Message: text})
+	return text
+}
+
+// Trace logs a message at the trace level
+func (l *Logger) Trace(message ...interface{}) { l.log(log.TraceLevel, message...) }
+
+// Debug logs a message at the debug level
+func (l *Logger) Debug(message ...interface{}) { l.log(log.DebugLevel, message...) }
+
+// Verbose logs a message at the verbose level
+func (l *Logger) Verbose(message ...interface{}) { l.log(log.VerboseLevel, message...) }
+
+// Info logs a message at the info level
+func (l *Logger) Info(message ...interface{}) { l.log(log.InfoLevel, message...) }
+
+// Warn logs a message at the warn level
+func (l *Logger) Warn(message ...interface{}) { l.log(log.WarnLevel, message...) }
+
+// Fatal logs a message at the fatal level and panics
+func (l *Logger) Fatal(message ...interface{}) {
+	panic("stimtest: fatal: " + l.log(log.FatalLevel, message...))
+}
+
+// Exit logs a message at the fatal level and panics with the exit code
+func (l *Logger) Exit(code int, message ...interface{}) {
+	panic(fmt.Sprintf("stimtest: exit %d: %s", code, l.log(log.FatalLevel, message...)))
+}
+
+// GetLogLevel returns the trace level as every message is kept
+func (l *Logger) GetLogLevel() log.Level {
+	return log.TraceLevel
+}
+
+// messageText returns the message with its {} placeholders replaced by the
+// arguments
+func messageText(message ...interface{}) string {
+	if len(message) == 0 {
+		return ""
+	}
+	format := fmt.Sprintf("%v", message[0])
+	args := message[1:]
+
+	var sb strings.Builder
+	for i, part := range strings.Split(format, "{}") {
+		sb.WriteString(part)
+		if i < len(args) {
+			sb.WriteString(fmt.Sprintf("%v", args[i]))
+		}
+	}
+	return sb.String()
+}
+
+// Prompter is a fake of the stim prompts that answers from a script.
+// Prompts with an override return it without being asked, like the stim
+// prompts.
+type Prompter struct {
+
+	// Answers are the answers to the prompts by label.  An empty answer
+	// takes the default value.  Yes/no prompts are answered with `y` or `n`.
+	Answers map[string]string
+
+	// Asked are the labels of the prompts that were asked, in order
+	Asked []string
+
+	// Remembered are the selections of the remembered prompts by key, which
+	// can also be set as the last selections
+	Remembered map[string][]string
+}
+
+// answer returns the scripted answer of a prompt
+func (p *Prompter) answer(label string) (string, error) {
+	p.Asked = append(p.Asked, label)
+	answer, ok := p.Answers[label]
+	if !ok {
+		return "", fmt.Errorf("stimtest: no answer for the prompt '%s'", label)
+	}
+	return answer, nil
+}
+
+// PromptBool answers a yes/no prompt
+func (p *Prompter) PromptBool(label string, override bool, defaultvalue bool) (bool, error) {
+	if override {
+		return true, nil
+	}
+	answer, err := p.answer(label)
+	if err != nil {
+		return false, err
+	}
+	if answer == "" {
+		return defaultvalue, nil
+	}
+	return strings.HasPrefix(strings.ToLower(answer), "y"), nil
+}
+
+// PromptString answers a text prompt
+func (p *Prompter) PromptString(label string, defaultvalue string) (string, error) {
+	answer, err := p.answer(label)
+	if err != nil {
+		return "", err
+	}
+	if answer == "" {
+		return defaultvalue, nil
+	}
+	return answer, nil
+}
+
+// PromptList answers a list prompt.  The answer must be in the list.
+func (p *Prompter) PromptList(label string, list []string, override string) (string, error) {
+	if override != "" {
+		return override, nil
+	}
+	answer, err := p.answer(label)
+	if err != nil {
+		return "", err
+	}
+	for _, item := range list {
+		if item == answer {
+			return answer, nil
+		}
+	}
+	return "", fmt.Errorf("stimtest: answer '%s' to the prompt '%s' is not one of %v", answer, label, list)
+}
+
+// PromptListRemembered answers a list prompt and remembers the answer under
+// the key
+func (p *Prompter) PromptListRemembered(key string, label string, list []string, override string) (string, error) {
+	answer, err := p.PromptList(label, list, override)
+	if err != nil || override != "" {
+		return answer, err
+	}
+	p.remember(key, []string{answer})
+	return answer, nil
+}
+
+// PromptMultiList answers a multi-select prompt with the comma separated
+// items of the answer, each of which must be in the list.  An empty answer
+// takes the remembered selection.
+func (p *Prompter) PromptMultiList(key string, label string, list []string, override []string) ([]string, error) {
+	if len(override) > 0 {
+		return override, nil
+	}
+	answer, err := p.answer(label)
+	if err != nil {
+		return nil, err
+	}
+	if answer == "" {
+		return p.Remembered[key], nil
+	}
+
+	selected := []string{}
+	for _, item := range strings.Split(answer, ",") {
+		item = strings.TrimSpace(item)
+		found := false
+		for _, listItem := range list {
+			found = found || listItem == item
+		}
+		if !found {
+			return nil, fmt.Errorf("stimtest: answer '%s' to the prompt '%s' is not one of %v", item, label, list)
+		}
+		selected = append(selected, item)
+	}
+	p.remember(key, selected)
+	return selected, nil
+}
+
+// remember saves a selection under the key, if set
+func (p *Prompter) remember(key string, selection []string) {
+	if key == "" {
+		return
+	}
+	if p.Remembered == nil {
+		p.Remembered = make(map[string][]string)
+	}
+	p.Remembered[key] = selection
+}
@@ -0,0 +1,132 @@
+package stimtest
+
+import (
+	"testing"
+
+	log "github.com/PremiereGlobal/stim/pkg/stimlog"
+	"gotest.tools/assert"
+)
+
+func TestConfig(t *testing.T) {
+	config := Config{
+		"deploy.method":    "shell",
+		"deploy.yes":       true,
+		"retry.attempts":   "3",
+		"deploy.set":       []string{"A=1", "B=2"},
+		"deploy.set-files": "C=./c.pem",
+	}
+
+	assert.Equal(t, config.ConfigGetString("deploy.method"), "shell")
+	assert.Equal(t, config.ConfigGetString("deploy.file"), "")
+	assert.Equal(t, config.ConfigGetBool("deploy.yes"), true)
+	assert.Equal(t, config.ConfigGetBool("deploy.tui"), false)
+	assert.Equal(t, config.ConfigGetInt("retry.attempts"), 3)
+	assert.DeepEqual(t, config.ConfigGetStringSlice("deploy.set"), []string{"A=1", "B=2"})
+	assert.DeepEqual(t, config.ConfigGetStringSlice("deploy.set-files"), []string{"C=./c.pem"})
+	assert.Equal(t, len(config.ConfigGetStringSlice("deploy.service")), 0)
+}
+
+func TestVault(t *testing.T) {
+	vault := &Vault{Token: "s.token", Address: "https://vault:8200", Secrets: map[string]map[string]string{
+		"secret/app/db":        {"password": "hunter2"},
+		"secret/app/api":       {"key": "abc"},
+		"secret/app/jobs/sync": {"token": "xyz"},
+	}}
+
+	value, err := vault.GetSecretKey("secret/app/db", "password")
+	assert.NilError(t, err)
+	assert.Equal(t, value, "hunter2")
+	_, err = vault.GetSecretKey("secret/app/db", "user")
+	assert.Error(t, err, "Vault: Could not find key `user` for secret `secret/app/db`")
+	_, err = vault.GetSecretKeys("secret/app/cache")
+	assert.Error(t, err, "Vault: Could not find secret `secret/app/cache`")
+
+	keys, err := vault.GetSecretKeys("secret/app/api")
+	assert.NilError(t, err)
+	keys["key"] = "changed"
+	assert.Equal(t, vault.Secrets["secret/app/api"]["key"], "abc")
+
+	names, err := vault.ListSecrets("secret/app/")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, names, []string{"api", "db", "jobs"})
+
+	_, err = (&Vault{}).GetToken()
+	assert.Error(t, err, "Vault: No token")
+}
+
+func TestLogger(t *testing.T) {
+	logger := &Logger{}
+	logger.Info("Deploying to '{}' environment in instance: {}", "prod", "us-east")
+	logger.Debug("Resolved {} tags", 2)
+	logger.Warn("Unable to write {}", "history.jsonl")
+
+	assert.DeepEqual(t, logger.Messages(log.InfoLevel), []string{
+		"Deploying to 'prod' environment in instance: us-east",
+		"Unable to write history.jsonl",
+	})
+	assert.DeepEqual(t, logger.Messages(log.WarnLevel), []string{"Unable to write history.jsonl"})
+	assert.Equal(t, len(logger.Entries(log.TraceLevel)), 3)
+
+	defer func() {
+		assert.Equal(t, recover(), "stimtest: exit 5: Deploy failed")
+	}()
+	logger.Exit(5, "Deploy failed")
+}
+
+func TestPrompter(t *testing.T) {
+	prompter := &Prompter{Answers: map[string]string{
+		"Which environment?": "prod",
+		"Proceed?":           "y",
+		"Namespace":          "",
+	}}
+
+	environment, err := prompter.PromptList("Which environment?", []string{"dev", "prod"}, "")
+	assert.NilError(t, err)
+	assert.Equal(t, environment, "prod")
+	instance, err := prompter.PromptList("Which instance?", []string{"us-east"}, "us-east")
+	assert.NilError(t, err)
+	assert.Equal(t, instance, "us-east")
+	proceed, err := prompter.PromptBool("Proceed?", false, false)
+	assert.NilError(t, err)
+	assert.Assert(t, proceed)
+	namespace, err := prompter.PromptString("Namespace", "default")
+	assert.NilError(t, err)
+	assert.Equal(t, namespace, "default")
+
+	_, err = prompter.PromptString("Reason", "")
+	assert.Error(t, err, "stimtest: no answer for the prompt 'Reason'")
+	_, err = prompter.PromptList("Which environment?", []string{"dev"}, "")
+	assert.Error(t, err, "stimtest: answer 'prod' to the prompt 'Which environment?' is not one of [dev]")
+
+	assert.DeepEqual(t, prompter.Asked, []string{"Which environment?", "Proceed?", "Namespace", "Reason", "Which environment?"})
+}
+
+func TestPrompterRemembered(t *testing.T) {
+	prompter := &Prompter{
+		Answers: map[string]string{
+			"Which environment?": "prod",
+			"Which instances?":   "us-east-1, us-west-2",
+			"Which services?":    "",
+		},
+		Remembered: map[string][]string{"services": {"api"}},
+	}
+
+	environment, err := prompter.PromptListRemembered("environment", "Which environment?", []string{"dev", "prod"}, "")
+	assert.NilError(t, err)
+	assert.Equal(t, environment, "prod")
+	instances, err := prompter.PromptMultiList("instances", "Which instances?", []string{"us-east-1", "us-west-2", "eu-west-1"}, nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, instances, []string{"us-east-1", "us-west-2"})
+	services, err := prompter.PromptMultiList("services", "Which services?", []string{"api", "web"}, nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, services, []string{"api"})
+
+	assert.DeepEqual(t, prompter.Remembered, map[string][]string{
+		"environment": {"prod"},
+		"instances":   {"us-east-1", "us-west-2"},
+		"services":    {"api"},
+	})
+
+	_, err = prompter.PromptMultiList("", "Which instances?", []string{"us-east-1"}, nil)
+	assert.Error(t, err, "stimtest: answer 'us-west-2' to the prompt 'Which instances?' is not one of [us-east-1]")
+}
@@ -0,0 +1,106 @@
+package stim
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/PremiereGlobal/stim/pkg/downloader"
+)
+
+// toolProgressStep is the percentage between download progress logs
+const toolProgressStep = 25
+
+// DownloadTools downloads the tools that are not in the tool cache
+// concurrently.  Downloads are verified against the `tools.manifest` checksum
+// manifest and `tools.offline` fails on tools that aren't cached.
+func (stim *Stim) DownloadTools(downloaders map[string]downloader.Downloader) error {
+
+	defer stim.Time(PhaseToolDownload)()
+
+	var manifest downloader.Manifest
+	if path := stim.ConfigGetString("tools.manifest"); path != "" {
+		var err error
+		manifest, err = downloader.LoadManifest(path)
+		if err != nil {
+			return ConfigError(err)
+		}
+	}
+	offline := stim.ConfigGetBool("tools.offline")
+	requireChecksums := stim.ConfigGetBool("tools.require-checksums")
+
+	var names []string
+	for name := range downloaders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		dl := downloaders[name]
+		checksum := manifest.Checksum(name, dl.GetVersion(), dl.GetPlatform())
+		if checksum == "" && requireChecksums {
+			return ConfigError(fmt.Errorf("No checksum for %s %s (%s) in the tools.manifest, which is required by tools.require-checksums", name, dl.GetVersion(), dl.GetPlatform()))
+		}
+		dl.SetOptions(downloader.Options{
+			Checksum: checksum,
+			Offline:  offline,
+			Progress: stim.toolProgress(name, dl.GetVersion()),
+		})
+	}
+
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string, dl downloader.Downloader) {
+			defer wg.Done()
+			result, err := dl.Download()
+			if errors.Is(err, downloader.ErrNotCached) {
+				errs[i] = ConfigError(fmt.Errorf("%v.  Run without tools.offline to download it", err))
+				return
+			}
+			if err != nil {
+				errs[i] = fmt.Errorf("Download of %s %s failed: %v", name, dl.GetVersion(), err)
+				return
+			}
+			if !result.FileExists {
+				stim.log.Info("Downloaded {} {} in {} (sha256 {})", name, dl.GetVersion(), result.DownloadDuration, result.Checksum)
+			}
+		}(i, name, downloaders[name])
+	}
+	wg.Wait()
+
+	var failed []error
+	var messages []string
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+			messages = append(messages, err.Error())
+		}
+	}
+	switch len(failed) {
+	case 0:
+		return nil
+	case 1:
+		return failed[0]
+	}
+	return NewExitError(ExitCode(failed[0]), errors.New(strings.Join(messages, "; ")))
+}
+
+// toolProgress returns a progress function that logs every
+// toolProgressStep percent of a tool download
+func (stim *Stim) toolProgress(name string, version string) func(int64, int64) {
+	logged := -1
+	return func(read int64, total int64) {
+		if total <= 0 {
+			return
+		}
+		percent := int(read * 100 / total)
+		if step := percent / toolProgressStep * toolProgressStep; step > logged {
+			logged = step
+			stim.log.Info("Downloading {} {}: {}% of {} MB", name, version, step, fmt.Sprintf("%.1f", float64(total)/1e6))
+		}
+	}
+}
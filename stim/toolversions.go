@@ -0,0 +1,87 @@
+package stim
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/PremiereGlobal/stim/pkg/kubernetes"
+)
+
+// releaseVersion matches the release part of a server version, without any
+// vendor suffix (ex. v1.18.9 of v1.18.9-eks-d1db3c)
+var releaseVersion = regexp.MustCompile(`^v?[0-9]+\.[0-9]+\.[0-9]+`)
+
+// DetectToolVersion detects the version of a tool that matches the target
+// servers: kubectl matches the Kubernetes API server and helm matches the
+// Tiller deployed in the cluster.  If the version can't be detected, the
+// `tools.<tool>.version` config is used.
+func (stim *Stim) DetectToolVersion(name string, kc *kubernetes.Config) (string, error) {
+
+	version, err := stim.serverToolVersion(name, kc)
+	if err == nil && version != "" {
+		stim.log.Debug("Detected tool version {}/{}", name, version)
+		return version, nil
+	}
+
+	hint := stim.ConfigGetString("tools." + name + ".version")
+	if hint != "" {
+		if err != nil {
+			stim.log.Debug("Unable to detect the version of {}: {}", name, err)
+		}
+		stim.log.Debug("Setting tool version {}/{} based on the tools.{}.version config", name, hint, name)
+		return hint, nil
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("Unable to determine version for %s: %v.  Set the version in the deploy config or the `tools.%s.version` config", name, err, name)
+	}
+	return "", ConfigError(fmt.Errorf("Unable to determine version for %s.  Set the version in the deploy config or the `tools.%s.version` config", name, name))
+}
+
+// serverToolVersion returns the version of a tool matching its server, or an
+// empty string if there is no server to match
+func (stim *Stim) serverToolVersion(name string, kc *kubernetes.Config) (string, error) {
+
+	switch name {
+	case "vault":
+		vault, err := stim.NewVault()
+		if err != nil {
+			return "", err
+		}
+		return vault.Version()
+	case "kubectl", "helm":
+		if kc == nil {
+			return "", errors.New("Kubernetes server not specified")
+		}
+		k, err := kubernetes.New(kc)
+		if err != nil {
+			return "", fmt.Errorf("Unable to load Kube config: %v", err)
+		}
+		if name == "helm" {
+			// Helm v3 doesn't record the client version with its releases, so
+			// only clusters with a Tiller can be matched
+			return k.TillerVersion()
+		}
+		version, err := k.Version()
+		if err != nil {
+			return "", err
+		}
+		return kubectlVersion(version)
+	}
+
+	return "", nil
+}
+
+// kubectlVersion returns the kubectl release matching a Kubernetes server
+// version
+func kubectlVersion(serverVersion string) (string, error) {
+	version := releaseVersion.FindString(serverVersion)
+	if version == "" {
+		return "", fmt.Errorf("Unrecognized Kubernetes server version '%s'", serverVersion)
+	}
+	if version[0] != 'v' {
+		version = "v" + version
+	}
+	return version, nil
+}
@@ -0,0 +1,23 @@
+package stim
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestKubectlVersion(t *testing.T) {
+	for server, expected := range map[string]string{
+		"v1.18.9":            "v1.18.9",
+		"v1.18.9-eks-d1db3c": "v1.18.9",
+		"v1.17.12-gke.1504":  "v1.17.12",
+		"1.16.3+k3s1":        "v1.16.3",
+	} {
+		version, err := kubectlVersion(server)
+		assert.NilError(t, err)
+		assert.Equal(t, version, expected, server)
+	}
+
+	_, err := kubectlVersion("unknown")
+	assert.ErrorContains(t, err, "Unrecognized Kubernetes server version")
+}
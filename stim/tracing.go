@@ -0,0 +1,120 @@
+package stim
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/PremiereGlobal/stim/pkg/tracing"
+	"github.com/spf13/cobra"
+)
+
+// otlpEndpointEnv is the standard OpenTelemetry environment variable of the
+// OTLP endpoint, which is passed to deploys along with the trace context
+const otlpEndpointEnv = "OTEL_EXPORTER_OTLP_ENDPOINT"
+
+// tracingInit starts the trace of the command when `tracing.endpoint` is set.
+// The trace continues the `TRACEPARENT` trace context, if any (ex. from a CI
+// pipeline).
+func (stim *Stim) tracingInit(cmd *cobra.Command) {
+
+	if stim.ConfigGetString("tracing.endpoint") == "" || isInformational(cmd) {
+		return
+	}
+
+	stim.tracer = tracing.NewTracer(stim.clock, os.Getenv(tracing.TraceparentEnv))
+	root := stim.tracer.Start(cmd.CommandPath(), nil, map[string]string{"stim.profile": stim.ConfigGetProfile()})
+	root.Start = stim.startTime
+	stim.spans = []*tracing.Span{root}
+	stim.log.Debug("Stim-Tracing: Trace ID {}", stim.tracer.TraceID())
+}
+
+// StartSpan starts a span of the command's trace, as a child of the current
+// span, and returns the function that ends it with the error of the
+// operation.  Spans started in the meantime are its children.  Without
+// tracing it does nothing.
+//
+//	endSpan := stim.StartSpan("deploy prod/us-east-1", attributes)
+//	err := deploy()
+//	endSpan(err)
+func (stim *Stim) StartSpan(name string, attributes map[string]string) func(err error) {
+
+	if stim.tracer == nil {
+		return func(error) {}
+	}
+
+	stim.traceMu.Lock()
+	defer stim.traceMu.Unlock()
+	span := stim.tracer.Start(name, stim.spans[len(stim.spans)-1], attributes)
+	stim.spans = append(stim.spans, span)
+
+	return func(err error) {
+		span.Finish(err)
+
+		stim.traceMu.Lock()
+		defer stim.traceMu.Unlock()
+		for i := len(stim.spans) - 1; i > 0; i-- {
+			if stim.spans[i] == span {
+				stim.spans = append(stim.spans[:i], stim.spans[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// TraceEnv returns the environment variables that continue the trace in
+// another process (ex. the deploy container): `TRACEPARENT` set to the
+// current span and the OTLP endpoint.  Without tracing it returns nil.
+func (stim *Stim) TraceEnv() []string {
+
+	if stim.tracer == nil {
+		return nil
+	}
+
+	stim.traceMu.Lock()
+	defer stim.traceMu.Unlock()
+	return []string{
+		tracing.TraceparentEnv + "=" + stim.spans[len(stim.spans)-1].Traceparent(),
+		otlpEndpointEnv + "=" + stim.ConfigGetString("tracing.endpoint"),
+	}
+}
+
+// exportTrace ends the trace of the command and exports it to
+// `tracing.endpoint` with the `tracing.headers` (`NAME=VALUE`).  An empty
+// errMessage means the command succeeded.  Errors are only logged at debug
+// level so that tracing never gets in the way of the command.
+func (stim *Stim) exportTrace(errMessage string) {
+
+	if stim.tracer == nil {
+		return
+	}
+
+	var err error
+	if errMessage != "" {
+		err = errors.New(errMessage)
+	}
+	stim.spans[0].Finish(err)
+
+	headers := map[string]string{}
+	for _, header := range stim.ConfigGetStringSlice("tracing.headers") {
+		parts := strings.SplitN(header, "=", 2)
+		if len(parts) != 2 {
+			stim.log.Debug("Stim-Tracing: Ignoring invalid header '{}', expected NAME=VALUE", header)
+			continue
+		}
+		headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	host, _ := os.Hostname()
+	resource := &tracing.Resource{
+		ServiceName:    "stim",
+		ServiceVersion: version,
+		Attributes:     map[string]string{"host.name": host},
+	}
+
+	endpoint := stim.ConfigGetString("tracing.endpoint")
+	err = tracing.Export(endpoint, headers, resource, stim.tracer.Spans())
+	if err != nil {
+		stim.log.Debug("Stim-Tracing: Unable to export the trace to {}: {}", endpoint, err)
+	}
+}
@@ -0,0 +1,71 @@
+package stim
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/clock"
+	"github.com/PremiereGlobal/stim/pkg/tracing"
+	"gotest.tools/assert"
+)
+
+func TestStartSpanWithoutTracing(t *testing.T) {
+	stim := New()
+
+	endSpan := stim.StartSpan("deploy dev/us-east-1", nil)
+	endSpan(nil)
+
+	assert.Assert(t, stim.TraceEnv() == nil)
+}
+
+func TestStartSpan(t *testing.T) {
+	stim := New()
+	fake := clock.NewFake(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	stim.SetClock(fake)
+	stim.startTime = fake.Now()
+	stim.ConfigOverride("tracing.endpoint", "http://localhost:4318")
+	stim.tracingInit(stim.rootCmd)
+
+	endDeploy := stim.StartSpan("deploy dev/us-east-1", map[string]string{"deploy.environment": "dev"})
+	stopAuth := stim.Time(PhaseVaultAuth)
+	fake.Advance(time.Second)
+	stopAuth()
+	stopRun := stim.Time(PhaseContainerRun)
+	env := stim.TraceEnv()
+	fake.Advance(2 * time.Second)
+	stopRun()
+	endDeploy(errors.New("Deploy failed"))
+
+	spans := stim.tracer.Spans()
+	assert.Equal(t, len(spans), 3)
+	auth, run, deploy := spans[0], spans[1], spans[2]
+	root := stim.spans[0]
+
+	assert.Equal(t, auth.Name, PhaseVaultAuth)
+	assert.Equal(t, auth.ParentID, deploy.SpanID)
+	assert.Equal(t, auth.End.Sub(auth.Start), time.Second)
+	assert.Equal(t, run.ParentID, deploy.SpanID)
+	assert.Equal(t, deploy.ParentID, root.SpanID)
+	assert.Equal(t, deploy.Attributes["deploy.environment"], "dev")
+	assert.Error(t, deploy.Err, "Deploy failed")
+	assert.Equal(t, len(stim.spans), 1)
+
+	assert.DeepEqual(t, env, []string{
+		"TRACEPARENT=" + run.Traceparent(),
+		"OTEL_EXPORTER_OTLP_ENDPOINT=http://localhost:4318",
+	})
+}
+
+func TestTracingContinuesTraceparent(t *testing.T) {
+	stim := New()
+	traceparent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	os.Setenv(tracing.TraceparentEnv, traceparent)
+	defer os.Unsetenv(tracing.TraceparentEnv)
+	stim.ConfigOverride("tracing.endpoint", "http://localhost:4318")
+	stim.tracingInit(stim.rootCmd)
+
+	assert.Equal(t, stim.tracer.TraceID().String(), "4bf92f3577b34da6a3ce929d0e0e4736")
+	assert.Equal(t, stim.spans[0].ParentID.String(), "00f067aa0ba902b7")
+}
@@ -0,0 +1,111 @@
+package stim
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/selfupdate"
+	"github.com/spf13/cobra"
+)
+
+// AnnotationNoUpdateCheck marks a command that doesn't show the notice of a
+// new stim release (ex. commands whose output is read by scripts)
+//
+//	cmd.Annotations = map[string]string{stim.AnnotationNoUpdateCheck: "true"}
+const AnnotationNoUpdateCheck = "stim.no-update-check"
+
+// updateCheckCacheFile is the cache file of the last automatic update check
+const updateCheckCacheFile = "update-check.json"
+
+// defaultUpdateCheckInterval is how often GitHub is queried for new releases
+const defaultUpdateCheckInterval = 24 * time.Hour
+
+// updateCheckTimeout keeps a slow GitHub from delaying the command
+const updateCheckTimeout = 3 * time.Second
+
+// releasePublicKey is the base64 encoded Ed25519 public key that the
+// checksums of stim releases are signed with.  It is set at build time with
+// -ldflags "-X github.com/PremiereGlobal/stim/stim.releasePublicKey=<key>"
+var releasePublicKey string
+
+// updateCheck is the cached result of the automatic update check
+type updateCheck struct {
+	CheckedAt     time.Time `json:"checkedAt"`
+	LatestVersion string    `json:"latestVersion"`
+}
+
+// Updater returns the updater for stim releases
+func (stim *Stim) Updater() *selfupdate.Updater {
+	return stim.newUpdater(0)
+}
+
+// newUpdater returns an updater with the given request timeout, or the
+// default timeout if zero
+func (stim *Stim) newUpdater(timeout time.Duration) *selfupdate.Updater {
+	return selfupdate.New(&selfupdate.Config{
+		Repository: stim.ConfigGetString("update.repository"),
+		Timeout:    timeout,
+		PublicKey:  releasePublicKey,
+	})
+}
+
+// checkForUpdate logs a notice after the command if a newer stim release is
+// available.  GitHub is queried at most once per `update.check-interval` and
+// errors are only logged at debug level so that the check never gets in the
+// way of the command.
+func (stim *Stim) checkForUpdate(cmd *cobra.Command) {
+
+	if stim.ConfigGetBool("update.disable-check") || stim.IsAutomated() {
+		return
+	}
+	if cmd.Annotations[AnnotationNoUpdateCheck] == "true" || strings.HasPrefix(cmd.Name(), "__") || isInformational(cmd) {
+		return
+	}
+
+	// Local and branch builds can't be compared with releases
+	if _, err := selfupdate.CompareVersions(version, version); err != nil {
+		return
+	}
+
+	interval := defaultUpdateCheckInterval
+	if i := stim.ConfigGetString("update.check-interval"); i != "" {
+		d, err := time.ParseDuration(i)
+		if err != nil {
+			stim.log.Debug("Stim-Update: Invalid update.check-interval '{}': {}", i, err)
+		} else {
+			interval = d
+		}
+	}
+
+	path := filepath.Join(stim.ConfigGetCacheDir(""), updateCheckCacheFile)
+	check := &updateCheck{}
+	if b, err := ioutil.ReadFile(path); err == nil {
+		json.Unmarshal(b, check)
+	}
+
+	now := stim.clock.Now()
+	if now.Sub(check.CheckedAt) >= interval {
+		// Failed checks are cached too so that stim doesn't wait on GitHub for
+		// every command while offline
+		check.CheckedAt = now
+		release, err := stim.newUpdater(updateCheckTimeout).LatestRelease()
+		if err != nil {
+			stim.log.Debug("Stim-Update: Unable to check for a new release: {}", err)
+		} else {
+			check.LatestVersion = release.Version
+		}
+		b, _ := json.Marshal(check)
+		err = ioutil.WriteFile(path, b, 0600)
+		if err != nil {
+			stim.log.Debug("Stim-Update: Unable to cache the update check: {}", err)
+		}
+	}
+
+	newer, err := selfupdate.CompareVersions(check.LatestVersion, version)
+	if err == nil && newer > 0 {
+		stim.log.Info("A new version of stim is available ({} -> {}).  Run `stim update` to install it", version, check.LatestVersion)
+	}
+}
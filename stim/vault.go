@@ -10,9 +10,20 @@ import (
 // The main input is the vault-address
 // Will update the user's ~/.vault-token file with a new token
 func (stim *Stim) Vault() *vault.Vault {
+	vault, err := stim.NewVault()
+	if err != nil {
+		stim.Fatal(err)
+	}
+	return vault
+}
+
+// NewVault is the same as Vault but returns an error instead of exiting if
+// the login fails
+func (stim *Stim) NewVault() (*vault.Vault, error) {
 	if stim.vault == nil {
 
 		stim.log.Debug("Stim-Vault: Creating")
+		defer stim.Time(PhaseVaultAuth)()
 
 		username := stim.ConfigGetString("vault-username")
 
@@ -28,6 +39,27 @@ func (stim *Stim) Vault() *vault.Vault {
 			}
 		}
 
+		var minTokenTTL time.Duration
+		mtt := stim.ConfigGetString("vault-token-min-ttl")
+		if mtt != "" {
+			minTokenTTL, err = time.ParseDuration(mtt)
+			if err != nil {
+				stim.log.Warn("Stim-vault: bad duration value:{} caused error:{}", mtt, err)
+				minTokenTTL = time.Duration(0)
+			}
+		}
+
+		// `auth.method` is the legacy name of the `vault.auth-method` option
+		authMethod := stim.ConfigGetString("vault.auth-method")
+		if authMethod == "" {
+			authMethod = stim.ConfigGetString("auth.method")
+		}
+
+		store, err := stim.Credentials()
+		if err != nil {
+			return nil, err
+		}
+
 		va := stim.ConfigGetString("vault-address")
 		stim.log.Debug("Vault Address: ({})", va)
 
@@ -35,17 +67,34 @@ func (stim *Stim) Vault() *vault.Vault {
 		vault, err := vault.New(&vault.Config{
 			Address:              va, // Default is 127.0.0.1
 			Noprompt:             stim.ConfigGetBool("noprompt") == false && stim.IsAutomated(),
-			AuthPath:             stim.ConfigGetString("auth.method"),
+			AuthMethod:           authMethod,
+			AuthPath:             stim.ConfigGetString("vault.auth-path"),
+			Role:                 stim.ConfigGetString("vault.role"),
+			RoleID:               stim.ConfigGetString("vault.role-id"),
+			SecretID:             stim.ConfigGetString("vault.secret-id"),
+			JWT:                  stim.ConfigGetString("vault.jwt"),
+			JWTPath:              stim.ConfigGetString("vault.jwt-path"),
+			OIDCCallbackPort:     stim.ConfigGetInt("vault.oidc-callback-port"),
 			Username:             username, // If set in the configs, pass in user
 			UsernameSkipPrompt:   stim.ConfigGetBool("vault-username-skip-prompt"),
 			InitialTokenDuration: timeInDuration,
+			TokenCachePath:       stim.ConfigGetString("vault-token-cache-path"),
+			MinTokenTTL:          minTokenTTL,
 			Log:                  stim.log,
+			Clock:                stim.clock,
+			Recorder:             stim.bom,
+			Retry:                stim.RetryPolicy(),
+			Credentials:          store,
+			Context:              stim.Context(),
 		})
 		if err != nil {
-			stim.log.Fatal(err)
+			return nil, AuthError(err)
 		}
 		stim.vault = vault
 
+		// Detect read-only mode from the token policies
+		stim.detectReadOnly()
+
 		// Update the username set in local configs to make logins more friendly
 		err = stim.UpdateVaultUser(vault.GetUser())
 		if err != nil && !stim.IsAutomated() {
@@ -53,5 +102,5 @@ func (stim *Stim) Vault() *vault.Vault {
 		}
 	}
 
-	return stim.vault
+	return stim.vault, nil
 }
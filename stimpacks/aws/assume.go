@@ -0,0 +1,248 @@
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	awspkg "github.com/PremiereGlobal/stim/pkg/aws"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// maxSessionNameLength is the longest role session name allowed by STS
+const maxSessionNameLength = 64
+
+// invalidSessionNameChars are the characters not allowed in a role session name
+var invalidSessionNameChars = regexp.MustCompile(`[^\w+=,.@-]`)
+
+// roleChain is an entry of `aws.role-chains`: the Vault AWS mount and role of
+// the base credentials and the roles that are assumed one after the other
+type roleChain struct {
+	Account string       `mapstructure:"account"`
+	Role    string       `mapstructure:"role"`
+	Region  string       `mapstructure:"region"`
+	Roles   []*chainRole `mapstructure:"roles"`
+}
+
+// chainRole is a role of a chain
+type chainRole struct {
+	ARN         string `mapstructure:"arn"`
+	ExternalID  string `mapstructure:"external-id"`
+	MFASerial   string `mapstructure:"mfa-serial"`
+	Duration    string `mapstructure:"duration"`
+	SessionName string `mapstructure:"session-name"`
+}
+
+// chainProfile holds the additional fields we write for role chain credentials
+type chainProfile struct {
+	SessionToken string `ini:"aws_session_token"`
+	Expiration   string `ini:"aws_expiration"`
+	RoleChain    string `ini:"stim_role_chain"`
+}
+
+// Assume gets the credentials of the last role of a role chain
+func (a *Aws) Assume(args []string) error {
+
+	chains := make(map[string]*roleChain)
+	err := a.stim.ConfigUnmarshalKey("aws.role-chains", &chains)
+	if err != nil {
+		return stim.ConfigError(fmt.Errorf("Invalid `aws.role-chains` config: %v", err))
+	}
+	if len(chains) == 0 {
+		return stim.ConfigError(errors.New("No role chains are set in `aws.role-chains`"))
+	}
+
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	} else if a.stim.IsAutomated() {
+		return stim.UsageError(errors.New("IsAutomated is detected: the role chain must be specified"))
+	} else {
+		names := make([]string, 0, len(chains))
+		for n := range chains {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		name, err = a.stim.PromptList("Select role chain", names, "")
+		if err != nil {
+			return err
+		}
+	}
+
+	chain, ok := chains[name]
+	if !ok {
+		return stim.UsageError(fmt.Errorf("Unknown role chain '%s'", name))
+	}
+	durations, err := validateRoleChain(chain)
+	if err != nil {
+		return stim.ConfigError(fmt.Errorf("Invalid role chain '%s': %v", name, err))
+	}
+
+	a.aws, err = a.stim.NewAws("", "")
+	if err != nil {
+		return err
+	}
+	a.vault, err = a.stim.NewVault()
+	if err != nil {
+		return err
+	}
+
+	// Base credentials from Vault
+	secret, err := a.vault.AWScredentials(chain.Account, chain.Role)
+	if err != nil {
+		return err
+	}
+	accessKey, _ := secret.Data["access_key"].(string)
+	secretKey, _ := secret.Data["secret_key"].(string)
+	sessionToken, _ := secret.Data["security_token"].(string)
+	a.log.Debug("AWS IAM Access Key: " + accessKey)
+	a.log.Debug("AWS IAM Vault Lease Id: " + secret.LeaseID)
+
+	// The assumed role sessions need the base credentials to stay valid
+	a.vault.KeepLeases()
+
+	// New IAM users take a while to become active
+	if sessionToken == "" {
+		err = a.aws.CreateSession(accessKey, secretKey)
+		if err != nil {
+			return err
+		}
+		err = a.aws.VerifyActiveCreds()
+		if err != nil {
+			return stim.AuthError(err)
+		}
+	}
+
+	user, err := a.vault.GetUsername()
+	if err != nil || user == "" {
+		a.log.Debug("Unable to get the Vault user name for the session name: {}", err)
+		user = "stim"
+	}
+
+	mfaCode := a.stim.ConfigGetString("aws-assume-mfa-code")
+	var expiration time.Time
+	for i, role := range chain.Roles {
+		err = a.aws.CreateSessionWithToken(accessKey, secretKey, sessionToken, chain.Region)
+		if err != nil {
+			return err
+		}
+
+		options := &awspkg.AssumeRoleOptions{
+			RoleARN:     role.ARN,
+			SessionName: roleSessionName(role.SessionName, user),
+			ExternalID:  role.ExternalID,
+			MFASerial:   role.MFASerial,
+			Duration:    durations[i],
+		}
+		if role.MFASerial != "" {
+			options.MFAToken, err = a.getMFACode(role.MFASerial, mfaCode)
+			if err != nil {
+				return err
+			}
+			// A code can only be used once
+			mfaCode = ""
+		}
+
+		a.log.Debug("Assuming role {} as {}", role.ARN, options.SessionName)
+		creds, err := a.aws.AssumeRole(options)
+		if err != nil {
+			return stim.AuthError(err)
+		}
+		accessKey, secretKey, sessionToken = *creds.AccessKeyId, *creds.SecretAccessKey, *creds.SessionToken
+		expiration = *creds.Expiration
+	}
+	a.log.Debug("AWS Assumed Role Access Key: " + accessKey)
+	a.log.Debug("AWS Assumed Role Access Expiration: {}", a.stim.FormatRelative(expiration))
+
+	profileName := a.stim.ConfigGetString("aws-assume-profile")
+	if profileName != "" {
+		profile := awspkg.Profile{
+			AccessKeyID:     accessKey,
+			SecretAccessKey: secretKey,
+		}
+		extra := chainProfile{
+			SessionToken: sessionToken,
+			Expiration:   expiration.UTC().Format(time.RFC3339),
+			RoleChain:    name,
+		}
+		defaultProfile := a.stim.ConfigGetBool("aws-assume-default-profile")
+		if defaultProfile {
+			a.log.Debug("Setting {} credentials as default", profileName)
+		}
+		err = a.aws.SaveProfile(profileName, &profile, defaultProfile, &extra)
+		if err != nil {
+			return err
+		}
+	}
+
+	if a.stim.ConfigGetBool("aws-assume-source") {
+		fmt.Println("export AWS_ACCESS_KEY_ID=" + accessKey)
+		fmt.Println("export AWS_SECRET_ACCESS_KEY=" + secretKey)
+		fmt.Println("export AWS_SESSION_TOKEN=" + sessionToken)
+	} else if profileName == "" {
+		fmt.Println("AWS_ACCESS_KEY_ID=" + accessKey)
+		fmt.Println("AWS_SECRET_ACCESS_KEY=" + secretKey)
+		fmt.Println("AWS_SESSION_TOKEN=" + sessionToken)
+	} else {
+		a.log.Info("Saved credentials to profile {} (expires {})", profileName, a.stim.FormatTime(expiration))
+	}
+
+	return nil
+}
+
+// getMFACode returns the given code or prompts for the code of the MFA device
+func (a *Aws) getMFACode(serial string, code string) (string, error) {
+	if code != "" {
+		return code, nil
+	}
+	if a.stim.IsAutomated() {
+		return "", stim.UsageError(fmt.Errorf("IsAutomated is detected: --mfa-code must be specified for MFA device %s", serial))
+	}
+	return a.stim.PromptString(fmt.Sprintf("MFA code for %s", serial), "")
+}
+
+// validateRoleChain checks a role chain and returns the parsed session
+// durations of its roles
+func validateRoleChain(chain *roleChain) ([]time.Duration, error) {
+	if chain.Account == "" || chain.Role == "" {
+		return nil, errors.New("`account` and `role` of the Vault base credentials must be set")
+	}
+	if len(chain.Roles) == 0 {
+		return nil, errors.New("at least one role must be set in `roles`")
+	}
+
+	durations := make([]time.Duration, len(chain.Roles))
+	for i, role := range chain.Roles {
+		if role.ARN == "" {
+			return nil, errors.New("`arn` must be set for each role")
+		}
+		if role.Duration == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(role.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration '%s' of role %s", role.Duration, role.ARN)
+		}
+		if duration < 15*time.Minute {
+			return nil, fmt.Errorf("duration of role %s must be at least 15m", role.ARN)
+		}
+		durations[i] = duration
+	}
+
+	return durations, nil
+}
+
+// roleSessionName returns the session name of a role, defaulting to the user
+// name so that CloudTrail shows who assumed the role
+func roleSessionName(name string, user string) string {
+	if name == "" {
+		name = "stim-" + user
+	}
+	name = invalidSessionNameChars.ReplaceAllString(name, "-")
+	if len(name) > maxSessionNameLength {
+		name = name[:maxSessionNameLength]
+	}
+	return name
+}
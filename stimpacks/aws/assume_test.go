@@ -0,0 +1,44 @@
+package aws
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestValidateRoleChain(t *testing.T) {
+	chain := &roleChain{
+		Account: "aws-jump",
+		Role:    "jump",
+		Roles: []*chainRole{
+			{ARN: "arn:aws:iam::111111111111:role/jump"},
+			{ARN: "arn:aws:iam::222222222222:role/admin", ExternalID: "abc", Duration: "30m"},
+		},
+	}
+	durations, err := validateRoleChain(chain)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, durations, []time.Duration{0, 30 * time.Minute})
+
+	chain.Roles[1].Duration = "5m"
+	_, err = validateRoleChain(chain)
+	assert.ErrorContains(t, err, "at least 15m")
+
+	chain.Roles[1].ARN = ""
+	_, err = validateRoleChain(chain)
+	assert.ErrorContains(t, err, "`arn` must be set")
+
+	_, err = validateRoleChain(&roleChain{Account: "aws-jump", Role: "jump"})
+	assert.ErrorContains(t, err, "at least one role")
+
+	_, err = validateRoleChain(&roleChain{Roles: chain.Roles})
+	assert.ErrorContains(t, err, "`account` and `role`")
+}
+
+func TestRoleSessionName(t *testing.T) {
+	assert.Equal(t, roleSessionName("", "jdoe@example.com"), "stim-jdoe@example.com")
+	assert.Equal(t, roleSessionName("", "j doe/ops"), "stim-j-doe-ops")
+	assert.Equal(t, roleSessionName("deploy", "jdoe"), "deploy")
+	assert.Equal(t, len(roleSessionName("", strings.Repeat("x", 100))), maxSessionNameLength)
+}
@@ -26,11 +26,8 @@ func (a *Aws) Command(viper *viper.Viper) *cobra.Command {
 		Use:   "login",
 		Short: "aws login",
 		Long:  "Create AWS credentials",
-		Run: func(cmd *cobra.Command, args []string) {
-			err := a.Login()
-			if err != nil {
-				a.stim.Fatal(err)
-			}
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.Login()
 		},
 	}
 	a.stim.BindCommand(loginCmd, cmd)
@@ -46,6 +43,7 @@ func (a *Aws) Command(viper *viper.Viper) *cobra.Command {
 
 	loginCmd.Flags().StringP("account", "a", "", "AWS Account")
 	viper.BindPFlag("aws-account", loginCmd.Flags().Lookup("account"))
+	a.stim.BindFlagCompletion(loginCmd, "account", "aws-accounts", a.completeAccounts)
 
 	loginCmd.Flags().StringP("role", "r", "", "AWS Vault role")
 	viper.BindPFlag("aws-role", loginCmd.Flags().Lookup("role"))
@@ -62,5 +60,239 @@ func (a *Aws) Command(viper *viper.Viper) *cobra.Command {
 	loginCmd.Flags().StringP("web-ttl", "b", "1h", "Time-to-live for AWS web console access (min 15m, max 36h)")
 	viper.BindPFlag("aws.web-ttl", loginCmd.Flags().Lookup("web-ttl"))
 
+	var assumeCmd = &cobra.Command{
+		Use:   "assume [chain]",
+		Short: "Assume a chain of roles",
+		Long:  "Get the credentials of the last role of a role chain in `aws.role-chains`, starting from base credentials from Vault and assuming each role in turn",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.Assume(args)
+		},
+	}
+	a.stim.BindCommand(assumeCmd, cmd)
+
+	assumeCmd.Flags().BoolP("source", "s", false, "output env source for current shell")
+	viper.BindPFlag("aws-assume-source", assumeCmd.Flags().Lookup("source"))
+
+	assumeCmd.Flags().StringP("profile", "p", "", "Profile name to save credentials as")
+	viper.BindPFlag("aws-assume-profile", assumeCmd.Flags().Lookup("profile"))
+
+	assumeCmd.Flags().BoolP("default-profile", "d", false, "If --profile is set, also save credentials as the [default] profile")
+	viper.BindPFlag("aws-assume-default-profile", assumeCmd.Flags().Lookup("default-profile"))
+
+	assumeCmd.Flags().String("mfa-code", "", "MFA code for the first role with an `mfa-serial`.  Prompts if not set")
+	viper.BindPFlag("aws-assume-mfa-code", assumeCmd.Flags().Lookup("mfa-code"))
+
+	var ssoLoginCmd = &cobra.Command{
+		Use:   "sso-login",
+		Short: "aws sso login",
+		Long:  "Create AWS credentials using AWS IAM Identity Center (SSO)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.SSOLogin()
+		},
+	}
+	a.stim.BindCommand(ssoLoginCmd, cmd)
+
+	ssoLoginCmd.Flags().String("start-url", "", "SSO start URL (ex. https://my-org.awsapps.com/start)")
+	viper.BindPFlag("aws.sso.start-url", ssoLoginCmd.Flags().Lookup("start-url"))
+
+	ssoLoginCmd.Flags().String("sso-region", "", "Region of the SSO instance")
+	viper.BindPFlag("aws.sso.region", ssoLoginCmd.Flags().Lookup("sso-region"))
+
+	ssoLoginCmd.Flags().StringP("account-id", "a", "", "AWS account ID. Prompts if not set")
+	viper.BindPFlag("aws-sso-account-id", ssoLoginCmd.Flags().Lookup("account-id"))
+
+	ssoLoginCmd.Flags().StringP("role-name", "r", "", "SSO role (permission set) name. Prompts if not set")
+	viper.BindPFlag("aws-sso-role-name", ssoLoginCmd.Flags().Lookup("role-name"))
+
+	ssoLoginCmd.Flags().StringP("profile", "p", "", "Profile name to save credentials as (Default: <account-id>/<role-name>)")
+	viper.BindPFlag("aws-sso-profile", ssoLoginCmd.Flags().Lookup("profile"))
+
+	ssoLoginCmd.Flags().BoolP("default-profile", "d", false, "Also save credentials as the [default] profile")
+	viper.BindPFlag("aws.sso.default-profile", ssoLoginCmd.Flags().Lookup("default-profile"))
+
+	ssoLoginCmd.Flags().BoolP("source", "s", false, "output env source for current shell")
+	viper.BindPFlag("aws-sso-source", ssoLoginCmd.Flags().Lookup("source"))
+
+	ssoLoginCmd.Flags().BoolP("output", "o", false, "Output the verification URL to console (don't launch URL)")
+	viper.BindPFlag("aws-sso-output", ssoLoginCmd.Flags().Lookup("output"))
+
+	var refreshCmd = &cobra.Command{
+		Use:   "refresh [profile...]",
+		Short: "Refresh expired stim-managed profiles",
+		Long:  "Refresh the credentials of the given (or --all) profiles created by `stim aws login --use-profiles` and `stim aws sso-login` that are expired or expire within the threshold",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.Refresh(args)
+		},
+	}
+	a.stim.BindCommand(refreshCmd, cmd)
+
+	refreshCmd.Flags().Bool("all", false, "Check all stim-managed profiles")
+	viper.BindPFlag("aws-refresh-all", refreshCmd.Flags().Lookup("all"))
+
+	refreshCmd.Flags().String("threshold", "15m", "Refresh profiles expiring within this duration")
+	viper.BindPFlag("aws-refresh-threshold", refreshCmd.Flags().Lookup("threshold"))
+
+	var ecrLoginCmd = &cobra.Command{
+		Use:   "ecr-login [registry]",
+		Short: "Log Docker in to an ECR registry",
+		Long:  "Get an ECR authorization token with AWS credentials from Vault and log Docker in to the registry (<account-id>.dkr.ecr.<region>.amazonaws.com), saving the login in the Docker credential store.  Without a registry, logs in to the registry of the AWS account of the credentials in --region",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.ECRLogin(args)
+		},
+	}
+	a.stim.BindCommand(ecrLoginCmd, cmd)
+
+	ecrLoginCmd.Flags().StringP("account", "a", "", "AWS Account (Vault AWS mount)")
+	viper.BindPFlag("aws.ecr.account", ecrLoginCmd.Flags().Lookup("account"))
+	a.stim.BindFlagCompletion(ecrLoginCmd, "account", "aws-accounts", a.completeAccounts)
+
+	ecrLoginCmd.Flags().StringP("role", "r", "", "AWS Vault role")
+	viper.BindPFlag("aws.ecr.role", ecrLoginCmd.Flags().Lookup("role"))
+
+	ecrLoginCmd.Flags().String("region", "", "Region of the registry, if no registry is given")
+	viper.BindPFlag("aws-ecr-region", ecrLoginCmd.Flags().Lookup("region"))
+
+	ecrLoginCmd.Flags().Bool("print", false, "Print the docker login command instead of running it")
+	viper.BindPFlag("aws-ecr-print", ecrLoginCmd.Flags().Lookup("print"))
+
+	var keysCmd = &cobra.Command{
+		Use:   "keys",
+		Short: "Audit and rotate IAM access keys",
+		Long:  "Audit and rotate static IAM access keys",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+	a.stim.BindCommand(keysCmd, cmd)
+
+	keysCmd.PersistentFlags().StringP("profile", "p", "", "AWS profile to use (Default: AWS default credentials)")
+	viper.BindPFlag("aws-keys-profile", keysCmd.PersistentFlags().Lookup("profile"))
+
+	keysCmd.PersistentFlags().StringP("user", "u", "", "IAM user name (Default: user of the credentials)")
+	viper.BindPFlag("aws-keys-user", keysCmd.PersistentFlags().Lookup("user"))
+
+	var keysListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List IAM access keys",
+		Long:  "List a user's IAM access keys with their age and when they were last used",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.ListKeys()
+		},
+	}
+	a.stim.BindCommand(keysListCmd, keysCmd)
+
+	keysListCmd.Flags().Int("max-age", 90, "Flag keys older than this many days for rotation")
+	viper.BindPFlag("aws.keys.max-age-days", keysListCmd.Flags().Lookup("max-age"))
+	keysListCmd.Flags().StringP("output", "o", stim.OutputTable, "Output format (table|json)")
+	viper.BindPFlag("aws-keys-list-output", keysListCmd.Flags().Lookup("output"))
+
+	var keysRotateCmd = &cobra.Command{
+		Use:         "rotate",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Rotate an IAM access key",
+		Long:        "Create a new access key, update every profile using the old key, then deactivate/delete the old key",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.RotateKey()
+		},
+	}
+	a.stim.BindCommand(keysRotateCmd, keysCmd)
+
+	keysRotateCmd.Flags().Bool("deactivate", false, "Deactivate the old key without prompting")
+	viper.BindPFlag("aws-keys-deactivate", keysRotateCmd.Flags().Lookup("deactivate"))
+
+	keysRotateCmd.Flags().Bool("delete", false, "Delete the old key without prompting (requires --deactivate when automated)")
+	viper.BindPFlag("aws-keys-delete", keysRotateCmd.Flags().Lookup("delete"))
+
+	var dynamodbCmd = &cobra.Command{
+		Use:   "dynamodb",
+		Short: "Inspect DynamoDB tables",
+		Long:  "Read-only inspection of DynamoDB tables",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+	a.stim.BindCommand(dynamodbCmd, cmd)
+
+	dynamodbCmd.PersistentFlags().StringP("profile", "p", "", "AWS profile to use (Default: AWS default credentials)")
+	viper.BindPFlag("aws-dynamodb-profile", dynamodbCmd.PersistentFlags().Lookup("profile"))
+
+	dynamodbCmd.PersistentFlags().String("region", "", "AWS region (Default: region of the profile)")
+	viper.BindPFlag("aws-dynamodb-region", dynamodbCmd.PersistentFlags().Lookup("region"))
+
+	dynamodbCmd.PersistentFlags().StringP("output", "o", stim.OutputTable, "Output format (table|json)")
+	viper.BindPFlag("aws-dynamodb-output", dynamodbCmd.PersistentFlags().Lookup("output"))
+
+	var dynamodbListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List DynamoDB tables",
+		Long:  "List the DynamoDB tables of the region with their estimated item counts and sizes",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.ListTables()
+		},
+	}
+	a.stim.BindCommand(dynamodbListCmd, dynamodbCmd)
+
+	var dynamodbDescribeCmd = &cobra.Command{
+		Use:   "describe <table>",
+		Short: "Describe a DynamoDB table",
+		Long:  "Show the status, estimated item count and size, keys and indexes of a DynamoDB table",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.DescribeTable(args[0])
+		},
+	}
+	a.stim.BindCommand(dynamodbDescribeCmd, dynamodbCmd)
+
+	var s3Cmd = &cobra.Command{
+		Use:   "s3",
+		Short: "Inspect S3 buckets",
+		Long:  "Read-only inspection of S3 buckets",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+	a.stim.BindCommand(s3Cmd, cmd)
+
+	s3Cmd.PersistentFlags().StringP("profile", "p", "", "AWS profile to use (Default: AWS default credentials)")
+	viper.BindPFlag("aws-s3-profile", s3Cmd.PersistentFlags().Lookup("profile"))
+
+	s3Cmd.PersistentFlags().String("region", "", "AWS region (Default: region of the bucket)")
+	viper.BindPFlag("aws-s3-region", s3Cmd.PersistentFlags().Lookup("region"))
+
+	s3Cmd.PersistentFlags().StringP("output", "o", stim.OutputTable, "Output format (table|json)")
+	viper.BindPFlag("aws-s3-output", s3Cmd.PersistentFlags().Lookup("output"))
+
+	var s3ListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List S3 buckets",
+		Long:  "List the S3 buckets of the account",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.ListBuckets()
+		},
+	}
+	a.stim.BindCommand(s3ListCmd, s3Cmd)
+
+	var s3InspectCmd = &cobra.Command{
+		Use:   "inspect <bucket>",
+		Short: "Inspect an S3 bucket",
+		Long:  "Count the objects under a prefix of an S3 bucket (up to --max-scan objects) and list the most recently modified ones",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.InspectBucket(args[0])
+		},
+	}
+	a.stim.BindCommand(s3InspectCmd, s3Cmd)
+
+	s3InspectCmd.Flags().String("prefix", "", "Only inspect objects with this key prefix")
+	viper.BindPFlag("aws-s3-prefix", s3InspectCmd.Flags().Lookup("prefix"))
+
+	s3InspectCmd.Flags().Int("recent", 20, "Number of most recently modified objects to list")
+	viper.BindPFlag("aws-s3-recent", s3InspectCmd.Flags().Lookup("recent"))
+
+	s3InspectCmd.Flags().Int("max-scan", 10000, "Maximum number of objects to scan for the count")
+	viper.BindPFlag("aws-s3-max-scan", s3InspectCmd.Flags().Lookup("max-scan"))
+
 	return cmd
 }
@@ -0,0 +1,83 @@
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	awspkg "github.com/PremiereGlobal/stim/pkg/aws"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// ECRLogin gets an ECR authorization token with AWS credentials from Vault
+// and logs Docker in to the registry, or prints the `docker login` command
+func (a *Aws) ECRLogin(args []string) error {
+
+	accountID := ""
+	region := a.stim.ConfigGetString("aws-ecr-region")
+	if len(args) > 0 {
+		host := ecrRegistryHost(args[0])
+		var ok bool
+		accountID, region, ok = awspkg.ParseECRHost(host)
+		if !ok {
+			return stim.UsageError(fmt.Errorf("'%s' is not an ECR registry.  Must be <account-id>.dkr.ecr.<region>.amazonaws.com", args[0]))
+		}
+	} else if region == "" {
+		return stim.UsageError(errors.New("Either the registry or --region must be specified"))
+	}
+
+	vaultAccount, vaultRole, err := a.getCredentials("aws.ecr.account", "aws.ecr.role")
+	if err != nil {
+		return err
+	}
+	a.log.Debug("Account: {} Role: {}", vaultAccount, vaultRole)
+
+	auth, err := a.stim.ECRAuthorization(accountID, region, vaultAccount, vaultRole)
+	if err != nil {
+		return err
+	}
+	host := ecrRegistryHost(auth.Endpoint)
+
+	// The login is only valid as long as the credentials it was made with
+	if vaultAccount != "" {
+		vault, err := a.stim.NewVault()
+		if err != nil {
+			return err
+		}
+		vault.KeepLeases()
+	}
+
+	if a.stim.ConfigGetBool("aws-ecr-print") {
+		fmt.Println(dockerLoginCommand(host, auth))
+		return nil
+	}
+
+	// docker saves the login in its credential store (`credsStore` or
+	// `credHelpers` of ~/.docker/config.json)
+	cmd := exec.Command("docker", "login", "--username", auth.Username, "--password-stdin", host)
+	cmd.Stdin = strings.NewReader(auth.Password)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		return fmt.Errorf("docker login to %s failed: %v", host, err)
+	}
+
+	a.log.Info("Logged in to {} (expires {})", host, a.stim.FormatTime(auth.ExpiresAt))
+	return nil
+}
+
+// ecrRegistryHost returns the host of a registry endpoint
+func ecrRegistryHost(endpoint string) string {
+	host := strings.TrimPrefix(strings.TrimPrefix(endpoint, "https://"), "http://")
+	return strings.TrimSuffix(host, "/")
+}
+
+// dockerLoginCommand returns the shell command that logs Docker in to the
+// registry.  The password is passed on stdin so it isn't in the docker
+// command line.
+func dockerLoginCommand(host string, auth *awspkg.ECRAuthorization) string {
+	return fmt.Sprintf("printf '%%s' '%s' | docker login --username %s --password-stdin %s", auth.Password, auth.Username, host)
+}
@@ -0,0 +1,39 @@
+package aws
+
+import (
+	"testing"
+
+	awspkg "github.com/PremiereGlobal/stim/pkg/aws"
+	"gotest.tools/assert"
+)
+
+func TestParseECRHost(t *testing.T) {
+	account, region, ok := awspkg.ParseECRHost("123456789012.dkr.ecr.us-west-2.amazonaws.com")
+	assert.Assert(t, ok)
+	assert.Equal(t, account, "123456789012")
+	assert.Equal(t, region, "us-west-2")
+
+	account, region, ok = awspkg.ParseECRHost("123456789012.dkr.ecr-fips.us-gov-west-1.amazonaws.com")
+	assert.Assert(t, ok)
+	assert.Equal(t, region, "us-gov-west-1")
+
+	_, region, ok = awspkg.ParseECRHost("123456789012.dkr.ecr.cn-north-1.amazonaws.com.cn")
+	assert.Assert(t, ok)
+	assert.Equal(t, region, "cn-north-1")
+
+	for _, host := range []string{"registry-1.docker.io", "docker.example.com", "12345.dkr.ecr.us-west-2.amazonaws.com", "123456789012.dkr.ecr.us-west-2.amazonaws.com.example.com"} {
+		_, _, ok = awspkg.ParseECRHost(host)
+		assert.Assert(t, !ok, host)
+	}
+}
+
+func TestECRRegistryHost(t *testing.T) {
+	assert.Equal(t, ecrRegistryHost("https://123456789012.dkr.ecr.us-west-2.amazonaws.com"), "123456789012.dkr.ecr.us-west-2.amazonaws.com")
+	assert.Equal(t, ecrRegistryHost("123456789012.dkr.ecr.us-west-2.amazonaws.com/"), "123456789012.dkr.ecr.us-west-2.amazonaws.com")
+}
+
+func TestDockerLoginCommand(t *testing.T) {
+	auth := &awspkg.ECRAuthorization{Username: "AWS", Password: "eyJwYXlsb2FkIjoi=="}
+	assert.Equal(t, dockerLoginCommand("123456789012.dkr.ecr.us-west-2.amazonaws.com", auth),
+		"printf '%s' 'eyJwYXlsb2FkIjoi==' | docker login --username AWS --password-stdin 123456789012.dkr.ecr.us-west-2.amazonaws.com")
+}
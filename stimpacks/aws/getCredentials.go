@@ -2,32 +2,59 @@ package aws
 
 import (
 	"errors"
+
+	"github.com/PremiereGlobal/stim/stim"
 )
 
 // GetCredentials will get the aws mount and role from the user
 func (a *Aws) GetCredentials() (string, string, error) {
+	return a.getCredentials("aws-account", "aws-role")
+}
+
+// getCredentials gets the aws mount and role from the config keys, prompting
+// for the ones that aren't set
+func (a *Aws) getCredentials(accountKey string, roleKey string) (string, string, error) {
 	if a.vault == nil {
-		a.vault = a.stim.Vault()
+		var err error
+		a.vault, err = a.stim.NewVault()
+		if err != nil {
+			return "", "", err
+		}
 	}
 
 	mounts, err := a.vault.GetMounts("aws")
-	a.stim.Fatal(err)
+	if err != nil {
+		return "", "", err
+	}
 
-	vaultAccount := a.stim.ConfigGetString("aws-account")
+	vaultAccount := a.stim.ConfigGetString(accountKey)
 	if vaultAccount == "" && a.stim.IsAutomated() {
-		a.stim.Fatal(errors.New("Vault aws mount not specified"))
+		return "", "", stim.UsageError(errors.New("Vault aws mount not specified"))
 	} else if vaultAccount == "" {
 		vaultAccount, err = a.stim.PromptSearchList("Choose AWS account", mounts)
-		a.stim.Fatal(err)
+		if err != nil {
+			return "", "", err
+		}
 	}
 
-	vaultRole := a.stim.ConfigGetString("aws-role")
+	vaultRole := a.stim.ConfigGetString(roleKey)
 	if vaultRole == "" && a.stim.IsAutomated() {
-		a.stim.Fatal(errors.New("Vault aws role not specified"))
+		return "", "", stim.UsageError(errors.New("Vault aws role not specified"))
 	} else if vaultRole == "" {
 		vaultRole, err = a.stim.PromptListVault(vaultAccount+"/roles", "Select Role", "")
-		a.stim.Fatal(err)
+		if err != nil {
+			return "", "", err
+		}
 	}
 
 	return vaultAccount, vaultRole, nil
 }
+
+// completeAccounts returns the Vault AWS mounts for shell completion
+func (a *Aws) completeAccounts() ([]string, error) {
+	vault, err := a.stim.NewVault()
+	if err != nil {
+		return nil, err
+	}
+	return vault.GetMounts("aws")
+}
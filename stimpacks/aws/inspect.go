@@ -0,0 +1,207 @@
+package aws
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	stimaws "github.com/PremiereGlobal/stim/pkg/aws"
+)
+
+// ListTables prints the DynamoDB tables of the region with their estimated
+// item counts and sizes
+func (a *Aws) ListTables() error {
+
+	err := a.createInspectSession("aws-dynamodb", "")
+	if err != nil {
+		return err
+	}
+
+	names, err := a.aws.ListTables()
+	if err != nil {
+		return err
+	}
+
+	tables := []*stimaws.Table{}
+	for _, name := range names {
+		table, err := a.aws.DescribeTable(name)
+		if err != nil {
+			return err
+		}
+		tables = append(tables, table)
+	}
+
+	return a.stim.PrintOutput(a.stim.ConfigGetString("aws-dynamodb-output"), tables, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "TABLE\tSTATUS\tITEMS (EST.)\tSIZE (EST.)\tBILLING\tCREATED")
+		for _, t := range tables {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\n", t.Name, t.Status, t.ItemCount, formatBytes(t.SizeBytes), t.BillingMode, a.stim.FormatTime(t.Created))
+		}
+	})
+}
+
+// DescribeTable prints the status, estimated size and keys of a DynamoDB
+// table
+func (a *Aws) DescribeTable(name string) error {
+
+	err := a.createInspectSession("aws-dynamodb", "")
+	if err != nil {
+		return err
+	}
+
+	table, err := a.aws.DescribeTable(name)
+	if err != nil {
+		return err
+	}
+
+	return a.stim.PrintOutput(a.stim.ConfigGetString("aws-dynamodb-output"), table, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "Table:\t%s\n", table.Name)
+		fmt.Fprintf(w, "Status:\t%s\n", table.Status)
+		fmt.Fprintf(w, "Items (est.):\t%d\n", table.ItemCount)
+		fmt.Fprintf(w, "Size (est.):\t%s\n", formatBytes(table.SizeBytes))
+		fmt.Fprintf(w, "Partition Key:\t%s\n", table.PartitionKey)
+		if table.SortKey != "" {
+			fmt.Fprintf(w, "Sort Key:\t%s\n", table.SortKey)
+		}
+		if len(table.Indexes) > 0 {
+			fmt.Fprintf(w, "Indexes:\t%s\n", strings.Join(table.Indexes, ", "))
+		}
+		if table.StreamViewType != "" {
+			fmt.Fprintf(w, "Stream:\t%s\n", table.StreamViewType)
+		}
+		fmt.Fprintf(w, "Billing:\t%s\n", table.BillingMode)
+		fmt.Fprintf(w, "Created:\t%s\n", a.stim.FormatTime(table.Created))
+	})
+}
+
+// ListBuckets prints the S3 buckets of the account
+func (a *Aws) ListBuckets() error {
+
+	err := a.createInspectSession("aws-s3", "")
+	if err != nil {
+		return err
+	}
+
+	buckets, err := a.aws.ListBuckets()
+	if err != nil {
+		return err
+	}
+
+	return a.stim.PrintOutput(a.stim.ConfigGetString("aws-s3-output"), buckets, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "BUCKET\tCREATED")
+		for _, b := range buckets {
+			fmt.Fprintf(w, "%s\t%s\n", b.Name, a.stim.FormatTime(b.Created))
+		}
+	})
+}
+
+// bucketSummary is the object count and most recent objects of a bucket
+// prefix
+type bucketSummary struct {
+	Bucket    string            `json:"bucket"`
+	Prefix    string            `json:"prefix"`
+	Objects   int               `json:"objects"`
+	Truncated bool              `json:"truncated"`
+	SizeBytes int64             `json:"sizeBytes"`
+	Recent    []*stimaws.Object `json:"recent"`
+}
+
+// InspectBucket prints the number of objects under a prefix of an S3 bucket,
+// counting up to --max-scan objects, and the most recently modified ones
+func (a *Aws) InspectBucket(bucket string) error {
+
+	err := a.createInspectSession("aws-s3", bucket)
+	if err != nil {
+		return err
+	}
+
+	prefix := a.stim.ConfigGetString("aws-s3-prefix")
+	objects, truncated, err := a.aws.ListObjects(bucket, prefix, a.stim.ConfigGetInt("aws-s3-max-scan"))
+	if err != nil {
+		return err
+	}
+
+	summary := summarizeObjects(bucket, prefix, objects, truncated, a.stim.ConfigGetInt("aws-s3-recent"))
+
+	return a.stim.PrintOutput(a.stim.ConfigGetString("aws-s3-output"), summary, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "Objects in s3://%s/%s: %s (%s)\n\n", bucket, prefix, formatCount(summary.Objects, truncated), formatBytes(summary.SizeBytes))
+		if len(summary.Recent) == 0 {
+			return
+		}
+		fmt.Fprintln(w, "LAST MODIFIED\tSIZE\tSTORAGE CLASS\tKEY")
+		for _, o := range summary.Recent {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", a.stim.FormatTime(o.LastModified), formatBytes(o.Size), o.StorageClass, o.Key)
+		}
+	})
+}
+
+// createInspectSession creates an AWS session with the profile and region of
+// the command (or the default credentials).  Without a region, the session
+// of a bucket uses the region of the bucket.
+func (a *Aws) createInspectSession(prefix string, bucket string) error {
+
+	profile := a.stim.ConfigGetString(prefix + "-profile")
+	region := a.stim.ConfigGetString(prefix + "-region")
+
+	var err error
+	a.aws, err = a.stim.NewAws("", "")
+	if err != nil {
+		return err
+	}
+	err = a.aws.CreateDefaultSession(profile, region)
+	if err != nil || bucket == "" || region != "" {
+		return err
+	}
+
+	region, err = a.aws.GetBucketRegion(bucket)
+	if err != nil {
+		return err
+	}
+	a.log.Debug("Bucket {} is in {}", bucket, region)
+	return a.aws.CreateDefaultSession(profile, region)
+}
+
+// summarizeObjects returns the count and total size of the objects and the
+// given number of most recently modified ones
+func summarizeObjects(bucket string, prefix string, objects []*stimaws.Object, truncated bool, recent int) *bucketSummary {
+
+	summary := &bucketSummary{Bucket: bucket, Prefix: prefix, Objects: len(objects), Truncated: truncated}
+	for _, o := range objects {
+		summary.SizeBytes += o.Size
+	}
+
+	sorted := make([]*stimaws.Object, len(objects))
+	copy(sorted, objects)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].LastModified.After(sorted[j].LastModified)
+	})
+	if recent < len(sorted) {
+		sorted = sorted[:recent]
+	}
+	summary.Recent = sorted
+
+	return summary
+}
+
+// formatCount formats an object count, which is a lower bound if the listing
+// was truncated
+func formatCount(count int, truncated bool) string {
+	if truncated {
+		return fmt.Sprintf("more than %d", count)
+	}
+	return fmt.Sprintf("%d", count)
+}
+
+// formatBytes formats a size in bytes with a binary unit (ex. 1.5 GiB)
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,43 @@
+package aws
+
+import (
+	"testing"
+	"time"
+
+	stimaws "github.com/PremiereGlobal/stim/pkg/aws"
+	"gotest.tools/assert"
+)
+
+func TestSummarizeObjects(t *testing.T) {
+	now := time.Now()
+	objects := []*stimaws.Object{
+		{Key: "logs/a", Size: 100, LastModified: now.Add(-3 * time.Hour)},
+		{Key: "logs/b", Size: 200, LastModified: now.Add(-1 * time.Hour)},
+		{Key: "logs/c", Size: 300, LastModified: now.Add(-2 * time.Hour)},
+	}
+
+	summary := summarizeObjects("bucket", "logs/", objects, true, 2)
+	assert.Equal(t, summary.Objects, 3)
+	assert.Equal(t, summary.SizeBytes, int64(600))
+	assert.Assert(t, summary.Truncated)
+	assert.Equal(t, len(summary.Recent), 2)
+	assert.Equal(t, summary.Recent[0].Key, "logs/b")
+	assert.Equal(t, summary.Recent[1].Key, "logs/c")
+
+	// The listing order is not changed
+	assert.Equal(t, objects[0].Key, "logs/a")
+
+	summary = summarizeObjects("bucket", "", objects, false, 20)
+	assert.Equal(t, len(summary.Recent), 3)
+}
+
+func TestFormatCount(t *testing.T) {
+	assert.Equal(t, formatCount(42, false), "42")
+	assert.Equal(t, formatCount(10000, true), "more than 10000")
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, formatBytes(512), "512 B")
+	assert.Equal(t, formatBytes(1536), "1.5 KiB")
+	assert.Equal(t, formatBytes(5*1024*1024*1024), "5.0 GiB")
+}
@@ -0,0 +1,216 @@
+package aws
+
+import (
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/i18n"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// iamMaxAccessKeys is the maximum number of access keys an IAM user can have
+const iamMaxAccessKeys = 2
+
+// keyOutput is an access key in the JSON output of `stim aws keys list`,
+// with exact timestamps and ages
+type keyOutput struct {
+	UserName        string     `json:"userName"`
+	ID              string     `json:"accessKeyId"`
+	Status          string     `json:"status"`
+	Created         time.Time  `json:"created"`
+	AgeSeconds      int64      `json:"ageSeconds"`
+	LastUsed        *time.Time `json:"lastUsed,omitempty"`
+	LastUsedService string     `json:"lastUsedService,omitempty"`
+	LastUsedRegion  string     `json:"lastUsedRegion,omitempty"`
+	Rotate          bool       `json:"rotate"`
+}
+
+// ListKeys prints the IAM access keys of a user along with their age and
+// when they were last used
+func (a *Aws) ListKeys() error {
+
+	err := a.createKeySession()
+	if err != nil {
+		return err
+	}
+
+	keys, err := a.aws.ListAccessKeys(a.stim.ConfigGetString("aws-keys-user"))
+	if err != nil {
+		return err
+	}
+
+	format := a.stim.ConfigGetString("aws-keys-list-output")
+	if len(keys) == 0 && format != stim.OutputJSON {
+		fmt.Println("No access keys found")
+		return nil
+	}
+
+	maxAge := time.Duration(a.stim.ConfigGetInt("aws.keys.max-age-days")) * 24 * time.Hour
+
+	output := make([]*keyOutput, 0, len(keys))
+	for _, k := range keys {
+		key := &keyOutput{
+			UserName:        k.UserName,
+			ID:              k.ID,
+			Status:          k.Status,
+			Created:         k.Created,
+			AgeSeconds:      int64(k.Age().Seconds()),
+			LastUsedService: k.LastUsedService,
+			LastUsedRegion:  k.LastUsedRegion,
+			Rotate:          k.Age() > maxAge,
+		}
+		if !k.LastUsed.IsZero() {
+			lastUsed := k.LastUsed
+			key.LastUsed = &lastUsed
+		}
+		output = append(output, key)
+	}
+
+	return a.stim.PrintOutput(format, output, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "USER\tACCESS KEY ID\tSTATUS\tCREATED\tAGE\tLAST USED\tSERVICE\tREGION\tNOTE")
+		for _, k := range output {
+			lastUsed := "never"
+			if k.LastUsed != nil {
+				lastUsed = a.stim.FormatRelative(*k.LastUsed)
+			}
+			expired := ""
+			if k.Rotate {
+				expired = "ROTATE"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", k.UserName, k.ID, k.Status, a.stim.FormatTime(k.Created),
+				a.stim.FormatDuration(time.Duration(k.AgeSeconds)*time.Second), lastUsed, k.LastUsedService, k.LastUsedRegion, expired)
+		}
+	})
+}
+
+// RotateKey replaces the access key of the configured profile with a new one,
+// updates every profile using the old key, then deactivates and (optionally)
+// deletes the old key.  Only keys stored in the credentials file can be
+// rotated, otherwise the new secret would have nowhere to go.
+func (a *Aws) RotateKey() error {
+
+	err := a.createKeySession()
+	if err != nil {
+		return err
+	}
+
+	user := a.stim.ConfigGetString("aws-keys-user")
+
+	oldKeyID, err := a.aws.GetAccessKeyID()
+	if err != nil {
+		return err
+	}
+
+	keys, err := a.aws.ListAccessKeys(user)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, k := range keys {
+		found = found || k.ID == oldKeyID
+	}
+	if !found {
+		return fmt.Errorf("Access key %s of the profile does not belong to the user, only static access keys can be rotated", oldKeyID)
+	}
+	if len(keys) >= iamMaxAccessKeys {
+		return fmt.Errorf("User already has %d access keys, delete the unused one before rotating", len(keys))
+	}
+
+	profiles, err := a.aws.FindAccessKeyProfiles(oldKeyID)
+	if err != nil {
+		return err
+	}
+	if len(profiles) == 0 {
+		return fmt.Errorf("Access key %s is not used by any profile in the AWS credentials file (it may be set in the environment), only keys stored in profiles can be rotated", oldKeyID)
+	}
+
+	newKey, err := a.aws.CreateAccessKey(user)
+	if err != nil {
+		return err
+	}
+	a.log.Info("Created access key {}", newKey.AccessKeyID)
+
+	// Make sure the new key works before saving it or disabling the old one
+	newAws, err := a.stim.NewAws(newKey.AccessKeyID, newKey.SecretAccessKey)
+	if err != nil {
+		return a.abortRotation(user, newKey.AccessKeyID, err)
+	}
+	err = newAws.VerifyActiveCreds()
+	if err != nil {
+		return a.abortRotation(user, newKey.AccessKeyID, err)
+	}
+
+	profiles, err = a.aws.ReplaceAccessKey(oldKeyID, newKey)
+	if err != nil {
+		return a.abortRotation(user, newKey.AccessKeyID, err)
+	}
+	if len(profiles) == 0 {
+		return a.abortRotation(user, newKey.AccessKeyID, fmt.Errorf("No profiles using access key %s were updated", oldKeyID))
+	}
+	for _, p := range profiles {
+		a.log.Info("Updated profile {}", p)
+	}
+
+	deactivate := a.stim.ConfigGetBool("aws-keys-deactivate")
+	if !deactivate && !a.stim.IsAutomated() {
+		deactivate, err = a.stim.PromptBool(a.stim.Message(i18n.MessageDeactivateAccessKey, oldKeyID), false, true)
+		if err != nil {
+			return err
+		}
+	}
+	if !deactivate {
+		a.log.Info("Old access key {} is still active", oldKeyID)
+		return nil
+	}
+
+	// The old key may no longer be usable by this session, so use the new one
+	err = newAws.DeactivateAccessKey(user, oldKeyID)
+	if err != nil {
+		return err
+	}
+	a.log.Info("Deactivated access key {}", oldKeyID)
+
+	remove := a.stim.ConfigGetBool("aws-keys-delete")
+	if !remove && !a.stim.IsAutomated() {
+		remove, err = a.stim.PromptBool(a.stim.Message(i18n.MessageDeleteAccessKey, oldKeyID), false, false)
+		if err != nil {
+			return err
+		}
+	}
+	if remove {
+		err = newAws.DeleteAccessKey(user, oldKeyID)
+		if err != nil {
+			return err
+		}
+		a.log.Info("Deleted access key {}", oldKeyID)
+	}
+
+	return nil
+}
+
+// abortRotation deletes the new access key of a failed rotation so that the
+// user isn't left with an unsaved key, and returns the rotation error
+func (a *Aws) abortRotation(user string, newKeyID string, rotateErr error) error {
+
+	err := a.aws.DeleteAccessKey(user, newKeyID)
+	if err != nil {
+		return fmt.Errorf("%v (unable to delete new access key %s, delete it manually: %v)", rotateErr, newKeyID, err)
+	}
+	a.log.Info("Deleted new access key {}, the old key is unchanged", newKeyID)
+
+	return rotateErr
+}
+
+// createKeySession creates an AWS session using the configured profile (or
+// the default credentials if not set)
+func (a *Aws) createKeySession() error {
+
+	var err error
+	a.aws, err = a.stim.NewAws("", "")
+	if err != nil {
+		return err
+	}
+	return a.aws.CreateDefaultSession(a.stim.ConfigGetString("aws-keys-profile"), "")
+}
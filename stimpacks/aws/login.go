@@ -6,6 +6,7 @@ import (
 	"time"
 
 	awspkg "github.com/PremiereGlobal/stim/pkg/aws"
+	"github.com/PremiereGlobal/stim/stim"
 	"github.com/skratchdot/open-golang/open"
 )
 
@@ -17,16 +18,24 @@ var stimURL = "https://github.com/PremiereGlobal/stim"
 type stimProfile struct {
 	SessionToken string `ini:"aws_session_token"`
 	LeaseID      string `ini:"vault_lease_id"`
+	Expiration   string `ini:"aws_expiration"`
 }
 
 // Login gets IAM or STS credentials
 func (a *Aws) Login() error {
 
 	// Create an unauthenticated Aws instance
-	a.aws = a.stim.Aws("", "")
+	var err error
+	a.aws, err = a.stim.NewAws("", "")
+	if err != nil {
+		return err
+	}
 
 	// Create a Vault instance
-	a.vault = a.stim.Vault()
+	a.vault, err = a.stim.NewVault()
+	if err != nil {
+		return err
+	}
 
 	// Prompt the user (or get from arguments) the account and role
 	account, role, err := a.GetCredentials()
@@ -63,7 +72,7 @@ func (a *Aws) Login() error {
 	onlyOutput := a.stim.ConfigGetBool("aws-output")
 
 	if stsLogin && a.stim.IsAutomated() {
-		a.log.Fatal(errors.New("IsAutomated is detected: web login can not be used."))
+		return stim.UsageError(errors.New("IsAutomated is detected: web login can not be used."))
 	}
 
 	secret, err := a.vault.AWScredentials(account, role)
@@ -90,6 +99,9 @@ func (a *Aws) Login() error {
 	}
 	a.log.Debug("AWS IAM Access Expiration: " + leaseSecret.String() + " from now")
 
+	// The credentials outlive the command
+	a.vault.KeepLeases()
+
 	if useProfiles {
 
 		// Construct our new base profile
@@ -100,7 +112,8 @@ func (a *Aws) Login() error {
 
 		// Construct our new stim profile
 		stimProfile := stimProfile{
-			LeaseID: secret.LeaseID,
+			LeaseID:    secret.LeaseID,
+			Expiration: a.stim.Clock().Now().Add(leaseSecret).UTC().Format(time.RFC3339),
 		}
 
 		defaultProfile := a.stim.ConfigGetBool("aws.default-profile")
@@ -119,7 +132,10 @@ func (a *Aws) Login() error {
 		}
 
 		a.aws.CreateSession(accessKey, secretKey)
-		a.aws.WaitForActiveCreds()
+		err = a.aws.VerifyActiveCreds()
+		if err != nil {
+			return stim.AuthError(err)
+		}
 
 		// Get the username from Vault for the current token
 		federatedUsername, err := a.vault.GetUsername()
@@ -132,9 +148,12 @@ func (a *Aws) Login() error {
 			federatedUsername = "UnknownUser"
 		}
 
-		federationCreds := a.aws.GetFederationToken(federatedUsername, webTtl)
+		federationCreds, err := a.aws.GetFederationToken(federatedUsername, webTtl)
+		if err != nil {
+			return stim.AuthError(err)
+		}
 		a.log.Debug("AWS Federated Access Key: " + *federationCreds.AccessKeyId)
-		a.log.Debug("AWS Federated Access Expires: " + federationCreds.Expiration.Sub(time.Now()).String() + " from now")
+		a.log.Debug("AWS Federated Access Expires: " + federationCreds.Expiration.Sub(a.stim.Clock().Now()).String() + " from now")
 		loginURL, err := awspkg.CreateAWSLoginURL(*federationCreds.AccessKeyId, *federationCreds.SecretAccessKey, *federationCreds.SessionToken, stimURL)
 		a.log.Trace("AWS Console Login URL: " + loginURL)
 		if err != nil {
@@ -0,0 +1,331 @@
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	awspkg "github.com/PremiereGlobal/stim/pkg/aws"
+)
+
+// The sources of stim-managed profiles
+const (
+	profileSourceVault = "vault"
+	profileSourceSSO   = "sso"
+)
+
+// managedProfile is a profile in the credentials file created by
+// `stim aws login --use-profiles` or `stim aws sso-login`
+type managedProfile struct {
+	name        string
+	source      string
+	accessKeyID string
+	leaseID     string
+	expiration  time.Time
+	account     string
+	role        string
+	startURL    string
+	region      string
+	isDefault   bool
+
+	// Set when the profile is refreshed
+	stale   bool
+	profile *awspkg.Profile
+	extra   interface{}
+	err     error
+}
+
+// Refresh refreshes the stim-managed profiles that are expired or expire
+// within the threshold.  Credentials are fetched concurrently and then saved,
+// and a summary table is printed.
+func (a *Aws) Refresh(names []string) error {
+
+	var err error
+	a.aws, err = a.stim.NewAws("", "")
+	if err != nil {
+		return err
+	}
+
+	all := a.stim.ConfigGetBool("aws-refresh-all")
+	if !all && len(names) == 0 {
+		return errors.New("Profile name(s) or --all must be given")
+	}
+
+	threshold, err := time.ParseDuration(a.stim.ConfigGetString("aws-refresh-threshold"))
+	if err != nil {
+		return fmt.Errorf("Invalid --threshold: %v", err)
+	}
+
+	profiles, err := a.getManagedProfiles()
+	if err != nil {
+		return err
+	}
+	if !all {
+		profiles, err = selectProfiles(profiles, names)
+		if err != nil {
+			return err
+		}
+	}
+	if len(profiles) == 0 {
+		fmt.Println("No stim-managed profiles found")
+		return nil
+	}
+
+	// Vault credentials saved before expirations were recorded need a lease lookup
+	for _, p := range profiles {
+		if p.source == profileSourceVault && p.expiration.IsZero() && p.leaseID != "" {
+			vault, err := a.stim.NewVault()
+			if err != nil {
+				return err
+			}
+			ttl, err := vault.LookupLease(p.leaseID)
+			if err != nil {
+				a.log.Debug("Unable to look up lease of profile {}: {}", p.name, err)
+				continue
+			}
+			p.expiration = a.stim.Clock().Now().Add(ttl)
+		}
+		p.stale = p.expiration.IsZero() || p.expiration.Sub(a.stim.Clock().Now()) < threshold
+	}
+
+	ssoTokens := a.getRefreshSSOTokens(profiles)
+
+	// Log in to Vault (which may prompt) before the concurrent refresh
+	for _, p := range profiles {
+		if p.stale && p.source == profileSourceVault {
+			_, err := a.stim.NewVault()
+			if err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, p := range profiles {
+		if !p.stale {
+			continue
+		}
+		wg.Add(1)
+		go func(p *managedProfile) {
+			defer wg.Done()
+			if p.source == profileSourceVault {
+				p.err = a.refreshVaultProfile(p)
+			} else {
+				p.err = a.refreshSSOProfile(p, ssoTokens[p.startURL])
+			}
+		}(p)
+	}
+	wg.Wait()
+
+	// The credentials file is written once the concurrent refreshes are done
+	failed := 0
+	for _, p := range profiles {
+		if p.stale && p.err == nil {
+			p.err = a.aws.SaveProfile(p.name, p.profile, p.isDefault, p.extra)
+		}
+		if p.err != nil {
+			failed++
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROFILE\tSOURCE\tEXPIRES\tSTATUS")
+	for _, p := range profiles {
+		expires := "unknown"
+		if !p.expiration.IsZero() {
+			expires = a.stim.FormatRelative(p.expiration)
+		}
+		status := "ok"
+		switch {
+		case p.err != nil:
+			status = "failed: " + p.err.Error()
+		case p.stale:
+			status = "refreshed"
+		}
+		if p.isDefault {
+			status += " (default)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", p.name, p.source, expires, status)
+	}
+	w.Flush()
+
+	if failed > 0 {
+		return fmt.Errorf("%d profile(s) could not be refreshed", failed)
+	}
+
+	return nil
+}
+
+// getManagedProfiles returns the stim-managed profiles in the credentials
+// file sorted by name.  The default profile is not returned separately, it is
+// updated along with the profile it is a copy of.
+func (a *Aws) getManagedProfiles() ([]*managedProfile, error) {
+
+	sections, err := a.aws.GetProfiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles []*managedProfile
+	for name, keys := range sections {
+		if name == "default" {
+			continue
+		}
+
+		p := &managedProfile{name: name, accessKeyID: keys["aws_access_key_id"]}
+		if expiration, ok := keys["aws_expiration"]; ok {
+			p.expiration, _ = time.Parse(time.RFC3339, expiration)
+		}
+
+		switch {
+		case keys["vault_lease_id"] != "":
+			p.source = profileSourceVault
+			p.leaseID = keys["vault_lease_id"]
+			parts := strings.SplitN(name, "/", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			p.account, p.role = parts[0], parts[1]
+		case keys["stim_sso_account_id"] != "":
+			p.source = profileSourceSSO
+			p.startURL = keys["stim_sso_start_url"]
+			p.region = keys["stim_sso_region"]
+			p.account = keys["stim_sso_account_id"]
+			p.role = keys["stim_sso_role_name"]
+		default:
+			continue
+		}
+
+		if p.accessKeyID != "" && sections["default"]["aws_access_key_id"] == p.accessKeyID {
+			p.isDefault = true
+		}
+		profiles = append(profiles, p)
+	}
+
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].name < profiles[j].name
+	})
+
+	return profiles, nil
+}
+
+// selectProfiles returns the named profiles
+func selectProfiles(profiles []*managedProfile, names []string) ([]*managedProfile, error) {
+	var selected []*managedProfile
+	for _, name := range names {
+		found := false
+		for _, p := range profiles {
+			if p.name == name {
+				selected = append(selected, p)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("Profile '%s' is not a stim-managed profile", name)
+		}
+	}
+	return selected, nil
+}
+
+// getRefreshSSOTokens returns the SSO token for the start URL of each stale
+// SSO profile.  Logins happen one at a time before the concurrent refresh
+// since they may need the user to approve them in the browser.
+func (a *Aws) getRefreshSSOTokens(profiles []*managedProfile) map[string]*awspkg.SSOToken {
+
+	tokens := make(map[string]*awspkg.SSOToken)
+	for _, p := range profiles {
+		if !p.stale || p.source != profileSourceSSO {
+			continue
+		}
+		if _, ok := tokens[p.startURL]; ok {
+			continue
+		}
+
+		token, err := a.aws.GetCachedSSOToken(p.startURL)
+		if err == nil && token == nil && !a.stim.IsAutomated() {
+			token, err = a.aws.SSOLogin(p.startURL, p.region, func(auth *awspkg.SSODeviceAuthorization) {
+				fmt.Printf("Approve the AWS SSO login for %s in your browser. Verify the code matches:\n", p.startURL)
+				fmt.Printf("\n    %s\n\n", auth.UserCode)
+				fmt.Printf("Visit:\n\n    %s\n\n", auth.VerificationURIComplete)
+			})
+		}
+		if err != nil {
+			a.log.Warn("Unable to get SSO token for {}: {}", p.startURL, err)
+		}
+		tokens[p.startURL] = token
+	}
+
+	return tokens
+}
+
+// refreshVaultProfile gets new credentials for the account and role of the
+// profile from Vault
+func (a *Aws) refreshVaultProfile(p *managedProfile) error {
+
+	ttl, err := time.ParseDuration(a.stim.ConfigGetString("aws.ttl"))
+	if err != nil {
+		return fmt.Errorf("Error parsing config value aws.ttl: %s", a.stim.ConfigGetString("aws.ttl"))
+	}
+
+	vault, err := a.stim.NewVault()
+	if err != nil {
+		return err
+	}
+	secret, err := vault.AWScredentials(p.account, p.role)
+	if err != nil {
+		return err
+	}
+	leaseTTL, err := vault.RenewLease(secret.LeaseID, ttl)
+	if err != nil {
+		return err
+	}
+
+	vault.KeepLeases()
+
+	p.expiration = a.stim.Clock().Now().Add(leaseTTL)
+	p.profile = &awspkg.Profile{
+		AccessKeyID:     secret.Data["access_key"].(string),
+		SecretAccessKey: secret.Data["secret_key"].(string),
+	}
+	p.extra = &stimProfile{
+		LeaseID:    secret.LeaseID,
+		Expiration: p.expiration.UTC().Format(time.RFC3339),
+	}
+
+	return nil
+}
+
+// refreshSSOProfile gets new role credentials for the profile through SSO
+func (a *Aws) refreshSSOProfile(p *managedProfile, token *awspkg.SSOToken) error {
+
+	if token == nil {
+		return errors.New("SSO login required, run `stim aws sso-login`")
+	}
+
+	creds, err := a.aws.GetSSORoleCredentials(token, p.account, p.role)
+	if err != nil {
+		return err
+	}
+
+	p.expiration = time.Unix(0, creds.Expiration*int64(time.Millisecond))
+	p.profile = &awspkg.Profile{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+	}
+	p.extra = &ssoProfile{
+		SessionToken: creds.SessionToken,
+		Expiration:   p.expiration.UTC().Format(time.RFC3339),
+		StartURL:     p.startURL,
+		Region:       p.region,
+		AccountID:    p.account,
+		RoleName:     p.role,
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	awspkg "github.com/PremiereGlobal/stim/pkg/aws"
+	"github.com/skratchdot/open-golang/open"
+)
+
+// ssoProfile holds the additional fields we write for SSO credentials
+type ssoProfile struct {
+	SessionToken string `ini:"aws_session_token"`
+	Expiration   string `ini:"aws_expiration"`
+	StartURL     string `ini:"stim_sso_start_url"`
+	Region       string `ini:"stim_sso_region"`
+	AccountID    string `ini:"stim_sso_account_id"`
+	RoleName     string `ini:"stim_sso_role_name"`
+}
+
+// SSOLogin gets temporary role credentials through AWS IAM Identity Center (SSO)
+func (a *Aws) SSOLogin() error {
+
+	var err error
+	a.aws, err = a.stim.NewAws("", "")
+	if err != nil {
+		return err
+	}
+
+	startURL := a.stim.ConfigGetString("aws.sso.start-url")
+	if startURL == "" {
+		return errors.New("SSO start URL not specified, set aws.sso.start-url or use --start-url")
+	}
+	region := a.stim.ConfigGetString("aws.sso.region")
+	if region == "" {
+		return errors.New("SSO region not specified, set aws.sso.region or use --sso-region")
+	}
+
+	onlyOutput := a.stim.ConfigGetBool("aws-sso-output")
+	token, err := a.aws.SSOLogin(startURL, region, func(auth *awspkg.SSODeviceAuthorization) {
+		fmt.Println("Approve the AWS SSO login in your browser. Verify the code matches:")
+		fmt.Printf("\n    %s\n\n", auth.UserCode)
+		fmt.Printf("If the browser does not open, visit:\n\n    %s\n\n", auth.VerificationURIComplete)
+		if !onlyOutput {
+			err := open.Start(auth.VerificationURIComplete)
+			if err != nil {
+				a.log.Warn("Unable to launch browser: {}", err)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	accountID, err := a.getSSOAccount(token)
+	if err != nil {
+		return err
+	}
+
+	roleName := a.stim.ConfigGetString("aws-sso-role-name")
+	if roleName == "" {
+		if a.stim.IsAutomated() {
+			return errors.New("IsAutomated is detected: --role-name must be specified")
+		}
+		roles, err := a.aws.ListSSOAccountRoles(token, accountID)
+		if err != nil {
+			return err
+		}
+		if len(roles) == 0 {
+			return fmt.Errorf("No SSO roles available in account %s", accountID)
+		}
+		roleName, err = a.stim.PromptList("Select Role", roles, "")
+		if err != nil {
+			return err
+		}
+	}
+	a.log.Debug("Account: {} Role: {}", accountID, roleName)
+
+	creds, err := a.aws.GetSSORoleCredentials(token, accountID, roleName)
+	if err != nil {
+		return err
+	}
+	expiration := time.Unix(0, creds.Expiration*int64(time.Millisecond))
+	a.log.Debug("AWS SSO Access Key: " + creds.AccessKeyID)
+	a.log.Debug("AWS SSO Access Expiration: {}", a.stim.FormatRelative(expiration))
+
+	profileName := a.stim.ConfigGetString("aws-sso-profile")
+	if profileName == "" {
+		profileName = accountID + "/" + roleName
+	}
+
+	profile := awspkg.Profile{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+	}
+	ssoProfile := ssoProfile{
+		SessionToken: creds.SessionToken,
+		Expiration:   expiration.UTC().Format(time.RFC3339),
+		StartURL:     startURL,
+		Region:       region,
+		AccountID:    accountID,
+		RoleName:     roleName,
+	}
+
+	defaultProfile := a.stim.ConfigGetBool("aws.sso.default-profile")
+	if defaultProfile {
+		a.log.Debug("Setting {} credentials as default", profileName)
+	}
+	err = a.aws.SaveProfile(profileName, &profile, defaultProfile, &ssoProfile)
+	if err != nil {
+		return err
+	}
+
+	if a.stim.ConfigGetBool("aws-sso-source") {
+		fmt.Println("export AWS_ACCESS_KEY_ID=" + creds.AccessKeyID)
+		fmt.Println("export AWS_SECRET_ACCESS_KEY=" + creds.SecretAccessKey)
+		fmt.Println("export AWS_SESSION_TOKEN=" + creds.SessionToken)
+	} else {
+		a.log.Info("Saved credentials to profile {} (expires {})", profileName, a.stim.FormatTime(expiration))
+	}
+
+	return nil
+}
+
+// getSSOAccount returns the configured account ID or prompts the user to
+// select one of the accounts available through SSO
+func (a *Aws) getSSOAccount(token *awspkg.SSOToken) (string, error) {
+
+	accountID := a.stim.ConfigGetString("aws-sso-account-id")
+	if accountID != "" {
+		return accountID, nil
+	}
+	if a.stim.IsAutomated() {
+		return "", errors.New("IsAutomated is detected: --account-id must be specified")
+	}
+
+	accounts, err := a.aws.ListSSOAccounts(token)
+	if err != nil {
+		return "", err
+	}
+	if len(accounts) == 0 {
+		return "", errors.New("No AWS accounts available through SSO")
+	}
+
+	names := make([]string, len(accounts))
+	ids := make(map[string]string)
+	for i, account := range accounts {
+		names[i] = fmt.Sprintf("%s (%s)", account.AccountName, account.AccountID)
+		ids[names[i]] = account.AccountID
+	}
+
+	selected, err := a.stim.PromptList("Select Account", names, "")
+	if err != nil {
+		return "", err
+	}
+
+	return ids[selected], nil
+}
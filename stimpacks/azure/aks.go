@@ -0,0 +1,254 @@
+package azure
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"text/tabwriter"
+
+	azurepkg "github.com/PremiereGlobal/stim/pkg/azure"
+	"github.com/PremiereGlobal/stim/pkg/kubernetes"
+	stimvault "github.com/PremiereGlobal/stim/pkg/vault"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// listClusters lists the AKS clusters of the subscription
+func (a *Azure) listClusters() error {
+
+	client, err := a.client()
+	if err != nil {
+		return err
+	}
+	defer a.saveToken(client)
+	subscription, err := a.getSubscription(client)
+	if err != nil {
+		return err
+	}
+
+	clusters, err := client.ManagedClusters(subscription)
+	if err != nil {
+		return err
+	}
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].Name < clusters[j].Name })
+
+	return a.stim.PrintOutput(a.stim.ConfigGetString("azure-aks-output"), clusters, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "NAME\tRESOURCE GROUP\tLOCATION\tVERSION\tSTATE")
+		for _, cluster := range clusters {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", cluster.Name, cluster.ResourceGroup(), cluster.Location, cluster.Properties.KubernetesVersion, cluster.Properties.PowerState.Code)
+		}
+	})
+}
+
+// getCredentials creates a kubeconfig context for an AKS cluster and, with
+// --vault-service-account, writes its credentials to the kube-config secret
+// in Vault
+func (a *Azure) getCredentials(args []string) error {
+
+	serviceAccount := a.stim.ConfigGetString("azure-aks-vault-service-account")
+	if serviceAccount != "" && a.stim.IsReadOnly() {
+		return stim.UsageError(errors.New("--vault-service-account writes to Vault and can't be used in read-only mode"))
+	}
+
+	client, err := a.client()
+	if err != nil {
+		return err
+	}
+	defer a.saveToken(client)
+	subscription, err := a.getSubscription(client)
+	if err != nil {
+		return err
+	}
+
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	}
+	resourceGroup := a.stim.ConfigGetString("azure-aks-resource-group")
+	if name == "" || resourceGroup == "" {
+		cluster, err := a.findCluster(client, subscription, name)
+		if err != nil {
+			return err
+		}
+		name = cluster.Name
+		resourceGroup = cluster.ResourceGroup()
+	}
+
+	kubeconfig, err := client.ManagedClusterUserKubeconfig(subscription, resourceGroup, name)
+	if err != nil {
+		return err
+	}
+	credentials, err := azurepkg.KubeconfigCredentials(kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	context := a.stim.ConfigGetString("azure-aks-context")
+	if context == "" {
+		context = name
+	}
+	namespace := a.stim.ConfigGetString("azure-aks-namespace")
+
+	kubeConfig := kubernetes.NewConfig()
+	err = kubeConfig.Modify(&kubernetes.ConfigOptions{
+		ClusterName:             context,
+		ClusterServer:           credentials.Server,
+		ClusterCA:               credentials.CA,
+		AuthName:                context + "-aks",
+		AuthToken:               credentials.Token,
+		ContextName:             context,
+		ContextSetCurrent:       !a.stim.ConfigGetBool("azure-aks-keep-current-context"),
+		ContextDefaultNamespace: namespace,
+	})
+	if err != nil {
+		return err
+	}
+	a.log.Info("Created context {} for AKS cluster {}/{}", context, resourceGroup, name)
+
+	if serviceAccount != "" {
+		path := a.stim.KubeConfigSecretPath(context, serviceAccount)
+		vault, err := a.stim.NewVault()
+		if err != nil {
+			return err
+		}
+		existing, err := a.existingKubeConfigKeys(vault, path)
+		if err != nil {
+			return err
+		}
+		err = vault.WriteSecretKeys(path, vaultKubeConfigKeys(existing, credentials, namespace))
+		if err != nil {
+			return err
+		}
+		a.log.Info("Wrote the credentials of {} to {}, deploys can use them with `kubernetes.cluster: {}` and `kubernetes.serviceAccount: {}`", context, path, context, serviceAccount)
+	}
+
+	return nil
+}
+
+// findCluster returns the AKS cluster with the name, or prompts for one if
+// the name is empty
+func (a *Azure) findCluster(client *azurepkg.Azure, subscription string, name string) (*azurepkg.ManagedCluster, error) {
+
+	clusters, err := client.ManagedClusters(subscription)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		if a.stim.IsAutomated() {
+			return nil, stim.UsageError(errors.New("IsAutomated is detected: the cluster must be specified"))
+		}
+		names := make([]string, len(clusters))
+		for i, cluster := range clusters {
+			names[i] = cluster.Name
+		}
+		sort.Strings(names)
+		name, err = a.stim.PromptList("Select Cluster", names, "")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var found *azurepkg.ManagedCluster
+	for _, cluster := range clusters {
+		if cluster.Name != name {
+			continue
+		}
+		if found != nil {
+			return nil, stim.UsageError(fmt.Errorf("There are AKS clusters named %s in resource groups %s and %s, use --resource-group", name, found.ResourceGroup(), cluster.ResourceGroup()))
+		}
+		found = cluster
+	}
+	if found == nil {
+		return nil, fmt.Errorf("AKS cluster %s not found in subscription %s", name, subscription)
+	}
+
+	return found, nil
+}
+
+// getSubscription returns the subscription from --subscription or the
+// `azure.subscription-id` config, or prompts for one
+func (a *Azure) getSubscription(client *azurepkg.Azure) (string, error) {
+
+	subscription := a.stim.ConfigGetString("azure-aks-subscription")
+	if subscription == "" {
+		subscription = a.stim.ConfigGetString("azure.subscription-id")
+	}
+	if subscription != "" {
+		return subscription, nil
+	}
+	if a.stim.IsAutomated() {
+		return "", stim.UsageError(errors.New("IsAutomated is detected: --subscription must be specified"))
+	}
+
+	subscriptions, err := client.Subscriptions()
+	if err != nil {
+		return "", err
+	}
+	if len(subscriptions) == 0 {
+		return "", errors.New("No Azure subscriptions available")
+	}
+
+	names := make([]string, len(subscriptions))
+	ids := make(map[string]string)
+	for i, s := range subscriptions {
+		names[i] = fmt.Sprintf("%s (%s)", s.Name, s.ID)
+		ids[names[i]] = s.ID
+	}
+	selected, err := a.stim.PromptList("Select Subscription", names, "")
+	if err != nil {
+		return "", err
+	}
+
+	return ids[selected], nil
+}
+
+// client returns an Azure client logged in as the service principal of
+// `azure.vault-account`/`azure.vault-role`, or with the token of `stim azure
+// login --device-code`
+func (a *Azure) client() (*azurepkg.Azure, error) {
+	client, err := a.stim.NewAzure(a.stim.ConfigGetString("azure.vault-account"), a.stim.ConfigGetString("azure.vault-role"))
+	if err != nil {
+		return nil, stim.AuthError(err)
+	}
+	return client, nil
+}
+
+// saveToken saves the token of a device code login, which the client may
+// have refreshed, for the next commands
+func (a *Azure) saveToken(client *azurepkg.Azure) {
+	if a.stim.ConfigGetString("azure.vault-account") != "" {
+		return
+	}
+	err := a.stim.SaveAzureToken(client.Token())
+	if err != nil {
+		a.log.Debug("Unable to save the Azure token: {}", err)
+	}
+}
+
+// existingKubeConfigKeys returns the keys of the kube-config secret, if it
+// exists
+func (a *Azure) existingKubeConfigKeys(vault *stimvault.Vault, path string) (map[string]string, error) {
+	keys, err := vault.GetSecretKeys(path)
+	if stimvault.IsNotFound(err) {
+		a.log.Debug("No existing kube-config secret {}", path)
+		return nil, nil
+	}
+	return keys, err
+}
+
+// vaultKubeConfigKeys returns the keys of the kube-config secret with the
+// credentials of an AKS cluster.  The other keys of the existing secret are
+// kept.
+func vaultKubeConfigKeys(existing map[string]string, credentials *azurepkg.ClusterCredentials, namespace string) map[string]string {
+	keys := make(map[string]string, len(existing)+4)
+	for key, value := range existing {
+		keys[key] = value
+	}
+	keys["cluster-server"] = credentials.Server
+	keys["cluster-ca"] = credentials.CA
+	keys["user-token"] = credentials.Token
+	if namespace != "" {
+		keys["default-namespace"] = namespace
+	}
+	return keys
+}
@@ -0,0 +1,25 @@
+package azure
+
+import (
+	"testing"
+
+	azurepkg "github.com/PremiereGlobal/stim/pkg/azure"
+	"gotest.tools/assert"
+)
+
+func TestVaultKubeConfigKeys(t *testing.T) {
+	credentials := &azurepkg.ClusterCredentials{Server: "https://aks-1", CA: "new-ca", Token: "new-token"}
+
+	keys := vaultKubeConfigKeys(nil, credentials, "")
+	assert.DeepEqual(t, keys, map[string]string{"cluster-server": "https://aks-1", "cluster-ca": "new-ca", "user-token": "new-token"})
+
+	existing := map[string]string{"cluster-server": "https://old", "user-token": "old-token", "default-namespace": "web", "owner": "team-a"}
+	keys = vaultKubeConfigKeys(existing, credentials, "")
+	assert.Equal(t, keys["user-token"], "new-token")
+	assert.Equal(t, keys["default-namespace"], "web")
+	assert.Equal(t, keys["owner"], "team-a")
+	assert.Equal(t, existing["user-token"], "old-token")
+
+	keys = vaultKubeConfigKeys(existing, credentials, "api")
+	assert.Equal(t, keys["default-namespace"], "api")
+}
@@ -0,0 +1,22 @@
+package azure
+
+import (
+	"github.com/PremiereGlobal/stim/pkg/stimlog"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// Azure is the stimpack that logs in to Azure and configures AKS clusters
+type Azure struct {
+	name string
+	stim *stim.Stim
+	log  stimlog.StimLogger
+}
+
+func New() *Azure {
+	azure := &Azure{name: "azure"}
+	return azure
+}
+
+func (a *Azure) Name() string {
+	return a.name
+}
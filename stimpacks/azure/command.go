@@ -0,0 +1,112 @@
+package azure
+
+import (
+	"github.com/PremiereGlobal/stim/stim"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func (a *Azure) BindStim(s *stim.Stim) {
+	a.stim = s
+	a.log = s.GetLogger()
+}
+
+func (a *Azure) Command(viper *viper.Viper) *cobra.Command {
+
+	var cmd = &cobra.Command{
+		Use:   "azure",
+		Short: "Interact with Azure",
+		Long:  "Get Azure credentials and configure AKS clusters",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	var loginCmd = &cobra.Command{
+		Use:   "login",
+		Short: "azure login",
+		Long:  "Get the credentials of a service principal from a Vault Azure secrets engine role as ARM_* env vars (used by terraform), or log in as yourself with a device code",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.Login()
+		},
+	}
+	a.stim.BindCommand(loginCmd, cmd)
+
+	loginCmd.Flags().StringP("account", "a", "", "Vault Azure secrets engine mount")
+	viper.BindPFlag("azure-account", loginCmd.Flags().Lookup("account"))
+	a.stim.BindFlagCompletion(loginCmd, "account", "azure-accounts", a.completeAccounts)
+
+	loginCmd.Flags().StringP("role", "r", "", "Vault Azure role")
+	viper.BindPFlag("azure-role", loginCmd.Flags().Lookup("role"))
+
+	loginCmd.Flags().Bool("device-code", false, "Log in as yourself with a device code instead of Vault.  The token is cached for the other `stim azure` commands")
+	viper.BindPFlag("azure-device-code", loginCmd.Flags().Lookup("device-code"))
+
+	loginCmd.Flags().String("tenant", "", "Azure AD tenant ID (Default: tenant of the Vault mount, or your home tenant with --device-code)")
+	viper.BindPFlag("azure.tenant-id", loginCmd.Flags().Lookup("tenant"))
+
+	loginCmd.Flags().String("subscription", "", "Subscription ID (Default: subscription of the Vault mount)")
+	viper.BindPFlag("azure.subscription-id", loginCmd.Flags().Lookup("subscription"))
+
+	loginCmd.Flags().BoolP("source", "s", false, "output env source for current shell")
+	viper.BindPFlag("azure-source", loginCmd.Flags().Lookup("source"))
+
+	loginCmd.Flags().BoolP("output", "o", false, "Output the device code URL to console (don't launch URL)")
+	viper.BindPFlag("azure-output", loginCmd.Flags().Lookup("output"))
+
+	var aksCmd = &cobra.Command{
+		Use:   "aks",
+		Short: "Configure AKS clusters",
+		Long:  "List AKS clusters and create kubeconfig contexts for them.  Commands log in as the service principal of `azure.vault-account`/`azure.vault-role` if set, otherwise with the token of `stim azure login --device-code`",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+	a.stim.BindCommand(aksCmd, cmd)
+
+	aksCmd.PersistentFlags().String("subscription", "", "Subscription ID (Default: azure.subscription-id, or prompts)")
+	viper.BindPFlag("azure-aks-subscription", aksCmd.PersistentFlags().Lookup("subscription"))
+
+	var aksListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List AKS clusters",
+		Long:  "List the AKS clusters of a subscription",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.listClusters()
+		},
+	}
+	a.stim.BindCommand(aksListCmd, aksCmd)
+
+	aksListCmd.Flags().StringP("output", "o", stim.OutputTable, "Output format (table|json)")
+	viper.BindPFlag("azure-aks-output", aksListCmd.Flags().Lookup("output"))
+
+	var aksCredentialsCmd = &cobra.Command{
+		Use:   "get-credentials [cluster]",
+		Short: "Create a kubeconfig context for an AKS cluster",
+		Long:  "Create a kubeconfig context for an AKS cluster with local accounts.  With --vault-service-account, the credentials are also written to the kube-config secret in Vault so that deploys with `kubernetes.cluster` set to the context name can use them",
+		Example: "  stim azure aks get-credentials aks-prod-westus2 --context prod-westus2\n" +
+			"  stim azure aks get-credentials aks-prod-westus2 --context prod-westus2 --vault-service-account deploy",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return a.getCredentials(args)
+		},
+	}
+	a.stim.BindCommand(aksCredentialsCmd, aksCmd)
+
+	aksCredentialsCmd.Flags().StringP("resource-group", "g", "", "Resource group of the cluster (Default: found from the cluster name)")
+	viper.BindPFlag("azure-aks-resource-group", aksCredentialsCmd.Flags().Lookup("resource-group"))
+
+	aksCredentialsCmd.Flags().StringP("context", "c", "", "Name of the context (Default: cluster name)")
+	viper.BindPFlag("azure-aks-context", aksCredentialsCmd.Flags().Lookup("context"))
+
+	aksCredentialsCmd.Flags().StringP("namespace", "n", "", "Default namespace of the context")
+	viper.BindPFlag("azure-aks-namespace", aksCredentialsCmd.Flags().Lookup("namespace"))
+
+	aksCredentialsCmd.Flags().Bool("keep-current-context", false, "Don't switch to the new context")
+	viper.BindPFlag("azure-aks-keep-current-context", aksCredentialsCmd.Flags().Lookup("keep-current-context"))
+
+	aksCredentialsCmd.Flags().String("vault-service-account", "", "Also write the credentials to the kube-config secret of the context name and this service account in Vault")
+	viper.BindPFlag("azure-aks-vault-service-account", aksCredentialsCmd.Flags().Lookup("vault-service-account"))
+
+	return cmd
+}
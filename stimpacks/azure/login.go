@@ -0,0 +1,141 @@
+package azure
+
+import (
+	"errors"
+	"fmt"
+
+	azurepkg "github.com/PremiereGlobal/stim/pkg/azure"
+	"github.com/PremiereGlobal/stim/stim"
+	"github.com/skratchdot/open-golang/open"
+)
+
+// Login gets the credentials of a service principal from Vault, or logs in
+// with a device code
+func (a *Azure) Login() error {
+
+	if a.stim.ConfigGetBool("azure-device-code") {
+		return a.deviceCodeLogin()
+	}
+
+	account, role, err := a.getAccountRole()
+	if err != nil {
+		return err
+	}
+	a.log.Debug("Account: {} Role: {}", account, role)
+
+	sp, err := a.stim.AzureServicePrincipal(account, role)
+	if err != nil {
+		return stim.AuthError(err)
+	}
+
+	// Check that the service principal works before handing it out
+	err = a.stim.AzureLogin(azurepkg.New(&azurepkg.Config{TenantID: sp.TenantID}), sp)
+	if err != nil {
+		return stim.AuthError(err)
+	}
+	a.log.Debug("Azure service principal: {} (Vault lease {})", sp.ClientID, sp.LeaseID)
+	vault, err := a.stim.NewVault()
+	if err != nil {
+		return err
+	}
+	vault.KeepLeases()
+
+	prefix := ""
+	if a.stim.ConfigGetBool("azure-source") {
+		prefix = "export "
+	}
+	for _, env := range sp.Envs() {
+		fmt.Println(prefix + env)
+	}
+
+	return nil
+}
+
+// deviceCodeLogin logs in as the user and caches the token for the other
+// `stim azure` commands
+func (a *Azure) deviceCodeLogin() error {
+
+	if a.stim.ConfigGetBool("azure-source") {
+		return stim.UsageError(errors.New("--source can only be used with Vault credentials, terraform can't use the token of a device code login"))
+	}
+	if a.stim.IsAutomated() {
+		return stim.UsageError(errors.New("IsAutomated is detected: --device-code can not be used"))
+	}
+
+	client := azurepkg.New(&azurepkg.Config{TenantID: a.stim.ConfigGetString("azure.tenant-id")})
+	onlyOutput := a.stim.ConfigGetBool("azure-output")
+	err := client.LoginDeviceCode(a.stim.ConfigGetString("azure.client-id"), func(code *azurepkg.DeviceCode) {
+		fmt.Println("Approve the Azure login in your browser with the code:")
+		fmt.Printf("\n    %s\n\n", code.UserCode)
+		fmt.Printf("If the browser does not open, visit:\n\n    %s\n\n", code.VerificationURI)
+		if !onlyOutput {
+			err := open.Start(code.VerificationURI)
+			if err != nil {
+				a.log.Warn("Unable to launch browser: {}", err)
+			}
+		}
+	})
+	if err != nil {
+		return stim.AuthError(err)
+	}
+
+	err = a.stim.SaveAzureToken(client.Token())
+	if err != nil {
+		return err
+	}
+	a.log.Info("Logged in to Azure tenant {} (token expires {})", client.Token().TenantID, a.stim.FormatTime(client.Token().ExpiresOn))
+
+	return nil
+}
+
+// getAccountRole returns the Vault Azure mount and role from the flags or
+// the `azure.vault-account` and `azure.vault-role` config, or prompts for
+// them
+func (a *Azure) getAccountRole() (string, string, error) {
+
+	vault, err := a.stim.NewVault()
+	if err != nil {
+		return "", "", err
+	}
+	account := a.stim.ConfigGetString("azure-account")
+	if account == "" {
+		account = a.stim.ConfigGetString("azure.vault-account")
+	}
+	if account == "" && a.stim.IsAutomated() {
+		return "", "", stim.UsageError(errors.New("Vault azure mount not specified"))
+	} else if account == "" {
+		mounts, err := vault.GetMounts("azure")
+		if err != nil {
+			return "", "", err
+		}
+		account, err = a.stim.PromptSearchList("Choose Azure account", mounts)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	role := a.stim.ConfigGetString("azure-role")
+	if role == "" {
+		role = a.stim.ConfigGetString("azure.vault-role")
+	}
+	if role == "" && a.stim.IsAutomated() {
+		return "", "", stim.UsageError(errors.New("Vault azure role not specified"))
+	} else if role == "" {
+		var err error
+		role, err = a.stim.PromptListVault(account+"/roles", "Select Role", "")
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	return account, role, nil
+}
+
+// completeAccounts returns the Vault Azure mounts for shell completion
+func (a *Azure) completeAccounts() ([]string, error) {
+	vault, err := a.stim.NewVault()
+	if err != nil {
+		return nil, err
+	}
+	return vault.GetMounts("azure")
+}
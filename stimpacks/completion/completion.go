@@ -27,8 +27,9 @@ func (c *Completion) BindStim(s *stim.Stim) {
 func (c *Completion) Command(viper *viper.Viper) *cobra.Command {
 
 	var cmd = &cobra.Command{
-		Use:   "completion SHELL",
-		Short: "Output shell completion for the given shell (bash or zsh)",
+		Use:         "completion SHELL",
+		Annotations: map[string]string{stim.AnnotationInformational: "true"},
+		Short:       "Output shell completion for the given shell (bash or zsh)",
 		Long: `Output shell completion for the given shell (bash or zsh)
 The following ought to suffice for loading the Bash completions:
 	source <(stim completion bash)
@@ -40,7 +41,7 @@ loading it with compinit.
 
 		`,
 		ValidArgs: []string{"bash", "zsh"},
-		Args: cobra.ExactArgs(1),
+		Args:      cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			if err := c.stim.GetCompletion(args[0]); err != nil {
 				fmt.Println(err)
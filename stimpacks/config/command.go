@@ -0,0 +1,125 @@
+package config
+
+import (
+	"github.com/PremiereGlobal/stim/stim"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func (c *Config) BindStim(stim *stim.Stim) {
+	c.stim = stim
+}
+
+func (c *Config) Command(viper *viper.Viper) *cobra.Command {
+
+	var cmd = &cobra.Command{
+		Use:   "config",
+		Short: "Manage the stim config",
+		Long:  "Get, set and edit stim config options and switch between the profiles defined in the config",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	var getContextsCmd = &cobra.Command{
+		Use:   "get-contexts",
+		Short: "List profiles",
+		Long:  "List the profiles in the stim config.  The active profile is marked with a `*`",
+		Run: func(cmd *cobra.Command, args []string) {
+			c.getContexts()
+		},
+	}
+	c.stim.BindCommand(getContextsCmd, cmd)
+
+	var currentContextCmd = &cobra.Command{
+		Use:   "current-context",
+		Short: "Print the active profile",
+		Long:  "Print the active profile",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.currentContext()
+		},
+	}
+	c.stim.BindCommand(currentContextCmd, cmd)
+
+	var useContextCmd = &cobra.Command{
+		Use:   "use-context <profile>",
+		Short: "Set the default profile",
+		Long:  "Set the profile used when --profile is not given.  Use --unset to go back to the top-level config",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.useContext(args)
+		},
+	}
+	useContextCmd.Flags().Bool("unset", false, "Clear the default profile")
+	viper.BindPFlag("config-unset-profile", useContextCmd.Flags().Lookup("unset"))
+	c.stim.BindCommand(useContextCmd, cmd)
+
+	var getCmd = &cobra.Command{
+		Use:   "get <key>",
+		Short: "Print a config option",
+		Long:  "Print the value of a config option as resolved by stim, including the active profile, environment variables and flags",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.get(args[0])
+		},
+	}
+	c.stim.BindCommand(getCmd, cmd)
+
+	var setCmd = &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Set a config option",
+		Long:  "Validate and set a config option in the stim config file.  Lists are comma separated.  Options in a profile are set with `profiles.<profile>.<key>`",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.set(args[0], args[1])
+		},
+	}
+	setCmd.Flags().Bool("force", false, "Set the option without validating it")
+	viper.BindPFlag("config-force", setCmd.Flags().Lookup("force"))
+	c.stim.BindCommand(setCmd, cmd)
+
+	var unsetCmd = &cobra.Command{
+		Use:   "unset <key>",
+		Short: "Remove a config option",
+		Long:  "Remove a config option (or a whole section, ex. `aws.sso`) from the stim config file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.unset(args[0])
+		},
+	}
+	c.stim.BindCommand(unsetCmd, cmd)
+
+	var listCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List config options",
+		Long:  "List the options set in the stim config file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.list()
+		},
+	}
+	c.stim.BindCommand(listCmd, cmd)
+
+	var editCmd = &cobra.Command{
+		Use:   "edit",
+		Short: "Edit the stim config file",
+		Long:  "Open the stim config file in $EDITOR (defaults to vi) and check that it is still valid afterwards",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.edit()
+		},
+	}
+	c.stim.BindCommand(editCmd, cmd)
+
+	var orgCmd = &cobra.Command{
+		Use:   "org",
+		Short: "Show the org config",
+		Long:  "Show the org-managed config fetched from `org-config.url`, whose settings are the defaults of the stim config.  Locked settings can't be overridden",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.org()
+		},
+	}
+	orgCmd.Flags().Bool("refresh", false, "Fetch the org config now instead of waiting for `org-config.refresh-interval`")
+	viper.BindPFlag("config-org-refresh", orgCmd.Flags().Lookup("refresh"))
+	c.stim.BindCommand(orgCmd, cmd)
+
+	return cmd
+}
@@ -0,0 +1,19 @@
+package config
+
+import (
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+type Config struct {
+	name string
+	stim *stim.Stim
+}
+
+func New() *Config {
+	config := &Config{name: "config"}
+	return config
+}
+
+func (c *Config) Name() string {
+	return c.name
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// getContexts prints the profiles in the stim config
+func (c *Config) getContexts() {
+	current := c.stim.ConfigGetProfile()
+	for _, profile := range c.stim.ConfigGetProfiles() {
+		marker := " "
+		if profile == current {
+			marker = "*"
+		}
+		fmt.Printf("%s %s\n", marker, profile)
+	}
+}
+
+// currentContext prints the active profile
+func (c *Config) currentContext() error {
+	profile := c.stim.ConfigGetProfile()
+	if profile == "" {
+		return stim.ConfigError(errors.New("No profile is in use"))
+	}
+	fmt.Println(profile)
+	return nil
+}
+
+// useContext sets (or clears) the default profile in the stim config file
+func (c *Config) useContext(args []string) error {
+	log := c.stim.GetLogger()
+
+	if c.stim.ConfigGetBool("config-unset-profile") {
+		if len(args) > 0 {
+			return errors.New("A profile cannot be given with --unset")
+		}
+		err := c.stim.ConfigUseProfile("")
+		if err != nil {
+			return err
+		}
+		log.Info("Cleared the default profile")
+		return nil
+	}
+
+	if len(args) == 0 {
+		return errors.New("Profile name not specified")
+	}
+
+	err := c.stim.ConfigUseProfile(args[0])
+	if err != nil {
+		return err
+	}
+	log.Info("Switched to profile '{}'", args[0])
+
+	return nil
+}
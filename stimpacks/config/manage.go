@@ -0,0 +1,167 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/PremiereGlobal/stim/stim"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// defaultEditor is used by `stim config edit` when EDITOR is not set
+const defaultEditor = "vi"
+
+// get prints the value of a config option as resolved by stim (including the
+// active profile, environment variables and command line flags)
+func (c *Config) get(key string) error {
+	if !c.stim.ConfigHasValue(key) {
+		return fmt.Errorf("Config option '%s' is not set", key)
+	}
+
+	fmt.Println(formatValue(c.stim.ConfigGetRaw(key)))
+	return nil
+}
+
+// set validates the value and writes it to the stim config file
+func (c *Config) set(key string, value string) error {
+	if c.stim.ConfigIsLocked(key) {
+		return stim.ConfigError(fmt.Errorf("Config option '%s' is locked by the org config", key))
+	}
+
+	var parsed interface{} = value
+	if !c.stim.ConfigGetBool("config-force") {
+		var err error
+		parsed, err = parseSetting(key, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := c.stim.ConfigSetRaw(key, parsed)
+	if err != nil {
+		return err
+	}
+
+	c.stim.GetLogger().Info("Set '{}' to '{}'", key, formatValue(parsed))
+	return nil
+}
+
+// unset removes a config option from the stim config file
+func (c *Config) unset(key string) error {
+	values, err := c.stim.ConfigGetFileValues()
+	if err != nil {
+		return err
+	}
+	if !hasKey(values, key) {
+		return fmt.Errorf("Config option '%s' is not set in the stim config file", key)
+	}
+
+	err = c.stim.ConfigRemoveKey(key)
+	if err != nil {
+		return err
+	}
+
+	c.stim.GetLogger().Info("Removed '{}'", key)
+	return nil
+}
+
+// list prints the options set in the stim config file
+func (c *Config) list() error {
+	values, err := c.stim.ConfigGetFileValues()
+	if err != nil {
+		return err
+	}
+
+	var keys []string
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fmt.Printf("%s = %s\n", key, formatValue(values[key]))
+	}
+	return nil
+}
+
+// edit opens the stim config file in EDITOR and checks it is still valid YAML
+func (c *Config) edit() error {
+	configFile, err := c.stim.ConfigGetStimConfigFile()
+	if err != nil {
+		return err
+	}
+
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = defaultEditor
+	}
+
+	// EDITOR may contain arguments (ex. `code --wait`)
+	editorArgs := strings.Fields(editor)
+	cmd := exec.Command(editorArgs[0], append(editorArgs[1:], configFile)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		return fmt.Errorf("Error running editor '%s': %v", editor, err)
+	}
+
+	b, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return err
+	}
+	config := make(map[string]interface{})
+	err = yaml.Unmarshal(b, &config)
+	if err != nil {
+		return fmt.Errorf("The stim config file %s is no longer valid YAML, please fix it: %v", configFile, err)
+	}
+
+	return nil
+}
+
+// hasKey returns true if the key or any key below it is set
+func hasKey(values map[string]interface{}, key string) bool {
+	for k := range values {
+		if k == key || strings.HasPrefix(k, key+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// formatValue formats a config value for output
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case []string:
+		return strings.Join(v, ",")
+	case []interface{}:
+		var items []string
+		for _, item := range v {
+			if _, ok := item.(map[string]interface{}); ok {
+				return formatJSON(value)
+			}
+			items = append(items, fmt.Sprintf("%v", item))
+		}
+		return strings.Join(items, ",")
+	case map[string]interface{}:
+		return formatJSON(value)
+	case nil:
+		return ""
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// formatJSON formats complex values as JSON so they print on one line
+func formatJSON(value interface{}) string {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(b)
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/PremiereGlobal/stim/pkg/orgconfig"
+	"github.com/PremiereGlobal/stim/pkg/utils"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// org prints the org config in use, fetching it first with --refresh
+func (c *Config) org() error {
+
+	overlay := c.stim.OrgConfig()
+	if c.stim.ConfigGetBool("config-org-refresh") {
+		var err error
+		overlay, err = c.stim.RefreshOrgConfig()
+		if err != nil {
+			return err
+		}
+		c.stim.GetLogger().Info("Fetched the org config from {}", c.stim.ConfigGetString("org-config.url"))
+	}
+	if overlay == nil {
+		if c.stim.ConfigGetString("org-config.url") == "" {
+			return stim.ConfigError(errors.New("No org config, `org-config.url` is not set"))
+		}
+		return errors.New("The org config could not be loaded, run with --refresh to see why")
+	}
+
+	printOrgConfig(c.stim, overlay)
+	return nil
+}
+
+// printOrgConfig prints the source and settings of the org config.  Locked
+// settings are marked with `(locked)`.
+func printOrgConfig(s *stim.Stim, overlay *orgconfig.Overlay) {
+
+	fmt.Printf("URL: %s\n", s.ConfigGetString("org-config.url"))
+	fmt.Printf("Fetched: %s\n", s.FormatTime(overlay.FetchedAt))
+	if overlay.RefreshError != nil {
+		fmt.Printf("Refresh failed: %v\n", overlay.RefreshError)
+	}
+	fmt.Println()
+
+	values := overlay.Flatten()
+	var keys []string
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		locked := ""
+		if utils.Contains(overlay.Locked, key) {
+			locked = " (locked)"
+		}
+		fmt.Printf("%s = %s%s\n", key, formatValue(values[key]), locked)
+	}
+}
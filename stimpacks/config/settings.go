@@ -0,0 +1,258 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/credentials"
+	"github.com/PremiereGlobal/stim/pkg/utils"
+)
+
+// Setting types
+const (
+	typeString   = "string"
+	typeBool     = "bool"
+	typeInt      = "int"
+	typeDuration = "duration"
+	typeList     = "list"
+)
+
+// setting describes a stim config option that can be managed with
+// `stim config set`
+type setting struct {
+	Type   string
+	Values []string
+}
+
+// logLevels and logFormats are the values of the logging options
+var logLevels = []string{"trace", "debug", "verbose", "info", "warn", "error"}
+var logFormats = []string{"console", "json"}
+
+// settings are the options that `stim config set` accepts (see CONFIG.md).
+// Options that are lists of objects (ex. notify.backends) must be edited with
+// `stim config edit`.
+var settings = map[string]setting{
+	"path":                         {Type: typeString},
+	"cache-path":                   {Type: typeString},
+	"current-profile":              {Type: typeString},
+	"audit.disable":                {Type: typeBool},
+	"audit.path":                   {Type: typeString},
+	"audit.webhook":                {Type: typeString},
+	"audit.s3":                     {Type: typeString},
+	"audit.s3-profile":             {Type: typeString},
+	"audit.s3-region":              {Type: typeString},
+	"audit.vault-path":             {Type: typeString},
+	"aws.default-profile":          {Type: typeBool},
+	"aws.ttl":                      {Type: typeDuration},
+	"aws.use-profiles":             {Type: typeBool},
+	"aws.web-ttl":                  {Type: typeDuration},
+	"aws.keys.max-age-days":        {Type: typeInt},
+	"aws.sso.start-url":            {Type: typeString},
+	"aws.sso.region":               {Type: typeString},
+	"aws.sso.default-profile":      {Type: typeBool},
+	"aws.ecr.account":              {Type: typeString},
+	"aws.ecr.role":                 {Type: typeString},
+	"azure.client-id":              {Type: typeString},
+	"azure.subscription-id":        {Type: typeString},
+	"azure.tenant-id":              {Type: typeString},
+	"azure.vault-account":          {Type: typeString},
+	"azure.vault-role":             {Type: typeString},
+	"credentials.store":            {Type: typeString, Values: credentials.Stores},
+	"datadog.site":                 {Type: typeString},
+	"datadog.vault-apikey-key":     {Type: typeString},
+	"datadog.vault-appkey-key":     {Type: typeString},
+	"datadog.vault-path":           {Type: typeString},
+	"deploy.check-image":           {Type: typeBool},
+	"deploy.tui":                   {Type: typeBool},
+	"deploy.multi-select":          {Type: typeBool},
+	"deploy.watch-debounce":        {Type: typeDuration},
+	"deploy.file":                  {Type: typeString},
+	"deploy.method":                {Type: typeString, Values: []string{"auto", "docker", "podman", "containerd", "kubernetes", "shell"}},
+	"deploy.podman.host":           {Type: typeString},
+	"deploy.kubernetes.namespace":  {Type: typeString},
+	"deploy.history-path":          {Type: typeString},
+	"deploy.freeze-path":           {Type: typeString},
+	"deploy.protected-envs":        {Type: typeList},
+	"deploy.require-signed-config": {Type: typeList},
+	"deploy.signature.type":        {Type: typeString, Values: []string{"cosign", "minisign", "gpg"}},
+	"deploy.signature.key":         {Type: typeString},
+	"deploy.signature.signers":     {Type: typeList},
+	"deploy.policy.paths":          {Type: typeList},
+	"deploy.policy.bundle-url":     {Type: typeString},
+	"deploy.policy.bundle-sha256":  {Type: typeString},
+	"github.repo":                  {Type: typeString},
+	"github.url":                   {Type: typeString},
+	"github.vault-token-key":       {Type: typeString},
+	"github.vault-token-path":      {Type: typeString},
+	"kube.hpa.max-duration":        {Type: typeDuration},
+	"kube.locked-clusters":         {Type: typeList},
+	"kube.max-unlock-duration":     {Type: typeDuration},
+	"kube.sync.clusters":           {Type: typeList},
+	"locale":                       {Type: typeString, Values: []string{"en", "es"}},
+	"logging.file.disable":         {Type: typeBool},
+	"logging.file.format":          {Type: typeString, Values: logFormats},
+	"logging.file.level":           {Type: typeString, Values: logLevels},
+	"logging.file.max-backups":     {Type: typeInt},
+	"logging.file.max-size":        {Type: typeInt},
+	"logging.file.path":            {Type: typeString},
+	"logging.format":               {Type: typeString, Values: logFormats},
+	"logging.level":                {Type: typeString, Values: logLevels},
+	"metrics.job":                  {Type: typeString},
+	"metrics.pushgateway":          {Type: typeString},
+	"metrics.statsd":               {Type: typeString},
+	"org-config.url":               {Type: typeString},
+	"org-config.public-key":        {Type: typeString},
+	"org-config.refresh-interval":  {Type: typeDuration},
+	"pagerduty.email":              {Type: typeString},
+	"pagerduty.vault-apikey-key":   {Type: typeString},
+	"pagerduty.vault-apikey-path":  {Type: typeString},
+	"slack.captain-rotation":       {Type: typeList},
+	"slack.captain-emoji":          {Type: typeString},
+	"slack.oauth.callback-port":    {Type: typeInt},
+	"slack.oauth.client-id":        {Type: typeString},
+	"slack.oauth.client-secret":    {Type: typeString},
+	"slack.oauth.scopes":           {Type: typeList},
+	"slack.serve.listen":           {Type: typeString},
+	"slack.unfurl.history-url":     {Type: typeString},
+	"slack.unfurl.vault-prefixes":  {Type: typeList},
+	"slack.workspace":              {Type: typeString},
+	"ssh.inventory-path":           {Type: typeString},
+	"stimpacks.aws.enabled":        {Type: typeBool},
+	"stimpacks.azure.enabled":      {Type: typeBool},
+	"stimpacks.completion.enabled": {Type: typeBool},
+	"stimpacks.config.enabled":     {Type: typeBool},
+	"stimpacks.datadog.enabled":    {Type: typeBool},
+	"stimpacks.deploy.enabled":     {Type: typeBool},
+	"stimpacks.doctor.enabled":     {Type: typeBool},
+	"stimpacks.github.enabled":     {Type: typeBool},
+	"stimpacks.init.enabled":       {Type: typeBool},
+	"stimpacks.kubernetes.enabled": {Type: typeBool},
+	"stimpacks.pagerduty.enabled":  {Type: typeBool},
+	"stimpacks.registry.enabled":   {Type: typeBool},
+	"stimpacks.schema.enabled":     {Type: typeBool},
+	"stimpacks.slack.enabled":      {Type: typeBool},
+	"stimpacks.ssh.enabled":        {Type: typeBool},
+	"stimpacks.terraform.enabled":  {Type: typeBool},
+	"stimpacks.update.enabled":     {Type: typeBool},
+	"stimpacks.vault.enabled":      {Type: typeBool},
+	"stimpacks.version.enabled":    {Type: typeBool},
+	"tools.helm.version":           {Type: typeString},
+	"tools.kubectl.version":        {Type: typeString},
+	"tools.opa.version":            {Type: typeString},
+	"tools.manifest":               {Type: typeString},
+	"tools.offline":                {Type: typeBool},
+	"tools.require-checksums":      {Type: typeBool},
+	"tools.terraform.version":      {Type: typeString},
+	"tools.vault.version":          {Type: typeString},
+	"terraform.file":               {Type: typeString},
+	"read-only":                    {Type: typeBool},
+	"read-only-policies":           {Type: typeList},
+	"retry.delay":                  {Type: typeDuration},
+	"retry.jitter-percent":         {Type: typeInt},
+	"retry.max-delay":              {Type: typeDuration},
+	"retry.max-retries":            {Type: typeInt},
+	"retry.on":                     {Type: typeList},
+	"timings":                      {Type: typeBool},
+	"tracing.endpoint":             {Type: typeString},
+	"tracing.headers":              {Type: typeList},
+	"update.disable-check":         {Type: typeBool},
+	"update.check-interval":        {Type: typeDuration},
+	"update.repository":            {Type: typeString},
+	"utc":                          {Type: typeBool},
+	"vault.auth-method":            {Type: typeString, Values: []string{"ldap", "userpass", "oidc", "jwt", "approle", "kubernetes", "token"}},
+	"vault.auth-path":              {Type: typeString},
+	"vault.break-glass.channel":    {Type: typeString},
+	"vault.break-glass.max-ttl":    {Type: typeDuration},
+	"vault.break-glass.path":       {Type: typeString},
+	"vault.break-glass.token-role": {Type: typeString},
+	"vault.database.mount":         {Type: typeString},
+	"vault.keep-leases":            {Type: typeBool},
+	"vault.pki.mount":              {Type: typeString},
+	"vault.pki.role":               {Type: typeString},
+	"vault.role":                   {Type: typeString},
+	"vault.role-id":                {Type: typeString},
+	"vault.jwt-path":               {Type: typeString},
+	"vault.oidc-callback-port":     {Type: typeInt},
+	"vault.secret-concurrency":     {Type: typeInt},
+	"vault.ssh.mount":              {Type: typeString},
+	"vault.ssh.role":               {Type: typeString},
+	"vault.kubeConfigPathTemplate": {Type: typeString},
+	"vault-address":                {Type: typeString},
+	"vault-initial-token-duration": {Type: typeDuration},
+	"vault-token-cache-path":       {Type: typeString},
+	"vault-token-min-ttl":          {Type: typeDuration},
+	"vault-username":               {Type: typeString},
+	"vault-username-skip-prompt":   {Type: typeBool},
+	"verbose":                      {Type: typeBool},
+}
+
+// settingKeys returns the sorted names of the settings
+func settingKeys() []string {
+	var keys []string
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// profileSettingKey returns the setting name of a `profiles.<name>.<key>` key
+// or the key itself
+func profileSettingKey(key string) string {
+	parts := strings.SplitN(key, ".", 3)
+	if len(parts) == 3 && parts[0] == "profiles" {
+		return parts[2]
+	}
+	return key
+}
+
+// parseSetting validates the value of a setting and converts it to the type
+// stored in the config file
+func parseSetting(key string, value string) (interface{}, error) {
+
+	s, ok := settings[profileSettingKey(key)]
+	if !ok {
+		return nil, fmt.Errorf("Unknown config option '%s'.  Use --force to set it anyway or `stim config edit` for complex options", key)
+	}
+	if profileSettingKey(key) == "current-profile" && key != "current-profile" {
+		return nil, fmt.Errorf("'current-profile' cannot be set in a profile")
+	}
+
+	if len(s.Values) > 0 && !utils.Contains(s.Values, value) {
+		return nil, fmt.Errorf("Invalid value '%s' for '%s', must be one of %v", value, key, s.Values)
+	}
+
+	switch s.Type {
+	case typeBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid value '%s' for '%s', must be true or false", value, key)
+		}
+		return b, nil
+	case typeInt:
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid value '%s' for '%s', must be an integer", value, key)
+		}
+		return i, nil
+	case typeDuration:
+		_, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid value '%s' for '%s', must be a duration (ex. 24h)", value, key)
+		}
+		return value, nil
+	case typeList:
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		return list, nil
+	}
+
+	return value, nil
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestParseSetting(t *testing.T) {
+	tests := []struct {
+		key      string
+		value    string
+		expected interface{}
+		err      string
+	}{
+		{"vault-address", "https://vault.my-company.com", "https://vault.my-company.com", ""},
+		{"aws.use-profiles", "true", true, ""},
+		{"aws.use-profiles", "yes", nil, "Invalid value 'yes' for 'aws.use-profiles', must be true or false"},
+		{"aws.keys.max-age-days", "30", 30, ""},
+		{"aws.ttl", "24h", "24h", ""},
+		{"aws.ttl", "1 day", nil, "Invalid value '1 day' for 'aws.ttl', must be a duration (ex. 24h)"},
+		{"kube.sync.clusters", "c1, c2,", []string{"c1", "c2"}, ""},
+		{"vault.auth-method", "oidc", "oidc", ""},
+		{"vault.auth-method", "github", nil, "Invalid value 'github' for 'vault.auth-method', must be one of [ldap userpass oidc jwt approle kubernetes token]"},
+		{"profiles.lab.vault-address", "https://vault.lab.local", "https://vault.lab.local", ""},
+		{"profiles.lab.current-profile", "prod", nil, "'current-profile' cannot be set in a profile"},
+		{"vault.adress", "https://vault", nil, "Unknown config option 'vault.adress'.  Use --force to set it anyway or `stim config edit` for complex options"},
+	}
+
+	for _, test := range tests {
+		actual, err := parseSetting(test.key, test.value)
+		if test.err != "" {
+			assert.Error(t, err, test.err)
+		} else {
+			assert.NilError(t, err)
+			assert.DeepEqual(t, actual, test.expected)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package datadog
+
+import (
+	"strings"
+
+	datadogpkg "github.com/PremiereGlobal/stim/pkg/datadog"
+	"github.com/PremiereGlobal/stim/stim"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func (d *Datadog) BindStim(s *stim.Stim) {
+	d.stim = s
+}
+
+func (d *Datadog) Command(viper *viper.Viper) *cobra.Command {
+
+	var cmd = &cobra.Command{
+		Use:   "datadog",
+		Short: "Post deploy events and mute monitors in Datadog",
+		Long:  "Post events to the Datadog event stream and mute monitors by tag during a deploy window.  The Datadog keys are read from Vault (see datadog.vault-path in the stim config)",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	var eventCmd = &cobra.Command{
+		Use:         "event <title>",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Post an event",
+		Long:        "Post an event (ex. a deploy) to the Datadog event stream.  The alert type is one of " + strings.Join(datadogpkg.AlertTypes, ", "),
+		Example:     "  stim datadog event 'Deployed web v1.2.0' --tag env:prod --tag service:web --alert-type success",
+		Args:        cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.postEvent(args[0])
+		},
+	}
+	d.stim.BindCommand(eventCmd, cmd)
+
+	eventCmd.Flags().String("text", "", "Text of the event")
+	viper.BindPFlag("datadog-event-text", eventCmd.Flags().Lookup("text"))
+	eventCmd.Flags().StringSliceP("tag", "t", nil, "Tag of the event (ex. env:prod).  Can be repeated")
+	viper.BindPFlag("datadog-event-tags", eventCmd.Flags().Lookup("tag"))
+	eventCmd.Flags().String("alert-type", datadogpkg.AlertInfo, "Alert type of the event")
+	viper.BindPFlag("datadog-event-alert-type", eventCmd.Flags().Lookup("alert-type"))
+	eventCmd.Flags().String("aggregation-key", "", "Groups related events (ex. the start and end of a deploy)")
+	viper.BindPFlag("datadog-event-aggregation-key", eventCmd.Flags().Lookup("aggregation-key"))
+
+	var muteCmd = &cobra.Command{
+		Use: "mute",
+		Annotations: map[string]string{
+			stim.AnnotationMutating:   "true",
+			stim.AnnotationStderrLogs: "true",
+		},
+		Short:   "Mute monitors by tag",
+		Long:    "Schedule a Datadog downtime that mutes the monitors with all the given tags for a time and print its ID.  Needs an application key",
+		Example: "  id=$(stim datadog mute --tag service:web --scope env:prod --duration 30m)\n  stim datadog unmute $id",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.mute()
+		},
+	}
+	d.stim.BindCommand(muteCmd, cmd)
+
+	muteCmd.Flags().StringSliceP("tag", "t", nil, "Required. Monitor tag (ex. service:web).  Monitors with all the tags are muted.  Can be repeated")
+	viper.BindPFlag("datadog-mute-tags", muteCmd.Flags().Lookup("tag"))
+	muteCmd.Flags().StringSliceP("scope", "s", nil, "Only mute the monitor groups in this scope (ex. env:prod).  Can be repeated (Default: all groups)")
+	viper.BindPFlag("datadog-mute-scope", muteCmd.Flags().Lookup("scope"))
+	muteCmd.Flags().StringP("duration", "d", "1h", "How long the monitors are muted for")
+	viper.BindPFlag("datadog-mute-duration", muteCmd.Flags().Lookup("duration"))
+	muteCmd.Flags().StringP("message", "m", "", "Message of the downtime (Default: Muted by stim for <user>)")
+	viper.BindPFlag("datadog-mute-message", muteCmd.Flags().Lookup("message"))
+
+	var unmuteCmd = &cobra.Command{
+		Use:         "unmute [downtime-id...]",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Unmute monitors",
+		Long:        "Cancel Datadog downtimes by ID, or all the active and scheduled downtimes of monitors with the given tags.  Needs an application key",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.unmute(args)
+		},
+	}
+	d.stim.BindCommand(unmuteCmd, cmd)
+
+	unmuteCmd.Flags().StringSliceP("tag", "t", nil, "Cancel the downtimes muting monitors with all these tags.  Can be repeated")
+	viper.BindPFlag("datadog-unmute-tags", unmuteCmd.Flags().Lookup("tag"))
+
+	return cmd
+}
@@ -0,0 +1,21 @@
+package datadog
+
+import (
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// Datadog is the stimpack that posts deploy events and mutes monitors in
+// Datadog
+type Datadog struct {
+	name string
+	stim *stim.Stim
+}
+
+func New() *Datadog {
+	datadog := &Datadog{name: "datadog"}
+	return datadog
+}
+
+func (d *Datadog) Name() string {
+	return d.name
+}
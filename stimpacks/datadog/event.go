@@ -0,0 +1,33 @@
+package datadog
+
+import (
+	"fmt"
+	"strings"
+
+	datadogpkg "github.com/PremiereGlobal/stim/pkg/datadog"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// postEvent posts an event to the Datadog event stream
+func (d *Datadog) postEvent(title string) error {
+
+	alertType := d.stim.ConfigGetString("datadog-event-alert-type")
+	if !datadogpkg.ValidAlertType(alertType) {
+		return stim.UsageError(fmt.Errorf("Invalid alert type '%s', must be one of %s", alertType, strings.Join(datadogpkg.AlertTypes, ", ")))
+	}
+
+	id, err := d.stim.Datadog().PostEvent(&datadogpkg.Event{
+		Title:          title,
+		Text:           d.stim.ConfigGetString("datadog-event-text"),
+		Tags:           d.stim.ConfigGetStringSlice("datadog-event-tags"),
+		AlertType:      alertType,
+		AggregationKey: d.stim.ConfigGetString("datadog-event-aggregation-key"),
+		SourceTypeName: "stim",
+	})
+	if err != nil {
+		return err
+	}
+
+	d.stim.GetLogger().Info("Posted Datadog event {}", id)
+	return nil
+}
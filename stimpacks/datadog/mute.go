@@ -0,0 +1,98 @@
+package datadog
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	datadogpkg "github.com/PremiereGlobal/stim/pkg/datadog"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// mute schedules a downtime of the monitors with the tags and prints its ID
+func (d *Datadog) mute() error {
+
+	tags := d.stim.ConfigGetStringSlice("datadog-mute-tags")
+	if len(tags) == 0 {
+		return stim.UsageError(errors.New("No monitor tags specified, use --tag"))
+	}
+
+	duration, err := time.ParseDuration(d.stim.ConfigGetString("datadog-mute-duration"))
+	if err != nil || duration <= 0 {
+		return stim.UsageError(fmt.Errorf("Invalid duration '%s'", d.stim.ConfigGetString("datadog-mute-duration")))
+	}
+
+	message := d.stim.ConfigGetString("datadog-mute-message")
+	if message == "" {
+		user, err := d.stim.User()
+		if err != nil {
+			user = "unknown"
+		}
+		message = "Muted by stim for " + user
+	}
+
+	end := d.stim.Clock().Now().Add(duration)
+	downtime, err := d.stim.Datadog().CreateDowntime(&datadogpkg.Downtime{
+		Scope:       d.stim.ConfigGetStringSlice("datadog-mute-scope"),
+		MonitorTags: tags,
+		End:         end.Unix(),
+		Message:     message,
+	})
+	if err != nil {
+		return err
+	}
+
+	d.stim.GetLogger().Info("Muted monitors with tags {} until {} (downtime {})", tags, d.stim.FormatTime(end), downtime.ID)
+	fmt.Println(downtime.ID)
+	return nil
+}
+
+// unmute cancels the downtimes with the IDs, or the downtimes of the monitors
+// with the `--tag` tags
+func (d *Datadog) unmute(args []string) error {
+
+	tags := d.stim.ConfigGetStringSlice("datadog-unmute-tags")
+	if len(args) == 0 && len(tags) == 0 {
+		return stim.UsageError(errors.New("Give the IDs of the downtimes to cancel or use --tag"))
+	}
+	if len(args) > 0 && len(tags) > 0 {
+		return stim.UsageError(errors.New("Downtime IDs and --tag can't be used together"))
+	}
+
+	var ids []int64
+	for _, arg := range args {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return stim.UsageError(fmt.Errorf("Invalid downtime ID '%s'", arg))
+		}
+		ids = append(ids, id)
+	}
+
+	client := d.stim.Datadog()
+	if len(tags) > 0 {
+		downtimes, err := client.Downtimes()
+		if err != nil {
+			return err
+		}
+		for _, downtime := range downtimes {
+			if !downtime.Disabled && len(downtime.MonitorTags) > 0 && downtime.HasTags(tags) {
+				ids = append(ids, downtime.ID)
+			}
+		}
+		if len(ids) == 0 {
+			d.stim.GetLogger().Info("No downtimes of monitors with tags {}", tags)
+			return nil
+		}
+	}
+
+	for _, id := range ids {
+		err := client.CancelDowntime(id)
+		if err != nil {
+			return fmt.Errorf("Unable to cancel downtime %d: %v", id, err)
+		}
+		d.stim.GetLogger().Info("Cancelled downtime {}", id)
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/PremiereGlobal/stim/pkg/i18n"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// AccessCheck is a Vault path that a deploy touches and the capability the
+// deploy needs on it.  Environment and Instance are empty for paths used by
+// every environment or instance.
+type AccessCheck struct {
+	Environment string
+	Instance    string
+	Check       string
+	Path        string
+	Capability  string
+}
+
+// AccessChecks returns the Vault paths that deploying the instances of a
+// deployment config file (the default one if empty) touches, for `stim vault
+// check-access`.  Empty environment and instance names select all of them.
+// AWS secrets are read with the AWS credential chain rather than Vault, so
+// they are not included.
+func (d *Deploy) AccessChecks(configFile string, environmentName string, instanceName string) ([]*AccessCheck, error) {
+
+	d.log = d.stim.GetLogger()
+
+	err := d.loadConfigFile(configFile)
+	if err == nil {
+		err = processConfig(&d.config, d.stim, d.log)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if environmentName != "" {
+		if _, ok := d.config.environmentMap[environmentName]; !ok {
+			return nil, stim.ConfigError(errors.New(d.stim.Message(i18n.MessageUnknownEnvironment, environmentName)))
+		}
+	}
+
+	checks := []*AccessCheck{{Check: "freezes", Path: d.freezePath(), Capability: "list"}}
+	for _, environment := range d.config.Environments {
+		if environmentName != "" && environment.Name != environmentName {
+			continue
+		}
+
+		if instanceName != "" {
+			if _, ok := environment.instanceMap[instanceName]; !ok {
+				return nil, stim.ConfigError(fmt.Errorf("Provided instance value '%s' is not in environment '%s'", instanceName, environment.Name))
+			}
+		}
+		checks = append(checks, d.environmentAccessChecks(environment)...)
+
+		for _, instance := range environment.Instances {
+			if instanceName != "" && instance.Name != instanceName {
+				continue
+			}
+			checks = append(checks, instanceAccessChecks(environment, instance, d.stim.KubeConfigSecretPath(instance.Spec.Kubernetes.Cluster, instance.Spec.Kubernetes.ServiceAccount))...)
+		}
+	}
+
+	return checks, nil
+}
+
+// environmentAccessChecks returns the Vault paths of the tokens and keys used
+// by the GitHub Deployments, releases and Datadog events of an environment
+func (d *Deploy) environmentAccessChecks(environment *Environment) []*AccessCheck {
+
+	var checks []*AccessCheck
+	add := func(check string, path string) {
+		if path != "" {
+			checks = append(checks, &AccessCheck{Environment: environment.Name, Check: check, Path: path, Capability: "read"})
+		}
+	}
+
+	if environment.Github != nil {
+		add("github", environment.Github.SecretPath)
+	}
+	if environment.Release != nil && environment.Release.Github != nil {
+		add("release", environment.Release.Github.SecretPath)
+	}
+	if environment.Release != nil && environment.Release.Gitlab != nil {
+		add("release", environment.Release.Gitlab.SecretPath)
+	}
+	if environment.Datadog != nil {
+		add("datadog", d.datadogVaultPath(environment.Datadog))
+	}
+
+	return checks
+}
+
+// instanceAccessChecks returns the kube-config secret and the Vault secrets of
+// an instance
+func instanceAccessChecks(environment *Environment, instance *Instance, kubeConfigPath string) []*AccessCheck {
+
+	checks := []*AccessCheck{{Environment: environment.Name, Instance: instance.Name, Check: "kube-config", Path: kubeConfigPath, Capability: "read"}}
+	for _, secret := range instance.Spec.Secrets {
+		if secret.isVault() {
+			checks = append(checks, &AccessCheck{Environment: environment.Name, Instance: instance.Name, Check: "secret", Path: secret.SecretPath, Capability: "read"})
+		}
+	}
+
+	return checks
+}
@@ -0,0 +1,38 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/PremiereGlobal/stim/stim"
+	"gotest.tools/assert"
+)
+
+func TestInstanceAccessChecks(t *testing.T) {
+	environment := &Environment{Name: "prod"}
+	instance := &Instance{Name: "us-west-2", Spec: &Spec{Secrets: []*SecretItem{
+		vaultSecret("secret/prod/db", 0, map[string]string{"DB_PASSWORD": "password"}),
+		{AwsSsm: &AwsSsmSecret{Path: "/prod"}},
+	}}}
+
+	checks := instanceAccessChecks(environment, instance, "secret/kube/prod/default")
+	assert.DeepEqual(t, checks, []*AccessCheck{
+		{Environment: "prod", Instance: "us-west-2", Check: "kube-config", Path: "secret/kube/prod/default", Capability: "read"},
+		{Environment: "prod", Instance: "us-west-2", Check: "secret", Path: "secret/prod/db", Capability: "read"},
+	})
+}
+
+func TestEnvironmentAccessChecks(t *testing.T) {
+	s := stim.New()
+	d := &Deploy{stim: s, log: s.GetLogger()}
+	environment := &Environment{
+		Name:    "prod",
+		Github:  &GithubDeployment{Repo: "org/web"},
+		Release: &Release{Gitlab: &GitlabRelease{SecretPath: "secret/gitlab"}},
+		Datadog: &DatadogDeploy{SecretPath: "secret/datadog"},
+	}
+
+	assert.DeepEqual(t, d.environmentAccessChecks(environment), []*AccessCheck{
+		{Environment: "prod", Check: "release", Path: "secret/gitlab", Capability: "read"},
+		{Environment: "prod", Check: "datadog", Path: "secret/datadog", Capability: "read"},
+	})
+}
@@ -0,0 +1,133 @@
+package deploy
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// addAwsSecrets reads the AWS Secrets Manager and SSM Parameter Store secrets
+// of the instance and adds their values to the environment variables
+func (d *Deploy) addAwsSecrets(instance *Instance) error {
+
+	// Secrets are in precedence order (global, environment, instance) so later
+	// values overwrite earlier ones
+	values := make(map[string]string)
+	var names []string
+	for _, secret := range instance.Spec.Secrets {
+
+		var secretValues map[string]string
+		var err error
+		if secret.AwsSecretsManager != nil {
+			secretValues, err = d.getAwsSecretsManagerValues(secret)
+		} else if secret.AwsSsm != nil {
+			secretValues, err = d.getAwsSsmValues(secret)
+		} else {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		for name, value := range secretValues {
+			if _, ok := values[name]; !ok {
+				names = append(names, name)
+			}
+			values[name] = value
+		}
+	}
+
+	for _, name := range names {
+		instance.Spec.EnvironmentVars = append(instance.Spec.EnvironmentVars, &EnvironmentVar{Name: name, Value: values[name]})
+	}
+
+	return nil
+}
+
+// getAwsSecretsManagerValues returns the env var values of a Secrets Manager secret
+func (d *Deploy) getAwsSecretsManagerValues(secret *SecretItem) (map[string]string, error) {
+
+	config := secret.AwsSecretsManager
+
+	aws, err := d.stim.NewAws("", "")
+	if err != nil {
+		return nil, err
+	}
+	err = aws.CreateDefaultSession(config.Profile, config.Region)
+	if err != nil {
+		return nil, err
+	}
+
+	d.log.Debug("Reading AWS Secrets Manager secret {}", config.SecretID)
+	secretString, err := aws.GetSecretValue(config.SecretID, config.VersionStage)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read AWS Secrets Manager secret '%s': %v", config.SecretID, err)
+	}
+
+	// Only parse the value as JSON if a key is requested
+	var data map[string]interface{}
+	for _, key := range secret.SecretMaps {
+		if key != "" {
+			err = json.Unmarshal([]byte(secretString), &data)
+			if err != nil {
+				return nil, fmt.Errorf("AWS Secrets Manager secret '%s' is not a JSON object: %v", config.SecretID, err)
+			}
+			break
+		}
+	}
+
+	values := make(map[string]string)
+	for name, key := range secret.SecretMaps {
+		if key == "" {
+			values[name] = secretString
+			continue
+		}
+
+		value, ok := data[key]
+		if !ok {
+			return nil, fmt.Errorf("Key '%s' not found in AWS Secrets Manager secret '%s'", key, config.SecretID)
+		}
+		values[name] = fmt.Sprintf("%v", value)
+	}
+
+	return values, nil
+}
+
+// getAwsSsmValues returns the env var values of SSM Parameter Store parameters
+func (d *Deploy) getAwsSsmValues(secret *SecretItem) (map[string]string, error) {
+
+	config := secret.AwsSsm
+
+	aws, err := d.stim.NewAws("", "")
+	if err != nil {
+		return nil, err
+	}
+	err = aws.CreateDefaultSession(config.Profile, config.Region)
+	if err != nil {
+		return nil, err
+	}
+
+	parameterNames := make(map[string]string)
+	var parameters []string
+	for name, parameter := range secret.SecretMaps {
+		if config.Path != "" && !strings.HasPrefix(parameter, "/") {
+			parameter = path.Join(config.Path, parameter)
+		}
+		parameterNames[name] = parameter
+		parameters = append(parameters, parameter)
+	}
+
+	d.log.Debug("Reading AWS SSM parameters {}", parameters)
+	parameterValues, err := aws.GetParameters(parameters)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read AWS SSM parameters: %v", err)
+	}
+
+	values := make(map[string]string)
+	for name, parameter := range parameterNames {
+		values[name] = parameterValues[parameter]
+	}
+
+	return values, nil
+}
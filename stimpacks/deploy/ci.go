@@ -0,0 +1,305 @@
+package deploy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// The pipeline formats of `stim deploy generate-ci`
+const (
+	ciFormatGithubActions = "github-actions"
+	ciFormatGitlab        = "gitlab"
+	ciFormatJenkinsfile   = "jenkinsfile"
+)
+
+// ciFormats are the valid values of `--format`
+var ciFormats = []string{ciFormatGithubActions, ciFormatGitlab, ciFormatJenkinsfile}
+
+// ciImageRepo is the image that pipeline jobs run stim in
+const ciImageRepo = "premiereglobal/stim"
+
+// ciInvalidIDRegexp matches the characters that can't be used in job names
+var ciInvalidIDRegexp = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// ciPlainArgRegexp matches command arguments that don't need quoting
+var ciPlainArgRegexp = regexp.MustCompile(`^[A-Za-z0-9_./-]+$`)
+
+// ciPipeline describes the deploy jobs of a generated pipeline
+type ciPipeline struct {
+	// Source is the deploy file or service that the pipeline was generated from
+	Source string
+
+	// Image is the stim image the jobs run in
+	Image string
+
+	// Stages are the environments in the order of the deploy config.  Each
+	// stage runs after the previous one.
+	Stages []*ciStage
+
+	// Variables are the env vars referenced in the deploy config, which the
+	// CI must provide as secrets
+	Variables []string
+
+	// Aws is true if an instance reads AWS secrets, which need AWS credentials
+	Aws bool
+}
+
+// ciStage is an environment of a pipeline
+type ciStage struct {
+	Environment string
+
+	// Manual stages wait for someone to start them, for environments with a
+	// confirmation, approvals or that are protected
+	Manual bool
+
+	Jobs []*ciJob
+}
+
+// ciJob deploys an instance
+type ciJob struct {
+	ID       string
+	Instance string
+	Command  string
+}
+
+// GenerateCI prints a pipeline definition that deploys every instance of the
+// deploy config, environment by environment
+func (d *Deploy) GenerateCI() error {
+
+	d.log = d.stim.GetLogger()
+
+	format := d.stim.ConfigGetString("deploy-generate-ci-format")
+	render, ok := map[string]func(*ciPipeline) string{
+		ciFormatGithubActions: renderGithubActions,
+		ciFormatGitlab:        renderGitlab,
+		ciFormatJenkinsfile:   renderJenkinsfile,
+	}[format]
+	if !ok {
+		return stim.UsageError(fmt.Errorf("Invalid --format '%s'.  Must be one of ['%s']", format, strings.Join(ciFormats, "','")))
+	}
+
+	// The env vars referenced in the config are the secrets of the pipeline.
+	// Unset ones are left as references, since they are set by the CI.
+	referenced := make(map[string]bool)
+	lookupEnv = func(name string) (string, bool) {
+		referenced[name] = true
+		if value, ok := os.LookupEnv(name); ok {
+			return value, true
+		}
+		return "${" + name + "}", true
+	}
+	defer func() { lookupEnv = os.LookupEnv }()
+
+	err := d.parseConfig()
+	if err != nil {
+		return err
+	}
+
+	pipeline := d.ciPipeline()
+	for name := range referenced {
+		pipeline.Variables = append(pipeline.Variables, name)
+	}
+	sort.Strings(pipeline.Variables)
+
+	fmt.Print(render(pipeline))
+	return nil
+}
+
+// ciPipeline returns the pipeline of the deploy config
+func (d *Deploy) ciPipeline() *ciPipeline {
+
+	source := ""
+	if d.service != nil {
+		source = "--service " + d.service.Name
+		if root := d.stim.ConfigGetString("deploy.workspace-root"); root != "" {
+			source += " --workspace-root " + filepath.ToSlash(root)
+		}
+	} else {
+		source = "-f " + filepath.ToSlash(filepath.Clean(d.config.configFilePath))
+	}
+
+	image := ciImageRepo
+	if version := d.stim.GetVersion(); strings.HasPrefix(version, "v") {
+		image += ":" + version + "-deploy"
+	}
+
+	pipeline := &ciPipeline{Source: source, Image: image}
+	for _, environment := range d.config.Environments {
+		stage := &ciStage{Environment: environment.Name, Manual: d.isProtectedEnvironment(environment.Name)}
+		if environment.Policy != nil && (environment.Policy.Confirm != "" || environment.Policy.Approvals != nil) {
+			stage.Manual = true
+		}
+
+		for _, instance := range environment.Instances {
+			if instance.Spec.AddConfirmationPrompt {
+				stage.Manual = true
+			}
+			for _, secret := range instance.Spec.Secrets {
+				if !secret.isVault() {
+					pipeline.Aws = true
+				}
+			}
+			stage.Jobs = append(stage.Jobs, &ciJob{
+				ID:       ciJobID(environment.Name, instance.Name),
+				Instance: instance.Name,
+				Command:  ciDeployCommand(source, environment.Name, instance.Name),
+			})
+		}
+		pipeline.Stages = append(pipeline.Stages, stage)
+	}
+
+	return pipeline
+}
+
+// ciJobID returns the job name of an instance deploy
+func ciJobID(environment string, instance string) string {
+	id := ciInvalidIDRegexp.ReplaceAllString(strings.ToLower(environment+"-"+instance), "-")
+	return "deploy-" + strings.Trim(id, "-")
+}
+
+// ciDeployCommand returns the stim command that deploys an instance in a
+// pipeline.  Vault is logged in to with AppRole, with the role ID in
+// VAULT_ROLE_ID and the secret ID in STIM_VAULT_SECRET_ID.
+func ciDeployCommand(source string, environment string, instance string) string {
+	return fmt.Sprintf("stim deploy %s -e %s -i %s -y --is-automated --method shell --auth-method approle --role-id \"$VAULT_ROLE_ID\"",
+		source, ciQuote(environment), ciQuote(instance))
+}
+
+// ciQuote quotes a command argument for the shell if needed
+func ciQuote(arg string) string {
+	if ciPlainArgRegexp.MatchString(arg) {
+		return arg
+	}
+	return "'" + strings.Replace(arg, "'", `'"'"'`, -1) + "'"
+}
+
+// ciSecrets returns the secrets a pipeline needs besides the Vault address
+// and AppRole role ID, which aren't secret
+func ciSecrets(pipeline *ciPipeline) []string {
+	secrets := []string{"VAULT_SECRET_ID"}
+	if pipeline.Aws {
+		secrets = append(secrets, "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY")
+	}
+	return append(secrets, pipeline.Variables...)
+}
+
+// renderGithubActions returns a GitHub Actions workflow.  Each instance is a
+// job in the GitHub environment of the same name, so that the protection
+// rules of the environment (ex. required reviewers) apply.
+func renderGithubActions(pipeline *ciPipeline) string {
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Generated by `stim deploy generate-ci` for %s\n", pipeline.Source)
+	b.WriteString("# Set the VAULT_ADDR and VAULT_ROLE_ID variables and the secrets below in the repository settings.\n")
+	b.WriteString("# Add required reviewers to the GitHub environments that need an approval.\n")
+	b.WriteString("name: Deploy\n\non:\n  workflow_dispatch: {}\n\nenv:\n")
+	b.WriteString("  STIM_VAULT_ADDRESS: ${{ vars.VAULT_ADDR }}\n")
+	b.WriteString("  VAULT_ROLE_ID: ${{ vars.VAULT_ROLE_ID }}\n")
+	for _, secret := range ciSecrets(pipeline) {
+		name := secret
+		if secret == "VAULT_SECRET_ID" {
+			name = "STIM_VAULT_SECRET_ID"
+		}
+		fmt.Fprintf(&b, "  %s: ${{ secrets.%s }}\n", name, secret)
+	}
+
+	b.WriteString("\njobs:\n")
+	var needs []string
+	for _, stage := range pipeline.Stages {
+		var ids []string
+		for _, job := range stage.Jobs {
+			fmt.Fprintf(&b, "  %s:\n", job.ID)
+			fmt.Fprintf(&b, "    name: Deploy %s/%s\n", stage.Environment, job.Instance)
+			if len(needs) > 0 {
+				fmt.Fprintf(&b, "    needs: [%s]\n", strings.Join(needs, ", "))
+			}
+			b.WriteString("    runs-on: ubuntu-latest\n")
+			fmt.Fprintf(&b, "    container: %s\n", pipeline.Image)
+			fmt.Fprintf(&b, "    environment: %s\n", stage.Environment)
+			b.WriteString("    steps:\n      - uses: actions/checkout@v4\n")
+			fmt.Fprintf(&b, "      - run: %s\n", job.Command)
+			ids = append(ids, job.ID)
+		}
+		if len(ids) > 0 {
+			needs = ids
+		}
+	}
+
+	return b.String()
+}
+
+// renderGitlab returns a GitLab CI pipeline with a stage per environment
+func renderGitlab(pipeline *ciPipeline) string {
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Generated by `stim deploy generate-ci` for %s\n", pipeline.Source)
+	b.WriteString("# Set the VAULT_ADDR and VAULT_ROLE_ID CI/CD variables, and these as masked variables:\n")
+	fmt.Fprintf(&b, "#   %s\n", strings.Join(ciSecrets(pipeline), ", "))
+	b.WriteString("stages:\n")
+	for _, stage := range pipeline.Stages {
+		fmt.Fprintf(&b, "  - %s\n", stage.Environment)
+	}
+
+	b.WriteString("\nvariables:\n  STIM_VAULT_ADDRESS: $VAULT_ADDR\n  STIM_VAULT_SECRET_ID: $VAULT_SECRET_ID\n")
+	b.WriteString("\n.stim-deploy:\n  image:\n")
+	fmt.Fprintf(&b, "    name: %s\n", pipeline.Image)
+	b.WriteString("    entrypoint: [\"\"]\n")
+
+	for _, stage := range pipeline.Stages {
+		for _, job := range stage.Jobs {
+			fmt.Fprintf(&b, "\n%s:\n  extends: .stim-deploy\n", job.ID)
+			fmt.Fprintf(&b, "  stage: %s\n", stage.Environment)
+			fmt.Fprintf(&b, "  environment:\n    name: %s/%s\n", stage.Environment, job.Instance)
+			fmt.Fprintf(&b, "  script:\n    - %s\n", job.Command)
+			if stage.Manual {
+				b.WriteString("  when: manual\n")
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// renderJenkinsfile returns a declarative Jenkins pipeline with a stage per
+// environment.  Secrets are Jenkins credentials with lowercase names (ex.
+// `vault-secret-id`).
+func renderJenkinsfile(pipeline *ciPipeline) string {
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Generated by `stim deploy generate-ci` for %s\n", pipeline.Source)
+	b.WriteString("// Set VAULT_ADDR and VAULT_ROLE_ID in the Jenkins environment and add the credentials below.\n")
+	b.WriteString("pipeline {\n  agent {\n    docker {\n")
+	fmt.Fprintf(&b, "      image '%s'\n", pipeline.Image)
+	b.WriteString("      args '--entrypoint='\n    }\n  }\n")
+
+	b.WriteString("  environment {\n    STIM_VAULT_ADDRESS = \"${env.VAULT_ADDR}\"\n")
+	for _, secret := range ciSecrets(pipeline) {
+		name := secret
+		if secret == "VAULT_SECRET_ID" {
+			name = "STIM_VAULT_SECRET_ID"
+		}
+		fmt.Fprintf(&b, "    %s = credentials('%s')\n", name, strings.Replace(strings.ToLower(secret), "_", "-", -1))
+	}
+	b.WriteString("  }\n  stages {\n")
+
+	for _, stage := range pipeline.Stages {
+		fmt.Fprintf(&b, "    stage('%s') {\n", stage.Environment)
+		if stage.Manual {
+			fmt.Fprintf(&b, "      input {\n        message 'Deploy to %s?'\n      }\n", stage.Environment)
+		}
+		b.WriteString("      steps {\n")
+		for _, job := range stage.Jobs {
+			fmt.Fprintf(&b, "        sh '%s'\n", strings.Replace(job.Command, "'", `\'`, -1))
+		}
+		b.WriteString("      }\n    }\n")
+	}
+	b.WriteString("  }\n}\n")
+
+	return b.String()
+}
@@ -0,0 +1,63 @@
+package deploy
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/PremiereGlobal/stim/stim"
+	"gotest.tools/assert"
+)
+
+func TestCIJobID(t *testing.T) {
+	assert.Equal(t, ciJobID("prod", "us-west-2"), "deploy-prod-us-west-2")
+	assert.Equal(t, ciJobID("Prod EU", "blue.1"), "deploy-prod-eu-blue-1")
+}
+
+func TestCIDeployCommand(t *testing.T) {
+	assert.Equal(t, ciDeployCommand("-f stim.deploy.yaml", "prod", "us west"),
+		`stim deploy -f stim.deploy.yaml -e prod -i 'us west' -y --is-automated --method shell --auth-method approle --role-id "$VAULT_ROLE_ID"`)
+}
+
+func TestRenderCI(t *testing.T) {
+	s := stim.New()
+	s.ConfigOverride("deploy.protected-envs", []string{"prod*"})
+	d := &Deploy{stim: s, log: s.GetLogger(), config: Config{
+		configFilePath: "./deploy/stim.deploy.yaml",
+		Environments: []*Environment{
+			{Name: "dev", Instances: []*Instance{
+				{Name: "us-west-2", Spec: &Spec{}},
+				{Name: "eu-west-1", Spec: &Spec{Secrets: []*SecretItem{{AwsSsm: &AwsSsmSecret{}}}}},
+			}},
+			{Name: "stage", Policy: &Policy{Confirm: confirmPrompt}, Instances: []*Instance{
+				{Name: "us-west-2", Spec: &Spec{}},
+			}},
+			{Name: "prod", Instances: []*Instance{
+				{Name: "us-west-2", Spec: &Spec{}},
+			}},
+		},
+	}}
+
+	pipeline := d.ciPipeline()
+	pipeline.Variables = []string{"API_URL"}
+	assert.Assert(t, pipeline.Aws)
+	assert.Assert(t, !pipeline.Stages[0].Manual)
+	assert.Assert(t, pipeline.Stages[1].Manual)
+	assert.Assert(t, pipeline.Stages[2].Manual)
+
+	for format, render := range map[string]func(*ciPipeline) string{
+		ciFormatGithubActions: renderGithubActions,
+		ciFormatGitlab:        renderGitlab,
+		ciFormatJenkinsfile:   renderJenkinsfile,
+	} {
+		goldenFile := filepath.Join("testdata", "ci", format+".golden")
+		actual := render(pipeline)
+		if *update {
+			assert.NilError(t, ioutil.WriteFile(goldenFile, []byte(actual), 0644))
+		}
+
+		expected, err := ioutil.ReadFile(goldenFile)
+		assert.NilError(t, err)
+		assert.Equal(t, string(expected), actual, format)
+	}
+}
@@ -15,22 +15,352 @@ func (d *Deploy) BindStim(s *stim.Stim) {
 // This function sets up the cli command parameters and returns the command
 func (d *Deploy) Command(viper *viper.Viper) *cobra.Command {
 	var deployCmd = &cobra.Command{
-		Use:   "deploy",
-		Short: "Deploy helper",
-		Long:  "Deployment helper using Vault + Kubernetes + Helm",
-		Run: func(cmd *cobra.Command, args []string) {
-			d.Run()
+		Use:         "deploy",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Deploy helper",
+		Long:        "Deployment helper using Vault + Kubernetes + Helm",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.Run()
 		},
 	}
 
 	deployCmd.PersistentFlags().StringP("deploy-file", "f", "", "Deployment file")
 	viper.BindPFlag("deploy.file", deployCmd.PersistentFlags().Lookup("deploy-file"))
+	deployCmd.PersistentFlags().StringSlice("service", nil, "Service of the workspace to deploy, or 'all'.  Can be repeated")
+	viper.BindPFlag("deploy.service", deployCmd.PersistentFlags().Lookup("service"))
+	d.stim.BindFlagCompletion(deployCmd, "service", "deploy-services", d.completeServices)
+	deployCmd.PersistentFlags().String("workspace-root", "", "Directory of the stim.workspace.yaml file, or to find the stim.deploy.yaml files of the services in (default \".\")")
+	viper.BindPFlag("deploy.workspace-root", deployCmd.PersistentFlags().Lookup("workspace-root"))
 	deployCmd.PersistentFlags().StringP("environment", "e", "", "Environment to deploy to")
 	viper.BindPFlag("deploy.environment", deployCmd.PersistentFlags().Lookup("environment"))
+	d.stim.BindFlagCompletion(deployCmd, "environment", "deploy-environments", d.completeEnvironments)
 	deployCmd.PersistentFlags().StringP("instance", "i", "", "Instance to deploy to")
 	viper.BindPFlag("deploy.instance", deployCmd.PersistentFlags().Lookup("instance"))
-	deployCmd.PersistentFlags().StringP("method", "m", "auto", "Method to use for deployment.  Valid values are 'auto' 'docker' or 'shell'.  Auto will use docker if it is available or fall back to shell if not.")
+	deployCmd.PersistentFlags().StringP("method", "m", "auto", "Method to use for deployment.  Valid values are 'auto' 'docker' 'podman' 'containerd' 'kubernetes' or 'shell'.  Auto will use docker if it is available or fall back to shell if not.")
 	viper.BindPFlag("deploy.method", deployCmd.PersistentFlags().Lookup("method"))
+	deployCmd.PersistentFlags().BoolP("yes", "y", false, "Skip confirmation prompts, including typed confirmations.  Approvals and freeze windows still apply")
+	viper.BindPFlag("deploy.yes", deployCmd.PersistentFlags().Lookup("yes"))
+	deployCmd.PersistentFlags().String("override-freeze", "", "Deploy during a freeze window.  The reason is logged and sent to the `freeze-override` notification event")
+	viper.BindPFlag("deploy.override-freeze", deployCmd.PersistentFlags().Lookup("override-freeze"))
+	deployCmd.PersistentFlags().StringSlice("set", nil, "Set an env var for this run in the format NAME=VALUE, overriding the deploy config.  Can be repeated")
+	viper.BindPFlag("deploy.set", deployCmd.PersistentFlags().Lookup("set"))
+	deployCmd.PersistentFlags().StringSlice("set-file", nil, "Set an env var for this run to the content of a file in the format NAME=PATH, overriding the deploy config.  Can be repeated")
+	viper.BindPFlag("deploy.set-file", deployCmd.PersistentFlags().Lookup("set-file"))
+	deployCmd.PersistentFlags().Int("secret-concurrency", 0, "Number of Vault secrets to fetch and check at once (default 8)")
+	viper.BindPFlag("vault.secret-concurrency", deployCmd.PersistentFlags().Lookup("secret-concurrency"))
+	deployCmd.Flags().String("bom", "", "Write a JSON bill of materials of the Vault paths, images, clusters and AWS APIs used by the deploy to this file")
+	viper.BindPFlag("deploy.bom", deployCmd.Flags().Lookup("bom"))
+	deployCmd.Flags().Bool("offline", false, "Fail instead of downloading tools that are not in the tool cache (shell method)")
+	viper.BindPFlag("tools.offline", deployCmd.Flags().Lookup("offline"))
+	deployCmd.Flags().Bool("skip-secret-check", false, "Don't check that the Vault secrets of the instance(s) exist and are readable before deploying")
+	viper.BindPFlag("deploy.skip-secret-check", deployCmd.Flags().Lookup("skip-secret-check"))
+	deployCmd.Flags().Bool("tui", false, "Show the instances, cluster and last deploy of each environment and instance when prompting for them")
+	viper.BindPFlag("deploy.tui", deployCmd.Flags().Lookup("tui"))
+	deployCmd.Flags().Bool("multi-select", false, "Select any number of instances (and services) at the prompt instead of one or ALL")
+	viper.BindPFlag("deploy.multi-select", deployCmd.Flags().Lookup("multi-select"))
+	deployCmd.Flags().Bool("check-image", false, "Check that the tag of the deploy container exists in its registry before deploying")
+	viper.BindPFlag("deploy.check-image", deployCmd.Flags().Lookup("check-image"))
+	deployCmd.Flags().Bool("watch", false, "Deploy, then redeploy the selected (non-protected) instance whenever the deploy config or deploy directory changes")
+	viper.BindPFlag("deploy.watch", deployCmd.Flags().Lookup("watch"))
+	deployCmd.Flags().String("watch-debounce", "", "How long the files must be unchanged before redeploying with --watch (default 1s)")
+	viper.BindPFlag("deploy.watch-debounce", deployCmd.Flags().Lookup("watch-debounce"))
+
+	var explainCmd = &cobra.Command{
+		Use:   "explain",
+		Short: "Show the resolved deploy config",
+		Long:  "Shows the resolved deploy config of an instance and whether each value came from the global, environment or instance spec",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.Explain()
+		},
+	}
+
+	d.stim.BindCommand(explainCmd, deployCmd)
+
+	var explainSecretCmd = &cobra.Command{
+		Use:   "explain-secret <name>",
+		Short: "Show where an env var comes from",
+		Long:  "Shows which spec level sets an env var of an instance, the Vault (or AWS) secret and key it is read from, the lower level definitions it overrides and whether the current token can read it.  The value is never printed",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.ExplainSecret(args[0])
+		},
+	}
+
+	d.stim.BindCommand(explainSecretCmd, deployCmd)
+
+	var envCmd = &cobra.Command{
+		Use:         "env",
+		Annotations: map[string]string{stim.AnnotationStderrLogs: "true"},
+		Short:       "Write the environment of a deploy",
+		Long:        "Writes the merged env vars that the deploy script of an instance runs with (the deploy config, images, stim env vars and secrets) as a .env file or shell exports, to reproduce the deploy environment locally.  Secret values are redacted unless --show-secrets is given and confirmed",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.ExportEnv()
+		},
+	}
+
+	envCmd.Flags().String("format", envFormatDotenv, "Output format (dotenv|export)")
+	viper.BindPFlag("deploy-env-format", envCmd.Flags().Lookup("format"))
+	envCmd.Flags().StringP("output", "o", "", "File to write the environment to instead of stdout")
+	viper.BindPFlag("deploy-env-output", envCmd.Flags().Lookup("output"))
+	envCmd.Flags().Bool("show-secrets", false, "Include the values of secrets, after a confirmation (skipped with -y)")
+	viper.BindPFlag("deploy-env-show-secrets", envCmd.Flags().Lookup("show-secrets"))
+
+	d.stim.BindCommand(envCmd, deployCmd)
+
+	var preflightCmd = &cobra.Command{
+		Use:     "preflight",
+		Aliases: []string{"check-secrets"},
+		Short:   "Check the Vault secrets of a deploy",
+		Long:    "Checks that every Vault secret and key referenced by an instance (including its kube-config secret) exists and can be read by the current token.  Secret values are not printed",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.Preflight()
+		},
+	}
+
+	d.stim.BindCommand(preflightCmd, deployCmd)
+
+	var diffCmd = &cobra.Command{
+		Use:   "diff",
+		Short: "Show the changes a deploy would make",
+		Long:  "Renders the manifests (or the Helm chart) of an instance, applies them to the cluster with a server-side dry run and shows the differences with the live objects.  Only the `manifests` and `helm` deployment types are supported and Secret values are masked",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.Diff()
+		},
+	}
+
+	d.stim.BindCommand(diffCmd, deployCmd)
+
+	var historyCmd = &cobra.Command{
+		Use:   "history",
+		Short: "List past deploys",
+		Long:  "Lists the recorded deploys of the deploy config (who, when, commit, tag and result), newest first.  Deploys are read from `deploy.history-path` in Vault if it is set, otherwise from this machine.  Filter with --environment and --instance",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.History()
+		},
+	}
+
+	historyCmd.Flags().Int("limit", 20, "Number of deploys to list")
+	viper.BindPFlag("deploy-history-limit", historyCmd.Flags().Lookup("limit"))
+	historyCmd.Flags().StringP("output", "o", stim.OutputTable, "Output format (table|json)")
+	viper.BindPFlag("deploy-history-output", historyCmd.Flags().Lookup("output"))
+
+	d.stim.BindCommand(historyCmd, deployCmd)
+
+	var describeCmd = &cobra.Command{
+		Use:   "describe <id>",
+		Short: "Show the details of a past deploy",
+		Long:  "Shows the full record of a deploy listed by `stim deploy history`, including the resolved env vars.  The values of secrets are redacted",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.Describe(args[0])
+		},
+	}
+
+	describeCmd.Flags().StringP("output", "o", stim.OutputTable, "Output format (table|json)")
+	viper.BindPFlag("deploy-describe-output", describeCmd.Flags().Lookup("output"))
+
+	d.stim.BindCommand(describeCmd, deployCmd)
+
+	var previewCmd = &cobra.Command{
+		Use:   "preview",
+		Short: "Manage preview environments",
+		Long:  "Create and destroy dynamically named preview environments (ex. for pull requests) from the `previews` template in the deploy config",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	previewCmd.PersistentFlags().StringP("name", "n", "", "Name of the preview environment (ex. pr-1234)")
+	viper.BindPFlag("deploy-preview-name", previewCmd.PersistentFlags().Lookup("name"))
+	previewCmd.PersistentFlags().StringSlice("param", []string{}, "Preview parameter in the format NAME=VALUE.  Can be repeated")
+	viper.BindPFlag("deploy-preview-params", previewCmd.PersistentFlags().Lookup("param"))
+
+	var previewCreateCmd = &cobra.Command{
+		Use:         "create",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Create or update a preview environment",
+		Long:        "Deploys to each instance of a preview environment created from the `previews` template",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.Preview(false)
+		},
+	}
+
+	var previewDestroyCmd = &cobra.Command{
+		Use:         "destroy",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Destroy a preview environment",
+		Long:        "Runs the `previews.destroyScript` for each instance of a preview environment",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.Preview(true)
+		},
+	}
+
+	d.stim.BindCommand(previewCreateCmd, previewCmd)
+	d.stim.BindCommand(previewDestroyCmd, previewCmd)
+	d.stim.BindCommand(previewCmd, deployCmd)
+
+	var rotateSecretsCmd = &cobra.Command{
+		Use:         "rotate-secrets",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Rotate the secrets of an instance",
+		Long:        "Rotates the Vault secrets in the `rotate` config of an instance, then redeploys, restarts or runs the hooks set in the config and waits for the health checks.  The deploy policy of the environment (freeze windows, confirmations and approvals) applies",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.RotateSecrets()
+		},
+	}
+
+	rotateSecretsCmd.Flags().StringSlice("secret", nil, "Name of a secret in the `rotate` config to rotate.  Can be repeated.  Defaults to all secrets")
+	viper.BindPFlag("deploy-rotate-secrets", rotateSecretsCmd.Flags().Lookup("secret"))
+
+	d.stim.BindCommand(rotateSecretsCmd, deployCmd)
+
+	var checksumsCmd = &cobra.Command{
+		Use:   "checksums",
+		Short: "Write the checksums of the deploy config to sign",
+		Long:  "Writes the SHA-256 checksums of the deploy config file and the files of the deploy directory to `<deploy file>.sum`.  Sign it with cosign, minisign or GPG to `<deploy file>.sum.sig` for environments that require a signed deploy config",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.WriteChecksums()
+		},
+	}
+
+	d.stim.BindCommand(checksumsCmd, deployCmd)
+
+	var verifyCmd = &cobra.Command{
+		Use:   "verify",
+		Short: "Verify the signature of the deploy config",
+		Long:  "Verifies the signature of `<deploy file>.sum` with the tool and public key of `deploy.signature.type` and `deploy.signature.key`, and that the deploy config file and deploy directory match the signed checksums",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.VerifySignature()
+		},
+	}
+
+	d.stim.BindCommand(verifyCmd, deployCmd)
+
+	var checkPolicyCmd = &cobra.Command{
+		Use:   "check-policy",
+		Short: "Check the deploy config against the deploy policies",
+		Long:  "Evaluates the Rego policies of `deploy.policy.paths` and `deploy.policy.bundle-url` against the merged spec of the selected instance(s) with `opa eval`, as `stim deploy` does before deploying.  Vault secrets are not read",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.CheckPolicies()
+		},
+	}
+
+	d.stim.BindCommand(checkPolicyCmd, deployCmd)
+
+	var generateCICmd = &cobra.Command{
+		Use:   "generate-ci",
+		Short: "Generate a CI pipeline for the deploy config",
+		Long:  "Prints a GitHub Actions workflow, GitLab CI pipeline or Jenkinsfile that runs `stim deploy` for every instance of the deploy config, environment by environment, with placeholders for the Vault AppRole login and the env vars referenced in the config",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.GenerateCI()
+		},
+	}
+
+	generateCICmd.Flags().String("format", ciFormatGithubActions, "Pipeline format (github-actions|gitlab|jenkinsfile)")
+	viper.BindPFlag("deploy-generate-ci-format", generateCICmd.Flags().Lookup("format"))
+
+	d.stim.BindCommand(generateCICmd, deployCmd)
+
+	var packageCmd = &cobra.Command{
+		Use:   "package",
+		Short: "Package a deploy to run later or elsewhere",
+		Long:  "Bundles the deployment directory, the resolved (non-secret) deploy config and the templates that don't use secrets of the selected instance(s) into a tarball with a manifest, so that the reviewed files are deployed as-is later or on a machine without the repo (`stim deploy run-package`).  Tag expressions and `valueFrom` values that aren't sensitive are resolved when packaging, secrets are read when the package is deployed",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.Package()
+		},
+	}
+
+	packageCmd.Flags().StringP("output", "o", "", "File to write the package to (default \"<deployment>-<environment>[-<instance>].tar.gz\")")
+	viper.BindPFlag("deploy-package-output", packageCmd.Flags().Lookup("output"))
+
+	d.stim.BindCommand(packageCmd, deployCmd)
+
+	var runPackageCmd = &cobra.Command{
+		Use:         "run-package <package>",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Deploy a package",
+		Long:        "Deploys a package created by `stim deploy package` to its instance(s), after checking its files against its manifest.  The secrets are read from Vault and the deploy policy of the environment applies as when deploying from the deploy config",
+		Args:        cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.RunPackage(args[0])
+		},
+	}
+
+	d.stim.BindCommand(runPackageCmd, deployCmd)
+
+	var promoteCmd = &cobra.Command{
+		Use:         "promote",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Deploy the version of one environment to another",
+		Long:        "Deploys the image tags of the last deploy to the --from environment (from the deploy history) to the --to environment, ex. from stage to prod.  The last deploy to the --from environment must have succeeded.  The deploy policy of the --to environment applies as for any deploy",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.Promote()
+		},
+	}
+
+	promoteCmd.Flags().String("from", "", "Environment whose deployed version is promoted")
+	viper.BindPFlag("deploy-promote-from", promoteCmd.Flags().Lookup("from"))
+	d.stim.BindFlagCompletion(promoteCmd, "from", "deploy-environments", d.completeEnvironments)
+	promoteCmd.Flags().String("to", "", "Environment to deploy the version to")
+	viper.BindPFlag("deploy-promote-to", promoteCmd.Flags().Lookup("to"))
+	d.stim.BindFlagCompletion(promoteCmd, "to", "deploy-environments", d.completeEnvironments)
+
+	d.stim.BindCommand(promoteCmd, deployCmd)
+
+	var freezeCmd = &cobra.Command{
+		Use:   "freeze",
+		Short: "Manage deploy freeze windows",
+		Long:  "Manage the central deploy freeze windows stored in Vault.  Deploys to an environment in a freeze window are denied unless --override-freeze is given",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	var freezeAddCmd = &cobra.Command{
+		Use:         "add",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Add a freeze window",
+		Long:        "Add a freeze window to Vault",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.FreezeAdd()
+		},
+	}
+
+	freezeAddCmd.Flags().String("start", "", "Start of the freeze as an RFC 3339 timestamp (ex. 2024-12-20T00:00:00Z).  Defaults to now")
+	viper.BindPFlag("deploy-freeze-start", freezeAddCmd.Flags().Lookup("start"))
+	freezeAddCmd.Flags().String("end", "", "End of the freeze as an RFC 3339 timestamp")
+	viper.BindPFlag("deploy-freeze-end", freezeAddCmd.Flags().Lookup("end"))
+	freezeAddCmd.Flags().String("duration", "", "Length of the freeze (ex. 72h), instead of --end")
+	viper.BindPFlag("deploy-freeze-duration", freezeAddCmd.Flags().Lookup("duration"))
+	freezeAddCmd.Flags().String("reason", "", "Required. Reason shown when a deploy is denied")
+	viper.BindPFlag("deploy-freeze-reason", freezeAddCmd.Flags().Lookup("reason"))
+	freezeAddCmd.Flags().StringSlice("environments", nil, "Environments to freeze.  Defaults to all environments")
+	viper.BindPFlag("deploy-freeze-environments", freezeAddCmd.Flags().Lookup("environments"))
+
+	var freezeListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List freeze windows",
+		Long:  "List the current and upcoming freeze windows from Vault and the deploy config",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.FreezeList()
+		},
+	}
+
+	var freezeRemoveCmd = &cobra.Command{
+		Use:         "remove <id>",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Remove a freeze window",
+		Long:        "Remove a freeze window from Vault",
+		Args:        cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.FreezeRemove(args[0])
+		},
+	}
+
+	d.stim.BindCommand(freezeAddCmd, freezeCmd)
+	d.stim.BindCommand(freezeListCmd, freezeCmd)
+	d.stim.BindCommand(freezeRemoveCmd, freezeCmd)
+	d.stim.BindCommand(freezeCmd, deployCmd)
 
 	return deployCmd
 }
@@ -1,12 +1,16 @@
 package deploy
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/PremiereGlobal/stim/pkg/i18n"
+	"github.com/PremiereGlobal/stim/pkg/notify"
+	log "github.com/PremiereGlobal/stim/pkg/stimlog"
 	"github.com/PremiereGlobal/stim/pkg/utils"
 	"github.com/PremiereGlobal/stim/stim"
 	v2e "github.com/PremiereGlobal/vault-to-envs/pkg/vaulttoenvs"
@@ -14,26 +18,36 @@ import (
 )
 
 const (
-	defaultContainerRepo   = "premiereglobal/kube-vault-deploy"
-	defaultContainerTag    = "0.3.3"
-	defaultDeployDirectory = "./"
-	defaultDeployScript    = "deploy.sh"
-	defaultConfigFile      = "./stim.deploy.yaml"
+	defaultContainerRepo    = "premiereglobal/kube-vault-deploy"
+	defaultContainerTag     = "0.3.3"
+	defaultDeployDirectory  = "./"
+	defaultDeployScript     = "deploy.sh"
+	defaultPowerShellScript = "deploy.ps1"
+	defaultConfigFile       = "./stim.deploy.yaml"
 )
 
 // Config is the root structure for the deployment configuration
 type Config struct {
 	configFilePath string
-	Deployment     Deployment     `yaml:"deployment"`
-	Global         Global         `yaml:"global"`
-	Environments   []*Environment `yaml:"environments"`
+	Extends        []string        `yaml:"extends"`
+	Deployment     Deployment      `yaml:"deployment"`
+	Global         Global          `yaml:"global"`
+	Environments   []*Environment  `yaml:"environments"`
+	Notifications  *Notifications  `yaml:"notifications"`
+	Previews       *Previews       `yaml:"previews"`
+	Freezes        []*FreezeWindow `yaml:"freezes"`
 	environmentMap map[string]int
+	// extendsChecksums are the checksums of the bases read for `extends`, by
+	// source
+	extendsChecksums map[string]string
 }
 
 // Deployment describes details about the deployment assets (directories, files, etc)
 type Deployment struct {
+	Type              string    `yaml:"type"`
 	Directory         string    `yaml:"directory"`
 	Script            string    `yaml:"script"`
+	Shell             string    `yaml:"shell"`
 	Container         Container `yaml:"container"`
 	fullDirectoryPath string
 }
@@ -52,45 +66,224 @@ type Global struct {
 // Spec contains the spec of a given environment/instance
 type Spec struct {
 	Kubernetes            Kubernetes              `yaml:"kubernetes"`
-	Secrets               []*v2e.SecretItem       `yaml:"secrets"`
+	Secrets               []*SecretItem           `yaml:"secrets"`
 	EnvironmentVars       []*EnvironmentVar       `yaml:"env"`
 	AddConfirmationPrompt bool                    `yaml:"addConfirmationPrompt"`
 	Tools                 map[string]stim.EnvTool `yaml:"tools"`
+	ConfigMap             *ConfigMap              `yaml:"configMap"`
+	Templates             []*Template             `yaml:"templates"`
+	Helm                  *Helm                   `yaml:"helm"`
+	Manifests             *Manifests              `yaml:"manifests"`
+	Hooks                 *Hooks                  `yaml:"hooks"`
+	HealthChecks          *HealthChecks           `yaml:"healthChecks"`
+	Rotate                *Rotate                 `yaml:"rotate"`
+	Images                []*Image                `yaml:"images"`
 }
 
 // Kubernetes describes the Kubernetes configuration to use
 type Kubernetes struct {
 	ServiceAccount string `yaml:"serviceAccount"`
 	Cluster        string `yaml:"cluster"`
+	Namespace      string `yaml:"namespace"`
+}
+
+// SecretItem describes a secret whose values are set as environment variables.
+// Secrets are read from Vault unless one of the AWS sources is set, in which
+// case `set` maps environment variable names to the keys/parameters to read.
+type SecretItem struct {
+	v2e.SecretItem    `yaml:",inline"`
+	AwsSecretsManager *AwsSecretsManagerSecret `yaml:"awsSecretsManager" json:"-"`
+	AwsSsm            *AwsSsmSecret            `yaml:"awsSsm" json:"-"`
+}
+
+// AwsSecretsManagerSecret describes a secret stored in AWS Secrets Manager.
+// The `set` keys are read from the secret's JSON value, an empty key sets the
+// whole value.
+type AwsSecretsManagerSecret struct {
+	SecretID     string `yaml:"secretId"`
+	VersionStage string `yaml:"versionStage"`
+	Region       string `yaml:"region"`
+	Profile      string `yaml:"profile"`
+}
+
+// AwsSsmSecret describes parameters stored in AWS SSM Parameter Store.  The
+// `set` parameter names are relative to Path unless they start with a `/`.
+type AwsSsmSecret struct {
+	Path    string `yaml:"path"`
+	Region  string `yaml:"region"`
+	Profile string `yaml:"profile"`
+}
+
+// isVault returns true if the secret is read from Vault
+func (s *SecretItem) isVault() bool {
+	return s.AwsSecretsManager == nil && s.AwsSsm == nil
+}
+
+// ConfigMap describes a Kubernetes ConfigMap that the resolved (non-secret)
+// environment variables are published to after a deploy
+type ConfigMap struct {
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace"`
+}
+
+// Release describes the git tag (and optional GitHub or GitLab release)
+// created for the deployed commit after each successful instance deploy
+type Release struct {
+	Tag      string         `yaml:"tag"`
+	Remote   string         `yaml:"remote"`
+	SkipPush bool           `yaml:"skipPush"`
+	Github   *GithubRelease `yaml:"github"`
+	Gitlab   *GitlabRelease `yaml:"gitlab"`
+}
+
+// GithubRelease describes the GitHub repo that releases are created in.  The
+// token is read from Vault if SecretPath is set, otherwise from GITHUB_TOKEN.
+type GithubRelease struct {
+	Repo       string `yaml:"repo"`
+	URL        string `yaml:"url"`
+	SecretPath string `yaml:"secretPath"`
+	SecretKey  string `yaml:"secretKey"`
+}
+
+// GithubDeployment describes the GitHub repo that deploys are recorded in as
+// GitHub Deployments.  The token is read from Vault if SecretPath is set,
+// otherwise as for `stim github`.
+type GithubDeployment struct {
+	Repo           string `yaml:"repo"`
+	Environment    string `yaml:"environment"`
+	EnvironmentURL string `yaml:"environmentUrl"`
+	Production     bool   `yaml:"production"`
+	URL            string `yaml:"url"`
+	SecretPath     string `yaml:"secretPath"`
+	SecretKey      string `yaml:"secretKey"`
+}
+
+// DatadogDeploy describes the Datadog events posted for each instance deploy
+// and the monitors muted while it runs.  `{ENVIRONMENT}`, `{INSTANCE}` and
+// `{CLUSTER}` are replaced in the tags.  The keys are read from Vault at
+// SecretPath if set, otherwise as for `stim datadog`.
+type DatadogDeploy struct {
+	Tags       []string     `yaml:"tags"`
+	Mute       *DatadogMute `yaml:"mute"`
+	SecretPath string       `yaml:"secretPath"`
+}
+
+// DatadogMute describes the monitors muted during a deploy.  The downtime
+// ends with the deploy, or after Duration if stim can't cancel it.
+type DatadogMute struct {
+	MonitorTags []string `yaml:"monitorTags"`
+	Scope       []string `yaml:"scope"`
+	Duration    string   `yaml:"duration"`
+}
+
+// GitlabRelease describes the GitLab project that releases are created in.
+// The token is read from Vault if SecretPath is set, otherwise from
+// GITLAB_TOKEN.
+type GitlabRelease struct {
+	Project    string `yaml:"project"`
+	URL        string `yaml:"url"`
+	SecretPath string `yaml:"secretPath"`
+	SecretKey  string `yaml:"secretKey"`
+}
+
+// Notifications describes where deploy start/success/failure events are sent.
+// Environment-level notifications replace the global Slack, Pagerduty and/or
+// backends settings for that environment.
+type Notifications struct {
+	Name      string                  `yaml:"name"`
+	Disabled  bool                    `yaml:"disabled"`
+	Slack     *SlackNotification      `yaml:"slack"`
+	Pagerduty *PagerdutyNotification  `yaml:"pagerduty"`
+	Backends  []*notify.BackendConfig `yaml:"backends"`
+}
+
+// SlackNotification describes the Slack channels deploy events are posted to
+type SlackNotification struct {
+	Channels []string `yaml:"channels"`
+	Username string   `yaml:"username"`
+	IconURL  string   `yaml:"iconUrl"`
+	Events   []string `yaml:"events"`
+}
+
+// PagerdutyNotification describes the Pagerduty services that deploy events
+// are sent to as change events
+type PagerdutyNotification struct {
+	Services []string `yaml:"services"`
+	Events   []string `yaml:"events"`
 }
 
 // Environment describes a deployment environment (i.e. dev, stage, prod, etc.)
 type Environment struct {
-	Name            string      `yaml:"name"`
-	Spec            *Spec       `yaml:"spec"`
-	Instances       []*Instance `yaml:"instances"`
-	RemoveAllPrompt bool        `yaml:"removeAllPrompt"`
+	Name            string            `yaml:"name"`
+	Spec            *Spec             `yaml:"spec"`
+	Instances       []*Instance       `yaml:"instances"`
+	RemoveAllPrompt bool              `yaml:"removeAllPrompt"`
+	Notifications   *Notifications    `yaml:"notifications"`
+	Release         *Release          `yaml:"release"`
+	Github          *GithubDeployment `yaml:"github"`
+	Datadog         *DatadogDeploy    `yaml:"datadog"`
+	Policy          *Policy           `yaml:"policy"`
+	Strategy        *Strategy         `yaml:"strategy"`
 	instanceMap     map[string]int
+	preview         bool
 }
 
 // Instance describes an instance of a deployment within an environment (i.e. us-west-2 for env prod)
 type Instance struct {
-	Name string `yaml:"name"`
-	Spec *Spec  `yaml:"spec"`
+	Name    string `yaml:"name"`
+	Spec    *Spec  `yaml:"spec"`
+	origins map[string]string
 }
 
 // EnvironmentVar describes a shell env var to be injected into the deployment environment
 type EnvironmentVar struct {
-	Name  string `yaml:"name"`
-	Value string `yaml:"value"`
+	Name      string                `yaml:"name"`
+	Value     string                `yaml:"value"`
+	ValueFrom *EnvironmentVarSource `yaml:"valueFrom,omitempty"`
 }
 
 // parseConfig opens the deployment config file and ensures it is valid
-func (d *Deploy) parseConfig() {
+func (d *Deploy) parseConfig() error {
+	defer d.stim.Time(stim.PhaseDeployConfig)()
 
-	d.config = Config{}
+	err := d.loadConfig()
+	if err != nil {
+		return err
+	}
+	return processConfig(&d.config, d.stim, d.log)
+}
+
+// loadConfig reads the deployment config file, or the one of the selected
+// service, without resolving it
+func (d *Deploy) loadConfig() error {
+
+	if d.service == nil {
+		services, err := d.selectServices()
+		if err != nil {
+			return err
+		}
+		switch {
+		case services == nil:
+		case len(services) == 0:
+			return stim.Aborted(d.stim.Message(i18n.MessageNoService))
+		case len(services) > 1:
+			return stim.UsageError(errors.New("Only one service can be selected with --service for this command"))
+		default:
+			d.service = services[0]
+		}
+	}
+
+	if d.service != nil {
+		return d.loadServiceConfig(d.service)
+	}
+	return d.loadConfigFile(d.stim.ConfigGetString("deploy.file"))
+}
 
-	configFile := d.stim.ConfigGetString("deploy.file")
+// loadConfigFile reads the given deployment config file (the default one if
+// empty) without resolving it
+func (d *Deploy) loadConfigFile(configFile string) error {
+
+	d.config = Config{}
 
 	if configFile == "" {
 		setConfigDefault(&configFile, defaultConfigFile)
@@ -99,125 +292,86 @@ func (d *Deploy) parseConfig() {
 
 	_, err := os.Stat(configFile)
 	if err != nil && !os.IsExist(err) {
-		d.log.Fatal("No deployment config file exists at: {}", configFile)
+		return stim.ConfigError(fmt.Errorf("No deployment config file exists at: %s", configFile))
 	}
 
 	contentstring, err := ioutil.ReadFile(configFile)
 	if err != nil {
-		d.log.Fatal("Deployment config file could not be read: {}", err)
+		return stim.ConfigError(fmt.Errorf("Deployment config file could not be read: %v", err))
+	}
+
+	contentstring, err = interpolateEnv(contentstring, lookupEnv)
+	if err != nil {
+		return stim.ConfigError(err)
 	}
 
 	if ok, err := utils.IsYaml(contentstring); !ok {
-		d.log.Fatal("Deployment config file ({}) is not valid YAML: {}", configFile, err)
+		return stim.ConfigError(fmt.Errorf("Deployment config file (%s) is not valid YAML: %v", configFile, err))
 	}
 
 	err = yaml.Unmarshal([]byte(contentstring), &d.config)
 	if err != nil {
-		d.log.Fatal("Error parsing deployment config {}", err)
+		return stim.ConfigError(fmt.Errorf("Error parsing deployment config %v", err))
 	}
 
-	d.config.configFilePath = configFile
-
-	d.processConfig()
+	err = extendConfig(&d.config, configFile)
+	if err != nil {
+		return stim.ConfigError(err)
+	}
 
+	d.config.configFilePath = configFile
+	return nil
 }
 
-// processConfig ensures that the deployment config is valid
-func (d *Deploy) processConfig() {
-
-	// Set defaults
-	setConfigDefault(&d.config.Deployment.Container.Repo, defaultContainerRepo)
-	setConfigDefault(&d.config.Deployment.Container.Tag, defaultContainerTag)
-	setConfigDefault(&d.config.Deployment.Directory, defaultDeployDirectory)
-	setConfigDefault(&d.config.Deployment.Script, defaultDeployScript)
+// processConfig resolves the deployment config, applies the command line
+// overrides of the options and determines the full deployment directory path
+func processConfig(config *Config, options stim.ConfigReader, logger log.StimLogger) error {
 
-	// Create our global spec if it doesn't exist so we don't have to keep checking if it exists
-	if d.config.Global.Spec == nil {
-		d.config.Global.Spec = &Spec{}
+	err := resolveConfig(config)
+	if err != nil {
+		return stim.ConfigError(err)
 	}
 
-	d.validateSpec(d.config.Global.Spec)
-
-	d.config.environmentMap = make(map[string]int)
-	for i, environment := range d.config.Environments {
-
-		// Check to make sure that we don't have multiple environments with the same name
-		if _, ok := d.config.environmentMap[environment.Name]; ok {
-			d.log.Fatal("Error parsing config, duplicate environment name `{}` found", environment.Name)
-		}
-
-		// Ensure there are instances for this environment
-		if len(environment.Instances) <= 0 {
-			d.log.Fatal("No instances found for environment: `{}`", environment.Name)
-		}
-
-		d.config.environmentMap[environment.Name] = i
-
-		// Create our environment spec if it doesn't exist so we don't have to keep checking if it exists
-		if environment.Spec == nil {
-			environment.Spec = &Spec{}
-		}
-
-		d.validateSpec(environment.Spec)
-
-		environment.instanceMap = make(map[string]int)
-		for j, instance := range environment.Instances {
-
-			// Check to make sure that we don't have multiple instances with the same name
-			if _, ok := environment.instanceMap[instance.Name]; ok {
-				d.log.Fatal("Error parsing config, duplicate instance name '{}' for environment '{}'", instance.Name, environment.Name)
-			}
-
-			// Ensure the instance name does not conflict with the ALL option name.  This is a reserved name for designating a deployment to all instances in an environment via the manual prompt list
-			if strings.ToLower(instance.Name) == strings.ToLower(allOptionPrompt) || strings.ToLower(instance.Name) == strings.ToLower(allOptionCli) {
-				d.log.Fatal("Deployment config cannot have an instance named '{}'. It is a reserved name.", instance.Name)
-			}
+	err = addOverrides(config, options, logger)
+	if err != nil {
+		return err
+	}
 
-			environment.instanceMap[instance.Name] = j
+	// Determine the full directory path
+	configAbs, err := filepath.Abs(config.configFilePath)
+	if err != nil {
+		return fmt.Errorf("Error fetching deploy filepath '%v'", err)
+	}
+	config.Deployment.fullDirectoryPath = filepath.Join(filepath.Dir(configAbs), config.Deployment.Directory)
+	return nil
+}
 
-			// Create our instance spec if it doesn't exist so we don't have to keep checking if it exists
-			if instance.Spec == nil {
-				instance.Spec = &Spec{}
-			}
+// kubeConfigSecretMaps are the env vars set from the kube-config secret of
+// the cluster of every instance
+var kubeConfigSecretMaps = map[string]string{
+	"CLUSTER_SERVER": "cluster-server",
+	"CLUSTER_CA":     "cluster-ca",
+	"USER_TOKEN":     "user-token",
+}
 
-			d.validateSpec(instance.Spec)
-
-			// Merge all of the secrets and environment variables
-			// Instance-level specs take precedence, followed by environment-level then global-level
-			if instance.Spec.Kubernetes.ServiceAccount == "" {
-				if environment.Spec.Kubernetes.ServiceAccount != "" {
-					instance.Spec.Kubernetes.ServiceAccount = environment.Spec.Kubernetes.ServiceAccount
-				} else if d.config.Global.Spec.Kubernetes.ServiceAccount != "" {
-					instance.Spec.Kubernetes.ServiceAccount = d.config.Global.Spec.Kubernetes.ServiceAccount
-				} else {
-					d.log.Fatal("Kubernetes service account is not set for instance '{}' in environment '{}'", instance.Name, environment.Name)
-				}
-			}
-			if instance.Spec.Kubernetes.Cluster == "" {
-				if environment.Spec.Kubernetes.Cluster != "" {
-					instance.Spec.Kubernetes.Cluster = environment.Spec.Kubernetes.Cluster
-				} else if d.config.Global.Spec.Kubernetes.Cluster != "" {
-					instance.Spec.Kubernetes.Cluster = d.config.Global.Spec.Kubernetes.Cluster
-				} else {
-					d.log.Fatal("Kubernetes cluster is not set for instance '{}' in environment '{}'", instance.Name, environment.Name)
-				}
-			}
+// addStimEnvs adds the Vault and deployment env vars and the kube-config
+// secret (at the path returned by kubeConfigSecretPath) that stim provides to
+// every instance
+func addStimEnvs(config *Config, vault stim.VaultClient, kubeConfigSecretPath func(cluster string, serviceAccount string) string) error {
 
-			instance.Spec.Tools = mergeTools(instance.Spec.Tools, environment.Spec.Tools, d.config.Global.Spec.Tools)
-			instance.Spec.EnvironmentVars = mergeEnvVars(instance.Spec.EnvironmentVars, environment.Spec.EnvironmentVars, d.config.Global.Spec.EnvironmentVars)
-			instance.Spec.Secrets = mergeSecrets(instance.Spec.Secrets, environment.Spec.Secrets, d.config.Global.Spec.Secrets)
+	// Get Vault details
+	vaultToken, err := vault.GetToken()
+	if err != nil {
+		return stim.AuthError(fmt.Errorf("Error fetching Vault token for deploy '%v'", err))
+	}
 
-			// Get Vault details
-			vault := d.stim.Vault()
-			vaultToken, err := vault.GetToken()
-			if err != nil {
-				d.log.Fatal("Error fetching Vault token for deploy '{}'", err)
-			}
+	vaultAddress, err := vault.GetAddress()
+	if err != nil {
+		return fmt.Errorf("Error fetching Vault address for deploy '%v'", err)
+	}
 
-			vaultAddress, err := vault.GetAddress()
-			if err != nil {
-				d.log.Fatal("Error fetching Vault address for deploy '{}'", err)
-			}
+	for _, environment := range config.Environments {
+		for _, instance := range environment.Instances {
 
 			// Generate stim env vars
 			stimEnvs := []*EnvironmentVar{}
@@ -229,36 +383,38 @@ func (d *Deploy) processConfig() {
 				&EnvironmentVar{Name: "DEPLOY_INSTANCE", Value: instance.Name},
 				&EnvironmentVar{Name: "DEPLOY_CLUSTER", Value: instance.Spec.Kubernetes.Cluster},
 			}...)
+			if environment.preview {
+				stimEnvs = append(stimEnvs, &EnvironmentVar{Name: "DEPLOY_PREVIEW", Value: environment.Name})
+			}
 
 			// Generate the Kube config secret
-			var stimSecrets []*v2e.SecretItem
+			var stimSecrets []*SecretItem
 			secretMap := make(map[string]string)
-			secretMap["CLUSTER_SERVER"] = "cluster-server"
-			secretMap["CLUSTER_CA"] = "cluster-ca"
-			secretMap["USER_TOKEN"] = "user-token"
-			stimSecrets = append(stimSecrets, &v2e.SecretItem{
-				SecretPath: fmt.Sprintf("secret/kubernetes/%s/%s/kube-config", instance.Spec.Kubernetes.Cluster, instance.Spec.Kubernetes.ServiceAccount),
+			for name, key := range kubeConfigSecretMaps {
+				secretMap[name] = key
+			}
+			stimSecrets = append(stimSecrets, &SecretItem{SecretItem: v2e.SecretItem{
+				SecretPath: kubeConfigSecretPath(instance.Spec.Kubernetes.Cluster, instance.Spec.Kubernetes.ServiceAccount),
 				SecretMaps: secretMap,
-			})
+			}})
 
 			// Add stim envs/secrets and ensure no reserved env vars have been set
-			d.finalizeEnv(instance, stimEnvs, stimSecrets)
+			err = finalizeEnv(instance, stimEnvs, stimSecrets)
+			if err != nil {
+				return err
+			}
 		}
 	}
 
-	// Determine the full directory path
-	configAbs, err := filepath.Abs(d.config.configFilePath)
-	if err != nil {
-		d.log.Fatal("Error fetching deploy filepath '{}'", err)
-	}
-	d.config.Deployment.fullDirectoryPath = filepath.Join(filepath.Dir(configAbs), d.config.Deployment.Directory)
+	return nil
 }
 
-// Generate the list of reserved env var names
-func (d *Deploy) finalizeEnv(instance *Instance, stimEnvs []*EnvironmentVar, stimSecrets []*v2e.SecretItem) {
+// finalizeEnv adds the stim env vars and secrets to an instance and ensures
+// that the instance doesn't set any of them
+func finalizeEnv(instance *Instance, stimEnvs []*EnvironmentVar, stimSecrets []*SecretItem) error {
 
-	// Generate the list of reserved env var names (additionally SECRET_CONFIG as we'll add that one at the end)
-	reservedVarNames := []string{"SECRET_CONFIG", "STIM_DEPLOY"}
+	// Generate the list of reserved env var names (additionally the env vars that are added at the end or during the deploy)
+	reservedVarNames := []string{"SECRET_CONFIG", "STIM_DEPLOY", "DEPLOY_PREVIEW", "DEPLOY_NAMESPACE"}
 
 	for _, s := range stimEnvs {
 		reservedVarNames = append(reservedVarNames, s.Name)
@@ -269,27 +425,20 @@ func (d *Deploy) finalizeEnv(instance *Instance, stimEnvs []*EnvironmentVar, sti
 		}
 	}
 
-	// Exit if any user-provided environment vars conflict with reserved ones
-	for _, e := range instance.Spec.EnvironmentVars {
-		if utils.Contains(reservedVarNames, e.Name) {
-			d.log.Fatal("Reserved environment variable name '{}' found in config", e.Name)
-		}
-	}
-	for _, s := range instance.Spec.Secrets {
-		for m := range s.SecretMaps {
-			if utils.Contains(reservedVarNames, m) {
-				d.log.Fatal("Reserved environment variable name '{}' found in config", m)
-			}
-		}
+	// Exit if any user-provided environment vars conflict with reserved ones,
+	// listing all of them with the level they're set at
+	reserved := reservedEnvConflicts(instance, func(name string) bool { return utils.Contains(reservedVarNames, name) })
+	if len(reserved) > 0 {
+		return stim.ConfigError(fmt.Errorf("Reserved environment variable names found in config for instance '%s': %s", instance.Name, strings.Join(reserved, ", ")))
 	}
 
 	// Combine our secrets
 	instance.Spec.Secrets = append(instance.Spec.Secrets, stimSecrets...)
 
 	// Create the secret config
-	secretConfig, err := d.makeSecretConfig(instance)
+	secretConfig, err := makeSecretConfig(instance)
 	if err != nil {
-		d.log.Fatal("Error making secret config '{}'", err)
+		return fmt.Errorf("Error making secret config '%v'", err)
 	}
 	stimEnvs = append(stimEnvs, &EnvironmentVar{Name: "SECRET_CONFIG", Value: secretConfig})
 	stimEnvs = append(stimEnvs, &EnvironmentVar{Name: "STIM_DEPLOY", Value: "true"})
@@ -297,108 +446,5 @@ func (d *Deploy) finalizeEnv(instance *Instance, stimEnvs []*EnvironmentVar, sti
 	// Combine our env vars
 	instance.Spec.EnvironmentVars = append(instance.Spec.EnvironmentVars, stimEnvs...)
 
-}
-
-// validateSpec validates fields in a config 'spec' section to ensure that it
-// meets all requirements
-func (d *Deploy) validateSpec(spec *Spec) {
-	for toolName, toolSpec := range spec.Tools {
-		if toolName == "helm" && toolSpec.Version == "" {
-			d.log.Fatal("Version detection not supported for helm, please specify a version in the `spec.tools.helm` config")
-		}
-	}
-}
-
-// mergeEnvVars is used to merge environment variable configuration at the various levels it can be set at
-func mergeEnvVars(instance []*EnvironmentVar, environment []*EnvironmentVar, global []*EnvironmentVar) []*EnvironmentVar {
-
-	result := instance
-
-	// Add environment envVars (if they don't already exist)
-	for _, e := range environment {
-		exists := false
-		for _, inst := range result {
-			if inst.Name == e.Name {
-				exists = true
-			}
-		}
-
-		// Add the item if it doesn't exist
-		if !exists {
-			result = append(result, e)
-		}
-	}
-
-	// Add global envVars (if they don't already exist)
-	for _, g := range global {
-		exists := false
-		for _, inst := range result {
-			if inst.Name == g.Name {
-				exists = true
-			}
-		}
-
-		// Add the item if it doesn't exist
-		if !exists {
-			result = append(result, g)
-		}
-	}
-
-	return result
-}
-
-// mergeSecrets is used to merge secret configs at the various levels they can be set at
-func mergeSecrets(instance []*v2e.SecretItem, environment []*v2e.SecretItem, global []*v2e.SecretItem) []*v2e.SecretItem {
-
-	result := global
-
-	// Add environment envVars
-	for _, e := range environment {
-		result = append(result, e)
-	}
-
-	// Add global envVars to instance (if they don't already exist)
-	for _, inst := range instance {
-		result = append(result, inst)
-	}
-
-	return result
-}
-
-// mergeTools is used to merge tool configurations
-func mergeTools(instance map[string]stim.EnvTool, environment map[string]stim.EnvTool, global map[string]stim.EnvTool) map[string]stim.EnvTool {
-
-	result := make(map[string]stim.EnvTool)
-
-	// Set Global tools
-	for k, v := range global {
-		result[k] = v
-	}
-
-	// Overwrite with instance tools
-	for k, v := range environment {
-		if v.Unset == true {
-			delete(result, k)
-		} else {
-			result[k] = v
-		}
-	}
-
-	// Overwrite with instance tools
-	for k, v := range instance {
-		if v.Unset == true {
-			delete(result, k)
-		} else {
-			result[k] = v
-		}
-	}
-
-	return result
-}
-
-// setConfigDefault is used to set a default value (if it doesn't exist)
-func setConfigDefault(value *string, def string) {
-	if len(*value) == 0 {
-		*value = def
-	}
+	return nil
 }
@@ -0,0 +1,186 @@
+package deploy
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	log "github.com/PremiereGlobal/stim/pkg/stimlog"
+	"github.com/PremiereGlobal/stim/stim"
+	"github.com/PremiereGlobal/stim/stim/stimtest"
+	"gopkg.in/yaml.v2"
+	"gotest.tools/assert"
+)
+
+// testConfig parses a deploy config as if it were read from the path
+func testConfig(t *testing.T, content string, path string) *Config {
+	config := &Config{}
+	assert.NilError(t, yaml.Unmarshal([]byte(content), config))
+	config.configFilePath = path
+	return config
+}
+
+// envNames returns the names and values of env vars as NAME=VALUE
+func envNames(envs []*EnvironmentVar) []string {
+	names := make([]string, len(envs))
+	for i, e := range envs {
+		names[i] = e.Name + "=" + e.Value
+	}
+	return names
+}
+
+func TestMergeEnvVars(t *testing.T) {
+	tests := []struct {
+		name        string
+		instance    []*EnvironmentVar
+		environment []*EnvironmentVar
+		global      []*EnvironmentVar
+		expected    []string
+	}{
+		{"empty", nil, nil, nil, []string{}},
+		{"global only", nil, nil, []*EnvironmentVar{{Name: "A", Value: "g"}}, []string{"A=g"}},
+		{
+			"instance over environment over global",
+			[]*EnvironmentVar{{Name: "A", Value: "i"}},
+			[]*EnvironmentVar{{Name: "A", Value: "e"}, {Name: "B", Value: "e"}},
+			[]*EnvironmentVar{{Name: "A", Value: "g"}, {Name: "B", Value: "g"}, {Name: "C", Value: "g"}},
+			[]string{"A=i", "B=e", "C=g"},
+		},
+		{
+			"environment over global",
+			nil,
+			[]*EnvironmentVar{{Name: "B", Value: "e"}},
+			[]*EnvironmentVar{{Name: "A", Value: "g"}, {Name: "B", Value: "g"}},
+			[]string{"B=e", "A=g"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.DeepEqual(t, envNames(mergeEnvVars(test.instance, test.environment, test.global)), test.expected)
+		})
+	}
+}
+
+func TestProcessConfig(t *testing.T) {
+	content := `
+deployment:
+  directory: deploy
+global:
+  spec:
+    kubernetes:
+      cluster: blue.my-domain.com
+      serviceAccount: deployer
+    env:
+      - name: REPLICAS
+        value: "2"
+      - name: IMAGE_TAG
+        value: 1.0.0
+environments:
+  - name: stage
+    instances:
+      - name: stage1
+`
+
+	tests := []struct {
+		name     string
+		options  stimtest.Config
+		expected []string
+		err      string
+		exitCode int
+	}{
+		{"no overrides", stimtest.Config{}, []string{"REPLICAS=2", "IMAGE_TAG=1.0.0"}, "", 0},
+		{"set", stimtest.Config{"deploy.set": []string{"IMAGE_TAG=1.4.0-rc1", "DEBUG=true"}}, []string{"REPLICAS=2", "IMAGE_TAG=1.4.0-rc1", "DEBUG=true"}, "", 0},
+		{"reserved", stimtest.Config{"deploy.set": []string{"VAULT_TOKEN=abc"}}, nil, "Reserved environment variable name 'VAULT_TOKEN' can't be set with --set", stim.ExitCodeUsage},
+		{"missing file", stimtest.Config{"deploy.set-file": []string{"TLS_CERT=./missing.pem"}}, nil, "Error reading --set-file file for 'TLS_CERT'", stim.ExitCodeUsage},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := testConfig(t, content, filepath.Join("services", "api", "stim.deploy.yaml"))
+			logger := &stimtest.Logger{}
+
+			err := processConfig(config, test.options, logger)
+			if test.err != "" {
+				assert.ErrorContains(t, err, test.err)
+				assert.Equal(t, stim.ExitCode(err), test.exitCode)
+				return
+			}
+			assert.NilError(t, err)
+
+			instance := config.Environments[0].Instances[0]
+			assert.DeepEqual(t, envNames(instance.Spec.EnvironmentVars), test.expected)
+			assert.Assert(t, filepath.IsAbs(config.Deployment.fullDirectoryPath))
+			assert.Equal(t, filepath.Base(config.Deployment.fullDirectoryPath), "deploy")
+
+			for _, set := range test.options.ConfigGetStringSlice("deploy.set") {
+				assert.Equal(t, instance.origins["env."+strings.SplitN(set, "=", 2)[0]], originCLI)
+			}
+			assert.Equal(t, len(logger.Messages(log.DebugLevel)), len(test.options.ConfigGetStringSlice("deploy.set")))
+		})
+	}
+}
+
+func TestAddStimEnvs(t *testing.T) {
+	kubeConfigSecretPath := func(cluster string, serviceAccount string) string {
+		return "secret/kubernetes/" + cluster + "/" + serviceAccount + "/kube-config"
+	}
+
+	tests := []struct {
+		name     string
+		env      string
+		vault    *stimtest.Vault
+		expected []string
+		err      string
+		exitCode int
+	}{
+		{
+			"stim env vars",
+			"REPLICAS",
+			&stimtest.Vault{Token: "s.token", Address: "https://vault:8200"},
+			[]string{"REPLICAS", "VAULT_ADDR", "VAULT_TOKEN", "DEPLOY_ENVIRONMENT", "DEPLOY_INSTANCE", "DEPLOY_CLUSTER", "SECRET_CONFIG", "STIM_DEPLOY"},
+			"", 0,
+		},
+		{"reserved env var", "DEPLOY_CLUSTER", &stimtest.Vault{Token: "s.token"}, nil, "Reserved environment variable names found in config for instance 'stage1': DEPLOY_CLUSTER (env, global)", stim.ExitCodeConfig},
+		{"reserved kube-config env var", "USER_TOKEN", &stimtest.Vault{Token: "s.token"}, nil, "Reserved environment variable names found in config for instance 'stage1': USER_TOKEN (env, global)", stim.ExitCodeConfig},
+		{"no token", "REPLICAS", &stimtest.Vault{}, nil, "Error fetching Vault token for deploy", stim.ExitCodeAuth},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := testConfig(t, `
+global:
+  spec:
+    kubernetes:
+      cluster: blue.my-domain.com
+      serviceAccount: deployer
+    env:
+      - name: `+test.env+`
+        value: "2"
+environments:
+  - name: stage
+    instances:
+      - name: stage1
+`, "stim.deploy.yaml")
+			assert.NilError(t, resolveConfig(config))
+
+			err := addStimEnvs(config, test.vault, kubeConfigSecretPath)
+			if test.err != "" {
+				assert.ErrorContains(t, err, test.err)
+				assert.Equal(t, stim.ExitCode(err), test.exitCode)
+				return
+			}
+			assert.NilError(t, err)
+
+			spec := config.Environments[0].Instances[0].Spec
+			var names []string
+			for _, e := range spec.EnvironmentVars {
+				names = append(names, e.Name)
+			}
+			assert.DeepEqual(t, names, test.expected)
+			kubeConfig := spec.Secrets[len(spec.Secrets)-1]
+			assert.Equal(t, kubeConfig.SecretPath, "secret/kubernetes/blue.my-domain.com/deployer/kube-config")
+			assert.Equal(t, kubeConfig.SecretMaps["USER_TOKEN"], "user-token")
+		})
+	}
+}
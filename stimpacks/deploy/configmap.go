@@ -0,0 +1,76 @@
+package deploy
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/PremiereGlobal/stim/pkg/kubernetes"
+	"github.com/PremiereGlobal/stim/pkg/utils"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// sensitiveEnvVarNames are stim-generated env vars that must never be
+// published to the ConfigMap
+var sensitiveEnvVarNames = []string{"VAULT_TOKEN", "SECRET_CONFIG"}
+
+// isSensitiveEnvVar returns true if the env var is generated by stim, set
+// from a secret or from a sensitive value source
+func isSensitiveEnvVar(instance *Instance, name string) bool {
+	if utils.Contains(sensitiveEnvVarNames, name) {
+		return true
+	}
+	for _, e := range instance.Spec.EnvironmentVars {
+		if e.Name == name && e.ValueFrom != nil && e.ValueFrom.isSensitive() {
+			return true
+		}
+	}
+	for _, s := range instance.Spec.Secrets {
+		if _, ok := s.SecretMaps[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// publishConfigMap writes the resolved, non-secret environment variables of
+// the instance to the ConfigMap configured in its spec
+func (d *Deploy) publishConfigMap(environment *Environment, instance *Instance) error {
+
+	data := make(map[string]string)
+	for _, e := range instance.Spec.EnvironmentVars {
+		if !isSensitiveEnvVar(instance, e.Name) {
+			data[e.Name] = e.Value
+		}
+	}
+
+	labels := map[string]string{
+		"app.kubernetes.io/managed-by": "stim",
+		"stim.deploy/environment":      environment.Name,
+		"stim.deploy/instance":         instance.Name,
+	}
+
+	tmpDir, err := ioutil.TempDir("", "stim-deploy")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	kc, err := d.stim.KubeConfigFromVault(&stim.KubeConfigOptions{
+		Cluster:        instance.Spec.Kubernetes.Cluster,
+		ServiceAccount: instance.Spec.Kubernetes.ServiceAccount,
+		Path:           filepath.Join(tmpDir, "kubeconfig"),
+	})
+	if err != nil {
+		return err
+	}
+
+	kube, err := kubernetes.New(kc)
+	if err != nil {
+		return err
+	}
+
+	configMap := instance.Spec.ConfigMap
+	d.log.Info("Publishing deploy configuration to ConfigMap {}/{}", configMap.Namespace, configMap.Name)
+	return kube.ApplyConfigMap(configMap.Namespace, configMap.Name, data, labels)
+}
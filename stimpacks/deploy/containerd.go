@@ -0,0 +1,145 @@
+package deploy
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	log "github.com/PremiereGlobal/stim/pkg/stimlog"
+)
+
+// containerdEngine runs the deploy container with containerd through the
+// `nerdctl` CLI.  The containerd namespace is `CONTAINERD_NAMESPACE`, or the
+// nerdctl default.
+type containerdEngine struct {
+	log log.StimLogger
+}
+
+// Name is the name of the engine
+func (e *containerdEngine) Name() string {
+	return "containerd"
+}
+
+// Available checks that nerdctl is installed and can reach containerd
+func (e *containerdEngine) Available(ctx context.Context) error {
+
+	if _, err := exec.LookPath("nerdctl"); err != nil {
+		return fmt.Errorf("nerdctl is not installed: %v", err)
+	}
+
+	out, err := exec.CommandContext(ctx, "nerdctl", "info").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+// Login logs nerdctl in to the registry with `nerdctl login`
+func (e *containerdEngine) Login(host string, username string, password string) error {
+
+	cmd := exec.Command("nerdctl", "login", "--username", username, "--password-stdin", host)
+	cmd.Stdin = strings.NewReader(password)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Pull pulls the image and returns its id and digests
+func (e *containerdEngine) Pull(ctx context.Context, image string) (map[string]string, error) {
+
+	out, err := exec.CommandContext(ctx, "nerdctl", "pull", image).CombinedOutput()
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	for scanner.Scan() {
+		e.log.Debug(scanner.Text())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	details := map[string]string{}
+	out, err = exec.CommandContext(ctx, "nerdctl", "image", "inspect", image).Output()
+	if err != nil {
+		e.log.Warn("Unable to get the digest of image {}: {}", image, err)
+		return details, nil
+	}
+	var inspect []struct {
+		ID          string `json:"Id"`
+		RepoDigests []string
+	}
+	if err := json.Unmarshal(out, &inspect); err != nil || len(inspect) == 0 {
+		e.log.Warn("Unable to get the digest of image {}: {}", image, err)
+		return details, nil
+	}
+	details["id"] = inspect[0].ID
+	details["digest"] = strings.Join(inspect[0].RepoDigests, ",")
+
+	return details, nil
+}
+
+// Run runs the container with `nerdctl run` and waits for it to exit.  The
+// container is removed if the context is done first, stopping nerdctl alone
+// would leave it running.
+func (e *containerdEngine) Run(ctx context.Context, spec *containerSpec) (int, error) {
+
+	// The env vars are passed through the environment of nerdctl so that
+	// secrets aren't in its command line
+	cmd := exec.Command("nerdctl", nerdctlRunArgs(spec)...)
+	cmd.Env = append(os.Environ(), spec.Env...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stdout
+
+	err := cmd.Start()
+	if err != nil {
+		return 0, err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		out, rmErr := exec.Command("nerdctl", "rm", "--force", spec.Name).CombinedOutput()
+		if rmErr != nil {
+			e.log.Warn("Unable to remove the deploy container {}: {} {}", spec.Name, rmErr, strings.TrimSpace(string(out)))
+		}
+		<-done
+		return 0, ctx.Err()
+	}
+
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return 0, nil
+}
+
+// nerdctlRunArgs returns the arguments of `nerdctl run` for the container.
+// Env vars are only named, nerdctl reads their values from its environment.
+func nerdctlRunArgs(spec *containerSpec) []string {
+
+	args := []string{
+		"run", "--rm",
+		"--name", spec.Name,
+		"--workdir", spec.WorkDir,
+		"--volume", spec.DeployDir + ":" + spec.WorkDir,
+		"--volume", spec.CacheDir + ":" + spec.CacheMount,
+	}
+	for _, e := range spec.Env {
+		args = append(args, "--env", strings.SplitN(e, "=", 2)[0])
+	}
+	args = append(args, spec.Image)
+
+	return append(args, spec.Cmd...)
+}
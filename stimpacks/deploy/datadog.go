@@ -0,0 +1,150 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/datadog"
+)
+
+// defaultDatadogMuteDuration is the longest monitors stay muted when
+// `mute.duration` is not set
+const defaultDatadogMuteDuration = 30 * time.Minute
+
+// datadogDeploy is the Datadog client and downtime of an instance deploy
+type datadogDeploy struct {
+	client     *datadog.Datadog
+	downtimeID int64
+}
+
+// startDatadog posts the start event of an instance deploy and mutes its
+// monitors, if the environment has a `datadog` config.  Errors are logged
+// rather than failing the deploy.
+func (d *Deploy) startDatadog(environment *Environment, instance *Instance) {
+
+	config := environment.Datadog
+	if config == nil {
+		return
+	}
+
+	apiKey, appKey, err := d.stim.DatadogKeys(d.datadogVaultPath(config))
+	if err != nil {
+		d.log.Warn("Unable to read the Datadog keys: {}", err)
+		return
+	}
+	deploy := &datadogDeploy{client: datadog.New(&datadog.Config{APIKey: apiKey, AppKey: appKey, Site: d.stim.ConfigGetString("datadog.site")})}
+	if d.datadogDeploys == nil {
+		d.datadogDeploys = make(map[string]*datadogDeploy)
+	}
+	d.datadogDeploys[environment.Name+"/"+instance.Name] = deploy
+
+	d.postDatadogEvent(deploy, environment, instance, datadog.AlertInfo, "started", nil)
+
+	if config.Mute != nil {
+		duration := defaultDatadogMuteDuration
+		if config.Mute.Duration != "" {
+			duration, _ = time.ParseDuration(config.Mute.Duration)
+		}
+		downtime, err := deploy.client.CreateDowntime(&datadog.Downtime{
+			Scope:       d.datadogTags(config.Mute.Scope, environment, instance),
+			MonitorTags: d.datadogTags(config.Mute.MonitorTags, environment, instance),
+			End:         d.stim.Clock().Now().Add(duration).Unix(),
+			Message:     fmt.Sprintf("Muted by stim while deploying %s to %s/%s", d.deploymentName(environment), environment.Name, instance.Name),
+		})
+		if err != nil {
+			d.log.Warn("Unable to mute the Datadog monitors: {}", err)
+			return
+		}
+		d.log.Debug("Muted Datadog monitors {} (downtime {})", config.Mute.MonitorTags, downtime.ID)
+		deploy.downtimeID = downtime.ID
+	}
+}
+
+// endDatadog unmutes the monitors of an instance deploy and posts its success
+// or failure event.  Errors are logged rather than failing the deploy.
+func (d *Deploy) endDatadog(environment *Environment, instance *Instance, deployErr error) {
+
+	deploy, ok := d.datadogDeploys[environment.Name+"/"+instance.Name]
+	if !ok {
+		return
+	}
+
+	if deploy.downtimeID != 0 {
+		err := deploy.client.CancelDowntime(deploy.downtimeID)
+		if err != nil {
+			d.log.Warn("Unable to cancel Datadog downtime {}, the monitors stay muted until it ends: {}", deploy.downtimeID, err)
+		}
+	}
+
+	if deployErr != nil {
+		d.postDatadogEvent(deploy, environment, instance, datadog.AlertError, "failed", deployErr)
+	} else {
+		d.postDatadogEvent(deploy, environment, instance, datadog.AlertSuccess, "succeeded", nil)
+	}
+}
+
+// postDatadogEvent posts a deploy event.  The events of an instance deploy
+// are grouped by their aggregation key.
+func (d *Deploy) postDatadogEvent(deploy *datadogDeploy, environment *Environment, instance *Instance, alertType string, status string, deployErr error) {
+
+	user, err := d.stim.User()
+	if err != nil {
+		user = "unknown"
+	}
+
+	name := d.deploymentName(environment)
+	text := fmt.Sprintf("Deploy of %s to %s/%s by %s %s", name, environment.Name, instance.Name, user, status)
+	if deployErr != nil {
+		text += ": " + deployErr.Error()
+	}
+
+	_, err = deploy.client.PostEvent(&datadog.Event{
+		Title:          fmt.Sprintf("Deploy of %s to %s/%s %s", name, environment.Name, instance.Name, status),
+		Text:           text,
+		Tags:           d.datadogTags(environment.Datadog.Tags, environment, instance),
+		AlertType:      alertType,
+		AggregationKey: fmt.Sprintf("stim-%s-%s-%s", name, environment.Name, instance.Name),
+		SourceTypeName: "stim",
+	})
+	if err != nil {
+		d.log.Warn("Unable to post the Datadog event: {}", err)
+	}
+}
+
+// datadogTags replaces the placeholders in the tags
+func (d *Deploy) datadogTags(tags []string, environment *Environment, instance *Instance) []string {
+	replaced := make([]string, len(tags))
+	for i, tag := range tags {
+		replaced[i] = releaseTag(tag, environment, instance, d.stim.Clock().Now())
+	}
+	return replaced
+}
+
+// datadogVaultPath returns the Vault path of the Datadog keys of a deploy,
+// the `datadog.vault-path` stim config option if not set
+func (d *Deploy) datadogVaultPath(config *DatadogDeploy) string {
+	if config.SecretPath != "" {
+		return config.SecretPath
+	}
+	return d.stim.ConfigGetString("datadog.vault-path")
+}
+
+// validateDatadogDeploy validates a 'datadog' section
+func validateDatadogDeploy(config *DatadogDeploy) error {
+	if config == nil || config.Mute == nil {
+		return nil
+	}
+
+	if len(config.Mute.MonitorTags) == 0 {
+		return errors.New("`monitorTags` must be set in the `datadog.mute` config")
+	}
+	if config.Mute.Duration != "" {
+		duration, err := time.ParseDuration(config.Mute.Duration)
+		if err != nil || duration <= 0 {
+			return fmt.Errorf("Invalid `datadog.mute.duration` '%s'", config.Mute.Duration)
+		}
+	}
+
+	return nil
+}
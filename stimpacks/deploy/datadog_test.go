@@ -0,0 +1,57 @@
+package deploy
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/PremiereGlobal/stim/pkg/datadog"
+	"github.com/PremiereGlobal/stim/stim"
+	"gotest.tools/assert"
+)
+
+func TestValidateDatadogDeploy(t *testing.T) {
+	assert.NilError(t, validateDatadogDeploy(nil))
+	assert.NilError(t, validateDatadogDeploy(&DatadogDeploy{Tags: []string{"env:{ENVIRONMENT}"}}))
+	assert.NilError(t, validateDatadogDeploy(&DatadogDeploy{Mute: &DatadogMute{MonitorTags: []string{"service:web"}, Duration: "1h"}}))
+
+	assert.ErrorContains(t, validateDatadogDeploy(&DatadogDeploy{Mute: &DatadogMute{}}), "`monitorTags` must be set")
+	assert.ErrorContains(t, validateDatadogDeploy(&DatadogDeploy{Mute: &DatadogMute{MonitorTags: []string{"service:web"}, Duration: "soon"}}), "Invalid `datadog.mute.duration` 'soon'")
+}
+
+func TestEndDatadog(t *testing.T) {
+	var requests []string
+	var events []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if r.URL.Path == "/api/v1/events" {
+			event := map[string]interface{}{}
+			assert.NilError(t, json.NewDecoder(r.Body).Decode(&event))
+			events = append(events, event)
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	s := stim.New()
+	d := &Deploy{stim: s, log: s.GetLogger()}
+	environment := &Environment{Name: "prod", Notifications: &Notifications{Name: "web"}, Datadog: &DatadogDeploy{Tags: []string{"env:{ENVIRONMENT}", "region:{INSTANCE}"}}}
+	instance := &Instance{Name: "us-west-2", Spec: &Spec{}}
+
+	// Nothing is sent without a start
+	d.endDatadog(environment, instance, nil)
+	assert.Equal(t, len(requests), 0)
+
+	d.datadogDeploys = map[string]*datadogDeploy{
+		"prod/us-west-2": {client: datadog.New(&datadog.Config{APIKey: "api", AppKey: "app", APIURL: server.URL}), downtimeID: 7},
+	}
+	d.endDatadog(environment, instance, errors.New("timed out"))
+
+	assert.DeepEqual(t, requests, []string{"DELETE /api/v1/downtime/7", "POST /api/v1/events"})
+	assert.Equal(t, events[0]["title"], "Deploy of web to prod/us-west-2 failed")
+	assert.Equal(t, events[0]["alert_type"], "error")
+	assert.Equal(t, events[0]["aggregation_key"], "stim-web-prod-us-west-2")
+	assert.DeepEqual(t, events[0]["tags"], []interface{}{"env:prod", "region:us-west-2"})
+}
@@ -3,10 +3,12 @@ package deploy
 import (
 	"errors"
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/PremiereGlobal/stim/pkg/docker"
+	"github.com/PremiereGlobal/stim/pkg/github"
+	"github.com/PremiereGlobal/stim/pkg/i18n"
+	"github.com/PremiereGlobal/stim/pkg/notify"
 	log "github.com/PremiereGlobal/stim/pkg/stimlog"
 	"github.com/PremiereGlobal/stim/stim"
 )
@@ -17,17 +19,49 @@ const (
 )
 
 const (
-	DEPLOY_METHOD_UNKNOWN int = 0
-	DEPLOY_METHOD_DOCKER  int = 1
-	DEPLOY_METHOD_SHELL   int = 2
+	DEPLOY_METHOD_UNKNOWN    int = 0
+	DEPLOY_METHOD_DOCKER     int = 1
+	DEPLOY_METHOD_SHELL      int = 2
+	DEPLOY_METHOD_PODMAN     int = 3
+	DEPLOY_METHOD_CONTAINERD int = 4
+	DEPLOY_METHOD_KUBERNETES int = 5
 )
 
 // Deploy is the primary type for the stim deploy subcommand
 type Deploy struct {
-	name   string
-	stim   *stim.Stim
-	config Config
-	log    log.StimLogger
+	name      string
+	stim      *stim.Stim
+	config    Config
+	log       log.StimLogger
+	notifiers map[string]*notify.Router
+
+	// githubDeployments are the GitHub Deployments of the instances being
+	// deployed, by `<environment>/<instance>`
+	githubDeployments map[string]*githubDeployment
+
+	// datadogDeploys are the Datadog clients and downtimes of the instances
+	// being deployed, by `<environment>/<instance>`
+	datadogDeploys map[string]*datadogDeploy
+
+	// resolvedTags are the tags that tag expressions resolved to, by
+	// `<repo>:<expression>`
+	resolvedTags map[string]string
+
+	// resolvedEnvVars are the env vars with a `valueFrom` that have been
+	// resolved.  Env vars are shared by the instances they're merged into, so
+	// they're only resolved once.
+	resolvedEnvVars map[*EnvironmentVar]bool
+
+	// workspace is the workspace of the services and service is the one
+	// being deployed, nil if the deploy config isn't selected by service
+	workspace *Workspace
+	service   *Service
+
+	// pkg is the package being deployed by `stim deploy run-package`
+	pkg *deployPackage
+
+	// promotion is the version being promoted by `stim deploy promote`
+	promotion *promotion
 }
 
 // New creates a new 'Deploy' object
@@ -42,12 +76,92 @@ func (d *Deploy) Name() string {
 }
 
 // Run is the main entrypoint to the "deploy" command
-func (d *Deploy) Run() {
+func (d *Deploy) Run() error {
 
 	d.log = d.stim.GetLogger()
 
+	if d.stim.ConfigGetBool("deploy.watch") {
+		return d.watch()
+	}
+
+	// Record everything the deploy touches if a bill of materials is wanted.
+	// It is written even if the deploy fails.
+	bomPath := d.stim.ConfigGetString("deploy.bom")
+	if bomPath == "" {
+		return d.runServices()
+	}
+	user, _ := d.stim.User()
+	d.stim.BOM().Start("deploy", user, d.stim.Clock().Now())
+	err := d.runServices()
+	bomErr := d.stim.BOM().Finish(d.stim.Clock().Now(), err).WriteFile(bomPath)
+	if bomErr != nil {
+		d.log.Warn("Unable to write the bill of materials to {}: {}", bomPath, bomErr)
+	} else {
+		d.log.Info("Wrote the bill of materials to {}", bomPath)
+	}
+	return err
+}
+
+// runServices deploys each selected service of the workspace one after
+// another, or the deploy config if it isn't selected by service
+func (d *Deploy) runServices() error {
+
+	// Nothing is left over from an earlier run (ex. of a client)
+	d.workspace, d.service = nil, nil
+	services, err := d.selectServices()
+	if err != nil {
+		return err
+	}
+	if services == nil {
+		return d.run()
+	}
+	if len(services) == 0 {
+		d.log.Info(d.stim.Message(i18n.MessageNoService))
+		return nil
+	}
+
+	for _, service := range services {
+		d.log.Info("Deploying service: {}", service.Name)
+		d.stim.BOM().SetLabel("service", service.Name)
+		d.service = service
+
+		// Notifiers are created from the config of the service
+		d.notifiers = nil
+		err := d.run()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// run selects the environment and instance(s) and deploys them
+func (d *Deploy) run() error {
+
 	// Read in the config file and set up defaults
-	d.parseConfig()
+	err := d.parseConfig()
+	if err != nil {
+		return err
+	}
+	if d.promotion != nil {
+		err = d.loadPromotion()
+		if err != nil {
+			return err
+		}
+	}
+	vault, err := d.stim.NewVault()
+	if err != nil {
+		return err
+	}
+	err = addStimEnvs(&d.config, vault, d.stim.KubeConfigSecretPath)
+	if err != nil {
+		return err
+	}
+
+	// Keep the Vault token alive for the duration of the deploy(s)
+	vault.StartTokenRenewer()
+	defer vault.StopTokenRenewer()
 
 	// Determine the selected environment (via cli param) or prompt the user
 	selectedEnvironmentName := ""
@@ -56,20 +170,27 @@ func (d *Deploy) Run() {
 		if _, ok := d.config.environmentMap[environmentArg]; ok {
 			selectedEnvironmentName = environmentArg
 		} else {
-			d.log.Fatal("Provided environment value '{}' is not in config file", environmentArg)
+			return stim.ConfigError(errors.New(d.stim.Message(i18n.MessageUnknownEnvironment, environmentArg)))
+		}
+	} else if d.stim.ConfigGetBool("deploy.tui") {
+		selectedEnvironmentName, _ = d.stim.PromptListDetails(d.stim.Message(i18n.MessageWhichEnvironment), d.environmentItems(d.lastDeploys()), "")
+		if selectedEnvironmentName == "" {
+			d.log.Info(d.stim.Message(i18n.MessageNoEnvironment))
+			return nil
 		}
 	} else {
 		environmentList := make([]string, len(d.config.Environments))
 		for i, e := range d.config.Environments {
 			environmentList[i] = e.Name
 		}
-		selectedEnvironmentName, _ = d.stim.PromptList("Which environment?", environmentList, d.stim.ConfigGetString("deploy.environment"))
+		selectedEnvironmentName, _ = d.stim.PromptListRemembered(d.selectionKey("environment"), d.stim.Message(i18n.MessageWhichEnvironment), environmentList, "")
 		if selectedEnvironmentName == "" {
-			d.log.Info("No environment selected! exiting")
-			os.Exit(0)
+			d.log.Info(d.stim.Message(i18n.MessageNoEnvironment))
+			return nil
 		}
 	}
 	selectedEnvironment := d.config.Environments[d.config.environmentMap[selectedEnvironmentName]]
+	d.stim.BOM().SetLabel("environment", selectedEnvironment.Name)
 
 	// Determine the selected instance (via cli param) or prompt the user
 	instanceList := make([]string, 0)
@@ -81,69 +202,215 @@ func (d *Deploy) Run() {
 	for _, inst := range selectedEnvironment.Instances {
 		instanceList = append(instanceList, inst.Name)
 	}
-	selectedInstanceName, _ := d.stim.PromptList("Which instance?", instanceList, d.stim.ConfigGetString("deploy.instance"))
+	var selectedInstanceName string
+	var selectedInstances []*Instance
+	if d.stim.ConfigGetBool("deploy.tui") {
+		selectedInstanceName, _ = d.stim.PromptListDetails(d.stim.Message(i18n.MessageWhichInstance), d.instanceItems(selectedEnvironment, d.lastDeploys()), d.stim.ConfigGetString("deploy.instance"))
+	} else if d.stim.ConfigGetBool("deploy.multi-select") && d.stim.ConfigGetString("deploy.instance") == "" {
+		selectedInstances, selectedInstanceName = d.promptInstances(selectedEnvironment)
+	} else {
+		selectedInstanceName, _ = d.stim.PromptListRemembered(d.selectionKey("instance/"+selectedEnvironment.Name), d.stim.Message(i18n.MessageWhichInstance), instanceList, d.stim.ConfigGetString("deploy.instance"))
+	}
 	if selectedInstanceName == "" {
-		d.log.Info("No instance selected! exiting")
-		os.Exit(0)
+		d.log.Info(d.stim.Message(i18n.MessageNoInstance))
+		return nil
 	}
 	if strings.ToLower(selectedInstanceName) == strings.ToLower(allOptionPrompt) || strings.ToLower(selectedInstanceName) == strings.ToLower(allOptionCli) {
 		selectedInstanceName = allOptionCli
-	} else if _, ok := selectedEnvironment.instanceMap[selectedInstanceName]; !ok {
-		d.log.Fatal("Provided instance value '{}' is not in config file under environment '{}'", selectedInstanceName, selectedEnvironmentName)
+	} else if _, ok := selectedEnvironment.instanceMap[selectedInstanceName]; !ok && selectedInstances == nil {
+		return stim.ConfigError(errors.New(d.stim.Message(i18n.MessageUnknownInstance, selectedInstanceName, selectedEnvironmentName)))
+	}
+	d.stim.BOM().SetLabel("instance", selectedInstanceName)
+
+	// Check the secrets of every selected instance before deploying any of them
+	if selectedInstanceName == allOptionCli {
+		selectedInstances = selectedEnvironment.Instances
+	} else if selectedInstances == nil {
+		selectedInstances = []*Instance{selectedEnvironment.Instances[selectedEnvironment.instanceMap[selectedInstanceName]]}
+	}
+	err = d.checkSecrets(selectedInstances)
+	if err != nil {
+		return err
+	}
+	err = d.resolveImages(selectedInstances)
+	if err != nil {
+		return err
+	}
+	err = d.checkImage()
+	if err != nil {
+		return err
+	}
+	err = d.checkSpecPolicies(selectedEnvironment, selectedInstances)
+	if err != nil {
+		return err
 	}
 
 	// Run the deployment(s)
 	if selectedInstanceName == allOptionCli {
 		d.log.Info("Deploying to all clusters in environment: {}", selectedEnvironment.Name)
-		//Check if confirmation prompt is required
-		if selectedEnvironment.Spec.AddConfirmationPrompt {
-			//Do AddConfirmationPrompt, only if the instance is not passed on the cli
-			proceed, _ := d.stim.PromptBool("Proceed?", d.stim.ConfigGetString("deploy.instance") != "", false)
-			if !proceed {
-				os.Exit(1)
-			}
-		}
-		for _, inst := range selectedEnvironment.Instances {
-			if inst.Spec.AddConfirmationPrompt {
-				//Do AddConfirmationPrompt, only if the instance is not passed on the cli
-				proceed, _ := d.stim.PromptBool("Proceed?", d.stim.ConfigGetString("deploy.instance") != "", false)
-				if !proceed {
-					os.Exit(1)
-				}
-			}
-			d.Deploy(selectedEnvironment, inst)
+		err := d.checkPolicy(selectedEnvironment, allOptionCli, false)
+		if err != nil {
+			return err
+		}
+		return d.deployAll(selectedEnvironment)
+	} else if len(selectedInstances) > 1 {
+		d.log.Info("Deploying to instances {} in environment: {}", selectedInstanceName, selectedEnvironment.Name)
+		err := d.checkPolicy(selectedEnvironment, selectedInstanceName, false)
+		if err != nil {
+			return err
 		}
+		return d.deploySelected(selectedEnvironment, selectedInstances)
 	} else {
 		inst := selectedEnvironment.Instances[selectedEnvironment.instanceMap[selectedInstanceName]]
-		if selectedEnvironment.Spec.AddConfirmationPrompt || inst.Spec.AddConfirmationPrompt {
-			proceed, _ := d.stim.PromptBool("Proceed?", d.stim.ConfigGetString("deploy.instance") != "", false)
-			if !proceed {
-				os.Exit(1)
-			}
+		err := d.checkPolicy(selectedEnvironment, inst.Name, inst.Spec.AddConfirmationPrompt)
+		if err != nil {
+			return err
 		}
-		d.Deploy(selectedEnvironment, inst)
+		return d.Deploy(selectedEnvironment, inst)
 	}
-
 }
 
 // Deploy runs the deployment in the way that the user wants
-func (d *Deploy) Deploy(environment *Environment, instance *Instance) {
+func (d *Deploy) Deploy(environment *Environment, instance *Instance) error {
+
+	// No instance is started once the deploy is cancelled
+	err := d.stim.Context().Err()
+	if err != nil {
+		return err
+	}
 
 	d.log.Info("Deploying to '{}' environment in instance: {}", environment.Name, instance.Name)
 
-	deployMethod, err := d.DetermineDeployMethod()
+	endSpan := d.stim.StartSpan(fmt.Sprintf("deploy %s/%s", environment.Name, instance.Name), map[string]string{
+		"deploy.environment": environment.Name,
+		"deploy.instance":    instance.Name,
+		"deploy.cluster":     instance.Spec.Kubernetes.Cluster,
+	})
+	err = d.deployInstance(environment, instance)
+	endSpan(err)
+	return err
+}
+
+// deployInstance deploys the instance and sends its notifications and hooks
+func (d *Deploy) deployInstance(environment *Environment, instance *Instance) error {
+
+	start := d.stim.Clock().Now()
+
+	// Manifests are applied by stim itself so no deploy method is needed
+	deployMethod := DEPLOY_METHOD_UNKNOWN
+	var err error
+	if d.config.Deployment.Type != deployTypeManifests {
+		deployMethod, err = d.DetermineDeployMethod()
+		if err != nil {
+			return err
+		}
+	}
+
+	err = d.resolveEnvSources(instance)
+	if err != nil {
+		return stim.ConfigError(err)
+	}
+
+	d.addNamespace(instance)
+
+	d.notify(environment, instance, notifyStart, nil)
+	d.startGithubDeployment(environment, instance)
+	d.startDatadog(environment, instance)
+
+	err = d.runHooks(environment, instance, notifyStart, nil)
+	if err == nil {
+		err = d.runDeploy(deployMethod, environment, instance)
+	}
+	if err != nil {
+		d.notify(environment, instance, notifyFailure, err)
+		d.setGithubDeploymentStatus(environment, instance, github.StateFailure, err)
+		d.endDatadog(environment, instance, err)
+		d.recordHistory(environment, instance, deployMethod, start, err)
+		hookErr := d.runHooks(environment, instance, notifyFailure, err)
+		if hookErr != nil {
+			d.log.Warn(hookErr)
+		}
+		return stim.DeployError(err)
+	}
+
+	d.notify(environment, instance, notifySuccess, nil)
+	d.setGithubDeploymentStatus(environment, instance, github.StateSuccess, nil)
+	d.endDatadog(environment, instance, nil)
+
+	err = d.runHooks(environment, instance, notifySuccess, nil)
+	if err != nil {
+		d.log.Warn(err)
+	}
+
+	// Recorded after the release so that the release tag is in the record
+	d.release(environment, instance)
+	d.recordHistory(environment, instance, deployMethod, start, nil)
+
+	return nil
+}
+
+// runDeploy reads the AWS secrets, runs the deployment, waits for the health
+// checks and publishes the ConfigMap (if configured)
+func (d *Deploy) runDeploy(deployMethod int, environment *Environment, instance *Instance) error {
+
+	err := d.addAwsSecrets(instance)
+	if err != nil {
+		return fmt.Errorf("Error reading AWS secrets: %v", err)
+	}
+
+	stopTimer := d.stim.Time(stim.PhaseTemplates)
+	cleanupTemplates, err := d.renderTemplates(environment, instance)
+	stopTimer()
+	if err != nil {
+		return err
+	}
+	defer cleanupTemplates()
+
+	if d.config.Deployment.Type == deployTypeManifests {
+		err = d.applyManifests(environment, instance)
+		if err != nil {
+			return err
+		}
+	} else {
+		err = d.runDeployCommand(deployMethod, environment, instance)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = d.runHealthChecks(instance)
 	if err != nil {
-		d.log.Fatal(err)
+		return err
 	}
 
-	if deployMethod == DEPLOY_METHOD_DOCKER {
-		d.startDeployContainer(instance)
+	if instance.Spec.ConfigMap != nil {
+		err := d.publishConfigMap(environment, instance)
+		if err != nil {
+			return fmt.Errorf("Error publishing deploy ConfigMap: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// runDeployCommand runs the deploy script or Helm command in the deploy
+// container or the shell
+func (d *Deploy) runDeployCommand(deployMethod int, environment *Environment, instance *Instance) error {
+
+	command, err := d.deployCommand(environment, instance)
+	if err != nil {
+		return err
+	}
+
+	if isContainerMethod(deployMethod) {
+		err = d.startDeployContainer(deployMethod, instance, command)
 	} else if deployMethod == DEPLOY_METHOD_SHELL {
-		d.startDeployShell(instance)
+		err = d.startDeployShell(instance, command)
 	} else {
-		d.log.Fatal("Could not determine deployment method")
+		err = errors.New("Could not determine deployment method")
 	}
 
+	return err
 }
 
 // DetermineDeployMethod figures out the deploy method based on user input
@@ -182,6 +449,19 @@ func (d *Deploy) DetermineDeployMethod() (int, error) {
 		return DEPLOY_METHOD_SHELL, nil
 	}
 
+	// The other container engines are only used when selected
+	if method, ok := containerMethods[deployMethod]; ok && method != DEPLOY_METHOD_DOCKER {
+		engine, err := d.containerEngine(method, nil)
+		if err != nil {
+			return DEPLOY_METHOD_UNKNOWN, err
+		}
+		if err := engine.Available(d.stim.Context()); err != nil {
+			return DEPLOY_METHOD_UNKNOWN, fmt.Errorf("Cannot deploy with %s as it is not available: %v", engine.Name(), err)
+		}
+		d.log.Debug("Using {} to deploy (specified by user)", engine.Name())
+		return method, nil
+	}
+
 	// Below we're detecting some specific error cases to give more info to the user
 
 	if deployMethod == "docker" && isInDocker {
@@ -191,5 +471,5 @@ func (d *Deploy) DetermineDeployMethod() (int, error) {
 		return DEPLOY_METHOD_UNKNOWN, errors.New("Cannot deploy with Docker as it is not available")
 	}
 
-	return DEPLOY_METHOD_UNKNOWN, errors.New(fmt.Sprintf("Invalid deployment method '%s' provided.  Must be one of ['auto','docker','shell']", deployMethod))
+	return DEPLOY_METHOD_UNKNOWN, errors.New(fmt.Sprintf("Invalid deployment method '%s' provided.  Must be one of ['auto','docker','podman','containerd','kubernetes','shell']", deployMethod))
 }
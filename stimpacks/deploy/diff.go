@@ -0,0 +1,241 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/PremiereGlobal/stim/pkg/diff"
+	"github.com/PremiereGlobal/stim/pkg/kubernetes"
+	"github.com/PremiereGlobal/stim/stim"
+	"sigs.k8s.io/yaml"
+)
+
+// maskedValue replaces the values of Secret data in diffs
+const maskedValue = "***"
+
+// Diff prints the changes a deploy of the selected instance(s) would make to
+// the objects live in the cluster.  The manifests (or the `helm template`
+// output of the chart) are applied with a server-side dry run and diffed
+// against the live objects.  Nothing is changed in the cluster.
+func (d *Deploy) Diff() error {
+
+	d.log = d.stim.GetLogger()
+
+	err := d.parseConfig()
+	if err != nil {
+		return err
+	}
+
+	deploymentType := d.config.Deployment.Type
+	if deploymentType != deployTypeManifests && deploymentType != deployTypeHelm {
+		return stim.UsageError(fmt.Errorf("Diff is not supported for the `%s` deployment type, only for `%s` and `%s`", deploymentType, deployTypeManifests, deployTypeHelm))
+	}
+
+	vault, err := d.stim.NewVault()
+	if err != nil {
+		return err
+	}
+	err = addStimEnvs(&d.config, vault, d.stim.KubeConfigSecretPath)
+	if err != nil {
+		return err
+	}
+
+	environment, instances, err := d.selectInstances()
+	if err != nil || environment == nil {
+		return err
+	}
+
+	for i, instance := range instances {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("Environment: %s  Instance: %s\n\n", environment.Name, instance.Name)
+
+		changed, err := d.diffInstance(environment, instance)
+		if err != nil {
+			return err
+		}
+
+		if changed == 0 {
+			fmt.Println("No changes")
+		} else {
+			fmt.Printf("%d object(s) would change\n", changed)
+		}
+	}
+
+	return nil
+}
+
+// diffInstance prints the diff of each object of an instance that would
+// change and returns the number of changed objects
+func (d *Deploy) diffInstance(environment *Environment, instance *Instance) (int, error) {
+
+	d.addNamespace(instance)
+
+	err := d.addAwsSecrets(instance)
+	if err != nil {
+		return 0, fmt.Errorf("Error reading AWS secrets: %v", err)
+	}
+
+	err = d.resolveEnvSources(instance)
+	if err != nil {
+		return 0, stim.ConfigError(err)
+	}
+
+	cleanupTemplates, err := d.renderTemplates(environment, instance)
+	if err != nil {
+		return 0, err
+	}
+	defer cleanupTemplates()
+
+	objects, err := d.instanceObjects(environment, instance)
+	if err != nil {
+		return 0, err
+	}
+
+	kube, cleanup, err := d.instanceKubernetes(instance)
+	if err != nil {
+		return 0, err
+	}
+	defer cleanup()
+
+	namespace := instanceNamespace(instance)
+	changed := 0
+	var refs []kubernetes.ObjectRef
+	for _, object := range objects {
+		ref, live, merged, err := kube.DiffObject(object, namespace, manifestsFieldManager)
+		if err != nil {
+			return 0, err
+		}
+		refs = append(refs, ref)
+
+		text, err := objectDiff(ref, live, merged)
+		if err != nil {
+			return 0, err
+		}
+		if text != "" {
+			fmt.Print(text)
+			changed++
+		}
+	}
+
+	manifests := instance.Spec.Manifests
+	if d.config.Deployment.Type == deployTypeManifests && manifests.Prune {
+		previous, err := inventoryObjects(kube, namespace, manifestsInventory(environment, instance))
+		if err != nil {
+			return 0, err
+		}
+		for _, ref := range pruneObjects(previous, refs) {
+			fmt.Printf("--- live/%s\n+++ (pruned)\n", ref)
+			changed++
+		}
+	}
+
+	return changed, nil
+}
+
+// instanceObjects returns the objects a deploy of the instance would apply:
+// the manifests, or the output of `helm template` run in the deploy shell
+// environment
+func (d *Deploy) instanceObjects(environment *Environment, instance *Instance) ([]map[string]interface{}, error) {
+
+	if d.config.Deployment.Type == deployTypeManifests {
+		manifests := instance.Spec.Manifests
+		return readManifests(filepath.Join(d.config.Deployment.fullDirectoryPath, manifests.Path), manifests.Kustomize)
+	}
+
+	namespace, valuesFiles, err := d.renderHelmValues(environment, instance)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := d.instanceEnv(instance)
+	if err != nil {
+		return nil, err
+	}
+	defer e.Close()
+
+	command := helmTemplateCommand(instance.Spec.Helm, namespace, valuesFiles)
+	d.log.Debug("Running {}", command)
+	out, err := e.Run(command)
+	if err != nil {
+		return nil, fmt.Errorf("Error rendering the Helm chart: %v", err)
+	}
+
+	return parseManifests(out)
+}
+
+// objectDiff returns the unified diff of the YAML of a live object and the
+// object after the apply, or an empty string if the apply changes nothing.
+// The values of Secret data and stringData are masked.
+func objectDiff(ref kubernetes.ObjectRef, live map[string]interface{}, merged map[string]interface{}) (string, error) {
+
+	if merged == nil {
+		return "", errors.New("No object to compare with the live object")
+	}
+
+	live = kubernetes.ObjectForDiff(live)
+	merged = kubernetes.ObjectForDiff(merged)
+	if ref.Kind == "Secret" {
+		maskSecretData(live, merged)
+	}
+
+	from := ""
+	if live != nil {
+		b, err := yaml.Marshal(live)
+		if err != nil {
+			return "", err
+		}
+		from = string(b)
+	}
+	to, err := yaml.Marshal(merged)
+	if err != nil {
+		return "", err
+	}
+
+	return diff.Unified("live/"+ref.String(), "merged/"+ref.String(), from, string(to)), nil
+}
+
+// secretDataFields are the fields of a Secret with secret values
+var secretDataFields = []string{"data", "stringData"}
+
+// maskSecretData replaces the values of the data and stringData of a live
+// and merged Secret so that the diff only shows which keys changed
+func maskSecretData(live map[string]interface{}, merged map[string]interface{}) {
+	for _, field := range secretDataFields {
+		maskSecretField(field, live, merged)
+	}
+}
+
+// maskSecretField replaces the values of a field of a live and merged Secret
+func maskSecretField(field string, live map[string]interface{}, merged map[string]interface{}) {
+
+	var liveData map[string]interface{}
+	if live != nil {
+		liveData, _ = live[field].(map[string]interface{})
+	}
+	mergedData, _ := merged[field].(map[string]interface{})
+
+	maskedLive := make(map[string]interface{}, len(liveData))
+	maskedMerged := make(map[string]interface{}, len(mergedData))
+	for key, value := range liveData {
+		maskedLive[key] = maskedValue
+		if mergedValue, ok := mergedData[key]; ok && mergedValue != value {
+			maskedLive[key] = maskedValue + " (before)"
+		}
+	}
+	for key, value := range mergedData {
+		maskedMerged[key] = maskedValue
+		if liveValue, ok := liveData[key]; ok && liveValue != value {
+			maskedMerged[key] = maskedValue + " (after)"
+		}
+	}
+
+	if liveData != nil {
+		live[field] = maskedLive
+	}
+	if mergedData != nil {
+		merged[field] = maskedMerged
+	}
+}
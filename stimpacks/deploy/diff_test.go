@@ -0,0 +1,82 @@
+package deploy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PremiereGlobal/stim/pkg/kubernetes"
+	"gotest.tools/assert"
+)
+
+func TestObjectDiff(t *testing.T) {
+	ref := kubernetes.ObjectRef{APIVersion: "v1", Kind: "ConfigMap", Namespace: "apps", Name: "settings"}
+	live := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": "settings", "namespace": "apps", "resourceVersion": "41"},
+		"data":       map[string]interface{}{"mode": "blue"},
+	}
+	merged := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": "settings", "namespace": "apps", "resourceVersion": "42"},
+		"data":       map[string]interface{}{"mode": "green"},
+	}
+
+	text, err := objectDiff(ref, live, merged)
+	assert.NilError(t, err)
+	assert.Equal(t, text, `--- live/ConfigMap/apps/settings
++++ merged/ConfigMap/apps/settings
+@@ -1,6 +1,6 @@
+ apiVersion: v1
+ data:
+-  mode: blue
++  mode: green
+ kind: ConfigMap
+ metadata:
+   name: settings
+`)
+
+	// Only the resource version changes
+	merged["data"] = map[string]interface{}{"mode": "blue"}
+	text, err = objectDiff(ref, live, merged)
+	assert.NilError(t, err)
+	assert.Equal(t, text, "")
+
+	// New objects are diffed against nothing
+	text, err = objectDiff(ref, nil, merged)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(text, "@@ -0,0 +1,"))
+}
+
+func TestObjectDiffMasksSecrets(t *testing.T) {
+	ref := kubernetes.ObjectRef{APIVersion: "v1", Kind: "Secret", Namespace: "apps", Name: "db"}
+	live := map[string]interface{}{
+		"kind":     "Secret",
+		"metadata": map[string]interface{}{"name": "db"},
+		"data":     map[string]interface{}{"password": "b2xk", "user": "YXBw"},
+	}
+	merged := map[string]interface{}{
+		"kind":     "Secret",
+		"metadata": map[string]interface{}{"name": "db"},
+		"data":     map[string]interface{}{"password": "bmV3", "user": "YXBw", "host": "ZGI="},
+	}
+
+	text, err := objectDiff(ref, live, merged)
+	assert.NilError(t, err)
+	assert.Assert(t, !strings.Contains(text, "b2xk") && !strings.Contains(text, "bmV3") && !strings.Contains(text, "ZGI="), text)
+	assert.Assert(t, strings.Contains(text, "-  password: '*** (before)'"), text)
+	assert.Assert(t, strings.Contains(text, "+  password: '*** (after)'"), text)
+	assert.Assert(t, strings.Contains(text, "+  host: '***'"), text)
+	assert.Assert(t, strings.Contains(text, "   user: '***'"), text)
+
+	// The objects of the cluster are not changed
+	assert.Equal(t, live["data"].(map[string]interface{})["password"], "b2xk")
+
+	// stringData of new Secrets is masked too
+	merged["stringData"] = map[string]interface{}{"token": "plain-text"}
+	text, err = objectDiff(ref, nil, merged)
+	assert.NilError(t, err)
+	assert.Assert(t, !strings.Contains(text, "plain-text"), text)
+	assert.Assert(t, strings.Contains(text, "+  token: '***'"), text)
+}
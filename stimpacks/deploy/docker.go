@@ -3,34 +3,49 @@ package deploy
 import (
 	"bufio"
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/PremiereGlobal/stim/pkg/bom"
 	"github.com/PremiereGlobal/stim/pkg/docker"
 	"github.com/PremiereGlobal/stim/pkg/downloader"
+	log "github.com/PremiereGlobal/stim/pkg/stimlog"
+	"github.com/PremiereGlobal/stim/stim"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/mount"
 )
 
-func (d *Deploy) startDeployContainer(instance *Instance) {
+// startDeployContainer starts an instance deployment in the deploy container
+// with the container engine of the deploy method
+func (d *Deploy) startDeployContainer(deployMethod int, instance *Instance, command string) error {
 
-	dockerClient, err := docker.NewClient()
+	engine, err := d.containerEngine(deployMethod, instance)
 	if err != nil {
-		d.log.Fatal("Error creating docker client. {}", err)
+		return err
 	}
 
-	ctx := context.Background()
-
 	// Pull the deploy image
 	image := fmt.Sprintf("%s:%s", d.config.Deployment.Container.Repo, d.config.Deployment.Container.Tag)
-	reader, err := dockerClient.ImagePull(ctx, image, types.ImagePullOptions{})
+	err = d.ecrLogin(engine, image)
+	if err != nil {
+		return err
+	}
+	stopTimer := d.stim.Time(stim.PhaseImagePull)
+	details, err := engine.Pull(d.stim.Context(), image)
+	stopTimer()
 	if err != nil {
-		d.log.Fatal("Failed to pull deploy image. {}", err)
+		return fmt.Errorf("Failed to pull deploy image. %v", err)
 	}
 
-	scanner := bufio.NewScanner(reader)
-	for scanner.Scan() {
-		d.log.Debug(scanner.Text())
+	// Record the digest of the pulled image in the bill of materials
+	if d.stim.BOM().IsStarted() {
+		d.stim.BOM().Add(bom.KindImage, image, details)
 	}
 
 	var envs []string
@@ -43,9 +58,16 @@ func (d *Deploy) startDeployContainer(instance *Instance) {
 		envs = append(envs, fmt.Sprintf("%s=%s", e.Name, e.Value))
 	}
 
-	if _, ok := instance.Spec.Tools["helm"]; ok {
+	if helm, ok := instance.Spec.Tools["helm"]; ok {
 		if deprecatedHelmVersionSet == "" {
-			envs = append(envs, fmt.Sprintf("HELM_VERSION=%s", downloader.GetBaseVersion(instance.Spec.Tools["helm"].Version)))
+			version := helm.Version
+			if version == "" {
+				version, err = d.detectHelmVersion(instance)
+				if err != nil {
+					return err
+				}
+			}
+			envs = append(envs, fmt.Sprintf("HELM_VERSION=%s", downloader.GetBaseVersion(version)))
 		} else {
 			d.log.Warn("Both `spec.tools.helm` and the deprecated HELM_VERSION environment variable are set.  HELM_VERSION of '{}' is taking precedence", deprecatedHelmVersionSet)
 		}
@@ -56,82 +78,215 @@ func (d *Deploy) startDeployContainer(instance *Instance) {
 		// envs = append(envs, "HELM_MATCH_SERVER=false")
 	}
 
-	// Since we're using Docker, we need to mount the Linux binaries
-	hostCacheDir := d.stim.ConfigGetCacheDir("bin/linux")
-	cacheDir := "/bin-cache"
-	workDir := "/scripts"
+	// The deploy container has the Linux binaries of the tool cache
 	pathDir := "/stim/path"
 
-	// Create the container spec
-	cmd := []string{"/bin/sh", "-c", fmt.Sprintf("export PATH=%s:${PATH}; ./%s", pathDir, d.config.Deployment.Script)}
+	// The deploy script continues the trace from the container run span
+	defer d.stim.Time(stim.PhaseContainerRun)()
+	envs = append(envs, d.stim.TraceEnv()...)
+
+	// The container is always Linux, so PowerShell scripts need an image
+	// with PowerShell Core
+	cmd := []string{"/bin/sh", "-c", fmt.Sprintf("export PATH=%s:${PATH}; %s", pathDir, command)}
+	if d.config.Deployment.Shell == deployShellPowerShell {
+		cmd = []string{"pwsh", "-NoProfile", "-NonInteractive", "-Command", fmt.Sprintf("$env:PATH = '%s:' + $env:PATH; %s", pathDir, command)}
+	}
+
+	d.log.Info("--- START Stim deploy - {} container logs ---", engine.Name())
+	exitCode, err := engine.Run(d.stim.Context(), &containerSpec{
+		Name:       fmt.Sprintf("stim-deploy-%x", d.stim.Rand().Int63()),
+		Image:      image,
+		Cmd:        cmd,
+		Env:        envs,
+		DeployDir:  d.config.Deployment.fullDirectoryPath,
+		WorkDir:    "/scripts",
+		CacheDir:   d.stim.ConfigGetCacheDir("bin/linux"),
+		CacheMount: "/bin-cache",
+	})
+	d.log.Info("--- END Stim deploy - {} container logs ---", engine.Name())
+	if err != nil {
+		return fmt.Errorf("Deploy container error. %v", err)
+	}
+	if exitCode != 0 {
+		return fmt.Errorf("Deployment to '%s' resulted in non-zero exit code %d. Halting any further deployments...", instance.Name, exitCode)
+	}
+
+	return nil
+}
+
+// detectHelmVersion returns the helm version matching the Tiller in the
+// cluster of the instance, or the `tools.helm.version` config, so that the
+// deploy container doesn't have to detect it
+func (d *Deploy) detectHelmVersion(instance *Instance) (string, error) {
+
+	tmpDir, err := ioutil.TempDir("", "stim-deploy")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	kc, err := d.stim.KubeConfigFromVault(&stim.KubeConfigOptions{
+		Cluster:        instance.Spec.Kubernetes.Cluster,
+		ServiceAccount: instance.Spec.Kubernetes.ServiceAccount,
+		Path:           filepath.Join(tmpDir, "kubeconfig"),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return d.stim.DetectToolVersion("helm", kc)
+}
+
+// dockerEngine runs the deploy container with the Docker API of Docker, or
+// of Podman at its socket
+type dockerEngine struct {
+	name string
+
+	// host is the address of the API, the Docker default if empty
+	host string
+
+	// registryAuth is the encoded login of the registry of the image, set by
+	// Login
+	registryAuth string
+
+	log log.StimLogger
+}
+
+// Name is the name of the engine
+func (e *dockerEngine) Name() string {
+	return e.name
+}
+
+// Available checks that the API is reachable
+func (e *dockerEngine) Available(ctx context.Context) error {
+
+	dockerClient, err := docker.NewClientWithHost(e.host)
+	if err != nil {
+		return err
+	}
+
+	_, err = dockerClient.Ping(ctx)
+	return err
+}
+
+// Login sets the registry login that the image is pulled with.  The Docker
+// API doesn't keep logins, so it's sent with the pull.
+func (e *dockerEngine) Login(host string, username string, password string) error {
+	b, err := json.Marshal(types.AuthConfig{Username: username, Password: password, ServerAddress: host})
+	if err != nil {
+		return err
+	}
+	e.registryAuth = base64.URLEncoding.EncodeToString(b)
+	return nil
+}
+
+// Pull pulls the image and returns its id and digests
+func (e *dockerEngine) Pull(ctx context.Context, image string) (map[string]string, error) {
+
+	dockerClient, err := docker.NewClientWithHost(e.host)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := dockerClient.ImagePull(ctx, image, types.ImagePullOptions{RegistryAuth: e.registryAuth})
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		e.log.Debug(scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	details := map[string]string{}
+	inspect, _, err := dockerClient.ImageInspectWithRaw(ctx, image)
+	if err != nil {
+		e.log.Warn("Unable to get the digest of image {}: {}", image, err)
+	} else {
+		details["id"] = inspect.ID
+		details["digest"] = strings.Join(inspect.RepoDigests, ",")
+	}
+
+	return details, nil
+}
+
+// Run creates the container, streams its logs and waits for it to exit
+func (e *dockerEngine) Run(ctx context.Context, spec *containerSpec) (int, error) {
+
+	dockerClient, err := docker.NewClientWithHost(e.host)
+	if err != nil {
+		return 0, fmt.Errorf("Error creating docker client. %v", err)
+	}
+
 	resp, err := dockerClient.ContainerCreate(ctx, &container.Config{
-		Image:        image,
-		Cmd:          cmd,
+		Image:        spec.Image,
+		Cmd:          spec.Cmd,
 		Tty:          true,
-		Env:          envs,
+		Env:          spec.Env,
 		AttachStdout: true,
 		AttachStderr: true,
-		WorkingDir:   workDir,
+		WorkingDir:   spec.WorkDir,
 	}, &container.HostConfig{
 		AutoRemove: true,
 		Mounts: []mount.Mount{
 			mount.Mount{
 				Type:     mount.TypeBind,
-				Source:   d.config.Deployment.fullDirectoryPath,
-				Target:   workDir,
+				Source:   docker.MountSource(spec.DeployDir),
+				Target:   spec.WorkDir,
 				ReadOnly: false, // This could be set to false when the downloads don't go here
 			},
 			mount.Mount{
 				Type:     mount.TypeBind,
-				Source:   hostCacheDir,
-				Target:   cacheDir,
+				Source:   docker.MountSource(spec.CacheDir),
+				Target:   spec.CacheMount,
 				ReadOnly: false,
 			},
-			// mount.Mount{
-			// 	Type:     mount.TypeBind,
-			// 	Source:   e.GetPath()+"/",
-			// 	Target:   pathDir,
-			// 	ReadOnly: true,
-			// },
 		},
-	}, nil, "")
+	}, nil, spec.Name)
 	if err != nil {
-		d.log.Fatal("Error creating deploy container. {}", err)
+		return 0, fmt.Errorf("Error creating deploy container. %v", err)
 	}
 
+	// Remove the container if the deploy stops before it finishes, ex. when
+	// it's cancelled (the container only removes itself once it has run)
+	finished := false
+	defer func() {
+		if !finished {
+			dockerClient.ContainerRemove(context.Background(), resp.ID, types.ContainerRemoveOptions{Force: true})
+		}
+	}()
+
 	// Start the container
 	if err := dockerClient.ContainerStart(ctx, resp.ID, types.ContainerStartOptions{}); err != nil {
-		d.log.Fatal("Error starting deploy container. {}", err)
+		return 0, fmt.Errorf("Error starting deploy container. %v", err)
 	}
 
 	// Start capturing the logs
 	out, err := dockerClient.ContainerLogs(ctx, resp.ID, types.ContainerLogsOptions{Follow: true, ShowStdout: true, ShowStderr: true})
 	if err != nil {
-		d.log.Fatal("Error getting container logs. {}", err)
+		return 0, fmt.Errorf("Error getting container logs. %v", err)
 	}
 	defer out.Close()
 
-	d.log.Info("--- START Stim deploy - Docker container logs ---")
-	scanner = bufio.NewScanner(out)
+	scanner := bufio.NewScanner(out)
 	for scanner.Scan() {
 		fmt.Println(scanner.Text())
 	}
-	d.log.Info("--- END Stim deploy - Docker container logs ---")
 
 	// Wait for the container to finish
 	statusCh, errCh := dockerClient.ContainerWait(ctx, resp.ID, container.WaitConditionNotRunning)
 	select {
 	case err := <-errCh:
-		if err != nil {
-			d.log.Fatal("Deploy container error. {}", err)
-		}
+		return 0, err
 	case status := <-statusCh:
+		finished = true
 		if status.Error != nil {
-			d.log.Fatal("Deployment resulted in error. {}. Halting any further deployments...", status.Error.Message)
-		}
-		if status.StatusCode != 0 {
-			d.log.Fatal("Deployment to '{}' resulted in non-zero exit code {}. Halting any further deployments...", instance.Name, status.StatusCode)
+			return 0, fmt.Errorf("Deployment resulted in error. %s. Halting any further deployments...", status.Error.Message)
 		}
+		return int(status.StatusCode), nil
 	}
-
 }
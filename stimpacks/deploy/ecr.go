@@ -0,0 +1,42 @@
+package deploy
+
+import (
+	"fmt"
+
+	awspkg "github.com/PremiereGlobal/stim/pkg/aws"
+	"github.com/PremiereGlobal/stim/pkg/registry"
+)
+
+// ecrLogin logs the container engine in to the registry of the deploy image
+// if it's an ECR registry.  The AWS credentials are from the Vault AWS mount
+// and role of `aws.ecr.account` and `aws.ecr.role`, or the default credential
+// chain if they aren't set.
+func (d *Deploy) ecrLogin(engine containerEngine, image string) error {
+
+	// The nodes of the cluster pull the image of kubernetes jobs
+	if _, ok := engine.(*kubernetesEngine); ok {
+		return nil
+	}
+
+	// An invalid image fails when it's pulled
+	ref, err := registry.ParseReference(image)
+	if err != nil {
+		return nil
+	}
+	accountID, region, ok := awspkg.ParseECRHost(ref.Host)
+	if !ok {
+		return nil
+	}
+
+	d.log.Debug("Logging {} in to ECR registry {}", engine.Name(), ref.Host)
+	auth, err := d.stim.ECRAuthorization(accountID, region, d.stim.ConfigGetString("aws.ecr.account"), d.stim.ConfigGetString("aws.ecr.role"))
+	if err != nil {
+		return fmt.Errorf("Unable to get an ECR login for the deploy image %s: %v", image, err)
+	}
+	err = engine.Login(ref.Host, auth.Username, auth.Password)
+	if err != nil {
+		return fmt.Errorf("Unable to log %s in to ECR registry %s: %v", engine.Name(), ref.Host, err)
+	}
+
+	return nil
+}
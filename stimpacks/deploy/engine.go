@@ -0,0 +1,95 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/PremiereGlobal/stim/pkg/docker"
+)
+
+// containerEngine runs the deploy container of the docker, podman,
+// containerd and kubernetes deploy methods
+type containerEngine interface {
+	// Name is the name of the engine shown to the user (ex. `Podman`)
+	Name() string
+
+	// Available returns an error if containers can't be run with the engine
+	Available(ctx context.Context) error
+
+	// Login logs the engine in to a registry for the pull of the image
+	Login(host string, username string, password string) error
+
+	// Pull pulls the image and returns its `id` and `digest` for the bill of
+	// materials, if they are known.  The pull stops if the context is done.
+	Pull(ctx context.Context, image string) (map[string]string, error)
+
+	// Run runs the container to completion, printing its output, and returns
+	// its exit code.  The container is stopped and removed if the context is
+	// done first.
+	Run(ctx context.Context, spec *containerSpec) (int, error)
+}
+
+// containerSpec is the deploy container run by a container engine
+type containerSpec struct {
+	// Name is the name of the container, unique to the deploy
+	Name string
+
+	Image string
+	Cmd   []string
+	Env   []string
+
+	// DeployDir is the deployment directory, mounted (or copied) to WorkDir
+	DeployDir string
+	WorkDir   string
+
+	// CacheDir is the Linux tool cache of the host, mounted to CacheMount.
+	// Engines that don't run on the host start with an empty cache.
+	CacheDir   string
+	CacheMount string
+}
+
+// containerMethods are the deploy methods that run the deploy container, by
+// the value of `deploy.method`
+var containerMethods = map[string]int{
+	"docker":     DEPLOY_METHOD_DOCKER,
+	"podman":     DEPLOY_METHOD_PODMAN,
+	"containerd": DEPLOY_METHOD_CONTAINERD,
+	"kubernetes": DEPLOY_METHOD_KUBERNETES,
+}
+
+// isContainerMethod returns whether the deploy method runs the deploy
+// container
+func isContainerMethod(deployMethod int) bool {
+	for _, method := range containerMethods {
+		if method == deployMethod {
+			return true
+		}
+	}
+	return false
+}
+
+// containerEngine returns the engine of a container deploy method.  The
+// kubernetes engine runs the container in the cluster of the instance, so the
+// instance may only be nil to check if an engine is available.
+func (d *Deploy) containerEngine(deployMethod int, instance *Instance) (containerEngine, error) {
+	switch deployMethod {
+	case DEPLOY_METHOD_DOCKER:
+		return &dockerEngine{name: "Docker", log: d.log}, nil
+	case DEPLOY_METHOD_PODMAN:
+		return &dockerEngine{name: "Podman", host: d.podmanHost(), log: d.log}, nil
+	case DEPLOY_METHOD_CONTAINERD:
+		return &containerdEngine{log: d.log}, nil
+	case DEPLOY_METHOD_KUBERNETES:
+		return &kubernetesEngine{deploy: d, instance: instance}, nil
+	}
+	return nil, fmt.Errorf("Deploy method %d doesn't run a container", deployMethod)
+}
+
+// podmanHost returns the address of the Podman API socket, `deploy.podman.host`
+// if set
+func (d *Deploy) podmanHost() string {
+	if host := d.stim.ConfigGetString("deploy.podman.host"); host != "" {
+		return host
+	}
+	return docker.PodmanHost()
+}
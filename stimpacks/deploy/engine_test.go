@@ -0,0 +1,127 @@
+package deploy
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PremiereGlobal/stim/stim"
+	"gotest.tools/assert"
+)
+
+func TestIsContainerMethod(t *testing.T) {
+	assert.Assert(t, isContainerMethod(DEPLOY_METHOD_DOCKER))
+	assert.Assert(t, isContainerMethod(DEPLOY_METHOD_KUBERNETES))
+	assert.Assert(t, !isContainerMethod(DEPLOY_METHOD_SHELL))
+	assert.Assert(t, !isContainerMethod(DEPLOY_METHOD_UNKNOWN))
+}
+
+func TestDockerPullCancelled(t *testing.T) {
+	// The pull never finishes, as if the registry is unreachable
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/_ping") {
+			w.Header().Set("API-Version", "1.40")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	e := &dockerEngine{name: "Docker", host: "tcp://" + strings.TrimPrefix(server.URL, "http://"), log: stim.New().GetLogger()}
+	assert.NilError(t, e.Available(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	_, err := e.Pull(ctx, "premiereglobal/stim:deploy")
+	assert.ErrorContains(t, err, "context canceled")
+}
+
+func TestNerdctlRunArgs(t *testing.T) {
+	args := nerdctlRunArgs(&containerSpec{
+		Name:       "stim-deploy-abc",
+		Image:      "premiereglobal/stim:deploy",
+		Cmd:        []string{"/bin/sh", "-c", "./deploy.sh"},
+		Env:        []string{"VAULT_TOKEN=s.secret", "REPLICAS=3"},
+		DeployDir:  "/home/me/app",
+		WorkDir:    "/scripts",
+		CacheDir:   "/home/me/.stim/cache/bin/linux",
+		CacheMount: "/bin-cache",
+	})
+
+	// Values are passed in the environment of nerdctl, not its arguments
+	assert.DeepEqual(t, args, []string{
+		"run", "--rm",
+		"--name", "stim-deploy-abc",
+		"--workdir", "/scripts",
+		"--volume", "/home/me/app:/scripts",
+		"--volume", "/home/me/.stim/cache/bin/linux:/bin-cache",
+		"--env", "VAULT_TOKEN",
+		"--env", "REPLICAS",
+		"premiereglobal/stim:deploy",
+		"/bin/sh", "-c", "./deploy.sh",
+	})
+}
+
+func TestJobName(t *testing.T) {
+	assert.Equal(t, jobName("us-east-1", 0xabc), "stim-deploy-us-east-1-abc")
+	assert.Equal(t, jobName("EU_West (blue)", 0x123456789abc), "stim-deploy-eu-west-blue-12345678")
+	assert.Assert(t, len(jobName("an-instance-with-a-very-long-name-that-goes-on-and-on", 0xffffffff)) <= 63)
+}
+
+func TestJobCommand(t *testing.T) {
+	cmd := jobCommand(&containerSpec{WorkDir: "/scripts", Cmd: []string{"/bin/sh", "-c", "./deploy.sh"}})
+	assert.DeepEqual(t, cmd, []string{
+		"/bin/sh", "-c", "tar -xzf /stim/bundle/deploy.tar.gz -C /scripts && exec \"$@\"", "stim-deploy",
+		"/bin/sh", "-c", "./deploy.sh",
+	})
+}
+
+func TestEnvMap(t *testing.T) {
+	assert.DeepEqual(t, envMap([]string{"A=1", "B=x=y", "A=2", "INVALID"}), map[string]string{"A": "2", "B": "x=y"})
+}
+
+func TestPackDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stim-deploy-pack")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NilError(t, os.MkdirAll(filepath.Join(dir, "charts"), 0755))
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, "deploy.sh"), []byte("#!/bin/sh\n"), 0755))
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, "charts", "values.yaml"), []byte("replicas: 3\n"), 0644))
+
+	bundle, err := packDirectory(dir)
+	assert.NilError(t, err)
+
+	gz, err := gzip.NewReader(bytes.NewReader(bundle))
+	assert.NilError(t, err)
+	tr := tar.NewReader(gz)
+	files := map[string]int64{}
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			break
+		}
+		files[header.Name] = header.Mode
+	}
+
+	assert.Equal(t, len(files), 3)
+	assert.Equal(t, files["deploy.sh"], int64(0755))
+	_, ok := files["charts/values.yaml"]
+	assert.Assert(t, ok)
+}
@@ -0,0 +1,160 @@
+package deploy
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/PremiereGlobal/stim/pkg/shell"
+)
+
+// EnvironmentVarSource describes where the value of an env var is read from
+// when an instance is deployed
+type EnvironmentVarSource struct {
+	// File is the path of a file to set the value to the content of,
+	// relative to the deploy config file
+	File string `yaml:"file"`
+
+	// Command is a shell command, run in the directory of the deploy config
+	// file, to set the value to the output of (without the trailing newline)
+	Command string `yaml:"command"`
+
+	// Base64 is the base64 encoding of the value
+	Base64 string `yaml:"base64"`
+
+	// Sensitive values are redacted from the ConfigMap, hooks and history.
+	// Defaults to true.
+	Sensitive *bool `yaml:"sensitive"`
+}
+
+// isSensitive returns whether the value of the source is redacted
+func (s *EnvironmentVarSource) isSensitive() bool {
+	return s.Sensitive == nil || *s.Sensitive
+}
+
+// describe returns the source of the value, without the value
+func (s *EnvironmentVarSource) describe() string {
+	switch {
+	case s.File != "":
+		return "file:" + s.File
+	case s.Command != "":
+		return "command:" + s.Command
+	}
+	return "base64"
+}
+
+// validateEnvVars checks that every env var has a value or exactly one
+// value source
+func validateEnvVars(envs []*EnvironmentVar) error {
+	for _, e := range envs {
+		if e.ValueFrom == nil {
+			continue
+		}
+		if e.Value != "" {
+			return fmt.Errorf("Only one of `value` and `valueFrom` can be set for env var '%s'", e.Name)
+		}
+
+		sources := 0
+		for _, source := range []string{e.ValueFrom.File, e.ValueFrom.Command, e.ValueFrom.Base64} {
+			if source != "" {
+				sources++
+			}
+		}
+		if sources != 1 {
+			return fmt.Errorf("Exactly one of `file`, `command` and `base64` must be set in the `valueFrom` of env var '%s'", e.Name)
+		}
+
+		if e.ValueFrom.Base64 != "" {
+			if _, err := base64.StdEncoding.DecodeString(e.ValueFrom.Base64); err != nil {
+				return fmt.Errorf("Invalid `valueFrom.base64` of env var '%s': %v", e.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// resolveEnvSources sets the value of the env vars of the instance that have
+// a `valueFrom`.  Files and commands are relative to the directory of the
+// deploy config file.
+func (d *Deploy) resolveEnvSources(instance *Instance) error {
+
+	configAbs, err := filepath.Abs(d.config.configFilePath)
+	if err != nil {
+		return err
+	}
+
+	if d.resolvedEnvVars == nil {
+		d.resolvedEnvVars = make(map[*EnvironmentVar]bool)
+	}
+
+	return resolveEnvSources(instance, filepath.Dir(configAbs), d.resolvedEnvVars, runEnvCommand)
+}
+
+// resolveEnvSources sets the value of the env vars of the instance from
+// their sources, running commands with runCommand.  Env vars in resolved are
+// skipped and resolved ones are added to it.
+func resolveEnvSources(instance *Instance, dir string, resolved map[*EnvironmentVar]bool, runCommand func(command string, dir string) (string, error)) error {
+
+	for _, e := range instance.Spec.EnvironmentVars {
+		if e.ValueFrom == nil || resolved[e] {
+			continue
+		}
+
+		source := e.ValueFrom
+		switch {
+		case source.File != "":
+			path := source.File
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(dir, path)
+			}
+			b, err := ioutil.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("Error reading the file of env var '%s': %v", e.Name, err)
+			}
+			e.Value = string(b)
+		case source.Command != "":
+			out, err := runCommand(source.Command, dir)
+			if err != nil {
+				return fmt.Errorf("Error running the command of env var '%s': %v", e.Name, err)
+			}
+			e.Value = out
+		default:
+			b, err := base64.StdEncoding.DecodeString(source.Base64)
+			if err != nil {
+				return fmt.Errorf("Error decoding the base64 value of env var '%s': %v", e.Name, err)
+			}
+			e.Value = string(b)
+		}
+		resolved[e] = true
+	}
+
+	return nil
+}
+
+// runEnvCommand runs the command of an env var source in the shell and
+// returns its output without the trailing newline.  Its stderr is in the
+// error if it fails.
+func runEnvCommand(command string, dir string) (string, error) {
+
+	sh := shell.DefaultShell()
+	cmd := exec.Command(sh[0], append(sh[1:], command)...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			return "", err
+		}
+		return "", errors.New(err.Error() + ": " + message)
+	}
+
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
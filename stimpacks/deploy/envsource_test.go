@@ -0,0 +1,96 @@
+package deploy
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestValidateEnvVars(t *testing.T) {
+	assert.NilError(t, validateEnvVars([]*EnvironmentVar{
+		{Name: "REPLICAS", Value: "3"},
+		{Name: "COMMIT", ValueFrom: &EnvironmentVarSource{Command: "git rev-parse HEAD"}},
+		{Name: "CA", ValueFrom: &EnvironmentVarSource{Base64: "aGVsbG8="}},
+	}))
+
+	assert.ErrorContains(t, validateEnvVars([]*EnvironmentVar{
+		{Name: "CA", Value: "x", ValueFrom: &EnvironmentVarSource{File: "ca.pem"}},
+	}), "Only one of `value` and `valueFrom`")
+	assert.ErrorContains(t, validateEnvVars([]*EnvironmentVar{
+		{Name: "CA", ValueFrom: &EnvironmentVarSource{File: "ca.pem", Base64: "aGVsbG8="}},
+	}), "Exactly one of")
+	assert.ErrorContains(t, validateEnvVars([]*EnvironmentVar{
+		{Name: "CA", ValueFrom: &EnvironmentVarSource{}},
+	}), "Exactly one of")
+	assert.ErrorContains(t, validateEnvVars([]*EnvironmentVar{
+		{Name: "CA", ValueFrom: &EnvironmentVarSource{Base64: "not base64!"}},
+	}), "Invalid `valueFrom.base64` of env var 'CA'")
+}
+
+func TestResolveEnvSources(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stim-deploy-env")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, "ca.pem"), []byte("-----BEGIN CERTIFICATE-----\n"), 0644))
+
+	notSensitive := false
+	commit := &EnvironmentVar{Name: "COMMIT", ValueFrom: &EnvironmentVarSource{Command: "git rev-parse HEAD", Sensitive: &notSensitive}}
+	instance := &Instance{Spec: &Spec{EnvironmentVars: []*EnvironmentVar{
+		{Name: "REPLICAS", Value: "3"},
+		{Name: "CA", ValueFrom: &EnvironmentVarSource{File: "ca.pem"}},
+		{Name: "GREETING", ValueFrom: &EnvironmentVarSource{Base64: "aGVsbG8="}},
+		commit,
+	}}}
+
+	resolved := make(map[*EnvironmentVar]bool)
+	runs := 0
+	run := func(command string, runDir string) (string, error) {
+		runs++
+		assert.Equal(t, command, "git rev-parse HEAD")
+		assert.Equal(t, runDir, dir)
+		return "4f2a9c1", nil
+	}
+
+	assert.NilError(t, resolveEnvSources(instance, dir, resolved, run))
+	assert.Equal(t, instance.Spec.EnvironmentVars[0].Value, "3")
+	assert.Equal(t, instance.Spec.EnvironmentVars[1].Value, "-----BEGIN CERTIFICATE-----\n")
+	assert.Equal(t, instance.Spec.EnvironmentVars[2].Value, "hello")
+	assert.Equal(t, commit.Value, "4f2a9c1")
+
+	// Env vars shared with other instances are only resolved once
+	other := &Instance{Spec: &Spec{EnvironmentVars: []*EnvironmentVar{commit}}}
+	assert.NilError(t, resolveEnvSources(other, dir, resolved, run))
+	assert.Equal(t, runs, 1)
+
+	// Sources are sensitive unless set otherwise
+	assert.Assert(t, isSensitiveEnvVar(instance, "CA"))
+	assert.Assert(t, isSensitiveEnvVar(instance, "GREETING"))
+	assert.Assert(t, !isSensitiveEnvVar(instance, "COMMIT"))
+	assert.Assert(t, !isSensitiveEnvVar(instance, "REPLICAS"))
+
+	failing := &Instance{Spec: &Spec{EnvironmentVars: []*EnvironmentVar{
+		{Name: "VERSION", ValueFrom: &EnvironmentVarSource{Command: "./version.sh"}},
+	}}}
+	err = resolveEnvSources(failing, dir, resolved, func(string, string) (string, error) {
+		return "", errors.New("exit status 127: ./version.sh: not found")
+	})
+	assert.ErrorContains(t, err, "Error running the command of env var 'VERSION': exit status 127")
+
+	missing := &Instance{Spec: &Spec{EnvironmentVars: []*EnvironmentVar{
+		{Name: "KEY", ValueFrom: &EnvironmentVarSource{File: "missing.key"}},
+	}}}
+	assert.ErrorContains(t, resolveEnvSources(missing, dir, resolved, run), "Error reading the file of env var 'KEY'")
+}
+
+func TestRunEnvCommand(t *testing.T) {
+	out, err := runEnvCommand("echo 4f2a9c1", "")
+	assert.NilError(t, err)
+	assert.Equal(t, out, "4f2a9c1")
+
+	_, err = runEnvCommand("echo failed >&2; exit 3", "")
+	assert.ErrorContains(t, err, "exit status 3: failed")
+}
@@ -0,0 +1,374 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/PremiereGlobal/stim/pkg/i18n"
+	"github.com/PremiereGlobal/stim/pkg/utils"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// explainRow is a resolved value of an instance spec and the config level it
+// came from
+type explainRow struct {
+	Field  string
+	Value  string
+	Origin string
+}
+
+// Explain prints the resolved spec of the selected instance(s) along with
+// the config level (global, environment or instance) each value came from
+// and the lower level values that env vars and secrets override, followed by
+// the problems that would fail or confuse a deploy (ex. reserved names).
+// Vault is not accessed and secret values are never read.
+func (d *Deploy) Explain() error {
+
+	d.log = d.stim.GetLogger()
+
+	err := d.parseConfig()
+	if err != nil {
+		return err
+	}
+
+	environment, instances, err := d.selectInstances()
+	if err != nil || environment == nil {
+		return err
+	}
+
+	for i, instance := range instances {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("Environment: %s  Instance: %s\n\n", environment.Name, instance.Name)
+
+		overrides := explainOverrides(d.config.Global.Spec, environment, instance)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "FIELD\tVALUE\tFROM\tOVERRIDES")
+		for _, row := range explainInstance(instance) {
+			overridden := strings.Join(overrides[row.Field], ", ")
+			if overridden == "" {
+				overridden = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", row.Field, row.Value, row.Origin, overridden)
+		}
+		w.Flush()
+
+		if problems := explainProblems(instance); len(problems) > 0 {
+			fmt.Println("\nProblems:")
+			for _, problem := range problems {
+				fmt.Printf("  - %s\n", problem)
+			}
+		}
+	}
+
+	return nil
+}
+
+// selectInstances returns the environment and instance(s) selected with the
+// -e and -i arguments, prompting for them if they were not given.  The
+// environment is nil if nothing was selected at the prompts.
+func (d *Deploy) selectInstances() (*Environment, []*Instance, error) {
+
+	environmentName := d.stim.ConfigGetString("deploy.environment")
+	if environmentName == "" {
+		environmentList := make([]string, len(d.config.Environments))
+		for i, e := range d.config.Environments {
+			environmentList[i] = e.Name
+		}
+		environmentName, _ = d.stim.PromptListRemembered(d.selectionKey("environment"), d.stim.Message(i18n.MessageWhichEnvironment), environmentList, "")
+		if environmentName == "" {
+			d.log.Info(d.stim.Message(i18n.MessageNoEnvironment))
+			return nil, nil, nil
+		}
+	}
+	if _, ok := d.config.environmentMap[environmentName]; !ok {
+		return nil, nil, stim.ConfigError(errors.New(d.stim.Message(i18n.MessageUnknownEnvironment, environmentName)))
+	}
+	environment := d.config.Environments[d.config.environmentMap[environmentName]]
+
+	instanceName := d.stim.ConfigGetString("deploy.instance")
+	if instanceName == "" {
+		instanceList := []string{allOptionPrompt}
+		for _, inst := range environment.Instances {
+			instanceList = append(instanceList, inst.Name)
+		}
+		instanceName, _ = d.stim.PromptListRemembered(d.selectionKey("instance/"+environment.Name), d.stim.Message(i18n.MessageWhichInstance), instanceList, "")
+		if instanceName == "" {
+			d.log.Info(d.stim.Message(i18n.MessageNoInstance))
+			return nil, nil, nil
+		}
+	}
+
+	var instances []*Instance
+	if strings.ToLower(instanceName) == strings.ToLower(allOptionPrompt) || strings.ToLower(instanceName) == strings.ToLower(allOptionCli) {
+		instances = environment.Instances
+	} else if i, ok := environment.instanceMap[instanceName]; ok {
+		instances = []*Instance{environment.Instances[i]}
+	} else {
+		return nil, nil, stim.ConfigError(errors.New(d.stim.Message(i18n.MessageUnknownInstance, instanceName, environmentName)))
+	}
+
+	return environment, instances, nil
+}
+
+// completeEnvironments returns the environment names of the deploy config
+// for shell completion
+func (d *Deploy) completeEnvironments() ([]string, error) {
+
+	d.log = d.stim.GetLogger()
+
+	// Only the environment names are needed, so the config isn't resolved.
+	// In a workspace they are the environments of every service.
+	services := []*Service{nil}
+	if d.usesWorkspace() {
+		workspace, err := loadWorkspace(d.workspaceRoot())
+		if err != nil {
+			return nil, err
+		}
+		d.workspace = workspace
+		services = workspace.Services
+	}
+
+	names := []string{}
+	for _, service := range services {
+		var err error
+		if service != nil {
+			err = d.loadServiceConfig(service)
+		} else {
+			err = d.loadConfig()
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range d.config.Environments {
+			if !utils.Contains(names, e.Name) {
+				names = append(names, e.Name)
+			}
+		}
+	}
+	return names, nil
+}
+
+// explainInstance returns the resolved values of an instance spec in the
+// order they appear in the config.  Secrets show where the value is read
+// from rather than the value itself.
+func explainInstance(instance *Instance) []explainRow {
+
+	spec := instance.Spec
+	rows := []explainRow{
+		{Field: "kubernetes.cluster", Value: spec.Kubernetes.Cluster, Origin: instance.origins["kubernetes.cluster"]},
+		{Field: "kubernetes.serviceAccount", Value: spec.Kubernetes.ServiceAccount, Origin: instance.origins["kubernetes.serviceAccount"]},
+	}
+
+	if spec.Kubernetes.Namespace != "" {
+		rows = append(rows, explainRow{Field: "kubernetes.namespace", Value: spec.Kubernetes.Namespace, Origin: instance.origins["kubernetes.namespace"]})
+	}
+
+	if spec.ConfigMap != nil {
+		rows = append(rows, explainRow{Field: "configMap", Value: spec.ConfigMap.Namespace + "/" + spec.ConfigMap.Name, Origin: instance.origins["configMap"]})
+	}
+
+	if spec.HealthChecks != nil {
+		var checks []string
+		for _, rollout := range spec.HealthChecks.Rollouts {
+			checks = append(checks, rollout.Kind+"/"+rollout.Name)
+		}
+		for _, get := range spec.HealthChecks.HTTP {
+			checks = append(checks, get.URL)
+		}
+		rows = append(rows, explainRow{Field: "healthChecks", Value: strings.Join(checks, ","), Origin: instance.origins["healthChecks"]})
+	}
+
+	if spec.Rotate != nil {
+		var names []string
+		for _, secret := range spec.Rotate.Secrets {
+			names = append(names, secret.Name)
+		}
+		rows = append(rows, explainRow{Field: "rotate", Value: strings.Join(names, ","), Origin: instance.origins["rotate"]})
+	}
+
+	if spec.Helm != nil {
+		chart := spec.Helm.Chart
+		if spec.Helm.Repo != "" {
+			chart = spec.Helm.Repo + " " + chart
+		}
+		if spec.Helm.Version != "" {
+			chart += "@" + spec.Helm.Version
+		}
+		rows = append(rows, explainRow{Field: "helm.chart", Value: chart, Origin: instance.origins["helm.chart"]})
+		rows = append(rows, explainRow{Field: "helm.release", Value: spec.Helm.Release, Origin: instance.origins["helm.release"]})
+		if len(spec.Helm.ValuesFiles) > 0 {
+			rows = append(rows, explainRow{Field: "helm.valuesFiles", Value: strings.Join(spec.Helm.ValuesFiles, ","), Origin: instance.origins["helm.valuesFiles"]})
+		}
+	}
+
+	if spec.Manifests != nil {
+		path := spec.Manifests.Path
+		if spec.Manifests.Kustomize {
+			path += " (kustomize)"
+		}
+		rows = append(rows, explainRow{Field: "manifests.path", Value: path, Origin: instance.origins["manifests.path"]})
+		if spec.Manifests.Prune {
+			rows = append(rows, explainRow{Field: "manifests.prune", Value: "true", Origin: instance.origins["manifests.prune"]})
+		}
+	}
+
+	for _, event := range notifyEvents {
+		hooks := eventHooks(spec.Hooks, event)
+		if len(hooks) == 0 {
+			continue
+		}
+		var names []string
+		for i, hook := range hooks {
+			if hook.Name != "" {
+				names = append(names, hook.Name)
+			} else {
+				names = append(names, fmt.Sprintf("%s[%d]", event, i))
+			}
+		}
+		field := "hooks.on" + strings.Title(event)
+		rows = append(rows, explainRow{Field: field, Value: strings.Join(names, ","), Origin: instance.origins[field]})
+	}
+
+	for _, t := range spec.Templates {
+		rows = append(rows, explainRow{Field: "templates." + t.Output, Value: t.Input, Origin: instance.origins["templates."+t.Output]})
+	}
+	for _, image := range spec.Images {
+		rows = append(rows, explainRow{Field: "images." + image.Name, Value: image.Repo + ":" + image.Tag, Origin: instance.origins["images."+image.Name]})
+	}
+
+	var tools []string
+	for name := range spec.Tools {
+		tools = append(tools, name)
+	}
+	sort.Strings(tools)
+	for _, name := range tools {
+		rows = append(rows, explainRow{Field: "tools." + name, Value: spec.Tools[name].Version, Origin: instance.origins["tools."+name]})
+	}
+
+	for _, e := range spec.EnvironmentVars {
+		rows = append(rows, explainRow{Field: "env." + e.Name, Value: envValue(e), Origin: instance.origins["env."+e.Name]})
+	}
+
+	for i, secret := range spec.Secrets {
+		var names []string
+		for name := range secret.SecretMaps {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			rows = append(rows, explainRow{Field: "secrets." + name, Value: secretSource(secret, secret.SecretMaps[name]), Origin: instance.origins[fmt.Sprintf("secrets[%d]", i)]})
+		}
+	}
+
+	return rows
+}
+
+// secretSource describes where the value of a secret key is read from
+func secretSource(secret *SecretItem, key string) string {
+	switch {
+	case secret.AwsSecretsManager != nil:
+		if key == "" {
+			return "awsSecretsManager:" + secret.AwsSecretsManager.SecretID
+		}
+		return "awsSecretsManager:" + secret.AwsSecretsManager.SecretID + "#" + key
+	case secret.AwsSsm != nil:
+		if secret.AwsSsm.Path != "" && !strings.HasPrefix(key, "/") {
+			key = path.Join(secret.AwsSsm.Path, key)
+		}
+		return "awsSsm:" + key
+	}
+	return "vault:" + secret.SecretPath + "#" + key
+}
+
+// explainOverrides returns the lower level definitions that the env vars and
+// secrets of an instance override (ex. `global: info`), by explain field.
+// Secrets show where the value is read from rather than the value.
+func explainOverrides(global *Spec, environment *Environment, instance *Instance) map[string][]string {
+
+	overrides := make(map[string][]string)
+	add := func(field string, name string) {
+		for _, definition := range traceEnvVar(global, environment, instance, name, "") {
+			if definition.OverriddenBy == "" {
+				continue
+			}
+			value := definition.Source
+			if definition.Secret == nil {
+				value = definition.Value
+			}
+			overrides[field] = append(overrides[field], definition.Origin+": "+value)
+		}
+	}
+
+	for _, e := range instance.Spec.EnvironmentVars {
+		add("env."+e.Name, e.Name)
+	}
+	for _, secret := range instance.Spec.Secrets {
+		for name := range secret.SecretMaps {
+			add("secrets."+name, name)
+		}
+	}
+
+	return overrides
+}
+
+// explainProblems returns the problems of the resolved spec of an instance:
+// reserved env var names, which fail the deploy, and names set by both an env
+// var and a secret
+func explainProblems(instance *Instance) []string {
+
+	var problems []string
+	for _, conflict := range reservedEnvConflicts(instance, isReservedEnvName) {
+		problems = append(problems, fmt.Sprintf("%s is reserved by stim and can't be set in the deploy config", conflict))
+	}
+
+	for i, secret := range instance.Spec.Secrets {
+		for _, e := range instance.Spec.EnvironmentVars {
+			if _, ok := secret.SecretMaps[e.Name]; ok {
+				problems = append(problems, fmt.Sprintf("%s is set by both an env var (%s) and a secret (%s)", e.Name, instance.origins["env."+e.Name], instance.origins[fmt.Sprintf("secrets[%d]", i)]))
+			}
+		}
+	}
+
+	return problems
+}
+
+// reservedEnvConflicts returns the env vars and secrets of an instance with a
+// reserved name, in the form `NAME (env, global)`
+func reservedEnvConflicts(instance *Instance, isReserved func(name string) bool) []string {
+
+	var conflicts []string
+	describe := func(name string, kind string, origin string) string {
+		if origin == "" {
+			return fmt.Sprintf("%s (%s)", name, kind)
+		}
+		return fmt.Sprintf("%s (%s, %s)", name, kind, origin)
+	}
+
+	for _, e := range instance.Spec.EnvironmentVars {
+		if isReserved(e.Name) {
+			conflicts = append(conflicts, describe(e.Name, "env", instance.origins["env."+e.Name]))
+		}
+	}
+	for i, secret := range instance.Spec.Secrets {
+		var names []string
+		for name := range secret.SecretMaps {
+			if isReserved(name) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			conflicts = append(conflicts, describe(name, "secret", instance.origins[fmt.Sprintf("secrets[%d]", i)]))
+		}
+	}
+
+	return conflicts
+}
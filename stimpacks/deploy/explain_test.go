@@ -0,0 +1,56 @@
+package deploy
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestExplainOverrides(t *testing.T) {
+	global := &Spec{
+		Secrets:         []*SecretItem{vaultSecret("secret/global/db", 0, map[string]string{"DB_PASSWORD": "password"})},
+		EnvironmentVars: []*EnvironmentVar{{Name: "LOG_LEVEL", Value: "info"}, {Name: "REGION", Value: "us"}},
+	}
+	environment := &Environment{
+		Name: "prod",
+		Spec: &Spec{EnvironmentVars: []*EnvironmentVar{{Name: "LOG_LEVEL", Value: "warn"}}},
+	}
+	instance := &Instance{
+		Name: "us-west-2",
+		Spec: &Spec{
+			Secrets: []*SecretItem{vaultSecret("secret/prod/db", 0, map[string]string{"DB_PASSWORD": "password"})},
+			EnvironmentVars: []*EnvironmentVar{
+				{Name: "LOG_LEVEL", Value: "debug"},
+				{Name: "REGION", Value: "us"},
+			},
+		},
+		origins: map[string]string{"secrets[0]": originInstance, "env.LOG_LEVEL": originInstance, "env.REGION": originGlobal},
+	}
+
+	assert.DeepEqual(t, explainOverrides(global, environment, instance), map[string][]string{
+		"env.LOG_LEVEL":       {"environment: warn", "global: info"},
+		"secrets.DB_PASSWORD": {"global: vault:secret/global/db#password"},
+	})
+}
+
+func TestExplainProblems(t *testing.T) {
+	instance := &Instance{
+		Name: "us-west-2",
+		Spec: &Spec{
+			Secrets: []*SecretItem{vaultSecret("secret/prod/db", 0, map[string]string{"DB_HOST": "host", "USER_TOKEN": "token", "VAULT_TOKEN": "token"})},
+			EnvironmentVars: []*EnvironmentVar{
+				{Name: "DEPLOY_CLUSTER", Value: "blue"},
+				{Name: "DB_HOST", Value: "localhost"},
+			},
+		},
+		origins: map[string]string{"secrets[0]": originEnvironment, "env.DEPLOY_CLUSTER": originInstance, "env.DB_HOST": originGlobal},
+	}
+
+	// Every problem is reported, not just the first one
+	assert.DeepEqual(t, explainProblems(instance), []string{
+		"DEPLOY_CLUSTER (env, instance) is reserved by stim and can't be set in the deploy config",
+		"USER_TOKEN (secret, environment) is reserved by stim and can't be set in the deploy config",
+		"VAULT_TOKEN (secret, environment) is reserved by stim and can't be set in the deploy config",
+		"DB_HOST is set by both an env var (global) and a secret (environment)",
+	})
+}
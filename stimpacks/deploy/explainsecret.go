@@ -0,0 +1,186 @@
+package deploy
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// originStim is the level of the env vars that stim sets for every instance
+const originStim = "stim"
+
+// stimEnvNames are the env vars set by stim rather than the deploy config
+var stimEnvNames = []string{"VAULT_ADDR", "VAULT_TOKEN", "DEPLOY_ENVIRONMENT", "DEPLOY_INSTANCE", "DEPLOY_CLUSTER", "DEPLOY_PREVIEW", "DEPLOY_NAMESPACE", "SECRET_CONFIG", "STIM_DEPLOY"}
+
+// envDefinition is where an env var of an instance is defined
+type envDefinition struct {
+	Origin string
+	Source string
+	// Value is the value of plain env vars (the source of a `valueFrom`)
+	Value string
+	// Secret is the secret the value is read from, nil for plain env vars
+	Secret *SecretItem
+	Key    string
+	// OverriddenBy is the level of the definition that takes precedence over
+	// this one, if any
+	OverriddenBy string
+}
+
+// ExplainSecret prints where an env var of the selected instance(s) is
+// defined, the secret path and key it is read from and whether the current
+// token can read it.  The value is never printed.
+func (d *Deploy) ExplainSecret(name string) error {
+
+	d.log = d.stim.GetLogger()
+
+	err := d.parseConfig()
+	if err != nil {
+		return err
+	}
+
+	environment, instances, err := d.selectInstances()
+	if err != nil || environment == nil {
+		return err
+	}
+
+	failures := 0
+	for i, instance := range instances {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("Environment: %s  Instance: %s\n\n", environment.Name, instance.Name)
+
+		kubeConfigPath := d.stim.KubeConfigSecretPath(instance.Spec.Kubernetes.Cluster, instance.Spec.Kubernetes.ServiceAccount)
+		definitions := traceEnvVar(d.config.Global.Spec, environment, instance, name, kubeConfigPath)
+		if len(definitions) == 0 {
+			return stim.ConfigError(fmt.Errorf("`%s` is not set by the deploy config of instance '%s' in environment '%s'", name, instance.Name, environment.Name))
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tLEVEL\tSOURCE\tCHECK")
+		for _, definition := range definitions {
+			check, failed := d.checkEnvDefinition(definition)
+			if failed {
+				failures++
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, definition.Origin, definition.Source, check)
+		}
+		w.Flush()
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("%d secret check(s) failed for `%s`", failures, name)
+	}
+
+	return nil
+}
+
+// checkEnvDefinition checks that the current token can read the Vault secret
+// key of a definition in effect
+func (d *Deploy) checkEnvDefinition(definition *envDefinition) (string, bool) {
+
+	switch {
+	case definition.OverriddenBy != "":
+		return "overridden by " + definition.OverriddenBy, false
+	case definition.Secret == nil:
+		return "-", false
+	case !definition.Secret.isVault():
+		return "not checked (AWS)", false
+	}
+
+	vault, err := d.stim.NewVault()
+	if err != nil {
+		return err.Error(), true
+	}
+	check := preflightSecret(vault, "secret", definition.Secret.SecretPath, int(definition.Secret.Version), []string{definition.Key})
+	return check.Result, check.Failed
+}
+
+// traceEnvVar returns the definitions of an env var of an instance, starting
+// with the one in effect, followed by the definitions of lower levels that it
+// overrides.  kubeConfigPath is the kube-config secret of the instance.
+func traceEnvVar(global *Spec, environment *Environment, instance *Instance, name string, kubeConfigPath string) []*envDefinition {
+
+	// Env vars set by stim can't be set in the deploy config
+	for _, stimName := range stimEnvNames {
+		if stimName == name {
+			return []*envDefinition{{Origin: originStim, Source: "set by stim"}}
+		}
+	}
+	if key, ok := kubeConfigSecretMaps[name]; ok {
+		secret := &SecretItem{}
+		secret.SecretPath = kubeConfigPath
+		return []*envDefinition{{Origin: originStim, Source: definitionSource(secret, key), Secret: secret, Key: key}}
+	}
+
+	var effective []*envDefinition
+	for i, secret := range instance.Spec.Secrets {
+		if key, ok := secret.SecretMaps[name]; ok {
+			effective = append(effective, &envDefinition{Origin: instance.origins[fmt.Sprintf("secrets[%d]", i)], Source: definitionSource(secret, key), Secret: secret, Key: key})
+		}
+	}
+	for _, e := range instance.Spec.EnvironmentVars {
+		if e.Name == name {
+			effective = append(effective, &envDefinition{Origin: instance.origins["env."+name], Source: "env (not a secret)", Value: envValue(e)})
+		}
+	}
+	if len(effective) == 0 {
+		return nil
+	}
+
+	// The lower level definitions overridden by the one in effect
+	levels := []specLevel{{origin: originEnvironment, spec: environment.Spec}, {origin: originGlobal, spec: global}}
+	definitions := effective
+	for _, level := range levels {
+		if level.spec == nil || level.origin == effective[0].Origin || precedence(level.origin) > precedence(effective[0].Origin) {
+			continue
+		}
+		for _, secret := range level.spec.Secrets {
+			if key, ok := secret.SecretMaps[name]; ok {
+				definitions = append(definitions, &envDefinition{Origin: level.origin, Source: definitionSource(secret, key), Secret: secret, Key: key, OverriddenBy: effective[0].Origin})
+			}
+		}
+		for _, e := range level.spec.EnvironmentVars {
+			if e.Name == name {
+				definitions = append(definitions, &envDefinition{Origin: level.origin, Source: "env (not a secret)", Value: envValue(e), OverriddenBy: effective[0].Origin})
+			}
+		}
+	}
+
+	return definitions
+}
+
+// envValue returns the value of an env var of the deploy config, or where it
+// is read from if it has a `valueFrom`
+func envValue(e *EnvironmentVar) string {
+	if e.ValueFrom != nil {
+		return "valueFrom:" + e.ValueFrom.describe()
+	}
+	return e.Value
+}
+
+// definitionSource describes where the value of a secret key is read from,
+// including the pinned version of Vault secrets
+func definitionSource(secret *SecretItem, key string) string {
+	source := secretSource(secret, key)
+	if secret.isVault() && secret.Version != 0 {
+		source += fmt.Sprintf(" (version %d)", int(secret.Version))
+	}
+	return source
+}
+
+// precedence returns the precedence of a config level, higher levels
+// override lower ones
+func precedence(origin string) int {
+	switch origin {
+	case originGlobal:
+		return 0
+	case originEnvironment:
+		return 1
+	case originCLI:
+		return 3
+	}
+	return 2
+}
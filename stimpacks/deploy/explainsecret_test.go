@@ -0,0 +1,61 @@
+package deploy
+
+import (
+	"testing"
+
+	v2e "github.com/PremiereGlobal/vault-to-envs/pkg/vaulttoenvs"
+	"gotest.tools/assert"
+)
+
+func vaultSecret(path string, version float64, maps map[string]string) *SecretItem {
+	return &SecretItem{SecretItem: v2e.SecretItem{SecretPath: path, Version: version, SecretMaps: maps}}
+}
+
+func TestTraceEnvVar(t *testing.T) {
+	global := &Spec{
+		Secrets:         []*SecretItem{vaultSecret("secret/global/db", 0, map[string]string{"DB_PASSWORD": "password"})},
+		EnvironmentVars: []*EnvironmentVar{{Name: "LOG_LEVEL", Value: "info"}},
+	}
+	environment := &Environment{
+		Name: "prod",
+		Spec: &Spec{Secrets: []*SecretItem{vaultSecret("secret/prod/db", 0, map[string]string{"DB_PASSWORD": "password"})}},
+	}
+	instance := &Instance{
+		Name: "us-west-2",
+		Spec: &Spec{
+			Secrets:         []*SecretItem{vaultSecret("secret/prod/db", 0, map[string]string{"DB_PASSWORD": "password"})},
+			EnvironmentVars: []*EnvironmentVar{{Name: "LOG_LEVEL", Value: "info"}},
+		},
+		origins: map[string]string{"secrets[0]": originEnvironment, "env.LOG_LEVEL": originGlobal},
+	}
+
+	definitions := traceEnvVar(global, environment, instance, "DB_PASSWORD", "secret/kube/prod")
+	assert.Equal(t, len(definitions), 2)
+	assert.Equal(t, definitions[0].Origin, originEnvironment)
+	assert.Equal(t, definitions[0].Source, "vault:secret/prod/db#password")
+	assert.Equal(t, definitions[0].OverriddenBy, "")
+	assert.Equal(t, definitions[1].Origin, originGlobal)
+	assert.Equal(t, definitions[1].Source, "vault:secret/global/db#password")
+	assert.Equal(t, definitions[1].OverriddenBy, originEnvironment)
+
+	definitions = traceEnvVar(global, environment, instance, "LOG_LEVEL", "secret/kube/prod")
+	assert.Equal(t, len(definitions), 1)
+	assert.Equal(t, definitions[0].Origin, originGlobal)
+	assert.Assert(t, definitions[0].Secret == nil)
+
+	definitions = traceEnvVar(global, environment, instance, "DEPLOY_NAMESPACE", "secret/kube/prod")
+	assert.Equal(t, len(definitions), 1)
+	assert.Equal(t, definitions[0].Origin, originStim)
+	assert.Assert(t, definitions[0].Secret == nil)
+
+	definitions = traceEnvVar(global, environment, instance, "USER_TOKEN", "secret/kube/prod")
+	assert.Equal(t, len(definitions), 1)
+	assert.Equal(t, definitions[0].Source, "vault:secret/kube/prod#user-token")
+
+	assert.Equal(t, len(traceEnvVar(global, environment, instance, "MISSING", "secret/kube/prod")), 0)
+}
+
+func TestDefinitionSourceVersion(t *testing.T) {
+	assert.Equal(t, definitionSource(vaultSecret("secret/app", 3, nil), "key"), "vault:secret/app#key (version 3)")
+	assert.Equal(t, definitionSource(&SecretItem{AwsSsm: &AwsSsmSecret{Path: "/app"}}, "key"), "awsSsm:/app/key")
+}
@@ -0,0 +1,217 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// The formats of `stim deploy env`
+const (
+	envFormatDotenv = "dotenv"
+	envFormatExport = "export"
+)
+
+// envEntry is an env var of the deploy environment of an instance
+type envEntry struct {
+	Name     string
+	Value    string
+	Redacted bool
+}
+
+// ExportEnv writes the environment that the deploy script of an instance
+// runs with, so that it can be reproduced locally.  Secret values are only
+// included with --show-secrets, after a confirmation.
+func (d *Deploy) ExportEnv() error {
+
+	d.log = d.stim.GetLogger()
+
+	format := d.stim.ConfigGetString("deploy-env-format")
+	if format != envFormatDotenv && format != envFormatExport {
+		return stim.UsageError(fmt.Errorf("Invalid --format '%s', must be %s or %s", format, envFormatDotenv, envFormatExport))
+	}
+	showSecrets := d.stim.ConfigGetBool("deploy-env-show-secrets")
+
+	err := d.parseConfig()
+	if err != nil {
+		return err
+	}
+	vault, err := d.stim.NewVault()
+	if err != nil {
+		return err
+	}
+	err = addStimEnvs(&d.config, vault, d.stim.KubeConfigSecretPath)
+	if err != nil {
+		return err
+	}
+	environment, instances, err := d.selectInstances()
+	if err != nil {
+		return err
+	}
+	if environment == nil {
+		return stim.Aborted("No instance selected")
+	}
+	if len(instances) != 1 {
+		return stim.UsageError(errors.New("stim deploy env writes the environment of one instance, select it with -i"))
+	}
+	instance := instances[0]
+
+	if showSecrets {
+		yes := d.stim.ConfigGetBool("deploy.yes")
+		if !yes && d.stim.IsAutomated() {
+			return stim.UsageError(errors.New("--show-secrets must be confirmed, add -y to confirm it when automated"))
+		}
+		proceed, _ := d.stim.PromptBool(fmt.Sprintf("Write the secrets of %s/%s in plain text?", environment.Name, instance.Name), yes, false)
+		if !proceed {
+			return stim.Aborted("Cancelled, no secrets were written")
+		}
+	}
+
+	err = d.resolveImages(instances)
+	if err != nil {
+		return err
+	}
+	err = d.resolveEnvSources(instance)
+	if err != nil {
+		return stim.ConfigError(err)
+	}
+	d.addNamespace(instance)
+
+	var secretEnvs []string
+	if showSecrets {
+		err = d.addAwsSecrets(instance)
+		if err != nil {
+			return fmt.Errorf("Error reading AWS secrets: %v", err)
+		}
+		secretEnvs, err = d.stim.VaultSecretEnvs(vaultSecretItems(instance))
+		if err != nil {
+			return err
+		}
+	}
+
+	entries := instanceEnvEntries(instance, secretEnvs, showSecrets)
+	header := fmt.Sprintf("# Deploy environment of %s/%s, from `stim deploy env`\n", environment.Name, instance.Name)
+	if !showSecrets {
+		header += "# Secrets are redacted, use --show-secrets to include them\n"
+	}
+	content := header + formatEnv(entries, format)
+
+	path := d.stim.ConfigGetString("deploy-env-output")
+	if path == "" {
+		fmt.Print(content)
+		return nil
+	}
+	err = writeEnvFile(path, []byte(content))
+	if err != nil {
+		return err
+	}
+	d.log.Info("Wrote the deploy environment of {}/{} to {}", environment.Name, instance.Name, path)
+	if showSecrets {
+		d.log.Warn("{} has secrets in plain text, delete it once done", path)
+	}
+
+	return nil
+}
+
+// writeEnvFile writes the content to a new user-only file that replaces the
+// path, so that the secrets don't inherit the mode of an existing file (ex.
+// one readable by others)
+func writeEnvFile(path string, content []byte) error {
+
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// instanceEnvEntries returns the env vars of the instance followed by its
+// secrets.  The values of secrets and of env vars that look like secrets are
+// redacted unless showSecrets is set, in which case secretEnvs has the values
+// of the Vault secrets (`NAME=value`).
+func instanceEnvEntries(instance *Instance, secretEnvs []string, showSecrets bool) []*envEntry {
+
+	var entries []*envEntry
+	seen := make(map[string]bool)
+	add := func(name string, value string, redacted bool) {
+		if !seen[name] {
+			seen[name] = true
+			entries = append(entries, &envEntry{Name: name, Value: value, Redacted: redacted})
+		}
+	}
+
+	for _, e := range instance.Spec.EnvironmentVars {
+		add(e.Name, e.Value, !showSecrets && (isSensitiveEnvVar(instance, e.Name) || stim.IsSensitive(e.Name)))
+	}
+
+	if showSecrets {
+		for _, e := range secretEnvs {
+			parts := strings.SplitN(e, "=", 2)
+			if len(parts) == 2 {
+				add(parts[0], parts[1], false)
+			}
+		}
+		return entries
+	}
+
+	for _, s := range instance.Spec.Secrets {
+		names := make([]string, 0, len(s.SecretMaps))
+		for name := range s.SecretMaps {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			add(name, "", true)
+		}
+	}
+
+	return entries
+}
+
+// formatEnv formats the env vars as a .env file or as shell exports.
+// Redacted env vars are commented out.
+func formatEnv(entries []*envEntry, format string) string {
+
+	var b strings.Builder
+	for _, e := range entries {
+		switch {
+		case e.Redacted:
+			fmt.Fprintf(&b, "# %s=%s\n", e.Name, historyRedacted)
+		case format == envFormatExport:
+			fmt.Fprintf(&b, "export %s=%s\n", e.Name, shellQuote(e.Value))
+		default:
+			fmt.Fprintf(&b, "%s=%s\n", e.Name, dotenvQuote(e.Value))
+		}
+	}
+
+	return b.String()
+}
+
+// dotenvQuote quotes a .env value if it contains anything other than safe
+// characters.  Values are single-quoted (taken literally) unless they have
+// single quotes or newlines, which are escaped in double quotes.
+func dotenvQuote(value string) string {
+	if strings.Trim(value, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=@+,") == "" {
+		return value
+	}
+	if !strings.ContainsAny(value, "'\n\r") {
+		return "'" + value + "'"
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "$", `\$`).Replace(value) + `"`
+}
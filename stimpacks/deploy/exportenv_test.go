@@ -0,0 +1,86 @@
+package deploy
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	v2e "github.com/PremiereGlobal/vault-to-envs/pkg/vaulttoenvs"
+	"gotest.tools/assert"
+)
+
+func TestInstanceEnvEntries(t *testing.T) {
+	instance := &Instance{Name: "us-west-2", Spec: &Spec{
+		EnvironmentVars: []*EnvironmentVar{
+			{Name: "REPLICAS", Value: "3"},
+			{Name: "DB_PASSWORD", Value: "hunter2"},
+			{Name: "VAULT_TOKEN", Value: "s.token"},
+		},
+		Secrets: []*SecretItem{
+			{SecretItem: v2e.SecretItem{SecretPath: "secret/app", SecretMaps: map[string]string{"API_KEY": "key", "API_ID": "id"}}},
+		},
+	}}
+
+	assert.DeepEqual(t, instanceEnvEntries(instance, nil, false), []*envEntry{
+		{Name: "REPLICAS", Value: "3"},
+		{Name: "DB_PASSWORD", Value: "hunter2", Redacted: true},
+		{Name: "VAULT_TOKEN", Value: "s.token", Redacted: true},
+		{Name: "API_ID", Redacted: true},
+		{Name: "API_KEY", Redacted: true},
+	})
+
+	assert.DeepEqual(t, instanceEnvEntries(instance, []string{"API_KEY=k=v", "API_ID=1"}, true), []*envEntry{
+		{Name: "REPLICAS", Value: "3"},
+		{Name: "DB_PASSWORD", Value: "hunter2"},
+		{Name: "VAULT_TOKEN", Value: "s.token"},
+		{Name: "API_KEY", Value: "k=v"},
+		{Name: "API_ID", Value: "1"},
+	})
+}
+
+func TestFormatEnv(t *testing.T) {
+	entries := []*envEntry{
+		{Name: "REPLICAS", Value: "3"},
+		{Name: "GREETING", Value: "hello $USER"},
+		{Name: "CERT", Value: "line 1\nit's \"quoted\""},
+		{Name: "EMPTY"},
+		{Name: "API_KEY", Redacted: true},
+	}
+
+	assert.Equal(t, formatEnv(entries, envFormatDotenv), `REPLICAS=3
+GREETING='hello $USER'
+CERT="line 1\nit's \"quoted\""
+EMPTY=
+# API_KEY=<redacted>
+`)
+	assert.Equal(t, formatEnv(entries, envFormatExport), `export REPLICAS=3
+export GREETING='hello $USER'
+export CERT='line 1
+it'"'"'s "quoted"'
+export EMPTY=''
+# API_KEY=<redacted>
+`)
+}
+
+// An existing env file is replaced by a user-only file
+func TestWriteEnvFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stim-env")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "prod.env")
+	assert.NilError(t, ioutil.WriteFile(path, []byte("OLD=1\n"), 0644))
+
+	assert.NilError(t, writeEnvFile(path, []byte("API_KEY=secret\n")))
+	content, err := ioutil.ReadFile(path)
+	assert.NilError(t, err)
+	assert.Equal(t, string(content), "API_KEY=secret\n")
+	info, err := os.Stat(path)
+	assert.NilError(t, err)
+	assert.Equal(t, info.Mode().Perm(), os.FileMode(0600))
+
+	files, err := ioutil.ReadDir(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, len(files), 1)
+}
@@ -0,0 +1,339 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// maxExtendsDepth limits how deeply base configs can extend other configs
+const maxExtendsDepth = 10
+
+// gitSourcePrefix marks a base config in a git repo, ex.
+// git::https://github.com/my-org/deploy-bases.git//base.yaml?ref=v1
+const gitSourcePrefix = "git::"
+
+// extendsClient is used to download base configs from HTTPS URLs
+var extendsClient = &http.Client{Timeout: 30 * time.Second}
+
+// extendConfig merges the base configs listed in `extends` into the config.
+// Bases are applied in order with later bases and then the config itself
+// taking precedence.  Relative paths are relative to the extending file.  The
+// checksums of the bases are kept for the signed deploy config.
+func extendConfig(config *Config, source string) error {
+	sums := make(map[string]string)
+	err := extendConfigDepth(config, source, []string{source}, sums)
+	config.extendsChecksums = sums
+	return err
+}
+
+// extendConfigDepth extends the config, where chain is the list of sources
+// that led to it.  The checksum of each base read is added to sums.
+func extendConfigDepth(config *Config, source string, chain []string, sums map[string]string) error {
+
+	if len(config.Extends) == 0 {
+		return nil
+	}
+	if len(chain) > maxExtendsDepth {
+		return fmt.Errorf("Deploy config `extends` is nested more than %d levels: %s", maxExtendsDepth, strings.Join(chain, " -> "))
+	}
+
+	base := &Config{}
+	for _, extends := range config.Extends {
+
+		baseSource, err := resolveExtendsSource(extends, source)
+		if err != nil {
+			return fmt.Errorf("Invalid `extends` '%s' in '%s': %v", extends, source, err)
+		}
+		for _, s := range chain {
+			if s == baseSource {
+				return fmt.Errorf("Deploy config `extends` cycle: %s -> %s", strings.Join(chain, " -> "), baseSource)
+			}
+		}
+
+		content, err := readExtendsSource(baseSource)
+		if err != nil {
+			return fmt.Errorf("Error reading deploy config '%s': %v", baseSource, err)
+		}
+		sums[baseSource] = checksumBytes(content)
+		content, err = interpolateEnv(content, lookupEnv)
+		if err != nil {
+			return fmt.Errorf("%v in '%s'", err, baseSource)
+		}
+
+		next := &Config{}
+		err = yaml.Unmarshal(content, next)
+		if err != nil {
+			return fmt.Errorf("Error parsing deploy config '%s': %v", baseSource, err)
+		}
+
+		err = extendConfigDepth(next, baseSource, append(chain, baseSource), sums)
+		if err != nil {
+			return err
+		}
+
+		mergeBaseConfig(base, next)
+	}
+
+	mergeBaseConfig(base, config)
+	base.Extends = config.Extends
+	*config = *base
+
+	return nil
+}
+
+// resolveExtendsSource returns the source of a base config.  Relative paths
+// are resolved against the location of the extending file: its directory if
+// it's local, or its URL or its directory in the git repo if it's remote.  A
+// remote base can't extend local files.
+func resolveExtendsSource(extends string, source string) (string, error) {
+
+	if isRemoteSource(extends) {
+		return extends, checkRemoteSource(extends)
+	}
+	if !isRemoteSource(source) {
+		if filepath.IsAbs(extends) {
+			return extends, nil
+		}
+		return filepath.Join(filepath.Dir(source), extends), nil
+	}
+
+	if filepath.IsAbs(extends) || path.IsAbs(extends) {
+		return "", errors.New("a remote base can only extend remote bases or paths relative to itself")
+	}
+	relative := filepath.ToSlash(extends)
+
+	if strings.HasPrefix(source, gitSourcePrefix) {
+		repo, file, ref, err := splitGitSource(strings.TrimPrefix(source, gitSourcePrefix))
+		if err != nil {
+			return "", err
+		}
+		file = path.Join(path.Dir(file), relative)
+		if file == ".." || strings.HasPrefix(file, "../") {
+			return "", errors.New("the path is outside of the git repo of the extending base")
+		}
+		resolved := gitSourcePrefix + repo + "//" + file
+		if ref != "" {
+			resolved += "?ref=" + ref
+		}
+		return resolved, nil
+	}
+
+	base, err := url.Parse(source)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(relative)
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// isRemoteSource returns true if the source is a URL or git repo
+func isRemoteSource(source string) bool {
+	return strings.Contains(source, "://") || strings.HasPrefix(source, gitSourcePrefix)
+}
+
+// checkRemoteSource returns an error if a remote base isn't fetched over an
+// authenticated, encrypted transport: HTTPS, or git over HTTPS or SSH
+func checkRemoteSource(source string) error {
+
+	if !strings.HasPrefix(source, gitSourcePrefix) {
+		if !strings.HasPrefix(source, "https://") {
+			return errors.New("remote bases must be https:// URLs")
+		}
+		return nil
+	}
+
+	repo := strings.TrimPrefix(source, gitSourcePrefix)
+	switch {
+	case strings.HasPrefix(repo, "https://"), strings.HasPrefix(repo, "ssh://"):
+		return nil
+	case !strings.Contains(repo, "://") && strings.Contains(repo, "@") && strings.Contains(repo, ":"):
+		// The scp-like SSH syntax, ex. git@github.com:my-org/bases.git
+		return nil
+	}
+	return errors.New("git bases must be cloned over https:// or SSH")
+}
+
+// readExtendsSource reads a base config from a local path, HTTPS URL or git
+// repo
+func readExtendsSource(source string) ([]byte, error) {
+
+	if isRemoteSource(source) {
+		err := checkRemoteSource(source)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if strings.HasPrefix(source, gitSourcePrefix) {
+		return readGitSource(strings.TrimPrefix(source, gitSourcePrefix))
+	}
+
+	if strings.HasPrefix(source, "https://") {
+		resp, err := extendsClient.Get(source)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return nil, fmt.Errorf("Unexpected response %s", resp.Status)
+		}
+		return ioutil.ReadAll(resp.Body)
+	}
+
+	return ioutil.ReadFile(source)
+}
+
+// splitGitSource returns the repo, file path and ref of a git source in the
+// format <repo>//<path>[?ref=<branch or tag>]
+func splitGitSource(source string) (string, string, string, error) {
+
+	ref := ""
+	if i := strings.LastIndex(source, "?ref="); i >= 0 {
+		ref = source[i+len("?ref="):]
+		source = source[:i]
+	}
+
+	// Skip the `//` of the URL scheme when finding the path separator
+	schemeEnd := 0
+	if i := strings.Index(source, "://"); i >= 0 {
+		schemeEnd = i + len("://")
+	}
+	i := strings.Index(source[schemeEnd:], "//")
+	if i < 0 {
+		return "", "", "", errors.New("No file path in git source, must be in the format git::<repo>//<path>[?ref=<ref>]")
+	}
+
+	return source[:schemeEnd+i], source[schemeEnd+i+2:], ref, nil
+}
+
+// readGitSource reads a file from a shallow clone of a git repo.  The source
+// is in the format <repo>//<path>[?ref=<branch or tag>]
+func readGitSource(source string) ([]byte, error) {
+
+	repo, file, ref, err := splitGitSource(source)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpDir, err := ioutil.TempDir("", "stim-deploy-extends")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	args := []string{"clone", "--quiet", "--depth", "1"}
+	if ref != "" {
+		args = append(args, "--branch", ref)
+	}
+	out, err := exec.Command("git", append(args, repo, tmpDir)...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("git clone: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	return ioutil.ReadFile(filepath.Join(tmpDir, filepath.FromSlash(file)))
+}
+
+// mergeBaseConfig merges the config over the base config
+func mergeBaseConfig(base *Config, config *Config) {
+
+	if config.Deployment.Type != "" {
+		base.Deployment.Type = config.Deployment.Type
+	}
+	if config.Deployment.Directory != "" {
+		base.Deployment.Directory = config.Deployment.Directory
+	}
+	if config.Deployment.Script != "" {
+		base.Deployment.Script = config.Deployment.Script
+	}
+	if config.Deployment.Shell != "" {
+		base.Deployment.Shell = config.Deployment.Shell
+	}
+	if config.Deployment.Container.Repo != "" {
+		base.Deployment.Container.Repo = config.Deployment.Container.Repo
+	}
+	if config.Deployment.Container.Tag != "" {
+		base.Deployment.Container.Tag = config.Deployment.Container.Tag
+	}
+
+	if config.Global.Spec != nil {
+		if base.Global.Spec == nil {
+			base.Global.Spec = &Spec{}
+		}
+		base.Global.Spec = mergeBaseSpec(base.Global.Spec, config.Global.Spec)
+	}
+
+	// Environments with the same name replace the base environment
+	for _, environment := range config.Environments {
+		replaced := false
+		for i, baseEnvironment := range base.Environments {
+			if baseEnvironment.Name == environment.Name {
+				base.Environments[i] = environment
+				replaced = true
+			}
+		}
+		if !replaced {
+			base.Environments = append(base.Environments, environment)
+		}
+	}
+
+	if config.Notifications != nil {
+		base.Notifications = config.Notifications
+	}
+	if config.Previews != nil {
+		base.Previews = config.Previews
+	}
+
+	// Freezes of the base (ex. an organization-wide calendar) still apply
+	base.Freezes = append(base.Freezes, config.Freezes...)
+}
+
+// mergeBaseSpec merges a global spec over a base global spec using the same
+// rules as an environment spec over the global spec
+func mergeBaseSpec(base *Spec, spec *Spec) *Spec {
+
+	result := *spec
+
+	if result.Kubernetes.Cluster == "" {
+		result.Kubernetes.Cluster = base.Kubernetes.Cluster
+	}
+	if result.Kubernetes.ServiceAccount == "" {
+		result.Kubernetes.ServiceAccount = base.Kubernetes.ServiceAccount
+	}
+	if result.Kubernetes.Namespace == "" {
+		result.Kubernetes.Namespace = base.Kubernetes.Namespace
+	}
+	if result.ConfigMap == nil {
+		result.ConfigMap = base.ConfigMap
+	}
+	if result.HealthChecks == nil {
+		result.HealthChecks = base.HealthChecks
+	}
+	if result.Rotate == nil {
+		result.Rotate = base.Rotate
+	}
+	result.AddConfirmationPrompt = spec.AddConfirmationPrompt || base.AddConfirmationPrompt
+	result.EnvironmentVars = mergeEnvVars(spec.EnvironmentVars, base.EnvironmentVars, nil)
+	result.Secrets, _ = mergeSecrets(spec.Secrets, base.Secrets, nil)
+	result.Tools = mergeTools(spec.Tools, base.Tools, nil)
+	result.Templates = mergeTemplates(spec.Templates, base.Templates, nil)
+	result.Helm = mergeHelm(spec.Helm, base.Helm, nil)
+	result.Manifests = mergeManifests(spec.Manifests, base.Manifests, nil)
+	result.Hooks = mergeHooks(spec.Hooks, base.Hooks, nil)
+	result.Images = mergeImages(spec.Images, base.Images, nil)
+
+	return &result
+}
@@ -0,0 +1,65 @@
+package deploy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestResolveExtendsSource(t *testing.T) {
+	tests := []struct {
+		extends string
+		source  string
+		want    string
+		err     string
+	}{
+		{extends: "../base.yaml", source: "app/stim.deploy.yaml", want: "base.yaml"},
+		{extends: "https://config.example.com/stim/base.yaml", source: "stim.deploy.yaml", want: "https://config.example.com/stim/base.yaml"},
+		{extends: "git::git@github.com:my-org/bases.git//base.yaml", source: "stim.deploy.yaml", want: "git::git@github.com:my-org/bases.git//base.yaml"},
+
+		// Relative bases of remote bases are resolved against their location
+		{extends: "common.yaml", source: "https://config.example.com/stim/base.yaml", want: "https://config.example.com/stim/common.yaml"},
+		{extends: "../common.yaml", source: "git::https://github.com/my-org/bases.git//stim/base.yaml?ref=v1", want: "git::https://github.com/my-org/bases.git//common.yaml?ref=v1"},
+		{extends: "../../common.yaml", source: "git::https://github.com/my-org/bases.git//stim/base.yaml", err: "the path is outside of the git repo of the extending base"},
+		{extends: "/etc/stim/base.yaml", source: "https://config.example.com/stim/base.yaml", err: "a remote base can only extend remote bases or paths relative to itself"},
+
+		// Remote bases must be fetched over HTTPS or SSH
+		{extends: "http://config.example.com/stim/base.yaml", source: "stim.deploy.yaml", err: "remote bases must be https:// URLs"},
+		{extends: "git::http://github.com/my-org/bases.git//base.yaml", source: "stim.deploy.yaml", err: "git bases must be cloned over https:// or SSH"},
+		{extends: "git::git://github.com/my-org/bases.git//base.yaml", source: "stim.deploy.yaml", err: "git bases must be cloned over https:// or SSH"},
+	}
+
+	for _, test := range tests {
+		got, err := resolveExtendsSource(test.extends, test.source)
+		if test.err != "" {
+			assert.Error(t, err, test.err, test.extends)
+			continue
+		}
+		assert.NilError(t, err, test.extends)
+		assert.Equal(t, got, test.want)
+	}
+}
+
+func TestExtendRemoteRelative(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/stim/base.yaml":
+			w.Write([]byte("extends:\n  - common.yaml\ndeployment:\n  script: deploy.sh\n"))
+		case "/stim/common.yaml":
+			w.Write([]byte("deployment:\n  directory: ./deploy\n  script: common.sh\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	defer func(client *http.Client) { extendsClient = client }(extendsClient)
+	extendsClient = server.Client()
+
+	config := &Config{Extends: []string{server.URL + "/stim/base.yaml"}}
+	assert.NilError(t, extendConfig(config, "stim.deploy.yaml"))
+	assert.Equal(t, config.Deployment.Directory, "./deploy")
+	assert.Equal(t, config.Deployment.Script, "deploy.sh")
+	assert.Equal(t, len(config.extendsChecksums), 2)
+}
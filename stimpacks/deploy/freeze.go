@@ -0,0 +1,342 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/i18n"
+	"github.com/PremiereGlobal/stim/pkg/notify"
+	"github.com/PremiereGlobal/stim/pkg/utils"
+	stimvault "github.com/PremiereGlobal/stim/pkg/vault"
+)
+
+// defaultFreezePath is the Vault path of the central freeze windows, overridden
+// with the deploy.freeze-path config key.  Each freeze is a secret under the
+// path with `start`, `end`, `reason`, `environments` and `created-by` keys.
+const defaultFreezePath = "secret/stim/deploy/freezes"
+
+// The sources of freeze windows
+const (
+	freezeSourceVault       = "vault"
+	freezeSourceConfig      = "config"
+	freezeSourceEnvironment = "environment"
+)
+
+// appliesTo returns true if the freeze window covers the environment.  A
+// window without environments covers all environments.
+func (w *FreezeWindow) appliesTo(environment string) bool {
+	return len(w.Environments) == 0 || utils.Contains(w.Environments, environment)
+}
+
+// checkFreeze denies a deploy to an environment in a freeze window unless it
+// is overridden with --override-freeze, in which case the override is logged
+// and sent to the `freeze-override` notification event
+func (d *Deploy) checkFreeze(environment *Environment, target string) error {
+
+	freezes, err := d.environmentFreezes(environment)
+	if err != nil {
+		return err
+	}
+	window, err := activeFreeze(freezes, d.stim.Clock().Now())
+	if err != nil {
+		return err
+	}
+	if window == nil {
+		return nil
+	}
+
+	end, _ := time.Parse(time.RFC3339, window.End)
+	reason := d.stim.ConfigGetString("deploy.override-freeze")
+	if reason == "" {
+		return errors.New(d.stim.Message(i18n.MessageDeployFrozen, environment.Name, d.stim.FormatTime(end), window.Reason))
+	}
+
+	user, err := d.stim.User()
+	if err != nil {
+		user = "unknown"
+	}
+	d.log.Warn("{} is overriding the deploy freeze of environment {} ({}) with reason: {}", user, environment.Name, window.Reason, reason)
+
+	notifier, err := d.getNotifier(environment)
+	if err != nil {
+		d.log.Warn("Unable to set up deploy notifications: {}", err)
+		return nil
+	}
+	err = notifier.Notify("deploy."+notifyFreezeOverride, &notify.Payload{
+		Title:  fmt.Sprintf("%s overrode the deploy freeze of %s to deploy %s to %s/%s", user, environment.Name, d.deploymentName(environment), environment.Name, target),
+		Text:   reason,
+		Status: notifyStatuses[notifyFreezeOverride],
+		Fields: map[string]string{
+			"deployment":    d.deploymentName(environment),
+			"environment":   environment.Name,
+			"instance":      target,
+			"user":          user,
+			"freeze":        window.Reason,
+			"freeze-end":    window.End,
+			"override":      reason,
+			"freeze-source": window.source,
+		},
+	})
+	if err != nil {
+		d.log.Warn("Unable to send deploy notification: {}", err)
+	}
+
+	return nil
+}
+
+// environmentFreezes returns the freeze windows of the environment's policy,
+// the deploy config and Vault that cover the environment
+func (d *Deploy) environmentFreezes(environment *Environment) ([]*FreezeWindow, error) {
+
+	var freezes []*FreezeWindow
+	if environment.Policy != nil {
+		for _, w := range environment.Policy.Freezes {
+			w.source = freezeSourceEnvironment
+			freezes = append(freezes, w)
+		}
+	}
+	for _, w := range d.config.Freezes {
+		if w.appliesTo(environment.Name) {
+			w.source = freezeSourceConfig
+			freezes = append(freezes, w)
+		}
+	}
+
+	vaultFreezes, err := d.vaultFreezes()
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range vaultFreezes {
+		if w.appliesTo(environment.Name) {
+			freezes = append(freezes, w)
+		}
+	}
+
+	return freezes, nil
+}
+
+// vaultFreezes reads the central freeze windows from Vault.  A missing freeze
+// path means there are no freezes.
+func (d *Deploy) vaultFreezes() ([]*FreezeWindow, error) {
+
+	vault, err := d.stim.NewVault()
+	if err != nil {
+		return nil, err
+	}
+	path := d.freezePath()
+
+	// Only a missing path means there are no freezes, any other error (ex. a
+	// denied list) must not let deploys through a freeze
+	ids, err := vault.ListSecrets(path)
+	if stimvault.IsNotFound(err) {
+		d.log.Debug("No freezes found at {}", path)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error listing freezes at '%s': %v", path, err)
+	}
+
+	var freezes []*FreezeWindow
+	for _, id := range ids {
+		keys, err := vault.GetSecretKeys(path + "/" + id)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading freeze '%s': %v", id, err)
+		}
+		w := &FreezeWindow{
+			Start:     keys["start"],
+			End:       keys["end"],
+			Reason:    keys["reason"],
+			id:        id,
+			source:    freezeSourceVault,
+			createdBy: keys["created-by"],
+		}
+		if keys["environments"] != "" {
+			w.Environments = strings.Split(keys["environments"], ",")
+		}
+		freezes = append(freezes, w)
+	}
+
+	return freezes, nil
+}
+
+// freezePath returns the Vault path of the central freeze windows
+func (d *Deploy) freezePath() string {
+	path := d.stim.ConfigGetString("deploy.freeze-path")
+	if path == "" {
+		path = defaultFreezePath
+	}
+	return strings.TrimSuffix(path, "/")
+}
+
+// FreezeAdd adds a freeze window to Vault
+func (d *Deploy) FreezeAdd() error {
+
+	d.log = d.stim.GetLogger()
+
+	reason := d.stim.ConfigGetString("deploy-freeze-reason")
+	if reason == "" {
+		return errors.New("--reason must be given")
+	}
+
+	start := d.stim.Clock().Now()
+	if s := d.stim.ConfigGetString("deploy-freeze-start"); s != "" {
+		var err error
+		start, err = time.Parse(time.RFC3339, s)
+		if err != nil {
+			return fmt.Errorf("Invalid --start '%s', must be an RFC 3339 timestamp", s)
+		}
+	}
+
+	var end time.Time
+	if e := d.stim.ConfigGetString("deploy-freeze-end"); e != "" {
+		var err error
+		end, err = time.Parse(time.RFC3339, e)
+		if err != nil {
+			return fmt.Errorf("Invalid --end '%s', must be an RFC 3339 timestamp", e)
+		}
+	} else if duration := d.stim.ConfigGetString("deploy-freeze-duration"); duration != "" {
+		length, err := time.ParseDuration(duration)
+		if err != nil {
+			return fmt.Errorf("Invalid --duration '%s'", duration)
+		}
+		end = start.Add(length)
+	} else {
+		return errors.New("--end or --duration must be given")
+	}
+	if !end.After(start) {
+		return errors.New("The freeze must end after it starts")
+	}
+
+	user, err := d.stim.User()
+	if err != nil {
+		user = "unknown"
+	}
+
+	vault, err := d.stim.NewVault()
+	if err != nil {
+		return err
+	}
+	id := start.UTC().Format("20060102-150405")
+	err = vault.WriteSecretKeys(d.freezePath()+"/"+id, map[string]string{
+		"start":        start.UTC().Format(time.RFC3339),
+		"end":          end.UTC().Format(time.RFC3339),
+		"reason":       reason,
+		"environments": strings.Join(d.stim.ConfigGetStringSlice("deploy-freeze-environments"), ","),
+		"created-by":   user,
+	})
+	if err != nil {
+		return err
+	}
+
+	d.log.Info("Added freeze {} from {} to {}", id, d.stim.FormatTime(start), d.stim.FormatTime(end))
+	return nil
+}
+
+// FreezeList prints the current and upcoming freeze windows from Vault, and
+// from the deploy config if there is one
+func (d *Deploy) FreezeList() error {
+
+	d.log = d.stim.GetLogger()
+
+	freezes, err := d.vaultFreezes()
+	if err != nil {
+		return err
+	}
+
+	configFile := d.stim.ConfigGetString("deploy.file")
+	if configFile == "" {
+		configFile = defaultConfigFile
+	}
+	if _, err := os.Stat(configFile); err == nil {
+		err = d.parseConfig()
+		if err != nil {
+			return err
+		}
+		for _, w := range d.config.Freezes {
+			w.source = freezeSourceConfig
+			freezes = append(freezes, w)
+		}
+		for _, environment := range d.config.Environments {
+			if environment.Policy == nil {
+				continue
+			}
+			for _, w := range environment.Policy.Freezes {
+				window := *w
+				window.Environments = []string{environment.Name}
+				window.source = freezeSourceEnvironment
+				freezes = append(freezes, &window)
+			}
+		}
+	}
+
+	now := d.stim.Clock().Now()
+	var current []*FreezeWindow
+	for _, w := range freezes {
+		end, err := time.Parse(time.RFC3339, w.End)
+		if err == nil && end.After(now) {
+			current = append(current, w)
+		}
+	}
+	sort.SliceStable(current, func(i, j int) bool {
+		start1, _ := time.Parse(time.RFC3339, current[i].Start)
+		start2, _ := time.Parse(time.RFC3339, current[j].Start)
+		return start1.Before(start2)
+	})
+
+	if len(current) == 0 {
+		fmt.Println("No current or upcoming freezes")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tENVIRONMENTS\tSTART\tEND\tREASON\tSOURCE")
+	for _, f := range current {
+		start, _ := time.Parse(time.RFC3339, f.Start)
+		end, _ := time.Parse(time.RFC3339, f.End)
+		environments := "all"
+		if len(f.Environments) > 0 {
+			environments = strings.Join(f.Environments, ",")
+		}
+		id := f.id
+		if id == "" {
+			id = "-"
+		}
+		source := f.source
+		if f.createdBy != "" {
+			source += " (" + f.createdBy + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", id, environments, d.stim.FormatTime(start), d.stim.FormatTime(end), f.Reason, source)
+	}
+	w.Flush()
+
+	return nil
+}
+
+// FreezeRemove removes a freeze window from Vault
+func (d *Deploy) FreezeRemove(id string) error {
+
+	d.log = d.stim.GetLogger()
+
+	vault, err := d.stim.NewVault()
+	if err != nil {
+		return err
+	}
+	path := d.freezePath() + "/" + id
+	_, err = vault.GetSecretKeys(path)
+	if err != nil {
+		return fmt.Errorf("Freeze '%s' not found", id)
+	}
+
+	err = vault.DeleteSecret(path)
+	if err != nil {
+		return err
+	}
+
+	d.log.Info("Removed freeze {}", id)
+	return nil
+}
@@ -0,0 +1,155 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/PremiereGlobal/stim/pkg/github"
+)
+
+// defaultGithubEnvironment is the GitHub environment of a deploy when
+// `github.environment` is not set.  Each instance is its own environment so
+// that a deploy to one instance doesn't mark the others as inactive.
+const defaultGithubEnvironment = "{ENVIRONMENT}/{INSTANCE}"
+
+// githubDescriptionLimit is the longest description GitHub accepts
+const githubDescriptionLimit = 140
+
+// githubDeployment is the GitHub Deployment of an instance deploy
+type githubDeployment struct {
+	client *github.Github
+	repo   string
+	id     int64
+}
+
+// startGithubDeployment creates a GitHub Deployment of the deployed commit,
+// marked as in progress, if the environment has a `github` config.  Errors
+// are logged rather than failing the deploy.
+func (d *Deploy) startGithubDeployment(environment *Environment, instance *Instance) {
+
+	if environment.Github == nil {
+		return
+	}
+
+	deployment, err := d.createGithubDeployment(environment, instance)
+	if err != nil {
+		d.log.Warn("Unable to create the GitHub deployment: {}", err)
+		return
+	}
+	d.log.Debug("Created GitHub deployment {} in {}", deployment.id, deployment.repo)
+
+	if d.githubDeployments == nil {
+		d.githubDeployments = make(map[string]*githubDeployment)
+	}
+	d.githubDeployments[environment.Name+"/"+instance.Name] = deployment
+
+	d.setGithubDeploymentStatus(environment, instance, github.StateInProgress, nil)
+}
+
+// createGithubDeployment creates the GitHub Deployment of an instance deploy
+func (d *Deploy) createGithubDeployment(environment *Environment, instance *Instance) (*githubDeployment, error) {
+
+	config := environment.Github
+	client, err := d.githubClient(config.URL, config.SecretPath, config.SecretKey)
+	if err != nil {
+		return nil, err
+	}
+
+	commit, err := d.headCommit()
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := d.stim.User()
+	if err != nil {
+		user = "unknown"
+	}
+
+	created, err := client.CreateDeployment(config.Repo, &github.DeploymentRequest{
+		Ref:         commit,
+		Environment: releaseTag(config.Environment, environment, instance, d.stim.Clock().Now()),
+		Description: fmt.Sprintf("Deploy of %s by %s", d.deploymentName(environment), user),
+		Production:  config.Production,
+		Payload: map[string]string{
+			"environment": environment.Name,
+			"instance":    instance.Name,
+			"cluster":     instance.Spec.Kubernetes.Cluster,
+			"user":        user,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &githubDeployment{client: client, repo: config.Repo, id: created.ID}, nil
+}
+
+// setGithubDeploymentStatus sets the state of the GitHub Deployment of an
+// instance deploy (if one was created).  Errors are logged rather than
+// failing the deploy.
+func (d *Deploy) setGithubDeploymentStatus(environment *Environment, instance *Instance, state string, deployErr error) {
+
+	deployment, ok := d.githubDeployments[environment.Name+"/"+instance.Name]
+	if !ok {
+		return
+	}
+
+	status := &github.StatusRequest{State: state}
+	if deployErr != nil {
+		status.Description = deployErr.Error()
+		if len(status.Description) > githubDescriptionLimit {
+			status.Description = status.Description[:githubDescriptionLimit-3] + "..."
+		}
+	}
+	if environment.Github.EnvironmentURL != "" {
+		status.EnvironmentURL = releaseTag(environment.Github.EnvironmentURL, environment, instance, d.stim.Clock().Now())
+	}
+
+	err := deployment.client.CreateDeploymentStatus(deployment.repo, deployment.id, status)
+	if err != nil {
+		d.log.Warn("Unable to set the status of GitHub deployment {}: {}", deployment.id, err)
+	}
+}
+
+// githubClient returns a GitHub client for the API URL (the `github.url` stim
+// config option if empty).  The token is read from Vault at secretPath if set,
+// otherwise as for `stim github`.
+func (d *Deploy) githubClient(apiURL string, secretPath string, secretKey string) (*github.Github, error) {
+
+	if apiURL == "" {
+		apiURL = d.stim.ConfigGetString("github.url")
+	}
+
+	var token string
+	var err error
+	if secretPath != "" {
+		token, err = d.releaseToken(secretPath, secretKey, "GITHUB_TOKEN")
+	} else {
+		token, err = d.stim.GithubToken()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return github.New(&github.Config{Token: token, APIURL: apiURL}), nil
+}
+
+// validateGithubDeployment validates a 'github' section and sets its defaults
+func validateGithubDeployment(config *GithubDeployment) error {
+	if config == nil {
+		return nil
+	}
+
+	if config.Repo == "" {
+		return errors.New("`repo` must be set in the `github` config")
+	}
+	if len(strings.Split(config.Repo, "/")) != 2 {
+		return fmt.Errorf("Invalid `github.repo` '%s', must be in the format owner/name", config.Repo)
+	}
+	if config.Environment == "" {
+		config.Environment = defaultGithubEnvironment
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package deploy
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PremiereGlobal/stim/pkg/github"
+	"github.com/PremiereGlobal/stim/stim"
+	"gotest.tools/assert"
+)
+
+func TestValidateGithubDeployment(t *testing.T) {
+	assert.NilError(t, validateGithubDeployment(nil))
+
+	config := &GithubDeployment{Repo: "acme/app"}
+	assert.NilError(t, validateGithubDeployment(config))
+	assert.Equal(t, config.Environment, defaultGithubEnvironment)
+
+	assert.ErrorContains(t, validateGithubDeployment(&GithubDeployment{}), "`repo` must be set")
+	assert.ErrorContains(t, validateGithubDeployment(&GithubDeployment{Repo: "app"}), "must be in the format owner/name")
+}
+
+func TestSetGithubDeploymentStatus(t *testing.T) {
+	var statuses []map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.URL.Path, "/repos/acme/app/deployments/42/statuses")
+		status := map[string]string{}
+		assert.NilError(t, json.NewDecoder(r.Body).Decode(&status))
+		statuses = append(statuses, status)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	s := stim.New()
+	d := &Deploy{stim: s, log: s.GetLogger()}
+	environment := &Environment{Name: "prod", Github: &GithubDeployment{Repo: "acme/app", EnvironmentURL: "https://{INSTANCE}.example.com"}}
+	instance := &Instance{Name: "us-west-2", Spec: &Spec{Kubernetes: Kubernetes{Cluster: "prod.my-domain.com"}}}
+
+	// Nothing is sent without a deployment
+	d.setGithubDeploymentStatus(environment, instance, github.StateSuccess, nil)
+	assert.Equal(t, len(statuses), 0)
+
+	d.githubDeployments = map[string]*githubDeployment{
+		"prod/us-west-2": {client: github.New(&github.Config{APIURL: server.URL}), repo: "acme/app", id: 42},
+	}
+	d.setGithubDeploymentStatus(environment, instance, github.StateSuccess, nil)
+	d.setGithubDeploymentStatus(environment, instance, github.StateFailure, errors.New(strings.Repeat("x", 200)))
+
+	assert.Equal(t, len(statuses), 2)
+	assert.DeepEqual(t, statuses[0], map[string]string{"state": "success", "environment_url": "https://us-west-2.example.com"})
+	assert.Equal(t, statuses[1]["state"], "failure")
+	assert.Equal(t, len(statuses[1]["description"]), githubDescriptionLimit)
+}
@@ -0,0 +1,217 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/kubernetes"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// defaultHealthCheckTimeout is how long to wait for the health checks when no
+// timeout is set
+const defaultHealthCheckTimeout = 5 * time.Minute
+
+// healthCheckInterval is how often the health checks are polled
+const healthCheckInterval = 5 * time.Second
+
+// HealthChecks are checked after the deployment runs.  The deploy fails if
+// they don't all pass within the timeout.
+type HealthChecks struct {
+	Timeout  string     `yaml:"timeout"`
+	Rollouts []*Rollout `yaml:"rollouts"`
+	HTTP     []*HTTPGet `yaml:"http"`
+}
+
+// Rollout is a Deployment or StatefulSet whose rollout must complete
+type Rollout struct {
+	Kind      string `yaml:"kind"`
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace"`
+}
+
+// HTTPGet is a URL that must return the expected status
+type HTTPGet struct {
+	URL    string `yaml:"url"`
+	Status int    `yaml:"status"`
+}
+
+// healthCheck is a check that is polled until it passes
+type healthCheck struct {
+	name  string
+	check func() (bool, string, error)
+}
+
+// runHealthChecks polls the health checks of the instance until they all pass
+// or the timeout is reached
+func (d *Deploy) runHealthChecks(instance *Instance) error {
+
+	healthChecks := instance.Spec.HealthChecks
+	if healthChecks == nil || (len(healthChecks.Rollouts) == 0 && len(healthChecks.HTTP) == 0) {
+		return nil
+	}
+
+	timeout := defaultHealthCheckTimeout
+	if healthChecks.Timeout != "" {
+		timeout, _ = time.ParseDuration(healthChecks.Timeout)
+	}
+
+	var checks []*healthCheck
+	if len(healthChecks.Rollouts) > 0 {
+		kube, cleanup, err := d.instanceKubernetes(instance)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		namespace := instanceNamespace(instance)
+		for _, rollout := range healthChecks.Rollouts {
+			rollout := rollout
+			ns := rollout.Namespace
+			if ns == "" {
+				ns = namespace
+			}
+			checks = append(checks, &healthCheck{
+				name: fmt.Sprintf("%s %s/%s", rollout.Kind, ns, rollout.Name),
+				check: func() (bool, string, error) {
+					return kube.RolloutStatus(rollout.Kind, ns, rollout.Name)
+				},
+			})
+		}
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	for _, get := range healthChecks.HTTP {
+		get := get
+		checks = append(checks, &healthCheck{
+			name:  get.URL,
+			check: func() (bool, string, error) { return httpHealthCheck(client, get) },
+		})
+	}
+
+	return d.waitHealthChecks(checks, timeout)
+}
+
+// instanceKubernetes returns a Kubernetes client for the cluster of the
+// instance using its service account.  cleanup removes the temporary
+// kubeconfig and must be called when the client is no longer needed.
+func (d *Deploy) instanceKubernetes(instance *Instance) (*kubernetes.Kubernetes, func(), error) {
+
+	tmpDir, err := ioutil.TempDir("", "stim-deploy")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() { os.RemoveAll(tmpDir) }
+
+	kc, err := d.stim.KubeConfigFromVault(&stim.KubeConfigOptions{
+		Cluster:        instance.Spec.Kubernetes.Cluster,
+		ServiceAccount: instance.Spec.Kubernetes.ServiceAccount,
+		Path:           filepath.Join(tmpDir, "kubeconfig"),
+	})
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	kube, err := kubernetes.New(kc)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	return kube, cleanup, nil
+}
+
+// instanceNamespace returns the DEPLOY_NAMESPACE of the instance, the default
+// namespace of objects that don't set one
+func instanceNamespace(instance *Instance) string {
+	namespace := ""
+	for _, e := range instance.Spec.EnvironmentVars {
+		if e.Name == "DEPLOY_NAMESPACE" {
+			namespace = e.Value
+		}
+	}
+	return namespace
+}
+
+// waitHealthChecks polls the health checks until they all pass or the
+// timeout is reached
+func (d *Deploy) waitHealthChecks(checks []*healthCheck, timeout time.Duration) error {
+
+	d.log.Info("Waiting up to {} for {} health check(s)", timeout, len(checks))
+	deadline := d.stim.Clock().Now().Add(timeout)
+	for {
+		var pending []*healthCheck
+		for _, c := range checks {
+			done, message, err := c.check()
+			if err != nil {
+				return fmt.Errorf("Health check %s failed: %v", c.name, err)
+			}
+			if done {
+				d.log.Info("Health check {} passed: {}", c.name, message)
+				continue
+			}
+			d.log.Debug("Health check {} pending: {}", c.name, message)
+			pending = append(pending, c)
+		}
+		checks = pending
+
+		if len(checks) == 0 {
+			return nil
+		}
+		if d.stim.Clock().Now().After(deadline) {
+			return fmt.Errorf("Health check %s did not pass within %s", checks[0].name, timeout)
+		}
+		err := d.stim.Sleep(healthCheckInterval)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// httpHealthCheck returns true if the URL returns the expected status, or a
+// 2xx status if none is set.  Request errors are treated as not ready yet.
+func httpHealthCheck(client *http.Client, get *HTTPGet) (bool, string, error) {
+
+	resp, err := client.Get(get.URL)
+	if err != nil {
+		return false, err.Error(), nil
+	}
+	resp.Body.Close()
+
+	if get.Status != 0 {
+		return resp.StatusCode == get.Status, resp.Status, nil
+	}
+	return resp.StatusCode >= 200 && resp.StatusCode < 300, resp.Status, nil
+}
+
+// validateHealthChecks checks the rollout kinds, URLs and timeout
+func validateHealthChecks(healthChecks *HealthChecks) error {
+	if healthChecks == nil {
+		return nil
+	}
+	if healthChecks.Timeout != "" {
+		if _, err := time.ParseDuration(healthChecks.Timeout); err != nil {
+			return fmt.Errorf("Invalid health check timeout '%s'", healthChecks.Timeout)
+		}
+	}
+	for _, rollout := range healthChecks.Rollouts {
+		if rollout.Kind != "Deployment" && rollout.Kind != "StatefulSet" {
+			return fmt.Errorf("Invalid rollout kind '%s'.  Must be one of ['Deployment','StatefulSet']", rollout.Kind)
+		}
+		if rollout.Name == "" {
+			return errors.New("`name` must be set for each rollout health check")
+		}
+	}
+	for _, get := range healthChecks.HTTP {
+		if get.URL == "" {
+			return errors.New("`url` must be set for each http health check")
+		}
+	}
+	return nil
+}
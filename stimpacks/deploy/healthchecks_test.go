@@ -0,0 +1,56 @@
+package deploy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/clock"
+	"github.com/PremiereGlobal/stim/stim"
+	"gotest.tools/assert"
+)
+
+func TestHTTPHealthCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ready" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	done, _, err := httpHealthCheck(server.Client(), &HTTPGet{URL: server.URL + "/ready"})
+	assert.NilError(t, err)
+	assert.Assert(t, done)
+
+	done, _, err = httpHealthCheck(server.Client(), &HTTPGet{URL: server.URL + "/ready", Status: 200})
+	assert.NilError(t, err)
+	assert.Assert(t, !done)
+
+	done, message, err := httpHealthCheck(server.Client(), &HTTPGet{URL: server.URL + "/healthz"})
+	assert.NilError(t, err)
+	assert.Assert(t, !done)
+	assert.Equal(t, message, "503 Service Unavailable")
+}
+
+func TestWaitHealthChecks(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	s := stim.New()
+	s.SetClock(fake)
+	d := &Deploy{stim: s, log: s.GetLogger()}
+
+	polls := 0
+	ready := &healthCheck{name: "ready", check: func() (bool, string, error) {
+		polls++
+		return polls == 3, "", nil
+	}}
+	assert.NilError(t, d.waitHealthChecks([]*healthCheck{ready}, time.Minute))
+	assert.Equal(t, fake.Now(), start.Add(2*healthCheckInterval))
+
+	never := &healthCheck{name: "never", check: func() (bool, string, error) { return false, "", nil }}
+	err := d.waitHealthChecks([]*healthCheck{never}, time.Minute)
+	assert.Error(t, err, "Health check never did not pass within 1m0s")
+}
@@ -0,0 +1,203 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// The deployment types
+const (
+	deployTypeScript = "script"
+	deployTypeHelm   = "helm"
+)
+
+// helmValuesDirectory is where rendered values files are written, relative to
+// the temporary copy of the deployment directory made by renderTemplates
+const helmValuesDirectory = ".stim/helm"
+
+// Helm describes the chart deployed by the `helm` deployment type.  Chart is
+// a path relative to the deployment directory, or a chart name when Repo is
+// set.  Values files are rendered as templates before being passed to Helm.
+type Helm struct {
+	Chart       string   `yaml:"chart"`
+	Repo        string   `yaml:"repo"`
+	Version     string   `yaml:"version"`
+	Release     string   `yaml:"release"`
+	ValuesFiles []string `yaml:"valuesFiles"`
+	Wait        bool     `yaml:"wait"`
+	Atomic      bool     `yaml:"atomic"`
+	Timeout     string   `yaml:"timeout"`
+}
+
+// deployCommand returns the command that runs the deployment of the instance,
+// rendering the Helm values files first for the `helm` deployment type
+func (d *Deploy) deployCommand(environment *Environment, instance *Instance) (string, error) {
+
+	if d.config.Deployment.Type != deployTypeHelm {
+		return "./" + d.config.Deployment.Script, nil
+	}
+
+	namespace, valuesFiles, err := d.renderHelmValues(environment, instance)
+	if err != nil {
+		return "", err
+	}
+
+	return helmCommand(instance.Spec.Helm, namespace, valuesFiles), nil
+}
+
+// renderHelmValues renders the Helm values files of the instance and returns
+// the deploy namespace and the rendered files.  It must run after
+// renderTemplates, so the files are rendered into the temporary copy of the
+// deployment directory that its cleanup removes.
+func (d *Deploy) renderHelmValues(environment *Environment, instance *Instance) (string, []string, error) {
+
+	data, err := d.instanceTemplateData(environment, instance)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var valuesFiles []string
+	for i, valuesFile := range instance.Spec.Helm.ValuesFiles {
+		output := path.Join(helmValuesDirectory, environment.Name, instance.Name, fmt.Sprintf("%d-%s", i, path.Base(filepath.ToSlash(valuesFile))))
+		err := renderTemplate(d.config.Deployment.fullDirectoryPath, &Template{Input: valuesFile, Output: output}, data)
+		if err != nil {
+			return "", nil, fmt.Errorf("Error rendering Helm values file '%s': %v", valuesFile, err)
+		}
+		d.log.Debug("Rendered Helm values file {} to {}", valuesFile, output)
+		valuesFiles = append(valuesFiles, output)
+	}
+
+	return data.Namespace, valuesFiles, nil
+}
+
+// helmCommand returns the `helm upgrade --install` command for the chart
+func helmCommand(helm *Helm, namespace string, valuesFiles []string) string {
+
+	args := helmArgs([]string{"helm", "upgrade", "--install"}, helm, namespace, valuesFiles)
+	if helm.Wait {
+		args = append(args, "--wait")
+	}
+	if helm.Atomic {
+		args = append(args, "--atomic")
+	}
+	if helm.Timeout != "" {
+		args = append(args, "--timeout", helm.Timeout)
+	}
+
+	return shellJoin(args)
+}
+
+// helmTemplateCommand returns the `helm template` command that renders the
+// manifests of the chart
+func helmTemplateCommand(helm *Helm, namespace string, valuesFiles []string) string {
+	return shellJoin(helmArgs([]string{"helm", "template"}, helm, namespace, valuesFiles))
+}
+
+// helmArgs appends the release, chart, namespace and values files to a helm
+// command
+func helmArgs(args []string, helm *Helm, namespace string, valuesFiles []string) []string {
+
+	args = append(args, helm.Release, helm.Chart)
+	if helm.Repo != "" {
+		args = append(args, "--repo", helm.Repo)
+	}
+	if helm.Version != "" {
+		args = append(args, "--version", helm.Version)
+	}
+	if namespace != "" {
+		args = append(args, "--namespace", namespace)
+	}
+	for _, f := range valuesFiles {
+		args = append(args, "--values", f)
+	}
+
+	return args
+}
+
+// shellJoin quotes the arguments of a command for `sh -c`
+func shellJoin(args []string) string {
+	for i, arg := range args {
+		args[i] = shellQuote(arg)
+	}
+	return strings.Join(args, " ")
+}
+
+// shellQuote quotes an argument for `sh -c` if it contains anything other
+// than safe characters
+func shellQuote(arg string) string {
+	if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:=@+,") == "" {
+		return arg
+	}
+	return "'" + strings.Replace(arg, "'", `'"'"'`, -1) + "'"
+}
+
+// mergeHelm merges the Helm settings of each level field by field, with the
+// instance taking precedence over the environment and then the global spec
+func mergeHelm(instance *Helm, environment *Helm, global *Helm) *Helm {
+
+	if instance == nil && environment == nil && global == nil {
+		return nil
+	}
+
+	result := &Helm{}
+	for _, level := range []*Helm{global, environment, instance} {
+		if level == nil {
+			continue
+		}
+		if level.Chart != "" {
+			result.Chart = level.Chart
+		}
+		if level.Repo != "" {
+			result.Repo = level.Repo
+		}
+		if level.Version != "" {
+			result.Version = level.Version
+		}
+		if level.Release != "" {
+			result.Release = level.Release
+		}
+		if level.ValuesFiles != nil {
+			result.ValuesFiles = level.ValuesFiles
+		}
+		if level.Timeout != "" {
+			result.Timeout = level.Timeout
+		}
+		result.Wait = result.Wait || level.Wait
+		result.Atomic = result.Atomic || level.Atomic
+	}
+
+	return result
+}
+
+// validateHelm checks that the values files are within the deployment
+// directory
+func validateHelm(helm *Helm) error {
+	if helm == nil {
+		return nil
+	}
+	for _, f := range helm.ValuesFiles {
+		if filepath.IsAbs(f) || strings.HasPrefix(filepath.Clean(f), "..") {
+			return fmt.Errorf("Helm values file '%s' must be relative to the deployment directory", f)
+		}
+	}
+	return nil
+}
+
+// resolveHelm checks that a chart is set for the `helm` deployment type and
+// defaults the release name to the instance name
+func resolveHelm(deploymentType string, instance *Instance) error {
+	if deploymentType != deployTypeHelm {
+		return nil
+	}
+	if instance.Spec.Helm == nil || instance.Spec.Helm.Chart == "" {
+		return errors.New("`spec.helm.chart` must be set for the `helm` deployment type")
+	}
+	if instance.Spec.Helm.Release == "" {
+		instance.Spec.Helm.Release = instance.Name
+		instance.origins["helm.release"] = "default"
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package deploy
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/PremiereGlobal/stim/stim"
+	"gotest.tools/assert"
+)
+
+func TestHelmCommand(t *testing.T) {
+	command := helmCommand(&Helm{
+		Chart:   "./chart",
+		Repo:    "",
+		Version: "1.2.0",
+		Release: "my-app",
+		Atomic:  true,
+		Timeout: "10m",
+	}, "my namespace", []string{".stim/helm/stage/stage1/0-values.yaml"})
+
+	assert.Equal(t, command, "helm upgrade --install my-app ./chart --version 1.2.0 --namespace 'my namespace' --values .stim/helm/stage/stage1/0-values.yaml --atomic --timeout 10m")
+}
+
+func TestHelmTemplateCommand(t *testing.T) {
+	command := helmTemplateCommand(&Helm{
+		Chart:   "my-chart",
+		Repo:    "https://charts.example.com",
+		Release: "my-app",
+		Wait:    true,
+	}, "apps", []string{".stim/helm/stage/stage1/0-values.yaml"})
+
+	assert.Equal(t, command, "helm template my-app my-chart --repo https://charts.example.com --namespace apps --values .stim/helm/stage/stage1/0-values.yaml")
+}
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, shellQuote("my-app"), "my-app")
+	assert.Equal(t, shellQuote(""), "''")
+	assert.Equal(t, shellQuote("it's"), `'it'"'"'s'`)
+}
+
+func TestRenderHelmValuesStaged(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stim-deploy-helm")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, "values.yaml"), []byte(`password: {{ .Env.PASSWORD }}`), 0600))
+
+	s := stim.New()
+	d := &Deploy{stim: s, log: s.GetLogger()}
+	d.config.Deployment.Type = deployTypeHelm
+	d.config.Deployment.fullDirectoryPath = dir
+	instance := &Instance{Name: "us-west-2", Spec: &Spec{
+		EnvironmentVars: []*EnvironmentVar{{Name: "PASSWORD", Value: "s3cr3t"}},
+		Helm:            &Helm{Chart: "chart", Release: "web", ValuesFiles: []string{"values.yaml"}},
+	}}
+
+	cleanup, err := d.renderTemplates(&Environment{Name: "prod"}, instance)
+	assert.NilError(t, err)
+	staged := d.config.Deployment.fullDirectoryPath
+	assert.Assert(t, staged != dir)
+
+	_, valuesFiles, err := d.renderHelmValues(&Environment{Name: "prod"}, instance)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, valuesFiles, []string{".stim/helm/prod/us-west-2/0-values.yaml"})
+	out, err := ioutil.ReadFile(filepath.Join(staged, valuesFiles[0]))
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "password: s3cr3t")
+
+	cleanup()
+	_, err = os.Stat(staged)
+	assert.Assert(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(dir, ".stim"))
+	assert.Assert(t, os.IsNotExist(err))
+}
@@ -0,0 +1,444 @@
+package deploy
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// historyFile is the file (in the `deploy` cache directory) that the deploys
+// from this machine are recorded in, one JSON record per line
+const historyFile = "history.jsonl"
+
+// historyRedacted replaces the values of secret env vars in deploy records
+const historyRedacted = "<redacted>"
+
+// historyIDFormat is the time format that deploy record IDs start with, so
+// that they sort in time order.  The time is followed by a random suffix
+// (ex. `20200102T030405.000Z-8f14e45f`) so that deploys started in the same
+// millisecond on different machines don't overwrite each other in Vault.
+const historyIDFormat = "20060102T150405.000Z"
+
+// DeployRecord is the record of an instance deploy shown by `stim deploy
+// history` and `stim deploy describe`
+type DeployRecord struct {
+	ID              string            `json:"id"`
+	Deployment      string            `json:"deployment"`
+	Environment     string            `json:"environment"`
+	Instance        string            `json:"instance"`
+	Cluster         string            `json:"cluster"`
+	Namespace       string            `json:"namespace,omitempty"`
+	User            string            `json:"user"`
+	Host            string            `json:"host"`
+	Time            time.Time         `json:"time"`
+	DurationSeconds float64           `json:"durationSeconds"`
+	Result          string            `json:"result"`
+	Error           string            `json:"error,omitempty"`
+	Commit          string            `json:"commit,omitempty"`
+	Tag             string            `json:"tag,omitempty"`
+	Image           string            `json:"image,omitempty"`
+	Images          map[string]string `json:"images,omitempty"`
+	PromotedFrom    string            `json:"promotedFrom,omitempty"`
+	Env             map[string]string `json:"env,omitempty"`
+}
+
+// recordHistory records an instance deploy in the local history and, if
+// `deploy.history-path` is set, in Vault.  Errors are logged rather than
+// failing the deploy.
+func (d *Deploy) recordHistory(environment *Environment, instance *Instance, deployMethod int, start time.Time, deployErr error) {
+
+	record := d.deployRecord(environment, instance, deployMethod, start, deployErr)
+
+	path := filepath.Join(d.stim.ConfigGetCacheDir("deploy"), historyFile)
+	err := appendHistory(path, record)
+	if err != nil {
+		d.log.Debug("Unable to write {}: {}", path, err)
+	}
+
+	if historyPath := d.historyPath(); historyPath != "" {
+		vault, err := d.stim.NewVault()
+		if err == nil {
+			err = vault.WriteSecretKeys(historyPath+"/"+record.ID, recordKeys(record))
+		}
+		if err != nil {
+			d.log.Warn("Unable to write the deploy record to Vault: {}", err)
+		}
+	}
+}
+
+// deployRecord returns the record of an instance deploy, with the values of
+// secret env vars redacted
+func (d *Deploy) deployRecord(environment *Environment, instance *Instance, deployMethod int, start time.Time, deployErr error) *DeployRecord {
+
+	user, err := d.stim.User()
+	if err != nil {
+		user = "unknown"
+	}
+	host, _ := os.Hostname()
+
+	record := &DeployRecord{
+		ID:              fmt.Sprintf("%s-%08x", start.UTC().Format(historyIDFormat), d.stim.Rand().Uint32()),
+		Deployment:      d.deploymentName(environment),
+		Environment:     environment.Name,
+		Instance:        instance.Name,
+		Cluster:         instance.Spec.Kubernetes.Cluster,
+		Namespace:       instance.Spec.Kubernetes.Namespace,
+		User:            user,
+		Host:            host,
+		Time:            start.UTC(),
+		DurationSeconds: d.stim.Clock().Now().Sub(start).Seconds(),
+		Result:          notifySuccess,
+		Images:          deployedImages(instance),
+		Env:             redactedEnv(instance),
+	}
+	if d.promotion != nil && d.promotion.record != nil {
+		record.PromotedFrom = d.promotion.record.ID
+	}
+	if deployErr != nil {
+		record.Result = notifyFailure
+		record.Error = deployErr.Error()
+	}
+
+	// The deployed commit and its tag (ex. the release tag) are only known
+	// if the deploy config is in a git repo, or a package was created from
+	// one
+	if d.pkg != nil {
+		record.Commit, record.Tag = d.pkg.manifest.Commit, d.pkg.manifest.Tag
+	} else {
+		record.Commit, _ = d.git("rev-parse", "HEAD")
+		if record.Commit != "" {
+			record.Tag, _ = d.git("describe", "--tags", "--exact-match", "HEAD")
+		}
+	}
+	if isContainerMethod(deployMethod) {
+		record.Image = fmt.Sprintf("%s:%s", d.config.Deployment.Container.Repo, d.config.Deployment.Container.Tag)
+	}
+
+	return record
+}
+
+// deployedImages returns the references of the images of the instance, by
+// image name, as set in their env vars
+func deployedImages(instance *Instance) map[string]string {
+
+	var images map[string]string
+	for _, image := range instance.Spec.Images {
+		for _, e := range instance.Spec.EnvironmentVars {
+			if e.Name == image.Name {
+				if images == nil {
+					images = make(map[string]string)
+				}
+				images[image.Name] = e.Value
+			}
+		}
+	}
+
+	return images
+}
+
+// redactedEnv returns the env vars of the instance with the values of
+// secrets, stim-generated credentials and names that look like secrets (ex.
+// `--set DB_PASSWORD=...`) redacted.  Vault secrets are listed by name only.
+func redactedEnv(instance *Instance) map[string]string {
+
+	env := make(map[string]string)
+	for _, e := range instance.Spec.EnvironmentVars {
+		if isSensitiveEnvVar(instance, e.Name) || stim.IsSensitive(e.Name) {
+			env[e.Name] = historyRedacted
+		} else {
+			env[e.Name] = e.Value
+		}
+	}
+	for _, s := range instance.Spec.Secrets {
+		for name := range s.SecretMaps {
+			env[name] = historyRedacted
+		}
+	}
+
+	return env
+}
+
+// historyPath returns the Vault path that deploy records are shared in, empty
+// if they are only kept on this machine
+func (d *Deploy) historyPath() string {
+	return strings.TrimSuffix(d.stim.ConfigGetString("deploy.history-path"), "/")
+}
+
+// History prints the recorded deploys of the deploy config, newest first,
+// filtered by --environment and --instance
+func (d *Deploy) History() error {
+
+	d.log = d.stim.GetLogger()
+
+	limit := d.stim.ConfigGetInt("deploy-history-limit")
+	if limit <= 0 {
+		return stim.UsageError(fmt.Errorf("--limit must be greater than 0"))
+	}
+
+	err := d.parseConfig()
+	if err != nil {
+		return err
+	}
+
+	// The history (local or in Vault) is shared by every deployment, so
+	// only the deploys of the environments of this config are listed
+	deployments := make(map[string]bool)
+	for _, e := range d.config.Environments {
+		deployments[d.deploymentName(e)] = true
+	}
+
+	environment := d.stim.ConfigGetString("deploy.environment")
+	instance := d.stim.ConfigGetString("deploy.instance")
+	records, err := d.readHistory(func(r *DeployRecord) bool {
+		return deployments[r.Deployment] && (environment == "" || r.Environment == environment) && (instance == "" || r.Instance == instance)
+	}, limit)
+	if err != nil {
+		return err
+	}
+
+	return d.stim.PrintOutput(d.stim.ConfigGetString("deploy-history-output"), records, func(w *tabwriter.Writer) {
+		if len(records) == 0 {
+			fmt.Fprintln(w, "No deploys recorded")
+			return
+		}
+		fmt.Fprintln(w, "ID\tTIME\tDEPLOYMENT\tENVIRONMENT\tINSTANCE\tUSER\tCOMMIT\tTAG\tRESULT\tDURATION")
+		for _, r := range records {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.ID, d.stim.FormatTime(r.Time), r.Deployment, r.Environment, r.Instance, r.User, orDash(shortCommit(r.Commit)), orDash(r.Tag), r.Result, formatSeconds(r.DurationSeconds))
+		}
+	})
+}
+
+// Describe prints the full record of a deploy
+func (d *Deploy) Describe(id string) error {
+
+	d.log = d.stim.GetLogger()
+
+	records, err := d.readHistory(func(r *DeployRecord) bool {
+		return r.ID == id
+	}, 1)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return stim.UsageError(fmt.Errorf("Deploy '%s' not found.  `stim deploy history` lists the recorded deploys", id))
+	}
+	r := records[0]
+
+	return d.stim.PrintOutput(d.stim.ConfigGetString("deploy-describe-output"), r, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "ID:\t%s\n", r.ID)
+		fmt.Fprintf(w, "Deployment:\t%s\n", r.Deployment)
+		fmt.Fprintf(w, "Environment:\t%s\n", r.Environment)
+		fmt.Fprintf(w, "Instance:\t%s\n", r.Instance)
+		fmt.Fprintf(w, "Cluster:\t%s\n", r.Cluster)
+		fmt.Fprintf(w, "Namespace:\t%s\n", orDash(r.Namespace))
+		fmt.Fprintf(w, "User:\t%s\n", r.User)
+		fmt.Fprintf(w, "Host:\t%s\n", r.Host)
+		fmt.Fprintf(w, "Time:\t%s\n", d.stim.FormatTime(r.Time))
+		fmt.Fprintf(w, "Duration:\t%s\n", formatSeconds(r.DurationSeconds))
+		fmt.Fprintf(w, "Result:\t%s\n", r.Result)
+		if r.Error != "" {
+			fmt.Fprintf(w, "Error:\t%s\n", r.Error)
+		}
+		fmt.Fprintf(w, "Commit:\t%s\n", orDash(r.Commit))
+		fmt.Fprintf(w, "Tag:\t%s\n", orDash(r.Tag))
+		fmt.Fprintf(w, "Image:\t%s\n", orDash(r.Image))
+		if r.PromotedFrom != "" {
+			fmt.Fprintf(w, "Promoted from:\t%s\n", r.PromotedFrom)
+		}
+		if len(r.Images) > 0 {
+			fmt.Fprintln(w, "Images:\t")
+			images := make([]string, 0, len(r.Images))
+			for name := range r.Images {
+				images = append(images, name)
+			}
+			sort.Strings(images)
+			for _, name := range images {
+				fmt.Fprintf(w, "  %s\t%s\n", name, r.Images[name])
+			}
+		}
+		fmt.Fprintln(w, "Env:\t")
+		names := make([]string, 0, len(r.Env))
+		for name := range r.Env {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(w, "  %s\t%s\n", name, r.Env[name])
+		}
+	})
+}
+
+// readHistory returns up to limit deploy records that match, newest first.
+// Records are read from Vault if `deploy.history-path` is set, otherwise
+// from the local history.
+func (d *Deploy) readHistory(match func(*DeployRecord) bool, limit int) ([]*DeployRecord, error) {
+
+	var records []*DeployRecord
+	historyPath := d.historyPath()
+	if historyPath == "" {
+		path := filepath.Join(d.stim.ConfigGetCacheDir("deploy"), historyFile)
+		all, err := readHistoryFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read %s: %v", path, err)
+		}
+		for i := len(all) - 1; i >= 0 && len(records) < limit; i-- {
+			if match(all[i]) {
+				records = append(records, all[i])
+			}
+		}
+		return records, nil
+	}
+
+	vault, err := d.stim.NewVault()
+	if err != nil {
+		return nil, err
+	}
+	ids, err := vault.ListSecrets(historyPath)
+	if err != nil {
+		d.log.Debug("No deploy records found at {}: {}", historyPath, err)
+		return nil, nil
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+	for _, id := range ids {
+		if len(records) >= limit {
+			break
+		}
+		keys, err := vault.GetSecretKeys(historyPath + "/" + id)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading deploy record '%s': %v", id, err)
+		}
+		record := recordFromKeys(id, keys)
+		if match(record) {
+			records = append(records, record)
+		}
+	}
+
+	return records, nil
+}
+
+// appendHistory appends a record as a JSON line to the history file
+func appendHistory(path string, record *DeployRecord) error {
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	encoder := json.NewEncoder(f)
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(record)
+}
+
+// readHistoryFile reads the records of the history file, oldest first.  Lines
+// that can't be parsed are skipped.
+func readHistoryFile(path string) ([]*DeployRecord, error) {
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []*DeployRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		record := &DeployRecord{}
+		if json.Unmarshal(scanner.Bytes(), record) == nil {
+			records = append(records, record)
+		}
+	}
+
+	return records, scanner.Err()
+}
+
+// recordKeys returns the record as Vault secret keys
+func recordKeys(record *DeployRecord) map[string]string {
+	env, _ := json.Marshal(record.Env)
+	keys := map[string]string{
+		"deployment":       record.Deployment,
+		"environment":      record.Environment,
+		"instance":         record.Instance,
+		"cluster":          record.Cluster,
+		"namespace":        record.Namespace,
+		"user":             record.User,
+		"host":             record.Host,
+		"time":             record.Time.Format(time.RFC3339Nano),
+		"duration-seconds": strconv.FormatFloat(record.DurationSeconds, 'f', 3, 64),
+		"result":           record.Result,
+		"error":            record.Error,
+		"commit":           record.Commit,
+		"tag":              record.Tag,
+		"image":            record.Image,
+		"promoted-from":    record.PromotedFrom,
+		"env":              string(env),
+	}
+	if len(record.Images) > 0 {
+		images, _ := json.Marshal(record.Images)
+		keys["images"] = string(images)
+	}
+	return keys
+}
+
+// recordFromKeys returns the record stored as Vault secret keys
+func recordFromKeys(id string, keys map[string]string) *DeployRecord {
+	record := &DeployRecord{
+		ID:           id,
+		Deployment:   keys["deployment"],
+		Environment:  keys["environment"],
+		Instance:     keys["instance"],
+		Cluster:      keys["cluster"],
+		Namespace:    keys["namespace"],
+		User:         keys["user"],
+		Host:         keys["host"],
+		Result:       keys["result"],
+		Error:        keys["error"],
+		Commit:       keys["commit"],
+		Tag:          keys["tag"],
+		Image:        keys["image"],
+		PromotedFrom: keys["promoted-from"],
+	}
+	record.Time, _ = time.Parse(time.RFC3339Nano, keys["time"])
+	record.DurationSeconds, _ = strconv.ParseFloat(keys["duration-seconds"], 64)
+	if keys["images"] != "" {
+		json.Unmarshal([]byte(keys["images"]), &record.Images)
+	}
+	if keys["env"] != "" {
+		json.Unmarshal([]byte(keys["env"]), &record.Env)
+	}
+	return record
+}
+
+// shortCommit abbreviates a commit SHA for tables
+func shortCommit(commit string) string {
+	if len(commit) > 8 {
+		return commit[:8]
+	}
+	return commit
+}
+
+// formatSeconds formats a duration in seconds (ex. `1m32s`)
+func formatSeconds(seconds float64) string {
+	return (time.Duration(seconds*float64(time.Second)) / time.Second * time.Second).String()
+}
+
+// orDash returns `-` for empty table cells
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,119 @@
+package deploy
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/clock"
+	"github.com/PremiereGlobal/stim/stim"
+	v2e "github.com/PremiereGlobal/vault-to-envs/pkg/vaulttoenvs"
+	"gotest.tools/assert"
+)
+
+func TestRedactedEnv(t *testing.T) {
+	instance := &Instance{Name: "us-east", Spec: &Spec{
+		EnvironmentVars: []*EnvironmentVar{
+			{Name: "REPLICAS", Value: "3"},
+			{Name: "DB_PASSWORD", Value: "hunter2"},
+			{Name: "VAULT_TOKEN", Value: "s.token"},
+			{Name: "AWS_KEY", Value: "from-ssm"},
+		},
+		Secrets: []*SecretItem{
+			{SecretItem: v2e.SecretItem{SecretPath: "secret/app", SecretMaps: map[string]string{"API_KEY": "key"}}},
+			{SecretItem: v2e.SecretItem{SecretMaps: map[string]string{"AWS_KEY": "key"}}, AwsSsm: &AwsSsmSecret{Path: "/app"}},
+		},
+	}}
+
+	assert.DeepEqual(t, redactedEnv(instance), map[string]string{
+		"REPLICAS":    "3",
+		"DB_PASSWORD": historyRedacted,
+		"VAULT_TOKEN": historyRedacted,
+		"AWS_KEY":     historyRedacted,
+		"API_KEY":     historyRedacted,
+	})
+}
+
+func TestDeployRecordID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stim-deploy")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	start := time.Date(2020, 1, 2, 3, 4, 5, 6000000, time.UTC)
+	s := stim.New()
+	s.SetClock(clock.NewFake(start))
+	s.SetRand(rand.New(rand.NewSource(1)))
+	d := &Deploy{stim: s, log: s.GetLogger(), config: Config{configFilePath: filepath.Join(dir, "stim.deploy.yaml")}}
+	environment := &Environment{Name: "prod"}
+	instance := &Instance{Name: "us-east", Spec: &Spec{}}
+
+	// Deploys started in the same millisecond get different IDs, which still
+	// sort in time order
+	first := d.deployRecord(environment, instance, DEPLOY_METHOD_UNKNOWN, start, nil)
+	second := d.deployRecord(environment, instance, DEPLOY_METHOD_UNKNOWN, start, nil)
+	assert.Assert(t, regexp.MustCompile(`^20200102T030405\.006Z-[0-9a-f]{8}$`).MatchString(first.ID), first.ID)
+	assert.Assert(t, first.ID != second.ID)
+	assert.Assert(t, first.ID < d.deployRecord(environment, instance, DEPLOY_METHOD_UNKNOWN, start.Add(time.Millisecond), nil).ID)
+}
+
+func TestHistoryFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stim-deploy")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, historyFile)
+
+	records, err := readHistoryFile(path)
+	assert.NilError(t, err)
+	assert.Equal(t, len(records), 0)
+
+	first := &DeployRecord{ID: "20200102T030405.000Z-8f14e45f", Environment: "prod", Instance: "us-east", Result: notifySuccess, Env: map[string]string{"A": "<redacted>"}}
+	second := &DeployRecord{ID: "20200102T040405.000Z-c9f0f895", Environment: "prod", Instance: "us-west", Result: notifyFailure, Error: "boom"}
+	assert.NilError(t, appendHistory(path, first))
+	assert.NilError(t, appendHistory(path, second))
+
+	// Lines that can't be parsed are skipped
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	assert.NilError(t, err)
+	_, err = f.WriteString("{not json\n")
+	assert.NilError(t, err)
+	f.Close()
+
+	records, err = readHistoryFile(path)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, records, []*DeployRecord{first, second})
+}
+
+func TestRecordKeys(t *testing.T) {
+	record := &DeployRecord{
+		ID:              "20200102T030405.000Z-8f14e45f",
+		Deployment:      "web",
+		Environment:     "prod",
+		Instance:        "us-east",
+		Cluster:         "east",
+		User:            "jdoe",
+		Host:            "laptop",
+		Time:            time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		DurationSeconds: 92.5,
+		Result:          notifySuccess,
+		Commit:          "0123456789abcdef",
+		Tag:             "deploy/prod/us-east/2020-01-02-030405",
+		Images:          map[string]string{"APP_IMAGE": "my-org/app:1.4.0"},
+		PromotedFrom:    "20200101T030405.000Z-45c48cce",
+		Env:             map[string]string{"REPLICAS": "3"},
+	}
+
+	keys := recordKeys(record)
+	assert.Equal(t, keys["duration-seconds"], "92.500")
+	assert.Equal(t, keys["env"], `{"REPLICAS":"3"}`)
+	assert.Equal(t, keys["images"], `{"APP_IMAGE":"my-org/app:1.4.0"}`)
+	assert.DeepEqual(t, recordFromKeys(record.ID, keys), record)
+}
+
+func TestFormatSeconds(t *testing.T) {
+	assert.Equal(t, formatSeconds(92.7), "1m32s")
+	assert.Equal(t, shortCommit("0123456789abcdef"), "01234567")
+}
@@ -0,0 +1,193 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// defaultHookTimeout is how long a hook can run when no timeout is set
+const defaultHookTimeout = 5 * time.Minute
+
+// Hooks are local commands run on deploy events, for triggering systems that
+// stim doesn't integrate with.  Each event's list of hooks replaces the lists
+// of lower precedence levels.
+type Hooks struct {
+	OnStart   []*Hook `yaml:"onStart"`
+	OnSuccess []*Hook `yaml:"onSuccess"`
+	OnFailure []*Hook `yaml:"onFailure"`
+}
+
+// Hook is a command run with `sh -c` in the deployment directory.  Hooks
+// with Secrets set run in the environment of the deployment script, with the
+// Vault secrets, kubeconfig and tools of the instance.
+type Hook struct {
+	Name    string `yaml:"name"`
+	Run     string `yaml:"run"`
+	Timeout string `yaml:"timeout"`
+	Secrets bool   `yaml:"secrets"`
+}
+
+// runHooks runs the hooks of a deploy event
+func (d *Deploy) runHooks(environment *Environment, instance *Instance, event string, deployErr error) error {
+	return d.execHooks(instance, event, eventHooks(instance.Spec.Hooks, event), deployErr)
+}
+
+// execHooks runs hooks one at a time.  The deploy context is passed in
+// DEPLOY_* env vars along with the non-secret env vars of the instance, or
+// all of its env vars and secrets for hooks with `secrets`.  The first
+// failing hook stops the remaining hooks.
+func (d *Deploy) execHooks(instance *Instance, event string, hooks []*Hook, deployErr error) error {
+
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	user, err := d.stim.User()
+	if err != nil {
+		user = "unknown"
+	}
+
+	eventEnv := []string{"DEPLOY_EVENT=" + event, "DEPLOY_USER=" + user}
+	if deployErr != nil {
+		eventEnv = append(eventEnv, "DEPLOY_ERROR="+deployErr.Error())
+	}
+
+	env := os.Environ()
+	for _, e := range instance.Spec.EnvironmentVars {
+		if !isSensitiveEnvVar(instance, e.Name) {
+			env = append(env, fmt.Sprintf("%s=%s", e.Name, e.Value))
+		}
+	}
+	env = append(env, eventEnv...)
+
+	// The deploy environment is only set up (reading the Vault secrets) if a
+	// hook needs it
+	var secretEnv []string
+
+	for i, hook := range hooks {
+		name := hook.Name
+		if name == "" {
+			name = fmt.Sprintf("%s[%d]", event, i)
+		}
+
+		timeout := defaultHookTimeout
+		if hook.Timeout != "" {
+			timeout, _ = time.ParseDuration(hook.Timeout)
+		}
+
+		hookEnv := env
+		if hook.Secrets {
+			if secretEnv == nil {
+				e, err := d.instanceEnv(instance)
+				if err != nil {
+					return fmt.Errorf("Hook %s failed: %v", name, err)
+				}
+				defer e.Close()
+				secretEnv = append(append(os.Environ(), e.GetEnvVars()...), eventEnv...)
+			}
+			hookEnv = secretEnv
+		}
+
+		// Hooks run after the deploy was cancelled (ex. `on-failure` hooks)
+		// aren't stopped by the cancellation
+		parent := d.stim.Context()
+		if parent.Err() != nil {
+			parent = context.Background()
+		}
+
+		d.log.Info("Running {} hook {}", event, name)
+		ctx, cancel := context.WithTimeout(parent, timeout)
+		cmd := exec.CommandContext(ctx, "sh", "-c", hook.Run)
+		cmd.Dir = d.config.Deployment.fullDirectoryPath
+		cmd.Env = hookEnv
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		err := cmd.Run()
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("timed out after %s", timeout)
+		}
+		cancel()
+		if err != nil {
+			return fmt.Errorf("Hook %s failed: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+// eventHooks returns the hooks of a deploy event
+func eventHooks(hooks *Hooks, event string) []*Hook {
+	if hooks == nil {
+		return nil
+	}
+	switch event {
+	case notifyStart:
+		return hooks.OnStart
+	case notifySuccess:
+		return hooks.OnSuccess
+	case notifyFailure:
+		return hooks.OnFailure
+	}
+	return nil
+}
+
+// mergeHooks merges the hooks of each level, with the hooks of each event
+// taken from the instance, then the environment and then the global spec
+func mergeHooks(instance *Hooks, environment *Hooks, global *Hooks) *Hooks {
+
+	if instance == nil && environment == nil && global == nil {
+		return nil
+	}
+
+	result := &Hooks{}
+	for _, level := range []*Hooks{global, environment, instance} {
+		if level == nil {
+			continue
+		}
+		if level.OnStart != nil {
+			result.OnStart = level.OnStart
+		}
+		if level.OnSuccess != nil {
+			result.OnSuccess = level.OnSuccess
+		}
+		if level.OnFailure != nil {
+			result.OnFailure = level.OnFailure
+		}
+	}
+
+	return result
+}
+
+// validateHooks checks that each hook has a command and a valid timeout
+func validateHooks(hooks *Hooks) error {
+	if hooks == nil {
+		return nil
+	}
+	for _, event := range notifyEvents {
+		err := validateHookList(eventHooks(hooks, event))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateHookList checks that each hook of a list has a command and a valid
+// timeout
+func validateHookList(hooks []*Hook) error {
+	for _, hook := range hooks {
+		if hook.Run == "" {
+			return errors.New("`run` must be set for each hook")
+		}
+		if hook.Timeout != "" {
+			if _, err := time.ParseDuration(hook.Timeout); err != nil {
+				return fmt.Errorf("Invalid hook timeout '%s'", hook.Timeout)
+			}
+		}
+	}
+	return nil
+}
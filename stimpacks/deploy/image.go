@@ -0,0 +1,40 @@
+package deploy
+
+import (
+	"fmt"
+
+	"github.com/PremiereGlobal/stim/pkg/registry"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// checkImage checks that the tag of the deploy container exists in its
+// registry before deploying (if `deploy.check-image` is set), so a typo in
+// `deployment.container` fails before any instance deploys rather than when
+// the image is pulled
+func (d *Deploy) checkImage() error {
+
+	if !d.stim.ConfigGetBool("deploy.check-image") || d.config.Deployment.Type == deployTypeManifests || d.stim.ConfigGetString("deploy.method") == "shell" {
+		return nil
+	}
+
+	image := fmt.Sprintf("%s:%s", d.config.Deployment.Container.Repo, d.config.Deployment.Container.Tag)
+	ref, err := registry.ParseReference(image)
+	if err != nil {
+		return stim.ConfigError(fmt.Errorf("Invalid deploy container image: %v", err))
+	}
+
+	client, err := d.stim.Registry(ref.Host)
+	if err != nil {
+		return err
+	}
+	exists, err := client.ManifestExists(ref.Repo, ref.Reference())
+	if err != nil {
+		return fmt.Errorf("Unable to check the deploy container image %s: %v", image, err)
+	}
+	if !exists {
+		return stim.ConfigError(fmt.Errorf("The deploy container image %s doesn't exist.  Check `deployment.container` in the deploy config (`stim registry tags %s` lists the tags)", image, d.config.Deployment.Container.Repo))
+	}
+
+	d.log.Debug("The deploy container image {} exists", image)
+	return nil
+}
@@ -0,0 +1,76 @@
+package deploy
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// lookupEnv returns the values of the environment variables referenced in
+// deploy configs
+var lookupEnv = os.LookupEnv
+
+// envReferenceRegexp matches `${VAR}`, `$${VAR}` (an escaped reference) and
+// `{{ env "VAR" }}`
+var envReferenceRegexp = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)\}|\{\{-?\s*env\s+"([A-Za-z_][A-Za-z0-9_]*)"\s*-?\}\}`)
+
+// interpolateEnv replaces the environment variable references in the deploy
+// config with the values returned by lookup.  `$${VAR}` is replaced with a
+// literal `${VAR}` and comment lines are left as they are.  All of the unset
+// variables are returned in a single error.
+func interpolateEnv(content []byte, lookup func(string) (string, bool)) ([]byte, error) {
+
+	var missing []string
+	lines := strings.Split(string(content), "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+
+		lines[i] = envReferenceRegexp.ReplaceAllStringFunc(line, func(reference string) string {
+			if strings.HasPrefix(reference, "$$") {
+				return reference[1:]
+			}
+
+			match := envReferenceRegexp.FindStringSubmatch(reference)
+			name := match[1]
+			if name == "" {
+				name = match[2]
+			}
+
+			value, ok := lookup(name)
+			if !ok {
+				missing = append(missing, name)
+			}
+			return value
+		})
+	}
+
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("Environment variables referenced in the deploy config are not set: %s", strings.Join(missing, ", "))
+	}
+
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// escapeEnvReferences escapes the `${VAR}` references in content (ex. the
+// values of a resolved config) so that interpolateEnv leaves them as they are
+func escapeEnvReferences(content []byte) []byte {
+
+	lines := strings.Split(string(content), "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+
+		lines[i] = envReferenceRegexp.ReplaceAllStringFunc(line, func(reference string) string {
+			if strings.HasPrefix(reference, "{{") {
+				return reference
+			}
+			return "$" + reference
+		})
+	}
+
+	return []byte(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,206 @@
+package deploy
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/kubernetes"
+)
+
+const (
+	// jobBundleDir is where the deployment directory bundle is mounted in the
+	// deploy job
+	jobBundleDir  = "/stim/bundle"
+	jobBundleFile = "deploy.tar.gz"
+
+	// jobMaxBundleSize is the largest bundle that fits in a Secret (1MiB,
+	// with room for the env vars)
+	jobMaxBundleSize = 900 * 1024
+
+	// jobStartTimeout is how long the pod of the deploy job has to start,
+	// including pulling the image
+	jobStartTimeout = 10 * time.Minute
+)
+
+// jobNameInvalid matches the characters not allowed in the names of jobs
+var jobNameInvalid = regexp.MustCompile("[^a-z0-9-]+")
+
+// kubernetesEngine runs the deploy container as a Kubernetes Job in the
+// cluster of the instance, for agents that can't run containers.  The
+// deployment directory is copied to the job in a Secret, and the tool cache
+// starts empty.
+type kubernetesEngine struct {
+	deploy   *Deploy
+	instance *Instance
+}
+
+// Name is the name of the engine
+func (e *kubernetesEngine) Name() string {
+	return "Kubernetes Job"
+}
+
+// Available is always true, the access to the cluster of each instance is
+// checked when its job is created
+func (e *kubernetesEngine) Available(ctx context.Context) error {
+	return nil
+}
+
+// Login does nothing, the node that runs the job pulls the image with its own
+// credentials (ex. the IAM role of an EKS node)
+func (e *kubernetesEngine) Login(host string, username string, password string) error {
+	return nil
+}
+
+// Pull doesn't pull the image, it's pulled by the node that runs the job
+func (e *kubernetesEngine) Pull(ctx context.Context, image string) (map[string]string, error) {
+	return map[string]string{}, nil
+}
+
+// Run runs the deploy container in a job in the namespace of
+// `deploy.kubernetes.namespace`, or the namespace of the instance
+func (e *kubernetesEngine) Run(ctx context.Context, spec *containerSpec) (int, error) {
+
+	d := e.deploy
+
+	bundle, err := packDirectory(spec.DeployDir)
+	if err != nil {
+		return 0, fmt.Errorf("Unable to bundle the deployment directory: %v", err)
+	}
+	if len(bundle) > jobMaxBundleSize {
+		return 0, fmt.Errorf("The deployment directory is too large to run as a Kubernetes Job (%d KiB compressed, the limit is %d KiB)", len(bundle)/1024, jobMaxBundleSize/1024)
+	}
+
+	namespace := d.stim.ConfigGetString("deploy.kubernetes.namespace")
+	if namespace == "" {
+		namespace = instanceNamespace(e.instance)
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	kube, cleanup, err := d.instanceKubernetes(e.instance)
+	if err != nil {
+		return 0, err
+	}
+	defer cleanup()
+
+	name := jobName(e.instance.Name, d.stim.Rand().Int63())
+	d.log.Info("Running the deploy container in Job {}/{} of cluster {}", namespace, name, e.instance.Spec.Kubernetes.Cluster)
+
+	return kube.RunJob(ctx, &kubernetes.JobSpec{
+		Namespace: namespace,
+		Name:      name,
+		Labels: map[string]string{
+			"app.kubernetes.io/name":       "stim-deploy",
+			"app.kubernetes.io/instance":   jobNameInvalid.ReplaceAllString(strings.ToLower(e.instance.Name), "-"),
+			"app.kubernetes.io/managed-by": "stim",
+		},
+		Image:      spec.Image,
+		Command:    jobCommand(spec),
+		WorkingDir: spec.WorkDir,
+		Env:        envMap(spec.Env),
+		Files:      map[string][]byte{jobBundleFile: bundle},
+		FilesPath:  jobBundleDir,
+		EmptyDirs:  []string{spec.WorkDir, spec.CacheMount},
+	}, os.Stdout, jobStartTimeout)
+}
+
+// jobName returns the name of the deploy job of an instance, made unique with
+// the random suffix
+func jobName(instance string, random int64) string {
+	name := strings.Trim(jobNameInvalid.ReplaceAllString(strings.ToLower(instance), "-"), "-")
+	if len(name) > 40 {
+		name = strings.Trim(name[:40], "-")
+	}
+	suffix := fmt.Sprintf("%x", random)
+	if len(suffix) > 8 {
+		suffix = suffix[:8]
+	}
+	return fmt.Sprintf("stim-deploy-%s-%s", name, suffix)
+}
+
+// jobCommand returns the command of the deploy job, which unpacks the
+// deployment directory before running the command of the container
+func jobCommand(spec *containerSpec) []string {
+	unpack := fmt.Sprintf("tar -xzf %s/%s -C %s && exec \"$@\"", jobBundleDir, jobBundleFile, spec.WorkDir)
+	return append([]string{"/bin/sh", "-c", unpack, "stim-deploy"}, spec.Cmd...)
+}
+
+// envMap returns the `NAME=VALUE` env vars by name, later values taking
+// precedence like they do in a container
+func envMap(envs []string) map[string]string {
+	m := make(map[string]string)
+	for _, e := range envs {
+		parts := strings.SplitN(e, "=", 2)
+		if len(parts) == 2 {
+			m[parts[0]] = parts[1]
+		}
+	}
+	return m
+}
+
+// packDirectory returns the files of the directory as a gzipped tarball
+func packDirectory(dir string) ([]byte, error) {
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			link, err = os.Readlink(path)
+			if err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,334 @@
+package deploy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/PremiereGlobal/stim/pkg/kubernetes"
+	"github.com/PremiereGlobal/stim/stim"
+	"sigs.k8s.io/yaml"
+)
+
+const deployTypeManifests = "manifests"
+
+// manifestsFieldManager is the field manager of objects applied by stim
+const manifestsFieldManager = "stim"
+
+// manifestsInventoryKey is the ConfigMap key holding the applied objects
+const manifestsInventoryKey = "objects"
+
+// manifestSeparator splits a YAML stream into documents
+var manifestSeparator = regexp.MustCompile(`(?m)^---[ \t]*(#.*)?$`)
+
+// Manifests describes the Kubernetes manifests applied by the `manifests`
+// deployment type.  Path is a directory relative to the deployment directory.
+// When Prune is set, objects applied by a previous deploy that are no longer
+// in the manifests are deleted.  The applied objects are recorded in the
+// Inventory ConfigMap in the deploy namespace.
+type Manifests struct {
+	Path      string `yaml:"path"`
+	Kustomize bool   `yaml:"kustomize"`
+	Prune     bool   `yaml:"prune"`
+	Inventory string `yaml:"inventory"`
+}
+
+// applyManifests applies the manifests of the instance to its cluster with
+// server-side apply and prunes removed objects
+func (d *Deploy) applyManifests(environment *Environment, instance *Instance) error {
+
+	manifests := instance.Spec.Manifests
+	dir := filepath.Join(d.config.Deployment.fullDirectoryPath, manifests.Path)
+
+	objects, err := readManifests(dir, manifests.Kustomize)
+	if err != nil {
+		return err
+	}
+
+	namespace := ""
+	for _, e := range instance.Spec.EnvironmentVars {
+		if e.Name == "DEPLOY_NAMESPACE" {
+			namespace = e.Value
+		}
+	}
+
+	tmpDir, err := ioutil.TempDir("", "stim-deploy")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	kc, err := d.stim.KubeConfigFromVault(&stim.KubeConfigOptions{
+		Cluster:        instance.Spec.Kubernetes.Cluster,
+		ServiceAccount: instance.Spec.Kubernetes.ServiceAccount,
+		Path:           filepath.Join(tmpDir, "kubeconfig"),
+	})
+	if err != nil {
+		return err
+	}
+
+	kube, err := kubernetes.New(kc)
+	if err != nil {
+		return err
+	}
+
+	var applied []kubernetes.ObjectRef
+	for _, object := range objects {
+		ref, err := kube.ApplyObject(object, namespace, manifestsFieldManager)
+		if err != nil {
+			return err
+		}
+		d.log.Info("Applied {}", ref)
+		applied = append(applied, ref)
+	}
+
+	inventory := manifestsInventory(environment, instance)
+
+	if manifests.Prune {
+		previous, err := inventoryObjects(kube, namespace, inventory)
+		if err != nil {
+			return err
+		}
+		for _, ref := range pruneObjects(previous, applied) {
+			err := kube.DeleteObject(ref)
+			if err != nil {
+				return err
+			}
+			d.log.Info("Pruned {}", ref)
+		}
+	}
+
+	inventoryData, err := json.Marshal(applied)
+	if err != nil {
+		return err
+	}
+	labels := map[string]string{
+		"app.kubernetes.io/managed-by": "stim",
+		"stim.deploy/environment":      environment.Name,
+		"stim.deploy/instance":         instance.Name,
+	}
+
+	return kube.ApplyConfigMap(namespace, inventory, map[string]string{manifestsInventoryKey: string(inventoryData)}, labels)
+}
+
+// manifestsInventory returns the name of the inventory ConfigMap of an
+// instance
+func manifestsInventory(environment *Environment, instance *Instance) string {
+	if instance.Spec.Manifests.Inventory != "" {
+		return instance.Spec.Manifests.Inventory
+	}
+	return fmt.Sprintf("stim-manifests-%s-%s", environment.Name, instance.Name)
+}
+
+// inventoryObjects returns the objects applied by the previous deploy from
+// the inventory ConfigMap
+func inventoryObjects(kube *kubernetes.Kubernetes, namespace string, inventory string) ([]kubernetes.ObjectRef, error) {
+
+	data, err := kube.GetConfigMapData(namespace, inventory)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading manifests inventory: %v", err)
+	}
+
+	var previous []kubernetes.ObjectRef
+	if data[manifestsInventoryKey] != "" {
+		err = json.Unmarshal([]byte(data[manifestsInventoryKey]), &previous)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing manifests inventory: %v", err)
+		}
+	}
+
+	return previous, nil
+}
+
+// readManifests returns the objects in the manifests directory, or the output
+// of `kustomize build` when kustomize is set.  Namespaces and custom resource
+// definitions are ordered first so the objects that depend on them can be
+// applied.
+func readManifests(dir string, kustomize bool) ([]map[string]interface{}, error) {
+
+	var documents []string
+	if kustomize {
+		out, err := kustomizeBuild(dir)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, out)
+	} else {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading manifests directory: %v", err)
+		}
+		for _, f := range files {
+			ext := strings.ToLower(filepath.Ext(f.Name()))
+			if f.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+				continue
+			}
+			b, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+			if err != nil {
+				return nil, err
+			}
+			documents = append(documents, string(b))
+		}
+	}
+
+	var objects []map[string]interface{}
+	for _, document := range documents {
+		parsed, err := parseManifests(document)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, parsed...)
+	}
+
+	sort.SliceStable(objects, func(i, j int) bool {
+		return manifestOrder(objects[i]) < manifestOrder(objects[j])
+	})
+
+	return objects, nil
+}
+
+// kustomizeBuild runs `kustomize build`, falling back to `kubectl kustomize`
+// if kustomize is not installed
+func kustomizeBuild(dir string) (string, error) {
+
+	var cmd *exec.Cmd
+	if _, err := exec.LookPath("kustomize"); err == nil {
+		cmd = exec.Command("kustomize", "build", dir)
+	} else {
+		cmd = exec.Command("kubectl", "kustomize", dir)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("Error running kustomize: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return string(out), nil
+}
+
+// parseManifests splits a YAML stream into objects, expanding `List` kinds
+// and skipping empty documents
+func parseManifests(content string) ([]map[string]interface{}, error) {
+
+	var objects []map[string]interface{}
+	for _, document := range manifestSeparator.Split(content, -1) {
+		if strings.TrimSpace(document) == "" {
+			continue
+		}
+
+		var object map[string]interface{}
+		err := yaml.Unmarshal([]byte(document), &object)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing manifest: %v", err)
+		}
+		if object == nil {
+			continue
+		}
+
+		if kind, _ := object["kind"].(string); strings.HasSuffix(kind, "List") {
+			items, _ := object["items"].([]interface{})
+			for _, item := range items {
+				if itemObject, ok := item.(map[string]interface{}); ok {
+					objects = append(objects, itemObject)
+				}
+			}
+			continue
+		}
+
+		objects = append(objects, object)
+	}
+
+	return objects, nil
+}
+
+// manifestOrder returns the apply order of an object's kind
+func manifestOrder(object map[string]interface{}) int {
+	switch object["kind"] {
+	case "Namespace":
+		return 0
+	case "CustomResourceDefinition":
+		return 1
+	default:
+		return 2
+	}
+}
+
+// pruneObjects returns the previously applied objects that were not applied
+// this time, in reverse order
+func pruneObjects(previous []kubernetes.ObjectRef, applied []kubernetes.ObjectRef) []kubernetes.ObjectRef {
+
+	current := make(map[kubernetes.ObjectRef]bool)
+	for _, ref := range applied {
+		current[ref] = true
+	}
+
+	var prune []kubernetes.ObjectRef
+	for i := len(previous) - 1; i >= 0; i-- {
+		if !current[previous[i]] {
+			prune = append(prune, previous[i])
+		}
+	}
+
+	return prune
+}
+
+// mergeManifests merges the manifests settings of each level field by field,
+// with the instance taking precedence over the environment and then the
+// global spec
+func mergeManifests(instance *Manifests, environment *Manifests, global *Manifests) *Manifests {
+
+	if instance == nil && environment == nil && global == nil {
+		return nil
+	}
+
+	result := &Manifests{}
+	for _, level := range []*Manifests{global, environment, instance} {
+		if level == nil {
+			continue
+		}
+		if level.Path != "" {
+			result.Path = level.Path
+		}
+		if level.Inventory != "" {
+			result.Inventory = level.Inventory
+		}
+		result.Kustomize = result.Kustomize || level.Kustomize
+		result.Prune = result.Prune || level.Prune
+	}
+
+	return result
+}
+
+// validateManifests checks that the manifests path is within the deployment
+// directory
+func validateManifests(manifests *Manifests) error {
+	if manifests == nil || manifests.Path == "" {
+		return nil
+	}
+	if filepath.IsAbs(manifests.Path) || strings.HasPrefix(filepath.Clean(manifests.Path), "..") {
+		return fmt.Errorf("Manifests path '%s' must be relative to the deployment directory", manifests.Path)
+	}
+	return nil
+}
+
+// resolveManifests checks that a path is set for the `manifests` deployment
+// type
+func resolveManifests(deploymentType string, instance *Instance) error {
+	if deploymentType != deployTypeManifests {
+		return nil
+	}
+	if instance.Spec.Manifests == nil || instance.Spec.Manifests.Path == "" {
+		return errors.New("`spec.manifests.path` must be set for the `manifests` deployment type")
+	}
+	return nil
+}
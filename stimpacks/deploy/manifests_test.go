@@ -0,0 +1,44 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/PremiereGlobal/stim/pkg/kubernetes"
+	"gotest.tools/assert"
+)
+
+func TestParseManifests(t *testing.T) {
+	objects, err := parseManifests(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: first
+--- # comment
+---
+apiVersion: v1
+kind: List
+items:
+  - apiVersion: v1
+    kind: Service
+    metadata:
+      name: second
+  - apiVersion: v1
+    kind: Namespace
+    metadata:
+      name: third
+`)
+	assert.NilError(t, err)
+	assert.Equal(t, len(objects), 3)
+	assert.Equal(t, objects[1]["kind"], "Service")
+	assert.Equal(t, objects[2]["kind"], "Namespace")
+	assert.Equal(t, manifestOrder(objects[2]), 0)
+}
+
+func TestPruneObjects(t *testing.T) {
+	a := kubernetes.ObjectRef{APIVersion: "v1", Kind: "Service", Namespace: "ns", Name: "a"}
+	b := kubernetes.ObjectRef{APIVersion: "v1", Kind: "ConfigMap", Namespace: "ns", Name: "b"}
+	c := kubernetes.ObjectRef{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "ns", Name: "c"}
+
+	prune := pruneObjects([]kubernetes.ObjectRef{a, b, c}, []kubernetes.ObjectRef{b})
+	assert.DeepEqual(t, prune, []kubernetes.ObjectRef{c, a})
+}
@@ -0,0 +1,589 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/PremiereGlobal/stim/pkg/utils"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// The levels of the deploy config that a resolved value can come from
+const (
+	originGlobal      = "global"
+	originEnvironment = "environment"
+	originInstance    = "instance"
+)
+
+// specLevel is a spec along with the config level it was set at
+type specLevel struct {
+	origin string
+	spec   *Spec
+}
+
+// resolveConfig sets defaults, validates the config and merges the global and
+// environment specs into each instance spec.  It has no side effects outside
+// of the config so it can be used without Vault (i.e. `stim deploy explain`).
+func resolveConfig(config *Config) error {
+
+	// Set defaults
+	setConfigDefault(&config.Deployment.Container.Repo, defaultContainerRepo)
+	setConfigDefault(&config.Deployment.Container.Tag, defaultContainerTag)
+	setConfigDefault(&config.Deployment.Directory, defaultDeployDirectory)
+	setConfigDefault(&config.Deployment.Shell, deployShellSh)
+	if config.Deployment.Shell == deployShellPowerShell {
+		setConfigDefault(&config.Deployment.Script, defaultPowerShellScript)
+	}
+	setConfigDefault(&config.Deployment.Script, defaultDeployScript)
+	setConfigDefault(&config.Deployment.Type, deployTypeScript)
+
+	if config.Deployment.Type != deployTypeScript && config.Deployment.Type != deployTypeHelm && config.Deployment.Type != deployTypeManifests {
+		return fmt.Errorf("Invalid deployment type '%s'.  Must be one of ['%s','%s','%s']", config.Deployment.Type, deployTypeScript, deployTypeHelm, deployTypeManifests)
+	}
+
+	if config.Deployment.Shell != deployShellSh && config.Deployment.Shell != deployShellPowerShell {
+		return fmt.Errorf("Invalid deployment shell '%s'.  Must be one of ['%s','%s']", config.Deployment.Shell, deployShellSh, deployShellPowerShell)
+	}
+	if config.Deployment.Shell == deployShellPowerShell && config.Deployment.Type != deployTypeScript {
+		return fmt.Errorf("The `%s` shell is only supported for the `%s` deployment type", deployShellPowerShell, deployTypeScript)
+	}
+
+	// Create our global spec if it doesn't exist so we don't have to keep checking if it exists
+	if config.Global.Spec == nil {
+		config.Global.Spec = &Spec{}
+	}
+
+	err := validateSpec(config.Global.Spec)
+	if err != nil {
+		return err
+	}
+
+	err = validateNotifications(config.Notifications)
+	if err != nil {
+		return err
+	}
+
+	err = validateFreezes(config.Freezes)
+	if err != nil {
+		return err
+	}
+
+	config.environmentMap = make(map[string]int)
+	for i, environment := range config.Environments {
+
+		// Check to make sure that we don't have multiple environments with the same name
+		if _, ok := config.environmentMap[environment.Name]; ok {
+			return fmt.Errorf("Error parsing config, duplicate environment name `%s` found", environment.Name)
+		}
+
+		// Ensure there are instances for this environment
+		if len(environment.Instances) <= 0 {
+			return fmt.Errorf("No instances found for environment: `%s`", environment.Name)
+		}
+
+		config.environmentMap[environment.Name] = i
+
+		// Create our environment spec if it doesn't exist so we don't have to keep checking if it exists
+		if environment.Spec == nil {
+			environment.Spec = &Spec{}
+		}
+
+		err = validateSpec(environment.Spec)
+		if err != nil {
+			return err
+		}
+
+		err = validateNotifications(environment.Notifications)
+		if err != nil {
+			return fmt.Errorf("%v for environment '%s'", err, environment.Name)
+		}
+		environment.Notifications = mergeNotifications(config.Notifications, environment.Notifications)
+
+		err = validateRelease(environment.Release)
+		if err != nil {
+			return fmt.Errorf("%v for environment '%s'", err, environment.Name)
+		}
+
+		err = validateGithubDeployment(environment.Github)
+		if err != nil {
+			return fmt.Errorf("%v for environment '%s'", err, environment.Name)
+		}
+
+		err = validateDatadogDeploy(environment.Datadog)
+		if err != nil {
+			return fmt.Errorf("%v for environment '%s'", err, environment.Name)
+		}
+
+		err = validatePolicy(environment.Policy)
+		if err != nil {
+			return fmt.Errorf("%v for environment '%s'", err, environment.Name)
+		}
+
+		environment.instanceMap = make(map[string]int)
+		for j, instance := range environment.Instances {
+
+			// Check to make sure that we don't have multiple instances with the same name
+			if _, ok := environment.instanceMap[instance.Name]; ok {
+				return fmt.Errorf("Error parsing config, duplicate instance name '%s' for environment '%s'", instance.Name, environment.Name)
+			}
+
+			// Ensure the instance name does not conflict with the ALL option name.  This is a reserved name for designating a deployment to all instances in an environment via the manual prompt list
+			if strings.ToLower(instance.Name) == strings.ToLower(allOptionPrompt) || strings.ToLower(instance.Name) == strings.ToLower(allOptionCli) {
+				return fmt.Errorf("Deployment config cannot have an instance named '%s'. It is a reserved name.", instance.Name)
+			}
+
+			environment.instanceMap[instance.Name] = j
+
+			// Create our instance spec if it doesn't exist so we don't have to keep checking if it exists
+			if instance.Spec == nil {
+				instance.Spec = &Spec{}
+			}
+
+			err = validateSpec(instance.Spec)
+			if err != nil {
+				return err
+			}
+
+			instance.origins, err = resolveSpec(config.Global.Spec, environment.Spec, instance.Spec)
+			if err != nil {
+				return fmt.Errorf("%v for instance '%s' in environment '%s'", err, instance.Name, environment.Name)
+			}
+
+			err = resolveHelm(config.Deployment.Type, instance)
+			if err != nil {
+				return fmt.Errorf("%v for instance '%s' in environment '%s'", err, instance.Name, environment.Name)
+			}
+
+			err = resolveManifests(config.Deployment.Type, instance)
+			if err != nil {
+				return fmt.Errorf("%v for instance '%s' in environment '%s'", err, instance.Name, environment.Name)
+			}
+		}
+
+		err = validateStrategy(environment)
+		if err != nil {
+			return fmt.Errorf("%v for environment '%s'", err, environment.Name)
+		}
+	}
+
+	return nil
+}
+
+// resolveSpec merges the global and environment specs into the instance spec.
+// Instance-level specs take precedence, followed by environment-level then
+// global-level.  It returns the level each merged value came from.
+func resolveSpec(global *Spec, environment *Spec, instance *Spec) (map[string]string, error) {
+
+	origins := make(map[string]string)
+
+	// Levels are in increasing precedence so later origins overwrite earlier ones
+	levels := []specLevel{
+		{origin: originGlobal, spec: global},
+		{origin: originEnvironment, spec: environment},
+		{origin: originInstance, spec: instance},
+	}
+
+	for _, level := range levels {
+		if level.spec.Kubernetes.Cluster != "" {
+			origins["kubernetes.cluster"] = level.origin
+		}
+		if level.spec.Kubernetes.ServiceAccount != "" {
+			origins["kubernetes.serviceAccount"] = level.origin
+		}
+		if level.spec.Kubernetes.Namespace != "" {
+			origins["kubernetes.namespace"] = level.origin
+		}
+		if level.spec.ConfigMap != nil {
+			origins["configMap"] = level.origin
+		}
+		if level.spec.HealthChecks != nil {
+			origins["healthChecks"] = level.origin
+		}
+		if level.spec.Rotate != nil {
+			origins["rotate"] = level.origin
+		}
+		if level.spec.Helm != nil {
+			if level.spec.Helm.Chart != "" {
+				origins["helm.chart"] = level.origin
+			}
+			if level.spec.Helm.Release != "" {
+				origins["helm.release"] = level.origin
+			}
+			if level.spec.Helm.ValuesFiles != nil {
+				origins["helm.valuesFiles"] = level.origin
+			}
+		}
+		if level.spec.Hooks != nil {
+			if level.spec.Hooks.OnStart != nil {
+				origins["hooks.onStart"] = level.origin
+			}
+			if level.spec.Hooks.OnSuccess != nil {
+				origins["hooks.onSuccess"] = level.origin
+			}
+			if level.spec.Hooks.OnFailure != nil {
+				origins["hooks.onFailure"] = level.origin
+			}
+		}
+		if level.spec.Manifests != nil {
+			if level.spec.Manifests.Path != "" {
+				origins["manifests.path"] = level.origin
+			}
+			if level.spec.Manifests.Prune {
+				origins["manifests.prune"] = level.origin
+			}
+		}
+		for _, t := range level.spec.Templates {
+			origins["templates."+t.Output] = level.origin
+		}
+		for _, image := range level.spec.Images {
+			origins["images."+image.Name] = level.origin
+		}
+		for name, tool := range level.spec.Tools {
+			if tool.Unset {
+				delete(origins, "tools."+name)
+			} else {
+				origins["tools."+name] = level.origin
+			}
+		}
+		for _, e := range level.spec.EnvironmentVars {
+			origins["env."+e.Name] = level.origin
+		}
+	}
+
+	if instance.Kubernetes.ServiceAccount == "" {
+		if environment.Kubernetes.ServiceAccount != "" {
+			instance.Kubernetes.ServiceAccount = environment.Kubernetes.ServiceAccount
+		} else if global.Kubernetes.ServiceAccount != "" {
+			instance.Kubernetes.ServiceAccount = global.Kubernetes.ServiceAccount
+		} else {
+			return nil, errors.New("Kubernetes service account is not set")
+		}
+	}
+	if instance.Kubernetes.Cluster == "" {
+		if environment.Kubernetes.Cluster != "" {
+			instance.Kubernetes.Cluster = environment.Kubernetes.Cluster
+		} else if global.Kubernetes.Cluster != "" {
+			instance.Kubernetes.Cluster = global.Kubernetes.Cluster
+		} else {
+			return nil, errors.New("Kubernetes cluster is not set")
+		}
+	}
+	if instance.Kubernetes.Namespace == "" {
+		if environment.Kubernetes.Namespace != "" {
+			instance.Kubernetes.Namespace = environment.Kubernetes.Namespace
+		} else {
+			instance.Kubernetes.Namespace = global.Kubernetes.Namespace
+		}
+	}
+
+	if instance.ConfigMap == nil {
+		if environment.ConfigMap != nil {
+			instance.ConfigMap = environment.ConfigMap
+		} else {
+			instance.ConfigMap = global.ConfigMap
+		}
+	}
+
+	if instance.HealthChecks == nil {
+		if environment.HealthChecks != nil {
+			instance.HealthChecks = environment.HealthChecks
+		} else {
+			instance.HealthChecks = global.HealthChecks
+		}
+	}
+
+	if instance.Rotate == nil {
+		if environment.Rotate != nil {
+			instance.Rotate = environment.Rotate
+		} else {
+			instance.Rotate = global.Rotate
+		}
+	}
+
+	instance.Helm = mergeHelm(instance.Helm, environment.Helm, global.Helm)
+	instance.Manifests = mergeManifests(instance.Manifests, environment.Manifests, global.Manifests)
+	instance.Hooks = mergeHooks(instance.Hooks, environment.Hooks, global.Hooks)
+	instance.Tools = mergeTools(instance.Tools, environment.Tools, global.Tools)
+	instance.Templates = mergeTemplates(instance.Templates, environment.Templates, global.Templates)
+	instance.Images = mergeImages(instance.Images, environment.Images, global.Images)
+	instance.EnvironmentVars = mergeEnvVars(instance.EnvironmentVars, environment.EnvironmentVars, global.EnvironmentVars)
+	var secretOrigins []string
+	instance.Secrets, secretOrigins = mergeSecrets(instance.Secrets, environment.Secrets, global.Secrets)
+	for i, origin := range secretOrigins {
+		origins[fmt.Sprintf("secrets[%d]", i)] = origin
+	}
+
+	return origins, nil
+}
+
+// validateSpec validates fields in a config 'spec' section to ensure that it
+// meets all requirements
+func validateSpec(spec *Spec) error {
+	if spec.ConfigMap != nil && (spec.ConfigMap.Name == "" || spec.ConfigMap.Namespace == "") {
+		return errors.New("Both `name` and `namespace` must be set in the `spec.configMap` config")
+	}
+	err := validateEnvVars(spec.EnvironmentVars)
+	if err != nil {
+		return err
+	}
+	err = validateTemplates(spec.Templates)
+	if err != nil {
+		return err
+	}
+	err = validateHelm(spec.Helm)
+	if err != nil {
+		return err
+	}
+	err = validateManifests(spec.Manifests)
+	if err != nil {
+		return err
+	}
+	err = validateHooks(spec.Hooks)
+	if err != nil {
+		return err
+	}
+	err = validateHealthChecks(spec.HealthChecks)
+	if err != nil {
+		return err
+	}
+	err = validateImages(spec.Images)
+	if err != nil {
+		return err
+	}
+	err = validateRotate(spec.Rotate)
+	if err != nil {
+		return err
+	}
+	for _, secret := range spec.Secrets {
+		if secret.AwsSecretsManager != nil && secret.AwsSsm != nil {
+			return errors.New("Only one of `awsSecretsManager` and `awsSsm` can be set for a secret")
+		}
+		if secret.AwsSecretsManager != nil && secret.AwsSecretsManager.SecretID == "" {
+			return errors.New("`secretId` must be set for `awsSecretsManager` secrets")
+		}
+		if !secret.isVault() && secret.SecretPath != "" {
+			return fmt.Errorf("`secretPath` cannot be used with AWS secrets, found '%s'", secret.SecretPath)
+		}
+		if secret.Version != float64(int(secret.Version)) {
+			return fmt.Errorf("Secret version for '%s' must be a whole number, got %v", secret.SecretPath, secret.Version)
+		}
+	}
+	return nil
+}
+
+// validateNotifications validates a 'notifications' section
+func validateNotifications(notifications *Notifications) error {
+	if notifications == nil {
+		return nil
+	}
+
+	var events []string
+	if notifications.Slack != nil {
+		if len(notifications.Slack.Channels) == 0 {
+			return errors.New("At least one channel must be set in the `notifications.slack` config")
+		}
+		events = append(events, notifications.Slack.Events...)
+	}
+	if notifications.Pagerduty != nil {
+		if len(notifications.Pagerduty.Services) == 0 {
+			return errors.New("At least one service must be set in the `notifications.pagerduty` config")
+		}
+		events = append(events, notifications.Pagerduty.Events...)
+	}
+	for _, backend := range notifications.Backends {
+		if backend.Name == "" || backend.Type == "" {
+			return errors.New("Both `name` and `type` must be set in the `notifications.backends` config")
+		}
+		events = append(events, backend.Events...)
+	}
+	for _, event := range events {
+		if !utils.Contains(notifyEvents, event) {
+			return fmt.Errorf("Invalid notification event '%s'. Valid values are: [%s]", event, strings.Join(notifyEvents, ","))
+		}
+	}
+	return nil
+}
+
+// validateRelease validates a 'release' section and sets its defaults
+func validateRelease(release *Release) error {
+	if release == nil {
+		return nil
+	}
+
+	if release.Tag == "" {
+		release.Tag = defaultReleaseTag
+	}
+	if release.Remote == "" {
+		release.Remote = "origin"
+	}
+	if release.Github != nil && release.Gitlab != nil {
+		return errors.New("Only one of `github` or `gitlab` can be set in the `release` config")
+	}
+	if release.Github != nil && release.Github.Repo == "" {
+		return errors.New("`repo` must be set in the `release.github` config")
+	}
+	if release.Gitlab != nil && release.Gitlab.Project == "" {
+		return errors.New("`project` must be set in the `release.gitlab` config")
+	}
+	if (release.Github != nil || release.Gitlab != nil) && release.SkipPush {
+		return errors.New("`skipPush` cannot be set with a `github` or `gitlab` release as the tag must exist on the remote")
+	}
+
+	return nil
+}
+
+// mergeNotifications is used to merge the global and environment notifications.
+// Each environment-level setting (slack, pagerduty, backends) replaces the global one.
+func mergeNotifications(global *Notifications, environment *Notifications) *Notifications {
+
+	result := &Notifications{}
+	if global != nil {
+		*result = *global
+	}
+
+	if environment != nil {
+		if environment.Name != "" {
+			result.Name = environment.Name
+		}
+		if environment.Slack != nil {
+			result.Slack = environment.Slack
+		}
+		if environment.Pagerduty != nil {
+			result.Pagerduty = environment.Pagerduty
+		}
+		if environment.Backends != nil {
+			result.Backends = environment.Backends
+		}
+		result.Disabled = environment.Disabled
+	}
+
+	if result.Disabled || (result.Slack == nil && result.Pagerduty == nil && len(result.Backends) == 0) {
+		return nil
+	}
+
+	return result
+}
+
+// mergeEnvVars is used to merge environment variable configuration at the various levels it can be set at
+func mergeEnvVars(instance []*EnvironmentVar, environment []*EnvironmentVar, global []*EnvironmentVar) []*EnvironmentVar {
+
+	result := instance
+
+	// Add environment envVars (if they don't already exist)
+	for _, e := range environment {
+		exists := false
+		for _, inst := range result {
+			if inst.Name == e.Name {
+				exists = true
+			}
+		}
+
+		// Add the item if it doesn't exist
+		if !exists {
+			result = append(result, e)
+		}
+	}
+
+	// Add global envVars (if they don't already exist)
+	for _, g := range global {
+		exists := false
+		for _, inst := range result {
+			if inst.Name == g.Name {
+				exists = true
+			}
+		}
+
+		// Add the item if it doesn't exist
+		if !exists {
+			result = append(result, g)
+		}
+	}
+
+	return result
+}
+
+// mergeSecrets is used to merge secret configs at the various levels they can
+// be set at.  Secrets from all sources (Vault and AWS) share one namespace of
+// environment variable names, so a name set at a higher precedence level
+// (instance, then environment, then global) removes it from the lower level
+// secrets.  Within a level, later secrets take precedence.  Secrets left with
+// no names are dropped.  It returns the level each merged secret came from.
+func mergeSecrets(instance []*SecretItem, environment []*SecretItem, global []*SecretItem) ([]*SecretItem, []string) {
+
+	levels := []struct {
+		origin  string
+		secrets []*SecretItem
+	}{
+		{originGlobal, global},
+		{originEnvironment, environment},
+		{originInstance, instance},
+	}
+
+	// Walk the secrets from highest to lowest precedence, claiming names
+	var result []*SecretItem
+	var origins []string
+	claimed := make(map[string]bool)
+	for l := len(levels) - 1; l >= 0; l-- {
+		for i := len(levels[l].secrets) - 1; i >= 0; i-- {
+			secret := levels[l].secrets[i]
+
+			maps := make(map[string]string)
+			for name, key := range secret.SecretMaps {
+				if !claimed[name] {
+					maps[name] = key
+					claimed[name] = true
+				}
+			}
+			if len(maps) == 0 {
+				continue
+			}
+
+			// Copy so that filtering doesn't modify secrets shared by other instances
+			merged := *secret
+			merged.SecretMaps = maps
+			result = append([]*SecretItem{&merged}, result...)
+			origins = append([]string{levels[l].origin}, origins...)
+		}
+	}
+
+	return result, origins
+}
+
+// mergeTools is used to merge tool configurations
+func mergeTools(instance map[string]stim.EnvTool, environment map[string]stim.EnvTool, global map[string]stim.EnvTool) map[string]stim.EnvTool {
+
+	result := make(map[string]stim.EnvTool)
+
+	// Set Global tools
+	for k, v := range global {
+		result[k] = v
+	}
+
+	// Overwrite with instance tools
+	for k, v := range environment {
+		if v.Unset == true {
+			delete(result, k)
+		} else {
+			result[k] = v
+		}
+	}
+
+	// Overwrite with instance tools
+	for k, v := range instance {
+		if v.Unset == true {
+			delete(result, k)
+		} else {
+			result[k] = v
+		}
+	}
+
+	return result
+}
+
+// setConfigDefault is used to set a default value (if it doesn't exist)
+func setConfigDefault(value *string, def string) {
+	if len(*value) == 0 {
+		*value = def
+	}
+}
@@ -0,0 +1,145 @@
+package deploy
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+	"gotest.tools/assert"
+)
+
+// Run `go test ./stimpacks/deploy/ -update` to regenerate the golden files
+var update = flag.Bool("update", false, "update the .golden files in testdata")
+
+// resolvedConfig is the golden file format of a resolved deploy config
+type resolvedConfig struct {
+	Error      string             `yaml:"error,omitempty"`
+	Deployment *Deployment        `yaml:"deployment,omitempty"`
+	Instances  []resolvedInstance `yaml:"instances,omitempty"`
+}
+
+// resolvedInstance is the golden file format of a resolved instance
+type resolvedInstance struct {
+	Environment   string            `yaml:"environment"`
+	Instance      string            `yaml:"instance"`
+	Spec          *Spec             `yaml:"spec"`
+	Origins       map[string]string `yaml:"origins"`
+	Explain       []string          `yaml:"explain"`
+	Notifications *Notifications    `yaml:"notifications,omitempty"`
+}
+
+// testOptions are test-only top-level keys of the testdata.  `testEnv` are
+// the environment variables used for interpolation and `testPreview`
+// instantiates a preview environment before resolving the config.
+type testOptions struct {
+	TestEnv     map[string]string `yaml:"testEnv"`
+	TestPreview *struct {
+		Name   string            `yaml:"name"`
+		Params map[string]string `yaml:"params"`
+	} `yaml:"testPreview"`
+}
+
+func resolveTestdata(t *testing.T, path string) []byte {
+	b, err := ioutil.ReadFile(path)
+	assert.NilError(t, err)
+
+	options := testOptions{}
+	err = yaml.Unmarshal(b, &options)
+	assert.NilError(t, err)
+
+	result := resolvedConfig{}
+	b, err = interpolateEnv(b, func(name string) (string, bool) {
+		value, ok := options.TestEnv[name]
+		return value, ok
+	})
+	if err != nil {
+		result.Error = err.Error()
+		out, err := yaml.Marshal(result)
+		assert.NilError(t, err)
+		return out
+	}
+
+	config := Config{}
+	err = yaml.Unmarshal(b, &config)
+	assert.NilError(t, err)
+
+	err = extendConfig(&config, path)
+	if err != nil {
+		result.Error = err.Error()
+		out, err := yaml.Marshal(result)
+		assert.NilError(t, err)
+		return out
+	}
+
+	if options.TestPreview != nil {
+		var environment *Environment
+		environment, err = newPreviewEnvironment(config.Previews, options.TestPreview.Name, options.TestPreview.Params)
+		if err == nil {
+			config.Environments = append(config.Environments, environment)
+		}
+	}
+	if err == nil {
+		err = resolveConfig(&config)
+	}
+	if err != nil {
+		result.Error = err.Error()
+	} else {
+		result.Deployment = &config.Deployment
+		for _, environment := range config.Environments {
+			for _, instance := range environment.Instances {
+				var explain []string
+				for _, row := range explainInstance(instance) {
+					explain = append(explain, row.Field+" = "+row.Value+" ("+row.Origin+")")
+				}
+				result.Instances = append(result.Instances, resolvedInstance{
+					Environment:   environment.Name,
+					Instance:      instance.Name,
+					Spec:          instance.Spec,
+					Origins:       instance.origins,
+					Explain:       explain,
+					Notifications: environment.Notifications,
+				})
+			}
+		}
+	}
+
+	out, err := yaml.Marshal(result)
+	assert.NilError(t, err)
+	return out
+}
+
+func TestResolveConfig(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.yaml"))
+	assert.NilError(t, err)
+	assert.Assert(t, len(files) > 0, "No testdata found")
+
+	for _, file := range files {
+		file := file
+		t.Run(strings.TrimSuffix(filepath.Base(file), ".yaml"), func(t *testing.T) {
+			actual := resolveTestdata(t, file)
+
+			goldenFile := strings.TrimSuffix(file, ".yaml") + ".golden"
+			if *update {
+				err := ioutil.WriteFile(goldenFile, actual, 0644)
+				assert.NilError(t, err)
+			}
+
+			expected, err := ioutil.ReadFile(goldenFile)
+			assert.NilError(t, err)
+			assert.Equal(t, string(expected), string(actual))
+		})
+	}
+}
+
+func TestResolveConfigIsRepeatable(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.yaml"))
+	assert.NilError(t, err)
+
+	// Resolving must not depend on anything outside of the config file
+	for _, file := range files {
+		assert.Equal(t, string(resolveTestdata(t, file)), string(resolveTestdata(t, file)), file)
+	}
+}
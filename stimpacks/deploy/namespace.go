@@ -0,0 +1,35 @@
+package deploy
+
+// defaultNamespace is used when the namespace is not set in the spec or the
+// kube-config secret of the cluster
+const defaultNamespace = "default"
+
+// addNamespace adds the DEPLOY_NAMESPACE env var.  If `kubernetes.namespace`
+// is not set in the spec, the `default-namespace` of the cluster's
+// kube-config secret in Vault is used.  Nothing is done if the instance
+// already has it.
+func (d *Deploy) addNamespace(instance *Instance) {
+
+	for _, e := range instance.Spec.EnvironmentVars {
+		if e.Name == "DEPLOY_NAMESPACE" {
+			return
+		}
+	}
+
+	namespace := instance.Spec.Kubernetes.Namespace
+	if namespace == "" {
+		secretPath := d.stim.KubeConfigSecretPath(instance.Spec.Kubernetes.Cluster, instance.Spec.Kubernetes.ServiceAccount)
+		var value string
+		vault, err := d.stim.NewVault()
+		if err == nil {
+			value, err = vault.GetSecretKey(secretPath, "default-namespace")
+		}
+		if err != nil || value == "" {
+			d.log.Debug("No default namespace found for cluster '{}', using '{}'", instance.Spec.Kubernetes.Cluster, defaultNamespace)
+			value = defaultNamespace
+		}
+		namespace = value
+	}
+
+	instance.Spec.EnvironmentVars = append(instance.Spec.EnvironmentVars, &EnvironmentVar{Name: "DEPLOY_NAMESPACE", Value: namespace})
+}
@@ -0,0 +1,178 @@
+package deploy
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/PremiereGlobal/stim/pkg/notify"
+)
+
+// Deploy events that notifications can be sent for.  Events are sent to the
+// notification backends as `deploy.<event>`.
+const (
+	notifyStart   = "start"
+	notifySuccess = "success"
+	notifyFailure = "failure"
+
+	// notifyFreezeOverride is sent when a deploy freeze is overridden
+	notifyFreezeOverride = "freeze-override"
+
+	// Sent by `stim deploy rotate-secrets` once the health checks pass or
+	// the rotation fails
+	notifyRotateSuccess = "rotate-success"
+	notifyRotateFailure = "rotate-failure"
+)
+
+var notifyEvents = []string{notifyStart, notifySuccess, notifyFailure, notifyFreezeOverride, notifyRotateSuccess, notifyRotateFailure}
+
+// notifyStatuses are the payload statuses of each event
+var notifyStatuses = map[string]string{
+	notifyStart:   notify.StatusInfo,
+	notifySuccess: notify.StatusSuccess,
+	notifyFailure: notify.StatusFailure,
+
+	notifyFreezeOverride: notify.StatusFailure,
+	notifyRotateSuccess:  notify.StatusSuccess,
+	notifyRotateFailure:  notify.StatusFailure,
+}
+
+// notify sends the deploy event to the notification backends configured for
+// the environment and in the stim config.  Notification errors are logged but
+// never fail a deploy.
+func (d *Deploy) notify(environment *Environment, instance *Instance, event string, deployErr error) {
+
+	notifier, err := d.getNotifier(environment)
+	if err != nil {
+		d.log.Warn("Unable to set up deploy notifications: {}", err)
+		return
+	}
+	if notifier.Len() == 0 {
+		return
+	}
+
+	name := d.deploymentName(environment)
+
+	user, err := d.stim.User()
+	if err != nil {
+		user = "unknown"
+	}
+
+	title := fmt.Sprintf("Deploy of %s to %s/%s (%s) by %s", name, environment.Name, instance.Name, instance.Spec.Kubernetes.Cluster, user)
+	if event == notifyRotateSuccess || event == notifyRotateFailure {
+		title = fmt.Sprintf("Secret rotation of %s in %s/%s (%s) by %s", name, environment.Name, instance.Name, instance.Spec.Kubernetes.Cluster, user)
+	}
+
+	payload := &notify.Payload{
+		Title:  title,
+		Status: notifyStatuses[event],
+		Fields: map[string]string{
+			"deployment":  name,
+			"environment": environment.Name,
+			"instance":    instance.Name,
+			"cluster":     instance.Spec.Kubernetes.Cluster,
+			"user":        user,
+		},
+		Thread: environment.Name + "/" + instance.Name,
+	}
+	switch event {
+	case notifyStart:
+		payload.Title += " started"
+	case notifySuccess, notifyRotateSuccess:
+		payload.Title += " succeeded"
+	case notifyFailure, notifyRotateFailure:
+		payload.Title += " failed"
+		payload.Text = deployErr.Error()
+	}
+
+	err = notifier.Notify("deploy."+event, payload)
+	if err != nil {
+		d.log.Warn("Unable to send deploy notification: {}", err)
+	}
+}
+
+// deploymentName returns the name of the deployment used in messages, which
+// defaults to the name of the directory of the deploy config
+func (d *Deploy) deploymentName(environment *Environment) string {
+	if environment.Notifications != nil && environment.Notifications.Name != "" {
+		return environment.Notifications.Name
+	}
+	configAbs, _ := filepath.Abs(d.config.configFilePath)
+	return filepath.Base(filepath.Dir(configAbs))
+}
+
+// getNotifier returns the notification router of the environment, creating
+// it on first use so backends (ex. Slack threads) are shared between events
+func (d *Deploy) getNotifier(environment *Environment) (*notify.Router, error) {
+
+	if notifier, ok := d.notifiers[environment.Name]; ok {
+		return notifier, nil
+	}
+
+	notifier, err := d.stim.NewNotifier(notificationBackends(environment.Notifications))
+	if err != nil {
+		return nil, err
+	}
+
+	if d.notifiers == nil {
+		d.notifiers = make(map[string]*notify.Router)
+	}
+	d.notifiers[environment.Name] = notifier
+
+	return notifier, nil
+}
+
+// notificationBackends converts the deploy notifications config into
+// notification backend configs
+func notificationBackends(notifications *Notifications) []*notify.BackendConfig {
+
+	if notifications == nil {
+		return nil
+	}
+
+	var backends []*notify.BackendConfig
+	if notifications.Slack != nil {
+		for _, channel := range notifications.Slack.Channels {
+			backends = append(backends, &notify.BackendConfig{
+				Name:   "slack:" + channel,
+				Type:   "slack",
+				Events: deployEvents(notifications.Slack.Events),
+				Options: map[string]string{
+					"channel":  channel,
+					"username": notifications.Slack.Username,
+					"icon-url": notifications.Slack.IconURL,
+				},
+			})
+		}
+	}
+	if notifications.Pagerduty != nil {
+		for _, service := range notifications.Pagerduty.Services {
+			backends = append(backends, &notify.BackendConfig{
+				Name:    "pagerduty:" + service,
+				Type:    "pagerduty",
+				Events:  deployEvents(notifications.Pagerduty.Events),
+				Options: map[string]string{"service": service},
+			})
+		}
+	}
+	for _, backend := range notifications.Backends {
+		config := *backend
+		config.Events = deployEvents(backend.Events)
+		backends = append(backends, &config)
+	}
+
+	return backends
+}
+
+// deployEvents returns the notification event names of deploy events.  All
+// deploy events are returned if none are given.
+func deployEvents(events []string) []string {
+	if len(events) == 0 {
+		events = notifyEvents
+	}
+
+	result := make([]string, len(events))
+	for i, event := range events {
+		result[i] = "deploy." + event
+	}
+	return result
+}
@@ -0,0 +1,351 @@
+package deploy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/downloader"
+	"github.com/PremiereGlobal/stim/stim"
+	"gopkg.in/yaml.v2"
+)
+
+// opaQuery is the document that the `deny` and `warn` rules of the deploy
+// spec policies are read from, ie. policies are in `package stim.deploy`
+const opaQuery = "data.stim.deploy"
+
+// policyBundleClient is used to download policy bundles
+var policyBundleClient = &http.Client{Timeout: 30 * time.Second}
+
+// policyInput is the input document of the deploy spec policies
+type policyInput struct {
+	Environment string      `json:"environment"`
+	Instance    string      `json:"instance"`
+	Deployment  interface{} `json:"deployment"`
+	Policy      interface{} `json:"policy"`
+	Spec        interface{} `json:"spec"`
+}
+
+// policyResult is the outcome of the deploy spec policies for an instance
+type policyResult struct {
+	Deny []string
+	Warn []string
+}
+
+// specPoliciesConfigured returns true if the stim config sets deploy spec
+// policies
+func (d *Deploy) specPoliciesConfigured() bool {
+	return len(d.stim.ConfigGetStringSlice("deploy.policy.paths")) > 0 || d.stim.ConfigGetString("deploy.policy.bundle-url") != ""
+}
+
+// checkSpecPolicies evaluates the deploy spec policies of the stim config
+// (`deploy.policy.paths` and `deploy.policy.bundle-url`) against the merged
+// spec of each instance with `opa eval`.  Warnings are logged and any denial
+// fails the check.
+func (d *Deploy) checkSpecPolicies(environment *Environment, instances []*Instance) error {
+
+	if !d.specPoliciesConfigured() {
+		return nil
+	}
+
+	opa, err := d.opaPath()
+	if err != nil {
+		return err
+	}
+
+	bundle := ""
+	if url := d.stim.ConfigGetString("deploy.policy.bundle-url"); url != "" {
+		digest := d.stim.ConfigGetString("deploy.policy.bundle-sha256")
+		if digest == "" {
+			return stim.ConfigError(errors.New("`deploy.policy.bundle-sha256` must be set to the SHA-256 of the bundle of `deploy.policy.bundle-url`"))
+		}
+
+		dir, err := ioutil.TempDir("", "stim-policy")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+
+		bundle = filepath.Join(dir, "bundle.tar.gz")
+		err = downloadPolicyBundle(url, digest, bundle)
+		if err != nil {
+			return fmt.Errorf("Error downloading the deploy policy bundle '%s': %v", url, err)
+		}
+	}
+	args := opaEvalArgs(d.stim.ConfigGetStringSlice("deploy.policy.paths"), bundle)
+
+	var denied []string
+	for _, instance := range instances {
+		input, err := json.Marshal(d.policyInput(environment, instance))
+		if err != nil {
+			return err
+		}
+
+		cmd := exec.Command(opa, args...)
+		cmd.Stdin = bytes.NewReader(input)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return fmt.Errorf("Error evaluating the deploy policies: %v: %s", err, strings.TrimSpace(stderr.String()))
+		}
+
+		result, err := parseOpaResult(out)
+		if err != nil {
+			return fmt.Errorf("Error reading the deploy policy result: %v", err)
+		}
+		for _, message := range result.Warn {
+			d.log.Warn("Deploy policy warning for {}/{}: {}", environment.Name, instance.Name, message)
+		}
+		for _, message := range result.Deny {
+			denied = append(denied, fmt.Sprintf("%s/%s: %s", environment.Name, instance.Name, message))
+		}
+	}
+
+	if len(denied) > 0 {
+		return stim.ConfigError(fmt.Errorf("The deploy config is denied by the deploy policies:\n  - %s", strings.Join(denied, "\n  - ")))
+	}
+
+	d.log.Debug("Deploy policies passed for {} instance(s)", len(instances))
+	return nil
+}
+
+// CheckPolicies evaluates the deploy spec policies against the selected
+// instance(s) without deploying.  Vault isn't accessed, so the specs don't
+// have the env vars and secrets added by stim.
+func (d *Deploy) CheckPolicies() error {
+
+	d.log = d.stim.GetLogger()
+
+	if !d.specPoliciesConfigured() {
+		return stim.UsageError(errors.New("No deploy policies are configured.  Set `deploy.policy.paths` or `deploy.policy.bundle-url` in the stim config"))
+	}
+
+	err := d.parseConfig()
+	if err != nil {
+		return err
+	}
+	environment, instances, err := d.selectInstances()
+	if err != nil || environment == nil {
+		return err
+	}
+
+	err = d.checkSpecPolicies(environment, instances)
+	if err != nil {
+		return err
+	}
+
+	d.log.Info("The deploy config passes the deploy policies")
+	return nil
+}
+
+// opaPath returns the `opa` CLI that the deploy policies are evaluated with:
+// the `tools.opa.version` release, downloaded to the tool cache (and checked
+// against `tools.manifest`), or else the one on the PATH.  OPA isn't embedded
+// since the library needs a much newer Go than stim is built with.
+func (d *Deploy) opaPath() (string, error) {
+
+	if version := d.stim.ConfigGetString("tools.opa.version"); version != "" {
+		dl := downloader.NewOpaDownloader(version, d.stim.ConfigGetCacheDir("tools"))
+		err := d.stim.DownloadTools(map[string]downloader.Downloader{"opa": dl})
+		if err != nil {
+			return "", err
+		}
+		return dl.GetBinPath(), nil
+	}
+
+	path, err := exec.LookPath("opa")
+	if err != nil {
+		return "", stim.ConfigError(errors.New("The `opa` CLI must be installed, or `tools.opa.version` set to download it, to evaluate the deploy policies of `deploy.policy.paths` and `deploy.policy.bundle-url`.  See https://www.openpolicyagent.org/docs/latest/#running-opa"))
+	}
+	return path, nil
+}
+
+// opaEvalArgs returns the `opa eval` arguments that evaluate the policy
+// files or directories and bundle against the input on stdin
+func opaEvalArgs(paths []string, bundle string) []string {
+	args := []string{"eval", "--format", "json", "--stdin-input"}
+	for _, p := range paths {
+		args = append(args, "--data", p)
+	}
+	if bundle != "" {
+		args = append(args, "--bundle", bundle)
+	}
+	return append(args, opaQuery)
+}
+
+// downloadPolicyBundle downloads a policy bundle over HTTPS to a file and
+// checks that its SHA-256 is the expected hex digest.  The file is removed if
+// it doesn't match.
+func downloadPolicyBundle(url string, digest string, file string) error {
+
+	if !strings.HasPrefix(url, "https://") {
+		return stim.ConfigError(errors.New("The bundle URL must be https://"))
+	}
+
+	resp, err := policyBundleClient.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("Unexpected response %s", resp.Status)
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(f, hash), resp.Body)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if !strings.EqualFold(sum, strings.TrimSpace(digest)) {
+		os.Remove(file)
+		return fmt.Errorf("The bundle has SHA-256 %s but `deploy.policy.bundle-sha256` is %s", sum, digest)
+	}
+
+	return nil
+}
+
+// policyInput returns the input document of an instance, with the field
+// names of the deploy config and the values of sensitive env vars redacted
+func (d *Deploy) policyInput(environment *Environment, instance *Instance) *policyInput {
+	return &policyInput{
+		Environment: environment.Name,
+		Instance:    instance.Name,
+		Deployment:  yamlDocument(d.config.Deployment),
+		Policy:      yamlDocument(environment.Policy),
+		Spec:        yamlDocument(redactedSpec(instance)),
+	}
+}
+
+// redactedSpec returns a copy of the spec of the instance with the values of
+// secrets, stim-generated credentials and names that look like secrets
+// redacted
+func redactedSpec(instance *Instance) *Spec {
+
+	spec := *instance.Spec
+	spec.EnvironmentVars = make([]*EnvironmentVar, len(instance.Spec.EnvironmentVars))
+	for i, e := range instance.Spec.EnvironmentVars {
+		env := *e
+		if isSensitiveEnvVar(instance, e.Name) || stim.IsSensitive(e.Name) {
+			if env.Value != "" {
+				env.Value = historyRedacted
+			}
+			if env.ValueFrom != nil && env.ValueFrom.Base64 != "" {
+				source := *env.ValueFrom
+				source.Base64 = historyRedacted
+				env.ValueFrom = &source
+			}
+		}
+		spec.EnvironmentVars[i] = &env
+	}
+	return &spec
+}
+
+// yamlDocument returns the value as JSON compatible maps and lists, with the
+// field names of its YAML tags
+func yamlDocument(value interface{}) interface{} {
+
+	b, err := yaml.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	var document interface{}
+	if yaml.Unmarshal(b, &document) != nil {
+		return nil
+	}
+	return jsonCompatible(document)
+}
+
+// jsonCompatible converts the maps decoded from YAML to maps with string
+// keys so that they can be encoded to JSON
+func jsonCompatible(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = jsonCompatible(item)
+		}
+		return m
+	case []interface{}:
+		for i, item := range v {
+			v[i] = jsonCompatible(item)
+		}
+	}
+	return value
+}
+
+// parseOpaResult reads the `deny` and `warn` messages from the JSON output of
+// `opa eval`.  Messages that aren't strings are JSON encoded.
+func parseOpaResult(out []byte) (*policyResult, error) {
+
+	var output struct {
+		Result []struct {
+			Expressions []struct {
+				Value interface{} `json:"value"`
+			} `json:"expressions"`
+		} `json:"result"`
+	}
+	err := json.Unmarshal(out, &output)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &policyResult{}
+	for _, r := range output.Result {
+		for _, expression := range r.Expressions {
+			document, ok := expression.Value.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			result.Deny = append(result.Deny, policyMessages(document["deny"])...)
+			result.Warn = append(result.Warn, policyMessages(document["warn"])...)
+		}
+	}
+	sort.Strings(result.Deny)
+	sort.Strings(result.Warn)
+	return result, nil
+}
+
+// policyMessages returns the messages of a rule (a set or a single value)
+func policyMessages(value interface{}) []string {
+
+	var items []interface{}
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		items = v
+	default:
+		items = []interface{}{v}
+	}
+
+	messages := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			messages = append(messages, s)
+			continue
+		}
+		b, _ := json.Marshal(item)
+		messages = append(messages, string(b))
+	}
+	return messages
+}
@@ -0,0 +1,118 @@
+package deploy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestOpaEvalArgs(t *testing.T) {
+	assert.DeepEqual(t, opaEvalArgs([]string{"policies/", "extra.rego"}, "/tmp/bundle.tar.gz"), []string{
+		"eval", "--format", "json", "--stdin-input",
+		"--data", "policies/",
+		"--data", "extra.rego",
+		"--bundle", "/tmp/bundle.tar.gz",
+		"data.stim.deploy",
+	})
+}
+
+func TestDownloadPolicyBundle(t *testing.T) {
+	content := []byte("bundle")
+	sum := sha256.Sum256(content)
+	digest := hex.EncodeToString(sum[:])
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	}))
+	defer server.Close()
+	defer func(client *http.Client) { policyBundleClient = client }(policyBundleClient)
+	policyBundleClient = server.Client()
+
+	dir, err := ioutil.TempDir("", "stim-policy")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "bundle.tar.gz")
+
+	assert.NilError(t, downloadPolicyBundle(server.URL, digest, file))
+	b, err := ioutil.ReadFile(file)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, b, content)
+
+	// A bundle that doesn't match the digest isn't kept
+	os.Remove(file)
+	other := "0000000000000000000000000000000000000000000000000000000000000000"
+	assert.Error(t, downloadPolicyBundle(server.URL, other, file), "The bundle has SHA-256 "+digest+" but `deploy.policy.bundle-sha256` is "+other)
+	_, err = os.Stat(file)
+	assert.Assert(t, os.IsNotExist(err))
+
+	assert.Error(t, downloadPolicyBundle("http://policies.example.com/bundle.tar.gz", digest, file), "The bundle URL must be https://")
+}
+
+func TestParseOpaResult(t *testing.T) {
+	out := []byte(`{"result":[{"expressions":[{"value":{
+		"deny":["prod must have addConfirmationPrompt","image tag must not be latest"],
+		"warn":[{"msg":"no health checks"}],
+		"helper":true
+	},"text":"data.stim.deploy"}]}]}`)
+
+	result, err := parseOpaResult(out)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, result.Deny, []string{"image tag must not be latest", "prod must have addConfirmationPrompt"})
+	assert.DeepEqual(t, result.Warn, []string{`{"msg":"no health checks"}`})
+
+	// No policies in the package
+	result, err = parseOpaResult([]byte(`{}`))
+	assert.NilError(t, err)
+	assert.Equal(t, len(result.Deny), 0)
+
+	_, err = parseOpaResult([]byte(`not json`))
+	assert.Assert(t, err != nil)
+}
+
+func TestPolicyInput(t *testing.T) {
+	d := &Deploy{config: Config{Deployment: Deployment{Type: "helm", Container: Container{Repo: "premiereglobal/kube-vault-deploy", Tag: "latest"}}}}
+	environment := &Environment{Name: "prod", Policy: &Policy{Confirm: confirmTyped}}
+	instance := &Instance{
+		Name: "us-west-2",
+		Spec: &Spec{
+			AddConfirmationPrompt: true,
+			Secrets:               []*SecretItem{vaultSecret("secret/prod/db", 0, map[string]string{"DB_HOST": "host"})},
+			EnvironmentVars: []*EnvironmentVar{
+				{Name: "REPLICAS", Value: "3"},
+				{Name: "VAULT_TOKEN", Value: "s.secret"},
+				{Name: "API_KEY", Value: "key"},
+				{Name: "CA", ValueFrom: &EnvironmentVarSource{Base64: "aGVsbG8="}},
+			},
+		},
+	}
+
+	b, err := json.Marshal(d.policyInput(environment, instance))
+	assert.NilError(t, err)
+	var input map[string]interface{}
+	assert.NilError(t, json.Unmarshal(b, &input))
+
+	assert.Equal(t, input["environment"], "prod")
+	assert.Equal(t, input["instance"], "us-west-2")
+	assert.Equal(t, input["deployment"].(map[string]interface{})["container"].(map[string]interface{})["tag"], "latest")
+	assert.Equal(t, input["policy"].(map[string]interface{})["confirm"], confirmTyped)
+
+	spec := input["spec"].(map[string]interface{})
+	assert.Equal(t, spec["addConfirmationPrompt"], true)
+	env := spec["env"].([]interface{})
+	assert.Equal(t, env[0].(map[string]interface{})["value"], "3")
+	assert.Equal(t, env[1].(map[string]interface{})["value"], historyRedacted)
+	assert.Equal(t, env[2].(map[string]interface{})["value"], historyRedacted)
+	assert.Equal(t, env[3].(map[string]interface{})["valueFrom"].(map[string]interface{})["base64"], historyRedacted)
+
+	// The spec of the instance isn't changed
+	assert.Equal(t, instance.Spec.EnvironmentVars[1].Value, "s.secret")
+	assert.Equal(t, instance.Spec.EnvironmentVars[3].ValueFrom.Base64, "aGVsbG8=")
+}
@@ -0,0 +1,151 @@
+package deploy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	log "github.com/PremiereGlobal/stim/pkg/stimlog"
+	"github.com/PremiereGlobal/stim/pkg/utils"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// originCLI is the level of env vars set with `--set` and `--set-file`
+const originCLI = "cli"
+
+// envVarName matches valid environment variable names
+var envVarName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// parseOverrides returns the env vars set on the command line with
+// `--set NAME=VALUE` and `--set-file NAME=path`.  The value of `--set-file` is
+// the content of the file.
+func parseOverrides(sets []string, setFiles []string, readFile func(string) ([]byte, error)) ([]*EnvironmentVar, error) {
+
+	var overrides []*EnvironmentVar
+	seen := make(map[string]bool)
+
+	add := func(flag string, format string, arg string, fromFile bool) error {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return fmt.Errorf("Invalid --%s value '%s', must be in the format %s", flag, arg, format)
+		}
+		name, value := parts[0], parts[1]
+
+		if !envVarName.MatchString(name) {
+			return fmt.Errorf("Invalid environment variable name '%s' in --%s", name, flag)
+		}
+		if isReservedEnvName(name) {
+			return fmt.Errorf("Reserved environment variable name '%s' can't be set with --%s", name, flag)
+		}
+		if seen[name] {
+			return fmt.Errorf("Environment variable '%s' is set more than once with --set or --set-file", name)
+		}
+		seen[name] = true
+
+		if fromFile {
+			b, err := readFile(value)
+			if err != nil {
+				return fmt.Errorf("Error reading --%s file for '%s': %v", flag, name, err)
+			}
+			value = string(b)
+		}
+
+		overrides = append(overrides, &EnvironmentVar{Name: name, Value: value})
+		return nil
+	}
+
+	for _, arg := range sets {
+		if err := add("set", "NAME=VALUE", arg, false); err != nil {
+			return nil, err
+		}
+	}
+	for _, arg := range setFiles {
+		if err := add("set-file", "NAME=PATH", arg, true); err != nil {
+			return nil, err
+		}
+	}
+
+	return overrides, nil
+}
+
+// isReservedEnvName returns true if an env var is set by stim for every
+// instance
+func isReservedEnvName(name string) bool {
+	if _, ok := kubeConfigSecretMaps[name]; ok {
+		return true
+	}
+	return utils.Contains(stimEnvNames, name)
+}
+
+// applyOverrides sets the command line env vars on an instance, replacing
+// any env var or secret of the same name from the deploy config
+func applyOverrides(instance *Instance, overrides []*EnvironmentVar) {
+
+	if len(overrides) == 0 {
+		return
+	}
+
+	overridden := make(map[string]bool)
+	for _, o := range overrides {
+		overridden[o.Name] = true
+	}
+
+	var envs []*EnvironmentVar
+	for _, e := range instance.Spec.EnvironmentVars {
+		if !overridden[e.Name] {
+			envs = append(envs, e)
+		}
+	}
+
+	// Secrets are copies per instance (see mergeSecrets) so their maps can be
+	// filtered in place.  Secrets left with no env vars are dropped.
+	var secrets []*SecretItem
+	var secretOrigins []string
+	for i, secret := range instance.Spec.Secrets {
+		for name := range secret.SecretMaps {
+			if overridden[name] {
+				delete(secret.SecretMaps, name)
+			}
+		}
+		origin := instance.origins[fmt.Sprintf("secrets[%d]", i)]
+		delete(instance.origins, fmt.Sprintf("secrets[%d]", i))
+		if len(secret.SecretMaps) > 0 {
+			secrets = append(secrets, secret)
+			secretOrigins = append(secretOrigins, origin)
+		}
+	}
+	for i, origin := range secretOrigins {
+		instance.origins[fmt.Sprintf("secrets[%d]", i)] = origin
+	}
+
+	for _, o := range overrides {
+		envs = append(envs, &EnvironmentVar{Name: o.Name, Value: o.Value})
+		instance.origins["env."+o.Name] = originCLI
+	}
+
+	instance.Spec.EnvironmentVars = envs
+	instance.Spec.Secrets = secrets
+}
+
+// addOverrides applies the `--set` and `--set-file` env vars of the options
+// to every instance of the config
+func addOverrides(config *Config, options stim.ConfigReader, logger log.StimLogger) error {
+
+	overrides, err := parseOverrides(options.ConfigGetStringSlice("deploy.set"), options.ConfigGetStringSlice("deploy.set-file"), ioutil.ReadFile)
+	if err != nil {
+		return stim.UsageError(err)
+	}
+
+	for _, o := range overrides {
+		logger.Debug("Setting env var {} from the command line", o.Name)
+	}
+
+	for _, environment := range config.Environments {
+		for _, instance := range environment.Instances {
+			applyOverrides(instance, overrides)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package deploy
+
+import (
+	"errors"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestParseOverrides(t *testing.T) {
+	readFile := func(path string) ([]byte, error) {
+		if path == "cert.pem" {
+			return []byte("-----BEGIN CERTIFICATE-----\n"), nil
+		}
+		return nil, errors.New("no such file")
+	}
+
+	overrides, err := parseOverrides([]string{"IMAGE_TAG=1.2.3", "ARGS=a=b"}, []string{"TLS_CERT=cert.pem"}, readFile)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, overrides, []*EnvironmentVar{
+		{Name: "IMAGE_TAG", Value: "1.2.3"},
+		{Name: "ARGS", Value: "a=b"},
+		{Name: "TLS_CERT", Value: "-----BEGIN CERTIFICATE-----\n"},
+	})
+
+	_, err = parseOverrides([]string{"IMAGE_TAG"}, nil, readFile)
+	assert.ErrorContains(t, err, "must be in the format NAME=VALUE")
+	_, err = parseOverrides([]string{"IMAGE-TAG=1"}, nil, readFile)
+	assert.ErrorContains(t, err, "Invalid environment variable name")
+	_, err = parseOverrides([]string{"VAULT_TOKEN=abc"}, nil, readFile)
+	assert.ErrorContains(t, err, "Reserved environment variable name 'VAULT_TOKEN'")
+	_, err = parseOverrides(nil, []string{"USER_TOKEN=token"}, readFile)
+	assert.ErrorContains(t, err, "Reserved environment variable name 'USER_TOKEN'")
+	_, err = parseOverrides([]string{"A=1"}, []string{"A=cert.pem"}, readFile)
+	assert.ErrorContains(t, err, "set more than once")
+	_, err = parseOverrides(nil, []string{"A=missing.pem"}, readFile)
+	assert.ErrorContains(t, err, "no such file")
+}
+
+func TestApplyOverrides(t *testing.T) {
+	instance := &Instance{
+		Spec: &Spec{
+			EnvironmentVars: []*EnvironmentVar{{Name: "LOG_LEVEL", Value: "info"}, {Name: "REPLICAS", Value: "2"}},
+			Secrets: []*SecretItem{
+				vaultSecret("secret/app/api", 0, map[string]string{"API_KEY": "key"}),
+				vaultSecret("secret/app/db", 0, map[string]string{"DB_USER": "user", "DB_PASSWORD": "password"}),
+			},
+		},
+		origins: map[string]string{"env.LOG_LEVEL": originGlobal, "env.REPLICAS": originInstance, "secrets[0]": originGlobal, "secrets[1]": originEnvironment},
+	}
+
+	applyOverrides(instance, []*EnvironmentVar{{Name: "LOG_LEVEL", Value: "debug"}, {Name: "API_KEY", Value: "test"}, {Name: "DB_PASSWORD", Value: "test"}})
+
+	assert.DeepEqual(t, instance.Spec.EnvironmentVars, []*EnvironmentVar{
+		{Name: "REPLICAS", Value: "2"},
+		{Name: "LOG_LEVEL", Value: "debug"},
+		{Name: "API_KEY", Value: "test"},
+		{Name: "DB_PASSWORD", Value: "test"},
+	})
+	assert.Equal(t, len(instance.Spec.Secrets), 1)
+	assert.DeepEqual(t, instance.Spec.Secrets[0].SecretMaps, map[string]string{"DB_USER": "user"})
+	assert.DeepEqual(t, instance.origins, map[string]string{
+		"env.LOG_LEVEL":   originCLI,
+		"env.REPLICAS":    originInstance,
+		"env.API_KEY":     originCLI,
+		"env.DB_PASSWORD": originCLI,
+		"secrets[0]":      originEnvironment,
+	})
+}
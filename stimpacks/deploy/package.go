@@ -0,0 +1,628 @@
+package deploy
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PremiereGlobal/stim/stim"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// packageVersion is the version of the package format.  Packages of a
+	// newer version can't be deployed.
+	packageVersion = 1
+
+	// packageManifestFile is the manifest at the root of a package
+	packageManifestFile = "stim.package.json"
+
+	// packageConfigFile is the resolved deploy config at the root of a
+	// package
+	packageConfigFile = "stim.deploy.yaml"
+
+	// packageRenderedDir holds the templates rendered when the package was
+	// created, in a directory per instance
+	packageRenderedDir = ".rendered"
+)
+
+// packageManifest describes what a deploy package deploys, where it was
+// created from and the checksums of its files
+type packageManifest struct {
+	Version     int                `json:"version"`
+	Deployment  string             `json:"deployment"`
+	Service     string             `json:"service,omitempty"`
+	Environment string             `json:"environment"`
+	Instances   []*packageInstance `json:"instances"`
+	Image       string             `json:"image,omitempty"`
+	Commit      string             `json:"commit,omitempty"`
+	Tag         string             `json:"tag,omitempty"`
+	CreatedBy   string             `json:"createdBy"`
+	CreatedAt   time.Time          `json:"createdAt"`
+	StimVersion string             `json:"stimVersion"`
+
+	// Files are the SHA-256 checksums of the files of the package, other
+	// than the manifest
+	Files map[string]string `json:"files"`
+}
+
+// packageInstance is an instance that a package deploys to
+type packageInstance struct {
+	Name string `json:"name"`
+
+	// Images are the references of the images of the instance, with their
+	// resolved tags, by name
+	Images map[string]string `json:"images,omitempty"`
+
+	// Templates are the outputs of the templates rendered in the package.
+	// The templates that use secrets are rendered when it's deployed.
+	Templates []string `json:"templates,omitempty"`
+}
+
+// deployPackage is a package being deployed, extracted to dir
+type deployPackage struct {
+	dir      string
+	manifest *packageManifest
+}
+
+// Package bundles the deploy directory, the resolved (non-secret) config and
+// the templates that don't use secrets of the selected instance(s) into a
+// package that `stim deploy run-package` deploys later or on another machine
+func (d *Deploy) Package() error {
+
+	d.log = d.stim.GetLogger()
+
+	err := d.parseConfig()
+	if err != nil {
+		return err
+	}
+	environment, instances, err := d.selectInstances()
+	if err != nil {
+		return err
+	}
+	if environment == nil {
+		return stim.Aborted("No instance selected")
+	}
+
+	err = d.resolveImages(instances)
+	if err != nil {
+		return err
+	}
+	for _, instance := range instances {
+		err := d.resolvePackageEnvSources(instance)
+		if err != nil {
+			return stim.ConfigError(err)
+		}
+	}
+
+	// The package has the layout of the directory of the deploy config, so
+	// the paths relative to the config stay the same
+	configAbs, err := filepath.Abs(d.config.configFilePath)
+	if err != nil {
+		return err
+	}
+	configDir := filepath.Dir(configAbs)
+	deployDir, err := filepath.Rel(configDir, d.config.Deployment.fullDirectoryPath)
+	if err != nil || strings.HasPrefix(deployDir, "..") {
+		return stim.ConfigError(fmt.Errorf("The deployment directory %s must be in the directory of the deploy config to be packaged", d.config.Deployment.fullDirectoryPath))
+	}
+
+	staging, err := ioutil.TempDir("", "stim-package")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	err = d.copyPackageFiles(d.config.Deployment.fullDirectoryPath, filepath.Join(staging, deployDir), configAbs)
+	if err != nil {
+		return fmt.Errorf("Error copying the deployment directory: %v", err)
+	}
+	for _, reserved := range []string{packageManifestFile, packageConfigFile, packageRenderedDir} {
+		if _, err := os.Stat(filepath.Join(staging, reserved)); err == nil {
+			return stim.ConfigError(fmt.Errorf("The deployment directory can't be packaged, %s is reserved for the files of the package", reserved))
+		}
+	}
+
+	user, err := d.stim.User()
+	if err != nil {
+		user = "unknown"
+	}
+	manifest := &packageManifest{
+		Version:     packageVersion,
+		Deployment:  filepath.Base(configDir),
+		Environment: environment.Name,
+		CreatedBy:   user,
+		CreatedAt:   d.stim.Clock().Now().UTC(),
+		StimVersion: d.stim.GetVersion(),
+	}
+	if d.service != nil {
+		manifest.Service = d.service.Name
+	}
+	if d.config.Deployment.Type != deployTypeManifests {
+		manifest.Image = d.config.Deployment.Container.Repo + ":" + d.config.Deployment.Container.Tag
+	}
+	manifest.Commit, _ = d.git("rev-parse", "HEAD")
+	if manifest.Commit != "" {
+		manifest.Tag, _ = d.git("describe", "--tags", "--exact-match", "HEAD")
+	}
+
+	for _, instance := range instances {
+		rendered, err := d.packageTemplates(environment, instance, filepath.Join(staging, deployDir), filepath.Join(staging, packageRenderedDir, url.PathEscape(instance.Name)))
+		if err != nil {
+			return err
+		}
+		images := make(map[string]string)
+		for _, e := range instance.Spec.EnvironmentVars {
+			for _, image := range instance.Spec.Images {
+				if e.Name == image.Name {
+					images[image.Name] = e.Value
+				}
+			}
+		}
+		manifest.Instances = append(manifest.Instances, &packageInstance{Name: instance.Name, Images: images, Templates: rendered})
+	}
+
+	config, err := d.packageConfig(environment, instances, filepath.ToSlash(deployDir))
+	if err != nil {
+		return err
+	}
+	content, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(filepath.Join(staging, packageConfigFile), escapeEnvReferences(content), 0644)
+	if err != nil {
+		return err
+	}
+
+	manifest.Files, err = checksumFiles(staging, []string{staging}, nil)
+	if err != nil {
+		return err
+	}
+	content, err = json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(filepath.Join(staging, packageManifestFile), content, 0644)
+	if err != nil {
+		return err
+	}
+
+	bundle, err := packDirectory(staging)
+	if err != nil {
+		return err
+	}
+	output := d.stim.ConfigGetString("deploy-package-output")
+	if output == "" {
+		output = packageFileName(manifest)
+	}
+	err = ioutil.WriteFile(output, bundle, 0644)
+	if err != nil {
+		return err
+	}
+
+	d.log.Info("Wrote the package of {} to {} ({} files, {} KiB).  Deploy it with `stim deploy run-package {}`", environment.Name, output, len(manifest.Files), len(bundle)/1024, output)
+	return nil
+}
+
+// RunPackage deploys a package created by `stim deploy package`.  The secrets
+// are read and the deploy policy of the environment applies as when deploying
+// from the deploy config.
+func (d *Deploy) RunPackage(path string) error {
+
+	d.log = d.stim.GetLogger()
+
+	if len(d.stim.ConfigGetStringSlice("deploy.service")) > 0 || d.stim.ConfigGetString("deploy.file") != "" {
+		return stim.UsageError(errors.New("--service and --deploy-file can't be used with run-package, the package has its own deploy config"))
+	}
+
+	tmp, err := ioutil.TempDir("", "stim-package")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	pkg, err := openPackage(path, tmp)
+	if err != nil {
+		return stim.ConfigError(fmt.Errorf("Invalid package %s: %v", path, err))
+	}
+	manifest := pkg.manifest
+
+	target, err := packageTarget(manifest, d.stim.ConfigGetString("deploy.environment"), d.stim.ConfigGetString("deploy.instance"))
+	if err != nil {
+		return err
+	}
+
+	commit := manifest.Commit
+	if commit == "" {
+		commit = "unknown"
+	}
+	d.log.Info("Deploying the package of {} created by {} at {} (commit {})", manifest.Deployment, manifest.CreatedBy, d.stim.FormatTime(manifest.CreatedAt), shortCommit(commit))
+
+	d.stim.ConfigOverride("deploy.file", filepath.Join(pkg.dir, packageConfigFile))
+	d.stim.ConfigOverride("deploy.environment", manifest.Environment)
+	d.stim.ConfigOverride("deploy.instance", target)
+	d.pkg = pkg
+	return d.runServices()
+}
+
+// resolvePackageEnvSources sets the value of the env vars of the instance
+// whose `valueFrom` isn't sensitive.  Sensitive ones are resolved when the
+// package is deployed.
+func (d *Deploy) resolvePackageEnvSources(instance *Instance) error {
+
+	insensitive := &Instance{Name: instance.Name, Spec: &Spec{}}
+	for _, e := range instance.Spec.EnvironmentVars {
+		if e.ValueFrom != nil && !e.ValueFrom.isSensitive() {
+			insensitive.Spec.EnvironmentVars = append(insensitive.Spec.EnvironmentVars, e)
+		}
+	}
+
+	return d.resolveEnvSources(insensitive)
+}
+
+// packageConfig returns the resolved deploy config of the instances of the
+// environment.  The specs are merged into the instances, the images have
+// their resolved tags and the env vars whose `valueFrom` isn't sensitive are
+// set to their value.  Releases are left out as the package isn't a git
+// repo to tag.
+func (d *Deploy) packageConfig(environment *Environment, instances []*Instance, deployDir string) (*Config, error) {
+
+	deployment := d.config.Deployment
+	deployment.Directory = deployDir
+
+	packaged := *environment
+	packaged.Spec = nil
+	packaged.Release = nil
+	packaged.Instances = nil
+
+	// The rollout strategy is for deploys to all of the instances
+	if len(instances) < len(environment.Instances) {
+		packaged.Strategy = nil
+	}
+
+	for _, instance := range instances {
+		spec := *instance.Spec
+
+		spec.EnvironmentVars = nil
+		for _, e := range instance.Spec.EnvironmentVars {
+			if e.ValueFrom != nil && !e.ValueFrom.isSensitive() {
+				e = &EnvironmentVar{Name: e.Name, Value: e.Value}
+			}
+			spec.EnvironmentVars = append(spec.EnvironmentVars, e)
+		}
+
+		spec.Images = nil
+		for _, image := range instance.Spec.Images {
+			tag, err := d.resolveTag(image.Repo, image.Tag)
+			if err != nil {
+				return nil, err
+			}
+			spec.Images = append(spec.Images, &Image{Name: image.Name, Repo: image.Repo, Tag: tag})
+		}
+
+		packaged.Instances = append(packaged.Instances, &Instance{Name: instance.Name, Spec: &spec})
+	}
+
+	return &Config{
+		Deployment:   deployment,
+		Environments: []*Environment{&packaged},
+		Freezes:      d.config.Freezes,
+	}, nil
+}
+
+// packageTemplates renders the templates of the instance that don't use
+// secrets (or values only known when deploying) to renderedDir, and returns
+// their outputs
+func (d *Deploy) packageTemplates(environment *Environment, instance *Instance, directory string, renderedDir string) ([]string, error) {
+
+	data, deployValues := packageTemplateData(environment, instance)
+
+	var rendered []string
+	for _, t := range instance.Spec.Templates {
+		input, err := ioutil.ReadFile(filepath.Join(directory, t.Input))
+		if err != nil {
+			return nil, fmt.Errorf("Error reading template '%s': %v", t.Input, err)
+		}
+
+		if name := referencedName(string(input), deployValues); name != "" {
+			d.log.Info("Template {} of {} uses {}, it will be rendered when the package is deployed", t.Input, instance.Name, name)
+			continue
+		}
+
+		data.Values = t.Values
+		data.Lists = t.Lists
+		out, err := executeTemplate(t.Input, input, data)
+		if err != nil {
+			d.log.Info("Template {} of {} will be rendered when the package is deployed: {}", t.Input, instance.Name, err)
+			continue
+		}
+		err = writeRendered(filepath.Join(renderedDir, t.Output), out)
+		if err != nil {
+			return nil, err
+		}
+		rendered = append(rendered, t.Output)
+	}
+
+	return rendered, nil
+}
+
+// packageTemplateData returns the data that templates of the instance are
+// rendered with in a package, along with the names of the values that are
+// only known when deploying (ex. secrets)
+func packageTemplateData(environment *Environment, instance *Instance) (*templateData, []string) {
+
+	env := make(map[string]string)
+	deployValues := []string{"VAULT_ADDR", "VAULT_TOKEN", "SECRET_CONFIG"}
+	for _, e := range instance.Spec.EnvironmentVars {
+		if isSensitiveEnvVar(instance, e.Name) {
+			deployValues = append(deployValues, e.Name)
+		} else {
+			env[e.Name] = e.Value
+		}
+	}
+	for _, secret := range instance.Spec.Secrets {
+		for name := range secret.SecretMaps {
+			deployValues = append(deployValues, name)
+		}
+	}
+	for name := range kubeConfigSecretMaps {
+		deployValues = append(deployValues, name)
+	}
+
+	env["DEPLOY_ENVIRONMENT"] = environment.Name
+	env["DEPLOY_INSTANCE"] = instance.Name
+	env["DEPLOY_CLUSTER"] = instance.Spec.Kubernetes.Cluster
+	env["STIM_DEPLOY"] = "true"
+
+	// Without a namespace in the spec, it's the default namespace of the
+	// cluster in Vault
+	namespace := instance.Spec.Kubernetes.Namespace
+	if namespace == "" {
+		deployValues = append(deployValues, "DEPLOY_NAMESPACE", "Namespace")
+	} else {
+		env["DEPLOY_NAMESPACE"] = namespace
+	}
+
+	sort.Strings(deployValues)
+	return &templateData{
+		Env:         env,
+		Environment: environment.Name,
+		Instance:    instance.Name,
+		Cluster:     instance.Spec.Kubernetes.Cluster,
+		Namespace:   namespace,
+	}, deployValues
+}
+
+// referencedName returns the first of the names that is in the template, or
+// an empty string if none are
+func referencedName(template string, names []string) string {
+	for _, name := range names {
+		if strings.Contains(template, name) {
+			return name
+		}
+	}
+	return ""
+}
+
+// copyPackageFiles copies the files of the deployment directory to the
+// package, other than the deploy config file and the checksumsExcludedDirs
+func (d *Deploy) copyPackageFiles(src string, dst string, configFile string) error {
+
+	return filepath.Walk(src, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, file)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if info.IsDir() {
+			if checksumsExcludedDirs[info.Name()] {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(target, 0755)
+		}
+		if file == configFile {
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			d.log.Warn("Skipping {}, only regular files are packaged", rel)
+			return nil
+		}
+
+		in, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(out, in)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	})
+}
+
+// packageFileName is the default file name of a package, ex.
+// `my-app-prod-us-west-2.tar.gz`
+func packageFileName(manifest *packageManifest) string {
+	parts := []string{manifest.Deployment, manifest.Environment}
+	if len(manifest.Instances) == 1 {
+		parts = append(parts, manifest.Instances[0].Name)
+	}
+	name := strings.Join(parts, "-")
+	return strings.NewReplacer("/", "-", "\\", "-", " ", "-").Replace(name) + ".tar.gz"
+}
+
+// openPackage extracts a package in the directory and checks its files
+// against the manifest.  The package is extracted to a directory named after
+// the deployment, as deploys are named after the directory of their config.
+func openPackage(path string, dir string) (*deployPackage, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	extracted := filepath.Join(dir, "package")
+	err = unpackBundle(f, extracted)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(extracted, packageManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("No %s manifest found", packageManifestFile)
+	}
+	manifest := &packageManifest{}
+	err = json.Unmarshal(content, manifest)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading %s: %v", packageManifestFile, err)
+	}
+	if manifest.Version > packageVersion {
+		return nil, fmt.Errorf("The package was created by a newer version of stim (%s), upgrade stim to deploy it", manifest.StimVersion)
+	}
+
+	current, err := checksumFiles(extracted, []string{extracted}, map[string]bool{filepath.Join(extracted, packageManifestFile): true})
+	if err != nil {
+		return nil, err
+	}
+	if problems := compareChecksums(manifest.Files, current); len(problems) > 0 {
+		return nil, fmt.Errorf("The files don't match the manifest: %s", strings.Join(problems, ", "))
+	}
+
+	name := manifest.Deployment
+	if name != "" && name != "." && name != ".." && name == filepath.Base(name) {
+		renamed := filepath.Join(dir, name)
+		if err := os.Rename(extracted, renamed); err == nil {
+			extracted = renamed
+		}
+	}
+
+	return &deployPackage{dir: extracted, manifest: manifest}, nil
+}
+
+// unpackBundle extracts a gzipped tarball of files and directories to dir.
+// Paths outside of dir and other types of entries (ex. links) are errors.
+func unpackBundle(r io.Reader, dir string) error {
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("Not a gzipped tarball: %v", err)
+	}
+	tr := tar.NewReader(gz)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := filepath.Clean(filepath.FromSlash(header.Name))
+		if filepath.IsAbs(name) || strings.HasPrefix(name, "..") {
+			return fmt.Errorf("Invalid path %s", header.Name)
+		}
+		target := filepath.Join(dir, name)
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, 0755)
+		case tar.TypeReg:
+			err = os.MkdirAll(filepath.Dir(target), 0755)
+			if err == nil {
+				err = writeBundleFile(target, tr, header.FileInfo().Mode().Perm())
+			}
+		default:
+			return fmt.Errorf("Unsupported entry %s, only files and directories are allowed", header.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// writeBundleFile writes a file of a tarball
+func writeBundleFile(path string, r io.Reader, mode os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// packageTarget returns the `deploy.instance` that deploys the instances of
+// the package: its only instance or all of them.  The environment and
+// instance given on the command line must be in the package.
+func packageTarget(manifest *packageManifest, environment string, instance string) (string, error) {
+
+	if environment != "" && environment != manifest.Environment {
+		return "", stim.UsageError(fmt.Errorf("The package deploys to environment '%s', not '%s'", manifest.Environment, environment))
+	}
+
+	if instance == "" || strings.ToLower(instance) == allOptionCli {
+		if len(manifest.Instances) == 1 {
+			return manifest.Instances[0].Name, nil
+		}
+		return allOptionCli, nil
+	}
+
+	var names []string
+	for _, i := range manifest.Instances {
+		if i.Name == instance {
+			return instance, nil
+		}
+		names = append(names, i.Name)
+	}
+	return "", stim.UsageError(fmt.Errorf("Instance '%s' is not in the package, which deploys to %s", instance, strings.Join(names, ", ")))
+}
+
+// rendered returns whether the template output of the instance was rendered
+// in the package
+func (p *deployPackage) rendered(instance string, output string) bool {
+	for _, i := range p.manifest.Instances {
+		if i.Name != instance {
+			continue
+		}
+		for _, o := range i.Templates {
+			if o == output {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// copyRendered copies a template output of the instance rendered in the
+// package to the deployment directory
+func (p *deployPackage) copyRendered(instance string, output string, directory string) error {
+	content, err := ioutil.ReadFile(filepath.Join(p.dir, packageRenderedDir, url.PathEscape(instance), output))
+	if err != nil {
+		return err
+	}
+	return writeRendered(filepath.Join(directory, output), content)
+}
@@ -0,0 +1,210 @@
+package deploy
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/PremiereGlobal/stim/stim"
+	"gopkg.in/yaml.v2"
+	"gotest.tools/assert"
+)
+
+func TestPackageConfig(t *testing.T) {
+	config := testConfig(t, `
+deployment:
+  directory: scripts
+global:
+  spec:
+    env:
+    - name: GREETING
+      value: ${HOME_DIR}
+    - name: REGION
+      valueFrom:
+        base64: dXMtd2VzdC0y
+        sensitive: false
+    - name: API_KEY
+      valueFrom:
+        file: api-key.txt
+    images:
+    - name: APP_IMAGE
+      repo: my-org/app
+      tag: 1.4.0
+environments:
+- name: prod
+  release:
+    tag: deploy/{INSTANCE}
+  strategy:
+    canary:
+      instances: [canary]
+  spec:
+    kubernetes:
+      cluster: prod-cluster
+      serviceAccount: deploy
+  instances:
+  - name: canary
+  - name: main
+    spec:
+      env:
+      - name: REPLICAS
+        value: "3"
+`, "/app/stim.deploy.yaml")
+	assert.NilError(t, resolveConfig(config))
+
+	d := &Deploy{config: *config}
+	environment := config.Environments[0]
+	main := environment.Instances[1]
+	assert.NilError(t, d.resolvePackageEnvSources(main))
+
+	packaged, err := d.packageConfig(environment, []*Instance{main}, "scripts")
+	assert.NilError(t, err)
+	content, err := yaml.Marshal(packaged)
+	assert.NilError(t, err)
+
+	// The packaged config is read like any other deploy config
+	content, err = interpolateEnv(escapeEnvReferences(content), func(string) (string, bool) { return "", false })
+	assert.NilError(t, err)
+	reloaded := testConfig(t, string(content), "/tmp/app/stim.deploy.yaml")
+	assert.NilError(t, resolveConfig(reloaded))
+
+	assert.Equal(t, reloaded.Deployment.Directory, "scripts")
+	assert.Equal(t, len(reloaded.Environments), 1)
+	prod := reloaded.Environments[0]
+	assert.Assert(t, prod.Release == nil)
+	assert.Assert(t, prod.Strategy == nil)
+	assert.Equal(t, len(prod.Instances), 1)
+
+	spec := prod.Instances[0].Spec
+	assert.Equal(t, spec.Kubernetes.Cluster, "prod-cluster")
+	assert.DeepEqual(t, envNames(spec.EnvironmentVars), []string{"REPLICAS=3", "GREETING=${HOME_DIR}", "REGION=us-west-2", "API_KEY="})
+	assert.Equal(t, spec.EnvironmentVars[3].ValueFrom.File, "api-key.txt")
+	assert.Equal(t, len(spec.Images), 1)
+	assert.Equal(t, spec.Images[0].Tag, "1.4.0")
+
+	// The strategy is kept when all of the instances are packaged
+	packaged, err = d.packageConfig(environment, environment.Instances, "scripts")
+	assert.NilError(t, err)
+	assert.Assert(t, packaged.Environments[0].Strategy != nil)
+}
+
+func TestPackageTemplateData(t *testing.T) {
+	instance := &Instance{Name: "main", Spec: &Spec{
+		Kubernetes: Kubernetes{Cluster: "prod-cluster"},
+		EnvironmentVars: []*EnvironmentVar{
+			{Name: "REPLICAS", Value: "3"},
+			{Name: "TOKEN", Value: "t", ValueFrom: &EnvironmentVarSource{Command: "get-token"}},
+		},
+		Secrets: []*SecretItem{{}},
+	}}
+	instance.Spec.Secrets[0].SecretMaps = map[string]string{"DB_PASSWORD": "password"}
+
+	data, deployValues := packageTemplateData(&Environment{Name: "prod"}, instance)
+	assert.DeepEqual(t, data.Env, map[string]string{
+		"REPLICAS":           "3",
+		"DEPLOY_ENVIRONMENT": "prod",
+		"DEPLOY_INSTANCE":    "main",
+		"DEPLOY_CLUSTER":     "prod-cluster",
+		"STIM_DEPLOY":        "true",
+	})
+	assert.Equal(t, referencedName("replicas: {{ .Env.REPLICAS }}", deployValues), "")
+	assert.Equal(t, referencedName("password: {{ .Env.DB_PASSWORD }}", deployValues), "DB_PASSWORD")
+	assert.Equal(t, referencedName("token: {{ .Env.TOKEN }}", deployValues), "TOKEN")
+	assert.Equal(t, referencedName("namespace: {{ .Namespace }}", deployValues), "Namespace")
+
+	instance.Spec.Kubernetes.Namespace = "my-app"
+	data, deployValues = packageTemplateData(&Environment{Name: "prod"}, instance)
+	assert.Equal(t, data.Namespace, "my-app")
+	assert.Equal(t, data.Env["DEPLOY_NAMESPACE"], "my-app")
+	assert.Equal(t, referencedName("namespace: {{ .Namespace }}", deployValues), "")
+}
+
+func TestEscapeEnvReferences(t *testing.T) {
+	content := []byte("a: ${A}\nb: $${B}\n# ${C}\n")
+	unescaped, err := interpolateEnv(escapeEnvReferences(content), func(string) (string, bool) { return "", false })
+	assert.NilError(t, err)
+	assert.Equal(t, string(unescaped), string(content))
+}
+
+func TestPackageTarget(t *testing.T) {
+	manifest := &packageManifest{Environment: "prod", Instances: []*packageInstance{{Name: "us-east-1"}, {Name: "us-west-2"}}}
+
+	target, err := packageTarget(manifest, "", "")
+	assert.NilError(t, err)
+	assert.Equal(t, target, allOptionCli)
+	target, err = packageTarget(manifest, "prod", "us-west-2")
+	assert.NilError(t, err)
+	assert.Equal(t, target, "us-west-2")
+
+	_, err = packageTarget(manifest, "stage", "")
+	assert.Equal(t, stim.ExitCode(err), stim.ExitCodeUsage)
+	_, err = packageTarget(manifest, "", "eu-west-1")
+	assert.ErrorContains(t, err, "which deploys to us-east-1, us-west-2")
+
+	manifest.Instances = manifest.Instances[:1]
+	target, err = packageTarget(manifest, "", "all")
+	assert.NilError(t, err)
+	assert.Equal(t, target, "us-east-1")
+}
+
+func TestOpenPackage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stim-deploy-package")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	staging := filepath.Join(dir, "staging")
+	assert.NilError(t, os.MkdirAll(filepath.Join(staging, "scripts"), 0755))
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(staging, "scripts", "deploy.sh"), []byte("#!/bin/sh\n"), 0755))
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(staging, packageConfigFile), []byte("environments: []\n"), 0644))
+	sums, err := checksumFiles(staging, []string{staging}, nil)
+	assert.NilError(t, err)
+
+	writePackage := func(manifest string) string {
+		assert.NilError(t, ioutil.WriteFile(filepath.Join(staging, packageManifestFile), []byte(manifest), 0644))
+		bundle, err := packDirectory(staging)
+		assert.NilError(t, err)
+		path := filepath.Join(dir, "app.tar.gz")
+		assert.NilError(t, ioutil.WriteFile(path, bundle, 0644))
+		return path
+	}
+
+	path := writePackage(`{"version": 1, "deployment": "app", "environment": "prod", "files": {"stim.deploy.yaml": "` + sums[packageConfigFile] + `", "scripts/deploy.sh": "` + sums["scripts/deploy.sh"] + `"}}`)
+	extractDir := filepath.Join(dir, "extract")
+	pkg, err := openPackage(path, extractDir)
+	assert.NilError(t, err)
+	assert.Equal(t, pkg.dir, filepath.Join(extractDir, "app"))
+	assert.Equal(t, pkg.manifest.Environment, "prod")
+	info, err := os.Stat(filepath.Join(pkg.dir, "scripts", "deploy.sh"))
+	assert.NilError(t, err)
+	assert.Equal(t, info.Mode().Perm(), os.FileMode(0755))
+
+	path = writePackage(`{"version": 1, "deployment": "app", "files": {"stim.deploy.yaml": "` + sums[packageConfigFile] + `"}}`)
+	_, err = openPackage(path, filepath.Join(dir, "extract-modified"))
+	assert.ErrorContains(t, err, "The files don't match the manifest: scripts/deploy.sh")
+
+	path = writePackage(`{"version": 2, "stimVersion": "v9.0.0"}`)
+	_, err = openPackage(path, filepath.Join(dir, "extract-newer"))
+	assert.ErrorContains(t, err, "newer version of stim (v9.0.0)")
+}
+
+func TestUnpackBundle(t *testing.T) {
+	for name, header := range map[string]*tar.Header{
+		"Invalid path ../evil.sh":                {Name: "../evil.sh", Typeflag: tar.TypeReg, Mode: 0644},
+		"Unsupported entry link, only files and": {Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"},
+	} {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		assert.NilError(t, tw.WriteHeader(header))
+		assert.NilError(t, tw.Close())
+		assert.NilError(t, gz.Close())
+
+		dir, err := ioutil.TempDir("", "stim-deploy-unpack")
+		assert.NilError(t, err)
+		defer os.RemoveAll(dir)
+		assert.ErrorContains(t, unpackBundle(&buf, filepath.Join(dir, "out")), name)
+	}
+}
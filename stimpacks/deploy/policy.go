@@ -0,0 +1,297 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/i18n"
+	"github.com/PremiereGlobal/stim/pkg/slack"
+	"github.com/PremiereGlobal/stim/pkg/utils"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// The confirmation policies of an environment
+const (
+	confirmPrompt = "prompt"
+	confirmTyped  = "typed"
+)
+
+// The Slack reactions used to approve and reject a deploy
+const (
+	approveReaction = "white_check_mark"
+	rejectReaction  = "x"
+)
+
+// defaultApprovalTimeout is how long to wait for approvals when no timeout
+// is set
+const defaultApprovalTimeout = 30 * time.Minute
+
+// approvalPollInterval is how often the approval message reactions are read
+const approvalPollInterval = 10 * time.Second
+
+// Policy describes the checks made before deploying to an environment
+type Policy struct {
+	Confirm   string          `yaml:"confirm"`
+	Approvals *Approvals      `yaml:"approvals"`
+	Freezes   []*FreezeWindow `yaml:"freezes"`
+}
+
+// Approvals describes the Slack approvals required before deploying.  An
+// approval message is posted to the channel and the deploy waits for Count
+// users (other than the deployer) to react with :white_check_mark:.  Any
+// approver reacting with :x: rejects the deploy.
+type Approvals struct {
+	Count     int      `yaml:"count"`
+	Channel   string   `yaml:"channel"`
+	Approvers []string `yaml:"approvers"`
+	Timeout   string   `yaml:"timeout"`
+}
+
+// FreezeWindow is a time window in which deploys are denied.  Start and End
+// are RFC 3339 timestamps.  Environments is only used by the top-level
+// `freezes`, where no environments means all environments.
+type FreezeWindow struct {
+	Start        string   `yaml:"start"`
+	End          string   `yaml:"end"`
+	Reason       string   `yaml:"reason"`
+	Environments []string `yaml:"environments"`
+
+	// Set for freezes read from Vault or listed
+	id        string
+	source    string
+	createdBy string
+}
+
+// checkPolicy enforces the policy of the environment before deploying to the
+// target (an instance name or `all`).  prompt adds a confirmation prompt if
+// the policy doesn't already require one (ex. `addConfirmationPrompt`).
+func (d *Deploy) checkPolicy(environment *Environment, target string, prompt bool) error {
+
+	policy := environment.Policy
+	if policy == nil {
+		policy = &Policy{}
+	}
+	confirm := policy.Confirm
+	if confirm == "" && (prompt || environment.Spec.AddConfirmationPrompt) {
+		confirm = confirmPrompt
+	}
+	if d.isProtectedEnvironment(environment.Name) {
+		confirm = confirmTyped
+	}
+
+	err := d.checkSignedConfig(environment)
+	if err != nil {
+		return err
+	}
+
+	err = d.checkFreeze(environment, target)
+	if err != nil {
+		return err
+	}
+
+	yes := d.stim.ConfigGetBool("deploy.yes")
+	switch confirm {
+	case confirmPrompt:
+		// The prompt is skipped if the instance was given on the command line
+		proceed, _ := d.stim.PromptBool(d.stim.Message(i18n.MessageProceed), yes || d.stim.ConfigGetString("deploy.instance") != "", false)
+		if !proceed {
+			return stim.Aborted(d.stim.Message(i18n.MessageDeployCancelled))
+		}
+	case confirmTyped:
+		if !yes {
+			if d.stim.IsAutomated() {
+				return errors.New(d.stim.Message(i18n.MessageTypedConfirmAutomated, environment.Name))
+			}
+			expected := target
+			if target == allOptionCli {
+				expected = environment.Name
+			}
+			typed, _ := d.stim.PromptString(d.stim.Message(i18n.MessageTypeToConfirm, expected), "")
+			if strings.TrimSpace(typed) != expected {
+				return stim.Aborted(d.stim.Message(i18n.MessageConfirmationMismatch))
+			}
+		}
+	}
+
+	if policy.Approvals != nil && policy.Approvals.Count > 0 {
+		return d.requestApproval(environment, target, policy.Approvals)
+	}
+
+	return nil
+}
+
+// isProtectedEnvironment returns true if the environment matches one of the
+// `deploy.protected-envs` patterns (ex. `prod*`) of the stim config, which
+// always require a typed confirmation
+func (d *Deploy) isProtectedEnvironment(name string) bool {
+	for _, pattern := range d.stim.ConfigGetStringSlice("deploy.protected-envs") {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// requestApproval posts an approval message to Slack and waits for enough
+// approvals, a rejection or the timeout
+func (d *Deploy) requestApproval(environment *Environment, target string, approvals *Approvals) error {
+
+	slackClient, err := d.stim.NewSlack()
+	if err != nil {
+		return err
+	}
+
+	timeout := defaultApprovalTimeout
+	if approvals.Timeout != "" {
+		timeout, _ = time.ParseDuration(approvals.Timeout)
+	}
+
+	// The requester must be known, or they could approve their own deploy
+	user, err := d.stim.User()
+	if err != nil {
+		return fmt.Errorf("Unable to determine who requests the deploy approval: %v", err)
+	}
+	requester, err := slackClient.GetUserID(user)
+	if err != nil {
+		return fmt.Errorf("Unable to find the Slack user of %s to request the deploy approval: %v", user, err)
+	}
+
+	var approvers []string
+	if len(approvals.Approvers) > 0 {
+		approvers, err = slackClient.GetUserIDs(approvals.Approvers)
+		if err != nil {
+			return err
+		}
+	}
+
+	text := fmt.Sprintf("*%s* requests approval to deploy *%s* to *%s/%s*.  React with :%s: to approve (%d needed) or :%s: to reject.  Expires in %s.",
+		user, d.deploymentName(environment), environment.Name, target, approveReaction, approvals.Count, rejectReaction, d.stim.FormatDuration(timeout))
+	ts, err := slackClient.PostMessage(&slack.Message{Channel: approvals.Channel, Text: text})
+	if err != nil {
+		return fmt.Errorf("Error posting approval request: %v", err)
+	}
+
+	d.log.Info("Waiting up to {} for {} approval(s) in Slack channel {}", d.stim.FormatDuration(timeout), approvals.Count, approvals.Channel)
+	deadline := d.stim.Clock().Now().Add(timeout)
+	for {
+		reactions, err := slackClient.GetReactions(approvals.Channel, ts)
+		if err != nil {
+			d.log.Warn("Unable to read approval reactions: {}", err)
+		}
+
+		approved, rejectedBy := tallyApprovals(reactions, requester, approvers)
+		result := ""
+		switch {
+		case rejectedBy != "":
+			err = errors.New(d.stim.Message(i18n.MessageApprovalRejected, rejectedBy))
+			result = fmt.Sprintf(":%s: Rejected by <@%s>", rejectReaction, rejectedBy)
+		case len(approved) >= approvals.Count:
+			d.log.Info("Deploy approved in Slack by {}", strings.Join(approved, ", "))
+			result = fmt.Sprintf(":%s: Approved by <@%s>", approveReaction, strings.Join(approved, ">, <@"))
+		case d.stim.Clock().Now().After(deadline):
+			err = errors.New(d.stim.Message(i18n.MessageApprovalExpired, d.stim.FormatDuration(timeout)))
+			result = ":hourglass: Expired"
+		default:
+			err = d.stim.Sleep(approvalPollInterval)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		_, postErr := slackClient.PostMessage(&slack.Message{Channel: approvals.Channel, Text: result, ThreadTS: ts})
+		if postErr != nil {
+			d.log.Warn("Unable to post approval result: {}", postErr)
+		}
+		return err
+	}
+}
+
+// tallyApprovals returns the users that approved a deploy and the first user
+// that rejected it.  The requester can't approve their own deploy, and only
+// the approvers can approve or reject when approvers are given.  Without a
+// requester nobody can approve.
+func tallyApprovals(reactions map[string][]string, requester string, approvers []string) ([]string, string) {
+
+	allowed := func(user string) bool {
+		return user != requester && (len(approvers) == 0 || utils.Contains(approvers, user))
+	}
+	if requester == "" {
+		return nil, ""
+	}
+
+	for _, user := range reactions[rejectReaction] {
+		if allowed(user) {
+			return nil, user
+		}
+	}
+
+	var approved []string
+	for _, user := range reactions[approveReaction] {
+		if allowed(user) {
+			approved = append(approved, user)
+		}
+	}
+
+	return approved, ""
+}
+
+// activeFreeze returns the freeze window that now is in, if any
+func activeFreeze(freezes []*FreezeWindow, now time.Time) (*FreezeWindow, error) {
+	for _, window := range freezes {
+		start, err := time.Parse(time.RFC3339, window.Start)
+		if err != nil {
+			return nil, err
+		}
+		end, err := time.Parse(time.RFC3339, window.End)
+		if err != nil {
+			return nil, err
+		}
+		if !now.Before(start) && now.Before(end) {
+			return window, nil
+		}
+	}
+	return nil, nil
+}
+
+// validatePolicy validates a 'policy' section
+func validatePolicy(policy *Policy) error {
+	if policy == nil {
+		return nil
+	}
+	if policy.Confirm != "" && policy.Confirm != confirmPrompt && policy.Confirm != confirmTyped {
+		return fmt.Errorf("Invalid policy confirm value '%s'.  Must be one of ['%s','%s']", policy.Confirm, confirmPrompt, confirmTyped)
+	}
+	if policy.Approvals != nil {
+		if policy.Approvals.Count > 0 && policy.Approvals.Channel == "" {
+			return errors.New("`channel` must be set for policy approvals")
+		}
+		if policy.Approvals.Timeout != "" {
+			if _, err := time.ParseDuration(policy.Approvals.Timeout); err != nil {
+				return fmt.Errorf("Invalid policy approvals timeout '%s'", policy.Approvals.Timeout)
+			}
+		}
+	}
+	return validateFreezes(policy.Freezes)
+}
+
+// validateFreezes checks the timestamps of freeze windows
+func validateFreezes(freezes []*FreezeWindow) error {
+	for _, window := range freezes {
+		start, err := time.Parse(time.RFC3339, window.Start)
+		if err != nil {
+			return fmt.Errorf("Invalid freeze start '%s', must be an RFC 3339 timestamp", window.Start)
+		}
+		end, err := time.Parse(time.RFC3339, window.End)
+		if err != nil {
+			return fmt.Errorf("Invalid freeze end '%s', must be an RFC 3339 timestamp", window.End)
+		}
+		if !end.After(start) {
+			return fmt.Errorf("Freeze end '%s' must be after its start", window.End)
+		}
+	}
+	return nil
+}
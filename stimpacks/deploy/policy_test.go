@@ -0,0 +1,64 @@
+package deploy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PremiereGlobal/stim/stim"
+	"gotest.tools/assert"
+)
+
+func TestTallyApprovals(t *testing.T) {
+	reactions := map[string][]string{
+		approveReaction: {"U1", "U2", "U3"},
+	}
+
+	approved, rejectedBy := tallyApprovals(reactions, "U1", nil)
+	assert.DeepEqual(t, approved, []string{"U2", "U3"})
+	assert.Equal(t, rejectedBy, "")
+
+	approved, _ = tallyApprovals(reactions, "U1", []string{"U3"})
+	assert.DeepEqual(t, approved, []string{"U3"})
+
+	reactions[rejectReaction] = []string{"U1", "U4"}
+	_, rejectedBy = tallyApprovals(reactions, "U1", nil)
+	assert.Equal(t, rejectedBy, "U4")
+
+	reactions[rejectReaction] = nil
+	approved, _ = tallyApprovals(reactions, "", nil)
+	assert.Equal(t, len(approved), 0)
+}
+
+func TestActiveFreeze(t *testing.T) {
+	freezes := []*FreezeWindow{
+		{Start: "2024-12-20T00:00:00Z", End: "2025-01-02T00:00:00Z", Reason: "Holidays"},
+	}
+
+	window, err := activeFreeze(freezes, time.Date(2024, 12, 25, 0, 0, 0, 0, time.UTC))
+	assert.NilError(t, err)
+	assert.Equal(t, window.Reason, "Holidays")
+
+	window, err = activeFreeze(freezes, time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC))
+	assert.NilError(t, err)
+	assert.Assert(t, window == nil)
+}
+
+func TestFreezeWindowAppliesTo(t *testing.T) {
+	all := &FreezeWindow{Start: "2024-12-20T00:00:00Z", End: "2025-01-02T00:00:00Z"}
+	assert.Assert(t, all.appliesTo("prod"))
+
+	prod := &FreezeWindow{Start: "2024-12-20T00:00:00Z", End: "2025-01-02T00:00:00Z", Environments: []string{"prod"}}
+	assert.Assert(t, prod.appliesTo("prod"))
+	assert.Assert(t, !prod.appliesTo("stage"))
+}
+
+func TestIsProtectedEnvironment(t *testing.T) {
+	s := stim.New()
+	s.ConfigOverride("deploy.protected-envs", []string{"prod*", "billing"})
+	d := &Deploy{stim: s, log: s.GetLogger()}
+
+	assert.Assert(t, d.isProtectedEnvironment("prod"))
+	assert.Assert(t, d.isProtectedEnvironment("prod-eu"))
+	assert.Assert(t, d.isProtectedEnvironment("billing"))
+	assert.Assert(t, !d.isProtectedEnvironment("staging"))
+}
@@ -0,0 +1,185 @@
+package deploy
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/PremiereGlobal/stim/pkg/utils"
+	"github.com/PremiereGlobal/stim/pkg/vault"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// kubeConfigSecretKeys are the keys stim reads from the kube-config secret
+var kubeConfigSecretKeys = []string{"cluster-server", "cluster-ca", "user-token"}
+
+// preflightCheck is the result of checking a Vault secret
+type preflightCheck struct {
+	Check  string
+	Path   string
+	Result string
+	Failed bool
+}
+
+// Preflight checks that the Vault secrets referenced by the selected
+// instance(s) exist, have the referenced keys and are readable by the current
+// token.  Secret values are never printed.
+func (d *Deploy) Preflight() error {
+
+	d.log = d.stim.GetLogger()
+
+	err := d.parseConfig()
+	if err != nil {
+		return err
+	}
+
+	environment, instances, err := d.selectInstances()
+	if err != nil || environment == nil {
+		return err
+	}
+
+	failures := 0
+	for i, instance := range instances {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("Environment: %s  Instance: %s\n\n", environment.Name, instance.Name)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CHECK\tPATH\tRESULT")
+		for _, check := range d.preflightInstance(instance) {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", check.Check, check.Path, check.Result)
+			if check.Failed {
+				failures++
+			}
+		}
+		w.Flush()
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("%d preflight check(s) failed", failures)
+	}
+
+	return nil
+}
+
+// checkSecrets runs the preflight checks of the instances about to be deployed
+// and returns a single error listing every missing or unreadable secret, so
+// they are all reported before any deploy starts instead of failing inside the
+// deploy container
+func (d *Deploy) checkSecrets(instances []*Instance) error {
+
+	if d.stim.ConfigGetBool("deploy.skip-secret-check") {
+		d.log.Warn("Skipping the Vault secret checks (--skip-secret-check)")
+		return nil
+	}
+	defer d.stim.Time(stim.PhaseSecretCheck)()
+
+	var failures []string
+	for _, instance := range instances {
+		failures = append(failures, failedChecks(instance.Name, d.preflightInstance(instance))...)
+	}
+
+	if len(failures) > 0 {
+		return stim.ConfigError(fmt.Errorf("%d secret check(s) failed, nothing was deployed:\n  %s", len(failures), strings.Join(failures, "\n  ")))
+	}
+
+	d.log.Debug("Vault secret checks passed")
+	return nil
+}
+
+// failedChecks returns a line for each failed check of an instance
+func failedChecks(instanceName string, checks []preflightCheck) []string {
+	var failures []string
+	for _, check := range checks {
+		if check.Failed {
+			failures = append(failures, fmt.Sprintf("%s: %s %s: %s", instanceName, check.Check, check.Path, check.Result))
+		}
+	}
+	return failures
+}
+
+// preflightInstance checks the kube-config secret and the Vault secrets of an
+// instance, up to `vault.secret-concurrency` at once
+func (d *Deploy) preflightInstance(instance *Instance) []preflightCheck {
+
+	kubeConfigPath := d.stim.KubeConfigSecretPath(instance.Spec.Kubernetes.Cluster, instance.Spec.Kubernetes.ServiceAccount)
+	checks := []preflightCheck{{Check: "kube-config", Path: kubeConfigPath}}
+	versions := []int{0}
+	keys := [][]string{kubeConfigSecretKeys}
+
+	for _, secret := range instance.Spec.Secrets {
+		if !secret.isVault() {
+			continue
+		}
+
+		var secretKeys []string
+		for _, key := range secret.SecretMaps {
+			secretKeys = append(secretKeys, key)
+		}
+		sort.Strings(secretKeys)
+
+		checks = append(checks, preflightCheck{Check: "secret", Path: secret.SecretPath})
+		versions = append(versions, int(secret.Version))
+		keys = append(keys, secretKeys)
+	}
+
+	// Log in to Vault (which may prompt) before the concurrent checks
+	vault, err := d.stim.NewVault()
+	if err != nil {
+		for i := range checks {
+			checks[i].Result = err.Error()
+			checks[i].Failed = true
+		}
+		return checks
+	}
+
+	utils.ForEachConcurrent(len(checks), d.stim.SecretConcurrency(), func(i int) error {
+		checks[i] = preflightSecret(vault, checks[i].Check, checks[i].Path, versions[i], keys[i])
+		return nil
+	})
+
+	return checks
+}
+
+// preflightSecret checks that the secret is readable and has the given keys
+func preflightSecret(vault *vault.Vault, check string, path string, version int, keys []string) preflightCheck {
+
+	result := preflightCheck{Check: check, Path: path, Failed: true}
+	if version != 0 {
+		result.Path = fmt.Sprintf("%s (version %d)", path, version)
+	}
+
+	canRead, err := vault.CanReadSecret(path)
+	if err != nil {
+		result.Result = fmt.Sprintf("Unable to check capabilities: %v", err)
+		return result
+	}
+	if !canRead {
+		result.Result = "Token does not have read capability"
+		return result
+	}
+
+	secretKeys, err := vault.GetSecretKeyNames(path, version)
+	if err != nil {
+		result.Result = err.Error()
+		return result
+	}
+
+	var missing []string
+	for _, key := range keys {
+		if !utils.Contains(secretKeys, key) {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		result.Result = "Missing keys: " + strings.Join(missing, ", ")
+		return result
+	}
+
+	result.Result = "ok"
+	result.Failed = false
+	return result
+}
@@ -0,0 +1,21 @@
+package deploy
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestFailedChecks(t *testing.T) {
+	checks := []preflightCheck{
+		{Check: "kube-config", Path: "secret/kubernetes/prod/deploy/kube-config", Result: "ok"},
+		{Check: "secret", Path: "secret/app/db", Result: "Token does not have read capability", Failed: true},
+		{Check: "secret", Path: "secret/app/api (version 2)", Result: "Missing keys: key", Failed: true},
+	}
+
+	assert.DeepEqual(t, failedChecks("us-east-1", checks), []string{
+		"us-east-1: secret secret/app/db: Token does not have read capability",
+		"us-east-1: secret secret/app/api (version 2): Missing keys: key",
+	})
+	assert.Assert(t, len(failedChecks("us-east-1", checks[:1])) == 0)
+}
@@ -0,0 +1,199 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/PremiereGlobal/stim/pkg/i18n"
+	"github.com/PremiereGlobal/stim/stim"
+	"gopkg.in/yaml.v2"
+)
+
+// defaultPreviewNamePattern allows names that are valid DNS labels (and so
+// can be used in Kubernetes namespaces and hostnames), ex. pr-1234
+const defaultPreviewNamePattern = `[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?`
+
+// previewNamePlaceholder is replaced with the preview name in the template
+const previewNamePlaceholder = "{NAME}"
+
+// Previews describes dynamically named preview environments (ex. one per pull
+// request) that are created from a template environment
+type Previews struct {
+	NamePattern   string            `yaml:"namePattern"`
+	Parameters    map[string]string `yaml:"parameters"`
+	DestroyScript string            `yaml:"destroyScript"`
+	Environment   *Environment      `yaml:"environment"`
+}
+
+// newPreviewEnvironment creates a preview environment with the given name
+// from the previews template.  `{NAME}` is replaced with the name and
+// `{<PARAMETER>}` with each parameter value in every string of the template.
+// Parameters without a default value must be given.
+func newPreviewEnvironment(previews *Previews, name string, params map[string]string) (*Environment, error) {
+
+	if previews == nil || previews.Environment == nil {
+		return nil, errors.New("No `previews.environment` template found in the deploy config")
+	}
+
+	pattern := previews.NamePattern
+	if pattern == "" {
+		pattern = defaultPreviewNamePattern
+	}
+	nameRegexp, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("Invalid `previews.namePattern`: %v", err)
+	}
+	if !nameRegexp.MatchString(name) {
+		return nil, fmt.Errorf("Preview name '%s' does not match the pattern `%s`", name, pattern)
+	}
+
+	values := map[string]string{previewNamePlaceholder: name}
+	for param, value := range previews.Parameters {
+		values["{"+param+"}"] = value
+	}
+	for param, value := range params {
+		if _, ok := previews.Parameters[param]; !ok {
+			return nil, fmt.Errorf("Unknown preview parameter '%s', must be one of %v", param, previewParameterNames(previews))
+		}
+		values["{"+param+"}"] = value
+	}
+	for _, param := range previewParameterNames(previews) {
+		if values["{"+param+"}"] == "" {
+			return nil, fmt.Errorf("Preview parameter '%s' is required", param)
+		}
+	}
+
+	// Round trip the template through YAML so that every string can be
+	// replaced without knowing the structure of the spec
+	b, err := yaml.Marshal(previews.Environment)
+	if err != nil {
+		return nil, err
+	}
+	var template interface{}
+	err = yaml.Unmarshal(b, &template)
+	if err != nil {
+		return nil, err
+	}
+	b, err = yaml.Marshal(replacePlaceholders(template, values))
+	if err != nil {
+		return nil, err
+	}
+
+	environment := &Environment{}
+	err = yaml.Unmarshal(b, environment)
+	if err != nil {
+		return nil, err
+	}
+	environment.Name = name
+	environment.preview = true
+
+	return environment, nil
+}
+
+// replacePlaceholders replaces the placeholders in every string value of the
+// unmarshalled YAML
+func replacePlaceholders(node interface{}, values map[string]string) interface{} {
+
+	switch n := node.(type) {
+	case string:
+		for placeholder, value := range values {
+			n = strings.Replace(n, placeholder, value, -1)
+		}
+		return n
+	case []interface{}:
+		for i := range n {
+			n[i] = replacePlaceholders(n[i], values)
+		}
+	case map[interface{}]interface{}:
+		for key, value := range n {
+			n[key] = replacePlaceholders(value, values)
+		}
+	}
+
+	return node
+}
+
+// previewParameterNames returns the sorted names of the preview parameters
+func previewParameterNames(previews *Previews) []string {
+	var names []string
+	for name := range previews.Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Preview creates (or destroys) a preview environment and deploys to each of
+// its instances.  Destroying runs `previews.destroyScript` instead of the
+// deployment script.
+func (d *Deploy) Preview(destroy bool) error {
+
+	d.log = d.stim.GetLogger()
+
+	name := d.stim.ConfigGetString("deploy-preview-name")
+	if name == "" {
+		return stim.UsageError(errors.New("Preview name not specified, use --name"))
+	}
+
+	params := make(map[string]string)
+	for _, param := range d.stim.ConfigGetStringSlice("deploy-preview-params") {
+		parts := strings.SplitN(param, "=", 2)
+		if len(parts) != 2 {
+			return stim.UsageError(fmt.Errorf("Invalid preview parameter '%s', must be in the format NAME=VALUE", param))
+		}
+		params[parts[0]] = parts[1]
+	}
+
+	err := d.loadConfig()
+	if err != nil {
+		return err
+	}
+
+	environment, err := newPreviewEnvironment(d.config.Previews, name, params)
+	if err != nil {
+		return stim.ConfigError(err)
+	}
+	d.config.Environments = append(d.config.Environments, environment)
+
+	if destroy {
+		if d.config.Previews.DestroyScript == "" {
+			return stim.ConfigError(errors.New("No `previews.destroyScript` set in the deploy config"))
+		}
+		d.config.Deployment.Script = d.config.Previews.DestroyScript
+	}
+
+	err = processConfig(&d.config, d.stim, d.log)
+	if err != nil {
+		return err
+	}
+	vault, err := d.stim.NewVault()
+	if err != nil {
+		return err
+	}
+	err = addStimEnvs(&d.config, vault, d.stim.KubeConfigSecretPath)
+	if err != nil {
+		return err
+	}
+
+	vault.StartTokenRenewer()
+	defer vault.StopTokenRenewer()
+
+	if !destroy && environment.Spec.AddConfirmationPrompt {
+		proceed, _ := d.stim.PromptBool(d.stim.Message(i18n.MessageProceed), false, false)
+		if !proceed {
+			return stim.Aborted(d.stim.Message(i18n.MessageDeployCancelled))
+		}
+	}
+
+	for _, instance := range environment.Instances {
+		err = d.Deploy(environment, instance)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/PremiereGlobal/stim/pkg/i18n"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// promotion is a `stim deploy promote` of the version deployed to the source
+// environment
+type promotion struct {
+	from string
+
+	// record is the last deploy to the source environment, whose images are
+	// deployed
+	record *DeployRecord
+}
+
+// Promote deploys the images of the last deploy to the --from environment to
+// the --to environment.  Only versions whose deploy to the source environment
+// succeeded can be promoted.
+func (d *Deploy) Promote() error {
+
+	d.log = d.stim.GetLogger()
+
+	from := d.stim.ConfigGetString("deploy-promote-from")
+	to := d.stim.ConfigGetString("deploy-promote-to")
+	if from == "" || to == "" {
+		return stim.UsageError(errors.New("--from and --to are required"))
+	}
+	if from == to {
+		return stim.UsageError(errors.New("--from and --to must be different environments"))
+	}
+	if d.stim.ConfigGetString("deploy.environment") != "" {
+		return stim.UsageError(errors.New("--environment can't be used with promote, the target environment is --to"))
+	}
+
+	d.stim.ConfigOverride("deploy.environment", to)
+	d.promotion = &promotion{from: from}
+	return d.runServices()
+}
+
+// loadPromotion reads the last deploy to the source environment of the
+// promotion from the deploy history and checks that it can be promoted
+func (d *Deploy) loadPromotion() error {
+
+	i, ok := d.config.environmentMap[d.promotion.from]
+	if !ok {
+		return stim.ConfigError(errors.New(d.stim.Message(i18n.MessageUnknownEnvironment, d.promotion.from)))
+	}
+	source := d.config.Environments[i]
+	deployment := d.deploymentName(source)
+
+	records, err := d.readHistory(func(r *DeployRecord) bool {
+		return r.Deployment == deployment && r.Environment == source.Name
+	}, 1)
+	if err != nil {
+		return err
+	}
+	var record *DeployRecord
+	if len(records) > 0 {
+		record = records[0]
+	}
+	err = checkPromotable(record, source.Name)
+	if err != nil {
+		return err
+	}
+	d.promotion.record = record
+
+	d.log.Info("Promoting {} deployed to {}/{} by {} at {} (deploy {})", formatImages(record.Images), record.Environment, record.Instance, record.User, d.stim.FormatTime(record.Time), record.ID)
+
+	// The deploy scripts and config come from this checkout, only the images
+	// are promoted
+	if commit, err := d.headCommit(); err == nil && record.Commit != "" && commit != record.Commit {
+		d.log.Warn("The deploy config is at commit {} but {} was deployed from {}, the deploy config of this commit is used with the promoted images", shortCommit(commit), source.Name, shortCommit(record.Commit))
+	}
+
+	return nil
+}
+
+// checkPromotable checks that the last deploy to the source environment
+// succeeded and recorded the images it deployed
+func checkPromotable(record *DeployRecord, from string) error {
+
+	if record == nil {
+		return fmt.Errorf("No deploy to %s is recorded, so there is no version to promote.  Deploys are only recorded on this machine unless `deploy.history-path` is set", from)
+	}
+	if record.Result != notifySuccess {
+		return fmt.Errorf("The last deploy to %s (%s) failed, only versions that passed %s can be promoted", from, record.ID, from)
+	}
+	if len(record.Images) == 0 {
+		return fmt.Errorf("The last deploy to %s (%s) has no images recorded, only the `images` of the deploy config can be promoted", from, record.ID)
+	}
+
+	return nil
+}
+
+// tag returns the tag of the image that was deployed to the source
+// environment
+func (p *promotion) tag(image *Image) (string, error) {
+
+	ref, ok := p.record.Images[image.Name]
+	if !ok {
+		return "", fmt.Errorf("Image %s was not deployed to %s (%s), so it can't be promoted", image.Name, p.from, p.record.ID)
+	}
+	if !strings.HasPrefix(ref, image.Repo+":") {
+		return "", stim.ConfigError(fmt.Errorf("Image %s was deployed to %s as %s, which is not from the %s repo", image.Name, p.from, ref, image.Repo))
+	}
+
+	return strings.TrimPrefix(ref, image.Repo+":"), nil
+}
+
+// formatImages lists image references for logs, ex. `my-org/app:1.4.0,
+// my-org/worker:1.4.0`
+func formatImages(images map[string]string) string {
+
+	refs := make([]string, 0, len(images))
+	for _, ref := range images {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+
+	return strings.Join(refs, ", ")
+}
@@ -0,0 +1,52 @@
+package deploy
+
+import (
+	"testing"
+
+	"github.com/PremiereGlobal/stim/stim"
+	"gotest.tools/assert"
+)
+
+func TestCheckPromotable(t *testing.T) {
+	assert.ErrorContains(t, checkPromotable(nil, "stage"), "No deploy to stage is recorded")
+
+	record := &DeployRecord{ID: "20200102T030405.000Z-8f14e45f", Environment: "stage", Result: notifyFailure, Images: map[string]string{"APP_IMAGE": "my-org/app:1.4.0"}}
+	assert.Error(t, checkPromotable(record, "stage"), "The last deploy to stage (20200102T030405.000Z-8f14e45f) failed, only versions that passed stage can be promoted")
+
+	record.Result = notifySuccess
+	assert.NilError(t, checkPromotable(record, "stage"))
+
+	// Deploys recorded before images were recorded can't be promoted
+	record.Images = nil
+	assert.ErrorContains(t, checkPromotable(record, "stage"), "has no images recorded")
+}
+
+func TestPromotionTag(t *testing.T) {
+	p := &promotion{from: "stage", record: &DeployRecord{ID: "20200102T030405.000Z-8f14e45f", Images: map[string]string{
+		"APP_IMAGE":    "registry.example.com:5000/my-org/app:1.4.0",
+		"WORKER_IMAGE": "my-org/worker:2.0.1",
+	}}}
+
+	tag, err := p.tag(&Image{Name: "APP_IMAGE", Repo: "registry.example.com:5000/my-org/app", Tag: "latest-semver(>=1)"})
+	assert.NilError(t, err)
+	assert.Equal(t, tag, "1.4.0")
+
+	_, err = p.tag(&Image{Name: "WORKER_IMAGE", Repo: "other-org/worker", Tag: "2.0.1"})
+	assert.Equal(t, stim.ExitCode(err), stim.ExitCodeConfig)
+	assert.ErrorContains(t, err, "deployed to stage as my-org/worker:2.0.1")
+
+	_, err = p.tag(&Image{Name: "CRON_IMAGE", Repo: "my-org/cron", Tag: "1.0"})
+	assert.ErrorContains(t, err, "Image CRON_IMAGE was not deployed to stage")
+}
+
+func TestDeployedImages(t *testing.T) {
+	instance := &Instance{Name: "main", Spec: &Spec{
+		Images: []*Image{{Name: "APP_IMAGE", Repo: "my-org/app", Tag: "git-sha"}},
+	}}
+	assert.Assert(t, deployedImages(instance) == nil)
+
+	setEnvVar(instance, "APP_IMAGE", "my-org/app:0123abc", "global")
+	setEnvVar(instance, "APP_IMAGE_TAG", "0123abc", "global")
+	assert.DeepEqual(t, deployedImages(instance), map[string]string{"APP_IMAGE": "my-org/app:0123abc"})
+	assert.Equal(t, formatImages(map[string]string{"B": "b:1", "A": "a:2"}), "a:2, b:1")
+}
@@ -0,0 +1,227 @@
+package deploy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	githubpkg "github.com/PremiereGlobal/stim/pkg/github"
+)
+
+// defaultReleaseTag is the tag created when `release.tag` is not set
+const defaultReleaseTag = "deploy/{ENVIRONMENT}/{INSTANCE}/{DATE}-{TIME}"
+
+const defaultGitlabURL = "https://gitlab.com"
+
+// releaseClient is used for GitLab API requests
+var releaseClient = &http.Client{Timeout: 30 * time.Second}
+
+// releaseTag returns the tag name for a deploy with the placeholders replaced
+func releaseTag(template string, environment *Environment, instance *Instance, now time.Time) string {
+	now = now.UTC()
+	return strings.NewReplacer(
+		"{ENVIRONMENT}", environment.Name,
+		"{INSTANCE}", instance.Name,
+		"{CLUSTER}", instance.Spec.Kubernetes.Cluster,
+		"{DATE}", now.Format("2006-01-02"),
+		"{TIME}", now.Format("150405"),
+	).Replace(template)
+}
+
+// release tags the deployed commit and creates the GitHub or GitLab release
+// (if configured).  The deploy has already succeeded so errors are logged
+// rather than failing it.
+func (d *Deploy) release(environment *Environment, instance *Instance) {
+
+	if environment.Release == nil {
+		return
+	}
+
+	release := environment.Release
+	tag := releaseTag(release.Tag, environment, instance, d.stim.Clock().Now())
+	summary := d.releaseSummary(environment, instance)
+
+	err := d.createReleaseTag(release, tag, summary)
+	if err != nil {
+		d.log.Warn("Unable to tag the deployed commit: {}", err)
+		return
+	}
+	d.log.Info("Tagged the deployed commit as '{}'", tag)
+
+	if release.Github != nil {
+		err = d.createGithubRelease(release.Github, tag, summary)
+	} else if release.Gitlab != nil {
+		err = d.createGitlabRelease(release.Gitlab, tag, summary)
+	}
+	if err != nil {
+		d.log.Warn("Unable to create the release for tag '{}': {}", tag, err)
+	}
+}
+
+// createReleaseTag creates an annotated tag for the commit checked out in the
+// deploy config directory and pushes it to the remote
+func (d *Deploy) createReleaseTag(release *Release, tag string, summary string) error {
+
+	_, err := d.git("tag", "-a", tag, "-m", summary, "HEAD")
+	if err != nil {
+		return err
+	}
+
+	if !release.SkipPush {
+		_, err = d.git("push", release.Remote, "refs/tags/"+tag)
+	}
+
+	return err
+}
+
+// releaseSummary describes the deploy for the tag message and release notes
+func (d *Deploy) releaseSummary(environment *Environment, instance *Instance) string {
+
+	user, err := d.stim.User()
+	if err != nil {
+		user = "unknown"
+	}
+	commit, err := d.headCommit()
+	if err != nil {
+		commit = "unknown"
+	}
+
+	lines := []string{
+		fmt.Sprintf("Deployed to %s/%s by %s", environment.Name, instance.Name, user),
+		"",
+		"Environment: " + environment.Name,
+		"Instance: " + instance.Name,
+		"Cluster: " + instance.Spec.Kubernetes.Cluster,
+		"Commit: " + commit,
+		"Deployed by: " + user,
+		"Deployed at: " + d.stim.Clock().Now().UTC().Format(time.RFC3339),
+	}
+	return strings.Join(lines, "\n")
+}
+
+// git runs a git command in the deploy config directory and returns the
+// trimmed output
+func (d *Deploy) git(args ...string) (string, error) {
+
+	configAbs, err := filepath.Abs(d.config.configFilePath)
+	if err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command("git", append([]string{"-C", filepath.Dir(configAbs)}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// headCommit returns the commit checked out in the deploy config directory,
+// or the one the deployed package was created from
+func (d *Deploy) headCommit() (string, error) {
+	if d.pkg == nil {
+		return d.git("rev-parse", "HEAD")
+	}
+	if d.pkg.manifest.Commit == "" {
+		return "", errors.New("The package was not created from a git repo")
+	}
+	return d.pkg.manifest.Commit, nil
+}
+
+// releaseToken reads the API token from Vault (if secretPath is set) or the
+// given environment variable
+func (d *Deploy) releaseToken(secretPath string, secretKey string, envVar string) (string, error) {
+
+	if secretPath != "" {
+		if secretKey == "" {
+			secretKey = "token"
+		}
+		vault, err := d.stim.NewVault()
+		if err != nil {
+			return "", err
+		}
+		return vault.GetSecretKey(secretPath, secretKey)
+	}
+
+	token := os.Getenv(envVar)
+	if token == "" {
+		return "", fmt.Errorf("%s is not set", envVar)
+	}
+	return token, nil
+}
+
+// createGithubRelease creates a GitHub release for the tag
+func (d *Deploy) createGithubRelease(github *GithubRelease, tag string, summary string) error {
+
+	client, err := d.githubClient(github.URL, github.SecretPath, github.SecretKey)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.CreateRelease(github.Repo, &githubpkg.ReleaseRequest{TagName: tag, Name: tag, Body: summary})
+	return err
+}
+
+// createGitlabRelease creates a GitLab release for the tag
+func (d *Deploy) createGitlabRelease(gitlab *GitlabRelease, tag string, summary string) error {
+
+	token, err := d.releaseToken(gitlab.SecretPath, gitlab.SecretKey, "GITLAB_TOKEN")
+	if err != nil {
+		return err
+	}
+
+	baseURL := gitlab.URL
+	if baseURL == "" {
+		baseURL = defaultGitlabURL
+	}
+
+	return postRelease(
+		fmt.Sprintf("%s/api/v4/projects/%s/releases", strings.TrimSuffix(baseURL, "/"), url.PathEscape(gitlab.Project)),
+		map[string]string{"PRIVATE-TOKEN": token},
+		map[string]string{"tag_name": tag, "name": tag, "description": summary},
+	)
+}
+
+// postRelease posts the release as JSON and returns an error for non-2xx
+// responses
+func postRelease(endpoint string, headers map[string]string, body interface{}) error {
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := releaseClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("Unexpected response %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+
+	return nil
+}
@@ -0,0 +1,17 @@
+package deploy
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestReleaseTag(t *testing.T) {
+	environment := &Environment{Name: "prod"}
+	instance := &Instance{Name: "us-west-2", Spec: &Spec{Kubernetes: Kubernetes{Cluster: "prod.my-domain.com"}}}
+	now := time.Date(2024, 6, 1, 15, 4, 5, 0, time.UTC)
+
+	assert.Equal(t, releaseTag(defaultReleaseTag, environment, instance, now), "deploy/prod/us-west-2/2024-06-01-150405")
+	assert.Equal(t, releaseTag("release-{CLUSTER}-{DATE}", environment, instance, now), "release-prod.my-domain.com-2024-06-01")
+}
@@ -0,0 +1,297 @@
+package deploy
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/PremiereGlobal/stim/pkg/utils"
+	stimvault "github.com/PremiereGlobal/stim/pkg/vault"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// rotateHookEvent is the DEPLOY_EVENT of rotate hooks
+const rotateHookEvent = "rotate"
+
+// defaultGenerateLength is the length of generated secret values when no
+// length is set
+const defaultGenerateLength = 32
+
+// generateAlphabet is the characters of generated secret values.  Symbols are
+// left out so that values can be used in URLs and connection strings as is.
+const generateAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// Rotate maps the rotating Vault secrets of an instance to the actions that
+// make the application pick up the new values
+type Rotate struct {
+	Secrets  []*RotateSecret `yaml:"secrets"`
+	Redeploy bool            `yaml:"redeploy"`
+	Restart  []*Rollout      `yaml:"restart"`
+	Hooks    []*Hook         `yaml:"hooks"`
+}
+
+// RotateSecret is a Vault secret that is rotated by writing to its path.
+// Keys listed in `generate` are set to new random values, keys in `data` are
+// set and the other keys of the secret are kept.
+type RotateSecret struct {
+	Name     string            `yaml:"name"`
+	Path     string            `yaml:"path"`
+	Data     map[string]string `yaml:"data"`
+	Generate []string          `yaml:"generate"`
+	Length   int               `yaml:"length"`
+}
+
+// RotateSecrets rotates the secrets in the `rotate` config of the selected
+// instance(s) and then runs the rotate actions followed by the health checks
+// of each instance
+func (d *Deploy) RotateSecrets() error {
+
+	d.log = d.stim.GetLogger()
+
+	err := d.parseConfig()
+	if err != nil {
+		return err
+	}
+	vault, err := d.stim.NewVault()
+	if err != nil {
+		return err
+	}
+	err = addStimEnvs(&d.config, vault, d.stim.KubeConfigSecretPath)
+	if err != nil {
+		return err
+	}
+
+	environment, instances, err := d.selectInstances()
+	if err != nil || environment == nil {
+		return err
+	}
+
+	var rotating []*Instance
+	for _, instance := range instances {
+		if len(rotateSecrets(instance.Spec.Rotate, d.stim.ConfigGetStringSlice("deploy-rotate-secrets"))) > 0 {
+			rotating = append(rotating, instance)
+		}
+	}
+	if len(rotating) == 0 {
+		return stim.ConfigError(fmt.Errorf("No secrets to rotate in the `rotate` config of the selected instance(s) in environment '%s'", environment.Name))
+	}
+
+	// Keep the Vault token alive for the duration of the rotation(s)
+	vault.StartTokenRenewer()
+	defer vault.StopTokenRenewer()
+
+	target := allOptionCli
+	if len(instances) == 1 {
+		target = instances[0].Name
+	}
+	err = d.checkPolicy(environment, target, true)
+	if err != nil {
+		return err
+	}
+
+	for _, instance := range rotating {
+		err = d.rotateInstance(vault, environment, instance)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rotateInstance rotates the secrets of an instance and runs its rotate
+// actions.  The rotation is only reported as successful once the health
+// checks pass.
+func (d *Deploy) rotateInstance(vault secretRotator, environment *Environment, instance *Instance) error {
+
+	rotate := instance.Spec.Rotate
+	d.log.Info("Rotating secrets of '{}' environment in instance: {}", environment.Name, instance.Name)
+
+	err := d.rotateVaultSecrets(vault, rotateSecrets(rotate, d.stim.ConfigGetStringSlice("deploy-rotate-secrets")))
+	if err == nil {
+		err = d.runRotateActions(environment, instance)
+	}
+	if err != nil {
+		d.notify(environment, instance, notifyRotateFailure, err)
+		return stim.DeployError(err)
+	}
+
+	d.notify(environment, instance, notifyRotateSuccess, nil)
+	d.log.Info("Rotated secrets of instance {}", instance.Name)
+
+	return nil
+}
+
+// secretRotator reads and writes the Vault secrets being rotated
+type secretRotator interface {
+	GetSecretKeys(path string) (map[string]string, error)
+	WriteSecretKeys(path string, keys map[string]string) error
+}
+
+// rotateVaultSecrets writes the new value of each secret to Vault
+func (d *Deploy) rotateVaultSecrets(vault secretRotator, secrets []*RotateSecret) error {
+
+	for _, secret := range secrets {
+		d.log.Info("Rotating secret {} ({})", secret.Name, secret.Path)
+
+		keys, err := rotatedSecretKeys(vault, secret)
+		if err != nil {
+			return err
+		}
+
+		err = vault.WriteSecretKeys(secret.Path, keys)
+		if err != nil {
+			return fmt.Errorf("Unable to rotate secret %s: %v", secret.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// rotatedSecretKeys returns the keys written to rotate a secret.  Secrets with
+// `data` or `generate` keys are KV secrets: their existing keys are read and
+// kept, the `generate` keys are set to new random values and the `data` keys
+// are set.  A missing secret is created.  Other secrets are Vault rotation
+// endpoints (ex. `database/rotate-role/<role>`), which are written empty.
+func rotatedSecretKeys(vault secretRotator, secret *RotateSecret) (map[string]string, error) {
+
+	keys := make(map[string]string)
+	if len(secret.Data) == 0 && len(secret.Generate) == 0 {
+		return keys, nil
+	}
+
+	existing, err := vault.GetSecretKeys(secret.Path)
+	if err != nil && !stimvault.IsNotFound(err) {
+		return nil, fmt.Errorf("Unable to read secret %s: %v", secret.Name, err)
+	}
+	for key, value := range existing {
+		keys[key] = value
+	}
+
+	length := secret.Length
+	if length == 0 {
+		length = defaultGenerateLength
+	}
+	for _, key := range secret.Generate {
+		keys[key], err = generateSecretValue(length)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for key, value := range secret.Data {
+		keys[key] = value
+	}
+
+	return keys, nil
+}
+
+// runRotateActions runs the rotate hooks of the instance, then redeploys it
+// and restarts its rollouts so that the application uses the new secret
+// values, then waits for the health checks.  A redeploy already runs the
+// health checks so they are only run again if there are restarts.
+func (d *Deploy) runRotateActions(environment *Environment, instance *Instance) error {
+
+	rotate := instance.Spec.Rotate
+	d.addNamespace(instance)
+
+	err := d.execHooks(instance, rotateHookEvent, rotate.Hooks, nil)
+	if err != nil {
+		return err
+	}
+
+	if rotate.Redeploy {
+		err := d.Deploy(environment, instance)
+		if err != nil {
+			return err
+		}
+		if len(rotate.Restart) == 0 {
+			return nil
+		}
+	}
+
+	if len(rotate.Restart) > 0 {
+		kube, cleanup, err := d.instanceKubernetes(instance)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		namespace := instanceNamespace(instance)
+		for _, rollout := range rotate.Restart {
+			ns := rollout.Namespace
+			if ns == "" {
+				ns = namespace
+			}
+			d.log.Info("Restarting {} {}/{}", rollout.Kind, ns, rollout.Name)
+			err = kube.RolloutRestart(rollout.Kind, ns, rollout.Name, d.stim.Clock().Now())
+			if err != nil {
+				return fmt.Errorf("Unable to restart %s %s/%s: %v", rollout.Kind, ns, rollout.Name, err)
+			}
+		}
+	}
+
+	return d.runHealthChecks(instance)
+}
+
+// rotateSecrets returns the secrets of the rotate config, or only the named
+// secrets if any names are given
+func rotateSecrets(rotate *Rotate, names []string) []*RotateSecret {
+	if rotate == nil {
+		return nil
+	}
+	if len(names) == 0 {
+		return rotate.Secrets
+	}
+
+	var secrets []*RotateSecret
+	for _, secret := range rotate.Secrets {
+		if utils.Contains(names, secret.Name) {
+			secrets = append(secrets, secret)
+		}
+	}
+	return secrets
+}
+
+// generateSecretValue returns a random alphanumeric value of the given length
+func generateSecretValue(length int) (string, error) {
+	value := make([]byte, length)
+	max := big.NewInt(int64(len(generateAlphabet)))
+	for i := range value {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		value[i] = generateAlphabet[n.Int64()]
+	}
+	return string(value), nil
+}
+
+// validateRotate checks the secrets, restarts and hooks of a rotate config
+func validateRotate(rotate *Rotate) error {
+	if rotate == nil {
+		return nil
+	}
+	names := make(map[string]bool)
+	for _, secret := range rotate.Secrets {
+		if secret.Name == "" || secret.Path == "" {
+			return errors.New("`name` and `path` must be set for each rotate secret")
+		}
+		if names[secret.Name] {
+			return fmt.Errorf("Duplicate rotate secret name '%s'", secret.Name)
+		}
+		names[secret.Name] = true
+		if secret.Length < 0 {
+			return fmt.Errorf("Invalid length %d for rotate secret '%s'", secret.Length, secret.Name)
+		}
+	}
+	for _, rollout := range rotate.Restart {
+		if rollout.Kind != "Deployment" && rollout.Kind != "StatefulSet" {
+			return fmt.Errorf("Invalid restart kind '%s'.  Must be one of ['Deployment','StatefulSet']", rollout.Kind)
+		}
+		if rollout.Name == "" {
+			return errors.New("`name` must be set for each rotate restart")
+		}
+	}
+	return validateHookList(rotate.Hooks)
+}
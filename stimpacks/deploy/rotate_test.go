@@ -0,0 +1,79 @@
+package deploy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PremiereGlobal/stim/stim/stimtest"
+	"gotest.tools/assert"
+)
+
+func TestRotateSecrets(t *testing.T) {
+	rotate := &Rotate{Secrets: []*RotateSecret{
+		{Name: "db", Path: "database/rotate-role/my-app"},
+		{Name: "api-key", Path: "secret/my-app"},
+	}}
+
+	assert.Equal(t, len(rotateSecrets(rotate, nil)), 2)
+	assert.Equal(t, len(rotateSecrets(nil, nil)), 0)
+
+	secrets := rotateSecrets(rotate, []string{"api-key", "missing"})
+	assert.Equal(t, len(secrets), 1)
+	assert.Equal(t, secrets[0].Name, "api-key")
+}
+
+func TestGenerateSecretValue(t *testing.T) {
+	a, err := generateSecretValue(48)
+	assert.NilError(t, err)
+	assert.Equal(t, len(a), 48)
+	assert.Equal(t, strings.Trim(a, generateAlphabet), "")
+
+	b, err := generateSecretValue(48)
+	assert.NilError(t, err)
+	assert.Assert(t, a != b)
+}
+
+// The keys of a KV secret that aren't rotated are kept, whether or not keys
+// are generated
+func TestRotateVaultSecrets(t *testing.T) {
+	vault := &stimtest.Vault{Secrets: map[string]map[string]string{
+		"secret/my-app":  {"username": "my-app", "password": "old", "api-key": "old"},
+		"secret/webhook": {"url": "https://hooks.my-domain.com", "token": "old"},
+	}}
+	d := &Deploy{log: &stimtest.Logger{}}
+
+	err := d.rotateVaultSecrets(vault, []*RotateSecret{
+		{Name: "db", Path: "database/rotate-role/my-app"},
+		{Name: "app", Path: "secret/my-app", Generate: []string{"password", "api-key"}, Length: 16},
+		{Name: "webhook", Path: "secret/webhook", Data: map[string]string{"token": "new"}},
+		{Name: "new", Path: "secret/new", Generate: []string{"key"}},
+	})
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, vault.Secrets["database/rotate-role/my-app"], map[string]string{})
+
+	app := vault.Secrets["secret/my-app"]
+	assert.Equal(t, len(app), 3)
+	assert.Equal(t, app["username"], "my-app")
+	assert.Equal(t, len(app["password"]), 16)
+	assert.Assert(t, app["api-key"] != "old" && app["api-key"] != app["password"])
+
+	assert.DeepEqual(t, vault.Secrets["secret/webhook"], map[string]string{"url": "https://hooks.my-domain.com", "token": "new"})
+
+	assert.Equal(t, len(vault.Secrets["secret/new"]["key"]), defaultGenerateLength)
+}
+
+func TestRotatedSecretKeysData(t *testing.T) {
+	vault := &stimtest.Vault{Secrets: map[string]map[string]string{
+		"secret/my-app": {"password": "old", "region": "us-east-1"},
+	}}
+
+	// `data` overrides generated keys
+	keys, err := rotatedSecretKeys(vault, &RotateSecret{Name: "app", Path: "secret/my-app", Generate: []string{"password", "region"}, Data: map[string]string{"region": "us-west-2"}})
+	assert.NilError(t, err)
+	assert.Equal(t, keys["region"], "us-west-2")
+	assert.Equal(t, len(keys["password"]), defaultGenerateLength)
+
+	// The existing secret isn't modified until it is written
+	assert.Equal(t, vault.Secrets["secret/my-app"]["password"], "old")
+}
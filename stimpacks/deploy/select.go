@@ -0,0 +1,144 @@
+package deploy
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/PremiereGlobal/stim/pkg/i18n"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// environmentItems returns the environments for the detailed prompt, with
+// their instances, policy and last deploy
+func (d *Deploy) environmentItems(lastDeploys map[string]*DeployRecord) []*stim.PromptItem {
+
+	items := make([]*stim.PromptItem, len(d.config.Environments))
+	for i, environment := range d.config.Environments {
+		names := make([]string, len(environment.Instances))
+		var last *DeployRecord
+		lastInstance := ""
+		for j, instance := range environment.Instances {
+			names[j] = instance.Name
+			if deploy, ok := lastDeploys[d.lastDeployKey(environment, instance)]; ok && (last == nil || deploy.Time.After(last.Time)) {
+				last = deploy
+				lastInstance = instance.Name
+			}
+		}
+
+		details := []string{"Instances: " + strings.Join(names, ", ")}
+		if environment.Policy != nil && environment.Policy.Confirm != "" {
+			details = append(details, "Confirm: "+environment.Policy.Confirm)
+		}
+		details = append(details, "Last deployed: "+d.describeLastDeploy(last, lastInstance))
+		items[i] = &stim.PromptItem{Name: environment.Name, Details: details}
+	}
+
+	return items
+}
+
+// instanceItems returns the instances of an environment for the detailed
+// prompt, with their cluster and last deploy
+func (d *Deploy) instanceItems(environment *Environment, lastDeploys map[string]*DeployRecord) []*stim.PromptItem {
+
+	items := []*stim.PromptItem{}
+	if !environment.RemoveAllPrompt {
+		details := []string{fmt.Sprintf("Deploys the %d instances of %s one after another", len(environment.Instances), environment.Name)}
+		if environment.Strategy != nil {
+			details = append(details, "Rollout: "+describeBatches(rolloutBatches(environment)))
+		}
+		items = append(items, &stim.PromptItem{Name: allOptionPrompt, Details: details})
+	}
+	for _, instance := range environment.Instances {
+		kube := instance.Spec.Kubernetes
+		details := []string{"Cluster: " + kube.Cluster}
+		if kube.Namespace != "" {
+			details = append(details, "Namespace: "+kube.Namespace)
+		}
+		details = append(details, "Last deployed: "+d.describeLastDeploy(lastDeploys[d.lastDeployKey(environment, instance)], ""))
+		items = append(items, &stim.PromptItem{Name: instance.Name, Details: details})
+	}
+
+	return items
+}
+
+// describeLastDeploy describes a last deploy (ex. `3 hours ago by jdoe
+// (success)`), with its instance if set
+func (d *Deploy) describeLastDeploy(last *DeployRecord, instance string) string {
+	if last == nil {
+		return "never from this machine"
+	}
+	description := fmt.Sprintf("%s by %s (%s)", d.stim.FormatRelative(last.Time), last.User, last.Result)
+	if instance != "" {
+		description = instance + " " + description
+	}
+	return description
+}
+
+// lastDeploys returns the last deploy of each instance from this machine, by
+// lastDeployKey, from the local deploy history
+func (d *Deploy) lastDeploys() map[string]*DeployRecord {
+	path := filepath.Join(d.stim.ConfigGetCacheDir("deploy"), historyFile)
+	records, err := readHistoryFile(path)
+	if err != nil {
+		d.log.Debug("Unable to read {}: {}", path, err)
+	}
+	return latestDeploys(records)
+}
+
+// lastDeployKey is the key of an instance in the last deploys, which are
+// read from the history shared by every deployment
+func (d *Deploy) lastDeployKey(environment *Environment, instance *Instance) string {
+	return d.deploymentName(environment) + "/" + environment.Name + "/" + instance.Name
+}
+
+// latestDeploys returns the latest of the deploy records of each instance,
+// by deployment, environment and instance (ex. `web/prod/us-east`)
+func latestDeploys(records []*DeployRecord) map[string]*DeployRecord {
+
+	lastDeploys := make(map[string]*DeployRecord)
+	for _, r := range records {
+		key := r.Deployment + "/" + r.Environment + "/" + r.Instance
+		if last, ok := lastDeploys[key]; !ok || !r.Time.Before(last.Time) {
+			lastDeploys[key] = r
+		}
+	}
+
+	return lastDeploys
+}
+
+// selectionKey is the key of a remembered prompt selection for this deploy
+// config (ex. `deploy/api/environment`)
+func (d *Deploy) selectionKey(name string) string {
+	configAbs, _ := filepath.Abs(d.config.configFilePath)
+	return "deploy/" + filepath.Base(filepath.Dir(configAbs)) + "/" + name
+}
+
+// promptInstances prompts to select any number of the instances of the
+// environment (`--multi-select`).  It returns the name of the selection: ALL,
+// an instance or the comma separated instances, which are also returned if
+// there is more than one but not all of them.  The name is empty if nothing
+// was selected.
+func (d *Deploy) promptInstances(environment *Environment) ([]*Instance, string) {
+
+	names := make([]string, len(environment.Instances))
+	for i, instance := range environment.Instances {
+		names[i] = instance.Name
+	}
+
+	selected, _ := d.stim.PromptMultiList(d.selectionKey("instances/"+environment.Name), d.stim.Message(i18n.MessageWhichInstance), names, nil)
+	switch {
+	case len(selected) == 0:
+		return nil, ""
+	case len(selected) == 1:
+		return nil, selected[0]
+	case len(selected) == len(names) && !environment.RemoveAllPrompt:
+		return nil, allOptionCli
+	}
+
+	instances := make([]*Instance, len(selected))
+	for i, name := range selected {
+		instances[i] = environment.Instances[environment.instanceMap[name]]
+	}
+	return instances, strings.Join(selected, ",")
+}
@@ -0,0 +1,58 @@
+package deploy
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/PremiereGlobal/stim/stim"
+	"gotest.tools/assert"
+)
+
+func TestInstanceItems(t *testing.T) {
+	s := stim.New()
+	d := &Deploy{stim: s, log: s.GetLogger()}
+	environment := &Environment{Name: "prod", Notifications: &Notifications{Name: "web"}, Instances: []*Instance{
+		{Name: "us-east", Spec: &Spec{Kubernetes: Kubernetes{Cluster: "east", Namespace: "web"}}},
+		{Name: "us-west", Spec: &Spec{Kubernetes: Kubernetes{Cluster: "west"}}},
+	}}
+	lastDeploys := map[string]*DeployRecord{
+		"web/prod/us-west": {Time: time.Now().Add(-time.Hour), User: "jdoe", Result: notifyFailure},
+	}
+
+	items := d.instanceItems(environment, lastDeploys)
+	assert.Equal(t, len(items), 3)
+	assert.Equal(t, items[0].Name, allOptionPrompt)
+	assert.DeepEqual(t, items[1].Details, []string{"Cluster: east", "Namespace: web", "Last deployed: never from this machine"})
+	assert.Equal(t, items[2].Details[0], "Cluster: west")
+	assert.Equal(t, items[2].Details[1], "Last deployed: "+s.FormatRelative(lastDeploys["web/prod/us-west"].Time)+" by jdoe (failure)")
+
+	environment.RemoveAllPrompt = true
+	assert.Equal(t, d.instanceItems(environment, lastDeploys)[0].Name, "us-east")
+}
+
+func TestLatestDeploys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stim-deploy")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, historyFile)
+
+	records, err := readHistoryFile(path)
+	assert.NilError(t, err)
+	assert.Equal(t, len(latestDeploys(records)), 0)
+
+	first := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	assert.NilError(t, appendHistory(path, &DeployRecord{Deployment: "web", Environment: "prod", Instance: "us-east", User: "jdoe", Time: first, Result: notifySuccess}))
+	assert.NilError(t, appendHistory(path, &DeployRecord{Deployment: "web", Environment: "prod", Instance: "us-east", User: "asmith", Time: first.Add(time.Hour), Result: notifyFailure}))
+	assert.NilError(t, appendHistory(path, &DeployRecord{Deployment: "api", Environment: "prod", Instance: "us-east", User: "jdoe", Time: first, Result: notifySuccess}))
+
+	records, err = readHistoryFile(path)
+	assert.NilError(t, err)
+	lastDeploys := latestDeploys(records)
+	assert.Equal(t, len(lastDeploys), 2)
+	assert.Equal(t, lastDeploys["web/prod/us-east"].User, "asmith")
+	assert.Equal(t, lastDeploys["web/prod/us-east"].Result, notifyFailure)
+	assert.Equal(t, lastDeploys["api/prod/us-east"].Time, first)
+}
@@ -3,11 +3,44 @@ package deploy
 import (
 	"fmt"
 
+	"github.com/PremiereGlobal/stim/pkg/env"
+	"github.com/PremiereGlobal/stim/pkg/shell"
 	"github.com/PremiereGlobal/stim/stim"
 )
 
+// The shells that deployment scripts can be run with
+const (
+	deployShellSh         = "sh"
+	deployShellPowerShell = "powershell"
+)
+
 // startDeployShell starts an instance deployment using the command shell
-func (d *Deploy) startDeployShell(instance *Instance) {
+func (d *Deploy) startDeployShell(instance *Instance, command string) error {
+
+	e, err := d.instanceEnv(instance)
+	if err != nil {
+		return err
+	}
+	defer e.Close()
+
+	d.log.Debug("Running {}", command)
+	stopTimer := d.stim.Time(stim.PhaseScriptRun)
+	e.AddEnvVars(d.stim.TraceEnv()...)
+	out, err := e.Run(command)
+	stopTimer()
+	if err != nil {
+		return fmt.Errorf("Error running command: %v", err)
+	}
+
+	d.log.Info(out)
+
+	return nil
+}
+
+// instanceEnv sets up the shell environment of an instance deployment, with
+// its env vars, secrets, kubeconfig and tools.  The environment must be
+// closed when done.
+func (d *Deploy) instanceEnv(instance *Instance) (*env.Env, error) {
 
 	envs := make([]string, len(instance.Spec.EnvironmentVars))
 	for i, e := range instance.Spec.EnvironmentVars {
@@ -15,24 +48,25 @@ func (d *Deploy) startDeployShell(instance *Instance) {
 	}
 
 	d.log.Debug("Setting working directory {}", d.config.Deployment.fullDirectoryPath)
-	e := d.stim.Env(&stim.EnvConfig{
+	return d.stim.Env(&stim.EnvConfig{
 		EnvVars: envs,
 		Kubernetes: &stim.EnvConfigKubernetes{
 			Cluster:          instance.Spec.Kubernetes.Cluster,
 			ServiceAccount:   instance.Spec.Kubernetes.ServiceAccount,
 			DefaultNamespace: "default"},
 		Vault: &stim.EnvConfigVault{
-			SecretItems: instance.Spec.Secrets,
+			SecretItems: vaultSecretItems(instance),
 		},
 		WorkDir: d.config.Deployment.fullDirectoryPath,
+		Shell:   d.deployShell(),
 		Tools:   instance.Spec.Tools,
 	})
+}
 
-	d.log.Debug("Running script ./{}", d.config.Deployment.Script)
-	out, err := e.Run("./" + d.config.Deployment.Script)
-	if err != nil {
-		d.log.Fatal("Error running command: {}", err)
+// deployShell returns the shell that the deployment runs in on this host
+func (d *Deploy) deployShell() []string {
+	if d.config.Deployment.Shell == deployShellPowerShell {
+		return shell.PowerShell()
 	}
-
-	d.log.Info(out)
+	return shell.DefaultShell()
 }
@@ -0,0 +1,427 @@
+package deploy
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// The tools that detached signatures of the deploy config are verified with
+const (
+	signatureCosign   = "cosign"
+	signatureMinisign = "minisign"
+	signatureGpg      = "gpg"
+)
+
+// signatureTypes are the valid values of `deploy.signature.type`
+var signatureTypes = []string{signatureCosign, signatureMinisign, signatureGpg}
+
+// checksumsSuffix is appended to the deploy config file name to get the
+// checksums file that is signed, ex. stim.deploy.yaml.sum
+const checksumsSuffix = ".sum"
+
+// signatureSuffix is appended to the checksums file name to get its detached
+// signature, ex. stim.deploy.yaml.sum.sig
+const signatureSuffix = ".sig"
+
+// checksumsExcludedDirs are directories of the deploy directory that aren't
+// checksummed: the git repo and the files rendered by stim
+var checksumsExcludedDirs = map[string]bool{".git": true, ".stim": true}
+
+// requiresSignedConfig returns true if deploys to the environment need a
+// signed deploy config, because it matches one of the
+// `deploy.require-signed-config` patterns of the stim (user or org) config or
+// because the deploy config has a signature, which is then always verified.
+// The deploy config can't require a signature itself, as it could be edited
+// along with the signature.
+func (d *Deploy) requiresSignedConfig(environment *Environment) bool {
+	for _, pattern := range d.stim.ConfigGetStringSlice("deploy.require-signed-config") {
+		if matched, _ := path.Match(pattern, environment.Name); matched {
+			return true
+		}
+	}
+	_, signature := d.checksumsPaths()
+	_, err := os.Stat(signature)
+	return err == nil
+}
+
+// checksumsPaths returns the checksums file and signature of the deploy config
+func (d *Deploy) checksumsPaths() (string, string) {
+	sums := d.config.configFilePath + checksumsSuffix
+	return sums, sums + signatureSuffix
+}
+
+// configChecksums returns the SHA-256 checksums of the deploy config file,
+// the files of the deploy directory and the bases it extends.  Local files are
+// keyed by their path relative to the directory of the config file and remote
+// bases by their source.
+func (d *Deploy) configChecksums() (map[string]string, error) {
+
+	configAbs, err := filepath.Abs(d.config.configFilePath)
+	if err != nil {
+		return nil, err
+	}
+	root := filepath.Dir(configAbs)
+	sums, sig := d.checksumsPaths()
+	sumsAbs, _ := filepath.Abs(sums)
+	sigAbs, _ := filepath.Abs(sig)
+
+	// Rendered templates are regenerated by every deploy
+	excluded := map[string]bool{sumsAbs: true, sigAbs: true}
+	for _, environment := range d.config.Environments {
+		for _, instance := range environment.Instances {
+			for _, template := range instance.Spec.Templates {
+				excluded[filepath.Join(d.config.Deployment.fullDirectoryPath, template.Output)] = true
+			}
+		}
+	}
+
+	checksums, err := checksumFiles(root, []string{configAbs, d.config.Deployment.fullDirectoryPath}, excluded)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bases are checksummed as they were read, so a remote base can't change
+	// between its check and its use
+	for source, sum := range d.config.extendsChecksums {
+		if !isRemoteSource(source) {
+			abs, err := filepath.Abs(source)
+			if err != nil {
+				return nil, err
+			}
+			rel, err := filepath.Rel(root, abs)
+			if err != nil {
+				return nil, err
+			}
+			source = filepath.ToSlash(rel)
+		}
+		checksums[source] = sum
+	}
+
+	return checksums, nil
+}
+
+// checksumFiles returns the SHA-256 checksums of the files and of every file
+// in the directories, keyed by their slash separated path relative to root.
+// Excluded (absolute) paths and the checksumsExcludedDirs are skipped.
+func checksumFiles(root string, paths []string, excluded map[string]bool) (map[string]string, error) {
+
+	sums := make(map[string]string)
+	for _, p := range paths {
+		err := filepath.Walk(p, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if checksumsExcludedDirs[info.Name()] {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if excluded[file] || !info.Mode().IsRegular() {
+				return nil
+			}
+
+			rel, err := filepath.Rel(root, file)
+			if err != nil {
+				return err
+			}
+			sum, err := checksumFile(file)
+			if err != nil {
+				return err
+			}
+			sums[filepath.ToSlash(rel)] = sum
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sums, nil
+}
+
+// checksumBytes returns the hex encoded SHA-256 checksum of content
+func checksumBytes(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// checksumFile returns the hex encoded SHA-256 checksum of a file
+func checksumFile(file string) (string, error) {
+
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// formatChecksums returns the checksums in the `sha256sum` format, sorted by
+// path, so that they can also be checked with `sha256sum -c`
+func formatChecksums(sums map[string]string) []byte {
+
+	files := make([]string, 0, len(sums))
+	for file := range sums {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	var b bytes.Buffer
+	for _, file := range files {
+		fmt.Fprintf(&b, "%s  %s\n", sums[file], file)
+	}
+	return b.Bytes()
+}
+
+// parseChecksums reads checksums in the `sha256sum` format
+func parseChecksums(content []byte) (map[string]string, error) {
+
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		parts := strings.SplitN(text, " ", 2)
+		if len(parts) != 2 || len(parts[0]) != sha256.Size*2 {
+			return nil, fmt.Errorf("Invalid checksum on line %d", line)
+		}
+		sums[strings.TrimLeft(parts[1], " *")] = parts[0]
+	}
+	return sums, scanner.Err()
+}
+
+// compareChecksums returns the files that differ between the signed and the
+// current checksums, sorted by path
+func compareChecksums(signed map[string]string, current map[string]string) []string {
+
+	var problems []string
+	for file, sum := range signed {
+		currentSum, ok := current[file]
+		switch {
+		case !ok:
+			problems = append(problems, file+" (missing)")
+		case currentSum != sum:
+			problems = append(problems, file+" (modified)")
+		}
+	}
+	for file := range current {
+		if _, ok := signed[file]; !ok {
+			problems = append(problems, file+" (not signed)")
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// signatureVerifyArgs returns the command that verifies the detached
+// signature of the checksums file with the public key of the signature type.
+// GPG needs a dedicated keyring or pinned fingerprints, since any key of the
+// default keyring would otherwise be trusted, and writes its status to stdout
+// so the signer can be checked with checkGpgStatus.
+func signatureVerifyArgs(signatureType string, key string, fingerprints []string, signature string, sums string) ([]string, error) {
+
+	switch signatureType {
+	case signatureCosign:
+		if key == "" {
+			return nil, errors.New("`deploy.signature.key` must be set to verify cosign signatures")
+		}
+		return []string{"cosign", "verify-blob", "--key", key, "--signature", signature, sums}, nil
+	case signatureMinisign:
+		if key == "" {
+			return nil, errors.New("`deploy.signature.key` must be set to verify minisign signatures")
+		}
+		return []string{"minisign", "-V", "-p", key, "-m", sums, "-x", signature}, nil
+	case signatureGpg:
+		if key == "" && len(fingerprints) == 0 {
+			return nil, errors.New("`deploy.signature.key` (a dedicated keyring) or `deploy.signature.signers` must be set to verify gpg signatures")
+		}
+		args := []string{"gpg", "--batch", "--status-fd", "1"}
+		if key != "" {
+			args = append(args, "--no-default-keyring", "--keyring", key)
+		}
+		return append(args, "--verify", signature, sums), nil
+	case "":
+		return nil, fmt.Errorf("`deploy.signature.type` must be set to verify the deploy config.  Must be one of ['%s']", strings.Join(signatureTypes, "','"))
+	}
+	return nil, fmt.Errorf("Invalid `deploy.signature.type` '%s'.  Must be one of ['%s']", signatureType, strings.Join(signatureTypes, "','"))
+}
+
+// normalizeFingerprint returns a key fingerprint in the format of the GPG
+// status output, ex. `0xab12 CD34` is `AB12CD34`
+func normalizeFingerprint(fingerprint string) string {
+	fingerprint = strings.ToUpper(strings.Replace(fingerprint, " ", "", -1))
+	return strings.TrimPrefix(fingerprint, "0X")
+}
+
+// checkGpgStatus checks the `--status-fd` output of `gpg --verify`: there
+// must be a valid signature and no bad one, and with pinned fingerprints a
+// valid signature must be made by one of them (or one of their subkeys).
+// Returns the fingerprint of the signer.
+func checkGpgStatus(status []byte, fingerprints []string) (string, error) {
+
+	pinned := make(map[string]bool)
+	for _, fingerprint := range fingerprints {
+		pinned[normalizeFingerprint(fingerprint)] = true
+	}
+
+	signer := ""
+	var signers []string
+	scanner := bufio.NewScanner(bytes.NewReader(status))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "[GNUPG:]" {
+			continue
+		}
+		switch fields[1] {
+		case "BADSIG", "ERRSIG", "EXPKEYSIG", "REVKEYSIG":
+			return "", fmt.Errorf("gpg reported %s", strings.Join(fields[1:], " "))
+		case "VALIDSIG":
+			if len(fields) < 3 {
+				continue
+			}
+			// The last field is the fingerprint of the primary key
+			primary := fields[len(fields)-1]
+			if signer == "" && (len(pinned) == 0 || pinned[fields[2]] || pinned[primary]) {
+				signer = fields[2]
+			}
+			signers = append(signers, fields[2])
+		}
+	}
+
+	if signer != "" {
+		return signer, nil
+	}
+	if len(signers) > 0 {
+		return "", fmt.Errorf("Signed by %s, which is not one of `deploy.signature.signers`", strings.Join(signers, ", "))
+	}
+	return "", errors.New("gpg reported no valid signature")
+}
+
+// verifyConfigSignature checks the signature of the checksums file of the
+// deploy config and that the config and deploy directory match it
+func (d *Deploy) verifyConfigSignature() error {
+
+	sums, signature := d.checksumsPaths()
+	content, err := ioutil.ReadFile(sums)
+	if err != nil {
+		return fmt.Errorf("Unable to read the checksums of the deploy config (create them with `stim deploy checksums` and sign them): %v", err)
+	}
+	if _, err := os.Stat(signature); err != nil {
+		return fmt.Errorf("No signature of the deploy config checksums found at %s", signature)
+	}
+
+	key := d.stim.ConfigGetString("deploy.signature.key")
+	if key != "" && !strings.Contains(key, "://") {
+		key, _ = filepath.Abs(key)
+	}
+	signatureType := d.stim.ConfigGetString("deploy.signature.type")
+	fingerprints := d.stim.ConfigGetStringSlice("deploy.signature.signers")
+	args, err := signatureVerifyArgs(signatureType, key, fingerprints, signature, sums)
+	if err != nil {
+		return err
+	}
+	d.log.Debug("Verifying the deploy config signature: {}", strings.Join(args, " "))
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if err != nil {
+		return fmt.Errorf("Invalid signature of the deploy config checksums %s: %v: %s", sums, err, strings.TrimSpace(stderr.String()+"\n"+stdout.String()))
+	}
+	if signatureType == signatureGpg {
+		signer, err := checkGpgStatus(stdout.Bytes(), fingerprints)
+		if err != nil {
+			return fmt.Errorf("Invalid signature of the deploy config checksums %s: %v", sums, err)
+		}
+		d.log.Debug("The deploy config checksums are signed by {}", signer)
+	}
+
+	signed, err := parseChecksums(content)
+	if err != nil {
+		return fmt.Errorf("Error reading %s: %v", sums, err)
+	}
+	current, err := d.configChecksums()
+	if err != nil {
+		return fmt.Errorf("Error computing the deploy config checksums: %v", err)
+	}
+	if problems := compareChecksums(signed, current); len(problems) > 0 {
+		return fmt.Errorf("The deploy config doesn't match its signed checksums: %s", strings.Join(problems, ", "))
+	}
+
+	d.log.Info("Verified the signature of the deploy config")
+	return nil
+}
+
+// checkSignedConfig verifies the deploy config signature if the environment
+// requires a signed config or the config is signed
+func (d *Deploy) checkSignedConfig(environment *Environment) error {
+	if !d.requiresSignedConfig(environment) {
+		return nil
+	}
+	err := d.verifyConfigSignature()
+	if err != nil {
+		return stim.ConfigError(fmt.Errorf("Deploys to environment '%s' require a valid signed deploy config.  %v", environment.Name, err))
+	}
+	return nil
+}
+
+// WriteChecksums writes the checksums file of the deploy config, to be signed
+// with the tool of `deploy.signature.type`
+func (d *Deploy) WriteChecksums() error {
+
+	err := d.parseConfig()
+	if err != nil {
+		return err
+	}
+
+	current, err := d.configChecksums()
+	if err != nil {
+		return err
+	}
+	sums, signature := d.checksumsPaths()
+	err = ioutil.WriteFile(sums, formatChecksums(current), 0644)
+	if err != nil {
+		return err
+	}
+
+	d.log.Info("Wrote the checksums of {} files to {}.  Sign it to create {}", len(current), sums, signature)
+	return nil
+}
+
+// VerifySignature verifies the signature of the deploy config without
+// deploying
+func (d *Deploy) VerifySignature() error {
+
+	err := d.parseConfig()
+	if err != nil {
+		return err
+	}
+	err = d.verifyConfigSignature()
+	if err != nil {
+		return stim.ConfigError(err)
+	}
+	return nil
+}
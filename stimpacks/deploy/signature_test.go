@@ -0,0 +1,186 @@
+package deploy
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PremiereGlobal/stim/stim"
+	"gotest.tools/assert"
+)
+
+func TestChecksums(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stim-deploy-sums")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, sub := range []string{"charts", ".git", ".stim/helm"} {
+		assert.NilError(t, os.MkdirAll(filepath.Join(dir, sub), 0755))
+	}
+	files := map[string]string{
+		"stim.deploy.yaml":      "deployment:\n  directory: ./\n",
+		"deploy.sh":             "#!/bin/sh\n",
+		"charts/values.yaml":    "replicas: 3\n",
+		"values.yaml":           "rendered: true\n",
+		".git/HEAD":             "ref: refs/heads/master\n",
+		".stim/helm/values.yml": "rendered: true\n",
+	}
+	for name, content := range files {
+		assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	// The config file is also in the deploy directory
+	sums, err := checksumFiles(dir, []string{filepath.Join(dir, "stim.deploy.yaml"), dir}, map[string]bool{filepath.Join(dir, "values.yaml"): true})
+	assert.NilError(t, err)
+	assert.Equal(t, len(sums), 3)
+	assert.Equal(t, sums["deploy.sh"], "a8076d3d28d21e02012b20eaf7dbf75409a6277134439025f282e368e3305abf")
+	assert.Assert(t, sums["charts/values.yaml"] != "")
+
+	// The checksums file can be read back
+	parsed, err := parseChecksums(formatChecksums(sums))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, parsed, sums)
+	assert.Assert(t, len(compareChecksums(parsed, sums)) == 0)
+
+	_, err = parseChecksums([]byte("not-a-checksum deploy.sh\n"))
+	assert.ErrorContains(t, err, "Invalid checksum on line 1")
+}
+
+func TestCompareChecksums(t *testing.T) {
+	signed := map[string]string{"stim.deploy.yaml": "aaa", "deploy.sh": "bbb", "charts/values.yaml": "ccc"}
+	current := map[string]string{"stim.deploy.yaml": "aaa", "deploy.sh": "ddd", "backdoor.sh": "eee"}
+
+	assert.DeepEqual(t, compareChecksums(signed, current), []string{
+		"backdoor.sh (not signed)",
+		"charts/values.yaml (missing)",
+		"deploy.sh (modified)",
+	})
+}
+
+func TestSignatureVerifyArgs(t *testing.T) {
+	args, err := signatureVerifyArgs("cosign", "cosign.pub", nil, "stim.deploy.yaml.sum.sig", "stim.deploy.yaml.sum")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, args, []string{"cosign", "verify-blob", "--key", "cosign.pub", "--signature", "stim.deploy.yaml.sum.sig", "stim.deploy.yaml.sum"})
+
+	args, err = signatureVerifyArgs("minisign", "minisign.pub", nil, "stim.deploy.yaml.sum.sig", "stim.deploy.yaml.sum")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, args, []string{"minisign", "-V", "-p", "minisign.pub", "-m", "stim.deploy.yaml.sum", "-x", "stim.deploy.yaml.sum.sig"})
+
+	// GPG needs a dedicated keyring or pinned fingerprints
+	args, err = signatureVerifyArgs("gpg", "/keys/deploy.kbx", nil, "stim.deploy.yaml.sum.sig", "stim.deploy.yaml.sum")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, args, []string{"gpg", "--batch", "--status-fd", "1", "--no-default-keyring", "--keyring", "/keys/deploy.kbx", "--verify", "stim.deploy.yaml.sum.sig", "stim.deploy.yaml.sum"})
+	args, err = signatureVerifyArgs("gpg", "", []string{"AB12"}, "stim.deploy.yaml.sum.sig", "stim.deploy.yaml.sum")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, args, []string{"gpg", "--batch", "--status-fd", "1", "--verify", "stim.deploy.yaml.sum.sig", "stim.deploy.yaml.sum"})
+	_, err = signatureVerifyArgs("gpg", "", nil, "stim.deploy.yaml.sum.sig", "stim.deploy.yaml.sum")
+	assert.ErrorContains(t, err, "`deploy.signature.signers` must be set")
+
+	_, err = signatureVerifyArgs("minisign", "", nil, "stim.deploy.yaml.sum.sig", "stim.deploy.yaml.sum")
+	assert.ErrorContains(t, err, "`deploy.signature.key` must be set")
+	_, err = signatureVerifyArgs("", "", nil, "stim.deploy.yaml.sum.sig", "stim.deploy.yaml.sum")
+	assert.ErrorContains(t, err, "`deploy.signature.type` must be set")
+	_, err = signatureVerifyArgs("pgp", "", nil, "stim.deploy.yaml.sum.sig", "stim.deploy.yaml.sum")
+	assert.ErrorContains(t, err, "Invalid `deploy.signature.type` 'pgp'")
+}
+
+func TestCheckGpgStatus(t *testing.T) {
+	subkey := "1111222233334444555566667777888899990000"
+	primary := "AAAABBBBCCCCDDDDEEEEFFFF0000111122223333"
+	status := []byte("[GNUPG:] NEWSIG\n[GNUPG:] GOODSIG 9999000011112222 Deploy Bot <deploy@my-domain.com>\n" +
+		"[GNUPG:] VALIDSIG " + subkey + " 2024-03-01 1709294400 0 4 0 22 10 00 " + primary + "\n[GNUPG:] TRUST_UNDEFINED 0 pgp\n")
+
+	// Any valid signature of a dedicated keyring
+	signer, err := checkGpgStatus(status, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, signer, subkey)
+
+	// The primary key or the signing subkey can be pinned
+	_, err = checkGpgStatus(status, []string{"0x" + strings.ToLower(primary)})
+	assert.NilError(t, err)
+	_, err = checkGpgStatus(status, []string{"1111 2222 3333 4444 5555  6666 7777 8888 9999 0000"})
+	assert.NilError(t, err)
+
+	_, err = checkGpgStatus(status, []string{"0123456789ABCDEF0123456789ABCDEF01234567"})
+	assert.Error(t, err, "Signed by "+subkey+", which is not one of `deploy.signature.signers`")
+
+	_, err = checkGpgStatus([]byte("[GNUPG:] NEWSIG\n[GNUPG:] NO_PUBKEY 9999000011112222\n"), nil)
+	assert.Error(t, err, "gpg reported no valid signature")
+
+	_, err = checkGpgStatus(append(status, []byte("[GNUPG:] BADSIG 9999000011112222 Mallory\n")...), nil)
+	assert.Error(t, err, "gpg reported BADSIG 9999000011112222 Mallory")
+}
+
+func TestRequiresSignedConfig(t *testing.T) {
+	s := stim.New()
+	s.ConfigOverride("deploy.require-signed-config", []string{"prod*"})
+	d := &Deploy{stim: s, log: s.GetLogger()}
+
+	assert.Assert(t, d.requiresSignedConfig(&Environment{Name: "prod-eu"}))
+	assert.Assert(t, !d.requiresSignedConfig(&Environment{Name: "staging", Policy: &Policy{}}))
+
+	// A signed deploy config is verified for every environment
+	dir, err := ioutil.TempDir("", "stim-deploy-sig")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	d.config.configFilePath = filepath.Join(dir, "stim.deploy.yaml")
+	_, signature := d.checksumsPaths()
+	assert.NilError(t, ioutil.WriteFile(signature, []byte("signature"), 0644))
+	assert.Assert(t, d.requiresSignedConfig(&Environment{Name: "staging", Policy: &Policy{}}))
+}
+
+func TestConfigChecksumsExtends(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stim-deploy-sums")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	remote := "environments:\n  - name: dev\n"
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(remote))
+	}))
+	defer server.Close()
+	defer func(client *http.Client) { extendsClient = client }(extendsClient)
+	extendsClient = server.Client()
+
+	assert.NilError(t, os.MkdirAll(filepath.Join(dir, "bases"), 0755))
+	assert.NilError(t, os.MkdirAll(filepath.Join(dir, "app"), 0755))
+	content := "extends:\n  - ../bases/base.yaml\n  - " + server.URL + "/base.yaml\ndeployment:\n  directory: ./\n"
+	files := map[string]string{
+		"app/stim.deploy.yaml": content,
+		"app/deploy.sh":        "#!/bin/sh\n",
+		"bases/base.yaml":      "global:\n  spec:\n    kubernetes:\n      cluster: prod\n",
+	}
+	for name, content := range files {
+		assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	checksums := func() map[string]string {
+		d := &Deploy{}
+		configFile := filepath.Join(dir, "app", "stim.deploy.yaml")
+		d.config.Extends = []string{"../bases/base.yaml", server.URL + "/base.yaml"}
+		assert.NilError(t, extendConfig(&d.config, configFile))
+		d.config.configFilePath = configFile
+		d.config.Deployment.fullDirectoryPath = filepath.Join(dir, "app")
+		sums, err := d.configChecksums()
+		assert.NilError(t, err)
+		return sums
+	}
+
+	// Bases outside of the deploy directory are checksummed
+	signed := checksums()
+	assert.Equal(t, len(signed), 4)
+	assert.Assert(t, signed["../bases/base.yaml"] != "")
+	assert.Assert(t, signed[server.URL+"/base.yaml"] != "")
+
+	// A changed local or remote base fails the verification
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, "bases/base.yaml"), []byte("global: {}\n"), 0644))
+	remote = "environments:\n  - name: prod\n"
+	assert.DeepEqual(t, compareChecksums(signed, checksums()), []string{
+		"../bases/base.yaml (modified)",
+		server.URL + "/base.yaml (modified)",
+	})
+}
@@ -0,0 +1,243 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/i18n"
+	"github.com/PremiereGlobal/stim/pkg/utils"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// Strategy is the rollout of a deploy to all the instances of an environment.
+// The canary instances are deployed first and must stay healthy for the canary
+// wait, then the other instances are deployed in batches with a pause between
+// batches.  The instances of a batch are deployed one after another.
+type Strategy struct {
+	Canary    *Canary `yaml:"canary"`
+	BatchSize int     `yaml:"batchSize"`
+	Pause     string  `yaml:"pause"`
+	Confirm   bool    `yaml:"confirm"`
+}
+
+// Canary describes the instances deployed before the others.  They default to
+// the first instance of the environment.
+type Canary struct {
+	Instances []string `yaml:"instances"`
+	Wait      string   `yaml:"wait"`
+}
+
+// rolloutBatch is a group of instances deployed together
+type rolloutBatch struct {
+	canary    bool
+	instances []*Instance
+}
+
+// validateStrategy checks the durations, batch size and canary instances of
+// an environment's strategy
+func validateStrategy(environment *Environment) error {
+
+	strategy := environment.Strategy
+	if strategy == nil {
+		return nil
+	}
+	if strategy.BatchSize < 0 {
+		return fmt.Errorf("Invalid strategy batchSize %d, must be 0 (one batch) or more", strategy.BatchSize)
+	}
+	if strategy.Pause != "" {
+		if _, err := time.ParseDuration(strategy.Pause); err != nil {
+			return fmt.Errorf("Invalid strategy pause '%s'", strategy.Pause)
+		}
+	}
+	if strategy.Canary != nil {
+		if strategy.Canary.Wait != "" {
+			if _, err := time.ParseDuration(strategy.Canary.Wait); err != nil {
+				return fmt.Errorf("Invalid strategy canary wait '%s'", strategy.Canary.Wait)
+			}
+		}
+		for _, name := range strategy.Canary.Instances {
+			if _, ok := environment.instanceMap[name]; !ok {
+				return fmt.Errorf("Strategy canary instance '%s' is not in the environment", name)
+			}
+		}
+		if len(strategy.Canary.Instances) >= len(environment.Instances) {
+			return errors.New("The strategy canary must leave at least one instance to roll out to")
+		}
+	}
+
+	return nil
+}
+
+// rolloutBatches returns the batches that a deploy to all the instances of the
+// environment is rolled out in, in order.  Without a strategy all the instances
+// are in one batch.
+func rolloutBatches(environment *Environment) []*rolloutBatch {
+
+	strategy := environment.Strategy
+	if strategy == nil {
+		return []*rolloutBatch{{instances: environment.Instances}}
+	}
+
+	var batches []*rolloutBatch
+	remaining := environment.Instances
+	if strategy.Canary != nil {
+		canaries := strategy.Canary.Instances
+		if len(canaries) == 0 && len(environment.Instances) > 0 {
+			canaries = []string{environment.Instances[0].Name}
+		}
+		canary := &rolloutBatch{canary: true}
+		remaining = nil
+		for _, instance := range environment.Instances {
+			if utils.Contains(canaries, instance.Name) {
+				canary.instances = append(canary.instances, instance)
+			} else {
+				remaining = append(remaining, instance)
+			}
+		}
+		batches = append(batches, canary)
+	}
+
+	size := strategy.BatchSize
+	if size == 0 {
+		size = len(remaining)
+	}
+	for start := 0; start < len(remaining); start += size {
+		end := start + size
+		if end > len(remaining) {
+			end = len(remaining)
+		}
+		batches = append(batches, &rolloutBatch{instances: remaining[start:end]})
+	}
+
+	return batches
+}
+
+// deployAll deploys to all the instances of the environment following its
+// strategy.  The rollout stops at the first instance that fails, or if the
+// canaries don't stay healthy.
+func (d *Deploy) deployAll(environment *Environment) error {
+
+	batches := rolloutBatches(environment)
+	strategy := environment.Strategy
+	if strategy != nil {
+		d.log.Info("Rolling out to environment {} in {} batch(es): {}", environment.Name, len(batches), describeBatches(batches))
+	}
+
+	count := 0
+	for i, batch := range batches {
+		if i > 0 {
+			err := d.betweenBatches(environment, batches[i-1], batch)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, inst := range batch.instances {
+			count++
+			d.log.Info("Instance {} of {}: {}", count, len(environment.Instances), inst.Name)
+			if inst.Spec.AddConfirmationPrompt {
+				//Do AddConfirmationPrompt, only if the instance is not passed on the cli
+				proceed, _ := d.stim.PromptBool(d.stim.Message(i18n.MessageProceed), d.stim.ConfigGetBool("deploy.yes") || d.stim.ConfigGetString("deploy.instance") != "", false)
+				if !proceed {
+					return stim.Aborted(d.stim.Message(i18n.MessageDeployCancelled))
+				}
+			}
+			err := d.Deploy(environment, inst)
+			if err != nil {
+				if strategy != nil && count < len(environment.Instances) {
+					d.log.Warn("Stopped the rollout of environment {}, {} instance(s) were not deployed", environment.Name, len(environment.Instances)-count)
+				}
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// deploySelected deploys the instances selected at the multi-select prompt
+// one after another.  The rollout strategy of the environment only applies
+// when all of its instances are deployed.
+func (d *Deploy) deploySelected(environment *Environment, instances []*Instance) error {
+
+	for i, inst := range instances {
+		d.log.Info("Instance {} of {}: {}", i+1, len(instances), inst.Name)
+		if inst.Spec.AddConfirmationPrompt {
+			proceed, _ := d.stim.PromptBool(d.stim.Message(i18n.MessageProceed), d.stim.ConfigGetBool("deploy.yes"), false)
+			if !proceed {
+				return stim.Aborted(d.stim.Message(i18n.MessageDeployCancelled))
+			}
+		}
+		err := d.Deploy(environment, inst)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// betweenBatches waits for the canaries to stay healthy (after the canary
+// batch) or pauses (after other batches), then asks to continue if the
+// strategy requires confirmation
+func (d *Deploy) betweenBatches(environment *Environment, previous *rolloutBatch, next *rolloutBatch) error {
+
+	strategy := environment.Strategy
+	if previous.canary {
+		wait, _ := time.ParseDuration(strategy.Canary.Wait)
+		if wait > 0 {
+			d.log.Info("Waiting {} before checking the canary instance(s) again", wait)
+			err := d.stim.Sleep(wait)
+			if err != nil {
+				return err
+			}
+		}
+		for _, inst := range previous.instances {
+			err := d.runHealthChecks(inst)
+			if err != nil {
+				return fmt.Errorf("Canary instance '%s' is not healthy, the rollout was stopped before the other instances: %v", inst.Name, err)
+			}
+		}
+		d.log.Info("Canary instance(s) {} passed their health checks", instanceNames(previous.instances))
+	} else if strategy.Pause != "" {
+		pause, _ := time.ParseDuration(strategy.Pause)
+		d.log.Info("Pausing {} before the next batch", pause)
+		err := d.stim.Sleep(pause)
+		if err != nil {
+			return err
+		}
+	}
+
+	if strategy.Confirm {
+		proceed, _ := d.stim.PromptBool(d.stim.Message(i18n.MessageContinueRollout, instanceNames(next.instances)), d.stim.ConfigGetBool("deploy.yes"), false)
+		if !proceed {
+			return stim.Aborted(d.stim.Message(i18n.MessageDeployCancelled))
+		}
+	}
+
+	return nil
+}
+
+// describeBatches describes the rollout batches (ex. `canary [us-east],
+// [us-west,eu-west]`)
+func describeBatches(batches []*rolloutBatch) string {
+	descriptions := make([]string, len(batches))
+	for i, batch := range batches {
+		descriptions[i] = "[" + instanceNames(batch.instances) + "]"
+		if batch.canary {
+			descriptions[i] = "canary " + descriptions[i]
+		}
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+// instanceNames returns the comma separated names of the instances
+func instanceNames(instances []*Instance) string {
+	names := make([]string, len(instances))
+	for i, instance := range instances {
+		names[i] = instance.Name
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,43 @@
+package deploy
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func strategyEnvironment(strategy *Strategy, names ...string) *Environment {
+	environment := &Environment{Name: "prod", Strategy: strategy, instanceMap: make(map[string]int)}
+	for i, name := range names {
+		environment.Instances = append(environment.Instances, &Instance{Name: name, Spec: &Spec{}})
+		environment.instanceMap[name] = i
+	}
+	return environment
+}
+
+func TestRolloutBatches(t *testing.T) {
+	environment := strategyEnvironment(nil, "a", "b", "c", "d", "e")
+	assert.Equal(t, describeBatches(rolloutBatches(environment)), "[a,b,c,d,e]")
+
+	environment.Strategy = &Strategy{Canary: &Canary{}, BatchSize: 2}
+	assert.Equal(t, describeBatches(rolloutBatches(environment)), "canary [a], [b,c], [d,e]")
+
+	environment.Strategy = &Strategy{Canary: &Canary{Instances: []string{"c", "e"}}}
+	assert.Equal(t, describeBatches(rolloutBatches(environment)), "canary [c,e], [a,b,d]")
+
+	environment.Strategy = &Strategy{BatchSize: 3}
+	batches := rolloutBatches(environment)
+	assert.Equal(t, describeBatches(batches), "[a,b,c], [d,e]")
+	assert.Assert(t, !batches[0].canary)
+}
+
+func TestValidateStrategy(t *testing.T) {
+	assert.NilError(t, validateStrategy(strategyEnvironment(nil, "a")))
+	assert.NilError(t, validateStrategy(strategyEnvironment(&Strategy{Canary: &Canary{Wait: "10m"}, BatchSize: 2, Pause: "5m"}, "a", "b")))
+
+	assert.Error(t, validateStrategy(strategyEnvironment(&Strategy{BatchSize: -1}, "a")), "Invalid strategy batchSize -1, must be 0 (one batch) or more")
+	assert.Error(t, validateStrategy(strategyEnvironment(&Strategy{Pause: "soon"}, "a")), "Invalid strategy pause 'soon'")
+	assert.Error(t, validateStrategy(strategyEnvironment(&Strategy{Canary: &Canary{Wait: "1"}}, "a", "b")), "Invalid strategy canary wait '1'")
+	assert.Error(t, validateStrategy(strategyEnvironment(&Strategy{Canary: &Canary{Instances: []string{"z"}}}, "a", "b")), "Strategy canary instance 'z' is not in the environment")
+	assert.Error(t, validateStrategy(strategyEnvironment(&Strategy{Canary: &Canary{Instances: []string{"a", "b"}}}, "a", "b")), "The strategy canary must leave at least one instance to roll out to")
+}
@@ -0,0 +1,226 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/PremiereGlobal/stim/pkg/registry"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// The tag expressions that are resolved at deploy time
+const (
+	tagLatestSemver = "latest-semver"
+	tagGitSha       = "git-sha"
+)
+
+// imageTagEnvSuffix is added to the env var name of an image for the env var
+// with only its tag
+const imageTagEnvSuffix = "_TAG"
+
+// Image is an application image whose reference (`<repo>:<tag>`) is set as
+// the `name` env var and whose tag is set as `<name>_TAG`.  The tag can be a
+// tag expression resolved at deploy time.
+type Image struct {
+	Name string `yaml:"name"`
+	Repo string `yaml:"repo"`
+	Tag  string `yaml:"tag"`
+}
+
+// parseTagExpression splits a tag expression (ex. `latest-semver(>=1.4,<2)`)
+// into its function and argument.  The function is empty for a plain tag.
+func parseTagExpression(tag string) (string, string, error) {
+
+	function, arg := tag, ""
+	if i := strings.Index(tag, "("); i >= 0 {
+		if !strings.HasSuffix(tag, ")") {
+			return "", "", fmt.Errorf("Invalid tag expression '%s', missing `)`", tag)
+		}
+		function, arg = tag[:i], strings.TrimSpace(tag[i+1:len(tag)-1])
+	}
+
+	switch function {
+	case tagLatestSemver:
+		if _, err := registry.ParseConstraint(arg); err != nil {
+			return "", "", err
+		}
+	case tagGitSha:
+		if arg != "" {
+			if n, err := strconv.Atoi(arg); err != nil || n < 4 || n > 40 {
+				return "", "", fmt.Errorf("Invalid tag expression '%s', the length of the SHA must be between 4 and 40", tag)
+			}
+		}
+	default:
+		if strings.Contains(tag, "(") {
+			return "", "", fmt.Errorf("Unknown tag expression '%s', must be %s(<constraint>) or %s(<length>)", tag, tagLatestSemver, tagGitSha)
+		}
+		return "", tag, nil
+	}
+
+	return function, arg, nil
+}
+
+// resolveTag returns the concrete tag of a tag expression.  Resolved tags are
+// cached so that every instance of a deploy gets the same tag.
+func (d *Deploy) resolveTag(repo string, tag string) (string, error) {
+
+	function, arg, err := parseTagExpression(tag)
+	if err != nil {
+		return "", stim.ConfigError(err)
+	}
+	if function == "" {
+		return tag, nil
+	}
+
+	key := repo + ":" + tag
+	if resolved, ok := d.resolvedTags[key]; ok {
+		return resolved, nil
+	}
+
+	var resolved string
+	switch function {
+	case tagGitSha:
+		resolved, err = d.headCommit()
+		if err != nil {
+			return "", fmt.Errorf("Unable to resolve the %s tag of %s: %v", tag, repo, err)
+		}
+		if arg != "" {
+			n, _ := strconv.Atoi(arg)
+			resolved = resolved[:n]
+		}
+	case tagLatestSemver:
+		resolved, err = d.latestSemverTag(repo, arg)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	d.log.Info("Resolved the {} tag of {} to {}", tag, repo, resolved)
+	if d.resolvedTags == nil {
+		d.resolvedTags = make(map[string]string)
+	}
+	d.resolvedTags[key] = resolved
+	return resolved, nil
+}
+
+// latestSemverTag returns the newest release tag of the repo in its registry
+// that matches the constraint
+func (d *Deploy) latestSemverTag(repo string, constraint string) (string, error) {
+
+	ref, err := registry.ParseReference(repo)
+	if err != nil {
+		return "", stim.ConfigError(fmt.Errorf("Invalid image repo: %v", err))
+	}
+	if ref.Tag != "" || ref.Digest != "" {
+		return "", stim.ConfigError(fmt.Errorf("Image repo '%s' must not have a tag or digest", repo))
+	}
+	c, _ := registry.ParseConstraint(constraint)
+
+	client, err := d.stim.Registry(ref.Host)
+	if err != nil {
+		return "", err
+	}
+	tags, err := client.Tags(ref.Repo)
+	if err != nil {
+		return "", fmt.Errorf("Unable to list the tags of %s: %v", repo, err)
+	}
+
+	latest := registry.LatestVersion(tags, c)
+	if latest == "" {
+		return "", stim.ConfigError(fmt.Errorf("No release tag of %s matches '%s' (`stim registry tags %s` lists the tags)", repo, constraint, repo))
+	}
+	return latest, nil
+}
+
+// resolveImages resolves the tag expressions of the deploy container and of
+// the images of the instances, and sets the image env vars of the instances.
+// When promoting, the images get the tags deployed to the source environment.
+func (d *Deploy) resolveImages(instances []*Instance) error {
+
+	container := &d.config.Deployment.Container
+	if d.config.Deployment.Type != deployTypeManifests && d.stim.ConfigGetString("deploy.method") != "shell" {
+		tag, err := d.resolveTag(container.Repo, container.Tag)
+		if err != nil {
+			return err
+		}
+		container.Tag = tag
+	}
+
+	for _, instance := range instances {
+		for _, image := range instance.Spec.Images {
+			var tag string
+			var err error
+			if d.promotion != nil {
+				tag, err = d.promotion.tag(image)
+			} else {
+				tag, err = d.resolveTag(image.Repo, image.Tag)
+			}
+			if err != nil {
+				return err
+			}
+			setEnvVar(instance, image.Name, image.Repo+":"+tag, instance.origins["images."+image.Name])
+			setEnvVar(instance, image.Name+imageTagEnvSuffix, tag, instance.origins["images."+image.Name])
+		}
+	}
+
+	return nil
+}
+
+// setEnvVar sets an env var of the instance, replacing one of the same name
+// from the deploy config.  Env vars set on the command line are kept.
+func setEnvVar(instance *Instance, name string, value string, origin string) {
+
+	if instance.origins == nil {
+		instance.origins = make(map[string]string)
+	}
+	if instance.origins["env."+name] == originCLI {
+		return
+	}
+	instance.origins["env."+name] = origin
+
+	// Env vars can be shared with the other instances of the environment, so
+	// they are replaced rather than changed
+	for i, e := range instance.Spec.EnvironmentVars {
+		if e.Name == name {
+			instance.Spec.EnvironmentVars[i] = &EnvironmentVar{Name: name, Value: value}
+			return
+		}
+	}
+	instance.Spec.EnvironmentVars = append(instance.Spec.EnvironmentVars, &EnvironmentVar{Name: name, Value: value})
+}
+
+// mergeImages merges the images of the spec levels by name, the instance
+// level taking precedence
+func mergeImages(instance []*Image, environment []*Image, global []*Image) []*Image {
+
+	var result []*Image
+	names := make(map[string]bool)
+	for _, level := range [][]*Image{instance, environment, global} {
+		for _, image := range level {
+			if !names[image.Name] {
+				names[image.Name] = true
+				result = append(result, image)
+			}
+		}
+	}
+
+	return result
+}
+
+// validateImages checks the names, repos and tag expressions of images
+func validateImages(images []*Image) error {
+	for _, image := range images {
+		if image.Name == "" || image.Repo == "" || image.Tag == "" {
+			return errors.New("`name`, `repo` and `tag` must be set in the `spec.images` config")
+		}
+		if isReservedEnvName(image.Name) || isReservedEnvName(image.Name+imageTagEnvSuffix) {
+			return fmt.Errorf("Image name '%s' is a reserved environment variable name", image.Name)
+		}
+		if _, _, err := parseTagExpression(image.Tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
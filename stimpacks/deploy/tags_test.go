@@ -0,0 +1,72 @@
+package deploy
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestParseTagExpression(t *testing.T) {
+	tests := []struct {
+		tag      string
+		function string
+		arg      string
+		err      string
+	}{
+		{"1.4.2", "", "1.4.2", ""},
+		{"latest", "", "latest", ""},
+		{"latest-semver", tagLatestSemver, "", ""},
+		{"latest-semver(>=1.4,<2)", tagLatestSemver, ">=1.4,<2", ""},
+		{"git-sha", tagGitSha, "", ""},
+		{"git-sha(7)", tagGitSha, "7", ""},
+		{"git-sha(2)", "", "", "Invalid tag expression 'git-sha(2)', the length of the SHA must be between 4 and 40"},
+		{"latest-semver(>=1.4", "", "", "Invalid tag expression 'latest-semver(>=1.4', missing `)`"},
+		{"latest-semver(>=x)", "", "", "Invalid version constraint '>=x': 'x' is not a semantic version"},
+		{"newest(1)", "", "", "Unknown tag expression 'newest(1)', must be latest-semver(<constraint>) or git-sha(<length>)"},
+	}
+
+	for _, test := range tests {
+		function, arg, err := parseTagExpression(test.tag)
+		if test.err != "" {
+			assert.Error(t, err, test.err)
+			continue
+		}
+		assert.NilError(t, err, test.tag)
+		assert.Equal(t, function, test.function, test.tag)
+		assert.Equal(t, arg, test.arg, test.tag)
+	}
+}
+
+func TestSetEnvVar(t *testing.T) {
+	shared := &EnvironmentVar{Name: "APP_IMAGE", Value: "app:old"}
+	instance := &Instance{Spec: &Spec{EnvironmentVars: []*EnvironmentVar{shared, {Name: "APP_IMAGE_TAG", Value: "cli"}}}, origins: map[string]string{"env.APP_IMAGE_TAG": originCLI}}
+
+	setEnvVar(instance, "APP_IMAGE", "app:1.4.2", originEnvironment)
+	setEnvVar(instance, "APP_IMAGE_TAG", "1.4.2", originEnvironment)
+	setEnvVar(instance, "WORKER_IMAGE", "worker:1.0", originGlobal)
+
+	assert.DeepEqual(t, instance.Spec.EnvironmentVars, []*EnvironmentVar{
+		{Name: "APP_IMAGE", Value: "app:1.4.2"},
+		{Name: "APP_IMAGE_TAG", Value: "cli"},
+		{Name: "WORKER_IMAGE", Value: "worker:1.0"},
+	})
+	assert.Equal(t, shared.Value, "app:old")
+	assert.Equal(t, instance.origins["env.APP_IMAGE"], originEnvironment)
+	assert.Equal(t, instance.origins["env.WORKER_IMAGE"], originGlobal)
+}
+
+func TestMergeImages(t *testing.T) {
+	merged := mergeImages(
+		[]*Image{{Name: "APP_IMAGE", Repo: "app", Tag: "git-sha"}},
+		[]*Image{{Name: "APP_IMAGE", Repo: "app", Tag: "latest-semver"}, {Name: "WORKER_IMAGE", Repo: "worker", Tag: "1.0"}},
+		nil,
+	)
+	assert.DeepEqual(t, merged, []*Image{{Name: "APP_IMAGE", Repo: "app", Tag: "git-sha"}, {Name: "WORKER_IMAGE", Repo: "worker", Tag: "1.0"}})
+}
+
+func TestValidateImages(t *testing.T) {
+	assert.NilError(t, validateImages([]*Image{{Name: "APP_IMAGE", Repo: "app", Tag: "latest-semver(>=1.4,<2)"}}))
+	assert.Error(t, validateImages([]*Image{{Name: "APP_IMAGE", Repo: "app"}}), "`name`, `repo` and `tag` must be set in the `spec.images` config")
+	assert.Error(t, validateImages([]*Image{{Name: "VAULT_TOKEN", Repo: "app", Tag: "1.0"}}), "Image name 'VAULT_TOKEN' is a reserved environment variable name")
+	assert.Error(t, validateImages([]*Image{{Name: "APP_IMAGE", Repo: "app", Tag: "git-sha(x)"}}), "Invalid tag expression 'git-sha(x)', the length of the SHA must be between 4 and 40")
+}
@@ -0,0 +1,554 @@
+package deploy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/PremiereGlobal/stim/pkg/vault"
+	"gopkg.in/yaml.v2"
+)
+
+// Template describes a file rendered with Go templates before the deployment
+// script runs.  Input and output paths are relative to the deployment
+// directory.
+type Template struct {
+	Input  string              `yaml:"input"`
+	Output string              `yaml:"output"`
+	Values map[string]string   `yaml:"values"`
+	Lists  map[string][]string `yaml:"lists"`
+}
+
+// templateData is the data templates are rendered with
+type templateData struct {
+	Env         map[string]string
+	Values      map[string]string
+	Lists       map[string][]string
+	Environment string
+	Instance    string
+	Cluster     string
+	Namespace   string
+}
+
+// templateFuncs are the functions available to templates.  They are named and
+// behave like their sprig (https://masterminds.github.io/sprig/) equivalents.
+// `env` and `expandenv` are left out, templates get the deploy env vars from
+// `.Env`.
+var templateFuncs = template.FuncMap{
+	// Defaults and flow control
+	"default": func(def interface{}, value ...interface{}) interface{} {
+		if len(value) == 0 || isEmpty(value[0]) {
+			return def
+		}
+		return value[0]
+	},
+	"empty": isEmpty,
+	"coalesce": func(values ...interface{}) interface{} {
+		for _, value := range values {
+			if !isEmpty(value) {
+				return value
+			}
+		}
+		return nil
+	},
+	"ternary": func(vt interface{}, vf interface{}, condition bool) interface{} {
+		if condition {
+			return vt
+		}
+		return vf
+	},
+	"required": func(message string, value interface{}) (interface{}, error) {
+		if value == nil {
+			return nil, errors.New(message)
+		}
+		if s, ok := value.(string); ok && s == "" {
+			return nil, errors.New(message)
+		}
+		return value, nil
+	},
+	"fail": func(message string) (string, error) { return "", errors.New(message) },
+
+	// Strings
+	"upper":      strings.ToUpper,
+	"lower":      strings.ToLower,
+	"title":      strings.Title,
+	"trim":       strings.TrimSpace,
+	"trimAll":    func(cutset string, s string) string { return strings.Trim(s, cutset) },
+	"trimPrefix": func(prefix string, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix string, s string) string { return strings.TrimSuffix(s, suffix) },
+	"trunc":      trunc,
+	"repeat":     func(count int, s string) string { return strings.Repeat(s, count) },
+	"nospace":    func(s string) string { return strings.Join(strings.Fields(s), "") },
+	"replace":    func(old string, new string, s string) string { return strings.Replace(s, old, new, -1) },
+	"contains":   func(substr string, s string) bool { return strings.Contains(s, substr) },
+	"hasPrefix":  func(prefix string, s string) bool { return strings.HasPrefix(s, prefix) },
+	"hasSuffix":  func(suffix string, s string) bool { return strings.HasSuffix(s, suffix) },
+	"split":      split,
+	"splitList":  func(sep string, s string) []string { return strings.Split(s, sep) },
+	"join":       func(sep string, list interface{}) string { return strings.Join(toStrings(list), sep) },
+	"quote": func(values ...interface{}) string {
+		return joinQuoted(values, func(s string) string { return fmt.Sprintf("%q", s) })
+	},
+	"squote": func(values ...interface{}) string {
+		return joinQuoted(values, func(s string) string { return "'" + s + "'" })
+	},
+	"indent":     indent,
+	"nindent":    func(spaces int, s string) string { return "\n" + indent(spaces, s) },
+	"toString":   toString,
+	"atoi":       func(s string) int { i, _ := strconv.Atoi(s); return i },
+	"regexMatch": func(regex string, s string) (bool, error) { return regexp.MatchString(regex, s) },
+	"regexReplaceAll": func(regex string, s string, repl string) (string, error) {
+		r, err := regexp.Compile(regex)
+		if err != nil {
+			return "", err
+		}
+		return r.ReplaceAllString(s, repl), nil
+	},
+
+	// Lists and dicts
+	"list":   func(values ...interface{}) []interface{} { return values },
+	"first":  func(list interface{}) interface{} { return listItem(list, 0) },
+	"last":   func(list interface{}) interface{} { return listItem(list, -1) },
+	"has":    func(needle interface{}, list interface{}) bool { return listHas(list, needle) },
+	"dict":   dict,
+	"get":    func(d map[string]interface{}, key string) interface{} { return d[key] },
+	"hasKey": func(d map[string]interface{}, key string) bool { _, ok := d[key]; return ok },
+	"keys":   dictKeys,
+
+	// Encoding
+	"b64enc": func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+	"b64dec": func(s string) (string, error) {
+		b, err := base64.StdEncoding.DecodeString(s)
+		return string(b), err
+	},
+	"sha256sum": func(s string) string {
+		sum := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(sum[:])
+	},
+	"toJson": func(value interface{}) (string, error) {
+		b, err := json.Marshal(value)
+		return string(b), err
+	},
+	"toPrettyJson": func(value interface{}) (string, error) {
+		b, err := json.MarshalIndent(value, "", "  ")
+		return string(b), err
+	},
+	"toYaml": func(value interface{}) (string, error) {
+		b, err := yaml.Marshal(value)
+		return strings.TrimSuffix(string(b), "\n"), err
+	},
+
+	// Paths
+	"base":  path.Base,
+	"dir":   path.Dir,
+	"ext":   path.Ext,
+	"clean": path.Clean,
+}
+
+// indent indents every line of s by the number of spaces
+func indent(spaces int, s string) string {
+	pad := strings.Repeat(" ", spaces)
+	return pad + strings.Replace(s, "\n", "\n"+pad, -1)
+}
+
+// isEmpty returns true for nil, false, zero numbers and empty strings, lists
+// and maps, like sprig's `empty`
+func isEmpty(value interface{}) bool {
+
+	v := reflect.ValueOf(value)
+	if !v.IsValid() {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// toString formats a value as a string, with byte slices as text
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// toStrings returns the items of a list as strings
+func toStrings(list interface{}) []string {
+
+	if strs, ok := list.([]string); ok {
+		return strs
+	}
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return []string{toString(list)}
+	}
+	strs := make([]string, v.Len())
+	for i := range strs {
+		strs[i] = toString(v.Index(i).Interface())
+	}
+	return strs
+}
+
+// joinQuoted quotes the non-nil values and joins them with spaces
+func joinQuoted(values []interface{}, quote func(string) string) string {
+	var quoted []string
+	for _, value := range values {
+		if value != nil {
+			quoted = append(quoted, quote(toString(value)))
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+// trunc truncates a string to length characters, or removes all but the last
+// -length characters if length is negative
+func trunc(length int, s string) string {
+	switch {
+	case length < 0 && len(s)+length > 0:
+		return s[len(s)+length:]
+	case length >= 0 && len(s) > length:
+		return s[:length]
+	}
+	return s
+}
+
+// split splits a string into a dict with the keys `_0`, `_1`...
+func split(sep string, s string) map[string]string {
+	parts := strings.Split(s, sep)
+	result := make(map[string]string, len(parts))
+	for i, part := range parts {
+		result["_"+strconv.Itoa(i)] = part
+	}
+	return result
+}
+
+// listItem returns the item of a list at the index, counted from the end if
+// negative, or nil
+func listItem(list interface{}, i int) interface{} {
+
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil
+	}
+	if i < 0 {
+		i += v.Len()
+	}
+	if i < 0 || i >= v.Len() {
+		return nil
+	}
+	return v.Index(i).Interface()
+}
+
+// listHas returns true if the list has the value
+func listHas(list interface{}, needle interface{}) bool {
+
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return false
+	}
+	for i := 0; i < v.Len(); i++ {
+		if reflect.DeepEqual(v.Index(i).Interface(), needle) {
+			return true
+		}
+	}
+	return false
+}
+
+// dict returns a dict of alternating keys and values
+func dict(values ...interface{}) map[string]interface{} {
+	d := make(map[string]interface{}, len(values)/2)
+	for i := 0; i+1 < len(values); i += 2 {
+		d[toString(values[i])] = values[i+1]
+	}
+	if len(values)%2 == 1 {
+		d[toString(values[len(values)-1])] = ""
+	}
+	return d
+}
+
+// dictKeys returns the keys of the dicts
+func dictKeys(dicts ...map[string]interface{}) []string {
+	var keys []string
+	for _, d := range dicts {
+		for key := range d {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// renderTemplates renders the templates of the instance.  The env vars
+// include the values of the instance secrets, so the templates (and the Helm
+// values files, rendered later by renderHelmValues) aren't rendered in the
+// deployment directory: it's copied to a temporary directory that the
+// instance is deployed from, and the returned cleanup removes it (with the
+// rendered files) and restores the deployment directory.  Templates rendered
+// when the deployed package was created are copied from the package instead.
+func (d *Deploy) renderTemplates(environment *Environment, instance *Instance) (func(), error) {
+
+	helmValues := d.config.Deployment.Type == deployTypeHelm && instance.Spec.Helm != nil && len(instance.Spec.Helm.ValuesFiles) > 0
+	if len(instance.Spec.Templates) == 0 && !helmValues {
+		return func() {}, nil
+	}
+
+	directory := d.config.Deployment.fullDirectoryPath
+	staged, err := ioutil.TempDir("", "stim-deploy")
+	if err != nil {
+		return nil, err
+	}
+	cleanup := func() {
+		d.config.Deployment.fullDirectoryPath = directory
+		os.RemoveAll(staged)
+	}
+	err = copyDirectory(directory, staged)
+	if err != nil {
+		cleanup()
+		return nil, fmt.Errorf("Error copying the deployment directory: %v", err)
+	}
+	d.config.Deployment.fullDirectoryPath = staged
+	d.log.Debug("Deploying {} from {} with its rendered templates", directory, staged)
+
+	var data *templateData
+	for _, t := range instance.Spec.Templates {
+		if d.pkg != nil && d.pkg.rendered(instance.Name, t.Output) {
+			err := d.pkg.copyRendered(instance.Name, t.Output, staged)
+			if err != nil {
+				cleanup()
+				return nil, fmt.Errorf("Error copying template '%s' from the package: %v", t.Output, err)
+			}
+			d.log.Debug("Copied template {} rendered in the package", t.Output)
+			continue
+		}
+
+		if data == nil {
+			data, err = d.instanceTemplateData(environment, instance)
+			if err != nil {
+				cleanup()
+				return nil, err
+			}
+		}
+		data.Values = t.Values
+		data.Lists = t.Lists
+		err := renderTemplate(staged, t, data)
+		if err != nil {
+			cleanup()
+			return nil, fmt.Errorf("Error rendering template '%s': %v", t.Input, err)
+		}
+		d.log.Debug("Rendered template {} to {}", t.Input, t.Output)
+	}
+
+	return cleanup, nil
+}
+
+// copyDirectory copies the files, directories and symlinks of a directory
+// (other than the checksumsExcludedDirs) to another, keeping their modes
+func copyDirectory(src string, dst string) error {
+
+	return filepath.Walk(src, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, file)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.IsDir():
+			if checksumsExcludedDirs[info.Name()] && rel != "." {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(file)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case !info.Mode().IsRegular():
+			return nil
+		}
+
+		in, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(out, in)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	})
+}
+
+// instanceTemplateData returns the data templates of the instance are
+// rendered with, without any values or lists
+func (d *Deploy) instanceTemplateData(environment *Environment, instance *Instance) (*templateData, error) {
+
+	env := make(map[string]string)
+	for _, e := range instance.Spec.EnvironmentVars {
+		env[e.Name] = e.Value
+	}
+	secrets, err := d.vaultSecretValues(instance)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range secrets {
+		env[name] = value
+	}
+
+	return &templateData{
+		Env:         env,
+		Environment: environment.Name,
+		Instance:    instance.Name,
+		Cluster:     instance.Spec.Kubernetes.Cluster,
+		Namespace:   env["DEPLOY_NAMESPACE"],
+	}, nil
+}
+
+// vaultSecretValues reads the values of the instance's Vault secrets
+func (d *Deploy) vaultSecretValues(instance *Instance) (map[string]string, error) {
+
+	var v *vault.Vault
+	values := make(map[string]string)
+	for _, secret := range instance.Spec.Secrets {
+		if !secret.isVault() {
+			continue
+		}
+		if v == nil {
+			var err error
+			v, err = d.stim.NewVault()
+			if err != nil {
+				return nil, err
+			}
+		}
+		keys, err := v.GetSecretKeysVersion(secret.SecretPath, int(secret.Version))
+		if err != nil {
+			return nil, err
+		}
+		for name, key := range secret.SecretMaps {
+			value, ok := keys[key]
+			if !ok {
+				return nil, fmt.Errorf("Key '%s' not found in secret '%s'", key, secret.SecretPath)
+			}
+			values[name] = value
+		}
+	}
+
+	return values, nil
+}
+
+// renderTemplate renders a template in the directory to its output file
+func renderTemplate(directory string, t *Template, data *templateData) error {
+
+	input, err := ioutil.ReadFile(filepath.Join(directory, t.Input))
+	if err != nil {
+		return err
+	}
+
+	out, err := executeTemplate(t.Input, input, data)
+	if err != nil {
+		return err
+	}
+
+	return writeRendered(filepath.Join(directory, t.Output), out)
+}
+
+// executeTemplate renders the content of a template
+func executeTemplate(name string, input []byte, data *templateData) ([]byte, error) {
+
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=default").Parse(string(input))
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	err = tmpl.Execute(&out, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+// writeRendered writes a rendered template, creating its directory
+func writeRendered(path string, content []byte) error {
+
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, content, 0600)
+}
+
+// mergeTemplates merges the templates of each level.  Templates with the same
+// output are replaced by the higher precedence level.
+func mergeTemplates(instance []*Template, environment []*Template, global []*Template) []*Template {
+
+	var result []*Template
+	outputs := make(map[string]bool)
+	for _, level := range [][]*Template{instance, environment, global} {
+		for _, t := range level {
+			if !outputs[t.Output] {
+				outputs[t.Output] = true
+				result = append(result, t)
+			}
+		}
+	}
+
+	return result
+}
+
+// validateTemplates checks that templates have an input and an output within
+// the deployment directory
+func validateTemplates(templates []*Template) error {
+	for _, t := range templates {
+		if t.Input == "" || t.Output == "" {
+			return errors.New("Both `input` and `output` must be set in the `spec.templates` config")
+		}
+		for _, p := range []string{t.Input, t.Output} {
+			if filepath.IsAbs(p) || strings.HasPrefix(filepath.Clean(p), "..") {
+				return fmt.Errorf("Template path '%s' must be relative to the deployment directory", p)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package deploy
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/PremiereGlobal/stim/stim"
+	"gotest.tools/assert"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stim-deploy-templates")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	input := `name: {{ .Env.APP_NAME | upper }}
+namespace: {{ .Namespace }}
+replicas: {{ .Values.replicas | default "1" }}
+hosts:{{ range .Lists.hosts }}
+  - {{ . | quote }}{{ end }}
+config: {{ toJson .Values | b64enc }}
+`
+	err = ioutil.WriteFile(filepath.Join(dir, "values.yaml.tmpl"), []byte(input), 0600)
+	assert.NilError(t, err)
+
+	err = renderTemplate(dir, &Template{Input: "values.yaml.tmpl", Output: "out/values.yaml"}, &templateData{
+		Env:       map[string]string{"APP_NAME": "my-app"},
+		Values:    map[string]string{"replicas": "3"},
+		Lists:     map[string][]string{"hosts": {"a.my-domain.com", "b.my-domain.com"}},
+		Namespace: "my-app",
+	})
+	assert.NilError(t, err)
+
+	out, err := ioutil.ReadFile(filepath.Join(dir, "out", "values.yaml"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), `name: MY-APP
+namespace: my-app
+replicas: 3
+hosts:
+  - "a.my-domain.com"
+  - "b.my-domain.com"
+config: eyJyZXBsaWNhcyI6IjMifQ==
+`)
+}
+
+func TestRenderTemplateMissingKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stim-deploy-templates")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "default.tmpl"), []byte(`{{ .Env.MISSING | default "none" }}`), 0600)
+	assert.NilError(t, err)
+	err = ioutil.WriteFile(filepath.Join(dir, "required.tmpl"), []byte(`{{ required "MISSING is required" .Env.MISSING }}`), 0600)
+	assert.NilError(t, err)
+
+	data := &templateData{Env: map[string]string{}}
+	err = renderTemplate(dir, &Template{Input: "default.tmpl", Output: "default"}, data)
+	assert.NilError(t, err)
+	out, err := ioutil.ReadFile(filepath.Join(dir, "default"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "none")
+
+	err = renderTemplate(dir, &Template{Input: "required.tmpl", Output: "required"}, data)
+	assert.ErrorContains(t, err, "MISSING is required")
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{{ 0 | default 5 }}`, "5"},
+		{`{{ list | empty }}`, "true"},
+		{`{{ coalesce "" "a" "b" }}`, "a"},
+		{`{{ ternary "yes" "no" true }}`, "yes"},
+		{`{{ "my-app-name" | trunc 6 }}`, "my-app"},
+		{`{{ "a,b,c" | splitList "," | last }}`, "c"},
+		{`{{ list "a" "b" | join "-" }}`, "a-b"},
+		{`{{ (split "." "a.b")._1 }}`, "b"},
+		{`{{ get (dict "a" 1) "a" }}`, "1"},
+		{`{{ has "b" (list "a" "b") }}`, "true"},
+		{`{{ "abc" | sha256sum | trunc 8 }}`, "ba7816bf"},
+		{`{{ regexReplaceAll "[0-9]+" "v123" "N" }}`, "vN"},
+		{`{{ "/a/b/c.yaml" | base }}`, "c.yaml"},
+		{`{{ quote "a" "b" }}`, `"a" "b"`},
+	}
+	for _, test := range tests {
+		out, err := executeTemplate("test", []byte(test.input), &templateData{})
+		assert.NilError(t, err, test.input)
+		assert.Equal(t, string(out), test.expected, test.input)
+	}
+
+	_, err := executeTemplate("test", []byte(`{{ fail "no" }}`), &templateData{})
+	assert.ErrorContains(t, err, "no")
+}
+
+func TestRenderTemplatesStaged(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stim-deploy-templates")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NilError(t, os.MkdirAll(filepath.Join(dir, ".git"), 0700))
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, "deploy.sh"), []byte("#!/bin/sh"), 0755))
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, "in.tmpl"), []byte(`{{ .Env.SECRET }}`), 0600))
+
+	s := stim.New()
+	d := &Deploy{stim: s, log: s.GetLogger()}
+	d.config.Deployment.fullDirectoryPath = dir
+	instance := &Instance{Name: "dev", Spec: &Spec{
+		EnvironmentVars: []*EnvironmentVar{{Name: "SECRET", Value: "s3cr3t"}},
+		Templates:       []*Template{{Input: "in.tmpl", Output: "out"}},
+	}}
+
+	cleanup, err := d.renderTemplates(&Environment{Name: "dev"}, instance)
+	assert.NilError(t, err)
+	staged := d.config.Deployment.fullDirectoryPath
+	assert.Assert(t, staged != dir)
+
+	out, err := ioutil.ReadFile(filepath.Join(staged, "out"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "s3cr3t")
+	info, err := os.Stat(filepath.Join(staged, "deploy.sh"))
+	assert.NilError(t, err)
+	assert.Equal(t, info.Mode().Perm(), os.FileMode(0755))
+	_, err = os.Stat(filepath.Join(staged, ".git"))
+	assert.Assert(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(dir, "out"))
+	assert.Assert(t, os.IsNotExist(err))
+
+	cleanup()
+	assert.Equal(t, d.config.Deployment.fullDirectoryPath, dir)
+	_, err = os.Stat(staged)
+	assert.Assert(t, os.IsNotExist(err))
+}
@@ -2,18 +2,22 @@ package deploy
 
 import (
 	"encoding/json"
+	"fmt"
+
+	v2e "github.com/PremiereGlobal/vault-to-envs/pkg/vaulttoenvs"
 )
 
 // makeSecretConfig generates a secret config json string based on the instance configuration
-func (d *Deploy) makeSecretConfig(instance *Instance) (string, error) {
+func makeSecretConfig(instance *Instance) (string, error) {
 
 	secretConfigString := ""
 
-	if len(instance.Spec.Secrets) > 0 {
+	secretItems := vaultSecretItems(instance)
+	if len(secretItems) > 0 {
 
-		b, err := json.Marshal(instance.Spec.Secrets)
+		b, err := json.Marshal(secretItems)
 		if err != nil {
-			d.log.Fatal("Unable to create secret config: {}", err)
+			return "", fmt.Errorf("Unable to create secret config: %v", err)
 		}
 
 		secretConfigString = string(b)
@@ -21,3 +25,16 @@ func (d *Deploy) makeSecretConfig(instance *Instance) (string, error) {
 
 	return secretConfigString, nil
 }
+
+// vaultSecretItems returns the instance secrets that are read from Vault
+func vaultSecretItems(instance *Instance) []*v2e.SecretItem {
+
+	var secretItems []*v2e.SecretItem
+	for _, s := range instance.Spec.Secrets {
+		if s.isVault() {
+			secretItems = append(secretItems, &s.SecretItem)
+		}
+	}
+
+	return secretItems
+}
@@ -0,0 +1,195 @@
+package deploy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// watchPollInterval is how often the watched files are checked for changes
+const watchPollInterval = 500 * time.Millisecond
+
+// defaultWatchDebounce is how long the files must be unchanged before a
+// redeploy when no debounce is set
+const defaultWatchDebounce = time.Second
+
+// fileState is what is compared to find the changed files
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// watch deploys the selected instance, then redeploys it whenever the deploy
+// config or a file of the deploy directory changes, until stim is cancelled
+func (d *Deploy) watch() error {
+
+	debounce := defaultWatchDebounce
+	if value := d.stim.ConfigGetString("deploy.watch-debounce"); value != "" {
+		var err error
+		debounce, err = time.ParseDuration(value)
+		if err != nil {
+			return stim.ConfigError(fmt.Errorf("Invalid deploy.watch-debounce '%s': %v", value, err))
+		}
+	}
+
+	err := d.selectWatchTarget()
+	if err != nil {
+		return err
+	}
+
+	for {
+		err := d.runServices()
+		if err != nil && d.stim.Context().Err() != nil {
+			return err
+		}
+		if err != nil {
+			d.log.Warn("Deploy failed, waiting for changes to redeploy: {}", err)
+		}
+
+		// Files written by the deploy itself (ex. rendered templates) are
+		// part of the snapshot, so they don't trigger another deploy
+		snapshot, err := d.watchSnapshot()
+		if err != nil {
+			return err
+		}
+		d.log.Info("Watching {} files for changes.  Press Ctrl+C to stop", len(snapshot))
+
+		changed, err := d.waitForChanges(snapshot, debounce)
+		if err != nil {
+			return err
+		}
+		d.log.Info("Changed: {}.  Redeploying", strings.Join(changed, ", "))
+	}
+}
+
+// selectWatchTarget selects the environment and instance to redeploy, so
+// that the redeploys don't prompt.  Protected environments and ones that
+// need approvals can't be watched.
+func (d *Deploy) selectWatchTarget() error {
+
+	// Only one service can be selected when the config is loaded
+	d.workspace, d.service = nil, nil
+	err := d.parseConfig()
+	if err != nil {
+		return err
+	}
+	if d.service != nil {
+		d.stim.ConfigOverride("deploy.service", []string{d.service.Name})
+	}
+
+	environment, instances, err := d.selectInstances()
+	if err != nil {
+		return err
+	}
+	if environment == nil {
+		return stim.Aborted("No instance selected")
+	}
+
+	if d.isProtectedEnvironment(environment.Name) || (environment.Policy != nil && environment.Policy.Approvals != nil && environment.Policy.Approvals.Count > 0) {
+		return stim.UsageError(fmt.Errorf("Environment '%s' is protected or needs approvals and can't be watched.  Use --watch with a development environment", environment.Name))
+	}
+
+	target := allOptionCli
+	if len(instances) == 1 {
+		target = instances[0].Name
+	}
+	d.stim.ConfigOverride("deploy.environment", environment.Name)
+	d.stim.ConfigOverride("deploy.instance", target)
+	return nil
+}
+
+// watchSnapshot returns the state of the deploy config file and of the files
+// of the deploy directory
+func (d *Deploy) watchSnapshot() (map[string]fileState, error) {
+
+	configAbs, err := filepath.Abs(d.config.configFilePath)
+	if err != nil {
+		return nil, err
+	}
+	return snapshotFiles([]string{configAbs, d.config.Deployment.fullDirectoryPath})
+}
+
+// waitForChanges waits until files differ from the snapshot and then haven't
+// changed for the debounce duration, and returns the changed files
+func (d *Deploy) waitForChanges(snapshot map[string]fileState, debounce time.Duration) ([]string, error) {
+
+	clock := d.stim.Clock()
+	current := snapshot
+	var lastChange time.Time
+	for {
+		// Ctrl-C stops watching
+		err := d.stim.Sleep(watchPollInterval)
+		if err != nil {
+			return nil, err
+		}
+
+		next, err := d.watchSnapshot()
+		if err != nil {
+			return nil, err
+		}
+		if len(changedFiles(current, next)) > 0 {
+			current = next
+			lastChange = clock.Now()
+			continue
+		}
+
+		if !lastChange.IsZero() && clock.Now().Sub(lastChange) >= debounce {
+			if changed := changedFiles(snapshot, current); len(changed) > 0 {
+				return changed, nil
+			}
+			// The files were changed back
+			lastChange = time.Time{}
+		}
+	}
+}
+
+// snapshotFiles returns the state of the files and of every file in the
+// directories.  The checksumsExcludedDirs are skipped.
+func snapshotFiles(paths []string) (map[string]fileState, error) {
+
+	states := make(map[string]fileState)
+	for _, p := range paths {
+		err := filepath.Walk(p, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if checksumsExcludedDirs[info.Name()] {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			states[file] = fileState{size: info.Size(), modTime: info.ModTime()}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return states, nil
+}
+
+// changedFiles returns the files that were added, removed or changed between
+// two snapshots, sorted
+func changedFiles(before map[string]fileState, after map[string]fileState) []string {
+
+	var changed []string
+	for file, state := range after {
+		if previous, ok := before[file]; !ok || previous.size != state.size || !previous.modTime.Equal(state.modTime) {
+			changed = append(changed, file)
+		}
+	}
+	for file := range before {
+		if _, ok := after[file]; !ok {
+			changed = append(changed, file)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
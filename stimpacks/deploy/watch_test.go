@@ -0,0 +1,59 @@
+package deploy
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestSnapshotFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stim-deploy-watch")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, sub := range []string{"charts", ".git", ".stim"} {
+		assert.NilError(t, os.MkdirAll(filepath.Join(dir, sub), 0755))
+	}
+	for _, name := range []string{"stim.deploy.yaml", "deploy.sh", "charts/values.yaml", ".git/HEAD", ".stim/last"} {
+		assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte("x"), 0644))
+	}
+
+	// The config file is also in the deploy directory
+	config := filepath.Join(dir, "stim.deploy.yaml")
+	before, err := snapshotFiles([]string{config, dir})
+	assert.NilError(t, err)
+	assert.Equal(t, len(before), 3)
+	assert.Assert(t, len(changedFiles(before, before)) == 0)
+
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, "deploy.sh"), []byte("changed"), 0644))
+	assert.NilError(t, os.Remove(filepath.Join(dir, "charts/values.yaml")))
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, "charts/new.yaml"), []byte("x"), 0644))
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, ".git/HEAD"), []byte("changed"), 0644))
+
+	after, err := snapshotFiles([]string{config, dir})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, changedFiles(before, after), []string{
+		filepath.Join(dir, "charts/new.yaml"),
+		filepath.Join(dir, "charts/values.yaml"),
+		filepath.Join(dir, "deploy.sh"),
+	})
+}
+
+func TestChangedFiles(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	before := map[string]fileState{
+		"a": {size: 1, modTime: now},
+		"b": {size: 1, modTime: now},
+		"c": {size: 1, modTime: now},
+	}
+	after := map[string]fileState{
+		"a": {size: 1, modTime: now},
+		"b": {size: 1, modTime: now.Add(time.Second)},
+		"c": {size: 2, modTime: now},
+	}
+	assert.DeepEqual(t, changedFiles(before, after), []string{"b", "c"})
+}
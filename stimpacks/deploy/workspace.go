@@ -0,0 +1,269 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/PremiereGlobal/stim/pkg/i18n"
+	"github.com/PremiereGlobal/stim/pkg/utils"
+	"github.com/PremiereGlobal/stim/stim"
+	"gopkg.in/yaml.v2"
+)
+
+// workspaceFile lists the services of a monorepo, in the workspace root
+const workspaceFile = "stim.workspace.yaml"
+
+// deployFileName is the name of the deploy config of a service
+const deployFileName = "stim.deploy.yaml"
+
+// selectionKeyServices is the key of the remembered selection of services
+const selectionKeyServices = "deploy/services"
+
+// skippedServiceDirs are not searched for deploy configs
+var skippedServiceDirs = []string{"node_modules", "vendor"}
+
+// Workspace is a monorepo of services that each have their own deploy config.
+// The global spec is shared by every service.
+type Workspace struct {
+	Services []*Service `yaml:"services"`
+	Global   Global     `yaml:"global"`
+}
+
+// Service is a service of a workspace.  Path is the deploy config file or
+// the directory it is in, relative to the workspace root.  It defaults to the
+// name of the service.
+type Service struct {
+	Name string `yaml:"name"`
+	Path string `yaml:"path"`
+	file string
+}
+
+// usesWorkspace returns true if the deploy config is selected by service,
+// either with --service or because there is a workspace file in the
+// workspace root and no deploy file is given
+func (d *Deploy) usesWorkspace() bool {
+	if len(d.stim.ConfigGetStringSlice("deploy.service")) > 0 {
+		return true
+	}
+	if d.stim.ConfigGetString("deploy.file") != "" {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(d.workspaceRoot(), workspaceFile))
+	return err == nil
+}
+
+// workspaceRoot returns the directory the services are found in
+func (d *Deploy) workspaceRoot() string {
+	root := d.stim.ConfigGetString("deploy.workspace-root")
+	setConfigDefault(&root, ".")
+	return root
+}
+
+// selectServices returns the services given with --service, prompting for
+// them if none are given.  It returns nil if the deploy config isn't selected
+// by service and an empty list if no service is selected at the prompt.
+func (d *Deploy) selectServices() ([]*Service, error) {
+
+	if !d.usesWorkspace() {
+		return nil, nil
+	}
+	if d.stim.ConfigGetString("deploy.file") != "" {
+		return nil, stim.UsageError(errors.New("--deploy-file and --service can't be used together"))
+	}
+
+	workspace, err := loadWorkspace(d.workspaceRoot())
+	if err != nil {
+		return nil, stim.ConfigError(err)
+	}
+	d.workspace = workspace
+
+	names := d.stim.ConfigGetStringSlice("deploy.service")
+	if len(names) == 0 && d.stim.ConfigGetBool("deploy.multi-select") {
+		names, _ = d.stim.PromptMultiList(selectionKeyServices, d.stim.Message(i18n.MessageWhichService), workspace.names(), nil)
+		if len(names) == 0 {
+			return []*Service{}, nil
+		}
+	} else if len(names) == 0 {
+		name, _ := d.stim.PromptListRemembered(selectionKeyServices, d.stim.Message(i18n.MessageWhichService), append([]string{allOptionPrompt}, workspace.names()...), "")
+		if name == "" {
+			return []*Service{}, nil
+		}
+		names = []string{name}
+	}
+
+	services, err := workspace.selectServices(names)
+	if err != nil {
+		return nil, stim.ConfigError(err)
+	}
+	return services, nil
+}
+
+// loadServiceConfig reads the deploy config of a service without resolving
+// it and merges it over the global spec of the workspace
+func (d *Deploy) loadServiceConfig(service *Service) error {
+
+	err := d.loadConfigFile(service.file)
+	if err != nil {
+		return err
+	}
+
+	if d.workspace.Global.Spec != nil {
+		spec := d.config.Global.Spec
+		if spec == nil {
+			spec = &Spec{}
+		}
+		d.config.Global.Spec = mergeBaseSpec(d.workspace.Global.Spec, spec)
+	}
+	return nil
+}
+
+// completeServices returns the service names of the workspace for shell
+// completion
+func (d *Deploy) completeServices() ([]string, error) {
+	workspace, err := loadWorkspace(d.workspaceRoot())
+	if err != nil {
+		return nil, err
+	}
+	return workspace.names(), nil
+}
+
+// loadWorkspace reads the workspace file in the root or, if there isn't one,
+// finds the deploy configs under the root
+func loadWorkspace(root string) (*Workspace, error) {
+
+	path := filepath.Join(root, workspaceFile)
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		services, err := discoverServices(root)
+		if err != nil {
+			return nil, err
+		}
+		return &Workspace{Services: services}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Workspace file could not be read: %v", err)
+	}
+
+	content, err = interpolateEnv(content, lookupEnv)
+	if err != nil {
+		return nil, fmt.Errorf("%v in '%s'", err, path)
+	}
+	workspace := &Workspace{}
+	err = yaml.Unmarshal(content, workspace)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing workspace file '%s': %v", path, err)
+	}
+	if len(workspace.Services) == 0 {
+		return nil, fmt.Errorf("No `services` in workspace file '%s'", path)
+	}
+
+	names := make(map[string]bool)
+	for _, service := range workspace.Services {
+		if service.Name == "" {
+			return nil, fmt.Errorf("Every service in workspace file '%s' must have a `name`", path)
+		}
+		if strings.ToLower(service.Name) == allOptionCli {
+			return nil, fmt.Errorf("Service name '%s' is reserved", service.Name)
+		}
+		if names[service.Name] {
+			return nil, fmt.Errorf("Service '%s' is in workspace file '%s' more than once", service.Name, path)
+		}
+		names[service.Name] = true
+
+		servicePath := service.Path
+		setConfigDefault(&servicePath, service.Name)
+		service.file = filepath.Join(root, filepath.FromSlash(servicePath))
+		if info, err := os.Stat(service.file); err == nil && info.IsDir() {
+			service.file = filepath.Join(service.file, deployFileName)
+		}
+	}
+
+	return workspace, nil
+}
+
+// discoverServices finds the deploy configs under the root.  Each service is
+// named after the directory its deploy config is in.  Hidden directories,
+// `node_modules` and `vendor` are skipped.
+func discoverServices(root string) ([]*Service, error) {
+
+	var services []*Service
+	dirs := make(map[string]string)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != root && (strings.HasPrefix(info.Name(), ".") || utils.Contains(skippedServiceDirs, info.Name())) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Name() != deployFileName {
+			return nil
+		}
+
+		dir := filepath.Dir(path)
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		name := filepath.Base(abs)
+		if other, ok := dirs[name]; ok {
+			return fmt.Errorf("The deploy configs in '%s' and '%s' are both service '%s', list the services in a %s to name them", other, dir, name, workspaceFile)
+		}
+		dirs[name] = dir
+
+		rel, err := filepath.Rel(root, dir)
+		if err != nil {
+			return err
+		}
+		services = append(services, &Service{Name: name, Path: filepath.ToSlash(rel), file: path})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(services) == 0 {
+		return nil, fmt.Errorf("No %s files found under '%s'", deployFileName, root)
+	}
+
+	return services, nil
+}
+
+// selectServices returns the services with the given names, or every service
+// if one of the names is `all`
+func (w *Workspace) selectServices(names []string) ([]*Service, error) {
+
+	var services []*Service
+	for _, name := range names {
+		if strings.ToLower(name) == allOptionCli || name == allOptionPrompt {
+			return w.Services, nil
+		}
+		found := false
+		for _, service := range w.Services {
+			if service.Name == name {
+				services = append(services, service)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("Service '%s' is not in the workspace, must be one of: %s", name, strings.Join(w.names(), ", "))
+		}
+	}
+
+	return services, nil
+}
+
+// names returns the names of the services
+func (w *Workspace) names() []string {
+	names := make([]string, len(w.Services))
+	for i, service := range w.Services {
+		names[i] = service.Name
+	}
+	return names
+}
@@ -0,0 +1,104 @@
+package deploy
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+// writeWorkspaceFiles writes the files (by slash separated path) under a new
+// workspace root
+func writeWorkspaceFiles(t *testing.T, files map[string]string) string {
+	root, err := ioutil.TempDir("", "stim-workspace")
+	assert.NilError(t, err)
+	for path, content := range files {
+		path = filepath.Join(root, filepath.FromSlash(path))
+		assert.NilError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		assert.NilError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	}
+	return root
+}
+
+func TestDiscoverServices(t *testing.T) {
+	root := writeWorkspaceFiles(t, map[string]string{
+		"services/api/stim.deploy.yaml":                "",
+		"services/web/stim.deploy.yaml":                "",
+		"services/web/node_modules/x/stim.deploy.yaml": "",
+		".git/stim.deploy.yaml":                        "",
+		"README.md":                                    "",
+	})
+	defer os.RemoveAll(root)
+
+	workspace, err := loadWorkspace(root)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, workspace.names(), []string{"api", "web"})
+	assert.Equal(t, workspace.Services[0].Path, "services/api")
+	assert.Equal(t, workspace.Services[0].file, filepath.Join(root, "services", "api", deployFileName))
+
+	root = writeWorkspaceFiles(t, map[string]string{
+		"a/api/stim.deploy.yaml": "",
+		"b/api/stim.deploy.yaml": "",
+	})
+	defer os.RemoveAll(root)
+	_, err = loadWorkspace(root)
+	assert.ErrorContains(t, err, "are both service 'api', list the services in a stim.workspace.yaml to name them")
+}
+
+func TestLoadWorkspaceFile(t *testing.T) {
+	root := writeWorkspaceFiles(t, map[string]string{
+		workspaceFile: `
+services:
+  - name: api
+  - name: worker
+    path: services/api/stim.worker.yaml
+  - name: web
+    path: frontend
+global:
+  spec:
+    kubernetes:
+      cluster: blue.mydomain.com
+`,
+		"api/stim.deploy.yaml":      "",
+		"frontend/stim.deploy.yaml": "",
+	})
+	defer os.RemoveAll(root)
+
+	workspace, err := loadWorkspace(root)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, workspace.names(), []string{"api", "worker", "web"})
+	assert.Equal(t, workspace.Services[0].file, filepath.Join(root, "api", deployFileName))
+	assert.Equal(t, workspace.Services[1].file, filepath.Join(root, "services", "api", "stim.worker.yaml"))
+	assert.Equal(t, workspace.Services[2].file, filepath.Join(root, "frontend", deployFileName))
+	assert.Equal(t, workspace.Global.Spec.Kubernetes.Cluster, "blue.mydomain.com")
+
+	services, err := workspace.selectServices([]string{"web", "api"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{services[0].Name, services[1].Name}, []string{"web", "api"})
+	services, err = workspace.selectServices([]string{"ALL"})
+	assert.NilError(t, err)
+	assert.Equal(t, len(services), 3)
+	_, err = workspace.selectServices([]string{"db"})
+	assert.Error(t, err, "Service 'db' is not in the workspace, must be one of: api, worker, web")
+}
+
+func TestLoadWorkspaceFileErrors(t *testing.T) {
+	tests := []struct {
+		content string
+		err     string
+	}{
+		{"services: []", "No `services` in workspace file"},
+		{"services: [{path: api}]", "must have a `name`"},
+		{"services: [{name: api}, {name: api}]", "Service 'api' is in workspace file"},
+		{"services: [{name: all}]", "Service name 'all' is reserved"},
+	}
+
+	for _, test := range tests {
+		root := writeWorkspaceFiles(t, map[string]string{workspaceFile: test.content})
+		_, err := loadWorkspace(root)
+		os.RemoveAll(root)
+		assert.ErrorContains(t, err, test.err, test.content)
+	}
+}
@@ -26,13 +26,13 @@ func (d *Discover) Command(viper *viper.Viper) *cobra.Command {
 		Use:   "clusters",
 		Short: "Use to discover services, endpoints, etc.",
 		Long:  "Use to discover services, endpoints, etc.",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			result, err := d.DiscoverClusters()
 			if err != nil {
-				d.stim.Fatal(err)
-			} else {
-				fmt.Println(result)
+				return err
 			}
+			fmt.Println(result)
+			return nil
 		},
 	}
 
@@ -19,7 +19,10 @@ func (d *Discover) Name() string {
 }
 
 func (d *Discover) DiscoverClusters() (string, error) {
-	p := d.stim.Prometheus()
+	p, err := d.stim.NewPrometheus()
+	if err != nil {
+		return "", err
+	}
 	result, err := p.QueryInstant("kubernetes_build_info{}")
 	if err != nil {
 		return "", err
@@ -0,0 +1,303 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/certs"
+	"github.com/PremiereGlobal/stim/pkg/docker"
+	"github.com/PremiereGlobal/stim/pkg/utils"
+	stimvault "github.com/PremiereGlobal/stim/pkg/vault"
+	"github.com/docker/docker/api/types"
+	"github.com/hashicorp/vault/api"
+	"github.com/mitchellh/go-homedir"
+)
+
+// checkTimeout is how long a check waits on a server or command
+const checkTimeout = 5 * time.Second
+
+// Thresholds for warning about credentials that are about to expire
+const (
+	vaultTokenWarnTTL = time.Hour
+	awsWarnTTL        = 15 * time.Minute
+)
+
+// Doctor runs the checks of the local environment and prints the report.
+// Checks that fail return an error so the exit code can be used in scripts.
+func (d *Doctor) Doctor() error {
+
+	var results []result
+	results = append(results, d.checkConfig()...)
+	results = append(results, d.checkDocker())
+	results = append(results, d.checkVault()...)
+	results = append(results, d.checkAws())
+	results = append(results, d.checkKubectl())
+	if d.stim.ConfigGetString("deploy.policy.bundle-url") != "" || len(d.stim.ConfigGetStringSlice("deploy.policy.paths")) > 0 {
+		results = append(results, d.checkOpa())
+	}
+
+	r := newReport(results)
+	color := useColor(d.stim.ConfigGetBool("doctor-no-color"))
+	err := d.stim.PrintOutput(d.stim.ConfigGetString("doctor-output"), r, func(w *tabwriter.Writer) {
+		r.print(w, color)
+	})
+	if err != nil {
+		return err
+	}
+
+	if r.Failed > 0 {
+		return fmt.Errorf("%d check(s) failed", r.Failed)
+	}
+
+	return nil
+}
+
+// checkConfig checks that the stim config file can be read and isn't
+// readable by other users
+func (d *Doctor) checkConfig() []result {
+
+	path, err := filepath.Abs(d.stim.ConfigGetString("config-file"))
+	if err != nil {
+		return []result{{Check: "Config file", Status: statusFail, Message: err.Error()}}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return []result{{Check: "Config file", Status: statusFail, Message: err.Error()}}
+	}
+
+	_, err = d.stim.ConfigGetFileValues()
+	if err != nil {
+		return []result{{Check: "Config file", Status: statusFail, Message: fmt.Sprintf("Unable to parse %s: %v", path, err), Hint: "Fix the YAML with `stim config edit`"}}
+	}
+
+	results := []result{configFileResult(path, info.Mode(), runtime.GOOS)}
+	if profile := d.stim.ConfigGetString("current-profile"); profile != "" {
+		results = append(results, result{Check: "Config profile", Status: statusPass, Message: profile})
+	}
+
+	return results
+}
+
+// configFileResult warns if the config file (which can hold credentials) is
+// readable by other users.  Windows doesn't have Unix permissions.
+func configFileResult(path string, mode os.FileMode, goos string) result {
+
+	res := result{Check: "Config file", Status: statusPass, Message: path}
+	if goos != "windows" && mode.Perm()&0077 != 0 {
+		res.Status = statusWarn
+		res.Message = fmt.Sprintf("%s is accessible by other users (mode %04o)", path, mode.Perm())
+		res.Hint = fmt.Sprintf("Run `chmod 600 %s`", path)
+	}
+	return res
+}
+
+// checkDocker checks that the Docker API is reachable for deploys.  Podman
+// serves the Docker API when its socket is running.
+func (d *Doctor) checkDocker() result {
+
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+
+	res := result{Check: "Docker"}
+	client, err := docker.NewClient()
+	if err == nil {
+		var version types.Version
+		version, err = client.ServerVersion(ctx)
+		if err == nil {
+			name := version.Platform.Name
+			if name == "" {
+				name = "Docker"
+			}
+			res.Status = statusPass
+			res.Message = fmt.Sprintf("%s %s at %s", name, version.Version, client.DaemonHost())
+			return res
+		}
+	}
+
+	if method := d.stim.ConfigGetString("deploy.method"); method != "" && method != "auto" && method != "docker" {
+		res.Status = statusPass
+		res.Message = fmt.Sprintf("Not reachable, but not needed since deploy.method is %s", method)
+		return res
+	}
+
+	res.Status = statusWarn
+	res.Message = fmt.Sprintf("Not reachable: %v", err)
+	res.Hint = "Start Docker, it's needed by `stim deploy` (or deploy with `--method shell`)"
+	if _, lookErr := exec.LookPath("podman"); lookErr == nil {
+		res.Hint = "Podman is installed, start its API socket with `podman system service --time=0` and deploy with `--method podman`"
+	}
+	return res
+}
+
+// checkVault checks that the Vault server is reachable and that there is a
+// valid token, without prompting to log in
+func (d *Doctor) checkVault() []result {
+
+	address := d.stim.ConfigGetString("vault-address")
+	if address == "" {
+		return []result{{Check: "Vault server", Status: statusFail, Message: "vault-address is not set", Hint: "Run `stim config set vault-address <url>`"}}
+	}
+
+	config := api.DefaultConfig()
+	config.Address = address
+	config.Timeout = checkTimeout
+	config.MaxRetries = 0
+	if transport, ok := config.HttpClient.Transport.(*http.Transport); ok {
+		certs.ConfigureTransport(transport)
+	}
+	client, err := api.NewClient(config)
+	if err != nil {
+		return []result{{Check: "Vault server", Status: statusFail, Message: err.Error(), Hint: "Check the vault-address config option"}}
+	}
+
+	health, err := client.Sys().Health()
+	if err != nil {
+		return []result{{Check: "Vault server", Status: statusFail, Message: fmt.Sprintf("Unable to reach %s: %v", address, err), Hint: "Check the vault-address and your network or VPN connection"}}
+	}
+	if health.Sealed || !health.Initialized {
+		return []result{{Check: "Vault server", Status: statusFail, Message: fmt.Sprintf("%s is sealed or not initialized", address), Hint: "Contact the Vault administrators"}}
+	}
+	results := []result{{Check: "Vault server", Status: statusPass, Message: fmt.Sprintf("%s (version %s)", address, health.Version)}}
+
+	// Check the cached token the same way other commands do, but fail
+	// instead of prompting to log in
+	d.stim.ConfigOverride("is-automated", true)
+	vault, err := d.stim.NewVault()
+	if err != nil {
+		return append(results, result{Check: "Vault token", Status: statusFail, Message: fmt.Sprintf("No valid token: %v", err), Hint: "Run `stim vault login`"})
+	}
+	status, err := vault.GetTokenStatus()
+	if err != nil {
+		return append(results, result{Check: "Vault token", Status: statusFail, Message: err.Error(), Hint: "Run `stim vault login`"})
+	}
+
+	return append(results, vaultTokenResult(status))
+}
+
+// vaultTokenResult warns if the Vault token expires soon
+func vaultTokenResult(status *stimvault.TokenStatus) result {
+
+	res := result{Check: "Vault token", Status: statusPass}
+	switch {
+	case status.ExpireTime.IsZero():
+		res.Message = "Valid, never expires"
+	case status.TTL < vaultTokenWarnTTL:
+		res.Status = statusWarn
+		res.Message = fmt.Sprintf("Expires in %s", utils.HumanizeDuration(status.TTL))
+		res.Hint = "Run `stim vault login` to get a new token before long commands"
+	default:
+		res.Message = fmt.Sprintf("Valid for %s", utils.HumanizeDuration(status.TTL))
+	}
+	return res
+}
+
+// checkAws checks the expiration of the credentials in the AWS credentials
+// file
+func (d *Doctor) checkAws() result {
+
+	if os.Getenv("AWS_ACCESS_KEY_ID") != "" {
+		return result{Check: "AWS credentials", Status: statusPass, Message: "Using AWS_ACCESS_KEY_ID from the environment"}
+	}
+
+	// Don't let the AWS client create the credentials file
+	home, err := homedir.Dir()
+	if err != nil {
+		return result{Check: "AWS credentials", Status: statusFail, Message: err.Error()}
+	}
+	if _, err := os.Stat(filepath.Join(home, ".aws", "credentials")); os.IsNotExist(err) {
+		return awsResult(nil, d.stim.Clock().Now())
+	}
+
+	a, err := d.stim.NewAws("", "")
+	if err != nil {
+		return result{Check: "AWS credentials", Status: statusFail, Message: err.Error()}
+	}
+	profiles, err := a.GetProfiles()
+	if err != nil {
+		return result{Check: "AWS credentials", Status: statusFail, Message: fmt.Sprintf("Unable to read the credentials file: %v", err), Hint: "Fix or remove ~/.aws/credentials"}
+	}
+
+	return awsResult(profiles, d.stim.Clock().Now())
+}
+
+// awsResult warns about expired or expiring profiles.  Only stim-managed
+// profiles record an `aws_expiration`, other profiles are assumed to have
+// long-lived keys.
+func awsResult(profiles map[string]map[string]string, now time.Time) result {
+
+	res := result{Check: "AWS credentials"}
+	if len(profiles["default"]) == 0 {
+		res.Status = statusWarn
+		res.Message = "No default profile"
+		res.Hint = "Run `stim aws login` or `stim aws sso-login --default-profile`"
+		return res
+	}
+
+	var expired, expiring []string
+	for name, keys := range profiles {
+		expiration, err := time.Parse(time.RFC3339, keys["aws_expiration"])
+		if err != nil {
+			continue
+		}
+		switch {
+		case !expiration.After(now):
+			expired = append(expired, name)
+		case expiration.Sub(now) < awsWarnTTL:
+			expiring = append(expiring, name)
+		}
+	}
+	sort.Strings(expired)
+	sort.Strings(expiring)
+
+	switch {
+	case len(expired) > 0:
+		res.Status = statusWarn
+		res.Message = fmt.Sprintf("Expired profile(s): %s", strings.Join(expired, ", "))
+		res.Hint = "Run `stim aws refresh --all`"
+	case len(expiring) > 0:
+		res.Status = statusWarn
+		res.Message = fmt.Sprintf("Profile(s) expiring within %s: %s", utils.HumanizeDuration(awsWarnTTL), strings.Join(expiring, ", "))
+		res.Hint = "Run `stim aws refresh --all`"
+	default:
+		res.Status = statusPass
+		res.Message = fmt.Sprintf("%d profile(s), none expired", len(profiles))
+	}
+	return res
+}
+
+// checkKubectl checks that kubectl is on the PATH
+func (d *Doctor) checkKubectl() result {
+
+	path, err := exec.LookPath("kubectl")
+	if err != nil {
+		return result{Check: "kubectl", Status: statusWarn, Message: "Not found on the PATH", Hint: "Install kubectl, it's used by `stim deploy --method shell` and `stim kube exec`, `logs` and `port-forward`"}
+	}
+
+	return result{Check: "kubectl", Status: statusPass, Message: path}
+}
+
+// checkOpa checks that the opa CLI is on the PATH, or downloaded by deploys,
+// deploys evaluate the deploy policies with it
+func (d *Doctor) checkOpa() result {
+
+	if version := d.stim.ConfigGetString("tools.opa.version"); version != "" {
+		return result{Check: "opa", Status: statusPass, Message: "Version " + version + " is downloaded by deploys (tools.opa.version)"}
+	}
+
+	path, err := exec.LookPath("opa")
+	if err != nil {
+		return result{Check: "opa", Status: statusFail, Message: "Not found on the PATH", Hint: "Install opa (https://www.openpolicyagent.org/docs/latest/#running-opa) or set tools.opa.version to download it, deploys evaluate the deploy policies of the stim config with it"}
+	}
+
+	return result{Check: "opa", Status: statusPass, Message: path}
+}
@@ -0,0 +1,25 @@
+package doctor
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func (d *Doctor) Command(viper *viper.Viper) *cobra.Command {
+
+	var cmd = &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the local environment for common problems",
+		Long:  "Checks the stim config file, Docker (or Podman), Vault connectivity and token, AWS credentials, kubectl and (if deploy policies are configured) opa and prints a pass/warn/fail report with hints to fix each problem.  Exits non-zero if any check fails.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return d.Doctor()
+		},
+	}
+
+	cmd.Flags().StringP("output", "o", "table", "Optional. Output format (table or json)")
+	viper.BindPFlag("doctor-output", cmd.Flags().Lookup("output"))
+	cmd.Flags().Bool("no-color", false, "Optional. Don't color the report (also disabled by the NO_COLOR environment variable)")
+	viper.BindPFlag("doctor-no-color", cmd.Flags().Lookup("no-color"))
+
+	return cmd
+}
@@ -0,0 +1,22 @@
+package doctor
+
+import (
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+type Doctor struct {
+	name string
+	stim *stim.Stim
+}
+
+func New() *Doctor {
+	return &Doctor{name: "doctor"}
+}
+
+func (d *Doctor) Name() string {
+	return d.name
+}
+
+func (d *Doctor) BindStim(s *stim.Stim) {
+	d.stim = s
+}
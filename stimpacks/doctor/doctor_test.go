@@ -0,0 +1,101 @@
+package doctor
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/vault"
+	"gotest.tools/assert"
+)
+
+func TestConfigFileResult(t *testing.T) {
+	res := configFileResult("/home/me/.stim/config.yaml", 0600, "linux")
+	assert.Equal(t, res.Status, statusPass)
+
+	res = configFileResult("/home/me/.stim/config.yaml", 0644, "linux")
+	assert.Equal(t, res.Status, statusWarn)
+	assert.Equal(t, res.Message, "/home/me/.stim/config.yaml is accessible by other users (mode 0644)")
+	assert.Equal(t, res.Hint, "Run `chmod 600 /home/me/.stim/config.yaml`")
+
+	res = configFileResult(`C:\Users\me\.stim\config.yaml`, 0666, "windows")
+	assert.Equal(t, res.Status, statusPass)
+}
+
+func TestVaultTokenResult(t *testing.T) {
+	now := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	res := vaultTokenResult(&vault.TokenStatus{TTL: 8 * time.Hour, ExpireTime: now.Add(8 * time.Hour)})
+	assert.Equal(t, res.Status, statusPass)
+	assert.Equal(t, res.Message, "Valid for 8h")
+
+	res = vaultTokenResult(&vault.TokenStatus{TTL: 10 * time.Minute, ExpireTime: now.Add(10 * time.Minute)})
+	assert.Equal(t, res.Status, statusWarn)
+	assert.Equal(t, res.Message, "Expires in 10m")
+
+	res = vaultTokenResult(&vault.TokenStatus{})
+	assert.Equal(t, res.Status, statusPass)
+	assert.Equal(t, res.Message, "Valid, never expires")
+}
+
+func TestAwsResult(t *testing.T) {
+	now := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	profile := func(expiration time.Duration) map[string]string {
+		return map[string]string{
+			"aws_access_key_id": "ASIA",
+			"aws_expiration":    now.Add(expiration).Format(time.RFC3339),
+		}
+	}
+	static := map[string]string{"aws_access_key_id": "AKIA"}
+
+	tests := []struct {
+		name     string
+		profiles map[string]map[string]string
+		status   string
+		message  string
+	}{
+		{"no default", map[string]map[string]string{"dev/admin": profile(time.Hour)}, statusWarn, "No default profile"},
+		{"static keys", map[string]map[string]string{"default": static}, statusPass, "1 profile(s), none expired"},
+		{"valid", map[string]map[string]string{"default": profile(time.Hour), "dev/admin": profile(time.Hour)}, statusPass, "2 profile(s), none expired"},
+		{
+			"expired",
+			map[string]map[string]string{"default": profile(-time.Minute), "prod/admin": profile(-time.Hour), "dev/admin": profile(5 * time.Minute)},
+			statusWarn, "Expired profile(s): default, prod/admin",
+		},
+		{"expiring", map[string]map[string]string{"default": static, "dev/admin": profile(5 * time.Minute)}, statusWarn, "Profile(s) expiring within 15m: dev/admin"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res := awsResult(test.profiles, now)
+			assert.Equal(t, res.Status, test.status)
+			assert.Equal(t, res.Message, test.message)
+		})
+	}
+}
+
+func TestReportPrint(t *testing.T) {
+	r := newReport([]result{
+		{Check: "Config file", Status: statusPass, Message: "/home/me/.stim/config.yaml"},
+		{Check: "Docker", Status: statusWarn, Message: "Not reachable", Hint: "Start Docker"},
+		{Check: "Vault token", Status: statusFail, Message: "No valid token", Hint: "Run `stim vault login`"},
+	})
+	assert.Equal(t, r.Passed, 1)
+	assert.Equal(t, r.Warned, 1)
+	assert.Equal(t, r.Failed, 1)
+
+	var b bytes.Buffer
+	r.print(&b, false)
+	assert.Equal(t, b.String(), `PASS  Config file  /home/me/.stim/config.yaml
+WARN  Docker       Not reachable
+                   hint: Start Docker
+FAIL  Vault token  No valid token
+                   hint: Run `+"`stim vault login`"+`
+
+1 passed, 1 warning(s), 1 failed
+`)
+
+	b.Reset()
+	r.print(&b, true)
+	assert.Assert(t, bytes.HasPrefix(b.Bytes(), []byte("\x1b[32mPASS\x1b[0m  Config file  ")))
+}
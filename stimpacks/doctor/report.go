@@ -0,0 +1,94 @@
+package doctor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/chzyer/readline"
+)
+
+// Check statuses
+const (
+	statusPass = "pass"
+	statusWarn = "warn"
+	statusFail = "fail"
+)
+
+// statusColors are the ANSI colors of each status in the report
+var statusColors = map[string]string{
+	statusPass: "\x1b[32m",
+	statusWarn: "\x1b[33m",
+	statusFail: "\x1b[31m",
+}
+
+const colorReset = "\x1b[0m"
+
+// result is the outcome of a check, with a hint to fix it if it didn't pass
+type result struct {
+	Check   string `json:"check"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+// report is the output of `stim doctor`
+type report struct {
+	Results []result `json:"results"`
+	Passed  int      `json:"passed"`
+	Warned  int      `json:"warned"`
+	Failed  int      `json:"failed"`
+}
+
+// newReport counts the results by status
+func newReport(results []result) *report {
+	r := &report{Results: results}
+	for _, res := range results {
+		switch res.Status {
+		case statusPass:
+			r.Passed++
+		case statusWarn:
+			r.Warned++
+		case statusFail:
+			r.Failed++
+		}
+	}
+	return r
+}
+
+// print writes a line for each result, followed by its hint, and a summary.
+// Tabs aren't used since the color codes would throw off the alignment of a
+// tabwriter.
+func (r *report) print(w io.Writer, color bool) {
+
+	width := 0
+	for _, res := range r.Results {
+		if len(res.Check) > width {
+			width = len(res.Check)
+		}
+	}
+
+	for _, res := range r.Results {
+		label := strings.ToUpper(res.Status)
+		if color {
+			label = statusColors[res.Status] + label + colorReset
+		}
+		fmt.Fprintf(w, "%s  %-*s  %s\n", label, width, res.Check, res.Message)
+		if res.Hint != "" {
+			fmt.Fprintf(w, "      %-*s  hint: %s\n", width, "", res.Hint)
+		}
+	}
+
+	fmt.Fprintf(w, "\n%d passed, %d warning(s), %d failed\n", r.Passed, r.Warned, r.Failed)
+}
+
+// useColor returns true if the report should be colored.  Colors are only
+// used on a terminal and can be turned off with NO_COLOR
+// (https://no-color.org) or --no-color.
+func useColor(noColor bool) bool {
+	if noColor || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return readline.IsTerminal(int(os.Stdout.Fd()))
+}
@@ -0,0 +1,129 @@
+package github
+
+import (
+	"strings"
+
+	githubpkg "github.com/PremiereGlobal/stim/pkg/github"
+	"github.com/PremiereGlobal/stim/stim"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func (g *Github) BindStim(s *stim.Stim) {
+	g.stim = s
+}
+
+func (g *Github) Command(viper *viper.Viper) *cobra.Command {
+
+	var cmd = &cobra.Command{
+		Use:   "github",
+		Short: "Record deploys in GitHub",
+		Long:  "Create GitHub Deployments, Deployment Statuses and Releases so that GitHub's environment and release views reflect what was deployed",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	cmd.PersistentFlags().StringP("repo", "r", "", "Required. GitHub repository in the format owner/name (Default: the github.repo config option)")
+	viper.BindPFlag("github.repo", cmd.PersistentFlags().Lookup("repo"))
+
+	var deploymentCmd = &cobra.Command{
+		Use:   "deployment",
+		Short: "GitHub deployment commands",
+		Long:  "Create GitHub Deployments and set their status",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+	g.stim.BindCommand(deploymentCmd, cmd)
+
+	var deploymentCreateCmd = &cobra.Command{
+		Use: "create",
+		Annotations: map[string]string{
+			stim.AnnotationMutating:   "true",
+			stim.AnnotationStderrLogs: "true",
+		},
+		Short:   "Create a deployment",
+		Long:    "Create a GitHub Deployment of a commit to an environment and print its ID, which is used to set its status",
+		Example: "  id=$(stim github deployment create -r acme/app -e production --state in_progress)\n  stim github deployment status $id -r acme/app --state success",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return g.createDeployment()
+		},
+	}
+	g.stim.BindCommand(deploymentCreateCmd, deploymentCmd)
+
+	deploymentCreateCmd.Flags().String("ref", "", "Commit SHA, branch or tag that was deployed (Default: the commit checked out in the current directory)")
+	viper.BindPFlag("github-deployment-ref", deploymentCreateCmd.Flags().Lookup("ref"))
+	deploymentCreateCmd.Flags().StringP("environment", "e", "", "Required. Name of the GitHub environment (ex. production)")
+	viper.BindPFlag("github-deployment-environment", deploymentCreateCmd.Flags().Lookup("environment"))
+	deploymentCreateCmd.Flags().StringP("description", "d", "", "Description of the deployment")
+	viper.BindPFlag("github-deployment-description", deploymentCreateCmd.Flags().Lookup("description"))
+	deploymentCreateCmd.Flags().Bool("production", false, "Mark the environment as a production environment")
+	viper.BindPFlag("github-deployment-production", deploymentCreateCmd.Flags().Lookup("production"))
+	deploymentCreateCmd.Flags().String("state", "", "Also set the initial status of the deployment (ex. in_progress)")
+	viper.BindPFlag("github-deployment-create-state", deploymentCreateCmd.Flags().Lookup("state"))
+	deploymentCreateCmd.Flags().StringP("output", "o", "", "Output format (table or json) (Default: only print the deployment ID)")
+	viper.BindPFlag("github-deployment-output", deploymentCreateCmd.Flags().Lookup("output"))
+
+	var deploymentStatusCmd = &cobra.Command{
+		Use:         "status <deployment-id>",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Set the status of a deployment",
+		Long:        "Set the status of a GitHub Deployment.  The state is one of " + strings.Join(githubpkg.States, ", "),
+		Args:        cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return g.setDeploymentStatus(args[0])
+		},
+	}
+	g.stim.BindCommand(deploymentStatusCmd, deploymentCmd)
+
+	deploymentStatusCmd.Flags().StringP("state", "s", "", "Required. State of the deployment ("+strings.Join(githubpkg.States, ", ")+")")
+	viper.BindPFlag("github-deployment-state", deploymentStatusCmd.Flags().Lookup("state"))
+	deploymentStatusCmd.Flags().StringP("description", "d", "", "Description of the status")
+	viper.BindPFlag("github-deployment-status-description", deploymentStatusCmd.Flags().Lookup("description"))
+	deploymentStatusCmd.Flags().String("environment-url", "", "URL of the deployed environment")
+	viper.BindPFlag("github-deployment-environment-url", deploymentStatusCmd.Flags().Lookup("environment-url"))
+	deploymentStatusCmd.Flags().String("log-url", "", "URL of the deploy logs (ex. the CI job)")
+	viper.BindPFlag("github-deployment-log-url", deploymentStatusCmd.Flags().Lookup("log-url"))
+
+	var releaseCmd = &cobra.Command{
+		Use:   "release",
+		Short: "GitHub release commands",
+		Long:  "Create GitHub Releases",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+	g.stim.BindCommand(releaseCmd, cmd)
+
+	var releaseCreateCmd = &cobra.Command{
+		Use: "create <tag>",
+		Annotations: map[string]string{
+			stim.AnnotationMutating:   "true",
+			stim.AnnotationStderrLogs: "true",
+		},
+		Short: "Create a release",
+		Long:  "Create a GitHub Release for a tag and print its URL.  The tag is created from --target if it doesn't exist",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return g.createRelease(args[0])
+		},
+	}
+	g.stim.BindCommand(releaseCreateCmd, releaseCmd)
+
+	releaseCreateCmd.Flags().String("target", "", "Commit SHA or branch to create the tag from (Default: the repository's default branch)")
+	viper.BindPFlag("github-release-target", releaseCreateCmd.Flags().Lookup("target"))
+	releaseCreateCmd.Flags().StringP("name", "n", "", "Name of the release (Default: the tag)")
+	viper.BindPFlag("github-release-name", releaseCreateCmd.Flags().Lookup("name"))
+	releaseCreateCmd.Flags().StringP("notes", "m", "", "Release notes")
+	viper.BindPFlag("github-release-notes", releaseCreateCmd.Flags().Lookup("notes"))
+	releaseCreateCmd.Flags().StringP("notes-file", "f", "", "File containing the release notes")
+	viper.BindPFlag("github-release-notes-file", releaseCreateCmd.Flags().Lookup("notes-file"))
+	releaseCreateCmd.Flags().Bool("draft", false, "Create an unpublished draft release")
+	viper.BindPFlag("github-release-draft", releaseCreateCmd.Flags().Lookup("draft"))
+	releaseCreateCmd.Flags().Bool("prerelease", false, "Mark the release as a prerelease")
+	viper.BindPFlag("github-release-prerelease", releaseCreateCmd.Flags().Lookup("prerelease"))
+
+	return cmd
+}
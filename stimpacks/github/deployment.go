@@ -0,0 +1,126 @@
+package github
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	githubpkg "github.com/PremiereGlobal/stim/pkg/github"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// createDeployment creates a deployment and prints its ID
+func (g *Github) createDeployment() error {
+
+	repo, err := g.repo()
+	if err != nil {
+		return err
+	}
+
+	environment := g.stim.ConfigGetString("github-deployment-environment")
+	if environment == "" {
+		return stim.UsageError(errors.New("GitHub environment not specified, use --environment"))
+	}
+
+	state := g.stim.ConfigGetString("github-deployment-create-state")
+	if state != "" && !githubpkg.ValidState(state) {
+		return stim.UsageError(fmt.Errorf("Invalid state '%s', must be one of %s", state, strings.Join(githubpkg.States, ", ")))
+	}
+
+	ref := g.stim.ConfigGetString("github-deployment-ref")
+	if ref == "" {
+		ref, err = headCommit()
+		if err != nil {
+			return stim.UsageError(fmt.Errorf("Unable to find the checked out commit, use --ref: %v", err))
+		}
+	}
+
+	client := g.stim.Github()
+	deployment, err := client.CreateDeployment(repo, &githubpkg.DeploymentRequest{
+		Ref:         ref,
+		Environment: environment,
+		Description: g.stim.ConfigGetString("github-deployment-description"),
+		Production:  g.stim.ConfigGetBool("github-deployment-production"),
+	})
+	if err != nil {
+		return err
+	}
+	g.stim.GetLogger().Info("Created deployment {} of {} to '{}'", deployment.ID, ref, environment)
+
+	if state != "" {
+		err = client.CreateDeploymentStatus(repo, deployment.ID, &githubpkg.StatusRequest{State: state})
+		if err != nil {
+			return fmt.Errorf("Created deployment %d but unable to set its status: %v", deployment.ID, err)
+		}
+	}
+
+	output := g.stim.ConfigGetString("github-deployment-output")
+	if output == "" {
+		fmt.Println(deployment.ID)
+		return nil
+	}
+	return g.stim.PrintOutput(output, deployment, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "ID\tENVIRONMENT\tSHA")
+		fmt.Fprintf(w, "%d\t%s\t%s\n", deployment.ID, deployment.Environment, deployment.Sha)
+	})
+}
+
+// setDeploymentStatus sets the status of a deployment
+func (g *Github) setDeploymentStatus(id string) error {
+
+	repo, err := g.repo()
+	if err != nil {
+		return err
+	}
+
+	deploymentID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return stim.UsageError(fmt.Errorf("Invalid deployment ID '%s'", id))
+	}
+
+	state := g.stim.ConfigGetString("github-deployment-state")
+	if !githubpkg.ValidState(state) {
+		return stim.UsageError(fmt.Errorf("Invalid state '%s', must be one of %s", state, strings.Join(githubpkg.States, ", ")))
+	}
+
+	err = g.stim.Github().CreateDeploymentStatus(repo, deploymentID, &githubpkg.StatusRequest{
+		State:          state,
+		Description:    g.stim.ConfigGetString("github-deployment-status-description"),
+		EnvironmentURL: g.stim.ConfigGetString("github-deployment-environment-url"),
+		LogURL:         g.stim.ConfigGetString("github-deployment-log-url"),
+	})
+	if err != nil {
+		return err
+	}
+
+	g.stim.GetLogger().Info("Set the status of deployment {} to {}", deploymentID, state)
+	return nil
+}
+
+// repo returns the `owner/name` of the GitHub repository
+func (g *Github) repo() (string, error) {
+	repo := g.stim.ConfigGetString("github.repo")
+	if repo == "" {
+		return "", stim.UsageError(errors.New("GitHub repository not specified, use --repo"))
+	}
+	if len(strings.Split(repo, "/")) != 2 {
+		return "", stim.UsageError(fmt.Errorf("Invalid GitHub repository '%s', must be in the format owner/name", repo))
+	}
+	return repo, nil
+}
+
+// headCommit returns the commit checked out in the current directory
+func headCommit() (string, error) {
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,21 @@
+package github
+
+import (
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// Github is the stimpack that records deploys as GitHub Deployments and
+// Releases
+type Github struct {
+	name string
+	stim *stim.Stim
+}
+
+func New() *Github {
+	github := &Github{name: "github"}
+	return github
+}
+
+func (g *Github) Name() string {
+	return g.name
+}
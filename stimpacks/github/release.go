@@ -0,0 +1,52 @@
+package github
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	githubpkg "github.com/PremiereGlobal/stim/pkg/github"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// createRelease creates a release for the tag and prints its URL
+func (g *Github) createRelease(tag string) error {
+
+	repo, err := g.repo()
+	if err != nil {
+		return err
+	}
+
+	notes := g.stim.ConfigGetString("github-release-notes")
+	if notesFile := g.stim.ConfigGetString("github-release-notes-file"); notesFile != "" {
+		if notes != "" {
+			return stim.UsageError(errors.New("Only one of --notes or --notes-file can be set"))
+		}
+		data, err := ioutil.ReadFile(notesFile)
+		if err != nil {
+			return err
+		}
+		notes = string(data)
+	}
+
+	name := g.stim.ConfigGetString("github-release-name")
+	if name == "" {
+		name = tag
+	}
+
+	release, err := g.stim.Github().CreateRelease(repo, &githubpkg.ReleaseRequest{
+		TagName:    tag,
+		Target:     g.stim.ConfigGetString("github-release-target"),
+		Name:       name,
+		Body:       notes,
+		Draft:      g.stim.ConfigGetBool("github-release-draft"),
+		Prerelease: g.stim.ConfigGetBool("github-release-prerelease"),
+	})
+	if err != nil {
+		return err
+	}
+
+	g.stim.GetLogger().Info("Created release '{}'", name)
+	fmt.Println(release.HTMLURL)
+	return nil
+}
@@ -0,0 +1,249 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/kubernetes"
+	"github.com/PremiereGlobal/stim/pkg/notify"
+	pd "github.com/PremiereGlobal/stim/pkg/pagerduty"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// clusterCertificate is a certificate along with the cluster it was found in
+type clusterCertificate struct {
+	cluster string
+	*kubernetes.Certificate
+}
+
+// certificateOutput is an expiring certificate in the JSON output of
+// `stim kube certs`, with the exact expiry and time left
+type certificateOutput struct {
+	Cluster     string    `json:"cluster,omitempty"`
+	Source      string    `json:"source"`
+	Namespace   string    `json:"namespace,omitempty"`
+	Name        string    `json:"name"`
+	Subject     string    `json:"subject"`
+	Hosts       []string  `json:"hosts"`
+	NotAfter    time.Time `json:"notAfter"`
+	SecondsLeft int64     `json:"secondsLeft"`
+	Critical    bool      `json:"critical"`
+}
+
+// scanCertificates scans the selected clusters (and the local kubeconfig) for
+// certificates that expire within the configured number of days
+func (k *Kubernetes) scanCertificates() error {
+
+	days := k.stim.ConfigGetInt("kube-certs-days")
+	criticalDays := k.stim.ConfigGetInt("kube-certs-critical-days")
+	warnWithin := time.Duration(days) * 24 * time.Hour
+	criticalWithin := time.Duration(criticalDays) * 24 * time.Hour
+
+	sa := k.stim.ConfigGetString("kube-certs-service-account")
+	if sa == "" && k.stim.IsAutomated() {
+		return errors.New("Kubernetes service account not specified")
+	}
+
+	clusters, err := k.getCertificateClusters()
+	if err != nil {
+		return err
+	}
+
+	tmpDir, err := ioutil.TempDir("", "stim-kube-certs")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	var expiring []*clusterCertificate
+	for _, cluster := range clusters {
+
+		clusterSA := sa
+		if clusterSA == "" {
+			clusterSA, err = k.stim.PromptListVault(k.stim.KubeServiceAccountListPath(cluster), "Select Service Account for "+cluster, "")
+			if err != nil {
+				return err
+			}
+		}
+
+		k.stim.GetLogger().Info("Scanning certificates in cluster {}", cluster)
+		kc, err := k.stim.KubeConfigFromVault(&stim.KubeConfigOptions{
+			Cluster:        cluster,
+			ServiceAccount: clusterSA,
+			Path:           filepath.Join(tmpDir, cluster),
+		})
+		if err != nil {
+			return err
+		}
+
+		kube, err := kubernetes.New(kc)
+		if err != nil {
+			return err
+		}
+
+		certs, warnings, err := kube.GetTLSCertificates()
+		if err != nil {
+			k.stim.GetLogger().Warn("Unable to scan certificates in cluster {}: {}", cluster, err)
+			continue
+		}
+		for _, warning := range warnings {
+			k.stim.GetLogger().Warn("Cluster {}: {}", cluster, warning)
+		}
+
+		for _, cert := range certs {
+			if cert.ExpiresWithin(warnWithin) {
+				expiring = append(expiring, &clusterCertificate{cluster: cluster, Certificate: cert})
+			}
+		}
+	}
+
+	// Also check the client certificates in the user's kubeconfig
+	if !k.stim.ConfigGetBool("kube-certs-skip-kubeconfig") {
+		certs, warnings, err := kubernetes.NewConfig().GetClientCertificates()
+		if err != nil {
+			k.stim.GetLogger().Warn("Unable to scan kubeconfig client certificates: {}", err)
+		}
+		for _, warning := range warnings {
+			k.stim.GetLogger().Warn(warning)
+		}
+		for _, cert := range certs {
+			if cert.ExpiresWithin(warnWithin) {
+				expiring = append(expiring, &clusterCertificate{Certificate: cert})
+			}
+		}
+	}
+
+	sort.Slice(expiring, func(i, j int) bool {
+		return expiring[i].NotAfter.Before(expiring[j].NotAfter)
+	})
+
+	format := k.stim.ConfigGetString("kube-certs-output")
+	if len(expiring) == 0 && format != stim.OutputJSON {
+		fmt.Printf("No certificates expiring within %d days\n", days)
+		return nil
+	}
+
+	output := make([]*certificateOutput, 0, len(expiring))
+	for _, c := range expiring {
+		output = append(output, &certificateOutput{
+			Cluster:     c.cluster,
+			Source:      c.Source,
+			Namespace:   c.Namespace,
+			Name:        c.Name,
+			Subject:     c.Subject,
+			Hosts:       c.Hosts,
+			NotAfter:    c.NotAfter,
+			SecondsLeft: int64(time.Until(c.NotAfter).Seconds()),
+			Critical:    c.ExpiresWithin(criticalWithin),
+		})
+	}
+	err = k.stim.PrintOutput(format, output, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "CLUSTER\tSOURCE\tNAMESPACE\tNAME\tSUBJECT\tEXPIRES\tTIME LEFT\tHOSTS")
+		for _, c := range output {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", c.Cluster, c.Source, c.Namespace, c.Name, c.Subject,
+				k.stim.FormatTime(c.NotAfter), k.stim.FormatDuration(time.Duration(c.SecondsLeft)*time.Second), strings.Join(c.Hosts, ","))
+		}
+	})
+	if err != nil || len(expiring) == 0 {
+		return err
+	}
+
+	k.notifyExpiringCertificates(expiring, days, criticalWithin)
+
+	// Open incidents for the critical ones, if requested
+	service := k.stim.ConfigGetString("kube-certs-pagerduty-service")
+	if service != "" {
+		pagerduty := k.stim.Pagerduty()
+		for _, c := range expiring {
+			if !c.ExpiresWithin(criticalWithin) {
+				continue
+			}
+
+			id := strings.Join([]string{c.cluster, c.Source, c.Namespace, c.Name}, "/")
+			_, err := pagerduty.SendEvent(&pd.Event{
+				Action:   pd.EventTrigger,
+				Service:  service,
+				Severity: "critical",
+				Summary:  fmt.Sprintf("Certificate %s (%s) expires %s", id, c.Subject, c.NotAfter.Format("2006-01-02")),
+				Source:   c.cluster,
+				Class:    "certificate-expiry",
+				DedupKey: "stim-cert-expiry-" + id,
+			})
+			if err != nil {
+				return err
+			}
+			k.stim.GetLogger().Info("Opened Pagerduty incident for certificate {}", id)
+		}
+	}
+
+	return nil
+}
+
+// notifyExpiringCertificates sends a `kube.certs.expiring` event to the
+// notification backends in the stim config.  Nothing is sent if no
+// certificates are inside the warning window.
+func (k *Kubernetes) notifyExpiringCertificates(expiring []*clusterCertificate, days int, criticalWithin time.Duration) {
+
+	if len(expiring) == 0 {
+		return
+	}
+
+	critical := 0
+	var lines []string
+	for _, c := range expiring {
+		if c.ExpiresWithin(criticalWithin) {
+			critical++
+		}
+		lines = append(lines, fmt.Sprintf("%s/%s/%s/%s expires %s", c.cluster, c.Source, c.Namespace, c.Name, c.NotAfter.Format("2006-01-02")))
+	}
+	status := notify.StatusInfo
+	if critical > 0 {
+		status = notify.StatusFailure
+	}
+
+	err := k.stim.Notify("kube.certs.expiring", &notify.Payload{
+		Title:  fmt.Sprintf("%d certificates expiring within %d days (%d critical)", len(expiring), days, critical),
+		Text:   strings.Join(lines, "\n"),
+		Status: status,
+	})
+	if err != nil {
+		k.stim.GetLogger().Warn("Unable to send certificate notification: {}", err)
+	}
+}
+
+// getCertificateClusters returns the clusters to scan.  If none are given,
+// the user is prompted to choose one, or all clusters in Vault are used
+// when running automated.
+func (k *Kubernetes) getCertificateClusters() ([]string, error) {
+
+	clusters := k.stim.ConfigGetStringSlice("kube-certs-clusters")
+	if len(clusters) > 0 {
+		return clusters, nil
+	}
+
+	vault, err := k.stim.NewVault()
+	if err != nil {
+		return nil, err
+	}
+	all, err := vault.ListSecrets(k.stim.KubeClusterListPath())
+	if err != nil {
+		return nil, err
+	}
+	if k.stim.IsAutomated() || k.stim.ConfigGetBool("kube-certs-all-clusters") {
+		return all, nil
+	}
+
+	cluster, err := k.stim.PromptList("Select Cluster", all, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{cluster}, nil
+}
@@ -1,7 +1,9 @@
 package kubernetes
 
 import (
+	"github.com/PremiereGlobal/stim/stim"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
@@ -20,16 +22,14 @@ func (k *Kubernetes) Command(viper *viper.Viper) *cobra.Command {
 		Use:   "config",
 		Short: "Create/modify a Kubernetes context",
 		Long:  "Create/modify a Kubernetes context",
-		Run: func(cmd *cobra.Command, args []string) {
-			err := k.configureContext()
-			if err != nil {
-				k.stim.Fatal(err)
-			}
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return k.configureContext()
 		},
 	}
 
 	configCmd.Flags().StringP("cluster", "c", "", "Required. Name of cluster to config")
 	viper.BindPFlag("kube-config-cluster", configCmd.Flags().Lookup("cluster"))
+	k.stim.BindFlagCompletion(configCmd, "cluster", "kube-clusters", k.completeClusters)
 	configCmd.Flags().StringP("service-account", "s", "", "Required. Name of service account to use")
 	viper.BindPFlag("kube-service-account", configCmd.Flags().Lookup("service-account"))
 	configCmd.Flags().StringP("context", "t", "", "Optional. Name of context to set. Default is cluster name")
@@ -41,5 +41,331 @@ func (k *Kubernetes) Command(viper *viper.Viper) *cobra.Command {
 
 	k.stim.BindCommand(configCmd, cmd)
 
+	var ctxCmd = &cobra.Command{
+		Use:   "ctx [context]",
+		Short: "Switch the current context",
+		Long:  "Switch the current kubeconfig context, or select one with a fuzzy search.  Contexts created by `stim kube config` and `stim kube sync` are included, and --list shows which ones were synced",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := ""
+			if len(args) > 0 {
+				name = args[0]
+			}
+			return k.switchContext(name)
+		},
+	}
+
+	ctxCmd.Flags().BoolP("list", "l", false, "List the contexts instead of switching")
+	viper.BindPFlag("kube-ctx-list", ctxCmd.Flags().Lookup("list"))
+	ctxCmd.Flags().StringP("output", "o", "table", "Output format of --list (table or json)")
+	viper.BindPFlag("kube-ctx-output", ctxCmd.Flags().Lookup("output"))
+
+	k.stim.BindCommand(ctxCmd, cmd)
+
+	var nsCmd = &cobra.Command{
+		Use:   "ns [namespace]",
+		Short: "Switch the namespace of the current context",
+		Long:  "Set the default namespace of the current kubeconfig context, or select one of the namespaces of the cluster with a fuzzy search",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := ""
+			if len(args) > 0 {
+				name = args[0]
+			}
+			return k.switchNamespace(name)
+		},
+	}
+
+	nsCmd.Flags().BoolP("list", "l", false, "List the namespaces of the cluster instead of switching")
+	viper.BindPFlag("kube-ns-list", nsCmd.Flags().Lookup("list"))
+
+	k.stim.BindCommand(nsCmd, cmd)
+
+	var certsCmd = &cobra.Command{
+		Use:   "certs",
+		Short: "Scan for expiring certificates",
+		Long:  "Scan cluster TLS secrets, ingresses and kubeconfig client certificates for certificates nearing expiry",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return k.scanCertificates()
+		},
+	}
+
+	certsCmd.Flags().StringSliceP("cluster", "c", nil, "Optional. Cluster(s) to scan. Prompts if not set")
+	viper.BindPFlag("kube-certs-clusters", certsCmd.Flags().Lookup("cluster"))
+	k.stim.BindFlagCompletion(certsCmd, "cluster", "kube-clusters", k.completeClusters)
+	certsCmd.Flags().BoolP("all-clusters", "A", false, "Optional. Scan all clusters in Vault")
+	viper.BindPFlag("kube-certs-all-clusters", certsCmd.Flags().Lookup("all-clusters"))
+	certsCmd.Flags().StringP("service-account", "s", "", "Optional. Name of service account to use. Prompts if not set")
+	viper.BindPFlag("kube-certs-service-account", certsCmd.Flags().Lookup("service-account"))
+	certsCmd.Flags().IntP("days", "d", 30, "Optional. Report certificates expiring within this many days")
+	viper.BindPFlag("kube-certs-days", certsCmd.Flags().Lookup("days"))
+	certsCmd.Flags().Int("critical-days", 7, "Optional. Certificates expiring within this many days are critical")
+	viper.BindPFlag("kube-certs-critical-days", certsCmd.Flags().Lookup("critical-days"))
+	certsCmd.Flags().String("pagerduty-service", "", "Optional. Open Pagerduty incidents on this service for critical certificates")
+	viper.BindPFlag("kube-certs-pagerduty-service", certsCmd.Flags().Lookup("pagerduty-service"))
+	certsCmd.Flags().Bool("skip-kubeconfig", false, "Optional. Don't scan client certificates in the local kubeconfig")
+	viper.BindPFlag("kube-certs-skip-kubeconfig", certsCmd.Flags().Lookup("skip-kubeconfig"))
+	certsCmd.Flags().StringP("output", "o", stim.OutputTable, "Optional. Output format, table or json")
+	viper.BindPFlag("kube-certs-output", certsCmd.Flags().Lookup("output"))
+
+	k.stim.BindCommand(certsCmd, cmd)
+
+	var syncCmd = &cobra.Command{
+		Use:   "sync",
+		Short: "Sync Kubernetes contexts for all clusters",
+		Long:  "Create/update a Kubernetes context for every cluster in Vault (or the kube.sync.clusters config) and prune contexts for clusters that no longer exist",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return k.syncContexts()
+		},
+	}
+
+	syncCmd.Flags().StringSliceP("cluster", "c", nil, "Optional. Cluster(s) to sync. Stale contexts are not pruned when set")
+	viper.BindPFlag("kube-sync-clusters", syncCmd.Flags().Lookup("cluster"))
+	k.stim.BindFlagCompletion(syncCmd, "cluster", "kube-clusters", k.completeClusters)
+	syncCmd.Flags().StringP("service-account", "s", "", "Optional. Name of service account to use for all clusters. Prompts if a cluster has more than one")
+	viper.BindPFlag("kube-sync-service-account", syncCmd.Flags().Lookup("service-account"))
+	syncCmd.Flags().StringP("context-prefix", "p", "", "Optional. Prefix to add to the context names")
+	viper.BindPFlag("kube-sync-context-prefix", syncCmd.Flags().Lookup("context-prefix"))
+	syncCmd.Flags().Bool("prune", true, "Optional. Remove previously synced contexts for clusters that no longer exist")
+	viper.BindPFlag("kube-sync-prune", syncCmd.Flags().Lookup("prune"))
+
+	k.stim.BindCommand(syncCmd, cmd)
+
+	var getSecretCmd = &cobra.Command{
+		Use:   "get-secret <name>",
+		Short: "Show a Kubernetes secret with its values masked",
+		Long:  "Show the keys of a Kubernetes secret with base64-decoded values.  Values are masked unless selected with --show.  Every access is logged and sent to the `kube.secret.get` notification event",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return k.getSecret(args[0])
+		},
+	}
+
+	getSecretCmd.Flags().StringP("namespace", "n", "", "Optional. Namespace of the secret. Default is the namespace of the context")
+	viper.BindPFlag("kube-secret-namespace", getSecretCmd.Flags().Lookup("namespace"))
+	getSecretCmd.Flags().StringSlice("show", nil, "Optional. Key(s) to show the value of")
+	viper.BindPFlag("kube-secret-show", getSecretCmd.Flags().Lookup("show"))
+	getSecretCmd.Flags().StringP("cluster", "c", "", "Optional. Cluster to read the secret from using credentials from Vault. Default is the current context")
+	viper.BindPFlag("kube-secret-cluster", getSecretCmd.Flags().Lookup("cluster"))
+	k.stim.BindFlagCompletion(getSecretCmd, "cluster", "kube-clusters", k.completeClusters)
+	getSecretCmd.Flags().StringP("service-account", "s", "", "Optional. Name of service account to use with --cluster. Prompts if not set")
+	viper.BindPFlag("kube-secret-service-account", getSecretCmd.Flags().Lookup("service-account"))
+
+	k.stim.BindCommand(getSecretCmd, cmd)
+
+	var unlockCmd = &cobra.Command{
+		Use:         "unlock <cluster>",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Unlock the contexts of a locked cluster",
+		Long:        "Allow the contexts of a cluster in kube.locked-clusters to be used for a limited time.  Unlocks are logged and sent to the `kube.unlock` notification event",
+		Args:        cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return k.unlockCluster(args[0])
+		},
+	}
+
+	unlockCmd.Flags().StringP("duration", "d", "30m", "Optional. How long the cluster stays unlocked (ex. 30m)")
+	viper.BindPFlag("kube-unlock-duration", unlockCmd.Flags().Lookup("duration"))
+	unlockCmd.Flags().StringP("reason", "r", "", "Optional. Reason for the unlock, included in the notification")
+	viper.BindPFlag("kube-unlock-reason", unlockCmd.Flags().Lookup("reason"))
+
+	k.stim.BindCommand(unlockCmd, cmd)
+
+	var lockCmd = &cobra.Command{
+		Use:         "lock [cluster...]",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Lock unlocked clusters again",
+		Long:        "Lock the given clusters (or all unlocked clusters) before their unlock expires",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return k.lockClusters(args)
+		},
+	}
+
+	k.stim.BindCommand(lockCmd, cmd)
+
+	var rotateSACmd = &cobra.Command{
+		Use:         "rotate-sa",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Rotate the token of a service account",
+		Long:        "Creates a new token for a service account, writes it to the kube-config secret in Vault and, once the new token works from Vault, deletes the old token secret to revoke it",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return k.rotateServiceAccount()
+		},
+	}
+
+	rotateSACmd.Flags().StringP("cluster", "c", "", "Required. Name of the cluster. Prompts if not set")
+	viper.BindPFlag("kube-rotate-cluster", rotateSACmd.Flags().Lookup("cluster"))
+	k.stim.BindFlagCompletion(rotateSACmd, "cluster", "kube-clusters", k.completeClusters)
+	rotateSACmd.Flags().StringP("service-account", "s", "", "Required. Name of the service account in Vault. Prompts if not set")
+	viper.BindPFlag("kube-rotate-service-account", rotateSACmd.Flags().Lookup("service-account"))
+	rotateSACmd.Flags().StringP("namespace", "n", "", "Optional. Namespace of the service account. Default is the default-namespace of the kube-config secret")
+	viper.BindPFlag("kube-rotate-namespace", rotateSACmd.Flags().Lookup("namespace"))
+	rotateSACmd.Flags().String("name", "", "Optional. Name of the service account in the cluster. Default is the service account name in Vault")
+	viper.BindPFlag("kube-rotate-name", rotateSACmd.Flags().Lookup("name"))
+	rotateSACmd.Flags().String("admin-service-account", "", "Optional. Service account in Vault used to create and delete the token secrets. Default is the rotated service account")
+	viper.BindPFlag("kube-rotate-admin-service-account", rotateSACmd.Flags().Lookup("admin-service-account"))
+	rotateSACmd.Flags().Bool("keep-old", false, "Optional. Don't delete the old token secret")
+	viper.BindPFlag("kube-rotate-keep-old", rotateSACmd.Flags().Lookup("keep-old"))
+
+	k.stim.BindCommand(rotateSACmd, cmd)
+
+	var execCmd = &cobra.Command{
+		Use:         "exec <pod> [-- command...]",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Run a command in a pod with credentials from Vault",
+		Long:        "Run a command (default sh) in a pod with kubectl, using a temporary kubeconfig from Vault for the same identity as deploys.  ~/.kube/config is not changed",
+		Args:        cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return k.execPod(args[0], args[1:])
+		},
+	}
+
+	k.bindKubectlFlags(execCmd, execCmd.Flags(), "kube-exec", viper)
+	execCmd.Flags().String("container", "", "Optional. Container of the pod. Default is the first container")
+	viper.BindPFlag("kube-exec-container", execCmd.Flags().Lookup("container"))
+
+	k.stim.BindCommand(execCmd, cmd)
+
+	var portForwardCmd = &cobra.Command{
+		Use:   "port-forward <resource> <[local:]remote>...",
+		Short: "Forward local ports to a pod with credentials from Vault",
+		Long:  "Forward local ports to a pod, deployment (ex. deploy/web) or service (ex. svc/web) with kubectl, using a temporary kubeconfig from Vault for the same identity as deploys.  ~/.kube/config is not changed",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return k.portForward(args[0], args[1:])
+		},
+	}
+
+	k.bindKubectlFlags(portForwardCmd, portForwardCmd.Flags(), "kube-port-forward", viper)
+	portForwardCmd.Flags().String("address", "", "Optional. Local addresses to listen on, comma separated. Default is localhost")
+	viper.BindPFlag("kube-port-forward-address", portForwardCmd.Flags().Lookup("address"))
+
+	k.stim.BindCommand(portForwardCmd, cmd)
+
+	var logsCmd = &cobra.Command{
+		Use:   "logs <resource>",
+		Short: "Print the logs of a pod with credentials from Vault",
+		Long:  "Print the logs of a pod, deployment (ex. deploy/web) or job with kubectl, using a temporary kubeconfig from Vault for the same identity as deploys.  ~/.kube/config is not changed",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return k.podLogs(args[0])
+		},
+	}
+
+	k.bindKubectlFlags(logsCmd, logsCmd.Flags(), "kube-logs", viper)
+	logsCmd.Flags().String("container", "", "Optional. Container of the pod. Default is the first container")
+	viper.BindPFlag("kube-logs-container", logsCmd.Flags().Lookup("container"))
+	logsCmd.Flags().BoolP("follow", "f", false, "Optional. Keep printing new logs")
+	viper.BindPFlag("kube-logs-follow", logsCmd.Flags().Lookup("follow"))
+	logsCmd.Flags().BoolP("previous", "p", false, "Optional. Print the logs of the previous instance of the container")
+	viper.BindPFlag("kube-logs-previous", logsCmd.Flags().Lookup("previous"))
+	logsCmd.Flags().Int("tail", -1, "Optional. Number of recent lines to print, -1 for all lines")
+	viper.BindPFlag("kube-logs-tail", logsCmd.Flags().Lookup("tail"))
+	logsCmd.Flags().String("since", "", "Optional. Only print logs newer than a duration (ex. 1h)")
+	viper.BindPFlag("kube-logs-since", logsCmd.Flags().Lookup("since"))
+
+	k.stim.BindCommand(logsCmd, cmd)
+
+	var hpaCmd = &cobra.Command{
+		Use:   "hpa",
+		Short: "Inspect and override HorizontalPodAutoscalers",
+		Long:  "Show the status of HorizontalPodAutoscalers and temporarily override their replica limits (ex. during incidents or load tests), using credentials from Vault",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	k.bindKubectlFlags(hpaCmd, hpaCmd.PersistentFlags(), "kube-hpa", viper)
+	hpaCmd.PersistentFlags().StringP("selector", "l", "", "Optional. Label selector of the HPAs (ex. app=web)")
+	viper.BindPFlag("kube-hpa-selector", hpaCmd.PersistentFlags().Lookup("selector"))
+
+	var hpaStatusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "Show the status of HPAs",
+		Long:  "Show the replica limits, current and desired replicas, CPU utilization and overrides of the HorizontalPodAutoscalers matching a selector",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return k.hpaStatus()
+		},
+	}
+
+	hpaStatusCmd.Flags().BoolP("all-namespaces", "A", false, "Optional. Show the HPAs of all namespaces")
+	viper.BindPFlag("kube-hpa-all-namespaces", hpaStatusCmd.Flags().Lookup("all-namespaces"))
+	hpaStatusCmd.Flags().StringP("output", "o", stim.OutputTable, "Optional. Output format, table or json")
+	viper.BindPFlag("kube-hpa-output", hpaStatusCmd.Flags().Lookup("output"))
+
+	var hpaOverrideCmd = &cobra.Command{
+		Use:         "override <hpa>",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Temporarily override the replica limits of an HPA",
+		Long:        "Set the min and/or max replicas of a HorizontalPodAutoscaler for a limited time.  Stim waits and reverts the override when it expires (or on Ctrl-C) unless --no-wait is set.  Overrides and reverts are recorded in the audit log and sent to the `kube.hpa.override` and `kube.hpa.revert` notification events",
+		Args:        cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return k.hpaOverride(args[0])
+		},
+	}
+
+	hpaOverrideCmd.Flags().Int("min", 0, "Optional. Min replicas during the override. Default is the current min")
+	viper.BindPFlag("kube-hpa-override-min", hpaOverrideCmd.Flags().Lookup("min"))
+	hpaOverrideCmd.Flags().Int("max", 0, "Optional. Max replicas during the override. Default is the current max")
+	viper.BindPFlag("kube-hpa-override-max", hpaOverrideCmd.Flags().Lookup("max"))
+	hpaOverrideCmd.Flags().StringP("duration", "d", "1h", "Optional. How long the override lasts (ex. 30m)")
+	viper.BindPFlag("kube-hpa-override-duration", hpaOverrideCmd.Flags().Lookup("duration"))
+	hpaOverrideCmd.Flags().StringP("reason", "r", "", "Optional. Reason for the override, included in the notification")
+	viper.BindPFlag("kube-hpa-override-reason", hpaOverrideCmd.Flags().Lookup("reason"))
+	hpaOverrideCmd.Flags().Bool("no-wait", false, "Optional. Don't wait to revert the override.  Expired overrides are reverted by `stim kube hpa revert --expired`")
+	viper.BindPFlag("kube-hpa-override-no-wait", hpaOverrideCmd.Flags().Lookup("no-wait"))
+
+	var hpaRevertCmd = &cobra.Command{
+		Use:         "revert [hpa...]",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Revert HPA overrides",
+		Long:        "Restore the replica limits from before the override of the given HorizontalPodAutoscalers, or of every HPA whose override has expired with --expired",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return k.hpaRevert(args)
+		},
+	}
+
+	hpaRevertCmd.Flags().Bool("expired", false, "Optional. Revert every expired override in the namespace")
+	viper.BindPFlag("kube-hpa-revert-expired", hpaRevertCmd.Flags().Lookup("expired"))
+
+	k.stim.BindCommand(hpaStatusCmd, hpaCmd)
+	k.stim.BindCommand(hpaOverrideCmd, hpaCmd)
+	k.stim.BindCommand(hpaRevertCmd, hpaCmd)
+	k.stim.BindCommand(hpaCmd, cmd)
+
+	var credentialCmd = &cobra.Command{
+		Use:    "credential",
+		Hidden: true,
+		Annotations: map[string]string{
+			stim.AnnotationStderrLogs:    "true",
+			stim.AnnotationNoUpdateCheck: "true",
+		},
+		Short: "Exec credential plugin for locked contexts",
+		Long:  "Prints the token of a cluster as a kubectl exec credential.  Used by the contexts of clusters in kube.locked-clusters, which only get a token while the cluster is unlocked",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return k.credential()
+		},
+	}
+
+	credentialCmd.Flags().StringP("cluster", "c", "", "Required. Name of the cluster")
+	viper.BindPFlag("kube-credential-cluster", credentialCmd.Flags().Lookup("cluster"))
+	credentialCmd.Flags().StringP("service-account", "s", "", "Required. Name of the service account")
+	viper.BindPFlag("kube-credential-service-account", credentialCmd.Flags().Lookup("service-account"))
+
+	k.stim.BindCommand(credentialCmd, cmd)
+
 	return cmd
 }
+
+// bindKubectlFlags adds the cluster, service account and namespace flags of
+// the commands that run kubectl (or the Kubernetes API) with credentials from
+// Vault to the flag set of the command
+func (k *Kubernetes) bindKubectlFlags(cmd *cobra.Command, flags *pflag.FlagSet, prefix string, viper *viper.Viper) {
+	flags.StringP("cluster", "c", "", "Required. Name of the cluster. Prompts if not set")
+	viper.BindPFlag(prefix+"-cluster", flags.Lookup("cluster"))
+	k.stim.BindFlagCompletion(cmd, "cluster", "kube-clusters", k.completeClusters)
+	flags.StringP("service-account", "s", "", "Required. Name of the service account. Prompts if not set")
+	viper.BindPFlag(prefix+"-service-account", flags.Lookup("service-account"))
+	flags.StringP("namespace", "n", "", "Optional. Namespace of the resource. Default is the default-namespace of the kube-config secret")
+	viper.BindPFlag(prefix+"-namespace", flags.Lookup("namespace"))
+}
@@ -1,6 +1,7 @@
 package kubernetes
 
 import (
+	"github.com/PremiereGlobal/stim/pkg/i18n"
 	"github.com/PremiereGlobal/stim/pkg/kubernetes"
 	// "github.com/davecgh/go-spew/spew"
 )
@@ -8,22 +9,24 @@ import (
 func (k *Kubernetes) configureContext() error {
 
 	// Create a Vault instance
-	k.vault = k.stim.Vault()
-
 	var err error
+	k.vault, err = k.stim.NewVault()
+	if err != nil {
+		return err
+	}
 
-	cluster, err := k.stim.PromptListVault("secret/kubernetes", "Select Cluster", k.stim.ConfigGetString("kube-config-cluster"))
+	cluster, err := k.stim.PromptListVault(k.stim.KubeClusterListPath(), "Select Cluster", k.stim.ConfigGetString("kube-config-cluster"))
 	if err != nil {
 		return err
 	}
 
-	sa, err := k.stim.PromptListVault("secret/kubernetes/"+cluster, "Select Service Account", k.stim.ConfigGetString("kube-service-account"))
+	sa, err := k.stim.PromptListVault(k.stim.KubeServiceAccountListPath(cluster), "Select Service Account", k.stim.ConfigGetString("kube-service-account"))
 	if err != nil {
 		return err
 	}
 
 	// Get secrets from Vault
-	secretValues, err := k.vault.GetSecretKeys("secret/kubernetes/" + cluster + "/" + sa + "/kube-config")
+	secretValues, err := k.vault.GetSecretKeys(k.stim.KubeConfigSecretPath(cluster, sa))
 	if err != nil {
 		return err
 	}
@@ -44,7 +47,7 @@ func (k *Kubernetes) configureContext() error {
 		}
 	}
 
-	currentContext, err := k.stim.PromptBool("Set as current context?", k.stim.ConfigGetBool("kube-current-context"), true)
+	currentContext, err := k.stim.PromptBool(k.stim.Message(i18n.MessageSetCurrentContext), k.stim.ConfigGetBool("kube-current-context"), true)
 	if err != nil {
 		return err
 	}
@@ -61,6 +64,14 @@ func (k *Kubernetes) configureContext() error {
 		ContextDefaultNamespace: namespace,
 	}
 
+	// Contexts of locked clusters get the token from `stim kube credential`
+	if k.stim.IsKubeClusterLockable(cluster) {
+		kubeConfigOptions.AuthExecCommand, kubeConfigOptions.AuthExecArgs, err = k.stim.KubeCredentialCommand(cluster, sa)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Gets us a kubeConfig object using the default kubeconfig paths, etc.
 	kubeConfig := kubernetes.NewConfig()
 	err = kubeConfig.Modify(kubeConfigOptions)
@@ -70,3 +81,12 @@ func (k *Kubernetes) configureContext() error {
 
 	return nil
 }
+
+// completeClusters returns the clusters in Vault for shell completion
+func (k *Kubernetes) completeClusters() ([]string, error) {
+	vault, err := k.stim.NewVault()
+	if err != nil {
+		return nil, err
+	}
+	return vault.ListSecrets(k.stim.KubeClusterListPath())
+}
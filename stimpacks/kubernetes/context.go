@@ -0,0 +1,175 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/PremiereGlobal/stim/pkg/kubernetes"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// kubeContext is a kubeconfig context, marked if it was created by `stim kube
+// sync`
+type kubeContext struct {
+	kubernetes.Context
+	Synced bool `json:"synced"`
+}
+
+// switchContext switches to the given context, or prompts for one.  With
+// --list (or when not interactive) the contexts are listed instead.
+func (k *Kubernetes) switchContext(name string) error {
+
+	kc := kubernetes.NewConfig()
+
+	if name == "" {
+		contexts, err := k.listContexts(kc)
+		if err != nil {
+			return err
+		}
+		if k.stim.ConfigGetBool("kube-ctx-list") || k.stim.IsAutomated() {
+			return k.stim.PrintOutput(k.stim.ConfigGetString("kube-ctx-output"), contexts, func(w *tabwriter.Writer) {
+				fmt.Fprintln(w, "CURRENT\tNAME\tCLUSTER\tNAMESPACE\tSYNCED")
+				for _, context := range contexts {
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\n", strings.TrimSpace(currentMark(context.Current)), context.Name, context.Cluster, context.Namespace, context.Synced)
+				}
+			})
+		}
+
+		var names []string
+		for _, context := range contexts {
+			names = append(names, context.Name)
+		}
+		if len(names) == 0 {
+			return errors.New("No contexts in the kubeconfig, create one with `stim kube config` or `stim kube sync`")
+		}
+		name, err = k.stim.PromptList("Select Context", currentFirst(names, currentContext(contexts)), "")
+		if err != nil {
+			return err
+		}
+	}
+
+	err := kc.UseContext(name)
+	if err != nil {
+		return stim.UsageError(err)
+	}
+
+	k.stim.GetLogger().Info("Switched to context {}", name)
+	return nil
+}
+
+// listContexts returns the contexts of the kubeconfig, marking the ones
+// created by `stim kube sync`
+func (k *Kubernetes) listContexts(kc *kubernetes.Config) ([]kubeContext, error) {
+
+	contexts, err := kc.Contexts()
+	if err != nil {
+		return nil, err
+	}
+
+	synced, err := readSyncState(filepath.Join(k.stim.ConfigGetCacheDir("kube"), syncStateFile))
+	if err != nil {
+		k.stim.GetLogger().Debug("Unable to read the synced contexts: {}", err)
+	}
+	isSynced := make(map[string]bool)
+	for _, name := range synced {
+		isSynced[name] = true
+	}
+
+	result := make([]kubeContext, 0, len(contexts))
+	for _, context := range contexts {
+		result = append(result, kubeContext{Context: context, Synced: isSynced[context.Name]})
+	}
+
+	return result, nil
+}
+
+// switchNamespace sets the namespace of the current context to the given
+// namespace, or prompts for one of the namespaces of the cluster.  With
+// --list (or when not interactive) the namespaces are listed instead.
+func (k *Kubernetes) switchNamespace(name string) error {
+
+	kc := kubernetes.NewConfig()
+
+	context, err := kc.CurrentContext()
+	if err != nil {
+		return err
+	}
+	if context == "" {
+		return errors.New("No current context, select one with `stim kube ctx`")
+	}
+	current, err := kc.Namespace()
+	if err != nil {
+		return err
+	}
+
+	list := k.stim.ConfigGetBool("kube-ns-list") || (name == "" && k.stim.IsAutomated())
+
+	var namespaces []string
+	if name == "" || list {
+		kube, err := kubernetes.New(kc)
+		if err != nil {
+			return err
+		}
+		namespaces, err = kube.ListNamespaces()
+		if err != nil {
+			return fmt.Errorf("Unable to list the namespaces of context %s (give the namespace instead): %v", context, err)
+		}
+	}
+
+	if list {
+		for _, namespace := range namespaces {
+			fmt.Printf("%s%s\n", currentMark(namespace == current), namespace)
+		}
+		return nil
+	}
+
+	if name == "" {
+		name, err = k.stim.PromptList("Select Namespace", currentFirst(namespaces, current), "")
+		if err != nil {
+			return err
+		}
+	}
+
+	err = kc.SetNamespace(name)
+	if err != nil {
+		return err
+	}
+
+	k.stim.GetLogger().Info("Switched context {} to namespace {}", context, name)
+	return nil
+}
+
+// currentContext returns the name of the current context
+func currentContext(contexts []kubeContext) string {
+	for _, context := range contexts {
+		if context.Current {
+			return context.Name
+		}
+	}
+	return ""
+}
+
+// currentFirst moves the current item to the top of the list, so it is
+// selected by default
+func currentFirst(list []string, current string) []string {
+	result := make([]string, 0, len(list))
+	for _, item := range list {
+		if item == current {
+			result = append([]string{item}, result...)
+		} else {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// currentMark returns the column marking the current context or namespace
+func currentMark(current bool) string {
+	if current {
+		return "* "
+	}
+	return "  "
+}
@@ -0,0 +1,12 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestCurrentFirst(t *testing.T) {
+	assert.DeepEqual(t, currentFirst([]string{"default", "kube-system", "web"}, "web"), []string{"web", "default", "kube-system"})
+	assert.DeepEqual(t, currentFirst([]string{"default", "web"}, "other"), []string{"default", "web"})
+}
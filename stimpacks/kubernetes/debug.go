@@ -0,0 +1,176 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	"github.com/PremiereGlobal/stim/pkg/kubernetes"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// execPod runs a command in a pod, a shell if no command is given
+func (k *Kubernetes) execPod(pod string, command []string) error {
+	return k.kubectl("kube-exec", execArgs(pod, k.stim.ConfigGetString("kube-exec-container"), isTerminal(os.Stdin), command))
+}
+
+// portForward forwards local ports to a pod, deployment or service
+func (k *Kubernetes) portForward(resource string, ports []string) error {
+	args := []string{"port-forward", resource}
+	if address := k.stim.ConfigGetString("kube-port-forward-address"); address != "" {
+		args = append(args, "--address", address)
+	}
+	return k.kubectl("kube-port-forward", append(args, ports...))
+}
+
+// podLogs prints the logs of a pod, deployment or job
+func (k *Kubernetes) podLogs(resource string) error {
+	return k.kubectl("kube-logs", logsArgs(resource,
+		k.stim.ConfigGetString("kube-logs-container"),
+		k.stim.ConfigGetBool("kube-logs-follow"),
+		k.stim.ConfigGetBool("kube-logs-previous"),
+		k.stim.ConfigGetInt("kube-logs-tail"),
+		k.stim.ConfigGetString("kube-logs-since"),
+	))
+}
+
+// kubectl runs kubectl with a temporary kubeconfig built from the kube-config
+// secret of the cluster and service account in Vault.  This runs kubectl as
+// the same identity as deploys, without changing ~/.kube/config.  Flags are
+// read from the config keys starting with the given prefix (ex.
+// `kube-exec-cluster`).
+func (k *Kubernetes) kubectl(prefix string, args []string) error {
+
+	log := k.stim.GetLogger()
+
+	tmpDir, err := ioutil.TempDir("", "stim-kubectl")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	vc, err := k.vaultKubeConfig(prefix, tmpDir)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Running kubectl {} in {}/{} as service account {}", args[0], vc.cluster, vc.namespace, vc.serviceAccount)
+
+	cmd := exec.Command("kubectl", append([]string{"--kubeconfig", vc.path, "--namespace", vc.namespace}, args...)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	// Ctrl-C stops kubectl (ex. a port-forward or following logs) and stim
+	// waits for it so the temporary kubeconfig is removed
+	_, stop := k.stim.TrapInterrupts()
+	defer stop()
+
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return stim.NewExitError(exitErr.ExitCode(), fmt.Errorf("kubectl %s exited with code %d", args[0], exitErr.ExitCode()))
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to run kubectl: %v", err)
+	}
+
+	return nil
+}
+
+// vaultContext is a temporary kubeconfig for a cluster and service account
+// from Vault
+type vaultContext struct {
+	config         *kubernetes.Config
+	path           string
+	cluster        string
+	serviceAccount string
+	namespace      string
+}
+
+// vaultKubeConfig writes a kubeconfig in tmpDir for the cluster and service
+// account of the `<prefix>-cluster` and `<prefix>-service-account` flags,
+// prompting for them if not set.  The namespace is the `<prefix>-namespace`
+// flag or the default namespace of the kube-config secret.
+func (k *Kubernetes) vaultKubeConfig(prefix string, tmpDir string) (*vaultContext, error) {
+
+	cluster, err := k.stim.PromptListVault(k.stim.KubeClusterListPath(), "Select Cluster", k.stim.ConfigGetString(prefix+"-cluster"))
+	if err != nil {
+		return nil, err
+	}
+	sa, err := k.stim.PromptListVault(k.stim.KubeServiceAccountListPath(cluster), "Select Service Account", k.stim.ConfigGetString(prefix+"-service-account"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Locked clusters get their token from `stim kube credential`, which
+	// refuses unless the cluster is unlocked
+	path := filepath.Join(tmpDir, cluster)
+	kc, err := k.stim.KubeConfigFromVault(&stim.KubeConfigOptions{
+		Cluster:        cluster,
+		ServiceAccount: sa,
+		Path:           path,
+		UseLock:        true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := k.stim.ConfigGetString(prefix + "-namespace")
+	if namespace == "" {
+		namespace, err = kc.Namespace()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &vaultContext{config: kc, path: path, cluster: cluster, serviceAccount: sa, namespace: namespace}, nil
+}
+
+// execArgs returns the kubectl arguments to run the command in the pod,
+// interactively if stdin is a terminal
+func execArgs(pod string, container string, tty bool, command []string) []string {
+	args := []string{"exec", "-i"}
+	if tty {
+		args = append(args, "-t")
+	}
+	if container != "" {
+		args = append(args, "--container", container)
+	}
+	if len(command) == 0 {
+		command = []string{"sh"}
+	}
+	return append(append(args, pod, "--"), command...)
+}
+
+// logsArgs returns the kubectl arguments to print the logs of the resource.
+// A negative tail prints all lines.
+func logsArgs(resource string, container string, follow bool, previous bool, tail int, since string) []string {
+	args := []string{"logs", resource}
+	if container != "" {
+		args = append(args, "--container", container)
+	}
+	if follow {
+		args = append(args, "--follow")
+	}
+	if previous {
+		args = append(args, "--previous")
+	}
+	if tail >= 0 {
+		args = append(args, "--tail", strconv.Itoa(tail))
+	}
+	if since != "" {
+		args = append(args, "--since", since)
+	}
+	return args
+}
+
+// isTerminal returns true if the file is a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,17 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestExecArgs(t *testing.T) {
+	assert.DeepEqual(t, execArgs("web-1", "", false, nil), []string{"exec", "-i", "web-1", "--", "sh"})
+	assert.DeepEqual(t, execArgs("web-1", "app", true, []string{"ls", "-la"}), []string{"exec", "-i", "-t", "--container", "app", "web-1", "--", "ls", "-la"})
+}
+
+func TestLogsArgs(t *testing.T) {
+	assert.DeepEqual(t, logsArgs("deploy/web", "", false, false, -1, ""), []string{"logs", "deploy/web"})
+	assert.DeepEqual(t, logsArgs("web-1", "app", true, true, 0, "1h"), []string{"logs", "web-1", "--container", "app", "--follow", "--previous", "--tail", "0", "--since", "1h"})
+}
@@ -0,0 +1,267 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/kubernetes"
+	"github.com/PremiereGlobal/stim/pkg/notify"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// hpaStatus prints the HorizontalPodAutoscalers matching the selector with
+// their replicas, CPU utilization and overrides
+func (k *Kubernetes) hpaStatus() error {
+
+	tmpDir, err := ioutil.TempDir("", "stim-kube-hpa")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	vc, err := k.vaultKubeConfig("kube-hpa", tmpDir)
+	if err != nil {
+		return err
+	}
+	kube, err := kubernetes.New(vc.config)
+	if err != nil {
+		return err
+	}
+
+	namespace := vc.namespace
+	if k.stim.ConfigGetBool("kube-hpa-all-namespaces") {
+		namespace = ""
+	}
+	hpas, err := kube.ListHPAs(namespace, k.stim.ConfigGetString("kube-hpa-selector"))
+	if err != nil {
+		return err
+	}
+
+	now := k.stim.Clock().Now()
+	return k.stim.PrintOutput(k.stim.ConfigGetString("kube-hpa-output"), hpas, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "NAMESPACE\tNAME\tTARGET\tMIN\tMAX\tCURRENT\tDESIRED\tCPU\tOVERRIDE")
+		for _, hpa := range hpas {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%d\t%d\t%s\t%s\n", hpa.Namespace, hpa.Name, hpa.Target, hpa.MinReplicas, hpa.MaxReplicas, hpa.CurrentReplicas, hpa.DesiredReplicas, cpuUtilization(hpa), k.overrideStatus(hpa.Override, now))
+		}
+	})
+}
+
+// hpaOverride temporarily sets the replica limits of an HPA.  Unless
+// --no-wait is set, stim waits for the override to expire (or Ctrl-C) and
+// reverts it.  Overrides are sent to the `kube.hpa.override` notification
+// event.
+func (k *Kubernetes) hpaOverride(name string) error {
+
+	log := k.stim.GetLogger()
+
+	min := int32(k.stim.ConfigGetInt("kube-hpa-override-min"))
+	max := int32(k.stim.ConfigGetInt("kube-hpa-override-max"))
+	if min == 0 && max == 0 {
+		return stim.UsageError(errors.New("At least one of --min or --max must be set"))
+	}
+
+	duration, err := time.ParseDuration(k.stim.ConfigGetString("kube-hpa-override-duration"))
+	if err != nil || duration <= 0 {
+		return stim.UsageError(fmt.Errorf("Invalid duration '%s' (ex. 1h)", k.stim.ConfigGetString("kube-hpa-override-duration")))
+	}
+	if maxDuration := k.stim.ConfigGetString("kube.hpa.max-duration"); maxDuration != "" {
+		limit, err := time.ParseDuration(maxDuration)
+		if err != nil {
+			return stim.ConfigError(fmt.Errorf("Invalid kube.hpa.max-duration '%s': %v", maxDuration, err))
+		}
+		if duration > limit {
+			return stim.UsageError(fmt.Errorf("HPAs can be overridden for at most %s", limit))
+		}
+	}
+
+	tmpDir, err := ioutil.TempDir("", "stim-kube-hpa")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	vc, err := k.vaultKubeConfig("kube-hpa", tmpDir)
+	if err != nil {
+		return err
+	}
+	kube, err := kubernetes.New(vc.config)
+	if err != nil {
+		return err
+	}
+
+	user, err := k.stim.User()
+	if err != nil {
+		user = "unknown"
+	}
+	reason := k.stim.ConfigGetString("kube-hpa-override-reason")
+	until := k.stim.Clock().Now().Add(duration)
+
+	hpa, err := kube.OverrideHPA(vc.namespace, name, min, max, kubernetes.HPAOverride{
+		User:   user,
+		Reason: reason,
+		Until:  until,
+	})
+	k.stim.AuditAction("stim kube hpa override", hpaAuditArgs(vc, name, min, max, duration), err)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Overrode HPA {}/{} in {} to min {} max {} until {}", vc.namespace, name, vc.cluster, hpa.MinReplicas, hpa.MaxReplicas, k.stim.FormatTime(until))
+	err = k.stim.Notify("kube.hpa.override", &notify.Payload{
+		Title:  fmt.Sprintf("%s overrode HPA %s/%s in %s to %d-%d replicas for %s", user, vc.namespace, name, vc.cluster, hpa.MinReplicas, hpa.MaxReplicas, duration),
+		Status: notify.StatusInfo,
+		Fields: map[string]string{
+			"User":      user,
+			"Cluster":   vc.cluster,
+			"Namespace": vc.namespace,
+			"HPA":       name,
+			"Replicas":  fmt.Sprintf("%d-%d (was %d-%d)", hpa.MinReplicas, hpa.MaxReplicas, hpa.Override.MinReplicas, hpa.Override.MaxReplicas),
+			"Duration":  duration.String(),
+			"Reason":    reason,
+		},
+	})
+	if err != nil {
+		log.Warn("Unable to send HPA override notification: {}", err)
+	}
+
+	if k.stim.ConfigGetBool("kube-hpa-override-no-wait") {
+		log.Info("Run `stim kube hpa revert {}` (or `stim kube hpa revert --expired`) to revert the override", name)
+		return nil
+	}
+
+	// Wait for the override to expire.  Ctrl-C reverts it early.
+	log.Info("Waiting to revert the override at {}.  Press Ctrl-C to revert it now", k.stim.FormatTime(until))
+	interrupts, stop := k.stim.TrapInterrupts()
+	defer stop()
+	select {
+	case <-k.stim.Clock().After(duration):
+	case <-interrupts:
+		log.Info("Interrupted, reverting the override now")
+	}
+
+	return k.revertHPA(kube, vc, name, "stim kube hpa override (auto-revert)")
+}
+
+// hpaRevert reverts the overrides of the given HPAs, or of every HPA whose
+// override has expired with --expired
+func (k *Kubernetes) hpaRevert(names []string) error {
+
+	expired := k.stim.ConfigGetBool("kube-hpa-revert-expired")
+	if len(names) == 0 && !expired {
+		return stim.UsageError(errors.New("Give the HPA(s) to revert or use --expired"))
+	}
+
+	tmpDir, err := ioutil.TempDir("", "stim-kube-hpa")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	vc, err := k.vaultKubeConfig("kube-hpa", tmpDir)
+	if err != nil {
+		return err
+	}
+	kube, err := kubernetes.New(vc.config)
+	if err != nil {
+		return err
+	}
+
+	if expired {
+		hpas, err := kube.ListHPAs(vc.namespace, k.stim.ConfigGetString("kube-hpa-selector"))
+		if err != nil {
+			return err
+		}
+		now := k.stim.Clock().Now()
+		for _, hpa := range hpas {
+			if hpa.Override != nil && !now.Before(hpa.Override.Until) {
+				names = append(names, hpa.Name)
+			}
+		}
+		if len(names) == 0 {
+			k.stim.GetLogger().Info("No expired HPA overrides in {}/{}", vc.cluster, vc.namespace)
+		}
+	}
+
+	for _, name := range names {
+		err = k.revertHPA(kube, vc, name, "stim kube hpa revert")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// revertHPA reverts the override of an HPA, recording it in the audit log and
+// sending it to the `kube.hpa.revert` notification event
+func (k *Kubernetes) revertHPA(kube *kubernetes.Kubernetes, vc *vaultContext, name string, action string) error {
+
+	log := k.stim.GetLogger()
+
+	override, err := kube.RevertHPA(vc.namespace, name)
+	k.stim.AuditAction(action, []string{"--cluster", vc.cluster, "--namespace", vc.namespace, name}, err)
+	if err != nil {
+		return fmt.Errorf("Unable to revert the override of HPA %s/%s in %s: %v", vc.namespace, name, vc.cluster, err)
+	}
+	if override == nil {
+		log.Info("HPA {}/{} in {} is not overridden", vc.namespace, name, vc.cluster)
+		return nil
+	}
+
+	log.Info("Reverted HPA {}/{} in {} to min {} max {}", vc.namespace, name, vc.cluster, override.MinReplicas, override.MaxReplicas)
+	user, err := k.stim.User()
+	if err != nil {
+		user = "unknown"
+	}
+	err = k.stim.Notify("kube.hpa.revert", &notify.Payload{
+		Title:  fmt.Sprintf("%s reverted the override of HPA %s/%s in %s to %d-%d replicas", user, vc.namespace, name, vc.cluster, override.MinReplicas, override.MaxReplicas),
+		Status: notify.StatusInfo,
+		Fields: map[string]string{
+			"User":          user,
+			"Cluster":       vc.cluster,
+			"Namespace":     vc.namespace,
+			"HPA":           name,
+			"Replicas":      fmt.Sprintf("%d-%d", override.MinReplicas, override.MaxReplicas),
+			"Overridden By": override.User,
+		},
+	})
+	if err != nil {
+		log.Warn("Unable to send HPA revert notification: {}", err)
+	}
+
+	return nil
+}
+
+// overrideStatus describes the override of an HPA for the status table
+func (k *Kubernetes) overrideStatus(override *kubernetes.HPAOverride, now time.Time) string {
+	if override == nil {
+		return "-"
+	}
+	if !now.Before(override.Until) {
+		return fmt.Sprintf("expired %s (by %s)", k.stim.FormatRelative(override.Until), override.User)
+	}
+	return fmt.Sprintf("until %s (by %s, was %d-%d)", k.stim.FormatTime(override.Until), override.User, override.MinReplicas, override.MaxReplicas)
+}
+
+// cpuUtilization returns the current and target CPU utilization of an HPA
+// (ex. `45%/70%`)
+func cpuUtilization(hpa *kubernetes.HPA) string {
+	current, target := "<unknown>", "-"
+	if hpa.CurrentCPU != nil {
+		current = strconv.Itoa(int(*hpa.CurrentCPU)) + "%"
+	}
+	if hpa.TargetCPU != nil {
+		target = strconv.Itoa(int(*hpa.TargetCPU)) + "%"
+	}
+	return current + "/" + target
+}
+
+// hpaAuditArgs returns the arguments of an HPA override for the audit log
+func hpaAuditArgs(vc *vaultContext, name string, min int32, max int32, duration time.Duration) []string {
+	return []string{"--cluster", vc.cluster, "--namespace", vc.namespace, name, "--min", strconv.Itoa(int(min)), "--max", strconv.Itoa(int(max)), "--duration", duration.String()}
+}
@@ -0,0 +1,15 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/PremiereGlobal/stim/pkg/kubernetes"
+	"gotest.tools/assert"
+)
+
+func TestCPUUtilization(t *testing.T) {
+	current, target := int32(45), int32(70)
+	assert.Equal(t, cpuUtilization(&kubernetes.HPA{CurrentCPU: &current, TargetCPU: &target}), "45%/70%")
+	assert.Equal(t, cpuUtilization(&kubernetes.HPA{TargetCPU: &target}), "<unknown>/70%")
+	assert.Equal(t, cpuUtilization(&kubernetes.HPA{}), "<unknown>/-")
+}
@@ -0,0 +1,187 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/kubernetes"
+	"github.com/PremiereGlobal/stim/pkg/notify"
+	"github.com/PremiereGlobal/stim/stim"
+	"gopkg.in/yaml.v2"
+)
+
+// unlockStateFile is the file in the kube cache directory with the time each
+// locked cluster is unlocked until
+const unlockStateFile = "unlocked-clusters.yaml"
+
+// unlockCluster allows the locked contexts of the cluster to get tokens from
+// `stim kube credential` for the given duration
+func (k *Kubernetes) unlockCluster(cluster string) error {
+
+	log := k.stim.GetLogger()
+
+	if !k.stim.IsKubeClusterLockable(cluster) {
+		return stim.UsageError(fmt.Errorf("Cluster '%s' is not in kube.locked-clusters, its contexts are never locked", cluster))
+	}
+
+	duration, err := time.ParseDuration(k.stim.ConfigGetString("kube-unlock-duration"))
+	if err != nil || duration <= 0 {
+		return stim.UsageError(fmt.Errorf("Invalid duration '%s' (ex. 30m)", k.stim.ConfigGetString("kube-unlock-duration")))
+	}
+	if max := k.stim.ConfigGetString("kube.max-unlock-duration"); max != "" {
+		maxDuration, err := time.ParseDuration(max)
+		if err != nil {
+			return stim.ConfigError(fmt.Errorf("Invalid kube.max-unlock-duration '%s': %v", max, err))
+		}
+		if duration > maxDuration {
+			return stim.UsageError(fmt.Errorf("Clusters can be unlocked for at most %s", maxDuration))
+		}
+	}
+
+	statePath := filepath.Join(k.stim.ConfigGetCacheDir("kube"), unlockStateFile)
+	state, err := readUnlockState(statePath)
+	if err != nil {
+		return err
+	}
+	now := k.stim.Clock().Now()
+	for c := range state {
+		if _, unlocked := unlockedUntil(state, c, now); !unlocked {
+			delete(state, c)
+		}
+	}
+	until := now.Add(duration)
+	state[cluster] = until
+	err = writeUnlockState(statePath, state)
+	if err != nil {
+		return err
+	}
+
+	user, err := k.stim.User()
+	if err != nil {
+		user = "unknown"
+	}
+	reason := k.stim.ConfigGetString("kube-unlock-reason")
+	log.Info("Unlocked cluster {} until {}", cluster, k.stim.FormatTime(until))
+	err = k.stim.Notify("kube.unlock", &notify.Payload{
+		Title:  fmt.Sprintf("%s unlocked Kubernetes cluster %s for %s", user, cluster, duration),
+		Status: notify.StatusInfo,
+		Fields: map[string]string{
+			"User":     user,
+			"Cluster":  cluster,
+			"Duration": duration.String(),
+			"Reason":   reason,
+		},
+	})
+	if err != nil {
+		log.Warn("Unable to send unlock notification: {}", err)
+	}
+
+	return nil
+}
+
+// lockClusters locks the given clusters again before their unlock expires, or
+// all clusters if none are given
+func (k *Kubernetes) lockClusters(clusters []string) error {
+
+	statePath := filepath.Join(k.stim.ConfigGetCacheDir("kube"), unlockStateFile)
+	state, err := readUnlockState(statePath)
+	if err != nil {
+		return err
+	}
+
+	if len(clusters) == 0 {
+		for cluster := range state {
+			clusters = append(clusters, cluster)
+		}
+		sort.Strings(clusters)
+	}
+	for _, cluster := range clusters {
+		delete(state, cluster)
+		k.stim.GetLogger().Info("Locked cluster {}", cluster)
+	}
+
+	return writeUnlockState(statePath, state)
+}
+
+// credential prints the exec credential with the token of the cluster and
+// service account for kubectl.  Locked clusters only get a token while they
+// are unlocked and the credential expires with the unlock.
+func (k *Kubernetes) credential() error {
+
+	cluster := k.stim.ConfigGetString("kube-credential-cluster")
+	sa := k.stim.ConfigGetString("kube-credential-service-account")
+	if cluster == "" || sa == "" {
+		return stim.UsageError(errors.New("--cluster and --service-account are required"))
+	}
+
+	var expiration time.Time
+	if k.stim.IsKubeClusterLockable(cluster) {
+		state, err := readUnlockState(filepath.Join(k.stim.ConfigGetCacheDir("kube"), unlockStateFile))
+		if err != nil {
+			return err
+		}
+		var unlocked bool
+		expiration, unlocked = unlockedUntil(state, cluster, k.stim.Clock().Now())
+		if !unlocked {
+			return stim.AuthError(fmt.Errorf("Cluster '%s' is locked.  Run `stim kube unlock %s --duration 30m` to use it", cluster, cluster))
+		}
+	}
+
+	vault, err := k.stim.NewVault()
+	if err != nil {
+		return err
+	}
+	token, err := vault.GetSecretKey(k.stim.KubeConfigSecretPath(cluster, sa), "user-token")
+	if err != nil {
+		return err
+	}
+
+	b, err := kubernetes.ExecCredential(token, expiration)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
+
+// unlockedUntil returns the time the cluster is unlocked until and whether it
+// is unlocked now
+func unlockedUntil(state map[string]time.Time, cluster string, now time.Time) (time.Time, bool) {
+	until, ok := state[cluster]
+	if !ok || !now.Before(until) {
+		return time.Time{}, false
+	}
+	return until, true
+}
+
+// readUnlockState returns the time each cluster is unlocked until
+func readUnlockState(path string) (map[string]time.Time, error) {
+
+	state := make(map[string]time.Time)
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(b, &state)
+	return state, err
+}
+
+// writeUnlockState saves the time each cluster is unlocked until
+func writeUnlockState(path string, state map[string]time.Time) error {
+
+	b, err := yaml.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, b, 0600)
+}
@@ -0,0 +1,39 @@
+package kubernetes
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestUnlockState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-lock")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, unlockStateFile)
+	state, err := readUnlockState(path)
+	assert.NilError(t, err)
+	assert.Equal(t, len(state), 0)
+
+	now := time.Date(2024, 5, 1, 16, 0, 0, 0, time.UTC)
+	state["prod-usw2"] = now.Add(30 * time.Minute)
+	assert.NilError(t, writeUnlockState(path, state))
+
+	state, err = readUnlockState(path)
+	assert.NilError(t, err)
+
+	until, unlocked := unlockedUntil(state, "prod-usw2", now)
+	assert.Assert(t, unlocked)
+	assert.Assert(t, until.Equal(now.Add(30*time.Minute)))
+
+	_, unlocked = unlockedUntil(state, "prod-usw2", now.Add(30*time.Minute))
+	assert.Assert(t, !unlocked)
+
+	_, unlocked = unlockedUntil(state, "prod-use1", now)
+	assert.Assert(t, !unlocked)
+}
@@ -0,0 +1,217 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/PremiereGlobal/stim/pkg/kubernetes"
+	"github.com/PremiereGlobal/stim/pkg/notify"
+	stimvault "github.com/PremiereGlobal/stim/pkg/vault"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// rotateServiceAccount creates a new token for the service account of a
+// cluster, writes it to the kube-config secret in Vault and, once the new
+// token works from Vault, deletes the old token secret to revoke it
+func (k *Kubernetes) rotateServiceAccount() error {
+
+	log := k.stim.GetLogger()
+
+	cluster, err := k.stim.PromptListVault(k.stim.KubeClusterListPath(), "Select Cluster", k.stim.ConfigGetString("kube-rotate-cluster"))
+	if err != nil {
+		return err
+	}
+	sa, err := k.stim.PromptListVault(k.stim.KubeServiceAccountListPath(cluster), "Select Service Account", k.stim.ConfigGetString("kube-rotate-service-account"))
+	if err != nil {
+		return err
+	}
+
+	vault, err := k.stim.NewVault()
+	if err != nil {
+		return err
+	}
+	path := k.stim.KubeConfigSecretPath(cluster, sa)
+	existing, err := vault.GetSecretKeys(path)
+	if err != nil {
+		return err
+	}
+
+	// The Kubernetes service account defaults to the one named like the Vault
+	// service account in the default namespace of the kube-config secret
+	name := k.stim.ConfigGetString("kube-rotate-name")
+	if name == "" {
+		name = sa
+	}
+	namespace := k.stim.ConfigGetString("kube-rotate-namespace")
+	if namespace == "" {
+		namespace = existing["default-namespace"]
+	}
+	if namespace == "" {
+		return stim.UsageError(errors.New("The kube-config secret has no default-namespace, use --namespace to set the namespace of the service account"))
+	}
+
+	// The token secret is created with the credentials of the admin service
+	// account, which defaults to the rotated one
+	admin := k.stim.ConfigGetString("kube-rotate-admin-service-account")
+	if admin == "" {
+		admin = sa
+	}
+
+	tmpDir, err := ioutil.TempDir("", "stim-kube-rotate")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	kc, err := k.stim.KubeConfigFromVault(&stim.KubeConfigOptions{
+		Cluster:        cluster,
+		ServiceAccount: admin,
+		Path:           filepath.Join(tmpDir, "admin"),
+	})
+	if err != nil {
+		return err
+	}
+	kube, err := kubernetes.New(kc)
+	if err != nil {
+		return err
+	}
+
+	oldSecrets, err := kube.FindServiceAccountTokenSecrets(namespace, name, existing["user-token"])
+	if err != nil {
+		return err
+	}
+
+	log.Info("Creating a new token for service account {}/{} in {}", namespace, name, cluster)
+	token, err := kube.CreateServiceAccountToken(namespace, name)
+	if err != nil {
+		return err
+	}
+
+	// Remove the new token if it can't replace the old one, unless the
+	// kube-config secret couldn't be restored and still holds it
+	inVault, err := k.storeServiceAccountToken(vault, cluster, sa, path, existing, token, tmpDir)
+	if err != nil {
+		if inVault {
+			log.Warn("Keeping the new token secret {}/{}, which the kube-config secret {} still holds", namespace, token.SecretName, path)
+			return err
+		}
+		deleteErr := kube.DeleteSecret(namespace, token.SecretName)
+		if deleteErr != nil {
+			log.Warn("Unable to delete the new token secret {}/{}: {}", namespace, token.SecretName, deleteErr)
+		}
+		return err
+	}
+	log.Info("Updated the kube-config secret {} with the token of secret {}/{}", path, namespace, token.SecretName)
+
+	if len(oldSecrets) == 0 {
+		log.Warn("No token secret of service account {}/{} holds the old token, it was not revoked", namespace, name)
+	} else if k.stim.ConfigGetBool("kube-rotate-keep-old") {
+		log.Info("Keeping the old token secret(s) {}", oldSecrets)
+	} else {
+		for _, secret := range oldSecrets {
+			err = kube.DeleteSecret(namespace, secret)
+			if err != nil {
+				return fmt.Errorf("The new token is in Vault but the old token secret %s/%s could not be deleted: %v", namespace, secret, err)
+			}
+			log.Info("Deleted the old token secret {}/{}", namespace, secret)
+		}
+	}
+
+	user, err := k.stim.User()
+	if err != nil {
+		user = "unknown"
+	}
+	err = k.stim.Notify("kube.sa.rotate", &notify.Payload{
+		Title:  fmt.Sprintf("%s rotated the token of service account %s/%s in %s", user, namespace, name, cluster),
+		Status: notify.StatusInfo,
+		Fields: map[string]string{
+			"User":            user,
+			"Cluster":         cluster,
+			"Service Account": fmt.Sprintf("%s/%s", namespace, name),
+			"Vault Path":      path,
+		},
+	})
+	if err != nil {
+		log.Warn("Unable to send service account rotation notification: {}", err)
+	}
+
+	return nil
+}
+
+// storeServiceAccountToken checks that the new token works, writes it to the
+// kube-config secret in Vault and checks that the secret in Vault works.  The
+// previous secret is restored if the check of the secret in Vault fails.
+// inVault is true if the kube-config secret holds the new token, even when an
+// error is returned because it couldn't be restored.
+func (k *Kubernetes) storeServiceAccountToken(vault *stimvault.Vault, cluster string, sa string, path string, existing map[string]string, token *kubernetes.ServiceAccountToken, tmpDir string) (inVault bool, err error) {
+
+	err = checkKubeConfig(kubernetes.NewConfigFromPath(filepath.Join(tmpDir, "new")), &kubernetes.ConfigOptions{
+		ClusterName:       cluster,
+		ClusterServer:     existing["cluster-server"],
+		ClusterCA:         rotatedKubeConfigKeys(existing, token)["cluster-ca"],
+		AuthName:          cluster + "-" + sa,
+		AuthToken:         token.Token,
+		ContextName:       cluster,
+		ContextSetCurrent: true,
+	})
+	if err != nil {
+		return false, fmt.Errorf("The new token was not accepted by %s: %v", cluster, err)
+	}
+
+	err = vault.WriteSecretKeys(path, rotatedKubeConfigKeys(existing, token))
+	if err != nil {
+		return false, err
+	}
+
+	kc, err := k.stim.KubeConfigFromVault(&stim.KubeConfigOptions{
+		Cluster:        cluster,
+		ServiceAccount: sa,
+		Path:           filepath.Join(tmpDir, "vault"),
+	})
+	if err == nil {
+		err = checkKubeConfig(kc, nil)
+	}
+	if err != nil {
+		restoreErr := vault.WriteSecretKeys(path, existing)
+		if restoreErr != nil {
+			k.stim.GetLogger().Warn("Unable to restore the kube-config secret {}: {}", path, restoreErr)
+		}
+		return restoreErr != nil, fmt.Errorf("The kube-config secret in Vault does not work with the new token: %v", err)
+	}
+
+	return true, nil
+}
+
+// checkKubeConfig checks that the credentials of the kubeconfig, after
+// applying the options if any, are accepted by the cluster
+func checkKubeConfig(kc *kubernetes.Config, options *kubernetes.ConfigOptions) error {
+	if options != nil {
+		err := kc.Modify(options)
+		if err != nil {
+			return err
+		}
+	}
+	kube, err := kubernetes.New(kc)
+	if err != nil {
+		return err
+	}
+	return kube.CheckAuthenticated()
+}
+
+// rotatedKubeConfigKeys returns the keys of the kube-config secret with the
+// new token.  The CA of the token secret is used if it has one and the other
+// keys (ex. default-namespace) are kept.
+func rotatedKubeConfigKeys(existing map[string]string, token *kubernetes.ServiceAccountToken) map[string]string {
+	keys := make(map[string]string, len(existing)+1)
+	for key, value := range existing {
+		keys[key] = value
+	}
+	keys["user-token"] = token.Token
+	if token.CA != "" {
+		keys["cluster-ca"] = token.CA
+	}
+	return keys
+}
@@ -0,0 +1,30 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/PremiereGlobal/stim/pkg/kubernetes"
+	"gotest.tools/assert"
+)
+
+func TestRotatedKubeConfigKeys(t *testing.T) {
+	existing := map[string]string{
+		"cluster-server":    "https://prod-usw2",
+		"cluster-ca":        "old-ca",
+		"user-token":        "old-token",
+		"default-namespace": "apps",
+	}
+
+	keys := rotatedKubeConfigKeys(existing, &kubernetes.ServiceAccountToken{Token: "new-token", CA: "new-ca"})
+	assert.DeepEqual(t, keys, map[string]string{
+		"cluster-server":    "https://prod-usw2",
+		"cluster-ca":        "new-ca",
+		"user-token":        "new-token",
+		"default-namespace": "apps",
+	})
+	assert.Equal(t, existing["user-token"], "old-token")
+
+	// The CA is kept if the token secret has none
+	keys = rotatedKubeConfigKeys(existing, &kubernetes.ServiceAccountToken{Token: "new-token"})
+	assert.Equal(t, keys["cluster-ca"], "old-ca")
+}
@@ -0,0 +1,154 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/PremiereGlobal/stim/pkg/kubernetes"
+	"github.com/PremiereGlobal/stim/pkg/notify"
+	"github.com/PremiereGlobal/stim/pkg/utils"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// getSecret prints the keys of a Kubernetes Secret with their values masked,
+// except for the keys selected with --show.  Every access is logged and sent
+// to the `kube.secret.get` notification event so there is an audit trail of
+// who read which values.
+func (k *Kubernetes) getSecret(name string) error {
+
+	log := k.stim.GetLogger()
+	show := k.stim.ConfigGetStringSlice("kube-secret-show")
+
+	kc, cluster, cleanup, err := k.secretKubeConfig()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	namespace := k.stim.ConfigGetString("kube-secret-namespace")
+	if namespace == "" {
+		namespace, err = kc.Namespace()
+		if err != nil {
+			return err
+		}
+	}
+
+	kube, err := kubernetes.New(kc)
+	if err != nil {
+		return err
+	}
+
+	data, secretType, err := kube.GetSecretData(namespace, name)
+	if err != nil {
+		return err
+	}
+
+	var keys []string
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range show {
+		if _, ok := data[key]; !ok {
+			return fmt.Errorf("Key '%s' not found in secret %s/%s, must be one of [%s]", key, namespace, name, strings.Join(keys, ", "))
+		}
+	}
+
+	user, err := k.stim.User()
+	if err != nil {
+		user = "unknown"
+	}
+	log.Info("User {} read secret {}/{} in {} (revealed keys: [{}])", user, namespace, name, cluster, strings.Join(show, ", "))
+	err = k.stim.Notify("kube.secret.get", &notify.Payload{
+		Title:  fmt.Sprintf("%s read Kubernetes secret %s/%s in %s", user, namespace, name, cluster),
+		Status: notify.StatusInfo,
+		Fields: map[string]string{
+			"User":          user,
+			"Cluster":       cluster,
+			"Namespace":     namespace,
+			"Secret":        name,
+			"Revealed Keys": strings.Join(show, ", "),
+		},
+	})
+	if err != nil {
+		log.Warn("Unable to send secret access notification: {}", err)
+	}
+
+	fmt.Printf("Secret: %s/%s  Type: %s\n\n", namespace, name, secretType)
+
+	// Multi-line values are printed after the table
+	var multiLine []string
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE")
+	for _, key := range keys {
+		value := string(data[key])
+		switch {
+		case !utils.Contains(show, key):
+			value = fmt.Sprintf("<hidden, %d bytes>", len(data[key]))
+		case strings.Contains(value, "\n"):
+			multiLine = append(multiLine, key)
+			value = "<shown below>"
+		}
+		fmt.Fprintf(w, "%s\t%s\n", key, value)
+	}
+	w.Flush()
+
+	for _, key := range multiLine {
+		fmt.Printf("\n--- %s ---\n%s\n", key, strings.TrimSuffix(string(data[key]), "\n"))
+	}
+
+	return nil
+}
+
+// secretKubeConfig returns the kubeconfig to read the secret with and the
+// name of the cluster.  With --cluster, a temporary kubeconfig is created
+// from Vault, otherwise the current context of the local kubeconfig is used.
+func (k *Kubernetes) secretKubeConfig() (*kubernetes.Config, string, func(), error) {
+
+	cluster := k.stim.ConfigGetString("kube-secret-cluster")
+	if cluster == "" {
+		kc := kubernetes.NewConfig()
+		context, err := kc.CurrentContext()
+		if err != nil {
+			return nil, "", nil, err
+		}
+		if context == "" {
+			return nil, "", nil, errors.New("No current Kubernetes context, use --cluster to read the secret with credentials from Vault")
+		}
+		return kc, context, func() {}, nil
+	}
+
+	sa := k.stim.ConfigGetString("kube-secret-service-account")
+	if sa == "" {
+		var err error
+		sa, err = k.stim.PromptListVault(k.stim.KubeServiceAccountListPath(cluster), "Select Service Account", "")
+		if err != nil {
+			return nil, "", nil, err
+		}
+	}
+
+	tmpDir, err := ioutil.TempDir("", "stim-kube-secret")
+	if err != nil {
+		return nil, "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(tmpDir) }
+
+	kc, err := k.stim.KubeConfigFromVault(&stim.KubeConfigOptions{
+		Cluster:        cluster,
+		ServiceAccount: sa,
+		Path:           filepath.Join(tmpDir, cluster),
+	})
+	if err != nil {
+		cleanup()
+		return nil, "", nil, err
+	}
+
+	return kc, cluster, cleanup, nil
+}
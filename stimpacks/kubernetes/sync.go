@@ -0,0 +1,178 @@
+package kubernetes
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/PremiereGlobal/stim/pkg/kubernetes"
+	"github.com/PremiereGlobal/stim/stim"
+	"gopkg.in/yaml.v2"
+)
+
+// syncStateFile is the file in the kube cache directory that tracks which
+// contexts were created by sync, so that only those are ever pruned
+const syncStateFile = "sync-contexts.yaml"
+
+// syncContexts creates/updates a kubeconfig context for each cluster and
+// removes previously synced contexts for clusters that no longer exist
+func (k *Kubernetes) syncContexts() error {
+
+	clusters, prune, err := k.getSyncClusters()
+	if err != nil {
+		return err
+	}
+
+	sa := k.stim.ConfigGetString("kube-sync-service-account")
+	prefix := k.stim.ConfigGetString("kube-sync-context-prefix")
+
+	var synced []string
+	for _, cluster := range clusters {
+		contextName := prefix + cluster
+
+		// Contexts are tracked even if the sync fails so a temporary error
+		// doesn't cause them to be pruned
+		synced = append(synced, contextName)
+
+		clusterSA := sa
+		if clusterSA == "" {
+			clusterSA, err = k.getSyncServiceAccount(cluster)
+			if err != nil {
+				k.stim.GetLogger().Warn("Skipping cluster {}: {}", cluster, err)
+				continue
+			}
+		}
+
+		_, err = k.stim.KubeConfigFromVault(&stim.KubeConfigOptions{
+			Cluster:            cluster,
+			ServiceAccount:     clusterSA,
+			ContextName:        contextName,
+			KeepCurrentContext: true,
+			UseLock:            true,
+		})
+		if err != nil {
+			k.stim.GetLogger().Warn("Unable to sync cluster {}: {}", cluster, err)
+			continue
+		}
+		k.stim.GetLogger().Info("Synced context {} ({})", contextName, clusterSA)
+	}
+
+	statePath := filepath.Join(k.stim.ConfigGetCacheDir("kube"), syncStateFile)
+	previous, err := readSyncState(statePath)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[string]bool)
+	for _, name := range synced {
+		current[name] = true
+	}
+
+	var stale []string
+	for _, name := range previous {
+		if !current[name] {
+			stale = append(stale, name)
+		}
+	}
+
+	if len(stale) > 0 {
+		if prune {
+			err = kubernetes.NewConfig().RemoveContexts(stale)
+			if err != nil {
+				return err
+			}
+			for _, name := range stale {
+				k.stim.GetLogger().Info("Pruned context {}", name)
+			}
+		} else {
+			// Keep tracking the stale contexts so a later sync can prune them
+			synced = append(synced, stale...)
+		}
+	}
+
+	return writeSyncState(statePath, synced)
+}
+
+// getSyncClusters returns the clusters to sync and whether stale contexts
+// should be pruned.  Clusters given on the command line are synced without
+// pruning since they are usually a subset of all clusters.
+func (k *Kubernetes) getSyncClusters() ([]string, bool, error) {
+
+	prune := k.stim.ConfigGetBool("kube-sync-prune")
+
+	clusters := k.stim.ConfigGetStringSlice("kube-sync-clusters")
+	if len(clusters) > 0 {
+		return clusters, false, nil
+	}
+
+	clusters = k.stim.ConfigGetStringSlice("kube.sync.clusters")
+	if len(clusters) > 0 {
+		return clusters, prune, nil
+	}
+
+	vault, err := k.stim.NewVault()
+	if err != nil {
+		return nil, false, err
+	}
+	clusters, err = vault.ListSecrets(k.stim.KubeClusterListPath())
+	if err != nil {
+		return nil, false, err
+	}
+
+	return clusters, prune, nil
+}
+
+// getSyncServiceAccount returns the service account to use for the cluster.
+// If the cluster only has one it is used, otherwise the user is prompted.
+func (k *Kubernetes) getSyncServiceAccount(cluster string) (string, error) {
+
+	vault, err := k.stim.NewVault()
+	if err != nil {
+		return "", err
+	}
+	accounts, err := vault.ListSecrets(k.stim.KubeServiceAccountListPath(cluster))
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case len(accounts) == 0:
+		return "", errors.New("No service accounts found")
+	case len(accounts) == 1:
+		return accounts[0], nil
+	case k.stim.IsAutomated():
+		return "", errors.New("Multiple service accounts found, use --service-account to choose one")
+	}
+
+	return k.stim.PromptList("Select Service Account for "+cluster, accounts, "")
+}
+
+// readSyncState returns the previously synced context names
+func readSyncState(path string) ([]string, error) {
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var contexts []string
+	err = yaml.Unmarshal(b, &contexts)
+	return contexts, err
+}
+
+// writeSyncState saves the synced context names
+func writeSyncState(path string, contexts []string) error {
+
+	sort.Strings(contexts)
+	b, err := yaml.Marshal(contexts)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, b, 0600)
+}
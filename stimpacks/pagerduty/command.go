@@ -13,11 +13,12 @@ func (p *Pagerduty) BindStim(s *stim.Stim) {
 func (p *Pagerduty) Command(viper *viper.Viper) *cobra.Command {
 
 	var cmd = &cobra.Command{
-		Use:   "pagerduty",
-		Short: "Send events to Pagerduty",
-		Long:  `Sends trigger, acknowledge and resolve events to Pagerduty`,
-		Run: func(cmd *cobra.Command, args []string) {
-			p.SendEvent()
+		Use:         "pagerduty",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Send events to Pagerduty and manage on-call schedules",
+		Long:        `Sends trigger, acknowledge and resolve events to Pagerduty.  Subcommands show who is on call and manage schedule overrides`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return p.SendEvent()
 		},
 	}
 
@@ -51,9 +52,240 @@ func (p *Pagerduty) Command(viper *viper.Viper) *cobra.Command {
 	cmd.Flags().StringP("dedupkey", "", "", "UniquedDe-duplication key for the alert. Should the same between all actions for a single incident")
 	viper.BindPFlag("pagerduty-dedupkey", cmd.Flags().Lookup("dedupkey"))
 
+	cmd.PersistentFlags().String("output", "table", "Output format of the oncall, schedules, override and incidents commands (table or json)")
+	viper.BindPFlag("pagerduty-output", cmd.PersistentFlags().Lookup("output"))
+
+	var oncallCmd = &cobra.Command{
+		Use:   "oncall",
+		Short: "Show who is on call",
+		Long:  "Show who is currently on call for a schedule, or for all schedules",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return p.OnCall()
+		},
+	}
+	p.stim.BindCommand(oncallCmd, cmd)
+
+	oncallCmd.Flags().StringP("schedule", "s", "", "Name or ID of the schedule (Default: all schedules)")
+	viper.BindPFlag("pagerduty-oncall-schedule", oncallCmd.Flags().Lookup("schedule"))
+
+	var schedulesCmd = &cobra.Command{
+		Use:   "schedules",
+		Short: "Pagerduty schedule commands",
+		Long:  "Pagerduty on-call schedule commands",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+	p.stim.BindCommand(schedulesCmd, cmd)
+
+	var schedulesListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List schedules",
+		Long:  "List the on-call schedules",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return p.ListSchedules()
+		},
+	}
+	p.stim.BindCommand(schedulesListCmd, schedulesCmd)
+
+	schedulesListCmd.Flags().StringP("query", "q", "", "Only list schedules whose name matches")
+	viper.BindPFlag("pagerduty-schedules-query", schedulesListCmd.Flags().Lookup("query"))
+
+	var overrideCmd = &cobra.Command{
+		Use:   "override",
+		Short: "Pagerduty schedule override commands",
+		Long:  "Pagerduty on-call schedule override commands",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+	p.stim.BindCommand(overrideCmd, cmd)
+
+	var overrideCreateCmd = &cobra.Command{
+		Use:         "create",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Create a schedule override",
+		Long:        "Temporarily put a user on call for a schedule",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return p.CreateOverride()
+		},
+	}
+	p.stim.BindCommand(overrideCreateCmd, overrideCmd)
+
+	overrideCreateCmd.Flags().StringP("schedule", "s", "", "Required. Name or ID of the schedule")
+	viper.BindPFlag("pagerduty-override-schedule", overrideCreateCmd.Flags().Lookup("schedule"))
+	overrideCreateCmd.Flags().StringP("user", "u", "", "Required. Email of the user to put on call")
+	viper.BindPFlag("pagerduty-override-user", overrideCreateCmd.Flags().Lookup("user"))
+	overrideCreateCmd.Flags().String("start", "", "Start of the override in RFC3339 format (Default: now)")
+	viper.BindPFlag("pagerduty-override-start", overrideCreateCmd.Flags().Lookup("start"))
+	overrideCreateCmd.Flags().String("end", "", "End of the override in RFC3339 format. Overrides --duration")
+	viper.BindPFlag("pagerduty-override-end", overrideCreateCmd.Flags().Lookup("end"))
+	overrideCreateCmd.Flags().StringP("duration", "d", "1h", "Length of the override (ex. 30m, 8h)")
+	viper.BindPFlag("pagerduty-override-duration", overrideCreateCmd.Flags().Lookup("duration"))
+
+	cmd.PersistentFlags().String("from", "", "Your Pagerduty email, used when changing incidents (Default: the pagerduty.email config option)")
+	viper.BindPFlag("pagerduty.email", cmd.PersistentFlags().Lookup("from"))
+
+	var incidentsCmd = &cobra.Command{
+		Use:   "incidents",
+		Short: "List incidents",
+		Long:  "List the open incidents, or incidents matching the filters",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return p.ListIncidents()
+		},
+	}
+	p.stim.BindCommand(incidentsCmd, cmd)
+	bindIncidentFilterFlags(incidentsCmd, viper, "incidents")
+	incidentsCmd.Flags().StringSlice("status", nil, "Only list incidents with these statuses (triggered, acknowledged or resolved) (Default: triggered,acknowledged)")
+	viper.BindPFlag("pagerduty-incidents-status", incidentsCmd.Flags().Lookup("status"))
+
+	var triggerCmd = &cobra.Command{
+		Use:         "trigger",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Trigger an alert",
+		Long:        "Trigger an alert on a service with the Events API v2 and print its dedup key.  Triggers with the dedup key of an open alert are grouped into it, and the alert is resolved with `stim pagerduty resolve --dedup-key`",
+		Example:     "  stim pagerduty trigger --service payments --severity critical --summary \"Nightly backup failed\" --dedup-key nightly-backup --details-file details.json",
+		Args:        cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return p.Trigger()
+		},
+	}
+	p.stim.BindCommand(triggerCmd, cmd)
+
+	triggerCmd.Flags().StringP("service", "s", "", "Name of the Pagerduty service (prompts if not given)")
+	viper.BindPFlag("pagerduty-trigger-service", triggerCmd.Flags().Lookup("service"))
+	triggerCmd.Flags().StringP("summary", "m", "", "Required. Summary of the alert")
+	viper.BindPFlag("pagerduty-trigger-summary", triggerCmd.Flags().Lookup("summary"))
+	triggerCmd.Flags().StringP("severity", "r", "error", "Severity of the alert (critical, error, warning or info)")
+	viper.BindPFlag("pagerduty-trigger-severity", triggerCmd.Flags().Lookup("severity"))
+	triggerCmd.Flags().String("dedup-key", "", "Dedup key of the alert (Default: generated by Pagerduty)")
+	viper.BindPFlag("pagerduty-trigger-dedup-key", triggerCmd.Flags().Lookup("dedup-key"))
+	triggerCmd.Flags().StringP("details-file", "f", "", "JSON file with an object of custom details for the alert")
+	viper.BindPFlag("pagerduty-trigger-details-file", triggerCmd.Flags().Lookup("details-file"))
+	triggerCmd.Flags().StringP("source", "o", "", "System having the problem, such as a hostname (Default: the hostname)")
+	viper.BindPFlag("pagerduty-trigger-source", triggerCmd.Flags().Lookup("source"))
+	triggerCmd.Flags().StringP("component", "c", "", "Part of the system that is broken")
+	viper.BindPFlag("pagerduty-trigger-component", triggerCmd.Flags().Lookup("component"))
+	triggerCmd.Flags().StringP("group", "g", "", "Cluster or grouping of sources")
+	viper.BindPFlag("pagerduty-trigger-group", triggerCmd.Flags().Lookup("group"))
+	triggerCmd.Flags().StringP("class", "l", "", "Class or type of the event")
+	viper.BindPFlag("pagerduty-trigger-class", triggerCmd.Flags().Lookup("class"))
+
+	var changeCmd = &cobra.Command{
+		Use:         "change",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Send a change event",
+		Long:        "Send a change event (ex. a deploy or a config change) to a service with the Events API v2.  Change events show on the service's timeline and never open incidents",
+		Example:     "  stim pagerduty change --service payments --summary \"Rotated the database password\"",
+		Args:        cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return p.Change()
+		},
+	}
+	p.stim.BindCommand(changeCmd, cmd)
+
+	changeCmd.Flags().StringP("service", "s", "", "Name of the Pagerduty service (prompts if not given)")
+	viper.BindPFlag("pagerduty-change-service", changeCmd.Flags().Lookup("service"))
+	changeCmd.Flags().StringP("summary", "m", "", "Required. Summary of the change")
+	viper.BindPFlag("pagerduty-change-summary", changeCmd.Flags().Lookup("summary"))
+	changeCmd.Flags().StringP("details-file", "f", "", "JSON file with an object of custom details for the change")
+	viper.BindPFlag("pagerduty-change-details-file", changeCmd.Flags().Lookup("details-file"))
+	changeCmd.Flags().StringP("source", "o", "", "Source of the change (Default: the hostname)")
+	viper.BindPFlag("pagerduty-change-source", changeCmd.Flags().Lookup("source"))
+
+	var ackCmd = &cobra.Command{
+		Use:         "ack [incident-id...]",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Acknowledge incidents",
+		Long:        "Acknowledge the given incidents, or all triggered incidents matching the filters with --all.  With --dedup-key the alert of a `stim pagerduty trigger` is acknowledged with the Events API instead",
+		Example:     "  stim pagerduty ack --service payments --all\n  stim pagerduty ack --service payments --dedup-key nightly-backup",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return p.Ack(args)
+		},
+	}
+	p.stim.BindCommand(ackCmd, cmd)
+	bindIncidentActionFlags(ackCmd, viper, actionAck)
+	ackCmd.Flags().String("dedup-key", "", "Acknowledge the alert with this dedup key with the Events API.  Requires a single --service")
+	viper.BindPFlag("pagerduty-ack-dedup-key", ackCmd.Flags().Lookup("dedup-key"))
+
+	var resolveCmd = &cobra.Command{
+		Use:         "resolve [incident-id...]",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Resolve incidents",
+		Long:        "Resolve the given incidents, or all open incidents matching the filters with --all.  With --dedup-key the alert of a `stim pagerduty trigger` is resolved with the Events API instead",
+		Example:     "  stim pagerduty resolve --service payments --dedup-key nightly-backup",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return p.Resolve(args)
+		},
+	}
+	p.stim.BindCommand(resolveCmd, cmd)
+	bindIncidentActionFlags(resolveCmd, viper, actionResolve)
+	resolveCmd.Flags().String("dedup-key", "", "Resolve the alert with this dedup key with the Events API.  Requires a single --service")
+	viper.BindPFlag("pagerduty-resolve-dedup-key", resolveCmd.Flags().Lookup("dedup-key"))
+
+	var snoozeCmd = &cobra.Command{
+		Use:         "snooze [incident-id...]",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Snooze incidents",
+		Long:        "Snooze the given acknowledged incidents, or all acknowledged incidents matching the filters with --all.  Snoozed incidents are triggered again after the duration",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return p.Snooze(args)
+		},
+	}
+	p.stim.BindCommand(snoozeCmd, cmd)
+	bindIncidentActionFlags(snoozeCmd, viper, actionSnooze)
+	snoozeCmd.Flags().StringP("duration", "d", "1h", "How long to snooze the incidents for (ex. 30m, 4h)")
+	viper.BindPFlag("pagerduty-snooze-duration", snoozeCmd.Flags().Lookup("duration"))
+
+	var reassignCmd = &cobra.Command{
+		Use:         "reassign [incident-id...]",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Reassign incidents",
+		Long:        "Assign the given incidents, or all open incidents matching the filters with --all, to other users",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return p.Reassign(args)
+		},
+	}
+	p.stim.BindCommand(reassignCmd, cmd)
+	bindIncidentActionFlags(reassignCmd, viper, actionReassign)
+	reassignCmd.Flags().StringSlice("to", nil, "Required. Emails of the users to assign the incidents to")
+	viper.BindPFlag("pagerduty-reassign-to", reassignCmd.Flags().Lookup("to"))
+
+	var mergeCmd = &cobra.Command{
+		Use:         "merge <target-incident-id> [incident-id...]",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Merge incidents",
+		Long:        "Merge the given incidents, or all open incidents matching the filters with --all, into the target incident",
+		Args:        cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return p.Merge(args)
+		},
+	}
+	p.stim.BindCommand(mergeCmd, cmd)
+	bindIncidentActionFlags(mergeCmd, viper, actionMerge)
+
 	return cmd
 }
 
+// bindIncidentFilterFlags adds the flags that select incidents to the command
+func bindIncidentFilterFlags(cmd *cobra.Command, viper *viper.Viper, action string) {
+	cmd.Flags().StringSlice("service", nil, "Only incidents of these services")
+	viper.BindPFlag("pagerduty-"+action+"-service", cmd.Flags().Lookup("service"))
+	cmd.Flags().StringSlice("urgency", nil, "Only incidents with these urgencies (high or low)")
+	viper.BindPFlag("pagerduty-"+action+"-urgency", cmd.Flags().Lookup("urgency"))
+	cmd.Flags().StringSlice("assigned-to", nil, "Only incidents assigned to these users (emails)")
+	viper.BindPFlag("pagerduty-"+action+"-assigned-to", cmd.Flags().Lookup("assigned-to"))
+}
+
+// bindIncidentActionFlags adds the flags of a command changing incidents
+func bindIncidentActionFlags(cmd *cobra.Command, viper *viper.Viper, action string) {
+	bindIncidentFilterFlags(cmd, viper, action)
+	cmd.Flags().Bool("all", false, "Change all incidents matching the filters")
+	viper.BindPFlag("pagerduty-"+action+"-all", cmd.Flags().Lookup("all"))
+	cmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation when changing more than one incident")
+	viper.BindPFlag("pagerduty-"+action+"-yes", cmd.Flags().Lookup("yes"))
+}
+
 // type Event struct {
 // 	Action    string `mapstructure:"notify-pagerduty-action"`
 // 	Service   string `mapstructure:"notify-pagerduty-service"`
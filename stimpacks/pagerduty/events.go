@@ -0,0 +1,155 @@
+package pagerduty
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	pd "github.com/PremiereGlobal/stim/pkg/pagerduty"
+	"github.com/PremiereGlobal/stim/stim"
+	"sigs.k8s.io/yaml"
+)
+
+// Trigger sends a trigger event with the Events API v2 and prints the dedup
+// key of the alert, which resolves it with `stim pagerduty resolve
+// --dedup-key`
+func (p *Pagerduty) Trigger() error {
+
+	details, err := readDetailsFile(p.stim.ConfigGetString("pagerduty-trigger-details-file"))
+	if err != nil {
+		return err
+	}
+
+	pagerduty := p.stim.Pagerduty()
+
+	service, err := p.eventService(pagerduty, p.stim.ConfigGetString("pagerduty-trigger-service"))
+	if err != nil {
+		return err
+	}
+
+	summary := p.stim.ConfigGetString("pagerduty-trigger-summary")
+	if summary == "" {
+		return stim.UsageError(errors.New("--summary is required"))
+	}
+
+	dedupKey, err := pagerduty.SendEvent(&pd.Event{
+		Action:        pd.EventTrigger,
+		Service:       service,
+		Summary:       summary,
+		Severity:      p.stim.ConfigGetString("pagerduty-trigger-severity"),
+		Source:        p.stim.ConfigGetString("pagerduty-trigger-source"),
+		Component:     p.stim.ConfigGetString("pagerduty-trigger-component"),
+		Group:         p.stim.ConfigGetString("pagerduty-trigger-group"),
+		Class:         p.stim.ConfigGetString("pagerduty-trigger-class"),
+		DedupKey:      p.stim.ConfigGetString("pagerduty-trigger-dedup-key"),
+		CustomDetails: details,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(dedupKey)
+	return nil
+}
+
+// sendAlertEvent sends an acknowledge or resolve event for the alert of the
+// --dedup-key of the ack or resolve command
+func (p *Pagerduty) sendAlertEvent(action string, eventAction string, args []string) error {
+
+	if len(args) > 0 || p.stim.ConfigGetBool("pagerduty-"+action+"-all") {
+		return stim.UsageError(errors.New("Incident IDs and --all can't be used with --dedup-key"))
+	}
+	services := p.stim.ConfigGetStringSlice("pagerduty-" + action + "-service")
+	if len(services) != 1 {
+		return stim.UsageError(errors.New("Exactly one --service is required with --dedup-key"))
+	}
+
+	dedupKey := p.stim.ConfigGetString("pagerduty-" + action + "-dedup-key")
+	_, err := p.stim.Pagerduty().SendEvent(&pd.Event{
+		Action:   eventAction,
+		Service:  services[0],
+		DedupKey: dedupKey,
+	})
+	if err != nil {
+		return err
+	}
+
+	p.stim.GetLogger().Info("Sent {} event for alert {} to service {}", eventAction, dedupKey, services[0])
+	return nil
+}
+
+// Change sends a change event (ex. a deploy or a config change), which shows
+// on the service's timeline but never opens an incident
+func (p *Pagerduty) Change() error {
+
+	details, err := readDetailsFile(p.stim.ConfigGetString("pagerduty-change-details-file"))
+	if err != nil {
+		return err
+	}
+
+	pagerduty := p.stim.Pagerduty()
+
+	service, err := p.eventService(pagerduty, p.stim.ConfigGetString("pagerduty-change-service"))
+	if err != nil {
+		return err
+	}
+
+	summary := p.stim.ConfigGetString("pagerduty-change-summary")
+	if summary == "" {
+		return stim.UsageError(errors.New("--summary is required"))
+	}
+
+	err = pagerduty.SendChangeEvent(&pd.ChangeEvent{
+		Service: service,
+		Summary: summary,
+		Source:  p.stim.ConfigGetString("pagerduty-change-source"),
+		Details: details,
+	})
+	if err != nil {
+		return err
+	}
+
+	p.stim.GetLogger().Info("Sent change event to service {}", service)
+	return nil
+}
+
+// eventService returns the service of an event, prompting for it if not
+// given
+func (p *Pagerduty) eventService(pagerduty *pd.Pagerduty, service string) (string, error) {
+
+	if service != "" {
+		return service, nil
+	}
+	if p.stim.IsAutomated() {
+		return "", stim.UsageError(errors.New("--service is required"))
+	}
+
+	services, err := pagerduty.GetServices()
+	if err != nil {
+		return "", err
+	}
+
+	return p.stim.PromptSearchList("Choose Service:", services)
+}
+
+// readDetailsFile reads the custom details of an event from a JSON (or YAML)
+// object.  Returns nil if no file is given.
+func readDetailsFile(path string) (map[string]interface{}, error) {
+
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var details map[string]interface{}
+	err = yaml.Unmarshal(data, &details)
+	if err != nil {
+		return nil, stim.UsageError(fmt.Errorf("Unable to parse the details file %s, it must contain a JSON object: %v", path, err))
+	}
+
+	return details, nil
+}
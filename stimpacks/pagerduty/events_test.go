@@ -0,0 +1,30 @@
+package pagerduty
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestReadDetailsFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stim-pagerduty")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	details, err := readDetailsFile("")
+	assert.NilError(t, err)
+	assert.Assert(t, details == nil)
+
+	path := filepath.Join(dir, "details.json")
+	assert.NilError(t, ioutil.WriteFile(path, []byte(`{"job": "nightly-backup", "exit-code": 1, "hosts": ["db1", "db2"]}`), 0644))
+	details, err = readDetailsFile(path)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, details, map[string]interface{}{"job": "nightly-backup", "exit-code": float64(1), "hosts": []interface{}{"db1", "db2"}})
+
+	assert.NilError(t, ioutil.WriteFile(path, []byte(`["not", "an", "object"]`), 0644))
+	_, err = readDetailsFile(path)
+	assert.ErrorContains(t, err, "it must contain a JSON object")
+}
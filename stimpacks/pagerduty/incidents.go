@@ -0,0 +1,272 @@
+package pagerduty
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/pagerduty"
+)
+
+// The incident actions, used in the config keys of their flags
+const (
+	actionAck      = "ack"
+	actionResolve  = "resolve"
+	actionSnooze   = "snooze"
+	actionReassign = "reassign"
+	actionMerge    = "merge"
+)
+
+// actionStatuses are the statuses of the incidents each action can be
+// applied to with --all
+var actionStatuses = map[string][]string{
+	actionAck:      {pagerduty.StatusTriggered},
+	actionResolve:  {pagerduty.StatusTriggered, pagerduty.StatusAcknowledged},
+	actionSnooze:   {pagerduty.StatusAcknowledged},
+	actionReassign: {pagerduty.StatusTriggered, pagerduty.StatusAcknowledged},
+	actionMerge:    {pagerduty.StatusTriggered, pagerduty.StatusAcknowledged},
+}
+
+// ListIncidents prints the incidents matching the filter flags
+func (p *Pagerduty) ListIncidents() error {
+
+	pd := p.stim.Pagerduty()
+
+	filter, err := p.incidentFilter(pd, "incidents")
+	if err != nil {
+		return err
+	}
+	filter.Statuses = p.stim.ConfigGetStringSlice("pagerduty-incidents-status")
+
+	incidents, err := pd.ListIncidents(filter)
+	if err != nil {
+		return err
+	}
+
+	return p.printOutput(incidents, p.incidentTable(incidents))
+}
+
+// Ack acknowledges incidents, or the alert of --dedup-key with the Events API
+func (p *Pagerduty) Ack(args []string) error {
+	if p.stim.ConfigGetString("pagerduty-ack-dedup-key") != "" {
+		return p.sendAlertEvent(actionAck, pagerduty.EventAcknowledge, args)
+	}
+	return p.updateIncidents(actionAck, args, "Acknowledge", func(pd *pagerduty.Pagerduty, from string, incidents []pagerduty.Incident) error {
+		return pd.SetIncidentStatus(from, incidentIDs(incidents), pagerduty.StatusAcknowledged)
+	})
+}
+
+// Resolve resolves incidents, or the alert of --dedup-key with the Events API
+func (p *Pagerduty) Resolve(args []string) error {
+	if p.stim.ConfigGetString("pagerduty-resolve-dedup-key") != "" {
+		return p.sendAlertEvent(actionResolve, pagerduty.EventResolve, args)
+	}
+	return p.updateIncidents(actionResolve, args, "Resolve", func(pd *pagerduty.Pagerduty, from string, incidents []pagerduty.Incident) error {
+		return pd.SetIncidentStatus(from, incidentIDs(incidents), pagerduty.StatusResolved)
+	})
+}
+
+// Snooze snoozes acknowledged incidents
+func (p *Pagerduty) Snooze(args []string) error {
+
+	duration, err := time.ParseDuration(p.stim.ConfigGetString("pagerduty-snooze-duration"))
+	if err != nil || duration <= 0 {
+		return fmt.Errorf("Invalid snooze duration '%s'", p.stim.ConfigGetString("pagerduty-snooze-duration"))
+	}
+
+	return p.updateIncidents(actionSnooze, args, "Snooze", func(pd *pagerduty.Pagerduty, from string, incidents []pagerduty.Incident) error {
+		for _, incident := range incidents {
+			err := pd.SnoozeIncident(from, incident.ID, duration)
+			if err != nil {
+				return fmt.Errorf("Unable to snooze incident %d: %v", incident.Number, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Reassign assigns incidents to other users
+func (p *Pagerduty) Reassign(args []string) error {
+
+	emails := p.stim.ConfigGetStringSlice("pagerduty-reassign-to")
+	if len(emails) == 0 {
+		return errors.New("At least one user must be given with --to")
+	}
+
+	users, err := userIDs(p.stim.Pagerduty(), emails)
+	if err != nil {
+		return err
+	}
+
+	return p.updateIncidents(actionReassign, args, "Reassign", func(pd *pagerduty.Pagerduty, from string, incidents []pagerduty.Incident) error {
+		return pd.ReassignIncidents(from, incidentIDs(incidents), users)
+	})
+}
+
+// Merge merges incidents into the target incident (the first argument)
+func (p *Pagerduty) Merge(args []string) error {
+
+	target, sources := args[0], args[1:]
+
+	return p.updateIncidents(actionMerge, sources, "Merge into "+target, func(pd *pagerduty.Pagerduty, from string, incidents []pagerduty.Incident) error {
+		var sourceIDs []string
+		for _, id := range incidentIDs(incidents) {
+			if id != target {
+				sourceIDs = append(sourceIDs, id)
+			}
+		}
+		if len(sourceIDs) == 0 {
+			return errors.New("No incidents to merge")
+		}
+		return pd.MergeIncidents(from, target, sourceIDs)
+	})
+}
+
+// updateIncidents selects the incidents given as arguments, or those matching
+// the filter flags with --all, confirms the bulk change and applies it
+func (p *Pagerduty) updateIncidents(action string, args []string, verb string, update func(pd *pagerduty.Pagerduty, from string, incidents []pagerduty.Incident) error) error {
+
+	pd := p.stim.Pagerduty()
+
+	incidents, err := p.selectIncidents(pd, action, args)
+	if err != nil {
+		return err
+	}
+
+	if len(incidents) == 0 {
+		fmt.Println("No matching incidents")
+		return nil
+	}
+
+	from, err := p.fromEmail()
+	if err != nil {
+		return err
+	}
+
+	if len(incidents) > 1 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		p.incidentTable(incidents)(w)
+		w.Flush()
+
+		yes := p.stim.ConfigGetBool("pagerduty-" + action + "-yes")
+		if !yes && p.stim.IsAutomated() {
+			return fmt.Errorf("Use --yes to change %d incidents non-interactively", len(incidents))
+		}
+		proceed, _ := p.stim.PromptBool(fmt.Sprintf("%s %d incidents?", verb, len(incidents)), yes, false)
+		if !proceed {
+			return errors.New("Cancelled")
+		}
+	}
+
+	err = update(pd, from, incidents)
+	if err != nil {
+		return err
+	}
+
+	p.stim.GetLogger().Info("{}: {} incident(s) updated", verb, len(incidents))
+	return nil
+}
+
+// selectIncidents returns the incidents given as arguments, or those matching
+// the filter flags with --all that the action can be applied to
+func (p *Pagerduty) selectIncidents(pd *pagerduty.Pagerduty, action string, args []string) ([]pagerduty.Incident, error) {
+
+	all := p.stim.ConfigGetBool("pagerduty-" + action + "-all")
+	switch {
+	case len(args) > 0 && all:
+		return nil, errors.New("Incident IDs and --all can't be used together")
+	case len(args) > 0:
+		var incidents []pagerduty.Incident
+		for _, id := range args {
+			incident, err := pd.GetIncident(id)
+			if err != nil {
+				return nil, err
+			}
+			incidents = append(incidents, *incident)
+		}
+		return incidents, nil
+	case all:
+		filter, err := p.incidentFilter(pd, action)
+		if err != nil {
+			return nil, err
+		}
+		filter.Statuses = actionStatuses[action]
+		return pd.ListIncidents(filter)
+	}
+
+	return nil, errors.New("Give the IDs of the incidents, or --all to select the incidents matching the filters")
+}
+
+// incidentFilter returns the incident filter from the flags of the command
+func (p *Pagerduty) incidentFilter(pd *pagerduty.Pagerduty, action string) (*pagerduty.IncidentFilter, error) {
+
+	filter := &pagerduty.IncidentFilter{
+		Urgencies: p.stim.ConfigGetStringSlice("pagerduty-" + action + "-urgency"),
+	}
+
+	for _, service := range p.stim.ConfigGetStringSlice("pagerduty-" + action + "-service") {
+		id, err := pd.GetServiceID(service)
+		if err != nil {
+			return nil, err
+		}
+		filter.ServiceIDs = append(filter.ServiceIDs, id)
+	}
+
+	var err error
+	filter.UserIDs, err = userIDs(pd, p.stim.ConfigGetStringSlice("pagerduty-"+action+"-assigned-to"))
+	if err != nil {
+		return nil, err
+	}
+
+	return filter, nil
+}
+
+// fromEmail returns the email of the Pagerduty user making changes
+func (p *Pagerduty) fromEmail() (string, error) {
+
+	from := p.stim.ConfigGetString("pagerduty.email")
+	if from != "" {
+		return from, nil
+	}
+	if p.stim.IsAutomated() {
+		return "", errors.New("Your Pagerduty email must be given with --from or the `pagerduty.email` config option")
+	}
+
+	return p.stim.PromptString("Your Pagerduty email", "")
+}
+
+// incidentTable returns a function printing the incidents as a table
+func (p *Pagerduty) incidentTable(incidents []pagerduty.Incident) func(w *tabwriter.Writer) {
+	return func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "NUMBER\tID\tSTATUS\tURGENCY\tSERVICE\tASSIGNED TO\tCREATED\tTITLE")
+		for _, i := range incidents {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", i.Number, i.ID, i.Status, i.Urgency, i.Service,
+				strings.Join(i.Assignees, ", "), p.stim.FormatRelative(i.CreatedAt), i.Title)
+		}
+	}
+}
+
+// userIDs returns the IDs of the users with the emails
+func userIDs(pd *pagerduty.Pagerduty, emails []string) ([]string, error) {
+	var ids []string
+	for _, email := range emails {
+		user, err := pd.GetUser(email)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, user.ID)
+	}
+	return ids, nil
+}
+
+// incidentIDs returns the IDs of the incidents
+func incidentIDs(incidents []pagerduty.Incident) []string {
+	ids := make([]string, len(incidents))
+	for i, incident := range incidents {
+		ids[i] = incident.ID
+	}
+	return ids
+}
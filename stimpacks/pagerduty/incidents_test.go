@@ -0,0 +1,67 @@
+package pagerduty
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PremiereGlobal/stim/pkg/pagerduty"
+	"github.com/PremiereGlobal/stim/pkg/stimlog"
+	"github.com/PremiereGlobal/stim/stim"
+	"gotest.tools/assert"
+)
+
+// --all selects the incidents matching the filter flags, with the statuses
+// the action can be applied to
+func TestSelectIncidents(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/services":
+			fmt.Fprint(w, `{"services":[{"id":"S1","name":"Payments"}]}`)
+		case "/users":
+			fmt.Fprint(w, `{"users":[{"id":"U1","email":"ops@my-domain.com"}]}`)
+		case "/incidents":
+			queries = append(queries, r.URL.RawQuery)
+			fmt.Fprint(w, `{"incidents":[{"id":"P1","status":"acknowledged"},{"id":"P2","status":"acknowledged"}]}`)
+		case "/incidents/P3":
+			fmt.Fprint(w, `{"incident":{"id":"P3","status":"triggered"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	pd := pagerduty.New("api-key", stimlog.GetLogger())
+	pd.SetHTTPClient(&http.Client{})
+	pd.SetAPIEndpoint(server.URL)
+
+	s := stim.New()
+	p := &Pagerduty{stim: s}
+
+	_, err := p.selectIncidents(pd, actionSnooze, nil)
+	assert.Error(t, err, "Give the IDs of the incidents, or --all to select the incidents matching the filters")
+
+	incidents, err := p.selectIncidents(pd, actionSnooze, []string{"P3"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, incidentIDs(incidents), []string{"P3"})
+
+	s.ConfigOverride("pagerduty-snooze-all", true)
+	s.ConfigOverride("pagerduty-snooze-service", []string{"payments"})
+	s.ConfigOverride("pagerduty-snooze-assigned-to", []string{"ops@my-domain.com"})
+
+	_, err = p.selectIncidents(pd, actionSnooze, []string{"P3"})
+	assert.Error(t, err, "Incident IDs and --all can't be used together")
+
+	incidents, err = p.selectIncidents(pd, actionSnooze, nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, incidentIDs(incidents), []string{"P1", "P2"})
+
+	assert.Equal(t, len(queries), 1)
+	for _, param := range []string{"service_ids%5B%5D=S1", "user_ids%5B%5D=U1", "statuses%5B%5D=acknowledged"} {
+		assert.Assert(t, strings.Contains(queries[0], param), queries[0])
+	}
+	assert.Assert(t, !strings.Contains(queries[0], "triggered"), queries[0])
+}
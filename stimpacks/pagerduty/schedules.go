@@ -0,0 +1,141 @@
+package pagerduty
+
+import (
+	"errors"
+	"fmt"
+	"text/tabwriter"
+	"time"
+)
+
+// OnCall prints who is currently on call, for the selected schedule or all
+// schedules
+func (p *Pagerduty) OnCall() error {
+
+	pagerduty := p.stim.Pagerduty()
+
+	var scheduleIDs []string
+	scheduleName := p.stim.ConfigGetString("pagerduty-oncall-schedule")
+	if scheduleName != "" {
+		schedule, err := pagerduty.GetSchedule(scheduleName)
+		if err != nil {
+			return err
+		}
+		scheduleIDs = []string{schedule.ID}
+	}
+
+	oncalls, err := pagerduty.GetOnCalls(scheduleIDs)
+	if err != nil {
+		return err
+	}
+
+	return p.printOutput(oncalls, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "SCHEDULE\tESCALATION POLICY\tLEVEL\tUSER\tUNTIL")
+		for _, o := range oncalls {
+			until := "always"
+			if !o.End.IsZero() {
+				until = p.stim.FormatTime(o.End)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", o.Schedule, o.EscalationPolicy, o.EscalationLevel, o.User, until)
+		}
+	})
+}
+
+// ListSchedules prints the on-call schedules
+func (p *Pagerduty) ListSchedules() error {
+
+	schedules, err := p.stim.Pagerduty().ListSchedules(p.stim.ConfigGetString("pagerduty-schedules-query"))
+	if err != nil {
+		return err
+	}
+
+	return p.printOutput(schedules, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "ID\tNAME\tTIME ZONE\tDESCRIPTION")
+		for _, s := range schedules {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.ID, s.Name, s.TimeZone, s.Description)
+		}
+	})
+}
+
+// CreateOverride puts a user on call for a schedule for a period of time
+func (p *Pagerduty) CreateOverride() error {
+
+	pagerduty := p.stim.Pagerduty()
+
+	scheduleName := p.stim.ConfigGetString("pagerduty-override-schedule")
+	if scheduleName == "" {
+		if p.stim.IsAutomated() {
+			return errors.New("Pagerduty `schedule` not specified")
+		}
+		schedules, err := pagerduty.ListSchedules("")
+		if err != nil {
+			return err
+		}
+		names := make([]string, len(schedules))
+		for i, s := range schedules {
+			names[i] = s.Name
+		}
+		scheduleName, err = p.stim.PromptSearchList("Choose Schedule:", names)
+		if err != nil {
+			return err
+		}
+	}
+
+	schedule, err := pagerduty.GetSchedule(scheduleName)
+	if err != nil {
+		return err
+	}
+
+	email := p.stim.ConfigGetString("pagerduty-override-user")
+	if email == "" {
+		if p.stim.IsAutomated() {
+			return errors.New("Pagerduty override `user` not specified")
+		}
+		email, err = p.stim.PromptString("User email", "")
+		if err != nil {
+			return err
+		}
+	}
+
+	user, err := pagerduty.GetUser(email)
+	if err != nil {
+		return err
+	}
+
+	start := p.stim.Clock().Now()
+	if s := p.stim.ConfigGetString("pagerduty-override-start"); s != "" {
+		start, err = time.Parse(time.RFC3339, s)
+		if err != nil {
+			return fmt.Errorf("Invalid override start '%s', must be in RFC3339 format (ex. 2006-01-02T15:04:05-07:00)", s)
+		}
+	}
+
+	var end time.Time
+	if e := p.stim.ConfigGetString("pagerduty-override-end"); e != "" {
+		end, err = time.Parse(time.RFC3339, e)
+		if err != nil {
+			return fmt.Errorf("Invalid override end '%s', must be in RFC3339 format (ex. 2006-01-02T15:04:05-07:00)", e)
+		}
+	} else {
+		duration, err := time.ParseDuration(p.stim.ConfigGetString("pagerduty-override-duration"))
+		if err != nil {
+			return fmt.Errorf("Invalid override duration: %v", err)
+		}
+		end = start.Add(duration)
+	}
+
+	override, err := pagerduty.CreateOverride(schedule.ID, user.ID, start, end)
+	if err != nil {
+		return err
+	}
+
+	return p.printOutput(override, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "ID\tSCHEDULE\tUSER\tSTART\tEND")
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", override.ID, schedule.Name, override.User,
+			p.stim.FormatTime(override.Start), p.stim.FormatTime(override.End))
+	})
+}
+
+// printOutput prints the data as JSON or as a table
+func (p *Pagerduty) printOutput(data interface{}, table func(w *tabwriter.Writer)) error {
+	return p.stim.PrintOutput(p.stim.ConfigGetString("pagerduty-output"), data, table)
+}
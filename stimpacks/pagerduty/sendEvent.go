@@ -2,11 +2,13 @@ package pagerduty
 
 import (
 	"errors"
+	"fmt"
 
 	pd "github.com/PremiereGlobal/stim/pkg/pagerduty"
+	"github.com/PremiereGlobal/stim/stim"
 )
 
-func (p *Pagerduty) SendEvent() {
+func (p *Pagerduty) SendEvent() error {
 
 	var err error
 
@@ -15,28 +17,34 @@ func (p *Pagerduty) SendEvent() {
 	// Prompt for the service name (if not provided)
 	serviceName := p.stim.ConfigGetString("pagerduty-service")
 	if serviceName == "" && p.stim.IsAutomated() {
-		p.stim.Fatal(errors.New("Pagerduty `service name` not specified"))
+		return stim.UsageError(errors.New("Pagerduty `service name` not specified"))
 	} else if serviceName == "" {
 
 		// Get the channel list
 		services, err := pagerduty.GetServices()
-		p.stim.Fatal(err)
+		if err != nil {
+			return err
+		}
 
 		// Prompt for channel
 		serviceName, err = p.stim.PromptSearchList("Choose Service:", services)
-		p.stim.Fatal(err)
+		if err != nil {
+			return err
+		}
 
 	}
 
 	// Prompt for the summary text (if not provided)
 	summary := p.stim.ConfigGetString("pagerduty-summary")
 	if summary == "" && p.stim.IsAutomated() {
-		p.stim.Fatal(errors.New("Pagerduty `summary` not specified"))
+		return stim.UsageError(errors.New("Pagerduty `summary` not specified"))
 	} else if summary == "" {
 
 		// Prompt
 		summary, err = p.stim.PromptString("Summary Text", "")
-		p.stim.Fatal(err)
+		if err != nil {
+			return err
+		}
 
 	}
 
@@ -79,24 +87,28 @@ func (p *Pagerduty) SendEvent() {
 	// Prompt for the action (if not provided)
 	action := p.stim.ConfigGetString("pagerduty-action")
 	if action == "" && p.stim.IsAutomated() {
-		p.stim.Fatal(errors.New("Pagerduty `action` not specified"))
+		return stim.UsageError(errors.New("Pagerduty `action` not specified"))
 	} else if action == "" {
 
 		// Prompt
 		action, err = p.stim.PromptString("Action (trigger/resolve)", "trigger")
-		p.stim.Fatal(err)
+		if err != nil {
+			return err
+		}
 
 	}
 
 	// Prompt for the severity (if not provided)
 	severity := p.stim.ConfigGetString("pagerduty-severity")
 	if severity == "" && p.stim.IsAutomated() {
-		p.stim.Fatal(errors.New("Pagerduty `severity` not specified"))
+		return stim.UsageError(errors.New("Pagerduty `severity` not specified"))
 	} else if severity == "" {
 
 		// Prompt
 		severity, err = p.stim.PromptString("Severity (info, warning, error, critical)", "warning")
-		p.stim.Fatal(err)
+		if err != nil {
+			return err
+		}
 
 	}
 
@@ -113,7 +125,11 @@ func (p *Pagerduty) SendEvent() {
 		DedupKey:  dedupKey,
 	}
 
-	err = pagerduty.SendEvent(event)
-	p.stim.Fatal(err)
+	dedupKey, err = pagerduty.SendEvent(event)
+	if err != nil {
+		return err
+	}
 
+	fmt.Println(dedupKey)
+	return nil
 }
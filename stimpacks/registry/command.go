@@ -0,0 +1,70 @@
+package registry
+
+import (
+	"github.com/PremiereGlobal/stim/stim"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func (r *Registry) BindStim(s *stim.Stim) {
+	r.stim = s
+}
+
+func (r *Registry) Command(viper *viper.Viper) *cobra.Command {
+
+	var cmd = &cobra.Command{
+		Use:   "registry",
+		Short: "Interact with container registries",
+		Long:  "List and inspect the tags of container images and promote images between repositories.  Registry credentials are read from Vault (see registry.credentials in the stim config)",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	cmd.PersistentFlags().StringP("output", "o", "table", "Output format of the tags and inspect commands (table or json)")
+	viper.BindPFlag("registry-output", cmd.PersistentFlags().Lookup("output"))
+
+	var tagsCmd = &cobra.Command{
+		Use:     "tags <repo>",
+		Short:   "List the tags of a repository",
+		Long:    "List the tags of an image repository (ex. docker.example.com/team/app)",
+		Example: "  stim registry tags docker.example.com/team/app --filter '^v1\\.'",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return r.tags(args[0])
+		},
+	}
+	r.stim.BindCommand(tagsCmd, cmd)
+
+	tagsCmd.Flags().StringP("filter", "f", "", "Only list tags matching this regular expression")
+	viper.BindPFlag("registry-tags-filter", tagsCmd.Flags().Lookup("filter"))
+
+	var inspectCmd = &cobra.Command{
+		Use:   "inspect <image>",
+		Short: "Show the details of an image",
+		Long:  "Show the digest, size, creation time and platforms of an image (ex. docker.example.com/team/app:v1.2.0).  Fails if the tag doesn't exist",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return r.inspect(args[0])
+		},
+	}
+	r.stim.BindCommand(inspectCmd, cmd)
+
+	var promoteCmd = &cobra.Command{
+		Use:         "promote <source-image> <destination-repo[:tag]>",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Copy an image to another repository or tag",
+		Long:        "Tag an image in another repository (or under another tag) of the same registry without pulling it, so the promoted image is identical.  The destination tag defaults to the source tag",
+		Example:     "  stim registry promote docker.example.com/staging/app:v1.2.0 docker.example.com/prod/app",
+		Args:        cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return r.promote(args[0], args[1])
+		},
+	}
+	r.stim.BindCommand(promoteCmd, cmd)
+
+	promoteCmd.Flags().Bool("force", false, "Replace the destination tag if it already points to a different image")
+	viper.BindPFlag("registry-promote-force", promoteCmd.Flags().Lookup("force"))
+
+	return cmd
+}
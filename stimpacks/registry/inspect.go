@@ -0,0 +1,85 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	registrypkg "github.com/PremiereGlobal/stim/pkg/registry"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// imageDetails are the details of an image shown by `stim registry inspect`
+type imageDetails struct {
+	Image     string    `json:"image"`
+	Digest    string    `json:"digest"`
+	MediaType string    `json:"mediaType"`
+	Size      int64     `json:"size,omitempty"`
+	Created   time.Time `json:"created,omitempty"`
+	Platforms []string  `json:"platforms"`
+}
+
+// inspect shows the details of an image
+func (r *Registry) inspect(image string) error {
+
+	ref, err := parseImage(image)
+	if err != nil {
+		return err
+	}
+
+	client, err := r.stim.Registry(ref.Host)
+	if err != nil {
+		return err
+	}
+	manifest, err := client.Manifest(ref.Repo, ref.Reference())
+	if err == registrypkg.ErrNotFound {
+		return fmt.Errorf("Image %s not found", ref)
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to inspect %s: %v", ref, err)
+	}
+
+	details := &imageDetails{Image: ref.String(), Digest: manifest.Digest, MediaType: manifest.MediaType, Platforms: []string{}}
+	if manifest.IsList() {
+		for _, child := range manifest.Manifests {
+			if child.Platform != nil {
+				details.Platforms = append(details.Platforms, child.Platform.String())
+			}
+		}
+	} else {
+		details.Size = manifest.Size()
+		config, err := client.ImageConfig(ref.Repo, manifest)
+		if err != nil {
+			return fmt.Errorf("Unable to inspect %s: %v", ref, err)
+		}
+		details.Created = config.Created
+		details.Platforms = append(details.Platforms, config.OS+"/"+config.Architecture)
+	}
+
+	return r.stim.PrintOutput(r.stim.ConfigGetString("registry-output"), details, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "Image:\t%s\n", details.Image)
+		fmt.Fprintf(w, "Digest:\t%s\n", details.Digest)
+		fmt.Fprintf(w, "Media Type:\t%s\n", details.MediaType)
+		if details.Size > 0 {
+			fmt.Fprintf(w, "Size:\t%.1f MB\n", float64(details.Size)/1000/1000)
+		}
+		if !details.Created.IsZero() {
+			fmt.Fprintf(w, "Created:\t%s\n", r.stim.FormatTime(details.Created))
+		}
+		fmt.Fprintf(w, "Platforms:\t%s\n", strings.Join(details.Platforms, ", "))
+	})
+}
+
+// parseImage parses an image reference given as an argument
+func parseImage(image string) (*registrypkg.Reference, error) {
+	if image == "" {
+		return nil, stim.UsageError(errors.New("Image not specified"))
+	}
+	ref, err := registrypkg.ParseReference(image)
+	if err != nil {
+		return nil, stim.UsageError(err)
+	}
+	return ref, nil
+}
@@ -0,0 +1,75 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+
+	registrypkg "github.com/PremiereGlobal/stim/pkg/registry"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// promote copies the source image to the destination repository and tag
+func (r *Registry) promote(source string, destination string) error {
+
+	src, err := parseImage(source)
+	if err != nil {
+		return err
+	}
+	dst, err := parseImage(destination)
+	if err != nil {
+		return err
+	}
+	if src.Host != dst.Host {
+		return stim.UsageError(fmt.Errorf("Images can only be promoted within a registry, %s and %s are different registries", src.Host, dst.Host))
+	}
+	if dst.Digest != "" {
+		return stim.UsageError(errors.New("The destination must be a repository or tag, not a digest"))
+	}
+
+	// The destination tag defaults to the source tag
+	dstTag := dst.Tag
+	if dstTag == "" && src.Digest == "" {
+		dstTag = src.Reference()
+	}
+	if dstTag == "" {
+		return stim.UsageError(errors.New("The destination tag must be set when promoting a digest"))
+	}
+	if src.Repo == dst.Repo && src.Reference() == dstTag {
+		return stim.UsageError(errors.New("The source and destination are the same image"))
+	}
+
+	client, err := r.stim.Registry(src.Host)
+	if err != nil {
+		return err
+	}
+
+	// Don't silently move a tag that already points to another image
+	srcManifest, err := client.Manifest(src.Repo, src.Reference())
+	if err == registrypkg.ErrNotFound {
+		return fmt.Errorf("Image %s not found", src)
+	}
+	if err != nil {
+		return err
+	}
+	dstManifest, err := client.Manifest(dst.Repo, dstTag)
+	if err != nil && err != registrypkg.ErrNotFound {
+		return err
+	}
+	if err == nil {
+		if dstManifest.Digest == srcManifest.Digest {
+			r.stim.GetLogger().Info("{}/{}:{} is already {}", dst.Host, dst.Repo, dstTag, srcManifest.Digest)
+			return nil
+		}
+		if !r.stim.ConfigGetBool("registry-promote-force") {
+			return fmt.Errorf("%s/%s:%s already exists as %s, use --force to replace it", dst.Host, dst.Repo, dstTag, dstManifest.Digest)
+		}
+	}
+
+	digest, err := client.Copy(src.Repo, src.Reference(), dst.Repo, dstTag)
+	if err != nil {
+		return fmt.Errorf("Unable to promote %s: %v", src, err)
+	}
+
+	r.stim.GetLogger().Info("Promoted {} to {}/{}:{} ({})", src, dst.Host, dst.Repo, dstTag, digest)
+	return nil
+}
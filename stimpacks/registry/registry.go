@@ -0,0 +1,21 @@
+package registry
+
+import (
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// Registry is the stimpack that lists, inspects and promotes container
+// images
+type Registry struct {
+	name string
+	stim *stim.Stim
+}
+
+func New() *Registry {
+	registry := &Registry{name: "registry"}
+	return registry
+}
+
+func (r *Registry) Name() string {
+	return r.name
+}
@@ -0,0 +1,49 @@
+package registry
+
+import (
+	"fmt"
+	"regexp"
+	"text/tabwriter"
+
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// tags lists the tags of the repository
+func (r *Registry) tags(repo string) error {
+
+	ref, err := parseImage(repo)
+	if err != nil {
+		return err
+	}
+
+	var filter *regexp.Regexp
+	if pattern := r.stim.ConfigGetString("registry-tags-filter"); pattern != "" {
+		filter, err = regexp.Compile(pattern)
+		if err != nil {
+			return stim.UsageError(fmt.Errorf("Invalid --filter: %v", err))
+		}
+	}
+
+	client, err := r.stim.Registry(ref.Host)
+	if err != nil {
+		return err
+	}
+	all, err := client.Tags(ref.Repo)
+	if err != nil {
+		return fmt.Errorf("Unable to list the tags of %s/%s: %v", ref.Host, ref.Repo, err)
+	}
+
+	tags := []string{}
+	for _, tag := range all {
+		if filter == nil || filter.MatchString(tag) {
+			tags = append(tags, tag)
+		}
+	}
+
+	return r.stim.PrintOutput(r.stim.ConfigGetString("registry-output"), tags, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "TAG")
+		for _, tag := range tags {
+			fmt.Fprintln(w, tag)
+		}
+	})
+}
@@ -0,0 +1,25 @@
+package scaffold
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func (s *Scaffold) Command(viper *viper.Viper) *cobra.Command {
+
+	var cmd = &cobra.Command{
+		Use:   "init",
+		Short: "Create a deploy config for a new service",
+		Long:  "Interactively creates a stim.deploy.yaml (environments, instances, clusters, service accounts and tools) and a starter deploy.sh.  The kube-config secret in Vault of each cluster and service account is checked as they are entered",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return s.Init()
+		},
+	}
+
+	cmd.Flags().StringP("deploy-file", "f", "stim.deploy.yaml", "Deploy config file to create")
+	viper.BindPFlag("init-file", cmd.Flags().Lookup("deploy-file"))
+	cmd.Flags().Bool("force", false, "Overwrite the deploy config file if it exists")
+	viper.BindPFlag("init-force", cmd.Flags().Lookup("force"))
+
+	return cmd
+}
@@ -0,0 +1,247 @@
+package scaffold
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/PremiereGlobal/stim/pkg/vault"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// defaultDeployScript is the deploy script created next to the deploy config
+const defaultDeployScript = "deploy.sh"
+
+// kubeConfigKeys are the keys of the kube-config secret that deploys read
+var kubeConfigKeys = []string{"cluster-server", "cluster-ca", "user-token"}
+
+// toolNames are the tools that a deploy config can require
+var toolNames = []string{"helm", "kubectl", "terraform", "vault"}
+
+// service is the deploy config being created
+type service struct {
+	Environments []*environment
+	Tools        []string
+}
+
+// environment is an environment of the deploy config
+type environment struct {
+	Name      string
+	Instances []*instance
+}
+
+// instance is an instance of an environment
+type instance struct {
+	Name           string
+	Cluster        string
+	ServiceAccount string
+}
+
+// Init prompts for the environments, instances, clusters, service accounts
+// and tools of a new service and writes its deploy config and a starter
+// deploy script
+func (s *Scaffold) Init() error {
+
+	log := s.stim.GetLogger()
+
+	file := s.stim.ConfigGetString("init-file")
+	if _, err := os.Stat(file); err == nil && !s.stim.ConfigGetBool("init-force") {
+		return stim.UsageError(fmt.Errorf("%s already exists.  Use --force to overwrite it", file))
+	}
+	if s.stim.IsAutomated() {
+		return stim.UsageError(errors.New("stim init prompts for the deploy config and can't be automated"))
+	}
+
+	// The kube-config secrets are checked if Vault can be used
+	v, err := s.stim.NewVault()
+	if err != nil {
+		log.Warn("Unable to use Vault, the clusters and service accounts won't be checked: {}", err)
+		v = nil
+	}
+
+	svc := &service{}
+	names, err := s.promptNames("Environments (comma separated)", "dev,prod")
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		env := &environment{Name: name}
+		instances, err := s.promptNames(fmt.Sprintf("Instances of %s (comma separated, ex. us-west-2)", name), "")
+		if err != nil {
+			return err
+		}
+		for _, instanceName := range instances {
+			inst := &instance{Name: instanceName}
+			inst.Cluster, inst.ServiceAccount, err = s.promptKubernetes(v, name+"/"+instanceName)
+			if err != nil {
+				return err
+			}
+			env.Instances = append(env.Instances, inst)
+		}
+		svc.Environments = append(svc.Environments, env)
+	}
+
+	tools, err := s.stim.PromptString(fmt.Sprintf("Tools (comma separated, from %s)", strings.Join(toolNames, ", ")), "kubectl")
+	if err != nil {
+		return err
+	}
+	svc.Tools, err = parseTools(tools)
+	if err != nil {
+		return stim.UsageError(err)
+	}
+
+	err = ioutil.WriteFile(file, renderDeployConfig(svc), 0644)
+	if err != nil {
+		return err
+	}
+	log.Info("Wrote {}", file)
+
+	script := filepath.Join(filepath.Dir(file), defaultDeployScript)
+	if _, err := os.Stat(script); os.IsNotExist(err) {
+		err = ioutil.WriteFile(script, []byte(deployScript), 0755)
+		if err != nil {
+			return err
+		}
+		log.Info("Wrote {}", script)
+	}
+
+	log.Info("Run `stim deploy explain -f {}` to check the resolved config and `stim deploy preflight -f {}` to check its secrets", file, file)
+	return nil
+}
+
+// promptNames prompts for a comma separated list of names until at least one
+// is entered
+func (s *Scaffold) promptNames(label string, defaultValue string) ([]string, error) {
+	for {
+		value, err := s.stim.PromptString(label, defaultValue)
+		if err != nil {
+			return nil, err
+		}
+		if names := splitNames(value); len(names) > 0 {
+			return names, nil
+		}
+	}
+}
+
+// promptKubernetes prompts for the cluster and service account of an
+// instance, from the ones in Vault if it can be used, until their
+// kube-config secret has the keys needed to deploy or the user keeps them
+func (s *Scaffold) promptKubernetes(v *vault.Vault, target string) (string, string, error) {
+
+	for {
+		cluster, err := s.promptVaultName(v, s.stim.KubeClusterListPath(), fmt.Sprintf("Cluster of %s", target))
+		if err != nil {
+			return "", "", err
+		}
+		serviceAccount, err := s.promptVaultName(v, s.stim.KubeServiceAccountListPath(cluster), fmt.Sprintf("Service account of %s in %s", target, cluster))
+		if err != nil {
+			return "", "", err
+		}
+		if v == nil {
+			return cluster, serviceAccount, nil
+		}
+
+		secretPath := s.stim.KubeConfigSecretPath(cluster, serviceAccount)
+		keys, err := v.GetSecretKeyNames(secretPath, 0)
+		if err == nil {
+			err = checkKubeConfigKeys(keys)
+		}
+		if err == nil {
+			return cluster, serviceAccount, nil
+		}
+
+		s.stim.GetLogger().Warn("The kube-config secret {} can't be used to deploy: {}", secretPath, err)
+		keep, err := s.stim.PromptBool("Use this cluster and service account anyway?", false, false)
+		if err != nil {
+			return "", "", err
+		}
+		if keep {
+			return cluster, serviceAccount, nil
+		}
+	}
+}
+
+// promptVaultName prompts to select a name from a Vault list, or to enter
+// one if the list can't be read
+func (s *Scaffold) promptVaultName(v *vault.Vault, listPath string, label string) (string, error) {
+
+	if v != nil {
+		list, err := v.ListSecrets(listPath)
+		if err == nil && len(list) > 0 {
+			names := make([]string, len(list))
+			for i, name := range list {
+				names[i] = strings.TrimSuffix(name, "/")
+			}
+			return s.stim.PromptList(label, names, "")
+		}
+		s.stim.GetLogger().Debug("Unable to list {}: {}", listPath, err)
+	}
+
+	for {
+		name, err := s.stim.PromptString(label, "")
+		if err != nil {
+			return "", err
+		}
+		if name = strings.TrimSpace(name); name != "" {
+			return name, nil
+		}
+	}
+}
+
+// checkKubeConfigKeys returns an error listing the keys that deploys need
+// and are missing from a kube-config secret
+func checkKubeConfigKeys(keys []string) error {
+
+	found := make(map[string]bool)
+	for _, key := range keys {
+		found[key] = true
+	}
+
+	var missing []string
+	for _, key := range kubeConfigKeys {
+		if !found[key] {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing keys %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// splitNames returns the names of a comma separated list without blanks or
+// duplicates
+func splitNames(value string) []string {
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// parseTools returns the sorted tools of a comma separated list
+func parseTools(value string) ([]string, error) {
+
+	tools := splitNames(value)
+	for _, tool := range tools {
+		known := false
+		for _, name := range toolNames {
+			known = known || tool == name
+		}
+		if !known {
+			return nil, fmt.Errorf("Unknown tool '%s'.  Must be one of ['%s']", tool, strings.Join(toolNames, "','"))
+		}
+	}
+	sort.Strings(tools)
+	return tools, nil
+}
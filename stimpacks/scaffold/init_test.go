@@ -0,0 +1,51 @@
+package scaffold
+
+import (
+	"testing"
+
+	"github.com/PremiereGlobal/stim/stimpacks/deploy"
+	"gopkg.in/yaml.v2"
+	"gotest.tools/assert"
+)
+
+func TestRenderDeployConfig(t *testing.T) {
+	svc := &service{
+		Environments: []*environment{
+			{Name: "dev", Instances: []*instance{{Name: "us-west-2", Cluster: "blue.dev.my-domain.com", ServiceAccount: "deploy"}}},
+			{Name: "prod", Instances: []*instance{
+				{Name: "us-west-2", Cluster: "blue.prod.my-domain.com", ServiceAccount: "deploy"},
+				{Name: "eu: west", Cluster: "green.prod.my-domain.com", ServiceAccount: "deploy"},
+			}},
+		},
+		Tools: []string{"helm", "kubectl"},
+	}
+
+	// The config is a valid deploy config
+	config := &deploy.Config{}
+	assert.NilError(t, yaml.UnmarshalStrict(renderDeployConfig(svc), config))
+	assert.Equal(t, config.Deployment.Script, "deploy.sh")
+	assert.Equal(t, len(config.Global.Spec.Tools), 2)
+	assert.Equal(t, len(config.Environments), 2)
+	assert.Equal(t, config.Environments[1].Instances[1].Name, "eu: west")
+	assert.Equal(t, config.Environments[1].Instances[1].Spec.Kubernetes.Cluster, "green.prod.my-domain.com")
+	assert.Equal(t, config.Environments[0].Instances[0].Spec.Kubernetes.ServiceAccount, "deploy")
+}
+
+func TestCheckKubeConfigKeys(t *testing.T) {
+	assert.NilError(t, checkKubeConfigKeys([]string{"cluster-ca", "cluster-server", "default-namespace", "user-token"}))
+	assert.Error(t, checkKubeConfigKeys([]string{"cluster-server"}), "missing keys cluster-ca, user-token")
+}
+
+func TestSplitNames(t *testing.T) {
+	assert.DeepEqual(t, splitNames(" dev, prod,,dev "), []string{"dev", "prod"})
+	assert.Equal(t, len(splitNames(" , ")), 0)
+}
+
+func TestParseTools(t *testing.T) {
+	tools, err := parseTools("kubectl, helm")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, tools, []string{"helm", "kubectl"})
+
+	_, err = parseTools("kubectl,kustomize")
+	assert.ErrorContains(t, err, "Unknown tool 'kustomize'")
+}
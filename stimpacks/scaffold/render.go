@@ -0,0 +1,71 @@
+package scaffold
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// deployScript is the starter deploy script
+const deployScript = `#!/bin/bash
+set -eu -o pipefail
+
+# Exit if not running with ` + "`stim deploy`" + `
+if [ ! ${STIM_DEPLOY+x} ]; then echo "Must be run with 'stim deploy'"; exit 1; fi
+
+echo "Deploying to ${DEPLOY_ENVIRONMENT} in instance ${DEPLOY_INSTANCE} in cluster ${DEPLOY_CLUSTER} (namespace ${DEPLOY_NAMESPACE})"
+
+# Replace with the deployment of the service, ex. helm upgrade --install or
+# kubectl apply.  The kubectl context is set to the cluster of the instance.
+kubectl get pods --namespace "${DEPLOY_NAMESPACE}"
+`
+
+// renderDeployConfig returns the deploy config of the service, with comments
+// pointing to what to add next
+func renderDeployConfig(svc *service) []byte {
+
+	var b bytes.Buffer
+	b.WriteString("# Deploy config created by `stim init`.  See the deploy docs for every option:\n")
+	b.WriteString("# https://github.com/PremiereGlobal/stim/blob/master/docs/DEPLOY.md\n")
+	b.WriteString("deployment:\n")
+	fmt.Fprintf(&b, "  script: %s\n", defaultDeployScript)
+
+	b.WriteString("\nglobal:\n  # Global spec (applies to all environments)\n  spec:\n")
+	if len(svc.Tools) > 0 {
+		b.WriteString("    # CLI tools of the deploy, matched to the version of their server if no version is set\n    tools:\n")
+		for _, tool := range svc.Tools {
+			fmt.Fprintf(&b, "      %s: {}\n", tool)
+		}
+	}
+	b.WriteString("    # Environment variables of every instance\n    env: []\n")
+	b.WriteString("    # Vault secrets to set environment variables from, ex.\n")
+	b.WriteString("    #   - secretPath: secret/my-service\n    #     set:\n    #       DB_PASSWORD: db-password\n")
+	b.WriteString("    secrets: []\n")
+
+	b.WriteString("\nenvironments:\n")
+	for i, env := range svc.Environments {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "  - name: %s\n    instances:\n", yamlScalar(env.Name))
+		for _, inst := range env.Instances {
+			fmt.Fprintf(&b, "      - name: %s\n", yamlScalar(inst.Name))
+			b.WriteString("        spec:\n          kubernetes:\n")
+			fmt.Fprintf(&b, "            cluster: %s\n", yamlScalar(inst.Cluster))
+			fmt.Fprintf(&b, "            serviceAccount: %s\n", yamlScalar(inst.ServiceAccount))
+		}
+	}
+
+	return b.Bytes()
+}
+
+// yamlScalar returns the value as a YAML scalar, quoted if needed
+func yamlScalar(value string) string {
+	b, err := yaml.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%q", value)
+	}
+	return strings.TrimSuffix(string(b), "\n")
+}
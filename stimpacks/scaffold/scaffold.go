@@ -0,0 +1,23 @@
+package scaffold
+
+import (
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// Scaffold is the `init` stimpack, which creates the files of a new service
+type Scaffold struct {
+	name string
+	stim *stim.Stim
+}
+
+func New() *Scaffold {
+	return &Scaffold{name: "init"}
+}
+
+func (s *Scaffold) Name() string {
+	return s.name
+}
+
+func (s *Scaffold) BindStim(st *stim.Stim) {
+	s.stim = st
+}
@@ -0,0 +1,29 @@
+package schema
+
+import (
+	"github.com/PremiereGlobal/stim/stim"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func (s *Schema) BindStim(stim *stim.Stim) {
+	s.stim = stim
+}
+
+func (s *Schema) Command(viper *viper.Viper) *cobra.Command {
+
+	var cmd = &cobra.Command{
+		Use:         "schema",
+		Annotations: map[string]string{stim.AnnotationInformational: "true"},
+		Short:       "Output a machine-readable description of stim",
+		Long:        "Output the command tree, flags, config keys and deploy config schema of this stim binary",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return s.Print(cmd.Root(), viper)
+		},
+	}
+
+	cmd.Flags().StringP("format", "f", "json", "Output format (json or yaml)")
+	viper.BindPFlag("schema-format", cmd.Flags().Lookup("format"))
+
+	return cmd
+}
@@ -0,0 +1,170 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/PremiereGlobal/stim/stimpacks/deploy"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+)
+
+// Description is the machine-readable description of the stim binary
+type Description struct {
+	Version      string      `json:"version" yaml:"version"`
+	Commands     *Command    `json:"commands" yaml:"commands"`
+	ConfigKeys   []string    `json:"configKeys" yaml:"configKeys"`
+	DeployConfig *TypeSchema `json:"deployConfig" yaml:"deployConfig"`
+}
+
+// Command describes a command and its subcommands
+type Command struct {
+	Name        string     `json:"name" yaml:"name"`
+	Path        string     `json:"path" yaml:"path"`
+	Usage       string     `json:"usage" yaml:"usage"`
+	Short       string     `json:"short,omitempty" yaml:"short,omitempty"`
+	Long        string     `json:"long,omitempty" yaml:"long,omitempty"`
+	Aliases     []string   `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	Runnable    bool       `json:"runnable" yaml:"runnable"`
+	Flags       []*Flag    `json:"flags,omitempty" yaml:"flags,omitempty"`
+	Subcommands []*Command `json:"subcommands,omitempty" yaml:"subcommands,omitempty"`
+}
+
+// Flag describes a command line flag
+type Flag struct {
+	Name       string `json:"name" yaml:"name"`
+	Shorthand  string `json:"shorthand,omitempty" yaml:"shorthand,omitempty"`
+	Type       string `json:"type" yaml:"type"`
+	Default    string `json:"default" yaml:"default"`
+	Usage      string `json:"usage" yaml:"usage"`
+	Persistent bool   `json:"persistent" yaml:"persistent"`
+}
+
+// TypeSchema is a JSON Schema style description of a config type
+type TypeSchema struct {
+	Type                 string                 `json:"type" yaml:"type"`
+	Properties           map[string]*TypeSchema `json:"properties,omitempty" yaml:"properties,omitempty"`
+	Items                *TypeSchema            `json:"items,omitempty" yaml:"items,omitempty"`
+	AdditionalProperties *TypeSchema            `json:"additionalProperties,omitempty" yaml:"additionalProperties,omitempty"`
+}
+
+// Print outputs the description of the given root command in the configured format
+func (s *Schema) Print(root *cobra.Command, viper *viper.Viper) error {
+
+	keys := viper.AllKeys()
+	sort.Strings(keys)
+
+	description := &Description{
+		Version:      s.stim.GetVersion(),
+		Commands:     describeCommand(root),
+		ConfigKeys:   keys,
+		DeployConfig: describeType(reflect.TypeOf(deploy.Config{})),
+	}
+
+	var out []byte
+	var err error
+	switch format := s.stim.ConfigGetString("schema-format"); format {
+	case "json":
+		out, err = json.MarshalIndent(description, "", "  ")
+	case "yaml":
+		out, err = yaml.Marshal(description)
+	default:
+		return fmt.Errorf("Unsupported schema format '%s', must be one of [json, yaml]", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(strings.TrimSpace(string(out)))
+	return nil
+}
+
+// describeCommand describes the given command and all of its (non-hidden) subcommands
+func describeCommand(cmd *cobra.Command) *Command {
+
+	c := &Command{
+		Name:     cmd.Name(),
+		Path:     cmd.CommandPath(),
+		Usage:    cmd.UseLine(),
+		Short:    cmd.Short,
+		Long:     cmd.Long,
+		Aliases:  cmd.Aliases,
+		Runnable: cmd.Runnable(),
+	}
+
+	// Only flags defined on this command are included, persistent flags are
+	// described on the command that defines them
+	persistent := cmd.PersistentFlags()
+	cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+		c.Flags = append(c.Flags, describeFlag(f, persistent.Lookup(f.Name) != nil))
+	})
+
+	for _, sub := range cmd.Commands() {
+		if sub.Hidden {
+			continue
+		}
+		c.Subcommands = append(c.Subcommands, describeCommand(sub))
+	}
+
+	return c
+}
+
+// describeFlag describes the given flag
+func describeFlag(f *pflag.Flag, persistent bool) *Flag {
+	return &Flag{
+		Name:       f.Name,
+		Shorthand:  f.Shorthand,
+		Type:       f.Value.Type(),
+		Default:    f.DefValue,
+		Usage:      f.Usage,
+		Persistent: persistent,
+	}
+}
+
+// describeType describes the YAML structure of the given type
+func describeType(t reflect.Type) *TypeSchema {
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return describeType(t.Elem())
+	case reflect.Struct:
+		ts := &TypeSchema{Type: "object", Properties: make(map[string]*TypeSchema)}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := strings.Split(field.Tag.Get("yaml"), ",")
+
+			// Inlined structs add their properties to this one
+			if field.Anonymous && len(tag) > 1 && tag[1] == "inline" {
+				for name, property := range describeType(field.Type).Properties {
+					ts.Properties[name] = property
+				}
+				continue
+			}
+
+			name := tag[0]
+			if field.PkgPath != "" || name == "" || name == "-" {
+				continue
+			}
+			ts.Properties[name] = describeType(field.Type)
+		}
+		return ts
+	case reflect.Slice, reflect.Array:
+		return &TypeSchema{Type: "array", Items: describeType(t.Elem())}
+	case reflect.Map:
+		return &TypeSchema{Type: "object", AdditionalProperties: describeType(t.Elem())}
+	case reflect.Bool:
+		return &TypeSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &TypeSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &TypeSchema{Type: "number"}
+	default:
+		return &TypeSchema{Type: "string"}
+	}
+}
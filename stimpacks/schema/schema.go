@@ -0,0 +1,19 @@
+package schema
+
+import (
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+type Schema struct {
+	name string
+	stim *stim.Stim
+}
+
+func New() *Schema {
+	schema := &Schema{name: "schema"}
+	return schema
+}
+
+func (s *Schema) Name() string {
+	return s.name
+}
@@ -0,0 +1,81 @@
+package slack
+
+import (
+	"regexp"
+	"strings"
+
+	slackpkg "github.com/PremiereGlobal/stim/pkg/slack"
+)
+
+// workspaceNameReplacer matches the characters of a team name that are
+// replaced to make a workspace name
+var workspaceNameReplacer = regexp.MustCompile(`[^a-z0-9]+`)
+
+// auth runs the Slack OAuth flow and saves the token of the workspace
+func (s *Slack) auth() error {
+
+	log := s.stim.GetLogger()
+
+	clientID := s.stim.ConfigGetString("slack.oauth.client-id")
+	clientSecret := s.stim.ConfigGetString("slack.oauth.client-secret")
+	if clientID == "" || clientSecret == "" {
+		vault, err := s.stim.NewVault()
+		if err != nil {
+			return err
+		}
+		keys, err := vault.GetSecretKeys("secret/slack/stimbot")
+		if err != nil {
+			return err
+		}
+		if clientID == "" {
+			clientID = keys["client-id"]
+		}
+		if clientSecret == "" {
+			clientSecret = keys["client-secret"]
+		}
+	}
+
+	scopes := s.stim.ConfigGetStringSlice("slack.oauth.scopes")
+	if len(scopes) == 0 {
+		scopes = DEFAULT_OAUTH_SCOPES
+	}
+	port := s.stim.ConfigGetInt("slack.oauth.callback-port")
+	if port == 0 {
+		port = DEFAULT_CALLBACK_PORT
+	}
+
+	token, err := slackpkg.Authorize(&slackpkg.OAuthConfig{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scopes:       scopes,
+		CallbackPort: port,
+		Log:          log,
+	})
+	if err != nil {
+		return err
+	}
+
+	workspace := s.stim.ConfigGetString("slack.workspace")
+	if workspace == "" {
+		workspace = workspaceName(token.TeamName)
+	}
+
+	err = s.stim.SaveSlackToken(workspace, token.Token)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Saved the Slack token of workspace '{}' ({})", workspace, token.TeamName)
+	if s.stim.ConfigGetString("slack.workspace") == "" {
+		log.Info("Use it with `--workspace {}`, or by default with `stim config set slack.workspace {}`", workspace, workspace)
+	}
+
+	return nil
+}
+
+// workspaceName returns the name a workspace is saved as when no name is
+// given, the lowercase team name with dashes (ex. `acme-corp` for `Acme
+// Corp`)
+func workspaceName(teamName string) string {
+	return strings.Trim(workspaceNameReplacer.ReplaceAllString(strings.ToLower(teamName), "-"), "-")
+}
@@ -0,0 +1,13 @@
+package slack
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestWorkspaceName(t *testing.T) {
+	assert.Equal(t, workspaceName("Acme Corp"), "acme-corp")
+	assert.Equal(t, workspaceName("  Acme & Co. (Dev) "), "acme-co-dev")
+	assert.Equal(t, workspaceName("acme"), "acme")
+}
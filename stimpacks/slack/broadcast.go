@@ -0,0 +1,281 @@
+package slack
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	slackpkg "github.com/PremiereGlobal/stim/pkg/slack"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// The statuses of the targets of a broadcast
+const (
+	broadcastDelivered = "delivered"
+	broadcastSkipped   = "already-delivered"
+	broadcastFailed    = "failed"
+	broadcastPending   = "pending"
+)
+
+// broadcastMaxAttempts is the number of times a message is sent to a target
+// that is rate limited before it fails
+const broadcastMaxAttempts = 5
+
+// broadcastState is the progress of a broadcast, saved after every message so
+// that an interrupted broadcast resumes without messaging a target twice
+type broadcastState struct {
+	MessageHash string            `json:"messageHash"`
+	Delivered   map[string]string `json:"delivered"`
+}
+
+// broadcastResult is the delivery of a broadcast to a target, for the report
+type broadcastResult struct {
+	Target    string `json:"target"`
+	Status    string `json:"status"`
+	Timestamp string `json:"timestamp,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// broadcast sends a message to every channel and user of a list, pacing the
+// messages and waiting out rate limits.  The progress is saved to the state
+// file so that running the same command again resumes an interrupted
+// broadcast and retries the failed targets.
+func (s *Slack) broadcast() error {
+
+	log := s.stim.GetLogger()
+
+	channelsFile := s.stim.ConfigGetString("slack-broadcast-channels-file")
+	messageFile := s.stim.ConfigGetString("slack-broadcast-message-file")
+	if channelsFile == "" || messageFile == "" {
+		return stim.UsageError(errors.New("--channels-file and --message-file are required"))
+	}
+	interval, err := time.ParseDuration(s.stim.ConfigGetString("slack-broadcast-interval"))
+	if err != nil || interval < 0 {
+		return stim.UsageError(fmt.Errorf("Invalid --interval '%s', must be a duration (ex. 1s)", s.stim.ConfigGetString("slack-broadcast-interval")))
+	}
+
+	data, err := ioutil.ReadFile(channelsFile)
+	if err != nil {
+		return err
+	}
+	targets := slackpkg.ParseTargets(data)
+	if len(targets) == 0 {
+		return stim.UsageError(fmt.Errorf("No channels or users in %s", channelsFile))
+	}
+	markdown, err := ioutil.ReadFile(messageFile)
+	if err != nil {
+		return err
+	}
+	text := slackpkg.MarkdownToMrkdwn(string(markdown))
+	if text == "" {
+		return stim.UsageError(fmt.Errorf("The message in %s is empty", messageFile))
+	}
+
+	statePath := s.stim.ConfigGetString("slack-broadcast-state-file")
+	if statePath == "" {
+		statePath = channelsFile + ".progress.json"
+	}
+	state, err := readBroadcastState(statePath, messageHash(markdown))
+	if err != nil {
+		return err
+	}
+	if len(state.Delivered) > 0 {
+		log.Info("Resuming the broadcast from {}, {} targets already have the message", statePath, len(state.Delivered))
+	}
+
+	var pending []string
+	for _, target := range targets {
+		if _, ok := state.Delivered[target]; !ok {
+			pending = append(pending, target)
+		}
+	}
+
+	slack := s.stim.Slack()
+	ctx := s.stim.Context()
+	ids, unresolved, err := slack.ResolveTargets(ctx, pending)
+	if err != nil {
+		return err
+	}
+
+	message := &slackpkg.Message{
+		Text:     text,
+		Username: DEFAULT_MESSAGE_USERNAME,
+		IconUrl:  DEFAULT_MESSAGE_ICON_URL,
+	}
+	send := func(target string) (string, error) {
+		if err, ok := unresolved[target]; ok {
+			return "", err
+		}
+		return slack.PostMessageTo(ctx, ids[target], message)
+	}
+	save := func() error {
+		return writeBroadcastState(statePath, state)
+	}
+
+	results, err := s.deliver(targets, state, interval, send, save)
+	if err != nil && ctx.Err() == nil {
+		return err
+	}
+
+	delivered, failed := 0, 0
+	for _, r := range results {
+		switch r.Status {
+		case broadcastDelivered, broadcastSkipped:
+			delivered++
+		case broadcastFailed:
+			failed++
+		}
+	}
+
+	printErr := s.stim.PrintOutput(s.stim.ConfigGetString("slack-broadcast-output"), results, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "TARGET\tSTATUS\tDETAILS")
+		for _, r := range results {
+			details := r.Timestamp
+			if r.Error != "" {
+				details = r.Error
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", r.Target, r.Status, details)
+		}
+	})
+	if printErr != nil {
+		return printErr
+	}
+	log.Info("Delivered to {} of {} targets, {} failed", delivered, len(targets), failed)
+
+	if err != nil {
+		return fmt.Errorf("Broadcast interrupted, run the same command to resume it: %v", err)
+	}
+	if failed > 0 {
+		return fmt.Errorf("The message could not be delivered to %d of %d targets, run the same command to retry them", failed, len(targets))
+	}
+
+	// Nothing is left to resume
+	os.Remove(statePath)
+	return nil
+}
+
+// deliver sends the message to the targets that don't have it yet, in order,
+// waiting interval between messages.  The state is saved after every
+// delivery.  The results are returned along with the error of the
+// cancellation if the command is cancelled, the targets that were not sent
+// to being pending.
+func (s *Slack) deliver(targets []string, state *broadcastState, interval time.Duration, send func(target string) (string, error), save func() error) ([]*broadcastResult, error) {
+
+	log := s.stim.GetLogger()
+
+	var results []*broadcastResult
+	var cancelled error
+	sent := 0
+	for _, target := range targets {
+		if timestamp, ok := state.Delivered[target]; ok {
+			results = append(results, &broadcastResult{Target: target, Status: broadcastSkipped, Timestamp: timestamp})
+			continue
+		}
+		if cancelled == nil && sent > 0 {
+			cancelled = s.stim.Sleep(interval)
+		}
+		if cancelled != nil {
+			results = append(results, &broadcastResult{Target: target, Status: broadcastPending})
+			continue
+		}
+
+		timestamp, err := s.sendWithRetries(target, send)
+		sent++
+		if err != nil && s.stim.Context().Err() != nil {
+			cancelled = err
+			results = append(results, &broadcastResult{Target: target, Status: broadcastPending})
+			continue
+		}
+		if err != nil {
+			log.Warn("Unable to send the message to {}: {}", target, err)
+			results = append(results, &broadcastResult{Target: target, Status: broadcastFailed, Error: err.Error()})
+			continue
+		}
+
+		log.Debug("Sent the message to {}", target)
+		results = append(results, &broadcastResult{Target: target, Status: broadcastDelivered, Timestamp: timestamp})
+		state.Delivered[target] = timestamp
+		err = save()
+		if err != nil {
+			return results, fmt.Errorf("Unable to save the progress of the broadcast: %v", err)
+		}
+	}
+
+	return results, cancelled
+}
+
+// sendWithRetries sends the message to a target, waiting as long as Slack
+// asks when rate limited
+func (s *Slack) sendWithRetries(target string, send func(target string) (string, error)) (string, error) {
+
+	for attempt := 1; ; attempt++ {
+		timestamp, err := send(target)
+		retry, ok := slackpkg.RetryAfter(err)
+		if !ok || attempt == broadcastMaxAttempts {
+			return timestamp, err
+		}
+
+		s.stim.GetLogger().Warn("Rate limited by Slack, sending to {} again in {}", target, retry)
+		err = s.stim.Sleep(retry)
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// messageHash identifies the message of a broadcast in its state file
+func messageHash(message []byte) string {
+	sum := sha256.Sum256(message)
+	return hex.EncodeToString(sum[:])
+}
+
+// readBroadcastState reads the progress of a broadcast of the message, or
+// returns an empty state if there is none
+func readBroadcastState(path string, hash string) (*broadcastState, error) {
+
+	state := &broadcastState{MessageHash: hash, Delivered: make(map[string]string)}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	saved := &broadcastState{}
+	err = json.Unmarshal(data, saved)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid broadcast progress file %s: %v", path, err)
+	}
+	if saved.MessageHash != hash {
+		return nil, stim.UsageError(fmt.Errorf("%s is the progress of a broadcast of another message, delete it (or use --state-file) to start a new broadcast", path))
+	}
+	if saved.Delivered != nil {
+		state.Delivered = saved.Delivered
+	}
+
+	return state, nil
+}
+
+// writeBroadcastState saves the progress of a broadcast, replacing the file
+// in one step so that an interruption doesn't leave it truncated
+func writeBroadcastState(path string, state *broadcastState) error {
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,84 @@
+package slack
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/clock"
+	"github.com/PremiereGlobal/stim/stim"
+	slackapi "github.com/nlopes/slack"
+	"gotest.tools/assert"
+)
+
+func TestDeliver(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	s := &Slack{stim: stim.New()}
+	s.stim.SetClock(fake)
+
+	state := &broadcastState{Delivered: map[string]string{"#done": "1.0"}}
+	attempts := make(map[string]int)
+	send := func(target string) (string, error) {
+		attempts[target]++
+		switch {
+		case target == "#busy" && attempts[target] < 3:
+			return "", &slackapi.RateLimitedError{RetryAfter: 30 * time.Second}
+		case target == "#archived":
+			return "", errors.New("is_archived")
+		}
+		return "2.0", nil
+	}
+	saves := 0
+	save := func() error {
+		saves++
+		return nil
+	}
+
+	results, err := s.deliver([]string{"#done", "#busy", "#archived", "@alice"}, state, time.Second, send, save)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, results, []*broadcastResult{
+		{Target: "#done", Status: broadcastSkipped, Timestamp: "1.0"},
+		{Target: "#busy", Status: broadcastDelivered, Timestamp: "2.0"},
+		{Target: "#archived", Status: broadcastFailed, Error: "is_archived"},
+		{Target: "@alice", Status: broadcastDelivered, Timestamp: "2.0"},
+	})
+	assert.Equal(t, attempts["#busy"], 3)
+	assert.Equal(t, saves, 2)
+	assert.DeepEqual(t, state.Delivered, map[string]string{"#done": "1.0", "#busy": "2.0", "@alice": "2.0"})
+
+	// Two rate limit waits and a pause before each of the other two messages
+	assert.Equal(t, fake.Now().Sub(start), time.Minute+2*time.Second)
+
+	// The targets left when the command is cancelled are pending
+	s.stim.Cancel("Interrupted")
+	results, err = s.deliver([]string{"#archived", "#new"}, state, time.Second, send, save)
+	assert.Assert(t, err != nil)
+	assert.Equal(t, results[0].Status, broadcastPending)
+	assert.Equal(t, results[1].Status, broadcastPending)
+}
+
+func TestBroadcastState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stim-slack")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "channels.txt.progress.json")
+
+	hash := messageHash([]byte("# Maintenance"))
+	state, err := readBroadcastState(path, hash)
+	assert.NilError(t, err)
+	assert.Equal(t, len(state.Delivered), 0)
+
+	state.Delivered["#general"] = "1.0"
+	assert.NilError(t, writeBroadcastState(path, state))
+	saved, err := readBroadcastState(path, hash)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, saved, state)
+
+	// The progress of another message isn't resumed
+	_, err = readBroadcastState(path, messageHash([]byte("# Outage")))
+	assert.Equal(t, stim.ExitCode(err), stim.ExitCodeUsage)
+}
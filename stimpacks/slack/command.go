@@ -13,18 +13,22 @@ func (s *Slack) BindStim(stim *stim.Stim) {
 func (s *Slack) Command(viper *viper.Viper) *cobra.Command {
 
 	var cmd = &cobra.Command{
-		Use:   "slack",
-		Short: "Interact with Slack",
-		Long:  `Send/Recieve messages, etc. to/from Slack`,
-		Run: func(cmd *cobra.Command, args []string) {
-			s.postMessage()
+		Use:         "slack",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Interact with Slack",
+		Long:        `Send/Recieve messages, etc. to/from Slack`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return s.postMessage()
 		},
 	}
 
+	cmd.PersistentFlags().StringP("workspace", "w", "", "Workspace (from `stim slack auth`) to use instead of the stimbot token in Vault")
+	viper.BindPFlag("slack.workspace", cmd.PersistentFlags().Lookup("workspace"))
+
 	cmd.Flags().StringP("channel", "c", "", "Required. The channel name to send the message to")
 	viper.BindPFlag("slack.channel", cmd.Flags().Lookup("channel"))
 
-	cmd.Flags().StringP("message", "m", "", "Required. The message to send")
+	cmd.Flags().StringP("message", "m", "", "The message to send. Required unless --file contains blocks or attachments")
 	viper.BindPFlag("slack.message", cmd.Flags().Lookup("message"))
 
 	cmd.Flags().StringP("username", "u", "", "Username for the message to appear as")
@@ -33,5 +37,125 @@ func (s *Slack) Command(viper *viper.Viper) *cobra.Command {
 	cmd.Flags().StringP("icon-url", "i", "", "Url to use as the icon for the message")
 	viper.BindPFlag("slack.icon-url", cmd.Flags().Lookup("icon-url"))
 
+	cmd.Flags().StringP("file", "f", "", "JSON or YAML file containing a rich message payload (text, blocks and/or attachments)")
+	viper.BindPFlag("slack.file", cmd.Flags().Lookup("file"))
+
+	cmd.Flags().String("color", "", "Send the message as an attachment with this color (good, warning, danger or a hex color)")
+	viper.BindPFlag("slack.color", cmd.Flags().Lookup("color"))
+
+	cmd.Flags().StringP("thread-ts", "t", "", "Timestamp of the message to reply to in a thread")
+	viper.BindPFlag("slack.thread-ts", cmd.Flags().Lookup("thread-ts"))
+
+	cmd.Flags().Bool("broadcast", false, "Also send a thread reply to the channel")
+	viper.BindPFlag("slack.broadcast", cmd.Flags().Lookup("broadcast"))
+
+	cmd.Flags().String("update-ts", "", "Timestamp of an existing message to update instead of sending a new one")
+	viper.BindPFlag("slack.update-ts", cmd.Flags().Lookup("update-ts"))
+
+	var authCmd = &cobra.Command{
+		Use:   "auth",
+		Short: "Authorize stim in a Slack workspace",
+		Long:  "Approve the stim Slack app in the browser and save its token for the workspace (in the credential store if `credentials.store` is set).  The token is saved under --workspace, or the team name if not given",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return s.auth()
+		},
+	}
+	s.stim.BindCommand(authCmd, cmd)
+
+	var topicCmd = &cobra.Command{
+		Use:   "topic",
+		Short: "Manage channel topics",
+		Long:  "Get and set channel topics and the captain mention at the end of a topic",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+	s.stim.BindCommand(topicCmd, cmd)
+
+	var topicGetCmd = &cobra.Command{
+		Use:   "get <channel>",
+		Short: "Print a channel topic",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return s.getTopic(args[0])
+		},
+	}
+	s.stim.BindCommand(topicGetCmd, topicCmd)
+
+	var topicSetCmd = &cobra.Command{
+		Use:         "set <channel> <topic>",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Set a channel topic",
+		Long:        "Set the text of a channel topic.  The captain mention (if any) is kept",
+		Args:        cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return s.setTopic(args[0], args[1])
+		},
+	}
+	s.stim.BindCommand(topicSetCmd, topicCmd)
+
+	topicSetCmd.Flags().Bool("clear-captain", false, "Remove the captain mention from the topic")
+	viper.BindPFlag("slack-topic-clear-captain", topicSetCmd.Flags().Lookup("clear-captain"))
+
+	var topicCaptainCmd = &cobra.Command{
+		Use:         "captain <channel> [user]",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Set the captain mentioned in a channel topic",
+		Long:        "Set the captain mentioned at the end of a channel topic to the given user (email, user name or display name), or rotate to the next user in the rotation if no user is given",
+		Args:        cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			user := ""
+			if len(args) > 1 {
+				user = args[1]
+			}
+			return s.setCaptain(args[0], user)
+		},
+	}
+	s.stim.BindCommand(topicCaptainCmd, topicCmd)
+
+	topicCaptainCmd.Flags().StringSlice("rotation", nil, "Users to rotate the captain through, in order")
+	viper.BindPFlag("slack.captain-rotation", topicCaptainCmd.Flags().Lookup("rotation"))
+	topicCaptainCmd.Flags().String("emoji", "", "Emoji to show before the captain mention (Default: "+DEFAULT_CAPTAIN_EMOJI+")")
+	viper.BindPFlag("slack.captain-emoji", topicCaptainCmd.Flags().Lookup("emoji"))
+
+	var broadcastCmd = &cobra.Command{
+		Use:         "broadcast",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Send a message to many channels and users",
+		Long:        "Send a Markdown message to every channel and user of a list (ex. for incident and maintenance announcements), waiting out Slack rate limits.  The progress is saved so that running the same command again resumes an interrupted broadcast and retries the failed targets.  Prints a report of the delivered and failed targets",
+		Args:        cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return s.broadcast()
+		},
+	}
+	s.stim.BindCommand(broadcastCmd, cmd)
+
+	broadcastCmd.Flags().String("channels-file", "", "Required. File with the channels (#name), users (@name or email) and IDs to send to, one per line")
+	viper.BindPFlag("slack-broadcast-channels-file", broadcastCmd.Flags().Lookup("channels-file"))
+	broadcastCmd.Flags().String("message-file", "", "Required. Markdown file with the message to send")
+	viper.BindPFlag("slack-broadcast-message-file", broadcastCmd.Flags().Lookup("message-file"))
+	broadcastCmd.Flags().String("state-file", "", "File to save the progress to (Default: <channels file>.progress.json)")
+	viper.BindPFlag("slack-broadcast-state-file", broadcastCmd.Flags().Lookup("state-file"))
+	broadcastCmd.Flags().String("interval", "1s", "Time to wait between messages")
+	viper.BindPFlag("slack-broadcast-interval", broadcastCmd.Flags().Lookup("interval"))
+	broadcastCmd.Flags().StringP("output", "o", stim.OutputTable, "Output format of the report (table|json)")
+	viper.BindPFlag("slack-broadcast-output", broadcastCmd.Flags().Lookup("output"))
+
+	var serveCmd = &cobra.Command{
+		Use:         "serve",
+		Annotations: map[string]string{stim.AnnotationNoUpdateCheck: "true"},
+		Short:       "Serve Slack link previews",
+		Long:        "Serve the Slack Events API endpoint (/slack/events) that previews links to Vault secrets and deploy history in messages.  When `vault.break-glass.channel` is set, it also approves and denies break-glass access requests from their reactions",
+		Args:        cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return s.serve()
+		},
+	}
+	s.stim.BindCommand(serveCmd, cmd)
+
+	serveCmd.Flags().String("listen", "", "Address to listen on (Default: "+DEFAULT_SERVE_LISTEN+")")
+	viper.BindPFlag("slack.serve.listen", serveCmd.Flags().Lookup("listen"))
+
 	return cmd
 }
@@ -2,11 +2,15 @@ package slack
 
 import (
 	"errors"
+	"fmt"
+	"io/ioutil"
 
 	slackpkg "github.com/PremiereGlobal/stim/pkg/slack"
+	"github.com/PremiereGlobal/stim/stim"
+	slackapi "github.com/nlopes/slack"
 )
 
-func (s *Slack) postMessage() {
+func (s *Slack) postMessage() error {
 
 	var err error
 
@@ -16,50 +20,98 @@ func (s *Slack) postMessage() {
 	// Prompt for the channel name (if not provided)
 	channelName := s.stim.ConfigGetString("slack.channel")
 	if channelName == "" && s.stim.IsAutomated() {
-		s.stim.Fatal(errors.New("Slack channel not specified"))
+		return stim.UsageError(errors.New("Slack channel not specified"))
 	} else if channelName == "" {
 
 		// Get the channel list
 		channels, err := slack.GetChannels()
-		s.stim.Fatal(err)
+		if err != nil {
+			return err
+		}
 
 		// Prompt for channel
 		channelName, err = s.stim.PromptSearchList("Choose Channel:", channels)
-		s.stim.Fatal(err)
+		if err != nil {
+			return err
+		}
 
 	}
 
+	// Load the rich message payload (if provided)
+	payload := &slackpkg.Payload{}
+	payloadFile := s.stim.ConfigGetString("slack.file")
+	if payloadFile != "" {
+		data, err := ioutil.ReadFile(payloadFile)
+		if err != nil {
+			return err
+		}
+
+		payload, err = slackpkg.ParsePayload(data)
+		if err != nil {
+			return fmt.Errorf("Unable to parse Slack message file %s: %v", payloadFile, err)
+		}
+	}
+
 	// Prompt for the message (if not provided)
+	// Text is optional when the payload has blocks or attachments
 	text := s.stim.ConfigGetString("slack.message")
-	if text == "" && s.stim.IsAutomated() {
-		s.stim.Fatal(errors.New("Slack message not specified"))
-	} else if text == "" {
+	if text == "" {
+		text = payload.Text
+	}
+	hasContent := len(payload.Blocks.BlockSet) > 0 || len(payload.Attachments) > 0
+	if text == "" && !hasContent {
+		if s.stim.IsAutomated() {
+			return stim.UsageError(errors.New("Slack message not specified"))
+		}
 		text, err = s.stim.PromptString("Message", "")
-		s.stim.Fatal(err)
+		if err != nil {
+			return err
+		}
 	}
 
 	username := s.stim.ConfigGetString("slack.username")
 	if username == "" && !s.stim.IsAutomated() {
 		username, err = s.stim.PromptString("Display Name", DEFAULT_MESSAGE_USERNAME)
-		s.stim.Fatal(err)
+		if err != nil {
+			return err
+		}
 	}
 
 	iconUrl := s.stim.ConfigGetString("slack.icon-url")
 	if iconUrl == "" && !s.stim.IsAutomated() {
 		iconUrl, err = s.stim.PromptString("Icon URL", DEFAULT_MESSAGE_ICON_URL)
-		s.stim.Fatal(err)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Construct the message
 	message := &slackpkg.Message{
-		Channel:  channelName,
-		Username: username,
-		Text:     text,
-		IconUrl:  iconUrl,
+		Channel:     channelName,
+		Username:    username,
+		Text:        text,
+		IconUrl:     iconUrl,
+		Blocks:      payload.Blocks.BlockSet,
+		Attachments: payload.Attachments,
+		ThreadTS:    s.stim.ConfigGetString("slack.thread-ts"),
+		Broadcast:   s.stim.ConfigGetBool("slack.broadcast"),
+		UpdateTS:    s.stim.ConfigGetString("slack.update-ts"),
+	}
+
+	// A color sends the text as a colored attachment
+	color := s.stim.ConfigGetString("slack.color")
+	if color != "" {
+		message.Attachments = append([]slackapi.Attachment{{Color: color, Text: text, Fallback: text}}, message.Attachments...)
+		message.Text = ""
+	}
+
+	// Post the message and output the timestamp so it can be threaded/updated
+	timestamp, err := slack.PostMessage(message)
+	if err != nil {
+		return err
 	}
 
-	// Post the message
-	err = slack.PostMessage(message)
-	s.stim.Fatal(err)
+	fmt.Println(timestamp)
 
+	return nil
 }
@@ -7,8 +7,15 @@ import (
 const (
 	DEFAULT_MESSAGE_USERNAME = "stim"
 	DEFAULT_MESSAGE_ICON_URL = "https://vignette.wikia.nocookie.net/fallout/images/7/7e/FoS_stimpak.png/revision/latest"
+	DEFAULT_CAPTAIN_EMOJI    = ":ship:"
+	DEFAULT_SERVE_LISTEN     = ":8080"
+	DEFAULT_CALLBACK_PORT    = 8251
 )
 
+// DEFAULT_OAUTH_SCOPES are the scopes that `stim slack auth` requests, enough
+// for posting messages, managing topics and looking up users
+var DEFAULT_OAUTH_SCOPES = []string{"channels:read", "channels:write", "chat:write:user", "reactions:write", "users:read", "users:read.email"}
+
 type Slack struct {
 	name string
 	stim *stim.Stim
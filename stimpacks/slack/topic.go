@@ -0,0 +1,107 @@
+package slack
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	slackpkg "github.com/PremiereGlobal/stim/pkg/slack"
+)
+
+// channelName returns the channel name without a leading `#`
+func channelName(name string) string {
+	return strings.TrimPrefix(name, "#")
+}
+
+// getTopic prints the topic of a channel
+func (s *Slack) getTopic(channel string) error {
+
+	topic, err := s.stim.Slack().GetChannelTopic(channelName(channel))
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(topic)
+	return nil
+}
+
+// setTopic sets the text of a channel topic, keeping the captain mention
+// unless --clear-captain is set
+func (s *Slack) setTopic(channel string, text string) error {
+
+	slack := s.stim.Slack()
+	channel = channelName(channel)
+
+	current, err := slack.GetChannelTopic(channel)
+	if err != nil {
+		return err
+	}
+
+	topic := slackpkg.ParseTopic(current)
+	topic.Text = text
+	if s.stim.ConfigGetBool("slack-topic-clear-captain") {
+		topic.Captain = ""
+	}
+
+	return s.updateTopic(slack, channel, current, topic)
+}
+
+// setCaptain sets the captain mention of a channel topic to the given user,
+// or to the next user in the rotation if no user is given
+func (s *Slack) setCaptain(channel string, user string) error {
+
+	slack := s.stim.Slack()
+	channel = channelName(channel)
+
+	current, err := slack.GetChannelTopic(channel)
+	if err != nil {
+		return err
+	}
+	topic := slackpkg.ParseTopic(current)
+
+	var captain string
+	if user != "" {
+		captain, err = slack.GetUserID(user)
+		if err != nil {
+			return err
+		}
+	} else {
+		users := s.stim.ConfigGetStringSlice("slack.captain-rotation")
+		if len(users) == 0 {
+			return errors.New("No captain given and no rotation set, use --rotation or set slack.captain-rotation")
+		}
+
+		rotation, err := slack.GetUserIDs(users)
+		if err != nil {
+			return err
+		}
+
+		captain, err = slackpkg.NextCaptain(rotation, topic.Captain)
+		if err != nil {
+			return err
+		}
+	}
+
+	topic.Captain = captain
+	if emoji := s.stim.ConfigGetString("slack.captain-emoji"); emoji != "" {
+		topic.Emoji = emoji
+	} else if topic.Emoji == "" {
+		topic.Emoji = DEFAULT_CAPTAIN_EMOJI
+	}
+
+	return s.updateTopic(slack, channel, current, topic)
+}
+
+// updateTopic sets the channel topic if it changed and prints it
+func (s *Slack) updateTopic(slack *slackpkg.Slack, channel string, current string, topic *slackpkg.Topic) error {
+
+	if topic.String() != current {
+		err := slack.SetChannelTopic(channel, topic.String())
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Println(topic.String())
+	return nil
+}
@@ -0,0 +1,274 @@
+package slack
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PremiereGlobal/stim/stim"
+	vaultstimpack "github.com/PremiereGlobal/stim/stimpacks/vault"
+	"github.com/nlopes/slack"
+)
+
+// unfurlEventsPath is the path of the Slack Events API request URL
+const unfurlEventsPath = "/slack/events"
+
+// vaultUIPath is the path of secrets in the Vault UI
+const vaultUIPath = "/ui/vault/secrets/"
+
+// serve runs the Slack Events API endpoint that previews the links to Vault
+// secrets and deploy history in messages, and decides break-glass access
+// requests from their reactions when `vault.break-glass.channel` is set
+func (s *Slack) serve() error {
+
+	log := s.stim.GetLogger()
+
+	vault, err := s.stim.NewVault()
+	if err != nil {
+		return err
+	}
+	vault.StartTokenRenewer()
+	defer vault.StopTokenRenewer()
+
+	signingSecret, err := vault.GetSecretKey("secret/slack/stimbot", "signing-secret")
+	if err != nil {
+		return stim.ConfigError(fmt.Errorf("Unable to read the Slack signing secret (`signing-secret` of secret/slack/stimbot): %v", err))
+	}
+
+	slackClient := s.stim.Slack()
+	handler, err := slackClient.NewUnfurlHandler(signingSecret, s.unfurl)
+	if err != nil {
+		return err
+	}
+
+	if s.stim.ConfigGetString("vault.break-glass.channel") != "" {
+		v := vaultstimpack.New()
+		v.BindStim(s.stim)
+		onReaction, err := v.AccessReactionHandler(vault, slackClient)
+		if err != nil {
+			return err
+		}
+		handler.OnReactionAdded(onReaction)
+		log.Info("Deciding break-glass access requests in Slack channel {}", s.stim.ConfigGetString("vault.break-glass.channel"))
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(unfurlEventsPath, handler)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	listen := s.stim.ConfigGetString("slack.serve.listen")
+	if listen == "" {
+		listen = DEFAULT_SERVE_LISTEN
+	}
+	log.Info("Serving Slack events on {}{}", listen, unfurlEventsPath)
+	server := &http.Server{
+		Addr:         listen,
+		Handler:      mux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+	return server.ListenAndServe()
+}
+
+// unfurl returns the preview of a link to a Vault secret or a deploy, or nil
+// for other links
+func (s *Slack) unfurl(link string) (*slack.Attachment, error) {
+
+	if path, list, ok := parseVaultLink(s.stim.ConfigGetString("vault-address"), link); ok {
+		if !hasPathPrefix(path, s.stim.ConfigGetStringSlice("slack.unfurl.vault-prefixes")) {
+			return nil, nil
+		}
+		return s.unfurlVaultPath(link, path, list)
+	}
+
+	if id, ok := parseDeployLink(s.stim.ConfigGetString("slack.unfurl.history-url"), link); ok {
+		return s.unfurlDeploy(link, id)
+	}
+
+	return nil, nil
+}
+
+// unfurlVaultPath previews a Vault secret with the names of its keys (never
+// their values) and its version, or the secrets under a Vault path
+func (s *Slack) unfurlVaultPath(link string, path string, list bool) (*slack.Attachment, error) {
+
+	vault, err := s.stim.NewVault()
+	if err != nil {
+		return nil, err
+	}
+	attachment := &slack.Attachment{
+		Title:      path,
+		TitleLink:  link,
+		Footer:     "Vault",
+		MarkdownIn: []string{"fields"},
+	}
+
+	if list {
+		secrets, err := vault.ListSecrets(path)
+		if err != nil {
+			return nil, err
+		}
+		attachment.Fields = append(attachment.Fields, slack.AttachmentField{
+			Title: fmt.Sprintf("Secrets (%d)", len(secrets)),
+			Value: codeList(secrets),
+		})
+		return attachment, nil
+	}
+
+	keys, err := vault.GetSecretKeyNames(path, 0)
+	if err != nil {
+		return nil, err
+	}
+	attachment.Fields = append(attachment.Fields, slack.AttachmentField{
+		Title: fmt.Sprintf("Keys (%d)", len(keys)),
+		Value: codeList(keys),
+	})
+
+	// Only KV v2 secrets have versions
+	if version, err := vault.SecretVersion(path, 0); err == nil {
+		attachment.Fields = append(attachment.Fields, slack.AttachmentField{
+			Title: "Version",
+			Value: fmt.Sprintf("%d", version),
+			Short: true,
+		})
+	}
+
+	return attachment, nil
+}
+
+// unfurlDeploy previews a deploy from its audit event in Vault
+func (s *Slack) unfurlDeploy(link string, id string) (*slack.Attachment, error) {
+
+	auditPath := strings.TrimSuffix(s.stim.ConfigGetString("audit.vault-path"), "/")
+	if auditPath == "" {
+		return nil, errors.New("Deploy history links need `audit.vault-path` to be set")
+	}
+
+	vault, err := s.stim.NewVault()
+	if err != nil {
+		return nil, err
+	}
+	event, err := vault.GetSecretKeys(auditPath + "/" + id)
+	if err != nil {
+		return nil, err
+	}
+
+	color := "good"
+	if event["result"] != "success" {
+		color = "danger"
+	}
+
+	attachment := &slack.Attachment{
+		Title:     strings.TrimSpace(event["command"] + " " + event["args"]),
+		TitleLink: link,
+		Color:     color,
+		Footer:    "stim " + event["version"],
+		Fields: []slack.AttachmentField{
+			{Title: "User", Value: event["user"], Short: true},
+			{Title: "Result", Value: event["result"], Short: true},
+			{Title: "Host", Value: event["host"], Short: true},
+			{Title: "Duration", Value: event["duration-seconds"] + "s", Short: true},
+		},
+	}
+	if t, err := time.Parse(time.RFC3339Nano, event["time"]); err == nil {
+		attachment.Ts = json.Number(fmt.Sprintf("%d", t.Unix()))
+	}
+	if event["error"] != "" {
+		attachment.Fields = append(attachment.Fields, slack.AttachmentField{Title: "Error", Value: event["error"]})
+	}
+
+	return attachment, nil
+}
+
+// parseVaultLink returns the secret path of a link to the Vault UI (ex.
+// `https://vault/ui/vault/secrets/secret/show/app/db` is `secret/app/db`) and
+// whether it lists the secrets under the path
+func parseVaultLink(vaultAddress string, link string) (string, bool, bool) {
+
+	prefix := strings.TrimSuffix(vaultAddress, "/") + vaultUIPath
+	if vaultAddress == "" || !strings.HasPrefix(link, prefix) {
+		return "", false, false
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", false, false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(u.Path, strings.TrimSuffix(vaultUIPath, "/")+"/"), "/", 3)
+	if len(parts) < 2 {
+		return "", false, false
+	}
+
+	mount, err := url.PathUnescape(parts[0])
+	if err != nil {
+		return "", false, false
+	}
+	secretPath := strings.Trim(mount, "/")
+	if len(parts) == 3 {
+		secretPath = path.Clean(secretPath + "/" + strings.Trim(parts[2], "/"))
+	}
+
+	switch {
+	case parts[1] == "show" && len(parts) == 3:
+		return secretPath, false, true
+	case parts[1] == "list":
+		return secretPath, true, true
+	}
+	return "", false, false
+}
+
+// parseDeployLink returns the audit event ID of a deploy history link, which
+// is the deploy history URL followed by the ID.  IDs that would read another
+// Vault path than an event of the deploy history are rejected.
+func parseDeployLink(historyURL string, link string) (string, bool) {
+
+	prefix := strings.TrimSuffix(historyURL, "/") + "/"
+	if historyURL == "" || !strings.HasPrefix(link, prefix) {
+		return "", false
+	}
+
+	id := strings.TrimPrefix(link, prefix)
+	if i := strings.IndexAny(id, "?#"); i >= 0 {
+		id = id[:i]
+	}
+	id, err := url.PathUnescape(strings.Trim(id, "/"))
+	if err != nil || id == "" || id == "." || id == ".." || strings.ContainsAny(id, "/\\") {
+		return "", false
+	}
+
+	return id, true
+}
+
+// hasPathPrefix returns true if the path is one of the prefixes or under one
+// of them, once `.` and `..` are resolved
+func hasPathPrefix(secretPath string, prefixes []string) bool {
+	secretPath = path.Clean(secretPath)
+	for _, prefix := range prefixes {
+		prefix = strings.Trim(prefix, "/")
+		if secretPath == prefix || strings.HasPrefix(secretPath, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// codeList formats the names as inline code for Slack
+func codeList(names []string) string {
+	if len(names) == 0 {
+		return "_none_"
+	}
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = "`" + name + "`"
+	}
+	sort.Strings(quoted)
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,74 @@
+package slack
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestParseVaultLink(t *testing.T) {
+	tests := []struct {
+		link string
+		path string
+		list bool
+		ok   bool
+	}{
+		{"https://vault.example.com/ui/vault/secrets/secret/show/app/db", "secret/app/db", false, true},
+		{"https://vault.example.com/ui/vault/secrets/secret/show/app/db?version=2", "secret/app/db", false, true},
+		{"https://vault.example.com/ui/vault/secrets/secret/list/app/", "secret/app", true, true},
+		{"https://vault.example.com/ui/vault/secrets/secret/list", "secret", true, true},
+		{"https://vault.example.com/ui/vault/secrets/secret/show", "", false, false},
+		{"https://vault.example.com/ui/vault/secrets/secret/edit/app/db", "", false, false},
+		{"https://vault.example.com/ui/vault/access", "", false, false},
+		{"https://other.example.com/ui/vault/secrets/secret/show/app/db", "", false, false},
+		{"https://vault.example.com/ui/vault/secrets/secret/show/app/../private/db", "secret/private/db", false, true},
+	}
+
+	for _, test := range tests {
+		path, list, ok := parseVaultLink("https://vault.example.com/", test.link)
+		assert.Equal(t, path, test.path, test.link)
+		assert.Equal(t, list, test.list, test.link)
+		assert.Equal(t, ok, test.ok, test.link)
+	}
+
+	_, _, ok := parseVaultLink("", "https://vault.example.com/ui/vault/secrets/secret/show/app/db")
+	assert.Assert(t, !ok)
+}
+
+func TestParseDeployLink(t *testing.T) {
+	history := "https://stim.example.com/deploys"
+	tests := []struct {
+		link string
+		id   string
+		ok   bool
+	}{
+		{"https://stim.example.com/deploys/20200102T030405.000000000Z-jdoe", "20200102T030405.000000000Z-jdoe", true},
+		{"https://stim.example.com/deploys/20200102T030405.000000000Z-jdoe/?tab=log#top", "20200102T030405.000000000Z-jdoe", true},
+		{"https://stim.example.com/deploys/", "", false},
+		{"https://stim.example.com/deploys/a/b", "", false},
+		{"https://stim.example.com/deploysx/a", "", false},
+		{"https://stim.example.com/deploys/..", "", false},
+		{"https://stim.example.com/deploys/./", "", false},
+		{"https://stim.example.com/deploys/%2e%2e", "", false},
+		{"https://stim.example.com/deploys/a%2Fb", "", false},
+	}
+
+	for _, test := range tests {
+		id, ok := parseDeployLink(history, test.link)
+		assert.Equal(t, id, test.id, test.link)
+		assert.Equal(t, ok, test.ok, test.link)
+	}
+
+	_, ok := parseDeployLink("", "https://stim.example.com/deploys/a")
+	assert.Assert(t, !ok)
+}
+
+func TestHasPathPrefix(t *testing.T) {
+	prefixes := []string{"secret/apps/", "secret/shared"}
+	assert.Assert(t, hasPathPrefix("secret/apps/db", prefixes))
+	assert.Assert(t, hasPathPrefix("secret/shared", prefixes))
+	assert.Assert(t, !hasPathPrefix("secret/appsx/db", prefixes))
+	assert.Assert(t, !hasPathPrefix("secret/apps/../private/db", prefixes))
+	assert.Assert(t, hasPathPrefix("secret/shared/./db", prefixes))
+	assert.Assert(t, !hasPathPrefix("secret/apps/db", nil))
+}
@@ -0,0 +1,40 @@
+package ssh
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func (s *Ssh) Command(viper *viper.Viper) *cobra.Command {
+
+	var cmd = &cobra.Command{
+		Use:   "ssh",
+		Short: "Used to config SSH access to hosts",
+		Long:  "Used to config SSH access to hosts",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	var setupCmd = &cobra.Command{
+		Use:   "setup",
+		Short: "Write SSH config and known_hosts from the Vault host inventory",
+		Long:  "Renders a Host entry for every host in the Vault host inventory into a stim-managed block of the SSH config and writes their host keys to a stim-managed known_hosts file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return s.setup()
+		},
+	}
+
+	setupCmd.Flags().StringSliceP("environment", "e", nil, "Optional. Only set up hosts in these environment(s)")
+	viper.BindPFlag("ssh-setup-environments", setupCmd.Flags().Lookup("environment"))
+	setupCmd.Flags().String("ssh-config", "", "Optional. Path of the SSH config (defaults to ~/.ssh/config)")
+	viper.BindPFlag("ssh-setup-config", setupCmd.Flags().Lookup("ssh-config"))
+	setupCmd.Flags().String("known-hosts", "", "Optional. Path of the stim-managed known_hosts file (defaults to ~/.ssh/known_hosts_stim)")
+	viper.BindPFlag("ssh-setup-known-hosts", setupCmd.Flags().Lookup("known-hosts"))
+	setupCmd.Flags().Bool("dry-run", false, "Optional. Print the SSH config block and known_hosts instead of writing them")
+	viper.BindPFlag("ssh-setup-dry-run", setupCmd.Flags().Lookup("dry-run"))
+
+	s.stim.BindCommand(setupCmd, cmd)
+
+	return cmd
+}
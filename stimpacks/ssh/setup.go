@@ -0,0 +1,270 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/PremiereGlobal/stim/pkg/notify"
+	"github.com/mitchellh/go-homedir"
+)
+
+// defaultInventoryPath is the Vault path of the host inventory, overridden
+// with the ssh.inventory-path config key.  Hosts are stored as
+// `<path>/<environment>/<host>`.
+const defaultInventoryPath = "secret/ssh/hosts"
+
+// The markers around the stim-managed block of the SSH config
+const (
+	blockBegin = "# BEGIN stim managed hosts (stim ssh setup)"
+	blockEnd   = "# END stim managed hosts"
+)
+
+// host is a host in the Vault inventory.  The host secret keys are
+// `hostname`, `user`, `port`, `proxy-jump`, `identity-file` and `host-keys`
+// (one `<type> <base64 key>` per line).
+type host struct {
+	name        string
+	environment string
+	hostname    string
+	user        string
+	port        string
+	proxyJump   string
+	identity    string
+	hostKeys    []string
+}
+
+// setup reads the host inventory from Vault and writes the SSH config block
+// and known_hosts file
+func (s *Ssh) setup() error {
+
+	log := s.stim.GetLogger()
+
+	hosts, err := s.getHosts(s.stim.ConfigGetStringSlice("ssh-setup-environments"))
+	if err != nil {
+		return err
+	}
+	if len(hosts) == 0 {
+		return errors.New("No hosts found in the Vault host inventory")
+	}
+
+	home, err := homedir.Dir()
+	if err != nil {
+		return err
+	}
+	configPath := s.stim.ConfigGetString("ssh-setup-config")
+	if configPath == "" {
+		configPath = filepath.Join(home, ".ssh", "config")
+	}
+	knownHostsPath := s.stim.ConfigGetString("ssh-setup-known-hosts")
+	if knownHostsPath == "" {
+		knownHostsPath = filepath.Join(home, ".ssh", "known_hosts_stim")
+	}
+
+	block := renderConfigBlock(hosts, knownHostsPath)
+	knownHosts := renderKnownHosts(hosts)
+
+	if s.stim.ConfigGetBool("ssh-setup-dry-run") {
+		fmt.Printf("# %s\n%s\n# %s\n%s", configPath, block, knownHostsPath, knownHosts)
+		return nil
+	}
+
+	err = os.MkdirAll(filepath.Dir(configPath), 0700)
+	if err != nil {
+		return err
+	}
+
+	existing, err := ioutil.ReadFile(configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	err = ioutil.WriteFile(configPath, []byte(replaceConfigBlock(string(existing), block)), 0600)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(knownHostsPath, []byte(knownHosts), 0600)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, h := range hosts {
+		names = append(names, h.name)
+	}
+	log.Info("Wrote {} host(s) to {} and {}", len(hosts), configPath, knownHostsPath)
+
+	user, err := s.stim.User()
+	if err != nil {
+		user = "unknown"
+	}
+	err = s.stim.Notify("ssh.setup", &notify.Payload{
+		Title:  fmt.Sprintf("%s set up SSH config for %d host(s)", user, len(hosts)),
+		Status: notify.StatusInfo,
+		Fields: map[string]string{
+			"User":  user,
+			"Hosts": strings.Join(names, ", "),
+		},
+	})
+	if err != nil {
+		log.Warn("Unable to send SSH setup notification: {}", err)
+	}
+
+	return nil
+}
+
+// getHosts reads the hosts of the given environments (or all environments)
+// from the Vault inventory, sorted by environment and name
+func (s *Ssh) getHosts(environments []string) ([]*host, error) {
+
+	vault, err := s.stim.NewVault()
+	if err != nil {
+		return nil, err
+	}
+
+	inventoryPath := s.stim.ConfigGetString("ssh.inventory-path")
+	if inventoryPath == "" {
+		inventoryPath = defaultInventoryPath
+	}
+
+	if len(environments) == 0 {
+		list, err := vault.ListSecrets(inventoryPath)
+		if err != nil {
+			return nil, err
+		}
+		environments = list
+	}
+
+	var hosts []*host
+	for _, environment := range environments {
+		names, err := vault.ListSecrets(inventoryPath + "/" + environment)
+		if err != nil {
+			return nil, fmt.Errorf("Error listing hosts of environment '%s': %v", environment, err)
+		}
+		for _, name := range names {
+			keys, err := vault.GetSecretKeys(inventoryPath + "/" + environment + "/" + name)
+			if err != nil {
+				return nil, err
+			}
+			h, err := newHost(environment, name, keys)
+			if err != nil {
+				return nil, err
+			}
+			hosts = append(hosts, h)
+		}
+	}
+
+	sort.Slice(hosts, func(i, j int) bool {
+		if hosts[i].environment != hosts[j].environment {
+			return hosts[i].environment < hosts[j].environment
+		}
+		return hosts[i].name < hosts[j].name
+	})
+
+	return hosts, nil
+}
+
+// newHost creates a host from its inventory secret keys
+func newHost(environment string, name string, keys map[string]string) (*host, error) {
+
+	h := &host{
+		name:        name,
+		environment: environment,
+		hostname:    keys["hostname"],
+		user:        keys["user"],
+		port:        keys["port"],
+		proxyJump:   keys["proxy-jump"],
+		identity:    keys["identity-file"],
+	}
+	if h.hostname == "" {
+		return nil, fmt.Errorf("Host '%s/%s' is missing the `hostname` key", environment, name)
+	}
+	for _, line := range strings.Split(keys["host-keys"], "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			h.hostKeys = append(h.hostKeys, line)
+		}
+	}
+
+	return h, nil
+}
+
+// renderConfigBlock renders the stim-managed block of SSH config Host entries.
+// Hosts with host keys only trust the keys in the stim known_hosts file.
+func renderConfigBlock(hosts []*host, knownHostsPath string) string {
+
+	var b strings.Builder
+	b.WriteString(blockBegin + "\n")
+	environment := ""
+	for _, h := range hosts {
+		if h.environment != environment {
+			environment = h.environment
+			fmt.Fprintf(&b, "\n# %s\n", environment)
+		}
+		fmt.Fprintf(&b, "Host %s\n", h.name)
+		fmt.Fprintf(&b, "  HostName %s\n", h.hostname)
+		if h.user != "" {
+			fmt.Fprintf(&b, "  User %s\n", h.user)
+		}
+		if h.port != "" {
+			fmt.Fprintf(&b, "  Port %s\n", h.port)
+		}
+		if h.proxyJump != "" {
+			fmt.Fprintf(&b, "  ProxyJump %s\n", h.proxyJump)
+		}
+		if h.identity != "" {
+			fmt.Fprintf(&b, "  IdentityFile %s\n", h.identity)
+		}
+		if len(h.hostKeys) > 0 {
+			fmt.Fprintf(&b, "  UserKnownHostsFile %s\n", knownHostsPath)
+			b.WriteString("  StrictHostKeyChecking yes\n")
+		}
+	}
+	// End the last Host section, so the global options of a config the block
+	// is prepended to still apply to every host
+	b.WriteString("\nHost *\n")
+	b.WriteString(blockEnd + "\n")
+
+	return b.String()
+}
+
+// renderKnownHosts renders the known_hosts lines of the hosts' keys
+func renderKnownHosts(hosts []*host) string {
+
+	var b strings.Builder
+	for _, h := range hosts {
+		pattern := h.hostname
+		if h.port != "" && h.port != "22" {
+			pattern = fmt.Sprintf("[%s]:%s", h.hostname, h.port)
+		}
+		for _, key := range h.hostKeys {
+			fmt.Fprintf(&b, "%s %s\n", pattern, key)
+		}
+	}
+
+	return b.String()
+}
+
+// replaceConfigBlock replaces the stim-managed block of an SSH config, or
+// prepends it if there is none.  The block goes first since ssh uses the first
+// value found for each option, and it ends with `Host *` so it doesn't capture
+// the options that follow it.
+func replaceConfigBlock(config string, block string) string {
+
+	start := strings.Index(config, blockBegin)
+	end := strings.Index(config, blockEnd)
+	if start >= 0 && end > start {
+		end += len(blockEnd)
+		if end < len(config) && config[end] == '\n' {
+			end++
+		}
+		return config[:start] + block + config[end:]
+	}
+
+	if config == "" {
+		return block
+	}
+	return block + "\n" + config
+}
@@ -0,0 +1,60 @@
+package ssh
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestRenderHosts(t *testing.T) {
+	bastion, err := newHost("prod", "prod-bastion", map[string]string{
+		"hostname":  "bastion.prod.my-domain.com",
+		"user":      "ops",
+		"host-keys": "ssh-ed25519 AAAA1\n\nssh-rsa AAAA2\n",
+	})
+	assert.NilError(t, err)
+	db, err := newHost("prod", "prod-db", map[string]string{
+		"hostname":   "10.0.0.5",
+		"port":       "2222",
+		"proxy-jump": "prod-bastion",
+		"host-keys":  "ssh-ed25519 AAAA3",
+	})
+	assert.NilError(t, err)
+	hosts := []*host{bastion, db}
+
+	assert.Equal(t, renderConfigBlock(hosts, "/home/me/.ssh/known_hosts_stim"), blockBegin+`
+
+# prod
+Host prod-bastion
+  HostName bastion.prod.my-domain.com
+  User ops
+  UserKnownHostsFile /home/me/.ssh/known_hosts_stim
+  StrictHostKeyChecking yes
+Host prod-db
+  HostName 10.0.0.5
+  Port 2222
+  ProxyJump prod-bastion
+  UserKnownHostsFile /home/me/.ssh/known_hosts_stim
+  StrictHostKeyChecking yes
+
+Host *
+`+blockEnd+"\n")
+
+	assert.Equal(t, renderKnownHosts(hosts), `bastion.prod.my-domain.com ssh-ed25519 AAAA1
+bastion.prod.my-domain.com ssh-rsa AAAA2
+[10.0.0.5]:2222 ssh-ed25519 AAAA3
+`)
+
+	_, err = newHost("prod", "broken", map[string]string{})
+	assert.Error(t, err, "Host 'prod/broken' is missing the `hostname` key")
+}
+
+func TestReplaceConfigBlock(t *testing.T) {
+	block := blockBegin + "\nHost a\n" + blockEnd + "\n"
+
+	assert.Equal(t, replaceConfigBlock("", block), block)
+	assert.Equal(t, replaceConfigBlock("Host mine\n", block), block+"\nHost mine\n")
+
+	existing := "Host first\n" + blockBegin + "\nHost old\n" + blockEnd + "\nHost last\n"
+	assert.Equal(t, replaceConfigBlock(existing, block), "Host first\n"+block+"Host last\n")
+}
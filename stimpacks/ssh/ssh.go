@@ -0,0 +1,23 @@
+package ssh
+
+import (
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+type Ssh struct {
+	name string
+	stim *stim.Stim
+}
+
+func New() *Ssh {
+	s := &Ssh{name: "ssh"}
+	return s
+}
+
+func (s *Ssh) Name() string {
+	return s.name
+}
+
+func (s *Ssh) BindStim(stim *stim.Stim) {
+	s.stim = stim
+}
@@ -0,0 +1,78 @@
+package terraform
+
+import (
+	"fmt"
+
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// awsCredentialEnvs reads short-lived AWS credentials from the Vault AWS role
+// and returns them as env vars, along with the Vault lease of the
+// credentials
+func (t *Terraform) awsCredentialEnvs(config *Aws) ([]string, string, error) {
+
+	t.log.Debug("Getting AWS credentials from Vault {}/creds/{}", config.Account, config.Role)
+	vault, err := t.stim.NewVault()
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := vault.AWScredentials(config.Account, config.Role)
+	if err != nil {
+		return nil, "", stim.AuthError(fmt.Errorf("Unable to get AWS credentials from Vault: %v", err))
+	}
+	accessKey, _ := secret.Data["access_key"].(string)
+	secretKey, _ := secret.Data["secret_key"].(string)
+	sessionToken, _ := secret.Data["security_token"].(string)
+
+	// New IAM users take a while to become active.  The credentials are
+	// revoked if they can't be checked, since terraform won't run with them.
+	if sessionToken == "" {
+		err = t.verifyAwsCredentials(accessKey, secretKey)
+		if err != nil {
+			t.revokeLease(secret.LeaseID)
+			return nil, "", stim.AuthError(err)
+		}
+	}
+
+	envs := []string{
+		"AWS_ACCESS_KEY_ID=" + accessKey,
+		"AWS_SECRET_ACCESS_KEY=" + secretKey,
+	}
+	if sessionToken != "" {
+		envs = append(envs, "AWS_SESSION_TOKEN="+sessionToken)
+	}
+	if config.Region != "" {
+		envs = append(envs, "AWS_REGION="+config.Region, "AWS_DEFAULT_REGION="+config.Region)
+	}
+
+	return envs, secret.LeaseID, nil
+}
+
+// verifyAwsCredentials waits for new IAM credentials to become active
+func (t *Terraform) verifyAwsCredentials(accessKey string, secretKey string) error {
+
+	aws, err := t.stim.NewAws("", "")
+	if err != nil {
+		return err
+	}
+	err = aws.CreateSession(accessKey, secretKey)
+	if err != nil {
+		return err
+	}
+	return aws.VerifyActiveCreds()
+}
+
+// revokeLease revokes the Vault lease of the AWS or Azure credentials once
+// terraform is done with them
+func (t *Terraform) revokeLease(leaseID string) {
+	if leaseID == "" {
+		return
+	}
+	vault, err := t.stim.NewVault()
+	if err == nil {
+		err = vault.RevokeLease(leaseID)
+	}
+	if err != nil {
+		t.log.Warn("Unable to revoke the credentials lease {}: {}", leaseID, err)
+	}
+}
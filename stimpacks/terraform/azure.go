@@ -0,0 +1,32 @@
+package terraform
+
+import (
+	"github.com/PremiereGlobal/stim/pkg/azure"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// azureCredentialEnvs reads a short-lived service principal from the Vault
+// Azure role and returns it as the ARM_* env vars of the azurerm provider,
+// along with the Vault lease of the service principal
+func (t *Terraform) azureCredentialEnvs(config *Azure) ([]string, string, error) {
+
+	sp, err := t.stim.AzureServicePrincipal(config.Account, config.Role)
+	if err != nil {
+		return nil, "", stim.AuthError(err)
+	}
+	if config.Tenant != "" {
+		sp.TenantID = config.Tenant
+	}
+	if config.Subscription != "" {
+		sp.SubscriptionID = config.Subscription
+	}
+
+	// New service principals take a while to propagate in Azure AD
+	err = t.stim.AzureLogin(azure.New(&azure.Config{TenantID: sp.TenantID}), sp)
+	if err != nil {
+		t.revokeLease(sp.LeaseID)
+		return nil, "", stim.AuthError(err)
+	}
+
+	return sp.Envs(), sp.LeaseID, nil
+}
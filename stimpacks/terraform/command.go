@@ -0,0 +1,59 @@
+package terraform
+
+import (
+	"github.com/PremiereGlobal/stim/stim"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func (t *Terraform) BindStim(s *stim.Stim) {
+	t.stim = s
+	t.log = s.GetLogger()
+}
+
+func (t *Terraform) Command(viper *viper.Viper) *cobra.Command {
+
+	var cmd = &cobra.Command{
+		Use:   "terraform",
+		Short: "Run terraform with Vault credentials",
+		Long:  "Run terraform plan/apply for an environment instance of stim.tf.yaml, in its own workspace, with short-lived AWS credentials and secrets from Vault",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	cmd.PersistentFlags().StringP("file", "f", "", "Terraform config file (Default: ./stim.tf.yaml)")
+	viper.BindPFlag("terraform.file", cmd.PersistentFlags().Lookup("file"))
+	cmd.PersistentFlags().StringP("environment", "e", "", "Environment to run terraform for")
+	viper.BindPFlag("terraform-environment", cmd.PersistentFlags().Lookup("environment"))
+	t.stim.BindFlagCompletion(cmd, "environment", "terraform-environments", t.completeEnvironments)
+	cmd.PersistentFlags().StringP("instance", "i", "", "Instance to run terraform for")
+	viper.BindPFlag("terraform-instance", cmd.PersistentFlags().Lookup("instance"))
+
+	var planCmd = &cobra.Command{
+		Use:     "plan [-- terraform args]",
+		Short:   "Show the changes terraform would make",
+		Long:    "Select the workspace of the instance and run `terraform plan`.  Arguments after `--` are passed to terraform",
+		Example: "  stim terraform plan -e prod -i us-west-2 -- -target=module.db",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return t.Run(false, args)
+		},
+	}
+	t.stim.BindCommand(planCmd, cmd)
+
+	var applyCmd = &cobra.Command{
+		Use:         "apply [-- terraform args]",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Plan and apply changes",
+		Long:        "Select the workspace of the instance, run `terraform plan` and, once confirmed according to the policy of the environment, apply the plan.  Arguments after `--` are passed to terraform plan",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return t.Run(true, args)
+		},
+	}
+	t.stim.BindCommand(applyCmd, cmd)
+
+	applyCmd.Flags().BoolP("yes", "y", false, "Skip the confirmation prompts, including typed confirmations")
+	viper.BindPFlag("terraform-yes", applyCmd.Flags().Lookup("yes"))
+
+	return cmd
+}
@@ -0,0 +1,311 @@
+package terraform
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/PremiereGlobal/stim/pkg/utils"
+	"github.com/PremiereGlobal/stim/stim"
+	v2e "github.com/PremiereGlobal/vault-to-envs/pkg/vaulttoenvs"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	defaultConfigFile = "./stim.tf.yaml"
+	defaultDirectory  = "./"
+	defaultWorkspace  = "{ENVIRONMENT}-{INSTANCE}"
+)
+
+// The confirmation policies of an environment
+const (
+	confirmPrompt = "prompt"
+	confirmTyped  = "typed"
+)
+
+// Config is the root structure of the terraform config (stim.tf.yaml)
+type Config struct {
+	Terraform      Settings       `yaml:"terraform"`
+	Global         Global         `yaml:"global"`
+	Environments   []*Environment `yaml:"environments"`
+	environmentMap map[string]int
+}
+
+// Settings describes where the terraform code is and how it is run
+type Settings struct {
+	Directory         string `yaml:"directory"`
+	Version           string `yaml:"version"`
+	Workspace         string `yaml:"workspace"`
+	fullDirectoryPath string
+}
+
+// Global describes the spec shared by all environments
+type Global struct {
+	Spec *Spec `yaml:"spec"`
+}
+
+// Spec contains the spec of a given environment/instance.  Instance values
+// override environment values, which override global values.  Secrets and
+// env vars are added together.
+type Spec struct {
+	Workspace       string            `yaml:"workspace"`
+	Aws             *Aws              `yaml:"aws"`
+	Azure           *Azure            `yaml:"azure"`
+	Secrets         []*v2e.SecretItem `yaml:"secrets"`
+	EnvironmentVars []*EnvironmentVar `yaml:"env"`
+	Vars            map[string]string `yaml:"vars"`
+}
+
+// Aws describes the Vault AWS secrets engine role that the short-lived AWS
+// credentials of terraform are read from
+type Aws struct {
+	Account string `yaml:"account"`
+	Role    string `yaml:"role"`
+	Region  string `yaml:"region"`
+}
+
+// Azure describes the Vault Azure secrets engine role that the short-lived
+// service principal of terraform is read from.  The tenant and subscription
+// default to those of the secrets engine.
+type Azure struct {
+	Account      string `yaml:"account"`
+	Role         string `yaml:"role"`
+	Tenant       string `yaml:"tenant"`
+	Subscription string `yaml:"subscription"`
+}
+
+// Environment describes an environment (i.e. dev, stage, prod, etc.)
+type Environment struct {
+	Name        string      `yaml:"name"`
+	Spec        *Spec       `yaml:"spec"`
+	Instances   []*Instance `yaml:"instances"`
+	Policy      *Policy     `yaml:"policy"`
+	instanceMap map[string]int
+}
+
+// Policy describes the confirmation required before applying to an
+// environment
+type Policy struct {
+	Confirm string `yaml:"confirm"`
+}
+
+// Instance describes an instance of an environment (i.e. us-west-2 for env
+// prod), with a terraform workspace of its own
+type Instance struct {
+	Name string `yaml:"name"`
+	Spec *Spec  `yaml:"spec"`
+}
+
+// EnvironmentVar describes an env var set when running terraform
+type EnvironmentVar struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+}
+
+// parseConfig reads the terraform config file and resolves the instance specs
+func (t *Terraform) parseConfig() error {
+
+	configFile := t.stim.ConfigGetString("terraform.file")
+	if configFile == "" {
+		configFile = defaultConfigFile
+		t.log.Debug("Terraform config file not specified, using {}", defaultConfigFile)
+	}
+
+	content, err := ioutil.ReadFile(configFile)
+	if os.IsNotExist(err) {
+		return stim.ConfigError(fmt.Errorf("No terraform config file exists at: %s", configFile))
+	} else if err != nil {
+		return stim.ConfigError(fmt.Errorf("Terraform config file could not be read: %v", err))
+	}
+
+	if ok, err := utils.IsYaml(content); !ok {
+		return stim.ConfigError(fmt.Errorf("Terraform config file (%s) is not valid YAML: %v", configFile, err))
+	}
+
+	t.config = Config{}
+	err = yaml.Unmarshal(content, &t.config)
+	if err != nil {
+		return stim.ConfigError(fmt.Errorf("Error parsing terraform config %v", err))
+	}
+
+	err = resolveConfig(&t.config)
+	if err != nil {
+		return stim.ConfigError(err)
+	}
+
+	configAbs, err := filepath.Abs(configFile)
+	if err != nil {
+		return fmt.Errorf("Error fetching terraform config filepath '%v'", err)
+	}
+	t.config.Terraform.fullDirectoryPath = filepath.Join(filepath.Dir(configAbs), t.config.Terraform.Directory)
+
+	return nil
+}
+
+// resolveConfig sets defaults, validates the config and merges the global and
+// environment specs into each instance spec
+func resolveConfig(config *Config) error {
+
+	if config.Terraform.Directory == "" {
+		config.Terraform.Directory = defaultDirectory
+	}
+	if config.Terraform.Workspace == "" {
+		config.Terraform.Workspace = defaultWorkspace
+	}
+	if config.Global.Spec == nil {
+		config.Global.Spec = &Spec{}
+	}
+
+	if len(config.Environments) == 0 {
+		return errors.New("No environments are set in the terraform config")
+	}
+
+	config.environmentMap = make(map[string]int)
+	for i, environment := range config.Environments {
+
+		if environment.Name == "" {
+			return errors.New("Every environment must have a `name`")
+		}
+		if _, ok := config.environmentMap[environment.Name]; ok {
+			return fmt.Errorf("Duplicate environment name `%s` found", environment.Name)
+		}
+		config.environmentMap[environment.Name] = i
+
+		if len(environment.Instances) == 0 {
+			return fmt.Errorf("No instances set for environment `%s`", environment.Name)
+		}
+
+		err := validatePolicy(environment.Policy)
+		if err != nil {
+			return fmt.Errorf("Environment `%s`: %v", environment.Name, err)
+		}
+
+		environment.instanceMap = make(map[string]int)
+		for j, instance := range environment.Instances {
+
+			if instance.Name == "" {
+				return fmt.Errorf("Every instance of environment `%s` must have a `name`", environment.Name)
+			}
+			if _, ok := environment.instanceMap[instance.Name]; ok {
+				return fmt.Errorf("Duplicate instance name `%s` found in environment `%s`", instance.Name, environment.Name)
+			}
+			environment.instanceMap[instance.Name] = j
+
+			instance.Spec = mergeSpecs(config.Global.Spec, environment.Spec, instance.Spec)
+			if instance.Spec.Workspace == "" {
+				instance.Spec.Workspace = config.Terraform.Workspace
+			}
+			instance.Spec.Workspace = strings.NewReplacer("{ENVIRONMENT}", environment.Name, "{INSTANCE}", instance.Name).Replace(instance.Spec.Workspace)
+
+			err = validateSpec(instance.Spec)
+			if err != nil {
+				return fmt.Errorf("Instance `%s/%s`: %v", environment.Name, instance.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// mergeSpecs returns the spec of an instance from the global, environment and
+// instance specs (in precedence order, nil specs are skipped)
+func mergeSpecs(specs ...*Spec) *Spec {
+
+	merged := &Spec{Vars: make(map[string]string)}
+	for _, spec := range specs {
+		if spec == nil {
+			continue
+		}
+
+		if spec.Workspace != "" {
+			merged.Workspace = spec.Workspace
+		}
+		if spec.Aws != nil {
+			if merged.Aws == nil {
+				merged.Aws = &Aws{}
+			}
+			if spec.Aws.Account != "" {
+				merged.Aws.Account = spec.Aws.Account
+			}
+			if spec.Aws.Role != "" {
+				merged.Aws.Role = spec.Aws.Role
+			}
+			if spec.Aws.Region != "" {
+				merged.Aws.Region = spec.Aws.Region
+			}
+		}
+		if spec.Azure != nil {
+			if merged.Azure == nil {
+				merged.Azure = &Azure{}
+			}
+			if spec.Azure.Account != "" {
+				merged.Azure.Account = spec.Azure.Account
+			}
+			if spec.Azure.Role != "" {
+				merged.Azure.Role = spec.Azure.Role
+			}
+			if spec.Azure.Tenant != "" {
+				merged.Azure.Tenant = spec.Azure.Tenant
+			}
+			if spec.Azure.Subscription != "" {
+				merged.Azure.Subscription = spec.Azure.Subscription
+			}
+		}
+		merged.Secrets = append(merged.Secrets, spec.Secrets...)
+		merged.EnvironmentVars = append(merged.EnvironmentVars, spec.EnvironmentVars...)
+		for name, value := range spec.Vars {
+			merged.Vars[name] = value
+		}
+	}
+
+	return merged
+}
+
+// validateSpec checks a resolved instance spec
+func validateSpec(spec *Spec) error {
+	if spec.Aws != nil && (spec.Aws.Account == "") != (spec.Aws.Role == "") {
+		return errors.New("`aws` must set both the `account` (Vault AWS mount) and the `role`")
+	}
+	if spec.Azure != nil && (spec.Azure.Account == "" || spec.Azure.Role == "") {
+		return errors.New("`azure` must set both the `account` (Vault Azure mount) and the `role`")
+	}
+	if strings.ContainsAny(spec.Workspace, "/ ") {
+		return fmt.Errorf("Invalid workspace name '%s'", spec.Workspace)
+	}
+	for _, secret := range spec.Secrets {
+		if secret.SecretPath == "" {
+			return errors.New("Every secret must have a `secretPath`")
+		}
+	}
+	return nil
+}
+
+// validatePolicy validates a 'policy' section
+func validatePolicy(policy *Policy) error {
+	if policy == nil || policy.Confirm == "" {
+		return nil
+	}
+	if policy.Confirm != confirmPrompt && policy.Confirm != confirmTyped {
+		return fmt.Errorf("Invalid policy confirm value '%s'.  Must be one of ['%s','%s']", policy.Confirm, confirmPrompt, confirmTyped)
+	}
+	return nil
+}
+
+// completeEnvironments returns the environments of the terraform config for
+// shell completion
+func (t *Terraform) completeEnvironments() ([]string, error) {
+
+	err := t.parseConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(t.config.Environments))
+	for i, e := range t.config.Environments {
+		names[i] = e.Name
+	}
+	return names, nil
+}
@@ -0,0 +1,108 @@
+package terraform
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v2"
+	"gotest.tools/assert"
+)
+
+const testConfig = `
+terraform:
+  directory: ./infra
+global:
+  spec:
+    aws:
+      account: aws-dev
+      role: terraform
+    vars:
+      team: web
+environments:
+  - name: dev
+    instances:
+      - name: us-west-2
+        spec:
+          aws:
+            region: us-west-2
+  - name: prod
+    policy:
+      confirm: typed
+    spec:
+      aws:
+        account: aws-prod
+      vars:
+        size: large
+    instances:
+      - name: us-east-1
+        spec:
+          workspace: prod
+          env:
+            - name: TF_LOG
+              value: INFO
+`
+
+func parseTestConfig(t *testing.T, content string) (*Config, error) {
+	config := &Config{}
+	assert.NilError(t, yaml.Unmarshal([]byte(content), config))
+	return config, resolveConfig(config)
+}
+
+func TestResolveConfig(t *testing.T) {
+	config, err := parseTestConfig(t, testConfig)
+	assert.NilError(t, err)
+
+	dev := config.Environments[0].Instances[0].Spec
+	assert.Equal(t, dev.Workspace, "dev-us-west-2")
+	assert.DeepEqual(t, *dev.Aws, Aws{Account: "aws-dev", Role: "terraform", Region: "us-west-2"})
+	assert.DeepEqual(t, dev.Vars, map[string]string{"team": "web"})
+
+	prod := config.Environments[1].Instances[0].Spec
+	assert.Equal(t, prod.Workspace, "prod")
+	assert.DeepEqual(t, *prod.Aws, Aws{Account: "aws-prod", Role: "terraform"})
+	assert.DeepEqual(t, tfVarEnvs(prod.Vars), []string{"TF_VAR_size=large", "TF_VAR_team=web"})
+	assert.Equal(t, len(prod.EnvironmentVars), 1)
+
+	// The global spec isn't changed by the merge
+	assert.Equal(t, config.Global.Spec.Aws.Region, "")
+}
+
+func TestMergeAzure(t *testing.T) {
+	spec := mergeSpecs(
+		&Spec{Azure: &Azure{Account: "azure", Role: "terraform", Tenant: "tenant"}},
+		&Spec{Azure: &Azure{Subscription: "prod-sub"}},
+		&Spec{Azure: &Azure{Role: "terraform-admin"}},
+	)
+	assert.DeepEqual(t, *spec.Azure, Azure{Account: "azure", Role: "terraform-admin", Tenant: "tenant", Subscription: "prod-sub"})
+	assert.NilError(t, validateSpec(spec))
+}
+
+func TestResolveConfigErrors(t *testing.T) {
+	tests := []struct {
+		config string
+		err    string
+	}{
+		{`environments: []`, "No environments are set in the terraform config"},
+		{"environments:\n  - name: dev", "No instances set for environment `dev`"},
+		{"environments:\n  - name: dev\n    instances: [{name: a}, {name: a}]", "Duplicate instance name `a` found in environment `dev`"},
+		{"environments:\n  - name: dev\n    policy: {confirm: always}\n    instances: [{name: a}]", "Environment `dev`: Invalid policy confirm value 'always'.  Must be one of ['prompt','typed']"},
+		{"environments:\n  - name: dev\n    spec: {aws: {role: terraform}}\n    instances: [{name: a}]", "Instance `dev/a`: `aws` must set both the `account` (Vault AWS mount) and the `role`"},
+		{"environments:\n  - name: dev\n    spec: {azure: {account: azure-dev}}\n    instances: [{name: a}]", "Instance `dev/a`: `azure` must set both the `account` (Vault Azure mount) and the `role`"},
+		{"terraform: {workspace: '{ENVIRONMENT}/{INSTANCE}'}\nenvironments:\n  - name: dev\n    instances: [{name: a}]", "Instance `dev/a`: Invalid workspace name 'dev/a'"},
+	}
+
+	for _, test := range tests {
+		_, err := parseTestConfig(t, test.config)
+		assert.Error(t, err, test.err)
+	}
+}
+
+func TestConfirmPolicy(t *testing.T) {
+	assert.Equal(t, confirmPolicy(&Environment{Name: "dev"}, nil), confirmPrompt)
+	assert.Equal(t, confirmPolicy(&Environment{Name: "dev", Policy: &Policy{Confirm: confirmTyped}}, nil), confirmTyped)
+	assert.Equal(t, confirmPolicy(&Environment{Name: "prod-eu", Policy: &Policy{Confirm: confirmPrompt}}, []string{"prod*"}), confirmTyped)
+}
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, shellQuote("-target=module.db"), "'-target=module.db'")
+	assert.Equal(t, shellQuote("it's"), `'it'\''s'`)
+}
@@ -0,0 +1,53 @@
+package terraform
+
+import (
+	"errors"
+	"path"
+	"strings"
+
+	"github.com/PremiereGlobal/stim/pkg/i18n"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// checkPolicy asks for the confirmation the environment's policy requires
+// before applying to the instance.  Applies are always confirmed with a
+// prompt unless the policy requires a typed confirmation.
+func (t *Terraform) checkPolicy(environment *Environment, instance *Instance) error {
+
+	if t.stim.ConfigGetBool("terraform-yes") {
+		return nil
+	}
+
+	switch confirmPolicy(environment, t.stim.ConfigGetStringSlice("deploy.protected-envs")) {
+	case confirmPrompt:
+		proceed, _ := t.stim.PromptBool(t.stim.Message(i18n.MessageProceed), false, false)
+		if !proceed {
+			return stim.Aborted(t.stim.Message(i18n.MessageApplyCancelled))
+		}
+	case confirmTyped:
+		if t.stim.IsAutomated() {
+			return errors.New(t.stim.Message(i18n.MessageApplyTypedAutomated, environment.Name))
+		}
+		typed, _ := t.stim.PromptString(t.stim.Message(i18n.MessageTypeToConfirmApply, instance.Name), "")
+		if strings.TrimSpace(typed) != instance.Name {
+			return stim.Aborted(t.stim.Message(i18n.MessageApplyMismatch))
+		}
+	}
+
+	return nil
+}
+
+// confirmPolicy returns the confirmation required to apply to the
+// environment.  Environments matching the protected patterns (ex. `prod*`)
+// always require a typed confirmation.
+func confirmPolicy(environment *Environment, protected []string) string {
+	for _, pattern := range protected {
+		if matched, _ := path.Match(pattern, environment.Name); matched {
+			return confirmTyped
+		}
+	}
+	if environment.Policy != nil && environment.Policy.Confirm != "" {
+		return environment.Policy.Confirm
+	}
+	return confirmPrompt
+}
@@ -0,0 +1,227 @@
+package terraform
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/PremiereGlobal/stim/pkg/env"
+	"github.com/PremiereGlobal/stim/pkg/i18n"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// noChangesOutput is printed by `terraform plan` when there is nothing to
+// apply
+const noChangesOutput = "No changes."
+
+// Run plans the changes of the selected instance and, if apply is set,
+// applies them once confirmed.  args are passed to `terraform plan`.
+func (t *Terraform) Run(apply bool, args []string) error {
+
+	err := t.parseConfig()
+	if err != nil {
+		return err
+	}
+
+	environment, instance, err := t.selectInstance()
+	if err != nil || instance == nil {
+		return err
+	}
+
+	// Keep the Vault token alive while terraform runs
+	vault, err := t.stim.NewVault()
+	if err != nil {
+		return err
+	}
+	vault.StartTokenRenewer()
+	defer vault.StopTokenRenewer()
+
+	e, err := t.instanceEnv(instance)
+	if err != nil {
+		return err
+	}
+	defer e.Close()
+
+	if instance.Spec.Aws != nil {
+		awsEnvs, leaseID, err := t.awsCredentialEnvs(instance.Spec.Aws)
+		if err != nil {
+			return err
+		}
+		defer t.revokeLease(leaseID)
+		e.AddEnvVars(awsEnvs...)
+	}
+	if instance.Spec.Azure != nil {
+		azureEnvs, leaseID, err := t.azureCredentialEnvs(instance.Spec.Azure)
+		if err != nil {
+			return err
+		}
+		defer t.revokeLease(leaseID)
+		e.AddEnvVars(azureEnvs...)
+	}
+
+	_, err = t.terraform(e, "init", "-input=false")
+	if err != nil {
+		return err
+	}
+	err = t.selectWorkspace(e, instance.Spec.Workspace)
+	if err != nil {
+		return err
+	}
+
+	t.log.Info("Planning '{}' environment in instance {} (workspace {})", environment.Name, instance.Name, instance.Spec.Workspace)
+	planFile := filepath.Join(e.GetPath(), "tfplan")
+	planArgs := []string{"plan", "-input=false"}
+	if apply {
+		planArgs = append(planArgs, "-out="+planFile)
+	}
+	out, err := t.terraform(e, append(planArgs, args...)...)
+	if err != nil {
+		return err
+	}
+	t.log.Info(out)
+
+	if !apply {
+		return nil
+	}
+	if strings.Contains(out, noChangesOutput) {
+		t.log.Info("Nothing to apply")
+		return nil
+	}
+
+	err = t.checkPolicy(environment, instance)
+	if err != nil {
+		return err
+	}
+
+	out, err = t.terraform(e, "apply", "-input=false", planFile)
+	if err != nil {
+		return stim.DeployError(err)
+	}
+	t.log.Info(out)
+
+	return nil
+}
+
+// selectInstance returns the environment and instance given on the command
+// line, prompting for the ones that aren't.  The instance is nil if none was
+// selected.
+func (t *Terraform) selectInstance() (*Environment, *Instance, error) {
+
+	environmentName := t.stim.ConfigGetString("terraform-environment")
+	if environmentName == "" {
+		names := make([]string, len(t.config.Environments))
+		for i, e := range t.config.Environments {
+			names[i] = e.Name
+		}
+		environmentName, _ = t.stim.PromptListRemembered("terraform/environment", t.stim.Message(i18n.MessageWhichEnvironment), names, "")
+		if environmentName == "" {
+			t.log.Info(t.stim.Message(i18n.MessageNoEnvironment))
+			return nil, nil, nil
+		}
+	}
+	i, ok := t.config.environmentMap[environmentName]
+	if !ok {
+		return nil, nil, stim.ConfigError(fmt.Errorf("Provided environment value '%s' is not in config file", environmentName))
+	}
+	environment := t.config.Environments[i]
+
+	instanceName := t.stim.ConfigGetString("terraform-instance")
+	if instanceName == "" {
+		names := make([]string, len(environment.Instances))
+		for i, inst := range environment.Instances {
+			names[i] = inst.Name
+		}
+		instanceName, _ = t.stim.PromptListRemembered("terraform/instance/"+environment.Name, t.stim.Message(i18n.MessageWhichInstance), names, "")
+		if instanceName == "" {
+			t.log.Info(t.stim.Message(i18n.MessageNoInstance))
+			return nil, nil, nil
+		}
+	}
+	i, ok = environment.instanceMap[instanceName]
+	if !ok {
+		return nil, nil, stim.ConfigError(fmt.Errorf("Provided instance value '%s' is not in config file under environment '%s'", instanceName, environmentName))
+	}
+
+	return environment, environment.Instances[i], nil
+}
+
+// instanceEnv sets up the shell environment terraform runs in, with the env
+// vars, variables and secrets of the instance and the terraform binary.  The
+// environment must be closed when done.
+func (t *Terraform) instanceEnv(instance *Instance) (*env.Env, error) {
+
+	// Providers are cached across runs as HOME is a new directory every time
+	envs := []string{
+		"TF_IN_AUTOMATION=true",
+		"TF_PLUGIN_CACHE_DIR=" + t.stim.ConfigGetCacheDir("terraform-plugins"),
+	}
+	for _, e := range instance.Spec.EnvironmentVars {
+		envs = append(envs, fmt.Sprintf("%s=%s", e.Name, e.Value))
+	}
+	envs = append(envs, tfVarEnvs(instance.Spec.Vars)...)
+
+	t.log.Debug("Setting working directory {}", t.config.Terraform.fullDirectoryPath)
+	e, err := t.stim.Env(&stim.EnvConfig{
+		EnvVars: envs,
+		Vault: &stim.EnvConfigVault{
+			SecretItems: instance.Spec.Secrets,
+		},
+		WorkDir: t.config.Terraform.fullDirectoryPath,
+		Tools:   map[string]stim.EnvTool{"terraform": {Version: t.config.Terraform.Version}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	e.AddEnvVars(t.stim.TraceEnv()...)
+
+	return e, nil
+}
+
+// tfVarEnvs returns the terraform variables as TF_VAR_ env vars, sorted by
+// name
+func tfVarEnvs(vars map[string]string) []string {
+	envs := make([]string, 0, len(vars))
+	for name, value := range vars {
+		envs = append(envs, fmt.Sprintf("TF_VAR_%s=%s", name, value))
+	}
+	sort.Strings(envs)
+	return envs
+}
+
+// selectWorkspace selects the terraform workspace, creating it if it doesn't
+// exist yet
+func (t *Terraform) selectWorkspace(e *env.Env, workspace string) error {
+	_, err := t.terraform(e, "workspace", "select", workspace)
+	if err == nil {
+		return nil
+	}
+
+	t.log.Info("Creating terraform workspace {}", workspace)
+	_, err = t.terraform(e, "workspace", "new", workspace)
+	return err
+}
+
+// terraform runs terraform with the arguments in the environment and returns
+// its output
+func (t *Terraform) terraform(e *env.Env, args ...string) (string, error) {
+
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+
+	t.log.Debug("Running terraform {}", strings.Join(args, " "))
+	defer t.stim.Time(stim.PhaseTerraform)()
+	out, err := e.Run("terraform " + strings.Join(quoted, " "))
+	if err != nil {
+		return out, fmt.Errorf("Error running terraform %s: %v", args[0], err)
+	}
+
+	return out, nil
+}
+
+// shellQuote quotes an argument for the POSIX shell that terraform is run with
+func shellQuote(arg string) string {
+	return "'" + strings.Replace(arg, "'", `'\''`, -1) + "'"
+}
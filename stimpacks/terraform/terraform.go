@@ -0,0 +1,24 @@
+package terraform
+
+import (
+	log "github.com/PremiereGlobal/stim/pkg/stimlog"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// Terraform is the stimpack that runs terraform plan/apply with credentials
+// and secrets from Vault
+type Terraform struct {
+	name   string
+	stim   *stim.Stim
+	config Config
+	log    log.StimLogger
+}
+
+func New() *Terraform {
+	terraform := &Terraform{name: "terraform"}
+	return terraform
+}
+
+func (t *Terraform) Name() string {
+	return t.name
+}
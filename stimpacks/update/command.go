@@ -0,0 +1,33 @@
+package update
+
+import (
+	"github.com/PremiereGlobal/stim/stim"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func (u *Update) BindStim(s *stim.Stim) {
+	u.stim = s
+}
+
+func (u *Update) Command(viper *viper.Viper) *cobra.Command {
+
+	var cmd = &cobra.Command{
+		Use:         "update",
+		Annotations: map[string]string{stim.AnnotationNoUpdateCheck: "true"},
+		Short:       "Update stim to the latest release",
+		Long:        "Downloads the latest stim release for this platform from GitHub, verifies its checksum and replaces the running binary",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return u.Update()
+		},
+	}
+
+	cmd.Flags().Bool("check", false, "Only check whether a newer release is available")
+	viper.BindPFlag("update-check", cmd.Flags().Lookup("check"))
+	cmd.Flags().Bool("force", false, "Install the latest release even if it is not newer than the running version (ex. for local builds)")
+	viper.BindPFlag("update-force", cmd.Flags().Lookup("force"))
+	cmd.Flags().BoolP("yes", "y", false, "Skip the confirmation prompt")
+	viper.BindPFlag("update-yes", cmd.Flags().Lookup("yes"))
+
+	return cmd
+}
@@ -0,0 +1,84 @@
+package update
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/PremiereGlobal/stim/pkg/i18n"
+	"github.com/PremiereGlobal/stim/pkg/selfupdate"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// Update installs the latest stim release over the running binary, or only
+// reports whether there is a newer release with --check
+func (u *Update) Update() error {
+
+	log := u.stim.GetLogger()
+	current := u.stim.GetVersion()
+	force := u.stim.ConfigGetBool("update-force")
+
+	updater := u.stim.Updater()
+	release, err := updater.LatestRelease()
+	if err != nil {
+		return err
+	}
+
+	// Local and branch builds can't be compared with releases
+	newer, compareErr := selfupdate.CompareVersions(release.Version, current)
+
+	if u.stim.ConfigGetBool("update-check") {
+		switch {
+		case compareErr != nil:
+			fmt.Printf("Running stim %s, the latest release is %s\n", current, release.Version)
+		case newer > 0:
+			fmt.Printf("A new version of stim is available: %s -> %s\n%s\n", current, release.Version, release.URL)
+		default:
+			fmt.Printf("stim %s is up to date\n", current)
+		}
+		return nil
+	}
+
+	if !force {
+		if compareErr != nil {
+			return stim.UsageError(fmt.Errorf("The running version '%s' is not a release.  Use --force to install %s", current, release.Version))
+		}
+		if newer <= 0 {
+			log.Info("stim {} is up to date", current)
+			return nil
+		}
+	}
+
+	path, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	path, err = filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+
+	yes := u.stim.ConfigGetBool("update-yes")
+	if !yes && u.stim.IsAutomated() {
+		return stim.UsageError(errors.New(u.stim.Message(i18n.MessageUpdateAutomated)))
+	}
+	proceed, _ := u.stim.PromptBool(u.stim.Message(i18n.MessageUpdateConfirm, path, current, release.Version), yes, true)
+	if !proceed {
+		return stim.Aborted(u.stim.Message(i18n.MessageUpdateCancelled))
+	}
+
+	log.Info("Downloading stim {}", release.Version)
+	binary, err := updater.Download(release)
+	if err != nil {
+		return err
+	}
+
+	err = selfupdate.Install(binary, path)
+	if err != nil {
+		return fmt.Errorf("Unable to replace %s: %v.  Run `stim update` with permission to write to it or reinstall stim", path, err)
+	}
+
+	log.Info("Updated stim from {} to {}", current, release.Version)
+	return nil
+}
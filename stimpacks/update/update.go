@@ -0,0 +1,19 @@
+package update
+
+import (
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+type Update struct {
+	name string
+	stim *stim.Stim
+}
+
+func New() *Update {
+	update := &Update{name: "update"}
+	return update
+}
+
+func (u *Update) Name() string {
+	return u.name
+}
@@ -0,0 +1,109 @@
+package vault
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/PremiereGlobal/stim/stimpacks/deploy"
+)
+
+// accessResult is a row of `stim vault check-access`
+type accessResult struct {
+	Environment  string   `json:"environment,omitempty"`
+	Instance     string   `json:"instance,omitempty"`
+	Check        string   `json:"check"`
+	Path         string   `json:"path"`
+	Capability   string   `json:"capability"`
+	Capabilities []string `json:"capabilities"`
+	Pass         bool     `json:"pass"`
+	Error        string   `json:"error,omitempty"`
+}
+
+// CheckAccess checks the capabilities of the current token on every Vault
+// path that a deploy config touches and prints a pass/fail matrix
+func (v *Vault) CheckAccess() error {
+
+	d := deploy.New()
+	d.BindStim(v.stim)
+	checks, err := d.AccessChecks(v.stim.ConfigGetString("vault-check-access-file"), v.stim.ConfigGetString("vault-check-access-environment"), v.stim.ConfigGetString("vault-check-access-instance"))
+	if err != nil {
+		return err
+	}
+
+	vault, err := v.stim.NewVault()
+	if err != nil {
+		return err
+	}
+
+	// Instances often share paths, so each path is only checked once
+	type checked struct {
+		pass         bool
+		capabilities []string
+		err          error
+	}
+	cache := make(map[string]*checked)
+
+	results := make([]*accessResult, len(checks))
+	failures := 0
+	for i, check := range checks {
+		key := check.Capability + " " + check.Path
+		c, ok := cache[key]
+		if !ok {
+			c = &checked{}
+			c.pass, c.capabilities, c.err = vault.SecretCapabilities(check.Path, check.Capability)
+			cache[key] = c
+		}
+
+		results[i] = &accessResult{
+			Environment:  check.Environment,
+			Instance:     check.Instance,
+			Check:        check.Check,
+			Path:         check.Path,
+			Capability:   check.Capability,
+			Capabilities: c.capabilities,
+			Pass:         c.pass && c.err == nil,
+		}
+		if c.err != nil {
+			results[i].Error = c.err.Error()
+		}
+		if !results[i].Pass {
+			failures++
+		}
+	}
+
+	err = v.stim.PrintOutput(v.stim.ConfigGetString("vault-check-access-output"), results, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "ENVIRONMENT\tINSTANCE\tCHECK\tPATH\tNEEDS\tHAS\tRESULT")
+		for _, r := range results {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", orDash(r.Environment), orDash(r.Instance), r.Check, r.Path, r.Capability, orDash(strings.Join(r.Capabilities, ",")), accessResultText(r))
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("%d of %d access check(s) failed", failures, len(results))
+	}
+
+	return nil
+}
+
+// accessResultText is the RESULT column of a row
+func accessResultText(r *accessResult) string {
+	switch {
+	case r.Error != "":
+		return "FAIL (" + r.Error + ")"
+	case !r.Pass:
+		return "FAIL"
+	}
+	return "pass"
+}
+
+// orDash returns `-` for empty table cells
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,675 @@
+package vault
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/notify"
+	"github.com/PremiereGlobal/stim/pkg/slack"
+	"github.com/PremiereGlobal/stim/pkg/utils"
+	stimvault "github.com/PremiereGlobal/stim/pkg/vault"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// The statuses of a break-glass access request
+const (
+	accessPending  = "pending"
+	accessApproved = "approved"
+	accessDenied   = "denied"
+	accessExpired  = "expired"
+)
+
+// The reactions that approve or deny an access request in Slack
+const (
+	accessApproveReaction = "white_check_mark"
+	accessDenyReaction    = "x"
+)
+
+// defaultBreakGlassPath is the Vault path that access requests are recorded
+// under when `vault.break-glass.path` is not set
+const defaultBreakGlassPath = "secret/stim/break-glass"
+
+// defaultBreakGlassMaxTTL is the longest access that can be requested when
+// `vault.break-glass.max-ttl` is not set
+const defaultBreakGlassMaxTTL = 4 * time.Hour
+
+// defaultAccessRequestTimeout is how long a request waits for approval when
+// no timeout is given
+const defaultAccessRequestTimeout = 30 * time.Minute
+
+// accessPollInterval is how often the access request record is read while
+// waiting for approval
+const accessPollInterval = 10 * time.Second
+
+// accessWrapTTL is how long the wrapped token of an approved request can be
+// unwrapped by the requester
+const accessWrapTTL = 15 * time.Minute
+
+// accessLocator matches the `<entity ID>/<request ID>` of an access request
+// in its Slack message
+var accessLocator = regexp.MustCompile("Request `([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})/([0-9]{8}-[0-9]{6}-[0-9a-f]{6})`")
+
+// accessPolicyName matches the names of the policies that can be requested
+var accessPolicyName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// slackLink matches the links that Slack adds to the text of messages, ex.
+// `<https://example.com>`
+var slackLink = regexp.MustCompile(`<((?:https?|mailto):[^|>]+)(?:\|[^>]*)?>`)
+
+// accessRequest is a break-glass access request.  Requests are recorded in
+// Vault under the identity entity of the requester, which only they (and the
+// approval server) can read, so that they are auditable and so that the
+// approval server can hand the requester a response-wrapped token.
+type accessRequest struct {
+	ID           string
+	EntityID     string
+	Requester    string
+	Policies     []string
+	Duration     time.Duration
+	Reason       string
+	Status       string
+	RequestedAt  time.Time
+	ExpiresAt    time.Time
+	DecidedBy    string
+	DecidedAt    time.Time
+	Accessor     string
+	WrapToken    string
+	SlackChannel string
+	SlackTS      string
+}
+
+// keys returns the secret keys that a request is recorded as
+func (r *accessRequest) keys() map[string]string {
+
+	keys := map[string]string{
+		"requester":     r.Requester,
+		"policies":      strings.Join(r.Policies, ","),
+		"duration":      r.Duration.String(),
+		"reason":        r.Reason,
+		"status":        r.Status,
+		"requested-at":  r.RequestedAt.UTC().Format(time.RFC3339),
+		"expires-at":    r.ExpiresAt.UTC().Format(time.RFC3339),
+		"slack-channel": r.SlackChannel,
+		"slack-ts":      r.SlackTS,
+	}
+	if r.DecidedBy != "" {
+		keys["decided-by"] = r.DecidedBy
+		keys["decided-at"] = r.DecidedAt.UTC().Format(time.RFC3339)
+	}
+	if r.Accessor != "" {
+		keys["accessor"] = r.Accessor
+	}
+	if r.WrapToken != "" {
+		keys["wrap-token"] = r.WrapToken
+	}
+
+	return keys
+}
+
+// text returns the Slack message of the request.  It only depends on the
+// record so that the approval server can check that the message that was
+// reacted to shows what the record asks for.
+func (r *accessRequest) text() string {
+	return fmt.Sprintf(":rotating_light: *%s* requests break-glass access to Vault policies *%s* for *%s*: %s\nRequest `%s/%s`.  React with :%s: to approve or :%s: to deny before %s.",
+		escapeSlackText(r.Requester), strings.Join(r.Policies, ", "), utils.HumanizeDuration(r.Duration), escapeSlackText(r.Reason),
+		r.EntityID, r.ID, accessApproveReaction, accessDenyReaction, utils.FormatTime(r.ExpiresAt, true))
+}
+
+// parseAccessRequest returns the request recorded in the keys of a secret
+func parseAccessRequest(entityID string, id string, keys map[string]string) (*accessRequest, error) {
+
+	request := &accessRequest{
+		ID:           id,
+		EntityID:     entityID,
+		Requester:    keys["requester"],
+		Reason:       keys["reason"],
+		Status:       keys["status"],
+		DecidedBy:    keys["decided-by"],
+		Accessor:     keys["accessor"],
+		WrapToken:    keys["wrap-token"],
+		SlackChannel: keys["slack-channel"],
+		SlackTS:      keys["slack-ts"],
+	}
+	if keys["policies"] != "" {
+		request.Policies = strings.Split(keys["policies"], ",")
+	}
+	if request.Requester == "" || request.Status == "" || len(request.Policies) == 0 {
+		return nil, fmt.Errorf("'%s' is not a break-glass access request", id)
+	}
+
+	var err error
+	request.Duration, err = time.ParseDuration(keys["duration"])
+	if err != nil {
+		return nil, fmt.Errorf("Invalid duration of access request '%s': %v", id, err)
+	}
+	request.RequestedAt, err = time.Parse(time.RFC3339, keys["requested-at"])
+	if err != nil {
+		return nil, fmt.Errorf("Invalid request time of access request '%s': %v", id, err)
+	}
+	request.ExpiresAt, err = time.Parse(time.RFC3339, keys["expires-at"])
+	if err != nil {
+		return nil, fmt.Errorf("Invalid expiry time of access request '%s': %v", id, err)
+	}
+	if keys["decided-at"] != "" {
+		request.DecidedAt, err = time.Parse(time.RFC3339, keys["decided-at"])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid decision time of access request '%s': %v", id, err)
+		}
+	}
+
+	return request, nil
+}
+
+// validateAccessRequest checks the policies, duration and reason of a new
+// access request
+func validateAccessRequest(policies []string, duration time.Duration, maxTTL time.Duration, reason string) error {
+	if len(policies) == 0 {
+		return errors.New("At least one policy must be given with --policy")
+	}
+	for _, policy := range policies {
+		if policy == "root" {
+			return errors.New("The `root` policy can't be requested")
+		}
+		if !accessPolicyName.MatchString(policy) {
+			return fmt.Errorf("Invalid policy name '%s'", policy)
+		}
+	}
+	if duration <= 0 {
+		return errors.New("The length of the access must be given with --duration (ex. 1h)")
+	}
+	if duration > maxTTL {
+		return fmt.Errorf("The access can't be longer than %s (vault.break-glass.max-ttl)", maxTTL)
+	}
+	if strings.TrimSpace(reason) == "" {
+		return errors.New("A reason (ex. the incident) must be given with --reason")
+	}
+	return nil
+}
+
+// newAccessRequestID returns a unique ID for an access request, starting with
+// the time of the request so that requests list in order
+func newAccessRequestID(now time.Time) (string, error) {
+	b := make([]byte, 3)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return now.UTC().Format("20060102-150405") + "-" + hex.EncodeToString(b), nil
+}
+
+// parseAccessLocator returns the entity ID and request ID of the access
+// request of a Slack message
+func parseAccessLocator(text string) (string, string, bool) {
+	match := accessLocator.FindStringSubmatch(text)
+	if match == nil {
+		return "", "", false
+	}
+	return match[1], match[2], true
+}
+
+// escapeSlackText escapes the characters that Slack uses for formatting
+// commands
+func escapeSlackText(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// unlinkSlackText removes the links that Slack adds to URLs in messages
+func unlinkSlackText(text string) string {
+	return slackLink.ReplaceAllString(text, "$1")
+}
+
+// isEntityName returns true if the requester is one of the names of an
+// identity entity, or the display name of a token of one of its aliases (ex.
+// `ldap-alice`)
+func isEntityName(requester string, names []string) bool {
+	for _, name := range names {
+		if strings.EqualFold(requester, name) || strings.HasSuffix(strings.ToLower(requester), "-"+strings.ToLower(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// decideAccessReaction returns the status that a reaction of a Slack user
+// gives a pending access request, or an empty status for reactions that
+// don't decide requests.  The requester's Slack users can't decide their own
+// requests, and requests past their expiry can only expire.
+func decideAccessReaction(request *accessRequest, requesterSlackIDs []string, reactor string, reaction string, now time.Time) (string, error) {
+
+	status := ""
+	switch reaction {
+	case accessApproveReaction:
+		status = accessApproved
+	case accessDenyReaction:
+		status = accessDenied
+	default:
+		return "", nil
+	}
+
+	if request.Status != accessPending {
+		return "", fmt.Errorf("Access request %s is already %s", request.ID, request.Status)
+	}
+	if !now.Before(request.ExpiresAt) {
+		return accessExpired, nil
+	}
+	if len(requesterSlackIDs) == 0 {
+		return "", fmt.Errorf("No Slack user found for requester %s, the request can't be decided", request.Requester)
+	}
+	for _, id := range requesterSlackIDs {
+		if id == reactor {
+			return "", errors.New("Access requests must be approved or denied by someone other than the requester")
+		}
+	}
+
+	return status, nil
+}
+
+// breakGlassPath returns the Vault path of an access request record, under
+// the identity entity of the requester
+func (v *Vault) breakGlassPath(entityID string, id string) string {
+	base := v.stim.ConfigGetString("vault.break-glass.path")
+	if base == "" {
+		base = defaultBreakGlassPath
+	}
+	return path.Join(base, entityID, id)
+}
+
+// RequestAccess requests time-boxed access to Vault policies.  The request
+// is posted to Slack and recorded in Vault under the identity entity of the
+// current token, then this waits for a second person to approve it with a
+// reaction and prints the issued token.
+func (v *Vault) RequestAccess() error {
+
+	log := v.stim.GetLogger()
+
+	policies := v.stim.ConfigGetStringSlice("vault-request-access-policies")
+	reason := v.stim.ConfigGetString("vault-request-access-reason")
+
+	var duration time.Duration
+	if arg := v.stim.ConfigGetString("vault-request-access-duration"); arg != "" {
+		var err error
+		duration, err = time.ParseDuration(arg)
+		if err != nil {
+			return stim.UsageError(fmt.Errorf("Invalid --duration '%s': %v", arg, err))
+		}
+	}
+
+	timeout := defaultAccessRequestTimeout
+	if arg := v.stim.ConfigGetString("vault-request-access-timeout"); arg != "" {
+		var err error
+		timeout, err = time.ParseDuration(arg)
+		if err != nil {
+			return stim.UsageError(fmt.Errorf("Invalid --timeout '%s': %v", arg, err))
+		}
+	}
+
+	maxTTL, err := v.breakGlassMaxTTL()
+	if err != nil {
+		return err
+	}
+
+	err = validateAccessRequest(policies, duration, maxTTL, reason)
+	if err != nil {
+		return stim.UsageError(err)
+	}
+
+	channel := v.stim.ConfigGetString("vault.break-glass.channel")
+	if channel == "" {
+		return stim.ConfigError(errors.New("No Slack channel for access requests, set vault.break-glass.channel"))
+	}
+
+	vault, err := v.stim.NewVault()
+	if err != nil {
+		return err
+	}
+	identity, err := vault.LookupSelfIdentity()
+	if err != nil {
+		return err
+	}
+	if identity.EntityID == "" {
+		return stim.AuthError(errors.New("Break-glass access needs a Vault token with an identity entity, log in with your own user"))
+	}
+	requester := identity.Username
+	if requester == "" {
+		requester = identity.DisplayName
+	}
+
+	slackClient, err := v.stim.NewSlack()
+	if err != nil {
+		return err
+	}
+
+	now := v.stim.Clock().Now()
+	id, err := newAccessRequestID(now)
+	if err != nil {
+		return err
+	}
+	request := &accessRequest{
+		ID:           id,
+		EntityID:     identity.EntityID,
+		Requester:    requester,
+		Policies:     policies,
+		Duration:     duration,
+		Reason:       reason,
+		Status:       accessPending,
+		RequestedAt:  now,
+		ExpiresAt:    now.Add(timeout),
+		SlackChannel: channel,
+	}
+
+	// The message is posted first so that the record is only written once:
+	// requesters can create their record but not change it
+	request.SlackTS, err = slackClient.PostMessage(&slack.Message{Channel: channel, Text: request.text()})
+	if err != nil {
+		return fmt.Errorf("Error posting access request: %v", err)
+	}
+	err = vault.WriteSecretKeys(v.breakGlassPath(request.EntityID, id), request.keys())
+	if err != nil {
+		v.postAccessReply(slackClient, request, ":warning: Cancelled, the request could not be recorded")
+		return fmt.Errorf("Error recording access request: %v", err)
+	}
+
+	v.notifyAccess("request", request, fmt.Sprintf("%s requested break-glass access to %s for %s", requester, strings.Join(policies, ", "), duration), notify.StatusInfo)
+
+	log.Info("Requested access {}, waiting up to {} for approval in Slack channel {}", id, v.stim.FormatDuration(timeout), channel)
+	for {
+		keys, err := vault.GetSecretKeys(v.breakGlassPath(request.EntityID, id))
+		if err == nil {
+			var current *accessRequest
+			current, err = parseAccessRequest(request.EntityID, id, keys)
+			if err == nil {
+				request = current
+			}
+		}
+		if err != nil {
+			log.Warn("Unable to read access request: {}", err)
+		}
+
+		switch {
+		case request.Status == accessDenied:
+			return fmt.Errorf("Access request denied by %s", request.DecidedBy)
+		case request.Status == accessApproved:
+			return v.claimAccess(vault, request)
+		case request.Status != accessPending:
+			return fmt.Errorf("Access request is %s", request.Status)
+		case !v.stim.Clock().Now().Before(request.ExpiresAt):
+			v.postAccessReply(slackClient, request, ":hourglass: Expired")
+			return fmt.Errorf("Access request was not approved within %s", v.stim.FormatDuration(timeout))
+		}
+
+		err = v.stim.Sleep(accessPollInterval)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// claimAccess unwraps and prints the token of an approved request
+func (v *Vault) claimAccess(vault *stimvault.Vault, request *accessRequest) error {
+
+	log := v.stim.GetLogger()
+
+	token, err := vault.UnwrapToken(request.WrapToken)
+	if err != nil {
+		return fmt.Errorf("Error unwrapping the access token: %v", err)
+	}
+
+	log.Info("Access request {} approved by {}", request.ID, request.DecidedBy)
+
+	fmt.Printf("Token:       %s\n", token.Token)
+	fmt.Printf("Accessor:    %s\n", token.Accessor)
+	fmt.Printf("Expires:     %s\n", v.stim.FormatTime(v.stim.Clock().Now().Add(token.TTL)))
+	fmt.Printf("Policies:    %s\n", strings.Join(token.Policies, ", "))
+
+	return nil
+}
+
+// breakGlassMaxTTL returns the longest access that can be requested
+func (v *Vault) breakGlassMaxTTL() (time.Duration, error) {
+	arg := v.stim.ConfigGetString("vault.break-glass.max-ttl")
+	if arg == "" {
+		return defaultBreakGlassMaxTTL, nil
+	}
+	maxTTL, err := time.ParseDuration(arg)
+	if err != nil {
+		return 0, stim.ConfigError(fmt.Errorf("Invalid vault.break-glass.max-ttl '%s': %v", arg, err))
+	}
+	return maxTTL, nil
+}
+
+// accessVault is the part of Vault that access requests are decided with
+type accessVault interface {
+	GetSecretKeys(path string) (map[string]string, error)
+	WriteSecretKeys(path string, keys map[string]string) error
+	GetEntityNames(entityID string) ([]string, error)
+	CreateToken(options *stimvault.TokenCreateOptions) (*stimvault.CreatedToken, error)
+}
+
+// accessSlack is the part of Slack that access requests are decided with
+type accessSlack interface {
+	GetMessageText(channelID string, timestamp string) (string, error)
+	GetUserID(user string) (string, error)
+	GetUserName(id string) (string, error)
+	PostMessage(msg *slack.Message) (string, error)
+}
+
+// accessApprover decides access requests from the reactions to their Slack
+// messages.  It runs in `stim slack serve` with a Vault token that can read
+// and update every request record and create break-glass tokens, so that
+// requesters never hold the power to issue their own token.
+type accessApprover struct {
+	vault     *Vault
+	store     accessVault
+	chat      accessSlack
+	channel   string
+	channelID string
+
+	// mu makes sure that concurrent reactions can't both decide a request
+	mu sync.Mutex
+}
+
+// AccessReactionHandler returns the handler of the Slack reactions that
+// approve or deny access requests in the `vault.break-glass.channel` channel
+func (v *Vault) AccessReactionHandler(vault *stimvault.Vault, slackClient *slack.Slack) (slack.ReactionHandler, error) {
+
+	channel := v.stim.ConfigGetString("vault.break-glass.channel")
+	if channel == "" {
+		return nil, stim.ConfigError(errors.New("No Slack channel for access requests, set vault.break-glass.channel"))
+	}
+	channelID, err := slackClient.GetChannelID(channel)
+	if err != nil {
+		return nil, err
+	}
+
+	approver := &accessApprover{vault: v, store: vault, chat: slackClient, channel: channel, channelID: channelID}
+	return approver.handleReaction, nil
+}
+
+// handleReaction decides the access request of the message that was reacted
+// to, if any.  Problems are replied in the thread of the request.
+func (a *accessApprover) handleReaction(event *slack.ReactionAddedEvent) {
+
+	log := a.vault.stim.GetLogger()
+
+	if event.Item.Type != "message" || event.Item.Channel != a.channelID {
+		return
+	}
+	if event.Reaction != accessApproveReaction && event.Reaction != accessDenyReaction {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	request, err := a.readRequest(event.Item.TS)
+	if err != nil {
+		log.Warn("Unable to decide access request: {}", err)
+		return
+	}
+	if request == nil {
+		return
+	}
+
+	err = a.decide(request, event.User, event.Reaction)
+	if err != nil {
+		log.Warn("Unable to decide access request {}: {}", request.ID, err)
+		a.vault.postAccessReply(a.chat, request, ":warning: "+err.Error())
+	}
+}
+
+// readRequest reads the access request of a Slack message, or nil if the
+// message isn't one.  The record must point back to the message, and the
+// message must show what the record asks for.
+func (a *accessApprover) readRequest(ts string) (*accessRequest, error) {
+
+	text, err := a.chat.GetMessageText(a.channelID, ts)
+	if err != nil {
+		return nil, err
+	}
+	entityID, id, ok := parseAccessLocator(text)
+	if !ok {
+		return nil, nil
+	}
+
+	keys, err := a.store.GetSecretKeys(a.vault.breakGlassPath(entityID, id))
+	if err != nil {
+		return nil, fmt.Errorf("Error reading access request '%s': %v", id, err)
+	}
+	request, err := parseAccessRequest(entityID, id, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.SlackChannel != a.channel || request.SlackTS != ts || unlinkSlackText(text) != request.text() {
+		return nil, fmt.Errorf("The Slack message of access request '%s' doesn't match its record", id)
+	}
+
+	return request, nil
+}
+
+// decide records the decision of a reaction to a request.  The requester is
+// who the identity entity that the record is stored under says, and approved
+// requests get a response-wrapped token in the record.
+func (a *accessApprover) decide(request *accessRequest, reactor string, reaction string) error {
+
+	log := a.vault.stim.GetLogger()
+
+	names, err := a.store.GetEntityNames(request.EntityID)
+	if err != nil {
+		return fmt.Errorf("Error reading the identity of the requester: %v", err)
+	}
+	if !isEntityName(request.Requester, names) {
+		return fmt.Errorf("Requester %s is not the Vault identity that the request is recorded under", request.Requester)
+	}
+
+	var requesterSlackIDs []string
+	for _, name := range names {
+		if id, err := a.chat.GetUserID(name); err == nil {
+			requesterSlackIDs = append(requesterSlackIDs, id)
+		}
+	}
+
+	now := a.vault.stim.Clock().Now()
+	status, err := decideAccessReaction(request, requesterSlackIDs, reactor, reaction, now)
+	if err != nil || status == "" {
+		return err
+	}
+
+	decidedBy, err := a.chat.GetUserName(reactor)
+	if err != nil {
+		decidedBy = reactor
+	}
+
+	path := a.vault.breakGlassPath(request.EntityID, request.ID)
+	request.Status = status
+	if status == accessExpired {
+		err = a.store.WriteSecretKeys(path, request.keys())
+		if err != nil {
+			return fmt.Errorf("Error recording the expiry: %v", err)
+		}
+		a.vault.postAccessReply(a.chat, request, ":hourglass: Expired")
+		return nil
+	}
+	request.DecidedBy = decidedBy
+	request.DecidedAt = now
+
+	if status == accessDenied {
+		err = a.store.WriteSecretKeys(path, request.keys())
+		if err != nil {
+			return fmt.Errorf("Error recording the denial: %v", err)
+		}
+		log.Info("Denied access request {} of {}", request.ID, request.Requester)
+		a.vault.notifyAccess("deny", request, fmt.Sprintf("%s denied break-glass access of %s to %s", decidedBy, request.Requester, strings.Join(request.Policies, ", ")), notify.StatusFailure)
+		a.vault.postAccessReply(a.chat, request, ":x: Denied by "+decidedBy)
+		return nil
+	}
+
+	token, err := a.store.CreateToken(&stimvault.TokenCreateOptions{
+		Policies:       request.Policies,
+		TTL:            request.Duration,
+		ExplicitMaxTTL: request.Duration,
+		NotRenewable:   true,
+		DisplayName:    "break-glass-" + request.Requester,
+		Metadata: map[string]string{
+			"requested-by": request.Requester,
+			"approved-by":  decidedBy,
+			"reason":       request.Reason,
+			"request-id":   request.ID,
+			"created-with": "stim",
+		},
+		Role:    a.vault.stim.ConfigGetString("vault.break-glass.token-role"),
+		WrapTTL: accessWrapTTL,
+	})
+	if err != nil {
+		return err
+	}
+
+	request.Accessor = token.Accessor
+	request.WrapToken = token.WrapToken
+	err = a.store.WriteSecretKeys(path, request.keys())
+	if err != nil {
+		return fmt.Errorf("Error recording the approval: %v", err)
+	}
+
+	log.Info("Approved access request {} of {} (token accessor {})", request.ID, request.Requester, token.Accessor)
+	a.vault.notifyAccess("approve", request, fmt.Sprintf("%s approved break-glass access of %s to %s for %s", decidedBy, request.Requester, strings.Join(request.Policies, ", "), request.Duration), notify.StatusSuccess)
+	a.vault.postAccessReply(a.chat, request, fmt.Sprintf(":white_check_mark: Approved by %s.  Access expires in %s.", decidedBy, utils.HumanizeDuration(request.Duration)))
+
+	return nil
+}
+
+// postAccessReply replies in the Slack thread of an access request
+func (v *Vault) postAccessReply(chat accessSlack, request *accessRequest, text string) {
+	_, err := chat.PostMessage(&slack.Message{Channel: request.SlackChannel, Text: text, ThreadTS: request.SlackTS})
+	if err != nil {
+		v.stim.GetLogger().Warn("Unable to post the access request result: {}", err)
+	}
+}
+
+// notifyAccess sends a `vault.access.<event>` notification about an access
+// request
+func (v *Vault) notifyAccess(event string, request *accessRequest, title string, status string) {
+
+	fields := map[string]string{
+		"Request":   request.ID,
+		"Requester": request.Requester,
+		"Policies":  strings.Join(request.Policies, ", "),
+		"Duration":  request.Duration.String(),
+		"Reason":    request.Reason,
+	}
+	if request.DecidedBy != "" {
+		fields["Decided By"] = request.DecidedBy
+	}
+
+	err := v.stim.Notify("vault.access."+event, &notify.Payload{Title: title, Status: status, Fields: fields})
+	if err != nil {
+		v.stim.GetLogger().Warn("Unable to send access request notification: {}", err)
+	}
+}
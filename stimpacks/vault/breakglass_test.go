@@ -0,0 +1,288 @@
+package vault
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/clock"
+	"github.com/PremiereGlobal/stim/pkg/slack"
+	stimvault "github.com/PremiereGlobal/stim/pkg/vault"
+	"github.com/PremiereGlobal/stim/stim"
+	"gotest.tools/assert"
+)
+
+func TestAccessRequestKeys(t *testing.T) {
+	requested := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	request := &accessRequest{
+		ID:           "20240301-120000-a1b2c3",
+		EntityID:     "0c6a9a9e-1b2c-4d5e-8f90-a1b2c3d4e5f6",
+		Requester:    "alice",
+		Policies:     []string{"prod-admin", "prod-db"},
+		Duration:     time.Hour,
+		Reason:       "INC-123",
+		Status:       accessApproved,
+		RequestedAt:  requested,
+		ExpiresAt:    requested.Add(30 * time.Minute),
+		DecidedBy:    "bob",
+		DecidedAt:    requested.Add(5 * time.Minute),
+		Accessor:     "accessor",
+		WrapToken:    "wrap",
+		SlackChannel: "incidents",
+		SlackTS:      "1709294400.000100",
+	}
+
+	keys := request.keys()
+	assert.Equal(t, keys["policies"], "prod-admin,prod-db")
+	assert.Equal(t, keys["duration"], "1h0m0s")
+	assert.Equal(t, keys["decided-at"], "2024-03-01T12:05:00Z")
+	assert.Equal(t, keys["expires-at"], "2024-03-01T12:30:00Z")
+
+	parsed, err := parseAccessRequest(request.EntityID, request.ID, keys)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, parsed, request)
+
+	_, err = parseAccessRequest(request.EntityID, "other", map[string]string{"user": "app"})
+	assert.ErrorContains(t, err, "is not a break-glass access request")
+}
+
+func TestValidateAccessRequest(t *testing.T) {
+	assert.NilError(t, validateAccessRequest([]string{"prod-admin"}, time.Hour, 4*time.Hour, "INC-123"))
+	assert.ErrorContains(t, validateAccessRequest(nil, time.Hour, 4*time.Hour, "INC-123"), "--policy")
+	assert.ErrorContains(t, validateAccessRequest([]string{"root"}, time.Hour, 4*time.Hour, "INC-123"), "`root` policy")
+	assert.ErrorContains(t, validateAccessRequest([]string{"prod-*"}, time.Hour, 4*time.Hour, "INC-123"), "Invalid policy name 'prod-*'")
+	assert.ErrorContains(t, validateAccessRequest([]string{"prod-admin"}, 0, 4*time.Hour, "INC-123"), "--duration")
+	assert.ErrorContains(t, validateAccessRequest([]string{"prod-admin"}, 8*time.Hour, 4*time.Hour, "INC-123"), "can't be longer than 4h0m0s")
+	assert.ErrorContains(t, validateAccessRequest([]string{"prod-admin"}, time.Hour, 4*time.Hour, " "), "--reason")
+}
+
+func TestNewAccessRequestID(t *testing.T) {
+	id, err := newAccessRequestID(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	assert.NilError(t, err)
+	assert.Assert(t, len(id) == len("20240301-120000-a1b2c3"))
+	assert.Equal(t, id[:16], "20240301-120000-")
+}
+
+func TestParseAccessLocator(t *testing.T) {
+	request := &accessRequest{
+		ID:        "20240301-120000-a1b2c3",
+		EntityID:  "0c6a9a9e-1b2c-4d5e-8f90-a1b2c3d4e5f6",
+		Requester: "alice",
+		Policies:  []string{"prod-admin"},
+		Duration:  time.Hour,
+		Reason:    "INC-123 <https://status.my-domain.com> & more",
+		ExpiresAt: time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC),
+	}
+
+	entityID, id, ok := parseAccessLocator(request.text())
+	assert.Assert(t, ok)
+	assert.Equal(t, entityID, request.EntityID)
+	assert.Equal(t, id, request.ID)
+
+	_, _, ok = parseAccessLocator("Request `../other/20240301-120000-a1b2c3`")
+	assert.Assert(t, !ok)
+
+	// Slack keeps the escaped text and links the URLs
+	posted := strings.Replace(request.text(), "https://status.my-domain.com", "<https://status.my-domain.com>", 1)
+	assert.Equal(t, unlinkSlackText(posted), request.text())
+}
+
+func TestDecideAccessReaction(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 10, 0, 0, time.UTC)
+	pending := func() *accessRequest {
+		return &accessRequest{ID: "r1", Requester: "alice", Status: accessPending, ExpiresAt: now.Add(20 * time.Minute)}
+	}
+
+	status, err := decideAccessReaction(pending(), []string{"UALICE"}, "UBOB", accessApproveReaction, now)
+	assert.NilError(t, err)
+	assert.Equal(t, status, accessApproved)
+
+	status, err = decideAccessReaction(pending(), []string{"UALICE"}, "UBOB", accessDenyReaction, now)
+	assert.NilError(t, err)
+	assert.Equal(t, status, accessDenied)
+
+	status, err = decideAccessReaction(pending(), []string{"UALICE"}, "UBOB", "eyes", now)
+	assert.NilError(t, err)
+	assert.Equal(t, status, "")
+
+	_, err = decideAccessReaction(pending(), []string{"UALICE"}, "UALICE", accessApproveReaction, now)
+	assert.Error(t, err, "Access requests must be approved or denied by someone other than the requester")
+
+	// Without the requester's Slack user, self-approval can't be ruled out
+	_, err = decideAccessReaction(pending(), nil, "UBOB", accessApproveReaction, now)
+	assert.ErrorContains(t, err, "No Slack user found for requester alice")
+
+	status, err = decideAccessReaction(pending(), []string{"UALICE"}, "UBOB", accessApproveReaction, now.Add(20*time.Minute))
+	assert.NilError(t, err)
+	assert.Equal(t, status, accessExpired)
+
+	denied := pending()
+	denied.Status = accessDenied
+	_, err = decideAccessReaction(denied, []string{"UALICE"}, "UBOB", accessApproveReaction, now)
+	assert.Error(t, err, "Access request r1 is already denied")
+}
+
+// fakeAccessVault records access requests and created tokens in memory
+type fakeAccessVault struct {
+	secrets map[string]map[string]string
+	names   []string
+	created []*stimvault.TokenCreateOptions
+}
+
+func (f *fakeAccessVault) GetSecretKeys(path string) (map[string]string, error) {
+	keys, ok := f.secrets[path]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return keys, nil
+}
+
+func (f *fakeAccessVault) WriteSecretKeys(path string, keys map[string]string) error {
+	f.secrets[path] = keys
+	return nil
+}
+
+func (f *fakeAccessVault) GetEntityNames(entityID string) ([]string, error) {
+	return f.names, nil
+}
+
+func (f *fakeAccessVault) CreateToken(options *stimvault.TokenCreateOptions) (*stimvault.CreatedToken, error) {
+	f.created = append(f.created, options)
+	return &stimvault.CreatedToken{Accessor: "accessor", WrapToken: "wrap"}, nil
+}
+
+// fakeAccessSlack has a single message and the users alice and bob
+type fakeAccessSlack struct {
+	text    string
+	replies []string
+}
+
+func (f *fakeAccessSlack) GetMessageText(channelID string, timestamp string) (string, error) {
+	if channelID != "C1" || timestamp != "1709294400.000100" {
+		return "", errors.New("not found")
+	}
+	return f.text, nil
+}
+
+func (f *fakeAccessSlack) GetUserID(user string) (string, error) {
+	switch user {
+	case "alice":
+		return "UALICE", nil
+	case "bob":
+		return "UBOB", nil
+	}
+	return "", errors.New("not found")
+}
+
+func (f *fakeAccessSlack) GetUserName(id string) (string, error) {
+	return strings.ToLower(strings.TrimPrefix(id, "U")), nil
+}
+
+func (f *fakeAccessSlack) PostMessage(msg *slack.Message) (string, error) {
+	f.replies = append(f.replies, msg.Text)
+	return "", nil
+}
+
+func TestAccessApprover(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 10, 0, 0, time.UTC)
+	recordPath := "secret/stim/break-glass/0c6a9a9e-1b2c-4d5e-8f90-a1b2c3d4e5f6/20240301-120000-a1b2c3"
+
+	setup := func() (*accessApprover, *fakeAccessVault, *fakeAccessSlack, *clock.Fake) {
+		s := stim.New()
+		fake := clock.NewFake(now)
+		s.SetClock(fake)
+		s.ConfigOverride("vault.break-glass.token-role", "break-glass")
+
+		request := &accessRequest{
+			ID:           "20240301-120000-a1b2c3",
+			EntityID:     "0c6a9a9e-1b2c-4d5e-8f90-a1b2c3d4e5f6",
+			Requester:    "alice",
+			Policies:     []string{"prod-admin"},
+			Duration:     time.Hour,
+			Reason:       "INC-123",
+			Status:       accessPending,
+			RequestedAt:  now,
+			ExpiresAt:    now.Add(30 * time.Minute),
+			SlackChannel: "incidents",
+			SlackTS:      "1709294400.000100",
+		}
+		store := &fakeAccessVault{
+			secrets: map[string]map[string]string{recordPath: request.keys()},
+			names:   []string{"entity_0c6a9a9e", "alice"},
+		}
+		chat := &fakeAccessSlack{text: request.text()}
+		approver := &accessApprover{vault: &Vault{stim: s}, store: store, chat: chat, channel: "incidents", channelID: "C1"}
+		return approver, store, chat, fake
+	}
+	react := func(approver *accessApprover, user string, reaction string) {
+		event := &slack.ReactionAddedEvent{User: user, Reaction: reaction}
+		event.Item.Type = "message"
+		event.Item.Channel = "C1"
+		event.Item.TS = "1709294400.000100"
+		approver.handleReaction(event)
+	}
+
+	// Approve
+	approver, store, chat, _ := setup()
+	react(approver, "UBOB", accessApproveReaction)
+	assert.Equal(t, store.secrets[recordPath]["status"], accessApproved)
+	assert.Equal(t, store.secrets[recordPath]["decided-by"], "bob")
+	assert.Equal(t, store.secrets[recordPath]["wrap-token"], "wrap")
+	assert.Equal(t, len(store.created), 1)
+	assert.DeepEqual(t, store.created[0].Policies, []string{"prod-admin"})
+	assert.Equal(t, store.created[0].Role, "break-glass")
+	assert.Equal(t, store.created[0].ExplicitMaxTTL, time.Hour)
+	assert.Equal(t, store.created[0].Metadata["approved-by"], "bob")
+	assert.DeepEqual(t, chat.replies, []string{":white_check_mark: Approved by bob.  Access expires in 1h."})
+
+	// A second reaction doesn't issue another token
+	react(approver, "UCAROL", accessApproveReaction)
+	assert.Equal(t, len(store.created), 1)
+	assert.Equal(t, chat.replies[1], ":warning: Access request 20240301-120000-a1b2c3 is already approved")
+
+	// Deny
+	approver, store, chat, _ = setup()
+	react(approver, "UBOB", accessDenyReaction)
+	assert.Equal(t, store.secrets[recordPath]["status"], accessDenied)
+	assert.Equal(t, store.secrets[recordPath]["wrap-token"], "")
+	assert.Equal(t, len(store.created), 0)
+	assert.DeepEqual(t, chat.replies, []string{":x: Denied by bob"})
+
+	// Self-approval
+	approver, store, chat, _ = setup()
+	react(approver, "UALICE", accessApproveReaction)
+	assert.Equal(t, store.secrets[recordPath]["status"], accessPending)
+	assert.Equal(t, len(store.created), 0)
+	assert.DeepEqual(t, chat.replies, []string{":warning: Access requests must be approved or denied by someone other than the requester"})
+
+	// Expiry
+	approver, store, chat, fake := setup()
+	fake.Advance(30 * time.Minute)
+	react(approver, "UBOB", accessApproveReaction)
+	assert.Equal(t, store.secrets[recordPath]["status"], accessExpired)
+	assert.Equal(t, len(store.created), 0)
+	assert.DeepEqual(t, chat.replies, []string{":hourglass: Expired"})
+
+	// A requester claiming another identity
+	approver, store, chat, _ = setup()
+	store.names = []string{"mallory"}
+	react(approver, "UBOB", accessApproveReaction)
+	assert.Equal(t, store.secrets[recordPath]["status"], accessPending)
+	assert.DeepEqual(t, chat.replies, []string{":warning: Requester alice is not the Vault identity that the request is recorded under"})
+
+	// A record changed after its message was posted
+	approver, store, chat, _ = setup()
+	store.secrets[recordPath]["policies"] = "prod-admin,prod-db"
+	react(approver, "UBOB", accessApproveReaction)
+	assert.Equal(t, len(store.created), 0)
+	assert.Equal(t, len(chat.replies), 0)
+
+	// Other reactions and channels are ignored
+	approver, store, chat, _ = setup()
+	react(approver, "UBOB", "eyes")
+	approver.channelID = "C2"
+	react(approver, "UBOB", accessApproveReaction)
+	assert.Equal(t, store.secrets[recordPath]["status"], accessPending)
+	assert.Equal(t, len(chat.replies), 0)
+}
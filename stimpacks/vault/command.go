@@ -31,8 +31,8 @@ func (v *Vault) Command(viper *viper.Viper) *cobra.Command {
 		Use:   "login",
 		Short: "login to Vault",
 		Long:  "Login and obtain a token from Vault",
-		Run: func(cmd *cobra.Command, args []string) {
-			v.Login()
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Login()
 		},
 	}
 
@@ -40,5 +40,300 @@ func (v *Vault) Command(viper *viper.Viper) *cobra.Command {
 	viper.BindPFlag("vault-initial-token-duration", loginCmd.Flags().Lookup("token-duration"))
 
 	v.stim.BindCommand(loginCmd, vaultCmd)
+
+	var tokenCmd = &cobra.Command{
+		Use:   "token",
+		Short: "Vault token helper",
+		Long:  "Inspect the cached Vault token and create tokens for automation",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	var tokenStatusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "Show the current token status",
+		Long:  "Show the remaining TTL, renewability and policies of the current Vault token",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.TokenStatus()
+		},
+	}
+
+	tokenStatusCmd.Flags().StringP("output", "o", "text", "Output format (text or json)")
+	viper.BindPFlag("vault-token-status-output", tokenStatusCmd.Flags().Lookup("output"))
+	v.stim.BindCommand(tokenStatusCmd, tokenCmd)
+
+	var tokenCreateCmd = &cobra.Command{
+		Use:         "create",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Create a token for automation",
+		Long:        "Create a child token with the given policies, TTL and use limit.  Requires `sudo` on `auth/token/create` in Vault",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.TokenCreate()
+		},
+	}
+
+	tokenCreateCmd.Flags().StringSlice("policy", []string{}, "Policy to attach to the token.  Can be repeated")
+	viper.BindPFlag("vault-token-create-policies", tokenCreateCmd.Flags().Lookup("policy"))
+	tokenCreateCmd.Flags().String("ttl", "", "TTL of the token (ex. 24h).  Defaults to the Vault default TTL")
+	viper.BindPFlag("vault-token-create-ttl", tokenCreateCmd.Flags().Lookup("ttl"))
+	tokenCreateCmd.Flags().Int("use-limit", 0, "Number of times the token can be used (0 is unlimited)")
+	viper.BindPFlag("vault-token-create-use-limit", tokenCreateCmd.Flags().Lookup("use-limit"))
+	tokenCreateCmd.Flags().String("display-name", "", "Display name of the token (ex. the script that will use it)")
+	viper.BindPFlag("vault-token-create-display-name", tokenCreateCmd.Flags().Lookup("display-name"))
+
+	v.stim.BindCommand(tokenCreateCmd, tokenCmd)
+	v.stim.BindCommand(tokenCmd, vaultCmd)
+
+	var requestAccessCmd = &cobra.Command{
+		Use:         "request-access",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Request time-boxed elevated access (break-glass)",
+		Long:        "Request a token with elevated Vault policies for a limited time, ex. during an incident.  The request is recorded in Vault and posted to the `vault.break-glass.channel` Slack channel, and must be approved by a second person by reacting with :white_check_mark: (or denied with :x:), which `stim slack serve` picks up.  The token is printed once approved and expires after the requested duration",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.RequestAccess()
+		},
+	}
+
+	requestAccessCmd.Flags().StringSlice("policy", []string{}, "Required. Policy to request.  Can be repeated")
+	viper.BindPFlag("vault-request-access-policies", requestAccessCmd.Flags().Lookup("policy"))
+	requestAccessCmd.Flags().String("duration", "", "Required. How long the access is needed for (ex. 1h)")
+	viper.BindPFlag("vault-request-access-duration", requestAccessCmd.Flags().Lookup("duration"))
+	requestAccessCmd.Flags().String("reason", "", "Required. Reason for the access (ex. the incident)")
+	viper.BindPFlag("vault-request-access-reason", requestAccessCmd.Flags().Lookup("reason"))
+	requestAccessCmd.Flags().String("timeout", "", "How long to wait for approval (default 30m)")
+	viper.BindPFlag("vault-request-access-timeout", requestAccessCmd.Flags().Lookup("timeout"))
+
+	v.stim.BindCommand(requestAccessCmd, vaultCmd)
+
+	var readCmd = &cobra.Command{
+		Use:   "read <path>",
+		Short: "Read a secret",
+		Long:  "Print the keys of a secret.  For KV v2 secrets a specific version can be read, negative versions go back from the latest version.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.Read(args[0])
+		},
+	}
+
+	readCmd.Flags().Int("secret-version", 0, "Version of a KV v2 secret to read (ex. 3, or -1 for the version before the latest)")
+	viper.BindPFlag("vault-read-version", readCmd.Flags().Lookup("secret-version"))
+	readCmd.Flags().StringP("key", "k", "", "Only print the value of this key")
+	viper.BindPFlag("vault-read-key", readCmd.Flags().Lookup("key"))
+
+	v.stim.BindCommand(readCmd, vaultCmd)
+
+	var kvCmd = &cobra.Command{
+		Use:   "kv",
+		Short: "KV v2 secret version helpers",
+		Long:  "Compare and roll back versions of secrets on KV v2 mounts",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	var kvDiffCmd = &cobra.Command{
+		Use:   "diff <path>",
+		Short: "Show the changes between two versions of a secret",
+		Long:  "Show the keys that were added, removed or changed between two versions of a KV v2 secret.  Defaults to the version before the latest and the latest version.  Values are hidden unless --show-values is given",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.KVDiff(args[0])
+		},
+	}
+
+	kvDiffCmd.Flags().StringSlice("versions", nil, "The two versions to compare (ex. 3,4).  0 is the latest version and negative versions go back from the latest version")
+	viper.BindPFlag("vault-kv-diff-versions", kvDiffCmd.Flags().Lookup("versions"))
+	kvDiffCmd.Flags().Bool("show-values", false, "Show the secret values")
+	viper.BindPFlag("vault-kv-diff-show-values", kvDiffCmd.Flags().Lookup("show-values"))
+
+	var kvRollbackCmd = &cobra.Command{
+		Use:         "rollback <path>",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Roll back a secret to an earlier version",
+		Long:        "Write an earlier version of a KV v2 secret as its new latest version.  The changes are shown before confirming",
+		Args:        cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.KVRollback(args[0])
+		},
+	}
+
+	kvRollbackCmd.Flags().Int("to", 0, "Required. The version to roll back to (ex. 3, or -1 for the version before the latest)")
+	viper.BindPFlag("vault-kv-rollback-to", kvRollbackCmd.Flags().Lookup("to"))
+	kvRollbackCmd.Flags().Bool("show-values", false, "Show the secret values")
+	viper.BindPFlag("vault-kv-rollback-show-values", kvRollbackCmd.Flags().Lookup("show-values"))
+	kvRollbackCmd.Flags().BoolP("yes", "y", false, "Skip the confirmation prompt")
+	viper.BindPFlag("vault-kv-rollback-yes", kvRollbackCmd.Flags().Lookup("yes"))
+
+	v.stim.BindCommand(kvDiffCmd, kvCmd)
+	v.stim.BindCommand(kvRollbackCmd, kvCmd)
+	v.stim.BindCommand(kvCmd, vaultCmd)
+
+	var checkAccessCmd = &cobra.Command{
+		Use:   "check-access",
+		Short: "Check the token's capabilities on the paths of a deploy",
+		Long:  "Check that the current Vault token has the capabilities needed on every Vault path a deploy config touches (Vault secrets, kube-config secrets, freeze windows and the GitHub, release and Datadog tokens) and print a pass/fail matrix.  Exits with an error if any check fails",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.CheckAccess()
+		},
+	}
+
+	checkAccessCmd.Flags().StringP("deploy-file", "f", "", "Deployment file (defaults to the `stim deploy` default)")
+	viper.BindPFlag("vault-check-access-file", checkAccessCmd.Flags().Lookup("deploy-file"))
+	checkAccessCmd.Flags().StringP("environment", "e", "", "Only check this environment")
+	viper.BindPFlag("vault-check-access-environment", checkAccessCmd.Flags().Lookup("environment"))
+	checkAccessCmd.Flags().StringP("instance", "i", "", "Only check this instance")
+	viper.BindPFlag("vault-check-access-instance", checkAccessCmd.Flags().Lookup("instance"))
+	checkAccessCmd.Flags().StringP("output", "o", "table", "Output format (table or json)")
+	viper.BindPFlag("vault-check-access-output", checkAccessCmd.Flags().Lookup("output"))
+
+	v.stim.BindCommand(checkAccessCmd, vaultCmd)
+
+	var sshCmd = &cobra.Command{
+		Use:   "ssh",
+		Short: "SSH certificate helper",
+		Long:  "Sign SSH keys with the SSH CA of Vault",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	var sshSignCmd = &cobra.Command{
+		Use:   "sign",
+		Short: "Sign an SSH public key",
+		Long:  "Sign an SSH public key with a role of the SSH secrets engine (the `vault.ssh.mount` mount, default `ssh-client-signer`) and write the certificate next to the key (ex. `~/.ssh/id_ed25519-cert.pub`).  Defaults to the first of `~/.ssh/id_ed25519.pub`, `id_ecdsa.pub` and `id_rsa.pub`.  With --agent the key and certificate are added to ssh-agent, starting one if needed",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.SSHSign()
+		},
+	}
+
+	sshSignCmd.Flags().StringP("public-key", "k", "", "SSH public key to sign (ex. ~/.ssh/id_ed25519.pub)")
+	viper.BindPFlag("vault-ssh-public-key", sshSignCmd.Flags().Lookup("public-key"))
+	sshSignCmd.Flags().StringP("role", "r", "", "Role to sign the key with (defaults to `vault.ssh.role`, or prompts)")
+	viper.BindPFlag("vault-ssh-role", sshSignCmd.Flags().Lookup("role"))
+	sshSignCmd.Flags().String("ttl", "", "TTL of the certificate (ex. 8h).  Defaults to the role's TTL")
+	viper.BindPFlag("vault-ssh-ttl", sshSignCmd.Flags().Lookup("ttl"))
+	sshSignCmd.Flags().StringSlice("principals", []string{}, "Principals (usernames) to request.  Defaults to the role's default principals")
+	viper.BindPFlag("vault-ssh-principals", sshSignCmd.Flags().Lookup("principals"))
+	sshSignCmd.Flags().Bool("agent", false, "Add the key and certificate to ssh-agent for the lifetime of the certificate")
+	viper.BindPFlag("vault-ssh-agent", sshSignCmd.Flags().Lookup("agent"))
+
+	v.stim.BindCommand(sshSignCmd, sshCmd)
+	v.stim.BindCommand(sshCmd, vaultCmd)
+
+	var pkiCmd = &cobra.Command{
+		Use:   "pki",
+		Short: "PKI certificate helper",
+		Long:  "Issue TLS certificates from the PKI secrets engine of Vault",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	var pkiIssueCmd = &cobra.Command{
+		Use:         "issue",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Issue a certificate",
+		Long:        "Issue a certificate with a role of the PKI secrets engine (the `vault.pki.mount` mount, default `pki`) and write `<common-name>.crt`, `.key` and `-chain.crt` files, or a Kubernetes TLS secret in the current context with --to-k8s-secret.  With --renew-before an existing certificate is only replaced when it expires within that window",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.PKIIssue()
+		},
+	}
+
+	pkiIssueCmd.Flags().StringP("role", "r", "", "Role to issue the certificate with (defaults to `vault.pki.role`)")
+	viper.BindPFlag("vault-pki-role", pkiIssueCmd.Flags().Lookup("role"))
+	pkiIssueCmd.Flags().StringP("common-name", "n", "", "Required. Common name of the certificate (ex. foo.example.com)")
+	viper.BindPFlag("vault-pki-common-name", pkiIssueCmd.Flags().Lookup("common-name"))
+	pkiIssueCmd.Flags().StringSlice("alt-names", []string{}, "Additional DNS names of the certificate")
+	viper.BindPFlag("vault-pki-alt-names", pkiIssueCmd.Flags().Lookup("alt-names"))
+	pkiIssueCmd.Flags().String("ttl", "", "TTL of the certificate (ex. 720h).  Defaults to the role's TTL")
+	viper.BindPFlag("vault-pki-ttl", pkiIssueCmd.Flags().Lookup("ttl"))
+	pkiIssueCmd.Flags().String("out-dir", ".", "Directory to write the certificate, key and chain files to")
+	viper.BindPFlag("vault-pki-out-dir", pkiIssueCmd.Flags().Lookup("out-dir"))
+	pkiIssueCmd.Flags().String("to-k8s-secret", "", "Write a TLS secret (<namespace>/<name>) in the current Kubernetes context instead of files")
+	viper.BindPFlag("vault-pki-to-k8s-secret", pkiIssueCmd.Flags().Lookup("to-k8s-secret"))
+	pkiIssueCmd.Flags().String("renew-before", "", "Only issue a certificate if the existing one expires within this duration (ex. 168h)")
+	viper.BindPFlag("vault-pki-renew-before", pkiIssueCmd.Flags().Lookup("renew-before"))
+
+	v.stim.BindCommand(pkiIssueCmd, pkiCmd)
+	v.stim.BindCommand(pkiCmd, vaultCmd)
+
+	var dbCmd = &cobra.Command{
+		Use:   "db",
+		Short: "Database credentials helper",
+		Long:  "Get dynamic database credentials from the database secrets engine of Vault",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	var dbCredsCmd = &cobra.Command{
+		Use:         "creds <role> [-- command...]",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Get dynamic database credentials",
+		Long:        "Create database credentials with a role of the database secrets engine (the `vault.database.mount` mount, default `database`) and print them.  With a command after `--`, the credentials are set in its environment, their lease is renewed while it runs and revoked when it exits (ex. `stim vault db creds app -- psql`)",
+		Args:        cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			role, command, err := databaseCredsArgs(args, cmd.ArgsLenAtDash())
+			if err != nil {
+				return err
+			}
+			return v.DatabaseCreds(role, command)
+		},
+	}
+
+	dbCredsCmd.Flags().String("mount", "", "Mount of the database secrets engine (defaults to `vault.database.mount`, or `database`)")
+	viper.BindPFlag("vault.database.mount", dbCredsCmd.Flags().Lookup("mount"))
+	dbCredsCmd.Flags().BoolP("source", "s", false, "Print the credentials as export commands for the current shell")
+	viper.BindPFlag("vault-db-source", dbCredsCmd.Flags().Lookup("source"))
+	dbCredsCmd.Flags().String("username-env", "DB_USERNAME", "Env var of the username")
+	viper.BindPFlag("vault-db-username-env", dbCredsCmd.Flags().Lookup("username-env"))
+	dbCredsCmd.Flags().String("password-env", "DB_PASSWORD", "Env var of the password")
+	viper.BindPFlag("vault-db-password-env", dbCredsCmd.Flags().Lookup("password-env"))
+	dbCredsCmd.Flags().String("ttl", "", "Increment to renew the lease by while the command runs (ex. 1h).  Defaults to the lease duration")
+	viper.BindPFlag("vault-db-ttl", dbCredsCmd.Flags().Lookup("ttl"))
+
+	v.stim.BindCommand(dbCredsCmd, dbCmd)
+	v.stim.BindCommand(dbCmd, vaultCmd)
+
+	var leasesCmd = &cobra.Command{
+		Use:   "leases",
+		Short: "Dynamic secret lease helper",
+		Long:  "List and revoke the leases of dynamic secrets (ex. AWS or database credentials) that stim commands left behind.  The leases of the dynamic secrets read by a command are revoked when it ends, unless the credentials were handed out (ex. `stim aws login`) or `vault.keep-leases` is set.",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	var leasesListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the leases left behind",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.ListLeases()
+		},
+	}
+
+	leasesListCmd.Flags().StringP("output", "o", stim.OutputTable, "Output format (table|json)")
+	viper.BindPFlag("vault-leases-output", leasesListCmd.Flags().Lookup("output"))
+
+	v.stim.BindCommand(leasesListCmd, leasesCmd)
+
+	var leasesRevokeCmd = &cobra.Command{
+		Use:         "revoke [lease-id...]",
+		Annotations: map[string]string{stim.AnnotationMutating: "true"},
+		Short:       "Revoke leases",
+		Long:        "Revoke leases by ID, or all the leases left behind with --all",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return v.RevokeLeases(args)
+		},
+	}
+
+	leasesRevokeCmd.Flags().Bool("all", false, "Revoke all the leases left behind")
+	viper.BindPFlag("vault-leases-all", leasesRevokeCmd.Flags().Lookup("all"))
+
+	v.stim.BindCommand(leasesRevokeCmd, leasesCmd)
+	v.stim.BindCommand(leasesCmd, vaultCmd)
+
 	return vaultCmd
 }
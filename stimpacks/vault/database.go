@@ -0,0 +1,132 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	vaultpkg "github.com/PremiereGlobal/stim/pkg/vault"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// defaultDatabaseMount is the mount of the database secrets engine when
+// `vault.database.mount` is not set
+const defaultDatabaseMount = "database"
+
+// DatabaseCreds gets dynamic database credentials from a role of the database
+// secrets engine.  Without a command they are printed (or exported).  With a
+// command they are set in its environment, the lease is renewed while it runs
+// and revoked once it exits.
+func (v *Vault) DatabaseCreds(role string, command []string) error {
+
+	log := v.stim.GetLogger()
+
+	var ttl time.Duration
+	if arg := v.stim.ConfigGetString("vault-db-ttl"); arg != "" {
+		var err error
+		ttl, err = time.ParseDuration(arg)
+		if err != nil {
+			return stim.UsageError(fmt.Errorf("Invalid --ttl '%s': %v", arg, err))
+		}
+	}
+
+	mount := v.stim.ConfigGetString("vault.database.mount")
+	if mount == "" {
+		mount = defaultDatabaseMount
+	}
+
+	vault, err := v.stim.NewVault()
+	if err != nil {
+		return err
+	}
+	creds, err := vault.GetDatabaseCredentials(mount, role)
+	if err != nil {
+		return err
+	}
+	envs := databaseEnvs(creds, v.stim.ConfigGetString("vault-db-username-env"), v.stim.ConfigGetString("vault-db-password-env"))
+
+	if len(command) == 0 {
+		vault.KeepLeases()
+		log.Info("Created database user {} with lease {} (expires in {}).  Revoke it with `vault lease revoke {}`", creds.Username, creds.LeaseID, creds.LeaseDuration.String(), creds.LeaseID)
+		fmt.Print(formatEnvs(envs, v.stim.ConfigGetBool("vault-db-source")))
+		return nil
+	}
+
+	// The credentials are revoked even if the command fails
+	defer func() {
+		err := vault.RevokeLease(creds.LeaseID)
+		if err != nil {
+			log.Warn("Unable to revoke the database credentials lease {}: {}", creds.LeaseID, err)
+			return
+		}
+		log.Debug("Revoked the database credentials lease {}", creds.LeaseID)
+	}()
+
+	if creds.Renewable {
+		increment := ttl
+		if increment == 0 {
+			increment = creds.LeaseDuration
+		}
+		stopRenewer := vault.StartLeaseRenewer(creds.LeaseID, creds.LeaseDuration, increment)
+		defer stopRenewer()
+	}
+
+	log.Info("Running {} as database user {}", command[0], creds.Username)
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Env = append(os.Environ(), envs...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	// Ctrl-C stops the command (ex. an interactive psql) and stim waits for
+	// it so the credentials are revoked
+	_, stop := v.stim.TrapInterrupts()
+	defer stop()
+
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return stim.NewExitError(exitErr.ExitCode(), fmt.Errorf("%s exited with code %d", command[0], exitErr.ExitCode()))
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to run %s: %v", command[0], err)
+	}
+
+	return nil
+}
+
+// databaseCredsArgs returns the role and the command of the `creds` arguments,
+// which are given after `--`.  dash is the number of arguments before `--`,
+// or -1 if there is none.
+func databaseCredsArgs(args []string, dash int) (string, []string, error) {
+	if dash == -1 {
+		dash = len(args)
+	}
+	if dash != 1 {
+		return "", nil, stim.UsageError(errors.New("Exactly one role must be given, and the command to run after `--`"))
+	}
+	return args[0], args[1:], nil
+}
+
+// databaseEnvs returns the env vars of the credentials
+func databaseEnvs(creds *vaultpkg.DatabaseCredentials, usernameEnv string, passwordEnv string) []string {
+	return []string{usernameEnv + "=" + creds.Username, passwordEnv + "=" + creds.Password}
+}
+
+// formatEnvs returns the env vars one per line, as `export` commands for the
+// shell if export is set
+func formatEnvs(envs []string, export bool) string {
+	var b strings.Builder
+	for _, env := range envs {
+		if export {
+			parts := strings.SplitN(env, "=", 2)
+			fmt.Fprintf(&b, "export %s='%s'\n", parts[0], strings.Replace(parts[1], "'", `'"'"'`, -1))
+		} else {
+			fmt.Fprintln(&b, env)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,34 @@
+package vault
+
+import (
+	"testing"
+
+	vaultpkg "github.com/PremiereGlobal/stim/pkg/vault"
+	"gotest.tools/assert"
+)
+
+func TestDatabaseCredsArgs(t *testing.T) {
+	role, command, err := databaseCredsArgs([]string{"app"}, -1)
+	assert.NilError(t, err)
+	assert.Equal(t, role, "app")
+	assert.Equal(t, len(command), 0)
+
+	role, command, err = databaseCredsArgs([]string{"app", "psql", "-h", "db"}, 1)
+	assert.NilError(t, err)
+	assert.Equal(t, role, "app")
+	assert.DeepEqual(t, command, []string{"psql", "-h", "db"})
+
+	// The command must be after `--`
+	_, _, err = databaseCredsArgs([]string{"app", "psql"}, -1)
+	assert.ErrorContains(t, err, "Exactly one role")
+	_, _, err = databaseCredsArgs([]string{"psql"}, 0)
+	assert.ErrorContains(t, err, "Exactly one role")
+}
+
+func TestFormatEnvs(t *testing.T) {
+	envs := databaseEnvs(&vaultpkg.DatabaseCredentials{Username: "v-app-x1", Password: "it's-secret"}, "PGUSER", "PGPASSWORD")
+	assert.DeepEqual(t, envs, []string{"PGUSER=v-app-x1", "PGPASSWORD=it's-secret"})
+
+	assert.Equal(t, formatEnvs(envs, false), "PGUSER=v-app-x1\nPGPASSWORD=it's-secret\n")
+	assert.Equal(t, formatEnvs(envs, true), "export PGUSER='v-app-x1'\nexport PGPASSWORD='it'\"'\"'s-secret'\n")
+}
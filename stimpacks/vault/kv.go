@@ -0,0 +1,209 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/PremiereGlobal/stim/pkg/i18n"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// hiddenValue replaces secret values in diffs unless --show-values is given
+const hiddenValue = "(hidden)"
+
+// The kinds of change of a secret key between two versions
+const (
+	changeAdded   = "added"
+	changeRemoved = "removed"
+	changeChanged = "changed"
+)
+
+// secretChange is a key that differs between two versions of a secret
+type secretChange struct {
+	Key    string
+	Change string
+	From   string
+	To     string
+}
+
+// KVDiff prints the keys that differ between two versions of a KV v2 secret.
+// The versions default to the version before the latest and the latest.
+func (v *Vault) KVDiff(path string) error {
+
+	versions := []int{-1, 0}
+	if args := v.stim.ConfigGetStringSlice("vault-kv-diff-versions"); len(args) > 0 {
+		if len(args) != 2 {
+			return stim.UsageError(errors.New("Two versions must be given with --versions (ex. --versions 3,4)"))
+		}
+		for i, arg := range args {
+			version, err := strconv.Atoi(strings.TrimSpace(arg))
+			if err != nil {
+				return stim.UsageError(fmt.Errorf("Invalid version '%s'", arg))
+			}
+			versions[i] = version
+		}
+	}
+
+	vault, err := v.stim.NewVault()
+	if err != nil {
+		return err
+	}
+
+	from, err := vault.SecretVersion(path, versions[0])
+	if err != nil {
+		return err
+	}
+	to, err := vault.SecretVersion(path, versions[1])
+	if err != nil {
+		return err
+	}
+
+	changes, err := v.kvChanges(path, from, to)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Secret: %s  Versions: %d -> %d\n\n", path, from, to)
+	printChanges(changes, v.stim.ConfigGetBool("vault-kv-diff-show-values"))
+
+	return nil
+}
+
+// KVRollback writes an earlier version of a KV v2 secret as its new latest
+// version after showing the changes and confirming
+func (v *Vault) KVRollback(path string) error {
+
+	log := v.stim.GetLogger()
+
+	target := v.stim.ConfigGetInt("vault-kv-rollback-to")
+	if target == 0 {
+		return stim.UsageError(errors.New("The version to roll back to must be given with --to"))
+	}
+
+	vault, err := v.stim.NewVault()
+	if err != nil {
+		return err
+	}
+
+	current, err := vault.SecretVersion(path, 0)
+	if err != nil {
+		return err
+	}
+	target, err = vault.SecretVersion(path, target)
+	if err != nil {
+		return err
+	}
+	if target == current {
+		return fmt.Errorf("Version %d is already the latest version of `%s`", target, path)
+	}
+
+	changes, err := v.kvChanges(path, current, target)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		log.Info("Version {} of `{}` is the same as the latest version {}, nothing to roll back", target, path, current)
+		return nil
+	}
+
+	fmt.Printf("Rolling back %s from version %d to %d\n\n", path, current, target)
+	printChanges(changes, v.stim.ConfigGetBool("vault-kv-rollback-show-values"))
+	fmt.Println()
+
+	yes := v.stim.ConfigGetBool("vault-kv-rollback-yes")
+	if !yes && v.stim.IsAutomated() {
+		return stim.UsageError(errors.New(v.stim.Message(i18n.MessageRollbackAutomated)))
+	}
+	proceed, _ := v.stim.PromptBool(v.stim.Message(i18n.MessageRollbackConfirm), yes, false)
+	if !proceed {
+		return stim.Aborted(v.stim.Message(i18n.MessageRollbackCancelled))
+	}
+
+	version, err := vault.RollbackSecret(path, target)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Rolled back `{}` to version {} as new version {}", path, target, version)
+	return nil
+}
+
+// kvChanges returns the changes between two versions of a secret
+func (v *Vault) kvChanges(path string, from int, to int) ([]secretChange, error) {
+
+	vault, err := v.stim.NewVault()
+	if err != nil {
+		return nil, err
+	}
+
+	fromKeys, err := vault.GetSecretKeysVersion(path, from)
+	if err != nil {
+		return nil, err
+	}
+	toKeys, err := vault.GetSecretKeysVersion(path, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffSecrets(fromKeys, toKeys), nil
+}
+
+// diffSecrets returns the keys that were added, removed or changed between
+// two versions of a secret, sorted by key
+func diffSecrets(from map[string]string, to map[string]string) []secretChange {
+
+	var changes []secretChange
+	for key, value := range from {
+		toValue, ok := to[key]
+		switch {
+		case !ok:
+			changes = append(changes, secretChange{Key: key, Change: changeRemoved, From: value})
+		case toValue != value:
+			changes = append(changes, secretChange{Key: key, Change: changeChanged, From: value, To: toValue})
+		}
+	}
+	for key, value := range to {
+		if _, ok := from[key]; !ok {
+			changes = append(changes, secretChange{Key: key, Change: changeAdded, To: value})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
+
+// printChanges prints the changes as a table.  Values are hidden unless
+// showValues is set.
+func printChanges(changes []secretChange, showValues bool) {
+
+	if len(changes) == 0 {
+		fmt.Println("No changes")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tCHANGE\tFROM\tTO")
+	for _, c := range changes {
+		from, to := c.From, c.To
+		if !showValues {
+			from, to = hideValue(c.Change != changeAdded), hideValue(c.Change != changeRemoved)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.Key, c.Change, from, to)
+	}
+	w.Flush()
+}
+
+// hideValue returns the placeholder for a value that exists
+func hideValue(exists bool) string {
+	if exists {
+		return hiddenValue
+	}
+	return ""
+}
@@ -0,0 +1,19 @@
+package vault
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestDiffSecrets(t *testing.T) {
+	from := map[string]string{"user": "app", "password": "old", "host": "db1"}
+	to := map[string]string{"user": "app", "password": "new", "port": "5432"}
+
+	assert.DeepEqual(t, diffSecrets(from, to), []secretChange{
+		{Key: "host", Change: changeRemoved, From: "db1"},
+		{Key: "password", Change: changeChanged, From: "old", To: "new"},
+		{Key: "port", Change: changeAdded, To: "5432"},
+	})
+	assert.Equal(t, len(diffSecrets(from, from)), 0)
+}
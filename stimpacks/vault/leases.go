@@ -0,0 +1,94 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"text/tabwriter"
+
+	vaultpkg "github.com/PremiereGlobal/stim/pkg/vault"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// ListLeases prints the leases of dynamic secrets that commands left behind
+// (handed out credentials, `vault.keep-leases`, or failed revokes)
+func (v *Vault) ListLeases() error {
+
+	leases, err := v.stim.RecordedLeases()
+	if err != nil {
+		return err
+	}
+	if leases == nil {
+		leases = []*vaultpkg.Lease{}
+	}
+
+	return v.stim.PrintOutput(v.stim.ConfigGetString("vault-leases-output"), leases, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "LEASE ID\tCREATED\tEXPIRES")
+		for _, lease := range leases {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", lease.ID, v.stim.FormatTime(lease.Created), v.stim.FormatTime(lease.Expires))
+		}
+	})
+}
+
+// RevokeLeases revokes leases (all the recorded ones with --all) and stops
+// recording them
+func (v *Vault) RevokeLeases(ids []string) error {
+
+	log := v.stim.GetLogger()
+
+	all := v.stim.ConfigGetBool("vault-leases-all")
+	if all == (len(ids) > 0) {
+		return stim.UsageError(errors.New("Either lease IDs or --all must be given"))
+	}
+
+	recorded, err := v.stim.RecordedLeases()
+	if err != nil {
+		return err
+	}
+	if all {
+		for _, lease := range recorded {
+			ids = append(ids, lease.ID)
+		}
+		if len(ids) == 0 {
+			log.Info("No leases to revoke")
+			return nil
+		}
+	}
+
+	vault, err := v.stim.NewVault()
+	if err != nil {
+		return err
+	}
+	revoked := make(map[string]bool)
+	failed := 0
+	for _, id := range ids {
+		err := vault.RevokeLease(id)
+		if err != nil {
+			log.Warn("Unable to revoke lease {}: {}", id, err)
+			failed++
+			continue
+		}
+		log.Info("Revoked lease {}", id)
+		revoked[id] = true
+	}
+
+	err = v.stim.WriteRecordedLeases(remainingLeases(recorded, revoked))
+	if err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("Unable to revoke %d of %d leases", failed, len(ids))
+	}
+	return nil
+}
+
+// remainingLeases returns the leases that weren't revoked
+func remainingLeases(leases []*vaultpkg.Lease, revoked map[string]bool) []*vaultpkg.Lease {
+	var remaining []*vaultpkg.Lease
+	for _, lease := range leases {
+		if !revoked[lease.ID] {
+			remaining = append(remaining, lease)
+		}
+	}
+	return remaining
+}
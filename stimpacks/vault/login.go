@@ -1,7 +1,8 @@
 package vault
 
 // Login will connect to Vault server and login
-func (v *Vault) Login() {
+func (v *Vault) Login() error {
 	// Get a new Vault from the API
-	_ = v.stim.Vault()
+	_, err := v.stim.NewVault()
+	return err
 }
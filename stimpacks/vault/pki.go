@@ -0,0 +1,215 @@
+package vault
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/PremiereGlobal/stim/pkg/kubernetes"
+	stimvault "github.com/PremiereGlobal/stim/pkg/vault"
+	"github.com/PremiereGlobal/stim/stim"
+)
+
+// defaultPKIMount is the mount of the PKI secrets engine when
+// `vault.pki.mount` is not set
+const defaultPKIMount = "pki"
+
+// PKIIssue issues a certificate from the PKI secrets engine of Vault and
+// writes the certificate, key and CA chain to files or to a Kubernetes TLS
+// secret.  With --renew-before an existing certificate is only replaced when
+// it expires within that window, so the command can run from cron.
+func (v *Vault) PKIIssue() error {
+
+	log := v.stim.GetLogger()
+
+	role := v.stim.ConfigGetString("vault-pki-role")
+	if role == "" {
+		role = v.stim.ConfigGetString("vault.pki.role")
+	}
+	if role == "" {
+		return stim.UsageError(errors.New("--role is required (or set `vault.pki.role`)"))
+	}
+
+	commonName := v.stim.ConfigGetString("vault-pki-common-name")
+	if commonName == "" {
+		return stim.UsageError(errors.New("--common-name is required"))
+	}
+
+	var ttl, renewBefore time.Duration
+	var err error
+	if arg := v.stim.ConfigGetString("vault-pki-ttl"); arg != "" {
+		ttl, err = time.ParseDuration(arg)
+		if err != nil {
+			return stim.UsageError(fmt.Errorf("Invalid --ttl '%s': %v", arg, err))
+		}
+	}
+	if arg := v.stim.ConfigGetString("vault-pki-renew-before"); arg != "" {
+		renewBefore, err = time.ParseDuration(arg)
+		if err != nil {
+			return stim.UsageError(fmt.Errorf("Invalid --renew-before '%s': %v", arg, err))
+		}
+	}
+
+	mount := v.stim.ConfigGetString("vault.pki.mount")
+	if mount == "" {
+		mount = defaultPKIMount
+	}
+
+	var target certificateTarget
+	if ref := v.stim.ConfigGetString("vault-pki-to-k8s-secret"); ref != "" {
+		namespace, name, err := parseSecretRef(ref)
+		if err != nil {
+			return err
+		}
+		kube, err := kubernetes.New(kubernetes.NewConfig())
+		if err != nil {
+			return err
+		}
+		target = &secretTarget{kube: kube, namespace: namespace, name: name}
+	} else {
+		target = newFileTarget(v.stim.ConfigGetString("vault-pki-out-dir"), commonName)
+	}
+
+	if renewBefore > 0 {
+		existing, err := target.read()
+		if err != nil {
+			log.Debug("Unable to read the existing certificate of {}: {}", target, err)
+		}
+		renew, notAfter := needsRenewal(existing, renewBefore, v.stim.Clock().Now())
+		if !renew {
+			log.Info("The certificate in {} is valid until {}, not renewing", target, notAfter.Format(time.RFC3339))
+			return nil
+		}
+	}
+
+	vault, err := v.stim.NewVault()
+	if err != nil {
+		return err
+	}
+	issued, err := vault.IssueCertificate(mount, role, commonName, v.stim.ConfigGetStringSlice("vault-pki-alt-names"), ttl)
+	if err != nil {
+		return err
+	}
+
+	err = target.write(issued)
+	if err != nil {
+		return err
+	}
+	log.Info("Wrote the certificate for {} issued by role {} to {} (serial {}, expires {})", commonName, role, target, issued.SerialNumber, issued.Expiration.Format(time.RFC3339))
+
+	return nil
+}
+
+// certificateTarget is where an issued certificate is written
+type certificateTarget interface {
+
+	// read returns the PEM encoded certificate currently in the target, or nil
+	// if there is none
+	read() ([]byte, error)
+
+	// write writes the issued certificate, key and CA chain
+	write(issued *stimvault.IssuedCertificate) error
+
+	String() string
+}
+
+// fileTarget writes the certificate, key and CA chain to files
+type fileTarget struct {
+	certificate string
+	key         string
+	chain       string
+}
+
+// newFileTarget returns a target that writes `<name>.crt`, `<name>.key` and
+// `<name>-chain.crt` to the directory.  Wildcards in the common name are
+// replaced by `wildcard` (ex. `wildcard.example.com.crt`).
+func newFileTarget(dir string, commonName string) *fileTarget {
+	base := filepath.Join(dir, strings.Replace(commonName, "*", "wildcard", -1))
+	return &fileTarget{
+		certificate: base + ".crt",
+		key:         base + ".key",
+		chain:       base + "-chain.crt",
+	}
+}
+
+func (t *fileTarget) read() ([]byte, error) {
+	return ioutil.ReadFile(t.certificate)
+}
+
+func (t *fileTarget) write(issued *stimvault.IssuedCertificate) error {
+
+	err := ioutil.WriteFile(t.key, []byte(issued.PrivateKey+"\n"), 0600)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(t.certificate, []byte(issued.Certificate+"\n"), 0644)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(t.chain, []byte(strings.Join(issued.CAChain, "\n")+"\n"), 0644)
+}
+
+func (t *fileTarget) String() string {
+	return t.certificate
+}
+
+// secretTarget writes the certificate to a Kubernetes TLS secret.  `tls.crt`
+// holds the certificate followed by the CA chain, as ingress controllers
+// expect.
+type secretTarget struct {
+	kube      *kubernetes.Kubernetes
+	namespace string
+	name      string
+}
+
+func (t *secretTarget) read() ([]byte, error) {
+	data, _, err := t.kube.GetSecretData(t.namespace, t.name)
+	if err != nil {
+		return nil, err
+	}
+	return data["tls.crt"], nil
+}
+
+func (t *secretTarget) write(issued *stimvault.IssuedCertificate) error {
+	certificate := strings.Join(append([]string{issued.Certificate}, issued.CAChain...), "\n") + "\n"
+	return t.kube.ApplyTLSSecret(t.namespace, t.name, map[string][]byte{
+		"tls.crt": []byte(certificate),
+		"tls.key": []byte(issued.PrivateKey + "\n"),
+		"ca.crt":  []byte(strings.Join(issued.CAChain, "\n") + "\n"),
+	})
+}
+
+func (t *secretTarget) String() string {
+	return "secret " + t.namespace + "/" + t.name
+}
+
+// parseSecretRef splits a `namespace/name` secret reference
+func parseSecretRef(ref string) (string, string, error) {
+	parts := strings.Split(ref, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", stim.UsageError(fmt.Errorf("Invalid --to-k8s-secret '%s', must be <namespace>/<name>", ref))
+	}
+	return parts[0], parts[1], nil
+}
+
+// needsRenewal returns true if there is no valid existing certificate or if
+// it expires within the renewal window, along with its expiration
+func needsRenewal(existing []byte, renewBefore time.Duration, now time.Time) (bool, time.Time) {
+
+	block, _ := pem.Decode(existing)
+	if block == nil {
+		return true, time.Time{}
+	}
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return true, time.Time{}
+	}
+
+	return certificate.NotAfter.Sub(now) < renewBefore, certificate.NotAfter
+}
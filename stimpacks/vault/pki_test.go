@@ -0,0 +1,68 @@
+package vault
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestParseSecretRef(t *testing.T) {
+	namespace, name, err := parseSecretRef("web/web-tls")
+	assert.NilError(t, err)
+	assert.Equal(t, namespace, "web")
+	assert.Equal(t, name, "web-tls")
+
+	for _, ref := range []string{"web-tls", "/web-tls", "web/", "a/b/c"} {
+		_, _, err = parseSecretRef(ref)
+		assert.ErrorContains(t, err, "must be <namespace>/<name>")
+	}
+}
+
+func TestNewFileTarget(t *testing.T) {
+	target := newFileTarget("certs", "*.example.com")
+	assert.Equal(t, target.certificate, "certs/wildcard.example.com.crt")
+	assert.Equal(t, target.key, "certs/wildcard.example.com.key")
+	assert.Equal(t, target.chain, "certs/wildcard.example.com-chain.crt")
+}
+
+func TestNeedsRenewal(t *testing.T) {
+	now := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	notAfter := now.Add(30 * 24 * time.Hour)
+	existing := testCertificate(t, notAfter)
+
+	renew, expiration := needsRenewal(existing, 7*24*time.Hour, now)
+	assert.Equal(t, renew, false)
+	assert.Assert(t, expiration.Equal(notAfter))
+
+	renew, _ = needsRenewal(existing, 31*24*time.Hour, now)
+	assert.Equal(t, renew, true)
+
+	renew, _ = needsRenewal(nil, time.Hour, now)
+	assert.Equal(t, renew, true)
+}
+
+// testCertificate returns a PEM encoded self-signed certificate that expires
+// at notAfter
+func testCertificate(t *testing.T, notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "foo.example.com"},
+		NotBefore:    notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NilError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
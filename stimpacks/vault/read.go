@@ -0,0 +1,44 @@
+package vault
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Read prints the keys of a secret, or a single key if --key is set
+func (v *Vault) Read(path string) error {
+
+	vault, err := v.stim.NewVault()
+	if err != nil {
+		return err
+	}
+
+	secrets, err := vault.GetSecretKeysVersion(path, v.stim.ConfigGetInt("vault-read-version"))
+	if err != nil {
+		return err
+	}
+
+	// Dynamic secrets (ex. `aws/creds/<role>`) are handed out, so their
+	// leases aren't revoked when the command ends
+	vault.KeepLeases()
+
+	if key := v.stim.ConfigGetString("vault-read-key"); key != "" {
+		value, ok := secrets[key]
+		if !ok {
+			return fmt.Errorf("Could not find key `%s` for secret `%s`", key, path)
+		}
+		fmt.Println(value)
+		return nil
+	}
+
+	keys := make([]string, 0, len(secrets))
+	for key := range secrets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Printf("%s=%s\n", key, secrets[key])
+	}
+
+	return nil
+}
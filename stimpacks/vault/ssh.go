@@ -0,0 +1,199 @@
+package vault
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PremiereGlobal/stim/stim"
+	"github.com/mitchellh/go-homedir"
+)
+
+// defaultSSHMount is the mount of the SSH secrets engine when
+// `vault.ssh.mount` is not set
+const defaultSSHMount = "ssh-client-signer"
+
+// defaultSSHKeys are the public keys signed when no key is given, in order of
+// preference
+var defaultSSHKeys = []string{"id_ed25519.pub", "id_ecdsa.pub", "id_rsa.pub"}
+
+// SSHSign signs the user's SSH public key with the SSH CA of Vault, writes the
+// certificate next to the key and optionally adds the key and certificate to
+// ssh-agent
+func (v *Vault) SSHSign() error {
+
+	log := v.stim.GetLogger()
+
+	publicKeyPath := v.stim.ConfigGetString("vault-ssh-public-key")
+	if publicKeyPath == "" {
+		home, err := homedir.Dir()
+		if err != nil {
+			return err
+		}
+		publicKeyPath, err = findPublicKey(filepath.Join(home, ".ssh"))
+		if err != nil {
+			return err
+		}
+	}
+	publicKey, err := readPublicKey(publicKeyPath)
+	if err != nil {
+		return err
+	}
+
+	var ttl time.Duration
+	if arg := v.stim.ConfigGetString("vault-ssh-ttl"); arg != "" {
+		ttl, err = time.ParseDuration(arg)
+		if err != nil {
+			return stim.UsageError(fmt.Errorf("Invalid --ttl '%s': %v", arg, err))
+		}
+	}
+
+	mount := v.stim.ConfigGetString("vault.ssh.mount")
+	if mount == "" {
+		mount = defaultSSHMount
+	}
+
+	vault, err := v.stim.NewVault()
+	if err != nil {
+		return err
+	}
+
+	role := v.stim.ConfigGetString("vault-ssh-role")
+	if role == "" {
+		role = v.stim.ConfigGetString("vault.ssh.role")
+	}
+	if role == "" {
+		roles, err := vault.ListSSHRoles(mount)
+		if err != nil {
+			return err
+		}
+		role, err = v.stim.PromptListRemembered("vault/ssh/role", "Select Role", roles, "")
+		if err != nil {
+			return err
+		}
+	}
+
+	signed, err := vault.SignSSHKey(mount, role, publicKey, ttl, v.stim.ConfigGetStringSlice("vault-ssh-principals"))
+	if err != nil {
+		return err
+	}
+
+	certificatePath := sshCertificatePath(publicKeyPath)
+	err = ioutil.WriteFile(certificatePath, []byte(strings.TrimSpace(signed.Certificate)+"\n"), 0644)
+	if err != nil {
+		return err
+	}
+	log.Info("Wrote the certificate of {} signed by role {} to {} (serial {})", publicKeyPath, role, certificatePath, signed.SerialNumber)
+
+	if !v.stim.ConfigGetBool("vault-ssh-agent") {
+		return nil
+	}
+
+	return v.addToAgent(strings.TrimSuffix(publicKeyPath, ".pub"), ttl)
+}
+
+// findPublicKey returns the first of the default public keys in the SSH
+// directory
+func findPublicKey(dir string) (string, error) {
+	for _, name := range defaultSSHKeys {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", stim.ConfigError(fmt.Errorf("No SSH public key (%s) found in %s, set one with --public-key or create one with `ssh-keygen -t ed25519`", strings.Join(defaultSSHKeys, ", "), dir))
+}
+
+// readPublicKey reads an OpenSSH public key (`<type> <base64 key>
+// [comment]`), refusing private keys
+func readPublicKey(path string) (string, error) {
+
+	if !strings.HasSuffix(path, ".pub") {
+		return "", stim.UsageError(fmt.Errorf("'%s' is not a public key (.pub), only the public key is sent to Vault", path))
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", stim.ConfigError(fmt.Errorf("Unable to read the SSH public key: %v", err))
+	}
+
+	key := strings.TrimSpace(string(b))
+	fields := strings.Fields(key)
+	if len(fields) < 2 || strings.Contains(key, "PRIVATE KEY") {
+		return "", stim.ConfigError(fmt.Errorf("'%s' is not an OpenSSH public key", path))
+	}
+
+	return key, nil
+}
+
+// sshCertificatePath returns the path that OpenSSH looks for the certificate
+// of a key at (ex. `id_ed25519-cert.pub` for `id_ed25519.pub`)
+func sshCertificatePath(publicKeyPath string) string {
+	return strings.TrimSuffix(publicKeyPath, ".pub") + "-cert.pub"
+}
+
+// addToAgent adds the private key, with its certificate, to ssh-agent for the
+// lifetime of the certificate.  If no agent is running one is started and the
+// commands to use it from the shell are printed.
+func (v *Vault) addToAgent(privateKeyPath string, ttl time.Duration) error {
+
+	log := v.stim.GetLogger()
+	env := os.Environ()
+
+	if os.Getenv("SSH_AUTH_SOCK") == "" {
+		out, err := exec.Command("ssh-agent", "-s").Output()
+		if err != nil {
+			return fmt.Errorf("Unable to start ssh-agent: %v", err)
+		}
+		agentEnv := parseAgentEnv(string(out))
+		if agentEnv["SSH_AUTH_SOCK"] == "" {
+			return errors.New("Unable to start ssh-agent: no SSH_AUTH_SOCK in its output")
+		}
+		for _, name := range []string{"SSH_AUTH_SOCK", "SSH_AGENT_PID"} {
+			env = append(env, name+"="+agentEnv[name])
+		}
+		fmt.Printf("Started ssh-agent (pid %s).  To use it from this shell run:\n\n    export SSH_AUTH_SOCK=%s SSH_AGENT_PID=%s\n\n", agentEnv["SSH_AGENT_PID"], agentEnv["SSH_AUTH_SOCK"], agentEnv["SSH_AGENT_PID"])
+	}
+
+	// ssh-add loads the `-cert.pub` certificate next to the key as well
+	args := []string{privateKeyPath}
+	if ttl > 0 {
+		args = append([]string{"-t", strconv.Itoa(int(ttl.Seconds()))}, args...)
+	}
+	cmd := exec.Command("ssh-add", args...)
+	cmd.Env = env
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf("Unable to add %s to ssh-agent: %v", privateKeyPath, err)
+	}
+	log.Debug("Added {} to ssh-agent", privateKeyPath)
+
+	return nil
+}
+
+// parseAgentEnv returns the variables set by the Bourne shell output of
+// `ssh-agent -s` (ex. `SSH_AUTH_SOCK=/tmp/ssh-x/agent.1; export SSH_AUTH_SOCK;`)
+func parseAgentEnv(output string) map[string]string {
+
+	env := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		assignment := strings.SplitN(scanner.Text(), ";", 2)[0]
+		parts := strings.SplitN(assignment, "=", 2)
+		if len(parts) == 2 {
+			env[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+
+	return env
+}
@@ -0,0 +1,134 @@
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	stimvault "github.com/PremiereGlobal/stim/pkg/vault"
+)
+
+// tokenStatusOutput is the JSON output of `stim vault token status`
+type tokenStatusOutput struct {
+	Accessor   string     `json:"accessor"`
+	TTLSeconds int64      `json:"ttlSeconds"`
+	ExpireTime *time.Time `json:"expireTime,omitempty"`
+	Renewable  bool       `json:"renewable"`
+	Policies   []string   `json:"policies"`
+}
+
+// TokenStatus prints details about the current Vault token
+func (v *Vault) TokenStatus() error {
+
+	vault, err := v.stim.NewVault()
+	if err != nil {
+		return err
+	}
+	status, err := vault.GetTokenStatus()
+	if err != nil {
+		return err
+	}
+
+	switch format := v.stim.ConfigGetString("vault-token-status-output"); format {
+	case "json":
+		output := tokenStatusOutput{
+			Accessor:   status.Accessor,
+			TTLSeconds: int64(status.TTL.Seconds()),
+			Renewable:  status.Renewable,
+			Policies:   status.Policies,
+		}
+		if !status.ExpireTime.IsZero() {
+			output.ExpireTime = &status.ExpireTime
+		}
+		b, err := json.MarshalIndent(output, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	case "text", "":
+	default:
+		return fmt.Errorf("Invalid output format '%s', must be one of [text, json]", format)
+	}
+
+	fmt.Printf("Accessor:    %s\n", status.Accessor)
+	if status.ExpireTime.IsZero() {
+		fmt.Printf("TTL:         never expires\n")
+		fmt.Printf("Expires:     never\n")
+	} else {
+		fmt.Printf("TTL:         %s\n", v.stim.FormatDuration(status.TTL))
+		fmt.Printf("Expires:     %s\n", v.stim.FormatTime(status.ExpireTime))
+	}
+	fmt.Printf("Renewable:   %t\n", status.Renewable)
+	fmt.Printf("Policies:    %s\n", strings.Join(status.Policies, ", "))
+
+	return nil
+}
+
+// TokenCreate creates a child token for automation.  Only tokens that can
+// create tokens with any policies (admins) are allowed to use it.
+func (v *Vault) TokenCreate() error {
+
+	log := v.stim.GetLogger()
+	vault, err := v.stim.NewVault()
+	if err != nil {
+		return err
+	}
+
+	policies := v.stim.ConfigGetStringSlice("vault-token-create-policies")
+	if len(policies) == 0 {
+		return errors.New("At least one policy must be given with --policy")
+	}
+	displayName := v.stim.ConfigGetString("vault-token-create-display-name")
+	if displayName == "" {
+		return errors.New("A display name must be given with --display-name")
+	}
+
+	var ttl time.Duration
+	if ttlArg := v.stim.ConfigGetString("vault-token-create-ttl"); ttlArg != "" {
+		var err error
+		ttl, err = time.ParseDuration(ttlArg)
+		if err != nil {
+			return fmt.Errorf("Invalid --ttl '%s': %v", ttlArg, err)
+		}
+	}
+
+	allowed, err := vault.CanCreateTokens()
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return errors.New("Creating tokens requires `sudo` on `auth/token/create` in Vault")
+	}
+
+	user, err := v.stim.User()
+	if err != nil {
+		user = "unknown"
+	}
+
+	token, err := vault.CreateToken(&stimvault.TokenCreateOptions{
+		Policies:    policies,
+		TTL:         ttl,
+		NumUses:     v.stim.ConfigGetInt("vault-token-create-use-limit"),
+		DisplayName: displayName,
+		Metadata:    map[string]string{"created-by": user, "created-with": "stim"},
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info("Created Vault token '{}' (accessor {}) with policies [{}]", displayName, token.Accessor, strings.Join(token.Policies, ", "))
+
+	fmt.Printf("Token:       %s\n", token.Token)
+	fmt.Printf("Accessor:    %s\n", token.Accessor)
+	if token.TTL > 0 {
+		fmt.Printf("TTL:         %s\n", v.stim.FormatDuration(token.TTL))
+	} else {
+		fmt.Printf("TTL:         never expires\n")
+	}
+	fmt.Printf("Policies:    %s\n", strings.Join(token.Policies, ", "))
+
+	return nil
+}
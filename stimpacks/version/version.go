@@ -27,9 +27,10 @@ func (v *Version) BindStim(s *stim.Stim) {
 func (v *Version) Command(viper *viper.Viper) *cobra.Command {
 
 	var cmd = &cobra.Command{
-		Use:   "version",
-		Short: "Print the client version",
-		Long:  `Print the client version`,
+		Use:         "version",
+		Annotations: map[string]string{stim.AnnotationInformational: "true"},
+		Short:       "Print the client version",
+		Long:        `Print the client version`,
 		Run: func(cmd *cobra.Command, args []string) {
 			fmt.Printf("stim/%v\n", v.stim.GetVersion())
 		},